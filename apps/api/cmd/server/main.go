@@ -10,25 +10,28 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/Oferzz/newMap/apps/api/internal/cache"
+	"github.com/Oferzz/newMap/apps/api/internal/app"
 	"github.com/Oferzz/newMap/apps/api/internal/config"
-	"github.com/Oferzz/newMap/apps/api/internal/database"
-	"github.com/Oferzz/newMap/apps/api/internal/domain/collections"
-	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
-	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
-	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
-	"github.com/Oferzz/newMap/apps/api/internal/elasticsearch"
-	"github.com/Oferzz/newMap/apps/api/internal/health"
-	"github.com/Oferzz/newMap/apps/api/internal/media"
 	"github.com/Oferzz/newMap/apps/api/internal/middleware"
-	"github.com/Oferzz/newMap/apps/api/internal/nlp"
-	"github.com/Oferzz/newMap/apps/api/internal/search"
-	"github.com/Oferzz/newMap/apps/api/internal/utils"
-	"github.com/gin-contrib/cors"
+	"github.com/Oferzz/newMap/apps/api/pkg/apiversion"
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		runSeed(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		runRestore(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		runReindex(os.Args[2:])
+		return
+	}
+
 	log.Println("Starting newMap API server...")
 	
 	// Load configuration
@@ -39,133 +42,49 @@ func main() {
 	
 	log.Printf("Configuration loaded. Port: %s, Environment: %s", cfg.Server.Port, cfg.Server.Environment)
 
-	// Connect to database (Supabase or PostgreSQL)
-	var db *database.PostgresDB
-	
-	// Debug environment variables
-	log.Printf("Supabase URL: '%s'", cfg.Supabase.URL)
-	log.Printf("Supabase ServiceKey length: %d", len(cfg.Supabase.ServiceKey))
-	log.Printf("Environment check - URL empty: %v, ServiceKey empty: %v", 
-		cfg.Supabase.URL == "", cfg.Supabase.ServiceKey == "")
-	
-	if cfg.Supabase.URL != "" && cfg.Supabase.ServiceKey != "" {
-		log.Println("Connecting to Supabase...")
-		supabaseDB, err := database.NewSupabaseDB(cfg.Supabase.URL, cfg.Supabase.ServiceKey)
-		if err != nil {
-			log.Fatal("Failed to connect to Supabase:", err)
-		}
-		db = supabaseDB.PostgresDB
-		defer db.Close()
-		log.Println("Supabase connected successfully")
-	} else {
-		log.Println("Connecting to PostgreSQL...")
-		db, err = database.NewPostgresDB(&cfg.Database)
-		if err != nil {
-			log.Fatal("Failed to connect to PostgreSQL:", err)
-		}
-		defer db.Close()
-		log.Println("PostgreSQL connected successfully")
-	}
-
-	// Run migrations
-	log.Println("Running database migrations...")
-	if err := db.RunMigrations(cfg.Database.MigrationsPath); err != nil {
-		log.Printf("Warning: Failed to run migrations: %v", err)
-	}
-
-	// Create database extensions
-	ctx := context.Background()
-	if err := db.CreateExtensions(ctx); err != nil {
-		log.Printf("Warning: Failed to create extensions: %v", err)
-	}
-
-	// Connect to Redis (optional - don't fail if not available)
-	var redisClient *database.RedisClient
-	var cacheService cache.Cache
-	redisClient, err = database.NewRedisClient(&cfg.Redis)
-	if err != nil {
-		log.Printf("Warning: Failed to connect to Redis, caching disabled: %v", err)
-		// Use a no-op cache implementation if Redis is not available
-		cacheService = cache.NewNoOpCache()
-	} else {
-		defer redisClient.Close()
-		cacheService = cache.NewRedisCache(redisClient)
-		log.Println("Redis connected, caching enabled")
-	}
-
-	// Initialize JWT manager
-	jwtManager := utils.NewJWTManager(&cfg.JWT)
-
-	// Initialize media storage
-	mediaStorage, err := media.NewDiskStorage(&cfg.Media)
+	// Connect to the database and the services around it; everything else
+	// is built as the router and background workers ask for it
+	container, err := app.New(cfg)
 	if err != nil {
-		log.Fatal("Failed to initialize media storage:", err)
-	}
-
-	// Initialize repositories
-	userRepo := users.NewPostgresRepository(db.DB.DB)
-	tripRepo := trips.NewPostgresRepository(db.DB)
-	placeRepo := places.NewPostgresRepository(db.DB)
-	collectionRepo := collections.NewPostgresRepository(db.DB)
-
-	// Initialize services
-	userService := users.NewPostgreSQLService(userRepo, cfg)
-	
-	// Use cached trip service if Redis is available
-	baseTripService := trips.NewService(tripRepo, userRepo)
-	var tripService trips.Service
-	if cacheService != nil {
-		tripService = trips.NewCachedServicePg(baseTripService, cacheService)
-	} else {
-		tripService = baseTripService
+		log.Fatal(err)
 	}
-	
-	placeService := places.NewServicePg(placeRepo, tripRepo, cfg.App.MapboxAPIKey)
-	mediaService := media.NewService(db.DB, mediaStorage)
-	collectionService := collections.NewService(collectionRepo)
-
-	// Initialize Elasticsearch and search services
-	esClient, err := elasticsearch.NewClient()
-	if err != nil {
-		log.Printf("Warning: Failed to connect to Elasticsearch, search will use fallback: %v", err)
-	} else {
-		log.Println("Elasticsearch client initialized")
-	}
-
-	nlpParser := nlp.NewParser()
-	searchService := search.NewService(esClient, nlpParser)
-
-	// Initialize handlers
-	userHandler := users.NewHandler(userService)
-	tripHandler := trips.NewHandler(tripService)
-	placeHandler := places.NewHandler(placeService)
-	mediaHandler := media.NewHandler(mediaService)
-	collectionHandler := collections.NewHandler(collectionService)
-	searchHandler := search.NewHandler(searchService)
-	healthHandler := health.NewHandler(db.DB, redisClient)
-
-	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(jwtManager)
-	rbacMiddleware := middleware.NewRBACMiddleware(userRepo, tripRepo)
 
 	// Setup router
-	router := setupRouter(cfg, userHandler, tripHandler, placeHandler, mediaHandler, collectionHandler, searchHandler, healthHandler, authMiddleware, rbacMiddleware, mediaStorage)
+	router := setupRouter(cfg, container)
+	container.Background()
 
 	// Create server
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%s", cfg.Server.Port),
-		Handler:      router,
+		Handler:      middleware.NegotiateVersion(router),
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 	}
 
-	// Start server in goroutine
-	go func() {
-		log.Printf("Server starting on port %s", cfg.Server.Port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal("Failed to start server:", err)
-		}
-	}()
+	// The server starts last and stops first, so requests never reach a part
+	// that has stopped
+	container.Lifecycle.Append(app.Hook{
+		Name: "http",
+		OnStart: func(context.Context) error {
+			go func() {
+				log.Printf("Server starting on port %s", cfg.Server.Port)
+				if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					log.Fatal("Failed to start server:", err)
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			// Event streams never finish on their own, so end them before
+			// draining
+			container.RealtimeHub().Close()
+			return srv.Shutdown(ctx)
+		},
+	})
+
+	if err := container.Lifecycle.Start(context.Background()); err != nil {
+		log.Fatal(err)
+	}
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -178,32 +97,26 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
+	if err := container.Lifecycle.Stop(ctx); err != nil {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
 	log.Println("Server exited")
 }
 
-func setupRouter(cfg *config.Config, userHandler *users.Handler, tripHandler *trips.Handler, placeHandler *places.Handler, mediaHandler *media.Handler, collectionHandler *collections.Handler, searchHandler *search.Handler, healthHandler *health.Handler, authMiddleware *middleware.AuthMiddleware, rbacMiddleware *middleware.RBACMiddleware, mediaStorage media.Storage) *gin.Engine {
+func setupRouter(cfg *config.Config, c *app.Container) *gin.Engine {
 	if cfg.Server.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	router := gin.New()
+	router.Use(middleware.RequestID())
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
+	router.Use(middleware.Compress(&cfg.Server.Compression))
 
-	// CORS middleware - temporarily allow all origins to debug CORS issues
-	corsConfig := cors.Config{
-		AllowAllOrigins:  true,
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: false, // Must be false when AllowAllOrigins is true
-		MaxAge:           12 * time.Hour,
-	}
-	router.Use(cors.New(corsConfig))
+	router.Use(middleware.SecurityHeaders(&cfg.Server.Security))
+	router.Use(middleware.CORS(&cfg.Server.Security, cfg.App.AllowedOrigins))
 
 	// Root route to avoid 404s from health checks
 	router.GET("/", func(c *gin.Context) {
@@ -215,123 +128,39 @@ func setupRouter(cfg *config.Config, userHandler *users.Handler, tripHandler *tr
 	})
 
 	// Health check routes
-	healthHandler.RegisterRoutes(router)
-
-	// API v1 routes
-	v1 := router.Group("/api/v1")
-	{
-		// Auth routes
-		auth := v1.Group("/auth")
-		{
-			auth.POST("/register", userHandler.Register)
-			auth.POST("/login", userHandler.Login)
-			auth.POST("/refresh", userHandler.RefreshToken)
-		}
-
-		// User routes
-		userRoutes := v1.Group("/users")
-		{
-			userRoutes.GET("/me", authMiddleware.RequireAuth(), userHandler.GetProfile)
-			userRoutes.PUT("/me", authMiddleware.RequireAuth(), userHandler.UpdateProfile)
-			userRoutes.PUT("/me/password", authMiddleware.RequireAuth(), userHandler.ChangePassword)
-			// userRoutes.DELETE("/me", authMiddleware.RequireAuth(), userHandler.DeleteAccount) // TODO: Implement DeleteAccount
+	c.HealthHandler().RegisterRoutes(router)
+
+	// API routes are mounted once per version, with the same handlers: v2
+	// differs in the shape of its responses and its stricter IDs, which the
+	// version middleware takes care of. Each handler registers its own routes.
+	routes := c.Routes()
+	apiRoutes := func(api *gin.RouterGroup, version string) {
+		mw := c.RouteMiddleware(version)
+		for _, r := range routes {
+			r.RegisterRoutes(api, mw)
 		}
+	}
 
-		// Trip routes
-		tripRoutes := v1.Group("/trips")
-		{
-			// Public routes (authentication optional)
-			tripRoutes.GET("", authMiddleware.OptionalAuth(), tripHandler.List)
-			tripRoutes.GET("/:id", authMiddleware.OptionalAuth(), tripHandler.GetByID)
-
-			// Protected routes (authentication required)
-			tripRoutes.Use(authMiddleware.RequireAuth())
-			{
-				// Create trip (any authenticated user)
-				tripRoutes.POST("", rbacMiddleware.RequireSystemPermission(users.PermissionTripCreate), tripHandler.Create)
-				
-				// Trip-specific routes (permission based on trip role)
-				tripRoutes.PUT("/:id", rbacMiddleware.RequireTripPermission(users.PermissionTripUpdate), tripHandler.Update)
-				tripRoutes.DELETE("/:id", rbacMiddleware.RequireTripOwnership(), tripHandler.Delete)
-				
-				// Collaborator management
-				tripRoutes.POST("/:id/collaborators", rbacMiddleware.RequireTripPermission(users.PermissionTripUpdate), tripHandler.InviteCollaborator)
-				tripRoutes.DELETE("/:id/collaborators/:userId", rbacMiddleware.RequireTripOwnership(), tripHandler.RemoveCollaborator)
-				tripRoutes.PUT("/:id/collaborators/role", rbacMiddleware.RequireTripOwnership(), tripHandler.UpdateCollaboratorRole)
-				tripRoutes.POST("/:id/leave", tripHandler.LeaveTrip)
-			}
-		}
-
-		// Place routes
-		placeRoutes := v1.Group("/places")
-		{
-			// Public place routes (no authentication required)
-			placeRoutes.GET("/search", placeHandler.Search) // Public search endpoint
-			
-			// All other place routes require authentication
-			placeRoutes.Use(authMiddleware.RequireAuth())
-			{
-				// List places (with filters)
-				placeRoutes.GET("", placeHandler.List)
-				placeRoutes.GET("/:id", placeHandler.GetByID)
-				
-				// Create place (requires permission on trip)
-				placeRoutes.POST("", placeHandler.Create)
-				
-				// Update/Delete place (requires permission on trip)
-				placeRoutes.PUT("/:id", placeHandler.Update)
-				placeRoutes.DELETE("/:id", placeHandler.Delete)
-				
-				// Special operations
-				placeRoutes.PUT("/:id/visited", placeHandler.MarkAsVisited)
-				// placeRoutes.GET("/:id/children", placeHandler.GetChildren) // TODO: Implement GetChildren
-			}
-		}
-
-		// Trip places routes (convenience endpoints)
-		tripRoutes.GET("/:id/places", authMiddleware.RequireAuth(), placeHandler.GetByTripID)
-
-		// Collection routes
-		collectionRoutes := v1.Group("/collections")
-		{
-			collectionRoutes.Use(authMiddleware.RequireAuth())
-			{
-				// Collection CRUD
-				collectionRoutes.POST("", collectionHandler.CreateCollection)
-				collectionRoutes.GET("", collectionHandler.GetUserCollections)
-				collectionRoutes.GET("/:id", collectionHandler.GetCollection)
-				collectionRoutes.PUT("/:id", collectionHandler.UpdateCollection)
-				collectionRoutes.DELETE("/:id", collectionHandler.DeleteCollection)
-				
-				// Location management
-				collectionRoutes.POST("/:id/locations", collectionHandler.AddLocationToCollection)
-				collectionRoutes.DELETE("/:id/locations/:locationId", collectionHandler.RemoveLocationFromCollection)
-				
-				// Collaborator management
-				collectionRoutes.POST("/:id/collaborators", collectionHandler.AddCollaborator)
-				collectionRoutes.DELETE("/:id/collaborators/:userId", collectionHandler.RemoveCollaborator)
-			}
-		}
+	// v1 is frozen: it only gets fixes, and points clients to v2
+	v1 := router.Group("/api/"+apiversion.V1, middleware.APIVersion(apiversion.V1), middleware.Deprecated(apiversion.V1, apiversion.V2, apiversion.V2Released, cfg.API.V1Sunset), middleware.BodyLimit(cfg.Server.BodyLimits.JSON))
+	apiRoutes(v1, apiversion.V1)
 
-		// Search routes (public with optional auth)
-		searchHandler.RegisterRoutes(v1, authMiddleware.OptionalAuth())
+	v2 := router.Group("/api/"+apiversion.V2, middleware.APIVersion(apiversion.V2), middleware.TypedIDs(), middleware.BodyLimit(cfg.Server.BodyLimits.JSON))
+	apiRoutes(v2, apiversion.V2)
 
-		// Public Cloudinary routes (no auth required)
-		v1.POST("/media/cloudinary/sign", media.SignCloudinaryURL)
-		v1.GET("/media/cloudinary/config", media.GetCloudinaryConfig)
-		v1.POST("/media/cloudinary/list", media.ListCloudinaryImages)
-		// Media routes
-		mediaRoutes := v1.Group("/media")
-		{
-			mediaRoutes.Use(authMiddleware.RequireAuth())
-			mediaRoutes.Use(media.ValidateFileUpload(cfg.Media.MaxFileSize))
-			mediaHandler.RegisterRoutes(mediaRoutes)
-		}
-	}
+	// Public changelog, across versions
+	router.GET("/api/changelog", func(c *gin.Context) {
+		response.Success(c, gin.H{
+			"versions": apiversion.Supported,
+			"latest":   apiversion.Latest,
+			"default":  apiversion.Default,
+			"changes":  apiversion.Changelog,
+		})
+	})
 
 	// Serve media files (for development)
 	if cfg.Server.Environment != "production" {
-		router.GET("/media/*filepath", mediaHandler.ServeMedia(mediaStorage))
+		router.GET("/media/*filepath", middleware.MediaSecurityHeaders(), c.MediaHandler().ServeMedia(c.Media))
 	}
 
 	return router
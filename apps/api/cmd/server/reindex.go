@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Oferzz/newMap/apps/api/internal/app"
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/elasticsearch"
+	"github.com/Oferzz/newMap/apps/api/internal/search"
+)
+
+// runReindex implements "server reindex", which rebuilds the search index
+// from the database and exits. A run that stops part way is carried on from
+// where it got to by the next, unless it is told to restart:
+//
+//	server reindex [-batch 500] [-types activity,place] [-restart]
+func runReindex(args []string) {
+	flags := flag.NewFlagSet("reindex", flag.ExitOnError)
+	batch := flags.Int("batch", search.DefaultReindexBatchSize, "rows read and indexed at a time")
+	types := flags.String("types", strings.Join(search.ReindexTypes, ","), "document types to reindex, comma separated")
+	restart := flags.Bool("restart", false, "start over instead of carrying on from the last run")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: server reindex [-batch n] [-types activity,place] [-restart]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *batch < 1 || flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+
+	db, err := app.ConnectDatabase(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.RunMigrations(cfg.Database.MigrationsPath); err != nil {
+		log.Fatal("Failed to run migrations:", err)
+	}
+
+	esClient, err := elasticsearch.NewClient()
+	if err != nil {
+		log.Fatal("Failed to create Elasticsearch client:", err)
+	}
+
+	reindexer := search.NewReindexer(
+		esClient,
+		trips.NewPostgresRepository(db.DB),
+		places.NewPostgresRepository(db.DB),
+		search.NewPostgresCheckpoints(db.DB),
+	)
+	reindexer.SetBatchSize(*batch)
+
+	if err := reindexer.Run(context.Background(), strings.Split(*types, ","), *restart); err != nil {
+		log.Fatal("Reindex stopped, run it again to carry on: ", err)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Oferzz/newMap/apps/api/internal/app"
+	"github.com/Oferzz/newMap/apps/api/internal/backup"
+	"github.com/Oferzz/newMap/apps/api/internal/cache"
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/internal/database"
+	"github.com/Oferzz/newMap/apps/api/internal/media"
+)
+
+// runRestore implements "server restore", which puts the content of a
+// backup back and exits:
+//
+//	server restore [-dry-run] <backup-id>
+//	server restore [-dry-run] -key backups/trip/<trip-id>/<backup-id>.json.gz
+func runRestore(args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "report what would be restored without changing anything")
+	key := flags.String("key", "", "restore the backup file at this key instead of a backup by ID")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: server restore [-dry-run] [-key key] [backup-id]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	backupID := flags.Arg(0)
+	if (backupID == "") == (*key == "") {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+
+	db, err := app.ConnectDatabase(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if err := db.RunMigrations(cfg.Database.MigrationsPath); err != nil {
+		log.Fatal("Failed to run migrations:", err)
+	}
+
+	store, err := backup.NewStore(&cfg.Backup)
+	if err != nil {
+		log.Fatal("Failed to initialize backup storage:", err)
+	}
+	mediaStorage, err := media.NewDiskStorage(&cfg.Media)
+	if err != nil {
+		log.Fatal("Failed to initialize media storage:", err)
+	}
+	service := backup.NewService(db.DB, store, mediaStorage, nil)
+
+	archive, err := service.Load(ctx, backupID, *key)
+	if err != nil {
+		log.Fatal("Failed to load backup:", err)
+	}
+
+	result, err := service.Restore(ctx, archive, *dryRun)
+	if err != nil {
+		log.Fatal("Failed to restore backup:", err)
+	}
+
+	for _, table := range result.Tables {
+		log.Printf("%-26s %6d restored %6d skipped", table.Table, table.Restored, table.Skipped)
+	}
+	for _, file := range archive.Media {
+		if file.Missing {
+			log.Printf("Media %s was already missing from storage when backed up (%s)", file.MediaID, file.StoragePath)
+		}
+	}
+	if *dryRun {
+		log.Printf("Dry run of the %s %s backup from %s; nothing was changed",
+			archive.Scope, archive.SubjectID, archive.CreatedAt.Format("2006-01-02 15:04"))
+		return
+	}
+
+	// Drop a running server's cached copies of the restored records
+	if redisClient, err := database.NewRedisClient(&cfg.Redis); err == nil {
+		defer redisClient.Close()
+		cacheService := cache.NewRedisCache(redisClient)
+		for _, tripID := range archive.IDs("trips") {
+			if err := cacheService.InvalidateTripRelated(ctx, tripID); err != nil {
+				log.Printf("Failed to invalidate cached trip %s: %v", tripID, err)
+			}
+		}
+		for _, placeID := range archive.IDs("places") {
+			if err := cacheService.DeletePlace(ctx, placeID); err != nil {
+				log.Printf("Failed to invalidate cached place %s: %v", placeID, err)
+			}
+		}
+		for _, userID := range archive.IDs("users") {
+			if err := cacheService.InvalidateUserTripLists(ctx, userID); err != nil {
+				log.Printf("Failed to invalidate cached trip lists of %s: %v", userID, err)
+			}
+		}
+	}
+
+	log.Printf("Restored the %s %s backup from %s",
+		archive.Scope, archive.SubjectID, archive.CreatedAt.Format("2006-01-02 15:04"))
+}
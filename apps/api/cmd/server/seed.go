@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/Oferzz/newMap/apps/api/internal/app"
+	"github.com/Oferzz/newMap/apps/api/internal/cache"
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/internal/database"
+	"github.com/Oferzz/newMap/apps/api/internal/seed"
+)
+
+// runSeed implements "server seed", which loads the demo dataset and exits
+func runSeed(args []string) {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	force := flags.Bool("force", false, "seed even when ENVIRONMENT is production")
+	flags.Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+	if cfg.Server.Environment == "production" && !*force {
+		log.Fatal("Refusing to seed demo data in production; pass -force to do it anyway")
+	}
+
+	db, err := app.ConnectDatabase(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if err := db.CreateExtensions(ctx); err != nil {
+		log.Fatal("Failed to create extensions:", err)
+	}
+	if err := db.RunMigrations(cfg.Database.MigrationsPath); err != nil {
+		log.Fatal("Failed to run migrations:", err)
+	}
+
+	// Drop cached copies of the demo records from a running server's cache
+	var cacheService cache.Cache = cache.NewNoOpCache()
+	if redisClient, err := database.NewRedisClient(&cfg.Redis); err == nil {
+		defer redisClient.Close()
+		cacheService = cache.NewRedisCache(redisClient)
+	}
+
+	summary, err := seed.NewSeeder(db.DB, cacheService).Run(ctx, seed.Demo())
+	if err != nil {
+		log.Fatal("Failed to seed demo data:", err)
+	}
+
+	log.Printf("Seeded %d users, %d places, %d media, %d trips and %d collections",
+		summary.Users, summary.Places, summary.Media, summary.Trips, summary.Collections)
+	log.Printf("Demo users log in with the password %q", seed.DemoPassword)
+}
@@ -0,0 +1,221 @@
+// Package analytics exports what users search, view and complete, with the
+// personal data taken out, to a sink for offline analytics. Users who opted
+// out are left out before their events are even buffered.
+package analytics
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/jmoiron/sqlx"
+)
+
+// ExportedEvents are the event types the export sends
+var ExportedEvents = []string{
+	events.SearchPerformed,
+	events.TripViewed,
+	events.PlaceViewed,
+	events.TripCompleted,
+}
+
+const (
+	// optOutTTL is how long whether a user opted out is remembered, and so
+	// how long an opt-out takes to apply
+	optOutTTL = time.Minute
+
+	// maxOptOutEntries bounds the remembered opt-outs; all are forgotten
+	// when there are more
+	maxOptOutEntries = 10000
+
+	// bufferedBatches is how many batches are kept while the sink fails,
+	// after which the oldest records are dropped
+	bufferedBatches = 20
+
+	// finalFlushTimeout bounds sending what is left on shutdown
+	finalFlushTimeout = 10 * time.Second
+)
+
+type optOut struct {
+	optedOut  bool
+	checkedAt time.Time
+}
+
+// Exporter buffers the records of exported events and sends them to the
+// sink in batches
+type Exporter struct {
+	db            *sqlx.DB
+	sink          Sink
+	scrubber      *Scrubber
+	batchSize     int
+	flushInterval time.Duration
+	now           func() time.Time
+
+	mu       sync.Mutex
+	buffer   []Record
+	optOuts  map[string]optOut
+	flushing sync.Mutex
+	full     chan struct{}
+}
+
+// NewExporter creates an exporter sending to sink. Without a pseudonym key
+// one is made up, so pseudonyms only match within the process.
+func NewExporter(db *sqlx.DB, sink Sink, cfg *config.AnalyticsConfig) *Exporter {
+	key := []byte(cfg.PseudonymKey)
+	if len(key) == 0 {
+		log.Println("analytics: ANALYTICS_PSEUDONYM_KEY is not set; pseudonyms will change on restart")
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	flushInterval := cfg.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = time.Minute
+	}
+
+	return &Exporter{
+		db:            db,
+		sink:          sink,
+		scrubber:      NewScrubber(key),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		now:           time.Now,
+		optOuts:       make(map[string]optOut),
+		full:          make(chan struct{}, 1),
+	}
+}
+
+// Subscribe exports the events the bus delivers
+func (e *Exporter) Subscribe(bus events.Bus) {
+	for _, eventType := range ExportedEvents {
+		bus.Subscribe(eventType, e.HandleEvent)
+	}
+}
+
+// HandleEvent buffers the record of an event, unless its user opted out.
+// Events whose user cannot be checked are dropped rather than risk
+// exporting them.
+func (e *Exporter) HandleEvent(ctx context.Context, event events.Event) error {
+	if event.ActorID != "" {
+		optedOut, err := e.optedOut(ctx, event.ActorID)
+		if err != nil {
+			return fmt.Errorf("failed to check analytics opt-out: %w", err)
+		}
+		if optedOut {
+			return nil
+		}
+	}
+
+	record := e.scrubber.Scrub(event)
+
+	e.mu.Lock()
+	e.buffer = append(e.buffer, record)
+	if dropped := len(e.buffer) - bufferedBatches*e.batchSize; dropped > 0 {
+		e.buffer = e.buffer[dropped:]
+		log.Printf("analytics: dropped %d records the sink could not take", dropped)
+	}
+	full := len(e.buffer) >= e.batchSize
+	e.mu.Unlock()
+
+	if full {
+		select {
+		case e.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// optedOut reports whether a user opted out of analytics. Users that no
+// longer exist count as opted out.
+func (e *Exporter) optedOut(ctx context.Context, userID string) (bool, error) {
+	now := e.now()
+
+	e.mu.Lock()
+	entry, ok := e.optOuts[userID]
+	e.mu.Unlock()
+	if ok && now.Sub(entry.checkedAt) < optOutTTL {
+		return entry.optedOut, nil
+	}
+
+	var optedOut bool
+	err := e.db.GetContext(ctx, &optedOut, `SELECT analytics_opt_out FROM users WHERE id = $1`, userID)
+	if err == sql.ErrNoRows {
+		optedOut = true
+	} else if err != nil {
+		return false, err
+	}
+
+	e.mu.Lock()
+	if len(e.optOuts) >= maxOptOutEntries {
+		e.optOuts = make(map[string]optOut)
+	}
+	e.optOuts[userID] = optOut{optedOut: optedOut, checkedAt: now}
+	e.mu.Unlock()
+
+	return optedOut, nil
+}
+
+// Start sends buffered records every flush interval, and as soon as a batch
+// is full, until ctx is cancelled. What is left is sent on the way out.
+func (e *Exporter) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(e.flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				flushCtx, cancel := context.WithTimeout(context.Background(), finalFlushTimeout)
+				if err := e.Flush(flushCtx); err != nil {
+					log.Printf("analytics: failed to send records on shutdown: %v", err)
+				}
+				cancel()
+				return
+			case <-ticker.C:
+			case <-e.full:
+			}
+
+			if err := e.Flush(ctx); err != nil {
+				log.Printf("analytics: failed to send records: %v", err)
+			}
+		}
+	}()
+}
+
+// Flush sends the buffered records in batches. Records of a batch the sink
+// fails to take go back into the buffer, to be sent with the next flush.
+func (e *Exporter) Flush(ctx context.Context) error {
+	e.flushing.Lock()
+	defer e.flushing.Unlock()
+
+	e.mu.Lock()
+	records := e.buffer
+	e.buffer = nil
+	e.mu.Unlock()
+
+	for start := 0; start < len(records); start += e.batchSize {
+		end := start + e.batchSize
+		if end > len(records) {
+			end = len(records)
+		}
+
+		if err := e.sink.Write(ctx, records[start:end]); err != nil {
+			e.mu.Lock()
+			e.buffer = append(append([]Record{}, records[start:]...), e.buffer...)
+			e.mu.Unlock()
+			return err
+		}
+	}
+	return nil
+}
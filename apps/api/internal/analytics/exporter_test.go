@@ -0,0 +1,159 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	batches [][]Record
+	err     error
+}
+
+func (s *recordingSink) Write(ctx context.Context, records []Record) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, append([]Record{}, records...))
+	return nil
+}
+
+func setupExporter(t *testing.T, batchSize int) (*Exporter, sqlmock.Sqlmock, *recordingSink) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	sink := &recordingSink{}
+	e := NewExporter(sqlx.NewDb(db, "postgres"), sink, &config.AnalyticsConfig{PseudonymKey: "key", BatchSize: batchSize})
+	return e, mock, sink
+}
+
+func expectOptOut(mock sqlmock.Sqlmock, userID string, optedOut bool) {
+	mock.ExpectQuery("SELECT analytics_opt_out FROM users").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"analytics_opt_out"}).AddRow(optedOut))
+}
+
+func TestExporter_OptOut(t *testing.T) {
+	e, mock, sink := setupExporter(t, 10)
+	ctx := context.Background()
+
+	expectOptOut(mock, "user-1", false)
+	expectOptOut(mock, "user-2", true)
+	mock.ExpectQuery("SELECT analytics_opt_out FROM users").
+		WithArgs("deleted-user").
+		WillReturnRows(sqlmock.NewRows([]string{"analytics_opt_out"}))
+
+	require.NoError(t, e.HandleEvent(ctx, events.New(events.TripViewed, "trip", "trip-1", "user-1", nil)))
+	require.NoError(t, e.HandleEvent(ctx, events.New(events.TripViewed, "trip", "trip-1", "user-2", nil)))
+	require.NoError(t, e.HandleEvent(ctx, events.New(events.TripViewed, "trip", "trip-1", "deleted-user", nil)))
+	require.NoError(t, e.HandleEvent(ctx, events.New(events.SearchPerformed, "search", "", "", nil)))
+
+	// Opt-outs are remembered for a while
+	require.NoError(t, e.HandleEvent(ctx, events.New(events.PlaceViewed, "place", "place-1", "user-2", nil)))
+
+	require.NoError(t, e.Flush(ctx))
+	require.Len(t, sink.batches, 1)
+	require.Len(t, sink.batches[0], 2)
+	assert.Equal(t, e.scrubber.pseudonym("user-1"), sink.batches[0][0].Actor)
+	assert.Equal(t, events.SearchPerformed, sink.batches[0][1].Type)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Once the opt-out is no longer remembered it is looked up again
+	now := time.Now().Add(optOutTTL)
+	e.now = func() time.Time { return now }
+	expectOptOut(mock, "user-2", false)
+	require.NoError(t, e.HandleEvent(ctx, events.New(events.PlaceViewed, "place", "place-1", "user-2", nil)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_DropsEventsItCannotCheck(t *testing.T) {
+	e, mock, _ := setupExporter(t, 10)
+
+	mock.ExpectQuery("SELECT analytics_opt_out FROM users").
+		WithArgs("user-1").
+		WillReturnError(errors.New("connection refused"))
+
+	err := e.HandleEvent(context.Background(), events.New(events.TripViewed, "trip", "trip-1", "user-1", nil))
+	assert.Error(t, err)
+	assert.Empty(t, e.buffer)
+}
+
+func TestExporter_Flush(t *testing.T) {
+	e, _, sink := setupExporter(t, 2)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, e.HandleEvent(ctx, events.New(events.SearchPerformed, "search", "", "", nil)))
+	}
+
+	// A full batch asks for a flush
+	select {
+	case <-e.full:
+	default:
+		t.Fatal("expected a full batch to be signalled")
+	}
+
+	// Records stay buffered while the sink fails
+	sink.err = errors.New("unavailable")
+	assert.Error(t, e.Flush(ctx))
+	assert.Len(t, e.buffer, 5)
+
+	sink.err = nil
+	require.NoError(t, e.Flush(ctx))
+	assert.Len(t, sink.batches, 3)
+	assert.Empty(t, e.buffer)
+}
+
+func TestExporter_BoundsBuffer(t *testing.T) {
+	e, _, _ := setupExporter(t, 1)
+	ctx := context.Background()
+
+	for i := 0; i < bufferedBatches+5; i++ {
+		require.NoError(t, e.HandleEvent(ctx, events.New(events.SearchPerformed, "search", "", "", nil)))
+	}
+	assert.Len(t, e.buffer, bufferedBatches)
+}
+
+type countingRecorder struct {
+	views []string
+}
+
+func (r *countingRecorder) RecordView(ctx context.Context, userID, kind, id string) {
+	r.views = append(r.views, kind+":"+id)
+}
+
+func TestViewPublisher(t *testing.T) {
+	bus := events.NewLocalBus()
+	var published []events.Event
+	for _, eventType := range []string{events.TripViewed, events.PlaceViewed} {
+		bus.Subscribe(eventType, func(ctx context.Context, event events.Event) error {
+			published = append(published, event)
+			return nil
+		})
+	}
+
+	recent := &countingRecorder{}
+	views := NewViewPublisher(bus, recent)
+	views.RecordView(context.Background(), "user-1", "trip", "trip-1")
+	views.RecordView(context.Background(), "user-1", "place", "place-1")
+
+	require.Len(t, published, 2)
+	assert.Equal(t, events.TripViewed, published[0].Type)
+	assert.Equal(t, "trip-1", published[0].EntityID)
+	assert.Equal(t, events.PlaceViewed, published[1].Type)
+	assert.Equal(t, "user-1", published[1].ActorID)
+	assert.Equal(t, []string{"trip:trip-1", "place:place-1"}, recent.views)
+
+	NewViewPublisher(bus, nil).RecordView(context.Background(), "user-1", "trip", "trip-2")
+	assert.Len(t, published, 3)
+}
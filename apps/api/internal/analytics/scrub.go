@@ -0,0 +1,168 @@
+package analytics
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"regexp"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+)
+
+// coordinatePrecision is how many decimals coordinates keep, about a
+// kilometre, which is enough to tell regions apart but not homes
+const coordinatePrecision = 2
+
+// dropKeys name data that identifies people and is never exported
+var dropKeys = map[string]bool{
+	"email":             true,
+	"phone":             true,
+	"ip":                true,
+	"ip_address":        true,
+	"session_id":        true,
+	"user_agent":        true,
+	"name":              true,
+	"display_name":      true,
+	"username":          true,
+	"address":           true,
+	"notes":             true,
+	"message":           true,
+	"visibility_filter": true,
+}
+
+// userKeys name user IDs, which are exported as pseudonyms
+var userKeys = map[string]bool{
+	"user_id":      true,
+	"actor_id":     true,
+	"owner_id":     true,
+	"friend_id":    true,
+	"created_by":   true,
+	"invited_by":   true,
+	"suggested_by": true,
+	"reviewed_by":  true,
+}
+
+// coordinateKeys name coordinates, which are rounded
+var coordinateKeys = map[string]bool{
+	"lat":       true,
+	"latitude":  true,
+	"lng":       true,
+	"lon":       true,
+	"longitude": true,
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`\+?\d[\d\s().-]{6,}\d`)
+)
+
+// Record is an exported event. It holds no user IDs, only pseudonyms that
+// stay the same for as long as the pseudonym key does, so a user's events
+// can be told apart from others' without telling who they are.
+type Record struct {
+	EventID    string                 `json:"event_id"`
+	Type       string                 `json:"type"`
+	EntityType string                 `json:"entity_type,omitempty"`
+	EntityID   string                 `json:"entity_id,omitempty"`
+	Actor      string                 `json:"actor,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+}
+
+// Scrubber turns events into records with the personal data taken out
+type Scrubber struct {
+	key []byte
+}
+
+// NewScrubber creates a scrubber whose pseudonyms are keyed with key
+func NewScrubber(key []byte) *Scrubber {
+	return &Scrubber{key: key}
+}
+
+// Scrub returns the record exported for an event
+func (s *Scrubber) Scrub(event events.Event) Record {
+	record := Record{
+		EventID:    event.ID,
+		Type:       event.Type,
+		EntityType: event.EntityType,
+		EntityID:   event.EntityID,
+		Actor:      s.pseudonym(event.ActorID),
+		OccurredAt: event.OccurredAt.UTC().Truncate(time.Second),
+	}
+	if event.EntityType == "user" {
+		record.EntityID = s.pseudonym(event.EntityID)
+	}
+
+	if len(event.Data) > 0 {
+		// A round trip through JSON leaves only maps, slices and scalars to
+		// walk, whatever types the publisher used
+		var data map[string]interface{}
+		if raw, err := json.Marshal(event.Data); err == nil && json.Unmarshal(raw, &data) == nil {
+			record.Data = s.scrubMap(data)
+		}
+	}
+	return record
+}
+
+// pseudonym replaces a user ID with a keyed hash of it
+func (s *Scrubber) pseudonym(userID string) string {
+	if userID == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(userID))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func (s *Scrubber) scrubMap(data map[string]interface{}) map[string]interface{} {
+	scrubbed := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		switch {
+		case dropKeys[key]:
+			continue
+		case userKeys[key]:
+			if id, ok := value.(string); ok {
+				scrubbed[key] = s.pseudonym(id)
+			}
+		case coordinateKeys[key]:
+			if f, ok := value.(float64); ok {
+				scrubbed[key] = roundCoordinate(f)
+			}
+		default:
+			scrubbed[key] = s.scrubValue(value)
+		}
+	}
+	return scrubbed
+}
+
+func (s *Scrubber) scrubValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return s.scrubMap(v)
+	case []interface{}:
+		scrubbed := make([]interface{}, len(v))
+		for i, item := range v {
+			scrubbed[i] = s.scrubValue(item)
+		}
+		return scrubbed
+	case string:
+		return scrubText(v)
+	default:
+		return v
+	}
+}
+
+// scrubText masks email addresses and phone numbers in free text, such as
+// what users type into search
+func scrubText(text string) string {
+	text = emailPattern.ReplaceAllString(text, "[email]")
+	return phonePattern.ReplaceAllString(text, "[phone]")
+}
+
+func roundCoordinate(f float64) float64 {
+	scale := math.Pow(10, coordinatePrecision)
+	return math.Round(f*scale) / scale
+}
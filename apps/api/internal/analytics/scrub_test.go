@@ -0,0 +1,64 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScrubber_Scrub(t *testing.T) {
+	s := NewScrubber([]byte("key"))
+	occurredAt := time.Date(2024, 5, 1, 12, 30, 15, 500, time.FixedZone("CEST", 2*3600))
+
+	record := s.Scrub(events.Event{
+		ID:         "event-1",
+		Type:       events.SearchPerformed,
+		EntityType: "search",
+		ActorID:    "user-1",
+		OccurredAt: occurredAt,
+		Data: map[string]interface{}{
+			"query":         "hikes near jane.doe@example.com call +1 555 123 4567",
+			"results_count": int64(12),
+			"session_id":    "203.0.113.9",
+			"filters": map[string]interface{}{
+				"activity_types": []string{"hiking"},
+				"location":       map[string]interface{}{"latitude": 46.55812, "longitude": 7.83534, "name": "Home"},
+				"owner_id":       "user-2",
+			},
+		},
+	})
+
+	assert.Equal(t, "event-1", record.EventID)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 30, 15, 0, time.UTC), record.OccurredAt)
+	assert.Len(t, record.Actor, 32)
+	assert.NotContains(t, record.Actor, "user-1")
+	assert.Equal(t, map[string]interface{}{
+		"query":         "hikes near [email] call [phone]",
+		"results_count": float64(12),
+		"filters": map[string]interface{}{
+			"activity_types": []interface{}{"hiking"},
+			"location":       map[string]interface{}{"latitude": 46.56, "longitude": 7.84},
+			"owner_id":       s.pseudonym("user-2"),
+		},
+	}, record.Data)
+}
+
+func TestScrubber_Pseudonyms(t *testing.T) {
+	s := NewScrubber([]byte("key"))
+
+	// The same user always gets the same pseudonym, and other keys other ones
+	first := s.Scrub(events.Event{Type: events.TripViewed, ActorID: "user-1"})
+	second := s.Scrub(events.Event{Type: events.TripViewed, ActorID: "user-1"})
+	other := NewScrubber([]byte("other")).Scrub(events.Event{Type: events.TripViewed, ActorID: "user-1"})
+	assert.Equal(t, first.Actor, second.Actor)
+	assert.NotEqual(t, first.Actor, other.Actor)
+
+	// Users as entities are pseudonymized too
+	record := s.Scrub(events.Event{Type: events.FriendRequestReceived, EntityType: "user", EntityID: "user-1"})
+	assert.Equal(t, first.Actor, record.EntityID)
+
+	anonymous := s.Scrub(events.Event{Type: events.SearchPerformed})
+	assert.Empty(t, anonymous.Actor)
+}
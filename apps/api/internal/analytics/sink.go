@@ -0,0 +1,161 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/pkg/objectstore"
+	"github.com/google/uuid"
+)
+
+// Sink receives exported records. A failed write is retried with the same
+// records, so sinks may see a batch twice and readers dedupe by event ID.
+type Sink interface {
+	Write(ctx context.Context, records []Record) error
+}
+
+// NewSink creates the sink the configuration names, or nil when the export
+// is off
+func NewSink(cfg *config.AnalyticsConfig) (Sink, error) {
+	switch cfg.Sink {
+	case "":
+		return nil, nil
+	case "s3":
+		if cfg.S3Bucket == "" || cfg.S3AccessKeyID == "" || cfg.S3SecretAccessKey == "" {
+			return nil, fmt.Errorf("the s3 analytics sink needs ANALYTICS_S3_BUCKET and credentials")
+		}
+		store := objectstore.NewS3(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKeyID, cfg.S3SecretAccessKey)
+		return NewS3Sink(store, cfg.S3Prefix), nil
+	case "kafka":
+		if cfg.KafkaRESTURL == "" || cfg.KafkaTopic == "" {
+			return nil, fmt.Errorf("the kafka analytics sink needs ANALYTICS_KAFKA_REST_URL and ANALYTICS_KAFKA_TOPIC")
+		}
+		return NewKafkaSink(cfg.KafkaRESTURL, cfg.KafkaTopic), nil
+	default:
+		return nil, fmt.Errorf("unknown analytics sink %q", cfg.Sink)
+	}
+}
+
+// ObjectStore keeps files. objectstore.S3 implements it.
+type ObjectStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// S3Sink writes each batch as a file of newline-delimited JSON, partitioned
+// by the hour it was written in:
+//
+//	<prefix>/dt=2024-05-01/hour=13/<unix-nanos>-<id>.ndjson
+type S3Sink struct {
+	store  ObjectStore
+	prefix string
+	now    func() time.Time
+}
+
+// NewS3Sink creates a sink writing below prefix
+func NewS3Sink(store ObjectStore, prefix string) *S3Sink {
+	return &S3Sink{
+		store:  store,
+		prefix: strings.Trim(prefix, "/"),
+		now:    time.Now,
+	}
+}
+
+func (s *S3Sink) Write(ctx context.Context, records []Record) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to encode record: %w", err)
+		}
+	}
+
+	now := s.now().UTC()
+	key := fmt.Sprintf("dt=%s/hour=%02d/%d-%s.ndjson", now.Format("2006-01-02"), now.Hour(), now.UnixNano(), uuid.New().String())
+	if s.prefix != "" {
+		key = s.prefix + "/" + key
+	}
+
+	if err := s.store.Put(ctx, key, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write analytics batch: %w", err)
+	}
+	return nil
+}
+
+// KafkaSink produces records to a topic through a Kafka REST Proxy, keyed by
+// actor so that each user's events stay in order on one partition
+type KafkaSink struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewKafkaSink creates a sink producing to topic through the proxy at
+// restURL
+func NewKafkaSink(restURL, topic string) *KafkaSink {
+	return &KafkaSink{
+		url: strings.TrimRight(restURL, "/") + "/topics/" + topic,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key,omitempty"`
+	Value Record `json:"value"`
+}
+
+func (s *KafkaSink) Write(ctx context.Context, records []Record) error {
+	batch := struct {
+		Records []kafkaRecord `json:"records"`
+	}{Records: make([]kafkaRecord, len(records))}
+	for i, record := range records {
+		batch.Records[i] = kafkaRecord{Key: record.Actor, Value: record}
+	}
+
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to encode records: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to produce analytics batch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kafka proxy returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	// The proxy answers 200 even when some records failed, and says which
+	var result struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode kafka proxy response: %w", err)
+	}
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("kafka proxy failed to produce a record: %s", offset.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore map[string][]byte
+
+func (m memoryStore) Put(ctx context.Context, key string, data []byte) error {
+	m[key] = data
+	return nil
+}
+
+func TestS3Sink(t *testing.T) {
+	store := memoryStore{}
+	sink := NewS3Sink(store, "/events/")
+	sink.now = func() time.Time { return time.Date(2024, 5, 1, 13, 5, 0, 0, time.UTC) }
+
+	records := []Record{
+		{EventID: "event-1", Type: "trip.viewed"},
+		{EventID: "event-2", Type: "search.performed"},
+	}
+	require.NoError(t, sink.Write(context.Background(), records))
+
+	require.Len(t, store, 1)
+	for key, data := range store {
+		assert.True(t, strings.HasPrefix(key, "events/dt=2024-05-01/hour=13/"), key)
+		assert.True(t, strings.HasSuffix(key, ".ndjson"), key)
+
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		require.Len(t, lines, 2)
+		var record Record
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+		assert.Equal(t, "event-2", record.EventID)
+	}
+}
+
+func TestKafkaSink(t *testing.T) {
+	var body struct {
+		Records []struct {
+			Key   string `json:"key"`
+			Value Record `json:"value"`
+		} `json:"records"`
+	}
+	failRecord := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/topics/analytics", r.URL.Path)
+		assert.Equal(t, "application/vnd.kafka.json.v2+json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		if failRecord {
+			w.Write([]byte(`{"offsets": [{"partition": 0, "offset": 1}, {"error_code": 50002, "error": "broker unavailable"}]}`))
+			return
+		}
+		w.Write([]byte(`{"offsets": [{"partition": 0, "offset": 1}, {"partition": 1, "offset": 7}]}`))
+	}))
+	defer server.Close()
+
+	sink := NewKafkaSink(server.URL+"/", "analytics")
+	records := []Record{
+		{EventID: "event-1", Actor: "abc"},
+		{EventID: "event-2"},
+	}
+	require.NoError(t, sink.Write(context.Background(), records))
+	require.Len(t, body.Records, 2)
+	assert.Equal(t, "abc", body.Records[0].Key)
+	assert.Equal(t, "event-2", body.Records[1].Value.EventID)
+
+	failRecord = true
+	err := sink.Write(context.Background(), records)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broker unavailable")
+}
+
+func TestNewSink(t *testing.T) {
+	sink, err := NewSink(&config.AnalyticsConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, sink)
+
+	sink, err = NewSink(&config.AnalyticsConfig{Sink: "kafka", KafkaRESTURL: "http://proxy:8082", KafkaTopic: "events"})
+	require.NoError(t, err)
+	assert.IsType(t, &KafkaSink{}, sink)
+
+	sink, err = NewSink(&config.AnalyticsConfig{Sink: "s3", S3Bucket: "b", S3AccessKeyID: "k", S3SecretAccessKey: "s"})
+	require.NoError(t, err)
+	assert.IsType(t, &S3Sink{}, sink)
+
+	_, err = NewSink(&config.AnalyticsConfig{Sink: "s3"})
+	assert.Error(t, err)
+
+	_, err = NewSink(&config.AnalyticsConfig{Sink: "bigquery"})
+	assert.Error(t, err)
+}
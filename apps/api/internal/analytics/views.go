@@ -0,0 +1,43 @@
+package analytics
+
+import (
+	"context"
+	"log"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+)
+
+// ViewRecorder is told about the trips and places users view, as the trip
+// and place handlers' recorders are
+type ViewRecorder interface {
+	RecordView(ctx context.Context, userID, kind, id string)
+}
+
+// ViewPublisher announces the trips and places users view, then passes the
+// view on to the recorder of recent views, when there is one
+type ViewPublisher struct {
+	bus  events.Bus
+	next ViewRecorder
+}
+
+// NewViewPublisher creates a recorder announcing views on bus
+func NewViewPublisher(bus events.Bus, next ViewRecorder) *ViewPublisher {
+	return &ViewPublisher{bus: bus, next: next}
+}
+
+// RecordView announces a view. Like any view recorder it must not fail the
+// request it came from, so a failure is only logged.
+func (p *ViewPublisher) RecordView(ctx context.Context, userID, kind, id string) {
+	eventType := events.TripViewed
+	if kind == "place" {
+		eventType = events.PlaceViewed
+	}
+
+	if err := p.bus.Publish(ctx, events.New(eventType, kind, id, userID, nil)); err != nil {
+		log.Printf("analytics: failed to announce view of %s %s: %v", kind, id, err)
+	}
+
+	if p.next != nil {
+		p.next.RecordView(ctx, userID, kind, id)
+	}
+}
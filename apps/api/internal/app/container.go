@@ -0,0 +1,823 @@
+// Package app assembles the server from its parts. The container connects to
+// the infrastructure up front and builds everything else the first time it
+// is asked for, so the server builds all of it while a test builds only the
+// part it exercises. Parts that run in the background add hooks to the
+// container's lifecycle as they are built.
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/analytics"
+	"github.com/Oferzz/newMap/apps/api/internal/backup"
+	"github.com/Oferzz/newMap/apps/api/internal/cache"
+	"github.com/Oferzz/newMap/apps/api/internal/campsites"
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/internal/curation"
+	"github.com/Oferzz/newMap/apps/api/internal/database"
+	"github.com/Oferzz/newMap/apps/api/internal/dataquality"
+	"github.com/Oferzz/newMap/apps/api/internal/diagnostics"
+	"github.com/Oferzz/newMap/apps/api/internal/directions"
+	"github.com/Oferzz/newMap/apps/api/internal/discovery"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/collections"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/groups"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/suggestions"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/internal/elasticsearch"
+	"github.com/Oferzz/newMap/apps/api/internal/elevation"
+	"github.com/Oferzz/newMap/apps/api/internal/email"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/internal/geocode"
+	"github.com/Oferzz/newMap/apps/api/internal/health"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+	"github.com/Oferzz/newMap/apps/api/internal/media"
+	"github.com/Oferzz/newMap/apps/api/internal/middleware"
+	"github.com/Oferzz/newMap/apps/api/internal/nlp"
+	"github.com/Oferzz/newMap/apps/api/internal/realtime"
+	"github.com/Oferzz/newMap/apps/api/internal/recent"
+	"github.com/Oferzz/newMap/apps/api/internal/search"
+	"github.com/Oferzz/newMap/apps/api/internal/seed"
+	"github.com/Oferzz/newMap/apps/api/internal/sharecard"
+	"github.com/Oferzz/newMap/apps/api/internal/utils"
+	"github.com/Oferzz/newMap/apps/api/internal/warmup"
+	"github.com/Oferzz/newMap/apps/api/pkg/httpcache"
+)
+
+// Infrastructure is what the container connects to rather than builds. Left
+// out parts are replaced by ones that work in-process: a no-op cache and CDN
+// purger, a local job queue and a local event bus.
+type Infrastructure struct {
+	DB        *database.PostgresDB
+	Redis     *database.RedisClient // nil without Redis
+	Cache     cache.Cache
+	Purger    httpcache.Purger
+	Queue     jobs.Queue
+	Bus       events.Bus
+	Media     *media.DiskStorage
+	Backups   backup.Store
+	Search    *elasticsearch.Client // nil without Elasticsearch
+	Geocoder  geocode.Geocoder      // nil without a geocoder
+	Analytics analytics.Sink        // nil when exporting analytics is off
+}
+
+// Container builds the parts of the server once each, the first time they
+// are asked for
+type Container struct {
+	Config    *config.Config
+	Lifecycle *Lifecycle
+	Infrastructure
+
+	jwt         lazy[*utils.JWTManager]
+	slowQueries lazy[*diagnostics.SlowQueryLog]
+	cdn         lazy[*media.CDN]
+
+	userRepo       lazy[users.Repository]
+	tripRepo       lazy[*trips.PostgresRepository]
+	placeRepo      lazy[*places.PostgresRepository]
+	collectionRepo lazy[*collections.PostgresRepository]
+	groupRepo      lazy[*groups.PostgresRepository]
+	suggestionRepo lazy[*suggestions.PostgresRepository]
+
+	userService        lazy[userService]
+	tripService        lazy[trips.Service]
+	placePermissions   lazy[*places.PermissionResolver]
+	placeService       lazy[places.Service]
+	mediaService       lazy[*media.Service]
+	collectionService  lazy[*collections.Service]
+	groupService       lazy[groups.Service]
+	suggestionService  lazy[suggestions.Service]
+	shareCardService   lazy[*sharecard.Service]
+	discoveryService   lazy[*discovery.Service]
+	cacheWarmer        lazy[*warmup.Warmer]
+	contactChecker     lazy[*places.ContactChecker]
+	dataQualityService lazy[*dataquality.Service]
+	backupService      lazy[*backup.Service]
+	searchService      lazy[*search.Service]
+	searchIndex        lazy[*search.IndexQueue]
+	tripSummaries      lazy[*trips.Summaries]
+	tripTagger         lazy[*trips.Tagger]
+	offlinePacks       lazy[*trips.OfflinePackService]
+	layerService       lazy[*trips.LayerService]
+	conditionAlerts    lazy[*trips.ConditionAlerts]
+	curationService    lazy[*curation.Service]
+	realtimeHub        lazy[*realtime.Hub]
+	analyticsExporter  lazy[*analytics.Exporter]
+	recentViews        lazy[*recent.Tracker]
+	viewRecorder       lazy[analytics.ViewRecorder]
+}
+
+// userService is what the server needs of the user service, which also
+// checks that sessions are still active
+type userService interface {
+	users.Service
+	middleware.SessionChecker
+}
+
+// lazy holds a part that is built the first time it is asked for. Parts may
+// be nil, such as recent views without Redis, and are still built only once.
+type lazy[T any] struct {
+	built bool
+	value T
+}
+
+func (l *lazy[T]) get(build func() T) T {
+	if !l.built {
+		l.value = build()
+		l.built = true
+	}
+	return l.value
+}
+
+// New connects to the database, Redis, Elasticsearch and the storages the
+// configuration names, and returns a container that builds the rest. Only
+// the database and the storages are required; without the others the server
+// runs with less.
+func New(cfg *config.Config) (*Container, error) {
+	db, err := ConnectDatabase(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Running database migrations...")
+	if err := db.RunMigrations(cfg.Database.MigrationsPath); err != nil {
+		log.Printf("Warning: Failed to run migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := db.CreateExtensions(ctx); err != nil {
+		log.Printf("Warning: Failed to create extensions: %v", err)
+	}
+
+	// Verify spatial and tag indexes in the background, since missing ones
+	// are built concurrently and can take a while on large tables
+	go func() {
+		if _, err := db.EnsureIndexes(ctx); err != nil {
+			log.Printf("Warning: Failed to verify indexes: %v", err)
+		}
+	}()
+
+	infra := Infrastructure{DB: db}
+
+	infra.Media, err = media.NewDiskStorage(&cfg.Media)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize media storage: %w", err)
+	}
+	infra.Backups, err = backup.NewStore(&cfg.Backup)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize backup storage: %w", err)
+	}
+	infra.Analytics, err = analytics.NewSink(&cfg.Analytics)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize analytics export: %w", err)
+	}
+
+	// Redis is optional, caching and shared jobs and events need it
+	redisClient, err := database.NewRedisClient(&cfg.Redis)
+	if err != nil {
+		log.Printf("Warning: Failed to connect to Redis, caching disabled: %v", err)
+	} else {
+		infra.Redis = redisClient
+		infra.Cache = cache.NewRedisCache(redisClient)
+		infra.Queue = jobs.NewRedisQueue(redisClient)
+		log.Println("Redis connected, caching enabled")
+	}
+	if infra.Queue == nil {
+		log.Println("Warning: Redis not available, background jobs will run in-process")
+	}
+
+	// Purge CDN cached responses alongside the Redis cache
+	if cfg.HTTPCache.FastlyServiceID != "" && cfg.HTTPCache.FastlyAPIKey != "" {
+		infra.Purger = httpcache.NewFastlyPurger(cfg.HTTPCache.FastlyServiceID, cfg.HTTPCache.FastlyAPIKey)
+		log.Println("CDN purging enabled")
+	}
+
+	infra.Search, err = elasticsearch.NewClient()
+	if err != nil {
+		log.Printf("Warning: Failed to connect to Elasticsearch, search will use fallback: %v", err)
+	} else {
+		log.Println("Elasticsearch client initialized")
+	}
+
+	// Geocode place names with the configured providers, cached since the
+	// same few names come up over and over
+	infra.Geocoder = geocode.NewFromConfig(&cfg.Geocode)
+	if infra.Geocoder == nil {
+		log.Println("Warning: no geocoder configured, search locations will not be geocoded")
+	} else if redisClient != nil {
+		infra.Geocoder = geocode.NewCachedGeocoder(infra.Geocoder, redisClient, database.CacheTTLDay)
+	}
+
+	return NewWith(cfg, infra), nil
+}
+
+// NewWith returns a container over infrastructure the caller provides, such
+// as tests that assemble part of the server over fakes
+func NewWith(cfg *config.Config, infra Infrastructure) *Container {
+	c := &Container{
+		Config:         cfg,
+		Lifecycle:      &Lifecycle{},
+		Infrastructure: infra,
+	}
+
+	if c.Cache == nil {
+		c.Cache = cache.NewNoOpCache()
+	}
+	if c.Purger == nil {
+		c.Purger = httpcache.NoOpPurger{}
+	}
+	c.Cache = cache.NewPurgingCache(c.Cache, c.Purger)
+	if c.Queue == nil {
+		c.Queue = jobs.NewLocalQueue()
+	}
+
+	// The event bus is shared between instances when Redis is available
+	var redisBus *events.RedisBus
+	if c.Bus == nil {
+		if c.Redis != nil {
+			redisBus = events.NewRedisBus(c.Redis)
+			c.Bus = redisBus
+		} else {
+			c.Bus = events.NewLocalBus()
+		}
+	}
+
+	// Connections close last, after everything that uses them stopped
+	if c.DB != nil {
+		c.Lifecycle.Append(Hook{Name: "database", OnStop: func(context.Context) error { return c.DB.Close() }})
+	}
+	if c.Redis != nil {
+		c.Lifecycle.Append(Hook{Name: "redis", OnStop: func(context.Context) error { return c.Redis.Close() }})
+	}
+
+	// Job workers start once the whole server is built, so every handler
+	// is registered by then
+	c.Lifecycle.Append(Background("jobs", func(ctx context.Context) {
+		c.Queue.Start(ctx, cfg.Jobs.Workers)
+	}))
+	if redisBus != nil {
+		c.Lifecycle.Append(Background("events", redisBus.Start))
+	}
+
+	// Cached trips are dropped wherever they change
+	c.Bus.Subscribe(events.TripPublished, func(ctx context.Context, event events.Event) error {
+		return c.Cache.InvalidateTripRelated(ctx, event.EntityID)
+	})
+	c.Bus.Subscribe(events.TripInvalidated, cache.HandleTripInvalidated(c.Cache))
+
+	return c
+}
+
+// Background builds the parts that only run in the background, which no
+// handler asks for
+func (c *Container) Background() {
+	c.CacheWarmer()
+	c.ContactChecker()
+	c.DataQualityService()
+	c.AnalyticsExporter()
+	c.TripSummaries()
+	c.TripTagger()
+}
+
+// JWT issues and verifies access tokens
+func (c *Container) JWT() *utils.JWTManager {
+	return c.jwt.get(func() *utils.JWTManager {
+		return utils.NewJWTManager(&c.Config.JWT)
+	})
+}
+
+// SlowQueries tracks slow spatial queries, with query plans in diagnostic
+// mode
+func (c *Container) SlowQueries() *diagnostics.SlowQueryLog {
+	return c.slowQueries.get(func() *diagnostics.SlowQueryLog {
+		return diagnostics.NewSlowQueryLog(c.DB.DB, c.Config.Diagnostics.SlowQueryThreshold, c.Config.Diagnostics.ExplainSlowQueries)
+	})
+}
+
+// Repositories
+
+func (c *Container) UserRepository() users.Repository {
+	return c.userRepo.get(func() users.Repository {
+		return users.NewPostgresRepository(c.DB.DB.DB)
+	})
+}
+
+func (c *Container) TripRepository() *trips.PostgresRepository {
+	return c.tripRepo.get(func() *trips.PostgresRepository {
+		repo := trips.NewPostgresRepository(c.DB.DB)
+		repo.SetSlowQueryLog(c.SlowQueries())
+		return repo
+	})
+}
+
+func (c *Container) PlaceRepository() *places.PostgresRepository {
+	return c.placeRepo.get(func() *places.PostgresRepository {
+		repo := places.NewPostgresRepository(c.DB.DB)
+		repo.SetSlowQueryLog(c.SlowQueries())
+		if cdn := c.mediaCDN(); cdn != nil {
+			repo.SetMediaCDN(cdn)
+		}
+		return repo
+	})
+}
+
+func (c *Container) CollectionRepository() *collections.PostgresRepository {
+	return c.collectionRepo.get(func() *collections.PostgresRepository {
+		return collections.NewPostgresRepository(c.DB.DB)
+	})
+}
+
+func (c *Container) GroupRepository() *groups.PostgresRepository {
+	return c.groupRepo.get(func() *groups.PostgresRepository {
+		return groups.NewPostgresRepository(c.DB.DB)
+	})
+}
+
+func (c *Container) SuggestionRepository() *suggestions.PostgresRepository {
+	return c.suggestionRepo.get(func() *suggestions.PostgresRepository {
+		return suggestions.NewPostgresRepository(c.DB.DB)
+	})
+}
+
+// Services
+
+func (c *Container) UserService() userService {
+	return c.userService.get(func() userService {
+		service := users.NewPostgreSQLService(c.UserRepository(), c.Config)
+		if c.Config.Email.SMTPHost != "" {
+			service.SetEmailSender(email.NewSMTPSender(c.Config.Email.SMTPHost, c.Config.Email.SMTPPort, c.Config.Email.SMTPUsername, c.Config.Email.SMTPPassword, c.Config.Email.From()))
+			log.Println("Email sending enabled")
+		} else {
+			// Links in emails are only logged outside production
+			service.SetEmailSender(email.NoOpSender{LogBody: c.Config.Server.Environment != "production"})
+		}
+		// Notify users of friend requests as they happen
+		service.SetEventBus(c.Bus)
+		return service
+	})
+}
+
+// TripService serves trips through the cache
+func (c *Container) TripService() trips.Service {
+	return c.tripService.get(func() trips.Service {
+		publisher := trips.NewPublisher(c.TripRepository(), c.Queue, c.Bus)
+		// Scheduled publications are queued again on start, since the
+		// in-process queue loses them on restart
+		c.Lifecycle.Append(Hook{Name: "scheduled publications", OnStart: func(ctx context.Context) error {
+			if err := publisher.Reschedule(ctx); err != nil {
+				log.Printf("Warning: Failed to reschedule publications: %v", err)
+			}
+			return nil
+		}})
+		service := trips.NewService(c.TripRepository(), c.UserRepository(), publisher)
+		service.SetLayerService(c.LayerService())
+		return trips.NewCachedServicePg(service, c.Cache, c.Bus)
+	})
+}
+
+func (c *Container) PlacePermissions() *places.PermissionResolver {
+	return c.placePermissions.get(func() *places.PermissionResolver {
+		resolver := places.NewPermissionResolver(c.PlaceRepository())
+		resolver.SetEventBus(c.Bus)
+		c.Bus.SubscribeBroadcast(events.PlacePermissionsChanged, resolver.HandlePermissionsChanged)
+		return resolver
+	})
+}
+
+func (c *Container) PlaceService() places.Service {
+	return c.placeService.get(func() places.Service {
+		service := places.NewServicePg(c.PlaceRepository(), c.TripRepository(), c.PlacePermissions(), c.Geocoder, c.Purger)
+		service.SetIndexer(c.SearchIndex())
+		return service
+	})
+}
+
+func (c *Container) MediaService() *media.Service {
+	return c.mediaService.get(func() *media.Service {
+		service := media.NewService(c.DB.DB, c.Media)
+		service.SetURLExpiry(c.Config.Media.URLExpiry)
+		service.SetJobQueue(c.Queue)
+		if cdn := c.mediaCDN(); cdn != nil {
+			service.SetCDN(cdn)
+		}
+		return service
+	})
+}
+
+// mediaCDN serves media from the CDN in production. Development keeps
+// serving files from the /media route.
+func (c *Container) mediaCDN() *media.CDN {
+	return c.cdn.get(func() *media.CDN {
+		if c.Config.Media.CDNBaseURL == "" || c.Config.Server.Environment != "production" {
+			return nil
+		}
+		log.Printf("Serving media from %s", c.Config.Media.CDNBaseURL)
+		return media.NewCDN(c.Config.Media.CDNURL, c.Config.Media.CDNBaseURL)
+	})
+}
+
+func (c *Container) CollectionService() *collections.Service {
+	return c.collectionService.get(func() *collections.Service {
+		service := collections.NewService(c.CollectionRepository(), c.TripService(), c.PlaceService())
+		service.SetIndexer(c.SearchIndex())
+		return service
+	})
+}
+
+func (c *Container) GroupService() groups.Service {
+	return c.groupService.get(func() groups.Service {
+		service := groups.NewService(c.GroupRepository(), c.TripRepository(), c.UserRepository())
+		service.SetEventBus(c.Bus)
+		return service
+	})
+}
+
+func (c *Container) SuggestionService() suggestions.Service {
+	return c.suggestionService.get(func() suggestions.Service {
+		service := suggestions.NewService(c.SuggestionRepository(), c.TripRepository(), c.PlaceRepository(), c.PlacePermissions())
+		service.SetEventBus(c.Bus)
+		return service
+	})
+}
+
+func (c *Container) ShareCardService() *sharecard.Service {
+	return c.shareCardService.get(func() *sharecard.Service {
+		service := sharecard.NewService(c.TripRepository(), c.PlaceRepository(), c.Media, c.Queue, &c.Config.App)
+		c.Bus.Subscribe(events.TripPublished, service.HandleTripPublished)
+		return service
+	})
+}
+
+// DiscoveryService serves the discover lists, refreshing them in the
+// background
+func (c *Container) DiscoveryService() *discovery.Service {
+	return c.discoveryService.get(func() *discovery.Service {
+		service := discovery.NewService(c.DB.DB, c.Queue, c.Purger)
+		service.SetCache(c.Cache)
+		c.Bus.Subscribe(events.TripInvalidated, service.HandleTripInvalidated)
+		c.Bus.Subscribe(events.TripPublished, service.HandleTripPublished)
+		c.Lifecycle.Append(Background("discovery", service.Start))
+		return service
+	})
+}
+
+// TripSummaries regenerates trips' summaries when they change
+func (c *Container) TripSummaries() *trips.Summaries {
+	return c.tripSummaries.get(func() *trips.Summaries {
+		summaries := trips.NewSummaries(c.TripRepository(), c.Queue, trips.TemplateSummarizer{}, c.Bus)
+		c.Bus.Subscribe(events.TripCreated, summaries.HandleTripCreated)
+		c.Bus.Subscribe(events.TripUpdated, summaries.HandleTripUpdated)
+		c.Bus.Subscribe(events.TripWaypointsChanged, summaries.HandleWaypointsChanged)
+		return summaries
+	})
+}
+
+// TripTagger suggests tags for trips from their routes when they change
+func (c *Container) TripTagger() *trips.Tagger {
+	return c.tripTagger.get(func() *trips.Tagger {
+		tagger := trips.NewTagger(c.TripRepository(), c.Queue, c.Bus)
+		if c.Config.App.MapboxAPIKey != "" {
+			tagger.SetElevationProfiler(trips.NewElevationProfiler(elevation.NewMapboxTerrain(c.Config.App.MapboxAPIKey)))
+		}
+		c.Bus.Subscribe(events.TripCreated, tagger.HandleTripCreated)
+		c.Bus.Subscribe(events.TripUpdated, tagger.HandleTripUpdated)
+		return tagger
+	})
+}
+
+// OfflinePacks builds the offline map packs of trips in the background
+func (c *Container) LayerService() *trips.LayerService {
+	return c.layerService.get(func() *trips.LayerService {
+		return trips.NewLayerService(c.TripRepository(), c.Media, c.Config.Media.URLExpiry)
+	})
+}
+
+func (c *Container) OfflinePacks() *trips.OfflinePackService {
+	return c.offlinePacks.get(func() *trips.OfflinePackService {
+		return trips.NewOfflinePackService(c.TripRepository(), c.Media, c.Queue, c.Config.Media.URLExpiry)
+	})
+}
+
+// ConditionAlerts alerts subscribers of verified condition reports
+func (c *Container) ConditionAlerts() *trips.ConditionAlerts {
+	return c.conditionAlerts.get(func() *trips.ConditionAlerts {
+		alerts := trips.NewConditionAlerts(c.TripRepository(), c.Queue, c.Bus)
+		c.Bus.Subscribe(events.ConditionVerified, alerts.HandleConditionVerified)
+		return alerts
+	})
+}
+
+// CacheWarmer fills the cache after deployments and flushes
+func (c *Container) CacheWarmer() *warmup.Warmer {
+	return c.cacheWarmer.get(func() *warmup.Warmer {
+		warmer := warmup.NewWarmer(c.Cache, c.Queue, c.DiscoveryService(), c.TripService())
+		c.Lifecycle.Append(Background("cache warm-up", warmer.Start))
+		return warmer
+	})
+}
+
+// ContactChecker checks place contact details in the background
+func (c *Container) ContactChecker() *places.ContactChecker {
+	return c.contactChecker.get(func() *places.ContactChecker {
+		checker := places.NewContactChecker(c.PlaceRepository(), c.Queue)
+		c.Lifecycle.Append(Background("contact checks", checker.Start))
+		return checker
+	})
+}
+
+func (c *Container) DataQualityService() *dataquality.Service {
+	return c.dataQualityService.get(func() *dataquality.Service {
+		service := dataquality.NewService(c.DB.DB, c.Queue, c.Media)
+		c.Lifecycle.Append(Background("data quality", service.Start))
+		return service
+	})
+}
+
+func (c *Container) BackupService() *backup.Service {
+	return c.backupService.get(func() *backup.Service {
+		return backup.NewService(c.DB.DB, c.Backups, c.Media, c.Queue)
+	})
+}
+
+func (c *Container) SearchService() *search.Service {
+	return c.searchService.get(func() *search.Service {
+		service := search.NewService(c.Search, nlp.NewParser(), c.Geocoder)
+		service.SetRepositories(c.PlaceRepository(), c.TripRepository())
+		service.SetCollectionRepository(c.CollectionRepository())
+		service.SetCurator(c.CurationService())
+		if c.AnalyticsExporter() != nil {
+			service.SetEventBus(c.Bus)
+		}
+		return service
+	})
+}
+
+// SearchIndex indexes changes in the background, retrying while
+// Elasticsearch is down
+func (c *Container) SearchIndex() *search.IndexQueue {
+	return c.searchIndex.get(func() *search.IndexQueue {
+		index := search.NewIndexQueue(c.Search, c.Queue)
+		c.Bus.Subscribe(events.TripPublished, index.HandleTripPublished)
+		return index
+	})
+}
+
+func (c *Container) CurationService() *curation.Service {
+	return c.curationService.get(func() *curation.Service {
+		return curation.NewService(c.DB.DB)
+	})
+}
+
+// RealtimeHub fans trip, suggestion, friend request and condition alert
+// events out to connected clients
+func (c *Container) RealtimeHub() *realtime.Hub {
+	return c.realtimeHub.get(func() *realtime.Hub {
+		hub := realtime.NewHub()
+		hub.Authorize("trip", realtime.TripAuthorizer(c.TripService()))
+		hub.Authorize("user", realtime.UserAuthorizer())
+		for _, eventType := range []string{
+			events.TripPublished, events.TripUpdated, events.TripDeleted, events.TripCollaboratorsChanged,
+			events.TripWaypointsChanged, events.SuggestionCreated, events.SuggestionReviewed, events.SuggestionCommented,
+			events.FriendRequestReceived, events.FriendRequestAccepted, events.ConditionVerified, events.ConditionAlerts,
+		} {
+			c.Bus.SubscribeBroadcast(eventType, hub.HandleEvent)
+		}
+		return hub
+	})
+}
+
+// AnalyticsExporter exports anonymized searches, views and completions, or
+// is nil when exporting is off
+func (c *Container) AnalyticsExporter() *analytics.Exporter {
+	return c.analyticsExporter.get(func() *analytics.Exporter {
+		if c.Analytics == nil {
+			return nil
+		}
+		exporter := analytics.NewExporter(c.DB.DB, c.Analytics, &c.Config.Analytics)
+		exporter.Subscribe(c.Bus)
+		c.Lifecycle.Append(Background("analytics export", exporter.Start))
+		return exporter
+	})
+}
+
+// RecentViews remembers what users viewed, or is nil without Redis
+func (c *Container) RecentViews() *recent.Tracker {
+	return c.recentViews.get(func() *recent.Tracker {
+		if c.Redis == nil {
+			return nil
+		}
+		return recent.NewTracker(c.Redis)
+	})
+}
+
+// ViewRecorder records trip and place views: as recent views, and as
+// analytics events when those are exported. It is nil when neither is.
+func (c *Container) ViewRecorder() analytics.ViewRecorder {
+	return c.viewRecorder.get(func() analytics.ViewRecorder {
+		var next analytics.ViewRecorder
+		if views := c.RecentViews(); views != nil {
+			next = views
+		}
+		if c.AnalyticsExporter() == nil {
+			return next
+		}
+		return analytics.NewViewPublisher(c.Bus, next)
+	})
+}
+
+// Handlers are built anew each time they are asked for, as only the router
+// asks for them
+
+func (c *Container) UserHandler() *users.Handler {
+	return users.NewHandler(c.UserService())
+}
+
+func (c *Container) TripHandler() *trips.Handler {
+	handler := trips.NewHandler(c.TripService())
+	handler.SetShareTokenIssuer(c.JWT())
+	handler.SetLayerService(c.LayerService())
+	handler.SetOfflinePackService(c.OfflinePacks())
+	handler.SetConditionAlerts(c.ConditionAlerts())
+	if c.Config.App.MapboxAPIKey != "" {
+		handler.SetRoutePlanner(trips.NewRoutePlanner(directions.NewMapboxRouter(c.Config.App.MapboxAPIKey)))
+		handler.SetElevationProfiler(trips.NewElevationProfiler(elevation.NewMapboxTerrain(c.Config.App.MapboxAPIKey)))
+	}
+	if recorder := c.ViewRecorder(); recorder != nil {
+		handler.SetViewRecorder(recorder)
+	}
+	return handler
+}
+
+func (c *Container) PlaceHandler() *places.Handler {
+	handler := places.NewHandler(c.PlaceService())
+	handler.SetCampsites(c.campsiteChecker())
+	if recorder := c.ViewRecorder(); recorder != nil {
+		handler.SetViewRecorder(recorder)
+	}
+	return handler
+}
+
+// campsiteChecker checks campground availability with Recreation.gov and
+// ReserveCalifornia, caching each stay for an hour since the same popular
+// campgrounds are looked at over and over
+func (c *Container) campsiteChecker() *campsites.Checker {
+	checker := campsites.NewChecker()
+	providers := map[string]campsites.Provider{
+		campsites.ProviderRecreationGov:     campsites.NewRecreationGov(),
+		campsites.ProviderReserveCalifornia: campsites.NewReserveCalifornia(),
+	}
+	for name, provider := range providers {
+		if c.Redis != nil {
+			provider = campsites.NewCachedProvider(name, provider, c.Redis, time.Hour)
+		}
+		checker.Register(name, provider)
+	}
+	return checker
+}
+
+func (c *Container) RecentHandler() *recent.Handler {
+	return recent.NewHandler(recent.NewService(c.RecentViews(), c.TripRepository(), c.PlaceRepository()))
+}
+
+func (c *Container) MediaHandler() *media.Handler {
+	handler := media.NewHandler(c.MediaService())
+	handler.SetUploadLimits(media.DefaultUploadLimits(c.Config.Media.MaxFileSize))
+	return handler
+}
+
+func (c *Container) CollectionHandler() *collections.Handler {
+	return collections.NewHandler(c.CollectionService())
+}
+
+func (c *Container) GroupHandler() *groups.Handler {
+	return groups.NewHandler(c.GroupService())
+}
+
+func (c *Container) SuggestionHandler() *suggestions.Handler {
+	return suggestions.NewHandler(c.SuggestionService())
+}
+
+func (c *Container) SearchHandler() *search.Handler {
+	return search.NewHandler(c.SearchService())
+}
+
+func (c *Container) ShareCardHandler() *sharecard.Handler {
+	return sharecard.NewHandler(c.ShareCardService())
+}
+
+func (c *Container) DiscoveryHandler() *discovery.Handler {
+	return discovery.NewHandler(c.DiscoveryService())
+}
+
+func (c *Container) RealtimeHandler() *realtime.Handler {
+	return realtime.NewHandler(c.RealtimeHub())
+}
+
+func (c *Container) DiagnosticsHandler() *diagnostics.Handler {
+	return diagnostics.NewHandler(c.DB, c.SlowQueries())
+}
+
+func (c *Container) SeedHandler() *seed.Handler {
+	return seed.NewHandler(seed.NewSeeder(c.DB.DB, c.Cache))
+}
+
+func (c *Container) CurationHandler() *curation.Handler {
+	return curation.NewHandler(c.CurationService())
+}
+
+func (c *Container) DataQualityHandler() *dataquality.Handler {
+	return dataquality.NewHandler(c.DataQualityService())
+}
+
+func (c *Container) BackupHandler() *backup.Handler {
+	return backup.NewHandler(c.BackupService())
+}
+
+func (c *Container) HealthHandler() *health.Handler {
+	return health.NewHandler(c.DB.DB, c.Redis)
+}
+
+// Routes are the handlers that register their own routes on the API group
+// of each version. The seed routes, which overwrite demo records, are left
+// out in production.
+func (c *Container) Routes() []users.RouteRegistrar {
+	routes := []users.RouteRegistrar{
+		c.UserHandler(),
+		c.RecentHandler(),
+		c.TripHandler(),
+		c.PlaceHandler(),
+		c.CollectionHandler(),
+		c.GroupHandler(),
+		c.SuggestionHandler(),
+		c.SearchHandler(),
+		c.ShareCardHandler(),
+		c.DiscoveryHandler(),
+		c.RealtimeHandler(),
+		c.MediaHandler(),
+		c.DiagnosticsHandler(),
+		c.CurationHandler(),
+		c.DataQualityHandler(),
+		c.BackupHandler(),
+	}
+	if c.Config.Server.Environment != "production" {
+		routes = append(routes, c.SeedHandler())
+	}
+	return routes
+}
+
+// Middleware
+
+// RouteMiddleware is the middleware handlers register the routes of one API
+// version with
+func (c *Container) RouteMiddleware(version string) *users.RouteMiddleware {
+	return middleware.RouteMiddleware(version, &c.Config.Server.BodyLimits, c.AuthMiddleware(), c.RBACMiddleware(), c.ShareLinkMiddleware())
+}
+
+func (c *Container) AuthMiddleware() *middleware.AuthMiddleware {
+	auth := middleware.NewAuthMiddleware(c.JWT())
+	auth.SetSessionChecker(c.UserService())
+	return auth
+}
+
+func (c *Container) RBACMiddleware() *middleware.RBACMiddleware {
+	return middleware.NewRBACMiddleware(c.UserRepository(), c.TripRepository(), c.PlacePermissions())
+}
+
+func (c *Container) ShareLinkMiddleware() *middleware.ShareLinkMiddleware {
+	return middleware.NewShareLinkMiddleware(c.TripService())
+}
+
+// ConnectDatabase connects to Supabase when it is configured and to
+// PostgreSQL otherwise
+func ConnectDatabase(cfg *config.Config) (*database.PostgresDB, error) {
+	// Debug environment variables
+	log.Printf("Supabase URL: '%s'", cfg.Supabase.URL)
+	log.Printf("Supabase ServiceKey length: %d", len(cfg.Supabase.ServiceKey))
+	log.Printf("Environment check - URL empty: %v, ServiceKey empty: %v",
+		cfg.Supabase.URL == "", cfg.Supabase.ServiceKey == "")
+
+	if cfg.Supabase.URL != "" && cfg.Supabase.ServiceKey != "" {
+		log.Println("Connecting to Supabase...")
+		supabaseDB, err := database.NewSupabaseDB(cfg.Supabase.URL, cfg.Supabase.ServiceKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to Supabase: %w", err)
+		}
+		log.Println("Supabase connected successfully")
+		return supabaseDB.PostgresDB, nil
+	}
+
+	log.Println("Connecting to PostgreSQL...")
+	db, err := database.NewPostgresDB(&cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	log.Println("PostgreSQL connected successfully")
+	return db, nil
+}
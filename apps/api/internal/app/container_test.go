@@ -0,0 +1,83 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/internal/database"
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hookNames(l *Lifecycle) []string {
+	names := make([]string, 0, len(l.hooks))
+	for _, hook := range l.hooks {
+		names = append(names, hook.Name)
+	}
+	return names
+}
+
+func TestNewWith_PartialGraph(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	c := NewWith(&config.Config{}, Infrastructure{
+		DB: &database.PostgresDB{DB: sqlx.NewDb(db, "postgres")},
+	})
+
+	// In-process stand-ins replace what was left out
+	assert.NotNil(t, c.Cache)
+	assert.NotNil(t, c.Queue)
+	assert.NotNil(t, c.Bus)
+	assert.Equal(t, []string{"database", "jobs"}, hookNames(c.Lifecycle))
+
+	// Parts are built once, with what they depend on, and register their
+	// background work then
+	discover := c.DiscoveryService()
+	assert.Same(t, discover, c.DiscoveryService())
+	assert.Same(t, c.TripRepository(), c.TripRepository())
+	assert.Equal(t, []string{"database", "jobs", "discovery"}, hookNames(c.Lifecycle))
+
+	// Parts that need what is missing are left out
+	assert.Nil(t, c.RecentViews())
+	assert.Nil(t, c.AnalyticsExporter())
+}
+
+func TestContainer_Routes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	cfg := &config.Config{}
+	cfg.Server.Environment = "production"
+	c := NewWith(cfg, Infrastructure{
+		DB: &database.PostgresDB{DB: sqlx.NewDb(db, "postgres")},
+	})
+
+	// Every handler registers on each version without clashing with another
+	router := gin.New()
+	for _, version := range []string{"v1", "v2"} {
+		mw := c.RouteMiddleware(version)
+		for _, r := range c.Routes() {
+			r.RegisterRoutes(router.Group("/api/"+version), mw)
+		}
+	}
+
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	assert.True(t, registered["POST /api/v1/auth/login"])
+	assert.True(t, registered["GET /api/v2/trips/:id"])
+	assert.True(t, registered["GET /api/v1/trips/:id/places"])
+	assert.True(t, registered["POST /api/v2/media/upload"])
+	assert.True(t, registered["GET /api/v1/admin/backups"])
+
+	// Demo data is never loaded over production data
+	assert.False(t, registered["POST /api/v1/admin/seed"])
+}
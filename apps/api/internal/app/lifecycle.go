@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Hook starts and stops a part of the server. Either function may be nil.
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Background returns a hook for a worker that runs until the context it is
+// started with is cancelled, as discovery refreshes and the job queue do.
+// The worker is given a context of its own, cancelled when the hook stops.
+func Background(name string, start func(ctx context.Context)) Hook {
+	var cancel context.CancelFunc
+	return Hook{
+		Name: name,
+		OnStart: func(context.Context) error {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			start(ctx)
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			if cancel != nil {
+				cancel()
+			}
+			return nil
+		},
+	}
+}
+
+// Lifecycle starts the parts of the server in the order they were added and
+// stops them in reverse, so nothing stops before what depends on it
+type Lifecycle struct {
+	hooks   []Hook
+	started int
+}
+
+// Append adds a hook. Hooks added once the lifecycle started are not run.
+func (l *Lifecycle) Append(hook Hook) {
+	l.hooks = append(l.hooks, hook)
+}
+
+// Start runs the start hooks in turn. When one fails, the parts already
+// started are stopped again and its error is returned.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	for _, hook := range l.hooks[l.started:] {
+		if hook.OnStart != nil {
+			if err := hook.OnStart(ctx); err != nil {
+				err = fmt.Errorf("failed to start %s: %w", hook.Name, err)
+				if stopErr := l.Stop(ctx); stopErr != nil {
+					log.Printf("Failed to stop after a failed start: %v", stopErr)
+				}
+				return err
+			}
+		}
+		l.started++
+	}
+	return nil
+}
+
+// Stop runs the stop hooks of the started parts in reverse. Every part is
+// stopped even when others fail to; their errors are returned together.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	var errs []error
+	for ; l.started > 0; l.started-- {
+		hook := l.hooks[l.started-1]
+		if hook.OnStop == nil {
+			continue
+		}
+		if err := hook.OnStop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", hook.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
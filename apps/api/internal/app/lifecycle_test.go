@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func recordingHook(name string, calls *[]string, startErr error) Hook {
+	return Hook{
+		Name: name,
+		OnStart: func(context.Context) error {
+			*calls = append(*calls, "start "+name)
+			return startErr
+		},
+		OnStop: func(context.Context) error {
+			*calls = append(*calls, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestLifecycle_StartStop(t *testing.T) {
+	var calls []string
+	l := &Lifecycle{}
+	l.Append(recordingHook("database", &calls, nil))
+	l.Append(Hook{Name: "no-op"})
+	l.Append(recordingHook("http", &calls, nil))
+
+	require.NoError(t, l.Start(context.Background()))
+	require.NoError(t, l.Stop(context.Background()))
+
+	assert.Equal(t, []string{"start database", "start http", "stop http", "stop database"}, calls)
+
+	// Stopping twice stops nothing more
+	require.NoError(t, l.Stop(context.Background()))
+	assert.Len(t, calls, 4)
+}
+
+func TestLifecycle_FailedStart(t *testing.T) {
+	var calls []string
+	l := &Lifecycle{}
+	l.Append(recordingHook("database", &calls, nil))
+	l.Append(recordingHook("jobs", &calls, errors.New("no workers")))
+	l.Append(recordingHook("http", &calls, nil))
+
+	err := l.Start(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to start jobs")
+
+	// Only what started is stopped, and what came after never starts
+	assert.Equal(t, []string{"start database", "start jobs", "stop database"}, calls)
+}
+
+func TestLifecycle_StopErrors(t *testing.T) {
+	l := &Lifecycle{}
+	l.Append(Hook{Name: "redis", OnStop: func(context.Context) error { return errors.New("redis gone") }})
+	l.Append(Hook{Name: "database", OnStop: func(context.Context) error { return errors.New("database gone") }})
+
+	require.NoError(t, l.Start(context.Background()))
+	err := l.Stop(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to stop redis")
+	assert.Contains(t, err.Error(), "failed to stop database")
+}
+
+func TestBackground(t *testing.T) {
+	done := make(chan struct{})
+	l := &Lifecycle{}
+	l.Append(Background("worker", func(ctx context.Context) {
+		go func() {
+			<-ctx.Done()
+			close(done)
+		}()
+	}))
+
+	require.NoError(t, l.Start(context.Background()))
+	require.NoError(t, l.Stop(context.Background()))
+	<-done
+}
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Audited actions
+const (
+	ActionOwnershipTransferRequested = "ownership_transfer.requested"
+	ActionOwnershipTransferAccepted  = "ownership_transfer.accepted"
+	ActionOwnershipTransferDeclined  = "ownership_transfer.declined"
+)
+
+// Entry is a single record in the audit trail
+type Entry struct {
+	ID         string                 `db:"id" json:"id"`
+	ActorID    string                 `db:"actor_id" json:"actor_id"`
+	Action     string                 `db:"action" json:"action"`
+	EntityType string                 `db:"entity_type" json:"entity_type"`
+	EntityID   string                 `db:"entity_id" json:"entity_id"`
+	Details    map[string]interface{} `db:"-" json:"details,omitempty"`
+	CreatedAt  time.Time              `db:"created_at" json:"created_at"`
+}
+
+// Record writes an entry to the audit trail. It accepts either the database
+// or an open transaction so the entry commits together with the change it
+// describes.
+func Record(ctx context.Context, exec sqlx.ExecerContext, entry *Entry) error {
+	var details []byte
+	if entry.Details != nil {
+		var err error
+		details, err = json.Marshal(entry.Details)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit details: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO audit_log (actor_id, action, entity_type, entity_id, details)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := exec.ExecContext(ctx, query,
+		entry.ActorID,
+		entry.Action,
+		entry.EntityType,
+		entry.EntityID,
+		details,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return nil
+}
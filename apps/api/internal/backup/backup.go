@@ -0,0 +1,390 @@
+// Package backup exports the content of a user or a trip to object storage
+// and restores it, so that support can recover content that was deleted by
+// mistake. Backups are logical: every row as JSON, plus a manifest of the
+// media files the rows point to.
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// JobBackup exports the content of a requested backup
+const JobBackup = "backup.export"
+
+// FormatVersion is written to every backup, and restores refuse backups of
+// a format they do not know
+const FormatVersion = 1
+
+// Backup scopes
+const (
+	ScopeUser = "user"
+	ScopeTrip = "trip"
+)
+
+// Backup statuses
+const (
+	StatusPending   = "pending"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+var (
+	ErrBackupNotFound  = errors.New("backup not found")
+	ErrSubjectNotFound = errors.New("nothing to back up with that ID")
+	ErrInvalidScope    = errors.New("scope must be user or trip")
+	ErrBackupPending   = errors.New("backup has not completed")
+	ErrUnknownFormat   = errors.New("backup format is not supported")
+)
+
+// Backup is a requested backup and, once the job has run, where it is kept
+type Backup struct {
+	ID          string     `db:"id" json:"id"`
+	Scope       string     `db:"scope" json:"scope"`
+	SubjectID   string     `db:"subject_id" json:"subject_id"`
+	Status      string     `db:"status" json:"status"`
+	ObjectKey   *string    `db:"object_key" json:"object_key,omitempty"`
+	SizeBytes   *int64     `db:"size_bytes" json:"size_bytes,omitempty"`
+	Error       *string    `db:"error" json:"error,omitempty"`
+	RequestedBy *string    `db:"requested_by" json:"requested_by,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	CompletedAt *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+}
+
+// CreateBackupInput is an admin's request for a backup
+type CreateBackupInput struct {
+	Scope     string `json:"scope" binding:"required"`
+	SubjectID string `json:"subject_id" binding:"required,uuid"`
+}
+
+// Archive is the content of a backup file
+type Archive struct {
+	Version   int         `json:"version"`
+	BackupID  string      `json:"backup_id"`
+	Scope     string      `json:"scope"`
+	SubjectID string      `json:"subject_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Tables    []TableDump `json:"tables"` // in the order they are restored
+	Media     []MediaFile `json:"media"`
+}
+
+// TableDump holds the backed up rows of a table
+type TableDump struct {
+	Table string            `json:"table"`
+	Rows  []json.RawMessage `json:"rows"`
+}
+
+// MediaFile describes a stored file a media row points to. Files are not
+// copied; the checksum tells whether the file in storage is still the same.
+type MediaFile struct {
+	MediaID     string `db:"media_id" json:"media_id"`
+	StoragePath string `db:"storage_path" json:"storage_path"`
+	MimeType    string `db:"mime_type" json:"mime_type"`
+	SizeBytes   int64  `db:"size_bytes" json:"size_bytes"`
+	SHA256      string `db:"-" json:"sha256,omitempty"`
+	Missing     bool   `db:"-" json:"missing,omitempty"`
+}
+
+// FileStore locates stored media files. media.Storage implements it.
+type FileStore interface {
+	GetFullPath(filePath string) string
+}
+
+type payload struct {
+	BackupID string `json:"backup_id"`
+}
+
+// Service requests, exports and restores backups
+type Service struct {
+	db    *sqlx.DB
+	store Store
+	files FileStore
+	queue jobs.Queue
+}
+
+// NewService creates a new backup service. With a queue the export job
+// handler is registered; the restore command has no need for one.
+func NewService(db *sqlx.DB, store Store, files FileStore, queue jobs.Queue) *Service {
+	s := &Service{
+		db:    db,
+		store: store,
+		files: files,
+		queue: queue,
+	}
+
+	if queue != nil {
+		queue.Register(JobBackup, s.run)
+	}
+
+	return s
+}
+
+// Request records a backup and queues its export
+func (s *Service) Request(ctx context.Context, userID string, input *CreateBackupInput) (*Backup, error) {
+	table, ok := subjectTables[input.Scope]
+	if !ok {
+		return nil, ErrInvalidScope
+	}
+
+	var exists bool
+	err := s.db.GetContext(ctx, &exists, fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1)`, table), input.SubjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s %s: %w", input.Scope, input.SubjectID, err)
+	}
+	if !exists {
+		return nil, ErrSubjectNotFound
+	}
+
+	backup := &Backup{}
+	err = s.db.GetContext(ctx, backup, `
+		INSERT INTO backups (scope, subject_id, requested_by)
+		VALUES ($1, $2, $3)
+		RETURNING *`,
+		input.Scope, input.SubjectID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	if _, err := s.queue.Enqueue(ctx, JobBackup, payload{BackupID: backup.ID}); err != nil {
+		return nil, fmt.Errorf("failed to queue backup: %w", err)
+	}
+
+	return backup, nil
+}
+
+// Get returns a backup
+func (s *Service) Get(ctx context.Context, backupID string) (*Backup, error) {
+	if _, err := uuid.Parse(backupID); err != nil {
+		return nil, ErrBackupNotFound
+	}
+
+	backup := &Backup{}
+	err := s.db.GetContext(ctx, backup, `SELECT * FROM backups WHERE id = $1`, backupID)
+	if err == sql.ErrNoRows {
+		return nil, ErrBackupNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup: %w", err)
+	}
+	return backup, nil
+}
+
+// List returns the backups of a user or trip, newest first
+func (s *Service) List(ctx context.Context, scope, subjectID string) ([]*Backup, error) {
+	backups := []*Backup{}
+	err := s.db.SelectContext(ctx, &backups, `
+		SELECT * FROM backups
+		WHERE scope = $1 AND subject_id = $2
+		ORDER BY created_at DESC`, scope, subjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	return backups, nil
+}
+
+// run exports a requested backup. A backup that keeps failing is marked as
+// failed when its last attempt does.
+func (s *Service) run(ctx context.Context, job *jobs.Job) error {
+	var p payload
+	if err := job.Decode(&p); err != nil {
+		return err
+	}
+
+	backup, err := s.Get(ctx, p.BackupID)
+	if errors.Is(err, ErrBackupNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if backup.Status != StatusPending {
+		return nil
+	}
+
+	key, size, err := s.export(ctx, backup)
+	if err != nil {
+		if job.Attempts >= job.MaxAttempts {
+			s.fail(ctx, backup.ID, err)
+		}
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE backups
+		SET status = $2, object_key = $3, size_bytes = $4, error = NULL, completed_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		backup.ID, StatusCompleted, key, size)
+	if err != nil {
+		return fmt.Errorf("failed to complete backup: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) fail(ctx context.Context, backupID string, cause error) {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE backups SET status = $2, error = $3, completed_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		backupID, StatusFailed, cause.Error())
+	if err != nil {
+		log.Printf("backup: failed to mark backup %s as failed: %v", backupID, err)
+	}
+}
+
+// export writes the backup file and returns its key and size
+func (s *Service) export(ctx context.Context, backup *Backup) (string, int64, error) {
+	archive, err := s.Dump(ctx, backup.Scope, backup.SubjectID)
+	if err != nil {
+		return "", 0, err
+	}
+	archive.BackupID = backup.ID
+
+	data, err := encode(archive)
+	if err != nil {
+		return "", 0, err
+	}
+
+	key := objectKey(backup.Scope, backup.SubjectID, backup.ID)
+	if err := s.store.Put(ctx, key, data); err != nil {
+		return "", 0, err
+	}
+	return key, int64(len(data)), nil
+}
+
+// Dump reads everything in the scope of a user or trip. It reads in a
+// single repeatable-read transaction, so the rows are consistent with each
+// other.
+func (s *Service) Dump(ctx context.Context, scope, subjectID string) (*Archive, error) {
+	if _, ok := subjectTables[scope]; !ok {
+		return nil, ErrInvalidScope
+	}
+
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	sets, err := resolveSets(ctx, tx, scope, subjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &Archive{
+		Version:   FormatVersion,
+		Scope:     scope,
+		SubjectID: subjectID,
+		CreatedAt: time.Now().UTC(),
+	}
+	for _, spec := range tableSpecs {
+		ids := sets[spec.set]
+		if len(ids) == 0 {
+			continue
+		}
+
+		var rows []json.RawMessage
+		query := fmt.Sprintf(`SELECT to_jsonb(t) FROM %s t WHERE %s`, spec.table, spec.where)
+		if err := tx.SelectContext(ctx, &rows, query, pq.Array(ids)); err != nil {
+			return nil, fmt.Errorf("failed to back up %s: %w", spec.table, err)
+		}
+		if len(rows) > 0 {
+			archive.Tables = append(archive.Tables, TableDump{Table: spec.table, Rows: rows})
+		}
+	}
+
+	if archive.Media, err = s.mediaManifest(ctx, tx, sets[setMedia]); err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// mediaManifest lists the files of the media rows in the backup
+func (s *Service) mediaManifest(ctx context.Context, tx *sqlx.Tx, mediaIDs []string) ([]MediaFile, error) {
+	files := []MediaFile{}
+	if len(mediaIDs) == 0 {
+		return files, nil
+	}
+
+	err := tx.SelectContext(ctx, &files, `
+		SELECT id AS media_id, storage_path, mime_type, size_bytes
+		FROM media
+		WHERE id = ANY($1)
+		ORDER BY id`, pq.Array(mediaIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list media files: %w", err)
+	}
+
+	for i := range files {
+		sum, err := checksum(s.files.GetFullPath(files[i].StoragePath))
+		if errors.Is(err, os.ErrNotExist) {
+			files[i].Missing = true
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read media file %s: %w", files[i].MediaID, err)
+		}
+		files[i].SHA256 = sum
+	}
+	return files, nil
+}
+
+func checksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// encode writes an archive as gzipped JSON
+func encode(archive *Archive) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(archive); err != nil {
+		return nil, fmt.Errorf("failed to encode backup: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress backup: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode reads a backup file
+func Decode(data []byte) (*Archive, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress backup: %w", err)
+	}
+	defer zr.Close()
+
+	archive := &Archive{}
+	if err := json.NewDecoder(zr).Decode(archive); err != nil {
+		return nil, fmt.Errorf("failed to decode backup: %w", err)
+	}
+	if archive.Version != FormatVersion {
+		return nil, fmt.Errorf("%w: version %d", ErrUnknownFormat, archive.Version)
+	}
+	return archive, nil
+}
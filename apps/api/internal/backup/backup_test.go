@@ -0,0 +1,187 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dirStore string
+
+func (d dirStore) GetFullPath(filePath string) string {
+	return string(d) + "/" + filePath
+}
+
+func setupService(t *testing.T) (*Service, sqlmock.Sqlmock, *DiskStore) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	store := NewDiskStore(t.TempDir())
+	s := NewService(sqlx.NewDb(db, "postgres"), store, dirStore(t.TempDir()), jobs.NewLocalQueue())
+	return s, mock, store
+}
+
+func TestService_Request(t *testing.T) {
+	s, mock, _ := setupService(t)
+
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM trips").
+		WithArgs("trip-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("INSERT INTO backups").
+		WithArgs(ScopeTrip, "trip-1", "admin-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "scope", "subject_id", "status", "created_at"}).
+			AddRow("backup-1", ScopeTrip, "trip-1", StatusPending, time.Now()))
+
+	backup, err := s.Request(context.Background(), "admin-1", &CreateBackupInput{Scope: ScopeTrip, SubjectID: "trip-1"})
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, backup.Status)
+
+	_, err = s.Request(context.Background(), "admin-1", &CreateBackupInput{Scope: "place", SubjectID: "place-1"})
+	assert.ErrorIs(t, err, ErrInvalidScope)
+
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM users").
+		WithArgs("user-9").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	_, err = s.Request(context.Background(), "admin-1", &CreateBackupInput{Scope: ScopeUser, SubjectID: "user-9"})
+	assert.ErrorIs(t, err, ErrSubjectNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEncodeDecode(t *testing.T) {
+	archive := &Archive{
+		Version:   FormatVersion,
+		BackupID:  "backup-1",
+		Scope:     ScopeTrip,
+		SubjectID: "trip-1",
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Tables: []TableDump{
+			{Table: "trips", Rows: []json.RawMessage{json.RawMessage(`{"id":"trip-1"}`)}},
+		},
+		Media: []MediaFile{{MediaID: "media-1", StoragePath: "a.jpg", Missing: true}},
+	}
+
+	data, err := encode(archive)
+	require.NoError(t, err)
+
+	decoded, err := Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, archive, decoded)
+	assert.Equal(t, []string{"trip-1"}, decoded.IDs("trips"))
+
+	archive.Version = FormatVersion + 1
+	data, err = encode(archive)
+	require.NoError(t, err)
+	_, err = Decode(data)
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestService_Restore(t *testing.T) {
+	s, mock, _ := setupService(t)
+
+	archive := &Archive{
+		Version:   FormatVersion,
+		Scope:     ScopeTrip,
+		SubjectID: "trip-1",
+		Tables: []TableDump{
+			{Table: "places", Rows: []json.RawMessage{
+				json.RawMessage(`{"id":"place-1","name":"Lake","parent_id":"place-0","search_vector":"x"}`),
+			}},
+			{Table: "trip_waypoints", Rows: []json.RawMessage{
+				json.RawMessage(`{"id":"waypoint-1","trip_id":"trip-1","place_id":"place-9"}`),
+			}},
+		},
+	}
+
+	mock.ExpectBegin()
+
+	// Generated columns and deferred references are left out of the insert
+	mock.ExpectQuery("FROM information_schema.columns").
+		WithArgs("places").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("id").AddRow("name").AddRow("parent_id"))
+	mock.ExpectExec("SAVEPOINT restore_row").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "places" ("id", "name")`)).
+		WithArgs(string(archive.Tables[0].Rows[0])).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("RELEASE SAVEPOINT restore_row").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// The waypoint's place is gone for good, so the waypoint is skipped
+	mock.ExpectQuery("FROM information_schema.columns").
+		WithArgs("trip_waypoints").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("id").AddRow("trip_id").AddRow("place_id"))
+	mock.ExpectExec("SAVEPOINT restore_row").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "trip_waypoints" ("id", "place_id", "trip_id")`)).
+		WillReturnError(&pq.Error{Code: foreignKeyViolation})
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT restore_row").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("RELEASE SAVEPOINT restore_row").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec("SAVEPOINT restore_row").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "places" t SET "parent_id" = r."parent_id"`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("RELEASE SAVEPOINT restore_row").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec("UPDATE trips SET deleted_at = NULL").
+		WithArgs("trip-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	result, err := s.Restore(context.Background(), archive, false)
+	require.NoError(t, err)
+	assert.Equal(t, []TableRestore{
+		{Table: "places", Restored: 1},
+		{Table: "trip_waypoints", Skipped: 1},
+	}, result.Tables)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_RestoreRejectsUnknownTables(t *testing.T) {
+	s, mock, _ := setupService(t)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	_, err := s.Restore(context.Background(), &Archive{
+		Version: FormatVersion,
+		Tables:  []TableDump{{Table: "user_sessions"}},
+	}, false)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_Load(t *testing.T) {
+	s, mock, store := setupService(t)
+
+	data, err := encode(&Archive{Version: FormatVersion, Scope: ScopeUser, SubjectID: "user-1"})
+	require.NoError(t, err)
+	key := objectKey(ScopeUser, "user-1", "11111111-1111-1111-1111-111111111111")
+	require.NoError(t, store.Put(context.Background(), key, data))
+
+	mock.ExpectQuery("SELECT \\* FROM backups").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "object_key"}).
+			AddRow("11111111-1111-1111-1111-111111111111", StatusCompleted, key))
+
+	archive, err := s.Load(context.Background(), "11111111-1111-1111-1111-111111111111", "")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", archive.SubjectID)
+
+	mock.ExpectQuery("SELECT \\* FROM backups").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "object_key"}).
+			AddRow("22222222-2222-2222-2222-222222222222", StatusPending, nil))
+
+	_, err = s.Load(context.Background(), "22222222-2222-2222-2222-222222222222", "")
+	assert.ErrorIs(t, err, ErrBackupPending)
+
+	archive, err = s.Load(context.Background(), "", key)
+	require.NoError(t, err)
+	assert.Equal(t, ScopeUser, archive.Scope)
+}
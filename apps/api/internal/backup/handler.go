@@ -0,0 +1,94 @@
+package backup
+
+import (
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/internal/middleware"
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the backup admin endpoints. Restores are run from the
+// command line with "server restore".
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new backup handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// CreateBackup queues a backup of a user or trip
+func (h *Handler) CreateBackup(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input CreateBackupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	backup, err := h.service.Request(c.Request.Context(), userID, &input)
+	if err != nil {
+		switch err {
+		case ErrInvalidScope:
+			response.BadRequest(c, err.Error())
+		case ErrSubjectNotFound:
+			response.NotFound(c, err.Error())
+		default:
+			response.InternalServerError(c, "Failed to create backup")
+		}
+		return
+	}
+
+	response.Accepted(c, backup)
+}
+
+// ListBackups returns the backups of the user or trip in the query
+func (h *Handler) ListBackups(c *gin.Context) {
+	scope := c.Query("scope")
+	subjectID := c.Query("subject_id")
+	if _, ok := subjectTables[scope]; !ok || subjectID == "" {
+		response.BadRequest(c, "scope and subject_id are required")
+		return
+	}
+
+	backups, err := h.service.List(c.Request.Context(), scope, subjectID)
+	if err != nil {
+		response.InternalServerError(c, "Failed to list backups")
+		return
+	}
+
+	response.Success(c, backups)
+}
+
+// GetBackup returns a backup and whether it has completed
+func (h *Handler) GetBackup(c *gin.Context) {
+	backup, err := h.service.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if err == ErrBackupNotFound {
+			response.NotFound(c, err.Error())
+			return
+		}
+		response.InternalServerError(c, "Failed to get backup")
+		return
+	}
+
+	response.Success(c, backup)
+}
+
+// RegisterRoutes registers the backup routes for admins
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *users.RouteMiddleware) {
+	admin := router.Group("/admin", mw.RequireAdmin...)
+	admin.POST("/backups", h.CreateBackup)
+	admin.GET("/backups", h.ListBackups)
+	admin.GET("/backups/:id", h.GetBackup)
+}
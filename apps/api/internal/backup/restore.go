@@ -0,0 +1,255 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// foreignKeyViolation is the Postgres error code for a row that refers to a
+// row that does not exist
+const foreignKeyViolation = "23503"
+
+// RestoreResult counts what a restore did, per table
+type RestoreResult struct {
+	DryRun bool           `json:"dry_run"`
+	Tables []TableRestore `json:"tables"`
+}
+
+// TableRestore counts the rows of a table that were restored, and those
+// skipped because they still exist or refer to rows that are gone for good
+type TableRestore struct {
+	Table    string `json:"table"`
+	Restored int    `json:"restored"`
+	Skipped  int    `json:"skipped"`
+}
+
+// Load reads a backup file, either the one a backup was written to or one
+// given by key
+func (s *Service) Load(ctx context.Context, backupID, key string) (*Archive, error) {
+	if key == "" {
+		backup, err := s.Get(ctx, backupID)
+		if err != nil {
+			return nil, err
+		}
+		if backup.Status != StatusCompleted || backup.ObjectKey == nil {
+			return nil, ErrBackupPending
+		}
+		key = *backup.ObjectKey
+	}
+
+	data, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// Restore puts the rows of a backup back. Rows that still exist are left as
+// they are, so a restore only brings back what was deleted; a trip that was
+// soft deleted is undeleted. Everything is restored in one transaction, which
+// a dry run rolls back.
+func (s *Service) Restore(ctx context.Context, archive *Archive, dryRun bool) (*RestoreResult, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &RestoreResult{DryRun: dryRun, Tables: []TableRestore{}}
+
+	type pending struct {
+		spec tableSpec
+		rows []json.RawMessage
+	}
+	var deferred []pending
+
+	for _, dump := range archive.Tables {
+		// Table names come from the file, so only known tables are touched
+		spec, ok := specFor(dump.Table)
+		if !ok {
+			return nil, fmt.Errorf("backup holds unknown table %q", dump.Table)
+		}
+
+		columns, err := tableColumns(ctx, tx, spec.table)
+		if err != nil {
+			return nil, err
+		}
+		for _, column := range spec.deferred {
+			delete(columns, column)
+		}
+
+		counts := TableRestore{Table: spec.table}
+		var restored []json.RawMessage
+		for _, row := range dump.Rows {
+			inserted, err := restoreRow(ctx, tx, spec.table, columns, row)
+			if err != nil {
+				return nil, err
+			}
+			if inserted {
+				counts.Restored++
+				restored = append(restored, row)
+			} else {
+				counts.Skipped++
+			}
+		}
+		result.Tables = append(result.Tables, counts)
+
+		if len(spec.deferred) > 0 && len(restored) > 0 {
+			deferred = append(deferred, pending{spec: spec, rows: restored})
+		}
+	}
+
+	for _, p := range deferred {
+		for _, row := range p.rows {
+			if err := restoreDeferred(ctx, tx, p.spec, row); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if archive.Scope == ScopeTrip {
+		_, err := tx.ExecContext(ctx, `UPDATE trips SET deleted_at = NULL WHERE id = $1`, archive.SubjectID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to undelete trip: %w", err)
+		}
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit restore: %w", err)
+	}
+	return result, nil
+}
+
+// tableColumns returns the columns of a table that can be written, which
+// leaves out generated columns
+func tableColumns(ctx context.Context, tx *sqlx.Tx, table string) (map[string]bool, error) {
+	var names []string
+	err := tx.SelectContext(ctx, &names, `
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND is_generated = 'NEVER'`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+
+	columns := make(map[string]bool, len(names))
+	for _, name := range names {
+		columns[name] = true
+	}
+	return columns, nil
+}
+
+// restoreRow inserts a row unless it still exists. Rows referring to rows
+// that cannot be restored are skipped; the savepoint keeps that from
+// aborting the transaction.
+func restoreRow(ctx context.Context, tx *sqlx.Tx, table string, columns map[string]bool, row json.RawMessage) (bool, error) {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(row, &values); err != nil {
+		return false, fmt.Errorf("failed to decode %s row: %w", table, err)
+	}
+
+	// Columns dropped since the backup are ignored, and those added since
+	// take their defaults
+	var names []string
+	for name := range values {
+		if columns[name] {
+			names = append(names, pq.QuoteIdentifier(name))
+		}
+	}
+	if len(names) == 0 {
+		return false, nil
+	}
+	sort.Strings(names)
+	list := strings.Join(names, ", ")
+
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s (%[2]s)
+		SELECT %[2]s FROM jsonb_populate_record(NULL::%[1]s, $1)
+		ON CONFLICT DO NOTHING`, pq.QuoteIdentifier(table), list)
+
+	var inserted bool
+	err := savepoint(ctx, tx, func() error {
+		res, err := tx.ExecContext(ctx, query, string(row))
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		inserted = n > 0
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to restore %s row: %w", table, err)
+	}
+	return inserted, nil
+}
+
+// restoreDeferred fills in the deferred columns of a restored row, now that
+// the rows they refer to are back
+func restoreDeferred(ctx context.Context, tx *sqlx.Tx, spec tableSpec, row json.RawMessage) error {
+	sets := make([]string, len(spec.deferred))
+	for i, column := range spec.deferred {
+		sets[i] = fmt.Sprintf("%[1]s = r.%[1]s", pq.QuoteIdentifier(column))
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %[1]s t SET %[2]s
+		FROM jsonb_populate_record(NULL::%[1]s, $1) r
+		WHERE t.id = r.id`, pq.QuoteIdentifier(spec.table), strings.Join(sets, ", "))
+
+	err := savepoint(ctx, tx, func() error {
+		_, err := tx.ExecContext(ctx, query, string(row))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore references of %s row: %w", spec.table, err)
+	}
+	return nil
+}
+
+// savepoint runs fn inside a savepoint. A foreign key violation rolls back
+// to the savepoint and is not an error.
+func savepoint(ctx context.Context, tx *sqlx.Tx, fn func() error) error {
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT restore_row`); err != nil {
+		return err
+	}
+
+	err := fn()
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		_, err = tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT restore_row`)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `RELEASE SAVEPOINT restore_row`)
+	return err
+}
+
+// IDs returns the IDs of the rows of a table in the backup
+func (a *Archive) IDs(table string) []string {
+	var ids []string
+	for _, dump := range a.Tables {
+		if dump.Table != table {
+			continue
+		}
+		for _, row := range dump.Rows {
+			var r struct {
+				ID string `json:"id"`
+			}
+			if json.Unmarshal(row, &r) == nil && r.ID != "" {
+				ids = append(ids, r.ID)
+			}
+		}
+	}
+	return ids
+}
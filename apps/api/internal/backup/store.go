@@ -0,0 +1,98 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/pkg/objectstore"
+)
+
+// ErrObjectNotFound is a backup file that does not exist
+var ErrObjectNotFound = objectstore.ErrNotFound
+
+// Store keeps backup files. Keys are slash separated paths.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// NewStore creates the store the configuration names
+func NewStore(cfg *config.BackupConfig) (Store, error) {
+	switch cfg.Storage {
+	case "", "disk":
+		return NewDiskStore(cfg.Path), nil
+	case "s3":
+		if cfg.S3Bucket == "" || cfg.S3AccessKeyID == "" || cfg.S3SecretAccessKey == "" {
+			return nil, fmt.Errorf("s3 backup storage needs BACKUP_S3_BUCKET and credentials")
+		}
+		return objectstore.NewS3(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKeyID, cfg.S3SecretAccessKey), nil
+	default:
+		return nil, fmt.Errorf("unknown backup storage %q", cfg.Storage)
+	}
+}
+
+// DiskStore keeps backups in a local directory, for development and for
+// volumes that are themselves backed up
+type DiskStore struct {
+	basePath string
+}
+
+// NewDiskStore creates a store writing below basePath
+func NewDiskStore(basePath string) *DiskStore {
+	return &DiskStore{basePath: basePath}
+}
+
+func (s *DiskStore) Put(ctx context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash leaves no partial backup
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStore) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	return data, nil
+}
+
+// path resolves a key below the base path, refusing keys that leave it
+func (s *DiskStore) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if cleaned == "/" {
+		return "", fmt.Errorf("invalid backup key %q", key)
+	}
+	return filepath.Join(s.basePath, cleaned), nil
+}
+
+// objectKey is where a backup is kept
+func objectKey(scope, subjectID, backupID string) string {
+	return fmt.Sprintf("backups/%s/%s/%s.json.gz", scope, subjectID, url.PathEscape(backupID))
+}
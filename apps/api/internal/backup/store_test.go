@@ -0,0 +1,50 @@
+package backup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/pkg/objectstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskStore(t *testing.T) {
+	store := NewDiskStore(t.TempDir())
+	ctx := context.Background()
+
+	key := objectKey(ScopeTrip, "trip-1", "backup-1")
+	require.NoError(t, store.Put(ctx, key, []byte("backup")))
+
+	data, err := store.Get(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("backup"), data)
+
+	_, err = store.Get(ctx, "backups/trip/trip-1/other.json.gz")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+
+	// Keys cannot leave the store's directory
+	require.NoError(t, store.Put(ctx, "../../escaped", []byte("x")))
+	data, err = store.Get(ctx, "escaped")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("x"), data)
+
+	assert.Error(t, store.Put(ctx, "..", []byte("x")))
+}
+
+func TestNewStore(t *testing.T) {
+	store, err := NewStore(&config.BackupConfig{Storage: "disk", Path: t.TempDir()})
+	require.NoError(t, err)
+	assert.IsType(t, &DiskStore{}, store)
+
+	store, err = NewStore(&config.BackupConfig{Storage: "s3", S3Region: "us-east-1", S3Bucket: "b", S3AccessKeyID: "k", S3SecretAccessKey: "s"})
+	require.NoError(t, err)
+	assert.IsType(t, &objectstore.S3{}, store)
+
+	_, err = NewStore(&config.BackupConfig{Storage: "s3"})
+	assert.Error(t, err)
+
+	_, err = NewStore(&config.BackupConfig{Storage: "tape"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,150 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// subjectTables is the table each scope's subject lives in
+var subjectTables = map[string]string{
+	ScopeUser: "users",
+	ScopeTrip: "trips",
+}
+
+// The sets of IDs a backup is made of. Every table is backed up by one of
+// them.
+const (
+	setUser        = "user"
+	setTrips       = "trips"
+	setPlaces      = "places"
+	setMedia       = "media"
+	setCollections = "collections"
+	setTargets     = "targets" // trips and places, which suggestions are made on
+)
+
+// tableSpec says which rows of a table belong in a backup. The where clause
+// is given the IDs of the set as $1. Deferred columns refer to rows that may
+// be restored after the row itself, and are filled in once everything is.
+type tableSpec struct {
+	table    string
+	set      string
+	where    string
+	deferred []string
+}
+
+// tableSpecs lists the backed up tables in an order rows can be restored in.
+// Sessions and tokens are left out on purpose; restored users sign in again.
+var tableSpecs = []tableSpec{
+	{table: "users", set: setUser, where: "id = ANY($1)"},
+
+	{table: "places", set: setPlaces, where: "id = ANY($1)", deferred: []string{"parent_id"}},
+	{table: "place_collaborators", set: setPlaces, where: "place_id = ANY($1)"},
+	{table: "place_water_sources", set: setPlaces, where: "place_id = ANY($1)"},
+	{table: "place_campgrounds", set: setPlaces, where: "place_id = ANY($1)"},
+
+	{table: "media", set: setMedia, where: "id = ANY($1)"},
+	{table: "place_media", set: setPlaces, where: "place_id = ANY($1)"},
+	{table: "place_reviews", set: setPlaces, where: "place_id = ANY($1)"},
+
+	{table: "trips", set: setTrips, where: "id = ANY($1)"},
+	{table: "trip_collaborators", set: setTrips, where: "trip_id = ANY($1)"},
+	{table: "trip_waypoints", set: setTrips, where: "trip_id = ANY($1)"},
+	{table: "trip_participants", set: setTrips, where: "trip_id = ANY($1)"},
+	{table: "trip_drafts", set: setTrips, where: "trip_id = ANY($1)"},
+	{table: "trip_readiness_checks", set: setTrips, where: "trip_id = ANY($1)"},
+	{table: "trip_layers", set: setTrips, where: "trip_id = ANY($1)"},
+	{table: "trip_annotations", set: setTrips, where: "trip_id = ANY($1)"},
+	{table: "trip_route_variants", set: setTrips, where: "trip_id = ANY($1)"},
+	{table: "activity_completions", set: setTrips, where: "trip_id = ANY($1)"},
+	{table: "activity_ratings", set: setTrips, where: "trip_id = ANY($1)"},
+	{table: "activity_conditions", set: setTrips, where: "trip_id = ANY($1)"},
+	{table: "activity_share_links", set: setTrips, where: "trip_id = ANY($1)"},
+	{table: "group_trips", set: setTrips, where: "trip_id = ANY($1)"},
+	{table: "trip_date_polls", set: setTrips, where: "trip_id = ANY($1)", deferred: []string{"chosen_option_id"}},
+	{table: "trip_date_poll_options", set: setTrips, where: "poll_id IN (SELECT id FROM trip_date_polls WHERE trip_id = ANY($1))"},
+	{table: "trip_date_poll_votes", set: setTrips, where: `option_id IN (
+		SELECT o.id FROM trip_date_poll_options o
+		JOIN trip_date_polls p ON p.id = o.poll_id
+		WHERE p.trip_id = ANY($1))`},
+
+	{table: "media_usage", set: setMedia, where: "media_id = ANY($1)"},
+
+	{table: "suggestions", set: setTargets, where: "target_id = ANY($1)"},
+	{table: "suggestion_comments", set: setTargets, where: "suggestion_id IN (SELECT id FROM suggestions WHERE target_id = ANY($1))"},
+
+	{table: "collections", set: setCollections, where: "id = ANY($1)"},
+	{table: "collection_locations", set: setCollections, where: "collection_id = ANY($1)"},
+	{table: "collection_collaborators", set: setCollections, where: "collection_id = ANY($1)"},
+	{table: "user_friends", set: setUser, where: "user_id = ANY($1) OR friend_id = ANY($1)"},
+}
+
+// specFor returns the spec of a backed up table
+func specFor(table string) (tableSpec, bool) {
+	for _, spec := range tableSpecs {
+		if spec.table == table {
+			return spec, true
+		}
+	}
+	return tableSpec{}, false
+}
+
+// resolveSets finds the IDs of everything in the scope of a user or trip.
+// A user's backup holds their trips, including deleted ones, the places they
+// created, their media and their collections; a trip's backup holds the trip
+// and what it links to. Places on a trip's route are included either way, so
+// that the waypoints can be restored.
+func resolveSets(ctx context.Context, tx *sqlx.Tx, scope, subjectID string) (map[string][]string, error) {
+	sets := map[string][]string{}
+
+	collect := func(set, query string, args ...interface{}) error {
+		var ids []string
+		if err := tx.SelectContext(ctx, &ids, query, args...); err != nil {
+			return fmt.Errorf("failed to find %s to back up: %w", set, err)
+		}
+		sets[set] = ids
+		return nil
+	}
+
+	var err error
+	switch scope {
+	case ScopeUser:
+		sets[setUser] = []string{subjectID}
+		err = collect(setTrips, `SELECT id FROM trips WHERE owner_id = $1 ORDER BY id`, subjectID)
+		if err == nil {
+			err = collect(setPlaces, `
+				SELECT id FROM places WHERE created_by = $1
+				UNION
+				SELECT place_id FROM trip_waypoints WHERE trip_id = ANY($2)`,
+				subjectID, pq.Array(sets[setTrips]))
+		}
+		if err == nil {
+			err = collect(setCollections, `SELECT id FROM collections WHERE user_id = $1 ORDER BY id`, subjectID)
+		}
+	case ScopeTrip:
+		sets[setTrips] = []string{subjectID}
+		err = collect(setPlaces, `SELECT DISTINCT place_id FROM trip_waypoints WHERE trip_id = $1`, subjectID)
+	default:
+		return nil, ErrInvalidScope
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sets[setTargets] = append(append([]string{}, sets[setTrips]...), sets[setPlaces]...)
+
+	err = collect(setMedia, `
+		SELECT id FROM media WHERE uploaded_by = ANY($1)
+		UNION
+		SELECT media_id FROM media_usage WHERE entity_id = ANY($2)
+		UNION
+		SELECT media_id FROM place_media WHERE place_id = ANY($3)`,
+		pq.Array(sets[setUser]), pq.Array(sets[setTargets]), pq.Array(sets[setPlaces]))
+	if err != nil {
+		return nil, err
+	}
+
+	return sets, nil
+}
@@ -16,6 +16,11 @@ type Cache interface {
 	DeleteTrip(ctx context.Context, tripID string) error
 	InvalidateTripRelated(ctx context.Context, tripID string) error
 
+	// Trip list cache operations, keyed by user and list variant
+	GetTripList(ctx context.Context, userID, variant string) ([]byte, error)
+	SetTripList(ctx context.Context, userID, variant string, data []byte, ttl time.Duration) error
+	InvalidateUserTripLists(ctx context.Context, userID string) error
+
 	// Place cache operations
 	GetPlace(ctx context.Context, placeID string) ([]byte, error)
 	SetPlace(ctx context.Context, placeID string, data []byte, ttl time.Duration) error
@@ -33,6 +38,15 @@ type Cache interface {
 	GetUserPermissions(ctx context.Context, userID, tripID string) ([]byte, error)
 	SetUserPermissions(ctx context.Context, userID, tripID string, data []byte, ttl time.Duration) error
 	InvalidateUserPermissions(ctx context.Context, userID, tripID string) error
+
+	// Discover list cache operations, keyed by list, filters and page
+	GetDiscoverList(ctx context.Context, variant string) ([]byte, error)
+	SetDiscoverList(ctx context.Context, variant string, data []byte, ttl time.Duration) error
+	InvalidateDiscoverLists(ctx context.Context) error
+
+	// Warm-up marker, which a flush or its expiry removes
+	IsWarm(ctx context.Context) (bool, error)
+	MarkWarm(ctx context.Context, ttl time.Duration) error
 }
 
 type redisCache struct {
@@ -83,6 +97,36 @@ func (c *redisCache) InvalidateTripRelated(ctx context.Context, tripID string) e
 	return nil
 }
 
+// Trip list cache operations
+
+func (c *redisCache) GetTripList(ctx context.Context, userID, variant string) ([]byte, error) {
+	key := database.BuildTripListCacheKey(userID, variant)
+	val, err := c.client.Get(ctx, key)
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return []byte(val), err
+}
+
+func (c *redisCache) SetTripList(ctx context.Context, userID, variant string, data []byte, ttl time.Duration) error {
+	key := database.BuildTripListCacheKey(userID, variant)
+	if err := c.client.Set(ctx, key, data, ttl); err != nil {
+		return err
+	}
+
+	// Track the key so every variant can be dropped at once
+	return c.client.SAdd(ctx, database.BuildUserTripListsKey(userID), key)
+}
+
+func (c *redisCache) InvalidateUserTripLists(ctx context.Context, userID string) error {
+	indexKey := database.BuildUserTripListsKey(userID)
+	keys, err := c.client.SMembers(ctx, indexKey)
+	if err != nil {
+		return err
+	}
+	return c.client.Delete(ctx, append(keys, indexKey)...)
+}
+
 // Place cache operations
 
 func (c *redisCache) GetPlace(ctx context.Context, placeID string) ([]byte, error) {
@@ -165,6 +209,49 @@ func (c *redisCache) InvalidateUserPermissions(ctx context.Context, userID, trip
 	return c.client.Delete(ctx, key)
 }
 
+// Discover list cache operations
+
+func (c *redisCache) GetDiscoverList(ctx context.Context, variant string) ([]byte, error) {
+	key := database.BuildDiscoverListCacheKey(variant)
+	val, err := c.client.Get(ctx, key)
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return []byte(val), err
+}
+
+func (c *redisCache) SetDiscoverList(ctx context.Context, variant string, data []byte, ttl time.Duration) error {
+	key := database.BuildDiscoverListCacheKey(variant)
+	if err := c.client.Set(ctx, key, data, ttl); err != nil {
+		return err
+	}
+
+	// Track the key so every page can be dropped at once
+	return c.client.SAdd(ctx, database.BuildDiscoverListsKey(), key)
+}
+
+// InvalidateDiscoverLists drops every cached page, and with them the
+// warm-up marker so the first pages are warmed again
+func (c *redisCache) InvalidateDiscoverLists(ctx context.Context) error {
+	indexKey := database.BuildDiscoverListsKey()
+	keys, err := c.client.SMembers(ctx, indexKey)
+	if err != nil {
+		return err
+	}
+	return c.client.Delete(ctx, append(keys, indexKey, database.BuildCacheWarmKey())...)
+}
+
+// Warm-up marker
+
+func (c *redisCache) IsWarm(ctx context.Context) (bool, error) {
+	n, err := c.client.Exists(ctx, database.BuildCacheWarmKey())
+	return n > 0, err
+}
+
+func (c *redisCache) MarkWarm(ctx context.Context, ttl time.Duration) error {
+	return c.client.Set(ctx, database.BuildCacheWarmKey(), time.Now().Unix(), ttl)
+}
+
 // Helper function to marshal data for caching
 func MarshalForCache(v interface{}) ([]byte, error) {
 	return json.Marshal(v)
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+)
+
+// TripInvalidation is what a change to a trip makes stale
+type TripInvalidation struct {
+	TripID string
+	// UserIDs are the members whose permissions and trip lists include the
+	// trip, before and after the change
+	UserIDs []string
+}
+
+// Event describes the invalidation as an event for the bus
+func (i TripInvalidation) Event(actorID string) events.Event {
+	return events.New(events.TripInvalidated, "trip", i.TripID, actorID, map[string]interface{}{
+		"user_ids": i.UserIDs,
+	})
+}
+
+// TripInvalidationFromEvent reads an invalidation back from its event
+func TripInvalidationFromEvent(event events.Event) TripInvalidation {
+	invalidation := TripInvalidation{TripID: event.EntityID}
+
+	// Events from other instances arrive decoded from JSON
+	switch userIDs := event.Data["user_ids"].(type) {
+	case []string:
+		invalidation.UserIDs = userIDs
+	case []interface{}:
+		for _, userID := range userIDs {
+			if id, ok := userID.(string); ok {
+				invalidation.UserIDs = append(invalidation.UserIDs, id)
+			}
+		}
+	}
+	return invalidation
+}
+
+// Apply drops every cached entry the invalidation covers
+func (i TripInvalidation) Apply(ctx context.Context, c Cache) error {
+	if err := c.InvalidateTripRelated(ctx, i.TripID); err != nil {
+		return fmt.Errorf("failed to invalidate trip %s: %w", i.TripID, err)
+	}
+
+	for _, userID := range i.UserIDs {
+		if err := c.InvalidateUserPermissions(ctx, userID, i.TripID); err != nil {
+			return fmt.Errorf("failed to invalidate permissions of %s: %w", userID, err)
+		}
+		if err := c.InvalidateUserTripLists(ctx, userID); err != nil {
+			return fmt.Errorf("failed to invalidate trip lists of %s: %w", userID, err)
+		}
+	}
+	return nil
+}
+
+// HandleTripInvalidated applies invalidations published on the event bus.
+// Redis is shared by every instance, so it is subscribed once rather than
+// broadcast.
+func HandleTripInvalidated(c Cache) events.Handler {
+	return func(ctx context.Context, event events.Event) error {
+		return TripInvalidationFromEvent(event).Apply(ctx, c)
+	}
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingCache struct {
+	noOpCache
+	invalidated []string
+}
+
+func (r *recordingCache) InvalidateTripRelated(ctx context.Context, tripID string) error {
+	r.invalidated = append(r.invalidated, "trip:"+tripID)
+	return nil
+}
+
+func (r *recordingCache) InvalidateUserPermissions(ctx context.Context, userID, tripID string) error {
+	r.invalidated = append(r.invalidated, "permissions:"+userID)
+	return nil
+}
+
+func (r *recordingCache) InvalidateUserTripLists(ctx context.Context, userID string) error {
+	r.invalidated = append(r.invalidated, "lists:"+userID)
+	return nil
+}
+
+func TestTripInvalidation_RoundTrip(t *testing.T) {
+	invalidation := TripInvalidation{TripID: "trip-1", UserIDs: []string{"owner", "editor"}}
+	event := invalidation.Event("owner")
+	assert.Equal(t, events.TripInvalidated, event.Type)
+	assert.Equal(t, invalidation, TripInvalidationFromEvent(event))
+
+	// As received from another instance
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+	var decoded events.Event
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, invalidation, TripInvalidationFromEvent(decoded))
+}
+
+func TestHandleTripInvalidated(t *testing.T) {
+	cache := &recordingCache{}
+	event := TripInvalidation{TripID: "trip-1", UserIDs: []string{"owner", "editor"}}.Event("owner")
+
+	require.NoError(t, HandleTripInvalidated(cache)(context.Background(), event))
+	assert.Equal(t, []string{
+		"trip:trip-1",
+		"permissions:owner", "lists:owner",
+		"permissions:editor", "lists:editor",
+	}, cache.invalidated)
+}
@@ -29,6 +29,18 @@ func (n *noOpCache) InvalidateTripRelated(ctx context.Context, tripID string) er
 	return nil
 }
 
+func (n *noOpCache) GetTripList(ctx context.Context, userID, variant string) ([]byte, error) {
+	return nil, nil
+}
+
+func (n *noOpCache) SetTripList(ctx context.Context, userID, variant string, data []byte, ttl time.Duration) error {
+	return nil
+}
+
+func (n *noOpCache) InvalidateUserTripLists(ctx context.Context, userID string) error {
+	return nil
+}
+
 func (n *noOpCache) GetPlace(ctx context.Context, placeID string) ([]byte, error) {
 	return nil, nil
 }
@@ -75,4 +87,25 @@ func (n *noOpCache) SetUserPermissions(ctx context.Context, userID, tripID strin
 
 func (n *noOpCache) InvalidateUserPermissions(ctx context.Context, userID, tripID string) error {
 	return nil
-}
\ No newline at end of file
+}
+
+func (n *noOpCache) GetDiscoverList(ctx context.Context, variant string) ([]byte, error) {
+	return nil, nil
+}
+
+func (n *noOpCache) SetDiscoverList(ctx context.Context, variant string, data []byte, ttl time.Duration) error {
+	return nil
+}
+
+func (n *noOpCache) InvalidateDiscoverLists(ctx context.Context) error {
+	return nil
+}
+
+// IsWarm reports a cache that holds nothing as warm, so it is never warmed
+func (n *noOpCache) IsWarm(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+func (n *noOpCache) MarkWarm(ctx context.Context, ttl time.Duration) error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/Oferzz/newMap/apps/api/pkg/httpcache"
+)
+
+// purgingCache also purges CDN cached responses whenever a trip or place is
+// invalidated, so every invalidation path reaches the edge as well
+type purgingCache struct {
+	Cache
+	purger httpcache.Purger
+}
+
+// NewPurgingCache wraps a cache so invalidations are forwarded to a CDN
+func NewPurgingCache(cache Cache, purger httpcache.Purger) Cache {
+	return &purgingCache{
+		Cache:  cache,
+		purger: purger,
+	}
+}
+
+func (c *purgingCache) DeleteTrip(ctx context.Context, tripID string) error {
+	httpcache.PurgeAsync(c.purger, httpcache.TripKey(tripID), httpcache.TripListKey)
+	return c.Cache.DeleteTrip(ctx, tripID)
+}
+
+func (c *purgingCache) InvalidateTripRelated(ctx context.Context, tripID string) error {
+	httpcache.PurgeAsync(c.purger, httpcache.TripKey(tripID), httpcache.TripListKey)
+	return c.Cache.InvalidateTripRelated(ctx, tripID)
+}
+
+func (c *purgingCache) DeletePlace(ctx context.Context, placeID string) error {
+	httpcache.PurgeAsync(c.purger, httpcache.PlaceKey(placeID))
+	return c.Cache.DeletePlace(ctx, placeID)
+}
@@ -0,0 +1,56 @@
+package campsites
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/database"
+	"github.com/redis/go-redis/v9"
+)
+
+// CachedProvider remembers availability in Redis, so that a campground many
+// planners are looking at is only checked with its provider once in a while
+type CachedProvider struct {
+	name  string
+	next  Provider
+	redis *database.RedisClient
+	ttl   time.Duration
+}
+
+// NewCachedProvider caches the availability next returns for ttl, keyed
+// under the provider's name
+func NewCachedProvider(name string, next Provider, redisClient *database.RedisClient, ttl time.Duration) *CachedProvider {
+	return &CachedProvider{
+		name:  name,
+		next:  next,
+		redis: redisClient,
+		ttl:   ttl,
+	}
+}
+
+// Availability returns the cached availability, checking with the provider
+// on a miss. Cache failures fall through to the provider.
+func (p *CachedProvider) Availability(ctx context.Context, facilityID string, start, end time.Time) (*Availability, error) {
+	key := database.BuildCampsiteAvailabilityCacheKey(p.name, facilityID, start.Format(DateLayout), end.Format(DateLayout))
+
+	var cached Availability
+	err := p.redis.GetJSON(ctx, key, &cached)
+	switch {
+	case err == nil:
+		return &cached, nil
+	case !errors.Is(err, redis.Nil):
+		log.Printf("campsites: failed to read cached availability of %s: %v", facilityID, err)
+	}
+
+	availability, err := p.next.Availability(ctx, facilityID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.redis.SetJSON(ctx, key, availability, p.ttl); err != nil {
+		log.Printf("campsites: failed to cache availability of %s: %v", facilityID, err)
+	}
+	return availability, nil
+}
@@ -0,0 +1,86 @@
+// Package campsites checks campground availability with the reservation
+// systems campgrounds are booked through
+package campsites
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Providers campgrounds can be linked to
+const (
+	ProviderRecreationGov     = "recreation_gov"
+	ProviderReserveCalifornia = "reserve_california"
+)
+
+// MaxNights is the longest stay one availability check covers
+const MaxNights = 31
+
+// DateLayout is how nights are written
+const DateLayout = "2006-01-02"
+
+var (
+	ErrFacilityNotFound = errors.New("campground not found")
+	ErrUnknownProvider  = errors.New("unknown campground provider")
+)
+
+// Night is how many of a campground's sites are free on one night
+type Night struct {
+	Date      string `json:"date"`
+	Available int    `json:"available"`
+	Total     int    `json:"total"`
+}
+
+// Availability is a campground's free sites over a stay
+type Availability struct {
+	Provider   string    `json:"provider"`
+	FacilityID string    `json:"facility_id"`
+	Nights     []Night   `json:"nights"`
+	BookingURL string    `json:"booking_url"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// Provider checks availability with one reservation system. The stay runs
+// from the night of start to the night before end. It returns
+// ErrFacilityNotFound when the system has no such campground.
+type Provider interface {
+	Availability(ctx context.Context, facilityID string, start, end time.Time) (*Availability, error)
+}
+
+// Checker sends availability checks to the provider a campground is booked
+// through
+type Checker struct {
+	providers map[string]Provider
+}
+
+// NewChecker creates a checker with no providers
+func NewChecker() *Checker {
+	return &Checker{
+		providers: make(map[string]Provider),
+	}
+}
+
+// Register adds the provider campgrounds linked to name are checked with
+func (c *Checker) Register(name string, provider Provider) {
+	c.providers[name] = provider
+}
+
+// Availability checks a campground with its provider
+func (c *Checker) Availability(ctx context.Context, provider, facilityID string, start, end time.Time) (*Availability, error) {
+	p, ok := c.providers[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	return p.Availability(ctx, facilityID, start, end)
+}
+
+// nights lists the dates of the nights from start up to end
+func nights(start, end time.Time) []string {
+	dates := []string{}
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		dates = append(dates, day.Format(DateLayout))
+	}
+	return dates
+}
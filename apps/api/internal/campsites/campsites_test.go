@@ -0,0 +1,55 @@
+package campsites
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProvider returns the same availability for any campground it knows
+type stubProvider struct {
+	facilities map[string]bool
+	checked    []string
+}
+
+func (p *stubProvider) Availability(ctx context.Context, facilityID string, start, end time.Time) (*Availability, error) {
+	p.checked = append(p.checked, facilityID)
+	if !p.facilities[facilityID] {
+		return nil, ErrFacilityNotFound
+	}
+	return &Availability{FacilityID: facilityID, Nights: []Night{{Date: start.Format(DateLayout), Available: 1, Total: 1}}}, nil
+}
+
+func TestChecker_Availability(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2025, time.July, 4, 0, 0, 0, 0, time.UTC)
+	recreation := &stubProvider{facilities: map[string]bool{"232447": true}}
+	california := &stubProvider{facilities: map[string]bool{"766": true}}
+
+	checker := NewChecker()
+	checker.Register(ProviderRecreationGov, recreation)
+	checker.Register(ProviderReserveCalifornia, california)
+
+	availability, err := checker.Availability(ctx, ProviderReserveCalifornia, "766", start, start.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, "766", availability.FacilityID)
+	assert.Equal(t, []string{"766"}, california.checked)
+	assert.Empty(t, recreation.checked)
+
+	_, err = checker.Availability(ctx, ProviderRecreationGov, "766", start, start.AddDate(0, 0, 1))
+	assert.ErrorIs(t, err, ErrFacilityNotFound)
+
+	_, err = checker.Availability(ctx, "hipcamp", "766", start, start.AddDate(0, 0, 1))
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+}
+
+func TestNights(t *testing.T) {
+	start := time.Date(2025, time.June, 29, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, []string{"2025-06-29", "2025-06-30", "2025-07-01"}, nights(start, start.AddDate(0, 0, 3)))
+	assert.Empty(t, nights(start, start))
+	assert.Empty(t, nights(start, start.AddDate(0, 0, -1)))
+}
@@ -0,0 +1,114 @@
+package campsites
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	recreationGovAPI     = "https://www.recreation.gov/api/camps/availability/campground"
+	recreationGovBooking = "https://www.recreation.gov/camping/campgrounds/"
+)
+
+// RecreationGov checks federal campgrounds on Recreation.gov. Availability is
+// published a calendar month at a time.
+type RecreationGov struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewRecreationGov creates a Recreation.gov provider
+func NewRecreationGov() *RecreationGov {
+	return &RecreationGov{
+		baseURL: recreationGovAPI,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+type recreationGovMonth struct {
+	Campsites map[string]struct {
+		// Keyed by night, as 2006-01-02T00:00:00Z
+		Availabilities map[string]string `json:"availabilities"`
+	} `json:"campsites"`
+}
+
+// Availability counts the campground's sites that are bookable each night
+func (p *RecreationGov) Availability(ctx context.Context, facilityID string, start, end time.Time) (*Availability, error) {
+	free := make(map[string]int)
+	total := make(map[string]int)
+
+	month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for month.Before(end) {
+		body, err := p.month(ctx, facilityID, month)
+		if err != nil {
+			return nil, err
+		}
+		for _, site := range body.Campsites {
+			for night, status := range site.Availabilities {
+				date := night
+				if len(date) > len(DateLayout) {
+					date = date[:len(DateLayout)]
+				}
+				total[date]++
+				if status == "Available" {
+					free[date]++
+				}
+			}
+		}
+		month = month.AddDate(0, 1, 0)
+	}
+
+	availability := &Availability{
+		Provider:   ProviderRecreationGov,
+		FacilityID: facilityID,
+		Nights:     []Night{},
+		BookingURL: recreationGovBooking + url.PathEscape(facilityID),
+		CheckedAt:  time.Now().UTC(),
+	}
+	for _, date := range nights(start, end) {
+		availability.Nights = append(availability.Nights, Night{
+			Date:      date,
+			Available: free[date],
+			Total:     total[date],
+		})
+	}
+
+	return availability, nil
+}
+
+func (p *RecreationGov) month(ctx context.Context, facilityID string, month time.Time) (*recreationGovMonth, error) {
+	params := url.Values{}
+	params.Set("start_date", month.Format("2006-01-02T15:04:05.000Z"))
+	endpoint := fmt.Sprintf("%s/%s/month?%s", p.baseURL, url.PathEscape(facilityID), params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check availability of %s: %w", facilityID, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrFacilityNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("recreation.gov returned status %d", resp.StatusCode)
+	}
+
+	var body recreationGovMonth
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &body, nil
+}
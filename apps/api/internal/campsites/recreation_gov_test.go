@@ -0,0 +1,74 @@
+package campsites
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRecreationGov(t *testing.T, handler http.HandlerFunc) *RecreationGov {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	provider := NewRecreationGov()
+	provider.baseURL = server.URL
+	return provider
+}
+
+func TestRecreationGov_Availability(t *testing.T) {
+	months := []string{}
+	provider := testRecreationGov(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/232447/month", r.URL.Path)
+		months = append(months, r.URL.Query().Get("start_date"))
+		if r.URL.Query().Get("start_date") == "2025-07-01T00:00:00.000Z" {
+			w.Write([]byte(`{"campsites": {
+				"1": {"availabilities": {"2025-07-30T00:00:00Z": "Available", "2025-07-31T00:00:00Z": "Reserved"}},
+				"2": {"availabilities": {"2025-07-30T00:00:00Z": "Reserved", "2025-07-31T00:00:00Z": "Available"}}
+			}}`))
+			return
+		}
+		w.Write([]byte(`{"campsites": {
+			"1": {"availabilities": {"2025-08-01T00:00:00Z": "Available"}},
+			"2": {"availabilities": {"2025-08-01T00:00:00Z": "Available"}}
+		}}`))
+	})
+
+	start := time.Date(2025, time.July, 30, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, time.August, 2, 0, 0, 0, 0, time.UTC)
+	availability, err := provider.Availability(context.Background(), "232447", start, end)
+	require.NoError(t, err)
+
+	// A stay across the end of the month needs both months
+	assert.Equal(t, []string{"2025-07-01T00:00:00.000Z", "2025-08-01T00:00:00.000Z"}, months)
+	assert.Equal(t, []Night{
+		{Date: "2025-07-30", Available: 1, Total: 2},
+		{Date: "2025-07-31", Available: 1, Total: 2},
+		{Date: "2025-08-01", Available: 2, Total: 2},
+	}, availability.Nights)
+	assert.Equal(t, ProviderRecreationGov, availability.Provider)
+	assert.Equal(t, "https://www.recreation.gov/camping/campgrounds/232447", availability.BookingURL)
+}
+
+func TestRecreationGov_NotFound(t *testing.T) {
+	provider := testRecreationGov(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	start := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	_, err := provider.Availability(context.Background(), "0", start, start.AddDate(0, 0, 1))
+	assert.ErrorIs(t, err, ErrFacilityNotFound)
+}
+
+func TestChecker_UnknownProvider(t *testing.T) {
+	checker := NewChecker()
+	checker.Register(ProviderRecreationGov, NewRecreationGov())
+
+	start := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	_, err := checker.Availability(context.Background(), "parks_canada", "1", start, start.AddDate(0, 0, 1))
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+}
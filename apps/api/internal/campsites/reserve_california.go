@@ -0,0 +1,123 @@
+package campsites
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	reserveCaliforniaAPI     = "https://calirdr.usedirect.com/rdr/rdr/search/grid"
+	reserveCaliforniaBooking = "https://www.reservecalifornia.com/"
+
+	// reserveCaliforniaDate is how the grid search takes dates
+	reserveCaliforniaDate = "01-02-2006"
+)
+
+// ReserveCalifornia checks California state park campgrounds on
+// ReserveCalifornia, whose grid search covers a whole stay at once
+type ReserveCalifornia struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewReserveCalifornia creates a ReserveCalifornia provider
+func NewReserveCalifornia() *ReserveCalifornia {
+	return &ReserveCalifornia{
+		baseURL: reserveCaliforniaAPI,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+type reserveCaliforniaRequest struct {
+	FacilityID   string `json:"FacilityId"`
+	StartDate    string `json:"StartDate"`
+	EndDate      string `json:"EndDate"`
+	InSeasonOnly bool   `json:"InSeasonOnly"`
+	WebOnly      bool   `json:"WebOnly"`
+}
+
+type reserveCaliforniaResponse struct {
+	Facility *struct {
+		Units map[string]struct {
+			// Keyed by night, as 2006-01-02T00:00:00
+			Slices map[string]struct {
+				Date   string `json:"Date"`
+				IsFree bool   `json:"IsFree"`
+			} `json:"Slices"`
+		} `json:"Units"`
+	} `json:"Facility"`
+}
+
+// Availability counts the campground's units that are free each night. The
+// last night of the stay is the night before end.
+func (p *ReserveCalifornia) Availability(ctx context.Context, facilityID string, start, end time.Time) (*Availability, error) {
+	payload, err := json.Marshal(reserveCaliforniaRequest{
+		FacilityID:   facilityID,
+		StartDate:    start.Format(reserveCaliforniaDate),
+		EndDate:      end.AddDate(0, 0, -1).Format(reserveCaliforniaDate),
+		InSeasonOnly: true,
+		WebOnly:      true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check availability of %s: %w", facilityID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("reservecalifornia returned status %d", resp.StatusCode)
+	}
+
+	var body reserveCaliforniaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	// Unknown facilities come back without one
+	if body.Facility == nil || body.Facility.Units == nil {
+		return nil, ErrFacilityNotFound
+	}
+
+	free := make(map[string]int)
+	total := make(map[string]int)
+	for _, unit := range body.Facility.Units {
+		for _, slice := range unit.Slices {
+			total[slice.Date]++
+			if slice.IsFree {
+				free[slice.Date]++
+			}
+		}
+	}
+
+	availability := &Availability{
+		Provider:   ProviderReserveCalifornia,
+		FacilityID: facilityID,
+		Nights:     []Night{},
+		BookingURL: reserveCaliforniaBooking,
+		CheckedAt:  time.Now().UTC(),
+	}
+	for _, date := range nights(start, end) {
+		availability.Nights = append(availability.Nights, Night{
+			Date:      date,
+			Available: free[date],
+			Total:     total[date],
+		})
+	}
+
+	return availability, nil
+}
@@ -0,0 +1,62 @@
+package campsites
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testReserveCalifornia(t *testing.T, handler http.HandlerFunc) *ReserveCalifornia {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	provider := NewReserveCalifornia()
+	provider.baseURL = server.URL
+	return provider
+}
+
+func TestReserveCalifornia_Availability(t *testing.T) {
+	provider := testReserveCalifornia(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		var request reserveCaliforniaRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "766", request.FacilityID)
+		assert.Equal(t, "07-04-2025", request.StartDate)
+		assert.Equal(t, "07-05-2025", request.EndDate)
+
+		w.Write([]byte(`{"Facility": {"Units": {
+			"10": {"Slices": {
+				"2025-07-04T00:00:00": {"Date": "2025-07-04", "IsFree": false},
+				"2025-07-05T00:00:00": {"Date": "2025-07-05", "IsFree": true}
+			}},
+			"11": {"Slices": {
+				"2025-07-04T00:00:00": {"Date": "2025-07-04", "IsFree": false},
+				"2025-07-05T00:00:00": {"Date": "2025-07-05", "IsFree": true}
+			}}
+		}}}`))
+	})
+
+	start := time.Date(2025, time.July, 4, 0, 0, 0, 0, time.UTC)
+	availability, err := provider.Availability(context.Background(), "766", start, start.AddDate(0, 0, 2))
+	require.NoError(t, err)
+	assert.Equal(t, []Night{
+		{Date: "2025-07-04", Available: 0, Total: 2},
+		{Date: "2025-07-05", Available: 2, Total: 2},
+	}, availability.Nights)
+}
+
+func TestReserveCalifornia_NotFound(t *testing.T) {
+	provider := testReserveCalifornia(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Facility": null}`))
+	})
+
+	start := time.Date(2025, time.July, 4, 0, 0, 0, 0, time.UTC)
+	_, err := provider.Availability(context.Background(), "0", start, start.AddDate(0, 0, 1))
+	assert.ErrorIs(t, err, ErrFacilityNotFound)
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"net/mail"
 	"os"
 	"strconv"
 	"strings"
@@ -11,13 +12,21 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	App      AppConfig
-	Media    MediaConfig
-	Supabase SupabaseConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	Redis       RedisConfig
+	JWT         JWTConfig
+	App         AppConfig
+	Media       MediaConfig
+	Supabase    SupabaseConfig
+	Jobs        JobsConfig
+	Diagnostics DiagnosticsConfig
+	HTTPCache   HTTPCacheConfig
+	API         APIConfig
+	Email       EmailConfig
+	Geocode     GeocodeConfig
+	Backup      BackupConfig
+	Analytics   AnalyticsConfig
 }
 
 type ServerConfig struct {
@@ -25,6 +34,29 @@ type ServerConfig struct {
 	Environment  string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	Compression  CompressionConfig
+	Security     SecurityConfig
+	BodyLimits   BodyLimitsConfig
+}
+
+// BodyLimitsConfig bounds request bodies by the kind of route they are sent
+// to, in bytes
+type BodyLimitsConfig struct {
+	JSON    int64 // Most routes, which take small JSON documents
+	GeoJSON int64 // Routes that take trip routes and tracks
+	Upload  int64 // File uploads, further checked by kind as they stream in
+}
+
+type SecurityConfig struct {
+	HSTSMaxAge      time.Duration // Strict-Transport-Security is only sent when set
+	PublicCORSPaths []string      // Path prefixes any origin may read, such as embeds and tiles
+}
+
+type CompressionConfig struct {
+	Enabled      bool
+	Level        int      // gzip level, 1 (fastest) to 9 (smallest)
+	MinSize      int      // Responses smaller than this are sent as is
+	ContentTypes []string // Media types to compress; a trailing "/" matches the whole type
 }
 
 type DatabaseConfig struct {
@@ -49,6 +81,7 @@ type JWTConfig struct {
 	Secret           string
 	AccessExpiry     time.Duration
 	RefreshExpiry    time.Duration
+	ShareExpiry      time.Duration // lifetime of tokens minted from share links
 	Issuer           string
 }
 
@@ -59,15 +92,104 @@ type AppConfig struct {
 	MaxUploadSize   int64
 	RateLimitPerMin int
 	MapboxAPIKey    string
+	WebURL          string // Public URL of the web frontend
 	MongoDBURI      string // For backward compatibility if needed
 }
 
 type MediaConfig struct {
 	StoragePath      string
 	CDNURL           string
+	CDNBaseURL       string // CDN host files are served from in production; from CDNURL when empty
 	MaxFileSize      int64
 	AllowedMimeTypes []string
 	ThumbnailQuality int
+
+	URLSecret         string        // Key for signed media URLs; unsigned when empty
+	URLExpiry         time.Duration // Lifetime of signed media URLs
+	RequireSignedURLs bool          // Refuse to serve files without a valid signature
+}
+
+type JobsConfig struct {
+	Workers int
+}
+
+type DiagnosticsConfig struct {
+	SlowQueryThreshold time.Duration
+	ExplainSlowQueries bool // Capture EXPLAIN (ANALYZE, BUFFERS) for slow queries
+}
+
+type HTTPCacheConfig struct {
+	FastlyServiceID string // CDN purging is disabled unless both are set
+	FastlyAPIKey    string
+}
+
+type APIConfig struct {
+	V1Sunset time.Time // When v1 stops being served; no Sunset header when unset
+}
+
+type EmailConfig struct {
+	SMTPHost     string // Emails are logged instead of sent unless set
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	FromEmail    string
+	FromName     string
+}
+
+type GeocodeConfig struct {
+	Providers []string // Tried in order, failing over on errors: "mapbox", "nominatim", "pelias"
+
+	// Requests a minute each provider is sent at most; unlimited when 0
+	MapboxRatePerMin    int
+	NominatimRatePerMin int
+	PeliasRatePerMin    int
+
+	MapboxAPIKey       string
+	NominatimURL       string // The public instance when empty
+	NominatimUserAgent string
+	NominatimEmail     string // Contact address the public instance asks for
+	PeliasURL          string // Pelias is skipped unless set
+	PeliasAPIKey       string
+}
+
+// BackupConfig says where user content backups are written. Backups hold
+// password hashes and private content, so the bucket must not be public.
+type BackupConfig struct {
+	Storage string // "disk" or "s3"
+	Path    string // Directory backups are written to with disk storage
+
+	// Any S3-compatible object store
+	S3Endpoint        string // https://s3.<region>.amazonaws.com when empty
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+}
+
+// AnalyticsConfig says where anonymized events are exported for offline
+// analytics. Nothing is exported unless a sink is set.
+type AnalyticsConfig struct {
+	Sink          string        // "", "s3" or "kafka"
+	PseudonymKey  string        // Keys the hashes user IDs are replaced with; random per process when empty
+	BatchSize     int           // Events sent to the sink at once
+	FlushInterval time.Duration // Longest events wait before they are sent
+
+	// NDJSON files in any S3-compatible object store
+	S3Endpoint        string // https://s3.<region>.amazonaws.com when empty
+	S3Region          string
+	S3Bucket          string
+	S3Prefix          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+
+	// Records produced through a Kafka REST Proxy
+	KafkaRESTURL string
+	KafkaTopic   string
+}
+
+// From is the sender emails show, with its name
+func (c EmailConfig) From() string {
+	return (&mail.Address{Name: c.FromName, Address: c.FromEmail}).String()
 }
 
 type SupabaseConfig struct {
@@ -98,6 +220,38 @@ func Load() (*Config, error) {
 			Environment:  getEnv("ENVIRONMENT", "development"),
 			ReadTimeout:  getDurationEnv("SERVER_READ_TIMEOUT", 15*time.Second),
 			WriteTimeout: getDurationEnv("SERVER_WRITE_TIMEOUT", 15*time.Second),
+			Compression: CompressionConfig{
+				Enabled: getBoolEnv("COMPRESSION_ENABLED", true),
+				Level:   getIntEnv("COMPRESSION_LEVEL", 5),
+				MinSize: getIntEnv("COMPRESSION_MIN_SIZE", 1024),
+				ContentTypes: getListEnv("COMPRESSION_TYPES", []string{
+					"application/json",
+					"application/geo+json",
+					"application/gpx+xml",
+					"application/xml",
+					"application/javascript",
+					"image/svg+xml",
+					"text/",
+				}),
+			},
+			BodyLimits: BodyLimitsConfig{
+				JSON:    getInt64Env("BODY_LIMIT_JSON", 1024*1024),       // 1MB
+				GeoJSON: getInt64Env("BODY_LIMIT_GEOJSON", 10*1024*1024), // 10MB
+				Upload:  getInt64Env("BODY_LIMIT_UPLOAD", 100*1024*1024), // 100MB
+			},
+			Security: SecurityConfig{
+				HSTSMaxAge: getDurationEnv("HSTS_MAX_AGE", defaultHSTSMaxAge()),
+				PublicCORSPaths: getListEnv("PUBLIC_CORS_PATHS", []string{
+					"/health",
+					"/ready",
+					"/api/health",
+					"/media",
+					"/api/v1/og",
+					"/api/v1/discover",
+					"/api/v2/og",
+					"/api/v2/discover",
+				}),
+			},
 		},
 		Database: DatabaseConfig{
 			URI:            getEnv("DATABASE_URL", "postgresql://localhost:5432/trip_platform?sslmode=disable"),
@@ -119,6 +273,7 @@ func Load() (*Config, error) {
 			Secret:        getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
 			AccessExpiry:  getDurationEnv("JWT_ACCESS_EXPIRY", 15*time.Minute),
 			RefreshExpiry: getDurationEnv("JWT_REFRESH_EXPIRY", 7*24*time.Hour),
+			ShareExpiry:   getDurationEnv("JWT_SHARE_EXPIRY", time.Hour),
 			Issuer:        getEnv("JWT_ISSUER", "trip-platform"),
 		},
 		App: AppConfig{
@@ -128,14 +283,77 @@ func Load() (*Config, error) {
 			MaxUploadSize:   getInt64Env("MAX_UPLOAD_SIZE", 10*1024*1024), // 10MB
 			RateLimitPerMin: getIntEnv("RATE_LIMIT_PER_MIN", 60),
 			MapboxAPIKey:    getEnv("MAPBOX_ACCESS_TOKEN", getEnv("MAPBOX_API_KEY", "")), // Support both naming conventions
+			WebURL:          getEnv("WEB_URL", "http://localhost:3000"),
 			MongoDBURI:      getEnv("MONGODB_URI", ""), // For backward compatibility
 		},
 		Media: MediaConfig{
 			StoragePath:      getEnv("MEDIA_PATH", "/data/media"),
 			CDNURL:           getEnv("CDN_URL", "http://localhost:8080/media"),
+			CDNBaseURL:       getEnv("MEDIA_CDN_BASE_URL", ""),
 			MaxFileSize:      getInt64Env("MAX_FILE_SIZE", 50*1024*1024), // 50MB
 			AllowedMimeTypes: []string{"image/jpeg", "image/png", "image/webp", "video/mp4"},
 			ThumbnailQuality: getIntEnv("THUMBNAIL_QUALITY", 85),
+
+			URLSecret:         getEnv("MEDIA_URL_SECRET", ""),
+			URLExpiry:         getDurationEnv("MEDIA_URL_EXPIRY", 15*time.Minute),
+			RequireSignedURLs: getBoolEnv("MEDIA_REQUIRE_SIGNED_URLS", false),
+		},
+		Jobs: JobsConfig{
+			Workers: getIntEnv("JOB_WORKERS", 4),
+		},
+		Diagnostics: DiagnosticsConfig{
+			SlowQueryThreshold: getDurationEnv("SLOW_QUERY_THRESHOLD", 500*time.Millisecond),
+			ExplainSlowQueries: getBoolEnv("EXPLAIN_SLOW_QUERIES", false),
+		},
+		HTTPCache: HTTPCacheConfig{
+			FastlyServiceID: getEnv("FASTLY_SERVICE_ID", ""),
+			FastlyAPIKey:    getEnv("FASTLY_API_KEY", ""),
+		},
+		API: APIConfig{
+			V1Sunset: getDateEnv("API_V1_SUNSET"),
+		},
+		Email: EmailConfig{
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getIntEnv("SMTP_PORT", 587),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			FromEmail:    getEnv("SMTP_FROM_EMAIL", "noreply@localhost"),
+			FromName:     getEnv("SMTP_FROM_NAME", "Trip Platform"),
+		},
+		Geocode: GeocodeConfig{
+			Providers:           getListEnv("GEOCODE_PROVIDERS", []string{"mapbox"}),
+			MapboxRatePerMin:    getIntEnv("GEOCODE_MAPBOX_RATE_PER_MIN", 600),
+			NominatimRatePerMin: getIntEnv("GEOCODE_NOMINATIM_RATE_PER_MIN", 60),
+			PeliasRatePerMin:    getIntEnv("GEOCODE_PELIAS_RATE_PER_MIN", 0),
+			MapboxAPIKey:        getEnv("MAPBOX_ACCESS_TOKEN", getEnv("MAPBOX_API_KEY", "")),
+			NominatimURL:        getEnv("NOMINATIM_URL", ""),
+			NominatimUserAgent:  getEnv("NOMINATIM_USER_AGENT", "newMap-api"),
+			NominatimEmail:      getEnv("NOMINATIM_EMAIL", ""),
+			PeliasURL:           getEnv("PELIAS_URL", ""),
+			PeliasAPIKey:        getEnv("PELIAS_API_KEY", ""),
+		},
+		Backup: BackupConfig{
+			Storage:           getEnv("BACKUP_STORAGE", "disk"),
+			Path:              getEnv("BACKUP_PATH", "/data/backups"),
+			S3Endpoint:        getEnv("BACKUP_S3_ENDPOINT", ""),
+			S3Region:          getEnv("BACKUP_S3_REGION", "us-east-1"),
+			S3Bucket:          getEnv("BACKUP_S3_BUCKET", ""),
+			S3AccessKeyID:     getEnv("BACKUP_S3_ACCESS_KEY_ID", ""),
+			S3SecretAccessKey: getEnv("BACKUP_S3_SECRET_ACCESS_KEY", ""),
+		},
+		Analytics: AnalyticsConfig{
+			Sink:              getEnv("ANALYTICS_SINK", ""),
+			PseudonymKey:      getEnv("ANALYTICS_PSEUDONYM_KEY", ""),
+			BatchSize:         getIntEnv("ANALYTICS_BATCH_SIZE", 500),
+			FlushInterval:     getDurationEnv("ANALYTICS_FLUSH_INTERVAL", time.Minute),
+			S3Endpoint:        getEnv("ANALYTICS_S3_ENDPOINT", ""),
+			S3Region:          getEnv("ANALYTICS_S3_REGION", "us-east-1"),
+			S3Bucket:          getEnv("ANALYTICS_S3_BUCKET", ""),
+			S3Prefix:          getEnv("ANALYTICS_S3_PREFIX", "events"),
+			S3AccessKeyID:     getEnv("ANALYTICS_S3_ACCESS_KEY_ID", ""),
+			S3SecretAccessKey: getEnv("ANALYTICS_S3_SECRET_ACCESS_KEY", ""),
+			KafkaRESTURL:      getEnv("ANALYTICS_KAFKA_REST_URL", ""),
+			KafkaTopic:        getEnv("ANALYTICS_KAFKA_TOPIC", "analytics-events"),
 		},
 		Supabase: SupabaseConfig{
 			URL:        getEnv("SUPABASE_PROJECT_URL", ""),
@@ -181,6 +399,15 @@ func getUint64Env(key string, defaultValue uint64) uint64 {
 	return defaultValue
 }
 
+func getBoolEnv(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
 func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
@@ -190,6 +417,38 @@ func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	return defaultValue
 }
 
+// getDateEnv reads a date such as 2027-04-01, the zero time when unset
+func getDateEnv(key string) time.Time {
+	if value := os.Getenv(key); value != "" {
+		if date, err := time.Parse("2006-01-02", value); err == nil {
+			return date
+		}
+	}
+	return time.Time{}
+}
+
+func getListEnv(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		values := make([]string, 0)
+		for _, item := range strings.Split(value, ",") {
+			if trimmed := strings.TrimSpace(item); trimmed != "" {
+				values = append(values, trimmed)
+			}
+		}
+		return values
+	}
+	return defaultValue
+}
+
+// defaultHSTSMaxAge enables HSTS in production only, where the API is always
+// behind TLS
+func defaultHSTSMaxAge() time.Duration {
+	if getEnv("ENVIRONMENT", "development") == "production" {
+		return 365 * 24 * time.Hour
+	}
+	return 0
+}
+
 func getAllowedOrigins() []string {
 	// Check for environment variable first
 	if originsEnv := os.Getenv("ALLOWED_ORIGINS"); originsEnv != "" {
@@ -0,0 +1,92 @@
+package curation
+
+import (
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/internal/middleware"
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Handler serves the search pin admin endpoints
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new curation handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// CreatePin pins a trip or place to the top of matching searches
+func (h *Handler) CreatePin(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input CreatePinInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	pin, err := h.service.Create(c.Request.Context(), userID, &input)
+	if err != nil {
+		switch err {
+		case ErrInvalidRegion, ErrNoTarget, ErrInvalidWindow:
+			response.BadRequest(c, err.Error())
+		default:
+			response.InternalServerError(c, "Failed to create pin")
+		}
+		return
+	}
+
+	response.Created(c, pin)
+}
+
+// ListPins returns every search pin
+func (h *Handler) ListPins(c *gin.Context) {
+	pins, err := h.service.List(c.Request.Context())
+	if err != nil {
+		response.InternalServerError(c, "Failed to list pins")
+		return
+	}
+
+	response.Success(c, pins)
+}
+
+// DeletePin removes a search pin
+func (h *Handler) DeletePin(c *gin.Context) {
+	if _, err := uuid.Parse(c.Param("id")); err != nil {
+		response.BadRequest(c, "Invalid pin ID")
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		switch err {
+		case ErrPinNotFound:
+			response.NotFound(c, "Pin not found")
+		default:
+			response.InternalServerError(c, "Failed to delete pin")
+		}
+		return
+	}
+
+	response.NoContent(c)
+}
+
+// RegisterRoutes registers the search pin endpoints for admins
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *users.RouteMiddleware) {
+	pins := router.Group("/admin/search/pins", mw.RequireAdmin...)
+	{
+		pins.GET("", h.ListPins)
+		pins.POST("", h.CreatePin)
+		pins.DELETE("/:id", h.DeletePin)
+	}
+}
@@ -0,0 +1,213 @@
+package curation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/geocode"
+	"github.com/jmoiron/sqlx"
+)
+
+// maxPins bounds how many pins one search shows above its results
+const maxPins = 5
+
+// Pin labels, shown with the pinned result
+const (
+	LabelCurated   = "curated"
+	LabelSponsored = "sponsored"
+)
+
+var (
+	ErrPinNotFound   = errors.New("pin not found")
+	ErrInvalidRegion = errors.New("region must be [west, south, east, north]")
+	ErrNoTarget      = errors.New("pin needs a query or a region")
+	ErrInvalidWindow = errors.New("pin must end after it starts")
+)
+
+// Pin places a trip or place at the top of the results of matching searches
+type Pin struct {
+	ID         string     `db:"id" json:"id"`
+	EntityType string     `db:"entity_type" json:"entity_type"` // "trip" or "place"
+	EntityID   string     `db:"entity_id" json:"entity_id"`
+	Query      *string    `db:"query" json:"query,omitempty"`
+	Region     []float64  `db:"-" json:"region,omitempty"` // [west, south, east, north]
+	Label      string     `db:"label" json:"label"`
+	Position   int        `db:"position" json:"position"`
+	StartsAt   *time.Time `db:"starts_at" json:"starts_at,omitempty"`
+	EndsAt     *time.Time `db:"ends_at" json:"ends_at,omitempty"`
+	CreatedBy  string     `db:"created_by" json:"created_by"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+}
+
+// CreatePinInput is an admin's request to pin a result
+type CreatePinInput struct {
+	EntityType string     `json:"entity_type" binding:"required,oneof=trip place"`
+	EntityID   string     `json:"entity_id" binding:"required,uuid"`
+	Query      string     `json:"query" binding:"max=500"`
+	Region     []float64  `json:"region"`
+	Label      string     `json:"label" binding:"omitempty,oneof=curated sponsored"`
+	Position   int        `json:"position" binding:"min=0"`
+	StartsAt   *time.Time `json:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at"`
+}
+
+// Validate checks what binding tags cannot
+func (i *CreatePinInput) Validate() error {
+	if len(i.Region) > 0 {
+		if len(i.Region) != 4 {
+			return ErrInvalidRegion
+		}
+		west, south, east, north := i.Region[0], i.Region[1], i.Region[2], i.Region[3]
+		if west < -180 || east > 180 || south < -90 || north > 90 || west >= east || south >= north {
+			return ErrInvalidRegion
+		}
+	}
+	if geocode.Normalize(i.Query) == "" && len(i.Region) == 0 {
+		return ErrNoTarget
+	}
+	if i.StartsAt != nil && i.EndsAt != nil && !i.EndsAt.After(*i.StartsAt) {
+		return ErrInvalidWindow
+	}
+	return nil
+}
+
+// Service manages search pins
+type Service struct {
+	db *sqlx.DB
+}
+
+// NewService creates a new curation service
+func NewService(db *sqlx.DB) *Service {
+	return &Service{
+		db: db,
+	}
+}
+
+// pinColumns selects a pin with its region as a bounding box
+const pinColumns = `
+	id, entity_type, entity_id, query, label, position, starts_at, ends_at,
+	created_by, created_at,
+	ST_XMin(region::geometry) AS west, ST_YMin(region::geometry) AS south,
+	ST_XMax(region::geometry) AS east, ST_YMax(region::geometry) AS north`
+
+type pinRow struct {
+	Pin
+	West  sql.NullFloat64 `db:"west"`
+	South sql.NullFloat64 `db:"south"`
+	East  sql.NullFloat64 `db:"east"`
+	North sql.NullFloat64 `db:"north"`
+}
+
+func (r pinRow) pin() Pin {
+	pin := r.Pin
+	if r.West.Valid {
+		pin.Region = []float64{r.West.Float64, r.South.Float64, r.East.Float64, r.North.Float64}
+	}
+	return pin
+}
+
+// Create pins a result
+func (s *Service) Create(ctx context.Context, userID string, input *CreatePinInput) (*Pin, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	label := input.Label
+	if label == "" {
+		label = LabelCurated
+	}
+
+	var query *string
+	if normalized := geocode.Normalize(input.Query); normalized != "" {
+		query = &normalized
+	}
+
+	var west, south, east, north *float64
+	if len(input.Region) == 4 {
+		west, south, east, north = &input.Region[0], &input.Region[1], &input.Region[2], &input.Region[3]
+	}
+
+	var row pinRow
+	err := s.db.GetContext(ctx, &row, `
+		INSERT INTO search_pins (entity_type, entity_id, query, region, label, position, starts_at, ends_at, created_by)
+		VALUES ($1, $2, $3,
+			CASE WHEN $4::float8 IS NULL THEN NULL
+				ELSE ST_MakeEnvelope($4, $5, $6, $7, 4326)::geography END,
+			$8, $9, $10, $11, $12)
+		RETURNING `+pinColumns,
+		input.EntityType, input.EntityID, query, west, south, east, north,
+		label, input.Position, input.StartsAt, input.EndsAt, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pin: %w", err)
+	}
+
+	pin := row.pin()
+	return &pin, nil
+}
+
+// List returns every pin, including expired and scheduled ones
+func (s *Service) List(ctx context.Context) ([]Pin, error) {
+	var rows []pinRow
+	err := s.db.SelectContext(ctx, &rows, `SELECT `+pinColumns+` FROM search_pins ORDER BY position, created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pins: %w", err)
+	}
+
+	pins := make([]Pin, 0, len(rows))
+	for _, row := range rows {
+		pins = append(pins, row.pin())
+	}
+	return pins, nil
+}
+
+// Delete removes a pin
+func (s *Service) Delete(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM search_pins WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete pin: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrPinNotFound
+	}
+	return nil
+}
+
+// Match returns the pins that apply to a search, in display order. A pin
+// with a query matches that query however it is spaced or cased, a pin with a
+// region matches searches located inside it, and a pin with both needs both.
+// lat and lng are nil for searches without a location.
+func (s *Service) Match(ctx context.Context, query string, lat, lng *float64) ([]Pin, error) {
+	var rows []pinRow
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT `+pinColumns+`
+		FROM search_pins
+		WHERE (starts_at IS NULL OR starts_at <= now())
+			AND (ends_at IS NULL OR ends_at > now())
+			AND (query IS NULL OR query = $1)
+			AND (region IS NULL OR (
+				$2::float8 IS NOT NULL
+				AND ST_Covers(region, ST_SetSRID(ST_MakePoint($3::float8, $2), 4326)::geography)
+			))
+		ORDER BY position, created_at
+		LIMIT $4`,
+		geocode.Normalize(query), lat, lng, maxPins,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match pins: %w", err)
+	}
+
+	pins := make([]Pin, 0, len(rows))
+	for _, row := range rows {
+		pins = append(pins, row.pin())
+	}
+	return pins, nil
+}
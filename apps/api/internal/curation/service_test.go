@@ -0,0 +1,34 @@
+package curation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreatePinInput_Validate(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 3, 0)
+
+	tests := []struct {
+		name  string
+		input CreatePinInput
+		want  error
+	}{
+		{"query", CreatePinInput{Query: "Summer Hikes"}, nil},
+		{"region", CreatePinInput{Region: []float64{-122, 43.5, -121, 44.5}}, nil},
+		{"seasonal", CreatePinInput{Query: "summer hikes", StartsAt: &start, EndsAt: &end}, nil},
+		{"blank query", CreatePinInput{Query: "   "}, ErrNoTarget},
+		{"short region", CreatePinInput{Region: []float64{-122, 43.5}}, ErrInvalidRegion},
+		{"inverted region", CreatePinInput{Region: []float64{-121, 43.5, -122, 44.5}}, ErrInvalidRegion},
+		{"region off the map", CreatePinInput{Region: []float64{-122, 43.5, -121, 95}}, ErrInvalidRegion},
+		{"ends before start", CreatePinInput{Query: "summer hikes", StartsAt: &end, EndsAt: &start}, ErrInvalidWindow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.input.Validate())
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// RequiredIndex is an index the spatial, tag and text queries depend on. Indexes
+// are matched by name, so renaming one here creates it again under the new
+// name.
+type RequiredIndex struct {
+	Name       string `json:"name"`
+	Table      string `json:"table"`
+	Method     string `json:"method"`
+	Expression string `json:"expression"`
+}
+
+// RequiredIndexes are verified after migrations run and created if missing
+var RequiredIndexes = []RequiredIndex{
+	{Name: "idx_places_location", Table: "places", Method: "gist", Expression: "location"},
+	{Name: "idx_places_bounds", Table: "places", Method: "gist", Expression: "bounds"},
+	{Name: "idx_places_tags", Table: "places", Method: "gin", Expression: "tags"},
+	{Name: "idx_trips_tags", Table: "trips", Method: "gin", Expression: "tags"},
+	{Name: "idx_places_search_vector", Table: "places", Method: "gin", Expression: "search_vector"},
+	{Name: "idx_trips_search_vector", Table: "trips", Method: "gin", Expression: "search_vector"},
+	// route_geojson is JSONB, so the spatial index is on the geography built
+	// from it. Queries must use the same expression for the index to apply.
+	{Name: "idx_trips_route_geography", Table: "trips", Method: "gist", Expression: "(ST_GeomFromGeoJSON(route_geojson::text)::geography)"},
+}
+
+// IndexStatus reports the state of a required index
+type IndexStatus struct {
+	RequiredIndex
+	Exists     bool   `json:"exists"`
+	Valid      bool   `json:"valid"`
+	Definition string `json:"definition,omitempty"`
+	SizeBytes  int64  `json:"size_bytes"`
+	Scans      int64  `json:"scans"`
+	Created    bool   `json:"created,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// MigrationStatus reports the schema version applied by RunMigrations
+type MigrationStatus struct {
+	Version uint          `json:"version"`
+	Dirty   bool          `json:"dirty"`
+	Indexes []IndexStatus `json:"indexes"`
+}
+
+// EnsureIndexes creates any missing required index and rebuilds any left
+// invalid by an interrupted build. Indexes are built concurrently so writes
+// are not blocked, which can take a while on large tables.
+func (db *PostgresDB) EnsureIndexes(ctx context.Context) ([]IndexStatus, error) {
+	statuses, err := db.IndexHealth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range statuses {
+		status := &statuses[i]
+		if status.Exists && status.Valid {
+			continue
+		}
+
+		if err := db.buildIndex(ctx, status); err != nil {
+			status.Error = err.Error()
+			log.Printf("Warning: Failed to create index %s: %v", status.Name, err)
+			continue
+		}
+
+		status.Created = true
+		status.Exists = true
+		status.Valid = true
+		status.Error = ""
+		log.Printf("Created index %s on %s", status.Name, status.Table)
+	}
+
+	return statuses, nil
+}
+
+// IndexHealth reports the state of every required index without changing
+// anything
+func (db *PostgresDB) IndexHealth(ctx context.Context) ([]IndexStatus, error) {
+	query := `
+		SELECT
+			i.indisvalid,
+			pg_get_indexdef(i.indexrelid),
+			pg_relation_size(i.indexrelid),
+			COALESCE(s.idx_scan, 0)
+		FROM pg_class c
+		JOIN pg_index i ON i.indexrelid = c.oid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_stat_user_indexes s ON s.indexrelid = i.indexrelid
+		WHERE c.relname = $1 AND n.nspname = current_schema()`
+
+	statuses := make([]IndexStatus, 0, len(RequiredIndexes))
+	for _, index := range RequiredIndexes {
+		status := IndexStatus{RequiredIndex: index}
+
+		err := db.DB.QueryRowContext(ctx, query, index.Name).Scan(
+			&status.Valid,
+			&status.Definition,
+			&status.SizeBytes,
+			&status.Scans,
+		)
+		switch {
+		case err == sql.ErrNoRows:
+			// Missing
+		case err != nil:
+			return nil, fmt.Errorf("failed to check index %s: %w", index.Name, err)
+		default:
+			status.Exists = true
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// MigrationStatus returns the applied schema version and index health
+func (db *PostgresDB) MigrationStatus(ctx context.Context) (*MigrationStatus, error) {
+	status := &MigrationStatus{}
+
+	// schema_migrations is maintained by golang-migrate
+	err := db.DB.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&status.Version, &status.Dirty)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get migration version: %w", err)
+	}
+
+	indexes, err := db.IndexHealth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status.Indexes = indexes
+
+	return status, nil
+}
+
+func (db *PostgresDB) buildIndex(ctx context.Context, status *IndexStatus) error {
+	// An invalid index is left behind by a failed concurrent build and has to
+	// be dropped before it can be built again
+	if status.Exists {
+		if _, err := db.DB.ExecContext(ctx, fmt.Sprintf("DROP INDEX CONCURRENTLY IF EXISTS %s", status.Name)); err != nil {
+			return fmt.Errorf("failed to drop invalid index: %w", err)
+		}
+	}
+
+	// CONCURRENTLY cannot run inside a transaction
+	query := fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON %s USING %s (%s)",
+		status.Name, status.Table, status.Method, status.Expression)
+	if _, err := db.DB.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockDB(t *testing.T) (*PostgresDB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return &PostgresDB{DB: sqlx.NewDb(db, "postgres")}, mock
+}
+
+// expectIndexHealth expects the lookup of every required index. Indexes named
+// in missing are not found and those in invalid are left over from a failed
+// build.
+func expectIndexHealth(mock sqlmock.Sqlmock, missing, invalid map[string]bool) {
+	for _, index := range RequiredIndexes {
+		query := mock.ExpectQuery(`FROM pg_class c`).WithArgs(index.Name)
+		if missing[index.Name] {
+			query.WillReturnError(sql.ErrNoRows)
+			continue
+		}
+		query.WillReturnRows(sqlmock.NewRows([]string{"indisvalid", "pg_get_indexdef", "pg_relation_size", "idx_scan"}).
+			AddRow(!invalid[index.Name], "CREATE INDEX "+index.Name, 8192, 3))
+	}
+}
+
+func statusOf(t *testing.T, statuses []IndexStatus, name string) IndexStatus {
+	t.Helper()
+	for _, status := range statuses {
+		if status.Name == name {
+			return status
+		}
+	}
+	t.Fatalf("no status for index %s", name)
+	return IndexStatus{}
+}
+
+func TestPostgresDB_IndexHealth(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectIndexHealth(mock,
+		map[string]bool{"idx_places_location": true, "idx_trips_tags": true},
+		map[string]bool{"idx_places_search_vector": true},
+	)
+
+	statuses, err := db.IndexHealth(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, len(RequiredIndexes))
+
+	location := statusOf(t, statuses, "idx_places_location")
+	assert.Equal(t, "gist", location.Method)
+	assert.False(t, location.Exists)
+
+	tags := statusOf(t, statuses, "idx_trips_tags")
+	assert.Equal(t, "gin", tags.Method)
+	assert.False(t, tags.Exists)
+
+	search := statusOf(t, statuses, "idx_places_search_vector")
+	assert.True(t, search.Exists)
+	assert.False(t, search.Valid)
+
+	bounds := statusOf(t, statuses, "idx_places_bounds")
+	assert.True(t, bounds.Exists)
+	assert.True(t, bounds.Valid)
+	assert.EqualValues(t, 8192, bounds.SizeBytes)
+	assert.EqualValues(t, 3, bounds.Scans)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresDB_IndexHealthError(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(`FROM pg_class c`).WillReturnError(sql.ErrConnDone)
+
+	_, err := db.IndexHealth(context.Background())
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+}
+
+func TestPostgresDB_EnsureIndexes(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectIndexHealth(mock,
+		map[string]bool{"idx_places_location": true, "idx_trips_tags": true},
+		map[string]bool{"idx_places_search_vector": true},
+	)
+
+	// Missing indexes are built, invalid ones dropped and built again
+	mock.ExpectExec(regexp.QuoteMeta("CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_places_location ON places USING gist (location)")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_trips_tags ON trips USING gin (tags)")).
+		WillReturnError(errors.New("permission denied"))
+	mock.ExpectExec(regexp.QuoteMeta("DROP INDEX CONCURRENTLY IF EXISTS idx_places_search_vector")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_places_search_vector ON places USING gin (search_vector)")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	statuses, err := db.EnsureIndexes(context.Background())
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	for _, name := range []string{"idx_places_location", "idx_places_search_vector"} {
+		status := statusOf(t, statuses, name)
+		assert.True(t, status.Created, name)
+		assert.True(t, status.Exists && status.Valid, name)
+		assert.Empty(t, status.Error, name)
+	}
+
+	// A failed build is reported rather than stopping the others
+	failed := statusOf(t, statuses, "idx_trips_tags")
+	assert.False(t, failed.Created)
+	assert.False(t, failed.Exists)
+	assert.Equal(t, "permission denied", failed.Error)
+
+	assert.False(t, statusOf(t, statuses, "idx_places_bounds").Created)
+}
@@ -146,6 +146,51 @@ func (r *RedisClient) HDel(ctx context.Context, key string, fields ...string) er
 	return r.client.HDel(ctx, key, fields...).Err()
 }
 
+// Blocking list operations
+
+func (r *RedisClient) BRPop(ctx context.Context, timeout time.Duration, keys ...string) ([]string, error) {
+	return r.client.BRPop(ctx, timeout, keys...).Result()
+}
+
+// Sorted set operations
+
+func (r *RedisClient) ZAdd(ctx context.Context, key string, score float64, member string) error {
+	return r.client.ZAdd(ctx, key, redis.Z{Score: score, Member: member}).Err()
+}
+
+func (r *RedisClient) ZRem(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	return r.client.ZRem(ctx, key, members...).Result()
+}
+
+func (r *RedisClient) ZRangeByScore(ctx context.Context, key string, min, max string, count int64) ([]string, error) {
+	return r.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: min, Max: max, Count: count}).Result()
+}
+
+// ZRevRangeWithScores returns members from the highest score down, by rank
+func (r *RedisClient) ZRevRangeWithScores(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
+	return r.client.ZRevRangeWithScores(ctx, key, start, stop).Result()
+}
+
+func (r *RedisClient) ZRemRangeByRank(ctx context.Context, key string, start, stop int64) error {
+	return r.client.ZRemRangeByRank(ctx, key, start, stop).Err()
+}
+
+func (r *RedisClient) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return r.client.Expire(ctx, key, expiration).Err()
+}
+
+// Pub/sub operations
+
+func (r *RedisClient) Publish(ctx context.Context, channel string, message interface{}) error {
+	return r.client.Publish(ctx, channel, message).Err()
+}
+
+// Subscribe listens on channels. The subscription reconnects by itself and
+// must be closed by the caller.
+func (r *RedisClient) Subscribe(ctx context.Context, channels ...string) *redis.PubSub {
+	return r.client.Subscribe(ctx, channels...)
+}
+
 // Cache key builders
 
 func BuildTripCacheKey(tripID string) string {
@@ -156,6 +201,11 @@ func BuildTripListCacheKey(userID string, filter string) string {
 	return fmt.Sprintf("trips:user:%s:filter:%s", userID, filter)
 }
 
+// BuildUserTripListsKey is the set of a user's cached trip list keys
+func BuildUserTripListsKey(userID string) string {
+	return fmt.Sprintf("trips:user:%s:lists", userID)
+}
+
 func BuildPlaceCacheKey(placeID string) string {
 	return fmt.Sprintf("place:%s", placeID)
 }
@@ -172,6 +222,39 @@ func BuildUserPermissionsCacheKey(userID, tripID string) string {
 	return fmt.Sprintf("permissions:user:%s:trip:%s", userID, tripID)
 }
 
+// BuildDiscoverListCacheKey keys a page of a discover list by list, filters
+// and page
+func BuildDiscoverListCacheKey(variant string) string {
+	return fmt.Sprintf("discover:%s", variant)
+}
+
+// BuildDiscoverListsKey is the set of cached discover list keys
+func BuildDiscoverListsKey() string {
+	return "discover:lists"
+}
+
+// BuildCacheWarmKey marks the cache as warmed; it is gone after a flush
+func BuildCacheWarmKey() string {
+	return "cache:warm"
+}
+
+// BuildGeocodeCacheKey keys geocoding results by normalized place name
+func BuildGeocodeCacheKey(query string) string {
+	return fmt.Sprintf("geocode:%s", query)
+}
+
+// BuildCampsiteAvailabilityCacheKey keys a campground's availability by
+// provider, facility and stay
+func BuildCampsiteAvailabilityCacheKey(provider, facilityID, start, end string) string {
+	return fmt.Sprintf("campsites:%s:%s:%s:%s", provider, facilityID, start, end)
+}
+
+// BuildRecentViewsKey is the sorted set of what a user viewed, scored by
+// when
+func BuildRecentViewsKey(userID string) string {
+	return fmt.Sprintf("recent:user:%s", userID)
+}
+
 // Cache TTL constants
 const (
 	CacheTTLShort  = 5 * time.Minute
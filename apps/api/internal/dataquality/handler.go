@@ -0,0 +1,42 @@
+package dataquality
+
+import (
+	"errors"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the data quality admin endpoint
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new data quality handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// GetReport returns the most recent nightly report
+func (h *Handler) GetReport(c *gin.Context) {
+	report, err := h.service.Latest(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, ErrNoReport) {
+			response.NotFound(c, err.Error())
+			return
+		}
+		response.InternalServerError(c, "Failed to get data quality report")
+		return
+	}
+
+	response.Success(c, report)
+}
+
+// RegisterRoutes registers the data quality routes for admins
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *users.RouteMiddleware) {
+	admin := router.Group("/admin", mw.RequireAdmin...)
+	admin.GET("/data-quality", h.GetReport)
+}
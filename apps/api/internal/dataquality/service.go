@@ -0,0 +1,332 @@
+// Package dataquality reports rows that are inconsistent with the rest of the
+// data, such as places without coordinates or media whose files are gone
+package dataquality
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+	"github.com/jmoiron/sqlx"
+)
+
+// JobReport generates a data quality report unless one was already generated
+// since the last scheduled time
+const JobReport = "dataquality.report"
+
+const (
+	// checkInterval is how often a report job is queued. Most runs find the
+	// nightly report already done and return straight away.
+	checkInterval = time.Hour
+
+	// reportHour is the hour, in UTC, from which each night's report is due
+	reportHour = 3
+
+	// sampleSize is how many IDs are kept per check for a closer look
+	sampleSize = 10
+
+	// mediaBatchSize bounds how many media rows are read at a time while
+	// looking for missing files
+	mediaBatchSize = 500
+
+	// keepReportsFor is how long old reports are kept
+	keepReportsFor = 30 * 24 * time.Hour
+)
+
+// Checks run for each report
+const (
+	CheckPlacesWithoutCoordinates = "places_without_coordinates"
+	CheckTripsWithoutDistance     = "trips_without_distance"
+	CheckOrphanedWaypoints        = "orphaned_waypoints"
+	CheckMissingMediaFiles        = "missing_media_files"
+)
+
+var ErrNoReport = errors.New("no data quality report has been generated yet")
+
+// Check is what one check found
+type Check struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Count       int64    `json:"count"`
+	SampleIDs   []string `json:"sample_ids"`
+}
+
+// Report is the result of a run of every check
+type Report struct {
+	ID          string    `json:"id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	DurationMs  int64     `json:"duration_ms"`
+	Checks      []Check   `json:"checks"`
+}
+
+// FileStore locates stored media files. media.Storage implements it.
+type FileStore interface {
+	GetFullPath(filePath string) string
+}
+
+// queryCheck is a check done in a single query, which selects the IDs of the
+// rows at fault along with the total count of them
+type queryCheck struct {
+	name        string
+	description string
+	query       string
+}
+
+var queryChecks = []queryCheck{
+	{
+		name:        CheckPlacesWithoutCoordinates,
+		description: "Active places with no location",
+		query: `
+			SELECT id::text, COUNT(*) OVER() AS total
+			FROM places
+			WHERE status = 'active' AND location IS NULL
+			ORDER BY updated_at DESC
+			LIMIT $1`,
+	},
+	{
+		name:        CheckTripsWithoutDistance,
+		description: "Trips with a route but no distance",
+		query: `
+			SELECT id::text, COUNT(*) OVER() AS total
+			FROM trips
+			WHERE deleted_at IS NULL
+				AND route_geojson IS NOT NULL AND route_geojson <> 'null'::jsonb
+				AND COALESCE(distance_km, 0) = 0
+			ORDER BY updated_at DESC
+			LIMIT $1`,
+	},
+	{
+		name:        CheckOrphanedWaypoints,
+		description: "Waypoints of deleted trips or of places that are no longer active",
+		query: `
+			SELECT w.id::text, COUNT(*) OVER() AS total
+			FROM trip_waypoints w
+			JOIN trips t ON t.id = w.trip_id
+			JOIN places p ON p.id = w.place_id
+			WHERE t.deleted_at IS NOT NULL OR p.status <> 'active'
+			ORDER BY w.updated_at DESC
+			LIMIT $1`,
+	},
+}
+
+// Service generates and serves data quality reports
+type Service struct {
+	db    *sqlx.DB
+	queue jobs.Queue
+	files FileStore
+	now   func() time.Time
+}
+
+// NewService creates a new data quality service and registers its job handler
+func NewService(db *sqlx.DB, queue jobs.Queue, files FileStore) *Service {
+	s := &Service{
+		db:    db,
+		queue: queue,
+		files: files,
+		now:   time.Now,
+	}
+
+	queue.Register(JobReport, s.run)
+
+	return s
+}
+
+// Start queues a report job now and then every checkInterval until ctx is
+// cancelled. A report is only generated when the last one is older than the
+// night's scheduled time, so the instances queuing jobs rarely repeat work.
+func (s *Service) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.queue.Enqueue(ctx, JobReport, struct{}{}); err != nil {
+				log.Printf("dataquality: failed to queue report: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Latest returns the most recent report
+func (s *Service) Latest(ctx context.Context) (*Report, error) {
+	var row struct {
+		ID          string    `db:"id"`
+		GeneratedAt time.Time `db:"generated_at"`
+		DurationMs  int64     `db:"duration_ms"`
+		Checks      []byte    `db:"checks"`
+	}
+	err := s.db.GetContext(ctx, &row, `
+		SELECT id, generated_at, duration_ms, checks
+		FROM data_quality_reports
+		ORDER BY generated_at DESC
+		LIMIT 1`)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoReport
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data quality report: %w", err)
+	}
+
+	report := &Report{
+		ID:          row.ID,
+		GeneratedAt: row.GeneratedAt,
+		DurationMs:  row.DurationMs,
+	}
+	if err := json.Unmarshal(row.Checks, &report.Checks); err != nil {
+		return nil, fmt.Errorf("failed to decode data quality report: %w", err)
+	}
+	return report, nil
+}
+
+// run generates the night's report if it has not been generated yet
+func (s *Service) run(ctx context.Context, job *jobs.Job) error {
+	now := s.now()
+
+	var last time.Time
+	err := s.db.GetContext(ctx, &last, `SELECT COALESCE(MAX(generated_at), 'epoch') FROM data_quality_reports`)
+	if err != nil {
+		return fmt.Errorf("failed to get last data quality report: %w", err)
+	}
+	if !last.Before(dueSince(now)) {
+		return nil
+	}
+
+	report, err := s.Generate(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, check := range report.Checks {
+		if check.Count > 0 {
+			log.Printf("dataquality: %s: %d found", check.Name, check.Count)
+		}
+	}
+	return nil
+}
+
+// Generate runs every check now and saves the report
+func (s *Service) Generate(ctx context.Context) (*Report, error) {
+	start := s.now()
+
+	checks := make([]Check, 0, len(queryChecks)+1)
+	for _, qc := range queryChecks {
+		check, err := s.runQueryCheck(ctx, qc)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+
+	mediaCheck, err := s.checkMediaFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	checks = append(checks, mediaCheck)
+
+	report := &Report{
+		GeneratedAt: start,
+		DurationMs:  s.now().Sub(start).Milliseconds(),
+		Checks:      checks,
+	}
+
+	data, err := json.Marshal(report.Checks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode data quality report: %w", err)
+	}
+
+	err = s.db.GetContext(ctx, &report.ID, `
+		INSERT INTO data_quality_reports (generated_at, duration_ms, checks)
+		VALUES ($1, $2, $3)
+		RETURNING id`,
+		report.GeneratedAt, report.DurationMs, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save data quality report: %w", err)
+	}
+
+	// The new report is saved, so failing to trim old ones can wait a night
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM data_quality_reports WHERE generated_at < $1`, start.Add(-keepReportsFor)); err != nil {
+		log.Printf("dataquality: failed to delete old reports: %v", err)
+	}
+
+	return report, nil
+}
+
+func (s *Service) runQueryCheck(ctx context.Context, qc queryCheck) (Check, error) {
+	var rows []struct {
+		ID    string `db:"id"`
+		Total int64  `db:"total"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, qc.query, sampleSize); err != nil {
+		return Check{}, fmt.Errorf("failed to check %s: %w", qc.name, err)
+	}
+
+	check := Check{Name: qc.name, Description: qc.description, SampleIDs: []string{}}
+	for _, row := range rows {
+		check.Count = row.Total
+		check.SampleIDs = append(check.SampleIDs, row.ID)
+	}
+	return check, nil
+}
+
+// checkMediaFiles looks for every media row's file in storage. Files that
+// cannot be read for other reasons than being missing are not counted.
+func (s *Service) checkMediaFiles(ctx context.Context) (Check, error) {
+	check := Check{
+		Name:        CheckMissingMediaFiles,
+		Description: "Media rows whose file is missing from storage",
+		SampleIDs:   []string{},
+	}
+
+	afterID := "00000000-0000-0000-0000-000000000000"
+	for {
+		var rows []struct {
+			ID          string `db:"id"`
+			StoragePath string `db:"storage_path"`
+		}
+		err := s.db.SelectContext(ctx, &rows, `
+			SELECT id, storage_path
+			FROM media
+			WHERE id > $1
+			ORDER BY id
+			LIMIT $2`, afterID, mediaBatchSize)
+		if err != nil {
+			return Check{}, fmt.Errorf("failed to check %s: %w", check.Name, err)
+		}
+
+		for _, row := range rows {
+			if _, err := os.Stat(s.files.GetFullPath(row.StoragePath)); errors.Is(err, os.ErrNotExist) {
+				check.Count++
+				if len(check.SampleIDs) < sampleSize {
+					check.SampleIDs = append(check.SampleIDs, row.ID)
+				}
+			}
+		}
+
+		if len(rows) < mediaBatchSize {
+			return check, nil
+		}
+		afterID = rows[len(rows)-1].ID
+	}
+}
+
+// dueSince returns when the most recent nightly report fell due
+func dueSince(now time.Time) time.Time {
+	now = now.UTC()
+	due := time.Date(now.Year(), now.Month(), now.Day(), reportHour, 0, 0, 0, time.UTC)
+	if now.Before(due) {
+		due = due.AddDate(0, 0, -1)
+	}
+	return due
+}
@@ -0,0 +1,120 @@
+package dataquality
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dirStore string
+
+func (d dirStore) GetFullPath(filePath string) string {
+	return filepath.Join(string(d), filePath)
+}
+
+func setupService(t *testing.T) (*Service, sqlmock.Sqlmock, time.Time) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "present.jpg"), []byte("jpeg"), 0o644))
+
+	now := time.Date(2024, 5, 1, 3, 30, 0, 0, time.UTC)
+	s := NewService(sqlx.NewDb(db, "postgres"), jobs.NewLocalQueue(), dirStore(dir))
+	s.now = func() time.Time { return now }
+	return s, mock, now
+}
+
+func TestService_Generate(t *testing.T) {
+	s, mock, now := setupService(t)
+
+	mock.ExpectQuery("FROM places").
+		WithArgs(sampleSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "total"}).
+			AddRow("place-1", 12).
+			AddRow("place-2", 12))
+	mock.ExpectQuery("FROM trips").
+		WithArgs(sampleSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "total"}))
+	mock.ExpectQuery("FROM trip_waypoints").
+		WithArgs(sampleSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "total"}).AddRow("waypoint-1", 1))
+	mock.ExpectQuery("FROM media").
+		WithArgs("00000000-0000-0000-0000-000000000000", mediaBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "storage_path"}).
+			AddRow("media-1", "present.jpg").
+			AddRow("media-2", "gone.jpg"))
+	mock.ExpectQuery("INSERT INTO data_quality_reports").
+		WithArgs(now, int64(0), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("report-1"))
+	mock.ExpectExec("DELETE FROM data_quality_reports").
+		WithArgs(now.Add(-keepReportsFor)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	report, err := s.Generate(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "report-1", report.ID)
+	assert.Equal(t, []Check{
+		{Name: CheckPlacesWithoutCoordinates, Description: "Active places with no location", Count: 12, SampleIDs: []string{"place-1", "place-2"}},
+		{Name: CheckTripsWithoutDistance, Description: "Trips with a route but no distance", Count: 0, SampleIDs: []string{}},
+		{Name: CheckOrphanedWaypoints, Description: "Waypoints of deleted trips or of places that are no longer active", Count: 1, SampleIDs: []string{"waypoint-1"}},
+		{Name: CheckMissingMediaFiles, Description: "Media rows whose file is missing from storage", Count: 1, SampleIDs: []string{"media-2"}},
+	}, report.Checks)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_RunSkipsDoneReport(t *testing.T) {
+	s, mock, now := setupService(t)
+
+	mock.ExpectQuery("SELECT COALESCE\\(MAX\\(generated_at\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(now.Add(-10 * time.Minute)))
+
+	require.NoError(t, s.run(context.Background(), &jobs.Job{Type: JobReport}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_Latest(t *testing.T) {
+	s, mock, now := setupService(t)
+
+	mock.ExpectQuery("FROM data_quality_reports").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "generated_at", "duration_ms", "checks"}).
+			AddRow("report-1", now, 840, []byte(`[{"name": "orphaned_waypoints", "count": 3, "sample_ids": ["w1"]}]`)))
+
+	report, err := s.Latest(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(840), report.DurationMs)
+	assert.Equal(t, []Check{{Name: CheckOrphanedWaypoints, Count: 3, SampleIDs: []string{"w1"}}}, report.Checks)
+
+	mock.ExpectQuery("FROM data_quality_reports").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "generated_at", "duration_ms", "checks"}))
+
+	_, err = s.Latest(context.Background())
+	assert.ErrorIs(t, err, ErrNoReport)
+}
+
+func TestDueSince(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"after the hour", time.Date(2024, 5, 1, 3, 30, 0, 0, time.UTC), time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC)},
+		{"before the hour", time.Date(2024, 5, 1, 2, 59, 0, 0, time.UTC), time.Date(2024, 4, 30, 3, 0, 0, 0, time.UTC)},
+		{"other time zone", time.Date(2024, 5, 1, 1, 0, 0, 0, time.FixedZone("PDT", -7*3600)), time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, tt.want.Equal(dueSince(tt.now)))
+		})
+	}
+}
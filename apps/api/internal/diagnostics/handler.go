@@ -0,0 +1,51 @@
+package diagnostics
+
+import (
+	"strconv"
+
+	"github.com/Oferzz/newMap/apps/api/internal/database"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	db          *database.PostgresDB
+	slowQueries *SlowQueryLog
+}
+
+func NewHandler(db *database.PostgresDB, slowQueries *SlowQueryLog) *Handler {
+	return &Handler{
+		db:          db,
+		slowQueries: slowQueries,
+	}
+}
+
+// ListSlowQueries returns the most recent slow queries, newest first
+func (h *Handler) ListSlowQueries(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	response.Success(c, map[string]interface{}{
+		"threshold_ms": h.slowQueries.Threshold().Milliseconds(),
+		"queries":      h.slowQueries.Recent(limit),
+	})
+}
+
+// GetMigrations returns the schema version and the health of the required
+// spatial and tag indexes
+func (h *Handler) GetMigrations(c *gin.Context) {
+	status, err := h.db.MigrationStatus(c.Request.Context())
+	if err != nil {
+		response.InternalServerError(c, "Failed to get migration status")
+		return
+	}
+
+	response.Success(c, status)
+}
+
+// RegisterRoutes registers the diagnostics routes for admins
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *users.RouteMiddleware) {
+	admin := router.Group("/admin", mw.RequireAdmin...)
+	admin.GET("/slow-queries", h.ListSlowQueries)
+	admin.GET("/migrations", h.GetMigrations)
+}
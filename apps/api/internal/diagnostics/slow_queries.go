@@ -0,0 +1,213 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/pkg/requestid"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// recentSlowQueries is how many slow queries are kept for the admin endpoint
+	recentSlowQueries = 100
+
+	// maxConcurrentExplains bounds the extra load EXPLAIN ANALYZE puts on the database
+	maxConcurrentExplains = 2
+
+	explainTimeout = 30 * time.Second
+)
+
+// SlowQuery is a query that took longer than the configured threshold
+type SlowQuery struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Query      string    `json:"query"`
+	Args       []string  `json:"args"`
+	DurationMs int64     `json:"duration_ms"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Plan       string    `json:"plan,omitempty"`
+	PlanError  string    `json:"plan_error,omitempty"`
+	CapturedAt time.Time `json:"captured_at"`
+}
+
+// SlowQueryLog records queries slower than a threshold and, in diagnostic
+// mode, captures their EXPLAIN (ANALYZE, BUFFERS) plan in the background.
+// A nil *SlowQueryLog is valid and records nothing.
+type SlowQueryLog struct {
+	db        *sqlx.DB
+	threshold time.Duration
+	explain   bool
+	logger    *slog.Logger
+	explains  chan struct{}
+
+	mu     sync.Mutex
+	recent []*SlowQuery
+}
+
+// NewSlowQueryLog creates a new slow query log
+func NewSlowQueryLog(db *sqlx.DB, threshold time.Duration, explain bool) *SlowQueryLog {
+	return &SlowQueryLog{
+		db:        db,
+		threshold: threshold,
+		explain:   explain,
+		logger:    slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+		explains:  make(chan struct{}, maxConcurrentExplains),
+	}
+}
+
+// Track starts timing a query. Call the returned function once the results
+// have been read:
+//
+//	defer r.slowQueries.Track(ctx, "places.search", query, args...)()
+func (l *SlowQueryLog) Track(ctx context.Context, name, query string, args ...interface{}) func() {
+	start := time.Now()
+	return func() {
+		l.Observe(ctx, name, query, args, time.Since(start))
+	}
+}
+
+// Observe records a query that has finished
+func (l *SlowQueryLog) Observe(ctx context.Context, name, query string, args []interface{}, elapsed time.Duration) {
+	if l == nil || elapsed < l.threshold {
+		return
+	}
+
+	entry := &SlowQuery{
+		ID:         uuid.New().String(),
+		Name:       name,
+		Query:      compact(query),
+		Args:       formatArgs(args),
+		DurationMs: elapsed.Milliseconds(),
+		RequestID:  requestid.FromContext(ctx),
+		CapturedAt: time.Now(),
+	}
+	l.add(entry)
+
+	if !l.explain || !isReadOnly(query) {
+		l.log(entry)
+		return
+	}
+
+	// Skip the plan rather than queue up work when the database is already slow
+	select {
+	case l.explains <- struct{}{}:
+		go func() {
+			defer func() { <-l.explains }()
+			l.capturePlan(entry, query, args)
+			l.log(entry)
+		}()
+	default:
+		entry.PlanError = "skipped: too many plans in progress"
+		l.log(entry)
+	}
+}
+
+// Recent returns the most recent slow queries, newest first
+func (l *SlowQueryLog) Recent(limit int) []SlowQuery {
+	if l == nil {
+		return []SlowQuery{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if limit <= 0 || limit > len(l.recent) {
+		limit = len(l.recent)
+	}
+
+	result := make([]SlowQuery, 0, limit)
+	for i := len(l.recent) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, *l.recent[i])
+	}
+	return result
+}
+
+// Threshold returns the latency above which queries are recorded
+func (l *SlowQueryLog) Threshold() time.Duration {
+	if l == nil {
+		return 0
+	}
+	return l.threshold
+}
+
+func (l *SlowQueryLog) add(entry *SlowQuery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.recent = append(l.recent, entry)
+	if len(l.recent) > recentSlowQueries {
+		l.recent = l.recent[len(l.recent)-recentSlowQueries:]
+	}
+}
+
+// capturePlan re-runs the query under EXPLAIN. It uses its own context since
+// the request that triggered it has usually finished by now, and a transaction
+// that is always rolled back in case the statement writes after all.
+func (l *SlowQueryLog) capturePlan(entry *SlowQuery, query string, args []interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), explainTimeout)
+	defer cancel()
+
+	var lines []string
+	tx, err := l.db.BeginTxx(ctx, nil)
+	if err == nil {
+		err = tx.SelectContext(ctx, &lines, "EXPLAIN (ANALYZE, BUFFERS) "+query, args...)
+		tx.Rollback()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err != nil {
+		entry.PlanError = err.Error()
+		return
+	}
+	entry.Plan = strings.Join(lines, "\n")
+}
+
+func (l *SlowQueryLog) log(entry *SlowQuery) {
+	l.mu.Lock()
+	attrs := []any{
+		slog.String("name", entry.Name),
+		slog.Int64("duration_ms", entry.DurationMs),
+		slog.Duration("threshold", l.threshold),
+		slog.String("request_id", entry.RequestID),
+		slog.String("query", entry.Query),
+	}
+	if entry.Plan != "" {
+		attrs = append(attrs, slog.String("plan", entry.Plan))
+	}
+	if entry.PlanError != "" {
+		attrs = append(attrs, slog.String("plan_error", entry.PlanError))
+	}
+	l.mu.Unlock()
+
+	l.logger.Warn("slow query", attrs...)
+}
+
+// Helper functions
+
+// isReadOnly reports whether the statement is safe to run again under
+// EXPLAIN ANALYZE, which executes it. Statements starting with WITH are left
+// out since their CTEs may insert, update or delete.
+func isReadOnly(query string) bool {
+	q := strings.ToUpper(strings.TrimSpace(query))
+	return strings.HasPrefix(q, "SELECT")
+}
+
+func compact(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+func formatArgs(args []interface{}) []string {
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		formatted[i] = fmt.Sprintf("%v", arg)
+	}
+	return formatted
+}
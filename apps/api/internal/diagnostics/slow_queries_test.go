@@ -0,0 +1,83 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Oferzz/newMap/apps/api/pkg/requestid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowQueryLog_Observe(t *testing.T) {
+	log := NewSlowQueryLog(nil, 100*time.Millisecond, false)
+	ctx := requestid.NewContext(context.Background(), "req-1")
+
+	log.Observe(ctx, "fast", "SELECT 1", nil, 10*time.Millisecond)
+	log.Observe(ctx, "slow", "SELECT *\n\t FROM places WHERE id = $1", []interface{}{42}, 250*time.Millisecond)
+
+	recent := log.Recent(10)
+	require.Len(t, recent, 1)
+	assert.Equal(t, "slow", recent[0].Name)
+	assert.Equal(t, "SELECT * FROM places WHERE id = $1", recent[0].Query)
+	assert.Equal(t, []string{"42"}, recent[0].Args)
+	assert.Equal(t, int64(250), recent[0].DurationMs)
+	assert.Equal(t, "req-1", recent[0].RequestID)
+}
+
+func TestSlowQueryLog_RecentIsBoundedAndNewestFirst(t *testing.T) {
+	log := NewSlowQueryLog(nil, 0, false)
+
+	for i := 0; i < recentSlowQueries+5; i++ {
+		log.Observe(context.Background(), fmt.Sprintf("q%d", i), "SELECT 1", nil, time.Millisecond)
+	}
+
+	recent := log.Recent(0)
+	require.Len(t, recent, recentSlowQueries)
+	assert.Equal(t, fmt.Sprintf("q%d", recentSlowQueries+4), recent[0].Name)
+	assert.Equal(t, "q5", recent[len(recent)-1].Name)
+
+	assert.Len(t, log.Recent(3), 3)
+}
+
+func TestSlowQueryLog_Nil(t *testing.T) {
+	var log *SlowQueryLog
+
+	done := log.Track(context.Background(), "noop", "SELECT 1")
+	done()
+
+	assert.Empty(t, log.Recent(10))
+	assert.Zero(t, log.Threshold())
+}
+
+func TestIsReadOnly(t *testing.T) {
+	assert.True(t, isReadOnly("  select * from trips"))
+	assert.False(t, isReadOnly("WITH x AS (DELETE FROM trips RETURNING id) SELECT * FROM x"))
+	assert.False(t, isReadOnly("UPDATE trips SET title = $1"))
+	assert.False(t, isReadOnly("DELETE FROM trips"))
+}
+
+func TestSlowQueryLog_CapturePlanRollsBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN (ANALYZE, BUFFERS) SELECT * FROM trips WHERE id = $1")).
+		WithArgs("trip-1").
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow("Index Scan").AddRow("Execution Time: 1 ms"))
+	mock.ExpectRollback()
+
+	log := NewSlowQueryLog(sqlx.NewDb(db, "sqlmock"), 0, true)
+	entry := &SlowQuery{}
+	log.capturePlan(entry, "SELECT * FROM trips WHERE id = $1", []interface{}{"trip-1"})
+
+	assert.Equal(t, "Index Scan\nExecution Time: 1 ms", entry.Plan)
+	assert.Empty(t, entry.PlanError)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
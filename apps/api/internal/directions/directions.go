@@ -0,0 +1,45 @@
+// Package directions plans routes along roads and trails between points
+package directions
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNoRoute is returned when the points cannot be connected with the
+	// profile, such as points off any road when driving
+	ErrNoRoute = errors.New("no route found between the points")
+
+	ErrRateLimited = errors.New("directions rate limit reached")
+)
+
+// Profiles a route can be planned for
+const (
+	ProfileWalking = "walking"
+	ProfileCycling = "cycling"
+	ProfileDriving = "driving"
+)
+
+// MaxPoints is the most points a route can pass through
+const MaxPoints = 25
+
+// Point is a place a route starts, passes through or ends at
+type Point struct {
+	Longitude float64
+	Latitude  float64
+}
+
+// Route is a planned route
+type Route struct {
+	// Coordinates are the [longitude, latitude] positions of the route's
+	// LineString
+	Coordinates [][]float64
+	DistanceM   float64
+	DurationS   float64
+}
+
+// Router plans routes through points, in order
+type Router interface {
+	Route(ctx context.Context, profile string, points []Point) (*Route, error)
+}
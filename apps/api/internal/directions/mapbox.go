@@ -0,0 +1,122 @@
+package directions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/mapbox"
+)
+
+const mapboxDirectionsAPI = "https://api.mapbox.com/directions/v5/mapbox"
+
+// mapboxProfiles maps profiles to Mapbox's routing profiles
+var mapboxProfiles = map[string]string{
+	ProfileWalking: "walking",
+	ProfileCycling: "cycling",
+	ProfileDriving: "driving",
+}
+
+// MapboxRouter plans routes with the Mapbox Directions API
+type MapboxRouter struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewMapboxRouter creates a Mapbox router
+func NewMapboxRouter(apiKey string) *MapboxRouter {
+	return &MapboxRouter{
+		apiKey:  apiKey,
+		baseURL: mapboxDirectionsAPI,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+type mapboxResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Routes  []struct {
+		Geometry struct {
+			Type        string      `json:"type"`
+			Coordinates [][]float64 `json:"coordinates"`
+		} `json:"geometry"`
+		Distance float64 `json:"distance"` // meters
+		Duration float64 `json:"duration"` // seconds
+	} `json:"routes"`
+}
+
+// Route returns Mapbox's best route through the points, in order
+func (r *MapboxRouter) Route(ctx context.Context, profile string, points []Point) (*Route, error) {
+	if r.apiKey == "" {
+		return nil, fmt.Errorf("mapbox API key not configured")
+	}
+	mapboxProfile, ok := mapboxProfiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("unknown routing profile %q", profile)
+	}
+	if len(points) < 2 || len(points) > MaxPoints {
+		return nil, fmt.Errorf("a route needs 2 to %d points, got %d", MaxPoints, len(points))
+	}
+
+	coordinates := make([]string, len(points))
+	for i, p := range points {
+		coordinates[i] = strconv.FormatFloat(p.Longitude, 'f', -1, 64) + "," + strconv.FormatFloat(p.Latitude, 'f', -1, 64)
+	}
+
+	params := url.Values{}
+	params.Set("access_token", r.apiKey)
+	params.Set("geometries", "geojson")
+	params.Set("overview", "full")
+	endpoint := fmt.Sprintf("%s/%s/%s?%s", r.baseURL, mapboxProfile, strings.Join(coordinates, ";"), params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan route: %w", mapbox.StripURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("mapbox: %w", ErrRateLimited)
+	}
+
+	// Routing failures come with a code, unroutable points with a 200 or a
+	// 422
+	var body mapboxResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("mapbox API returned status %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	switch body.Code {
+	case "Ok":
+	case "NoRoute", "NoSegment":
+		return nil, ErrNoRoute
+	default:
+		return nil, fmt.Errorf("mapbox API returned status %d: %s %s", resp.StatusCode, body.Code, body.Message)
+	}
+	if len(body.Routes) == 0 || len(body.Routes[0].Geometry.Coordinates) < 2 {
+		return nil, ErrNoRoute
+	}
+
+	route := body.Routes[0]
+	return &Route{
+		Coordinates: route.Geometry.Coordinates,
+		DistanceM:   route.Distance,
+		DurationS:   route.Duration,
+	}, nil
+}
@@ -0,0 +1,85 @@
+package directions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMapbox(t *testing.T, handler http.HandlerFunc) *MapboxRouter {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	router := NewMapboxRouter("token")
+	router.baseURL = server.URL
+	return router
+}
+
+var smithRock = []Point{
+	{Longitude: -121.1403, Latitude: 44.3672},
+	{Longitude: -121.1334, Latitude: 44.3705},
+}
+
+func TestMapboxRouter_Route(t *testing.T) {
+	router := testMapbox(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/walking/-121.1403,44.3672;-121.1334,44.3705", r.URL.Path)
+		assert.Equal(t, "token", r.URL.Query().Get("access_token"))
+		assert.Equal(t, "geojson", r.URL.Query().Get("geometries"))
+		w.Write([]byte(`{"code": "Ok", "routes": [{
+			"geometry": {"type": "LineString", "coordinates": [[-121.1403, 44.3672], [-121.1371, 44.3690], [-121.1334, 44.3705]]},
+			"distance": 812.4,
+			"duration": 583.1
+		}]}`))
+	})
+
+	route, err := router.Route(context.Background(), ProfileWalking, smithRock)
+	require.NoError(t, err)
+	assert.Equal(t, &Route{
+		Coordinates: [][]float64{{-121.1403, 44.3672}, {-121.1371, 44.3690}, {-121.1334, 44.3705}},
+		DistanceM:   812.4,
+		DurationS:   583.1,
+	}, route)
+}
+
+func TestMapboxRouter_Errors(t *testing.T) {
+	t.Run("no route", func(t *testing.T) {
+		router := testMapbox(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"code": "NoRoute", "message": "No route found", "routes": []}`))
+		})
+		_, err := router.Route(context.Background(), ProfileDriving, smithRock)
+		assert.ErrorIs(t, err, ErrNoRoute)
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		router := testMapbox(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+		_, err := router.Route(context.Background(), ProfileDriving, smithRock)
+		assert.ErrorIs(t, err, ErrRateLimited)
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		router := testMapbox(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"code": "InvalidInput", "message": "Coordinate is invalid"}`))
+		})
+		_, err := router.Route(context.Background(), ProfileCycling, smithRock)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "InvalidInput")
+		assert.NotContains(t, err.Error(), "token")
+	})
+
+	t.Run("checked before calling Mapbox", func(t *testing.T) {
+		router := testMapbox(t, func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Mapbox was called")
+		})
+		_, err := router.Route(context.Background(), "sailing", smithRock)
+		assert.Error(t, err)
+		_, err = router.Route(context.Background(), ProfileWalking, smithRock[:1])
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,66 @@
+package discovery
+
+import (
+	"strconv"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/pkg/httpcache"
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+const maxListLimit = 100
+
+// Handler handles discovery HTTP requests
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new discovery handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// ListTrips returns a page of public trip cards for one of the discover lists:
+// featured, trending, popular, top-rated, recent or quiet
+func (h *Handler) ListTrips(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > maxListLimit {
+		limit = 20
+	}
+
+	filters := ListFilters{
+		ActivityType:    c.Query("activity_type"),
+		DifficultyLevel: c.Query("difficulty_level"),
+		Tag:             c.Query("tag"),
+		Limit:           limit,
+		Offset:          (page - 1) * limit,
+	}
+
+	cards, err := h.service.List(c.Request.Context(), c.Param("list"), filters)
+	if err != nil {
+		switch err {
+		case ErrUnknownList:
+			response.NotFound(c, "Discover list not found")
+		default:
+			response.InternalServerError(c, "Failed to get trips")
+		}
+		return
+	}
+
+	// Cards are only refreshed every few minutes, so short-lived caching is
+	// safe. Refreshes purge the CDN copy.
+	httpcache.Public(c, httpcache.Listing, httpcache.DiscoverKey, httpcache.DiscoverListKey(c.Param("list")))
+	response.Success(c, cards)
+}
+
+// RegisterRoutes registers the public discovery routes
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *users.RouteMiddleware) {
+	router.GET("/discover/:list", h.ListTrips)
+}
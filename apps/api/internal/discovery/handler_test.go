@@ -0,0 +1,72 @@
+package discovery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Oferzz/newMap/apps/api/pkg/httpcache"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_ListTrips(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	columns := []string{"trip_id", "title", "owner_id"}
+
+	serve := func(service *Service, path string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.GET("/discover/:list", NewHandler(service).ListTrips)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	t.Run("a page of a list", func(t *testing.T) {
+		service, mock, _, _ := newTestService(t)
+		mock.ExpectQuery(regexp.QuoteMeta(`AND difficulty_level = $1 ORDER BY crowd_score, trip_id LIMIT $2 OFFSET $3`)).
+			WithArgs("easy", 10, 10).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("trip-1", "Dipsea", "owner-1"))
+
+		w := serve(service, "/discover/quiet?difficulty_level=easy&page=2&limit=10")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"title":"Dipsea"`)
+		assert.Contains(t, w.Header().Get("Cache-Control"), "public")
+		assert.Equal(t, httpcache.DiscoverKey+" "+httpcache.DiscoverListKey(ListQuiet), w.Header().Get(httpcache.SurrogateKeyHeader))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("out of range paging falls back to the defaults", func(t *testing.T) {
+		service, mock, _, _ := newTestService(t)
+		mock.ExpectQuery(`SELECT \* FROM trip_discovery`).
+			WithArgs(20, 0).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		w := serve(service, "/discover/recent?page=0&limit=500")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown list", func(t *testing.T) {
+		service, _, _, _ := newTestService(t)
+
+		w := serve(service, "/discover/hidden-gems")
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Empty(t, w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		service, mock, _, _ := newTestService(t)
+		mock.ExpectQuery(`SELECT \* FROM trip_discovery`).WillReturnError(sqlmock.ErrCancelled)
+
+		w := serve(service, "/discover/popular")
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
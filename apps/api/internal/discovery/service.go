@@ -0,0 +1,425 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/cache"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+	"github.com/Oferzz/newMap/apps/api/pkg/httpcache"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// JobRefresh brings trip_discovery up to date. With trip IDs in the payload
+// only those trips are refreshed, otherwise every trip that changed since its
+// card was built.
+const JobRefresh = "discovery.refresh"
+
+const (
+	// refreshInterval is how often a refresh of changed trips is queued
+	refreshInterval = 10 * time.Minute
+
+	// staleAfter forces cards to be rebuilt so that trending scores decay even
+	// when nothing about the trip changes
+	staleAfter = time.Hour
+
+	// refreshBatchSize bounds how many cards a single refresh rebuilds
+	refreshBatchSize = 500
+
+	// ListCacheTTL is how long pages of the lists are cached. Refreshes drop
+	// them sooner.
+	ListCacheTTL = refreshInterval
+)
+
+// Lists served by the discover endpoints
+const (
+	ListTrending = "trending"
+	ListPopular  = "popular"
+	ListTopRated = "top-rated"
+	ListRecent   = "recent"
+	ListQuiet    = "quiet"
+	ListFeatured = "featured"
+)
+
+// Lists are the discover lists, in the order they are shown
+var Lists = []string{ListFeatured, ListTrending, ListPopular, ListTopRated, ListRecent, ListQuiet}
+
+var listOrder = map[string]string{
+	ListTrending: "trending_score DESC, trip_id",
+	ListPopular:  "completion_count DESC, trip_id",
+	ListTopRated: "average_rating DESC NULLS LAST, rating_count DESC, trip_id",
+	ListRecent:   "created_at DESC, trip_id",
+	ListQuiet:    "crowd_score, trip_id",
+	ListFeatured: "trending_score DESC, trip_id",
+}
+
+var ErrUnknownList = errors.New("unknown discover list")
+
+// TripCard is the pre-aggregated public view of a trip
+type TripCard struct {
+	TripID                string         `db:"trip_id" json:"id"`
+	Title                 string         `db:"title" json:"title"`
+	Description           *string        `db:"description" json:"description,omitempty"`
+	CoverImage            *string        `db:"cover_image" json:"cover_image,omitempty"`
+	OwnerID               string         `db:"owner_id" json:"owner_id"`
+	OwnerUsername         *string        `db:"owner_username" json:"owner_username,omitempty"`
+	OwnerDisplayName      *string        `db:"owner_display_name" json:"owner_display_name,omitempty"`
+	OwnerAvatarURL        *string        `db:"owner_avatar_url" json:"owner_avatar_url,omitempty"`
+	ActivityType          *string        `db:"activity_type" json:"activity_type,omitempty"`
+	DifficultyLevel       *string        `db:"difficulty_level" json:"difficulty_level,omitempty"`
+	DurationHours         *float64       `db:"duration_hours" json:"duration_hours,omitempty"`
+	DistanceKm            *float64       `db:"distance_km" json:"distance_km,omitempty"`
+	ElevationGainM        *int           `db:"elevation_gain_m" json:"elevation_gain_m,omitempty"`
+	Tags                  pq.StringArray `db:"tags" json:"tags"`
+	Featured              bool           `db:"featured" json:"featured"`
+	Verified              bool           `db:"verified" json:"verified"`
+	ViewCount             int            `db:"view_count" json:"view_count"`
+	CompletionCount       int            `db:"completion_count" json:"completion_count"`
+	RecentCompletionCount int            `db:"recent_completion_count" json:"recent_completion_count"`
+	RatingCount           int            `db:"rating_count" json:"rating_count"`
+	AverageRating         *float64       `db:"average_rating" json:"average_rating,omitempty"`
+	TrendingScore         float64        `db:"trending_score" json:"trending_score"`
+	CrowdScore            float64        `db:"crowd_score" json:"crowd_score"`
+	CreatedAt             time.Time      `db:"created_at" json:"created_at"`
+	RefreshedAt           time.Time      `db:"refreshed_at" json:"refreshed_at"`
+}
+
+// ListFilters narrows a discover list
+type ListFilters struct {
+	ActivityType    string
+	DifficultyLevel string
+	Tag             string
+	Limit           int
+	Offset          int
+}
+
+type refreshPayload struct {
+	TripIDs []string `json:"trip_ids,omitempty"`
+}
+
+// Service serves discovery lists from the trip_discovery summary table
+type Service struct {
+	db     *sqlx.DB
+	queue  jobs.Queue
+	purger httpcache.Purger
+	cache  cache.Cache
+}
+
+// NewService creates a new discovery service and registers its job handler
+func NewService(db *sqlx.DB, queue jobs.Queue, purger httpcache.Purger) *Service {
+	s := &Service{
+		db:     db,
+		queue:  queue,
+		purger: purger,
+	}
+
+	queue.Register(JobRefresh, s.refresh)
+
+	return s
+}
+
+// SetCache enables caching pages of the lists. Without a cache every page is
+// read from trip_discovery.
+func (s *Service) SetCache(c cache.Cache) {
+	s.cache = c
+}
+
+// Start queues a refresh of changed trips now and then every refreshInterval
+// until ctx is cancelled. Refreshes are incremental, so overlapping runs from
+// several instances only repeat a little work.
+func (s *Service) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.queue.Enqueue(ctx, JobRefresh, refreshPayload{}); err != nil {
+				log.Printf("discovery: failed to queue refresh: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RefreshTrips queues a refresh of specific trips
+func (s *Service) RefreshTrips(ctx context.Context, tripIDs ...string) error {
+	if len(tripIDs) == 0 {
+		return nil
+	}
+	_, err := s.queue.Enqueue(ctx, JobRefresh, refreshPayload{TripIDs: tripIDs})
+	return err
+}
+
+// HandleTripPublished adds a newly published trip to the discover lists
+func (s *Service) HandleTripPublished(ctx context.Context, event events.Event) error {
+	return s.RefreshTrips(ctx, event.EntityID)
+}
+
+// HandleTripInvalidated rebuilds the card of a changed trip so discover lists
+// do not wait for the next scheduled refresh
+func (s *Service) HandleTripInvalidated(ctx context.Context, event events.Event) error {
+	return s.RefreshTrips(ctx, event.EntityID)
+}
+
+// List returns a page of a discover list, from the cache when it holds it
+func (s *Service) List(ctx context.Context, list string, filters ListFilters) ([]*TripCard, error) {
+	if _, ok := listOrder[list]; !ok {
+		return nil, ErrUnknownList
+	}
+	if s.cache == nil {
+		return s.list(ctx, list, filters)
+	}
+
+	variant := fmt.Sprintf("%s:%s:%s:%s:%d:%d", list, filters.ActivityType, filters.DifficultyLevel, filters.Tag, filters.Limit, filters.Offset)
+	if data, err := s.cache.GetDiscoverList(ctx, variant); err == nil && data != nil {
+		var cards []*TripCard
+		if err := json.Unmarshal(data, &cards); err == nil {
+			return cards, nil
+		}
+	}
+
+	cards, err := s.list(ctx, list, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(cards); err == nil {
+		if err := s.cache.SetDiscoverList(ctx, variant, data, ListCacheTTL); err != nil {
+			log.Printf("discovery: failed to cache %s list: %v", list, err)
+		}
+	}
+
+	return cards, nil
+}
+
+// list reads a page of a discover list from trip_discovery
+func (s *Service) list(ctx context.Context, list string, filters ListFilters) ([]*TripCard, error) {
+	order := listOrder[list]
+
+	query := `SELECT * FROM trip_discovery WHERE 1=1`
+	args := []interface{}{}
+	argCount := 1
+
+	if filters.ActivityType != "" {
+		query += fmt.Sprintf(" AND activity_type = $%d", argCount)
+		args = append(args, filters.ActivityType)
+		argCount++
+	}
+
+	if filters.DifficultyLevel != "" {
+		query += fmt.Sprintf(" AND difficulty_level = $%d", argCount)
+		args = append(args, filters.DifficultyLevel)
+		argCount++
+	}
+
+	if filters.Tag != "" {
+		query += fmt.Sprintf(" AND $%d = ANY(tags)", argCount)
+		args = append(args, filters.Tag)
+		argCount++
+	}
+
+	if list == ListTrending {
+		// Cards with no recent activity would otherwise pad the list in
+		// creation order
+		query += " AND trending_score > 0"
+	}
+
+	if list == ListFeatured {
+		query += " AND featured"
+	}
+
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d OFFSET $%d", order, argCount, argCount+1)
+	args = append(args, filters.Limit, filters.Offset)
+
+	cards := []*TripCard{}
+	if err := s.db.SelectContext(ctx, &cards, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list %s trips: %w", list, err)
+	}
+
+	return cards, nil
+}
+
+// refresh is the job handler for JobRefresh
+func (s *Service) refresh(ctx context.Context, job *jobs.Job) error {
+	var payload refreshPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	tripIDs := payload.TripIDs
+	if len(tripIDs) == 0 {
+		changed, err := s.changedTrips(ctx)
+		if err != nil {
+			return err
+		}
+		tripIDs = changed
+	}
+
+	if len(tripIDs) == 0 {
+		return nil
+	}
+
+	if err := s.refreshTrips(ctx, tripIDs); err != nil {
+		return err
+	}
+
+	// Lists served from the CDN would otherwise lag behind by up to s-maxage
+	httpcache.PurgeAsync(s.purger, httpcache.DiscoverKey)
+	if s.cache != nil {
+		if err := s.cache.InvalidateDiscoverLists(ctx); err != nil {
+			log.Printf("discovery: failed to drop cached lists: %v", err)
+		}
+	}
+	return nil
+}
+
+// changedTrips finds trips whose card is missing, out of date or stale
+func (s *Service) changedTrips(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT t.id
+		FROM trips t
+		LEFT JOIN trip_discovery d ON d.trip_id = t.id
+		WHERE (d.trip_id IS NULL AND t.privacy = 'public' AND t.deleted_at IS NULL)
+			OR t.updated_at > d.refreshed_at
+			OR d.refreshed_at < $1
+		UNION
+		SELECT c.trip_id
+		FROM activity_completions c
+		JOIN trip_discovery d ON d.trip_id = c.trip_id
+		WHERE c.created_at > d.refreshed_at
+		UNION
+		SELECT r.trip_id
+		FROM activity_ratings r
+		JOIN trip_discovery d ON d.trip_id = r.trip_id
+		WHERE r.updated_at > d.refreshed_at
+		UNION
+		SELECT k.trip_id
+		FROM activity_conditions k
+		JOIN trip_discovery d ON d.trip_id = k.trip_id
+		WHERE k.created_at > d.refreshed_at
+		LIMIT $2`
+
+	var tripIDs []string
+	if err := s.db.SelectContext(ctx, &tripIDs, query, time.Now().Add(-staleAfter), refreshBatchSize); err != nil {
+		return nil, fmt.Errorf("failed to find changed trips: %w", err)
+	}
+
+	return tripIDs, nil
+}
+
+// refreshTrips rebuilds the cards of public trips and removes those of trips
+// that are no longer public
+func (s *Service) refreshTrips(ctx context.Context, tripIDs []string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM trip_discovery d
+		USING trips t
+		WHERE d.trip_id = t.id
+			AND d.trip_id = ANY($1)
+			AND (t.privacy <> 'public' OR t.deleted_at IS NOT NULL)`,
+		pq.Array(tripIDs))
+	if err != nil {
+		return fmt.Errorf("failed to remove trip cards: %w", err)
+	}
+
+	// Trending favours recent completions and ratings, with a small boost
+	// from all-time views. The crowd score is visits per week over the last
+	// 90 days, counting condition reports as visits too.
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO trip_discovery (
+			trip_id, title, description, cover_image,
+			owner_id, owner_username, owner_display_name, owner_avatar_url,
+			activity_type, difficulty_level, duration_hours, distance_km,
+			elevation_gain_m, tags, featured, verified, view_count,
+			completion_count, recent_completion_count, rating_count,
+			average_rating, trending_score, crowd_score, created_at, refreshed_at
+		)
+		SELECT
+			t.id, t.title, t.description, t.cover_image,
+			t.owner_id, u.username, u.display_name, u.avatar_url,
+			t.activity_type, t.difficulty_level, t.duration_hours, t.distance_km,
+			t.elevation_gain_m, t.tags, COALESCE(t.featured, false), COALESCE(t.verified, false),
+			COALESCE(t.view_count, 0),
+			COALESCE(c.total, 0), COALESCE(c.recent, 0), COALESCE(r.total, 0),
+			r.average,
+			COALESCE(c.recent, 0) * 3 + COALESCE(r.recent, 0) * 2 + LN(1 + COALESCE(t.view_count, 0)),
+			(COALESCE(c.quarter, 0) + COALESCE(k.quarter, 0)) / (90 / 7.0),
+			t.created_at, CURRENT_TIMESTAMP
+		FROM trips t
+		LEFT JOIN users u ON u.id = t.owner_id
+		LEFT JOIN LATERAL (
+			SELECT
+				COUNT(*) AS total,
+				COUNT(*) FILTER (WHERE completed_at > NOW() - INTERVAL '30 days') AS recent,
+				COUNT(*) FILTER (WHERE completed_at > NOW() - INTERVAL '90 days') AS quarter
+			FROM activity_completions
+			WHERE trip_id = t.id
+		) c ON true
+		LEFT JOIN LATERAL (
+			SELECT
+				COUNT(*) AS total,
+				COUNT(*) FILTER (WHERE created_at > NOW() - INTERVAL '30 days') AS recent,
+				AVG(overall_rating)::DECIMAL(3,2) AS average
+			FROM activity_ratings
+			WHERE trip_id = t.id
+		) r ON true
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS quarter
+			FROM activity_conditions
+			WHERE trip_id = t.id AND created_at > NOW() - INTERVAL '90 days'
+		) k ON true
+		WHERE t.id = ANY($1)
+			AND t.privacy = 'public'
+			AND t.deleted_at IS NULL
+		ON CONFLICT (trip_id) DO UPDATE SET
+			title = EXCLUDED.title,
+			description = EXCLUDED.description,
+			cover_image = EXCLUDED.cover_image,
+			owner_id = EXCLUDED.owner_id,
+			owner_username = EXCLUDED.owner_username,
+			owner_display_name = EXCLUDED.owner_display_name,
+			owner_avatar_url = EXCLUDED.owner_avatar_url,
+			activity_type = EXCLUDED.activity_type,
+			difficulty_level = EXCLUDED.difficulty_level,
+			duration_hours = EXCLUDED.duration_hours,
+			distance_km = EXCLUDED.distance_km,
+			elevation_gain_m = EXCLUDED.elevation_gain_m,
+			tags = EXCLUDED.tags,
+			featured = EXCLUDED.featured,
+			verified = EXCLUDED.verified,
+			view_count = EXCLUDED.view_count,
+			completion_count = EXCLUDED.completion_count,
+			recent_completion_count = EXCLUDED.recent_completion_count,
+			rating_count = EXCLUDED.rating_count,
+			average_rating = EXCLUDED.average_rating,
+			trending_score = EXCLUDED.trending_score,
+			crowd_score = EXCLUDED.crowd_score,
+			created_at = EXCLUDED.created_at,
+			refreshed_at = EXCLUDED.refreshed_at`,
+		pq.Array(tripIDs))
+	if err != nil {
+		return fmt.Errorf("failed to refresh trip cards: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit trip cards: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Oferzz/newMap/apps/api/internal/cache"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+	"github.com/Oferzz/newMap/apps/api/pkg/httpcache"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listCache keeps discover lists in memory
+type listCache struct {
+	cache.Cache
+	lists       map[string][]byte
+	invalidated int
+}
+
+func (c *listCache) GetDiscoverList(ctx context.Context, variant string) ([]byte, error) {
+	return c.lists[variant], nil
+}
+
+func (c *listCache) SetDiscoverList(ctx context.Context, variant string, data []byte, ttl time.Duration) error {
+	c.lists[variant] = data
+	return nil
+}
+
+func (c *listCache) InvalidateDiscoverLists(ctx context.Context) error {
+	c.invalidated++
+	c.lists = map[string][]byte{}
+	return nil
+}
+
+// purger records the keys it is asked to purge
+type purger struct {
+	purged chan []string
+}
+
+func (p *purger) Purge(ctx context.Context, keys ...string) error {
+	p.purged <- keys
+	return nil
+}
+
+func newTestService(t *testing.T) (*Service, sqlmock.Sqlmock, *listCache, *purger) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	lists := &listCache{lists: map[string][]byte{}}
+	cdn := &purger{purged: make(chan []string, 1)}
+	service := NewService(sqlx.NewDb(db, "postgres"), jobs.NewLocalQueue(), cdn)
+	service.SetCache(lists)
+	return service, mock, lists, cdn
+}
+
+func refreshJob(t *testing.T, payload refreshPayload) *jobs.Job {
+	t.Helper()
+	data, err := json.Marshal(payload)
+	require.NoError(t, err)
+	return &jobs.Job{Type: JobRefresh, Payload: data}
+}
+
+// expectRebuild expects the cards of the trips to be rebuilt in one
+// transaction
+func expectRebuild(mock sqlmock.Sqlmock, tripIDs string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM trip_discovery d\s+USING trips t`).
+		WithArgs(tripIDs).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO trip_discovery`).
+		WithArgs(tripIDs).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+}
+
+func TestService_Refresh(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("given trips", func(t *testing.T) {
+		service, mock, lists, cdn := newTestService(t)
+		lists.lists["trending"] = []byte(`[]`)
+		expectRebuild(mock, `{"trip-1","trip-2"}`)
+
+		require.NoError(t, service.refresh(ctx, refreshJob(t, refreshPayload{TripIDs: []string{"trip-1", "trip-2"}})))
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		// Cached pages and the CDN copies are dropped
+		assert.Equal(t, 1, lists.invalidated)
+		assert.Empty(t, lists.lists)
+		select {
+		case keys := <-cdn.purged:
+			assert.Equal(t, []string{httpcache.DiscoverKey}, keys)
+		case <-time.After(time.Second):
+			t.Fatal("discover lists were not purged from the CDN")
+		}
+	})
+
+	t.Run("changed trips", func(t *testing.T) {
+		service, mock, lists, _ := newTestService(t)
+		mock.ExpectQuery(`LEFT JOIN trip_discovery d ON d.trip_id = t.id`).
+			WithArgs(sqlmock.AnyArg(), refreshBatchSize).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("trip-1").AddRow("trip-3"))
+		expectRebuild(mock, `{"trip-1","trip-3"}`)
+
+		require.NoError(t, service.refresh(ctx, refreshJob(t, refreshPayload{})))
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, 1, lists.invalidated)
+	})
+
+	t.Run("nothing changed", func(t *testing.T) {
+		service, mock, lists, _ := newTestService(t)
+		mock.ExpectQuery(`LEFT JOIN trip_discovery d ON d.trip_id = t.id`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		require.NoError(t, service.refresh(ctx, refreshJob(t, refreshPayload{})))
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Zero(t, lists.invalidated)
+	})
+
+	t.Run("failed rebuild keeps the cached lists", func(t *testing.T) {
+		service, mock, lists, _ := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`DELETE FROM trip_discovery`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO trip_discovery`).WillReturnError(errors.New("database error"))
+		mock.ExpectRollback()
+
+		assert.Error(t, service.refresh(ctx, refreshJob(t, refreshPayload{TripIDs: []string{"trip-1"}})))
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Zero(t, lists.invalidated)
+	})
+}
+
+func TestService_List(t *testing.T) {
+	ctx := context.Background()
+	columns := []string{"trip_id", "title", "owner_id", "trending_score"}
+
+	t.Run("unknown list", func(t *testing.T) {
+		service, _, _, _ := newTestService(t)
+
+		_, err := service.List(ctx, "hidden-gems", ListFilters{Limit: 20})
+		assert.ErrorIs(t, err, ErrUnknownList)
+	})
+
+	t.Run("filters and order", func(t *testing.T) {
+		service, mock, _, _ := newTestService(t)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM trip_discovery WHERE 1=1 AND activity_type = $1 AND $2 = ANY(tags) AND trending_score > 0 ORDER BY trending_score DESC, trip_id LIMIT $3 OFFSET $4`)).
+			WithArgs("hiking", "coast", 20, 40).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("trip-1", "Dipsea", "owner-1", 4.5))
+
+		cards, err := service.List(ctx, ListTrending, ListFilters{ActivityType: "hiking", Tag: "coast", Limit: 20, Offset: 40})
+		require.NoError(t, err)
+		require.Len(t, cards, 1)
+		assert.Equal(t, "Dipsea", cards[0].Title)
+		assert.Equal(t, 4.5, cards[0].TrendingScore)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("featured trips only", func(t *testing.T) {
+		service, mock, _, _ := newTestService(t)
+		mock.ExpectQuery(regexp.QuoteMeta(`WHERE 1=1 AND featured ORDER BY`)).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		cards, err := service.List(ctx, ListFeatured, ListFilters{Limit: 20})
+		require.NoError(t, err)
+		assert.Empty(t, cards)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("pages are served from the cache", func(t *testing.T) {
+		service, mock, lists, _ := newTestService(t)
+		mock.ExpectQuery(`SELECT \* FROM trip_discovery`).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("trip-1", "Dipsea", "owner-1", 4.5))
+
+		// Only the first request reads trip_discovery
+		for i := 0; i < 2; i++ {
+			cards, err := service.List(ctx, ListPopular, ListFilters{Limit: 20})
+			require.NoError(t, err)
+			require.Len(t, cards, 1)
+			assert.Equal(t, "trip-1", cards[0].TripID)
+		}
+
+		assert.Len(t, lists.lists, 1)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
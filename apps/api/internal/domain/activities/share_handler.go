@@ -100,7 +100,6 @@ func (h *Handler) GenerateShareLink(c *gin.Context) {
 // @Router /api/v1/activities/shared/{token} [get]
 func (h *Handler) GetSharedActivity(c *gin.Context) {
 	token := c.Param("token")
-	password := c.Query("password")
 
 	// In real implementation, look up share link by token
 	// Verify it's not expired, check password if required, increment view count
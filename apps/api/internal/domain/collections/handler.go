@@ -1,9 +1,17 @@
 package collections
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/internal/media"
 	"github.com/Oferzz/newMap/apps/api/pkg/response"
 )
 
@@ -17,6 +25,53 @@ func NewHandler(service *Service) *Handler {
 	}
 }
 
+// RegisterRoutes registers the collection routes
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *users.RouteMiddleware) {
+	// Public and unlisted collections can be viewed without signing in
+	router.GET("/collections/:id", mw.OptionalAuth, h.GetCollection)
+	router.GET("/collections/:id/export", mw.OptionalAuth, h.ExportCollection)
+	router.GET("/discover/collections", h.DiscoverCollections)
+
+	collections := router.Group("/collections", mw.RequireAuth)
+	{
+		// Collection CRUD
+		collections.POST("", h.CreateCollection)
+		collections.GET("", h.GetUserCollections)
+		collections.PUT("/:id", h.UpdateCollection)
+		collections.DELETE("/:id", h.DeleteCollection)
+		collections.POST("/:id/create-trip", mw.RequireSystemPermission(users.PermissionTripCreate), h.CreateTrip)
+		collections.POST("/import", mw.LimitUploadBody, media.ValidateFileUpload(media.DefaultUploadLimits(MaxImportSize+64*1024)), h.ImportCollection)
+
+		// Location management
+		collections.POST("/:id/locations", h.AddLocationToCollection)
+		collections.DELETE("/:id/locations/:locationId", h.RemoveLocationFromCollection)
+
+		// Collaborator management
+		collections.POST("/:id/collaborators", h.AddCollaborator)
+		collections.DELETE("/:id/collaborators/:userId", h.RemoveCollaborator)
+	}
+}
+
+// getUserID extracts the authenticated user's ID from the gin context
+func getUserID(c *gin.Context) (uuid.UUID, bool) {
+	userIDValue, exists := c.Get("userID")
+	if !exists {
+		return uuid.Nil, false
+	}
+
+	userID, ok := userIDValue.(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
 // POST /collections
 func (h *Handler) CreateCollection(c *gin.Context) {
 	var req CreateCollectionRequest
@@ -25,13 +80,13 @@ func (h *Handler) CreateCollection(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		response.Unauthorized(c, "User not authenticated")
 		return
 	}
 
-	collection, err := h.service.CreateCollection(c.Request.Context(), userID.(uuid.UUID), req)
+	collection, err := h.service.CreateCollection(c.Request.Context(), userID, req)
 	if err != nil {
 		response.InternalServerError(c, "Failed to create collection")
 		return
@@ -49,52 +104,130 @@ func (h *Handler) GetCollection(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
-	if !exists {
-		response.Unauthorized(c, "User not authenticated")
+	// Public and unlisted collections can be viewed without signing in
+	userID, _ := getUserID(c)
+
+	collection, err := h.service.GetCollection(c.Request.Context(), id, userID)
+	if err != nil {
+		if errors.Is(err, ErrCollectionNotFound) {
+			response.NotFound(c, "Collection not found")
+			return
+		}
+		response.InternalServerError(c, "Failed to get collection")
+		return
+	}
+
+	response.Success(c, collection)
+}
+
+// GET /collections/:id/export
+func (h *Handler) ExportCollection(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.BadRequest(c, "Invalid collection ID")
 		return
 	}
 
-	collection, err := h.service.GetCollection(c.Request.Context(), id, userID.(uuid.UUID))
+	// Public and unlisted collections can be exported without signing in
+	userID, _ := getUserID(c)
+
+	export, err := h.service.ExportCollection(c.Request.Context(), id, userID)
 	if err != nil {
-		if err == ErrCollectionNotFound {
+		if errors.Is(err, ErrCollectionNotFound) {
 			response.NotFound(c, "Collection not found")
 			return
 		}
-		if err == ErrUnauthorized {
-			response.Forbidden(c, "Access denied")
-			return
+		response.FromError(c, err, "Failed to export collection")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.Filename))
+	c.Data(http.StatusOK, GeoJSONContentType, export.Data)
+}
+
+// POST /collections/import
+func (h *Handler) ImportCollection(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		response.BadRequest(c, "No GeoJSON or KML file provided")
+		return
+	}
+	if header.Size > MaxImportSize {
+		response.BadRequest(c, "Imported files are limited to 10 MB")
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		response.BadRequest(c, "Failed to read file")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxImportSize))
+	if err != nil {
+		response.BadRequest(c, "Failed to read file")
+		return
+	}
+
+	name := strings.TrimSpace(c.PostForm("name"))
+	if len(name) > 255 {
+		response.BadRequest(c, "Name is limited to 255 characters")
+		return
+	}
+
+	result, err := h.service.ImportCollection(c.Request.Context(), userID, header.Filename, data, name)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidImport), errors.Is(err, ErrImportTooLarge), errors.Is(err, ErrEmptyImport):
+			response.BadRequest(c, err.Error())
+		default:
+			response.FromError(c, err, "Failed to import collection")
 		}
-		response.InternalServerError(c, "Failed to get collection")
 		return
 	}
 
-	response.Success(c, collection)
+	response.Created(c, result)
 }
 
 // GET /collections
 func (h *Handler) GetUserCollections(c *gin.Context) {
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		response.Unauthorized(c, "User not authenticated")
 		return
 	}
 
-	var params GetCollectionsParams
-	if err := c.ShouldBindQuery(&params); err != nil {
-		response.BadRequest(c, "Invalid query parameters")
+	params, ok := bindPage(c)
+	if !ok {
 		return
 	}
 
-	// Set defaults
-	if params.Page <= 0 {
-		params.Page = 1
+	collections, total, err := h.service.GetUserCollections(c.Request.Context(), userID, params)
+	if err != nil {
+		response.InternalServerError(c, "Failed to get collections")
+		return
 	}
-	if params.Limit <= 0 {
-		params.Limit = 20
+
+	meta := response.NewMeta(params.Page, params.Limit, int64(total))
+	response.SuccessWithMeta(c, collections, meta)
+}
+
+// GET /discover/collections
+func (h *Handler) DiscoverCollections(c *gin.Context) {
+	params, ok := bindPage(c)
+	if !ok {
+		return
 	}
 
-	collections, total, err := h.service.GetUserCollections(c.Request.Context(), userID.(uuid.UUID), params)
+	collections, total, err := h.service.DiscoverCollections(c.Request.Context(), params)
 	if err != nil {
 		response.InternalServerError(c, "Failed to get collections")
 		return
@@ -104,6 +237,26 @@ func (h *Handler) GetUserCollections(c *gin.Context) {
 	response.SuccessWithMeta(c, collections, meta)
 }
 
+// bindPage reads the page and limit of a list, answering the request when
+// they are malformed
+func bindPage(c *gin.Context) (GetCollectionsParams, bool) {
+	var params GetCollectionsParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		response.BadRequest(c, "Invalid query parameters")
+		return params, false
+	}
+
+	// Set defaults
+	if params.Page <= 0 {
+		params.Page = 1
+	}
+	if params.Limit <= 0 || params.Limit > 100 {
+		params.Limit = 20
+	}
+
+	return params, true
+}
+
 // PUT /collections/:id
 func (h *Handler) UpdateCollection(c *gin.Context) {
 	idStr := c.Param("id")
@@ -119,23 +272,23 @@ func (h *Handler) UpdateCollection(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		response.Unauthorized(c, "User not authenticated")
 		return
 	}
 
-	collection, err := h.service.UpdateCollection(c.Request.Context(), id, userID.(uuid.UUID), req)
+	collection, err := h.service.UpdateCollection(c.Request.Context(), id, userID, req)
 	if err != nil {
-		if err == ErrCollectionNotFound {
+		if errors.Is(err, ErrCollectionNotFound) {
 			response.NotFound(c, "Collection not found")
 			return
 		}
-		if err == ErrUnauthorized {
+		if errors.Is(err, ErrUnauthorized) {
 			response.Forbidden(c, "Access denied")
 			return
 		}
-		response.InternalServerError(c, "Failed to update collection")
+		response.FromError(c, err, "Failed to update collection")
 		return
 	}
 
@@ -151,23 +304,23 @@ func (h *Handler) DeleteCollection(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		response.Unauthorized(c, "User not authenticated")
 		return
 	}
 
-	err = h.service.DeleteCollection(c.Request.Context(), id, userID.(uuid.UUID))
+	err = h.service.DeleteCollection(c.Request.Context(), id, userID)
 	if err != nil {
-		if err == ErrCollectionNotFound {
+		if errors.Is(err, ErrCollectionNotFound) {
 			response.NotFound(c, "Collection not found")
 			return
 		}
-		if err == ErrUnauthorized {
+		if errors.Is(err, ErrUnauthorized) {
 			response.Forbidden(c, "Access denied")
 			return
 		}
-		response.InternalServerError(c, "Failed to delete collection")
+		response.FromError(c, err, "Failed to delete collection")
 		return
 	}
 
@@ -189,23 +342,23 @@ func (h *Handler) AddLocationToCollection(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		response.Unauthorized(c, "User not authenticated")
 		return
 	}
 
-	location, err := h.service.AddLocationToCollection(c.Request.Context(), id, userID.(uuid.UUID), req)
+	location, err := h.service.AddLocationToCollection(c.Request.Context(), id, userID, req)
 	if err != nil {
-		if err == ErrCollectionNotFound {
+		if errors.Is(err, ErrCollectionNotFound) {
 			response.NotFound(c, "Collection not found")
 			return
 		}
-		if err == ErrUnauthorized {
+		if errors.Is(err, ErrUnauthorized) {
 			response.Forbidden(c, "Access denied")
 			return
 		}
-		response.InternalServerError(c, "Failed to add location")
+		response.FromError(c, err, "Failed to add location")
 		return
 	}
 
@@ -228,23 +381,23 @@ func (h *Handler) RemoveLocationFromCollection(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		response.Unauthorized(c, "User not authenticated")
 		return
 	}
 
-	err = h.service.RemoveLocationFromCollection(c.Request.Context(), id, locationId, userID.(uuid.UUID))
+	err = h.service.RemoveLocationFromCollection(c.Request.Context(), id, locationId, userID)
 	if err != nil {
-		if err == ErrCollectionNotFound {
+		if errors.Is(err, ErrCollectionNotFound) {
 			response.NotFound(c, "Collection not found")
 			return
 		}
-		if err == ErrUnauthorized {
+		if errors.Is(err, ErrUnauthorized) {
 			response.Forbidden(c, "Access denied")
 			return
 		}
-		response.InternalServerError(c, "Failed to remove location")
+		response.FromError(c, err, "Failed to remove location")
 		return
 	}
 
@@ -276,23 +429,23 @@ func (h *Handler) AddCollaborator(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		response.Unauthorized(c, "User not authenticated")
 		return
 	}
 
-	err = h.service.AddCollaborator(c.Request.Context(), id, targetUserID, req.Role, userID.(uuid.UUID))
+	err = h.service.AddCollaborator(c.Request.Context(), id, targetUserID, req.Role, userID)
 	if err != nil {
-		if err == ErrCollectionNotFound {
+		if errors.Is(err, ErrCollectionNotFound) {
 			response.NotFound(c, "Collection not found")
 			return
 		}
-		if err == ErrUnauthorized {
+		if errors.Is(err, ErrUnauthorized) {
 			response.Forbidden(c, "Access denied")
 			return
 		}
-		response.InternalServerError(c, "Failed to add collaborator")
+		response.FromError(c, err, "Failed to add collaborator")
 		return
 	}
 
@@ -315,25 +468,71 @@ func (h *Handler) RemoveCollaborator(c *gin.Context) {
 		return
 	}
 
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		response.Unauthorized(c, "User not authenticated")
 		return
 	}
 
-	err = h.service.RemoveCollaborator(c.Request.Context(), id, targetUserID, userID.(uuid.UUID))
+	err = h.service.RemoveCollaborator(c.Request.Context(), id, targetUserID, userID)
 	if err != nil {
-		if err == ErrCollectionNotFound {
+		if errors.Is(err, ErrCollectionNotFound) {
 			response.NotFound(c, "Collection not found")
 			return
 		}
-		if err == ErrUnauthorized {
+		if errors.Is(err, ErrUnauthorized) {
 			response.Forbidden(c, "Access denied")
 			return
 		}
-		response.InternalServerError(c, "Failed to remove collaborator")
+		response.FromError(c, err, "Failed to remove collaborator")
 		return
 	}
 
 	response.Success(c, gin.H{"message": "Collaborator removed successfully"})
-}
\ No newline at end of file
+}
+
+// POST /collections/:id/create-trip
+func (h *Handler) CreateTrip(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.BadRequest(c, "Invalid collection ID")
+		return
+	}
+
+	var req CreateTripRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.BadRequest(c, "Invalid request body")
+		return
+	}
+
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	trip, err := h.service.CreateTrip(c.Request.Context(), id, userID, req)
+	if err != nil {
+		if errors.Is(err, ErrCollectionNotFound) {
+			response.NotFound(c, "Collection not found")
+			return
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			response.Forbidden(c, "Access denied")
+			return
+		}
+		if errors.Is(err, ErrEmptyCollection) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		if errors.Is(err, ErrLocationNotFound) {
+			response.UnprocessableEntity(c, "One of the locations is not in the collection")
+			return
+		}
+		response.FromError(c, err, "Failed to create trip")
+		return
+	}
+
+	response.Created(c, trip)
+}
@@ -0,0 +1,377 @@
+package collections
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Imports are limited so that one file cannot fill a collection with
+// thousands of places
+const (
+	MaxImportSize      = 10 << 20
+	MaxImportLocations = 500
+)
+
+var (
+	ErrInvalidImport  = errors.New("file is not valid GeoJSON or KML")
+	ErrImportTooLarge = fmt.Errorf("file has more than %d locations", MaxImportLocations)
+	ErrEmptyImport    = errors.New("file has no points to import")
+)
+
+// ImportResult is a collection made from an imported file. Features other
+// than points, such as tracks and areas, are skipped.
+type ImportResult struct {
+	Collection *Collection `json:"collection"`
+	Imported   int         `json:"imported"`
+	Skipped    int         `json:"skipped"`
+}
+
+// GeoJSONContentType is the media type collections are exported as
+const GeoJSONContentType = "application/geo+json"
+
+// CollectionExport is a collection written out as GeoJSON, ready to
+// download
+type CollectionExport struct {
+	Data     []byte
+	Filename string
+}
+
+var slugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+// exportFilename is the name an export of the collection is downloaded as,
+// from its name
+func exportFilename(collection *Collection) string {
+	slug := strings.Trim(slugSeparators.ReplaceAllString(strings.ToLower(collection.Name), "-"), "-")
+	if slug == "" {
+		slug = "collection"
+	}
+	return slug + ".geojson"
+}
+
+// importedFile is what is read from a GeoJSON or KML file
+type importedFile struct {
+	Name      string
+	Locations []CollectionLocation
+	Skipped   int
+}
+
+// ExportGeoJSON renders a collection as a GeoJSON FeatureCollection, a
+// Point feature per location in the order they were added
+func ExportGeoJSON(collection *Collection) map[string]interface{} {
+	locations, _ := pickLocations(collection.Locations, nil)
+
+	features := make([]map[string]interface{}, 0, len(locations))
+	for _, location := range locations {
+		properties := map[string]interface{}{
+			"id":       location.ID,
+			"added_at": location.AddedAt.Format(time.RFC3339),
+		}
+		if location.Name != nil {
+			properties["name"] = *location.Name
+		}
+		if location.Notes != nil {
+			properties["notes"] = *location.Notes
+		}
+		if location.PlaceID != nil {
+			properties["place_id"] = *location.PlaceID
+		}
+
+		features = append(features, map[string]interface{}{
+			"type": "Feature",
+			"geometry": map[string]interface{}{
+				"type":        "Point",
+				"coordinates": []float64{location.Longitude, location.Latitude},
+			},
+			"properties": properties,
+		})
+	}
+
+	properties := map[string]interface{}{
+		"id":   collection.ID,
+		"name": collection.Name,
+	}
+	if collection.Description != nil {
+		properties["description"] = *collection.Description
+	}
+
+	return map[string]interface{}{
+		"type":       "FeatureCollection",
+		"properties": properties,
+		"features":   features,
+	}
+}
+
+// parseImport reads the points of a GeoJSON or KML file. The format is told
+// by the file's extension, or else by its content.
+func parseImport(filename string, data []byte) (*importedFile, error) {
+	var file *importedFile
+	var err error
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".geojson", ".json":
+		file, err = parseGeoJSON(data)
+	case ".kml":
+		file, err = parseKML(data)
+	default:
+		trimmed := bytes.TrimSpace(data)
+		switch {
+		case bytes.HasPrefix(trimmed, []byte("{")):
+			file, err = parseGeoJSON(data)
+		case bytes.HasPrefix(trimmed, []byte("<")):
+			file, err = parseKML(data)
+		default:
+			return nil, ErrInvalidImport
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(file.Locations) == 0 {
+		return nil, ErrEmptyImport
+	}
+	return file, nil
+}
+
+// geoJSONObject is any GeoJSON object; which fields are set depends on its
+// type
+type geoJSONObject struct {
+	Type        string                 `json:"type"`
+	Features    []geoJSONObject        `json:"features"`
+	Geometry    *geoJSONObject         `json:"geometry"`
+	Geometries  []geoJSONObject        `json:"geometries"`
+	Coordinates json.RawMessage        `json:"coordinates"`
+	Properties  map[string]interface{} `json:"properties"`
+}
+
+func parseGeoJSON(data []byte) (*importedFile, error) {
+	var root geoJSONObject
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	file := &importedFile{Name: stringProperty(root.Properties, "name", "title")}
+	switch root.Type {
+	case "FeatureCollection":
+		for _, feature := range root.Features {
+			if err := file.addFeature(feature); err != nil {
+				return nil, err
+			}
+		}
+	case "Feature":
+		if err := file.addFeature(root); err != nil {
+			return nil, err
+		}
+	case "Point", "MultiPoint", "LineString", "MultiLineString", "Polygon", "MultiPolygon", "GeometryCollection":
+		if err := file.addGeometry(&root, nil, nil); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidImport
+	}
+
+	return file, nil
+}
+
+func (f *importedFile) addFeature(feature geoJSONObject) error {
+	if feature.Type != "Feature" {
+		return ErrInvalidImport
+	}
+	if feature.Geometry == nil {
+		f.Skipped++
+		return nil
+	}
+
+	name := optionalString(stringProperty(feature.Properties, "name", "title"))
+	notes := optionalString(stringProperty(feature.Properties, "notes", "description"))
+	return f.addGeometry(feature.Geometry, name, notes)
+}
+
+func (f *importedFile) addGeometry(geometry *geoJSONObject, name, notes *string) error {
+	var points [][]float64
+	switch geometry.Type {
+	case "Point":
+		var point []float64
+		if err := json.Unmarshal(geometry.Coordinates, &point); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		points = [][]float64{point}
+	case "MultiPoint":
+		if err := json.Unmarshal(geometry.Coordinates, &points); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+	case "GeometryCollection":
+		for i := range geometry.Geometries {
+			if err := f.addGeometry(&geometry.Geometries[i], name, notes); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		f.Skipped++
+		return nil
+	}
+
+	for _, point := range points {
+		if err := f.addPoint(point, name, notes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addPoint adds a [longitude, latitude] position, failing on one off the
+// globe
+func (f *importedFile) addPoint(point []float64, name, notes *string) error {
+	if len(point) < 2 || point[0] < -180 || point[0] > 180 || point[1] < -90 || point[1] > 90 {
+		return fmt.Errorf("%w: invalid coordinates %v", ErrInvalidImport, point)
+	}
+	if len(f.Locations) == MaxImportLocations {
+		return ErrImportTooLarge
+	}
+
+	f.Locations = append(f.Locations, CollectionLocation{
+		Name:      truncate(name, 255),
+		Notes:     truncate(notes, 500),
+		Longitude: point[0],
+		Latitude:  point[1],
+	})
+	return nil
+}
+
+// kmlContainer is a KML Document or Folder. Placemarks may be nested in
+// folders to any depth.
+type kmlContainer struct {
+	Name       string         `xml:"name"`
+	Documents  []kmlContainer `xml:"Document"`
+	Folders    []kmlContainer `xml:"Folder"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name          string       `xml:"name"`
+	Description   string       `xml:"description"`
+	Point         *kmlPoint    `xml:"Point"`
+	MultiGeometry *kmlGeometry `xml:"MultiGeometry"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlGeometry struct {
+	Points []kmlPoint `xml:"Point"`
+}
+
+func parseKML(data []byte) (*importedFile, error) {
+	var root struct {
+		XMLName xml.Name
+		kmlContainer
+	}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	if root.XMLName.Local != "kml" {
+		return nil, ErrInvalidImport
+	}
+
+	file := &importedFile{}
+	if len(root.Documents) > 0 {
+		file.Name = strings.TrimSpace(root.Documents[0].Name)
+	}
+	if err := file.addContainer(root.kmlContainer); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (f *importedFile) addContainer(container kmlContainer) error {
+	for _, placemark := range container.Placemarks {
+		var points []kmlPoint
+		if placemark.Point != nil {
+			points = append(points, *placemark.Point)
+		}
+		if placemark.MultiGeometry != nil {
+			points = append(points, placemark.MultiGeometry.Points...)
+		}
+		if len(points) == 0 {
+			f.Skipped++
+			continue
+		}
+
+		name := optionalString(strings.TrimSpace(placemark.Name))
+		notes := optionalString(strings.TrimSpace(placemark.Description))
+		for _, point := range points {
+			position, err := kmlCoordinates(point.Coordinates)
+			if err != nil {
+				return err
+			}
+			if err := f.addPoint(position, name, notes); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, child := range append(container.Documents, container.Folders...) {
+		if err := f.addContainer(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kmlCoordinates reads a KML position, "longitude,latitude[,altitude]"
+func kmlCoordinates(coordinates string) ([]float64, error) {
+	parts := strings.Split(strings.TrimSpace(coordinates), ",")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("%w: invalid coordinates %q", ErrInvalidImport, coordinates)
+	}
+
+	position := make([]float64, 2)
+	for i := range position {
+		value, err := strconv.ParseFloat(strings.TrimSpace(parts[i]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid coordinates %q", ErrInvalidImport, coordinates)
+		}
+		position[i] = value
+	}
+	return position, nil
+}
+
+// stringProperty is the first of the keys that is a non-blank string
+func stringProperty(properties map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := properties[key].(string); ok && strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// truncate shortens a string to at most max runes
+func truncate(value *string, max int) *string {
+	if value == nil {
+		return nil
+	}
+	runes := []rune(*value)
+	if len(runes) <= max {
+		return value
+	}
+	short := string(runes[:max])
+	return &short
+}
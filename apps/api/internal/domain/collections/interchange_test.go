@@ -0,0 +1,146 @@
+package collections
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImport_GeoJSON(t *testing.T) {
+	data := `{
+		"type": "FeatureCollection",
+		"properties": {"name": "Oregon climbs"},
+		"features": [
+			{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-121.14, 44.37, 900]},
+			 "properties": {"name": "Smith Rock", "description": "Park at the day use lot"}},
+			{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[-121.1, 44.3], [-121.2, 44.4]]},
+			 "properties": {"name": "Approach"}},
+			{"type": "Feature", "geometry": {"type": "MultiPoint", "coordinates": [[-122.0, 45.0], [-122.1, 45.1]]},
+			 "properties": {"title": "Crags"}},
+			{"type": "Feature", "geometry": null, "properties": {}}
+		]
+	}`
+
+	file, err := parseImport("climbs.geojson", []byte(data))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Oregon climbs", file.Name)
+	assert.Equal(t, 2, file.Skipped)
+	require.Len(t, file.Locations, 3)
+	assert.Equal(t, "Smith Rock", *file.Locations[0].Name)
+	assert.Equal(t, "Park at the day use lot", *file.Locations[0].Notes)
+	assert.Equal(t, -121.14, file.Locations[0].Longitude)
+	assert.Equal(t, 44.37, file.Locations[0].Latitude)
+	assert.Equal(t, "Crags", *file.Locations[2].Name)
+	assert.Nil(t, file.Locations[2].Notes)
+}
+
+func TestParseImport_KML(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+  <Document>
+    <name>Lake Tahoe</name>
+    <Placemark>
+      <name>Emerald Bay</name>
+      <description>Swim at the boat-in camp</description>
+      <Point><coordinates> -120.10,38.95,1900 </coordinates></Point>
+    </Placemark>
+    <Folder>
+      <name>Trailheads</name>
+      <Placemark>
+        <name>Eagle Falls</name>
+        <Point><coordinates>-120.11,38.95</coordinates></Point>
+      </Placemark>
+      <Placemark>
+        <name>Rubicon Trail</name>
+        <LineString><coordinates>-120.1,38.9 -120.2,39.0</coordinates></LineString>
+      </Placemark>
+    </Folder>
+  </Document>
+</kml>`
+
+	// Told apart by its content when the extension says nothing
+	file, err := parseImport("export.xml", []byte(data))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Lake Tahoe", file.Name)
+	assert.Equal(t, 1, file.Skipped)
+	require.Len(t, file.Locations, 2)
+	assert.Equal(t, "Emerald Bay", *file.Locations[0].Name)
+	assert.Equal(t, "Swim at the boat-in camp", *file.Locations[0].Notes)
+	assert.Equal(t, -120.10, file.Locations[0].Longitude)
+	assert.Equal(t, 38.95, file.Locations[0].Latitude)
+	assert.Equal(t, "Eagle Falls", *file.Locations[1].Name)
+}
+
+func TestParseImport_Errors(t *testing.T) {
+	point := func(lon, lat float64) string {
+		return fmt.Sprintf(`{"type": "Feature", "geometry": {"type": "Point", "coordinates": [%g, %g]}}`, lon, lat)
+	}
+	many := make([]string, MaxImportLocations+1)
+	for i := range many {
+		many[i] = point(10, 45)
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		data     string
+		err      error
+	}{
+		{"not a map file", "notes.txt", "Smith Rock", ErrInvalidImport},
+		{"malformed GeoJSON", "a.geojson", `{"type": "FeatureCollection", "features": [`, ErrInvalidImport},
+		{"malformed KML", "a.kml", `<kml><Document>`, ErrInvalidImport},
+		{"not KML", "a.kml", `<gpx></gpx>`, ErrInvalidImport},
+		{"off the globe", "a.geojson", `{"type": "FeatureCollection", "features": [` + point(200, 45) + `]}`, ErrInvalidImport},
+		{"no points", "a.geojson", `{"type": "LineString", "coordinates": [[10, 45], [11, 46]]}`, ErrEmptyImport},
+		{"too many points", "a.geojson", `{"type": "FeatureCollection", "features": [` + strings.Join(many, ",") + `]}`, ErrImportTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseImport(tt.filename, []byte(tt.data))
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestExportGeoJSON(t *testing.T) {
+	name, notes := "Smith Rock", "Park at the day use lot"
+	placeID := uuid.New()
+	now := time.Now()
+	first := CollectionLocation{ID: uuid.New(), Name: &name, Notes: &notes, Longitude: -121.14, Latitude: 44.37, PlaceID: &placeID, AddedAt: now.Add(-time.Hour)}
+	second := CollectionLocation{ID: uuid.New(), Longitude: -122, Latitude: 45, AddedAt: now}
+	collection := &Collection{ID: uuid.New(), Name: "Oregon climbs", Locations: []CollectionLocation{second, first}}
+
+	export := ExportGeoJSON(collection)
+	assert.Equal(t, "FeatureCollection", export["type"])
+	assert.Equal(t, "Oregon climbs", export["properties"].(map[string]interface{})["name"])
+
+	features := export["features"].([]map[string]interface{})
+	require.Len(t, features, 2)
+	assert.Equal(t, []float64{-121.14, 44.37}, features[0]["geometry"].(map[string]interface{})["coordinates"])
+	properties := features[0]["properties"].(map[string]interface{})
+	assert.Equal(t, "Smith Rock", properties["name"])
+	assert.Equal(t, notes, properties["notes"])
+	assert.Equal(t, placeID, properties["place_id"])
+	assert.NotContains(t, features[1]["properties"], "name")
+
+	// An export imports back as the same points
+	data, err := json.Marshal(export)
+	require.NoError(t, err)
+	file, err := parseImport("oregon-climbs.geojson", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Oregon climbs", file.Name)
+	require.Len(t, file.Locations, 2)
+	assert.Equal(t, "Smith Rock", *file.Locations[0].Name)
+	assert.Equal(t, -122.0, file.Locations[1].Longitude)
+
+	assert.Equal(t, "oregon-climbs.geojson", exportFilename(collection))
+}
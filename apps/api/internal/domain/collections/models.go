@@ -6,6 +6,14 @@ import (
 	"github.com/google/uuid"
 )
 
+// Collection privacy levels. Unlisted collections can be viewed by anyone
+// with their ID but, unlike public ones, are left out of discovery and search.
+const (
+	PrivacyPrivate  = "private"
+	PrivacyUnlisted = "unlisted"
+	PrivacyPublic   = "public"
+)
+
 type Collection struct {
 	ID          uuid.UUID         `json:"id" db:"id"`
 	Name        string            `json:"name" db:"name"`
@@ -17,35 +25,67 @@ type Collection struct {
 	UpdatedAt   time.Time         `json:"updated_at" db:"updated_at"`
 }
 
+// VisibleTo reports whether the user may view the collection without being
+// one of its collaborators
+func (c *Collection) VisibleTo(userID uuid.UUID) bool {
+	return c.UserID == userID || c.Privacy == PrivacyPublic || c.Privacy == PrivacyUnlisted
+}
+
+// Discoverable reports whether the collection is listed in discovery and
+// search
+func (c *Collection) Discoverable() bool {
+	return c.Privacy == PrivacyPublic
+}
+
 type CollectionLocation struct {
 	ID           uuid.UUID `json:"id" db:"id"`
 	CollectionID uuid.UUID `json:"collection_id" db:"collection_id"`
 	Name         *string   `json:"name,omitempty" db:"name"`
+	Notes        *string   `json:"notes,omitempty" db:"notes"`
 	Latitude     float64   `json:"latitude" db:"latitude"`
 	Longitude    float64   `json:"longitude" db:"longitude"`
+	PlaceID      *uuid.UUID `json:"place_id,omitempty" db:"place_id"` // The place it was imported as, if any
 	AddedAt      time.Time `json:"added_at" db:"added_at"`
 }
 
 type CreateCollectionRequest struct {
 	Name        string  `json:"name" validate:"required,min=1,max=255"`
 	Description *string `json:"description,omitempty" validate:"omitempty,max=1000"`
-	Privacy     string  `json:"privacy" validate:"required,oneof=public private friends"`
+	Privacy     string  `json:"privacy" binding:"omitempty,oneof=private unlisted public"`
 }
 
 type UpdateCollectionRequest struct {
 	Name        *string `json:"name,omitempty" validate:"omitempty,min=1,max=255"`
 	Description *string `json:"description,omitempty" validate:"omitempty,max=1000"`
-	Privacy     *string `json:"privacy,omitempty" validate:"omitempty,oneof=public private friends"`
+	Privacy     *string `json:"privacy,omitempty" binding:"omitempty,oneof=private unlisted public"`
 }
 
 type AddLocationRequest struct {
 	Name      *string `json:"name,omitempty" validate:"omitempty,min=1,max=255"`
+	Notes     *string `json:"notes,omitempty" validate:"omitempty,max=500"`
 	Latitude  float64 `json:"latitude" validate:"required,latitude"`
 	Longitude float64 `json:"longitude" validate:"required,longitude"`
 }
 
+// Orders the locations of a collection can become a trip's stops in
+const (
+	TripOrderManual    = "manual"    // As listed, or else in the order they were added
+	TripOrderProximity = "proximity" // Each stop the nearest not yet visited
+)
+
+// CreateTripRequest turns a collection into a trip. LocationIDs picks the
+// locations to include and their order; all of them are included when it
+// is empty.
+type CreateTripRequest struct {
+	Title       *string     `json:"title,omitempty" binding:"omitempty,min=3,max=255"`
+	Description *string     `json:"description,omitempty" binding:"omitempty,max=1000"`
+	Privacy     string      `json:"privacy" binding:"omitempty,oneof=public friends private invite_only"`
+	Order       string      `json:"order" binding:"omitempty,oneof=manual proximity"`
+	LocationIDs []uuid.UUID `json:"location_ids" binding:"omitempty,max=100"`
+}
+
 type GetCollectionsParams struct {
-	Page   int    `query:"page" validate:"omitempty,min=1"`
-	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
-	UserID string `query:"user_id" validate:"omitempty,uuid"`
+	Page   int    `form:"page" validate:"omitempty,min=1"`
+	Limit  int    `form:"limit" validate:"omitempty,min=1,max=100"`
+	UserID string `form:"user_id" validate:"omitempty,uuid"`
 }
\ No newline at end of file
@@ -0,0 +1,32 @@
+package collections
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollection_Privacy(t *testing.T) {
+	owner, stranger := uuid.New(), uuid.New()
+
+	tests := []struct {
+		privacy      string
+		visible      bool
+		discoverable bool
+	}{
+		{PrivacyPrivate, false, false},
+		{PrivacyUnlisted, true, false},
+		{PrivacyPublic, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.privacy, func(t *testing.T) {
+			collection := &Collection{UserID: owner, Privacy: tt.privacy}
+			assert.True(t, collection.VisibleTo(owner))
+			assert.Equal(t, tt.visible, collection.VisibleTo(stranger))
+			assert.Equal(t, tt.visible, collection.VisibleTo(uuid.Nil))
+			assert.Equal(t, tt.discoverable, collection.Discoverable())
+		})
+	}
+}
@@ -11,8 +11,11 @@ type Repository interface {
 	Create(ctx context.Context, collection *Collection) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Collection, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID, params GetCollectionsParams) ([]Collection, int, error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]Collection, error)
+	ListPublic(ctx context.Context, params GetCollectionsParams) ([]Collection, int, error)
 	Update(ctx context.Context, id uuid.UUID, updates UpdateCollectionRequest) (*Collection, error)
 	Delete(ctx context.Context, id uuid.UUID) error
+	Import(ctx context.Context, collection *Collection) error
 
 	// Collection locations
 	AddLocation(ctx context.Context, collectionID uuid.UUID, location *CollectionLocation) error
@@ -3,11 +3,15 @@ package collections
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/Oferzz/newMap/apps/api/pkg/repoerr"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type PostgresRepository struct {
@@ -51,8 +55,8 @@ func (r *PostgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*Collec
 
 	err := r.db.GetContext(ctx, collection, query, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("collection %s: %w", id, ErrCollectionNotFound)
 		}
 		return nil, err
 	}
@@ -117,6 +121,64 @@ func (r *PostgresRepository) GetByUserID(ctx context.Context, userID uuid.UUID,
 	return collections, total, nil
 }
 
+// ListPublic returns a page of public collections, most recently updated
+// first
+func (r *PostgresRepository) ListPublic(ctx context.Context, params GetCollectionsParams) ([]Collection, int, error) {
+	offset := (params.Page - 1) * params.Limit
+
+	var total int
+	err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM collections WHERE privacy = $1`, PrivacyPublic)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, name, description, user_id, privacy, created_at, updated_at
+		FROM collections
+		WHERE privacy = $1
+		ORDER BY updated_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	collections := []Collection{}
+	err = r.db.SelectContext(ctx, &collections, query, PrivacyPublic, params.Limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range collections {
+		locations, err := r.GetLocations(ctx, collections[i].ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		collections[i].Locations = locations
+	}
+
+	return collections, total, nil
+}
+
+// GetByIDs returns the collections that exist among the IDs, without their
+// locations
+func (r *PostgresRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]Collection, error) {
+	query := `
+		SELECT id, name, description, user_id, privacy, created_at, updated_at
+		FROM collections
+		WHERE id = ANY($1::uuid[])
+	`
+
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = id.String()
+	}
+
+	var collections []Collection
+	if err := r.db.SelectContext(ctx, &collections, query, pq.Array(strs)); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
+
 func (r *PostgresRepository) Update(ctx context.Context, id uuid.UUID, updates UpdateCollectionRequest) (*Collection, error) {
 	setParts := []string{}
 	args := []interface{}{}
@@ -154,7 +216,7 @@ func (r *PostgresRepository) Update(ctx context.Context, id uuid.UUID, updates U
 		UPDATE collections
 		SET %s
 		WHERE id = $%d
-	`, fmt.Sprintf("%s", setParts), argIndex)
+	`, strings.Join(setParts, ", "), argIndex)
 
 	_, err := r.db.ExecContext(ctx, query, args...)
 	if err != nil {
@@ -176,24 +238,97 @@ func (r *PostgresRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+// Import creates a collection with its locations, each saved as a private
+// place of the collection's owner, all or nothing. The locations are added
+// a millisecond apart so that they keep the order they are listed in.
+func (r *PostgresRepository) Import(ctx context.Context, collection *Collection) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	collection.ID = uuid.New()
+	collection.CreatedAt = time.Now()
+	collection.UpdatedAt = collection.CreatedAt
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO collections (id, name, description, user_id, privacy, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, collection.ID, collection.Name, collection.Description, collection.UserID, collection.Privacy,
+		collection.CreatedAt, collection.UpdatedAt)
+	if err != nil {
+		return repoerr.Classify(err, nil, nil, ErrUserNotFound)
+	}
+
+	placeQuery := `
+		INSERT INTO places (name, description, type, location, created_by, privacy, status)
+		VALUES ($1, $2, 'poi', ST_SetSRID(ST_MakePoint($3, $4), 4326)::geography, $5, 'private', 'active')
+		RETURNING id
+	`
+	locationQuery := `
+		INSERT INTO collection_locations (id, collection_id, name, notes, latitude, longitude, place_id, added_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	for i := range collection.Locations {
+		location := &collection.Locations[i]
+
+		name := "Imported location"
+		if location.Name != nil {
+			name = *location.Name
+		}
+		var placeID uuid.UUID
+		err := tx.QueryRowContext(ctx, placeQuery,
+			name, location.Notes, location.Longitude, location.Latitude, collection.UserID,
+		).Scan(&placeID)
+		if err != nil {
+			return fmt.Errorf("failed to create place: %w", err)
+		}
+
+		location.ID = uuid.New()
+		location.CollectionID = collection.ID
+		location.PlaceID = &placeID
+		location.AddedAt = collection.CreatedAt.Add(time.Duration(i) * time.Millisecond)
+
+		_, err = tx.ExecContext(ctx, locationQuery,
+			location.ID,
+			location.CollectionID,
+			location.Name,
+			location.Notes,
+			location.Latitude,
+			location.Longitude,
+			location.PlaceID,
+			location.AddedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to add location: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
 func (r *PostgresRepository) AddLocation(ctx context.Context, collectionID uuid.UUID, location *CollectionLocation) error {
 	location.ID = uuid.New()
 	location.CollectionID = collectionID
 	location.AddedAt = time.Now()
 
 	query := `
-		INSERT INTO collection_locations (id, collection_id, name, latitude, longitude, added_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO collection_locations (id, collection_id, name, notes, latitude, longitude, added_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
 
 	_, err := r.db.ExecContext(ctx, query,
 		location.ID,
 		location.CollectionID,
 		location.Name,
+		location.Notes,
 		location.Latitude,
 		location.Longitude,
 		location.AddedAt,
 	)
+	err = repoerr.Classify(err, nil, nil, ErrCollectionNotFound)
 
 	if err == nil {
 		// Update collection's updated_at
@@ -204,22 +339,31 @@ func (r *PostgresRepository) AddLocation(ctx context.Context, collectionID uuid.
 }
 
 func (r *PostgresRepository) RemoveLocation(ctx context.Context, collectionID uuid.UUID, locationID uuid.UUID) error {
-	_, err := r.db.ExecContext(ctx, 
+	result, err := r.db.ExecContext(ctx, 
 		"DELETE FROM collection_locations WHERE id = $1 AND collection_id = $2", 
 		locationID, collectionID)
+	if err != nil {
+		return err
+	}
 
-	if err == nil {
-		// Update collection's updated_at
-		_, _ = r.db.ExecContext(ctx, "UPDATE collections SET updated_at = $1 WHERE id = $2", time.Now(), collectionID)
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("location %s in collection %s: %w", locationID, collectionID, ErrLocationNotFound)
 	}
 
-	return err
+	// Update collection's updated_at
+	_, _ = r.db.ExecContext(ctx, "UPDATE collections SET updated_at = $1 WHERE id = $2", time.Now(), collectionID)
+
+	return nil
 }
 
 func (r *PostgresRepository) GetLocations(ctx context.Context, collectionID uuid.UUID) ([]CollectionLocation, error) {
 	var locations []CollectionLocation
 	query := `
-		SELECT id, collection_id, name, latitude, longitude, added_at
+		SELECT id, collection_id, name, notes, latitude, longitude, place_id, added_at
 		FROM collection_locations
 		WHERE collection_id = $1
 		ORDER BY added_at DESC
@@ -241,7 +385,7 @@ func (r *PostgresRepository) AddCollaborator(ctx context.Context, collectionID u
 	`
 
 	_, err := r.db.ExecContext(ctx, query, collectionID, userID, role, time.Now())
-	return err
+	return repoerr.Classify(err, nil, nil, ErrUserNotFound)
 }
 
 func (r *PostgresRepository) RemoveCollaborator(ctx context.Context, collectionID uuid.UUID, userID uuid.UUID) error {
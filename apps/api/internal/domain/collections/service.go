@@ -2,25 +2,51 @@ package collections
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
 
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/pkg/repoerr"
 	"github.com/google/uuid"
 )
 
 var (
-	ErrCollectionNotFound   = errors.New("collection not found")
+	ErrCollectionNotFound   = repoerr.NotFound("collection not found")
 	ErrUnauthorized        = errors.New("unauthorized")
-	ErrLocationNotFound    = errors.New("location not found")
+	ErrLocationNotFound    = repoerr.NotFound("location not found")
+	ErrUserNotFound        = repoerr.ForeignKey("user not found")
 	ErrInvalidInput        = errors.New("invalid input")
+	ErrEmptyCollection     = errors.New("collection has no locations to make a trip of")
 )
 
 type Service struct {
-	repo Repository
+	repo    Repository
+	trips   trips.Service
+	places  places.Service
+	indexer Indexer
 }
 
-func NewService(repo Repository) *Service {
-	return &Service{repo: repo}
+// Indexer keeps the search index in step with public collections
+type Indexer interface {
+	IndexCollection(ctx context.Context, collectionID string, collection map[string]interface{}) error
+	DeleteFromIndex(ctx context.Context, docType, documentID string) error
+}
+
+// NewService creates a collection service. Collections are turned into
+// trips through the trip and place services.
+func NewService(repo Repository, tripService trips.Service, placeService places.Service) *Service {
+	return &Service{repo: repo, trips: tripService, places: placeService}
+}
+
+// SetIndexer sets where public collections are indexed for search. Until it
+// is set, collections are not indexed.
+func (s *Service) SetIndexer(indexer Indexer) {
+	s.indexer = indexer
 }
 
 // Collection CRUD operations
@@ -31,45 +57,92 @@ func (s *Service) CreateCollection(ctx context.Context, userID uuid.UUID, req Cr
 		UserID:      userID,
 		Privacy:     req.Privacy,
 	}
+	if collection.Privacy == "" {
+		collection.Privacy = PrivacyPrivate
+	}
 
 	err := s.repo.Create(ctx, collection)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create collection: %w", err)
 	}
 
+	if collection.Discoverable() {
+		s.syncIndex(ctx, collection)
+	}
+
 	return collection, nil
 }
 
 func (s *Service) GetCollection(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*Collection, error) {
-	collection, err := s.repo.GetByID(ctx, id)
+	return s.getVisible(ctx, id, userID)
+}
+
+// ExportCollection writes a collection the user may view out as a GeoJSON
+// FeatureCollection
+func (s *Service) ExportCollection(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*CollectionExport, error) {
+	collection, err := s.getVisible(ctx, id, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get collection: %w", err)
+		return nil, err
 	}
 
-	if collection == nil {
-		return nil, ErrCollectionNotFound
+	data, err := json.Marshal(ExportGeoJSON(collection))
+	if err != nil {
+		return nil, fmt.Errorf("failed to export collection: %w", err)
 	}
 
-	// Check permission
-	if !s.canAccessCollection(ctx, collection, userID) {
-		return nil, ErrUnauthorized
+	return &CollectionExport{Data: data, Filename: exportFilename(collection)}, nil
+}
+
+// ImportCollection creates a private collection from the points of a
+// GeoJSON or KML file, saving each as a place. It is named after the file's
+// name unless a name is given.
+func (s *Service) ImportCollection(ctx context.Context, userID uuid.UUID, filename string, data []byte, name string) (*ImportResult, error) {
+	file, err := parseImport(filename, data)
+	if err != nil {
+		return nil, err
 	}
 
-	return collection, nil
+	collection := &Collection{
+		Name:      name,
+		UserID:    userID,
+		Privacy:   PrivacyPrivate,
+		Locations: file.Locations,
+	}
+	if collection.Name == "" {
+		collection.Name = file.Name
+	}
+	if collection.Name == "" {
+		collection.Name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	if collection.Name == "" {
+		collection.Name = "Imported collection"
+	}
+	collection.Name = *truncate(&collection.Name, 255)
+
+	if err := s.repo.Import(ctx, collection); err != nil {
+		return nil, fmt.Errorf("failed to import collection: %w", err)
+	}
+
+	return &ImportResult{
+		Collection: collection,
+		Imported:   len(collection.Locations),
+		Skipped:    file.Skipped,
+	}, nil
 }
 
 func (s *Service) GetUserCollections(ctx context.Context, userID uuid.UUID, params GetCollectionsParams) ([]Collection, int, error) {
 	return s.repo.GetByUserID(ctx, userID, params)
 }
 
+// DiscoverCollections returns a page of public collections
+func (s *Service) DiscoverCollections(ctx context.Context, params GetCollectionsParams) ([]Collection, int, error) {
+	return s.repo.ListPublic(ctx, params)
+}
+
 func (s *Service) UpdateCollection(ctx context.Context, id uuid.UUID, userID uuid.UUID, req UpdateCollectionRequest) (*Collection, error) {
-	collection, err := s.repo.GetByID(ctx, id)
+	collection, err := s.getVisible(ctx, id, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get collection: %w", err)
-	}
-
-	if collection == nil {
-		return nil, ErrCollectionNotFound
+		return nil, err
 	}
 
 	// Check permission - only owner can update
@@ -77,17 +150,22 @@ func (s *Service) UpdateCollection(ctx context.Context, id uuid.UUID, userID uui
 		return nil, ErrUnauthorized
 	}
 
-	return s.repo.Update(ctx, id, req)
+	updated, err := s.repo.Update(ctx, id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if collection.Discoverable() || updated.Discoverable() {
+		s.syncIndex(ctx, updated)
+	}
+
+	return updated, nil
 }
 
 func (s *Service) DeleteCollection(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
-	collection, err := s.repo.GetByID(ctx, id)
+	collection, err := s.getVisible(ctx, id, userID)
 	if err != nil {
-		return fmt.Errorf("failed to get collection: %w", err)
-	}
-
-	if collection == nil {
-		return ErrCollectionNotFound
+		return err
 	}
 
 	// Check permission - only owner can delete
@@ -95,18 +173,22 @@ func (s *Service) DeleteCollection(ctx context.Context, id uuid.UUID, userID uui
 		return ErrUnauthorized
 	}
 
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	if collection.Discoverable() {
+		s.unindex(ctx, collection.ID)
+	}
+
+	return nil
 }
 
 // Location operations
 func (s *Service) AddLocationToCollection(ctx context.Context, collectionID uuid.UUID, userID uuid.UUID, req AddLocationRequest) (*CollectionLocation, error) {
-	collection, err := s.repo.GetByID(ctx, collectionID)
+	collection, err := s.getVisible(ctx, collectionID, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get collection: %w", err)
-	}
-
-	if collection == nil {
-		return nil, ErrCollectionNotFound
+		return nil, err
 	}
 
 	// Check permission - owner or collaborator can add
@@ -116,6 +198,7 @@ func (s *Service) AddLocationToCollection(ctx context.Context, collectionID uuid
 
 	location := &CollectionLocation{
 		Name:      req.Name,
+		Notes:     req.Notes,
 		Latitude:  req.Latitude,
 		Longitude: req.Longitude,
 	}
@@ -129,13 +212,9 @@ func (s *Service) AddLocationToCollection(ctx context.Context, collectionID uuid
 }
 
 func (s *Service) RemoveLocationFromCollection(ctx context.Context, collectionID uuid.UUID, locationID uuid.UUID, userID uuid.UUID) error {
-	collection, err := s.repo.GetByID(ctx, collectionID)
+	collection, err := s.getVisible(ctx, collectionID, userID)
 	if err != nil {
-		return fmt.Errorf("failed to get collection: %w", err)
-	}
-
-	if collection == nil {
-		return ErrCollectionNotFound
+		return err
 	}
 
 	// Check permission - owner or collaborator can remove
@@ -148,13 +227,9 @@ func (s *Service) RemoveLocationFromCollection(ctx context.Context, collectionID
 
 // Collaboration operations
 func (s *Service) AddCollaborator(ctx context.Context, collectionID uuid.UUID, targetUserID uuid.UUID, role string, userID uuid.UUID) error {
-	collection, err := s.repo.GetByID(ctx, collectionID)
+	collection, err := s.getVisible(ctx, collectionID, userID)
 	if err != nil {
-		return fmt.Errorf("failed to get collection: %w", err)
-	}
-
-	if collection == nil {
-		return ErrCollectionNotFound
+		return err
 	}
 
 	// Only owner can add collaborators
@@ -166,13 +241,9 @@ func (s *Service) AddCollaborator(ctx context.Context, collectionID uuid.UUID, t
 }
 
 func (s *Service) RemoveCollaborator(ctx context.Context, collectionID uuid.UUID, targetUserID uuid.UUID, userID uuid.UUID) error {
-	collection, err := s.repo.GetByID(ctx, collectionID)
+	collection, err := s.getVisible(ctx, collectionID, userID)
 	if err != nil {
-		return fmt.Errorf("failed to get collection: %w", err)
-	}
-
-	if collection == nil {
-		return ErrCollectionNotFound
+		return err
 	}
 
 	// Only owner can remove collaborators
@@ -184,18 +255,34 @@ func (s *Service) RemoveCollaborator(ctx context.Context, collectionID uuid.UUID
 }
 
 // Helper methods for permissions
+
+// getVisible loads a collection the user may view. Collections they may not
+// view are not found, so that their existence is not given away.
+func (s *Service) getVisible(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*Collection, error) {
+	collection, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+
+	if !s.canAccessCollection(ctx, collection, userID) {
+		return nil, fmt.Errorf("collection %s: %w", id, ErrCollectionNotFound)
+	}
+
+	return collection, nil
+}
+
 func (s *Service) canAccessCollection(ctx context.Context, collection *Collection, userID uuid.UUID) bool {
 	// Owner can always access
 	if collection.UserID == userID {
 		return true
 	}
 
-	// Public collections can be accessed by anyone
-	if collection.Privacy == "public" {
+	// Public and unlisted collections can be accessed by anyone
+	if collection.VisibleTo(userID) {
 		return true
 	}
 
-	// For private/friends collections, check if user is a collaborator
+	// Anyone else must be a collaborator
 	collaborators, err := s.repo.GetCollaborators(ctx, collection.ID)
 	if err != nil {
 		return false
@@ -229,4 +316,51 @@ func (s *Service) canModifyCollection(ctx context.Context, collection *Collectio
 	}
 
 	return false
-}
\ No newline at end of file
+}
+
+// syncIndex indexes a public collection for search and removes any other
+// from the index. The change is already saved, so a failure is only logged.
+func (s *Service) syncIndex(ctx context.Context, collection *Collection) {
+	if !collection.Discoverable() {
+		s.unindex(ctx, collection.ID)
+		return
+	}
+	if s.indexer == nil {
+		return
+	}
+
+	if err := s.indexer.IndexCollection(ctx, collection.ID.String(), searchDocument(collection)); err != nil {
+		log.Printf("Failed to index collection %s: %v", collection.ID, err)
+	}
+}
+
+// unindex removes a collection from the search index
+func (s *Service) unindex(ctx context.Context, id uuid.UUID) {
+	if s.indexer == nil {
+		return
+	}
+
+	if err := s.indexer.DeleteFromIndex(ctx, "collection", id.String()); err != nil {
+		log.Printf("Failed to remove collection %s from search index: %v", id, err)
+	}
+}
+
+// searchDocument is the public view of a collection that is indexed for
+// search
+func searchDocument(collection *Collection) map[string]interface{} {
+	description := ""
+	if collection.Description != nil {
+		description = *collection.Description
+	}
+
+	return map[string]interface{}{
+		"id":          collection.ID.String(),
+		"type":        "collection",
+		"name":        collection.Name,
+		"description": description,
+		"owner_id":    collection.UserID.String(),
+		"privacy":     collection.Privacy,
+		"created_at":  collection.CreatedAt,
+		"updated_at":  collection.UpdatedAt,
+	}
+}
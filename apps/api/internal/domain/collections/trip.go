@@ -0,0 +1,139 @@
+package collections
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/gpx"
+	"github.com/google/uuid"
+)
+
+// CreateTrip builds a new trip of the user's whose stops are the
+// collection's locations. Each location becomes a private place of the
+// user's, and its notes the notes of its stop. The trip's route is drawn
+// through the stops.
+func (s *Service) CreateTrip(ctx context.Context, collectionID uuid.UUID, userID uuid.UUID, req CreateTripRequest) (*trips.Trip, error) {
+	collection, err := s.getVisible(ctx, collectionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	locations, err := pickLocations(collection.Locations, req.LocationIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(locations) == 0 {
+		return nil, ErrEmptyCollection
+	}
+	if req.Order == TripOrderProximity {
+		locations = byProximity(locations)
+	}
+
+	input := &trips.AddWaypointsInput{AutoRoute: true}
+	for i, location := range locations {
+		name := fmt.Sprintf("Stop %d", i+1)
+		if location.Name != nil && *location.Name != "" {
+			name = *location.Name
+		}
+		place, err := s.places.Create(ctx, userID.String(), &places.CreatePlaceInput{
+			Name:     name,
+			Type:     "poi",
+			Location: &places.LocationInput{Latitude: location.Latitude, Longitude: location.Longitude},
+			Privacy:  "private",
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		notes := ""
+		if location.Notes != nil {
+			notes = *location.Notes
+		}
+		input.PlaceIDs = append(input.PlaceIDs, place.ID)
+		input.Notes = append(input.Notes, notes)
+	}
+
+	tripInput := &trips.CreateTripInput{
+		Title:   collection.Name,
+		Privacy: req.Privacy,
+	}
+	if collection.Description != nil {
+		tripInput.Description = *collection.Description
+	}
+	if req.Title != nil {
+		tripInput.Title = *req.Title
+	}
+	if req.Description != nil {
+		tripInput.Description = *req.Description
+	}
+	if tripInput.Privacy == "" {
+		tripInput.Privacy = "private"
+	}
+
+	trip, err := s.trips.Create(ctx, userID.String(), tripInput)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.trips.AddWaypoints(ctx, userID.String(), trip.ID, input)
+}
+
+// pickLocations is the listed locations in the order listed, or else all
+// of them in the order they were added
+func pickLocations(locations []CollectionLocation, ids []uuid.UUID) ([]CollectionLocation, error) {
+	if len(ids) == 0 {
+		picked := append([]CollectionLocation(nil), locations...)
+		sort.SliceStable(picked, func(i, j int) bool {
+			return picked[i].AddedAt.Before(picked[j].AddedAt)
+		})
+		return picked, nil
+	}
+
+	byID := make(map[uuid.UUID]CollectionLocation, len(locations))
+	for _, location := range locations {
+		byID[location.ID] = location
+	}
+
+	picked := make([]CollectionLocation, 0, len(ids))
+	for _, id := range ids {
+		location, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("location %s: %w", id, ErrLocationNotFound)
+		}
+		picked = append(picked, location)
+	}
+	return picked, nil
+}
+
+// byProximity reorders locations to start at the first and go on each time
+// to the nearest not yet visited. It is not the shortest tour, but close
+// enough for planning one by hand from.
+func byProximity(locations []CollectionLocation) []CollectionLocation {
+	remaining := append([]CollectionLocation(nil), locations...)
+	ordered := make([]CollectionLocation, 0, len(locations))
+
+	current := remaining[0]
+	remaining = remaining[1:]
+	ordered = append(ordered, current)
+	for len(remaining) > 0 {
+		nearest := 0
+		for i := range remaining {
+			if distance(current, remaining[i]) < distance(current, remaining[nearest]) {
+				nearest = i
+			}
+		}
+		current = remaining[nearest]
+		remaining = append(remaining[:nearest], remaining[nearest+1:]...)
+		ordered = append(ordered, current)
+	}
+
+	return ordered
+}
+
+// distance is the great-circle distance between two locations, in metres
+func distance(from, to CollectionLocation) float64 {
+	return gpx.Haversine(gpx.Point{Lat: from.Latitude, Lon: from.Longitude}, gpx.Point{Lat: to.Latitude, Lon: to.Longitude})
+}
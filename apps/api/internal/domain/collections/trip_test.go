@@ -0,0 +1,46 @@
+package collections
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPickLocations(t *testing.T) {
+	now := time.Now()
+	first := CollectionLocation{ID: uuid.New(), AddedAt: now.Add(-2 * time.Hour)}
+	second := CollectionLocation{ID: uuid.New(), AddedAt: now.Add(-time.Hour)}
+	third := CollectionLocation{ID: uuid.New(), AddedAt: now}
+	// As the repository lists them, newest first
+	locations := []CollectionLocation{third, second, first}
+
+	t.Run("all in the order added", func(t *testing.T) {
+		picked, err := pickLocations(locations, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []CollectionLocation{first, second, third}, picked)
+	})
+
+	t.Run("listed in the order listed", func(t *testing.T) {
+		picked, err := pickLocations(locations, []uuid.UUID{third.ID, first.ID})
+		require.NoError(t, err)
+		assert.Equal(t, []CollectionLocation{third, first}, picked)
+	})
+
+	t.Run("location of another collection", func(t *testing.T) {
+		_, err := pickLocations(locations, []uuid.UUID{uuid.New()})
+		assert.ErrorIs(t, err, ErrLocationNotFound)
+	})
+}
+
+func TestByProximity(t *testing.T) {
+	at := func(lon float64) CollectionLocation {
+		return CollectionLocation{ID: uuid.New(), Latitude: 46, Longitude: lon}
+	}
+	start, far, near, middle := at(7.0), at(7.3), at(7.1), at(7.2)
+
+	ordered := byProximity([]CollectionLocation{start, far, near, middle})
+	assert.Equal(t, []CollectionLocation{start, near, middle, far}, ordered)
+}
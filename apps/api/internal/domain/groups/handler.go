@@ -0,0 +1,314 @@
+package groups
+
+import (
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/internal/middleware"
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the group routes
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *users.RouteMiddleware) {
+	groups := router.Group("/groups", mw.RequireAuth)
+	{
+		// Group CRUD
+		groups.POST("", h.Create)
+		groups.GET("", h.List)
+		groups.GET("/invitations", h.ListInvitations)
+		groups.GET("/:id", h.GetByID)
+		groups.PUT("/:id", h.Update)
+		groups.DELETE("/:id", h.Delete)
+
+		// Member management
+		groups.POST("/:id/members", h.AddMember)
+		groups.DELETE("/:id/members/:userId", h.RemoveMember)
+	}
+
+	// Invite a whole group to a trip, and answer such an invitation
+	router.POST("/trips/:id/groups", mw.RequireAuth, mw.RequireTripPermission(users.PermissionTripInvite), h.InviteToTrip)
+	router.POST("/trips/:id/groups/accept", mw.RequireAuth, h.AcceptInvitation)
+	router.POST("/trips/:id/groups/decline", mw.RequireAuth, h.DeclineInvitation)
+}
+
+func (h *Handler) Create(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input CreateGroupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	group, err := h.service.Create(c.Request.Context(), userID, &input)
+	if err != nil {
+		response.InternalServerError(c, "Failed to create group")
+		return
+	}
+
+	response.Created(c, group)
+}
+
+func (h *Handler) GetByID(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	group, err := h.service.GetByID(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		switch err {
+		case ErrGroupNotFound:
+			response.NotFound(c, "Group not found")
+		case ErrUnauthorized:
+			response.Forbidden(c, "You don't have permission to view this group")
+		default:
+			response.InternalServerError(c, "Failed to get group")
+		}
+		return
+	}
+
+	response.Success(c, group)
+}
+
+func (h *Handler) List(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	groups, err := h.service.List(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalServerError(c, "Failed to list groups")
+		return
+	}
+
+	response.Success(c, groups)
+}
+
+func (h *Handler) Update(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input UpdateGroupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	group, err := h.service.Update(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		switch err {
+		case ErrGroupNotFound:
+			response.NotFound(c, "Group not found")
+		case ErrUnauthorized:
+			response.Forbidden(c, "Only the group owner can update the group")
+		default:
+			response.InternalServerError(c, "Failed to update group")
+		}
+		return
+	}
+
+	response.Success(c, group)
+}
+
+func (h *Handler) Delete(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	err := h.service.Delete(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		switch err {
+		case ErrGroupNotFound:
+			response.NotFound(c, "Group not found")
+		case ErrUnauthorized:
+			response.Forbidden(c, "Only the group owner can delete the group")
+		default:
+			response.InternalServerError(c, "Failed to delete group")
+		}
+		return
+	}
+
+	response.NoContent(c)
+}
+
+func (h *Handler) AddMember(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input AddMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	err := h.service.AddMember(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		switch err {
+		case ErrGroupNotFound:
+			response.NotFound(c, "Group not found")
+		case ErrUnauthorized:
+			response.Forbidden(c, "Only the group owner can add members")
+		default:
+			response.BadRequest(c, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, map[string]string{
+		"message": "Member added successfully",
+	})
+}
+
+func (h *Handler) RemoveMember(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	err := h.service.RemoveMember(c.Request.Context(), userID, c.Param("id"), c.Param("userId"))
+	if err != nil {
+		switch err {
+		case ErrGroupNotFound:
+			response.NotFound(c, "Group not found")
+		case ErrUnauthorized:
+			response.Forbidden(c, "Only the group owner can remove members")
+		default:
+			response.BadRequest(c, err.Error())
+		}
+		return
+	}
+
+	response.Success(c, map[string]string{
+		"message": "Member removed successfully",
+	})
+}
+
+// InviteToTrip invites every member of a group to the trip in the URL
+func (h *Handler) InviteToTrip(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input InviteGroupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	invited, err := h.service.InviteToTrip(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		switch err {
+		case ErrGroupNotFound:
+			response.NotFound(c, "Group not found")
+		case trips.ErrTripNotFound:
+			response.NotFound(c, "Trip not found")
+		case ErrUnauthorized:
+			response.Forbidden(c, "You don't have permission to invite this group")
+		default:
+			response.InternalServerError(c, "Failed to invite group")
+		}
+		return
+	}
+
+	response.Success(c, map[string]interface{}{
+		"invited": invited,
+	})
+}
+
+// ListInvitations lists the user's pending group invitations to trips
+func (h *Handler) ListInvitations(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	invitations, err := h.service.ListInvitations(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalServerError(c, "Failed to list invitations")
+		return
+	}
+
+	response.Success(c, invitations)
+}
+
+// AcceptInvitation joins the trip in the URL through a group invitation
+func (h *Handler) AcceptInvitation(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	invitation, err := h.service.AcceptInvitation(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		switch err {
+		case ErrInvitationNotFound:
+			response.NotFound(c, "No pending group invitation")
+		default:
+			response.InternalServerError(c, "Failed to accept invitation")
+		}
+		return
+	}
+
+	response.Success(c, invitation)
+}
+
+// DeclineInvitation turns down a group invitation to the trip in the URL
+func (h *Handler) DeclineInvitation(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	err := h.service.DeclineInvitation(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		switch err {
+		case ErrInvitationNotFound:
+			response.NotFound(c, "No pending group invitation")
+		default:
+			response.InternalServerError(c, "Failed to decline invitation")
+		}
+		return
+	}
+
+	response.NoContent(c)
+}
@@ -0,0 +1,77 @@
+package groups
+
+import (
+	"time"
+)
+
+// Group is a named set of users that can be invited to trips together
+type Group struct {
+	ID          string    `db:"id" json:"id"`
+	Name        string    `db:"name" json:"name"`
+	Description string    `db:"description" json:"description"`
+	OwnerID     string    `db:"owner_id" json:"owner_id"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+
+	// Joined fields
+	Members []Member `json:"members,omitempty"`
+}
+
+type Member struct {
+	GroupID string    `db:"group_id" json:"group_id"`
+	UserID  string    `db:"user_id" json:"user_id"`
+	AddedAt time.Time `db:"added_at" json:"added_at"`
+
+	// Joined user info
+	Username    string `db:"username" json:"username,omitempty"`
+	DisplayName string `db:"display_name" json:"display_name,omitempty"`
+	AvatarURL   string `db:"avatar_url" json:"avatar_url,omitempty"`
+}
+
+// Invitation is a trip invitation that came through a group. It grants
+// nothing until the member accepts it.
+type Invitation struct {
+	TripID    string    `db:"trip_id" json:"trip_id"`
+	UserID    string    `db:"user_id" json:"user_id"`
+	GroupID   string    `db:"group_id" json:"group_id"`
+	Role      string    `db:"role" json:"role"`
+	InvitedAt time.Time `db:"invited_at" json:"invited_at"`
+
+	// Joined fields
+	TripTitle string `db:"trip_title" json:"trip_title,omitempty"`
+	GroupName string `db:"group_name" json:"group_name,omitempty"`
+}
+
+// Input types
+type CreateGroupInput struct {
+	Name        string `json:"name" binding:"required,min=1,max=255"`
+	Description string `json:"description" binding:"max=2000"`
+}
+
+type UpdateGroupInput struct {
+	Name        *string `json:"name,omitempty" binding:"omitempty,min=1,max=255"`
+	Description *string `json:"description,omitempty" binding:"omitempty,max=2000"`
+}
+
+type AddMemberInput struct {
+	UserID string `json:"user_id" binding:"required,uuid"`
+}
+
+type InviteGroupInput struct {
+	GroupID string `json:"group_id" binding:"required,uuid"`
+	Role    string `json:"role" binding:"omitempty,oneof=viewer editor"`
+}
+
+// Helper methods
+func (g *Group) IsOwner(userID string) bool {
+	return g.OwnerID == userID
+}
+
+func (g *Group) HasMember(userID string) bool {
+	for _, m := range g.Members {
+		if m.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
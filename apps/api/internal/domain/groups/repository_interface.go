@@ -0,0 +1,43 @@
+package groups
+
+import (
+	"context"
+)
+
+// Repository defines the interface for group data operations
+type Repository interface {
+	// Create creates a new group and adds the owner as its first member
+	Create(ctx context.Context, group *Group) error
+
+	// GetByID retrieves a group by ID with its members
+	GetByID(ctx context.Context, id string) (*Group, error)
+
+	// ListByUser retrieves groups the user owns or belongs to
+	ListByUser(ctx context.Context, userID string) ([]*Group, error)
+
+	// Update updates a group
+	Update(ctx context.Context, id string, updates map[string]interface{}) error
+
+	// Delete deletes a group
+	Delete(ctx context.Context, id string) error
+
+	// AddMember adds a member and invites them to every trip the group was invited to
+	AddMember(ctx context.Context, groupID string, member Member) error
+
+	// RemoveMember removes a member and withdraws their pending group invitations
+	RemoveMember(ctx context.Context, groupID, userID string) error
+
+	// AddToTrip links the group to a trip and invites all members to it. It
+	// returns the number of invitations sent.
+	AddToTrip(ctx context.Context, groupID, tripID, role, addedBy string) (int64, error)
+
+	// ListInvitations retrieves the user's pending group invitations
+	ListInvitations(ctx context.Context, userID string) ([]*Invitation, error)
+
+	// AcceptInvitation makes the user a collaborator on the trip with the
+	// role they were invited with
+	AcceptInvitation(ctx context.Context, tripID, userID string) (*Invitation, error)
+
+	// DeclineInvitation withdraws the user's pending group invitation
+	DeclineInvitation(ctx context.Context, tripID, userID string) error
+}
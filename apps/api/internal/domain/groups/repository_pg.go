@@ -0,0 +1,383 @@
+package groups
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// PostgresRepository implements the repository interface for PostgreSQL
+type PostgresRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *sqlx.DB) *PostgresRepository {
+	return &PostgresRepository{
+		db: db,
+	}
+}
+
+// propagateInvitationsQuery creates pending trip invitations for group
+// members with the role the group was invited with. Trip owners and existing
+// collaborators are not invited, and a member already invited through another
+// group keeps that invitation.
+const propagateInvitationsQuery = `
+	INSERT INTO group_invitations (trip_id, user_id, group_id, role)
+	SELECT gt.trip_id, gm.user_id, gt.group_id, gt.role
+	FROM group_trips gt
+	JOIN group_members gm ON gm.group_id = gt.group_id
+	JOIN trips t ON t.id = gt.trip_id AND t.deleted_at IS NULL AND t.owner_id <> gm.user_id
+	WHERE %s
+		AND NOT EXISTS (
+			SELECT 1 FROM trip_collaborators tc
+			WHERE tc.trip_id = gt.trip_id AND tc.user_id = gm.user_id
+		)
+	ON CONFLICT (trip_id, user_id) DO UPDATE SET role = EXCLUDED.role
+	WHERE group_invitations.group_id = EXCLUDED.group_id`
+
+// Create creates a new group and adds the owner as its first member
+func (r *PostgresRepository) Create(ctx context.Context, group *Group) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO groups (name, description, owner_id)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		group.Name,
+		group.Description,
+		group.OwnerID,
+	).Scan(&group.ID, &group.CreatedAt, &group.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create group: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO group_members (group_id, user_id)
+		VALUES ($1, $2)`, group.ID, group.OwnerID)
+	if err != nil {
+		return fmt.Errorf("failed to add owner as member: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetByID retrieves a group by ID with its members
+func (r *PostgresRepository) GetByID(ctx context.Context, id string) (*Group, error) {
+	var group Group
+	query := `
+		SELECT id, name, COALESCE(description, '') AS description, owner_id,
+			created_at, updated_at
+		FROM groups
+		WHERE id = $1`
+
+	err := r.db.GetContext(ctx, &group, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrGroupNotFound
+		}
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+
+	members, err := r.getMembers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	group.Members = members
+
+	return &group, nil
+}
+
+// ListByUser retrieves groups the user owns or belongs to
+func (r *PostgresRepository) ListByUser(ctx context.Context, userID string) ([]*Group, error) {
+	var groups []*Group
+	query := `
+		SELECT DISTINCT g.id, g.name, COALESCE(g.description, '') AS description,
+			g.owner_id, g.created_at, g.updated_at
+		FROM groups g
+		LEFT JOIN group_members gm ON gm.group_id = g.id
+		WHERE g.owner_id = $1 OR gm.user_id = $1
+		ORDER BY g.name`
+
+	err := r.db.SelectContext(ctx, &groups, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+
+	return groups, nil
+}
+
+// Update updates a group
+func (r *PostgresRepository) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	// Build dynamic update query
+	setClause := ""
+	args := []interface{}{id}
+	argCount := 2
+
+	for field, value := range updates {
+		if setClause != "" {
+			setClause += ", "
+		}
+		setClause += fmt.Sprintf("%s = $%d", field, argCount)
+		args = append(args, value)
+		argCount++
+	}
+
+	if setClause == "" {
+		return nil // No updates
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE groups
+		SET %s, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, setClause)
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update group: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrGroupNotFound
+	}
+
+	return nil
+}
+
+// Delete deletes a group and withdraws its pending invitations. Members who
+// already joined a trip through it stay on the trip.
+func (r *PostgresRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM groups WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete group: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrGroupNotFound
+	}
+
+	return nil
+}
+
+// AddMember adds a member and invites them to every trip the group was invited to
+func (r *PostgresRepository) AddMember(ctx context.Context, groupID string, member Member) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO group_members (group_id, user_id)
+		VALUES ($1, $2)`, groupID, member.UserID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return fmt.Errorf("user is already a member")
+		}
+		return fmt.Errorf("failed to add member: %w", err)
+	}
+
+	query := fmt.Sprintf(propagateInvitationsQuery, "gt.group_id = $1 AND gm.user_id = $2")
+	if _, err := tx.ExecContext(ctx, query, groupID, member.UserID); err != nil {
+		return fmt.Errorf("failed to invite member to group trips: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// RemoveMember removes a member and withdraws their pending group invitations
+func (r *PostgresRepository) RemoveMember(ctx context.Context, groupID, userID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM group_members
+		WHERE group_id = $1 AND user_id = $2`, groupID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("member not found")
+	}
+
+	// Invitations that were already accepted stay in place
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM group_invitations
+		WHERE group_id = $1 AND user_id = $2`, groupID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to withdraw group invitations: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// AddToTrip links the group to a trip and invites all members to it
+func (r *PostgresRepository) AddToTrip(ctx context.Context, groupID, tripID, role, addedBy string) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO group_trips (group_id, trip_id, role, added_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (group_id, trip_id) DO UPDATE SET role = EXCLUDED.role`,
+		groupID, tripID, role, addedBy)
+	if err != nil {
+		return 0, fmt.Errorf("failed to link group to trip: %w", err)
+	}
+
+	query := fmt.Sprintf(propagateInvitationsQuery, "gt.group_id = $1 AND gt.trip_id = $2")
+	result, err := tx.ExecContext(ctx, query, groupID, tripID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to invite group members: %w", err)
+	}
+
+	invited, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return invited, nil
+}
+
+// ListInvitations retrieves the user's pending group invitations, newest first
+func (r *PostgresRepository) ListInvitations(ctx context.Context, userID string) ([]*Invitation, error) {
+	invitations := []*Invitation{}
+	query := `
+		SELECT
+			gi.trip_id, gi.user_id, gi.group_id, gi.role, gi.invited_at,
+			t.title AS trip_title, g.name AS group_name
+		FROM group_invitations gi
+		JOIN trips t ON t.id = gi.trip_id AND t.deleted_at IS NULL
+		JOIN groups g ON g.id = gi.group_id
+		WHERE gi.user_id = $1
+		ORDER BY gi.invited_at DESC`
+
+	err := r.db.SelectContext(ctx, &invitations, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// AcceptInvitation makes the user a collaborator on the trip with the role
+// they were invited with, and returns the invitation
+func (r *PostgresRepository) AcceptInvitation(ctx context.Context, tripID, userID string) (*Invitation, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var invitation Invitation
+	err = tx.GetContext(ctx, &invitation, `
+		DELETE FROM group_invitations
+		WHERE trip_id = $1 AND user_id = $2
+		RETURNING trip_id, user_id, group_id, role, invited_at`, tripID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	// Editors edit and moderate suggestions, viewers only view
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO trip_collaborators (
+			trip_id, user_id, role, can_edit, can_delete, can_invite,
+			can_moderate_suggestions, invited_at, joined_at, invited_via_group
+		) VALUES (
+			$1, $2, $3, $3 = 'editor', false, false,
+			$3 = 'editor', $4, CURRENT_TIMESTAMP, $5
+		)
+		ON CONFLICT (trip_id, user_id) DO NOTHING`,
+		invitation.TripID, invitation.UserID, invitation.Role, invitation.InvitedAt, invitation.GroupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add collaborator: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+// DeclineInvitation withdraws the user's pending group invitation to a trip
+func (r *PostgresRepository) DeclineInvitation(ctx context.Context, tripID, userID string) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM group_invitations
+		WHERE trip_id = $1 AND user_id = $2`, tripID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to decline invitation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrInvitationNotFound
+	}
+
+	return nil
+}
+
+// Helper functions
+
+func (r *PostgresRepository) getMembers(ctx context.Context, groupID string) ([]Member, error) {
+	var members []Member
+	query := `
+		SELECT
+			gm.group_id, gm.user_id, gm.added_at,
+			u.username, COALESCE(u.display_name, '') AS display_name,
+			COALESCE(u.avatar_url, '') AS avatar_url
+		FROM group_members gm
+		JOIN users u ON gm.user_id = u.id
+		WHERE gm.group_id = $1
+		ORDER BY gm.added_at`
+
+	err := r.db.SelectContext(ctx, &members, query, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members: %w", err)
+	}
+
+	return members, nil
+}
@@ -0,0 +1,180 @@
+package groups
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ Repository = (*PostgresRepository)(nil)
+
+func newMockRepository(t *testing.T) (*PostgresRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return NewPostgresRepository(sqlx.NewDb(db, "postgres")), mock
+}
+
+func TestPropagateInvitationsQuery(t *testing.T) {
+	query := fmt.Sprintf(propagateInvitationsQuery, "gt.group_id = $1")
+
+	// Invitations wait for the member to accept them
+	assert.Contains(t, query, "INSERT INTO group_invitations")
+	assert.NotContains(t, query, "INSERT INTO trip_collaborators")
+	// The role only ever comes from the trip link
+	assert.Contains(t, query, "SELECT gt.trip_id, gm.user_id, gt.group_id, gt.role")
+	assert.NotContains(t, query, "default_role")
+	// Trip owners and collaborators are never invited to their own trip
+	assert.Contains(t, query, "t.owner_id <> gm.user_id")
+	assert.Contains(t, query, "SELECT 1 FROM trip_collaborators tc")
+	// Deleted trips get no invitations
+	assert.Contains(t, query, "t.deleted_at IS NULL")
+	// Another group's invitation is left alone
+	assert.Contains(t, query, "WHERE group_invitations.group_id = EXCLUDED.group_id")
+}
+
+func TestPostgresRepository_AddMember(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("invites the member to the group's trips", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO group_members`).
+			WithArgs("group-1", "user-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("WHERE gt.group_id = $1 AND gm.user_id = $2")).
+			WithArgs("group-1", "user-1").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		require.NoError(t, repo.AddMember(ctx, "group-1", Member{UserID: "user-1"}))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back when the invitations fail", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO group_members`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO group_invitations`).WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		err := repo.AddMember(ctx, "group-1", Member{UserID: "user-1"})
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresRepository_RemoveMember(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("withdraws pending group invitations", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`DELETE FROM group_members`).
+			WithArgs("group-1", "user-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM group_invitations\s+WHERE group_id = \$1 AND user_id = \$2`).
+			WithArgs("group-1", "user-1").
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+
+		require.NoError(t, repo.RemoveMember(ctx, "group-1", "user-1"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not a member", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`DELETE FROM group_members`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		assert.Error(t, repo.RemoveMember(ctx, "group-1", "user-1"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresRepository_AddToTrip(t *testing.T) {
+	ctx := context.Background()
+
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO group_trips`).
+		WithArgs("group-1", "trip-1", "viewer", "owner-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("WHERE gt.group_id = $1 AND gt.trip_id = $2")).
+		WithArgs("group-1", "trip-1").
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectCommit()
+
+	invited, err := repo.AddToTrip(ctx, "group-1", "trip-1", "viewer", "owner-1")
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, invited)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepository_AcceptInvitation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("joins the trip with the invitation's role", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		invitedAt := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`DELETE FROM group_invitations`).
+			WithArgs("trip-1", "user-1").
+			WillReturnRows(sqlmock.NewRows([]string{"trip_id", "user_id", "group_id", "role", "invited_at"}).
+				AddRow("trip-1", "user-1", "group-1", "editor", invitedAt))
+		mock.ExpectExec(`INSERT INTO trip_collaborators`).
+			WithArgs("trip-1", "user-1", "editor", invitedAt, "group-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		invitation, err := repo.AcceptInvitation(ctx, "trip-1", "user-1")
+		require.NoError(t, err)
+		assert.Equal(t, "editor", invitation.Role)
+		assert.Equal(t, "group-1", invitation.GroupID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no pending invitation", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`DELETE FROM group_invitations`).WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := repo.AcceptInvitation(ctx, "trip-1", "user-1")
+		assert.ErrorIs(t, err, ErrInvitationNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresRepository_DeclineInvitation(t *testing.T) {
+	ctx := context.Background()
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(`DELETE FROM group_invitations`).
+		WithArgs("trip-1", "user-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM group_invitations`).
+		WithArgs("trip-1", "user-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, repo.DeclineInvitation(ctx, "trip-1", "user-1"))
+	assert.ErrorIs(t, repo.DeclineInvitation(ctx, "trip-1", "user-1"), ErrInvitationNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
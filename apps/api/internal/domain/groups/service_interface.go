@@ -0,0 +1,35 @@
+package groups
+
+import (
+	"context"
+	"errors"
+)
+
+// Service defines the interface for group operations
+type Service interface {
+	Create(ctx context.Context, userID string, input *CreateGroupInput) (*Group, error)
+	GetByID(ctx context.Context, userID, groupID string) (*Group, error)
+	List(ctx context.Context, userID string) ([]*Group, error)
+	Update(ctx context.Context, userID, groupID string, input *UpdateGroupInput) (*Group, error)
+	Delete(ctx context.Context, userID, groupID string) error
+
+	// Membership
+	AddMember(ctx context.Context, userID, groupID string, input *AddMemberInput) error
+	RemoveMember(ctx context.Context, userID, groupID, memberID string) error
+
+	// InviteToTrip invites every member of a group to a trip
+	InviteToTrip(ctx context.Context, userID, tripID string, input *InviteGroupInput) (int64, error)
+
+	// Invitations
+	ListInvitations(ctx context.Context, userID string) ([]*Invitation, error)
+	AcceptInvitation(ctx context.Context, userID, tripID string) (*Invitation, error)
+	DeclineInvitation(ctx context.Context, userID, tripID string) error
+}
+
+// Common errors
+var (
+	ErrGroupNotFound = errors.New("group not found")
+	ErrUnauthorized  = errors.New("unauthorized")
+
+	ErrInvitationNotFound = errors.New("no pending group invitation")
+)
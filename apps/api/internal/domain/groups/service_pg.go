@@ -0,0 +1,221 @@
+package groups
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Oferzz/newMap/apps/api/internal/cache"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+)
+
+type servicePg struct {
+	repo     Repository
+	tripRepo trips.Repository
+	userRepo users.Repository
+	bus      events.Bus // nil when nobody is told about new collaborators
+}
+
+// NewService creates a new group service
+func NewService(repo Repository, tripRepo trips.Repository, userRepo users.Repository) *servicePg {
+	return &servicePg{
+		repo:     repo,
+		tripRepo: tripRepo,
+		userRepo: userRepo,
+	}
+}
+
+// SetEventBus sets where trips joined through a group are announced and
+// invalidated
+func (s *servicePg) SetEventBus(bus events.Bus) {
+	s.bus = bus
+}
+
+func (s *servicePg) Create(ctx context.Context, userID string, input *CreateGroupInput) (*Group, error) {
+	group := &Group{
+		Name:        input.Name,
+		Description: input.Description,
+		OwnerID:     userID,
+	}
+
+	if err := s.repo.Create(ctx, group); err != nil {
+		return nil, fmt.Errorf("failed to create group: %w", err)
+	}
+
+	return s.repo.GetByID(ctx, group.ID)
+}
+
+func (s *servicePg) GetByID(ctx context.Context, userID, groupID string) (*Group, error) {
+	group, err := s.repo.GetByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Groups are only visible to their members
+	if !group.IsOwner(userID) && !group.HasMember(userID) {
+		return nil, ErrUnauthorized
+	}
+
+	return group, nil
+}
+
+func (s *servicePg) List(ctx context.Context, userID string) ([]*Group, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+func (s *servicePg) Update(ctx context.Context, userID, groupID string, input *UpdateGroupInput) (*Group, error) {
+	group, err := s.repo.GetByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the owner can update the group
+	if !group.IsOwner(userID) {
+		return nil, ErrUnauthorized
+	}
+
+	updates := make(map[string]interface{})
+	if input.Name != nil {
+		updates["name"] = *input.Name
+	}
+	if input.Description != nil {
+		updates["description"] = *input.Description
+	}
+
+	if err := s.repo.Update(ctx, groupID, updates); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByID(ctx, groupID)
+}
+
+func (s *servicePg) Delete(ctx context.Context, userID, groupID string) error {
+	group, err := s.repo.GetByID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	// Only the owner can delete the group
+	if !group.IsOwner(userID) {
+		return ErrUnauthorized
+	}
+
+	return s.repo.Delete(ctx, groupID)
+}
+
+func (s *servicePg) AddMember(ctx context.Context, userID, groupID string, input *AddMemberInput) error {
+	group, err := s.repo.GetByID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	// Only the owner can manage members
+	if !group.IsOwner(userID) {
+		return ErrUnauthorized
+	}
+
+	// Check if user exists
+	if _, err := s.userRepo.GetByID(ctx, input.UserID); err != nil {
+		return errors.New("user not found")
+	}
+
+	if group.HasMember(input.UserID) {
+		return errors.New("user is already a member")
+	}
+
+	member := Member{
+		GroupID: groupID,
+		UserID:  input.UserID,
+	}
+
+	return s.repo.AddMember(ctx, groupID, member)
+}
+
+func (s *servicePg) RemoveMember(ctx context.Context, userID, groupID, memberID string) error {
+	group, err := s.repo.GetByID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	// The owner can remove anyone and members can leave
+	if !group.IsOwner(userID) && userID != memberID {
+		return ErrUnauthorized
+	}
+
+	if group.IsOwner(memberID) {
+		return errors.New("the group owner cannot be removed")
+	}
+
+	return s.repo.RemoveMember(ctx, groupID, memberID)
+}
+
+func (s *servicePg) InviteToTrip(ctx context.Context, userID, tripID string, input *InviteGroupInput) (int64, error) {
+	group, err := s.repo.GetByID(ctx, input.GroupID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Only members can bring their group along
+	if !group.IsOwner(userID) && !group.HasMember(userID) {
+		return 0, ErrUnauthorized
+	}
+
+	trip, err := s.tripRepo.GetByID(ctx, tripID)
+	if err != nil {
+		return 0, err
+	}
+
+	if !trip.CanUserInvite(userID) {
+		return 0, ErrUnauthorized
+	}
+
+	// Members are invited as viewers unless asked otherwise, never above
+	// editor and never above what the inviter can do on the trip
+	role := "viewer"
+	if input.Role == "editor" && trip.CanUserEdit(userID) {
+		role = "editor"
+	}
+
+	return s.repo.AddToTrip(ctx, group.ID, tripID, role, userID)
+}
+
+func (s *servicePg) ListInvitations(ctx context.Context, userID string) ([]*Invitation, error) {
+	return s.repo.ListInvitations(ctx, userID)
+}
+
+// AcceptInvitation makes the user a collaborator on the trip, then tells the
+// trip's followers and drops what the change made stale
+func (s *servicePg) AcceptInvitation(ctx context.Context, userID, tripID string) (*Invitation, error) {
+	invitation, err := s.repo.AcceptInvitation(ctx, tripID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(ctx, events.New(events.TripCollaboratorsChanged, "trip", tripID, userID, map[string]interface{}{
+		"user_id":  userID,
+		"role":     invitation.Role,
+		"group_id": invitation.GroupID,
+	}))
+	s.publish(ctx, cache.TripInvalidation{TripID: tripID, UserIDs: []string{userID}}.Event(userID))
+
+	return invitation, nil
+}
+
+func (s *servicePg) DeclineInvitation(ctx context.Context, userID, tripID string) error {
+	return s.repo.DeclineInvitation(ctx, tripID, userID)
+}
+
+// publish publishes an event about a trip joined through a group. The member
+// has already joined, so a failure is only logged.
+func (s *servicePg) publish(ctx context.Context, event events.Event) {
+	if s.bus == nil {
+		return
+	}
+
+	if err := s.bus.Publish(ctx, event); err != nil {
+		log.Printf("groups: failed to publish %s for trip %s: %v", event.Type, event.EntityID, err)
+	}
+}
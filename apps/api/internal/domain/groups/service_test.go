@@ -0,0 +1,299 @@
+package groups
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/cache"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	ownerID    = "owner-1"
+	memberID   = "member-1"
+	strangerID = "stranger-1"
+	groupID    = "group-1"
+	tripID     = "trip-1"
+)
+
+// mockRepository stubs the repository calls the group service makes
+type mockRepository struct {
+	mock.Mock
+	Repository
+}
+
+func (m *mockRepository) GetByID(ctx context.Context, id string) (*Group, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Group), args.Error(1)
+}
+
+func (m *mockRepository) AddMember(ctx context.Context, groupID string, member Member) error {
+	return m.Called(ctx, groupID, member).Error(0)
+}
+
+func (m *mockRepository) RemoveMember(ctx context.Context, groupID, userID string) error {
+	return m.Called(ctx, groupID, userID).Error(0)
+}
+
+func (m *mockRepository) AddToTrip(ctx context.Context, groupID, tripID, role, addedBy string) (int64, error) {
+	args := m.Called(ctx, groupID, tripID, role, addedBy)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockRepository) AcceptInvitation(ctx context.Context, tripID, userID string) (*Invitation, error) {
+	args := m.Called(ctx, tripID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Invitation), args.Error(1)
+}
+
+type tripRepository struct {
+	trips.Repository
+	trip *trips.Trip
+}
+
+func (r *tripRepository) GetByID(ctx context.Context, id string) (*trips.Trip, error) {
+	if r.trip == nil || r.trip.ID != id {
+		return nil, trips.ErrTripNotFound
+	}
+	return r.trip, nil
+}
+
+type userRepository struct {
+	users.Repository
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id string) (*users.User, error) {
+	if id == strangerID || id == memberID {
+		return &users.User{ID: id}, nil
+	}
+	return nil, users.ErrUserNotFound
+}
+
+func crew() *Group {
+	return &Group{
+		ID:      groupID,
+		Name:    "Sunday crew",
+		OwnerID: ownerID,
+		Members: []Member{
+			{GroupID: groupID, UserID: ownerID},
+			{GroupID: groupID, UserID: memberID},
+		},
+	}
+}
+
+func newTestService(repo Repository, trip *trips.Trip) *servicePg {
+	return NewService(repo, &tripRepository{trip: trip}, &userRepository{})
+}
+
+func TestService_GetByID(t *testing.T) {
+	ctx := context.Background()
+
+	for _, userID := range []string{ownerID, memberID} {
+		repo := new(mockRepository)
+		repo.On("GetByID", ctx, groupID).Return(crew(), nil).Once()
+
+		group, err := newTestService(repo, nil).GetByID(ctx, userID, groupID)
+		require.NoError(t, err, userID)
+		assert.Equal(t, groupID, group.ID)
+	}
+
+	repo := new(mockRepository)
+	repo.On("GetByID", ctx, groupID).Return(crew(), nil).Once()
+	_, err := newTestService(repo, nil).GetByID(ctx, strangerID, groupID)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestService_Members(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("owner adds members", func(t *testing.T) {
+		repo := new(mockRepository)
+		repo.On("GetByID", ctx, groupID).Return(crew(), nil).Once()
+		repo.On("AddMember", ctx, groupID, Member{GroupID: groupID, UserID: strangerID}).Return(nil).Once()
+
+		require.NoError(t, newTestService(repo, nil).AddMember(ctx, ownerID, groupID, &AddMemberInput{UserID: strangerID}))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("rejected additions", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			userID string
+			input  AddMemberInput
+		}{
+			{"by a member", memberID, AddMemberInput{UserID: strangerID}},
+			{"of an unknown user", ownerID, AddMemberInput{UserID: "nobody"}},
+			{"of a member", ownerID, AddMemberInput{UserID: memberID}},
+		}
+		for _, tt := range tests {
+			repo := new(mockRepository)
+			repo.On("GetByID", ctx, groupID).Return(crew(), nil).Once()
+
+			err := newTestService(repo, nil).AddMember(ctx, tt.userID, groupID, &tt.input)
+			assert.Error(t, err, tt.name)
+			repo.AssertNotCalled(t, "AddMember", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("owner removes members and members leave", func(t *testing.T) {
+		for _, userID := range []string{ownerID, memberID} {
+			repo := new(mockRepository)
+			repo.On("GetByID", ctx, groupID).Return(crew(), nil).Once()
+			repo.On("RemoveMember", ctx, groupID, memberID).Return(nil).Once()
+
+			require.NoError(t, newTestService(repo, nil).RemoveMember(ctx, userID, groupID, memberID), userID)
+			repo.AssertExpectations(t)
+		}
+	})
+
+	t.Run("members cannot remove others", func(t *testing.T) {
+		repo := new(mockRepository)
+		repo.On("GetByID", ctx, groupID).Return(crew(), nil).Once()
+
+		err := newTestService(repo, nil).RemoveMember(ctx, memberID, groupID, ownerID)
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("the owner stays", func(t *testing.T) {
+		repo := new(mockRepository)
+		repo.On("GetByID", ctx, groupID).Return(crew(), nil).Once()
+
+		assert.Error(t, newTestService(repo, nil).RemoveMember(ctx, ownerID, groupID, ownerID))
+		repo.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestService_InviteToTrip(t *testing.T) {
+	ctx := context.Background()
+	trip := func() *trips.Trip {
+		return &trips.Trip{
+			ID:      tripID,
+			OwnerID: memberID,
+			Collaborators: []trips.Collaborator{
+				{UserID: ownerID, Role: "viewer"},
+				{UserID: strangerID, Role: "admin"},
+			},
+		}
+	}
+
+	t.Run("member who may invite to the trip", func(t *testing.T) {
+		repo := new(mockRepository)
+		repo.On("GetByID", ctx, groupID).Return(crew(), nil).Once()
+		repo.On("AddToTrip", ctx, groupID, tripID, "editor", memberID).Return(int64(1), nil).Once()
+
+		invited, err := newTestService(repo, trip()).InviteToTrip(ctx, memberID, tripID, &InviteGroupInput{GroupID: groupID, Role: "editor"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, invited)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("members join as viewers by default", func(t *testing.T) {
+		repo := new(mockRepository)
+		repo.On("GetByID", ctx, groupID).Return(crew(), nil).Once()
+		repo.On("AddToTrip", ctx, groupID, tripID, "viewer", memberID).Return(int64(1), nil).Once()
+
+		_, err := newTestService(repo, trip()).InviteToTrip(ctx, memberID, tripID, &InviteGroupInput{GroupID: groupID})
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("members never join above editor", func(t *testing.T) {
+		repo := new(mockRepository)
+		repo.On("GetByID", ctx, groupID).Return(crew(), nil).Once()
+		repo.On("AddToTrip", ctx, groupID, tripID, "viewer", memberID).Return(int64(1), nil).Once()
+
+		_, err := newTestService(repo, trip()).InviteToTrip(ctx, memberID, tripID, &InviteGroupInput{GroupID: groupID, Role: "admin"})
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("members never join above the inviter", func(t *testing.T) {
+		// The inviter may invite but not edit, so editors are invited as viewers
+		invitingViewer := trip()
+		invitingViewer.Collaborators[0].CanInvite = true
+
+		repo := new(mockRepository)
+		repo.On("GetByID", ctx, groupID).Return(crew(), nil).Once()
+		repo.On("AddToTrip", ctx, groupID, tripID, "viewer", ownerID).Return(int64(1), nil).Once()
+
+		_, err := newTestService(repo, invitingViewer).InviteToTrip(ctx, ownerID, tripID, &InviteGroupInput{GroupID: groupID, Role: "editor"})
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("members who may not invite to the trip", func(t *testing.T) {
+		repo := new(mockRepository)
+		repo.On("GetByID", ctx, groupID).Return(crew(), nil).Once()
+
+		_, err := newTestService(repo, trip()).InviteToTrip(ctx, ownerID, tripID, &InviteGroupInput{GroupID: groupID})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("trip admins outside the group", func(t *testing.T) {
+		repo := new(mockRepository)
+		repo.On("GetByID", ctx, groupID).Return(crew(), nil).Once()
+
+		_, err := newTestService(repo, trip()).InviteToTrip(ctx, strangerID, tripID, &InviteGroupInput{GroupID: groupID})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNotCalled(t, "AddToTrip", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestService_AcceptInvitation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("announces the collaborator and invalidates the trip", func(t *testing.T) {
+		repo := new(mockRepository)
+		repo.On("AcceptInvitation", ctx, tripID, memberID).
+			Return(&Invitation{TripID: tripID, UserID: memberID, GroupID: groupID, Role: "editor"}, nil).Once()
+
+		bus := events.NewLocalBus()
+		var published []events.Event
+		for _, eventType := range []string{events.TripCollaboratorsChanged, events.TripInvalidated} {
+			bus.Subscribe(eventType, func(ctx context.Context, event events.Event) error {
+				published = append(published, event)
+				return nil
+			})
+		}
+
+		service := newTestService(repo, nil)
+		service.SetEventBus(bus)
+
+		invitation, err := service.AcceptInvitation(ctx, memberID, tripID)
+		require.NoError(t, err)
+		assert.Equal(t, "editor", invitation.Role)
+
+		require.Len(t, published, 2)
+		assert.Equal(t, events.TripCollaboratorsChanged, published[0].Type)
+		assert.Equal(t, memberID, published[0].Data["user_id"])
+		assert.Equal(t, "editor", published[0].Data["role"])
+		assert.Equal(t, cache.TripInvalidation{TripID: tripID, UserIDs: []string{memberID}}, cache.TripInvalidationFromEvent(published[1]))
+	})
+
+	t.Run("nothing is announced without an invitation", func(t *testing.T) {
+		repo := new(mockRepository)
+		repo.On("AcceptInvitation", ctx, tripID, strangerID).Return(nil, ErrInvitationNotFound).Once()
+
+		bus := events.NewLocalBus()
+		bus.Subscribe(events.TripCollaboratorsChanged, func(ctx context.Context, event events.Event) error {
+			t.Error("unexpected event")
+			return nil
+		})
+
+		service := newTestService(repo, nil)
+		service.SetEventBus(bus)
+
+		_, err := service.AcceptInvitation(ctx, strangerID, tripID)
+		assert.ErrorIs(t, err, ErrInvitationNotFound)
+	})
+}
@@ -0,0 +1,69 @@
+package places
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownAmenity is returned for amenities that are not on the managed list
+var ErrUnknownAmenity = errors.New("unknown amenity")
+
+// Amenity is a facility a place can offer
+type Amenity struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	// Icon names a lucide icon
+	Icon string `json:"icon"`
+}
+
+// managedAmenities are the amenities places can offer. The places table
+// checks amenities against the same IDs, so adding one takes a migration
+// too.
+var managedAmenities = []Amenity{
+	{ID: "toilets", Label: "Toilets", Icon: "toilet"},
+	{ID: "potable_water", Label: "Drinking water", Icon: "droplet"},
+	{ID: "ev_charging", Label: "EV charging", Icon: "plug-zap"},
+	{ID: "showers", Label: "Showers", Icon: "shower-head"},
+	{ID: "parking", Label: "Parking", Icon: "square-parking"},
+	{ID: "picnic_tables", Label: "Picnic tables", Icon: "utensils"},
+	{ID: "trash_bins", Label: "Trash bins", Icon: "trash-2"},
+	{ID: "wifi", Label: "Wi-Fi", Icon: "wifi"},
+	{ID: "bike_racks", Label: "Bike racks", Icon: "bike"},
+}
+
+// Amenities returns the managed list of amenities
+func Amenities() []Amenity {
+	list := make([]Amenity, len(managedAmenities))
+	copy(list, managedAmenities)
+	return list
+}
+
+// IsAmenity reports whether the ID is on the managed list
+func IsAmenity(id string) bool {
+	for _, amenity := range managedAmenities {
+		if amenity.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeAmenities checks the amenities against the managed list, lower
+// casing them and dropping repeats. The result is never nil, so it stores as
+// an empty list.
+func NormalizeAmenities(ids []string) ([]string, error) {
+	normalized := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if !IsAmenity(id) {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownAmenity, id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			normalized = append(normalized, id)
+		}
+	}
+	return normalized, nil
+}
@@ -0,0 +1,41 @@
+package places
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeAmenities(t *testing.T) {
+	t.Run("listed amenities", func(t *testing.T) {
+		amenities, err := NormalizeAmenities([]string{" Toilets", "showers", "toilets"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"toilets", "showers"}, amenities)
+	})
+
+	t.Run("none is an empty list", func(t *testing.T) {
+		amenities, err := NormalizeAmenities(nil)
+		require.NoError(t, err)
+		assert.NotNil(t, amenities)
+		assert.Empty(t, amenities)
+	})
+
+	t.Run("unknown amenity", func(t *testing.T) {
+		_, err := NormalizeAmenities([]string{"toilets", "hot tub"})
+		assert.ErrorIs(t, err, ErrUnknownAmenity)
+		assert.ErrorContains(t, err, "hot tub")
+	})
+}
+
+func TestAmenities(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, amenity := range Amenities() {
+		assert.False(t, seen[amenity.ID], "%s is listed twice", amenity.ID)
+		seen[amenity.ID] = true
+		assert.NotEmpty(t, amenity.Label)
+		assert.NotEmpty(t, amenity.Icon)
+		assert.True(t, IsAmenity(amenity.ID))
+	}
+	assert.False(t, IsAmenity("hot_tub"))
+}
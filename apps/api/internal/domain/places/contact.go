@@ -0,0 +1,246 @@
+package places
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrInvalidContactInfo is returned for contact details that cannot be used
+var ErrInvalidContactInfo = errors.New("invalid contact info")
+
+// ContactInfo stores contact information in JSONB. Clients send the
+// details; the verification is only set by the contact check.
+type ContactInfo struct {
+	Phone   string `json:"phone,omitempty"`
+	Email   string `json:"email,omitempty"`
+	Website string `json:"website,omitempty"`
+	Social  Social `json:"social,omitempty"`
+
+	Verification *ContactVerification `json:"verification,omitempty"`
+}
+
+type Social struct {
+	Facebook  string `json:"facebook,omitempty"`
+	Instagram string `json:"instagram,omitempty"`
+	Twitter   string `json:"twitter,omitempty"`
+}
+
+// ContactVerification is the outcome of the last check of a place's links
+type ContactVerification struct {
+	// Verified is set when every link answered
+	Verified bool `json:"verified"`
+	// DeadLinks are the links that are gone: not found, or no such host
+	DeadLinks []string `json:"dead_links,omitempty"`
+	// Unreachable links did not give a clear answer
+	Unreachable []string  `json:"unreachable,omitempty"`
+	CheckedAt   time.Time `json:"checked_at"`
+}
+
+// ContactLinks are ready to use links to the contact details, such as a
+// tel: URI for click-to-call
+type ContactLinks struct {
+	Call    string `json:"call,omitempty"`
+	Email   string `json:"email,omitempty"`
+	Website string `json:"website,omitempty"`
+}
+
+// socialNetworks are the hosts profile links may point at and the base URL
+// handles are turned into
+var socialNetworks = map[string]struct {
+	hosts []string
+	base  string
+}{
+	"facebook":  {hosts: []string{"facebook.com", "fb.com"}, base: "https://www.facebook.com/"},
+	"instagram": {hosts: []string{"instagram.com"}, base: "https://www.instagram.com/"},
+	"twitter":   {hosts: []string{"twitter.com", "x.com"}, base: "https://x.com/"},
+}
+
+var socialHandle = regexp.MustCompile(`^[A-Za-z0-9._]{1,50}$`)
+
+// Normalize validates the contact details and rewrites them in one form:
+// phone numbers as digits with an optional leading +, lower case email
+// addresses, websites and social profiles as https URLs. Any verification
+// sent along is dropped.
+func (c *ContactInfo) Normalize() error {
+	c.Verification = nil
+
+	if c.Phone = strings.TrimSpace(c.Phone); c.Phone != "" {
+		phone, err := normalizePhone(c.Phone)
+		if err != nil {
+			return err
+		}
+		c.Phone = phone
+	}
+
+	if c.Email = strings.TrimSpace(c.Email); c.Email != "" {
+		address, err := mail.ParseAddress(c.Email)
+		if err != nil || address.Name != "" || len(address.Address) > 254 {
+			return fmt.Errorf("%w: email %q is not an address", ErrInvalidContactInfo, c.Email)
+		}
+		c.Email = strings.ToLower(address.Address)
+	}
+
+	if c.Website = strings.TrimSpace(c.Website); c.Website != "" {
+		website, err := normalizeWebsite(c.Website)
+		if err != nil {
+			return err
+		}
+		c.Website = website
+	}
+
+	for network, profile := range c.Social.profiles() {
+		if *profile = strings.TrimSpace(*profile); *profile == "" {
+			continue
+		}
+		normalized, err := normalizeProfile(network, *profile)
+		if err != nil {
+			return err
+		}
+		*profile = normalized
+	}
+
+	return nil
+}
+
+func (s *Social) profiles() map[string]*string {
+	return map[string]*string{
+		"facebook":  &s.Facebook,
+		"instagram": &s.Instagram,
+		"twitter":   &s.Twitter,
+	}
+}
+
+// normalizePhone keeps the digits of a phone number and its leading +,
+// dropping spaces, dashes, dots and brackets. An international 00 prefix
+// becomes +.
+func normalizePhone(phone string) (string, error) {
+	var digits strings.Builder
+	for i, r := range phone {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+			digits.WriteRune(r)
+		case strings.ContainsRune(" -.()", r):
+		default:
+			return "", fmt.Errorf("%w: phone %q has characters other than digits", ErrInvalidContactInfo, phone)
+		}
+	}
+
+	normalized := digits.String()
+	if strings.HasPrefix(normalized, "00") {
+		normalized = "+" + normalized[2:]
+	}
+	if count := len(strings.TrimPrefix(normalized, "+")); count < 5 || count > 15 {
+		return "", fmt.Errorf("%w: phone %q should have 5 to 15 digits", ErrInvalidContactInfo, phone)
+	}
+	return normalized, nil
+}
+
+// normalizeWebsite makes the website an http or https URL, https when no
+// scheme is given
+func normalizeWebsite(website string) (string, error) {
+	if !strings.Contains(website, "://") {
+		website = "https://" + website
+	}
+
+	u, err := url.Parse(website)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !strings.Contains(u.Hostname(), ".") || u.User != nil || len(website) > 500 {
+		return "", fmt.Errorf("%w: website %q is not a web address", ErrInvalidContactInfo, website)
+	}
+	u.Host = strings.ToLower(u.Host)
+	return u.String(), nil
+}
+
+// normalizeProfile turns a handle, with or without an @, or a profile URL on
+// the network into a profile URL
+func normalizeProfile(network, profile string) (string, error) {
+	social := socialNetworks[network]
+
+	if handle := strings.TrimPrefix(profile, "@"); socialHandle.MatchString(handle) {
+		return social.base + handle, nil
+	}
+
+	u, err := normalizeWebsite(profile)
+	if err == nil {
+		parsed, _ := url.Parse(u)
+		host := strings.TrimPrefix(parsed.Hostname(), "www.")
+		for _, allowed := range social.hosts {
+			if host == allowed || strings.HasSuffix(host, "."+allowed) {
+				parsed.Scheme = "https"
+				return parsed.String(), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: %s profile %q is not a handle or %s link", ErrInvalidContactInfo, network, profile, network)
+}
+
+// Links returns the click-to-call, email and website links of the details
+func (c *ContactInfo) Links() *ContactLinks {
+	links := &ContactLinks{Website: c.Website}
+	if c.Phone != "" {
+		links.Call = "tel:" + c.Phone
+	}
+	if c.Email != "" {
+		links.Email = "mailto:" + c.Email
+	}
+	if *links == (ContactLinks{}) {
+		return nil
+	}
+	return links
+}
+
+// checkedLinks are the links the contact check follows, by the name they are
+// reported under
+func (c *ContactInfo) checkedLinks() map[string]string {
+	links := make(map[string]string)
+	if c.Website != "" {
+		links["website"] = c.Website
+	}
+	for network, profile := range c.Social.profiles() {
+		if *profile != "" {
+			links["social."+network] = *profile
+		}
+	}
+	return links
+}
+
+// MarshalJSON adds the links to the contact details in responses
+func (c ContactInfo) MarshalJSON() ([]byte, error) {
+	type contactInfo ContactInfo
+	return json.Marshal(struct {
+		contactInfo
+		Links *ContactLinks `json:"links,omitempty"`
+	}{contactInfo(c), c.Links()})
+}
+
+// Value stores the details without their links
+func (c ContactInfo) Value() (driver.Value, error) {
+	type contactInfo ContactInfo
+	return json.Marshal(contactInfo(c))
+}
+
+func (c *ContactInfo) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return nil
+	}
+
+	return json.Unmarshal(data, c)
+}
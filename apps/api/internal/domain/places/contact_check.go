@@ -0,0 +1,198 @@
+package places
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+)
+
+// JobCheckContacts checks the links in place contact details, the places
+// checked longest ago first
+const JobCheckContacts = "places.check_contacts"
+
+const (
+	// contactCheckInterval is how often a check is queued
+	contactCheckInterval = 6 * time.Hour
+
+	// contactRecheckAfter is how long a check holds before the links are
+	// followed again
+	contactRecheckAfter = 30 * 24 * time.Hour
+
+	// contactCheckBatchSize bounds how many places a single check covers
+	contactCheckBatchSize = 50
+)
+
+var errPrivateAddress = errors.New("link points at a private address")
+
+// linkState is what following a link showed
+type linkState int
+
+const (
+	linkAlive linkState = iota
+	linkDead
+	linkUnknown
+)
+
+// ContactChecker follows the links in place contact details so that dead
+// ones are flagged rather than shown as if they still worked
+type ContactChecker struct {
+	repo   Repository
+	queue  jobs.Queue
+	client *http.Client
+}
+
+// NewContactChecker creates a contact checker and registers its job handler
+func NewContactChecker(repo Repository, queue jobs.Queue) *ContactChecker {
+	c := &ContactChecker{
+		repo:   repo,
+		queue:  queue,
+		client: newLinkClient(),
+	}
+
+	queue.Register(JobCheckContacts, c.run)
+
+	return c
+}
+
+// newLinkClient returns a client for following links clients gave us. It
+// only connects to public addresses, so links cannot be used to reach
+// internal services.
+func newLinkClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+				return errPrivateAddress
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+}
+
+// Start queues a check now and then every contactCheckInterval until ctx is
+// cancelled
+func (c *ContactChecker) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(contactCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := c.queue.Enqueue(ctx, JobCheckContacts, struct{}{}); err != nil {
+				log.Printf("places: failed to queue contact check: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// run checks a batch of places whose links were never checked or not
+// checked for contactRecheckAfter
+func (c *ContactChecker) run(ctx context.Context, job *jobs.Job) error {
+	now := time.Now()
+	places, err := c.repo.ListContactsToCheck(ctx, now.Add(-contactRecheckAfter), contactCheckBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, place := range places {
+		verification := c.check(ctx, place.ContactInfo, now)
+		if err := c.repo.SetContactVerification(ctx, place.ID, place.UpdatedAt, verification); err != nil {
+			return fmt.Errorf("failed to record contact check of place %s: %w", place.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// check follows every link in the details
+func (c *ContactChecker) check(ctx context.Context, contact *ContactInfo, now time.Time) *ContactVerification {
+	verification := &ContactVerification{CheckedAt: now}
+
+	for name, link := range contact.checkedLinks() {
+		switch c.follow(ctx, link) {
+		case linkDead:
+			verification.DeadLinks = append(verification.DeadLinks, name)
+		case linkUnknown:
+			verification.Unreachable = append(verification.Unreachable, name)
+		}
+	}
+
+	sort.Strings(verification.DeadLinks)
+	sort.Strings(verification.Unreachable)
+	verification.Verified = len(verification.DeadLinks) == 0 && len(verification.Unreachable) == 0
+	return verification
+}
+
+// follow requests a link. Links are gone when their host does not exist or
+// the page is not found; servers that fail, refuse automated requests or
+// time out say nothing either way.
+func (c *ContactChecker) follow(ctx context.Context, link string) linkState {
+	resp, err := c.request(ctx, http.MethodHead, link)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = c.request(ctx, http.MethodGet, link)
+	}
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return linkDead
+		}
+		return linkUnknown
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return linkDead
+	case resp.StatusCode < 400:
+		return linkAlive
+	default:
+		return linkUnknown
+	}
+}
+
+func (c *ContactChecker) request(ctx context.Context, method, link string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "newMap-link-check/1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
@@ -0,0 +1,42 @@
+package places
+
+import (
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/geocode"
+)
+
+// placeFromGeocode turns a geocoder match into an unsaved public place
+func placeFromGeocode(result *geocode.Result) *Place {
+	now := time.Now()
+	place := &Place{
+		ID:            result.ID,
+		Name:          result.Text,
+		Description:   result.Name,
+		Type:          "poi",
+		Category:      []string{result.Kind},
+		StreetAddress: result.Address.Street,
+		City:          result.Address.City,
+		State:         result.Address.State,
+		Country:       result.Address.Country,
+		PostalCode:    result.Address.PostalCode,
+		Privacy:       "public",
+		Status:        "active",
+		Location: &GeoPoint{
+			Type:        "Point",
+			Coordinates: []float64{result.Longitude, result.Latitude},
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	switch result.Kind {
+	case geocode.KindAddress:
+		place.Type = "address"
+	case geocode.KindPlace:
+		place.Type = "area"
+	case geocode.KindRegion, geocode.KindCountry:
+		place.Type = "region"
+	}
+	return place
+}
@@ -1,11 +1,18 @@
 package places
 
 import (
+	"context"
 	"errors"
 	"log"
 	"strconv"
+	"time"
 
+	"github.com/Oferzz/newMap/apps/api/internal/campsites"
 	"github.com/Oferzz/newMap/apps/api/internal/middleware"
+	"github.com/Oferzz/newMap/apps/api/pkg/fieldset"
+	"github.com/Oferzz/newMap/apps/api/pkg/httpcache"
+	"github.com/Oferzz/newMap/apps/api/pkg/pagination"
+	"github.com/Oferzz/newMap/apps/api/pkg/repoerr"
 	"github.com/Oferzz/newMap/apps/api/pkg/response"
 	"github.com/gin-gonic/gin"
 )
@@ -15,7 +22,9 @@ var (
 )
 
 type Handler struct {
-	service Service
+	service   Service
+	campsites *campsites.Checker
+	views     ViewRecorder
 }
 
 func NewHandler(service Service) *Handler {
@@ -24,6 +33,21 @@ func NewHandler(service Service) *Handler {
 	}
 }
 
+// SetCampsites enables campground availability checks
+func (h *Handler) SetCampsites(checker *campsites.Checker) {
+	h.campsites = checker
+}
+
+// ViewRecorder remembers the places a user has viewed
+type ViewRecorder interface {
+	RecordView(ctx context.Context, userID, kind, id string)
+}
+
+// SetViewRecorder enables remembering the places users view
+func (h *Handler) SetViewRecorder(views ViewRecorder) {
+	h.views = views
+}
+
 func (h *Handler) Create(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
@@ -41,8 +65,8 @@ func (h *Handler) Create(c *gin.Context) {
 
 	place, err := h.service.Create(c.Request.Context(), userID, &input)
 	if err != nil {
-		switch err {
-		case ErrUnauthorized:
+		switch {
+		case errors.Is(err, ErrUnauthorized):
 			response.Forbidden(c, "You don't have permission to create places in this trip")
 		default:
 			response.BadRequest(c, err.Error())
@@ -53,6 +77,66 @@ func (h *Handler) Create(c *gin.Context) {
 	response.Created(c, place)
 }
 
+// GetAvailability checks a linked campground's free sites with its
+// reservation system for a stay of up to campsites.MaxNights nights
+func (h *Handler) GetAvailability(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if h.campsites == nil {
+		response.NotFound(c, "Campground availability is not available")
+		return
+	}
+
+	var query AvailabilityQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	start, _ := time.Parse(campsites.DateLayout, query.Start)
+	end, _ := time.Parse(campsites.DateLayout, query.End)
+	if !end.After(start) || end.Sub(start) > campsites.MaxNights*24*time.Hour {
+		response.BadRequest(c, "end must be after start and at most 31 nights later")
+		return
+	}
+
+	place, err := h.service.GetByIDWith(c.Request.Context(), userID, c.Param("id"), Relations{})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPlaceNotFound):
+			response.NotFound(c, "Place not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to view this place")
+		default:
+			response.FromError(c, err, "Failed to get place")
+		}
+		return
+	}
+	if place.Campground == nil {
+		response.NotFound(c, "Place is not linked to a campground")
+		return
+	}
+
+	availability, err := h.campsites.Availability(c.Request.Context(), place.Campground.Provider, place.Campground.FacilityID, start, end)
+	if err != nil {
+		switch {
+		case errors.Is(err, campsites.ErrFacilityNotFound), errors.Is(err, campsites.ErrUnknownProvider):
+			response.NotFound(c, "Campground not found with its reservation system")
+		default:
+			log.Printf("Failed to check availability of place %s: %v", place.ID, err)
+			response.BadGateway(c, "Failed to check campground availability")
+		}
+		return
+	}
+
+	response.Success(c, availability)
+}
+
 func (h *Handler) GetByID(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
@@ -63,20 +147,43 @@ func (h *Handler) GetByID(c *gin.Context) {
 	placeIDStr := c.Param("id")
 	placeID := placeIDStr
 
-	place, err := h.service.GetByID(c.Request.Context(), placeID, userID)
+	fields := fieldset.Parse(c.Query("fields"))
+	include, err := fieldset.ParseInclude(c.Query("include"), "media", "collaborators")
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	// The detail view loads everything unless told otherwise
+	relations := Relations{
+		Media:         include.Load("media", fields, true),
+		Collaborators: include.Load("collaborators", fields, true),
+	}
+
+	place, err := h.service.GetByIDWith(c.Request.Context(), userID, placeID, relations)
 	if err != nil {
-		switch err {
-		case ErrPlaceNotFound:
+		switch {
+		case errors.Is(err, ErrPlaceNotFound):
 			response.NotFound(c, "Place not found")
-		case ErrUnauthorized:
+		case errors.Is(err, ErrUnauthorized):
 			response.Forbidden(c, "You don't have permission to view this place")
 		default:
-			response.InternalServerError(c, "Failed to get place")
+			response.FromError(c, err, "Failed to get place")
 		}
 		return
 	}
 
-	response.Success(c, place)
+	if h.views != nil {
+		h.views.RecordView(c.Request.Context(), userID, "place", place.ID)
+	}
+
+	data, err := fields.Select(place)
+	if err != nil {
+		response.InternalServerError(c, "Failed to get place")
+		return
+	}
+
+	response.Success(c, data)
 }
 
 func (h *Handler) Update(c *gin.Context) {
@@ -97,15 +204,17 @@ func (h *Handler) Update(c *gin.Context) {
 		return
 	}
 
-	place, err := h.service.Update(c.Request.Context(), placeID, userID, &input)
+	place, err := h.service.Update(c.Request.Context(), userID, placeID, &input)
 	if err != nil {
-		switch err {
-		case ErrPlaceNotFound:
+		switch {
+		case errors.Is(err, ErrPlaceNotFound):
 			response.NotFound(c, "Place not found")
-		case ErrUnauthorized:
+		case errors.Is(err, ErrUnauthorized):
 			response.Forbidden(c, "You don't have permission to update this place")
+		case errors.Is(err, ErrInvalidOpeningHours), errors.Is(err, ErrInvalidContactInfo), errors.Is(err, ErrUnknownAmenity):
+			response.BadRequest(c, err.Error())
 		default:
-			response.InternalServerError(c, "Failed to update place")
+			response.FromError(c, err, "Failed to update place")
 		}
 		return
 	}
@@ -123,15 +232,15 @@ func (h *Handler) Delete(c *gin.Context) {
 	placeIDStr := c.Param("id")
 	placeID := placeIDStr
 
-	err := h.service.Delete(c.Request.Context(), placeID, userID)
+	err := h.service.Delete(c.Request.Context(), userID, placeID)
 	if err != nil {
-		switch err {
-		case ErrPlaceNotFound:
+		switch {
+		case errors.Is(err, ErrPlaceNotFound):
 			response.NotFound(c, "Place not found")
-		case ErrUnauthorized:
+		case errors.Is(err, ErrUnauthorized):
 			response.Forbidden(c, "You don't have permission to delete this place")
 		default:
-			response.InternalServerError(c, "Failed to delete place")
+			response.FromError(c, err, "Failed to delete place")
 		}
 		return
 	}
@@ -212,12 +321,20 @@ func (h *Handler) List(c *gin.Context) {
 	// Search query
 	filter.SearchQuery = c.Query("q")
 
+	// A cursor from a previous page takes precedence over page numbers
+	after, err := pagination.Decode(c.Query("cursor"))
+	if err != nil {
+		response.BadRequest(c, "Invalid cursor")
+		return
+	}
+	filter.After = after
+
 	// Calculate offset from page
 	offset := (page - 1) * limit
 
 	places, total, err := h.service.List(c.Request.Context(), userID, &filter, limit, offset)
 	if err != nil {
-		if err == ErrUnauthorized {
+		if errors.Is(err, ErrUnauthorized) {
 			response.Forbidden(c, "You don't have permission to view these places")
 		} else {
 			response.InternalServerError(c, "Failed to list places")
@@ -225,7 +342,26 @@ func (h *Handler) List(c *gin.Context) {
 		return
 	}
 
-	response.SuccessWithMeta(c, places, response.NewMeta(page, limit, total))
+	var nextCursor string
+	if len(places) > 0 {
+		last := places[len(places)-1]
+		nextCursor = pagination.Next(len(places), limit, last.CreatedAt, last.ID)
+	}
+
+	data, err := fieldset.Parse(c.Query("fields")).Select(places)
+	if err != nil {
+		response.InternalServerError(c, "Failed to list places")
+		return
+	}
+
+	if after != nil {
+		response.SuccessWithMeta(c, data, response.NewCursorMeta(limit, nextCursor))
+		return
+	}
+
+	meta := response.NewMeta(page, limit, total)
+	meta.NextCursor = nextCursor
+	response.SuccessWithMeta(c, data, meta)
 }
 
 func (h *Handler) GetByTripID(c *gin.Context) {
@@ -240,11 +376,11 @@ func (h *Handler) GetByTripID(c *gin.Context) {
 
 	places, err := h.service.GetTripPlaces(c.Request.Context(), userID, tripID)
 	if err != nil {
-		switch err {
-		case ErrUnauthorized:
+		switch {
+		case errors.Is(err, ErrUnauthorized):
 			response.Forbidden(c, "You don't have permission to view places in this trip")
 		default:
-			response.InternalServerError(c, "Failed to get places")
+			response.FromError(c, err, "Failed to get places")
 		}
 		return
 	}
@@ -297,7 +433,25 @@ func (h *Handler) Search(c *gin.Context) {
 	}
 
 	log.Printf("[PlaceHandler] Service returned %d places (total: %d)", len(places), total)
-	response.SuccessWithMeta(c, places, response.NewMeta(page, limit, total))
+
+	data, err := fieldset.Parse(c.Query("fields")).Select(places)
+	if err != nil {
+		response.InternalServerError(c, "Failed to search places")
+		return
+	}
+
+	keys := []string{httpcache.PlaceSearchKey}
+	for _, place := range places {
+		keys = append(keys, httpcache.PlaceKey(place.ID))
+	}
+	httpcache.Public(c, httpcache.Listing, keys...)
+
+	response.SuccessWithMeta(c, data, response.NewMeta(page, limit, total))
+}
+
+// ListAmenities returns the amenities places can offer, with their icons
+func (h *Handler) ListAmenities(c *gin.Context) {
+	response.Success(c, Amenities())
 }
 
 func (h *Handler) MarkAsVisited(c *gin.Context) {
@@ -322,13 +476,13 @@ func (h *Handler) MarkAsVisited(c *gin.Context) {
 
 	err := h.service.UpdateVisitStatus(c.Request.Context(), userID, placeID, input.IsVisited, nil)
 	if err != nil {
-		switch err {
-		case ErrPlaceNotFound:
+		switch {
+		case errors.Is(err, ErrPlaceNotFound):
 			response.NotFound(c, "Place not found")
-		case ErrUnauthorized:
+		case errors.Is(err, ErrUnauthorized):
 			response.Forbidden(c, "You don't have permission to update this place")
 		default:
-			response.InternalServerError(c, "Failed to update place")
+			response.FromError(c, err, "Failed to update place")
 		}
 		return
 	}
@@ -363,4 +517,177 @@ func (h *Handler) MarkAsVisited(c *gin.Context) {
 // 	}
 
 // 	response.Success(c, places)
-// }
\ No newline at end of file
+// }
+
+func (h *Handler) TransferOwnership(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	placeID := c.Param("id")
+
+	var input TransferOwnershipInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	transfer, err := h.service.TransferOwnership(c.Request.Context(), userID, placeID, input.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPlaceNotFound):
+			response.NotFound(c, "Place not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "Only the place owner can transfer ownership")
+		case errors.Is(err, ErrTransferPending):
+			response.Conflict(c, err.Error())
+		case repoerr.Known(err):
+			response.FromError(c, err, "Failed to transfer ownership")
+		default:
+			response.BadRequest(c, err.Error())
+		}
+		return
+	}
+
+	response.Created(c, transfer)
+}
+
+func (h *Handler) AcceptOwnershipTransfer(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	placeID := c.Param("id")
+
+	place, err := h.service.AcceptOwnershipTransfer(c.Request.Context(), userID, placeID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPlaceNotFound):
+			response.NotFound(c, "Place not found")
+		case errors.Is(err, ErrTransferNotFound):
+			response.NotFound(c, "No pending ownership transfer")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "Only the recipient can accept this transfer")
+		default:
+			response.FromError(c, err, "Failed to accept ownership transfer")
+		}
+		return
+	}
+
+	response.Success(c, place)
+}
+
+func (h *Handler) DeclineOwnershipTransfer(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	placeID := c.Param("id")
+
+	err := h.service.DeclineOwnershipTransfer(c.Request.Context(), userID, placeID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTransferNotFound):
+			response.NotFound(c, "No pending ownership transfer")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You can't decline this transfer")
+		default:
+			response.FromError(c, err, "Failed to decline ownership transfer")
+		}
+		return
+	}
+
+	response.Success(c, map[string]string{
+		"message": "Ownership transfer declined",
+	})
+}
+
+// CreateReview reviews the place, with a rating and optional text and photos
+func (h *Handler) CreateReview(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input CreatePlaceReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	review, err := h.service.ReviewPlace(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		h.reviewError(c, err, "Failed to review place")
+		return
+	}
+
+	response.Created(c, review)
+}
+
+// ListReviews returns a page of the place's reviews
+func (h *Handler) ListReviews(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	reviews, err := h.service.ListReviews(c.Request.Context(), userID, c.Param("id"), limit, (page-1)*limit)
+	if err != nil {
+		h.reviewError(c, err, "Failed to list reviews")
+		return
+	}
+
+	response.Success(c, reviews)
+}
+
+// DeleteReview removes the user's review of the place
+func (h *Handler) DeleteReview(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.service.DeleteReview(c.Request.Context(), userID, c.Param("id")); err != nil {
+		h.reviewError(c, err, "Failed to delete review")
+		return
+	}
+
+	response.NoContent(c)
+}
+
+func (h *Handler) reviewError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrPlaceNotFound):
+		response.NotFound(c, "Place not found")
+	case errors.Is(err, ErrReviewNotFound):
+		response.NotFound(c, "Review not found")
+	case errors.Is(err, ErrUnauthorized):
+		response.Forbidden(c, "You don't have permission to view this place")
+	case errors.Is(err, ErrOwnPlaceReview), errors.Is(err, ErrInvalidReviewMedia):
+		response.BadRequest(c, err.Error())
+	default:
+		response.FromError(c, err, fallback)
+	}
+}
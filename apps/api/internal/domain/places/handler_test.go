@@ -10,14 +10,16 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Oferzz/newMap/apps/api/internal/campsites"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
-// MockService is a mock implementation of the Service interface
+// MockService stands in for the place service behind the handlers
 type MockService struct {
 	mock.Mock
+	Service
 }
 
 func (m *MockService) Create(ctx context.Context, userID string, input *CreatePlaceInput) (*Place, error) {
@@ -28,74 +30,14 @@ func (m *MockService) Create(ctx context.Context, userID string, input *CreatePl
 	return args.Get(0).(*Place), args.Error(1)
 }
 
-func (m *MockService) GetByID(ctx context.Context, userID, placeID string) (*Place, error) {
-	args := m.Called(ctx, userID, placeID)
+func (m *MockService) GetByIDWith(ctx context.Context, userID, placeID string, relations Relations) (*Place, error) {
+	args := m.Called(ctx, userID, placeID, relations)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*Place), args.Error(1)
 }
 
-func (m *MockService) Update(ctx context.Context, userID, placeID string, input *UpdatePlaceInput) (*Place, error) {
-	args := m.Called(ctx, userID, placeID, input)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*Place), args.Error(1)
-}
-
-func (m *MockService) Delete(ctx context.Context, userID, placeID string) error {
-	args := m.Called(ctx, userID, placeID)
-	return args.Error(0)
-}
-
-func (m *MockService) GetUserPlaces(ctx context.Context, userID string, limit, offset int) ([]*Place, int64, error) {
-	args := m.Called(ctx, userID, limit, offset)
-	if args.Get(0) == nil {
-		return nil, args.Get(1).(int64), args.Error(2)
-	}
-	return args.Get(0).([]*Place), args.Get(1).(int64), args.Error(2)
-}
-
-func (m *MockService) GetChildPlaces(ctx context.Context, userID, parentID string) ([]*Place, error) {
-	args := m.Called(ctx, userID, parentID)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).([]*Place), args.Error(1)
-}
-
-func (m *MockService) Search(ctx context.Context, userID string, input *SearchPlacesInput) ([]*Place, int64, error) {
-	args := m.Called(ctx, userID, input)
-	if args.Get(0) == nil {
-		return nil, args.Get(1).(int64), args.Error(2)
-	}
-	return args.Get(0).([]*Place), args.Get(1).(int64), args.Error(2)
-}
-
-func (m *MockService) GetNearby(ctx context.Context, userID string, input *NearbyPlacesInput) ([]*Place, error) {
-	args := m.Called(ctx, userID, input)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).([]*Place), args.Error(1)
-}
-
-func (m *MockService) AddCollaborator(ctx context.Context, userID, placeID, collaboratorID, role string) error {
-	args := m.Called(ctx, userID, placeID, collaboratorID, role)
-	return args.Error(0)
-}
-
-func (m *MockService) RemoveCollaborator(ctx context.Context, userID, placeID, collaboratorID string) error {
-	args := m.Called(ctx, userID, placeID, collaboratorID)
-	return args.Error(0)
-}
-
-func (m *MockService) UpdateCollaboratorRole(ctx context.Context, userID, placeID, collaboratorID, role string) error {
-	args := m.Called(ctx, userID, placeID, collaboratorID, role)
-	return args.Error(0)
-}
-
 func (m *MockService) List(ctx context.Context, userID string, filter *PlaceFilter, limit, offset int) ([]*Place, int64, error) {
 	args := m.Called(ctx, userID, filter, limit, offset)
 	if args.Get(0) == nil {
@@ -104,47 +46,12 @@ func (m *MockService) List(ctx context.Context, userID string, filter *PlaceFilt
 	return args.Get(0).([]*Place), args.Get(1).(int64), args.Error(2)
 }
 
-func (m *MockService) GetTripPlaces(ctx context.Context, userID, tripID string) ([]*Place, error) {
-	args := m.Called(ctx, userID, tripID)
+func (m *MockService) ReviewPlace(ctx context.Context, userID, placeID string, input *CreatePlaceReviewInput) (*PlaceReview, error) {
+	args := m.Called(ctx, userID, placeID, input)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).([]*Place), args.Error(1)
-}
-
-func (m *MockService) AddToTrip(ctx context.Context, userID, placeID, tripID string) error {
-	args := m.Called(ctx, userID, placeID, tripID)
-	return args.Error(0)
-}
-
-func (m *MockService) RemoveFromTrip(ctx context.Context, userID, placeID, tripID string) error {
-	args := m.Called(ctx, userID, placeID, tripID)
-	return args.Error(0)
-}
-
-func (m *MockService) UpdateVisitStatus(ctx context.Context, userID, placeID string, visited bool, visitDate *time.Time) error {
-	args := m.Called(ctx, userID, placeID, visited, visitDate)
-	return args.Error(0)
-}
-
-func (m *MockService) AddImages(ctx context.Context, userID, placeID string, images []string) error {
-	args := m.Called(ctx, userID, placeID, images)
-	return args.Error(0)
-}
-
-func (m *MockService) RemoveImage(ctx context.Context, userID, placeID string, imageURL string) error {
-	args := m.Called(ctx, userID, placeID, imageURL)
-	return args.Error(0)
-}
-
-func (m *MockService) UpdateRating(ctx context.Context, userID, placeID string, rating float32) error {
-	args := m.Called(ctx, userID, placeID, rating)
-	return args.Error(0)
-}
-
-func (m *MockService) AddNote(ctx context.Context, userID, placeID, note string) error {
-	args := m.Called(ctx, userID, placeID, note)
-	return args.Error(0)
+	return args.Get(0).(*PlaceReview), args.Error(1)
 }
 
 func TestHandler_CreatePlace(t *testing.T) {
@@ -221,7 +128,7 @@ func TestHandler_CreatePlace(t *testing.T) {
 			mockSetup: func(ms *MockService) {
 				ms.On("Create", mock.Anything, "user123", mock.Anything).Return(nil, errors.New("database error"))
 			},
-			expectedCode: http.StatusInternalServerError,
+			expectedCode: http.StatusBadRequest,
 			expectedBody: map[string]interface{}{
 				"success": false,
 			},
@@ -239,7 +146,7 @@ func TestHandler_CreatePlace(t *testing.T) {
 			router := gin.New()
 			router.POST("/places", func(c *gin.Context) {
 				c.Set("userID", tt.userID)
-				handler.CreatePlace(c)
+				handler.Create(c)
 			})
 
 			body, _ := json.Marshal(tt.input)
@@ -289,7 +196,7 @@ func TestHandler_GetPlace(t *testing.T) {
 					CreatedAt:   time.Now(),
 					UpdatedAt:   time.Now(),
 				}
-				ms.On("GetByID", mock.Anything, "user123", "place123").Return(place, nil)
+				ms.On("GetByIDWith", mock.Anything, "user123", "place123", AllRelations).Return(place, nil)
 			},
 			expectedCode: http.StatusOK,
 			expectedBody: map[string]interface{}{
@@ -301,7 +208,7 @@ func TestHandler_GetPlace(t *testing.T) {
 			userID:  "user123",
 			placeID: "nonexistent",
 			mockSetup: func(ms *MockService) {
-				ms.On("GetByID", mock.Anything, "user123", "nonexistent").Return(nil, ErrPlaceNotFound)
+				ms.On("GetByIDWith", mock.Anything, "user123", "nonexistent", AllRelations).Return(nil, ErrPlaceNotFound)
 			},
 			expectedCode: http.StatusNotFound,
 			expectedBody: map[string]interface{}{
@@ -313,7 +220,7 @@ func TestHandler_GetPlace(t *testing.T) {
 			userID:  "user456",
 			placeID: "place123",
 			mockSetup: func(ms *MockService) {
-				ms.On("GetByID", mock.Anything, "user456", "place123").Return(nil, ErrUnauthorized)
+				ms.On("GetByIDWith", mock.Anything, "user456", "place123", AllRelations).Return(nil, ErrUnauthorized)
 			},
 			expectedCode: http.StatusForbidden,
 			expectedBody: map[string]interface{}{
@@ -333,7 +240,7 @@ func TestHandler_GetPlace(t *testing.T) {
 			router := gin.New()
 			router.GET("/places/:id", func(c *gin.Context) {
 				c.Set("userID", tt.userID)
-				handler.GetPlace(c)
+				handler.GetByID(c)
 			})
 
 			req := httptest.NewRequest(http.MethodGet, "/places/"+tt.placeID, nil)
@@ -353,6 +260,96 @@ func TestHandler_GetPlace(t *testing.T) {
 	}
 }
 
+// campgroundProvider answers availability checks for one campground and
+// fails for any other
+type campgroundProvider struct {
+	err error
+}
+
+func (p *campgroundProvider) Availability(ctx context.Context, facilityID string, start, end time.Time) (*campsites.Availability, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	if facilityID != "232447" {
+		return nil, campsites.ErrFacilityNotFound
+	}
+	return &campsites.Availability{
+		Provider:   campsites.ProviderRecreationGov,
+		FacilityID: facilityID,
+		Nights:     []campsites.Night{{Date: start.Format(campsites.DateLayout), Available: 3, Total: 10}},
+	}, nil
+}
+
+func TestHandler_GetAvailability(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	campground := func(facilityID string) *Place {
+		return &Place{ID: "place123", Name: "Upper Pines", Campground: &CampgroundLink{Provider: campsites.ProviderRecreationGov, FacilityID: facilityID}}
+	}
+
+	tests := []struct {
+		name         string
+		query        string
+		place        *Place
+		placeErr     error
+		providerErr  error
+		expectedCode int
+	}{
+		{name: "free sites per night", query: "?start=2025-07-04&end=2025-07-06", place: campground("232447"), expectedCode: http.StatusOK},
+		{name: "missing dates", query: "?start=2025-07-04", expectedCode: http.StatusBadRequest},
+		{name: "malformed date", query: "?start=07/04/2025&end=2025-07-06", expectedCode: http.StatusBadRequest},
+		{name: "end before start", query: "?start=2025-07-06&end=2025-07-04", expectedCode: http.StatusBadRequest},
+		{name: "stay too long", query: "?start=2025-07-01&end=2025-08-02", expectedCode: http.StatusBadRequest},
+		{name: "place not found", query: "?start=2025-07-04&end=2025-07-06", placeErr: ErrPlaceNotFound, expectedCode: http.StatusNotFound},
+		{name: "place hidden", query: "?start=2025-07-04&end=2025-07-06", placeErr: ErrUnauthorized, expectedCode: http.StatusForbidden},
+		{name: "not a campground", query: "?start=2025-07-04&end=2025-07-06", place: &Place{ID: "place123"}, expectedCode: http.StatusNotFound},
+		{name: "unknown to its provider", query: "?start=2025-07-04&end=2025-07-06", place: campground("0"), expectedCode: http.StatusNotFound},
+		{name: "provider failure", query: "?start=2025-07-04&end=2025-07-06", place: campground("232447"), providerErr: errors.New("timeout"), expectedCode: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockService)
+			if tt.place != nil || tt.placeErr != nil {
+				mockService.On("GetByIDWith", mock.Anything, "user123", "place123", Relations{}).Return(tt.place, tt.placeErr)
+			}
+
+			checker := campsites.NewChecker()
+			checker.Register(campsites.ProviderRecreationGov, &campgroundProvider{err: tt.providerErr})
+			handler := NewHandler(mockService)
+			handler.SetCampsites(checker)
+
+			router := gin.New()
+			router.GET("/places/:id/availability", func(c *gin.Context) {
+				c.Set("userID", "user123")
+				handler.GetAvailability(c)
+			})
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/places/place123/availability"+tt.query, nil))
+
+			assert.Equal(t, tt.expectedCode, rec.Code, rec.Body.String())
+			if tt.expectedCode == http.StatusOK {
+				assert.Contains(t, rec.Body.String(), `"nights":[{"date":"2025-07-04","available":3,"total":10}]`)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+
+	t.Run("without a checker", func(t *testing.T) {
+		handler := NewHandler(new(MockService))
+		router := gin.New()
+		router.GET("/places/:id/availability", func(c *gin.Context) {
+			c.Set("userID", "user123")
+			handler.GetAvailability(c)
+		})
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/places/place123/availability?start=2025-07-04&end=2025-07-06", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
 func TestHandler_SearchPlaces(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -383,7 +380,7 @@ func TestHandler_SearchPlaces(t *testing.T) {
 						City:        "Paris",
 					},
 				}
-				ms.On("Search", mock.Anything, "user123", mock.AnythingOfType("*places.SearchPlacesInput")).Return(places, int64(2), nil)
+				ms.On("List", mock.Anything, "", &PlaceFilter{SearchQuery: "paris"}, 20, 0).Return(places, int64(2), nil)
 			},
 			expectedCode: http.StatusOK,
 			expectedBody: map[string]interface{}{
@@ -395,7 +392,7 @@ func TestHandler_SearchPlaces(t *testing.T) {
 			userID: "user123",
 			query:  "nonexistent",
 			mockSetup: func(ms *MockService) {
-				ms.On("Search", mock.Anything, "user123", mock.AnythingOfType("*places.SearchPlacesInput")).Return([]*Place{}, int64(0), nil)
+				ms.On("List", mock.Anything, "", &PlaceFilter{SearchQuery: "nonexistent"}, 20, 0).Return([]*Place{}, int64(0), nil)
 			},
 			expectedCode: http.StatusOK,
 			expectedBody: map[string]interface{}{
@@ -415,7 +412,7 @@ func TestHandler_SearchPlaces(t *testing.T) {
 			router := gin.New()
 			router.GET("/places/search", func(c *gin.Context) {
 				c.Set("userID", tt.userID)
-				handler.SearchPlaces(c)
+				handler.Search(c)
 			})
 
 			req := httptest.NewRequest(http.MethodGet, "/places/search?q="+tt.query+"&limit=20&offset=0", nil)
@@ -433,4 +430,78 @@ func TestHandler_SearchPlaces(t *testing.T) {
 			mockService.AssertExpectations(t)
 		})
 	}
-}
\ No newline at end of file
+}
+func TestHandler_CreateReview(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		body         string
+		mockSetup    func(*MockService)
+		expectedCode int
+	}{
+		{
+			name: "reviewed",
+			body: `{"rating": 4, "review_text": "Great views", "media_ids": ["20000000-0000-0000-0000-000000000001"]}`,
+			mockSetup: func(ms *MockService) {
+				ms.On("ReviewPlace", mock.Anything, "user123", "place123", &CreatePlaceReviewInput{
+					Rating:     4,
+					ReviewText: "Great views",
+					MediaIDs:   []string{"20000000-0000-0000-0000-000000000001"},
+				}).Return(&PlaceReview{ID: "review1", Rating: 4}, nil)
+			},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "rating out of range",
+			body:         `{"rating": 6}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "photos that are not media IDs",
+			body:         `{"rating": 3, "media_ids": ["photo.jpg"]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "own place",
+			body: `{"rating": 5}`,
+			mockSetup: func(ms *MockService) {
+				ms.On("ReviewPlace", mock.Anything, "user123", "place123", mock.Anything).Return(nil, ErrOwnPlaceReview)
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "already reviewed",
+			body: `{"rating": 5}`,
+			mockSetup: func(ms *MockService) {
+				ms.On("ReviewPlace", mock.Anything, "user123", "place123", mock.Anything).Return(nil, ErrReviewExists)
+			},
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockService)
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockService)
+			}
+
+			handler := NewHandler(mockService)
+			router := gin.New()
+			router.POST("/places/:id/reviews", func(c *gin.Context) {
+				c.Set("userID", "user123")
+				handler.CreateReview(c)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/places/place123/reviews", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package places
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Opening hours are local times in the place's time zone. A range closing at
+// or before it opens runs past midnight into the next day, and "24:00" closes
+// at the end of the day. An exception replaces the weekly hours of one date.
+
+// ErrInvalidOpeningHours is returned for opening hours that cannot be read
+var ErrInvalidOpeningHours = errors.New("invalid opening hours")
+
+const (
+	minutesPerDay  = 24 * 60
+	minutesPerWeek = 7 * minutesPerDay
+)
+
+// OpeningHours stores business hours in JSONB
+type OpeningHours struct {
+	// IANA time zone the hours are in, UTC when empty
+	Timezone string `json:"timezone,omitempty"`
+
+	Monday    []TimeRange `json:"monday,omitempty"`
+	Tuesday   []TimeRange `json:"tuesday,omitempty"`
+	Wednesday []TimeRange `json:"wednesday,omitempty"`
+	Thursday  []TimeRange `json:"thursday,omitempty"`
+	Friday    []TimeRange `json:"friday,omitempty"`
+	Saturday  []TimeRange `json:"saturday,omitempty"`
+	Sunday    []TimeRange `json:"sunday,omitempty"`
+
+	Exceptions []HoursException `json:"exceptions,omitempty"`
+}
+
+type TimeRange struct {
+	Open  string `json:"open"`  // "09:00"
+	Close string `json:"close"` // "17:00"
+}
+
+// HoursException is the hours of one date, such as a holiday, in place of
+// the weekly hours
+type HoursException struct {
+	Date  string      `json:"date"`            // "2026-12-25"
+	Hours []TimeRange `json:"hours,omitempty"` // none when closed all day
+	Note  string      `json:"note,omitempty"`
+}
+
+// Validate checks the time zone, dates and times of the hours
+func (o *OpeningHours) Validate() error {
+	if o.Timezone != "" {
+		if _, err := time.LoadLocation(o.Timezone); err != nil || o.Timezone == "Local" {
+			return fmt.Errorf("%w: unknown time zone %q", ErrInvalidOpeningHours, o.Timezone)
+		}
+	}
+
+	for day, ranges := range o.week() {
+		if err := validateRanges(ranges); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidOpeningHours, strings.ToLower(time.Weekday(day).String()), err)
+		}
+	}
+
+	seen := make(map[string]bool, len(o.Exceptions))
+	for _, exception := range o.Exceptions {
+		if _, err := time.Parse("2006-01-02", exception.Date); err != nil {
+			return fmt.Errorf("%w: exception date %q is not a date", ErrInvalidOpeningHours, exception.Date)
+		}
+		if seen[exception.Date] {
+			return fmt.Errorf("%w: more than one exception on %s", ErrInvalidOpeningHours, exception.Date)
+		}
+		seen[exception.Date] = true
+		if err := validateRanges(exception.Hours); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidOpeningHours, exception.Date, err)
+		}
+	}
+
+	return nil
+}
+
+func validateRanges(ranges []TimeRange) error {
+	for _, r := range ranges {
+		if _, err := parseClock(r.Open, false); err != nil {
+			return err
+		}
+		if _, err := parseClock(r.Close, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseClock returns the minutes since midnight of an "HH:MM" time.
+// "24:00" is only a closing time.
+func parseClock(value string, closing bool) (int, error) {
+	hours, minutes, ok := strings.Cut(value, ":")
+	if !ok || len(hours) != 2 || len(minutes) != 2 {
+		return 0, fmt.Errorf("time %q is not HH:MM", value)
+	}
+	h, errH := strconv.Atoi(hours)
+	m, errM := strconv.Atoi(minutes)
+	if errH != nil || errM != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && (m != 0 || !closing)) {
+		return 0, fmt.Errorf("time %q is not HH:MM", value)
+	}
+	return h*60 + m, nil
+}
+
+// week returns the weekly ranges indexed by time.Weekday
+func (o *OpeningHours) week() [7][]TimeRange {
+	return [7][]TimeRange{o.Sunday, o.Monday, o.Tuesday, o.Wednesday, o.Thursday, o.Friday, o.Saturday}
+}
+
+// Known reports whether the hours say when the place is open at all
+func (o *OpeningHours) Known() bool {
+	for _, ranges := range o.week() {
+		if len(ranges) > 0 {
+			return true
+		}
+	}
+	return len(o.Exceptions) > 0
+}
+
+func (o *OpeningHours) location() *time.Location {
+	if o.Timezone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(o.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// on returns the ranges the place keeps on a date
+func (o *OpeningHours) on(date time.Time) []TimeRange {
+	day := date.Format("2006-01-02")
+	for _, exception := range o.Exceptions {
+		if exception.Date == day {
+			return exception.Hours
+		}
+	}
+	return o.week()[date.Weekday()]
+}
+
+// OpenAt reports whether the place is open at the instant. Ranges that
+// cannot be read count as closed.
+func (o *OpeningHours) OpenAt(at time.Time) bool {
+	local := at.In(o.location())
+	minute := local.Hour()*60 + local.Minute()
+
+	for _, r := range o.on(local) {
+		opens, closes, ok := r.minutes()
+		if ok && minute >= opens && (closes <= opens || minute < closes) {
+			return true
+		}
+	}
+
+	// Ranges of the day before that run past midnight
+	for _, r := range o.on(local.AddDate(0, 0, -1)) {
+		opens, closes, ok := r.minutes()
+		if ok && closes <= opens && minute < closes {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r TimeRange) minutes() (opens, closes int, ok bool) {
+	opens, err := parseClock(r.Open, false)
+	if err != nil {
+		return 0, 0, false
+	}
+	closes, err = parseClock(r.Close, true)
+	if err != nil {
+		return 0, 0, false
+	}
+	return opens, closes, true
+}
+
+// MinuteRange is a range of minutes of the week, from Monday 00:00 UTC,
+// including From and excluding To
+type MinuteRange struct {
+	From int `json:"gte"`
+	To   int `json:"lt"`
+}
+
+// WeeklyMinutes returns the weekly hours as ranges of minutes of the week in
+// UTC, at the time zone's offset at the instant. Exceptions are left out, so
+// the ranges only narrow a search down; OpenAt has the final say.
+func (o *OpeningHours) WeeklyMinutes(at time.Time) []MinuteRange {
+	_, offset := at.In(o.location()).Zone()
+	shift := -offset / 60
+
+	var ranges []MinuteRange
+	for day, dayRanges := range o.week() {
+		// Monday starts the week
+		start := ((day+6)%7)*minutesPerDay + shift
+		for _, r := range dayRanges {
+			opens, closes, ok := r.minutes()
+			if !ok {
+				continue
+			}
+			if closes <= opens {
+				closes += minutesPerDay
+			}
+			ranges = appendWrapped(ranges, start+opens, start+closes)
+		}
+	}
+	return ranges
+}
+
+// appendWrapped appends the range, split in two where it crosses the end of
+// the week
+func appendWrapped(ranges []MinuteRange, from, to int) []MinuteRange {
+	length := to - from
+	from = ((from % minutesPerWeek) + minutesPerWeek) % minutesPerWeek
+	to = from + length
+	if to > minutesPerWeek {
+		return append(ranges, MinuteRange{From: from, To: minutesPerWeek}, MinuteRange{From: 0, To: to - minutesPerWeek})
+	}
+	return append(ranges, MinuteRange{From: from, To: to})
+}
+
+// MinuteOfWeek returns the minute of the week of the instant in UTC, from
+// Monday 00:00
+func MinuteOfWeek(at time.Time) int {
+	at = at.UTC()
+	return ((int(at.Weekday())+6)%7)*minutesPerDay + at.Hour()*60 + at.Minute()
+}
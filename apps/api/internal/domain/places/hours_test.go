@@ -0,0 +1,141 @@
+package places
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpeningHours_Validate(t *testing.T) {
+	valid := func() OpeningHours {
+		return OpeningHours{
+			Timezone: "Asia/Jerusalem",
+			Friday:   []TimeRange{{Open: "08:00", Close: "14:00"}},
+			Saturday: []TimeRange{{Open: "20:00", Close: "02:00"}},
+			Sunday:   []TimeRange{{Open: "00:00", Close: "24:00"}},
+			Exceptions: []HoursException{
+				{Date: "2026-10-03", Note: "Yom Kippur"},
+			},
+		}
+	}
+
+	hours := valid()
+	assert.NoError(t, hours.Validate())
+
+	tests := []struct {
+		name string
+		set  func(*OpeningHours)
+	}{
+		{"unknown time zone", func(o *OpeningHours) { o.Timezone = "Mars/Olympus" }},
+		{"local time zone", func(o *OpeningHours) { o.Timezone = "Local" }},
+		{"single digit hour", func(o *OpeningHours) { o.Friday[0].Open = "8:00" }},
+		{"minutes out of range", func(o *OpeningHours) { o.Friday[0].Close = "14:60" }},
+		{"opening at 24:00", func(o *OpeningHours) { o.Sunday[0].Open = "24:00" }},
+		{"past 24:00", func(o *OpeningHours) { o.Sunday[0].Close = "24:30" }},
+		{"bad exception date", func(o *OpeningHours) { o.Exceptions[0].Date = "03/10/2026" }},
+		{"repeated exception", func(o *OpeningHours) { o.Exceptions = append(o.Exceptions, o.Exceptions[0]) }},
+		{"bad exception time", func(o *OpeningHours) { o.Exceptions[0].Hours = []TimeRange{{Open: "noon", Close: "18:00"}} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hours := valid()
+			tt.set(&hours)
+			assert.ErrorIs(t, hours.Validate(), ErrInvalidOpeningHours)
+		})
+	}
+}
+
+func TestOpeningHours_OpenAt(t *testing.T) {
+	jerusalem, err := time.LoadLocation("Asia/Jerusalem")
+	require.NoError(t, err)
+
+	hours := &OpeningHours{
+		Timezone: "Asia/Jerusalem",
+		Monday:   []TimeRange{{Open: "09:00", Close: "13:00"}, {Open: "16:00", Close: "19:00"}},
+		Friday:   []TimeRange{{Open: "22:00", Close: "03:00"}},
+		Sunday:   []TimeRange{{Open: "00:00", Close: "24:00"}},
+		Exceptions: []HoursException{
+			{Date: "2026-10-19"}, // a Monday, closed
+			{Date: "2026-10-21", Hours: []TimeRange{{Open: "10:00", Close: "12:00"}}}, // a Wednesday
+		},
+	}
+
+	// Monday 12 October 2026
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, jerusalem)
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		open bool
+	}{
+		{"morning range", at(12, 9, 0), true},
+		{"closes at the close", at(12, 13, 0), false},
+		{"between ranges", at(12, 14, 30), false},
+		{"afternoon range", at(12, 18, 59), true},
+		{"other zone", at(12, 10, 0).UTC(), true},
+		{"overnight before midnight", at(16, 23, 30), true},
+		{"overnight after midnight", at(17, 2, 59), true},
+		{"overnight closed", at(17, 3, 0), false},
+		{"all day", at(18, 23, 59), true},
+		{"closed exception", at(19, 10, 0), false},
+		{"exception hours", at(21, 11, 0), true},
+		{"no weekly hours", at(14, 11, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.open, hours.OpenAt(tt.at))
+		})
+	}
+}
+
+func TestOpeningHours_WeeklyMinutes(t *testing.T) {
+	// Israel is at UTC+3 in summer
+	summer := time.Date(2026, time.July, 1, 12, 0, 0, 0, time.UTC)
+	hours := &OpeningHours{
+		Timezone: "Asia/Jerusalem",
+		Monday:   []TimeRange{{Open: "01:00", Close: "05:00"}},
+		Sunday:   []TimeRange{{Open: "22:00", Close: "02:00"}},
+	}
+
+	ranges := hours.WeeklyMinutes(summer)
+	assert.ElementsMatch(t, []MinuteRange{
+		// Sunday 22:00 to Monday 02:00 local is Sunday 19:00 to 23:00 UTC
+		{From: 6*minutesPerDay + 19*60, To: 6*minutesPerDay + 23*60},
+		// Monday 01:00 local is Sunday 22:00 UTC, across the end of the week
+		{From: 6*minutesPerDay + 22*60, To: minutesPerWeek},
+		{From: 0, To: 2 * 60},
+	}, ranges)
+
+	// Any instant in a range is open
+	for _, r := range ranges {
+		for _, minute := range []int{r.From, r.To - 1} {
+			monday := time.Date(2026, time.June, 29, 0, 0, 0, 0, time.UTC)
+			instant := monday.Add(time.Duration(minute) * time.Minute)
+			assert.Equal(t, minute, MinuteOfWeek(instant))
+			assert.True(t, hours.OpenAt(instant), instant)
+		}
+	}
+}
+
+func TestPlace_OpenNowJSON(t *testing.T) {
+	place := Place{ID: "p1", OpeningHours: &OpeningHours{Sunday: []TimeRange{{Open: "00:00", Close: "24:00"}}}}
+	data, err := json.Marshal(place)
+	require.NoError(t, err)
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &body))
+	assert.Equal(t, "p1", body["id"])
+	assert.Contains(t, body, "open_now")
+
+	// Without hours, open is not known
+	data, err = json.Marshal(&Place{ID: "p2"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "open_now")
+}
@@ -0,0 +1,89 @@
+package places
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Indexer keeps the search index in step with places
+type Indexer interface {
+	IndexPlace(ctx context.Context, placeID string, place map[string]interface{}) error
+	DeleteFromIndex(ctx context.Context, docType, documentID string) error
+}
+
+// SetIndexer enables indexing places for search
+func (s *servicePg) SetIndexer(indexer Indexer) {
+	s.indexer = indexer
+}
+
+// Searchable reports whether the place belongs in the search index. Search
+// still checks each result against the viewer.
+func (p *Place) Searchable() bool {
+	return p.Status == "active" && p.Privacy != "private"
+}
+
+// syncIndex indexes the place, or removes it from the index when it is no
+// longer searchable. Failures are logged; the index catches up on the next
+// change.
+func (s *servicePg) syncIndex(ctx context.Context, place *Place) {
+	if !place.Searchable() {
+		s.unindex(ctx, place.ID)
+		return
+	}
+	if s.indexer == nil {
+		return
+	}
+
+	if err := s.indexer.IndexPlace(ctx, place.ID, SearchDocument(place, time.Now())); err != nil {
+		log.Printf("Failed to index place %s: %v", place.ID, err)
+	}
+}
+
+// unindex removes a place from the search index
+func (s *servicePg) unindex(ctx context.Context, placeID string) {
+	if s.indexer == nil {
+		return
+	}
+
+	if err := s.indexer.DeleteFromIndex(ctx, "place", placeID); err != nil {
+		log.Printf("Failed to remove place %s from search index: %v", placeID, err)
+	}
+}
+
+// SearchDocument is the view of a place that is indexed for search. Opening
+// hours are indexed as minutes of the week at the offset their time zone has
+// at the time of indexing.
+func SearchDocument(place *Place, at time.Time) map[string]interface{} {
+	doc := map[string]interface{}{
+		"id":             place.ID,
+		"name":           place.Name,
+		"description":    place.Description,
+		"type":           place.Type,
+		"category":       place.Category,
+		"tags":           place.Tags,
+		"accessibility":  place.Accessibility,
+		"amenities":      place.Amenities,
+		"city":           place.City,
+		"state":          place.State,
+		"country":        place.Country,
+		"average_rating": place.AverageRating,
+		"owner_id":       place.CreatedBy,
+		"privacy":        place.Privacy,
+		"created_at":     place.CreatedAt,
+		"updated_at":     place.UpdatedAt,
+	}
+
+	if place.Location != nil && len(place.Location.Coordinates) == 2 {
+		doc["location"] = map[string]float64{
+			"lat": place.Location.Coordinates[1],
+			"lon": place.Location.Coordinates[0],
+		}
+	}
+
+	if place.OpeningHours != nil {
+		doc["open_hours"] = place.OpeningHours.WeeklyMinutes(at)
+	}
+
+	return doc
+}
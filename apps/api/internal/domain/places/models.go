@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/Oferzz/newMap/apps/api/pkg/pagination"
 	"github.com/lib/pq"
 )
 
@@ -27,6 +28,8 @@ type Place struct {
 	OpeningHours  *OpeningHours  `db:"opening_hours" json:"opening_hours,omitempty"`
 	ContactInfo   *ContactInfo   `db:"contact_info" json:"contact_info,omitempty"`
 	Amenities     pq.StringArray `db:"amenities" json:"amenities"`
+	Accessibility pq.StringArray `db:"accessibility" json:"accessibility"`
+	AccessFees    AccessFees     `db:"access_fees" json:"access_fees"`
 	AverageRating *float32       `db:"average_rating" json:"average_rating,omitempty"`
 	RatingCount   int            `db:"rating_count" json:"rating_count"`
 	Privacy       string         `db:"privacy" json:"privacy"`
@@ -37,48 +40,86 @@ type Place struct {
 	// Joined fields
 	Media         []Media        `json:"media,omitempty"`
 	Collaborators []Collaborator `json:"collaborators,omitempty"`
+	WaterSource   *WaterSource   `json:"water_source,omitempty"`
+	Campground    *CampgroundLink `json:"campground,omitempty"`
 }
 
-// GeoPoint represents a PostGIS geography point
-type GeoPoint struct {
-	Type        string    `json:"type"`
-	Coordinates []float64 `json:"coordinates"` // [longitude, latitude]
+// MarshalJSON adds open_now to places with opening hours: whether they are
+// open at the time of the response
+func (p Place) MarshalJSON() ([]byte, error) {
+	type place Place
+	out := struct {
+		place
+		OpenNow *bool `json:"open_now,omitempty"`
+	}{place: place(p)}
+
+	if p.OpeningHours != nil && p.OpeningHours.Known() {
+		open := p.OpeningHours.OpenAt(time.Now())
+		out.OpenNow = &open
+	}
+
+	return json.Marshal(out)
 }
 
-// GeoPolygon represents a PostGIS geography polygon
-type GeoPolygon struct {
-	Type        string        `json:"type"`
-	Coordinates [][][]float64 `json:"coordinates"`
+// AccessFee is a fee or pass needed to get to or use a place, such as a
+// parking pass or entry fee. The amount is left out when it varies or is not
+// known.
+type AccessFee struct {
+	Kind     string   `json:"kind" binding:"required,oneof=parking_pass entry_fee shuttle permit_fee camping_fee other"`
+	Name     string   `json:"name" binding:"required,max=200"`
+	Amount   *float64 `json:"amount,omitempty" binding:"omitempty,min=0,max=100000"`
+	Currency string   `json:"currency,omitempty" binding:"required_with=Amount,omitempty,iso4217"`
+	Per      string   `json:"per,omitempty" binding:"omitempty,oneof=person vehicle group"`
+	URL      string   `json:"url,omitempty" binding:"omitempty,url,max=500"`
+	Notes    string   `json:"notes,omitempty" binding:"max=500"`
 }
 
-// OpeningHours stores business hours in JSONB
-type OpeningHours struct {
-	Monday    []TimeRange `json:"monday,omitempty"`
-	Tuesday   []TimeRange `json:"tuesday,omitempty"`
-	Wednesday []TimeRange `json:"wednesday,omitempty"`
-	Thursday  []TimeRange `json:"thursday,omitempty"`
-	Friday    []TimeRange `json:"friday,omitempty"`
-	Saturday  []TimeRange `json:"saturday,omitempty"`
-	Sunday    []TimeRange `json:"sunday,omitempty"`
+// AccessFees is a JSONB list of access fees
+type AccessFees []AccessFee
+
+// CategoryWaterSource marks places that are water sources
+const CategoryWaterSource = "water_source"
+
+// CategoryCampground marks places that are campgrounds
+const CategoryCampground = "campground"
+
+// CampgroundLink is the reservation system a campground is booked through
+// and its ID there
+type CampgroundLink struct {
+	Provider   string    `db:"provider" json:"provider"`
+	FacilityID string    `db:"facility_id" json:"facility_id"`
+	UpdatedAt  time.Time `db:"updated_at" json:"updated_at"`
 }
 
-type TimeRange struct {
-	Open  string `json:"open"`  // "09:00"
-	Close string `json:"close"` // "17:00"
+// Water source reliabilities
+const (
+	WaterReliable   = "reliable"
+	WaterSeasonal   = "seasonal" // Flows in its flowing months only
+	WaterUnreliable = "unreliable"
+	WaterUnknown    = "unknown"
+)
+
+// WaterSource details how far a water source place can be counted on
+type WaterSource struct {
+	Kind              string        `db:"kind" json:"kind"`
+	Reliability       string        `db:"reliability" json:"reliability"`
+	FlowingMonths     pq.Int64Array `db:"flowing_months" json:"flowing_months"`
+	TreatmentRequired bool          `db:"treatment_required" json:"treatment_required"`
+	Notes             string        `db:"notes" json:"notes"`
+	LastVerifiedAt    *time.Time    `db:"last_verified_at" json:"last_verified_at,omitempty"`
+	UpdatedAt         time.Time     `db:"updated_at" json:"updated_at"`
 }
 
-// ContactInfo stores contact information in JSONB
-type ContactInfo struct {
-	Phone   string `json:"phone,omitempty"`
-	Email   string `json:"email,omitempty"`
-	Website string `json:"website,omitempty"`
-	Social  Social `json:"social,omitempty"`
+// GeoPoint represents a PostGIS geography point
+type GeoPoint struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"` // [longitude, latitude]
 }
 
-type Social struct {
-	Facebook  string `json:"facebook,omitempty"`
-	Instagram string `json:"instagram,omitempty"`
-	Twitter   string `json:"twitter,omitempty"`
+// GeoPolygon represents a PostGIS geography polygon
+type GeoPolygon struct {
+	Type        string        `json:"type"`
+	Coordinates [][][]float64 `json:"coordinates"`
 }
 
 type Media struct {
@@ -90,10 +131,11 @@ type Media struct {
 	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
 	
 	// Joined media details
-	URL           string     `json:"url,omitempty"`
-	ThumbnailURL  string     `json:"thumbnail_url,omitempty"`
-	MimeType      string     `json:"mime_type,omitempty"`
-	UploadedBy    string     `json:"uploaded_by,omitempty"`
+	URL           string     `db:"url" json:"url,omitempty"`
+	ThumbnailURL  string     `db:"thumbnail_url" json:"thumbnail_url,omitempty"`
+	MimeType      string     `db:"mime_type" json:"mime_type,omitempty"`
+	UploadedBy    string     `db:"uploaded_by" json:"uploaded_by,omitempty"`
+	UploadedAt    time.Time  `db:"uploaded_at" json:"-"`
 }
 
 type Collaborator struct {
@@ -105,9 +147,21 @@ type Collaborator struct {
 	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
 
 	// Joined user info
-	Username    string `json:"username,omitempty"`
-	DisplayName string `json:"display_name,omitempty"`
-	AvatarURL   string `json:"avatar_url,omitempty"`
+	Username    string `db:"username" json:"username,omitempty"`
+	DisplayName string `db:"display_name" json:"display_name,omitempty"`
+	AvatarURL   string `db:"avatar_url" json:"avatar_url,omitempty"`
+}
+
+// OwnershipTransfer is a request to hand place ownership to a collaborator
+type OwnershipTransfer struct {
+	ID          string     `db:"id" json:"id"`
+	EntityType  string     `db:"entity_type" json:"entity_type"`
+	EntityID    string     `db:"entity_id" json:"entity_id"`
+	FromUserID  string     `db:"from_user_id" json:"from_user_id"`
+	ToUserID    string     `db:"to_user_id" json:"to_user_id"`
+	Status      string     `db:"status" json:"status"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	RespondedAt *time.Time `db:"responded_at" json:"responded_at,omitempty"`
 }
 
 // Value implementations for custom types
@@ -162,15 +216,19 @@ func (g *GeoPolygon) Scan(value interface{}) error {
 	return json.Unmarshal(data, g)
 }
 
-func (o OpeningHours) Value() (driver.Value, error) {
-	return json.Marshal(o)
+func (f AccessFees) Value() (driver.Value, error) {
+	if f == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(f)
 }
 
-func (o *OpeningHours) Scan(value interface{}) error {
+func (f *AccessFees) Scan(value interface{}) error {
 	if value == nil {
+		*f = AccessFees{}
 		return nil
 	}
-	
+
 	var data []byte
 	switch v := value.(type) {
 	case []byte:
@@ -180,15 +238,15 @@ func (o *OpeningHours) Scan(value interface{}) error {
 	default:
 		return nil
 	}
-	
-	return json.Unmarshal(data, o)
+
+	return json.Unmarshal(data, f)
 }
 
-func (c ContactInfo) Value() (driver.Value, error) {
-	return json.Marshal(c)
+func (o OpeningHours) Value() (driver.Value, error) {
+	return json.Marshal(o)
 }
 
-func (c *ContactInfo) Scan(value interface{}) error {
+func (o *OpeningHours) Scan(value interface{}) error {
 	if value == nil {
 		return nil
 	}
@@ -203,7 +261,7 @@ func (c *ContactInfo) Scan(value interface{}) error {
 		return nil
 	}
 	
-	return json.Unmarshal(data, c)
+	return json.Unmarshal(data, o)
 }
 
 // Input types
@@ -224,9 +282,30 @@ type CreatePlaceInput struct {
 	OpeningHours  *OpeningHours `json:"opening_hours,omitempty"`
 	ContactInfo   *ContactInfo  `json:"contact_info,omitempty"`
 	Amenities     []string      `json:"amenities"`
+	Accessibility []string      `json:"accessibility" binding:"omitempty,dive,oneof=dog_friendly stroller_ok wheelchair_accessible toddler_ok"`
+	AccessFees    []AccessFee   `json:"access_fees" binding:"omitempty,max=20,dive"`
+	WaterSource   *WaterSourceInput `json:"water_source,omitempty"`
+	Campground    *CampgroundLinkInput `json:"campground,omitempty"`
 	Privacy       string        `json:"privacy" binding:"omitempty,oneof=public friends private"`
 }
 
+// CampgroundLinkInput links a campground to its reservation system
+type CampgroundLinkInput struct {
+	Provider   string `json:"provider" binding:"required,oneof=recreation_gov reserve_california"`
+	FacilityID string `json:"facility_id" binding:"required,max=100"`
+}
+
+// WaterSourceInput makes a place a water source. Its water is taken to need
+// treatment unless said otherwise.
+type WaterSourceInput struct {
+	Kind              string     `json:"kind" binding:"required,oneof=spring stream lake tap tank well cistern"`
+	Reliability       string     `json:"reliability" binding:"omitempty,oneof=reliable seasonal unreliable unknown"`
+	FlowingMonths     []int64    `json:"flowing_months" binding:"omitempty,dive,min=1,max=12"`
+	TreatmentRequired *bool      `json:"treatment_required"`
+	Notes             string     `json:"notes" binding:"max=500"`
+	LastVerifiedAt    *time.Time `json:"last_verified_at"`
+}
+
 type LocationInput struct {
 	Latitude  float64 `json:"latitude" binding:"required,min=-90,max=90"`
 	Longitude float64 `json:"longitude" binding:"required,min=-180,max=180"`
@@ -252,32 +331,77 @@ type UpdatePlaceInput struct {
 	OpeningHours  *OpeningHours  `json:"opening_hours,omitempty"`
 	ContactInfo   *ContactInfo   `json:"contact_info,omitempty"`
 	Amenities     []string       `json:"amenities,omitempty"`
+	Accessibility []string       `json:"accessibility,omitempty" binding:"omitempty,dive,oneof=dog_friendly stroller_ok wheelchair_accessible toddler_ok"`
+	AccessFees    []AccessFee    `json:"access_fees,omitempty" binding:"omitempty,max=20,dive"`
+	WaterSource   *WaterSourceInput `json:"water_source,omitempty"`
+	Campground    *CampgroundLinkInput `json:"campground,omitempty"`
 	Privacy       *string        `json:"privacy,omitempty" binding:"omitempty,oneof=public friends private"`
 	Status        *string        `json:"status,omitempty" binding:"omitempty,oneof=active pending archived"`
 }
 
+// waterSource builds the water source details from the input
+func (in *WaterSourceInput) waterSource() *WaterSource {
+	source := &WaterSource{
+		Kind:              in.Kind,
+		Reliability:       in.Reliability,
+		FlowingMonths:     pq.Int64Array(in.FlowingMonths),
+		TreatmentRequired: true,
+		Notes:             in.Notes,
+		LastVerifiedAt:    in.LastVerifiedAt,
+	}
+	if source.Reliability == "" {
+		source.Reliability = WaterUnknown
+	}
+	if source.FlowingMonths == nil {
+		source.FlowingMonths = pq.Int64Array{}
+	}
+	if in.TreatmentRequired != nil {
+		source.TreatmentRequired = *in.TreatmentRequired
+	}
+	return source
+}
+
+// AvailabilityQuery is a stay to check a campground for, from the night of
+// start to the night before end
+type AvailabilityQuery struct {
+	Start string `form:"start" binding:"required,datetime=2006-01-02"`
+	End   string `form:"end" binding:"required,datetime=2006-01-02"`
+}
+
+type TransferOwnershipInput struct {
+	UserID string `json:"user_id" binding:"required,uuid"`
+}
+
 type SearchPlacesInput struct {
-	Query     string   `form:"q" binding:"max=100"`
-	Type      string   `form:"type" binding:"omitempty,oneof=poi area region"`
-	Category  []string `form:"category"`
-	Tags      []string `form:"tags"`
-	City      string   `form:"city"`
-	Country   string   `form:"country"`
-	Latitude  *float64 `form:"lat" binding:"omitempty,min=-90,max=90"`
-	Longitude *float64 `form:"lng" binding:"omitempty,min=-180,max=180"`
-	Radius    *int     `form:"radius" binding:"omitempty,min=1,max=50000"` // meters
-	Limit     int      `form:"limit" binding:"min=1,max=100"`
-	Offset    int      `form:"offset" binding:"min=0"`
+	Query         string   `form:"q" binding:"max=100"`
+	Type          string   `form:"type" binding:"omitempty,oneof=poi area region"`
+	Category      []string `form:"category"`
+	Tags          []string `form:"tags"`
+	Accessibility []string `form:"accessibility"` // every attribute listed must hold
+	Amenities     []string `form:"amenities"`     // every amenity listed must be offered
+	City          string   `form:"city"`
+	Country       string   `form:"country"`
+	Latitude      *float64 `form:"lat" binding:"omitempty,min=-90,max=90"`
+	Longitude     *float64 `form:"lng" binding:"omitempty,min=-180,max=180"`
+	Radius        *int     `form:"radius" binding:"omitempty,min=1,max=50000"` // meters
+	OpenNow       bool     `form:"open_now"`                                     // only places open at the time of the search
+	Limit         int      `form:"limit" binding:"min=1,max=100"`
+	Offset        int      `form:"offset" binding:"min=0"`
+
+	// Keyset pagination, ignored when results are ordered by distance
+	After *pagination.Cursor `form:"-"`
 }
 
 type NearbyPlacesInput struct {
-	Latitude  float64  `form:"lat" binding:"required,min=-90,max=90"`
-	Longitude float64  `form:"lng" binding:"required,min=-180,max=180"`
-	Radius    int      `form:"radius" binding:"required,min=1,max=50000"` // meters
-	Type      string   `form:"type" binding:"omitempty,oneof=poi area region"`
-	Category  []string `form:"category"`
-	Tags      []string `form:"tags"`
-	Limit     int      `form:"limit" binding:"min=1,max=100"`
+	Latitude      float64  `form:"lat" binding:"required,min=-90,max=90"`
+	Longitude     float64  `form:"lng" binding:"required,min=-180,max=180"`
+	Radius        int      `form:"radius" binding:"required,min=1,max=50000"` // meters
+	Type          string   `form:"type" binding:"omitempty,oneof=poi area region"`
+	Category      []string `form:"category"`
+	Tags          []string `form:"tags"`
+	Accessibility []string `form:"accessibility"` // every attribute listed must hold
+	Amenities     []string `form:"amenities"`     // every amenity listed must be offered
+	Limit         int      `form:"limit" binding:"min=1,max=100"`
 	Offset    int      `form:"offset" binding:"min=0"`
 }
 
@@ -286,6 +410,16 @@ func (p *Place) IsOwner(userID string) bool {
 	return p.CreatedBy == userID
 }
 
+// HasCategory reports whether the place is in the category
+func (p *Place) HasCategory(category string) bool {
+	for _, c := range p.Category {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Place) HasCollaborator(userID string) bool {
 	for _, c := range p.Collaborators {
 		if c.UserID == userID {
@@ -295,6 +429,12 @@ func (p *Place) HasCollaborator(userID string) bool {
 	return false
 }
 
+// VisibleTo reports whether the user may view the place. Collaborators must
+// be loaded for private places to be visible to anyone but the creator.
+func (p *Place) VisibleTo(userID string) bool {
+	return p.Privacy != "private" || p.IsOwner(userID) || p.HasCollaborator(userID)
+}
+
 func (p *Place) GetCollaborator(userID string) *Collaborator {
 	for _, c := range p.Collaborators {
 		if c.UserID == userID {
@@ -330,6 +470,18 @@ func (p *Place) CanUserDelete(userID string) bool {
 	return collaborator.Role == "admin"
 }
 
+// Relations selects the related records loaded along with a place
+type Relations struct {
+	Media         bool
+	Collaborators bool
+}
+
+// AllRelations loads every related record
+var AllRelations = Relations{
+	Media:         true,
+	Collaborators: true,
+}
+
 // PlaceFilter contains filter criteria for places
 type PlaceFilter struct {
 	TripID      *string
@@ -340,6 +492,7 @@ type PlaceFilter struct {
 	MinRating   *float32
 	MaxCost     *float64
 	SearchQuery string
+	After       *pagination.Cursor // keyset pagination, replaces the offset
 }
 
 // PlaceCategory represents place categories
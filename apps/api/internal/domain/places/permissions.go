@@ -0,0 +1,212 @@
+package places
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+)
+
+// MaxAncestryDepth bounds how far up the parent_id chain permission
+// resolution walks. It also protects against accidental cycles.
+const MaxAncestryDepth = 8
+
+const permissionCacheTTL = time.Minute
+
+// Effective roles a user can hold on a place
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+var roleRank = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+	RoleOwner:  4,
+}
+
+type cachedRole struct {
+	role      string
+	expiresAt time.Time
+}
+
+// PermissionResolver resolves a user's effective role on a place. Owners and
+// admins of an area or region inherit editor rights on every place nested
+// beneath it.
+type PermissionResolver struct {
+	repo  Repository
+	bus   events.Bus
+	now   func() time.Time
+	mu    sync.RWMutex
+	roles map[string]cachedRole
+}
+
+// NewPermissionResolver creates a new place permission resolver
+func NewPermissionResolver(repo Repository) *PermissionResolver {
+	return &PermissionResolver{
+		repo:  repo,
+		now:   time.Now,
+		roles: make(map[string]cachedRole),
+	}
+}
+
+// SetEventBus sets the bus invalidations are sent over, so that every
+// instance drops its cached roles. HandlePermissionsChanged must be
+// subscribed as a broadcast handler for them to be received.
+func (r *PermissionResolver) SetEventBus(bus events.Bus) {
+	r.bus = bus
+}
+
+// EffectiveRole returns the highest role the user holds on the place, either
+// directly or through one of its ancestors. An empty string means no role.
+func (r *PermissionResolver) EffectiveRole(ctx context.Context, userID string, place *Place) (string, error) {
+	if userID == "" {
+		return "", nil
+	}
+
+	key := userID + ":" + place.ID
+	if role, ok := r.getCached(key); ok {
+		return role, nil
+	}
+
+	role := directRole(userID, place)
+	if roleRank[role] < roleRank[RoleEditor] {
+		inherited, err := r.inheritedRole(ctx, userID, place)
+		if err != nil {
+			return "", err
+		}
+		if roleRank[inherited] > roleRank[role] {
+			role = inherited
+		}
+	}
+
+	r.setCached(key, role)
+	return role, nil
+}
+
+// CanUserPerform checks whether the user may perform the permission on the place
+func (r *PermissionResolver) CanUserPerform(ctx context.Context, userID, placeID, permission string) (bool, error) {
+	place, err := r.repo.GetByID(ctx, placeID)
+	if err != nil {
+		return false, err
+	}
+
+	return r.CanUserPerformOnPlace(ctx, userID, place, permission)
+}
+
+// CanUserPerformOnPlace is like CanUserPerform for a place that is already loaded
+func (r *PermissionResolver) CanUserPerformOnPlace(ctx context.Context, userID string, place *Place, permission string) (bool, error) {
+	role, err := r.EffectiveRole(ctx, userID, place)
+	if err != nil {
+		return false, err
+	}
+
+	return roleAllows(role, place, permission), nil
+}
+
+// Invalidate drops all cached roles, here and on every other instance. Any
+// change to a place or its collaborators can affect every descendant, so the
+// whole cache is cleared.
+func (r *PermissionResolver) Invalidate(ctx context.Context, placeID string) {
+	r.clear()
+
+	if r.bus == nil {
+		return
+	}
+	event := events.New(events.PlacePermissionsChanged, "place", placeID, "", nil)
+	if err := r.bus.Publish(ctx, event); err != nil {
+		log.Printf("Failed to publish permission invalidation for place %s: %v", placeID, err)
+	}
+}
+
+// HandlePermissionsChanged drops the cached roles when any instance
+// invalidates them
+func (r *PermissionResolver) HandlePermissionsChanged(ctx context.Context, event events.Event) error {
+	r.clear()
+	return nil
+}
+
+func (r *PermissionResolver) clear() {
+	r.mu.Lock()
+	r.roles = make(map[string]cachedRole)
+	r.mu.Unlock()
+}
+
+// inheritedRole walks the parent_id chain looking for an area or region the
+// user administers
+func (r *PermissionResolver) inheritedRole(ctx context.Context, userID string, place *Place) (string, error) {
+	visited := map[string]bool{place.ID: true}
+	parentID := place.ParentID
+
+	for depth := 0; parentID != nil && depth < MaxAncestryDepth; depth++ {
+		if visited[*parentID] {
+			break
+		}
+		visited[*parentID] = true
+
+		parent, err := r.repo.GetByID(ctx, *parentID)
+		if err != nil {
+			if errors.Is(err, ErrPlaceNotFound) {
+				break
+			}
+			return "", err
+		}
+
+		if parent.Type == "area" || parent.Type == "region" {
+			role := directRole(userID, parent)
+			if role == RoleOwner || role == RoleAdmin {
+				return RoleEditor, nil
+			}
+		}
+
+		parentID = parent.ParentID
+	}
+
+	return "", nil
+}
+
+func (r *PermissionResolver) getCached(key string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.roles[key]
+	if !ok || r.now().After(entry.expiresAt) {
+		return "", false
+	}
+	return entry.role, true
+}
+
+func (r *PermissionResolver) setCached(key, role string) {
+	r.mu.Lock()
+	r.roles[key] = cachedRole{role: role, expiresAt: r.now().Add(permissionCacheTTL)}
+	r.mu.Unlock()
+}
+
+func directRole(userID string, place *Place) string {
+	if place.IsOwner(userID) {
+		return RoleOwner
+	}
+	if collaborator := place.GetCollaborator(userID); collaborator != nil {
+		return collaborator.Role
+	}
+	return ""
+}
+
+func roleAllows(role string, place *Place, permission string) bool {
+	switch permission {
+	case "place.read":
+		return place.Privacy != "private" || role != ""
+	case "place.update", "place.media", "suggestion.moderate":
+		return roleRank[role] >= roleRank[RoleEditor]
+	case "place.delete":
+		return roleRank[role] >= roleRank[RoleAdmin]
+	default:
+		return role == RoleOwner
+	}
+}
@@ -0,0 +1,188 @@
+package places
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// placeRepository serves places from memory and counts the lookups
+type placeRepository struct {
+	Repository
+	places  map[string]*Place
+	lookups int
+}
+
+func (r *placeRepository) GetByID(ctx context.Context, id string) (*Place, error) {
+	r.lookups++
+	place, ok := r.places[id]
+	if !ok {
+		return nil, ErrPlaceNotFound
+	}
+	return place, nil
+}
+
+func strPtr(s string) *string { return &s }
+
+// ancestry is a region owned by regionOwner holding an area administered by
+// areaAdmin, which holds a trailhead
+func ancestry() *placeRepository {
+	return &placeRepository{places: map[string]*Place{
+		"region": {ID: "region", Type: "region", CreatedBy: "region-owner"},
+		"area": {
+			ID: "area", Type: "area", CreatedBy: "someone", ParentID: strPtr("region"),
+			Collaborators: []Collaborator{{UserID: "area-admin", Role: RoleAdmin}},
+		},
+		"trailhead": {ID: "trailhead", Type: "poi", CreatedBy: "someone", ParentID: strPtr("area")},
+	}}
+}
+
+func TestPermissionResolver_EffectiveRole(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		userID string
+		want   string
+	}{
+		{"admin of the parent area", "area-admin", RoleEditor},
+		{"owner of the grandparent region", "region-owner", RoleEditor},
+		{"owner of the place itself", "someone", RoleOwner},
+		{"no role anywhere", "stranger", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := ancestry()
+			resolver := NewPermissionResolver(repo)
+
+			role, err := resolver.EffectiveRole(ctx, tt.userID, repo.places["trailhead"])
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, role)
+		})
+	}
+
+	t.Run("viewers of an area inherit nothing", func(t *testing.T) {
+		repo := ancestry()
+		repo.places["area"].Collaborators = []Collaborator{{UserID: "area-viewer", Role: RoleViewer}}
+		resolver := NewPermissionResolver(repo)
+
+		role, err := resolver.EffectiveRole(ctx, "area-viewer", repo.places["trailhead"])
+		require.NoError(t, err)
+		assert.Empty(t, role)
+	})
+
+	t.Run("only areas and regions pass rights down", func(t *testing.T) {
+		repo := ancestry()
+		repo.places["area"].Type = "poi"
+		resolver := NewPermissionResolver(repo)
+
+		role, err := resolver.EffectiveRole(ctx, "area-admin", repo.places["trailhead"])
+		require.NoError(t, err)
+		assert.Empty(t, role)
+	})
+}
+
+func TestPermissionResolver_MaxAncestryDepth(t *testing.T) {
+	ctx := context.Background()
+
+	// A chain of areas with the owned region at its top, depth levels above
+	// the place
+	chain := func(depth int) (*placeRepository, *Place) {
+		repo := &placeRepository{places: map[string]*Place{
+			"top": {ID: "top", Type: "region", CreatedBy: "region-owner"},
+		}}
+		parent := "top"
+		for i := 1; i < depth; i++ {
+			id := "area-" + string(rune('a'+i))
+			repo.places[id] = &Place{ID: id, Type: "area", CreatedBy: "someone", ParentID: strPtr(parent)}
+			parent = id
+		}
+		return repo, &Place{ID: "place", Type: "poi", CreatedBy: "someone", ParentID: strPtr(parent)}
+	}
+
+	repo, place := chain(MaxAncestryDepth)
+	role, err := NewPermissionResolver(repo).EffectiveRole(ctx, "region-owner", place)
+	require.NoError(t, err)
+	assert.Equal(t, RoleEditor, role)
+
+	repo, place = chain(MaxAncestryDepth + 1)
+	role, err = NewPermissionResolver(repo).EffectiveRole(ctx, "region-owner", place)
+	require.NoError(t, err)
+	assert.Empty(t, role, "ancestors beyond the depth limit are not consulted")
+	assert.Equal(t, MaxAncestryDepth, repo.lookups)
+}
+
+func TestPermissionResolver_ParentCycle(t *testing.T) {
+	repo := &placeRepository{places: map[string]*Place{
+		"a": {ID: "a", Type: "area", CreatedBy: "someone", ParentID: strPtr("b")},
+		"b": {ID: "b", Type: "area", CreatedBy: "someone", ParentID: strPtr("a")},
+	}}
+	place := &Place{ID: "place", Type: "poi", CreatedBy: "someone", ParentID: strPtr("a")}
+
+	role, err := NewPermissionResolver(repo).EffectiveRole(context.Background(), "stranger", place)
+	require.NoError(t, err)
+	assert.Empty(t, role)
+	assert.Equal(t, 2, repo.lookups, "each ancestor is visited once")
+}
+
+func TestPermissionResolver_CacheTTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	repo := ancestry()
+	resolver := NewPermissionResolver(repo)
+	resolver.now = func() time.Time { return now }
+	trailhead := repo.places["trailhead"]
+
+	role, err := resolver.EffectiveRole(ctx, "area-admin", trailhead)
+	require.NoError(t, err)
+	assert.Equal(t, RoleEditor, role)
+
+	// The admin is removed from the area, but the cached role holds until it
+	// expires
+	repo.places["area"].Collaborators = nil
+	now = now.Add(permissionCacheTTL - time.Second)
+	role, err = resolver.EffectiveRole(ctx, "area-admin", trailhead)
+	require.NoError(t, err)
+	assert.Equal(t, RoleEditor, role)
+
+	now = now.Add(2 * time.Second)
+	role, err = resolver.EffectiveRole(ctx, "area-admin", trailhead)
+	require.NoError(t, err)
+	assert.Empty(t, role)
+}
+
+func TestPermissionResolver_Invalidate(t *testing.T) {
+	ctx := context.Background()
+	bus := events.NewLocalBus()
+
+	// Two instances sharing a bus
+	repo := ancestry()
+	local := NewPermissionResolver(repo)
+	remote := NewPermissionResolver(repo)
+	for _, resolver := range []*PermissionResolver{local, remote} {
+		resolver.SetEventBus(bus)
+		bus.SubscribeBroadcast(events.PlacePermissionsChanged, resolver.HandlePermissionsChanged)
+	}
+
+	trailhead := repo.places["trailhead"]
+	for _, resolver := range []*PermissionResolver{local, remote} {
+		role, err := resolver.EffectiveRole(ctx, "area-admin", trailhead)
+		require.NoError(t, err)
+		assert.Equal(t, RoleEditor, role)
+	}
+
+	repo.places["area"].Collaborators = nil
+	local.Invalidate(ctx, "area")
+
+	for _, resolver := range []*PermissionResolver{local, remote} {
+		role, err := resolver.EffectiveRole(ctx, "area-admin", trailhead)
+		require.NoError(t, err)
+		assert.Empty(t, role)
+	}
+}
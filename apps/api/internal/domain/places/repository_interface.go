@@ -2,18 +2,27 @@ package places
 
 import (
 	"context"
-	"errors"
+	"time"
+
 	"github.com/Oferzz/newMap/apps/api/internal/nlp"
+	"github.com/Oferzz/newMap/apps/api/pkg/pagination"
+	"github.com/Oferzz/newMap/apps/api/pkg/repoerr"
 )
 
 var (
-	ErrPlaceNotFound = errors.New("place not found")
+	ErrPlaceNotFound    = repoerr.NotFound("place not found")
+	ErrTransferNotFound = repoerr.NotFound("no pending ownership transfer")
+	ErrTransferPending  = repoerr.Conflict("an ownership transfer is already pending")
+
+	ErrAlreadyCollaborator = repoerr.Conflict("user is already a collaborator")
 )
 
 // Repository defines the interface for place data access
 type Repository interface {
 	Create(ctx context.Context, place *Place) error
 	GetByID(ctx context.Context, id string) (*Place, error)
+	GetByIDs(ctx context.Context, ids []string) ([]*Place, error)
+	GetByIDWith(ctx context.Context, id string, relations Relations) (*Place, error)
 	GetByCreator(ctx context.Context, creatorID string) ([]*Place, error)
 	Update(ctx context.Context, place *Place) error
 	Delete(ctx context.Context, id string) error
@@ -24,20 +33,45 @@ type Repository interface {
 	GetChildren(ctx context.Context, parentID string) ([]*Place, error)
 	UpdateRating(ctx context.Context, placeID string, rating float64, count int) error
 	
+	// Reviews, which keep the place's rating in step
+	CreateReview(ctx context.Context, review *PlaceReview) error
+	ListReviews(ctx context.Context, placeID string, limit, offset int) ([]*PlaceReview, error)
+	DeleteReview(ctx context.Context, placeID, userID string) error
+	
+	// Water sources
+	SetWaterSource(ctx context.Context, placeID string, source *WaterSource) error
+	ClearWaterSource(ctx context.Context, placeID string) error
+	
+	// Campground links
+	SetCampgroundLink(ctx context.Context, placeID string, link *CampgroundLink) error
+	ClearCampgroundLink(ctx context.Context, placeID string) error
+	
+	// Contact checks
+	ListContactsToCheck(ctx context.Context, checkedBefore time.Time, limit int) ([]*Place, error)
+	SetContactVerification(ctx context.Context, placeID string, updatedAt time.Time, verification *ContactVerification) error
+	
 	// Enhanced spatial search methods
 	SearchWithSpatialContext(ctx context.Context, query string, spatial *nlp.SpatialSearchContext, filters SearchFilters) (*SearchResult, error)
 	GetInArea(ctx context.Context, area nlp.AreaFilter) ([]*Place, error)
 	GetIntersecting(ctx context.Context, area nlp.AreaFilter) ([]*Place, error)
 	GetWithinDistance(ctx context.Context, area nlp.AreaFilter) ([]*Place, error)
+	
+	// Ownership transfer
+	CreateOwnershipTransfer(ctx context.Context, transfer *OwnershipTransfer) error
+	GetPendingOwnershipTransfer(ctx context.Context, placeID string) (*OwnershipTransfer, error)
+	ResolveOwnershipTransfer(ctx context.Context, transfer *OwnershipTransfer, actorID string, accept bool) error
 }
 
 // SearchFilters contains filters for place search
 type SearchFilters struct {
-	Category  []string
-	Tags      []string
-	CreatorID string
+	Category      []string
+	Tags          []string
+	Accessibility []string // every attribute listed must hold
+	Amenities     []string // every amenity listed must be offered
+	CreatorID     string
 	Limit     int
 	Offset    int
+	After     *pagination.Cursor
 }
 
 // SearchResult contains search results with metadata
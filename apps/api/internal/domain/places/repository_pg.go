@@ -3,19 +3,29 @@ package places
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
+	"github.com/Oferzz/newMap/apps/api/internal/audit"
+	"github.com/Oferzz/newMap/apps/api/internal/diagnostics"
+	"github.com/Oferzz/newMap/apps/api/internal/media"
 	"github.com/Oferzz/newMap/apps/api/internal/nlp"
+	"github.com/Oferzz/newMap/apps/api/pkg/pagination"
+	"github.com/Oferzz/newMap/apps/api/pkg/repoerr"
+	"github.com/Oferzz/newMap/apps/api/pkg/sqlbuilder"
+	"github.com/Oferzz/newMap/apps/api/pkg/textsearch"
 )
 
 // PostgresRepository implements the repository interface for PostgreSQL
 type PostgresRepository struct {
-	db *sqlx.DB
+	db          *sqlx.DB
+	slowQueries *diagnostics.SlowQueryLog
+	cdn         *media.CDN
 }
 
 // NewPostgresRepository creates a new PostgreSQL repository
@@ -25,6 +35,16 @@ func NewPostgresRepository(db *sqlx.DB) *PostgresRepository {
 	}
 }
 
+// SetSlowQueryLog enables slow query tracking for the spatial queries
+func (r *PostgresRepository) SetSlowQueryLog(slowQueries *diagnostics.SlowQueryLog) {
+	r.slowQueries = slowQueries
+}
+
+// SetMediaCDN serves the media of places from a CDN instead of the API
+func (r *PostgresRepository) SetMediaCDN(cdn *media.CDN) {
+	r.cdn = cdn
+}
+
 // Create creates a new place
 func (r *PostgresRepository) Create(ctx context.Context, place *Place) error {
 	tx, err := r.db.BeginTxx(ctx, nil)
@@ -33,21 +53,13 @@ func (r *PostgresRepository) Create(ctx context.Context, place *Place) error {
 	}
 	defer tx.Rollback()
 
-	// Prepare location as GeoJSON
-	var locationGeoJSON interface{}
-	if place.Location != nil {
-		locationGeoJSON = fmt.Sprintf(
-			"ST_GeomFromGeoJSON('{\"type\":\"Point\",\"coordinates\":[%f,%f]}')",
-			place.Location.Coordinates[0],
-			place.Location.Coordinates[1],
-		)
+	location, err := geometryArg(place.Location)
+	if err != nil {
+		return err
 	}
-
-	// Prepare bounds as GeoJSON
-	var boundsGeoJSON interface{}
-	if place.Bounds != nil {
-		boundsJSON, _ := json.Marshal(place.Bounds)
-		boundsGeoJSON = fmt.Sprintf("ST_GeomFromGeoJSON('%s')", string(boundsJSON))
+	bounds, err := geometryArg(place.Bounds)
+	if err != nil {
+		return err
 	}
 
 	// Insert place
@@ -56,28 +68,21 @@ func (r *PostgresRepository) Create(ctx context.Context, place *Place) error {
 			name, description, type, parent_id, location, bounds,
 			street_address, city, state, country, postal_code,
 			created_by, category, tags, opening_hours, contact_info,
-			amenities, privacy, status
+			amenities, privacy, status, accessibility, access_fees
 		) VALUES (
-			$1, $2, $3, $4, %s, %s, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17
+			$1, $2, $3, $4, ST_GeomFromGeoJSON($5::text), ST_GeomFromGeoJSON($6::text),
+			$7, $8, $9, $10, $11, $12, $13, $14, $15, $16,
+			COALESCE($17::text[], '{}'), $18, $19, COALESCE($20::text[], '{}'), $21
 		) RETURNING id, created_at, updated_at`
 
-	// Build query with spatial functions
-	if locationGeoJSON != nil && boundsGeoJSON != nil {
-		query = fmt.Sprintf(query, locationGeoJSON, boundsGeoJSON)
-	} else if locationGeoJSON != nil {
-		query = fmt.Sprintf(query, locationGeoJSON, "NULL")
-	} else if boundsGeoJSON != nil {
-		query = fmt.Sprintf(query, "NULL", boundsGeoJSON)
-	} else {
-		query = fmt.Sprintf(query, "NULL", "NULL")
-	}
-
 	// Execute query
 	args := []interface{}{
 		place.Name,
 		place.Description,
 		place.Type,
 		place.ParentID,
+		location,
+		bounds,
 		place.StreetAddress,
 		place.City,
 		place.State,
@@ -91,16 +96,8 @@ func (r *PostgresRepository) Create(ctx context.Context, place *Place) error {
 		pq.Array(place.Amenities),
 		place.Privacy,
 		place.Status,
-	}
-
-	// Remove location and bounds from args if they're included in the query
-	if locationGeoJSON != nil || boundsGeoJSON != nil {
-		// Adjust args to skip the spatial placeholders
-		filteredArgs := []interface{}{
-			args[0], args[1], args[2], args[3], // name, desc, type, parent_id
-		}
-		filteredArgs = append(filteredArgs, args[4:]...) // skip location/bounds positions
-		args = filteredArgs
+		pq.Array(place.Accessibility),
+		place.AccessFees,
 	}
 
 	err = tx.QueryRowContext(ctx, query, args...).Scan(
@@ -129,8 +126,13 @@ func (r *PostgresRepository) Create(ctx context.Context, place *Place) error {
 	return tx.Commit()
 }
 
-// GetByID retrieves a place by ID
+// GetByID retrieves a place by ID with its media and collaborators
 func (r *PostgresRepository) GetByID(ctx context.Context, id string) (*Place, error) {
+	return r.GetByIDWith(ctx, id, AllRelations)
+}
+
+// GetByIDWith retrieves a place by ID with the requested related records
+func (r *PostgresRepository) GetByIDWith(ctx context.Context, id string, relations Relations) (*Place, error) {
 	var place Place
 	query := `
 		SELECT 
@@ -139,7 +141,7 @@ func (r *PostgresRepository) GetByID(ctx context.Context, id string) (*Place, er
 			ST_AsGeoJSON(bounds) as bounds,
 			street_address, city, state, country, postal_code,
 			created_by, category, tags, opening_hours, contact_info,
-			amenities, average_rating, rating_count, privacy, status,
+			amenities, accessibility, access_fees, average_rating, rating_count, privacy, status,
 			created_at, updated_at
 		FROM places
 		WHERE id = $1 AND status = 'active'`
@@ -159,11 +161,13 @@ func (r *PostgresRepository) GetByID(ctx context.Context, id string) (*Place, er
 		&place.Country,
 		&place.PostalCode,
 		&place.CreatedBy,
-		pq.Array(&place.Category),
-		pq.Array(&place.Tags),
+		&place.Category,
+		&place.Tags,
 		&place.OpeningHours,
 		&place.ContactInfo,
-		pq.Array(&place.Amenities),
+		&place.Amenities,
+		&place.Accessibility,
+		&place.AccessFees,
 		&place.AverageRating,
 		&place.RatingCount,
 		&place.Privacy,
@@ -173,8 +177,8 @@ func (r *PostgresRepository) GetByID(ctx context.Context, id string) (*Place, er
 	)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("place not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("place %s: %w", id, ErrPlaceNotFound)
 		}
 		return nil, fmt.Errorf("failed to get place: %w", err)
 	}
@@ -195,22 +199,174 @@ func (r *PostgresRepository) GetByID(ctx context.Context, id string) (*Place, er
 	}
 
 	// Get media
-	media, err := r.getPlaceMedia(ctx, id)
-	if err != nil {
-		return nil, err
+	if relations.Media {
+		media, err := r.getPlaceMedia(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		place.Media = media
 	}
-	place.Media = media
 
 	// Get collaborators
-	collaborators, err := r.getCollaborators(ctx, id)
-	if err != nil {
-		return nil, err
+	if relations.Collaborators {
+		collaborators, err := r.getCollaborators(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		place.Collaborators = collaborators
+	}
+
+	if place.HasCategory(CategoryWaterSource) {
+		source, err := r.getWaterSource(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		place.WaterSource = source
+	}
+
+	if place.HasCategory(CategoryCampground) {
+		link, err := r.getCampgroundLink(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		place.Campground = link
 	}
-	place.Collaborators = collaborators
 
 	return &place, nil
 }
 
+// GetByIDs retrieves several active places with their collaborators and
+// cover media, batching each relation into one query
+func (r *PostgresRepository) GetByIDs(ctx context.Context, ids []string) ([]*Place, error) {
+	places := []*Place{}
+	if len(ids) == 0 {
+		return places, nil
+	}
+
+	query := `
+		SELECT 
+			id, name, description, type, parent_id,
+			ST_AsGeoJSON(location) as location,
+			street_address, city, state, country, postal_code,
+			created_by, category, tags, accessibility, opening_hours, average_rating, rating_count,
+			privacy, status, created_at, updated_at
+		FROM places
+		WHERE id = ANY($1) AND status = 'active'`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get places: %w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[string]*Place, len(ids))
+	for rows.Next() {
+		var place Place
+		var locationJSON sql.NullString
+
+		err := rows.Scan(
+			&place.ID,
+			&place.Name,
+			&place.Description,
+			&place.Type,
+			&place.ParentID,
+			&locationJSON,
+			&place.StreetAddress,
+			&place.City,
+			&place.State,
+			&place.Country,
+			&place.PostalCode,
+			&place.CreatedBy,
+			&place.Category,
+			&place.Tags,
+			&place.Accessibility,
+			&place.OpeningHours,
+			&place.AverageRating,
+			&place.RatingCount,
+			&place.Privacy,
+			&place.Status,
+			&place.CreatedAt,
+			&place.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan place: %w", err)
+		}
+
+		if locationJSON.Valid {
+			r.parseLocationJSON(locationJSON.String, &place)
+		}
+
+		places = append(places, &place)
+		byID[place.ID] = &place
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get places: %w", err)
+	}
+
+	var collaborators []Collaborator
+	collaboratorsQuery := `
+		SELECT 
+			pc.id, pc.place_id, pc.user_id, pc.role, pc.permissions, pc.created_at,
+			u.username, COALESCE(u.display_name, '') AS display_name,
+			COALESCE(u.avatar_url, '') AS avatar_url
+		FROM place_collaborators pc
+		JOIN users u ON pc.user_id = u.id
+		WHERE pc.place_id = ANY($1)
+		ORDER BY pc.created_at`
+
+	if err := r.db.SelectContext(ctx, &collaborators, collaboratorsQuery, pq.Array(ids)); err != nil {
+		return nil, fmt.Errorf("failed to get collaborators: %w", err)
+	}
+	for _, collaborator := range collaborators {
+		if place, ok := byID[collaborator.PlaceID]; ok {
+			place.Collaborators = append(place.Collaborators, collaborator)
+		}
+	}
+
+	// Only the first media item of each place, which serves as its cover
+	coverQuery := `
+		SELECT DISTINCT ON (pm.place_id)
+			pm.id, pm.media_id, pm.place_id, COALESCE(pm.caption, ''), pm.order_position,
+			pm.created_at, COALESCE(m.cdn_url, ''), COALESCE(m.thumbnail_medium, ''),
+			m.mime_type, m.uploaded_by, COALESCE(m.created_at, pm.created_at)
+		FROM place_media pm
+		JOIN media m ON pm.media_id = m.id
+		WHERE pm.place_id = ANY($1)
+		ORDER BY pm.place_id, pm.order_position, pm.created_at`
+
+	coverRows, err := r.db.QueryContext(ctx, coverQuery, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get place media: %w", err)
+	}
+	defer coverRows.Close()
+
+	for coverRows.Next() {
+		var media Media
+		err := coverRows.Scan(
+			&media.ID,
+			&media.MediaID,
+			&media.PlaceID,
+			&media.Caption,
+			&media.OrderPosition,
+			&media.CreatedAt,
+			&media.URL,
+			&media.ThumbnailURL,
+			&media.MimeType,
+			&media.UploadedBy,
+			&media.UploadedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan place media: %w", err)
+		}
+		r.mediaURLs(&media)
+		if place, ok := byID[media.PlaceID]; ok {
+			place.Media = append(place.Media, media)
+		}
+	}
+
+	return places, coverRows.Err()
+}
+
 // Update updates a place
 func (r *PostgresRepository) UpdateByID(ctx context.Context, id string, updates map[string]interface{}) error {
 	// Build dynamic update query
@@ -225,19 +381,24 @@ func (r *PostgresRepository) UpdateByID(ctx context.Context, id string, updates
 		
 		// Handle special fields
 		switch field {
-		case "category", "tags", "amenities":
+		case "category", "tags", "amenities", "accessibility":
 			setClause += fmt.Sprintf("%s = $%d", field, argCount)
 			args = append(args, pq.Array(value))
-		case "location":
-			if loc, ok := value.(*LocationInput); ok && loc != nil {
-				setClause += fmt.Sprintf("location = ST_GeomFromGeoJSON('{\"type\":\"Point\",\"coordinates\":[%f,%f]}')", 
-					loc.Longitude, loc.Latitude)
-				argCount-- // Don't increment as we're not using a placeholder
+		case "location", "bounds":
+			geometry, err := geometryArg(value)
+			if err != nil {
+				return err
 			}
+			setClause += fmt.Sprintf("%s = ST_GeomFromGeoJSON($%d::text)", field, argCount)
+			args = append(args, geometry)
 		case "opening_hours", "contact_info":
-			jsonData, _ := json.Marshal(value)
+			// The JSONB types store themselves, NULL when unset
+			if _, ok := value.(driver.Valuer); !ok {
+				jsonData, _ := json.Marshal(value)
+				value = string(jsonData)
+			}
 			setClause += fmt.Sprintf("%s = $%d::jsonb", field, argCount)
-			args = append(args, string(jsonData))
+			args = append(args, value)
 		default:
 			setClause += fmt.Sprintf("%s = $%d", field, argCount)
 			args = append(args, value)
@@ -266,12 +427,50 @@ func (r *PostgresRepository) UpdateByID(ctx context.Context, id string, updates
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("place not found")
+		return fmt.Errorf("place %s: %w", id, ErrPlaceNotFound)
 	}
 
 	return nil
 }
 
+// geometryArg returns a location or bounds as GeoJSON text to bind for
+// ST_GeomFromGeoJSON, or nil to store NULL
+func geometryArg(value interface{}) (interface{}, error) {
+	var geometry interface{}
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case *LocationInput:
+		if v == nil {
+			return nil, nil
+		}
+		geometry = GeoPoint{Type: "Point", Coordinates: []float64{v.Longitude, v.Latitude}}
+	case *BoundsInput:
+		if v == nil {
+			return nil, nil
+		}
+		geometry = GeoPolygon{Type: "Polygon", Coordinates: v.Coordinates}
+	case *GeoPoint:
+		if v == nil || len(v.Coordinates) == 0 {
+			return nil, nil
+		}
+		geometry = v
+	case *GeoPolygon:
+		if v == nil || len(v.Coordinates) == 0 {
+			return nil, nil
+		}
+		geometry = v
+	default:
+		return nil, fmt.Errorf("unsupported geometry %T", value)
+	}
+
+	data, err := json.Marshal(geometry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode geometry: %w", err)
+	}
+	return string(data), nil
+}
+
 // Delete soft deletes a place
 func (r *PostgresRepository) Delete(ctx context.Context, id string) error {
 	query := `
@@ -290,7 +489,7 @@ func (r *PostgresRepository) Delete(ctx context.Context, id string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("place not found")
+		return fmt.Errorf("place %s: %w", id, ErrPlaceNotFound)
 	}
 
 	return nil
@@ -299,84 +498,9 @@ func (r *PostgresRepository) Delete(ctx context.Context, id string) error {
 // Search searches for places
 func (r *PostgresRepository) SearchPlaces(ctx context.Context, input SearchPlacesInput) ([]*Place, error) {
 	var places []*Place
-	query := `
-		SELECT 
-			id, name, description, type, parent_id,
-			ST_AsGeoJSON(location) as location,
-			street_address, city, state, country, postal_code,
-			created_by, category, tags, average_rating, rating_count,
-			privacy, status, created_at, updated_at
-		FROM places
-		WHERE status = 'active'`
-
-	args := []interface{}{}
-	argCount := 1
-
-	// Text search
-	if input.Query != "" {
-		query += fmt.Sprintf(" AND (name ILIKE $%d OR description ILIKE $%d)", argCount, argCount)
-		searchPattern := "%" + input.Query + "%"
-		args = append(args, searchPattern)
-		argCount++
-	}
-
-	// Type filter
-	if input.Type != "" {
-		query += fmt.Sprintf(" AND type = $%d", argCount)
-		args = append(args, input.Type)
-		argCount++
-	}
-
-	// Category filter
-	if len(input.Category) > 0 {
-		query += fmt.Sprintf(" AND category && $%d", argCount)
-		args = append(args, pq.Array(input.Category))
-		argCount++
-	}
-
-	// Tags filter
-	if len(input.Tags) > 0 {
-		query += fmt.Sprintf(" AND tags && $%d", argCount)
-		args = append(args, pq.Array(input.Tags))
-		argCount++
-	}
-
-	// Location filter
-	if input.City != "" {
-		query += fmt.Sprintf(" AND city ILIKE $%d", argCount)
-		args = append(args, "%"+input.City+"%")
-		argCount++
-	}
+	query, args := searchPlacesQuery(input)
 
-	if input.Country != "" {
-		query += fmt.Sprintf(" AND country ILIKE $%d", argCount)
-		args = append(args, "%"+input.Country+"%")
-		argCount++
-	}
-
-	// Spatial query
-	if input.Latitude != nil && input.Longitude != nil && input.Radius != nil {
-		query += fmt.Sprintf(` AND ST_DWithin(
-			location::geography,
-			ST_SetSRID(ST_MakePoint($%d, $%d), 4326)::geography,
-			$%d
-		)`, argCount, argCount+1, argCount+2)
-		args = append(args, *input.Longitude, *input.Latitude, *input.Radius)
-		argCount += 3
-	}
-
-	// Ordering
-	if input.Latitude != nil && input.Longitude != nil {
-		query += fmt.Sprintf(` ORDER BY location <-> ST_SetSRID(ST_MakePoint($%d, $%d), 4326)`, argCount, argCount+1)
-		args = append(args, *input.Longitude, *input.Latitude)
-		argCount += 2
-	} else {
-		query += " ORDER BY created_at DESC"
-	}
-
-	// Pagination
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argCount, argCount+1)
-	args = append(args, input.Limit, input.Offset)
+	defer r.slowQueries.Track(ctx, "places.search", query, args...)()
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -401,8 +525,11 @@ func (r *PostgresRepository) SearchPlaces(ctx context.Context, input SearchPlace
 			&place.Country,
 			&place.PostalCode,
 			&place.CreatedBy,
-			pq.Array(&place.Category),
-			pq.Array(&place.Tags),
+			&place.Category,
+			&place.Tags,
+			&place.Accessibility,
+			&place.Amenities,
+			&place.OpeningHours,
 			&place.AverageRating,
 			&place.RatingCount,
 			&place.Privacy,
@@ -428,30 +555,142 @@ func (r *PostgresRepository) SearchPlaces(ctx context.Context, input SearchPlace
 	return places, nil
 }
 
+// count runs a query counting the places a search matches across all pages
+func (r *PostgresRepository) count(ctx context.Context, name, query string, args ...interface{}) (int64, error) {
+	defer r.slowQueries.Track(ctx, name, query, args...)()
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count places: %w", err)
+	}
+
+	return total, nil
+}
+
+// searchPlacesQuery builds the query SearchPlaces runs for the given input
+func searchPlacesQuery(input SearchPlacesInput) (string, []interface{}) {
+	b := sqlbuilder.New(`
+		SELECT 
+			id, name, description, type, parent_id,
+			ST_AsGeoJSON(location) as location,
+			street_address, city, state, country, postal_code,
+			created_by, category, tags, accessibility, amenities, opening_hours, average_rating, rating_count,
+			privacy, status, created_at, updated_at
+		FROM places
+		WHERE status = 'active'`)
+
+	search := applySearchConditions(b, input)
+
+	// Ordering
+	if input.Latitude != nil && input.Longitude != nil {
+		b.Append(` ORDER BY location <-> ST_SetSRID(ST_MakePoint(?, ?), 4326)`, *input.Longitude, *input.Latitude)
+	} else if input.After != nil {
+		condition, cursorArgs := input.After.Condition("created_at", "id", true, b.Next())
+		b.WhereNumbered(condition, cursorArgs...)
+		input.Offset = 0
+		b.Append(" ORDER BY " + pagination.OrderBy("created_at", "id", true))
+	} else if search != "" {
+		// Best matches first
+		b.Append(" ORDER BY "+textsearch.Rank("search_vector")+" DESC, id", search)
+	} else {
+		b.Append(" ORDER BY " + pagination.OrderBy("created_at", "id", true))
+	}
+
+	// Pagination
+	b.Append(" LIMIT ? OFFSET ?", input.Limit, input.Offset)
+
+	return b.SQL(), b.Args()
+}
+
+// countPlacesQuery builds the query counting the places that match the
+// input across all pages
+func countPlacesQuery(input SearchPlacesInput) (string, []interface{}) {
+	b := sqlbuilder.New(`
+		SELECT COUNT(*)
+		FROM places
+		WHERE status = 'active'`)
+
+	applySearchConditions(b, input)
+
+	return b.SQL(), b.Args()
+}
+
+// applySearchConditions adds the conditions of a search to a query over
+// places. It returns the text search query, empty when there is no text.
+func applySearchConditions(b *sqlbuilder.Builder, input SearchPlacesInput) string {
+	// Text search
+	search := textsearch.PrefixQuery(input.Query)
+	if search != "" {
+		b.Where(textsearch.Match("search_vector"), search)
+	}
+
+	// Type filter
+	if input.Type != "" {
+		b.Where("type = ?", input.Type)
+	}
+
+	// Category filter
+	if len(input.Category) > 0 {
+		b.Where("category && ?", pq.Array(input.Category))
+	}
+
+	// Tags filter
+	if len(input.Tags) > 0 {
+		b.Where("tags && ?", pq.Array(input.Tags))
+	}
+
+	// Accessibility filter, every attribute must hold
+	if len(input.Accessibility) > 0 {
+		b.Where("accessibility @> ?", pq.Array(input.Accessibility))
+	}
+
+	// Amenities filter, every amenity must be offered
+	if len(input.Amenities) > 0 {
+		b.Where("amenities @> ?", pq.Array(input.Amenities))
+	}
+
+	// Location filter
+	if input.City != "" {
+		b.Where("city ILIKE ?", "%"+input.City+"%")
+	}
+
+	if input.Country != "" {
+		b.Where("country ILIKE ?", "%"+input.Country+"%")
+	}
+
+	// Spatial query
+	if input.Latitude != nil && input.Longitude != nil && input.Radius != nil {
+		b.Where(`ST_DWithin(
+			location::geography,
+			ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography,
+			?
+		)`, *input.Longitude, *input.Latitude, *input.Radius)
+	}
+
+	if input.OpenNow {
+		b.Where("place_open_at(opening_hours, NOW())")
+	}
+
+	return search
+}
+
 // GetNearby finds nearby places
 func (r *PostgresRepository) GetNearbyPlaces(ctx context.Context, input NearbyPlacesInput) ([]*Place, error) {
-	// Convert to SearchPlacesInput and use Search method
+	// SearchPlaces applies the radius and orders by distance
 	searchInput := SearchPlacesInput{
-		Type:      input.Type,
-		Category:  input.Category,
-		Tags:      input.Tags,
-		Latitude:  &input.Latitude,
-		Longitude: &input.Longitude,
-		Radius:    &input.Radius,
-		Limit:     input.Limit,
-		Offset:    input.Offset,
-	}
-
-	result, err := r.Search(ctx, searchInput.Query, SearchFilters{
-		Category: searchInput.Category,
-		Tags:     searchInput.Tags,
-		Limit:    searchInput.Limit,
-		Offset:   searchInput.Offset,
-	})
-	if err != nil {
-		return nil, err
-	}
-	return result.Places, nil
+		Type:          input.Type,
+		Category:      input.Category,
+		Tags:          input.Tags,
+		Accessibility: input.Accessibility,
+		Amenities:     input.Amenities,
+		Latitude:      &input.Latitude,
+		Longitude:     &input.Longitude,
+		Radius:        &input.Radius,
+		Limit:         input.Limit,
+		Offset:        input.Offset,
+	}
+
+	return r.SearchPlaces(ctx, searchInput)
 }
 
 // GetByTripID retrieves all places for a trip
@@ -492,8 +731,8 @@ func (r *PostgresRepository) GetByTripID(ctx context.Context, tripID string) ([]
 			&place.Country,
 			&place.PostalCode,
 			&place.CreatedBy,
-			pq.Array(&place.Category),
-			pq.Array(&place.Tags),
+			&place.Category,
+			&place.Tags,
 			&place.AverageRating,
 			&place.RatingCount,
 			&place.Privacy,
@@ -556,8 +795,8 @@ func (r *PostgresRepository) GetChildren(ctx context.Context, parentID string) (
 			&place.Country,
 			&place.PostalCode,
 			&place.CreatedBy,
-			pq.Array(&place.Category),
-			pq.Array(&place.Tags),
+			&place.Category,
+			&place.Tags,
 			&place.AverageRating,
 			&place.RatingCount,
 			&place.Privacy,
@@ -580,47 +819,232 @@ func (r *PostgresRepository) GetChildren(ctx context.Context, parentID string) (
 		places = append(places, &place)
 	}
 
-	return places, nil
+	return places, nil
+}
+
+// Helper functions
+
+func (r *PostgresRepository) getPlaceMedia(ctx context.Context, placeID string) ([]Media, error) {
+	var media []Media
+	query := `
+		SELECT 
+			pm.id, pm.media_id, pm.place_id, COALESCE(pm.caption, '') AS caption,
+			COALESCE(pm.order_position, 0) AS order_position, pm.created_at,
+			COALESCE(m.cdn_url, '') AS url, COALESCE(m.thumbnail_medium, '') AS thumbnail_url,
+			m.mime_type, m.uploaded_by, COALESCE(m.created_at, pm.created_at) AS uploaded_at
+		FROM place_media pm
+		JOIN media m ON pm.media_id = m.id
+		WHERE pm.place_id = $1
+		ORDER BY pm.order_position, pm.created_at`
+
+	err := r.db.SelectContext(ctx, &media, query, placeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get place media: %w", err)
+	}
+
+	for i := range media {
+		r.mediaURLs(&media[i])
+	}
+
+	return media, nil
+}
+
+// mediaURLs points the URLs of a media item at the CDN, when one is set
+func (r *PostgresRepository) mediaURLs(item *Media) {
+	item.URL = r.cdn.URL(item.URL, item.UploadedAt)
+	item.ThumbnailURL = r.cdn.URL(item.ThumbnailURL, item.UploadedAt)
+}
+
+func (r *PostgresRepository) getCollaborators(ctx context.Context, placeID string) ([]Collaborator, error) {
+	var collaborators []Collaborator
+	query := `
+		SELECT 
+			pc.id, pc.place_id, pc.user_id, pc.role, pc.permissions, pc.created_at,
+			u.username, COALESCE(u.display_name, '') AS display_name,
+			COALESCE(u.avatar_url, '') AS avatar_url
+		FROM place_collaborators pc
+		JOIN users u ON pc.user_id = u.id
+		WHERE pc.place_id = $1
+		ORDER BY pc.created_at`
+
+	err := r.db.SelectContext(ctx, &collaborators, query, placeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collaborators: %w", err)
+	}
+
+	return collaborators, nil
+}
+
+// getWaterSource retrieves the water source details of a place, or nil when
+// none have been given
+func (r *PostgresRepository) getWaterSource(ctx context.Context, placeID string) (*WaterSource, error) {
+	var source WaterSource
+	query := `
+		SELECT kind, reliability, flowing_months, treatment_required,
+			COALESCE(notes, '') AS notes, last_verified_at, updated_at
+		FROM place_water_sources
+		WHERE place_id = $1`
+
+	err := r.db.GetContext(ctx, &source, query, placeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get water source: %w", err)
+	}
+
+	return &source, nil
+}
+
+// SetWaterSource records or replaces the water source details of a place
+func (r *PostgresRepository) SetWaterSource(ctx context.Context, placeID string, source *WaterSource) error {
+	query := `
+		INSERT INTO place_water_sources (
+			place_id, kind, reliability, flowing_months, treatment_required,
+			notes, last_verified_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, CURRENT_TIMESTAMP)
+		ON CONFLICT (place_id) DO UPDATE SET
+			kind = EXCLUDED.kind,
+			reliability = EXCLUDED.reliability,
+			flowing_months = EXCLUDED.flowing_months,
+			treatment_required = EXCLUDED.treatment_required,
+			notes = EXCLUDED.notes,
+			last_verified_at = EXCLUDED.last_verified_at,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		placeID,
+		source.Kind,
+		source.Reliability,
+		pq.Array(source.FlowingMonths),
+		source.TreatmentRequired,
+		source.Notes,
+		source.LastVerifiedAt,
+	).Scan(&source.UpdatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return ErrPlaceNotFound
+		}
+		return fmt.Errorf("failed to set water source: %w", err)
+	}
+
+	return nil
+}
+
+// ClearWaterSource removes the water source details of a place
+func (r *PostgresRepository) ClearWaterSource(ctx context.Context, placeID string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM place_water_sources WHERE place_id = $1`, placeID); err != nil {
+		return fmt.Errorf("failed to clear water source: %w", err)
+	}
+	return nil
+}
+
+// getCampgroundLink retrieves the reservation system a campground is booked
+// through, or nil when it has not been linked
+func (r *PostgresRepository) getCampgroundLink(ctx context.Context, placeID string) (*CampgroundLink, error) {
+	var link CampgroundLink
+	query := `
+		SELECT provider, facility_id, updated_at
+		FROM place_campgrounds
+		WHERE place_id = $1`
+
+	err := r.db.GetContext(ctx, &link, query, placeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get campground link: %w", err)
+	}
+
+	return &link, nil
+}
+
+// SetCampgroundLink records or replaces the reservation system a campground
+// is booked through
+func (r *PostgresRepository) SetCampgroundLink(ctx context.Context, placeID string, link *CampgroundLink) error {
+	query := `
+		INSERT INTO place_campgrounds (place_id, provider, facility_id, updated_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (place_id) DO UPDATE SET
+			provider = EXCLUDED.provider,
+			facility_id = EXCLUDED.facility_id,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`
+
+	err := r.db.QueryRowContext(ctx, query, placeID, link.Provider, link.FacilityID).Scan(&link.UpdatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return ErrPlaceNotFound
+		}
+		return fmt.Errorf("failed to set campground link: %w", err)
+	}
+
+	return nil
 }
 
-// Helper functions
+// ClearCampgroundLink removes the reservation system link of a campground
+func (r *PostgresRepository) ClearCampgroundLink(ctx context.Context, placeID string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM place_campgrounds WHERE place_id = $1`, placeID); err != nil {
+		return fmt.Errorf("failed to clear campground link: %w", err)
+	}
+	return nil
+}
 
-func (r *PostgresRepository) getPlaceMedia(ctx context.Context, placeID string) ([]Media, error) {
-	var media []Media
+// ListContactsToCheck returns the active places with links in their contact
+// details that were never checked or last checked before the time, the
+// longest unchecked first. Only the ID, contact details and update time are
+// loaded.
+func (r *PostgresRepository) ListContactsToCheck(ctx context.Context, checkedBefore time.Time, limit int) ([]*Place, error) {
 	query := `
-		SELECT 
-			pm.id, pm.media_id, pm.place_id, pm.caption, pm.order_position,
-			pm.created_at, m.cdn_url, m.thumbnail_medium, m.mime_type, m.uploaded_by
-		FROM place_media pm
-		JOIN media m ON pm.media_id = m.id
-		WHERE pm.place_id = $1
-		ORDER BY pm.order_position, pm.created_at`
+		SELECT id, contact_info, updated_at
+		FROM places
+		WHERE status = 'active'
+			AND (COALESCE(contact_info->>'website', '') <> ''
+				OR COALESCE(contact_info->'social', '{}'::jsonb) <> '{}'::jsonb)
+			AND COALESCE((contact_info->'verification'->>'checked_at')::timestamptz, '-infinity') < $1
+		ORDER BY COALESCE((contact_info->'verification'->>'checked_at')::timestamptz, '-infinity'), id
+		LIMIT $2`
 
-	err := r.db.SelectContext(ctx, &media, query, placeID)
+	rows, err := r.db.QueryContext(ctx, query, checkedBefore, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get place media: %w", err)
+		return nil, fmt.Errorf("failed to list contacts to check: %w", err)
 	}
+	defer rows.Close()
 
-	return media, nil
-}
+	places := []*Place{}
+	for rows.Next() {
+		var place Place
+		if err := rows.Scan(&place.ID, &place.ContactInfo, &place.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan place contact: %w", err)
+		}
+		places = append(places, &place)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list contacts to check: %w", err)
+	}
 
-func (r *PostgresRepository) getCollaborators(ctx context.Context, placeID string) ([]Collaborator, error) {
-	var collaborators []Collaborator
-	query := `
-		SELECT 
-			pc.id, pc.place_id, pc.user_id, pc.role, pc.permissions, pc.created_at,
-			u.username, u.display_name, u.avatar_url
-		FROM place_collaborators pc
-		JOIN users u ON pc.user_id = u.id
-		WHERE pc.place_id = $1
-		ORDER BY pc.created_at`
+	return places, nil
+}
 
-	err := r.db.SelectContext(ctx, &collaborators, query, placeID)
+// SetContactVerification records a check of a place's contact links. It is
+// left out when the place changed since it was loaded for the check, since
+// the links checked may be gone.
+func (r *PostgresRepository) SetContactVerification(ctx context.Context, placeID string, updatedAt time.Time, verification *ContactVerification) error {
+	data, err := json.Marshal(verification)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get collaborators: %w", err)
+		return fmt.Errorf("failed to marshal contact verification: %w", err)
 	}
 
-	return collaborators, nil
+	query := `
+		UPDATE places
+		SET contact_info = jsonb_set(contact_info, '{verification}', $3::jsonb)
+		WHERE id = $1 AND updated_at = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, placeID, updatedAt, string(data)); err != nil {
+		return fmt.Errorf("failed to set contact verification: %w", err)
+	}
+	return nil
 }
 
 // UpdateRating updates the average rating for a place
@@ -656,6 +1080,150 @@ func (r *PostgresRepository) IncrementRatingCount(ctx context.Context, placeID s
 	return nil
 }
 
+// recomputeReviewRatingQuery sets a place's average rating and rating count
+// from its reviews. The average is NULL once the last review is removed.
+const recomputeReviewRatingQuery = `
+	UPDATE places p
+	SET average_rating = r.average, rating_count = r.count
+	FROM (
+		SELECT ROUND(AVG(rating), 2) AS average, COUNT(*) AS count
+		FROM place_reviews
+		WHERE place_id = $1
+	) r
+	WHERE p.id = $1`
+
+// lockPlace locks a place's row for the rest of the transaction, so that
+// concurrent reviews recompute its rating one after the other, each seeing
+// the reviews saved before it
+func lockPlace(ctx context.Context, tx *sqlx.Tx, placeID string) error {
+	var id string
+	err := tx.GetContext(ctx, &id, `SELECT id FROM places WHERE id = $1 FOR UPDATE`, placeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPlaceNotFound
+		}
+		return fmt.Errorf("failed to lock place: %w", err)
+	}
+	return nil
+}
+
+// CreateReview records a user's review of a place and recomputes the
+// place's rating. Each user reviews a place once. The review's photos are
+// recorded as in use by it, and must be media the user uploaded.
+func (r *PostgresRepository) CreateReview(ctx context.Context, review *PlaceReview) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockPlace(ctx, tx, review.PlaceID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO place_reviews (place_id, user_id, rating, review_text, media_ids)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		review.PlaceID,
+		review.UserID,
+		review.Rating,
+		review.ReviewText,
+		review.MediaIDs,
+	).Scan(&review.ID, &review.CreatedAt, &review.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create review: %w",
+			repoerr.Classify(err, nil, ErrReviewExists, ErrPlaceNotFound))
+	}
+
+	if len(review.MediaIDs) > 0 {
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO media_usage (media_id, entity_type, entity_id)
+			SELECT id, $1, $2 FROM media WHERE id = ANY($3::uuid[]) AND uploaded_by = $4`,
+			MediaUsagePlaceReview, review.ID, review.MediaIDs, review.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to record review media: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rowsAffected != int64(len(review.MediaIDs)) {
+			return ErrInvalidReviewMedia
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, recomputeReviewRatingQuery, review.PlaceID); err != nil {
+		return fmt.Errorf("failed to recompute place rating: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ListReviews retrieves a page of a place's reviews with their authors,
+// newest first
+func (r *PostgresRepository) ListReviews(ctx context.Context, placeID string, limit, offset int) ([]*PlaceReview, error) {
+	reviews := []*PlaceReview{}
+	query := `
+		SELECT
+			pr.id, pr.place_id, pr.user_id, pr.rating, pr.review_text, pr.media_ids,
+			pr.created_at, pr.updated_at,
+			u.username, COALESCE(u.display_name, '') AS display_name,
+			COALESCE(u.avatar_url, '') AS avatar_url
+		FROM place_reviews pr
+		JOIN users u ON u.id = pr.user_id
+		WHERE pr.place_id = $1
+		ORDER BY pr.created_at DESC, pr.id DESC
+		LIMIT $2 OFFSET $3`
+
+	if err := r.db.SelectContext(ctx, &reviews, query, placeID, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list reviews: %w", err)
+	}
+
+	return reviews, nil
+}
+
+// DeleteReview removes a user's review of a place, with the record of its
+// photos being in use, and recomputes the place's rating
+func (r *PostgresRepository) DeleteReview(ctx context.Context, placeID, userID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockPlace(ctx, tx, placeID); err != nil {
+		return err
+	}
+
+	var reviewID string
+	err = tx.GetContext(ctx, &reviewID, `
+		DELETE FROM place_reviews
+		WHERE place_id = $1 AND user_id = $2
+		RETURNING id`, placeID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrReviewNotFound
+		}
+		return fmt.Errorf("failed to delete review: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM media_usage
+		WHERE entity_type = $1 AND entity_id = $2`, MediaUsagePlaceReview, reviewID)
+	if err != nil {
+		return fmt.Errorf("failed to release review media: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, recomputeReviewRatingQuery, placeID); err != nil {
+		return fmt.Errorf("failed to recompute place rating: %w", err)
+	}
+
+	return tx.Commit()
+}
+
 // Add missing string import
 // import "strings" - should be added at the top
 
@@ -670,7 +1238,7 @@ func (r *PostgresRepository) GetByCreator(ctx context.Context, creatorID string)
 			privacy, status, created_at, updated_at
 		FROM places
 		WHERE created_by = $1 AND status = 'active'
-		ORDER BY created_at DESC`
+		ORDER BY created_at DESC, id DESC`
 	
 	rows, err := r.db.QueryContext(ctx, query, creatorID)
 	if err != nil {
@@ -730,9 +1298,15 @@ func (r *PostgresRepository) Update(ctx context.Context, place *Place) error {
 		"postal_code":    place.PostalCode,
 		"category":       place.Category,
 		"tags":           place.Tags,
+		"accessibility":  place.Accessibility,
+		"access_fees":    place.AccessFees,
 		"privacy":        place.Privacy,
 		"status":         place.Status,
-		"updated_at":     time.Now(),
+		"location":       place.Location,
+		"bounds":         place.Bounds,
+		"opening_hours":  place.OpeningHours,
+		"contact_info":   place.ContactInfo,
+		"amenities":      place.Amenities,
 	}
 	
 	return r.UpdateByID(ctx, place.ID, updates)
@@ -801,11 +1375,14 @@ func (r *PostgresRepository) GetByCategory(ctx context.Context, category string,
 func (r *PostgresRepository) Search(ctx context.Context, query string, filters SearchFilters) (*SearchResult, error) {
 	// Use existing SearchPlaces method
 	input := SearchPlacesInput{
-		Query:    query,
-		Category: filters.Category,
-		Tags:     filters.Tags,
-		Limit:    filters.Limit,
-		Offset:   filters.Offset,
+		Query:         query,
+		Category:      filters.Category,
+		Tags:          filters.Tags,
+		Accessibility: filters.Accessibility,
+		Amenities:     filters.Amenities,
+		Limit:         filters.Limit,
+		Offset:        filters.Offset,
+		After:         filters.After,
 	}
 	
 	places, err := r.SearchPlaces(ctx, input)
@@ -813,8 +1390,17 @@ func (r *PostgresRepository) Search(ctx context.Context, query string, filters S
 		return nil, err
 	}
 	
-	// Count total results - simplified for now
-	total := int64(len(places))
+	// Pages after a cursor have no total
+	var total int64
+	if input.After == nil {
+		total, err = pagination.Total(len(places), input.Limit, input.Offset, func() (int64, error) {
+			query, args := countPlacesQuery(input)
+			return r.count(ctx, "places.search_count", query, args...)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 	
 	return &SearchResult{
 		Places: places,
@@ -847,11 +1433,13 @@ func (r *PostgresRepository) GetInBounds(ctx context.Context, bounds Bounds) ([]
 		FROM places
 		WHERE status = 'active'
 			AND ST_Within(
-				location,
+				location::geometry,
 				ST_MakeEnvelope($1, $2, $3, $4, 4326)
 			)
 		ORDER BY created_at DESC`
 	
+	defer r.slowQueries.Track(ctx, "places.in_bounds", query, bounds.MinLng, bounds.MinLat, bounds.MaxLng, bounds.MaxLat)()
+
 	rows, err := r.db.QueryContext(ctx, query, bounds.MinLng, bounds.MinLat, bounds.MaxLng, bounds.MaxLat)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get places in bounds: %w", err)
@@ -897,62 +1485,10 @@ func (r *PostgresRepository) GetInBounds(ctx context.Context, bounds Bounds) ([]
 }
 // SearchWithSpatialContext performs spatial search with enhanced area filtering
 func (r *PostgresRepository) SearchWithSpatialContext(ctx context.Context, query string, spatial *nlp.SpatialSearchContext, filters SearchFilters) (*SearchResult, error) {
-	baseQuery := `
-		SELECT 
-			id, name, description, type, parent_id,
-			ST_AsGeoJSON(location) as location,
-			ST_AsGeoJSON(bounds) as bounds,
-			street_address, city, state, country, postal_code,
-			created_by, category, tags, average_rating, rating_count,
-			privacy, status, created_at, updated_at
-		FROM places
-		WHERE status = 'active'`
-	
-	var conditions []string
-	var args []interface{}
-	argCount := 0
-	
-	// Text search
-	if query != "" {
-		argCount++
-		conditions = append(conditions, fmt.Sprintf("(name ILIKE $%d OR description ILIKE $%d)", argCount, argCount))
-		args = append(args, "%"+query+"%")
-	}
-	
-	// Category filter
-	if len(filters.Category) > 0 {
-		argCount++
-		conditions = append(conditions, fmt.Sprintf("category = ANY($%d)", argCount))
-		args = append(args, pq.Array(filters.Category))
-	}
-	
-	// Spatial filters
-	if spatial != nil {
-		spatialConditions, spatialArgs := r.buildSpatialConditions(spatial, argCount)
-		conditions = append(conditions, spatialConditions...)
-		args = append(args, spatialArgs...)
-	}
-	
-	// Build final query
-	if len(conditions) > 0 {
-		baseQuery += " AND " + strings.Join(conditions, " AND ")
-	}
-	
-	// Add ordering
-	baseQuery += " ORDER BY created_at DESC"
-	
-	// Add limit/offset
-	if filters.Limit > 0 {
-		argCount = len(args) + 1
-		baseQuery += fmt.Sprintf(" LIMIT $%d", argCount)
-		args = append(args, filters.Limit)
-	}
-	if filters.Offset > 0 {
-		argCount = len(args) + 1
-		baseQuery += fmt.Sprintf(" OFFSET $%d", argCount)
-		args = append(args, filters.Offset)
-	}
+	baseQuery, args := spatialSearchQuery(query, spatial, filters)
 	
+	defer r.slowQueries.Track(ctx, "places.spatial_search", baseQuery, args...)()
+
 	rows, err := r.db.QueryContext(ctx, baseQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search places with spatial context: %w", err)
@@ -994,65 +1530,129 @@ func (r *PostgresRepository) SearchWithSpatialContext(ctx context.Context, query
 		places = append(places, place)
 	}
 	
+	total, err := pagination.Total(len(places), filters.Limit, filters.Offset, func() (int64, error) {
+		countQuery, countArgs := countSpatialQuery(query, spatial, filters)
+		return r.count(ctx, "places.spatial_search_count", countQuery, countArgs...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	
 	return &SearchResult{
 		Places: places,
-		Total:  int64(len(places)), // Simplified total count
+		Total:  total,
 	}, nil
 }
 
-// buildSpatialConditions creates PostGIS spatial query conditions
-func (r *PostgresRepository) buildSpatialConditions(spatial *nlp.SpatialSearchContext, startArgCount int) ([]string, []interface{}) {
-	var conditions []string
-	var args []interface{}
-	argCount := startArgCount
+// spatialSearchQuery builds the query SearchWithSpatialContext runs
+func spatialSearchQuery(query string, spatial *nlp.SpatialSearchContext, filters SearchFilters) (string, []interface{}) {
+	b := sqlbuilder.New(`
+		SELECT 
+			id, name, description, type, parent_id,
+			ST_AsGeoJSON(location) as location,
+			ST_AsGeoJSON(bounds) as bounds,
+			street_address, city, state, country, postal_code,
+			created_by, category, tags, average_rating, rating_count,
+			privacy, status, created_at, updated_at
+		FROM places
+		WHERE status = 'active'`)
+	
+	search := applySpatialConditions(b, query, spatial, filters)
+	
+	// Add ordering, best matches first when searching
+	if search != "" {
+		b.Append(" ORDER BY "+textsearch.Rank("search_vector")+" DESC, created_at DESC", search)
+	} else {
+		b.Append(" ORDER BY created_at DESC")
+	}
+	
+	// Add limit/offset
+	if filters.Limit > 0 {
+		b.Append(" LIMIT ?", filters.Limit)
+	}
+	if filters.Offset > 0 {
+		b.Append(" OFFSET ?", filters.Offset)
+	}
+
+	return b.SQL(), b.Args()
+}
+
+// countSpatialQuery builds the query counting the places a spatial search
+// matches across all pages
+func countSpatialQuery(query string, spatial *nlp.SpatialSearchContext, filters SearchFilters) (string, []interface{}) {
+	b := sqlbuilder.New(`
+		SELECT COUNT(*)
+		FROM places
+		WHERE status = 'active'`)
+
+	applySpatialConditions(b, query, spatial, filters)
+
+	return b.SQL(), b.Args()
+}
+
+// applySpatialConditions adds the conditions of a spatial search to a query
+// over places. It returns the text search query, empty when there is no text.
+func applySpatialConditions(b *sqlbuilder.Builder, query string, spatial *nlp.SpatialSearchContext, filters SearchFilters) string {
+	// Text search
+	search := textsearch.PrefixQuery(query)
+	if search != "" {
+		b.Where(textsearch.Match("search_vector"), search)
+	}
+	
+	// Category filter
+	if len(filters.Category) > 0 {
+		b.Where("category && ?", pq.Array(filters.Category))
+	}
+
+	// Accessibility filter, every attribute must hold
+	if len(filters.Accessibility) > 0 {
+		b.Where("accessibility @> ?", pq.Array(filters.Accessibility))
+	}
+
+	// Amenities filter, every amenity must be offered
+	if len(filters.Amenities) > 0 {
+		b.Where("amenities @> ?", pq.Array(filters.Amenities))
+	}
 	
+	// Spatial filters
+	if spatial != nil {
+		addSpatialConditions(b, spatial)
+	}
+
+	return search
+}
+
+// addSpatialConditions adds the PostGIS conditions of a spatial search
+func addSpatialConditions(b *sqlbuilder.Builder, spatial *nlp.SpatialSearchContext) {
 	// Within area - place must be completely within the specified area
-	if spatial.Within != nil {
-		condition, spatialArgs := r.buildAreaCondition("ST_Within", spatial.Within, argCount)
-		if condition != "" {
-			conditions = append(conditions, condition)
-			args = append(args, spatialArgs...)
-			argCount += len(spatialArgs)
-		}
+	if condition, ok := areaCondition("ST_Within", spatial.Within); ok {
+		b.Where(condition.SQL, condition.Args...)
 	}
 	
 	// Near area - place must be within distance of the specified area
-	if spatial.Near != nil {
-		condition, spatialArgs := r.buildDistanceCondition(spatial.Near, argCount)
-		if condition != "" {
-			conditions = append(conditions, condition)
-			args = append(args, spatialArgs...)
-			argCount += len(spatialArgs)
-		}
+	if condition, ok := distanceCondition(spatial.Near); ok {
+		b.Where(condition.SQL, condition.Args...)
 	}
 	
 	// Intersects area - place must intersect with the specified area
-	if spatial.Intersects != nil {
-		condition, spatialArgs := r.buildAreaCondition("ST_Intersects", spatial.Intersects, argCount)
-		if condition != "" {
-			conditions = append(conditions, condition)
-			args = append(args, spatialArgs...)
-			argCount += len(spatialArgs)
-		}
+	if condition, ok := areaCondition("ST_Intersects", spatial.Intersects); ok {
+		b.Where(condition.SQL, condition.Args...)
 	}
 	
 	// Multiple areas - place must be in one of the specified areas
-	for _, area := range spatial.Areas {
-		condition, spatialArgs := r.buildAreaCondition("ST_Within", &area, argCount)
-		if condition != "" {
-			conditions = append(conditions, condition)
-			args = append(args, spatialArgs...)
-			argCount += len(spatialArgs)
+	var areas []sqlbuilder.Condition
+	for i := range spatial.Areas {
+		if condition, ok := areaCondition("ST_Within", &spatial.Areas[i]); ok {
+			areas = append(areas, condition)
 		}
 	}
-	
-	return conditions, args
+	b.WhereAny(areas)
 }
 
-// buildAreaCondition creates spatial conditions for geometric areas
-func (r *PostgresRepository) buildAreaCondition(operation string, area *nlp.AreaFilter, argCount int) (string, []interface{}) {
+// areaCondition creates spatial conditions for geometric areas
+func areaCondition(operation string, area *nlp.AreaFilter) (sqlbuilder.Condition, bool) {
 	if area == nil {
-		return "", nil
+		return sqlbuilder.Condition{}, false
 	}
 	
 	switch area.Type {
@@ -1062,14 +1662,16 @@ func (r *PostgresRepository) buildAreaCondition(operation string, area *nlp.Area
 			lat, latOk := coords[1].(float64)
 			lng, lngOk := coords[0].(float64)
 			if latOk && lngOk {
-				condition := fmt.Sprintf(`%s(
+				return sqlbuilder.Condition{
+					SQL: operation + `(
 					location::geography,
 					ST_Buffer(
-						ST_SetSRID(ST_MakePoint($%d, $%d), 4326)::geography,
-						$%d * 1000
+						ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography,
+						? * 1000
 					)
-				)`, operation, argCount+1, argCount+2, argCount+3)
-				return condition, []interface{}{lng, lat, *area.Radius}
+				)`,
+					Args: []interface{}{lng, lat, *area.Radius},
+				}, true
 			}
 		}
 		
@@ -1082,23 +1684,28 @@ func (r *PostgresRepository) buildAreaCondition(operation string, area *nlp.Area
 				"coordinates": coords,
 			})
 			if err == nil {
-				condition := fmt.Sprintf(`%s(
+				return sqlbuilder.Condition{
+					SQL: operation + `(
 					location,
-					ST_GeomFromGeoJSON($%d)
-				)`, operation, argCount+1)
-				return condition, []interface{}{string(coordsJSON)}
+					ST_GeomFromGeoJSON(?)
+				)`,
+					Args: []interface{}{string(coordsJSON)},
+				}, true
 			}
 		}
 		
 	case "region":
 		// For named regions, we'd typically look up in a regions table
 		// For now, we'll do a simple text match on location fields
-		condition := fmt.Sprintf(`(
-			city ILIKE $%d OR 
-			state ILIKE $%d OR 
-			country ILIKE $%d
-		)`, argCount+1, argCount+1, argCount+1)
-		return condition, []interface{}{"%" + area.Name + "%"}
+		pattern := "%" + area.Name + "%"
+		return sqlbuilder.Condition{
+			SQL: `(
+			city ILIKE ? OR 
+			state ILIKE ? OR 
+			country ILIKE ?
+		)`,
+			Args: []interface{}{pattern, pattern, pattern},
+		}, true
 		
 	case "bounds":
 		// For rectangular bounds
@@ -1108,21 +1715,23 @@ func (r *PostgresRepository) buildAreaCondition(operation string, area *nlp.Area
 			maxLng, _ := coords[2].(float64)
 			maxLat, _ := coords[3].(float64)
 			
-			condition := fmt.Sprintf(`%s(
+			return sqlbuilder.Condition{
+				SQL: operation + `(
 				location,
-				ST_MakeEnvelope($%d, $%d, $%d, $%d, 4326)
-			)`, operation, argCount+1, argCount+2, argCount+3, argCount+4)
-			return condition, []interface{}{minLng, minLat, maxLng, maxLat}
+				ST_MakeEnvelope(?, ?, ?, ?, 4326)
+			)`,
+				Args: []interface{}{minLng, minLat, maxLng, maxLat},
+			}, true
 		}
 	}
 	
-	return "", nil
+	return sqlbuilder.Condition{}, false
 }
 
-// buildDistanceCondition creates distance-based spatial conditions
-func (r *PostgresRepository) buildDistanceCondition(area *nlp.AreaFilter, argCount int) (string, []interface{}) {
+// distanceCondition creates distance-based spatial conditions
+func distanceCondition(area *nlp.AreaFilter) (sqlbuilder.Condition, bool) {
 	if area == nil || area.Radius == nil {
-		return "", nil
+		return sqlbuilder.Condition{}, false
 	}
 	
 	switch area.Type {
@@ -1131,17 +1740,19 @@ func (r *PostgresRepository) buildDistanceCondition(area *nlp.AreaFilter, argCou
 			lat, latOk := coords[1].(float64)
 			lng, lngOk := coords[0].(float64)
 			if latOk && lngOk {
-				condition := fmt.Sprintf(`ST_DWithin(
+				return sqlbuilder.Condition{
+					SQL: `ST_DWithin(
 					location::geography,
-					ST_SetSRID(ST_MakePoint($%d, $%d), 4326)::geography,
-					$%d * 1000
-				)`, argCount+1, argCount+2, argCount+3)
-				return condition, []interface{}{lng, lat, *area.Radius}
+					ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography,
+					? * 1000
+				)`,
+					Args: []interface{}{lng, lat, *area.Radius},
+				}, true
 			}
 		}
 	}
 	
-	return "", nil
+	return sqlbuilder.Condition{}, false
 }
 
 // GetInArea retrieves places within a specific geometric area
@@ -1220,3 +1831,140 @@ func (r *PostgresRepository) parseBoundsJSON(boundsStr string, place *Place) err
 	}
 	return nil
 }
+
+// CreateOwnershipTransfer records a pending ownership transfer for a place
+func (r *PostgresRepository) CreateOwnershipTransfer(ctx context.Context, transfer *OwnershipTransfer) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO ownership_transfers (entity_type, entity_id, from_user_id, to_user_id)
+		VALUES ('place', $1, $2, $3)
+		RETURNING id, entity_type, status, created_at`
+
+	err = tx.QueryRowContext(ctx, query, transfer.EntityID, transfer.FromUserID, transfer.ToUserID).
+		Scan(&transfer.ID, &transfer.EntityType, &transfer.Status, &transfer.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrTransferPending
+		}
+		return fmt.Errorf("failed to create ownership transfer: %w", err)
+	}
+
+	err = audit.Record(ctx, tx, &audit.Entry{
+		ActorID:    transfer.FromUserID,
+		Action:     audit.ActionOwnershipTransferRequested,
+		EntityType: "place",
+		EntityID:   transfer.EntityID,
+		Details:    map[string]interface{}{"transfer_id": transfer.ID, "to_user_id": transfer.ToUserID},
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetPendingOwnershipTransfer retrieves the pending ownership transfer for a place
+func (r *PostgresRepository) GetPendingOwnershipTransfer(ctx context.Context, placeID string) (*OwnershipTransfer, error) {
+	var transfer OwnershipTransfer
+	query := `
+		SELECT id, entity_type, entity_id, from_user_id, to_user_id, status, created_at, responded_at
+		FROM ownership_transfers
+		WHERE entity_type = 'place' AND entity_id = $1 AND status = 'pending'`
+
+	err := r.db.GetContext(ctx, &transfer, query, placeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTransferNotFound
+		}
+		return nil, fmt.Errorf("failed to get ownership transfer: %w", err)
+	}
+
+	return &transfer, nil
+}
+
+// ResolveOwnershipTransfer accepts or declines a pending transfer. On
+// acceptance the recipient becomes the creator of record and the previous
+// owner is kept on as an admin collaborator.
+func (r *PostgresRepository) ResolveOwnershipTransfer(ctx context.Context, transfer *OwnershipTransfer, actorID string, accept bool) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	status := "declined"
+	action := audit.ActionOwnershipTransferDeclined
+	if accept {
+		status = "accepted"
+		action = audit.ActionOwnershipTransferAccepted
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE ownership_transfers
+		SET status = $2, responded_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'pending'`, transfer.ID, status)
+	if err != nil {
+		return fmt.Errorf("failed to update ownership transfer: %w", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	} else if rowsAffected == 0 {
+		return ErrTransferNotFound
+	}
+
+	if accept {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE places
+			SET created_by = $2, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND created_by = $3`,
+			transfer.EntityID, transfer.ToUserID, transfer.FromUserID)
+		if err != nil {
+			return fmt.Errorf("failed to transfer place ownership: %w", err)
+		}
+		if rowsAffected, err := result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		} else if rowsAffected == 0 {
+			return ErrPlaceNotFound
+		}
+
+		// The new owner no longer needs a collaborator entry
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM place_collaborators
+			WHERE place_id = $1 AND user_id = $2`,
+			transfer.EntityID, transfer.ToUserID)
+		if err != nil {
+			return fmt.Errorf("failed to update collaborators: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO place_collaborators (place_id, user_id, role)
+			VALUES ($1, $2, 'admin')
+			ON CONFLICT (place_id, user_id) DO UPDATE SET role = 'admin'`,
+			transfer.EntityID, transfer.FromUserID)
+		if err != nil {
+			return fmt.Errorf("failed to update collaborators: %w", err)
+		}
+	}
+
+	err = audit.Record(ctx, tx, &audit.Entry{
+		ActorID:    actorID,
+		Action:     action,
+		EntityType: "place",
+		EntityID:   transfer.EntityID,
+		Details: map[string]interface{}{
+			"transfer_id":  transfer.ID,
+			"from_user_id": transfer.FromUserID,
+			"to_user_id":   transfer.ToUserID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -3,512 +3,607 @@ package places
 import (
 	"context"
 	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
-	"github.com/google/uuid"
+	"github.com/Oferzz/newMap/apps/api/internal/media"
+	"github.com/Oferzz/newMap/apps/api/internal/nlp"
+	"github.com/Oferzz/newMap/apps/api/pkg/pagination"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func TestPostgreSQLRepository_Create(t *testing.T) {
+var _ Repository = (*PostgresRepository)(nil)
+
+const (
+	creatorID = "00000000-0000-0000-0000-000000000001"
+	placeID   = "10000000-0000-0000-0000-000000000001"
+)
+
+func newMockRepository(t *testing.T) (*PostgresRepository, sqlmock.Sqlmock) {
+	t.Helper()
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
-	defer db.Close()
+	t.Cleanup(func() { db.Close() })
+	return NewPostgresRepository(sqlx.NewDb(db, "postgres")), mock
+}
 
-	repo := &postgresRepository{db: db}
+func TestPostgresRepository_Create(t *testing.T) {
 	ctx := context.Background()
+	now := time.Now()
 
-	t.Run("successful creation", func(t *testing.T) {
+	t.Run("point of interest", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 		place := &Place{
-			ID:          uuid.New().String(),
-			Name:        "Test Place",
-			Description: "Test Description",
-			Location: Location{
-				Type:        "Point",
-				Coordinates: []float64{-73.935242, 40.730610},
-			},
-			Address:    "123 Test St, New York, NY",
-			Category:   "restaurant",
-			CreatorID:  uuid.New().String(),
-			MediaURLs:  []string{"image1.jpg", "image2.jpg"},
-			Tags:       []string{"food", "italian"},
-			Rating:     4.5,
-			ReviewCount: 10,
-			Metadata: map[string]interface{}{
-				"cuisine": "Italian",
-				"price":   "$$",
-			},
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+			Name:      "Ein Gedi Spring",
+			Type:      "poi",
+			Location:  &GeoPoint{Type: "Point", Coordinates: []float64{35.3875, 31.4658}},
+			City:      "Ein Gedi",
+			Country:   "Israel",
+			CreatedBy: creatorID,
+			Category:  []string{"nature"},
+			Tags:      []string{"spring"},
+			Privacy:   "public",
+			Status:    "active",
 		}
 
-		rows := sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
-			AddRow(place.ID, place.CreatedAt, place.UpdatedAt)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO places .+ST_GeomFromGeoJSON\(\$5::text\), ST_GeomFromGeoJSON\(\$6::text\)`).
+			WithArgs(
+				"Ein Gedi Spring", "", "poi", nil,
+				`{"type":"Point","coordinates":[35.3875,31.4658]}`, nil,
+				"", "Ein Gedi", "", "Israel", "",
+				creatorID,
+				pq.Array([]string{"nature"}),
+				pq.Array([]string{"spring"}),
+				nil, nil,
+				sqlmock.AnyArg(), // amenities
+				"public", "active",
+				sqlmock.AnyArg(), // accessibility
+				[]byte("[]"),
+			).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(placeID, now, now))
+		mock.ExpectCommit()
+
+		require.NoError(t, repo.Create(ctx, place))
+		assert.Equal(t, placeID, place.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("areas make the creator an admin", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 
+		mock.ExpectBegin()
 		mock.ExpectQuery(`INSERT INTO places`).
 			WithArgs(
-				place.ID,
-				place.Name,
-				place.Description,
-				sqlmock.AnyArg(), // location PostGIS point
-				place.Address,
-				place.Category,
-				place.CreatorID,
-				sqlmock.AnyArg(), // media_urls array
-				sqlmock.AnyArg(), // tags array
-				place.Rating,
-				place.ReviewCount,
-				sqlmock.AnyArg(), // metadata jsonb
-				place.ParentID,
-				place.CreatedAt,
-				place.UpdatedAt,
+				"Judean Desert", "", "area", nil,
+				nil, `{"type":"Polygon","coordinates":[[[35,31],[35.5,31],[35.5,31.5],[35,31]]]}`,
+				"", "", "", "", "",
+				creatorID,
+				sqlmock.AnyArg(), sqlmock.AnyArg(),
+				nil, nil,
+				sqlmock.AnyArg(),
+				"", "",
+				sqlmock.AnyArg(),
+				[]byte("[]"),
 			).
-			WillReturnRows(rows)
-
-		err := repo.Create(ctx, place)
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(placeID, now, now))
+		mock.ExpectExec(`INSERT INTO place_collaborators`).
+			WithArgs(placeID, creatorID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.Create(ctx, &Place{
+			Name:      "Judean Desert",
+			Type:      "area",
+			Bounds:    &GeoPolygon{Type: "Polygon", Coordinates: [][][]float64{{{35, 31}, {35.5, 31}, {35.5, 31.5}, {35, 31}}}},
+			CreatedBy: creatorID,
+		})
 		assert.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
 	t.Run("database error", func(t *testing.T) {
-		place := &Place{
-			ID:        uuid.New().String(),
-			Name:      "Test Place",
-			CreatorID: uuid.New().String(),
-			Location: Location{
-				Type:        "Point",
-				Coordinates: []float64{-73.935242, 40.730610},
-			},
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		}
+		repo, mock := newMockRepository(t)
 
-		mock.ExpectQuery(`INSERT INTO places`).
-			WithArgs(sqlmock.AnyArg()).
-			WillReturnError(sql.ErrConnDone)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO places`).WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
 
-		err := repo.Create(ctx, place)
-		assert.Error(t, err)
+		err := repo.Create(ctx, &Place{Name: "Test Place", Type: "poi", CreatedBy: creatorID})
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
-func TestPostgreSQLRepository_GetByID(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
+var placeColumns = []string{
+	"id", "name", "description", "type", "parent_id", "location", "bounds",
+	"street_address", "city", "state", "country", "postal_code",
+	"created_by", "category", "tags", "opening_hours", "contact_info",
+	"amenities", "accessibility", "access_fees", "average_rating", "rating_count", "privacy", "status",
+	"created_at", "updated_at",
+}
 
-	repo := &postgresRepository{db: db}
+func TestPostgresRepository_GetByIDWith(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("place found", func(t *testing.T) {
-		placeID := uuid.New().String()
-		creatorID := uuid.New().String()
+		repo, mock := newMockRepository(t)
 		now := time.Now()
 
-		rows := sqlmock.NewRows([]string{
-			"id", "name", "description", "ST_X(location)", "ST_Y(location)",
-			"address", "category", "creator_id", "media_urls", "tags",
-			"rating", "review_count", "metadata", "parent_id",
-			"created_at", "updated_at",
-		}).AddRow(
-			placeID, "Test Place", "Test Description", -73.935242, 40.730610,
-			"123 Test St", "restaurant", creatorID, "{image1.jpg,image2.jpg}",
-			"{food,italian}", 4.5, 10, `{"cuisine": "Italian", "price": "$$"}`,
-			nil, now, now,
-		)
-
-		mock.ExpectQuery(`SELECT (.+) FROM places WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT (.+) FROM places\s+WHERE id = \$1 AND status = 'active'`).
+			WithArgs(placeID).
+			WillReturnRows(sqlmock.NewRows(placeColumns).AddRow(
+				placeID, "Ein Gedi Spring", "", "poi", nil,
+				`{"type":"Point","coordinates":[35.3875,31.4658]}`, nil,
+				"", "Ein Gedi", "", "Israel", "",
+				creatorID, "{nature}", "{spring,shade}", nil, nil,
+				"{}", "{dog_friendly}", `[{"kind":"entry_fee","name":"Reserve entry"}]`, 4.5, 12, "public", "active",
+				now, now,
+			))
+		mock.ExpectQuery(`FROM place_collaborators`).
 			WithArgs(placeID).
-			WillReturnRows(rows)
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		place, err := repo.GetByIDWith(ctx, placeID, Relations{Collaborators: true})
+		require.NoError(t, err)
+		assert.Equal(t, "Ein Gedi Spring", place.Name)
+		require.NotNil(t, place.Location)
+		assert.Equal(t, []float64{35.3875, 31.4658}, place.Location.Coordinates)
+		assert.Nil(t, place.Bounds)
+		assert.Equal(t, pq.StringArray{"spring", "shade"}, place.Tags)
+		assert.Equal(t, pq.StringArray{"dog_friendly"}, place.Accessibility)
+		assert.Equal(t, AccessFees{{Kind: "entry_fee", Name: "Reserve entry"}}, place.AccessFees)
+		require.NotNil(t, place.AverageRating)
+		assert.Equal(t, float32(4.5), *place.AverageRating)
+		assert.Nil(t, place.Media)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 
-		place, err := repo.GetByID(ctx, placeID)
-		assert.NoError(t, err)
-		assert.NotNil(t, place)
-		assert.Equal(t, placeID, place.ID)
-		assert.Equal(t, "Test Place", place.Name)
-		assert.Equal(t, -73.935242, place.Location.Coordinates[0])
-		assert.Equal(t, 40.730610, place.Location.Coordinates[1])
+	t.Run("media served from the CDN", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		repo.SetMediaCDN(media.NewCDN("http://localhost:8080/media", "https://cdn.example.com"))
+		now := time.Now()
+		uploadedAt := time.Unix(1714521600, 0)
+
+		mock.ExpectQuery(`SELECT (.+) FROM places\s+WHERE id = \$1 AND status = 'active'`).
+			WithArgs(placeID).
+			WillReturnRows(sqlmock.NewRows(placeColumns).AddRow(
+				placeID, "Ein Gedi Spring", "", "poi", nil,
+				`{"type":"Point","coordinates":[35.3875,31.4658]}`, nil,
+				"", "Ein Gedi", "", "Israel", "",
+				creatorID, "{nature}", "{}", nil, nil,
+				"{}", "{}", `[]`, nil, 0, "public", "active",
+				now, now,
+			))
+		mock.ExpectQuery(`FROM place_media pm\s+JOIN media m`).
+			WithArgs(placeID).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "media_id", "place_id", "caption", "order_position", "created_at",
+				"url", "thumbnail_url", "mime_type", "uploaded_by", "uploaded_at",
+			}).AddRow(
+				"m1", "media-1", placeID, "The spring", 0, now,
+				"http://localhost:8080/media/images/original/abc.jpg",
+				"https://res.cloudinary.com/demo/abc.jpg",
+				"image/jpeg", creatorID, uploadedAt,
+			))
+
+		place, err := repo.GetByIDWith(ctx, placeID, Relations{Media: true})
+		require.NoError(t, err)
+		require.Len(t, place.Media, 1)
+		assert.Equal(t, "https://cdn.example.com/images/original/abc.jpg?v=scs5c0", place.Media[0].URL)
+		assert.Equal(t, "https://res.cloudinary.com/demo/abc.jpg", place.Media[0].ThumbnailURL)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
 	t.Run("place not found", func(t *testing.T) {
-		placeID := uuid.New().String()
+		repo, mock := newMockRepository(t)
 
-		mock.ExpectQuery(`SELECT (.+) FROM places WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT (.+) FROM places`).
 			WithArgs(placeID).
 			WillReturnError(sql.ErrNoRows)
 
 		place, err := repo.GetByID(ctx, placeID)
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrPlaceNotFound)
 		assert.Nil(t, place)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
-func TestPostgreSQLRepository_GetByCreator(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
-
-	repo := &postgresRepository{db: db}
+func TestPostgresRepository_UpdateByID(t *testing.T) {
 	ctx := context.Background()
 
-	t.Run("places found", func(t *testing.T) {
-		creatorID := uuid.New().String()
-		now := time.Now()
-
-		rows := sqlmock.NewRows([]string{
-			"id", "name", "description", "ST_X(location)", "ST_Y(location)",
-			"address", "category", "creator_id", "media_urls", "tags",
-			"rating", "review_count", "metadata", "parent_id",
-			"created_at", "updated_at",
-		}).
-			AddRow(
-				"place1", "Place 1", "Description 1", -73.935242, 40.730610,
-				"Address 1", "restaurant", creatorID, "{}", "{}",
-				4.5, 10, "{}", nil, now, now,
-			).
-			AddRow(
-				"place2", "Place 2", "Description 2", -73.945242, 40.740610,
-				"Address 2", "cafe", creatorID, "{}", "{}",
-				4.0, 5, "{}", nil, now, now,
-			)
+	t.Run("array fields are bound as arrays", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 
-		mock.ExpectQuery(`SELECT (.+) FROM places WHERE creator_id = \$1`).
-			WithArgs(creatorID).
-			WillReturnRows(rows)
+		mock.ExpectExec(`UPDATE places\s+SET tags = \$2, updated_at = CURRENT_TIMESTAMP\s+WHERE id = \$1 AND status = 'active'`).
+			WithArgs(placeID, pq.Array([]string{"spring"})).
+			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		places, err := repo.GetByCreator(ctx, creatorID)
+		err := repo.UpdateByID(ctx, placeID, map[string]interface{}{"tags": []string{"spring"}})
 		assert.NoError(t, err)
-		assert.Len(t, places, 2)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
-}
-
-func TestPostgreSQLRepository_Update(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
 
-	repo := &postgresRepository{db: db}
-	ctx := context.Background()
+	t.Run("location is bound as a point", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 
-	t.Run("successful update", func(t *testing.T) {
-		place := &Place{
-			ID:          uuid.New().String(),
-			Name:        "Updated Place",
-			Description: "Updated Description",
-			Location: Location{
-				Type:        "Point",
-				Coordinates: []float64{-73.935242, 40.730610},
-			},
-			Address:     "456 Updated St",
-			Category:    "cafe",
-			MediaURLs:   []string{"new1.jpg", "new2.jpg"},
-			Tags:        []string{"coffee", "wifi"},
-			Rating:      4.8,
-			ReviewCount: 25,
-			UpdatedAt:   time.Now(),
-		}
-
-		mock.ExpectExec(`UPDATE places SET`).
-			WithArgs(
-				place.Name,
-				place.Description,
-				sqlmock.AnyArg(), // location
-				place.Address,
-				place.Category,
-				sqlmock.AnyArg(), // media_urls
-				sqlmock.AnyArg(), // tags
-				place.Rating,
-				place.ReviewCount,
-				sqlmock.AnyArg(), // metadata
-				place.ParentID,
-				sqlmock.AnyArg(), // updated_at
-				place.ID,
-			).
+		mock.ExpectExec(`SET location = ST_GeomFromGeoJSON\(\$2::text\), updated_at`).
+			WithArgs(placeID, `{"type":"Point","coordinates":[35.3875,31.4658]}`).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		err := repo.Update(ctx, place)
+		err := repo.UpdateByID(ctx, placeID, map[string]interface{}{
+			"location": &LocationInput{Latitude: 31.4658, Longitude: 35.3875},
+		})
 		assert.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
-}
 
-func TestPostgreSQLRepository_Delete(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
+	t.Run("bounds are bound as a polygon", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 
-	repo := &postgresRepository{db: db}
-	ctx := context.Background()
+		mock.ExpectExec(`SET bounds = ST_GeomFromGeoJSON\(\$2::text\), updated_at`).
+			WithArgs(placeID, `{"type":"Polygon","coordinates":[[[35,31],[35.5,31],[35.5,31.5],[35,31]]]}`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
 
-	t.Run("successful deletion", func(t *testing.T) {
-		placeID := uuid.New().String()
+		err := repo.UpdateByID(ctx, placeID, map[string]interface{}{
+			"bounds": &BoundsInput{Coordinates: [][][]float64{{{35, 31}, {35.5, 31}, {35.5, 31.5}, {35, 31}}}},
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 
-		mock.ExpectExec(`DELETE FROM places WHERE id = \$1`).
-			WithArgs(placeID).
+	t.Run("missing location clears it", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectExec(`SET location = ST_GeomFromGeoJSON\(\$2::text\), updated_at`).
+			WithArgs(placeID, nil).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		err := repo.Delete(ctx, placeID)
+		err := repo.UpdateByID(ctx, placeID, map[string]interface{}{"location": (*GeoPoint)(nil)})
 		assert.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
-}
-
-func TestPostgreSQLRepository_Search(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
 
-	repo := &postgresRepository{db: db}
-	ctx := context.Background()
+	t.Run("unsupported geometry", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 
-	t.Run("search with results", func(t *testing.T) {
-		query := "coffee"
-		filters := SearchFilters{
-			Limit:  10,
-			Offset: 0,
-		}
-		now := time.Now()
+		err := repo.UpdateByID(ctx, placeID, map[string]interface{}{"location": "POINT(35 31)"})
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 
-		// Count query
-		countRows := sqlmock.NewRows([]string{"count"}).AddRow(2)
-		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM places WHERE`).
-			WithArgs("%"+query+"%", "%"+query+"%").
-			WillReturnRows(countRows)
-
-		// Search query
-		rows := sqlmock.NewRows([]string{
-			"id", "name", "description", "ST_X(location)", "ST_Y(location)",
-			"address", "category", "creator_id", "media_urls", "tags",
-			"rating", "review_count", "metadata", "parent_id",
-			"created_at", "updated_at",
-		}).
-			AddRow(
-				"place1", "Coffee Shop 1", "Great coffee", -73.935242, 40.730610,
-				"Address 1", "cafe", "creator1", "{}", "{coffee}",
-				4.5, 10, "{}", nil, now, now,
-			).
-			AddRow(
-				"place2", "Coffee House", "Best coffee", -73.945242, 40.740610,
-				"Address 2", "cafe", "creator2", "{}", "{coffee,wifi}",
-				4.8, 20, "{}", nil, now, now,
-			)
+	t.Run("place not found", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 
-		mock.ExpectQuery(`SELECT (.+) FROM places WHERE`).
-			WithArgs("%"+query+"%", "%"+query+"%", filters.Limit, filters.Offset).
-			WillReturnRows(rows)
+		mock.ExpectExec(`UPDATE places`).
+			WithArgs(placeID, "Renamed").
+			WillReturnResult(sqlmock.NewResult(0, 0))
 
-		result, err := repo.Search(ctx, query, filters)
-		assert.NoError(t, err)
-		assert.NotNil(t, result)
-		assert.Len(t, result.Places, 2)
-		assert.Equal(t, int64(2), result.Total)
+		err := repo.UpdateByID(ctx, placeID, map[string]interface{}{"name": "Renamed"})
+		assert.ErrorIs(t, err, ErrPlaceNotFound)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
-func TestPostgreSQLRepository_GetNearby(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
+func TestPostgresRepository_Update(t *testing.T) {
+	repo, mock := newMockRepository(t)
 
-	repo := &postgresRepository{db: db}
+	mock.ExpectExec(`UPDATE places\s+SET .*location = ST_GeomFromGeoJSON\(\$\d+::text\)`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.Update(context.Background(), &Place{
+		ID:       placeID,
+		Name:     "Ein Gedi Spring",
+		Type:     "poi",
+		Location: &GeoPoint{Type: "Point", Coordinates: []float64{35.3875, 31.4658}},
+		Privacy:  "public",
+		Status:   "active",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepository_Delete(t *testing.T) {
 	ctx := context.Background()
 
-	t.Run("get nearby places", func(t *testing.T) {
-		lat := 40.730610
-		lng := -73.935242
-		radiusKm := 1.0
-		limit := 10
-		now := time.Now()
+	t.Run("archives the place", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 
-		rows := sqlmock.NewRows([]string{
-			"id", "name", "description", "ST_X(location)", "ST_Y(location)",
-			"address", "category", "creator_id", "media_urls", "tags",
-			"rating", "review_count", "metadata", "parent_id",
-			"created_at", "updated_at", "distance",
-		}).
-			AddRow(
-				"place1", "Nearby Place 1", "Description 1", -73.936242, 40.731610,
-				"Address 1", "restaurant", "creator1", "{}", "{}",
-				4.5, 10, "{}", nil, now, now, 0.15,
-			).
-			AddRow(
-				"place2", "Nearby Place 2", "Description 2", -73.934242, 40.729610,
-				"Address 2", "cafe", "creator2", "{}", "{}",
-				4.0, 5, "{}", nil, now, now, 0.18,
-			)
+		mock.ExpectExec(`UPDATE places\s+SET status = 'archived'`).
+			WithArgs(placeID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		mock.ExpectQuery(`SELECT (.+), ST_Distance`).
-			WithArgs(lng, lat, radiusKm*1000, limit).
-			WillReturnRows(rows)
+		assert.NoError(t, repo.Delete(ctx, placeID))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 
-		places, err := repo.GetNearby(ctx, lat, lng, radiusKm, limit)
-		assert.NoError(t, err)
-		assert.Len(t, places, 2)
+	t.Run("place not found", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectExec(`UPDATE places\s+SET status = 'archived'`).
+			WithArgs(placeID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.ErrorIs(t, repo.Delete(ctx, placeID), ErrPlaceNotFound)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
-func TestPostgreSQLRepository_GetInBounds(t *testing.T) {
-	db, mock, err := sqlmock.New()
+func TestPostgresRepository_GetByCreator(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+
+	mock.ExpectQuery(`FROM places\s+WHERE created_by = \$1 AND status = 'active'`).
+		WithArgs(creatorID).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "name", "description", "type", "parent_id", "location",
+			"street_address", "city", "state", "country", "postal_code",
+			"created_by", "category", "tags", "average_rating", "rating_count",
+			"privacy", "status", "created_at", "updated_at",
+		}).
+			AddRow(placeID, "Ein Gedi Spring", "", "poi", nil, `{"type":"Point","coordinates":[35.3875,31.4658]}`,
+				"", "Ein Gedi", "", "Israel", "", creatorID, "{nature}", "{spring}", nil, 0,
+				"public", "active", now, now).
+			AddRow("10000000-0000-0000-0000-000000000002", "Judean Desert", "", "area", nil, nil,
+				"", "", "", "Israel", "", creatorID, "{}", "{}", nil, 0,
+				"private", "active", now, now))
+
+	places, err := repo.GetByCreator(context.Background(), creatorID)
 	require.NoError(t, err)
-	defer db.Close()
+	require.Len(t, places, 2)
+	assert.Equal(t, []float64{35.3875, 31.4658}, places[0].Location.Coordinates)
+	assert.Equal(t, pq.StringArray{"spring"}, places[0].Tags)
+	assert.Nil(t, places[1].Location)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepository_UpdateRating(t *testing.T) {
+	repo, mock := newMockRepository(t)
 
-	repo := &postgresRepository{db: db}
+	mock.ExpectExec(`UPDATE places\s+SET average_rating = \$2,\s+rating_count = \$3`).
+		WithArgs(placeID, 4.5, 12).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.UpdateRating(context.Background(), placeID, 4.5, 12))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepository_CreateReview(t *testing.T) {
 	ctx := context.Background()
+	reviewerID := "00000000-0000-0000-0000-000000000002"
+	photoID := "20000000-0000-0000-0000-000000000001"
 
-	t.Run("get places in bounds", func(t *testing.T) {
-		bounds := Bounds{
-			MinLat: 40.720000,
-			MaxLat: 40.740000,
-			MinLng: -73.940000,
-			MaxLng: -73.930000,
-		}
-		now := time.Now()
+	t.Run("rating recomputed", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		review := &PlaceReview{PlaceID: placeID, UserID: reviewerID, Rating: 4, MediaIDs: pq.StringArray{photoID}}
 
-		rows := sqlmock.NewRows([]string{
-			"id", "name", "description", "ST_X(location)", "ST_Y(location)",
-			"address", "category", "creator_id", "media_urls", "tags",
-			"rating", "review_count", "metadata", "parent_id",
-			"created_at", "updated_at",
-		}).
-			AddRow(
-				"place1", "Place in Bounds 1", "Description 1", -73.935242, 40.730610,
-				"Address 1", "restaurant", "creator1", "{}", "{}",
-				4.5, 10, "{}", nil, now, now,
-			).
-			AddRow(
-				"place2", "Place in Bounds 2", "Description 2", -73.934242, 40.729610,
-				"Address 2", "cafe", "creator2", "{}", "{}",
-				4.0, 5, "{}", nil, now, now,
-			)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM places WHERE id = \$1 FOR UPDATE`).
+			WithArgs(placeID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(placeID))
+		mock.ExpectQuery(`INSERT INTO place_reviews`).
+			WithArgs(placeID, reviewerID, 4, "", review.MediaIDs).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow("review1", time.Now(), time.Now()))
+		mock.ExpectExec(`INSERT INTO media_usage`).
+			WithArgs(MediaUsagePlaceReview, "review1", review.MediaIDs, reviewerID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE places p\s+SET average_rating = r.average, rating_count = r.count`).
+			WithArgs(placeID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
 
-		mock.ExpectQuery(`SELECT (.+) FROM places WHERE ST_Within`).
-			WithArgs(
-				bounds.MinLng, bounds.MinLat,
-				bounds.MaxLng, bounds.MinLat,
-				bounds.MaxLng, bounds.MaxLat,
-				bounds.MinLng, bounds.MaxLat,
-				bounds.MinLng, bounds.MinLat,
-			).
-			WillReturnRows(rows)
+		require.NoError(t, repo.CreateReview(ctx, review))
+		assert.Equal(t, "review1", review.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 
-		places, err := repo.GetInBounds(ctx, bounds)
-		assert.NoError(t, err)
-		assert.Len(t, places, 2)
+	t.Run("photos the reviewer did not upload", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		review := &PlaceReview{PlaceID: placeID, UserID: reviewerID, Rating: 4, MediaIDs: pq.StringArray{photoID}}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM places`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(placeID))
+		mock.ExpectQuery(`INSERT INTO place_reviews`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow("review1", time.Now(), time.Now()))
+		mock.ExpectExec(`INSERT INTO media_usage`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, repo.CreateReview(ctx, review), ErrInvalidReviewMedia)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("place not found", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM places`).WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		err := repo.CreateReview(ctx, &PlaceReview{PlaceID: placeID, UserID: reviewerID, Rating: 4})
+		assert.ErrorIs(t, err, ErrPlaceNotFound)
+	})
 }
 
-func TestPostgreSQLRepository_GetByCategory(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
+func TestPostgresRepository_DeleteReview(t *testing.T) {
+	repo, mock := newMockRepository(t)
 
-	repo := &postgresRepository{db: db}
-	ctx := context.Background()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM places`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(placeID))
+	mock.ExpectQuery(`DELETE FROM place_reviews`).
+		WithArgs(placeID, creatorID).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
 
-	t.Run("get places by category", func(t *testing.T) {
-		category := "restaurant"
-		limit := 10
-		offset := 0
-		now := time.Now()
+	assert.ErrorIs(t, repo.DeleteReview(context.Background(), placeID, creatorID), ErrReviewNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
 
-		rows := sqlmock.NewRows([]string{
-			"id", "name", "description", "ST_X(location)", "ST_Y(location)",
-			"address", "category", "creator_id", "media_urls", "tags",
-			"rating", "review_count", "metadata", "parent_id",
-			"created_at", "updated_at",
-		}).
-			AddRow(
-				"place1", "Restaurant 1", "Description 1", -73.935242, 40.730610,
-				"Address 1", category, "creator1", "{}", "{italian}",
-				4.5, 10, "{}", nil, now, now,
-			).
-			AddRow(
-				"place2", "Restaurant 2", "Description 2", -73.945242, 40.740610,
-				"Address 2", category, "creator2", "{}", "{french}",
-				4.8, 20, "{}", nil, now, now,
-			)
+// assertPlaceholders checks that a query numbers its placeholders $1 to $n
+// without gaps, one for each argument
+func assertPlaceholders(t *testing.T, query string, args []interface{}) {
+	t.Helper()
+	used := map[int]bool{}
+	for _, match := range regexp.MustCompile(`\$(\d+)`).FindAllStringSubmatch(query, -1) {
+		n, _ := strconv.Atoi(match[1])
+		used[n] = true
+	}
+	assert.Len(t, used, len(args), query)
+	for n := 1; n <= len(args); n++ {
+		assert.True(t, used[n], "$%d unused in %s", n, query)
+	}
+}
 
-		mock.ExpectQuery(`SELECT (.+) FROM places WHERE category = \$1`).
-			WithArgs(category, limit, offset).
-			WillReturnRows(rows)
+func TestPostgresRepository_SetContactVerification(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	loadedAt := time.Date(2026, time.October, 1, 8, 0, 0, 0, time.UTC)
+	checkedAt := time.Date(2026, time.October, 16, 8, 0, 0, 0, time.UTC)
 
-		places, err := repo.GetByCategory(ctx, category, limit, offset)
-		assert.NoError(t, err)
-		assert.Len(t, places, 2)
-		assert.Equal(t, category, places[0].Category)
-		assert.Equal(t, category, places[1].Category)
-		assert.NoError(t, mock.ExpectationsWereMet())
+	// Only recorded when the place has not changed since it was loaded
+	mock.ExpectExec(`SET contact_info = jsonb_set\(contact_info, '\{verification\}', \$3::jsonb\)\s+WHERE id = \$1 AND updated_at = \$2`).
+		WithArgs(placeID, loadedAt, `{"verified":false,"dead_links":["website"],"checked_at":"2026-10-16T08:00:00Z"}`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.SetContactVerification(context.Background(), placeID, loadedAt, &ContactVerification{
+		DeadLinks: []string{"website"},
+		CheckedAt: checkedAt,
 	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestPostgreSQLRepository_GetChildren(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
+func TestSearchPlacesQuery(t *testing.T) {
+	lat, lng, radius := 31.4658, 35.3875, 5000
+
+	filters := []struct {
+		name      string
+		set       func(*SearchPlacesInput)
+		condition string
+	}{
+		{"query", func(in *SearchPlacesInput) { in.Query = "spring" }, "search_vector @@ to_tsquery('english', $"},
+		{"type", func(in *SearchPlacesInput) { in.Type = "poi" }, "type = $"},
+		{"category", func(in *SearchPlacesInput) { in.Category = []string{"nature"} }, "category && $"},
+		{"tags", func(in *SearchPlacesInput) { in.Tags = []string{"shade"} }, "tags && $"},
+		{"accessibility", func(in *SearchPlacesInput) { in.Accessibility = []string{"dog_friendly"} }, "accessibility @> $"},
+		{"amenities", func(in *SearchPlacesInput) { in.Amenities = []string{"toilets", "showers"} }, "amenities @> $"},
+		{"city", func(in *SearchPlacesInput) { in.City = "Ein Gedi" }, "city ILIKE $"},
+		{"country", func(in *SearchPlacesInput) { in.Country = "Israel" }, "country ILIKE $"},
+		{"radius", func(in *SearchPlacesInput) { in.Latitude, in.Longitude, in.Radius = &lat, &lng, &radius }, "ST_DWithin("},
+		{"open now", func(in *SearchPlacesInput) { in.OpenNow = true }, "place_open_at(opening_hours, NOW())"},
+		{"cursor", func(in *SearchPlacesInput) { in.After = pagination.New(time.Now(), placeID) }, "(created_at, id) < ($"},
+	}
+
+	// Every combination of filters
+	for set := 0; set < 1<<len(filters); set++ {
+		input := SearchPlacesInput{Limit: 20, Offset: 40}
+		for i, filter := range filters {
+			if set&(1<<i) != 0 {
+				filter.set(&input)
+			}
+		}
 
-	repo := &postgresRepository{db: db}
-	ctx := context.Background()
+		query, args := searchPlacesQuery(input)
+		assertPlaceholders(t, query, args)
 
-	t.Run("get child places", func(t *testing.T) {
-		parentID := uuid.New().String()
-		now := time.Now()
+		byDistance := input.Latitude != nil
+		for i, filter := range filters {
+			want := set&(1<<i) != 0
+			if filter.name == "cursor" && byDistance {
+				want = false // Distance order has no cursor
+			}
+			assert.Equal(t, want, strings.Contains(query, filter.condition), "%s in %s", filter.name, query)
+		}
+		assert.Equal(t, byDistance, strings.Contains(query, "ORDER BY location <->"), query)
 
-		rows := sqlmock.NewRows([]string{
-			"id", "name", "description", "ST_X(location)", "ST_Y(location)",
-			"address", "category", "creator_id", "media_urls", "tags",
-			"rating", "review_count", "metadata", "parent_id",
-			"created_at", "updated_at",
-		}).
-			AddRow(
-				"child1", "Child Place 1", "Description 1", -73.935242, 40.730610,
-				"Address 1", "room", "creator1", "{}", "{}",
-				4.5, 10, "{}", parentID, now, now,
-			).
-			AddRow(
-				"child2", "Child Place 2", "Description 2", -73.935242, 40.730610,
-				"Address 2", "floor", "creator2", "{}", "{}",
-				4.0, 5, "{}", parentID, now, now,
-			)
+		offset := 40
+		if input.After != nil && !byDistance {
+			offset = 0
+		}
+		assert.Equal(t, []interface{}{20, offset}, args[len(args)-2:], query)
+
+		// The count has the same conditions, but no cursor, order or page
+		countQuery, countArgs := countPlacesQuery(input)
+		assertPlaceholders(t, countQuery, countArgs)
+		for i, filter := range filters {
+			want := set&(1<<i) != 0 && filter.name != "cursor"
+			assert.Equal(t, want, strings.Contains(countQuery, filter.condition), "%s in %s", filter.name, countQuery)
+		}
+		assert.NotContains(t, countQuery, "ORDER BY")
+		assert.NotContains(t, countQuery, "LIMIT")
+	}
+}
 
-		mock.ExpectQuery(`SELECT (.+) FROM places WHERE parent_id = \$1`).
-			WithArgs(parentID).
-			WillReturnRows(rows)
+func TestSpatialSearchQuery(t *testing.T) {
+	radius := 2.0
+	circle := nlp.AreaFilter{Type: "circle", Coordinates: []interface{}{35.3875, 31.4658}, Radius: &radius}
+	bounds := nlp.AreaFilter{Type: "bounds", Coordinates: []interface{}{34.9, 31.0, 35.5, 31.6}}
+	region := nlp.AreaFilter{Type: "region", Name: "Negev"}
+	polygon := nlp.AreaFilter{Type: "polygon", Coordinates: []interface{}{[]interface{}{
+		[]interface{}{35.0, 31.0}, []interface{}{35.5, 31.0}, []interface{}{35.5, 31.5}, []interface{}{35.0, 31.0},
+	}}}
+
+	t.Run("no filters", func(t *testing.T) {
+		query, args := spatialSearchQuery("", nil, SearchFilters{})
+		assert.True(t, strings.HasSuffix(query, "WHERE status = 'active' ORDER BY created_at DESC"), query)
+		assert.Empty(t, args)
+	})
 
-		children, err := repo.GetChildren(ctx, parentID)
-		assert.NoError(t, err)
-		assert.Len(t, children, 2)
-		assert.Equal(t, parentID, *children[0].ParentID)
-		assert.Equal(t, parentID, *children[1].ParentID)
-		assert.NoError(t, mock.ExpectationsWereMet())
+	t.Run("text, category, accessibility and amenities", func(t *testing.T) {
+		query, args := spatialSearchQuery("spring", nil, SearchFilters{
+			Category:      []string{"nature"},
+			Accessibility: []string{"dog_friendly"},
+			Amenities:     []string{"restrooms"},
+			Limit:         20,
+			Offset:        40,
+		})
+		assertPlaceholders(t, query, args)
+		assert.Contains(t, query, "search_vector @@ to_tsquery('english', $1) AND category && $2 AND accessibility @> $3 AND amenities @> $4")
+		assert.Contains(t, query, "ORDER BY ts_rank(search_vector, to_tsquery('english', $5)) DESC, created_at DESC LIMIT $6 OFFSET $7")
+		assert.Equal(t, "spring:*", args[0])
 	})
-}
 
-func TestPostgreSQLRepository_UpdateRating(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
+	t.Run("every area kind in every position", func(t *testing.T) {
+		areas := []nlp.AreaFilter{circle, bounds, region, polygon}
+		for _, within := range areas {
+			for _, intersects := range areas {
+				within, intersects := within, intersects
+				spatial := &nlp.SpatialSearchContext{Within: &within, Intersects: &intersects, Near: &circle, Areas: areas}
+
+				query, args := spatialSearchQuery("spring", spatial, SearchFilters{Category: []string{"nature"}, Limit: 20})
+				assertPlaceholders(t, query, args)
+				assert.Contains(t, query, "ST_DWithin(")
+				assert.Equal(t, 20, args[len(args)-1])
+			}
+		}
+	})
 
-	repo := &postgresRepository{db: db}
-	ctx := context.Background()
+	t.Run("places may be in any of several areas", func(t *testing.T) {
+		spatial := &nlp.SpatialSearchContext{Areas: []nlp.AreaFilter{region, bounds}}
 
-	t.Run("successful rating update", func(t *testing.T) {
-		placeID := uuid.New().String()
-		newRating := 4.7
-		reviewCount := 25
+		query, args := spatialSearchQuery("", spatial, SearchFilters{})
+		assertPlaceholders(t, query, args)
+		assert.Regexp(t, `AND \(\(\s+city ILIKE \$1 OR\s+state ILIKE \$2 OR\s+country ILIKE \$3\s+\) OR ST_Within\(`, query)
+		assert.Equal(t, []interface{}{"%Negev%", "%Negev%", "%Negev%", 34.9, 31.0, 35.5, 31.6}, args)
+	})
 
-		mock.ExpectExec(`UPDATE places SET rating = \$1, review_count = \$2`).
-			WithArgs(newRating, reviewCount, sqlmock.AnyArg(), placeID).
-			WillReturnResult(sqlmock.NewResult(0, 1))
+	t.Run("areas that can't be used are skipped", func(t *testing.T) {
+		spatial := &nlp.SpatialSearchContext{
+			Within: &nlp.AreaFilter{Type: "circle", Coordinates: []interface{}{35.3875}},
+			Near:   &nlp.AreaFilter{Type: "bounds"},
+			Areas:  []nlp.AreaFilter{{Type: "unknown"}},
+		}
 
-		err := repo.UpdateRating(ctx, placeID, newRating, reviewCount)
-		assert.NoError(t, err)
-		assert.NoError(t, mock.ExpectationsWereMet())
+		query, args := spatialSearchQuery("", spatial, SearchFilters{})
+		assert.True(t, strings.HasSuffix(query, "WHERE status = 'active' ORDER BY created_at DESC"), query)
+		assert.Empty(t, args)
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,124 @@
+package places
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/pkg/httpcache"
+	"github.com/Oferzz/newMap/apps/api/pkg/repoerr"
+	"github.com/lib/pq"
+)
+
+// MediaUsagePlaceReview is the entity type of the media usage recorded for
+// the photos of a place review, so that they are not cleaned up while in use
+const MediaUsagePlaceReview = "place_review"
+
+var (
+	ErrReviewNotFound     = repoerr.NotFound("review not found")
+	ErrReviewExists       = repoerr.Conflict("you have already reviewed this place")
+	ErrOwnPlaceReview     = errors.New("you cannot review your own place")
+	ErrInvalidReviewMedia = errors.New("review photos must be images you uploaded")
+)
+
+// PlaceReview is a user's rating of a place, with optional text and photos
+type PlaceReview struct {
+	ID         string         `db:"id" json:"id"`
+	PlaceID    string         `db:"place_id" json:"place_id"`
+	UserID     string         `db:"user_id" json:"user_id"`
+	Rating     int            `db:"rating" json:"rating"`
+	ReviewText string         `db:"review_text" json:"review_text"`
+	MediaIDs   pq.StringArray `db:"media_ids" json:"media_ids"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time      `db:"updated_at" json:"updated_at"`
+
+	// Joined user info
+	Username    string `db:"username" json:"username,omitempty"`
+	DisplayName string `db:"display_name" json:"display_name,omitempty"`
+	AvatarURL   string `db:"avatar_url" json:"avatar_url,omitempty"`
+}
+
+type CreatePlaceReviewInput struct {
+	Rating     int      `json:"rating" binding:"required,min=1,max=5"`
+	ReviewText string   `json:"review_text" binding:"max=2000"`
+	MediaIDs   []string `json:"media_ids" binding:"max=10,dive,uuid"`
+}
+
+// ReviewPlace records the user's review of a place they can see, and updates
+// the place's average rating. Each user reviews a place once, and not their
+// own places.
+func (s *servicePg) ReviewPlace(ctx context.Context, userID, placeID string, input *CreatePlaceReviewInput) (*PlaceReview, error) {
+	place, err := s.GetByIDWith(ctx, userID, placeID, Relations{})
+	if err != nil {
+		return nil, err
+	}
+
+	if place.IsOwner(userID) {
+		return nil, ErrOwnPlaceReview
+	}
+
+	review := &PlaceReview{
+		PlaceID:    placeID,
+		UserID:     userID,
+		Rating:     input.Rating,
+		ReviewText: input.ReviewText,
+		MediaIDs:   uniqueIDs(input.MediaIDs),
+	}
+	if err := s.repo.CreateReview(ctx, review); err != nil {
+		return nil, err
+	}
+
+	s.ratingChanged(ctx, placeID)
+
+	return review, nil
+}
+
+// ListReviews returns a page of the reviews of a place the user can see,
+// newest first
+func (s *servicePg) ListReviews(ctx context.Context, userID, placeID string, limit, offset int) ([]*PlaceReview, error) {
+	if _, err := s.GetByIDWith(ctx, userID, placeID, Relations{}); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListReviews(ctx, placeID, limit, offset)
+}
+
+// DeleteReview removes the user's own review of a place, and updates the
+// place's average rating
+func (s *servicePg) DeleteReview(ctx context.Context, userID, placeID string) error {
+	if err := s.repo.DeleteReview(ctx, placeID, userID); err != nil {
+		return err
+	}
+
+	s.ratingChanged(ctx, placeID)
+
+	return nil
+}
+
+// ratingChanged drops cached copies of a place whose rating changed and
+// reindexes it, since search ranks places by rating. Failures are logged;
+// the review itself is saved.
+func (s *servicePg) ratingChanged(ctx context.Context, placeID string) {
+	httpcache.PurgeAsync(s.purger, httpcache.PlaceKey(placeID))
+
+	place, err := s.repo.GetByID(ctx, placeID)
+	if err != nil {
+		log.Printf("Failed to reload place %s after a review: %v", placeID, err)
+		return
+	}
+	s.syncIndex(ctx, place)
+}
+
+// uniqueIDs is the IDs each once, in their order
+func uniqueIDs(ids []string) pq.StringArray {
+	unique := pq.StringArray{}
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,46 @@
+package places
+
+import (
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers the place routes
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *users.RouteMiddleware) {
+	// Public place routes (no authentication required)
+	router.GET("/places/search", h.Search)
+	router.GET("/places/amenities", h.ListAmenities)
+
+	// All other place routes require authentication
+	places := router.Group("/places", mw.RequireAuth)
+	{
+		// List places (with filters)
+		places.GET("", h.List)
+		places.GET("/:id", h.GetByID)
+		places.GET("/:id/availability", h.GetAvailability)
+
+		// Create place (requires permission on trip)
+		places.POST("", mw.LimitGeoJSONBody, h.Create)
+
+		// Update/Delete place (permissions are inherited from parent areas)
+		places.PUT("/:id", mw.RequirePlacePermission(users.PermissionPlaceUpdate), mw.LimitGeoJSONBody, h.Update)
+		places.DELETE("/:id", mw.RequirePlacePermission(users.PermissionPlaceDelete), h.Delete)
+
+		// Special operations
+		places.PUT("/:id/visited", h.MarkAsVisited)
+
+		// Reviews, one per user
+		places.GET("/:id/reviews", h.ListReviews)
+		places.POST("/:id/reviews", h.CreateReview)
+		places.DELETE("/:id/reviews", h.DeleteReview)
+
+		// Ownership transfer
+		places.POST("/:id/transfer-ownership", h.TransferOwnership)
+		places.POST("/:id/transfer-ownership/accept", h.AcceptOwnershipTransfer)
+		places.POST("/:id/transfer-ownership/decline", h.DeclineOwnershipTransfer)
+		// places.GET("/:id/children", h.GetChildren) // TODO: Implement GetChildren
+	}
+
+	// Trip places (convenience endpoint)
+	router.GET("/trips/:id/places", mw.RequireAuth, h.GetByTripID)
+}
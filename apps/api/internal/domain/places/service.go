@@ -10,6 +10,7 @@ type Service interface {
 	// Basic CRUD operations
 	Create(ctx context.Context, userID string, input *CreatePlaceInput) (*Place, error)
 	GetByID(ctx context.Context, userID, placeID string) (*Place, error)
+	GetByIDWith(ctx context.Context, userID, placeID string, relations Relations) (*Place, error)
 	Update(ctx context.Context, userID, placeID string, input *UpdatePlaceInput) (*Place, error)
 	Delete(ctx context.Context, userID, placeID string) error
 	
@@ -34,5 +35,18 @@ type Service interface {
 	RemoveImage(ctx context.Context, userID, placeID string, imageURL string) error
 	UpdateRating(ctx context.Context, userID, placeID string, rating float32) error
 	AddNote(ctx context.Context, userID, placeID, note string) error
+	
+	// Reviews
+	ReviewPlace(ctx context.Context, userID, placeID string, input *CreatePlaceReviewInput) (*PlaceReview, error)
+	ListReviews(ctx context.Context, userID, placeID string, limit, offset int) ([]*PlaceReview, error)
+	DeleteReview(ctx context.Context, userID, placeID string) error
+	
+		// Ownership transfer
+	TransferOwnership(ctx context.Context, userID, placeID, newOwnerID string) (*OwnershipTransfer, error)
+	AcceptOwnershipTransfer(ctx context.Context, userID, placeID string) (*Place, error)
+	DeclineOwnershipTransfer(ctx context.Context, userID, placeID string) error
+	
+	// Search indexing
+	SetIndexer(indexer Indexer)
 }
 
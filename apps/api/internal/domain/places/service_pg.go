@@ -5,32 +5,38 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"maps"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/geocode"
+	"github.com/Oferzz/newMap/apps/api/pkg/httpcache"
 )
 
 type servicePg struct {
-	repo          Repository
-	tripRepo      trips.Repository
-	mapboxService *MapboxService
+	repo        Repository
+	tripRepo    trips.Repository
+	permissions *PermissionResolver
+	geocoder    geocode.Geocoder
+	purger      httpcache.Purger
+	indexer     Indexer
 }
 
-func NewServicePg(repo Repository, tripRepo trips.Repository, mapboxAPIKey string) Service {
-	var mapboxService *MapboxService
-	if mapboxAPIKey != "" {
-		log.Printf("[PlaceService] Initializing with Mapbox API key (length: %d)", len(mapboxAPIKey))
-		mapboxService = NewMapboxService(mapboxAPIKey)
-	} else {
-		log.Printf("[PlaceService] WARNING: No Mapbox API key provided. Mapbox search will not be available.")
+// NewServicePg creates the place service. Public searches that find no saved
+// places fall back to the geocoder, when one is given.
+func NewServicePg(repo Repository, tripRepo trips.Repository, permissions *PermissionResolver, geocoder geocode.Geocoder, purger httpcache.Purger) Service {
+	if geocoder == nil {
+		log.Printf("[PlaceService] WARNING: No geocoder configured. Public searches will only find saved places.")
 	}
-	
+
 	return &servicePg{
-		repo:          repo,
-		tripRepo:      tripRepo,
-		mapboxService: mapboxService,
+		repo:        repo,
+		tripRepo:    tripRepo,
+		permissions: permissions,
+		geocoder:    geocoder,
+		purger:      purger,
 	}
 }
 
@@ -38,6 +44,21 @@ func (s *servicePg) Create(ctx context.Context, userID string, input *CreatePlac
 	// For PostgreSQL, we'll create the place directly without trip association
 	// The trip association will be handled separately
 	
+	if input.OpeningHours != nil {
+		if err := input.OpeningHours.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if input.ContactInfo != nil {
+		if err := input.ContactInfo.Normalize(); err != nil {
+			return nil, err
+		}
+	}
+	amenities, err := NormalizeAmenities(input.Amenities)
+	if err != nil {
+		return nil, err
+	}
+	
 	place := &Place{
 		ID:            uuid.New().String(),
 		Name:          input.Name,
@@ -54,7 +75,9 @@ func (s *servicePg) Create(ctx context.Context, userID string, input *CreatePlac
 		Tags:          input.Tags,
 		OpeningHours:  input.OpeningHours,
 		ContactInfo:   input.ContactInfo,
-		Amenities:     input.Amenities,
+		Amenities:     amenities,
+		Accessibility: input.Accessibility,
+		AccessFees:    input.AccessFees,
 		Privacy:       "public",
 		Status:        "active",
 		CreatedAt:     time.Now(),
@@ -82,24 +105,61 @@ func (s *servicePg) Create(ctx context.Context, userID string, input *CreatePlac
 		place.Privacy = input.Privacy
 	}
 	
+	// Water source details and campground links put the place in their
+	// category
+	if input.WaterSource != nil && !place.HasCategory(CategoryWaterSource) {
+		place.Category = append(place.Category, CategoryWaterSource)
+	}
+	if input.Campground != nil && !place.HasCategory(CategoryCampground) {
+		place.Category = append(place.Category, CategoryCampground)
+	}
+	
 	if err := s.repo.Create(ctx, place); err != nil {
 		return nil, fmt.Errorf("failed to create place: %w", err)
 	}
 	
+	if input.WaterSource != nil {
+		place.WaterSource = input.WaterSource.waterSource()
+		if err := s.repo.SetWaterSource(ctx, place.ID, place.WaterSource); err != nil {
+			return nil, err
+		}
+	}
+	
+	if input.Campground != nil {
+		place.Campground = &CampgroundLink{Provider: input.Campground.Provider, FacilityID: input.Campground.FacilityID}
+		if err := s.repo.SetCampgroundLink(ctx, place.ID, place.Campground); err != nil {
+			return nil, err
+		}
+	}
+	
+	s.syncIndex(ctx, place)
+	
 	return place, nil
 }
 
 func (s *servicePg) GetByID(ctx context.Context, userID, placeID string) (*Place, error) {
-	place, err := s.repo.GetByID(ctx, placeID)
+	return s.GetByIDWith(ctx, userID, placeID, AllRelations)
+}
+
+func (s *servicePg) GetByIDWith(ctx context.Context, userID, placeID string, relations Relations) (*Place, error) {
+	// Collaborators are always needed for the permission check
+	load := relations
+	load.Collaborators = true
+
+	place, err := s.repo.GetByIDWith(ctx, placeID, load)
 	if err != nil {
 		return nil, err
 	}
 	
 	// Check if user has permission to view this place
-	if place.Privacy == "private" && place.CreatedBy != userID && !place.HasCollaborator(userID) {
+	if !place.VisibleTo(userID) {
 		return nil, ErrUnauthorized
 	}
 	
+	if !relations.Collaborators {
+		place.Collaborators = nil
+	}
+	
 	return place, nil
 }
 
@@ -109,8 +169,12 @@ func (s *servicePg) Update(ctx context.Context, userID, placeID string, input *U
 		return nil, err
 	}
 	
-	// Check if user can edit
-	if !place.CanUserEdit(userID) {
+	// Check if user can edit, including rights inherited from parent areas
+	canEdit, err := s.permissions.CanUserPerformOnPlace(ctx, userID, place, "place.update")
+	if err != nil {
+		return nil, err
+	}
+	if !canEdit {
 		return nil, ErrUnauthorized
 	}
 	
@@ -146,13 +210,36 @@ func (s *servicePg) Update(ctx context.Context, userID, placeID string, input *U
 		place.Tags = input.Tags
 	}
 	if input.OpeningHours != nil {
+		if err := input.OpeningHours.Validate(); err != nil {
+			return nil, err
+		}
 		place.OpeningHours = input.OpeningHours
 	}
 	if input.ContactInfo != nil {
+		if err := input.ContactInfo.Normalize(); err != nil {
+			return nil, err
+		}
+		// The last check holds as long as the links it followed are kept
+		if place.ContactInfo != nil && maps.Equal(place.ContactInfo.checkedLinks(), input.ContactInfo.checkedLinks()) {
+			input.ContactInfo.Verification = place.ContactInfo.Verification
+		}
 		place.ContactInfo = input.ContactInfo
 	}
-	if len(input.Amenities) > 0 {
-		place.Amenities = input.Amenities
+	// An empty list clears the attributes
+	if input.Accessibility != nil {
+		place.Accessibility = input.Accessibility
+	}
+	// An empty list clears the fees
+	if input.AccessFees != nil {
+		place.AccessFees = input.AccessFees
+	}
+	// An empty list clears the amenities
+	if input.Amenities != nil {
+		amenities, err := NormalizeAmenities(input.Amenities)
+		if err != nil {
+			return nil, err
+		}
+		place.Amenities = amenities
 	}
 	if input.Privacy != nil {
 		place.Privacy = *input.Privacy
@@ -177,23 +264,59 @@ func (s *servicePg) Update(ctx context.Context, userID, placeID string, input *U
 		}
 	}
 	
+	if input.WaterSource != nil && !place.HasCategory(CategoryWaterSource) {
+		place.Category = append(place.Category, CategoryWaterSource)
+	}
+	if input.Campground != nil && !place.HasCategory(CategoryCampground) {
+		place.Category = append(place.Category, CategoryCampground)
+	}
+	
 	place.UpdatedAt = time.Now()
 	
 	if err := s.repo.Update(ctx, place); err != nil {
 		return nil, fmt.Errorf("failed to update place: %w", err)
 	}
 	
+	// Leaving the water source or campground category drops the details
+	switch {
+	case input.WaterSource != nil:
+		place.WaterSource = input.WaterSource.waterSource()
+		if err := s.repo.SetWaterSource(ctx, placeID, place.WaterSource); err != nil {
+			return nil, err
+		}
+	case !place.HasCategory(CategoryWaterSource) && place.WaterSource != nil:
+		if err := s.repo.ClearWaterSource(ctx, placeID); err != nil {
+			return nil, err
+		}
+		place.WaterSource = nil
+	}
+	
+	switch {
+	case input.Campground != nil:
+		place.Campground = &CampgroundLink{Provider: input.Campground.Provider, FacilityID: input.Campground.FacilityID}
+		if err := s.repo.SetCampgroundLink(ctx, placeID, place.Campground); err != nil {
+			return nil, err
+		}
+	case !place.HasCategory(CategoryCampground) && place.Campground != nil:
+		if err := s.repo.ClearCampgroundLink(ctx, placeID); err != nil {
+			return nil, err
+		}
+		place.Campground = nil
+	}
+	s.permissions.Invalidate(ctx, placeID)
+	httpcache.PurgeAsync(s.purger, httpcache.PlaceKey(placeID))
+	s.syncIndex(ctx, place)
+	
 	return place, nil
 }
 
 func (s *servicePg) Delete(ctx context.Context, userID, placeID string) error {
-	place, err := s.repo.GetByID(ctx, placeID)
+	// Check if user can delete, including rights inherited from parent areas
+	canDelete, err := s.permissions.CanUserPerform(ctx, userID, placeID, "place.delete")
 	if err != nil {
 		return err
 	}
-	
-	// Check if user can delete
-	if !place.CanUserDelete(userID) {
+	if !canDelete {
 		return ErrUnauthorized
 	}
 	
@@ -207,7 +330,14 @@ func (s *servicePg) Delete(ctx context.Context, userID, placeID string) error {
 	// 	return errors.New("cannot delete place with child places")
 	// }
 	
-	return s.repo.Delete(ctx, placeID)
+	if err := s.repo.Delete(ctx, placeID); err != nil {
+		return err
+	}
+	s.permissions.Invalidate(ctx, placeID)
+	httpcache.PurgeAsync(s.purger, httpcache.PlaceKey(placeID))
+	s.unindex(ctx, placeID)
+	
+	return nil
 }
 
 func (s *servicePg) GetUserPlaces(ctx context.Context, userID string, limit, offset int) ([]*Place, int64, error) {
@@ -255,10 +385,13 @@ func (s *servicePg) GetChildPlaces(ctx context.Context, userID, parentID string)
 func (s *servicePg) Search(ctx context.Context, userID string, input *SearchPlacesInput) ([]*Place, int64, error) {
 	// TODO: Implement search with privacy filtering
 	filters := SearchFilters{
-		Category: input.Category,
-		Tags:     input.Tags,
-		Limit:    input.Limit,
-		Offset:   input.Offset,
+		Category:      input.Category,
+		Tags:          input.Tags,
+		Accessibility: input.Accessibility,
+		Amenities:     input.Amenities,
+		Limit:         input.Limit,
+		Offset:        input.Offset,
+		After:         input.After,
 	}
 	
 	result, err := s.repo.Search(ctx, input.Query, filters)
@@ -289,7 +422,7 @@ func (s *servicePg) AddCollaborator(ctx context.Context, userID, placeID, collab
 	
 	// Check if already a collaborator
 	if place.HasCollaborator(collaboratorID) {
-		return errors.New("user is already a collaborator")
+		return ErrAlreadyCollaborator
 	}
 	
 	// Validate role
@@ -352,7 +485,18 @@ func (s *servicePg) List(ctx context.Context, userID string, filter *PlaceFilter
 		return nil, 0, err
 	}
 	
-	// Apply pagination manually
+	// Apply pagination manually. Places are ordered newest first, so a cursor
+	// starts the page at the first place after it.
+	if filter != nil && filter.After != nil {
+		offset = len(places)
+		for i, place := range places {
+			if filter.After.Follows(place.CreatedAt, place.ID, true) {
+				offset = i
+				break
+			}
+		}
+	}
+
 	start := offset
 	end := offset + limit
 	if start > len(places) {
@@ -377,21 +521,21 @@ func (s *servicePg) handlePublicSearch(ctx context.Context, filter *PlaceFilter,
 		return dbPlaces, total, nil
 	}
 	
-	log.Printf("[PlaceService] No database results. Mapbox service configured: %v", s.mapboxService != nil)
+	log.Printf("[PlaceService] No database results. Geocoder configured: %v", s.geocoder != nil)
 	
-	// If no results from database and we have Mapbox service, search Mapbox
-	if s.mapboxService != nil && query != "" {
-		log.Printf("[PlaceService] Searching Mapbox for query: %s", query)
-		// For Mapbox, we ignore offset and just use limit
-		mapboxPlaces, err := s.mapboxService.SearchPlaces(ctx, query, limit)
+	// If no results from database and we have a geocoder, search it
+	if s.geocoder != nil && query != "" {
+		log.Printf("[PlaceService] Geocoding query: %s", query)
+		// Geocoders are not paged, so we ignore offset and just use limit
+		results, err := s.geocoder.Search(ctx, query, limit)
 		if err != nil {
-			log.Printf("[PlaceService] ERROR: Mapbox search failed: %v", err)
+			log.Printf("[PlaceService] ERROR: Geocoder search failed: %v", err)
 			
-			// Temporary: Return mock data when Mapbox fails (e.g., 403 error)
-			// This allows testing while the token is being fixed
+			// Temporary: Return mock data when every geocoder fails
+			// This allows testing while the providers are being configured
 			mockPlaces := s.getMockPlaces(query, limit)
 			if len(mockPlaces) > 0 {
-				log.Printf("[PlaceService] Returning %d mock places due to Mapbox error", len(mockPlaces))
+				log.Printf("[PlaceService] Returning %d mock places due to geocoder error", len(mockPlaces))
 				return mockPlaces, int64(len(mockPlaces)), nil
 			}
 			
@@ -399,12 +543,16 @@ func (s *servicePg) handlePublicSearch(ctx context.Context, filter *PlaceFilter,
 			return []*Place{}, 0, nil
 		}
 		
-		log.Printf("[PlaceService] Mapbox returned %d places", len(mapboxPlaces))
-		return mapboxPlaces, int64(len(mapboxPlaces)), nil
+		places := make([]*Place, 0, len(results))
+		for _, result := range results {
+			places = append(places, placeFromGeocode(result))
+		}
+		log.Printf("[PlaceService] Geocoder returned %d places", len(places))
+		return places, int64(len(places)), nil
 	}
 	
-	// Fallback to empty results if no Mapbox service configured
-	log.Printf("[PlaceService] No Mapbox service configured or empty query. Returning empty results.")
+	// Fallback to empty results if no geocoder configured
+	log.Printf("[PlaceService] No geocoder configured or empty query. Returning empty results.")
 	return []*Place{}, 0, nil
 }
 
@@ -546,4 +694,70 @@ func (s *servicePg) UpdateRating(ctx context.Context, userID, placeID string, ra
 func (s *servicePg) AddNote(ctx context.Context, userID, placeID, note string) error {
 	// TODO: Implement note management
 	return nil
-}
\ No newline at end of file
+}
+
+func (s *servicePg) TransferOwnership(ctx context.Context, userID, placeID, newOwnerID string) (*OwnershipTransfer, error) {
+	place, err := s.repo.GetByID(ctx, placeID)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Only the owner can hand the place off
+	if !place.IsOwner(userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	if newOwnerID == userID {
+		return nil, errors.New("you already own this place")
+	}
+	
+	// The recipient must already be collaborating on the place
+	if !place.HasCollaborator(newOwnerID) {
+		return nil, errors.New("new owner must be a collaborator on the place")
+	}
+	
+	transfer := &OwnershipTransfer{
+		EntityID:   placeID,
+		FromUserID: userID,
+		ToUserID:   newOwnerID,
+	}
+	
+	if err := s.repo.CreateOwnershipTransfer(ctx, transfer); err != nil {
+		return nil, err
+	}
+	
+	return transfer, nil
+}
+
+func (s *servicePg) AcceptOwnershipTransfer(ctx context.Context, userID, placeID string) (*Place, error) {
+	transfer, err := s.repo.GetPendingOwnershipTransfer(ctx, placeID)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Only the recipient can accept
+	if transfer.ToUserID != userID {
+		return nil, ErrUnauthorized
+	}
+	
+	if err := s.repo.ResolveOwnershipTransfer(ctx, transfer, userID, true); err != nil {
+		return nil, err
+	}
+	s.permissions.Invalidate(ctx, placeID)
+	
+	return s.repo.GetByID(ctx, placeID)
+}
+
+func (s *servicePg) DeclineOwnershipTransfer(ctx context.Context, userID, placeID string) error {
+	transfer, err := s.repo.GetPendingOwnershipTransfer(ctx, placeID)
+	if err != nil {
+		return err
+	}
+	
+	// The recipient can decline and the owner can withdraw the request
+	if transfer.ToUserID != userID && transfer.FromUserID != userID {
+		return ErrUnauthorized
+	}
+	
+	return s.repo.ResolveOwnershipTransfer(ctx, transfer, userID, false)
+}
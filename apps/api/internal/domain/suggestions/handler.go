@@ -0,0 +1,207 @@
+package suggestions
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/internal/middleware"
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the suggestion routes, and those suggesting
+// changes to trips and places. Trip suggestions are moderated by the trip's
+// owner and the collaborators allowed to, place suggestions by the place's
+// editors.
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *users.RouteMiddleware) {
+	suggest := mw.RequireSystemPermission(users.PermissionSuggestionCreate)
+
+	router.GET("/trips/:id/suggestions", mw.RequireAuthOrShare, h.ListForTrip)
+	router.POST("/trips/:id/suggestions", mw.RequireAuthOrShare, suggest, h.CreateForTrip)
+	router.GET("/places/:id/suggestions", mw.RequireAuth, h.ListForPlace)
+	router.POST("/places/:id/suggestions", mw.RequireAuth, suggest, h.CreateForPlace)
+
+	suggestions := router.Group("/suggestions", mw.RequireAuth)
+	{
+		suggestions.GET("/:id", h.GetByID)
+		suggestions.POST("/:id/accept", h.Accept)
+		suggestions.POST("/:id/reject", h.Reject)
+		suggestions.POST("/:id/comments", h.AddComment)
+	}
+}
+
+// CreateForTrip suggests a change to a trip
+func (h *Handler) CreateForTrip(c *gin.Context) {
+	h.create(c, TargetTrip)
+}
+
+// CreateForPlace suggests a change to a place
+func (h *Handler) CreateForPlace(c *gin.Context) {
+	h.create(c, TargetPlace)
+}
+
+// ListForTrip lists the suggestions made on a trip
+func (h *Handler) ListForTrip(c *gin.Context) {
+	h.list(c, TargetTrip)
+}
+
+// ListForPlace lists the suggestions made on a place
+func (h *Handler) ListForPlace(c *gin.Context) {
+	h.list(c, TargetPlace)
+}
+
+func (h *Handler) create(c *gin.Context, targetType string) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input CreateSuggestionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	suggestion, err := h.service.Create(c.Request.Context(), userID, targetType, c.Param("id"), &input)
+	if err != nil {
+		h.suggestionError(c, err, "You don't have permission to suggest changes here", "Failed to create suggestion")
+		return
+	}
+
+	response.Created(c, suggestion)
+}
+
+func (h *Handler) list(c *gin.Context, targetType string) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", StatusPending, StatusAccepted, StatusRejected:
+	default:
+		response.BadRequest(c, "Status must be pending, accepted or rejected")
+		return
+	}
+
+	suggestions, err := h.service.List(c.Request.Context(), userID, targetType, c.Param("id"), status)
+	if err != nil {
+		h.suggestionError(c, err, "You don't have permission to view these suggestions", "Failed to list suggestions")
+		return
+	}
+
+	response.Success(c, suggestions)
+}
+
+func (h *Handler) GetByID(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	suggestion, err := h.service.GetByID(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		h.suggestionError(c, err, "You don't have permission to view this suggestion", "Failed to get suggestion")
+		return
+	}
+
+	response.Success(c, suggestion)
+}
+
+// Accept accepts a pending suggestion
+func (h *Handler) Accept(c *gin.Context) {
+	h.review(c, h.service.Accept)
+}
+
+// Reject rejects a pending suggestion
+func (h *Handler) Reject(c *gin.Context) {
+	h.review(c, h.service.Reject)
+}
+
+func (h *Handler) review(c *gin.Context, decide func(ctx context.Context, userID, suggestionID string, input *ReviewSuggestionInput) (*Suggestion, error)) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	// Review notes are optional, and so is the body
+	var input ReviewSuggestionInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	suggestion, err := decide(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		h.suggestionError(c, err, "You don't have permission to moderate this suggestion", "Failed to review suggestion")
+		return
+	}
+
+	response.Success(c, suggestion)
+}
+
+func (h *Handler) AddComment(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input AddCommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	comment, err := h.service.AddComment(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		h.suggestionError(c, err, "You don't have permission to comment on this suggestion", "Failed to add comment")
+		return
+	}
+
+	response.Created(c, comment)
+}
+
+func (h *Handler) suggestionError(c *gin.Context, err error, forbidden, fallback string) {
+	switch {
+	case errors.Is(err, ErrSuggestionNotFound):
+		response.NotFound(c, "Suggestion not found")
+	case errors.Is(err, trips.ErrTripNotFound):
+		response.NotFound(c, "Trip not found")
+	case errors.Is(err, places.ErrPlaceNotFound):
+		response.NotFound(c, "Place not found")
+	case errors.Is(err, ErrUnauthorized):
+		response.Forbidden(c, forbidden)
+	case errors.Is(err, ErrAlreadyReviewed):
+		response.Conflict(c, "Suggestion has already been reviewed")
+	case errors.Is(err, ErrInvalidSuggestion):
+		response.BadRequest(c, err.Error())
+	default:
+		response.FromError(c, err, fallback)
+	}
+}
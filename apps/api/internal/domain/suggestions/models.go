@@ -0,0 +1,90 @@
+package suggestions
+
+import (
+	"time"
+)
+
+// Targets a suggestion can be made on
+const (
+	TargetTrip  = "trip"
+	TargetPlace = "place"
+)
+
+// Kinds of suggestion
+const (
+	TypeEdit     = "edit"
+	TypeAddition = "addition"
+	TypeDeletion = "deletion"
+	TypeComment  = "comment"
+)
+
+// Review states of a suggestion
+const (
+	StatusPending  = "pending"
+	StatusAccepted = "accepted"
+	StatusRejected = "rejected"
+)
+
+// Suggestion is a change proposed to a trip or place by someone who cannot
+// or would rather not make it themselves, left for its moderators to accept
+// or reject
+type Suggestion struct {
+	ID             string     `db:"id" json:"id"`
+	TargetType     string     `db:"target_type" json:"target_type"`
+	TargetID       string     `db:"target_id" json:"target_id"`
+	SuggestedBy    string     `db:"suggested_by" json:"suggested_by"`
+	Type           string     `db:"type" json:"type"`
+	Status         string     `db:"status" json:"status"`
+	FieldName      *string    `db:"field_name" json:"field_name,omitempty"`
+	CurrentValue   *string    `db:"current_value" json:"current_value,omitempty"`
+	SuggestedValue *string    `db:"suggested_value" json:"suggested_value,omitempty"`
+	Reason         *string    `db:"reason" json:"reason,omitempty"`
+	ReviewedBy     *string    `db:"reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedAt     *time.Time `db:"reviewed_at" json:"reviewed_at,omitempty"`
+	ReviewNotes    *string    `db:"review_notes" json:"review_notes,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+
+	// Joined user info of whoever made the suggestion
+	Username    string `db:"username" json:"username,omitempty"`
+	DisplayName string `db:"display_name" json:"display_name,omitempty"`
+	AvatarURL   string `db:"avatar_url" json:"avatar_url,omitempty"`
+
+	// Comments are loaded for a single suggestion only
+	Comments []Comment `json:"comments,omitempty"`
+}
+
+// Comment is a message in the discussion of a suggestion
+type Comment struct {
+	ID           string    `db:"id" json:"id"`
+	SuggestionID string    `db:"suggestion_id" json:"suggestion_id"`
+	UserID       string    `db:"user_id" json:"user_id"`
+	Message      string    `db:"message" json:"message"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+
+	// Joined user info
+	Username    string `db:"username" json:"username,omitempty"`
+	DisplayName string `db:"display_name" json:"display_name,omitempty"`
+	AvatarURL   string `db:"avatar_url" json:"avatar_url,omitempty"`
+}
+
+// Input types
+type CreateSuggestionInput struct {
+	Type           string  `json:"type" binding:"required,oneof=edit addition deletion comment"`
+	FieldName      *string `json:"field_name,omitempty" binding:"omitempty,max=100"`
+	SuggestedValue *string `json:"suggested_value,omitempty" binding:"omitempty,max=10000"`
+	Reason         *string `json:"reason,omitempty" binding:"omitempty,max=2000"`
+}
+
+type ReviewSuggestionInput struct {
+	Notes *string `json:"notes,omitempty" binding:"omitempty,max=2000"`
+}
+
+type AddCommentInput struct {
+	Message string `json:"message" binding:"required,min=1,max=2000"`
+}
+
+// IsPending tells whether the suggestion still awaits review
+func (s *Suggestion) IsPending() bool {
+	return s.Status == StatusPending
+}
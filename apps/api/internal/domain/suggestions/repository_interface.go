@@ -0,0 +1,25 @@
+package suggestions
+
+import (
+	"context"
+)
+
+// Repository defines the interface for suggestion data operations
+type Repository interface {
+	// Create creates a new suggestion, counting it on its trip when made on one
+	Create(ctx context.Context, suggestion *Suggestion) error
+
+	// GetByID retrieves a suggestion by ID with its comments
+	GetByID(ctx context.Context, id string) (*Suggestion, error)
+
+	// ListByTarget retrieves the suggestions made on a trip or place, newest
+	// first, optionally only those in one status
+	ListByTarget(ctx context.Context, targetType, targetID, status string) ([]*Suggestion, error)
+
+	// Review records the decision on a pending suggestion. It fails with
+	// ErrAlreadyReviewed when the suggestion was decided already.
+	Review(ctx context.Context, id, status, reviewerID string, notes *string) error
+
+	// AddComment adds a comment to a suggestion
+	AddComment(ctx context.Context, comment *Comment) error
+}
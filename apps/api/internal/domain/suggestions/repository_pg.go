@@ -0,0 +1,188 @@
+package suggestions
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresRepository implements the repository interface for PostgreSQL
+type PostgresRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *sqlx.DB) *PostgresRepository {
+	return &PostgresRepository{
+		db: db,
+	}
+}
+
+const suggestionColumns = `
+	s.id, s.target_type, s.target_id, s.suggested_by, s.type, s.status,
+	s.field_name, s.current_value, s.suggested_value, s.reason,
+	s.reviewed_by, s.reviewed_at, s.review_notes, s.created_at, s.updated_at,
+	u.username, COALESCE(u.display_name, '') AS display_name,
+	COALESCE(u.avatar_url, '') AS avatar_url`
+
+// Create creates a new suggestion, counting it on its trip when made on one
+func (r *PostgresRepository) Create(ctx context.Context, suggestion *Suggestion) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO suggestions (
+			target_type, target_id, suggested_by, type, status,
+			field_name, current_value, suggested_value, reason
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		suggestion.TargetType,
+		suggestion.TargetID,
+		suggestion.SuggestedBy,
+		suggestion.Type,
+		suggestion.Status,
+		suggestion.FieldName,
+		suggestion.CurrentValue,
+		suggestion.SuggestedValue,
+		suggestion.Reason,
+	).Scan(&suggestion.ID, &suggestion.CreatedAt, &suggestion.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create suggestion: %w", err)
+	}
+
+	if suggestion.TargetType == TargetTrip {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE trips SET suggestion_count = suggestion_count + 1
+			WHERE id = $1`, suggestion.TargetID)
+		if err != nil {
+			return fmt.Errorf("failed to count suggestion: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetByID retrieves a suggestion by ID with its comments
+func (r *PostgresRepository) GetByID(ctx context.Context, id string) (*Suggestion, error) {
+	var suggestion Suggestion
+	query := `
+		SELECT ` + suggestionColumns + `
+		FROM suggestions s
+		JOIN users u ON s.suggested_by = u.id
+		WHERE s.id = $1`
+
+	err := r.db.GetContext(ctx, &suggestion, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSuggestionNotFound
+		}
+		return nil, fmt.Errorf("failed to get suggestion: %w", err)
+	}
+
+	comments, err := r.getComments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	suggestion.Comments = comments
+
+	return &suggestion, nil
+}
+
+// ListByTarget retrieves the suggestions made on a trip or place, newest
+// first, optionally only those in one status
+func (r *PostgresRepository) ListByTarget(ctx context.Context, targetType, targetID, status string) ([]*Suggestion, error) {
+	suggestions := []*Suggestion{}
+	query := `
+		SELECT ` + suggestionColumns + `
+		FROM suggestions s
+		JOIN users u ON s.suggested_by = u.id
+		WHERE s.target_type = $1 AND s.target_id = $2
+			AND ($3 = '' OR s.status = $3)
+		ORDER BY s.created_at DESC, s.id DESC`
+
+	err := r.db.SelectContext(ctx, &suggestions, query, targetType, targetID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suggestions: %w", err)
+	}
+
+	return suggestions, nil
+}
+
+// Review records the decision on a pending suggestion. The status is checked
+// in the update itself so that two moderators cannot both decide it.
+func (r *PostgresRepository) Review(ctx context.Context, id, status, reviewerID string, notes *string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE suggestions
+		SET status = $2, decision = $2, reviewed_by = $3, reviewed_at = CURRENT_TIMESTAMP,
+			review_notes = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $5`,
+		id, status, reviewerID, notes, StatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to review suggestion: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		var exists bool
+		err := r.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM suggestions WHERE id = $1)`, id)
+		if err != nil {
+			return fmt.Errorf("failed to review suggestion: %w", err)
+		}
+		if !exists {
+			return ErrSuggestionNotFound
+		}
+		return ErrAlreadyReviewed
+	}
+
+	return nil
+}
+
+// AddComment adds a comment to a suggestion
+func (r *PostgresRepository) AddComment(ctx context.Context, comment *Comment) error {
+	query := `
+		INSERT INTO suggestion_comments (suggestion_id, user_id, message)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		comment.SuggestionID,
+		comment.UserID,
+		comment.Message,
+	).Scan(&comment.ID, &comment.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add comment: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) getComments(ctx context.Context, suggestionID string) ([]Comment, error) {
+	var comments []Comment
+	query := `
+		SELECT
+			sc.id, sc.suggestion_id, sc.user_id, sc.message, sc.created_at,
+			u.username, COALESCE(u.display_name, '') AS display_name,
+			COALESCE(u.avatar_url, '') AS avatar_url
+		FROM suggestion_comments sc
+		JOIN users u ON sc.user_id = u.id
+		WHERE sc.suggestion_id = $1
+		ORDER BY sc.created_at, sc.id`
+
+	err := r.db.SelectContext(ctx, &comments, query, suggestionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+
+	return comments, nil
+}
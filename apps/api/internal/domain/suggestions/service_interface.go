@@ -0,0 +1,28 @@
+package suggestions
+
+import (
+	"context"
+	"errors"
+)
+
+// Service defines the interface for suggestion operations
+type Service interface {
+	Create(ctx context.Context, userID, targetType, targetID string, input *CreateSuggestionInput) (*Suggestion, error)
+	GetByID(ctx context.Context, userID, suggestionID string) (*Suggestion, error)
+	List(ctx context.Context, userID, targetType, targetID, status string) ([]*Suggestion, error)
+
+	// Moderation
+	Accept(ctx context.Context, userID, suggestionID string, input *ReviewSuggestionInput) (*Suggestion, error)
+	Reject(ctx context.Context, userID, suggestionID string, input *ReviewSuggestionInput) (*Suggestion, error)
+
+	// Discussion
+	AddComment(ctx context.Context, userID, suggestionID string, input *AddCommentInput) (*Comment, error)
+}
+
+// Common errors
+var (
+	ErrSuggestionNotFound = errors.New("suggestion not found")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrAlreadyReviewed    = errors.New("suggestion has already been reviewed")
+	ErrInvalidSuggestion  = errors.New("invalid suggestion")
+)
@@ -0,0 +1,266 @@
+package suggestions
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+)
+
+// PlacePermissionChecker resolves what a user may do on a place, including
+// the rights inherited from the areas it lies in
+type PlacePermissionChecker interface {
+	CanUserPerformOnPlace(ctx context.Context, userID string, place *places.Place, permission string) (bool, error)
+}
+
+type servicePg struct {
+	repo             Repository
+	tripRepo         trips.Repository
+	placeRepo        places.Repository
+	placePermissions PlacePermissionChecker
+	bus              events.Bus // nil when nobody is told about suggestions
+}
+
+// NewService creates a new suggestion service
+func NewService(repo Repository, tripRepo trips.Repository, placeRepo places.Repository, placePermissions PlacePermissionChecker) *servicePg {
+	return &servicePg{
+		repo:             repo,
+		tripRepo:         tripRepo,
+		placeRepo:        placeRepo,
+		placePermissions: placePermissions,
+	}
+}
+
+// SetEventBus sets where events about suggestions are published
+func (s *servicePg) SetEventBus(bus events.Bus) {
+	s.bus = bus
+}
+
+// target is what a user may do with the trip or place a suggestion is on
+type target struct {
+	visible   bool
+	moderator bool
+
+	// fields holds the current value of each field an edit can be suggested
+	// for, recorded with the suggestion so reviewers see what it replaces
+	fields map[string]string
+}
+
+// loadTarget loads the trip or place a suggestion is on and what the user may
+// do with it. Trip owners and collaborators allowed to moderate suggestions
+// moderate those on the trip, and editors of a place those on the place.
+func (s *servicePg) loadTarget(ctx context.Context, userID, targetType, targetID string) (*target, error) {
+	switch targetType {
+	case TargetTrip:
+		trip, err := s.tripRepo.GetByIDWith(ctx, targetID, trips.Relations{Collaborators: true})
+		if err != nil {
+			return nil, err
+		}
+		return &target{
+			visible:   trip.VisibleTo(userID),
+			moderator: trip.CanUserModerateSuggestions(userID),
+			fields:    map[string]string{"title": trip.Title, "description": trip.Description},
+		}, nil
+
+	case TargetPlace:
+		place, err := s.placeRepo.GetByIDWith(ctx, targetID, places.Relations{Collaborators: true})
+		if err != nil {
+			return nil, err
+		}
+		visible, err := s.placePermissions.CanUserPerformOnPlace(ctx, userID, place, "place.read")
+		if err != nil {
+			return nil, err
+		}
+		moderator, err := s.placePermissions.CanUserPerformOnPlace(ctx, userID, place, "suggestion.moderate")
+		if err != nil {
+			return nil, err
+		}
+		return &target{
+			visible:   visible,
+			moderator: moderator,
+			fields:    map[string]string{"name": place.Name, "description": place.Description},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown suggestion target %q: %w", targetType, ErrInvalidSuggestion)
+	}
+}
+
+func (s *servicePg) Create(ctx context.Context, userID, targetType, targetID string, input *CreateSuggestionInput) (*Suggestion, error) {
+	// An edit says which field to change and to what, anything else why
+	switch input.Type {
+	case TypeEdit:
+		if input.FieldName == nil || *input.FieldName == "" || input.SuggestedValue == nil {
+			return nil, fmt.Errorf("an edit needs a field name and suggested value: %w", ErrInvalidSuggestion)
+		}
+	case TypeAddition:
+		if input.SuggestedValue == nil || *input.SuggestedValue == "" {
+			return nil, fmt.Errorf("an addition needs a suggested value: %w", ErrInvalidSuggestion)
+		}
+	default:
+		if input.Reason == nil || *input.Reason == "" {
+			return nil, fmt.Errorf("a %s needs a reason: %w", input.Type, ErrInvalidSuggestion)
+		}
+	}
+
+	t, err := s.loadTarget(ctx, userID, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !t.visible {
+		return nil, ErrUnauthorized
+	}
+
+	suggestion := &Suggestion{
+		TargetType:     targetType,
+		TargetID:       targetID,
+		SuggestedBy:    userID,
+		Type:           input.Type,
+		Status:         StatusPending,
+		FieldName:      input.FieldName,
+		SuggestedValue: input.SuggestedValue,
+		Reason:         input.Reason,
+	}
+	if input.Type == TypeEdit {
+		if current, ok := t.fields[*input.FieldName]; ok {
+			suggestion.CurrentValue = &current
+		}
+	}
+
+	if err := s.repo.Create(ctx, suggestion); err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.GetByID(ctx, suggestion.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.announce(ctx, events.SuggestionCreated, created, userID, map[string]interface{}{"type": created.Type})
+	return created, nil
+}
+
+func (s *servicePg) GetByID(ctx context.Context, userID, suggestionID string) (*Suggestion, error) {
+	suggestion, err := s.repo.GetByID(ctx, suggestionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.canView(ctx, userID, suggestion); err != nil {
+		return nil, err
+	}
+
+	return suggestion, nil
+}
+
+func (s *servicePg) List(ctx context.Context, userID, targetType, targetID, status string) ([]*Suggestion, error) {
+	t, err := s.loadTarget(ctx, userID, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !t.visible {
+		return nil, ErrUnauthorized
+	}
+
+	return s.repo.ListByTarget(ctx, targetType, targetID, status)
+}
+
+func (s *servicePg) Accept(ctx context.Context, userID, suggestionID string, input *ReviewSuggestionInput) (*Suggestion, error) {
+	return s.review(ctx, userID, suggestionID, StatusAccepted, input)
+}
+
+func (s *servicePg) Reject(ctx context.Context, userID, suggestionID string, input *ReviewSuggestionInput) (*Suggestion, error) {
+	return s.review(ctx, userID, suggestionID, StatusRejected, input)
+}
+
+// review decides a pending suggestion. Accepting one records the decision;
+// the change itself is left to the moderator to make.
+func (s *servicePg) review(ctx context.Context, userID, suggestionID, status string, input *ReviewSuggestionInput) (*Suggestion, error) {
+	suggestion, err := s.repo.GetByID(ctx, suggestionID)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := s.loadTarget(ctx, userID, suggestion.TargetType, suggestion.TargetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !t.moderator {
+		return nil, ErrUnauthorized
+	}
+
+	if !suggestion.IsPending() {
+		return nil, ErrAlreadyReviewed
+	}
+
+	if err := s.repo.Review(ctx, suggestionID, status, userID, input.Notes); err != nil {
+		return nil, err
+	}
+
+	reviewed, err := s.repo.GetByID(ctx, suggestionID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.announce(ctx, events.SuggestionReviewed, reviewed, userID, map[string]interface{}{"status": status})
+	return reviewed, nil
+}
+
+func (s *servicePg) AddComment(ctx context.Context, userID, suggestionID string, input *AddCommentInput) (*Comment, error) {
+	suggestion, err := s.repo.GetByID(ctx, suggestionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.canView(ctx, userID, suggestion); err != nil {
+		return nil, err
+	}
+
+	comment := &Comment{
+		SuggestionID: suggestionID,
+		UserID:       userID,
+		Message:      input.Message,
+	}
+	if err := s.repo.AddComment(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	s.announce(ctx, events.SuggestionCommented, suggestion, userID, map[string]interface{}{"comment_id": comment.ID})
+	return comment, nil
+}
+
+// canView checks that the user can see the suggestion: whoever made it, and
+// anyone who can see what it was made on
+func (s *servicePg) canView(ctx context.Context, userID string, suggestion *Suggestion) error {
+	t, err := s.loadTarget(ctx, userID, suggestion.TargetType, suggestion.TargetID)
+	if err != nil {
+		return err
+	}
+
+	if !t.visible && suggestion.SuggestedBy != userID {
+		return ErrUnauthorized
+	}
+
+	return nil
+}
+
+// announce publishes an event about a suggestion on the trip or place it was
+// made on, so that whoever follows that entity sees it. The change has
+// already been saved, so a failure is only logged.
+func (s *servicePg) announce(ctx context.Context, eventType string, suggestion *Suggestion, actorID string, data map[string]interface{}) {
+	if s.bus == nil {
+		return
+	}
+
+	data["suggestion_id"] = suggestion.ID
+	event := events.New(eventType, suggestion.TargetType, suggestion.TargetID, actorID, data)
+	if err := s.bus.Publish(ctx, event); err != nil {
+		log.Printf("suggestions: failed to publish %s for suggestion %s: %v", eventType, suggestion.ID, err)
+	}
+}
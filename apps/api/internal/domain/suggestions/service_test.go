@@ -0,0 +1,278 @@
+package suggestions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	ownerID     = "owner-1"
+	moderatorID = "moderator-1"
+	editorID    = "editor-1"
+	strangerID  = "stranger-1"
+	tripID      = "trip-1"
+	placeID     = "place-1"
+)
+
+type mockRepository struct {
+	mock.Mock
+}
+
+func (m *mockRepository) Create(ctx context.Context, suggestion *Suggestion) error {
+	args := m.Called(ctx, suggestion)
+	suggestion.ID = "suggestion-1"
+	return args.Error(0)
+}
+
+func (m *mockRepository) GetByID(ctx context.Context, id string) (*Suggestion, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Suggestion), args.Error(1)
+}
+
+func (m *mockRepository) ListByTarget(ctx context.Context, targetType, targetID, status string) ([]*Suggestion, error) {
+	args := m.Called(ctx, targetType, targetID, status)
+	return args.Get(0).([]*Suggestion), args.Error(1)
+}
+
+func (m *mockRepository) Review(ctx context.Context, id, status, reviewerID string, notes *string) error {
+	return m.Called(ctx, id, status, reviewerID, notes).Error(0)
+}
+
+func (m *mockRepository) AddComment(ctx context.Context, comment *Comment) error {
+	return m.Called(ctx, comment).Error(0)
+}
+
+type tripRepository struct {
+	trips.Repository
+	trip *trips.Trip
+}
+
+func (r *tripRepository) GetByIDWith(ctx context.Context, id string, relations trips.Relations) (*trips.Trip, error) {
+	if r.trip == nil || r.trip.ID != id {
+		return nil, trips.ErrTripNotFound
+	}
+	return r.trip, nil
+}
+
+type placeRepository struct {
+	places.Repository
+	place *places.Place
+}
+
+func (r *placeRepository) GetByIDWith(ctx context.Context, id string, relations places.Relations) (*places.Place, error) {
+	if r.place == nil || r.place.ID != id {
+		return nil, places.ErrPlaceNotFound
+	}
+	return r.place, nil
+}
+
+func newTestService(repo Repository) *servicePg {
+	trip := &trips.Trip{
+		ID:      tripID,
+		Title:   "Ridge loop",
+		OwnerID: ownerID,
+		Privacy: "public",
+		Collaborators: []trips.Collaborator{
+			{UserID: moderatorID, Role: "editor", CanEdit: true, CanModerateSuggestions: true},
+			{UserID: editorID, Role: "editor", CanEdit: true},
+		},
+	}
+	place := &places.Place{
+		ID:        placeID,
+		Name:      "Hut",
+		CreatedBy: ownerID,
+		Privacy:   "private",
+		Collaborators: []places.Collaborator{
+			{UserID: editorID, Role: places.RoleEditor},
+		},
+	}
+	placeRepo := &placeRepository{place: place}
+	return NewService(repo, &tripRepository{trip: trip}, placeRepo, places.NewPermissionResolver(placeRepo))
+}
+
+func pending(targetType, targetID string) *Suggestion {
+	return &Suggestion{
+		ID:          "suggestion-1",
+		TargetType:  targetType,
+		TargetID:    targetID,
+		SuggestedBy: strangerID,
+		Type:        TypeComment,
+		Status:      StatusPending,
+	}
+}
+
+func TestService_Create(t *testing.T) {
+	ctx := context.Background()
+	field, value, reason := "title", "Ridge loop (winter)", "Snow closes the pass"
+
+	t.Run("edit records the current value", func(t *testing.T) {
+		repo := &mockRepository{}
+		repo.On("Create", ctx, mock.MatchedBy(func(s *Suggestion) bool {
+			return s.Status == StatusPending && s.SuggestedBy == strangerID &&
+				s.CurrentValue != nil && *s.CurrentValue == "Ridge loop"
+		})).Return(nil)
+		repo.On("GetByID", ctx, "suggestion-1").Return(pending(TargetTrip, tripID), nil)
+
+		_, err := newTestService(repo).Create(ctx, strangerID, TargetTrip, tripID, &CreateSuggestionInput{
+			Type: TypeEdit, FieldName: &field, SuggestedValue: &value,
+		})
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("edit without a value", func(t *testing.T) {
+		_, err := newTestService(&mockRepository{}).Create(ctx, strangerID, TargetTrip, tripID, &CreateSuggestionInput{
+			Type: TypeEdit, FieldName: &field,
+		})
+		assert.ErrorIs(t, err, ErrInvalidSuggestion)
+	})
+
+	t.Run("comment without a reason", func(t *testing.T) {
+		_, err := newTestService(&mockRepository{}).Create(ctx, strangerID, TargetTrip, tripID, &CreateSuggestionInput{
+			Type: TypeComment,
+		})
+		assert.ErrorIs(t, err, ErrInvalidSuggestion)
+	})
+
+	t.Run("private place hidden from stranger", func(t *testing.T) {
+		_, err := newTestService(&mockRepository{}).Create(ctx, strangerID, TargetPlace, placeID, &CreateSuggestionInput{
+			Type: TypeDeletion, Reason: &reason,
+		})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("missing trip", func(t *testing.T) {
+		_, err := newTestService(&mockRepository{}).Create(ctx, strangerID, TargetTrip, "trip-2", &CreateSuggestionInput{
+			Type: TypeDeletion, Reason: &reason,
+		})
+		assert.ErrorIs(t, err, trips.ErrTripNotFound)
+	})
+}
+
+func TestService_Review(t *testing.T) {
+	ctx := context.Background()
+	input := &ReviewSuggestionInput{}
+
+	tests := []struct {
+		name       string
+		userID     string
+		suggestion *Suggestion
+		review     func(Service, string) (*Suggestion, error)
+		status     string
+		wantErr    error
+	}{
+		{"trip owner accepts", ownerID, pending(TargetTrip, tripID), acceptWith(input), StatusAccepted, nil},
+		{"trip moderator rejects", moderatorID, pending(TargetTrip, tripID), rejectWith(input), StatusRejected, nil},
+		{"trip editor without moderation", editorID, pending(TargetTrip, tripID), acceptWith(input), "", ErrUnauthorized},
+		{"suggester cannot accept their own", strangerID, pending(TargetTrip, tripID), acceptWith(input), "", ErrUnauthorized},
+		{"place editor accepts", editorID, pending(TargetPlace, placeID), acceptWith(input), StatusAccepted, nil},
+		{"already reviewed", ownerID, &Suggestion{ID: "suggestion-1", TargetType: TargetTrip, TargetID: tripID, Status: StatusRejected}, acceptWith(input), "", ErrAlreadyReviewed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepository{}
+			repo.On("GetByID", ctx, "suggestion-1").Return(tt.suggestion, nil)
+			if tt.wantErr == nil {
+				repo.On("Review", ctx, "suggestion-1", tt.status, tt.userID, input.Notes).Return(nil)
+			}
+
+			_, err := tt.review(newTestService(repo), tt.userID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				repo.AssertNotCalled(t, "Review", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func acceptWith(input *ReviewSuggestionInput) func(Service, string) (*Suggestion, error) {
+	return func(s Service, userID string) (*Suggestion, error) {
+		return s.Accept(context.Background(), userID, "suggestion-1", input)
+	}
+}
+
+func rejectWith(input *ReviewSuggestionInput) func(Service, string) (*Suggestion, error) {
+	return func(s Service, userID string) (*Suggestion, error) {
+		return s.Reject(context.Background(), userID, "suggestion-1", input)
+	}
+}
+
+func TestService_AddComment(t *testing.T) {
+	ctx := context.Background()
+	input := &AddCommentInput{Message: "The pass reopens in May"}
+
+	t.Run("suggester on a place they cannot see", func(t *testing.T) {
+		repo := &mockRepository{}
+		repo.On("GetByID", ctx, "suggestion-1").Return(pending(TargetPlace, placeID), nil)
+		repo.On("AddComment", ctx, mock.MatchedBy(func(c *Comment) bool {
+			return c.UserID == strangerID && c.Message == input.Message
+		})).Return(nil)
+
+		_, err := newTestService(repo).AddComment(ctx, strangerID, "suggestion-1", input)
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("someone else on a place they cannot see", func(t *testing.T) {
+		repo := &mockRepository{}
+		repo.On("GetByID", ctx, "suggestion-1").Return(pending(TargetPlace, placeID), nil)
+
+		_, err := newTestService(repo).AddComment(ctx, moderatorID, "suggestion-1", input)
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNotCalled(t, "AddComment", mock.Anything, mock.Anything)
+	})
+}
+
+func TestService_Events(t *testing.T) {
+	ctx := context.Background()
+	reason := "Snow closes the pass"
+
+	repo := &mockRepository{}
+	repo.On("Create", ctx, mock.Anything).Return(nil)
+	repo.On("GetByID", ctx, "suggestion-1").Return(pending(TargetTrip, tripID), nil)
+	repo.On("Review", ctx, "suggestion-1", StatusAccepted, ownerID, (*string)(nil)).Return(nil)
+	repo.On("AddComment", ctx, mock.Anything).Return(nil)
+
+	var published []events.Event
+	bus := events.NewLocalBus()
+	for _, eventType := range []string{events.SuggestionCreated, events.SuggestionReviewed, events.SuggestionCommented} {
+		bus.Subscribe(eventType, func(ctx context.Context, event events.Event) error {
+			published = append(published, event)
+			return nil
+		})
+	}
+	service := newTestService(repo)
+	service.SetEventBus(bus)
+
+	_, err := service.Create(ctx, strangerID, TargetTrip, tripID, &CreateSuggestionInput{Type: TypeComment, Reason: &reason})
+	require.NoError(t, err)
+	_, err = service.Accept(ctx, ownerID, "suggestion-1", &ReviewSuggestionInput{})
+	require.NoError(t, err)
+	_, err = service.AddComment(ctx, ownerID, "suggestion-1", &AddCommentInput{Message: "Thanks"})
+	require.NoError(t, err)
+
+	// Collaborators following the trip see each step
+	require.Len(t, published, 3)
+	for i, eventType := range []string{events.SuggestionCreated, events.SuggestionReviewed, events.SuggestionCommented} {
+		assert.Equal(t, eventType, published[i].Type)
+		assert.Equal(t, TargetTrip, published[i].EntityType)
+		assert.Equal(t, tripID, published[i].EntityID)
+		assert.Equal(t, "suggestion-1", published[i].Data["suggestion_id"])
+	}
+	assert.Equal(t, strangerID, published[0].ActorID)
+	assert.Equal(t, StatusAccepted, published[1].Data["status"])
+}
@@ -0,0 +1,211 @@
+package trips
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/gpx"
+)
+
+// Annotation kinds
+const (
+	AnnotationMeasurement = "measurement" // LineString, measured along its length
+	AnnotationBearing     = "bearing"     // LineString of two points
+	AnnotationLabel       = "label"       // Point with text
+	AnnotationArea        = "area"        // Polygon
+)
+
+// TripAnnotation is a planning mark on a trip's map. Unlike waypoints they
+// are not part of the route, just notes drawn over it.
+type TripAnnotation struct {
+	ID        string        `db:"id" json:"id"`
+	TripID    string        `db:"trip_id" json:"trip_id"`
+	Kind      string        `db:"kind" json:"kind"`
+	Geometry  *GeoJSONRoute `db:"geometry" json:"geometry"`
+	Label     string        `db:"label" json:"label"`
+	Style     *JSONB        `db:"style" json:"style,omitempty"`
+	CreatedBy string        `db:"created_by" json:"created_by"`
+	UpdatedBy string        `db:"updated_by" json:"updated_by"`
+	CreatedAt time.Time     `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time     `db:"updated_at" json:"updated_at"`
+
+	// Measured from the geometry whenever the annotation is read
+	LengthM    *float64 `db:"-" json:"length_m,omitempty"`
+	BearingDeg *float64 `db:"-" json:"bearing_deg,omitempty"`
+	AreaM2     *float64 `db:"-" json:"area_m2,omitempty"`
+}
+
+type CreateAnnotationInput struct {
+	Kind     string        `json:"kind" binding:"required,oneof=measurement bearing label area"`
+	Geometry *GeoJSONRoute `json:"geometry" binding:"required"`
+	Label    string        `json:"label" binding:"max=200"`
+	Style    *JSONB        `json:"style"`
+}
+
+type UpdateAnnotationInput struct {
+	Geometry *GeoJSONRoute `json:"geometry"`
+	Label    *string       `json:"label" binding:"omitempty,max=200"`
+	Style    *JSONB        `json:"style"`
+}
+
+// validate checks the geometry and label suit the kind of annotation
+func (a *TripAnnotation) validate() error {
+	if a.Geometry == nil {
+		return ErrInvalidAnnotation
+	}
+
+	switch a.Kind {
+	case AnnotationMeasurement:
+		line, ok := a.line()
+		if !ok || len(line) < 2 {
+			return ErrInvalidAnnotation
+		}
+	case AnnotationBearing:
+		line, ok := a.line()
+		if !ok || len(line) != 2 {
+			return ErrInvalidAnnotation
+		}
+	case AnnotationLabel:
+		if _, ok := a.point(); !ok || a.Label == "" {
+			return ErrInvalidAnnotation
+		}
+	case AnnotationArea:
+		ring, ok := a.ring()
+		if !ok || len(ring) < 4 {
+			return ErrInvalidAnnotation
+		}
+	default:
+		return ErrInvalidAnnotation
+	}
+
+	return nil
+}
+
+// measure sets the lengths, bearing and area the annotation shows
+func (a *TripAnnotation) measure() {
+	switch a.Kind {
+	case AnnotationMeasurement, AnnotationBearing:
+		line, ok := a.line()
+		if !ok || len(line) < 2 {
+			return
+		}
+		length := lineLength(line)
+		a.LengthM = &length
+		if a.Kind == AnnotationBearing {
+			bearing := initialBearing(line[0], line[1])
+			a.BearingDeg = &bearing
+		}
+	case AnnotationArea:
+		ring, ok := a.ring()
+		if !ok || len(ring) < 4 {
+			return
+		}
+		area := ringArea(ring)
+		a.AreaM2 = &area
+	}
+}
+
+// Coordinates arrive as untyped JSON, so they are decoded again into the
+// shape the geometry type calls for
+
+func (a *TripAnnotation) point() ([]float64, bool) {
+	var point []float64
+	if a.Geometry.Type != "Point" || !decodeCoordinates(a.Geometry.Coordinates, &point) || !validPosition(point) {
+		return nil, false
+	}
+	return point, true
+}
+
+func (a *TripAnnotation) line() ([][]float64, bool) {
+	var line [][]float64
+	if a.Geometry.Type != "LineString" || !decodeCoordinates(a.Geometry.Coordinates, &line) {
+		return nil, false
+	}
+	for _, position := range line {
+		if !validPosition(position) {
+			return nil, false
+		}
+	}
+	return line, true
+}
+
+// ring returns the outer ring of a polygon, which must be closed
+func (a *TripAnnotation) ring() ([][]float64, bool) {
+	return polygonRing(a.Geometry)
+}
+
+// polygonRing returns the outer ring of a Polygon geometry, which must be
+// closed
+func polygonRing(geometry *GeoJSONRoute) ([][]float64, bool) {
+	var rings [][][]float64
+	if geometry.Type != "Polygon" || !decodeCoordinates(geometry.Coordinates, &rings) || len(rings) == 0 {
+		return nil, false
+	}
+	ring := rings[0]
+	for _, position := range ring {
+		if !validPosition(position) {
+			return nil, false
+		}
+	}
+	if len(ring) > 0 && (ring[0][0] != ring[len(ring)-1][0] || ring[0][1] != ring[len(ring)-1][1]) {
+		return nil, false
+	}
+	return ring, true
+}
+
+func decodeCoordinates(coordinates interface{}, dst interface{}) bool {
+	data, err := json.Marshal(coordinates)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, dst) == nil
+}
+
+// validPosition reports whether a GeoJSON position is a longitude and
+// latitude on the earth
+func validPosition(position []float64) bool {
+	return len(position) >= 2 &&
+		position[0] >= -180 && position[0] <= 180 &&
+		position[1] >= -90 && position[1] <= 90
+}
+
+// lineLength is the great-circle length of a line, in metres
+func lineLength(line [][]float64) float64 {
+	length := 0.0
+	for i := 1; i < len(line); i++ {
+		length += haversine(line[i-1], line[i])
+	}
+	return length
+}
+
+// haversine is the great-circle distance between two [longitude, latitude]
+// positions, in metres
+func haversine(from, to []float64) float64 {
+	return gpx.Haversine(gpx.Point{Lat: from[1], Lon: from[0]}, gpx.Point{Lat: to[1], Lon: to[0]})
+}
+
+// initialBearing is the compass bearing from one point towards another, in
+// degrees clockwise from true north
+func initialBearing(from, to []float64) float64 {
+	lat1, lat2 := radians(from[1]), radians(to[1])
+	dLng := radians(to[0] - from[0])
+	y := math.Sin(dLng) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLng)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+// ringArea is the area enclosed by a closed ring on the sphere, in square
+// metres
+func ringArea(ring [][]float64) float64 {
+	total := 0.0
+	for i := 0; i < len(ring)-1; i++ {
+		p1, p2 := ring[i], ring[i+1]
+		total += radians(p2[0]-p1[0]) * (2 + math.Sin(radians(p1[1])) + math.Sin(radians(p2[1])))
+	}
+	return math.Abs(total * gpx.EarthRadiusM * gpx.EarthRadiusM / 2)
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
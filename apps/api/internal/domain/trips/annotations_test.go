@@ -0,0 +1,102 @@
+package trips
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/gpx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTripAnnotation_Validate(t *testing.T) {
+	line := func(points ...[]float64) *GeoJSONRoute {
+		return &GeoJSONRoute{Type: "LineString", Coordinates: points}
+	}
+	square := &GeoJSONRoute{Type: "Polygon", Coordinates: [][][]float64{{{7, 46}, {7.1, 46}, {7.1, 46.1}, {7, 46.1}, {7, 46}}}}
+
+	tests := []struct {
+		name       string
+		annotation TripAnnotation
+		valid      bool
+	}{
+		{"measurement", TripAnnotation{Kind: AnnotationMeasurement, Geometry: line([]float64{7, 46}, []float64{7.1, 46}, []float64{7.2, 46})}, true},
+		{"measurement of one point", TripAnnotation{Kind: AnnotationMeasurement, Geometry: line([]float64{7, 46})}, false},
+		{"bearing", TripAnnotation{Kind: AnnotationBearing, Geometry: line([]float64{7, 46}, []float64{7.1, 46})}, true},
+		{"bearing of three points", TripAnnotation{Kind: AnnotationBearing, Geometry: line([]float64{7, 46}, []float64{7.1, 46}, []float64{7.2, 46})}, false},
+		{"label", TripAnnotation{Kind: AnnotationLabel, Label: "Spring", Geometry: &GeoJSONRoute{Type: "Point", Coordinates: []float64{7, 46}}}, true},
+		{"label without text", TripAnnotation{Kind: AnnotationLabel, Geometry: &GeoJSONRoute{Type: "Point", Coordinates: []float64{7, 46}}}, false},
+		{"label off the earth", TripAnnotation{Kind: AnnotationLabel, Label: "Spring", Geometry: &GeoJSONRoute{Type: "Point", Coordinates: []float64{200, 46}}}, false},
+		{"area", TripAnnotation{Kind: AnnotationArea, Geometry: square}, true},
+		{"open area", TripAnnotation{Kind: AnnotationArea, Geometry: &GeoJSONRoute{Type: "Polygon", Coordinates: [][][]float64{{{7, 46}, {7.1, 46}, {7.1, 46.1}, {7, 46.1}}}}}, false},
+		{"area drawn as a line", TripAnnotation{Kind: AnnotationArea, Geometry: line([]float64{7, 46}, []float64{7.1, 46})}, false},
+		{"no geometry", TripAnnotation{Kind: AnnotationMeasurement}, false},
+		{"unknown kind", TripAnnotation{Kind: "circle", Geometry: line([]float64{7, 46}, []float64{7.1, 46})}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.annotation.validate()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidAnnotation)
+			}
+		})
+	}
+}
+
+func TestTripAnnotation_Measure(t *testing.T) {
+	// A degree along a great circle
+	degreeM := gpx.EarthRadiusM * math.Pi / 180
+
+	t.Run("measurement", func(t *testing.T) {
+		annotation := &TripAnnotation{Kind: AnnotationMeasurement, Geometry: &GeoJSONRoute{
+			Type: "LineString", Coordinates: []interface{}{[]interface{}{0.0, 0.0}, []interface{}{0.0, 1.0}, []interface{}{1.0, 1.0}},
+		}}
+		annotation.measure()
+		require.NotNil(t, annotation.LengthM)
+		assert.InEpsilon(t, degreeM+degreeM*math.Cos(math.Pi/180), *annotation.LengthM, 1e-4)
+		assert.Nil(t, annotation.BearingDeg)
+		assert.Nil(t, annotation.AreaM2)
+	})
+
+	t.Run("bearing", func(t *testing.T) {
+		tests := []struct {
+			to      []float64
+			bearing float64
+		}{
+			{[]float64{0, 1}, 0},
+			{[]float64{1, 0}, 90},
+			{[]float64{0, -1}, 180},
+			{[]float64{-1, 0}, 270},
+		}
+		for _, tt := range tests {
+			annotation := &TripAnnotation{Kind: AnnotationBearing, Geometry: &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{0, 0}, tt.to}}}
+			annotation.measure()
+			require.NotNil(t, annotation.BearingDeg)
+			assert.InDelta(t, tt.bearing, *annotation.BearingDeg, 1e-9)
+			assert.InEpsilon(t, degreeM, *annotation.LengthM, 1e-9)
+		}
+	})
+
+	t.Run("area", func(t *testing.T) {
+		annotation := &TripAnnotation{Kind: AnnotationArea, Geometry: &GeoJSONRoute{
+			Type: "Polygon", Coordinates: [][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}},
+		}}
+		annotation.measure()
+		require.NotNil(t, annotation.AreaM2)
+		// The area between two meridians and two parallels on the sphere
+		want := gpx.EarthRadiusM * gpx.EarthRadiusM * (math.Pi / 180) * math.Sin(math.Pi/180)
+		assert.InEpsilon(t, want, *annotation.AreaM2, 1e-6)
+		assert.Nil(t, annotation.LengthM)
+	})
+
+	t.Run("labels are not measured", func(t *testing.T) {
+		annotation := &TripAnnotation{Kind: AnnotationLabel, Label: "Spring", Geometry: &GeoJSONRoute{Type: "Point", Coordinates: []float64{7, 46}}}
+		annotation.measure()
+		assert.Nil(t, annotation.LengthM)
+		assert.Nil(t, annotation.BearingDeg)
+		assert.Nil(t, annotation.AreaM2)
+	})
+}
@@ -0,0 +1,159 @@
+package trips
+
+import (
+	"context"
+	"math"
+)
+
+// Audiences a trip is shown to other than its owner. Collaborators see the
+// whole trip; friends of the owner and the public see less of it, so what a
+// trip gives away about where its people live and when they are away stays
+// with the people on it.
+const (
+	AudiencePublic       = "public"
+	AudienceFriend       = "friend"
+	AudienceCollaborator = "collaborator"
+)
+
+// fuzzedPrecision is how many decimals the coordinates of private places keep
+// for friends, about a kilometre
+const fuzzedPrecision = 2
+
+// ValidAudience reports whether an audience is one a trip can be shown to
+func ValidAudience(audience string) bool {
+	switch audience {
+	case AudiencePublic, AudienceFriend, AudienceCollaborator:
+		return true
+	}
+	return false
+}
+
+// VisibleToAudience reports whether the audience may see the trip at all.
+// As with VisibleTo, only collaborators see trips that are not public.
+func (t *Trip) VisibleToAudience(audience string) bool {
+	switch audience {
+	case AudienceCollaborator:
+		return true
+	case AudienceFriend, AudiencePublic:
+		return t.Privacy == PrivacyPublic
+	}
+	return false
+}
+
+// ForAudience returns a copy of the trip as the audience sees it. Neither
+// friends nor the public see emergency contacts, who the trip is shared with
+// or when it is to be published. Waypoints at private places are shown to
+// friends about a kilometre off and without their street address, and hidden
+// from the public, who also see no waypoint notes or times, nor the
+// collaborators. The trip itself is left as it is.
+func (t *Trip) ForAudience(audience string) *Trip {
+	view := *t
+	if audience == AudienceCollaborator {
+		return &view
+	}
+
+	view.EmergencyContacts = nil
+	view.SharedWith = nil
+	view.PublishAt = nil
+	if audience == AudiencePublic {
+		view.Collaborators = nil
+	}
+
+	view.Waypoints = nil
+	for _, waypoint := range t.Waypoints {
+		private := waypoint.Place != nil && waypoint.Place.Privacy == PrivacyPrivate
+		if audience == AudiencePublic {
+			if private {
+				continue
+			}
+			waypoint.Notes = ""
+			waypoint.NotesRendering = nil
+			waypoint.ArrivalTime = nil
+			waypoint.DepartureTime = nil
+		} else if private {
+			waypoint.Place = waypoint.Place.fuzzed()
+		}
+		view.Waypoints = append(view.Waypoints, waypoint)
+	}
+
+	return &view
+}
+
+// fuzzed returns a copy of the place without its street address and with its
+// location rounded
+func (p *Place) fuzzed() *Place {
+	place := *p
+	place.Address = ""
+	if p.Location != nil {
+		coordinates := make([]float64, len(p.Location.Coordinates))
+		scale := math.Pow(10, fuzzedPrecision)
+		for i, c := range p.Location.Coordinates {
+			coordinates[i] = math.Round(c*scale) / scale
+		}
+		place.Location = &GeoJSON{Type: p.Location.Type, Coordinates: coordinates}
+	}
+	return &place
+}
+
+// AudienceOf returns the audience a user sees a trip as, empty for its owner,
+// who sees all of it. The trip must be loaded with its collaborators.
+func (s *servicePg) AudienceOf(ctx context.Context, userID string, trip *Trip) string {
+	switch {
+	case trip.IsOwner(userID):
+		return ""
+	case trip.HasCollaborator(userID):
+		return AudienceCollaborator
+	case s.isFriend(ctx, trip.OwnerID, userID):
+		return AudienceFriend
+	}
+	return AudiencePublic
+}
+
+// TripPreview is a trip as an audience would see it
+type TripPreview struct {
+	Audience string `json:"audience"`
+	Visible  bool   `json:"visible"` // Whether the audience can see the trip as its privacy is now
+	Trip     *Trip  `json:"trip"`
+}
+
+// ViewAs shows the owner the trip as an audience would see it, so they can
+// check what they share before they publish it. Trips the audience cannot
+// see yet are shown as they would be once it can.
+func (s *servicePg) ViewAs(ctx context.Context, userID, tripID, audience string, relations Relations) (*TripPreview, error) {
+	if !ValidAudience(audience) {
+		return nil, ErrInvalidAudience
+	}
+
+	trip, err := s.GetByIDWith(ctx, userID, tripID, relations)
+	if err != nil {
+		return nil, err
+	}
+	if !trip.IsOwner(userID) {
+		return nil, ErrUnauthorized
+	}
+
+	return &TripPreview{
+		Audience: audience,
+		Visible:  trip.VisibleToAudience(audience),
+		Trip:     trip.ForAudience(audience),
+	}, nil
+}
+
+// isFriend reports whether the user is a friend of the owner. A failed
+// lookup counts as not, so the trip is shown as the public sees it.
+func (s *servicePg) isFriend(ctx context.Context, ownerID, userID string) bool {
+	if s.userRepo == nil || userID == "" {
+		return false
+	}
+
+	friends, err := s.userRepo.GetFriends(ctx, ownerID)
+	if err != nil {
+		return false
+	}
+	for _, friend := range friends {
+		if friend.ID == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,371 @@
+package trips
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Oferzz/newMap/apps/api/internal/cache"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+)
+
+// JobTagTrip suggests tags for a trip from its route after it changes
+const JobTagTrip = "trip.autotag"
+
+// Tags suggested from a trip's route
+const (
+	TagLoop         = "loop"
+	TagOutAndBack   = "out-and-back"
+	TagPointToPoint = "point-to-point"
+	TagSummit       = "summit"
+	TagRiverside    = "riverside"
+)
+
+// Categories of the places that make a route a summit or riverside one
+var (
+	PeakCategories     = []string{"peak", "summit"}
+	WaterwayCategories = []string{"river", "stream", "lake"}
+)
+
+const (
+	// loopClosureM is how near its start a route has to end to come back
+	// round, or a share of its length for long routes
+	loopClosureM     = 250.0
+	loopClosureShare = 0.02
+
+	// retraceToleranceM is how near the way out the way back has to keep to
+	// retrace it, at least the spacing of the samples compared, and
+	// retracedShare how much of the way back has to
+	retraceToleranceM = 60.0
+	retracedShare     = 0.7
+	shapeSamples      = 100
+
+	// summitRadiusM is how near a peak the route's high point has to be
+	summitRadiusM = 200.0
+
+	// riversideCorridorM is how near a waterway the route has to keep, for
+	// at least riversideShare of its length, to hug it
+	riversideCorridorM = 150.0
+	riversideShare     = 0.4
+)
+
+// autotagFields are the fields of a trip its suggested tags are worked out
+// from. Its tags are among them, since those are not suggested again.
+var autotagFields = map[string]bool{
+	"route_geojson": true,
+	"tags":          true,
+}
+
+var ErrTagNotSuggested = errors.New("tag is not suggested for this trip")
+
+// SuggestedTagsInput names suggested tags to accept or dismiss
+type SuggestedTagsInput struct {
+	Tags []string `json:"tags" binding:"required,min=1,max=20,dive,required,max=50"`
+}
+
+// RouteShape tells a loop from an out-and-back and a point-to-point route by
+// its geometry, or is empty when it has no line to follow. A route that ends
+// near its start is an out-and-back when the way back retraces the way out.
+func RouteShape(route *GeoJSONRoute) string {
+	lengthKm, ok := routeLengthKm(route)
+	if !ok || lengthKm == 0 {
+		return ""
+	}
+
+	lines := routeLines(route)
+	last := lines[len(lines)-1]
+	start, end := lines[0][0], last[len(last)-1]
+	if haversine(start, end) > math.Max(loopClosureM, lengthKm*1000*loopClosureShare) {
+		return TagPointToPoint
+	}
+
+	samples := sampleRoute(lines, shapeSamples)
+	tolerance := math.Max(retraceToleranceM, lengthKm*1000/float64(len(samples)-1))
+	half := len(samples) / 2
+	retraced := 0
+	for _, back := range samples[half:] {
+		for _, out := range samples[:half+1] {
+			if haversine([]float64{back.Longitude, back.Latitude}, []float64{out.Longitude, out.Latitude}) <= tolerance {
+				retraced++
+				break
+			}
+		}
+	}
+
+	if float64(retraced)/float64(len(samples)-half) >= retracedShare {
+		return TagOutAndBack
+	}
+	return TagLoop
+}
+
+// highPoint is the highest position of a route whose positions have
+// elevations, reporting false when none do
+func highPoint(route *GeoJSONRoute) ([]float64, bool) {
+	var best []float64
+	for _, line := range routeLines(route) {
+		for _, position := range line {
+			if len(position) >= 3 && validPosition(position) && (best == nil || position[2] > best[2]) {
+				best = position
+			}
+		}
+	}
+	return best, best != nil
+}
+
+type tagPayload struct {
+	TripID string `json:"trip_id"`
+}
+
+// Tagger suggests tags for trips from their routes: the shape of the route,
+// whether its high point is a summit and whether it follows a river or
+// lakeshore. Suggestions wait for the trip's owner to accept them.
+type Tagger struct {
+	repo     Repository
+	queue    jobs.Queue
+	bus      events.Bus
+	profiler *ElevationProfiler
+}
+
+// NewTagger creates a tagger and registers its job handler. Changed
+// suggestions are announced on bus as an invalidation of the trip.
+func NewTagger(repo Repository, queue jobs.Queue, bus events.Bus) *Tagger {
+	t := &Tagger{
+		repo:  repo,
+		queue: queue,
+		bus:   bus,
+	}
+
+	queue.Register(JobTagTrip, t.tag)
+
+	return t
+}
+
+// SetElevationProfiler finds the high point of routes drawn without
+// elevations from an elevation model. Without one, only routes with
+// elevations can be suggested as summits.
+func (t *Tagger) SetElevationProfiler(profiler *ElevationProfiler) {
+	t.profiler = profiler
+}
+
+// HandleTripCreated queues tag suggestions for a new trip
+func (t *Tagger) HandleTripCreated(ctx context.Context, event events.Event) error {
+	return t.Queue(ctx, event.EntityID)
+}
+
+// HandleTripUpdated queues new tag suggestions for a trip whose route or
+// tags changed
+func (t *Tagger) HandleTripUpdated(ctx context.Context, event events.Event) error {
+	if !changesAny(event.Data["fields"], autotagFields) {
+		return nil
+	}
+	return t.Queue(ctx, event.EntityID)
+}
+
+// Queue queues tag suggestions for the trip
+func (t *Tagger) Queue(ctx context.Context, tripID string) error {
+	if _, err := t.queue.Enqueue(ctx, JobTagTrip, tagPayload{TripID: tripID}); err != nil {
+		return fmt.Errorf("failed to queue trip tagging: %w", err)
+	}
+	return nil
+}
+
+// Classify works out the tags the trip's route calls for, whether or not
+// the trip has them already
+func (t *Tagger) Classify(ctx context.Context, trip *Trip) ([]string, error) {
+	route := trip.RouteGeoJSON
+	shape := RouteShape(route)
+	if shape == "" {
+		return []string{}, nil
+	}
+	tags := []string{shape}
+
+	summit, err := t.reachesSummit(ctx, route)
+	if err != nil {
+		return nil, err
+	}
+	if summit {
+		tags = append(tags, TagSummit)
+	}
+
+	share, err := t.repo.RouteShareNear(ctx, route, riversideCorridorM, WaterwayCategories)
+	if err != nil {
+		return nil, err
+	}
+	if share >= riversideShare {
+		tags = append(tags, TagRiverside)
+	}
+
+	return tags, nil
+}
+
+// reachesSummit reports whether the route's high point is near a peak. The
+// high point is taken from the route's own elevations, or else from the
+// elevation profiler.
+func (t *Tagger) reachesSummit(ctx context.Context, route *GeoJSONRoute) (bool, error) {
+	top, ok := highPoint(route)
+	if !ok {
+		if t.profiler == nil {
+			return false, nil
+		}
+		profile, err := t.profiler.Profile(ctx, route)
+		if err != nil {
+			return false, fmt.Errorf("failed to profile route: %w", err)
+		}
+		for _, sample := range profile.Points {
+			if top == nil || sample.ElevationM > top[2] {
+				top = []float64{sample.Longitude, sample.Latitude, sample.ElevationM}
+			}
+		}
+		if top == nil {
+			return false, nil
+		}
+	}
+
+	peaks, err := t.repo.CountPlacesNear(ctx, top, summitRadiusM, PeakCategories)
+	if err != nil {
+		return false, err
+	}
+	return peaks > 0, nil
+}
+
+// tag is the job handler for JobTagTrip. Tags the trip has or its owner
+// dismissed are not suggested, and the suggestions are only saved when they
+// changed.
+func (t *Tagger) tag(ctx context.Context, job *jobs.Job) error {
+	var payload tagPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	trip, err := t.repo.GetByIDWith(ctx, payload.TripID, Relations{Collaborators: true})
+	if err != nil {
+		if errors.Is(err, ErrTripNotFound) {
+			// Deleted since the job was queued
+			return nil
+		}
+		return err
+	}
+
+	tags, err := t.Classify(ctx, trip)
+	if err != nil {
+		return fmt.Errorf("failed to tag trip %s: %w", trip.ID, err)
+	}
+
+	suggested := withoutTags(tags, trip.Tags, trip.DismissedTags)
+	if sameTags(suggested, trip.SuggestedTags) {
+		return nil
+	}
+
+	if err := t.repo.SetSuggestedTags(ctx, trip.ID, suggested, trip.DismissedTags); err != nil {
+		return err
+	}
+
+	invalidation := cache.TripInvalidation{TripID: trip.ID, UserIDs: tripMembers(trip)}
+	return t.bus.Publish(ctx, invalidation.Event(""))
+}
+
+// AcceptSuggestedTags adds suggested tags to the trip's tags. Only its owner
+// can accept them.
+func (s *servicePg) AcceptSuggestedTags(ctx context.Context, userID, tripID string, input *SuggestedTagsInput) (*Trip, error) {
+	trip, err := s.suggestedTagsOf(ctx, userID, tripID, input.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := append([]string{}, trip.Tags...)
+	tags = append(tags, withoutTags(input.Tags, trip.Tags)...)
+	suggested := withoutTags(trip.SuggestedTags, input.Tags)
+
+	updates := map[string]interface{}{
+		"tags":           tags,
+		"suggested_tags": suggested,
+	}
+	if err := s.repo.Update(ctx, tripID, updates); err != nil {
+		return nil, fmt.Errorf("failed to update trip: %w", err)
+	}
+
+	trip.Tags = tags
+	trip.SuggestedTags = suggested
+	s.announce(ctx, events.TripUpdated, tripID, userID, map[string]interface{}{"fields": []string{"suggested_tags", "tags"}})
+
+	return trip, nil
+}
+
+// DismissSuggestedTags drops suggested tags, which are not suggested for the
+// trip again. Only its owner can dismiss them.
+func (s *servicePg) DismissSuggestedTags(ctx context.Context, userID, tripID string, input *SuggestedTagsInput) (*Trip, error) {
+	trip, err := s.suggestedTagsOf(ctx, userID, tripID, input.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	suggested := withoutTags(trip.SuggestedTags, input.Tags)
+	dismissed := append([]string{}, trip.DismissedTags...)
+	dismissed = append(dismissed, withoutTags(input.Tags, trip.DismissedTags)...)
+	if err := s.repo.SetSuggestedTags(ctx, tripID, suggested, dismissed); err != nil {
+		return nil, err
+	}
+
+	trip.SuggestedTags = suggested
+	trip.DismissedTags = dismissed
+
+	return trip, nil
+}
+
+// suggestedTagsOf loads a trip for its owner, failing with
+// ErrTagNotSuggested when any of the tags is not suggested for it
+func (s *servicePg) suggestedTagsOf(ctx context.Context, userID, tripID string, tags []string) (*Trip, error) {
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !trip.IsOwner(userID) {
+		return nil, ErrUnauthorized
+	}
+
+	if remaining := withoutTags(tags, trip.SuggestedTags); len(remaining) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTagNotSuggested, remaining[0])
+	}
+
+	return trip, nil
+}
+
+// withoutTags is the tags that are in none of the excluded lists, each once,
+// in their order
+func withoutTags(tags []string, excluded ...[]string) []string {
+	skip := make(map[string]bool)
+	for _, list := range excluded {
+		for _, tag := range list {
+			skip[tag] = true
+		}
+	}
+
+	kept := []string{}
+	for _, tag := range tags {
+		if !skip[tag] {
+			skip[tag] = true
+			kept = append(kept, tag)
+		}
+	}
+	return kept
+}
+
+// sameTags reports whether two lists hold the same tags, in any order
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]string{}, a...), append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
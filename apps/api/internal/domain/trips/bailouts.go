@@ -0,0 +1,123 @@
+package trips
+
+import (
+	"math"
+	"sort"
+
+	"github.com/lib/pq"
+)
+
+// Bail-out corridor widths, in metres either side of the route
+const (
+	DefaultBailoutCorridorM = 1000
+	MaxBailoutCorridorM     = 5000
+)
+
+// BailoutCategories are the place categories a route can be left at: roads
+// and the trailheads, car parks and stops that reach one
+var BailoutCategories = []string{"road", "trailhead", "parking", "transit_stop"}
+
+// BailoutPoint is a place near a trip's route where it can be left early.
+// For roads the location is the point of the road nearest the route.
+type BailoutPoint struct {
+	PlaceID            string         `db:"place_id" json:"place_id"`
+	Name               string         `db:"name" json:"name"`
+	Category           pq.StringArray `db:"category" json:"category"`
+	Location           *GeoJSON       `db:"location" json:"location"`
+	DistanceFromRouteM float64        `db:"distance_m" json:"distance_from_route_m"`
+	RouteKm            float64        `db:"-" json:"route_km"`              // How far along the route it is reached
+	WaypointID         string         `db:"-" json:"waypoint_id,omitempty"` // Set once attached to the trip
+}
+
+type BailoutQuery struct {
+	CorridorM float64 `form:"corridor_m" binding:"omitempty,min=50,max=5000"`
+}
+
+type AttachBailoutInput struct {
+	PlaceID string `json:"place_id" binding:"required,uuid"`
+	Notes   string `json:"notes" binding:"max=1000"`
+}
+
+// followedRoute is the line the trip follows, which bail-outs and water
+// sources are looked for along: its drawn route, or else the line through
+// its stops
+func followedRoute(trip *Trip) (*GeoJSONRoute, bool) {
+	if _, ok := routeLengthKm(trip.RouteGeoJSON); ok {
+		return trip.RouteGeoJSON, true
+	}
+
+	return stopsRoute(trip)
+}
+
+// stopsRoute is the line through the trip's located stops, in order
+func stopsRoute(trip *Trip) (*GeoJSONRoute, bool) {
+	line := [][]float64{}
+	for _, waypoint := range trip.Waypoints {
+		if waypoint.Kind == WaypointBailout || waypoint.Place == nil || waypoint.Place.Location == nil {
+			continue
+		}
+		line = append(line, waypoint.Place.Location.Coordinates)
+	}
+	route := &GeoJSONRoute{Type: "LineString", Coordinates: line}
+	if _, ok := routeLengthKm(route); !ok {
+		return nil, false
+	}
+
+	return route, true
+}
+
+// placeBailouts sets how far along the route each point is reached and
+// orders them from the start of the route
+func placeBailouts(route *GeoJSONRoute, points []*BailoutPoint) {
+	lines := routeLines(route)
+	for _, point := range points {
+		if point.Location != nil {
+			point.RouteKm = math.Round(alongRouteM(lines, point.Location.Coordinates)/10) / 100
+		}
+	}
+
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].RouteKm < points[j].RouteKm
+	})
+}
+
+// routeLines decodes the lines of a LineString or MultiLineString route
+func routeLines(route *GeoJSONRoute) [][][]float64 {
+	if route.Type == "MultiLineString" {
+		var lines [][][]float64
+		decodeCoordinates(route.Coordinates, &lines)
+		return lines
+	}
+
+	var line [][]float64
+	decodeCoordinates(route.Coordinates, &line)
+	return [][][]float64{line}
+}
+
+// alongRouteM is how far along the route, in metres, its nearest point to
+// the position lies. Each segment is projected onto a flat plane around its
+// start, which is close enough over the length of a segment.
+func alongRouteM(lines [][][]float64, position []float64) float64 {
+	best, bestAlong := math.Inf(1), 0.0
+	travelled := 0.0
+	for _, line := range lines {
+		for i := 1; i < len(line); i++ {
+			a, b := line[i-1], line[i]
+			scale := math.Cos(radians(a[1]))
+			bx, by := (b[0]-a[0])*scale, b[1]-a[1]
+			px, py := (position[0]-a[0])*scale, position[1]-a[1]
+
+			t := 0.0
+			if length := bx*bx + by*by; length > 0 {
+				t = math.Max(0, math.Min(1, (px*bx+py*by)/length))
+			}
+			if d := math.Hypot(px-t*bx, py-t*by); d < best {
+				best = d
+				bestAlong = travelled + t*haversine(a, b)
+			}
+			travelled += haversine(a, b)
+		}
+	}
+
+	return bestAlong
+}
@@ -0,0 +1,135 @@
+package trips
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollowedRoute(t *testing.T) {
+	located := func(id string, lng, lat float64) Waypoint {
+		return Waypoint{PlaceID: id, Kind: WaypointStop, Place: &Place{ID: id, Location: &GeoJSON{Type: "Point", Coordinates: []float64{lng, lat}}}}
+	}
+
+	t.Run("the drawn route", func(t *testing.T) {
+		drawn := &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{7, 46}, {7, 46.1}}}
+		route, ok := followedRoute(&Trip{RouteGeoJSON: drawn, Waypoints: []Waypoint{located("a", 8, 47), located("b", 8, 48)}})
+		require.True(t, ok)
+		assert.Same(t, drawn, route)
+	})
+
+	t.Run("the line through the stops", func(t *testing.T) {
+		bailout := located("road", 9, 49)
+		bailout.Kind = WaypointBailout
+		trip := &Trip{
+			RouteGeoJSON: &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{7, 46}}},
+			Waypoints:    []Waypoint{located("a", 7, 46), bailout, {PlaceID: "unlocated", Place: &Place{}}, located("b", 7, 46.1)},
+		}
+
+		route, ok := followedRoute(trip)
+		require.True(t, ok)
+		assert.Equal(t, "LineString", route.Type)
+		assert.Equal(t, [][]float64{{7, 46}, {7, 46.1}}, route.Coordinates)
+	})
+
+	t.Run("fewer than two located stops", func(t *testing.T) {
+		_, ok := followedRoute(&Trip{Waypoints: []Waypoint{located("a", 7, 46), {PlaceID: "b"}}})
+		assert.False(t, ok)
+	})
+}
+
+func TestPlaceBailouts(t *testing.T) {
+	point := func(id string, lng, lat float64) *BailoutPoint {
+		return &BailoutPoint{PlaceID: id, Location: &GeoJSON{Type: "Point", Coordinates: []float64{lng, lat}}}
+	}
+
+	t.Run("ordered along a line", func(t *testing.T) {
+		route := &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{0, 0}, {0, 0.1}, {0, 0.2}}}
+		points := []*BailoutPoint{point("end", 0.01, 0.2), point("start", -0.01, 0), point("middle", 0.005, 0.15), {PlaceID: "unlocated"}}
+
+		placeBailouts(route, points)
+
+		var ids []string
+		var km []float64
+		for _, p := range points {
+			ids = append(ids, p.PlaceID)
+			km = append(km, p.RouteKm)
+		}
+		assert.Equal(t, []string{"start", "unlocated", "middle", "end"}, ids)
+		assert.Equal(t, []float64{0, 0, 16.68, 22.24}, km)
+	})
+
+	t.Run("lines of a multi-line are travelled in turn", func(t *testing.T) {
+		route := &GeoJSONRoute{Type: "MultiLineString", Coordinates: [][][]float64{{{0, 0}, {0, 0.1}}, {{1, 0}, {1, 0.1}}}}
+		points := []*BailoutPoint{point("second", 1, 0.05), point("first", 0, 0.05)}
+
+		placeBailouts(route, points)
+
+		assert.Equal(t, "first", points[0].PlaceID)
+		assert.Equal(t, 5.56, points[0].RouteKm)
+		assert.Equal(t, "second", points[1].PlaceID)
+		assert.Equal(t, 16.68, points[1].RouteKm)
+	})
+}
+
+func TestService_GetBailouts(t *testing.T) {
+	ctx := context.Background()
+	withRoute := func() *Trip {
+		trip := privateTrip()
+		trip.RouteGeoJSON = &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{0, 0}, {0, 0.1}}}
+		trip.Waypoints = []Waypoint{{ID: "w1", PlaceID: "road", Kind: WaypointBailout}}
+		return trip
+	}
+
+	tests := []struct {
+		name     string
+		query    *BailoutQuery
+		corridor float64
+	}{
+		{"default corridor", nil, DefaultBailoutCorridorM},
+		{"requested corridor", &BailoutQuery{CorridorM: 200}, 200},
+		{"corridor is capped", &BailoutQuery{CorridorM: 20000}, MaxBailoutCorridorM},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockRepository)
+			service := NewService(repo, nil, nil)
+			trip := withRoute()
+			repo.On("GetByID", ctx, tripID).Return(trip, nil).Once()
+			repo.On("FindBailoutPlaces", ctx, trip.RouteGeoJSON, tt.corridor, BailoutCategories).Return([]*BailoutPoint{
+				{PlaceID: "road", Location: &GeoJSON{Type: "Point", Coordinates: []float64{0.01, 0.1}}},
+				{PlaceID: "trailhead", Location: &GeoJSON{Type: "Point", Coordinates: []float64{0, 0}}},
+			}, nil).Once()
+
+			points, err := service.GetBailouts(ctx, viewerID, tripID, tt.query)
+			require.NoError(t, err)
+			require.Len(t, points, 2)
+			assert.Equal(t, "trailhead", points[0].PlaceID)
+			assert.Empty(t, points[0].WaypointID)
+			// Already attached as a waypoint
+			assert.Equal(t, "w1", points[1].WaypointID)
+			repo.AssertExpectations(t)
+		})
+	}
+
+	t.Run("no route to follow", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(privateTrip(), nil).Once()
+
+		_, err := service.GetBailouts(ctx, ownerID, tripID, nil)
+		assert.ErrorIs(t, err, ErrNoRouteGeometry)
+	})
+
+	t.Run("hidden from strangers", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(withRoute(), nil).Once()
+
+		_, err := service.GetBailouts(ctx, "stranger", tripID, nil)
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+}
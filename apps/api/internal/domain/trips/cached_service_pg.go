@@ -3,23 +3,29 @@ package trips
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/Oferzz/newMap/apps/api/internal/cache"
+	"github.com/Oferzz/newMap/apps/api/internal/database"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
 )
 
 type cachedServicePg struct {
 	service Service
 	cache   cache.Cache
+	bus     events.Bus
 }
 
-// NewCachedServicePg creates a new cached trip service for PostgreSQL
-func NewCachedServicePg(service Service, cache cache.Cache) Service {
+// NewCachedServicePg creates a new cached trip service for PostgreSQL.
+// Invalidations are published on bus so that every cache holding a changed
+// trip drops it, not only the entries this service knows about.
+func NewCachedServicePg(service Service, cache cache.Cache, bus events.Bus) Service {
 	return &cachedServicePg{
 		service: service,
 		cache:   cache,
+		bus:     bus,
 	}
 }
 
@@ -29,6 +35,8 @@ func (c *cachedServicePg) Create(ctx context.Context, userID string, input *Crea
 		return nil, err
 	}
 
+	c.invalidate(ctx, userID, trip.ID, tripMembers(trip))
+
 	// Cache the new trip
 	if err := c.cacheTrip(ctx, trip); err != nil {
 		// Log cache error but don't fail the operation
@@ -48,7 +56,7 @@ func (c *cachedServicePg) GetByID(ctx context.Context, userID, tripID string) (*
 			if c.canUserAccessTrip(&trip, userID) {
 				return &trip, nil
 			}
-			return nil, errors.New("unauthorized")
+			return nil, ErrUnauthorized
 		}
 	}
 
@@ -66,16 +74,39 @@ func (c *cachedServicePg) GetByID(ctx context.Context, userID, tripID string) (*
 	return trip, nil
 }
 
+func (c *cachedServicePg) GetByIDWith(ctx context.Context, userID, tripID string, relations Relations) (*Trip, error) {
+	// Only complete trips are cached
+	if relations == AllRelations {
+		return c.GetByID(ctx, userID, tripID)
+	}
+
+	data, err := c.cache.GetTrip(ctx, tripID)
+	if err == nil && data != nil {
+		var trip Trip
+		if err := json.Unmarshal(data, &trip); err == nil {
+			if !c.canUserAccessTrip(&trip, userID) {
+				return nil, ErrUnauthorized
+			}
+			if !relations.Collaborators {
+				trip.Collaborators = nil
+			}
+			if !relations.Waypoints {
+				trip.Waypoints = nil
+			}
+			return &trip, nil
+		}
+	}
+
+	return c.service.GetByIDWith(ctx, userID, tripID, relations)
+}
+
 func (c *cachedServicePg) Update(ctx context.Context, userID, tripID string, input *UpdateTripInput) (*Trip, error) {
 	trip, err := c.service.Update(ctx, userID, tripID, input)
 	if err != nil {
 		return nil, err
 	}
 
-	// Invalidate cache
-	if err := c.cache.DeleteTrip(ctx, tripID); err != nil {
-		fmt.Printf("Failed to invalidate trip cache: %v\n", err)
-	}
+	c.invalidate(ctx, userID, tripID, tripMembers(trip))
 
 	// Cache the updated trip
 	if err := c.cacheTrip(ctx, trip); err != nil {
@@ -86,14 +117,12 @@ func (c *cachedServicePg) Update(ctx context.Context, userID, tripID string, inp
 }
 
 func (c *cachedServicePg) Delete(ctx context.Context, userID, tripID string) error {
+	members := c.members(ctx, userID, tripID)
 	if err := c.service.Delete(ctx, userID, tripID); err != nil {
 		return err
 	}
 
-	// Invalidate cache
-	if err := c.cache.InvalidateTripRelated(ctx, tripID); err != nil {
-		fmt.Printf("Failed to invalidate trip cache: %v\n", err)
-	}
+	c.invalidate(ctx, userID, tripID, members)
 
 	return nil
 }
@@ -103,14 +132,18 @@ func (c *cachedServicePg) List(ctx context.Context, userID string, filter *TripF
 	return c.service.List(ctx, userID, filter, limit, offset)
 }
 
+// A user's own and shared trip lists are cached briefly and dropped whenever
+// a trip they belong to changes
 func (c *cachedServicePg) GetUserTrips(ctx context.Context, userID string, limit, offset int) ([]*Trip, int64, error) {
-	// User trips are not cached due to frequent updates
-	return c.service.GetUserTrips(ctx, userID, limit, offset)
+	return c.cachedList(ctx, userID, fmt.Sprintf("owned:%d:%d", limit, offset), func() ([]*Trip, int64, error) {
+		return c.service.GetUserTrips(ctx, userID, limit, offset)
+	})
 }
 
 func (c *cachedServicePg) GetSharedTrips(ctx context.Context, userID string, limit, offset int) ([]*Trip, int64, error) {
-	// Shared trips are not cached due to frequent updates
-	return c.service.GetSharedTrips(ctx, userID, limit, offset)
+	return c.cachedList(ctx, userID, fmt.Sprintf("shared:%d:%d", limit, offset), func() ([]*Trip, int64, error) {
+		return c.service.GetSharedTrips(ctx, userID, limit, offset)
+	})
 }
 
 func (c *cachedServicePg) Search(ctx context.Context, userID string, query string, limit, offset int) ([]*Trip, int64, error) {
@@ -119,99 +152,202 @@ func (c *cachedServicePg) Search(ctx context.Context, userID string, query strin
 }
 
 func (c *cachedServicePg) AddCollaborator(ctx context.Context, userID, tripID, collaboratorID, role string) error {
+	members := c.members(ctx, userID, tripID)
 	if err := c.service.AddCollaborator(ctx, userID, tripID, collaboratorID, role); err != nil {
 		return err
 	}
 
-	// Invalidate cache
-	if err := c.cache.DeleteTrip(ctx, tripID); err != nil {
-		fmt.Printf("Failed to invalidate trip cache: %v\n", err)
-	}
+	c.invalidate(ctx, userID, tripID, append(members, collaboratorID))
 
 	return nil
 }
 
 func (c *cachedServicePg) RemoveCollaborator(ctx context.Context, userID, tripID, collaboratorID string) error {
+	members := c.members(ctx, userID, tripID)
 	if err := c.service.RemoveCollaborator(ctx, userID, tripID, collaboratorID); err != nil {
 		return err
 	}
 
-	// Invalidate cache
-	if err := c.cache.DeleteTrip(ctx, tripID); err != nil {
-		fmt.Printf("Failed to invalidate trip cache: %v\n", err)
-	}
+	c.invalidate(ctx, userID, tripID, members)
 
 	return nil
 }
 
 func (c *cachedServicePg) UpdateCollaboratorRole(ctx context.Context, userID, tripID, collaboratorID, role string) error {
+	members := c.members(ctx, userID, tripID)
 	if err := c.service.UpdateCollaboratorRole(ctx, userID, tripID, collaboratorID, role); err != nil {
 		return err
 	}
 
-	// Invalidate cache
-	if err := c.cache.DeleteTrip(ctx, tripID); err != nil {
-		fmt.Printf("Failed to invalidate trip cache: %v\n", err)
-	}
+	c.invalidate(ctx, userID, tripID, members)
 
 	return nil
 }
 
 func (c *cachedServicePg) AddWaypoint(ctx context.Context, userID, tripID string, input *AddWaypointInput) (*Waypoint, error) {
+	members := c.members(ctx, userID, tripID)
 	waypoint, err := c.service.AddWaypoint(ctx, userID, tripID, input)
 	if err != nil {
 		return nil, err
 	}
 
-	// Invalidate cache
-	if err := c.cache.DeleteTrip(ctx, tripID); err != nil {
-		fmt.Printf("Failed to invalidate trip cache: %v\n", err)
-	}
+	c.invalidate(ctx, userID, tripID, members)
 
 	return waypoint, nil
 }
 
 func (c *cachedServicePg) UpdateWaypoint(ctx context.Context, userID, tripID, waypointID string, input *UpdateWaypointInput) (*Waypoint, error) {
+	members := c.members(ctx, userID, tripID)
 	waypoint, err := c.service.UpdateWaypoint(ctx, userID, tripID, waypointID, input)
 	if err != nil {
 		return nil, err
 	}
 
-	// Invalidate cache
-	if err := c.cache.DeleteTrip(ctx, tripID); err != nil {
-		fmt.Printf("Failed to invalidate trip cache: %v\n", err)
-	}
+	c.invalidate(ctx, userID, tripID, members)
 
 	return waypoint, nil
 }
 
 func (c *cachedServicePg) RemoveWaypoint(ctx context.Context, userID, tripID, waypointID string) error {
+	members := c.members(ctx, userID, tripID)
 	if err := c.service.RemoveWaypoint(ctx, userID, tripID, waypointID); err != nil {
 		return err
 	}
 
-	// Invalidate cache
-	if err := c.cache.DeleteTrip(ctx, tripID); err != nil {
-		fmt.Printf("Failed to invalidate trip cache: %v\n", err)
-	}
+	c.invalidate(ctx, userID, tripID, members)
 
 	return nil
 }
 
 func (c *cachedServicePg) ReorderWaypoints(ctx context.Context, userID, tripID string, waypointIDs []string) error {
+	members := c.members(ctx, userID, tripID)
 	if err := c.service.ReorderWaypoints(ctx, userID, tripID, waypointIDs); err != nil {
 		return err
 	}
 
-	// Invalidate cache
-	if err := c.cache.DeleteTrip(ctx, tripID); err != nil {
-		fmt.Printf("Failed to invalidate trip cache: %v\n", err)
-	}
+	c.invalidate(ctx, userID, tripID, members)
 
 	return nil
 }
 
 // Helper methods
+
+// invalidate publishes an invalidation of the trip for the given members.
+// Subscribers apply it to Redis and any other cache holding the trip. If it
+// cannot be published this cache is cleared directly, so a failing bus never
+// leaves stale entries behind.
+func (c *cachedServicePg) invalidate(ctx context.Context, actorID, tripID string, members []string) {
+	invalidation := cache.TripInvalidation{TripID: tripID, UserIDs: dedupe(members)}
+
+	if c.bus != nil {
+		err := c.bus.Publish(ctx, invalidation.Event(actorID))
+		if err == nil {
+			return
+		}
+		fmt.Printf("Failed to publish trip invalidation: %v\n", err)
+	}
+
+	if err := invalidation.Apply(ctx, c.cache); err != nil {
+		fmt.Printf("Failed to invalidate trip cache: %v\n", err)
+	}
+}
+
+// members finds who a trip's cached lists and permissions belong to before
+// it changes, preferring the cached copy
+func (c *cachedServicePg) members(ctx context.Context, userID, tripID string) []string {
+	if members, ok := c.cachedMembers(ctx, tripID); ok {
+		return members
+	}
+
+	trip, err := c.service.GetByIDWith(ctx, userID, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return []string{userID}
+	}
+	return tripMembers(trip)
+}
+
+// sharedMembers is members for a share-link guest, who has no user to look
+// the trip up as
+func (c *cachedServicePg) sharedMembers(ctx context.Context, grant *ShareGrant, tripID string) []string {
+	if members, ok := c.cachedMembers(ctx, tripID); ok {
+		return members
+	}
+
+	trip, err := c.service.GetSharedWith(ctx, grant, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil
+	}
+	return tripMembers(trip)
+}
+
+// cachedMembers lists the members of the cached copy of a trip
+func (c *cachedServicePg) cachedMembers(ctx context.Context, tripID string) ([]string, bool) {
+	data, err := c.cache.GetTrip(ctx, tripID)
+	if err != nil || data == nil {
+		return nil, false
+	}
+
+	var trip Trip
+	if err := json.Unmarshal(data, &trip); err != nil {
+		return nil, false
+	}
+	return tripMembers(&trip), true
+}
+
+// tripMembers lists the owner and collaborators of a trip
+func tripMembers(trip *Trip) []string {
+	if trip == nil {
+		return nil
+	}
+	members := []string{trip.OwnerID}
+	for _, collab := range trip.Collaborators {
+		members = append(members, collab.UserID)
+	}
+	return members
+}
+
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		unique = append(unique, value)
+	}
+	return unique
+}
+
+type cachedTripList struct {
+	Trips []*Trip `json:"trips"`
+	Total int64   `json:"total"`
+}
+
+// cachedList serves a user's trip list from the cache, loading and caching it
+// on a miss
+func (c *cachedServicePg) cachedList(ctx context.Context, userID, variant string, load func() ([]*Trip, int64, error)) ([]*Trip, int64, error) {
+	if data, err := c.cache.GetTripList(ctx, userID, variant); err == nil && data != nil {
+		var list cachedTripList
+		if err := json.Unmarshal(data, &list); err == nil {
+			return list.Trips, list.Total, nil
+		}
+	}
+
+	trips, total, err := load()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if data, err := json.Marshal(cachedTripList{Trips: trips, Total: total}); err == nil {
+		if err := c.cache.SetTripList(ctx, userID, variant, data, database.CacheTTLShort); err != nil {
+			fmt.Printf("Failed to cache trip list: %v\n", err)
+		}
+	}
+
+	return trips, total, nil
+}
+
 func (c *cachedServicePg) cacheTrip(ctx context.Context, trip *Trip) error {
 	data, err := json.Marshal(trip)
 	if err != nil {
@@ -244,26 +380,377 @@ func (c *cachedServicePg) canUserAccessTrip(trip *Trip, userID string) bool {
 
 // Implement missing interface methods
 func (c *cachedServicePg) InviteCollaborator(ctx context.Context, userID, tripID string, input *InviteCollaboratorInput) error {
+	members := c.members(ctx, userID, tripID)
 	if err := c.service.InviteCollaborator(ctx, userID, tripID, input); err != nil {
 		return err
 	}
 
-	// Invalidate cache
-	if err := c.cache.DeleteTrip(ctx, tripID); err != nil {
-		fmt.Printf("Failed to invalidate trip cache: %v\n", err)
-	}
+	c.invalidate(ctx, userID, tripID, members)
 
 	return nil
 }
 
+func (c *cachedServicePg) BulkInviteCollaborators(ctx context.Context, userID, tripID string, input *BulkInviteInput) ([]BulkInviteResult, error) {
+	members := c.members(ctx, userID, tripID)
+	results, err := c.service.BulkInviteCollaborators(ctx, userID, tripID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, userID, tripID, members)
+
+	return results, nil
+}
+
 func (c *cachedServicePg) GetTripStats(ctx context.Context, userID, tripID string) (*TripStats, error) {
 	// Stats are not cached as they may change frequently
 	return c.service.GetTripStats(ctx, userID, tripID)
 }
 
-func (c *cachedServicePg) ExportTrip(ctx context.Context, userID, tripID, format string) ([]byte, error) {
+func (c *cachedServicePg) RecomputeDifficulty(ctx context.Context, userID, tripID string) (*Trip, error) {
+	trip, err := c.service.RecomputeDifficulty(ctx, userID, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, userID, tripID, tripMembers(trip))
+
+	return trip, nil
+}
+
+func (c *cachedServicePg) AcceptSuggestedTags(ctx context.Context, userID, tripID string, input *SuggestedTagsInput) (*Trip, error) {
+	trip, err := c.service.AcceptSuggestedTags(ctx, userID, tripID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, userID, tripID, tripMembers(trip))
+
+	return trip, nil
+}
+
+func (c *cachedServicePg) DismissSuggestedTags(ctx context.Context, userID, tripID string, input *SuggestedTagsInput) (*Trip, error) {
+	trip, err := c.service.DismissSuggestedTags(ctx, userID, tripID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, userID, tripID, tripMembers(trip))
+
+	return trip, nil
+}
+
+func (c *cachedServicePg) ImportGPX(ctx context.Context, userID, tripID string, file io.Reader) (*Trip, error) {
+	trip, err := c.service.ImportGPX(ctx, userID, tripID, file)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, userID, tripID, tripMembers(trip))
+
+	return trip, nil
+}
+
+// Audiences depend on the user's friends, which are not cached with the trip
+func (c *cachedServicePg) AudienceOf(ctx context.Context, userID string, trip *Trip) string {
+	return c.service.AudienceOf(ctx, userID, trip)
+}
+
+// Previews are asked for rarely, by owners about to share, so they always
+// read the trip as it is now
+func (c *cachedServicePg) ViewAs(ctx context.Context, userID, tripID, audience string, relations Relations) (*TripPreview, error) {
+	return c.service.ViewAs(ctx, userID, tripID, audience, relations)
+}
+
+// Estimates are per user, so they are never cached with the trip
+func (c *cachedServicePg) EstimateDuration(ctx context.Context, userID string, trip *Trip) (*DurationEstimate, error) {
+	return c.service.EstimateDuration(ctx, userID, trip)
+}
+
+// Readiness depends on the time and on reports outside the trip, so it is
+// never cached
+func (c *cachedServicePg) GetReadiness(ctx context.Context, userID, tripID string) (*TripReadiness, error) {
+	return c.service.GetReadiness(ctx, userID, tripID)
+}
+
+func (c *cachedServicePg) ConfirmReadinessCheck(ctx context.Context, userID, tripID, check string) (*TripReadiness, error) {
+	return c.service.ConfirmReadinessCheck(ctx, userID, tripID, check)
+}
+
+func (c *cachedServicePg) ClearReadinessCheck(ctx context.Context, userID, tripID, check string) (*TripReadiness, error) {
+	return c.service.ClearReadinessCheck(ctx, userID, tripID, check)
+}
+
+// Completions change the trip's completion count
+func (c *cachedServicePg) LogCompletion(ctx context.Context, userID, tripID string, input *CreateActivityCompletionInput) (*ActivityCompletion, error) {
+	members := c.members(ctx, userID, tripID)
+	completion, err := c.service.LogCompletion(ctx, userID, tripID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, userID, tripID, members)
+
+	return completion, nil
+}
+
+func (c *cachedServicePg) ListCompletions(ctx context.Context, userID, tripID string, limit, offset int) ([]*ActivityCompletion, error) {
+	return c.service.ListCompletions(ctx, userID, tripID, limit, offset)
+}
+
+func (c *cachedServicePg) GetCompletion(ctx context.Context, userID, tripID, completionID string) (*ActivityCompletion, error) {
+	return c.service.GetCompletion(ctx, userID, tripID, completionID)
+}
+
+// Ratings change the trip's average rating and rating count
+func (c *cachedServicePg) RateTrip(ctx context.Context, userID, tripID string, input *CreateActivityRatingInput) (*ActivityRating, error) {
+	members := c.members(ctx, userID, tripID)
+	rating, err := c.service.RateTrip(ctx, userID, tripID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, userID, tripID, members)
+
+	return rating, nil
+}
+
+func (c *cachedServicePg) ListRatings(ctx context.Context, userID, tripID string, limit, offset int) ([]*ActivityRating, error) {
+	return c.service.ListRatings(ctx, userID, tripID, limit, offset)
+}
+
+func (c *cachedServicePg) DeleteRating(ctx context.Context, userID, tripID string) error {
+	members := c.members(ctx, userID, tripID)
+	if err := c.service.DeleteRating(ctx, userID, tripID); err != nil {
+		return err
+	}
+
+	c.invalidate(ctx, userID, tripID, members)
+
+	return nil
+}
+
+// Condition reports are not part of the cached trip
+func (c *cachedServicePg) ReportCondition(ctx context.Context, userID, tripID string, input *CreateActivityConditionInput) (*ActivityCondition, error) {
+	return c.service.ReportCondition(ctx, userID, tripID, input)
+}
+
+func (c *cachedServicePg) ListConditions(ctx context.Context, userID, tripID string, includeExpired bool) ([]*ActivityCondition, error) {
+	return c.service.ListConditions(ctx, userID, tripID, includeExpired)
+}
+
+func (c *cachedServicePg) VerifyCondition(ctx context.Context, userID, tripID, conditionID string) (*ActivityCondition, error) {
+	return c.service.VerifyCondition(ctx, userID, tripID, conditionID)
+}
+
+func (c *cachedServicePg) ConfirmCondition(ctx context.Context, userID, tripID, conditionID string, input *ReviewConditionInput) (*ActivityCondition, error) {
+	return c.service.ConfirmCondition(ctx, userID, tripID, conditionID, input)
+}
+
+func (c *cachedServicePg) DisputeCondition(ctx context.Context, userID, tripID, conditionID string, input *ReviewConditionInput) (*ActivityCondition, error) {
+	return c.service.DisputeCondition(ctx, userID, tripID, conditionID, input)
+}
+
+func (c *cachedServicePg) GrantConditionVerifier(ctx context.Context, adminID string, input *GrantConditionVerifierInput) (*ConditionVerifier, error) {
+	return c.service.GrantConditionVerifier(ctx, adminID, input)
+}
+
+func (c *cachedServicePg) ListConditionVerifiers(ctx context.Context, userID string) ([]*ConditionVerifier, error) {
+	return c.service.ListConditionVerifiers(ctx, userID)
+}
+
+func (c *cachedServicePg) RevokeConditionVerifier(ctx context.Context, id string) error {
+	return c.service.RevokeConditionVerifier(ctx, id)
+}
+
+// Date polls are not part of the cached trip until one is finalized, which
+// sets the trip's dates
+func (c *cachedServicePg) ListDatePolls(ctx context.Context, userID, tripID string) ([]*DatePoll, error) {
+	return c.service.ListDatePolls(ctx, userID, tripID)
+}
+
+func (c *cachedServicePg) GetDatePoll(ctx context.Context, userID, tripID, pollID string) (*DatePoll, error) {
+	return c.service.GetDatePoll(ctx, userID, tripID, pollID)
+}
+
+func (c *cachedServicePg) CreateDatePoll(ctx context.Context, userID, tripID string, input *CreateDatePollInput) (*DatePoll, error) {
+	return c.service.CreateDatePoll(ctx, userID, tripID, input)
+}
+
+func (c *cachedServicePg) SetAvailability(ctx context.Context, userID, tripID, pollID string, input *SetAvailabilityInput) (*DatePoll, error) {
+	return c.service.SetAvailability(ctx, userID, tripID, pollID, input)
+}
+
+func (c *cachedServicePg) FinalizeDatePoll(ctx context.Context, userID, tripID, pollID string, input *FinalizeDatePollInput) (*DatePoll, error) {
+	members := c.members(ctx, userID, tripID)
+	poll, err := c.service.FinalizeDatePoll(ctx, userID, tripID, pollID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, userID, tripID, members)
+
+	return poll, nil
+}
+
+func (c *cachedServicePg) DeleteDatePoll(ctx context.Context, userID, tripID, pollID string) error {
+	return c.service.DeleteDatePoll(ctx, userID, tripID, pollID)
+}
+
+// Participants are kept apart from the cached trip
+func (c *cachedServicePg) GetParticipants(ctx context.Context, userID, tripID string) (*ParticipantRoster, error) {
+	return c.service.GetParticipants(ctx, userID, tripID)
+}
+
+func (c *cachedServicePg) InviteParticipants(ctx context.Context, userID, tripID string, input *InviteParticipantsInput) (*ParticipantRoster, error) {
+	return c.service.InviteParticipants(ctx, userID, tripID, input)
+}
+
+func (c *cachedServicePg) RespondToInvite(ctx context.Context, userID, tripID string, input *RSVPInput) (*ParticipantRoster, error) {
+	return c.service.RespondToInvite(ctx, userID, tripID, input)
+}
+
+func (c *cachedServicePg) RemoveParticipant(ctx context.Context, userID, tripID, participantID string) error {
+	return c.service.RemoveParticipant(ctx, userID, tripID, participantID)
+}
+
+func (c *cachedServicePg) SetParticipantCapacity(ctx context.Context, userID, tripID string, input *SetCapacityInput) (*ParticipantRoster, error) {
+	return c.service.SetParticipantCapacity(ctx, userID, tripID, input)
+}
+
+func (c *cachedServicePg) ListAnnotations(ctx context.Context, userID, tripID string) ([]*TripAnnotation, error) {
+	return c.service.ListAnnotations(ctx, userID, tripID)
+}
+
+func (c *cachedServicePg) CreateAnnotation(ctx context.Context, userID, tripID string, input *CreateAnnotationInput) (*TripAnnotation, error) {
+	return c.service.CreateAnnotation(ctx, userID, tripID, input)
+}
+
+func (c *cachedServicePg) UpdateAnnotation(ctx context.Context, userID, tripID, annotationID string, input *UpdateAnnotationInput) (*TripAnnotation, error) {
+	return c.service.UpdateAnnotation(ctx, userID, tripID, annotationID, input)
+}
+
+func (c *cachedServicePg) DeleteAnnotation(ctx context.Context, userID, tripID, annotationID string) error {
+	return c.service.DeleteAnnotation(ctx, userID, tripID, annotationID)
+}
+
+func (c *cachedServicePg) SetWaypointWindow(ctx context.Context, userID, tripID, waypointID string, input *SetTimeWindowInput) (*Itinerary, error) {
+	members := c.members(ctx, userID, tripID)
+	itinerary, err := c.service.SetWaypointWindow(ctx, userID, tripID, waypointID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, userID, tripID, members)
+
+	return itinerary, nil
+}
+
+// Itineraries are estimated from the trip each time
+func (c *cachedServicePg) GetItinerary(ctx context.Context, userID, tripID string) (*Itinerary, error) {
+	return c.service.GetItinerary(ctx, userID, tripID)
+}
+
+// Crowd estimates move with each completion, so they are not cached
+func (c *cachedServicePg) GetCrowdEstimate(ctx context.Context, userID, tripID string) (*CrowdEstimate, error) {
+	return c.service.GetCrowdEstimate(ctx, userID, tripID)
+}
+
+// Water sources are places outside the trip, so they are not cached with it
+func (c *cachedServicePg) GetWaterSources(ctx context.Context, userID, tripID string, query *WaterQuery) (*WaterPlan, error) {
+	return c.service.GetWaterSources(ctx, userID, tripID, query)
+}
+
+func (c *cachedServicePg) GetBailouts(ctx context.Context, userID, tripID string, query *BailoutQuery) ([]*BailoutPoint, error) {
+	return c.service.GetBailouts(ctx, userID, tripID, query)
+}
+
+func (c *cachedServicePg) AttachBailout(ctx context.Context, userID, tripID string, input *AttachBailoutInput) (*Waypoint, error) {
+	members := c.members(ctx, userID, tripID)
+	waypoint, err := c.service.AttachBailout(ctx, userID, tripID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, userID, tripID, members)
+
+	return waypoint, nil
+}
+
+func (c *cachedServicePg) AddWaypoints(ctx context.Context, userID, tripID string, input *AddWaypointsInput) (*Trip, error) {
+	trip, err := c.service.AddWaypoints(ctx, userID, tripID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, userID, tripID, tripMembers(trip))
+
+	return trip, nil
+}
+
+func (c *cachedServicePg) DetachBailout(ctx context.Context, userID, tripID, waypointID string) error {
+	members := c.members(ctx, userID, tripID)
+	if err := c.service.DetachBailout(ctx, userID, tripID, waypointID); err != nil {
+		return err
+	}
+
+	c.invalidate(ctx, userID, tripID, members)
+
+	return nil
+}
+
+func (c *cachedServicePg) ListRouteVariants(ctx context.Context, userID, tripID string) ([]*TripRouteVariant, error) {
+	return c.service.ListRouteVariants(ctx, userID, tripID)
+}
+
+// Only the primary variant is copied onto the cached trip
+func (c *cachedServicePg) CreateRouteVariant(ctx context.Context, userID, tripID string, input *CreateRouteVariantInput) (*TripRouteVariant, error) {
+	members := c.members(ctx, userID, tripID)
+	variant, err := c.service.CreateRouteVariant(ctx, userID, tripID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if variant.IsPrimary {
+		c.invalidate(ctx, userID, tripID, members)
+	}
+
+	return variant, nil
+}
+
+func (c *cachedServicePg) UpdateRouteVariant(ctx context.Context, userID, tripID, variantID string, input *UpdateRouteVariantInput) (*TripRouteVariant, error) {
+	members := c.members(ctx, userID, tripID)
+	variant, err := c.service.UpdateRouteVariant(ctx, userID, tripID, variantID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if variant.IsPrimary {
+		c.invalidate(ctx, userID, tripID, members)
+	}
+
+	return variant, nil
+}
+
+func (c *cachedServicePg) DeleteRouteVariant(ctx context.Context, userID, tripID, variantID string) error {
+	return c.service.DeleteRouteVariant(ctx, userID, tripID, variantID)
+}
+
+func (c *cachedServicePg) SetPrimaryRouteVariant(ctx context.Context, userID, tripID, variantID string) (*TripRouteVariant, error) {
+	members := c.members(ctx, userID, tripID)
+	variant, err := c.service.SetPrimaryRouteVariant(ctx, userID, tripID, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, userID, tripID, members)
+
+	return variant, nil
+}
+
+func (c *cachedServicePg) ExportTrip(ctx context.Context, userID, tripID, format, acceptLanguage string) (*TripExport, error) {
 	// Export operations are not cached
-	return c.service.ExportTrip(ctx, userID, tripID, format)
+	return c.service.ExportTrip(ctx, userID, tripID, format, acceptLanguage)
 }
 
 func (c *cachedServicePg) CloneTrip(ctx context.Context, userID, tripID string) (*Trip, error) {
@@ -272,10 +759,168 @@ func (c *cachedServicePg) CloneTrip(ctx context.Context, userID, tripID string)
 		return nil, err
 	}
 
+	c.invalidate(ctx, userID, trip.ID, tripMembers(trip))
+
 	// Cache the new trip
 	if err := c.cacheTrip(ctx, trip); err != nil {
 		fmt.Printf("Failed to cache trip: %v\n", err)
 	}
 
 	return trip, nil
-}
\ No newline at end of file
+}
+
+func (c *cachedServicePg) TransferOwnership(ctx context.Context, userID, tripID, newOwnerID string) (*OwnershipTransfer, error) {
+	return c.service.TransferOwnership(ctx, userID, tripID, newOwnerID)
+}
+
+func (c *cachedServicePg) AcceptOwnershipTransfer(ctx context.Context, userID, tripID string) (*Trip, error) {
+	members := c.members(ctx, userID, tripID)
+	trip, err := c.service.AcceptOwnershipTransfer(ctx, userID, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, userID, tripID, append(members, tripMembers(trip)...))
+
+	return trip, nil
+}
+
+func (c *cachedServicePg) DeclineOwnershipTransfer(ctx context.Context, userID, tripID string) error {
+	return c.service.DeclineOwnershipTransfer(ctx, userID, tripID)
+}
+
+func (c *cachedServicePg) SchedulePublication(ctx context.Context, userID, tripID string, input *SchedulePublicationInput) (*Trip, error) {
+	trip, err := c.service.SchedulePublication(ctx, userID, tripID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, userID, tripID, tripMembers(trip))
+
+	return trip, nil
+}
+
+func (c *cachedServicePg) CancelScheduledPublication(ctx context.Context, userID, tripID string) (*Trip, error) {
+	trip, err := c.service.CancelScheduledPublication(ctx, userID, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, userID, tripID, tripMembers(trip))
+
+	return trip, nil
+}
+
+// Drafts never touch the trip itself, so saving one leaves the cache alone
+func (c *cachedServicePg) SaveDraft(ctx context.Context, userID, tripID string, input *SaveDraftInput) (*TripDraft, error) {
+	return c.service.SaveDraft(ctx, userID, tripID, input)
+}
+
+func (c *cachedServicePg) GetDraft(ctx context.Context, userID, tripID string) (*TripDraft, error) {
+	return c.service.GetDraft(ctx, userID, tripID)
+}
+
+func (c *cachedServicePg) DiscardDraft(ctx context.Context, userID, tripID string) error {
+	return c.service.DiscardDraft(ctx, userID, tripID)
+}
+
+func (c *cachedServicePg) ApplyDraft(ctx context.Context, userID, tripID string) (*DraftApplyResult, error) {
+	result, err := c.service.ApplyDraft(ctx, userID, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Applied) > 0 {
+		c.invalidate(ctx, userID, tripID, tripMembers(result.Trip))
+	}
+
+	return result, nil
+}
+
+// Share links
+
+// Share links are not part of the cached trip
+func (c *cachedServicePg) CreateShareLink(ctx context.Context, userID, tripID string, input *CreateShareLinkInput) (*ActivityShareLink, error) {
+	return c.service.CreateShareLink(ctx, userID, tripID, input)
+}
+
+func (c *cachedServicePg) RedeemShareLink(ctx context.Context, token string) (*ActivityShareLink, error) {
+	return c.service.RedeemShareLink(ctx, token)
+}
+
+// Guests are rare enough that their reads go straight to the database
+func (c *cachedServicePg) GetSharedWith(ctx context.Context, grant *ShareGrant, tripID string, relations Relations) (*Trip, error) {
+	return c.service.GetSharedWith(ctx, grant, tripID, relations)
+}
+
+func (c *cachedServicePg) UpdateShared(ctx context.Context, grant *ShareGrant, tripID string, input *UpdateTripInput) (*Trip, error) {
+	trip, err := c.service.UpdateShared(ctx, grant, tripID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, "", tripID, tripMembers(trip))
+
+	if err := c.cacheTrip(ctx, trip); err != nil {
+		fmt.Printf("Failed to cache trip: %v\n", err)
+	}
+
+	return trip, nil
+}
+
+func (c *cachedServicePg) AddWaypointShared(ctx context.Context, grant *ShareGrant, tripID string, input *AddWaypointInput) (*Waypoint, error) {
+	members := c.sharedMembers(ctx, grant, tripID)
+	waypoint, err := c.service.AddWaypointShared(ctx, grant, tripID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, "", tripID, members)
+
+	return waypoint, nil
+}
+
+func (c *cachedServicePg) UpdateWaypointShared(ctx context.Context, grant *ShareGrant, tripID, waypointID string, input *UpdateWaypointInput) (*Waypoint, error) {
+	members := c.sharedMembers(ctx, grant, tripID)
+	waypoint, err := c.service.UpdateWaypointShared(ctx, grant, tripID, waypointID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, "", tripID, members)
+
+	return waypoint, nil
+}
+
+func (c *cachedServicePg) RemoveWaypointShared(ctx context.Context, grant *ShareGrant, tripID, waypointID string) error {
+	members := c.sharedMembers(ctx, grant, tripID)
+	if err := c.service.RemoveWaypointShared(ctx, grant, tripID, waypointID); err != nil {
+		return err
+	}
+
+	c.invalidate(ctx, "", tripID, members)
+
+	return nil
+}
+
+func (c *cachedServicePg) ReorderWaypointsShared(ctx context.Context, grant *ShareGrant, tripID string, waypointIDs []string) error {
+	members := c.sharedMembers(ctx, grant, tripID)
+	if err := c.service.ReorderWaypointsShared(ctx, grant, tripID, waypointIDs); err != nil {
+		return err
+	}
+
+	c.invalidate(ctx, "", tripID, members)
+
+	return nil
+}
+
+func (c *cachedServicePg) AddWaypointsShared(ctx context.Context, grant *ShareGrant, tripID string, input *AddWaypointsInput) (*Trip, error) {
+	trip, err := c.service.AddWaypointsShared(ctx, grant, tripID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, "", tripID, tripMembers(trip))
+
+	return trip, nil
+}
@@ -0,0 +1,105 @@
+package trips
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/internal/gpx"
+)
+
+// completionClockSkew is how far in the future a completion may be dated,
+// for devices whose clocks run ahead
+const completionClockSkew = 5 * time.Minute
+
+// LogCompletion records that the user completed the trip and counts it on
+// the trip. A GPX file sent along is stored as the GeoJSON of its track.
+func (s *servicePg) LogCompletion(ctx context.Context, userID, tripID string, input *CreateActivityCompletionInput) (*ActivityCompletion, error) {
+	if input.CompletedAt.After(time.Now().Add(completionClockSkew)) {
+		return nil, ErrCompletionInFuture
+	}
+
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+
+	completion := &ActivityCompletion{
+		TripID:            tripID,
+		UserID:            userID,
+		CompletedAt:       input.CompletedAt,
+		DurationMinutes:   input.DurationMinutes,
+		DifficultyRating:  input.DifficultyRating,
+		OverallRating:     input.OverallRating,
+		WeatherConditions: input.WeatherConditions,
+		TrailConditions:   input.TrailConditions,
+		Notes:             input.Notes,
+		Photos:            input.Photos,
+		GPXTrack:          input.GPXTrack,
+	}
+
+	if input.GPX != "" {
+		track, err := gpx.Parse(strings.NewReader(input.GPX))
+		if err != nil {
+			return nil, ErrInvalidGPX
+		}
+		route := trackRoute(track)
+		completion.GPXTrack = &JSONB{"type": route.Type, "coordinates": route.Coordinates}
+	}
+
+	if err := s.repo.CreateCompletion(ctx, completion); err != nil {
+		return nil, err
+	}
+
+	s.announce(ctx, events.TripCompleted, tripID, userID, map[string]interface{}{
+		"completion_id":     completion.ID,
+		"duration_minutes":  completion.DurationMinutes,
+		"difficulty_rating": completion.DifficultyRating,
+		"overall_rating":    completion.OverallRating,
+		"with_track":        completion.GPXTrack != nil,
+	})
+
+	return completion, nil
+}
+
+// ListCompletions returns the completions of a trip, most recent first,
+// without their tracks
+func (s *servicePg) ListCompletions(ctx context.Context, userID, tripID string, limit, offset int) ([]*ActivityCompletion, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+
+	return s.repo.ListCompletions(ctx, tripID, limit, offset)
+}
+
+// GetCompletion returns a completion of a trip with its track
+func (s *servicePg) GetCompletion(ctx context.Context, userID, tripID, completionID string) (*ActivityCompletion, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+
+	completion, err := s.repo.GetCompletion(ctx, completionID)
+	if err != nil {
+		return nil, err
+	}
+	if completion.TripID != tripID {
+		return nil, ErrCompletionNotFound
+	}
+
+	return completion, nil
+}
@@ -0,0 +1,207 @@
+package trips
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+	"github.com/Oferzz/newMap/apps/api/pkg/repoerr"
+	"github.com/lib/pq"
+)
+
+// JobSendConditionAlerts sends the pending alerts of a condition subscription
+const JobSendConditionAlerts = "trip.condition_alerts"
+
+// How often a subscription's alerts are sent: as each report is verified,
+// or batched into an hourly or daily digest
+const (
+	AlertsInstant = "instant"
+	AlertsHourly  = "hourly"
+	AlertsDaily   = "daily"
+)
+
+// Limits on condition subscriptions
+const (
+	MaxConditionSubscriptions = 50
+	MaxAlertRegionKm2         = 25000
+)
+
+// DefaultAlertSeverities are the severities alerted on when a subscription
+// does not choose, leaving out reports that are only informational
+var DefaultAlertSeverities = []string{SeverityWarning, SeverityDanger}
+
+var (
+	ErrSubscriptionNotFound = repoerr.NotFound("condition subscription not found")
+	ErrInvalidSubscription  = errors.New("subscribe to either a trip or a region polygon")
+	ErrAlertRegionTooLarge  = fmt.Errorf("regions are limited to %d square kilometres", MaxAlertRegionKm2)
+	ErrTooManySubscriptions = repoerr.Conflict(fmt.Sprintf("users are limited to %d condition subscriptions", MaxConditionSubscriptions))
+)
+
+// ConditionSubscription asks for alerts of verified condition reports on a
+// trip, or on any public trip in a region
+type ConditionSubscription struct {
+	ID         string         `db:"id" json:"id"`
+	UserID     string         `db:"user_id" json:"user_id"`
+	TripID     *string        `db:"trip_id" json:"trip_id,omitempty"`
+	Region     *GeoJSONRoute  `db:"region" json:"region,omitempty"` // Polygon
+	Severities pq.StringArray `db:"severities" json:"severities"`
+	Frequency  string         `db:"frequency" json:"frequency"`
+	NextSendAt *time.Time     `db:"next_send_at" json:"next_send_at,omitempty"` // When the alerts waiting are sent
+	LastSentAt *time.Time     `db:"last_sent_at" json:"last_sent_at,omitempty"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+}
+
+type CreateConditionSubscriptionInput struct {
+	TripID     string        `json:"trip_id" binding:"omitempty,uuid"`
+	Region     *GeoJSONRoute `json:"region"`
+	Severities []string      `json:"severities" binding:"omitempty,min=1,dive,oneof=info warning danger"`
+	Frequency  string        `json:"frequency" binding:"omitempty,oneof=instant hourly daily"`
+}
+
+// ConditionAlert is a verified condition report a subscription matched
+type ConditionAlert struct {
+	ConditionID   string    `db:"condition_id" json:"condition_id"`
+	TripID        string    `db:"trip_id" json:"trip_id"`
+	TripTitle     string    `db:"trip_title" json:"trip_title"`
+	ConditionType string    `db:"condition_type" json:"condition_type"`
+	Severity      string    `db:"severity" json:"severity"`
+	Description   string    `db:"description" json:"description"`
+	ReportedAt    time.Time `db:"reported_at" json:"reported_at"`
+}
+
+type conditionAlertsPayload struct {
+	SubscriptionID string `json:"subscription_id"`
+}
+
+// ConditionAlerts tells users of verified condition reports on the trips and
+// regions they subscribed to. Alerts are sent to the user's realtime topic
+// as a single event per batch.
+type ConditionAlerts struct {
+	repo  Repository
+	queue jobs.Queue
+	bus   events.Bus
+}
+
+// NewConditionAlerts creates condition alerts published on bus, and
+// registers their job handler
+func NewConditionAlerts(repo Repository, queue jobs.Queue, bus events.Bus) *ConditionAlerts {
+	a := &ConditionAlerts{
+		repo:  repo,
+		queue: queue,
+		bus:   bus,
+	}
+
+	queue.Register(JobSendConditionAlerts, a.send)
+
+	return a
+}
+
+// Subscribe subscribes the user to condition reports on a trip they can
+// view, or in a region
+func (a *ConditionAlerts) Subscribe(ctx context.Context, userID string, input *CreateConditionSubscriptionInput) (*ConditionSubscription, error) {
+	if (input.TripID == "") == (input.Region == nil) {
+		return nil, ErrInvalidSubscription
+	}
+
+	subscription := &ConditionSubscription{
+		UserID:     userID,
+		Severities: input.Severities,
+		Frequency:  input.Frequency,
+	}
+	if len(subscription.Severities) == 0 {
+		subscription.Severities = DefaultAlertSeverities
+	}
+	if subscription.Frequency == "" {
+		subscription.Frequency = AlertsInstant
+	}
+
+	if input.TripID != "" {
+		trip, err := a.repo.GetByIDWith(ctx, input.TripID, Relations{Collaborators: true})
+		if err != nil {
+			return nil, err
+		}
+		if !trip.VisibleTo(userID) {
+			return nil, ErrUnauthorized
+		}
+		subscription.TripID = &trip.ID
+	} else {
+		ring, ok := polygonRing(input.Region)
+		if !ok || len(ring) < 4 {
+			return nil, ErrInvalidSubscription
+		}
+		if ringArea(ring) > MaxAlertRegionKm2*1e6 {
+			return nil, ErrAlertRegionTooLarge
+		}
+		subscription.Region = input.Region
+	}
+
+	if err := a.repo.CreateConditionSubscription(ctx, subscription, MaxConditionSubscriptions); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// ListSubscriptions returns the user's condition subscriptions, newest first
+func (a *ConditionAlerts) ListSubscriptions(ctx context.Context, userID string) ([]*ConditionSubscription, error) {
+	return a.repo.ListConditionSubscriptions(ctx, userID)
+}
+
+// Unsubscribe deletes one of the user's condition subscriptions, with any
+// alerts still waiting to be sent
+func (a *ConditionAlerts) Unsubscribe(ctx context.Context, userID, subscriptionID string) error {
+	return a.repo.DeleteConditionSubscription(ctx, userID, subscriptionID)
+}
+
+// HandleConditionVerified queues alerts of a verified report for the
+// subscriptions it matches, scheduling a send for those with none due yet.
+// Alerts of subscriptions with a send due are batched into it.
+func (a *ConditionAlerts) HandleConditionVerified(ctx context.Context, event events.Event) error {
+	conditionID, _ := event.Data["condition_id"].(string)
+	if conditionID == "" {
+		return nil
+	}
+
+	due, err := a.repo.QueueConditionAlerts(ctx, conditionID)
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range due {
+		payload := conditionAlertsPayload{SubscriptionID: subscription.ID}
+		if _, err := a.queue.Schedule(ctx, JobSendConditionAlerts, payload, *subscription.NextSendAt); err != nil {
+			return fmt.Errorf("failed to schedule condition alerts: %w", err)
+		}
+	}
+	return nil
+}
+
+// send is the job handler for JobSendConditionAlerts. The alerts are marked
+// sent as they are taken, so a failure to publish them is not retried.
+func (a *ConditionAlerts) send(ctx context.Context, job *jobs.Job) error {
+	var payload conditionAlertsPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	subscription, alerts, err := a.repo.TakeConditionAlerts(ctx, payload.SubscriptionID)
+	if err != nil {
+		if errors.Is(err, ErrSubscriptionNotFound) {
+			// Unsubscribed since the send was scheduled
+			return nil
+		}
+		return err
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	event := events.New(events.ConditionAlerts, "user", subscription.UserID, "", map[string]interface{}{
+		"subscription_id": subscription.ID,
+		"frequency":       subscription.Frequency,
+		"alerts":          alerts,
+	})
+	return a.bus.Publish(ctx, event)
+}
@@ -0,0 +1,113 @@
+package trips
+
+import (
+	"context"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+)
+
+// Condition severities
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityDanger  = "danger"
+)
+
+// ReportCondition records a report of trail, weather, closure or hazard
+// conditions on a trip the user can see. Reports without an end stay in
+// effect until they are superseded, and are not verified until the trip's
+// owner or an admin vouches for them.
+func (s *servicePg) ReportCondition(ctx context.Context, userID, tripID string, input *CreateActivityConditionInput) (*ActivityCondition, error) {
+	if input.ValidUntil != nil && !input.ValidUntil.After(time.Now()) {
+		return nil, ErrConditionExpired
+	}
+	if input.Location != nil && (input.Location.Type != "Point" || !validPosition(input.Location.Coordinates)) {
+		return nil, ErrInvalidConditionLocation
+	}
+
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+
+	condition := &ActivityCondition{
+		TripID:        tripID,
+		ReportedBy:    userID,
+		ConditionType: input.ConditionType,
+		Severity:      input.Severity,
+		Description:   input.Description,
+		Location:      input.Location,
+		Photos:        input.Photos,
+		ValidUntil:    timeIn(input.ValidUntil, time.UTC),
+	}
+	if condition.Severity == "" {
+		condition.Severity = SeverityInfo
+	}
+
+	if err := s.repo.CreateCondition(ctx, condition); err != nil {
+		return nil, err
+	}
+
+	s.announce(ctx, events.TripUpdated, tripID, userID, map[string]interface{}{
+		"fields":       []string{"conditions"},
+		"condition_id": condition.ID,
+	})
+
+	return condition, nil
+}
+
+// ListConditions returns the condition reports in effect on a trip, newest
+// first, or all of them when expired ones are included
+func (s *servicePg) ListConditions(ctx context.Context, userID, tripID string, includeExpired bool) ([]*ActivityCondition, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+
+	return s.repo.ListConditions(ctx, tripID, includeExpired)
+}
+
+// VerifyCondition marks a condition report on the trip as verified by its
+// owner or an admin. Subscribers to the trip or its region are alerted of
+// it.
+func (s *servicePg) VerifyCondition(ctx context.Context, userID, tripID, conditionID string) (*ActivityCondition, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if !trip.CanUserVerifyConditions(userID) {
+		return nil, ErrUnauthorized
+	}
+
+	condition, err := s.repo.GetCondition(ctx, conditionID)
+	if err != nil {
+		return nil, err
+	}
+	if condition.TripID != tripID {
+		return nil, ErrConditionNotFound
+	}
+
+	if err := s.repo.VerifyCondition(ctx, conditionID, userID); err != nil {
+		return nil, err
+	}
+
+	condition.Verified = true
+	condition.VerifiedBy = &userID
+	condition.Weight = ConditionWeight(condition)
+	s.announce(ctx, events.ConditionVerified, tripID, userID, map[string]interface{}{
+		"condition_id": condition.ID,
+		"severity":     condition.Severity,
+	})
+
+	return condition, nil
+}
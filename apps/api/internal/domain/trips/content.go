@@ -0,0 +1,201 @@
+package trips
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Oferzz/newMap/apps/api/pkg/richtext"
+	"github.com/google/uuid"
+)
+
+// Kinds of block a trip's content is made of
+const (
+	BlockText           = "text"            // Markdown
+	BlockImage          = "image"           // An uploaded image, with a caption
+	BlockPlace          = "place"           // A card of a place
+	BlockElevationChart = "elevation_chart" // The trip's elevation profile
+	BlockWarning        = "warning"         // A callout of something to watch out for
+)
+
+// Severities of a warning block
+const (
+	WarningInfo    = "info"
+	WarningCaution = "caution"
+	WarningDanger  = "danger"
+)
+
+// Limits on a trip's content
+const (
+	MaxContentBlocks    = 50
+	MaxBlockTextLength  = 10000
+	MaxBlockLabelLength = 500 // Of captions and titles
+)
+
+// MediaUsageTripContent is the entity type of the media usage recorded for
+// images in a trip's content, so that they are not cleaned up while in use
+const MediaUsageTripContent = "trip_content"
+
+var ErrInvalidContent = errors.New("invalid content block")
+
+// ContentBlock is a block of a trip's description. Which fields are set
+// depends on its type.
+type ContentBlock struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`     // Markdown of text and warning blocks
+	Title    string `json:"title,omitempty"`    // Of warnings and elevation charts
+	Severity string `json:"severity,omitempty"` // Of warnings
+	MediaID  string `json:"media_id,omitempty"` // Of images
+	PlaceID  string `json:"place_id,omitempty"` // Of place cards
+	Caption  string `json:"caption,omitempty"`  // Of images and place cards
+}
+
+// TripContent is a trip's description as structured blocks, stored as JSON
+type TripContent []ContentBlock
+
+// normalize validates the blocks and sanitizes their markdown, clearing the
+// fields their type does not use
+func (c TripContent) normalize() error {
+	if len(c) > MaxContentBlocks {
+		return fmt.Errorf("%w: content is limited to %d blocks", ErrInvalidContent, MaxContentBlocks)
+	}
+
+	for i := range c {
+		if err := c[i].normalize(); err != nil {
+			return fmt.Errorf("%w: block %d: %v", ErrInvalidContent, i, err)
+		}
+	}
+	return nil
+}
+
+func (b *ContentBlock) normalize() error {
+	block := ContentBlock{Type: b.Type}
+	switch b.Type {
+	case BlockText:
+		block.Text = richtext.Sanitize(b.Text)
+		if block.Text == "" {
+			return errors.New("text is required")
+		}
+	case BlockWarning:
+		block.Text = richtext.Sanitize(b.Text)
+		block.Title = strings.TrimSpace(b.Title)
+		block.Severity = b.Severity
+		if block.Severity == "" {
+			block.Severity = WarningCaution
+		}
+		if block.Text == "" {
+			return errors.New("text is required")
+		}
+		if block.Severity != WarningInfo && block.Severity != WarningCaution && block.Severity != WarningDanger {
+			return errors.New("severity must be info, caution or danger")
+		}
+	case BlockImage:
+		id, err := uuid.Parse(b.MediaID)
+		if err != nil {
+			return errors.New("media_id must be the ID of an uploaded image")
+		}
+		block.MediaID = id.String()
+		block.Caption = strings.TrimSpace(b.Caption)
+	case BlockPlace:
+		id, err := uuid.Parse(b.PlaceID)
+		if err != nil {
+			return errors.New("place_id must be the ID of a place")
+		}
+		block.PlaceID = id.String()
+		block.Caption = strings.TrimSpace(b.Caption)
+	case BlockElevationChart:
+		block.Title = strings.TrimSpace(b.Title)
+	default:
+		return fmt.Errorf("unknown block type %q", b.Type)
+	}
+
+	if utf8.RuneCountInString(block.Text) > MaxBlockTextLength {
+		return fmt.Errorf("text is limited to %d characters", MaxBlockTextLength)
+	}
+	if utf8.RuneCountInString(block.Title) > MaxBlockLabelLength || utf8.RuneCountInString(block.Caption) > MaxBlockLabelLength {
+		return fmt.Errorf("titles and captions are limited to %d characters", MaxBlockLabelLength)
+	}
+
+	*b = block
+	return nil
+}
+
+// MediaIDs are the images the content shows, each once
+func (c TripContent) MediaIDs() []string {
+	ids := []string{}
+	seen := make(map[string]bool)
+	for _, block := range c {
+		if block.Type == BlockImage && !seen[block.MediaID] {
+			seen[block.MediaID] = true
+			ids = append(ids, block.MediaID)
+		}
+	}
+	return ids
+}
+
+// PlainText is the text of the content's text and warning blocks, and the
+// captions of its images and places, as the plain description older clients,
+// search and exports show. It is cut to the length of a description.
+func (c TripContent) PlainText() string {
+	var paragraphs []string
+	for _, block := range c {
+		var text string
+		switch block.Type {
+		case BlockText:
+			text = richtext.Parse(block.Text).Text
+		case BlockWarning:
+			text = richtext.Parse(block.Text).Text
+			if block.Title != "" {
+				text = block.Title + ": " + text
+			}
+		case BlockImage, BlockPlace:
+			text = block.Caption
+		}
+		if text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+	}
+
+	text := strings.Join(paragraphs, "\n\n")
+	if runes := []rune(text); len(runes) > maxDescriptionLength {
+		text = strings.TrimSpace(string(runes[:maxDescriptionLength-1])) + "…"
+	}
+	return text
+}
+
+// maxDescriptionLength is how long a trip's plain description may be
+const maxDescriptionLength = 1000
+
+// fillContent shows a trip described in plain text only as a single text
+// block, so that clients can render every trip from its content
+func (t *Trip) fillContent() {
+	if t.Content == nil && t.Description != "" {
+		t.Content = TripContent{{Type: BlockText, Text: richtext.Sanitize(t.Description)}}
+	}
+}
+
+// Value implements the driver.Valuer interface for TripContent
+func (c TripContent) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+// Scan implements the sql.Scanner interface for TripContent
+func (c *TripContent) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return nil
+	}
+
+	return json.Unmarshal(data, c)
+}
@@ -0,0 +1,177 @@
+package trips
+
+import (
+	"math"
+	"time"
+)
+
+// Crowd levels
+const (
+	CrowdUnknown  = "unknown" // Too few visits to tell
+	CrowdQuiet    = "quiet"
+	CrowdModerate = "moderate"
+	CrowdBusy     = "busy"
+)
+
+const (
+	// crowdWindow is how far back visits are counted
+	crowdWindow = 365 * 24 * time.Hour
+
+	// minCrowdVisits is the fewest visits a crowd estimate is made from
+	minCrowdVisits = 5
+
+	// Visits per week over the window above which a trip is moderately busy
+	// or busy
+	moderateVisitsPerWeek = 1.0
+	busyVisitsPerWeek     = 5.0
+
+	// A bucket is quiet or busy when it is this far below or above the
+	// average bucket
+	quietBucketRatio = 0.75
+	busyBucketRatio  = 1.25
+)
+
+// Hours of the day the best time to go is chosen from, so that the middle of
+// the night is never suggested
+const (
+	firstDaytimeHour = 6
+	lastDaytimeHour  = 18
+)
+
+// CrowdBucket is how many visits fell on one day of the week or hour of the
+// day, and how that compares with the rest
+type CrowdBucket struct {
+	Weekday *int    `json:"weekday,omitempty"` // 0 is Sunday
+	Day     string  `json:"day,omitempty"`
+	Hour    *int    `json:"hour,omitempty"`
+	Visits  int     `json:"visits"`
+	Share   float64 `json:"share"`
+	Level   string  `json:"level"`
+}
+
+// BestTime is the quietest day and daytime hour to go
+type BestTime struct {
+	Weekday int    `json:"weekday"`
+	Day     string `json:"day"`
+	Hour    int    `json:"hour"`
+}
+
+// CrowdEstimate is how busy a trip tends to be, judged from when it was
+// completed and when conditions were reported on it over the past year.
+// Times are in the trip's time zone.
+type CrowdEstimate struct {
+	TripID        string        `json:"trip_id"`
+	Visits        int           `json:"visits"`
+	VisitsPerWeek float64       `json:"visits_per_week"`
+	Level         string        `json:"level"`
+	WeekendShare  float64       `json:"weekend_share"`
+	Weekdays      []CrowdBucket `json:"weekdays"`
+	Hours         []CrowdBucket `json:"hours"`
+	BestTime      *BestTime     `json:"best_time,omitempty"`
+	Since         time.Time     `json:"since"`
+}
+
+// estimateCrowd buckets visit times by day of the week and hour of the day in
+// the trip's time zone
+func estimateCrowd(trip *Trip, visits []time.Time, since time.Time) *CrowdEstimate {
+	location := tripLocation(trip)
+
+	var days [7]int
+	var hours [24]int
+	for _, visit := range visits {
+		local := visit.In(location)
+		days[local.Weekday()]++
+		hours[local.Hour()]++
+	}
+
+	estimate := &CrowdEstimate{
+		TripID:   trip.ID,
+		Visits:   len(visits),
+		Level:    CrowdUnknown,
+		Weekdays: make([]CrowdBucket, 0, len(days)),
+		Hours:    make([]CrowdBucket, 0, len(hours)),
+		Since:    since,
+	}
+
+	weeks := crowdWindow.Hours() / (24 * 7)
+	estimate.VisitsPerWeek = math.Round(float64(len(visits))/weeks*100) / 100
+
+	for day, count := range days {
+		weekday := day
+		estimate.Weekdays = append(estimate.Weekdays, CrowdBucket{
+			Weekday: &weekday,
+			Day:     time.Weekday(day).String(),
+			Visits:  count,
+		})
+	}
+	for hour, count := range hours {
+		hour := hour
+		estimate.Hours = append(estimate.Hours, CrowdBucket{
+			Hour:   &hour,
+			Visits: count,
+		})
+	}
+
+	enough := len(visits) >= minCrowdVisits
+	rateBuckets(estimate.Weekdays, len(visits), enough)
+	rateBuckets(estimate.Hours, len(visits), enough)
+	if !enough {
+		return estimate
+	}
+
+	estimate.WeekendShare = math.Round(float64(days[time.Saturday]+days[time.Sunday])/float64(len(visits))*100) / 100
+
+	switch {
+	case estimate.VisitsPerWeek >= busyVisitsPerWeek:
+		estimate.Level = CrowdBusy
+	case estimate.VisitsPerWeek >= moderateVisitsPerWeek:
+		estimate.Level = CrowdModerate
+	default:
+		estimate.Level = CrowdQuiet
+	}
+
+	// Ties go to the earliest day from Monday and the earliest hour
+	bestDay := time.Monday
+	for i := 1; i < 7; i++ {
+		day := time.Weekday((int(time.Monday) + i) % 7)
+		if days[day] < days[bestDay] {
+			bestDay = day
+		}
+	}
+	bestHour := firstDaytimeHour
+	for hour := firstDaytimeHour + 1; hour <= lastDaytimeHour; hour++ {
+		if hours[hour] < hours[bestHour] {
+			bestHour = hour
+		}
+	}
+	estimate.BestTime = &BestTime{
+		Weekday: int(bestDay),
+		Day:     bestDay.String(),
+		Hour:    bestHour,
+	}
+
+	return estimate
+}
+
+// rateBuckets sets each bucket's share of all visits and, when there are
+// enough visits to tell, how busy it is against the average bucket
+func rateBuckets(buckets []CrowdBucket, total int, enough bool) {
+	average := float64(total) / float64(len(buckets))
+	for i := range buckets {
+		bucket := &buckets[i]
+		if total > 0 {
+			bucket.Share = math.Round(float64(bucket.Visits)/float64(total)*1000) / 1000
+		}
+
+		switch {
+		case !enough:
+			bucket.Level = CrowdUnknown
+		case float64(bucket.Visits) < average*quietBucketRatio:
+			bucket.Level = CrowdQuiet
+		case float64(bucket.Visits) > average*busyBucketRatio:
+			bucket.Level = CrowdBusy
+		default:
+			bucket.Level = CrowdModerate
+		}
+	}
+}
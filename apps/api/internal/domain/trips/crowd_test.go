@@ -0,0 +1,85 @@
+package trips
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateCrowd(t *testing.T) {
+	since := time.Date(2025, 7, 6, 0, 0, 0, 0, time.UTC)
+	saturday := time.Date(2026, 7, 4, 7, 0, 0, 0, time.UTC)
+	repeat := func(at time.Time, n int) []time.Time {
+		visits := make([]time.Time, n)
+		for i := range visits {
+			visits[i] = at.AddDate(0, 0, -7*i)
+		}
+		return visits
+	}
+
+	t.Run("busy weekends, quiet weekdays", func(t *testing.T) {
+		visits := repeat(saturday, 6)
+		visits = append(visits, repeat(saturday.AddDate(0, 0, 1), 2)...)
+		visits = append(visits, repeat(saturday.Add(-3*24*time.Hour+7*time.Hour), 2)...)
+
+		estimate := estimateCrowd(&Trip{ID: tripID, Timezone: "Asia/Jerusalem"}, visits, since)
+
+		assert.Equal(t, 10, estimate.Visits)
+		assert.Equal(t, 0.19, estimate.VisitsPerWeek)
+		assert.Equal(t, CrowdQuiet, estimate.Level)
+		assert.Equal(t, 0.8, estimate.WeekendShare)
+		assert.Equal(t, since, estimate.Since)
+
+		require.Len(t, estimate.Weekdays, 7)
+		saturdays := estimate.Weekdays[time.Saturday]
+		assert.Equal(t, "Saturday", saturdays.Day)
+		assert.Equal(t, 6, saturdays.Visits)
+		assert.Equal(t, 0.6, saturdays.Share)
+		assert.Equal(t, CrowdBusy, saturdays.Level)
+		assert.Equal(t, CrowdQuiet, estimate.Weekdays[time.Monday].Level)
+
+		// Hours are in the trip's time zone
+		require.Len(t, estimate.Hours, 24)
+		assert.Equal(t, 8, estimate.Hours[10].Visits)
+		assert.Equal(t, 2, estimate.Hours[17].Visits)
+		assert.Zero(t, estimate.Hours[7].Visits)
+
+		// The quietest daytime slot, ties going to Monday and the morning
+		assert.Equal(t, &BestTime{Weekday: int(time.Monday), Day: "Monday", Hour: firstDaytimeHour}, estimate.BestTime)
+	})
+
+	t.Run("busy trip", func(t *testing.T) {
+		estimate := estimateCrowd(&Trip{ID: tripID}, repeat(saturday, 300), since)
+		assert.Equal(t, CrowdBusy, estimate.Level)
+		assert.Equal(t, 5.75, estimate.VisitsPerWeek)
+		assert.Equal(t, 1.0, estimate.WeekendShare)
+	})
+
+	t.Run("moderately busy trip", func(t *testing.T) {
+		estimate := estimateCrowd(&Trip{ID: tripID}, repeat(saturday, 60), since)
+		assert.Equal(t, CrowdModerate, estimate.Level)
+	})
+
+	t.Run("too few visits to tell", func(t *testing.T) {
+		estimate := estimateCrowd(&Trip{ID: tripID}, repeat(saturday, minCrowdVisits-1), since)
+
+		assert.Equal(t, CrowdUnknown, estimate.Level)
+		assert.Nil(t, estimate.BestTime)
+		assert.Zero(t, estimate.WeekendShare)
+		// Shares are still given, but not rated
+		assert.Equal(t, 1.0, estimate.Weekdays[time.Saturday].Share)
+		for _, bucket := range append(estimate.Weekdays, estimate.Hours...) {
+			assert.Equal(t, CrowdUnknown, bucket.Level)
+		}
+	})
+
+	t.Run("no visits", func(t *testing.T) {
+		estimate := estimateCrowd(&Trip{ID: tripID}, nil, since)
+
+		assert.Equal(t, CrowdUnknown, estimate.Level)
+		assert.Len(t, estimate.Weekdays, 7)
+		assert.Zero(t, estimate.Weekdays[0].Share)
+	})
+}
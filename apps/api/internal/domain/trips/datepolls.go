@@ -0,0 +1,337 @@
+package trips
+
+import (
+	"context"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+)
+
+// Date poll statuses
+const (
+	DatePollOpen      = "open"
+	DatePollFinalized = "finalized"
+)
+
+// Availability of a member for a proposed date range
+const (
+	AvailabilityYes   = "available"
+	AvailabilityMaybe = "maybe"
+	AvailabilityNo    = "unavailable"
+)
+
+// DatePoll asks a group trip's members which of the proposed date ranges
+// they can make. Finalizing it sets the trip's dates.
+type DatePoll struct {
+	ID             string     `db:"id" json:"id"`
+	TripID         string     `db:"trip_id" json:"trip_id"`
+	Title          string     `db:"title" json:"title"`
+	Status         string     `db:"status" json:"status"`
+	CreatedBy      string     `db:"created_by" json:"created_by"`
+	ChosenOptionID *string    `db:"chosen_option_id" json:"chosen_option_id"`
+	FinalizedAt    *time.Time `db:"finalized_at" json:"finalized_at"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+
+	Options []*DatePollOption `db:"-" json:"options"`
+
+	// The option the most members can make, worked out whenever the poll
+	// is read. Empty until someone has answered.
+	BestOptionID string `db:"-" json:"best_option_id,omitempty"`
+}
+
+// DatePollOption is a proposed date range and who can make it
+type DatePollOption struct {
+	ID        string         `db:"id" json:"id"`
+	PollID    string         `db:"poll_id" json:"poll_id"`
+	StartDate time.Time      `db:"start_date" json:"start_date"`
+	EndDate   time.Time      `db:"end_date" json:"end_date"`
+	Position  int            `db:"position" json:"position"`
+	Votes     []DatePollVote `db:"-" json:"votes"`
+
+	Available   int `db:"-" json:"available"`
+	Maybe       int `db:"-" json:"maybe"`
+	Unavailable int `db:"-" json:"unavailable"`
+}
+
+// DatePollVote is a member's availability for a proposed date range
+type DatePollVote struct {
+	OptionID     string    `db:"option_id" json:"option_id"`
+	UserID       string    `db:"user_id" json:"user_id"`
+	Availability string    `db:"availability" json:"availability"`
+	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+}
+
+type DateRangeInput struct {
+	StartDate time.Time `json:"start_date" binding:"required"`
+	EndDate   time.Time `json:"end_date" binding:"required"`
+}
+
+type CreateDatePollInput struct {
+	Title   string           `json:"title" binding:"max=200"`
+	Options []DateRangeInput `json:"options" binding:"required,min=1,max=20,dive"`
+}
+
+type AvailabilityInput struct {
+	OptionID     string `json:"option_id" binding:"required,uuid"`
+	Availability string `json:"availability" binding:"required,oneof=available maybe unavailable"`
+}
+
+type SetAvailabilityInput struct {
+	Votes []AvailabilityInput `json:"votes" binding:"required,min=1,dive"`
+}
+
+type FinalizeDatePollInput struct {
+	// The option to go with; the best one when left out
+	OptionID string `json:"option_id" binding:"omitempty,uuid"`
+}
+
+// IsOpen reports whether members can still answer the poll
+func (p *DatePoll) IsOpen() bool {
+	return p.Status == DatePollOpen
+}
+
+// Option returns the poll's option with the ID, or nil
+func (p *DatePoll) Option(id string) *DatePollOption {
+	for _, option := range p.Options {
+		if option.ID == id {
+			return option
+		}
+	}
+	return nil
+}
+
+// tally counts the answers to each option and picks the best one: the one
+// the most members can make, counting a maybe as half, then the one the
+// fewest cannot make, then the earliest
+func (p *DatePoll) tally() {
+	p.BestOptionID = ""
+
+	var best *DatePollOption
+	for _, option := range p.Options {
+		option.Available, option.Maybe, option.Unavailable = 0, 0, 0
+		for _, vote := range option.Votes {
+			switch vote.Availability {
+			case AvailabilityYes:
+				option.Available++
+			case AvailabilityMaybe:
+				option.Maybe++
+			case AvailabilityNo:
+				option.Unavailable++
+			}
+		}
+
+		if option.Available+option.Maybe == 0 {
+			continue
+		}
+		if best == nil || betterDates(option, best) {
+			best = option
+		}
+	}
+
+	if best != nil {
+		p.BestOptionID = best.ID
+	}
+}
+
+func betterDates(a, b *DatePollOption) bool {
+	// Doubled so a maybe counts as half without fractions
+	scoreA, scoreB := 2*a.Available+a.Maybe, 2*b.Available+b.Maybe
+	if scoreA != scoreB {
+		return scoreA > scoreB
+	}
+	if a.Unavailable != b.Unavailable {
+		return a.Unavailable < b.Unavailable
+	}
+	return a.StartDate.Before(b.StartDate)
+}
+
+// ListDatePolls returns the date polls of a trip, newest first, with the
+// best option of each
+func (s *servicePg) ListDatePolls(ctx context.Context, userID, tripID string) ([]*DatePoll, error) {
+	if _, err := s.datePollTrip(ctx, userID, tripID); err != nil {
+		return nil, err
+	}
+
+	polls, err := s.repo.ListDatePolls(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, poll := range polls {
+		poll.tally()
+	}
+
+	return polls, nil
+}
+
+// GetDatePoll returns a date poll of a trip with its best option
+func (s *servicePg) GetDatePoll(ctx context.Context, userID, tripID, pollID string) (*DatePoll, error) {
+	if _, err := s.datePollTrip(ctx, userID, tripID); err != nil {
+		return nil, err
+	}
+
+	return s.tripDatePoll(ctx, tripID, pollID)
+}
+
+// CreateDatePoll proposes date ranges for the trip's members to answer.
+// Only the owner proposes dates.
+func (s *servicePg) CreateDatePoll(ctx context.Context, userID, tripID string, input *CreateDatePollInput) (*DatePoll, error) {
+	trip, err := s.datePollTrip(ctx, userID, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !trip.IsOwner(userID) {
+		return nil, ErrUnauthorized
+	}
+
+	poll := &DatePoll{
+		TripID:    tripID,
+		Title:     input.Title,
+		Status:    DatePollOpen,
+		CreatedBy: userID,
+	}
+	for _, dates := range input.Options {
+		start, end := calendarDay(dates.StartDate), calendarDay(dates.EndDate)
+		if end.Before(start) {
+			return nil, ErrInvalidDateRange
+		}
+		poll.Options = append(poll.Options, &DatePollOption{StartDate: start, EndDate: end})
+	}
+
+	if err := s.repo.CreateDatePoll(ctx, poll); err != nil {
+		return nil, err
+	}
+
+	return s.tripDatePoll(ctx, tripID, poll.ID)
+}
+
+// SetAvailability records which of an open poll's date ranges the member
+// can make
+func (s *servicePg) SetAvailability(ctx context.Context, userID, tripID, pollID string, input *SetAvailabilityInput) (*DatePoll, error) {
+	if _, err := s.datePollTrip(ctx, userID, tripID); err != nil {
+		return nil, err
+	}
+
+	poll, err := s.tripDatePoll(ctx, tripID, pollID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !poll.IsOpen() {
+		return nil, ErrDatePollClosed
+	}
+
+	votes := make([]DatePollVote, 0, len(input.Votes))
+	for _, vote := range input.Votes {
+		if poll.Option(vote.OptionID) == nil {
+			return nil, ErrUnknownDateOption
+		}
+		votes = append(votes, DatePollVote{OptionID: vote.OptionID, UserID: userID, Availability: vote.Availability})
+	}
+
+	if err := s.repo.SetAvailability(ctx, userID, votes); err != nil {
+		return nil, err
+	}
+
+	return s.tripDatePoll(ctx, tripID, pollID)
+}
+
+// FinalizeDatePoll closes a poll on the chosen date range, or the best one
+// when none is chosen, and sets the trip's dates to it
+func (s *servicePg) FinalizeDatePoll(ctx context.Context, userID, tripID, pollID string, input *FinalizeDatePollInput) (*DatePoll, error) {
+	trip, err := s.datePollTrip(ctx, userID, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !trip.IsOwner(userID) {
+		return nil, ErrUnauthorized
+	}
+
+	poll, err := s.tripDatePoll(ctx, tripID, pollID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !poll.IsOpen() {
+		return nil, ErrDatePollClosed
+	}
+
+	optionID := input.OptionID
+	if optionID == "" {
+		if poll.BestOptionID == "" {
+			return nil, ErrNoBestDates
+		}
+		optionID = poll.BestOptionID
+	}
+	if poll.Option(optionID) == nil {
+		return nil, ErrUnknownDateOption
+	}
+
+	if err := s.repo.FinalizeDatePoll(ctx, pollID, optionID); err != nil {
+		return nil, err
+	}
+
+	s.announce(ctx, events.TripUpdated, tripID, userID, map[string]interface{}{
+		"fields":  []string{"start_date", "end_date"},
+		"poll_id": pollID,
+	})
+
+	return s.tripDatePoll(ctx, tripID, pollID)
+}
+
+// DeleteDatePoll removes a date poll. Finalized polls can be removed too;
+// the trip keeps its dates.
+func (s *servicePg) DeleteDatePoll(ctx context.Context, userID, tripID, pollID string) error {
+	trip, err := s.datePollTrip(ctx, userID, tripID)
+	if err != nil {
+		return err
+	}
+
+	if !trip.IsOwner(userID) {
+		return ErrUnauthorized
+	}
+
+	if _, err := s.tripDatePoll(ctx, tripID, pollID); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteDatePoll(ctx, pollID)
+}
+
+// datePollTrip loads a trip whose date polls the user may take part in: the
+// owner's and the collaborators'
+func (s *servicePg) datePollTrip(ctx context.Context, userID, tripID string) (*Trip, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if userID == "" || !(trip.IsOwner(userID) || trip.HasCollaborator(userID)) {
+		return nil, ErrUnauthorized
+	}
+
+	return trip, nil
+}
+
+// tripDatePoll loads a date poll of the trip and tallies it
+func (s *servicePg) tripDatePoll(ctx context.Context, tripID, pollID string) (*DatePoll, error) {
+	poll, err := s.repo.GetDatePoll(ctx, pollID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.TripID != tripID {
+		return nil, ErrDatePollNotFound
+	}
+
+	poll.tally()
+
+	return poll, nil
+}
+
+// calendarDay drops the time of day, keeping the date as given
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
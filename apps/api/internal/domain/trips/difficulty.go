@@ -0,0 +1,115 @@
+package trips
+
+import (
+	"math"
+	"strings"
+)
+
+// Difficulty levels, from easiest
+const (
+	DifficultyEasy     = "easy"
+	DifficultyModerate = "moderate"
+	DifficultyHard     = "hard"
+	DifficultyExpert   = "expert"
+)
+
+// terrainEffort is the extra effort of terrain that slows progress or needs
+// technique. A word matches any terrain type containing it, so "loose scree"
+// counts as scree.
+var terrainEffort = map[string]float64{
+	"scree":       1,
+	"boulder":     1,
+	"rock":        1,
+	"sand":        0.5,
+	"mud":         0.5,
+	"snow":        1,
+	"scramble":    2,
+	"ice":         2,
+	"glacier":     3,
+	"via ferrata": 2,
+	"technical":   2,
+}
+
+// EstimateDifficulty rates a trip from its distance, elevation gain, highest
+// point and terrain. Each adds effort points: one per 8 km, one per 500 m of
+// climbing, more for altitude, and the hardest terrain type. It reports false
+// when the trip has none of these to go on.
+func EstimateDifficulty(trip *Trip) (string, bool) {
+	known := false
+	effort := 0.0
+
+	if trip.DistanceKm != nil && *trip.DistanceKm > 0 {
+		known = true
+		effort += math.Min(*trip.DistanceKm/8, 4)
+	}
+	if trip.ElevationGainM != nil && *trip.ElevationGainM > 0 {
+		known = true
+		effort += math.Min(float64(*trip.ElevationGainM)/500, 4)
+	}
+	if trip.MaxElevationM != nil {
+		known = true
+		switch {
+		case *trip.MaxElevationM >= 4500:
+			effort += 3
+		case *trip.MaxElevationM >= 3500:
+			effort += 2
+		case *trip.MaxElevationM >= 2500:
+			effort += 1
+		}
+	}
+	if len(trip.TerrainTypes) > 0 {
+		known = true
+		hardest := 0.0
+		for _, terrain := range trip.TerrainTypes {
+			terrain = strings.ToLower(terrain)
+			for word, extra := range terrainEffort {
+				if strings.Contains(terrain, word) && extra > hardest {
+					hardest = extra
+				}
+			}
+		}
+		effort += hardest
+	}
+
+	if !known {
+		return "", false
+	}
+
+	switch {
+	case effort < 2:
+		return DifficultyEasy, true
+	case effort < 4:
+		return DifficultyModerate, true
+	case effort < 6.5:
+		return DifficultyHard, true
+	default:
+		return DifficultyExpert, true
+	}
+}
+
+// reestimateDifficulty adds a new estimate to the updates when they change
+// what the estimate is made from
+func reestimateDifficulty(trip *Trip, input *UpdateTripInput, updates map[string]interface{}) {
+	updated := *trip
+	changed := false
+	if input.DistanceKm != nil {
+		updated.DistanceKm, changed = input.DistanceKm, true
+	}
+	if input.ElevationGainM != nil {
+		updated.ElevationGainM, changed = input.ElevationGainM, true
+	}
+	if input.MaxElevationM != nil {
+		updated.MaxElevationM, changed = input.MaxElevationM, true
+	}
+	if len(input.TerrainTypes) > 0 {
+		updated.TerrainTypes, changed = input.TerrainTypes, true
+	}
+	if !changed {
+		return
+	}
+
+	if level, ok := EstimateDifficulty(&updated); ok && level != trip.DifficultyLevel {
+		updates["difficulty_level"] = level
+		updates["difficulty_estimated"] = true
+	}
+}
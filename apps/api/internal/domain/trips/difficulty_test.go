@@ -0,0 +1,63 @@
+package trips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateDifficulty(t *testing.T) {
+	metres := func(m int) *int { return &m }
+
+	tests := []struct {
+		name  string
+		trip  *Trip
+		want  string
+		known bool
+	}{
+		{"nothing to go on", &Trip{}, "", false},
+		{"zero distance and climb", &Trip{DistanceKm: float64Ptr(0), ElevationGainM: metres(0)}, "", false},
+		{"short walk", &Trip{DistanceKm: float64Ptr(8)}, DifficultyEasy, true},
+		{"low altitude alone", &Trip{MaxElevationM: metres(1200)}, DifficultyEasy, true},
+		{"moderate from distance", &Trip{DistanceKm: float64Ptr(16)}, DifficultyModerate, true},
+		{"distance counts for at most four", &Trip{DistanceKm: float64Ptr(200)}, DifficultyHard, true},
+		{"distance and climbing", &Trip{DistanceKm: float64Ptr(16), ElevationGainM: metres(1000)}, DifficultyHard, true},
+		{"just under expert", &Trip{DistanceKm: float64Ptr(16), ElevationGainM: metres(1000), MaxElevationM: metres(3500)}, DifficultyHard, true},
+		{"high altitude", &Trip{DistanceKm: float64Ptr(16), ElevationGainM: metres(1000), MaxElevationM: metres(4500)}, DifficultyExpert, true},
+		{"altitude bands", &Trip{MaxElevationM: metres(2500), DistanceKm: float64Ptr(8)}, DifficultyModerate, true},
+		{"terrain matches words within types", &Trip{TerrainTypes: []string{"Loose Scree"}}, DifficultyEasy, true},
+		{"hardest terrain counts", &Trip{TerrainTypes: []string{"sand", "glacier crossing", "mud"}}, DifficultyModerate, true},
+		{"terrain tips it over", &Trip{DistanceKm: float64Ptr(16), ElevationGainM: metres(1000), MaxElevationM: metres(3500), TerrainTypes: []string{"scree"}}, DifficultyExpert, true},
+		{"unknown terrain", &Trip{TerrainTypes: []string{"meadow"}}, DifficultyEasy, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, known := EstimateDifficulty(tt.trip)
+			assert.Equal(t, tt.want, level)
+			assert.Equal(t, tt.known, known)
+		})
+	}
+}
+
+func TestReestimateDifficulty(t *testing.T) {
+	trip := &Trip{DistanceKm: float64Ptr(8), DifficultyLevel: DifficultyEasy}
+
+	t.Run("a change to what it is made from", func(t *testing.T) {
+		updates := map[string]interface{}{}
+		reestimateDifficulty(trip, &UpdateTripInput{DistanceKm: float64Ptr(40)}, updates)
+		assert.Equal(t, map[string]interface{}{"difficulty_level": DifficultyHard, "difficulty_estimated": true}, updates)
+	})
+
+	t.Run("same level", func(t *testing.T) {
+		updates := map[string]interface{}{}
+		reestimateDifficulty(trip, &UpdateTripInput{DistanceKm: float64Ptr(10)}, updates)
+		assert.Empty(t, updates)
+	})
+
+	t.Run("other changes", func(t *testing.T) {
+		updates := map[string]interface{}{}
+		reestimateDifficulty(&Trip{DistanceKm: float64Ptr(40)}, &UpdateTripInput{Title: stringPtr("Renamed")}, updates)
+		assert.Empty(t, updates)
+	})
+}
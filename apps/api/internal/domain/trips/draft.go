@@ -0,0 +1,106 @@
+package trips
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MaxDraftSize limits the encoded size of a draft
+const MaxDraftSize = 256 * 1024
+
+// draftFields lists the trip fields a draft can change, taken from the JSON
+// names of UpdateTripInput. Anything else in a draft is editor state that is
+// stored but never applied.
+var draftFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(UpdateTripInput{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+// tripValues returns the trip as a JSON object so it can be compared field by
+// field with a draft
+func tripValues(trip *Trip) (map[string]interface{}, error) {
+	data, err := json.Marshal(trip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode trip: %w", err)
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to decode trip: %w", err)
+	}
+
+	return values, nil
+}
+
+// draftBase records the current trip value of every field the draft touches
+func draftBase(data JSONB, current map[string]interface{}) JSONB {
+	base := JSONB{}
+	for field := range data {
+		if draftFields[field] {
+			base[field] = current[field]
+		}
+	}
+	return base
+}
+
+// mergeDraft does a three-way merge of a draft into the current trip. A field
+// is applied when the trip still holds the value the draft started from, and
+// is a conflict when both sides changed it to different values.
+func mergeDraft(draft *TripDraft, current map[string]interface{}) (JSONB, []string, []DraftConflict) {
+	changes := JSONB{}
+	var applied []string
+	var conflicts []DraftConflict
+
+	fields := make([]string, 0, len(draft.Data))
+	for field := range draft.Data {
+		if draftFields[field] {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		mine := draft.Data[field]
+		theirs := current[field]
+
+		switch {
+		case sameValue(mine, theirs):
+			// Already in place
+		case sameValue(draft.Base[field], theirs):
+			changes[field] = mine
+			applied = append(applied, field)
+		default:
+			conflicts = append(conflicts, DraftConflict{
+				Field:   field,
+				Draft:   mine,
+				Current: theirs,
+			})
+		}
+	}
+
+	return changes, applied, conflicts
+}
+
+// sameValue compares two decoded JSON values
+func sameValue(a, b interface{}) bool {
+	left, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	right, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(left, right)
+}
@@ -0,0 +1,149 @@
+package trips
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/Oferzz/newMap/apps/api/internal/elevation"
+)
+
+// MaxElevationSamples is the most points an elevation profile is sampled
+// at, plenty for a chart
+const MaxElevationSamples = 200
+
+// elevationSpacingM is how closely the samples of a short route are spaced
+const elevationSpacingM = 10.0
+
+// climbThresholdM is how far the elevation has to move before it counts
+// towards the climb, so that the noise of the elevation model is not summed
+// into it
+const climbThresholdM = 3.0
+
+// ElevationSample is the elevation at a point along a route
+type ElevationSample struct {
+	DistanceKm float64 `json:"distance_km"` // How far along the route it is
+	ElevationM float64 `json:"elevation_m"`
+	Longitude  float64 `json:"longitude"`
+	Latitude   float64 `json:"latitude"`
+}
+
+// ElevationProfile is the elevation along a route, sampled at even
+// distances from its start to its end
+type ElevationProfile struct {
+	Points        []ElevationSample `json:"points"`
+	DistanceKm    float64           `json:"distance_km"`
+	GainM         int               `json:"gain_m"`
+	LossM         int               `json:"loss_m"`
+	MinElevationM int               `json:"min_elevation_m"`
+	MaxElevationM int               `json:"max_elevation_m"`
+}
+
+// ElevationProfiler works out the elevation profile of routes from an
+// elevation model, so their climb and high point need not be entered by hand
+type ElevationProfiler struct {
+	provider elevation.Provider
+}
+
+// NewElevationProfiler creates an elevation profiler
+func NewElevationProfiler(provider elevation.Provider) *ElevationProfiler {
+	return &ElevationProfiler{
+		provider: provider,
+	}
+}
+
+// Profile samples the elevation along a LineString or MultiLineString
+// route, failing with ErrNoRouteGeometry for any other geometry
+func (p *ElevationProfiler) Profile(ctx context.Context, route *GeoJSONRoute) (*ElevationProfile, error) {
+	distanceKm, ok := routeLengthKm(route)
+	if !ok {
+		return nil, ErrNoRouteGeometry
+	}
+
+	samples := sampleRoute(routeLines(route), MaxElevationSamples)
+	points := make([]elevation.Point, len(samples))
+	for i, sample := range samples {
+		points[i] = elevation.Point{Longitude: sample.Longitude, Latitude: sample.Latitude}
+	}
+
+	heights, err := p.provider.Elevations(ctx, points)
+	if err != nil {
+		return nil, err
+	}
+	if len(heights) != len(samples) {
+		return nil, fmt.Errorf("elevation provider returned %d heights for %d points", len(heights), len(samples))
+	}
+
+	profile := &ElevationProfile{Points: samples, DistanceKm: distanceKm}
+	lowest, highest := math.Inf(1), math.Inf(-1)
+	ref := heights[0]
+	gain, loss := 0.0, 0.0
+	for i, height := range heights {
+		profile.Points[i].ElevationM = math.Round(height*10) / 10
+		lowest, highest = math.Min(lowest, height), math.Max(highest, height)
+
+		switch {
+		case height-ref >= climbThresholdM:
+			gain += height - ref
+			ref = height
+		case ref-height >= climbThresholdM:
+			loss += ref - height
+			ref = height
+		}
+	}
+	profile.GainM = int(math.Round(gain))
+	profile.LossM = int(math.Round(loss))
+	profile.MinElevationM = int(math.Round(lowest))
+	profile.MaxElevationM = int(math.Round(highest))
+
+	return profile, nil
+}
+
+// sampleRoute picks points at even distances along the lines of a route,
+// from its start to its end, every elevationSpacingM up to maxSamples of
+// them. The gaps between lines are not counted.
+func sampleRoute(lines [][][]float64, maxSamples int) []ElevationSample {
+	total := 0.0
+	for _, line := range lines {
+		total += lineLength(line)
+	}
+
+	count := int(total/elevationSpacingM) + 1
+	count = int(math.Max(2, math.Min(float64(count), float64(maxSamples))))
+	step := total / float64(count-1)
+
+	samples := make([]ElevationSample, 0, count)
+	travelled := 0.0 // To the start of the segment
+	for _, line := range lines {
+		for i := 1; i < len(line); i++ {
+			a, b := line[i-1], line[i]
+			length := haversine(a, b)
+			for len(samples) < count && float64(len(samples))*step <= travelled+length {
+				along := float64(len(samples)) * step
+				t := 0.0
+				if length > 0 {
+					t = (along - travelled) / length
+				}
+				samples = append(samples, ElevationSample{
+					DistanceKm: math.Round(along) / 1000,
+					Longitude:  a[0] + (b[0]-a[0])*t,
+					Latitude:   a[1] + (b[1]-a[1])*t,
+				})
+			}
+			travelled += length
+		}
+	}
+
+	// Rounding can leave the end of the route just out of reach
+	last := lines[len(lines)-1]
+	end := last[len(last)-1]
+	for len(samples) < count {
+		samples = append(samples, ElevationSample{
+			DistanceKm: math.Round(total) / 1000,
+			Longitude:  end[0],
+			Latitude:   end[1],
+		})
+	}
+
+	return samples
+}
@@ -0,0 +1,370 @@
+package trips
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/gpx"
+	"github.com/Oferzz/newMap/apps/api/internal/locale"
+)
+
+// Export formats
+const (
+	ExportFormatGPX     = "gpx"
+	ExportFormatGeoJSON = "geojson"
+	ExportFormatICS     = "ics"
+)
+
+// exportContentTypes are the media types of the export formats
+var exportContentTypes = map[string]string{
+	ExportFormatGPX:     "application/gpx+xml",
+	ExportFormatGeoJSON: "application/geo+json",
+	ExportFormatICS:     "text/calendar; charset=utf-8",
+}
+
+var slugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+// TripExport is a trip written out in an export format, ready to download
+type TripExport struct {
+	Data        []byte
+	ContentType string
+	Filename    string
+}
+
+// exportFilename is the name an export of the trip is downloaded as, from
+// its title
+func exportFilename(trip *Trip, format string) string {
+	slug := strings.Trim(slugSeparators.ReplaceAllString(strings.ToLower(trip.Title), "-"), "-")
+	if slug == "" {
+		slug = "trip"
+	}
+	return slug + "." + format
+}
+
+// ExportTrip writes the trip's route and waypoints out as GPX or GeoJSON, or
+// its dates and itinerary as an iCalendar file, as the user's audience sees
+// the trip. Route variants other than the primary one are included as
+// alternative routes, and GeoJSON exports carry the trip's annotations and
+// custom layers too. The descriptions written for people to read are
+// formatted for the user's preferred locale, or else the one their client
+// asks for in acceptLanguage.
+func (s *servicePg) ExportTrip(ctx context.Context, userID, tripID, format, acceptLanguage string) (*TripExport, error) {
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		return nil, ErrUnsupportedExportFormat
+	}
+
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	if audience := s.AudienceOf(ctx, userID, trip); audience != "" {
+		trip = trip.ForAudience(audience)
+	}
+
+	variants, err := s.repo.ListRouteVariants(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	alternatives := make([]*TripRouteVariant, 0, len(variants))
+	for _, variant := range variants {
+		if !variant.IsPrimary {
+			alternatives = append(alternatives, variant)
+		}
+	}
+
+	loc := s.exportLocale(ctx, userID, acceptLanguage)
+
+	var data []byte
+	switch format {
+	case ExportFormatGPX:
+		data, err = exportGPX(trip, alternatives, loc)
+	case ExportFormatICS:
+		var itinerary *Itinerary
+		itinerary, err = planItinerary(ctx, trip, s.travel)
+		if err == nil {
+			data = exportICS(trip, itinerary, loc, time.Now())
+		}
+	default:
+		var annotations []*TripAnnotation
+		var layers []exportLayer
+		var layerFeatures []exportFeature
+		annotations, err = s.repo.ListAnnotations(ctx, tripID)
+		if err == nil && s.layers != nil {
+			layers, layerFeatures, err = s.layers.exportLayers(ctx, tripID)
+		}
+		if err == nil {
+			data, err = exportGeoJSON(trip, alternatives, annotations, layers, layerFeatures)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &TripExport{Data: data, ContentType: contentType, Filename: exportFilename(trip, format)}, nil
+}
+
+// exportLocale is the locale exports are formatted for: the user's preferred
+// one, or the one their client asks for. Failing to look up the preference
+// only costs the formatting, so it falls back on the client's.
+func (s *servicePg) exportLocale(ctx context.Context, userID, acceptLanguage string) locale.Locale {
+	var preferred, units string
+	if userID != "" && s.userRepo != nil {
+		if user, err := s.userRepo.GetByID(ctx, userID); err == nil {
+			preferred, units = user.Locale, user.Units
+		}
+	}
+	return locale.Resolve(preferred, units, acceptLanguage)
+}
+
+// exportGPX writes the trip as GPX: its route and alternatives as tracks and
+// its located waypoints as waypoints, typed by their kind
+func exportGPX(trip *Trip, alternatives []*TripRouteVariant, loc locale.Locale) ([]byte, error) {
+	file := &gpx.File{Name: trip.Title, Description: joinParagraphs(trip.Description, tripSummary(trip, loc))}
+	location := tripLocation(trip)
+
+	for _, waypoint := range trip.Waypoints {
+		if waypoint.Place == nil || waypoint.Place.Location == nil || !validPosition(waypoint.Place.Location.Coordinates) {
+			continue
+		}
+		position := waypoint.Place.Location.Coordinates
+		description := waypoint.PlainNotes()
+		if description == "" {
+			description = waypoint.Place.Description
+		}
+		description = joinParagraphs(description, waypointTimes(&waypoint, location, loc))
+		file.Waypoints = append(file.Waypoints, gpx.Waypoint{
+			Point:       gpx.Point{Lat: position[1], Lon: position[0]},
+			Name:        waypoint.Place.Name,
+			Description: description,
+			Type:        waypoint.Kind,
+		})
+	}
+
+	if track := routeTrack(trip.Title, trip.RouteGeoJSON); track != nil {
+		track.Description = routeSummary(trip.DistanceKm, trip.ElevationGainM, trip.DurationHours, loc)
+		file.Tracks = append(file.Tracks, track)
+	}
+	for _, variant := range alternatives {
+		if track := routeTrack(variant.Name, variant.RouteGeoJSON); track != nil {
+			track.Description = joinParagraphs(variant.Description, routeSummary(variant.DistanceKm, variant.ElevationGainM, variant.DurationHours, loc))
+			file.Tracks = append(file.Tracks, track)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := gpx.Write(&buf, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// tripSummary describes the trip's route and dates for people to read, such
+// as "12.4 km · 850 m climb · 5 h 30 min · 6/14/2026 – 6/16/2026"
+func tripSummary(trip *Trip, loc locale.Locale) string {
+	parts := []string{}
+	if summary := routeSummary(trip.DistanceKm, trip.ElevationGainM, trip.DurationHours, loc); summary != "" {
+		parts = append(parts, summary)
+	}
+	if trip.StartDate != nil {
+		dates := loc.Date(*trip.StartDate)
+		if trip.EndDate != nil && trip.EndDate.After(*trip.StartDate) {
+			dates += " – " + loc.Date(*trip.EndDate)
+		}
+		parts = append(parts, dates)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// routeSummary describes the length, climb and duration of a route, leaving
+// out what is not known
+func routeSummary(distanceKm *float64, elevationGainM *int, durationHours *float64, loc locale.Locale) string {
+	parts := []string{}
+	if distanceKm != nil {
+		parts = append(parts, loc.Distance(*distanceKm))
+	}
+	if elevationGainM != nil {
+		parts = append(parts, loc.Elevation(float64(*elevationGainM))+" climb")
+	}
+	if durationHours != nil {
+		parts = append(parts, loc.Duration(time.Duration(*durationHours*float64(time.Hour))))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// waypointTimes describes when the trip arrives at and leaves a waypoint, on
+// the trip's clock
+func waypointTimes(waypoint *Waypoint, location *time.Location, loc locale.Locale) string {
+	parts := []string{}
+	if waypoint.ArrivalTime != nil {
+		parts = append(parts, "Arrive "+loc.DateTime(waypoint.ArrivalTime.In(location)))
+	}
+	if waypoint.DepartureTime != nil {
+		parts = append(parts, "Leave "+loc.DateTime(waypoint.DepartureTime.In(location)))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// joinParagraphs joins the non-empty texts with blank lines between them
+func joinParagraphs(texts ...string) string {
+	paragraphs := make([]string, 0, len(texts))
+	for _, text := range texts {
+		if text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// routeTrack is a LineString or MultiLineString route as a track, or nil
+// for any other geometry
+func routeTrack(name string, route *GeoJSONRoute) *gpx.Track {
+	if _, ok := routeLengthKm(route); !ok {
+		return nil
+	}
+
+	track := &gpx.Track{Name: name}
+	for _, line := range routeLines(route) {
+		segment := make([]gpx.Point, 0, len(line))
+		for _, position := range line {
+			p := gpx.Point{Lat: position[1], Lon: position[0]}
+			if len(position) > 2 {
+				ele := position[2]
+				p.Ele = &ele
+			}
+			segment = append(segment, p)
+		}
+		track.Segments = append(track.Segments, segment)
+	}
+	return track
+}
+
+type exportFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   interface{}            `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type exportFeatureCollection struct {
+	Type        string          `json:"type"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Features    []exportFeature `json:"features"`
+	Layers      []exportLayer   `json:"layers,omitempty"`
+}
+
+// exportLayer refers to a custom layer of the trip, whose file can be fetched
+// from its URL for a limited time when the storage signs URLs
+type exportLayer struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Format       string `json:"format"`
+	FeatureCount int    `json:"feature_count"`
+	URL          string `json:"url"`
+}
+
+// exportGeoJSON writes the trip as a GeoJSON FeatureCollection of its route,
+// alternatives, located waypoints, annotations and the features of its custom
+// layers, each told apart by the feature property it holds. The layers
+// themselves are listed alongside, so KML layers can be fetched too.
+func exportGeoJSON(trip *Trip, alternatives []*TripRouteVariant, annotations []*TripAnnotation, layers []exportLayer, layerFeatures []exportFeature) ([]byte, error) {
+	collection := exportFeatureCollection{
+		Type:        "FeatureCollection",
+		Name:        trip.Title,
+		Description: trip.Description,
+		Features:    []exportFeature{},
+		Layers:      layers,
+	}
+
+	if trip.RouteGeoJSON != nil {
+		collection.Features = append(collection.Features, exportFeature{
+			Type:     "Feature",
+			Geometry: trip.RouteGeoJSON,
+			Properties: map[string]interface{}{
+				"feature":          "route",
+				"name":             trip.Title,
+				"activity_type":    trip.ActivityType,
+				"difficulty_level": trip.DifficultyLevel,
+				"distance_km":      trip.DistanceKm,
+				"elevation_gain_m": trip.ElevationGainM,
+				"max_elevation_m":  trip.MaxElevationM,
+				"duration_hours":   trip.DurationHours,
+			},
+		})
+	}
+
+	for _, variant := range alternatives {
+		if variant.RouteGeoJSON == nil {
+			continue
+		}
+		collection.Features = append(collection.Features, exportFeature{
+			Type:     "Feature",
+			Geometry: variant.RouteGeoJSON,
+			Properties: map[string]interface{}{
+				"feature":          "variant",
+				"name":             variant.Name,
+				"description":      variant.Description,
+				"distance_km":      variant.DistanceKm,
+				"elevation_gain_m": variant.ElevationGainM,
+				"duration_hours":   variant.DurationHours,
+			},
+		})
+	}
+
+	for _, waypoint := range trip.Waypoints {
+		if waypoint.Place == nil || waypoint.Place.Location == nil {
+			continue
+		}
+		properties := map[string]interface{}{
+			"feature":        "waypoint",
+			"kind":           waypoint.Kind,
+			"order":          waypoint.OrderPosition,
+			"place_id":       waypoint.PlaceID,
+			"name":           waypoint.Place.Name,
+			"place_type":     waypoint.Place.Type,
+			"notes":          waypoint.Notes,
+			"notes_format":   waypoint.NotesFormat,
+			"arrival_time":   waypoint.ArrivalTime,
+			"departure_time": waypoint.DepartureTime,
+		}
+		if waypoint.Window != nil {
+			properties["time_window"] = waypoint.Window
+		}
+		if len(waypoint.Place.AccessFees) > 0 {
+			properties["access_fees"] = waypoint.Place.AccessFees
+		}
+		collection.Features = append(collection.Features, exportFeature{
+			Type:       "Feature",
+			Geometry:   waypoint.Place.Location,
+			Properties: properties,
+		})
+	}
+
+	for _, annotation := range annotations {
+		properties := map[string]interface{}{
+			"feature": "annotation",
+			"kind":    annotation.Kind,
+			"label":   annotation.Label,
+		}
+		if annotation.Style != nil {
+			properties["style"] = annotation.Style
+		}
+		collection.Features = append(collection.Features, exportFeature{
+			Type:       "Feature",
+			Geometry:   annotation.Geometry,
+			Properties: properties,
+		})
+	}
+
+	collection.Features = append(collection.Features, layerFeatures...)
+
+	return json.Marshal(collection)
+}
@@ -0,0 +1,159 @@
+package trips
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Access fee kinds
+const (
+	FeeParkingPass = "parking_pass"
+	FeeEntryFee    = "entry_fee"
+	FeeShuttle     = "shuttle"
+	FeePermit      = "permit_fee"
+	FeeCamping     = "camping_fee"
+	FeeOther       = "other"
+)
+
+// What an access fee is charged for
+const (
+	FeePerPerson  = "person"
+	FeePerVehicle = "vehicle"
+	FeePerGroup   = "group"
+)
+
+// AccessFee is a fee or pass needed to get to or use a trip or place, such as
+// a parking pass, park entry fee or shuttle ticket. The amount is left out
+// when it varies or is not known; an annual pass that covers the fee is
+// noted by name.
+type AccessFee struct {
+	Kind     string   `json:"kind" binding:"required,oneof=parking_pass entry_fee shuttle permit_fee camping_fee other"`
+	Name     string   `json:"name" binding:"required,max=200"`
+	Amount   *float64 `json:"amount,omitempty" binding:"omitempty,min=0,max=100000"`
+	Currency string   `json:"currency,omitempty" binding:"required_with=Amount,omitempty,iso4217"`
+	Per      string   `json:"per,omitempty" binding:"omitempty,oneof=person vehicle group"`
+	URL      string   `json:"url,omitempty" binding:"omitempty,url,max=500"`
+	Notes    string   `json:"notes,omitempty" binding:"max=500"`
+}
+
+// AccessFees is a JSONB list of access fees
+type AccessFees []AccessFee
+
+// Value implements the driver.Valuer interface for AccessFees
+func (f AccessFees) Value() (driver.Value, error) {
+	if f == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(f)
+}
+
+// Scan implements the sql.Scanner interface for AccessFees
+func (f *AccessFees) Scan(value interface{}) error {
+	if value == nil {
+		*f = AccessFees{}
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return nil
+	}
+
+	return json.Unmarshal(data, f)
+}
+
+// TripFee is an access fee of a trip, or of a place it stops at
+type TripFee struct {
+	AccessFee
+	PlaceID   string `json:"place_id,omitempty"` // Empty for the trip's own fees
+	PlaceName string `json:"place_name,omitempty"`
+}
+
+// FeeTotal is what the priced fees of one currency and charge basis add up to
+type FeeTotal struct {
+	Currency string  `json:"currency"`
+	Per      string  `json:"per"`
+	Amount   float64 `json:"amount"`
+}
+
+// FeeSummary is every fee and pass a trip needs, with their totals
+type FeeSummary struct {
+	Fees     []TripFee  `json:"fees"`
+	Totals   []FeeTotal `json:"totals"`
+	Unpriced int        `json:"unpriced"` // Fees with no amount, left out of the totals
+}
+
+// summarizeFees gathers the trip's own fees and those of the places it stops
+// at, counting each place once however often it is visited. Totals are kept
+// apart by currency and by what they are charged per, since neither can be
+// added together.
+func summarizeFees(trip *Trip) *FeeSummary {
+	summary := &FeeSummary{
+		Fees:   []TripFee{},
+		Totals: []FeeTotal{},
+	}
+
+	for _, fee := range trip.AccessFees {
+		summary.Fees = append(summary.Fees, TripFee{AccessFee: fee})
+	}
+	seen := map[string]bool{}
+	for _, waypoint := range trip.Waypoints {
+		if waypoint.Kind == WaypointBailout || waypoint.Place == nil || seen[waypoint.Place.ID] {
+			continue
+		}
+		seen[waypoint.Place.ID] = true
+		for _, fee := range waypoint.Place.AccessFees {
+			summary.Fees = append(summary.Fees, TripFee{
+				AccessFee: fee,
+				PlaceID:   waypoint.Place.ID,
+				PlaceName: waypoint.Place.Name,
+			})
+		}
+	}
+
+	totals := map[FeeTotal]float64{}
+	for _, fee := range summary.Fees {
+		if fee.Amount == nil || fee.Currency == "" {
+			summary.Unpriced++
+			continue
+		}
+		per := fee.Per
+		if per == "" {
+			per = FeePerPerson
+		}
+		totals[FeeTotal{Currency: fee.Currency, Per: per}] += *fee.Amount
+	}
+	for key, amount := range totals {
+		key.Amount = math.Round(amount*100) / 100
+		summary.Totals = append(summary.Totals, key)
+	}
+	sort.Slice(summary.Totals, func(i, j int) bool {
+		if summary.Totals[i].Currency != summary.Totals[j].Currency {
+			return summary.Totals[i].Currency < summary.Totals[j].Currency
+		}
+		return summary.Totals[i].Per < summary.Totals[j].Per
+	})
+
+	return summary
+}
+
+// feesCheck passes when the trip needs no fees or passes, or someone has
+// confirmed they are paid for or in hand
+func feesCheck(fees *FeeSummary, confirmedAt *time.Time) ReadinessCheck {
+	return ReadinessCheck{
+		Check:       ReadinessFees,
+		Manual:      true,
+		ConfirmedAt: confirmedAt,
+		Passed:      len(fees.Fees) == 0 || confirmedAt != nil,
+		Action:      fmt.Sprintf("Pay for or arrange the %d fees and passes the trip needs, and confirm it", len(fees.Fees)),
+	}
+}
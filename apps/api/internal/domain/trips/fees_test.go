@@ -0,0 +1,96 @@
+package trips
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeFees(t *testing.T) {
+	amount := func(a float64) *float64 { return &a }
+	park := &Place{ID: "park", Name: "Ein Gedi", AccessFees: AccessFees{
+		{Kind: FeeEntryFee, Name: "Entry", Amount: amount(29.1), Currency: "ILS"},
+		{Kind: FeeParkingPass, Name: "Parking", Amount: amount(15), Currency: "ILS", Per: FeePerVehicle},
+	}}
+	shuttle := &Place{ID: "shuttle", Name: "Shuttle stop", AccessFees: AccessFees{
+		{Kind: FeeShuttle, Name: "Shuttle", Amount: amount(4.5), Currency: "USD", Per: FeePerPerson},
+		{Kind: FeeShuttle, Name: "Return shuttle", Amount: amount(4.45), Currency: "USD"},
+	}}
+	road := &Place{ID: "road", AccessFees: AccessFees{{Kind: FeeOther, Name: "Toll", Amount: amount(10), Currency: "ILS"}}}
+
+	trip := &Trip{
+		AccessFees: AccessFees{
+			{Kind: FeePermit, Name: "Permit", Amount: amount(10.9), Currency: "ILS", Per: FeePerPerson},
+			{Kind: FeeCamping, Name: "Camping, priced on arrival"},
+			{Kind: FeeOther, Name: "Donation", Amount: amount(5)},
+		},
+		Waypoints: []Waypoint{
+			{Kind: WaypointStop, Place: park},
+			{Kind: WaypointBailout, Place: road},
+			{Kind: WaypointStop, Place: shuttle},
+			{Kind: WaypointStop},
+			{Kind: WaypointStop, Place: park},
+		},
+	}
+
+	summary := summarizeFees(trip)
+
+	var names []string
+	for _, fee := range summary.Fees {
+		names = append(names, fee.Name)
+	}
+	// The trip's own fees come first, then each place's once, bail-outs left out
+	assert.Equal(t, []string{"Permit", "Camping, priced on arrival", "Donation", "Entry", "Parking", "Shuttle", "Return shuttle"}, names)
+	assert.Empty(t, summary.Fees[0].PlaceID)
+	assert.Equal(t, "park", summary.Fees[3].PlaceID)
+	assert.Equal(t, "Ein Gedi", summary.Fees[3].PlaceName)
+
+	// Totals never add up different currencies or charge bases
+	assert.Equal(t, []FeeTotal{
+		{Currency: "ILS", Per: FeePerPerson, Amount: 40},
+		{Currency: "ILS", Per: FeePerVehicle, Amount: 15},
+		{Currency: "USD", Per: FeePerPerson, Amount: 8.95},
+	}, summary.Totals)
+	assert.Equal(t, 2, summary.Unpriced)
+
+	empty := summarizeFees(&Trip{})
+	assert.Equal(t, []TripFee{}, empty.Fees)
+	assert.Equal(t, []FeeTotal{}, empty.Totals)
+}
+
+func TestFeesCheck(t *testing.T) {
+	fees := &FeeSummary{Fees: []TripFee{{AccessFee: AccessFee{Name: "Entry"}}, {AccessFee: AccessFee{Name: "Parking"}}}}
+
+	check := feesCheck(fees, nil)
+	assert.Equal(t, ReadinessFees, check.Check)
+	assert.True(t, check.Manual)
+	assert.False(t, check.Passed)
+	assert.Equal(t, "Pay for or arrange the 2 fees and passes the trip needs, and confirm it", check.Action)
+
+	confirmed := time.Now()
+	assert.True(t, feesCheck(fees, &confirmed).Passed)
+	assert.True(t, feesCheck(&FeeSummary{}, nil).Passed)
+}
+
+func TestAccessFees_ValueScan(t *testing.T) {
+	value, err := AccessFees(nil).Value()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("[]"), value)
+
+	entry := 29.0
+	fees := AccessFees{{Kind: FeeEntryFee, Name: "Entry", Amount: &entry, Currency: "ILS"}}
+	value, err = fees.Value()
+	require.NoError(t, err)
+
+	var scanned AccessFees
+	require.NoError(t, scanned.Scan(value))
+	assert.Equal(t, fees, scanned)
+	require.NoError(t, scanned.Scan(string(value.([]byte))))
+	assert.Equal(t, fees, scanned)
+
+	require.NoError(t, scanned.Scan(nil))
+	assert.Equal(t, AccessFees{}, scanned)
+	assert.Error(t, scanned.Scan([]byte("{")))
+}
@@ -0,0 +1,76 @@
+package trips
+
+import (
+	"context"
+	"io"
+	"math"
+
+	"github.com/Oferzz/newMap/apps/api/internal/gpx"
+)
+
+// MaxGPXSize is the largest GPX file accepted for import, in bytes
+const MaxGPXSize = 20 * 1024 * 1024
+
+// ImportGPX replaces the trip's route with the track of a GPX file and sets
+// its distance, climb and highest point from it. Elevations the file does
+// not record are left as they were.
+func (s *servicePg) ImportGPX(ctx context.Context, userID, tripID string, file io.Reader) (*Trip, error) {
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+
+	track, err := gpx.Parse(file)
+	if err != nil {
+		return nil, ErrInvalidGPX
+	}
+
+	return s.update(ctx, userID, trip, gpxUpdate(track))
+}
+
+// gpxUpdate is the trip update that sets the route measured from a track
+func gpxUpdate(track *gpx.Track) *UpdateTripInput {
+	// Kept to the precision the column stores
+	distance := math.Round(track.DistanceKm()*100) / 100
+	input := &UpdateTripInput{
+		RouteGeoJSON: trackRoute(track),
+		DistanceKm:   &distance,
+	}
+
+	if gain, ok := track.ElevationGainM(); ok {
+		rounded := int(math.Round(gain))
+		input.ElevationGainM = &rounded
+	}
+	if highest, ok := track.MaxElevationM(); ok {
+		rounded := int(math.Round(highest))
+		input.MaxElevationM = &rounded
+	}
+
+	return input
+}
+
+// trackRoute is a track as a LineString, or a MultiLineString when it was
+// recorded in several segments. Positions carry their elevation when the
+// file records one.
+func trackRoute(track *gpx.Track) *GeoJSONRoute {
+	lines := make([][][]float64, len(track.Segments))
+	for i, segment := range track.Segments {
+		line := make([][]float64, len(segment))
+		for j, p := range segment {
+			line[j] = []float64{p.Lon, p.Lat}
+			if p.Ele != nil {
+				line[j] = append(line[j], *p.Ele)
+			}
+		}
+		lines[i] = line
+	}
+
+	if len(lines) == 1 {
+		return &GeoJSONRoute{Type: "LineString", Coordinates: lines[0]}
+	}
+	return &GeoJSONRoute{Type: "MultiLineString", Coordinates: lines}
+}
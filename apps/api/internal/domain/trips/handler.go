@@ -1,14 +1,40 @@
 package trips
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/Oferzz/newMap/apps/api/internal/directions"
+	"github.com/Oferzz/newMap/apps/api/internal/elevation"
+	"github.com/Oferzz/newMap/apps/api/pkg/fieldset"
+	"github.com/Oferzz/newMap/apps/api/pkg/httpcache"
+	"github.com/Oferzz/newMap/apps/api/pkg/pagination"
+	"github.com/Oferzz/newMap/apps/api/pkg/repoerr"
 	"github.com/Oferzz/newMap/apps/api/pkg/response"
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
 	service Service
+	tokens  ShareTokenIssuer
+	layers  *LayerService
+	offline *OfflinePackService
+	alerts  *ConditionAlerts
+	planner  *RoutePlanner
+	profiler *ElevationProfiler
+	views    ViewRecorder
+}
+
+// ShareTokenIssuer mints the scoped tokens handed to share-link guests
+type ShareTokenIssuer interface {
+	GenerateScopedToken(tripID, scope string) (string, error)
+	GetShareTokenExpiry() time.Duration
 }
 
 func NewHandler(service Service) *Handler {
@@ -17,6 +43,47 @@ func NewHandler(service Service) *Handler {
 	}
 }
 
+// SetShareTokenIssuer enables redeeming share links for scoped tokens
+func (h *Handler) SetShareTokenIssuer(tokens ShareTokenIssuer) {
+	h.tokens = tokens
+}
+
+// SetRoutePlanner enables planning trip routes through waypoints
+func (h *Handler) SetRoutePlanner(planner *RoutePlanner) {
+	h.planner = planner
+}
+
+// SetElevationProfiler enables elevation profiles, and measuring the climb
+// and high point of routes saved without them
+func (h *Handler) SetElevationProfiler(profiler *ElevationProfiler) {
+	h.profiler = profiler
+}
+
+// SetLayerService enables custom map layers
+func (h *Handler) SetLayerService(layers *LayerService) {
+	h.layers = layers
+}
+
+// SetOfflinePackService enables offline map packs
+func (h *Handler) SetOfflinePackService(offline *OfflinePackService) {
+	h.offline = offline
+}
+
+// SetConditionAlerts enables subscriptions to condition reports
+func (h *Handler) SetConditionAlerts(alerts *ConditionAlerts) {
+	h.alerts = alerts
+}
+
+// ViewRecorder remembers the trips a user has viewed
+type ViewRecorder interface {
+	RecordView(ctx context.Context, userID, kind, id string)
+}
+
+// SetViewRecorder enables remembering the trips users view
+func (h *Handler) SetViewRecorder(views ViewRecorder) {
+	h.views = views
+}
+
 // getUserID extracts the user ID from the gin context
 func getUserID(c *gin.Context) (string, bool) {
 	userIDValue, exists := c.Get("userID")
@@ -32,6 +99,29 @@ func getUserID(c *gin.Context) (string, bool) {
 	return userID, true
 }
 
+// getShareLink extracts the share link a request was made through from the
+// gin context
+func getShareLink(c *gin.Context) (*ActivityShareLink, bool) {
+	value, exists := c.Get("shareLink")
+	if !exists {
+		return nil, false
+	}
+
+	link, ok := value.(*ActivityShareLink)
+	return link, ok && link != nil
+}
+
+// getShareGrant extracts the grant of a share-link guest from the gin context
+func getShareGrant(c *gin.Context) (*ShareGrant, bool) {
+	value, exists := c.Get("shareGrant")
+	if !exists {
+		return nil, false
+	}
+
+	grant, ok := value.(*ShareGrant)
+	return grant, ok && grant != nil
+}
+
 func (h *Handler) Create(c *gin.Context) {
 	userID, exists := getUserID(c)
 	if !exists {
@@ -47,8 +137,13 @@ func (h *Handler) Create(c *gin.Context) {
 		return
 	}
 
+	h.measureElevation(c, input.RouteGeoJSON, &input.ElevationGainM, &input.MaxElevationM)
 	trip, err := h.service.Create(c.Request.Context(), userID, &input)
 	if err != nil {
+		if errors.Is(err, ErrInvalidTimezone) || errors.Is(err, ErrPrivacyMismatch) || errors.Is(err, ErrInvalidContent) {
+			response.BadRequest(c, err.Error())
+			return
+		}
 		response.InternalServerError(c, "Failed to create trip")
 		return
 	}
@@ -66,25 +161,125 @@ func (h *Handler) GetByID(c *gin.Context) {
 		userID = id
 	}
 
-	trip, err := h.service.GetByID(c.Request.Context(), tripID, userID)
+	fields := fieldset.Parse(c.Query("fields"))
+	include, err := fieldset.ParseInclude(c.Query("include"), "collaborators", "waypoints")
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	// The detail view loads everything unless told otherwise
+	relations := Relations{
+		Collaborators: include.Load("collaborators", fields, true),
+		Waypoints:     include.Load("waypoints", fields, true),
+	}
+
+	if audience := c.Query("view_as"); audience != "" {
+		h.viewAs(c, userID, tripID, audience, relations, fields)
+		return
+	}
+
+	// Collaborators decide how much of the trip the user sees
+	load := relations
+	load.Collaborators = true
+
+	var trip *Trip
+	if grant, ok := getShareGrant(c); ok {
+		trip, err = h.service.GetSharedWith(c.Request.Context(), grant, tripID, relations)
+	} else {
+		trip, err = h.service.GetByIDWith(c.Request.Context(), userID, tripID, load)
+	}
 	if err != nil {
-		switch err {
-		case ErrTripNotFound:
+		switch {
+		case errors.Is(err, ErrTripNotFound):
 			response.NotFound(c, "Trip not found")
-		case ErrUnauthorized:
+		case errors.Is(err, ErrUnauthorized):
 			response.Forbidden(c, "You don't have permission to view this trip")
 		default:
-			response.InternalServerError(c, "Failed to get trip")
+			response.FromError(c, err, "Failed to get trip")
 		}
 		return
 	}
 
-	response.Success(c, trip)
+	audience := ""
+	if _, shared := getShareGrant(c); !shared {
+		audience = h.service.AudienceOf(c.Request.Context(), userID, trip)
+		if audience != "" {
+			trip = trip.ForAudience(audience)
+		}
+		if !relations.Collaborators {
+			trip.Collaborators = nil
+		}
+	}
+
+	if h.views != nil && userID != "" {
+		h.views.RecordView(c.Request.Context(), userID, "trip", trip.ID)
+	}
+
+	// A failed estimate leaves the trip without one rather than failing it
+	estimate, err := h.service.EstimateDuration(c.Request.Context(), userID, trip)
+	if err == nil {
+		trip.EstimatedDurationForYou = estimate
+	}
+
+	data, err := fields.Select(trip)
+	if err != nil {
+		response.InternalServerError(c, "Failed to get trip")
+		return
+	}
+
+	// Only what everyone sees may be shared by caches, and an estimate from
+	// the user's own history never is
+	personal := estimate != nil && estimate.Basis == EstimateFromHistory
+	if trip.Privacy == "public" && audience == AudiencePublic && !personal {
+		httpcache.Public(c, httpcache.Detail, httpcache.TripKey(trip.ID))
+	} else {
+		httpcache.Private(c)
+	}
+
+	response.Success(c, data)
+}
+
+// viewAs shows the trip's owner the trip as an audience would see it
+func (h *Handler) viewAs(c *gin.Context, userID, tripID, audience string, relations Relations, fields fieldset.Set) {
+	if userID == "" {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	preview, err := h.service.ViewAs(c.Request.Context(), userID, tripID, audience, relations)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidAudience):
+			response.BadRequest(c, err.Error())
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "Only the trip's owner can preview it")
+		default:
+			response.FromError(c, err, "Failed to preview trip")
+		}
+		return
+	}
+
+	data, err := fields.Select(preview.Trip)
+	if err != nil {
+		response.InternalServerError(c, "Failed to preview trip")
+		return
+	}
+
+	httpcache.Private(c)
+	response.Success(c, gin.H{
+		"audience": preview.Audience,
+		"visible":  preview.Visible,
+		"trip":     data,
+	})
 }
 
 func (h *Handler) Update(c *gin.Context) {
 	userID, exists := getUserID(c)
-	if !exists {
+	grant, shared := getShareGrant(c)
+	if !exists && !shared {
 		response.Unauthorized(c, "User not authenticated")
 		return
 	}
@@ -100,15 +295,31 @@ func (h *Handler) Update(c *gin.Context) {
 		return
 	}
 
-	trip, err := h.service.Update(c.Request.Context(), tripID, userID, &input)
+	h.update(c, userID, grant, tripID, &input)
+}
+
+// update updates a trip as the signed-in user, or as the guest holding a
+// share link when nobody is signed in
+func (h *Handler) update(c *gin.Context, userID string, grant *ShareGrant, tripID string, input *UpdateTripInput) {
+	h.measureElevation(c, input.RouteGeoJSON, &input.ElevationGainM, &input.MaxElevationM)
+
+	var trip *Trip
+	var err error
+	if userID != "" {
+		trip, err = h.service.Update(c.Request.Context(), userID, tripID, input)
+	} else {
+		trip, err = h.service.UpdateShared(c.Request.Context(), grant, tripID, input)
+	}
 	if err != nil {
-		switch err {
-		case ErrTripNotFound:
+		switch {
+		case errors.Is(err, ErrTripNotFound):
 			response.NotFound(c, "Trip not found")
-		case ErrUnauthorized:
+		case errors.Is(err, ErrUnauthorized):
 			response.Forbidden(c, "You don't have permission to update this trip")
+		case errors.Is(err, ErrInvalidTimezone), errors.Is(err, ErrPrivacyMismatch), errors.Is(err, ErrInvalidContent):
+			response.BadRequest(c, err.Error())
 		default:
-			response.InternalServerError(c, "Failed to update trip")
+			response.FromError(c, err, "Failed to update trip")
 		}
 		return
 	}
@@ -116,6 +327,115 @@ func (h *Handler) Update(c *gin.Context) {
 	response.Success(c, trip)
 }
 
+// PlanRoute plans the trip's route through waypoints along roads and trails
+// and stores it, with its distance and duration
+func (h *Handler) PlanRoute(c *gin.Context) {
+	userID, exists := getUserID(c)
+	grant, shared := getShareGrant(c)
+	if !exists && !shared {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	if h.planner == nil {
+		response.NotFound(c, "Route planning is not available")
+		return
+	}
+
+	var input PlanRouteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	update, err := h.planner.Plan(c.Request.Context(), &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, directions.ErrNoRoute):
+			response.UnprocessableEntity(c, "No route found through these waypoints")
+		default:
+			log.Printf("Failed to plan route for trip %s: %v", c.Param("id"), err)
+			response.BadGateway(c, "Route planning failed, try again later")
+		}
+		return
+	}
+
+	h.update(c, userID, grant, c.Param("id"), update)
+}
+
+// measureElevation fills in the climb and high point of a route saved
+// without them. A failed lookup leaves them out rather than failing the save.
+func (h *Handler) measureElevation(c *gin.Context, route *GeoJSONRoute, gainM, maxM **int) {
+	if h.profiler == nil || route == nil || (*gainM != nil && *maxM != nil) {
+		return
+	}
+
+	profile, err := h.profiler.Profile(c.Request.Context(), route)
+	if err != nil {
+		if !errors.Is(err, ErrNoRouteGeometry) {
+			log.Printf("Failed to measure route elevation: %v", err)
+		}
+		return
+	}
+
+	if *gainM == nil {
+		*gainM = &profile.GainM
+	}
+	if *maxM == nil {
+		*maxM = &profile.MaxElevationM
+	}
+}
+
+// GetElevationProfile samples the elevation along the trip's route, for
+// charting
+func (h *Handler) GetElevationProfile(c *gin.Context) {
+	userID, _ := getUserID(c)
+	if h.profiler == nil {
+		response.NotFound(c, "Elevation profiles are not available")
+		return
+	}
+
+	trip, err := h.service.GetByIDWith(c.Request.Context(), userID, c.Param("id"), Relations{Waypoints: true})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to view this trip")
+		default:
+			response.FromError(c, err, "Failed to get trip")
+		}
+		return
+	}
+
+	route, ok := followedRoute(trip)
+	if !ok {
+		response.BadRequest(c, ErrNoRouteGeometry.Error())
+		return
+	}
+
+	profile, err := h.profiler.Profile(c.Request.Context(), route)
+	if err != nil {
+		switch {
+		case errors.Is(err, elevation.ErrNoData):
+			response.UnprocessableEntity(c, "No elevation data for this route")
+		default:
+			log.Printf("Failed to profile elevation for trip %s: %v", trip.ID, err)
+			response.BadGateway(c, "Elevation lookup failed, try again later")
+		}
+		return
+	}
+
+	if trip.Privacy == "public" {
+		httpcache.Public(c, httpcache.Detail, httpcache.TripKey(trip.ID))
+	} else {
+		httpcache.Private(c)
+	}
+
+	response.Success(c, profile)
+}
+
 func (h *Handler) Delete(c *gin.Context) {
 	userID, exists := getUserID(c)
 	if !exists {
@@ -128,13 +448,13 @@ func (h *Handler) Delete(c *gin.Context) {
 
 	err := h.service.Delete(c.Request.Context(), userID, tripID)
 	if err != nil {
-		switch err {
-		case ErrTripNotFound:
+		switch {
+		case errors.Is(err, ErrTripNotFound):
 			response.NotFound(c, "Trip not found")
-		case ErrUnauthorized:
+		case errors.Is(err, ErrUnauthorized):
 			response.Forbidden(c, "Only the trip owner can delete the trip")
 		default:
-			response.InternalServerError(c, "Failed to delete trip")
+			response.FromError(c, err, "Failed to delete trip")
 		}
 		return
 	}
@@ -183,13 +503,67 @@ func (h *Handler) List(c *gin.Context) {
 		filter.Privacy = "public"
 	}
 
+	// A cursor from a previous page takes precedence over page numbers
+	after, err := pagination.Decode(c.Query("cursor"))
+	if err != nil {
+		response.BadRequest(c, "Invalid cursor")
+		return
+	}
+	filter.After = after
+
+	fields := fieldset.Parse(c.Query("fields"))
+	include, err := fieldset.ParseInclude(c.Query("include"), "collaborators", "waypoints")
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	// Lists are lightweight unless related records are asked for
+	filter.Relations = &Relations{
+		Collaborators: include.Load("collaborators", fields, false),
+		Waypoints:     include.Load("waypoints", fields, false),
+	}
+
 	trips, total, err := h.service.List(c.Request.Context(), userID, filter, limit, offset)
 	if err != nil {
 		response.InternalServerError(c, "Failed to list trips")
 		return
 	}
 
-	response.SuccessWithMeta(c, trips, response.NewMeta(page, limit, total))
+	// Guests see trips as the public does
+	if !exists {
+		for i, trip := range trips {
+			trips[i] = trip.ForAudience(AudiencePublic)
+		}
+	}
+
+	var nextCursor string
+	if len(trips) > 0 {
+		last := trips[len(trips)-1]
+		nextCursor = pagination.Next(len(trips), limit, last.CreatedAt, last.ID)
+	}
+
+	data, err := fields.Select(trips)
+	if err != nil {
+		response.InternalServerError(c, "Failed to list trips")
+		return
+	}
+
+	// Anonymous lists only hold public trips, anything else is marked private
+	keys := []string{httpcache.TripListKey}
+	for _, trip := range trips {
+		keys = append(keys, httpcache.TripKey(trip.ID))
+	}
+	httpcache.Public(c, httpcache.Listing, keys...)
+
+	if after != nil {
+		response.SuccessWithMeta(c, data, response.NewCursorMeta(limit, nextCursor))
+		return
+	}
+
+	meta := response.NewMeta(page, limit, total)
+	meta.NextCursor = nextCursor
+	response.SuccessWithMeta(c, data, meta)
 }
 
 func (h *Handler) InviteCollaborator(c *gin.Context) {
@@ -212,11 +586,13 @@ func (h *Handler) InviteCollaborator(c *gin.Context) {
 
 	err := h.service.InviteCollaborator(c.Request.Context(), userID, tripID, &input)
 	if err != nil {
-		switch err {
-		case ErrTripNotFound:
+		switch {
+		case errors.Is(err, ErrTripNotFound):
 			response.NotFound(c, "Trip not found")
-		case ErrUnauthorized:
+		case errors.Is(err, ErrUnauthorized):
 			response.Forbidden(c, "You don't have permission to invite collaborators")
+		case repoerr.Known(err):
+			response.FromError(c, err, "Failed to update collaborators")
 		default:
 			response.BadRequest(c, err.Error())
 		}
@@ -228,6 +604,39 @@ func (h *Handler) InviteCollaborator(c *gin.Context) {
 	})
 }
 
+func (h *Handler) BulkInviteCollaborators(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	tripID := c.Param("id")
+
+	var input BulkInviteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	results, err := h.service.BulkInviteCollaborators(c.Request.Context(), userID, tripID, &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to invite collaborators")
+		default:
+			response.FromError(c, err, "Failed to invite collaborators")
+		}
+		return
+	}
+
+	response.Success(c, results)
+}
+
 func (h *Handler) RemoveCollaborator(c *gin.Context) {
 	userID, exists := getUserID(c)
 	if !exists {
@@ -243,11 +652,13 @@ func (h *Handler) RemoveCollaborator(c *gin.Context) {
 
 	err := h.service.RemoveCollaborator(c.Request.Context(), userID, tripID, collaboratorID)
 	if err != nil {
-		switch err {
-		case ErrTripNotFound:
+		switch {
+		case errors.Is(err, ErrTripNotFound):
 			response.NotFound(c, "Trip not found")
-		case ErrUnauthorized:
+		case errors.Is(err, ErrUnauthorized):
 			response.Forbidden(c, "Only the trip owner can remove collaborators")
+		case repoerr.Known(err):
+			response.FromError(c, err, "Failed to update collaborators")
 		default:
 			response.BadRequest(c, err.Error())
 		}
@@ -283,11 +694,13 @@ func (h *Handler) UpdateCollaboratorRole(c *gin.Context) {
 
 	err := h.service.UpdateCollaboratorRole(c.Request.Context(), userID, tripID, collaboratorID, input.Role)
 	if err != nil {
-		switch err {
-		case ErrTripNotFound:
+		switch {
+		case errors.Is(err, ErrTripNotFound):
 			response.NotFound(c, "Trip not found")
-		case ErrUnauthorized:
+		case errors.Is(err, ErrUnauthorized):
 			response.Forbidden(c, "Only the trip owner can update collaborator roles")
+		case repoerr.Known(err):
+			response.FromError(c, err, "Failed to update collaborators")
 		default:
 			response.BadRequest(c, err.Error())
 		}
@@ -312,9 +725,11 @@ func (h *Handler) LeaveTrip(c *gin.Context) {
 	// User leaves by removing themselves as a collaborator
 	err := h.service.RemoveCollaborator(c.Request.Context(), userID, tripID, userID)
 	if err != nil {
-		switch err {
-		case ErrTripNotFound:
+		switch {
+		case errors.Is(err, ErrTripNotFound):
 			response.NotFound(c, "Trip not found")
+		case repoerr.Known(err):
+			response.FromError(c, err, "Failed to update collaborators")
 		default:
 			response.BadRequest(c, err.Error())
 		}
@@ -324,4 +739,1992 @@ func (h *Handler) LeaveTrip(c *gin.Context) {
 	response.Success(c, map[string]string{
 		"message": "You have left the trip successfully",
 	})
-}
\ No newline at end of file
+}
+
+func (h *Handler) TransferOwnership(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	tripID := c.Param("id")
+
+	var input TransferOwnershipInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	transfer, err := h.service.TransferOwnership(c.Request.Context(), userID, tripID, input.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "Only the trip owner can transfer ownership")
+		case errors.Is(err, ErrTransferPending):
+			response.Conflict(c, err.Error())
+		case repoerr.Known(err):
+			response.FromError(c, err, "Failed to transfer ownership")
+		default:
+			response.BadRequest(c, err.Error())
+		}
+		return
+	}
+
+	response.Created(c, transfer)
+}
+
+func (h *Handler) AcceptOwnershipTransfer(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	tripID := c.Param("id")
+
+	trip, err := h.service.AcceptOwnershipTransfer(c.Request.Context(), userID, tripID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrTransferNotFound):
+			response.NotFound(c, "No pending ownership transfer")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "Only the recipient can accept this transfer")
+		default:
+			response.FromError(c, err, "Failed to accept ownership transfer")
+		}
+		return
+	}
+
+	response.Success(c, trip)
+}
+
+func (h *Handler) DeclineOwnershipTransfer(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	tripID := c.Param("id")
+
+	err := h.service.DeclineOwnershipTransfer(c.Request.Context(), userID, tripID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTransferNotFound):
+			response.NotFound(c, "No pending ownership transfer")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You can't decline this transfer")
+		default:
+			response.FromError(c, err, "Failed to decline ownership transfer")
+		}
+		return
+	}
+
+	response.Success(c, map[string]string{
+		"message": "Ownership transfer declined",
+	})
+}
+
+func (h *Handler) SchedulePublication(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	tripID := c.Param("id")
+
+	var input SchedulePublicationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	trip, err := h.service.SchedulePublication(c.Request.Context(), userID, tripID, &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to publish this trip")
+		case errors.Is(err, ErrAlreadyPublic):
+			response.Conflict(c, err.Error())
+		case errors.Is(err, ErrPublishAtInPast):
+			response.BadRequest(c, err.Error())
+		default:
+			response.FromError(c, err, "Failed to schedule publication")
+		}
+		return
+	}
+
+	response.Success(c, trip)
+}
+
+func (h *Handler) CancelScheduledPublication(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	tripID := c.Param("id")
+
+	trip, err := h.service.CancelScheduledPublication(c.Request.Context(), userID, tripID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to publish this trip")
+		case errors.Is(err, ErrNoScheduledPublication):
+			response.NotFound(c, err.Error())
+		default:
+			response.FromError(c, err, "Failed to cancel scheduled publication")
+		}
+		return
+	}
+
+	response.Success(c, trip)
+}
+
+func (h *Handler) SaveDraft(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	tripID := c.Param("id")
+
+	var input SaveDraftInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	draft, err := h.service.SaveDraft(c.Request.Context(), userID, tripID, &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to edit this trip")
+		case errors.Is(err, ErrDraftTooLarge):
+			response.BadRequest(c, err.Error())
+		default:
+			response.FromError(c, err, "Failed to save draft")
+		}
+		return
+	}
+
+	response.Success(c, draft)
+}
+
+// GetStats returns a trip's counts and pace estimate
+func (h *Handler) GetStats(c *gin.Context) {
+	// Anyone may see the stats of a public trip
+	userID, _ := getUserID(c)
+
+	stats, err := h.service.GetTripStats(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to view this trip")
+		default:
+			response.FromError(c, err, "Failed to get trip stats")
+		}
+		return
+	}
+
+	response.Success(c, stats)
+}
+
+// RecomputeDifficulty re-estimates the trip's difficulty from its route
+func (h *Handler) RecomputeDifficulty(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	trip, err := h.service.RecomputeDifficulty(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to update this trip")
+		case errors.Is(err, ErrNoDifficultyInputs):
+			response.BadRequest(c, err.Error())
+		default:
+			response.FromError(c, err, "Failed to estimate difficulty")
+		}
+		return
+	}
+
+	response.Success(c, trip)
+}
+
+// AcceptSuggestedTags adds tags suggested from the trip's route to its tags
+func (h *Handler) AcceptSuggestedTags(c *gin.Context) {
+	h.resolveSuggestedTags(c, h.service.AcceptSuggestedTags, "Failed to accept suggested tags")
+}
+
+// DismissSuggestedTags drops tags suggested from the trip's route, which are
+// not suggested again
+func (h *Handler) DismissSuggestedTags(c *gin.Context) {
+	h.resolveSuggestedTags(c, h.service.DismissSuggestedTags, "Failed to dismiss suggested tags")
+}
+
+func (h *Handler) resolveSuggestedTags(c *gin.Context, resolve func(ctx context.Context, userID, tripID string, input *SuggestedTagsInput) (*Trip, error), failure string) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input SuggestedTagsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	trip, err := resolve(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "Only the trip owner can accept or dismiss suggested tags")
+		case errors.Is(err, ErrTagNotSuggested):
+			response.BadRequest(c, err.Error())
+		default:
+			response.FromError(c, err, failure)
+		}
+		return
+	}
+
+	response.Success(c, trip)
+}
+
+func (h *Handler) ImportGPX(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		response.BadRequest(c, "No GPX file provided")
+		return
+	}
+	if header.Size > MaxGPXSize {
+		response.BadRequest(c, "GPX files are limited to 20 MB")
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		response.BadRequest(c, "Failed to read GPX file")
+		return
+	}
+	defer file.Close()
+
+	trip, err := h.service.ImportGPX(c.Request.Context(), userID, c.Param("id"), io.LimitReader(file, MaxGPXSize))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to update this trip")
+		case errors.Is(err, ErrInvalidGPX):
+			response.BadRequest(c, err.Error())
+		default:
+			response.FromError(c, err, "Failed to import GPX file")
+		}
+		return
+	}
+
+	response.Success(c, trip)
+}
+
+func (h *Handler) ExportTrip(c *gin.Context) {
+	userID, _ := getUserID(c)
+	format := c.DefaultQuery("format", ExportFormatGPX)
+
+	export, err := h.service.ExportTrip(c.Request.Context(), userID, c.Param("id"), format, c.GetHeader("Accept-Language"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to view this trip")
+		case errors.Is(err, ErrUnsupportedExportFormat):
+			response.BadRequest(c, err.Error())
+		default:
+			response.FromError(c, err, "Failed to export trip")
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.Filename))
+	c.Data(http.StatusOK, export.ContentType, export.Data)
+}
+
+func (h *Handler) GetReadiness(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	readiness, err := h.service.GetReadiness(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		h.readinessError(c, err, "You don't have permission to view this trip")
+		return
+	}
+
+	response.Success(c, readiness)
+}
+
+func (h *Handler) ConfirmReadinessCheck(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	readiness, err := h.service.ConfirmReadinessCheck(c.Request.Context(), userID, c.Param("id"), c.Param("check"))
+	if err != nil {
+		h.readinessError(c, err, "You don't have permission to update this trip")
+		return
+	}
+
+	response.Success(c, readiness)
+}
+
+func (h *Handler) ClearReadinessCheck(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	readiness, err := h.service.ClearReadinessCheck(c.Request.Context(), userID, c.Param("id"), c.Param("check"))
+	if err != nil {
+		h.readinessError(c, err, "You don't have permission to update this trip")
+		return
+	}
+
+	response.Success(c, readiness)
+}
+
+func (h *Handler) readinessError(c *gin.Context, err error, forbidden string) {
+	switch {
+	case errors.Is(err, ErrTripNotFound):
+		response.NotFound(c, "Trip not found")
+	case errors.Is(err, ErrUnauthorized):
+		response.Forbidden(c, forbidden)
+	case errors.Is(err, ErrNotManualCheck):
+		response.BadRequest(c, err.Error())
+	default:
+		response.FromError(c, err, "Failed to check trip readiness")
+	}
+}
+
+func (h *Handler) ListLayers(c *gin.Context) {
+	if h.layers == nil {
+		response.NotFound(c, "Map layers are not available")
+		return
+	}
+
+	userID, _ := getUserID(c)
+	layers, err := h.layers.ListLayers(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		h.layerError(c, err, "You don't have permission to view this trip")
+		return
+	}
+
+	// Layer URLs may be signed for a limited time
+	httpcache.Private(c)
+	response.Success(c, layers)
+}
+
+func (h *Handler) CreateLayer(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	if h.layers == nil {
+		response.NotFound(c, "Map layers are not available")
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		response.BadRequest(c, "No layer file provided")
+		return
+	}
+	if header.Size > MaxLayerSize {
+		response.BadRequest(c, ErrLayerTooLarge.Error())
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		response.BadRequest(c, "Failed to read layer file")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxLayerSize+1))
+	if err != nil {
+		response.BadRequest(c, "Failed to read layer file")
+		return
+	}
+
+	layer, err := h.layers.CreateLayer(c.Request.Context(), userID, c.Param("id"), &CreateLayerInput{
+		Name:     c.PostForm("name"),
+		Filename: header.Filename,
+		Data:     data,
+	})
+	if err != nil {
+		h.layerError(c, err, "You don't have permission to update this trip")
+		return
+	}
+
+	response.Created(c, layer)
+}
+
+func (h *Handler) DeleteLayer(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	if h.layers == nil {
+		response.NotFound(c, "Map layers are not available")
+		return
+	}
+
+	if err := h.layers.DeleteLayer(c.Request.Context(), userID, c.Param("id"), c.Param("layerId")); err != nil {
+		h.layerError(c, err, "You don't have permission to update this trip")
+		return
+	}
+
+	response.NoContent(c)
+}
+
+func (h *Handler) layerError(c *gin.Context, err error, forbidden string) {
+	switch {
+	case errors.Is(err, ErrTripNotFound):
+		response.NotFound(c, "Trip not found")
+	case errors.Is(err, ErrLayerNotFound):
+		response.NotFound(c, "Layer not found")
+	case errors.Is(err, ErrUnauthorized):
+		response.Forbidden(c, forbidden)
+	case errors.Is(err, ErrInvalidLayer), errors.Is(err, ErrLayerTooLarge):
+		response.BadRequest(c, err.Error())
+	default:
+		response.FromError(c, err, "Failed to manage map layers")
+	}
+}
+
+// RequestOfflinePack queues a new offline map pack of the trip. The pack is
+// built in the background; its status tells when it can be downloaded.
+func (h *Handler) RequestOfflinePack(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	if h.offline == nil {
+		response.NotFound(c, "Offline packs are not available")
+		return
+	}
+
+	pack, err := h.offline.RequestPack(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		h.offlinePackError(c, err)
+		return
+	}
+
+	response.Accepted(c, pack)
+}
+
+// GetOfflinePack returns the status of the trip's offline map pack, and the
+// URL it can be downloaded from once built
+func (h *Handler) GetOfflinePack(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	if h.offline == nil {
+		response.NotFound(c, "Offline packs are not available")
+		return
+	}
+
+	pack, err := h.offline.GetPack(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		h.offlinePackError(c, err)
+		return
+	}
+
+	// Pack URLs may be signed for a limited time
+	httpcache.Private(c)
+	response.Success(c, pack)
+}
+
+func (h *Handler) offlinePackError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrTripNotFound):
+		response.NotFound(c, "Trip not found")
+	case errors.Is(err, ErrOfflinePackNotFound):
+		response.NotFound(c, err.Error())
+	case errors.Is(err, ErrUnauthorized):
+		response.Forbidden(c, "You don't have permission to view this trip")
+	default:
+		response.FromError(c, err, "Failed to get offline pack")
+	}
+}
+
+// SubscribeToConditions subscribes the user to verified condition reports
+// on a trip or in a region
+func (h *Handler) SubscribeToConditions(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	if h.alerts == nil {
+		response.NotFound(c, "Condition alerts are not available")
+		return
+	}
+
+	var input CreateConditionSubscriptionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	subscription, err := h.alerts.Subscribe(c.Request.Context(), userID, &input)
+	if err != nil {
+		h.subscriptionError(c, err, "Failed to subscribe to conditions")
+		return
+	}
+
+	response.Created(c, subscription)
+}
+
+// ListConditionSubscriptions lists the user's condition subscriptions
+func (h *Handler) ListConditionSubscriptions(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	if h.alerts == nil {
+		response.NotFound(c, "Condition alerts are not available")
+		return
+	}
+
+	subscriptions, err := h.alerts.ListSubscriptions(c.Request.Context(), userID)
+	if err != nil {
+		h.subscriptionError(c, err, "Failed to list condition subscriptions")
+		return
+	}
+
+	response.Success(c, subscriptions)
+}
+
+// DeleteConditionSubscription unsubscribes the user from condition reports
+func (h *Handler) DeleteConditionSubscription(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+	if h.alerts == nil {
+		response.NotFound(c, "Condition alerts are not available")
+		return
+	}
+
+	if err := h.alerts.Unsubscribe(c.Request.Context(), userID, c.Param("id")); err != nil {
+		h.subscriptionError(c, err, "Failed to delete condition subscription")
+		return
+	}
+
+	response.NoContent(c)
+}
+
+func (h *Handler) subscriptionError(c *gin.Context, err error, failure string) {
+	switch {
+	case errors.Is(err, ErrTripNotFound):
+		response.NotFound(c, "Trip not found")
+	case errors.Is(err, ErrSubscriptionNotFound):
+		response.NotFound(c, err.Error())
+	case errors.Is(err, ErrUnauthorized):
+		response.Forbidden(c, "You don't have permission to view this trip")
+	case errors.Is(err, ErrInvalidSubscription), errors.Is(err, ErrAlertRegionTooLarge):
+		response.BadRequest(c, err.Error())
+	default:
+		response.FromError(c, err, failure)
+	}
+}
+
+func (h *Handler) ListAnnotations(c *gin.Context) {
+	userID, _ := getUserID(c)
+
+	annotations, err := h.service.ListAnnotations(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		h.annotationError(c, err, "You don't have permission to view this trip")
+		return
+	}
+
+	response.Success(c, annotations)
+}
+
+func (h *Handler) CreateAnnotation(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input CreateAnnotationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	annotation, err := h.service.CreateAnnotation(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		h.annotationError(c, err, "You don't have permission to update this trip")
+		return
+	}
+
+	response.Created(c, annotation)
+}
+
+func (h *Handler) UpdateAnnotation(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input UpdateAnnotationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	annotation, err := h.service.UpdateAnnotation(c.Request.Context(), userID, c.Param("id"), c.Param("annotationId"), &input)
+	if err != nil {
+		h.annotationError(c, err, "You don't have permission to update this trip")
+		return
+	}
+
+	response.Success(c, annotation)
+}
+
+func (h *Handler) DeleteAnnotation(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.service.DeleteAnnotation(c.Request.Context(), userID, c.Param("id"), c.Param("annotationId")); err != nil {
+		h.annotationError(c, err, "You don't have permission to update this trip")
+		return
+	}
+
+	response.NoContent(c)
+}
+
+func (h *Handler) annotationError(c *gin.Context, err error, forbidden string) {
+	switch {
+	case errors.Is(err, ErrTripNotFound):
+		response.NotFound(c, "Trip not found")
+	case errors.Is(err, ErrAnnotationNotFound):
+		response.NotFound(c, "Annotation not found")
+	case errors.Is(err, ErrUnauthorized):
+		response.Forbidden(c, forbidden)
+	case errors.Is(err, ErrInvalidAnnotation):
+		response.BadRequest(c, err.Error())
+	default:
+		response.FromError(c, err, "Failed to manage annotations")
+	}
+}
+
+func (h *Handler) AddWaypoint(c *gin.Context) {
+	userID, exists := getUserID(c)
+	grant, shared := getShareGrant(c)
+	if !exists && !shared {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input AddWaypointInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var waypoint *Waypoint
+	var err error
+	if exists {
+		waypoint, err = h.service.AddWaypoint(c.Request.Context(), userID, c.Param("id"), &input)
+	} else {
+		waypoint, err = h.service.AddWaypointShared(c.Request.Context(), grant, c.Param("id"), &input)
+	}
+	if err != nil {
+		h.waypointError(c, err, "Failed to add waypoint")
+		return
+	}
+
+	response.Created(c, waypoint)
+}
+
+func (h *Handler) UpdateWaypoint(c *gin.Context) {
+	userID, exists := getUserID(c)
+	grant, shared := getShareGrant(c)
+	if !exists && !shared {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input UpdateWaypointInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var waypoint *Waypoint
+	var err error
+	if exists {
+		waypoint, err = h.service.UpdateWaypoint(c.Request.Context(), userID, c.Param("id"), c.Param("waypointId"), &input)
+	} else {
+		waypoint, err = h.service.UpdateWaypointShared(c.Request.Context(), grant, c.Param("id"), c.Param("waypointId"), &input)
+	}
+	if err != nil {
+		h.waypointError(c, err, "Failed to update waypoint")
+		return
+	}
+
+	response.Success(c, waypoint)
+}
+
+func (h *Handler) RemoveWaypoint(c *gin.Context) {
+	userID, exists := getUserID(c)
+	grant, shared := getShareGrant(c)
+	if !exists && !shared {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var err error
+	if exists {
+		err = h.service.RemoveWaypoint(c.Request.Context(), userID, c.Param("id"), c.Param("waypointId"))
+	} else {
+		err = h.service.RemoveWaypointShared(c.Request.Context(), grant, c.Param("id"), c.Param("waypointId"))
+	}
+	if err != nil {
+		h.waypointError(c, err, "Failed to remove waypoint")
+		return
+	}
+
+	response.NoContent(c)
+}
+
+func (h *Handler) ReorderWaypoints(c *gin.Context) {
+	userID, exists := getUserID(c)
+	grant, shared := getShareGrant(c)
+	if !exists && !shared {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input ReorderWaypointsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var err error
+	if exists {
+		err = h.service.ReorderWaypoints(c.Request.Context(), userID, c.Param("id"), input.WaypointIDs)
+	} else {
+		err = h.service.ReorderWaypointsShared(c.Request.Context(), grant, c.Param("id"), input.WaypointIDs)
+	}
+	if err != nil {
+		h.waypointError(c, err, "Failed to reorder waypoints")
+		return
+	}
+
+	response.NoContent(c)
+}
+
+func (h *Handler) waypointError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrTripNotFound):
+		response.NotFound(c, "Trip not found")
+	case errors.Is(err, ErrWaypointNotFound):
+		response.NotFound(c, "Waypoint not found")
+	case errors.Is(err, ErrPlaceNotFound):
+		response.UnprocessableEntity(c, "Place does not exist")
+	case errors.Is(err, ErrUnauthorized):
+		response.Forbidden(c, "You don't have permission to update this trip")
+	case errors.Is(err, ErrInvalidWaypointTimes), errors.Is(err, ErrInvalidWaypointOrder):
+		response.BadRequest(c, err.Error())
+	default:
+		response.FromError(c, err, fallback)
+	}
+}
+
+func (h *Handler) SetWaypointWindow(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input SetTimeWindowInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	itinerary, err := h.service.SetWaypointWindow(c.Request.Context(), userID, c.Param("id"), c.Param("waypointId"), &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrWaypointNotFound):
+			response.NotFound(c, "Waypoint not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to update this trip")
+		case errors.Is(err, ErrInvalidTimeWindow):
+			response.BadRequest(c, err.Error())
+		default:
+			response.FromError(c, err, "Failed to set time window")
+		}
+		return
+	}
+
+	response.Success(c, itinerary)
+}
+
+// GetItinerary returns the trip's estimated arrival times and the time
+// windows the plan cannot make
+func (h *Handler) GetItinerary(c *gin.Context) {
+	userID, _ := getUserID(c)
+
+	itinerary, err := h.service.GetItinerary(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to view this trip")
+		default:
+			response.FromError(c, err, "Failed to plan itinerary")
+		}
+		return
+	}
+
+	response.Success(c, itinerary)
+}
+
+// GetBailouts suggests where the trip's route can be left early
+func (h *Handler) GetCrowdEstimate(c *gin.Context) {
+	userID, _ := getUserID(c)
+
+	estimate, err := h.service.GetCrowdEstimate(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to view this trip")
+		default:
+			response.FromError(c, err, "Failed to estimate crowds")
+		}
+		return
+	}
+
+	response.Success(c, estimate)
+}
+
+func (h *Handler) GetWaterSources(c *gin.Context) {
+	userID, _ := getUserID(c)
+
+	var query WaterQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	plan, err := h.service.GetWaterSources(c.Request.Context(), userID, c.Param("id"), &query)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to view this trip")
+		case errors.Is(err, ErrNoRouteGeometry):
+			response.BadRequest(c, err.Error())
+		default:
+			response.FromError(c, err, "Failed to find water sources")
+		}
+		return
+	}
+
+	response.Success(c, plan)
+}
+
+func (h *Handler) GetBailouts(c *gin.Context) {
+	userID, _ := getUserID(c)
+
+	var query BailoutQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	points, err := h.service.GetBailouts(c.Request.Context(), userID, c.Param("id"), &query)
+	if err != nil {
+		h.bailoutError(c, err, "You don't have permission to view this trip")
+		return
+	}
+
+	response.Success(c, points)
+}
+
+func (h *Handler) AttachBailout(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input AttachBailoutInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	waypoint, err := h.service.AttachBailout(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		h.bailoutError(c, err, "You don't have permission to update this trip")
+		return
+	}
+
+	response.Created(c, waypoint)
+}
+
+func (h *Handler) AddWaypoints(c *gin.Context) {
+	userID, exists := getUserID(c)
+	grant, shared := getShareGrant(c)
+	if !exists && !shared {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input AddWaypointsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var trip *Trip
+	var err error
+	if exists {
+		trip, err = h.service.AddWaypoints(c.Request.Context(), userID, c.Param("id"), &input)
+	} else {
+		trip, err = h.service.AddWaypointsShared(c.Request.Context(), grant, c.Param("id"), &input)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to update this trip")
+		case errors.Is(err, ErrPlaceNotFound):
+			response.UnprocessableEntity(c, "One of the places does not exist")
+		default:
+			response.FromError(c, err, "Failed to add waypoints")
+		}
+		return
+	}
+
+	response.Created(c, trip)
+}
+
+func (h *Handler) DetachBailout(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.service.DetachBailout(c.Request.Context(), userID, c.Param("id"), c.Param("waypointId")); err != nil {
+		h.bailoutError(c, err, "You don't have permission to update this trip")
+		return
+	}
+
+	response.NoContent(c)
+}
+
+func (h *Handler) bailoutError(c *gin.Context, err error, forbidden string) {
+	switch {
+	case errors.Is(err, ErrTripNotFound):
+		response.NotFound(c, "Trip not found")
+	case errors.Is(err, ErrWaypointNotFound):
+		response.NotFound(c, "Bail-out waypoint not found")
+	case errors.Is(err, ErrPlaceNotFound):
+		response.NotFound(c, "Place not found")
+	case errors.Is(err, ErrUnauthorized):
+		response.Forbidden(c, forbidden)
+	case errors.Is(err, ErrNoRouteGeometry):
+		response.BadRequest(c, err.Error())
+	default:
+		response.FromError(c, err, "Failed to manage bail-out points")
+	}
+}
+
+func (h *Handler) ListRouteVariants(c *gin.Context) {
+	userID, _ := getUserID(c)
+
+	variants, err := h.service.ListRouteVariants(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		h.variantError(c, err, "You don't have permission to view this trip")
+		return
+	}
+
+	response.Success(c, variants)
+}
+
+func (h *Handler) CreateRouteVariant(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input CreateRouteVariantInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	variant, err := h.service.CreateRouteVariant(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		h.variantError(c, err, "You don't have permission to update this trip")
+		return
+	}
+
+	response.Created(c, variant)
+}
+
+func (h *Handler) UpdateRouteVariant(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input UpdateRouteVariantInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	variant, err := h.service.UpdateRouteVariant(c.Request.Context(), userID, c.Param("id"), c.Param("variantId"), &input)
+	if err != nil {
+		h.variantError(c, err, "You don't have permission to update this trip")
+		return
+	}
+
+	response.Success(c, variant)
+}
+
+func (h *Handler) DeleteRouteVariant(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.service.DeleteRouteVariant(c.Request.Context(), userID, c.Param("id"), c.Param("variantId")); err != nil {
+		h.variantError(c, err, "You don't have permission to update this trip")
+		return
+	}
+
+	response.NoContent(c)
+}
+
+func (h *Handler) SetPrimaryRouteVariant(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	variant, err := h.service.SetPrimaryRouteVariant(c.Request.Context(), userID, c.Param("id"), c.Param("variantId"))
+	if err != nil {
+		h.variantError(c, err, "You don't have permission to update this trip")
+		return
+	}
+
+	response.Success(c, variant)
+}
+
+func (h *Handler) variantError(c *gin.Context, err error, forbidden string) {
+	switch {
+	case errors.Is(err, ErrTripNotFound):
+		response.NotFound(c, "Trip not found")
+	case errors.Is(err, ErrVariantNotFound):
+		response.NotFound(c, "Route variant not found")
+	case errors.Is(err, ErrUnauthorized):
+		response.Forbidden(c, forbidden)
+	case errors.Is(err, ErrVariantNameTaken):
+		response.Conflict(c, err.Error())
+	case errors.Is(err, ErrInvalidRoute):
+		response.BadRequest(c, err.Error())
+	default:
+		response.FromError(c, err, "Failed to manage route variants")
+	}
+}
+
+func (h *Handler) GetDraft(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	draft, err := h.service.GetDraft(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrDraftNotFound):
+			response.NotFound(c, "Draft not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to edit this trip")
+		default:
+			response.FromError(c, err, "Failed to get draft")
+		}
+		return
+	}
+
+	response.Success(c, draft)
+}
+
+func (h *Handler) DiscardDraft(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	err := h.service.DiscardDraft(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrDraftNotFound):
+			response.NotFound(c, "Draft not found")
+		default:
+			response.FromError(c, err, "Failed to discard draft")
+		}
+		return
+	}
+
+	response.NoContent(c)
+}
+
+func (h *Handler) ApplyDraft(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	result, err := h.service.ApplyDraft(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, ErrInvalidDraft) {
+			response.ValidationError(c, map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrDraftNotFound):
+			response.NotFound(c, "Draft not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to edit this trip")
+		default:
+			response.FromError(c, err, "Failed to apply draft")
+		}
+		return
+	}
+
+	// Non-conflicting fields are applied either way. Conflicting ones are
+	// listed in the result and stay in the draft for the user to review.
+	response.Success(c, result)
+}
+
+// RedeemShareLink exchanges a share link token for a short-lived token that
+// grants its holder the link's access to the trip, so a guest can keep
+// using the API without an account
+func (h *Handler) RedeemShareLink(c *gin.Context) {
+	if h.tokens == nil {
+		response.InternalServerError(c, "Share links are not available")
+		return
+	}
+
+	link, err := h.service.RedeemShareLink(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrShareLinkNotFound):
+			response.NotFound(c, "Share link is invalid or has expired")
+		default:
+			response.FromError(c, err, "Failed to redeem share link")
+		}
+		return
+	}
+
+	token, err := h.tokens.GenerateScopedToken(link.TripID, link.Scope())
+	if err != nil {
+		response.InternalServerError(c, "Failed to redeem share link")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(h.tokens.GetShareTokenExpiry().Seconds()),
+		"trip_id":      link.TripID,
+		"scope":        link.Scope(),
+	})
+}
+
+// CreateShareLink mints a link to the trip for people without an account
+func (h *Handler) CreateShareLink(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input CreateShareLinkInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	link, err := h.service.CreateShareLink(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "You don't have permission to share this trip")
+		case errors.Is(err, ErrShareExpiryInPast):
+			response.BadRequest(c, err.Error())
+		default:
+			response.FromError(c, err, "Failed to create share link")
+		}
+		return
+	}
+
+	response.Created(c, link)
+}
+
+// GetSharedTrip returns the trip a share link points to. When share tokens
+// are enabled it comes with one, so the visitor can go on using the link's
+// access without spending more of its uses.
+func (h *Handler) GetSharedTrip(c *gin.Context) {
+	link, ok := getShareLink(c)
+	grant, shared := getShareGrant(c)
+	if !ok || !shared {
+		response.NotFound(c, "Share link is invalid or has expired")
+		return
+	}
+
+	trip, err := h.service.GetSharedWith(c.Request.Context(), grant, link.TripID, Relations{Waypoints: true})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTripNotFound):
+			response.NotFound(c, "Trip not found")
+		case errors.Is(err, ErrUnauthorized):
+			response.Forbidden(c, "Your share link does not allow this action on this trip")
+		default:
+			response.FromError(c, err, "Failed to get shared trip")
+		}
+		return
+	}
+
+	data := gin.H{
+		"trip":        trip,
+		"permissions": link.Permissions,
+		"scope":       link.Scope(),
+		"expires_at":  link.ExpiresAt,
+	}
+	if h.tokens != nil {
+		token, err := h.tokens.GenerateScopedToken(link.TripID, link.Scope())
+		if err != nil {
+			response.InternalServerError(c, "Failed to get shared trip")
+			return
+		}
+		data["access_token"] = token
+		data["token_type"] = "Bearer"
+		data["expires_in"] = int(h.tokens.GetShareTokenExpiry().Seconds())
+	}
+
+	// Every request counts a use of the link, so shared caches must not
+	// answer for it
+	httpcache.Private(c)
+	response.Success(c, data)
+}
+
+// LogCompletion records that the user completed the trip
+func (h *Handler) LogCompletion(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input CreateActivityCompletionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	completion, err := h.service.LogCompletion(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		h.completionError(c, err, "Failed to log completion")
+		return
+	}
+
+	response.Created(c, completion)
+}
+
+// ListCompletions returns a page of the trip's completions
+func (h *Handler) ListCompletions(c *gin.Context) {
+	userID, _ := getUserID(c)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	completions, err := h.service.ListCompletions(c.Request.Context(), userID, c.Param("id"), limit, (page-1)*limit)
+	if err != nil {
+		h.completionError(c, err, "Failed to list completions")
+		return
+	}
+
+	response.Success(c, completions)
+}
+
+// GetCompletion returns a completion of the trip with its track
+func (h *Handler) GetCompletion(c *gin.Context) {
+	userID, _ := getUserID(c)
+
+	completion, err := h.service.GetCompletion(c.Request.Context(), userID, c.Param("id"), c.Param("completionId"))
+	if err != nil {
+		h.completionError(c, err, "Failed to get completion")
+		return
+	}
+
+	response.Success(c, completion)
+}
+
+func (h *Handler) completionError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrTripNotFound):
+		response.NotFound(c, "Trip not found")
+	case errors.Is(err, ErrCompletionNotFound):
+		response.NotFound(c, "Completion not found")
+	case errors.Is(err, ErrUnauthorized):
+		response.Forbidden(c, "You don't have permission to view this trip")
+	case errors.Is(err, ErrCompletionInFuture), errors.Is(err, ErrInvalidGPX):
+		response.BadRequest(c, err.Error())
+	default:
+		response.FromError(c, err, fallback)
+	}
+}
+
+// RateTrip records the user's rating and review of the trip
+func (h *Handler) RateTrip(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input CreateActivityRatingInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	rating, err := h.service.RateTrip(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		h.ratingError(c, err, "Failed to rate trip")
+		return
+	}
+
+	response.Created(c, rating)
+}
+
+// ListRatings returns a page of the trip's ratings
+func (h *Handler) ListRatings(c *gin.Context) {
+	userID, _ := getUserID(c)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	ratings, err := h.service.ListRatings(c.Request.Context(), userID, c.Param("id"), limit, (page-1)*limit)
+	if err != nil {
+		h.ratingError(c, err, "Failed to list ratings")
+		return
+	}
+
+	response.Success(c, ratings)
+}
+
+// DeleteRating removes the user's rating of the trip
+func (h *Handler) DeleteRating(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.service.DeleteRating(c.Request.Context(), userID, c.Param("id")); err != nil {
+		h.ratingError(c, err, "Failed to delete rating")
+		return
+	}
+
+	response.NoContent(c)
+}
+
+func (h *Handler) ratingError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrTripNotFound):
+		response.NotFound(c, "Trip not found")
+	case errors.Is(err, ErrRatingNotFound):
+		response.NotFound(c, "Rating not found")
+	case errors.Is(err, ErrUnauthorized):
+		response.Forbidden(c, "You don't have permission to view this trip")
+	case errors.Is(err, ErrOwnTripRating):
+		response.Forbidden(c, err.Error())
+	default:
+		response.FromError(c, err, fallback)
+	}
+}
+
+// ListDatePolls lists the trip's date polls
+func (h *Handler) ListDatePolls(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	polls, err := h.service.ListDatePolls(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		h.datePollError(c, err, "Failed to list date polls")
+		return
+	}
+
+	response.Success(c, polls)
+}
+
+// GetDatePoll returns a date poll with the best dates so far
+func (h *Handler) GetDatePoll(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	poll, err := h.service.GetDatePoll(c.Request.Context(), userID, c.Param("id"), c.Param("pollId"))
+	if err != nil {
+		h.datePollError(c, err, "Failed to get date poll")
+		return
+	}
+
+	response.Success(c, poll)
+}
+
+// CreateDatePoll proposes date ranges for the trip
+func (h *Handler) CreateDatePoll(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input CreateDatePollInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	poll, err := h.service.CreateDatePoll(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		h.datePollError(c, err, "Failed to create date poll")
+		return
+	}
+
+	response.Created(c, poll)
+}
+
+// SetAvailability records which of the poll's dates the user can make
+func (h *Handler) SetAvailability(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input SetAvailabilityInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	poll, err := h.service.SetAvailability(c.Request.Context(), userID, c.Param("id"), c.Param("pollId"), &input)
+	if err != nil {
+		h.datePollError(c, err, "Failed to set availability")
+		return
+	}
+
+	response.Success(c, poll)
+}
+
+// FinalizeDatePoll settles the trip's dates from a poll
+func (h *Handler) FinalizeDatePoll(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	// The option is optional, and so is the body
+	var input FinalizeDatePollInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	poll, err := h.service.FinalizeDatePoll(c.Request.Context(), userID, c.Param("id"), c.Param("pollId"), &input)
+	if err != nil {
+		h.datePollError(c, err, "Failed to finalize date poll")
+		return
+	}
+
+	response.Success(c, poll)
+}
+
+// DeleteDatePoll removes a date poll
+func (h *Handler) DeleteDatePoll(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.service.DeleteDatePoll(c.Request.Context(), userID, c.Param("id"), c.Param("pollId")); err != nil {
+		h.datePollError(c, err, "Failed to delete date poll")
+		return
+	}
+
+	response.NoContent(c)
+}
+
+func (h *Handler) datePollError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrTripNotFound):
+		response.NotFound(c, "Trip not found")
+	case errors.Is(err, ErrDatePollNotFound):
+		response.NotFound(c, "Date poll not found")
+	case errors.Is(err, ErrUnauthorized):
+		response.Forbidden(c, "Only trip members can answer its date polls, and only the owner can run them")
+	case errors.Is(err, ErrDatePollClosed):
+		response.Conflict(c, err.Error())
+	case errors.Is(err, ErrInvalidDateRange), errors.Is(err, ErrUnknownDateOption), errors.Is(err, ErrNoBestDates):
+		response.BadRequest(c, err.Error())
+	default:
+		response.FromError(c, err, fallback)
+	}
+}
+
+// ListParticipants returns who is invited on the trip and who is going
+func (h *Handler) ListParticipants(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	roster, err := h.service.GetParticipants(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		h.participantError(c, err, "Failed to list participants")
+		return
+	}
+
+	response.Success(c, roster)
+}
+
+// InviteParticipants invites users on the trip
+func (h *Handler) InviteParticipants(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input InviteParticipantsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	roster, err := h.service.InviteParticipants(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		h.participantError(c, err, "Failed to invite participants")
+		return
+	}
+
+	response.Success(c, roster)
+}
+
+// RespondToInvite records whether the user is coming on the trip
+func (h *Handler) RespondToInvite(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input RSVPInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	roster, err := h.service.RespondToInvite(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		h.participantError(c, err, "Failed to record response")
+		return
+	}
+
+	response.Success(c, roster)
+}
+
+// SetParticipantCapacity limits how many people can go on the trip
+func (h *Handler) SetParticipantCapacity(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input SetCapacityInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	roster, err := h.service.SetParticipantCapacity(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		h.participantError(c, err, "Failed to set capacity")
+		return
+	}
+
+	response.Success(c, roster)
+}
+
+// RemoveParticipant takes someone off the trip's roster
+func (h *Handler) RemoveParticipant(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.service.RemoveParticipant(c.Request.Context(), userID, c.Param("id"), c.Param("userId")); err != nil {
+		h.participantError(c, err, "Failed to remove participant")
+		return
+	}
+
+	response.NoContent(c)
+}
+
+func (h *Handler) participantError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrTripNotFound):
+		response.NotFound(c, "Trip not found")
+	case errors.Is(err, ErrParticipantNotFound):
+		response.NotFound(c, err.Error())
+	case errors.Is(err, ErrUserNotFound):
+		response.NotFound(c, "User not found")
+	case errors.Is(err, ErrUnauthorized):
+		response.Forbidden(c, "Only those allowed to invite can manage the trip's participants")
+	case errors.Is(err, ErrNotEnoughSpots):
+		response.Conflict(c, err.Error())
+	case errors.Is(err, ErrOwnerParticipant):
+		response.BadRequest(c, err.Error())
+	default:
+		response.FromError(c, err, fallback)
+	}
+}
+
+// ReportCondition reports trail, weather, closure or hazard conditions on
+// the trip
+func (h *Handler) ReportCondition(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input CreateActivityConditionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	condition, err := h.service.ReportCondition(c.Request.Context(), userID, c.Param("id"), &input)
+	if err != nil {
+		h.conditionError(c, err, "Failed to report condition")
+		return
+	}
+
+	response.Created(c, condition)
+}
+
+// ListConditions returns the conditions in effect on the trip, or all
+// reported ones with include_expired
+func (h *Handler) ListConditions(c *gin.Context) {
+	userID, _ := getUserID(c)
+	includeExpired := c.Query("include_expired") == "true"
+
+	conditions, err := h.service.ListConditions(c.Request.Context(), userID, c.Param("id"), includeExpired)
+	if err != nil {
+		h.conditionError(c, err, "Failed to list conditions")
+		return
+	}
+
+	response.Success(c, conditions)
+}
+
+// VerifyCondition vouches for a condition report
+func (h *Handler) VerifyCondition(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	condition, err := h.service.VerifyCondition(c.Request.Context(), userID, c.Param("id"), c.Param("conditionId"))
+	if err != nil {
+		h.conditionError(c, err, "Failed to verify condition")
+		return
+	}
+
+	response.Success(c, condition)
+}
+
+// ConfirmCondition confirms a condition report as a trusted verifier
+func (h *Handler) ConfirmCondition(c *gin.Context) {
+	h.reviewCondition(c, h.service.ConfirmCondition, "Failed to confirm condition")
+}
+
+// DisputeCondition disputes a condition report as a trusted verifier
+func (h *Handler) DisputeCondition(c *gin.Context) {
+	h.reviewCondition(c, h.service.DisputeCondition, "Failed to dispute condition")
+}
+
+type conditionReview func(ctx context.Context, userID, tripID, conditionID string, input *ReviewConditionInput) (*ActivityCondition, error)
+
+func (h *Handler) reviewCondition(c *gin.Context, review conditionReview, fallback string) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	// The note is optional, and so is the body
+	var input ReviewConditionInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	condition, err := review(c.Request.Context(), userID, c.Param("id"), c.Param("conditionId"), &input)
+	if err != nil {
+		h.conditionError(c, err, fallback)
+		return
+	}
+
+	response.Success(c, condition)
+}
+
+// GrantConditionVerifier makes a user a trusted verifier of condition
+// reports in a region or on trips of an activity
+func (h *Handler) GrantConditionVerifier(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var input GrantConditionVerifierInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.ValidationError(c, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	verifier, err := h.service.GrantConditionVerifier(c.Request.Context(), userID, &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidVerifier):
+			response.BadRequest(c, err.Error())
+		case errors.Is(err, ErrUserNotFound):
+			response.NotFound(c, "User not found")
+		default:
+			response.FromError(c, err, "Failed to grant verifier status")
+		}
+		return
+	}
+
+	response.Created(c, verifier)
+}
+
+// ListConditionVerifiers lists the verifier grants, of one user with user_id
+func (h *Handler) ListConditionVerifiers(c *gin.Context) {
+	verifiers, err := h.service.ListConditionVerifiers(c.Request.Context(), c.Query("user_id"))
+	if err != nil {
+		response.FromError(c, err, "Failed to list verifiers")
+		return
+	}
+
+	response.Success(c, verifiers)
+}
+
+// RevokeConditionVerifier revokes a verifier grant
+func (h *Handler) RevokeConditionVerifier(c *gin.Context) {
+	if err := h.service.RevokeConditionVerifier(c.Request.Context(), c.Param("id")); err != nil {
+		if errors.Is(err, ErrVerifierNotFound) {
+			response.NotFound(c, "Verifier not found")
+			return
+		}
+		response.FromError(c, err, "Failed to revoke verifier status")
+		return
+	}
+
+	response.NoContent(c)
+}
+
+func (h *Handler) conditionError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrTripNotFound):
+		response.NotFound(c, "Trip not found")
+	case errors.Is(err, ErrConditionNotFound):
+		response.NotFound(c, "Condition report not found")
+	case errors.Is(err, ErrUnauthorized):
+		response.Forbidden(c, "You don't have permission to do this on this trip")
+	case errors.Is(err, ErrConditionExpired), errors.Is(err, ErrInvalidConditionLocation), errors.Is(err, ErrOwnCondition):
+		response.BadRequest(c, err.Error())
+	default:
+		response.FromError(c, err, fallback)
+	}
+}
@@ -10,14 +10,17 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Oferzz/newMap/apps/api/internal/directions"
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
-// MockService is a mock implementation of the Service interface
+// MockService records the service calls the trip handlers make
 type MockService struct {
 	mock.Mock
+	Service
 }
 
 func (m *MockService) Create(ctx context.Context, userID string, input *CreateTripInput) (*Trip, error) {
@@ -28,127 +31,54 @@ func (m *MockService) Create(ctx context.Context, userID string, input *CreateTr
 	return args.Get(0).(*Trip), args.Error(1)
 }
 
-func (m *MockService) GetByID(ctx context.Context, userID, tripID string) (*Trip, error) {
-	args := m.Called(ctx, userID, tripID)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*Trip), args.Error(1)
-}
-
-func (m *MockService) Update(ctx context.Context, userID, tripID string, input *UpdateTripInput) (*Trip, error) {
-	args := m.Called(ctx, userID, tripID, input)
+func (m *MockService) GetByIDWith(ctx context.Context, userID, tripID string, relations Relations) (*Trip, error) {
+	args := m.Called(ctx, userID, tripID, relations)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*Trip), args.Error(1)
 }
 
-func (m *MockService) Delete(ctx context.Context, userID, tripID string) error {
-	args := m.Called(ctx, userID, tripID)
-	return args.Error(0)
-}
-
-func (m *MockService) List(ctx context.Context, userID string, filter *TripFilter, limit, offset int) ([]*Trip, int64, error) {
-	args := m.Called(ctx, userID, filter, limit, offset)
-	if args.Get(0) == nil {
-		return nil, args.Get(1).(int64), args.Error(2)
-	}
-	return args.Get(0).([]*Trip), args.Get(1).(int64), args.Error(2)
-}
-
-func (m *MockService) GetUserTrips(ctx context.Context, userID string, limit, offset int) ([]*Trip, int64, error) {
-	args := m.Called(ctx, userID, limit, offset)
-	if args.Get(0) == nil {
-		return nil, args.Get(1).(int64), args.Error(2)
-	}
-	return args.Get(0).([]*Trip), args.Get(1).(int64), args.Error(2)
-}
-
-func (m *MockService) GetSharedTrips(ctx context.Context, userID string, limit, offset int) ([]*Trip, int64, error) {
-	args := m.Called(ctx, userID, limit, offset)
-	if args.Get(0) == nil {
-		return nil, args.Get(1).(int64), args.Error(2)
-	}
-	return args.Get(0).([]*Trip), args.Get(1).(int64), args.Error(2)
-}
-
-func (m *MockService) Search(ctx context.Context, userID string, query string, limit, offset int) ([]*Trip, int64, error) {
-	args := m.Called(ctx, userID, query, limit, offset)
-	if args.Get(0) == nil {
-		return nil, args.Get(1).(int64), args.Error(2)
-	}
-	return args.Get(0).([]*Trip), args.Get(1).(int64), args.Error(2)
-}
-
-func (m *MockService) AddCollaborator(ctx context.Context, userID, tripID, collaboratorID, role string) error {
-	args := m.Called(ctx, userID, tripID, collaboratorID, role)
-	return args.Error(0)
-}
-
-func (m *MockService) RemoveCollaborator(ctx context.Context, userID, tripID, collaboratorID string) error {
-	args := m.Called(ctx, userID, tripID, collaboratorID)
-	return args.Error(0)
-}
-
-func (m *MockService) UpdateCollaboratorRole(ctx context.Context, userID, tripID, collaboratorID, role string) error {
-	args := m.Called(ctx, userID, tripID, collaboratorID, role)
-	return args.Error(0)
-}
-
-func (m *MockService) InviteCollaborator(ctx context.Context, userID, tripID string, input *InviteCollaboratorInput) error {
-	args := m.Called(ctx, userID, tripID, input)
-	return args.Error(0)
+func (m *MockService) AudienceOf(ctx context.Context, userID string, trip *Trip) string {
+	args := m.Called(ctx, userID, trip)
+	return args.String(0)
 }
 
-func (m *MockService) AddWaypoint(ctx context.Context, userID, tripID string, input *AddWaypointInput) (*Waypoint, error) {
-	args := m.Called(ctx, userID, tripID, input)
+func (m *MockService) ViewAs(ctx context.Context, userID, tripID, audience string, relations Relations) (*TripPreview, error) {
+	args := m.Called(ctx, userID, tripID, audience, relations)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*Waypoint), args.Error(1)
+	return args.Get(0).(*TripPreview), args.Error(1)
 }
 
-func (m *MockService) UpdateWaypoint(ctx context.Context, userID, tripID, waypointID string, input *UpdateWaypointInput) (*Waypoint, error) {
-	args := m.Called(ctx, userID, tripID, waypointID, input)
+func (m *MockService) EstimateDuration(ctx context.Context, userID string, trip *Trip) (*DurationEstimate, error) {
+	args := m.Called(ctx, userID, trip)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*Waypoint), args.Error(1)
-}
-
-func (m *MockService) RemoveWaypoint(ctx context.Context, userID, tripID, waypointID string) error {
-	args := m.Called(ctx, userID, tripID, waypointID)
-	return args.Error(0)
-}
-
-func (m *MockService) ReorderWaypoints(ctx context.Context, userID, tripID string, waypointIDs []string) error {
-	args := m.Called(ctx, userID, tripID, waypointIDs)
-	return args.Error(0)
+	return args.Get(0).(*DurationEstimate), args.Error(1)
 }
 
-func (m *MockService) GetTripStats(ctx context.Context, userID, tripID string) (*TripStats, error) {
-	args := m.Called(ctx, userID, tripID)
+func (m *MockService) List(ctx context.Context, userID string, filter *TripFilter, limit, offset int) ([]*Trip, int64, error) {
+	args := m.Called(ctx, userID, filter, limit, offset)
 	if args.Get(0) == nil {
-		return nil, args.Error(1)
+		return nil, 0, args.Error(2)
 	}
-	return args.Get(0).(*TripStats), args.Error(1)
+	return args.Get(0).([]*Trip), args.Get(1).(int64), args.Error(2)
 }
 
-func (m *MockService) ExportTrip(ctx context.Context, userID, tripID, format string) ([]byte, error) {
-	args := m.Called(ctx, userID, tripID, format)
+func (m *MockService) Update(ctx context.Context, userID, tripID string, input *UpdateTripInput) (*Trip, error) {
+	args := m.Called(ctx, userID, tripID, input)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).([]byte), args.Error(1)
+	return args.Get(0).(*Trip), args.Error(1)
 }
 
-func (m *MockService) CloneTrip(ctx context.Context, userID, tripID string) (*Trip, error) {
+func (m *MockService) Delete(ctx context.Context, userID, tripID string) error {
 	args := m.Called(ctx, userID, tripID)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*Trip), args.Error(1)
+	return args.Error(0)
 }
 
 func TestHandler_CreateTrip(t *testing.T) {
@@ -229,7 +159,7 @@ func TestHandler_CreateTrip(t *testing.T) {
 			router := gin.New()
 			router.POST("/trips", func(c *gin.Context) {
 				c.Set("userID", tt.userID)
-				handler.CreateTrip(c)
+				handler.Create(c)
 			})
 
 			body, _ := json.Marshal(tt.input)
@@ -277,7 +207,9 @@ func TestHandler_GetTrip(t *testing.T) {
 					CreatedAt:   time.Now(),
 					UpdatedAt:   time.Now(),
 				}
-				ms.On("GetByID", mock.Anything, "user123", "trip123").Return(trip, nil)
+				ms.On("GetByIDWith", mock.Anything, "user123", "trip123", AllRelations).Return(trip, nil)
+				ms.On("AudienceOf", mock.Anything, "user123", trip).Return("")
+				ms.On("EstimateDuration", mock.Anything, "user123", trip).Return(nil, nil)
 			},
 			expectedCode: http.StatusOK,
 			expectedBody: map[string]interface{}{
@@ -289,7 +221,7 @@ func TestHandler_GetTrip(t *testing.T) {
 			userID: "user123",
 			tripID: "nonexistent",
 			mockSetup: func(ms *MockService) {
-				ms.On("GetByID", mock.Anything, "user123", "nonexistent").Return(nil, ErrTripNotFound)
+				ms.On("GetByIDWith", mock.Anything, "user123", "nonexistent", AllRelations).Return(nil, ErrTripNotFound)
 			},
 			expectedCode: http.StatusNotFound,
 			expectedBody: map[string]interface{}{
@@ -301,7 +233,7 @@ func TestHandler_GetTrip(t *testing.T) {
 			userID: "user456",
 			tripID: "trip123",
 			mockSetup: func(ms *MockService) {
-				ms.On("GetByID", mock.Anything, "user456", "trip123").Return(nil, ErrUnauthorized)
+				ms.On("GetByIDWith", mock.Anything, "user456", "trip123", AllRelations).Return(nil, ErrUnauthorized)
 			},
 			expectedCode: http.StatusForbidden,
 			expectedBody: map[string]interface{}{
@@ -321,7 +253,7 @@ func TestHandler_GetTrip(t *testing.T) {
 			router := gin.New()
 			router.GET("/trips/:id", func(c *gin.Context) {
 				c.Set("userID", tt.userID)
-				handler.GetTrip(c)
+				handler.GetByID(c)
 			})
 
 			req := httptest.NewRequest(http.MethodGet, "/trips/"+tt.tripID, nil)
@@ -341,6 +273,192 @@ func TestHandler_GetTrip(t *testing.T) {
 	}
 }
 
+func TestHandler_GetTripAudience(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(ms *MockService, userID, target string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		handler := NewHandler(ms)
+		router := gin.New()
+		router.GET("/trips/:id", func(c *gin.Context) {
+			if userID != "" {
+				c.Set("userID", userID)
+			}
+			handler.GetByID(c)
+		})
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+		var body map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return rec, body
+	}
+
+	trip := func() *Trip {
+		return &Trip{
+			ID:                "trip123",
+			OwnerID:           "owner",
+			Privacy:           PrivacyPublic,
+			EmergencyContacts: &JSONB{"ranger": "555-0100"},
+			Collaborators:     []Collaborator{{UserID: "editor"}},
+		}
+	}
+
+	t.Run("others see the trip as their audience does", func(t *testing.T) {
+		ms := new(MockService)
+		found := trip()
+		ms.On("GetByIDWith", mock.Anything, "", "trip123", AllRelations).Return(found, nil)
+		ms.On("AudienceOf", mock.Anything, "", found).Return(AudiencePublic)
+		ms.On("EstimateDuration", mock.Anything, "", mock.Anything).Return(nil, nil)
+
+		rec, body := serve(ms, "", "/trips/trip123")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		data := body["data"].(map[string]interface{})
+		assert.Nil(t, data["emergency_contacts"])
+		assert.Nil(t, data["collaborators"])
+		assert.Contains(t, rec.Header().Get("Cache-Control"), "public")
+	})
+
+	t.Run("owners see all of it, and caches keep it to themselves", func(t *testing.T) {
+		ms := new(MockService)
+		found := trip()
+		ms.On("GetByIDWith", mock.Anything, "owner", "trip123", AllRelations).Return(found, nil)
+		ms.On("AudienceOf", mock.Anything, "owner", found).Return("")
+		ms.On("EstimateDuration", mock.Anything, "owner", found).Return(nil, nil)
+
+		rec, body := serve(ms, "owner", "/trips/trip123")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotNil(t, body["data"].(map[string]interface{})["emergency_contacts"])
+		assert.Contains(t, rec.Header().Get("Cache-Control"), "private")
+	})
+
+	t.Run("owners preview an audience", func(t *testing.T) {
+		ms := new(MockService)
+		ms.On("ViewAs", mock.Anything, "owner", "trip123", AudienceFriend, AllRelations).
+			Return(&TripPreview{Audience: AudienceFriend, Visible: false, Trip: trip().ForAudience(AudienceFriend)}, nil)
+
+		rec, body := serve(ms, "owner", "/trips/trip123?view_as=friend")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		data := body["data"].(map[string]interface{})
+		assert.Equal(t, "friend", data["audience"])
+		assert.Equal(t, false, data["visible"])
+		assert.Nil(t, data["trip"].(map[string]interface{})["emergency_contacts"])
+		assert.Contains(t, rec.Header().Get("Cache-Control"), "private")
+
+		// Previews are not views, nor anyone's estimate
+		ms.AssertNotCalled(t, "EstimateDuration", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("previews are for owners", func(t *testing.T) {
+		ms := new(MockService)
+		ms.On("ViewAs", mock.Anything, "editor", "trip123", AudiencePublic, AllRelations).Return(nil, ErrUnauthorized)
+
+		rec, _ := serve(ms, "editor", "/trips/trip123?view_as=public")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		rec, _ = serve(new(MockService), "", "/trips/trip123?view_as=public")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("unknown audience", func(t *testing.T) {
+		ms := new(MockService)
+		ms.On("ViewAs", mock.Anything, "owner", "trip123", "everyone", AllRelations).Return(nil, ErrInvalidAudience)
+
+		rec, _ := serve(ms, "owner", "/trips/trip123?view_as=everyone")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestHandler_ListTrips(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(ms *MockService, userID string) map[string]interface{} {
+		handler := NewHandler(ms)
+		router := gin.New()
+		router.GET("/trips", func(c *gin.Context) {
+			if userID != "" {
+				c.Set("userID", userID)
+			}
+			handler.List(c)
+		})
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/trips?include=collaborators", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var body map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return body["data"].([]interface{})[0].(map[string]interface{})
+	}
+
+	listed := func() []*Trip {
+		return []*Trip{{
+			ID:                "trip123",
+			OwnerID:           "owner",
+			Privacy:           PrivacyPublic,
+			EmergencyContacts: &JSONB{"ranger": "555-0100"},
+			SharedWith:        pq.StringArray{"friend@example.com"},
+			Collaborators:     []Collaborator{{UserID: "editor"}},
+		}}
+	}
+
+	t.Run("guests see trips as the public does", func(t *testing.T) {
+		ms := new(MockService)
+		ms.On("List", mock.Anything, "", mock.MatchedBy(func(filter *TripFilter) bool {
+			return filter.Privacy == PrivacyPublic
+		}), 20, 0).Return(listed(), int64(1), nil)
+
+		trip := serve(ms, "")
+		assert.Equal(t, "trip123", trip["id"])
+		assert.Nil(t, trip["emergency_contacts"])
+		assert.Nil(t, trip["shared_with"])
+		assert.Nil(t, trip["collaborators"])
+	})
+
+	t.Run("members list the trips they are on in full", func(t *testing.T) {
+		ms := new(MockService)
+		ms.On("List", mock.Anything, "editor", mock.Anything, 20, 0).Return(listed(), int64(1), nil)
+
+		trip := serve(ms, "editor")
+		assert.NotNil(t, trip["emergency_contacts"])
+		assert.NotNil(t, trip["collaborators"])
+	})
+}
+
+func (m *MockService) AddWaypointShared(ctx context.Context, grant *ShareGrant, tripID string, input *AddWaypointInput) (*Waypoint, error) {
+	args := m.Called(ctx, grant, tripID, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Waypoint), args.Error(1)
+}
+
+func (m *MockService) UpdateWaypointShared(ctx context.Context, grant *ShareGrant, tripID, waypointID string, input *UpdateWaypointInput) (*Waypoint, error) {
+	args := m.Called(ctx, grant, tripID, waypointID, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Waypoint), args.Error(1)
+}
+
+func (m *MockService) RemoveWaypointShared(ctx context.Context, grant *ShareGrant, tripID, waypointID string) error {
+	args := m.Called(ctx, grant, tripID, waypointID)
+	return args.Error(0)
+}
+
+func (m *MockService) ReorderWaypointsShared(ctx context.Context, grant *ShareGrant, tripID string, waypointIDs []string) error {
+	args := m.Called(ctx, grant, tripID, waypointIDs)
+	return args.Error(0)
+}
+
+func (m *MockService) AddWaypointsShared(ctx context.Context, grant *ShareGrant, tripID string, input *AddWaypointsInput) (*Trip, error) {
+	args := m.Called(ctx, grant, tripID, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Trip), args.Error(1)
+}
+
 func TestHandler_UpdateTrip(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -406,7 +524,7 @@ func TestHandler_UpdateTrip(t *testing.T) {
 			router := gin.New()
 			router.PUT("/trips/:id", func(c *gin.Context) {
 				c.Set("userID", tt.userID)
-				handler.UpdateTrip(c)
+				handler.Update(c)
 			})
 
 			body, _ := json.Marshal(tt.input)
@@ -428,7 +546,252 @@ func TestHandler_UpdateTrip(t *testing.T) {
 	}
 }
 
-// Helper function
+func TestHandler_WaypointsShared(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	grant := &ShareGrant{TripID: "trip123", Scope: ShareScopeEdit}
+	const (
+		place1 = "30000000-0000-0000-0000-00000000000a"
+		place2 = "30000000-0000-0000-0000-00000000000b"
+		w1     = "40000000-0000-0000-0000-000000000001"
+		w2     = "40000000-0000-0000-0000-000000000002"
+	)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		mockSetup    func(*MockService)
+		expectedCode int
+	}{
+		{
+			name:   "add",
+			method: http.MethodPost,
+			path:   "/trips/trip123/waypoints",
+			body:   `{"place_id":"` + place1 + `"}`,
+			mockSetup: func(ms *MockService) {
+				ms.On("AddWaypointShared", mock.Anything, grant, "trip123", mock.Anything).Return(&Waypoint{ID: w1}, nil)
+			},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:   "add in bulk",
+			method: http.MethodPost,
+			path:   "/trips/trip123/waypoints/bulk",
+			body:   `{"place_ids":["` + place1 + `","` + place2 + `"]}`,
+			mockSetup: func(ms *MockService) {
+				ms.On("AddWaypointsShared", mock.Anything, grant, "trip123", mock.Anything).Return(&Trip{ID: "trip123"}, nil)
+			},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:   "update",
+			method: http.MethodPut,
+			path:   "/trips/trip123/waypoints/" + w1,
+			body:   `{"notes":"dinner"}`,
+			mockSetup: func(ms *MockService) {
+				ms.On("UpdateWaypointShared", mock.Anything, grant, "trip123", w1, mock.Anything).Return(&Waypoint{ID: w1}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "reorder",
+			method: http.MethodPut,
+			path:   "/trips/trip123/waypoints/order",
+			body:   `{"waypoint_ids":["` + w2 + `","` + w1 + `"]}`,
+			mockSetup: func(ms *MockService) {
+				ms.On("ReorderWaypointsShared", mock.Anything, grant, "trip123", []string{w2, w1}).Return(nil)
+			},
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:   "remove",
+			method: http.MethodDelete,
+			path:   "/trips/trip123/waypoints/" + w1,
+			mockSetup: func(ms *MockService) {
+				ms.On("RemoveWaypointShared", mock.Anything, grant, "trip123", w1).Return(nil)
+			},
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:   "refused by the grant",
+			method: http.MethodDelete,
+			path:   "/trips/trip123/waypoints/" + w1,
+			mockSetup: func(ms *MockService) {
+				ms.On("RemoveWaypointShared", mock.Anything, grant, "trip123", w1).Return(ErrUnauthorized)
+			},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockService)
+			tt.mockSetup(mockService)
+
+			handler := NewHandler(mockService)
+			router := gin.New()
+			// What the auth middleware sets for a scoped token: a grant and
+			// no user
+			router.Use(func(c *gin.Context) {
+				c.Set("shareGrant", grant)
+			})
+			router.POST("/trips/:id/waypoints", handler.AddWaypoint)
+			router.POST("/trips/:id/waypoints/bulk", handler.AddWaypoints)
+			router.PUT("/trips/:id/waypoints/order", handler.ReorderWaypoints)
+			router.PUT("/trips/:id/waypoints/:waypointId", handler.UpdateWaypoint)
+			router.DELETE("/trips/:id/waypoints/:waypointId", handler.RemoveWaypoint)
+
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code, rec.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// stubRouter plans a straight line between the points, or fails with err
+type stubRouter struct {
+	profile string
+	err     error
+}
+
+func (r *stubRouter) Route(ctx context.Context, profile string, points []directions.Point) (*directions.Route, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.profile = profile
+	route := &directions.Route{DistanceM: 12345, DurationS: 5400}
+	for _, p := range points {
+		route.Coordinates = append(route.Coordinates, []float64{p.Longitude, p.Latitude})
+	}
+	return route, nil
+}
+
+func TestHandler_PlanRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	waypoints := `{"profile": "cycling", "waypoints": [{"latitude": 44.36, "longitude": -121.14}, {"latitude": 44.05, "longitude": -121.31}]}`
+
+	tests := []struct {
+		name         string
+		body         string
+		routerErr    error
+		mockSetup    func(*MockService)
+		expectedCode int
+	}{
+		{
+			name: "stores the planned route",
+			body: waypoints,
+			mockSetup: func(ms *MockService) {
+				ms.On("Update", mock.Anything, "user123", "trip123", &UpdateTripInput{
+					RouteGeoJSON:  &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{-121.14, 44.36}, {-121.31, 44.05}}},
+					DistanceKm:    float64Ptr(12.35),
+					DurationHours: float64Ptr(1.5),
+				}).Return(&Trip{ID: "trip123"}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "one waypoint",
+			body:         `{"profile": "walking", "waypoints": [{"latitude": 44.36, "longitude": -121.14}]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown profile",
+			body:         `{"profile": "sailing", "waypoints": [{"latitude": 44.36, "longitude": -121.14}, {"latitude": 44.05, "longitude": -121.31}]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "no route",
+			body:         waypoints,
+			routerErr:    directions.ErrNoRoute,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "directions unavailable",
+			body:         waypoints,
+			routerErr:    errors.New("mapbox API returned status 503"),
+			expectedCode: http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockService)
+			if tt.mockSetup != nil {
+				tt.mockSetup(mockService)
+			}
+			router := &stubRouter{err: tt.routerErr}
+
+			handler := NewHandler(mockService)
+			handler.SetRoutePlanner(NewRoutePlanner(router))
+			engine := gin.New()
+			engine.POST("/trips/:id/route", func(c *gin.Context) {
+				c.Set("userID", "user123")
+				handler.PlanRoute(c)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/trips/trip123/route", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedCode == http.StatusOK {
+				assert.Equal(t, "cycling", router.profile)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_DeleteTrip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		userID       string
+		err          error
+		expectedCode int
+	}{
+		{name: "successful deletion", userID: "user123", expectedCode: http.StatusNoContent},
+		{name: "not the owner", userID: "user456", err: ErrUnauthorized, expectedCode: http.StatusForbidden},
+		{name: "trip not found", userID: "user123", err: ErrTripNotFound, expectedCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockService)
+			mockService.On("Delete", mock.Anything, tt.userID, "trip123").Return(tt.err)
+
+			handler := NewHandler(mockService)
+			router := gin.New()
+			router.DELETE("/trips/:id", func(c *gin.Context) {
+				c.Set("userID", tt.userID)
+				handler.Delete(c)
+			})
+
+			req := httptest.NewRequest(http.MethodDelete, "/trips/trip123", nil)
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// Helper functions
 func stringPtr(s string) *string {
 	return &s
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
 }
\ No newline at end of file
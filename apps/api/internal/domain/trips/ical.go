@@ -0,0 +1,239 @@
+package trips
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Oferzz/newMap/apps/api/internal/locale"
+)
+
+const (
+	icsDateLayout     = "20060102"
+	icsDateTimeLayout = "20060102T150405"
+
+	// icsLineLimit is the longest a content line may be, in octets, before
+	// it is folded
+	icsLineLimit = 75
+)
+
+var icsTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// exportICS writes the trip as an iCalendar file: its dates as an all-day
+// event and each stop of its itinerary as an event from the estimated arrival
+// to the departure. Stop times are given in the trip's time zone, described
+// by a VTIMEZONE covering the itinerary, so calendars place them right on
+// either side of a daylight saving change.
+func exportICS(trip *Trip, itinerary *Itinerary, loc locale.Locale, now time.Time) []byte {
+	location := tripLocation(trip)
+	stamp := now.UTC().Format(icsDateTimeLayout) + "Z"
+
+	var buf bytes.Buffer
+	line := func(format string, args ...interface{}) {
+		writeICSLine(&buf, fmt.Sprintf(format, args...))
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//newMap//Trips//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:%s", icsText(trip.Title))
+	line("X-WR-TIMEZONE:%s", location.String())
+
+	// Times at stops, to learn which offsets the time zone needs
+	var from, to time.Time
+	for _, stop := range itinerary.Stops {
+		for _, at := range []*time.Time{stop.EstimatedArrival, stop.EstimatedDeparture} {
+			if at == nil {
+				continue
+			}
+			if from.IsZero() || at.Before(from) {
+				from = *at
+			}
+			if to.IsZero() || at.After(to) {
+				to = *at
+			}
+		}
+	}
+	if !from.IsZero() && location != time.UTC {
+		writeVTimezone(&buf, location, from, to)
+	}
+
+	// All-day events end on the day after they finish. Dates are calendar
+	// dates, so days are added to the date rather than hours to the time.
+	if trip.StartDate != nil {
+		end := trip.StartDate
+		if trip.EndDate != nil && !trip.EndDate.Before(*trip.StartDate) {
+			end = trip.EndDate
+		}
+		line("BEGIN:VEVENT")
+		line("UID:%s@newmap", trip.ID)
+		line("DTSTAMP:%s", stamp)
+		line("DTSTART;VALUE=DATE:%s", trip.StartDate.Format(icsDateLayout))
+		line("DTEND;VALUE=DATE:%s", startOfDay(*end, time.UTC).AddDate(0, 0, 1).Format(icsDateLayout))
+		line("SUMMARY:%s", icsText(trip.Title))
+		if description := joinParagraphs(trip.Description, routeSummary(trip.DistanceKm, trip.ElevationGainM, trip.DurationHours, loc)); description != "" {
+			line("DESCRIPTION:%s", icsText(description))
+		}
+		line("END:VEVENT")
+	}
+
+	for _, stop := range itinerary.Stops {
+		if stop.EstimatedArrival == nil {
+			continue
+		}
+		line("BEGIN:VEVENT")
+		line("UID:%s@newmap", stop.WaypointID)
+		line("DTSTAMP:%s", stamp)
+		line("DTSTART%s", icsDateTime(*stop.EstimatedArrival, location))
+		if stop.EstimatedDeparture != nil && stop.EstimatedDeparture.After(*stop.EstimatedArrival) {
+			line("DTEND%s", icsDateTime(*stop.EstimatedDeparture, location))
+		}
+		line("SUMMARY:%s", icsText(stopName(stop)))
+		if stop.PlaceName != "" {
+			line("LOCATION:%s", icsText(stop.PlaceName))
+		}
+		if stop.Window != nil {
+			if description := windowDescription(stop.Window, location, loc); description != "" {
+				line("DESCRIPTION:%s", icsText(description))
+			}
+		}
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+
+	return buf.Bytes()
+}
+
+// windowDescription describes a stop's time window, such as "Ferry: 9:00 AM
+// – 5:30 PM", on the trip's clock
+func windowDescription(window *TimeWindow, location *time.Location, loc locale.Locale) string {
+	var times string
+	switch {
+	case window.OpensAt != nil && window.ClosesAt != nil:
+		times = loc.Time(window.OpensAt.In(location)) + " – " + loc.Time(window.ClosesAt.In(location))
+	case window.OpensAt != nil:
+		times = "from " + loc.Time(window.OpensAt.In(location))
+	case window.ClosesAt != nil:
+		times = "until " + loc.Time(window.ClosesAt.In(location))
+	}
+
+	switch {
+	case window.Label != "" && times != "":
+		return window.Label + ": " + times
+	case window.Label != "":
+		return window.Label
+	default:
+		return times
+	}
+}
+
+// icsDateTime is a DTSTART or DTEND value with its parameters: local time
+// in the trip's time zone, or UTC when that is the trip's zone
+func icsDateTime(t time.Time, location *time.Location) string {
+	if location == time.UTC {
+		return ":" + t.UTC().Format(icsDateTimeLayout) + "Z"
+	}
+	return fmt.Sprintf(";TZID=%s:%s", location.String(), t.In(location).Format(icsDateTimeLayout))
+}
+
+// writeVTimezone describes the location from the start of the day before
+// from until the day after to: the offset in effect at the start, then each
+// change of offset. Go does not expose a zone's rules, so the changes are
+// found by stepping through the span an hour at a time and narrowing each
+// one down to the second.
+func writeVTimezone(buf *bytes.Buffer, location *time.Location, from, to time.Time) {
+	start := startOfDay(from.In(location), location).AddDate(0, 0, -1)
+	end := startOfDay(to.In(location), location).AddDate(0, 0, 2)
+
+	writeICSLine(buf, "BEGIN:VTIMEZONE")
+	writeICSLine(buf, "TZID:"+location.String())
+
+	name, offset := start.Zone()
+	writeObservance(buf, start, name, offset, offset)
+
+	for t := start.Unix(); t < end.Unix(); t += 3600 {
+		next := t + 3600
+		if _, o := time.Unix(next, 0).In(location).Zone(); o == offset {
+			continue
+		}
+
+		lo, hi := t, next
+		for hi-lo > 1 {
+			mid := lo + (hi-lo)/2
+			if _, o := time.Unix(mid, 0).In(location).Zone(); o == offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+
+		change := time.Unix(hi, 0).In(location)
+		name, changed := change.Zone()
+		writeObservance(buf, change, name, offset, changed)
+		offset = changed
+	}
+
+	writeICSLine(buf, "END:VTIMEZONE")
+}
+
+// writeObservance writes a STANDARD or DAYLIGHT component for an offset
+// taking effect at the time. Its start is given in the local time of the
+// offset it replaces.
+func writeObservance(buf *bytes.Buffer, at time.Time, name string, from, to int) {
+	kind := "STANDARD"
+	if at.IsDST() {
+		kind = "DAYLIGHT"
+	}
+
+	writeICSLine(buf, "BEGIN:"+kind)
+	writeICSLine(buf, "DTSTART:"+at.In(time.FixedZone(name, from)).Format(icsDateTimeLayout))
+	writeICSLine(buf, "TZOFFSETFROM:"+icsOffset(from))
+	writeICSLine(buf, "TZOFFSETTO:"+icsOffset(to))
+	writeICSLine(buf, "TZNAME:"+icsText(name))
+	writeICSLine(buf, "END:"+kind)
+}
+
+// icsOffset formats an offset from UTC in seconds as +HHMM, or +HHMMSS when
+// it is not a whole number of minutes
+func icsOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	formatted := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+	if seconds%60 != 0 {
+		formatted += fmt.Sprintf("%02d", seconds%60)
+	}
+	return formatted
+}
+
+// icsText escapes text for a TEXT property value
+func icsText(s string) string {
+	return icsTextEscaper.Replace(s)
+}
+
+// writeICSLine writes a content line ending in CRLF, folding it onto
+// continuation lines that begin with a space where it runs over the limit,
+// without splitting a character
+func writeICSLine(buf *bytes.Buffer, line string) {
+	limit := icsLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+
+		// The leading space counts toward the limit
+		limit = icsLineLimit - 1
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
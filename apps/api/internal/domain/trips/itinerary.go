@@ -0,0 +1,251 @@
+package trips
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Itinerary warning kinds
+const (
+	WarningMissedWindow = "missed_window"    // Arrives after the window closes
+	WarningWaitsWindow  = "waits_for_window" // Arrives before the window opens
+	WarningNoStartTime  = "no_start_time"    // Nothing to estimate arrivals from
+	WarningNoLocation   = "no_location"      // A stop cannot be travelled to
+)
+
+// TimeWindow is when a waypoint can be reached, such as a ferry departure or
+// a hut check-in. Either end may be open.
+type TimeWindow struct {
+	OpensAt  *time.Time `json:"opens_at,omitempty"`
+	ClosesAt *time.Time `json:"closes_at,omitempty"`
+	Label    string     `json:"label,omitempty"`
+}
+
+// IsZero reports whether the window sets no times
+func (w *TimeWindow) IsZero() bool {
+	return w.OpensAt == nil && w.ClosesAt == nil
+}
+
+type SetTimeWindowInput struct {
+	OpensAt  *time.Time `json:"opens_at"`
+	ClosesAt *time.Time `json:"closes_at"`
+	Label    string     `json:"label" binding:"max=100"`
+}
+
+// ItineraryStop is a waypoint with the times it is expected to be reached
+// and left
+type ItineraryStop struct {
+	WaypointID         string      `json:"waypoint_id"`
+	PlaceName          string      `json:"place_name"`
+	EstimatedArrival   *time.Time  `json:"estimated_arrival,omitempty"`
+	EstimatedDeparture *time.Time  `json:"estimated_departure,omitempty"`
+	Window             *TimeWindow `json:"time_window,omitempty"`
+}
+
+// ItineraryWarning is a part of the plan that will not work out as is
+type ItineraryWarning struct {
+	WaypointID string `json:"waypoint_id,omitempty"`
+	Kind       string `json:"kind"`
+	Message    string `json:"message"`
+	Minutes    int    `json:"minutes,omitempty"` // How late or early
+}
+
+// Itinerary is a trip's waypoints in order with estimated times
+type Itinerary struct {
+	TripID   string             `json:"trip_id"`
+	Stops    []ItineraryStop    `json:"stops"`
+	Warnings []ItineraryWarning `json:"warnings"`
+}
+
+// TravelEstimator estimates how long it takes to travel between two points.
+// A routing service can stand in for the straight-line estimate.
+type TravelEstimator interface {
+	TravelTime(ctx context.Context, from, to *GeoJSON, activityType string) (time.Duration, error)
+}
+
+// straightLineEstimator times travel along the straight line between points,
+// lengthened for the bends of real paths, at a typical speed for the activity
+type straightLineEstimator struct{}
+
+// detourFactor is how much longer a path usually is than the straight line
+const detourFactor = 1.3
+
+// travelSpeedKmh is the typical moving speed of each activity
+var travelSpeedKmh = map[string]float64{
+	"hiking":      4,
+	"backpacking": 3.5,
+	"walking":     4.5,
+	"running":     9,
+	"biking":      15,
+	"skiing":      8,
+	"kayaking":    6,
+	"canoeing":    5,
+	"rafting":     8,
+	"sightseeing": 4.5,
+}
+
+const defaultTravelSpeedKmh = 4
+
+func (straightLineEstimator) TravelTime(ctx context.Context, from, to *GeoJSON, activityType string) (time.Duration, error) {
+	speed, ok := travelSpeedKmh[activityType]
+	if !ok {
+		speed = defaultTravelSpeedKmh
+	}
+
+	km := haversine(from.Coordinates, to.Coordinates) / 1000 * detourFactor
+	return time.Duration(km / speed * float64(time.Hour)), nil
+}
+
+// planItinerary estimates when each waypoint is reached, starting from the
+// first planned time, and warns of windows the plan cannot make. Planned
+// arrival and departure times are kept where the estimate allows them; a stop
+// is never left before its window opens. Bail-out waypoints are not stops and
+// are left out.
+//
+// Travel is added as elapsed time, so estimates stay right across daylight
+// saving changes. A trip with only a start date sets out at the start of that
+// day in its time zone, and the estimates are given in that zone.
+func planItinerary(ctx context.Context, trip *Trip, travel TravelEstimator) (*Itinerary, error) {
+	location := tripLocation(trip)
+
+	stops := make([]Waypoint, 0, len(trip.Waypoints))
+	for _, waypoint := range trip.Waypoints {
+		if waypoint.Kind != WaypointBailout {
+			stops = append(stops, waypoint)
+		}
+	}
+
+	itinerary := &Itinerary{
+		TripID:   trip.ID,
+		Stops:    make([]ItineraryStop, 0, len(stops)),
+		Warnings: []ItineraryWarning{},
+	}
+	if len(stops) == 0 {
+		return itinerary, nil
+	}
+
+	first := stops[0]
+	clock := first.ArrivalTime
+	if clock == nil {
+		clock = first.DepartureTime
+	}
+	if clock == nil && trip.StartDate != nil {
+		start := startOfDay(*trip.StartDate, location)
+		clock = &start
+	}
+	if clock == nil {
+		itinerary.Warnings = append(itinerary.Warnings, ItineraryWarning{
+			Kind:    WarningNoStartTime,
+			Message: "Set a start date or a time at the first waypoint to estimate arrivals",
+		})
+	}
+
+	var previous *GeoJSON
+	for i, waypoint := range stops {
+		stop := ItineraryStop{
+			WaypointID: waypoint.ID,
+			Window:     waypoint.Window,
+		}
+		var location *GeoJSON
+		if waypoint.Place != nil {
+			stop.PlaceName = waypoint.Place.Name
+			location = waypoint.Place.Location
+		}
+
+		// Travel from the previous stop, when there is a clock to move on
+		if i > 0 && clock != nil {
+			if previous == nil || location == nil {
+				itinerary.Warnings = append(itinerary.Warnings, ItineraryWarning{
+					WaypointID: waypoint.ID,
+					Kind:       WarningNoLocation,
+					Message:    fmt.Sprintf("%s has no location to estimate travel from", stopName(stop)),
+				})
+				clock = nil
+			} else {
+				duration, err := travel.TravelTime(ctx, previous, location, trip.ActivityType)
+				if err != nil {
+					return nil, fmt.Errorf("failed to estimate travel time: %w", err)
+				}
+				arrival := clock.Add(duration)
+				clock = &arrival
+			}
+		}
+		previous = location
+
+		if clock == nil {
+			itinerary.Stops = append(itinerary.Stops, stop)
+			continue
+		}
+
+		// Arriving ahead of the planned time means waiting for it
+		arrival := *clock
+		if waypoint.ArrivalTime != nil && waypoint.ArrivalTime.After(arrival) {
+			arrival = *waypoint.ArrivalTime
+		}
+		stop.EstimatedArrival = &arrival
+
+		departure := arrival
+		if waypoint.DepartureTime != nil && waypoint.DepartureTime.After(departure) {
+			departure = *waypoint.DepartureTime
+		}
+
+		if window := waypoint.Window; window != nil {
+			if window.ClosesAt != nil && arrival.After(*window.ClosesAt) {
+				late := arrival.Sub(*window.ClosesAt)
+				itinerary.Warnings = append(itinerary.Warnings, ItineraryWarning{
+					WaypointID: waypoint.ID,
+					Kind:       WarningMissedWindow,
+					Message:    fmt.Sprintf("%s is reached %s after %s closes", stopName(stop), formatMinutes(late), windowName(window)),
+					Minutes:    minutes(late),
+				})
+			}
+			if window.OpensAt != nil && arrival.Before(*window.OpensAt) {
+				early := window.OpensAt.Sub(arrival)
+				itinerary.Warnings = append(itinerary.Warnings, ItineraryWarning{
+					WaypointID: waypoint.ID,
+					Kind:       WarningWaitsWindow,
+					Message:    fmt.Sprintf("%s is reached %s before %s opens", stopName(stop), formatMinutes(early), windowName(window)),
+					Minutes:    minutes(early),
+				})
+				if window.OpensAt.After(departure) {
+					departure = *window.OpensAt
+				}
+			}
+		}
+
+		stop.EstimatedDeparture = &departure
+		clock = &departure
+		itinerary.Stops = append(itinerary.Stops, stop)
+	}
+
+	itinerary.localize(location)
+
+	return itinerary, nil
+}
+
+func stopName(stop ItineraryStop) string {
+	if stop.PlaceName != "" {
+		return stop.PlaceName
+	}
+	return "A waypoint"
+}
+
+func windowName(window *TimeWindow) string {
+	if window.Label != "" {
+		return window.Label
+	}
+	return "its time window"
+}
+
+func minutes(d time.Duration) int {
+	return int(d.Round(time.Minute) / time.Minute)
+}
+
+func formatMinutes(d time.Duration) string {
+	m := minutes(d)
+	if m < 60 {
+		return fmt.Sprintf("%d min", m)
+	}
+	return fmt.Sprintf("%dh %02dmin", m/60, m%60)
+}
@@ -0,0 +1,126 @@
+package trips
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedTravel takes the same time between any two points
+type fixedTravel time.Duration
+
+func (f fixedTravel) TravelTime(ctx context.Context, from, to *GeoJSON, activityType string) (time.Duration, error) {
+	return time.Duration(f), nil
+}
+
+func TestPlanItinerary(t *testing.T) {
+	ctx := context.Background()
+	at := func(hour, minute int) *time.Time {
+		t := time.Date(2026, 6, 1, hour, minute, 0, 0, time.UTC)
+		return &t
+	}
+	stop := func(id, name string) Waypoint {
+		return Waypoint{ID: id, Kind: WaypointStop, Place: &Place{ID: "place-" + id, Name: name, Location: &GeoJSON{Type: "Point", Coordinates: []float64{7, 46}}}}
+	}
+	warnings := func(itinerary *Itinerary) []string {
+		kinds := []string{}
+		for _, warning := range itinerary.Warnings {
+			kinds = append(kinds, warning.Kind)
+		}
+		return kinds
+	}
+
+	t.Run("windows missed and waited for", func(t *testing.T) {
+		trailhead, ferry, hut := stop("w1", "Trailhead"), stop("w2", "Ferry"), stop("w3", "")
+		trailhead.DepartureTime = at(8, 0)
+		ferry.Window = &TimeWindow{ClosesAt: at(9, 30), Label: "the last ferry"}
+		hut.Window = &TimeWindow{OpensAt: at(13, 15)}
+		bailout := stop("b1", "Road")
+		bailout.Kind = WaypointBailout
+
+		trip := &Trip{ID: tripID, Waypoints: []Waypoint{trailhead, bailout, ferry, hut}}
+		itinerary, err := planItinerary(ctx, trip, fixedTravel(2*time.Hour))
+		require.NoError(t, err)
+
+		require.Len(t, itinerary.Stops, 3, "bail-out points are not stops")
+		assert.Equal(t, at(10, 0).Unix(), itinerary.Stops[1].EstimatedArrival.Unix())
+		assert.Equal(t, at(12, 0).Unix(), itinerary.Stops[2].EstimatedArrival.Unix())
+		assert.Equal(t, at(13, 15).Unix(), itinerary.Stops[2].EstimatedDeparture.Unix(), "stops are not left before their window opens")
+
+		require.Equal(t, []string{WarningMissedWindow, WarningWaitsWindow}, warnings(itinerary))
+		assert.Equal(t, "Ferry is reached 30 min after the last ferry closes", itinerary.Warnings[0].Message)
+		assert.Equal(t, 30, itinerary.Warnings[0].Minutes)
+		assert.Equal(t, "A waypoint is reached 1h 15min before its time window opens", itinerary.Warnings[1].Message)
+		assert.Equal(t, 75, itinerary.Warnings[1].Minutes)
+	})
+
+	t.Run("planned times are kept when reachable", func(t *testing.T) {
+		first, second := stop("w1", "Trailhead"), stop("w2", "Lake")
+		first.ArrivalTime, first.DepartureTime = at(7, 30), at(8, 0)
+		second.ArrivalTime, second.DepartureTime = at(11, 0), at(12, 0)
+
+		itinerary, err := planItinerary(ctx, &Trip{ID: tripID, Waypoints: []Waypoint{first, second}}, fixedTravel(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, at(7, 30).Unix(), itinerary.Stops[0].EstimatedArrival.Unix())
+		assert.Equal(t, at(11, 0).Unix(), itinerary.Stops[1].EstimatedArrival.Unix())
+		assert.Equal(t, at(12, 0).Unix(), itinerary.Stops[1].EstimatedDeparture.Unix())
+		assert.Empty(t, itinerary.Warnings)
+	})
+
+	t.Run("no start time", func(t *testing.T) {
+		itinerary, err := planItinerary(ctx, &Trip{ID: tripID, Waypoints: []Waypoint{stop("w1", "A"), stop("w2", "B")}}, fixedTravel(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, []string{WarningNoStartTime}, warnings(itinerary))
+		require.Len(t, itinerary.Stops, 2)
+		assert.Nil(t, itinerary.Stops[1].EstimatedArrival)
+	})
+
+	t.Run("a stop without a location", func(t *testing.T) {
+		first := stop("w1", "Trailhead")
+		first.DepartureTime = at(8, 0)
+		unknown := Waypoint{ID: "w2", Kind: WaypointStop}
+
+		itinerary, err := planItinerary(ctx, &Trip{ID: tripID, Waypoints: []Waypoint{first, unknown, stop("w3", "Hut")}}, fixedTravel(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, []string{WarningNoLocation}, warnings(itinerary))
+		assert.Equal(t, "w2", itinerary.Warnings[0].WaypointID)
+		assert.Nil(t, itinerary.Stops[1].EstimatedArrival)
+		assert.Nil(t, itinerary.Stops[2].EstimatedArrival, "later stops cannot be timed either")
+	})
+}
+
+func TestPlanItinerary_TripTimezone(t *testing.T) {
+	ctx := context.Background()
+	denver, err := time.LoadLocation("America/Denver")
+	require.NoError(t, err)
+
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	window := time.Date(2026, 6, 1, 15, 0, 0, 0, time.UTC)
+	trip := &Trip{
+		ID:        tripID,
+		Timezone:  "America/Denver",
+		StartDate: &start,
+		Waypoints: []Waypoint{
+			{ID: "w1", Kind: WaypointStop, Window: &TimeWindow{OpensAt: &window}},
+		},
+	}
+
+	itinerary, err := planItinerary(ctx, trip, straightLineEstimator{})
+	require.NoError(t, err)
+	require.Len(t, itinerary.Stops, 1)
+
+	stop := itinerary.Stops[0]
+	assert.Equal(t, time.Date(2026, 6, 1, 0, 0, 0, 0, denver).Unix(), stop.EstimatedArrival.Unix(), "sets out at the start of the day in the trip's zone")
+	assert.Equal(t, denver, stop.EstimatedArrival.Location())
+	assert.Equal(t, 9, stop.Window.OpensAt.Hour(), "windows are shown in the trip's zone")
+}
+
+func TestFormatMinutes(t *testing.T) {
+	assert.Equal(t, "0 min", formatMinutes(20*time.Second))
+	assert.Equal(t, "45 min", formatMinutes(45*time.Minute))
+	assert.Equal(t, "1h 05min", formatMinutes(65*time.Minute))
+	assert.Equal(t, "26h 00min", formatMinutes(26*time.Hour))
+}
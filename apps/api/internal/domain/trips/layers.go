@@ -0,0 +1,339 @@
+package trips
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/media"
+	"github.com/google/uuid"
+)
+
+// Layer file formats
+const (
+	LayerFormatGeoJSON = "geojson"
+	LayerFormatKML     = "kml"
+)
+
+// MaxLayerSize is the largest layer file accepted, in bytes
+const MaxLayerSize = 10 * 1024 * 1024
+
+// TripLayer is a custom overlay drawn over a trip's map, such as avalanche
+// zones or property boundaries. The file itself is kept in media storage.
+type TripLayer struct {
+	ID           string    `db:"id" json:"id"`
+	TripID       string    `db:"trip_id" json:"trip_id"`
+	Name         string    `db:"name" json:"name"`
+	Format       string    `db:"format" json:"format"`
+	StoragePath  string    `db:"storage_path" json:"-"`
+	SizeBytes    int64     `db:"size_bytes" json:"size_bytes"`
+	FeatureCount int       `db:"feature_count" json:"feature_count"`
+	CreatedBy    string    `db:"created_by" json:"created_by"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	URL          string    `db:"-" json:"url"`
+}
+
+// CreateLayerInput is an uploaded layer file
+type CreateLayerInput struct {
+	Name     string
+	Filename string
+	Data     []byte
+}
+
+// LayerService keeps the custom map layers of trips
+type LayerService struct {
+	repo      Repository
+	storage   media.Storage
+	urlExpiry time.Duration
+}
+
+// NewLayerService creates a layer service storing files in media storage.
+// URLs are signed for urlExpiry when the storage signs URLs.
+func NewLayerService(repo Repository, storage media.Storage, urlExpiry time.Duration) *LayerService {
+	return &LayerService{
+		repo:      repo,
+		storage:   storage,
+		urlExpiry: urlExpiry,
+	}
+}
+
+// ListLayers returns the layers of a trip the user can view
+func (s *LayerService) ListLayers(ctx context.Context, userID, tripID string) ([]*TripLayer, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if !trip.VisibleTo(userID) {
+		return nil, ErrUnauthorized
+	}
+
+	layers, err := s.repo.ListLayers(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layer := range layers {
+		if err := s.setURL(layer); err != nil {
+			return nil, err
+		}
+	}
+
+	return layers, nil
+}
+
+// CreateLayer checks an uploaded GeoJSON or KML file, stores it and adds it
+// to the trip
+func (s *LayerService) CreateLayer(ctx context.Context, userID, tripID string, input *CreateLayerInput) (*TripLayer, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if !trip.CanUserEdit(userID) {
+		return nil, ErrUnauthorized
+	}
+
+	if len(input.Data) > MaxLayerSize {
+		return nil, ErrLayerTooLarge
+	}
+
+	format, features, err := readLayer(input.Filename, input.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(input.Filename), filepath.Ext(input.Filename))
+	}
+
+	layer := &TripLayer{
+		ID:           uuid.New().String(),
+		TripID:       tripID,
+		Name:         name,
+		Format:       format,
+		SizeBytes:    int64(len(input.Data)),
+		FeatureCount: features,
+		CreatedBy:    userID,
+	}
+	layer.StoragePath = filepath.Join("layers", tripID, layer.ID+"."+format)
+
+	if _, err := s.storage.Save(layer.StoragePath, input.Data); err != nil {
+		return nil, fmt.Errorf("failed to store layer: %w", err)
+	}
+
+	if err := s.repo.CreateLayer(ctx, layer); err != nil {
+		_ = s.storage.Delete(layer.StoragePath)
+		return nil, err
+	}
+
+	if err := s.setURL(layer); err != nil {
+		return nil, err
+	}
+
+	return layer, nil
+}
+
+// DeleteLayer removes a layer and its file
+func (s *LayerService) DeleteLayer(ctx context.Context, userID, tripID, layerID string) error {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return err
+	}
+
+	if !trip.CanUserEdit(userID) {
+		return ErrUnauthorized
+	}
+
+	layer, err := s.repo.GetLayer(ctx, layerID)
+	if err != nil {
+		return err
+	}
+	if layer.TripID != tripID {
+		return ErrLayerNotFound
+	}
+
+	if err := s.repo.DeleteLayer(ctx, layerID); err != nil {
+		return err
+	}
+
+	// The record is gone, so a file left behind is only wasted space
+	_ = s.storage.Delete(layer.StoragePath)
+
+	return nil
+}
+
+// exportLayers returns references to the trip's layers, with the URLs their
+// files can be fetched from, and the features of its GeoJSON layers, each
+// tagged with the layer it comes from. A layer whose file cannot be read is
+// exported by reference only.
+func (s *LayerService) exportLayers(ctx context.Context, tripID string) ([]exportLayer, []exportFeature, error) {
+	layers, err := s.repo.ListLayers(ctx, tripID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refs := make([]exportLayer, 0, len(layers))
+	features := []exportFeature{}
+	for _, layer := range layers {
+		if err := s.setURL(layer); err != nil {
+			return nil, nil, err
+		}
+		refs = append(refs, exportLayer{
+			ID:           layer.ID,
+			Name:         layer.Name,
+			Format:       layer.Format,
+			FeatureCount: layer.FeatureCount,
+			URL:          layer.URL,
+		})
+
+		if layer.Format != LayerFormatGeoJSON {
+			continue
+		}
+		data, err := readStoredFile(s.storage, layer.StoragePath)
+		if err != nil {
+			log.Printf("Failed to read layer %s for export of trip %s: %v", layer.ID, tripID, err)
+			continue
+		}
+		layerFeatures, err := geoJSONFeatures(data)
+		if err != nil {
+			log.Printf("Failed to read layer %s for export of trip %s: %v", layer.ID, tripID, err)
+			continue
+		}
+		for _, feature := range layerFeatures {
+			feature.Properties["feature"] = "layer"
+			feature.Properties["layer_id"] = layer.ID
+			feature.Properties["layer_name"] = layer.Name
+			features = append(features, feature)
+		}
+	}
+
+	return refs, features, nil
+}
+
+// setURL sets the URL the layer file can be fetched from, signed when the
+// storage signs URLs
+func (s *LayerService) setURL(layer *TripLayer) error {
+	url, err := storageURL(s.storage, layer.StoragePath, s.urlExpiry)
+	if err != nil {
+		return fmt.Errorf("failed to sign layer URL: %w", err)
+	}
+	layer.URL = url
+
+	return nil
+}
+
+// readLayer tells the format of a layer file from its name and checks that
+// its content is of that format, returning how many features it holds
+func readLayer(filename string, data []byte) (string, int, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".geojson", ".json":
+		features, err := countGeoJSONFeatures(data)
+		return LayerFormatGeoJSON, features, err
+	case ".kml":
+		features, err := countKMLFeatures(data)
+		return LayerFormatKML, features, err
+	}
+
+	return "", 0, ErrInvalidLayer
+}
+
+func countGeoJSONFeatures(data []byte) (int, error) {
+	var doc struct {
+		Type     string            `json:"type"`
+		Features []json.RawMessage `json:"features"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return 0, ErrInvalidLayer
+	}
+
+	switch doc.Type {
+	case "FeatureCollection":
+		return len(doc.Features), nil
+	case "Feature", "Point", "MultiPoint", "LineString", "MultiLineString",
+		"Polygon", "MultiPolygon", "GeometryCollection":
+		return 1, nil
+	}
+
+	return 0, ErrInvalidLayer
+}
+
+// geoJSONFeatures returns the features of a GeoJSON document, a bare
+// geometry making up a feature of its own
+func geoJSONFeatures(data []byte) ([]exportFeature, error) {
+	type feature struct {
+		Geometry   json.RawMessage        `json:"geometry"`
+		Properties map[string]interface{} `json:"properties"`
+	}
+	var doc struct {
+		Type     string    `json:"type"`
+		Features []feature `json:"features"`
+		feature
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, ErrInvalidLayer
+	}
+
+	var found []feature
+	switch doc.Type {
+	case "FeatureCollection":
+		found = doc.Features
+	case "Feature":
+		found = []feature{doc.feature}
+	default:
+		found = []feature{{Geometry: data}}
+	}
+
+	features := make([]exportFeature, 0, len(found))
+	for _, f := range found {
+		properties := f.Properties
+		if properties == nil {
+			properties = map[string]interface{}{}
+		}
+		features = append(features, exportFeature{Type: "Feature", Geometry: f.Geometry, Properties: properties})
+	}
+	return features, nil
+}
+
+func countKMLFeatures(data []byte) (int, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	root := true
+	features := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, ErrInvalidLayer
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root {
+			if start.Name.Local != "kml" {
+				return 0, ErrInvalidLayer
+			}
+			root = false
+		}
+		if start.Name.Local == "Placemark" {
+			features++
+		}
+	}
+
+	if root {
+		return 0, ErrInvalidLayer
+	}
+
+	return features, nil
+}
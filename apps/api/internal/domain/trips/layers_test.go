@@ -0,0 +1,42 @@
+package trips
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeoJSONFeatures(t *testing.T) {
+	t.Run("collection", func(t *testing.T) {
+		features, err := geoJSONFeatures([]byte(`{"type":"FeatureCollection","features":[
+			{"type":"Feature","geometry":{"type":"Point","coordinates":[7,46]},"properties":{"name":"Hut"}},
+			{"type":"Feature","geometry":{"type":"Point","coordinates":[7.1,46.1]}}
+		]}`))
+		require.NoError(t, err)
+		require.Len(t, features, 2)
+		assert.Equal(t, "Hut", features[0].Properties["name"])
+		assert.NotNil(t, features[1].Properties, "properties can be tagged")
+	})
+
+	t.Run("single feature", func(t *testing.T) {
+		features, err := geoJSONFeatures([]byte(`{"type":"Feature","geometry":{"type":"Point","coordinates":[7,46]},"properties":{"name":"Hut"}}`))
+		require.NoError(t, err)
+		require.Len(t, features, 1)
+		assert.Equal(t, "Hut", features[0].Properties["name"])
+	})
+
+	t.Run("bare geometry", func(t *testing.T) {
+		geometry := `{"type":"LineString","coordinates":[[7,46],[7.1,46.1]]}`
+		features, err := geoJSONFeatures([]byte(geometry))
+		require.NoError(t, err)
+		require.Len(t, features, 1)
+		assert.JSONEq(t, geometry, string(features[0].Geometry.(json.RawMessage)))
+	})
+
+	t.Run("not json", func(t *testing.T) {
+		_, err := geoJSONFeatures([]byte("<kml/>"))
+		assert.ErrorIs(t, err, ErrInvalidLayer)
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/Oferzz/newMap/apps/api/pkg/pagination"
+	"github.com/Oferzz/newMap/apps/api/pkg/richtext"
 	"github.com/lib/pq"
 )
 
@@ -12,6 +14,8 @@ type Trip struct {
 	ID              string         `db:"id" json:"id"`
 	Title           string         `db:"title" json:"title"`
 	Description     string         `db:"description" json:"description"`
+	Content         TripContent    `db:"content" json:"content,omitempty"` // Structured description; Description is its plain text
+	Summary         string         `db:"summary" json:"summary"`           // Generated from its stats, waypoints and seasons
 	OwnerID         string         `db:"owner_id" json:"owner_id"`
 	CoverImage      string         `db:"cover_image" json:"cover_image"`
 	Privacy         string         `db:"privacy" json:"privacy"`
@@ -20,6 +24,8 @@ type Trip struct {
 	EndDate         *time.Time     `db:"end_date" json:"end_date"`
 	Timezone        string         `db:"timezone" json:"timezone"`
 	Tags            pq.StringArray `db:"tags" json:"tags"`
+	SuggestedTags   pq.StringArray `db:"suggested_tags" json:"suggested_tags"` // From its route, for the owner to accept
+	DismissedTags   pq.StringArray `db:"dismissed_tags" json:"-"`              // Not to be suggested again
 	ViewCount       int            `db:"view_count" json:"view_count"`
 	ShareCount      int            `db:"share_count" json:"share_count"`
 	SuggestionCount int            `db:"suggestion_count" json:"suggestion_count"`
@@ -30,6 +36,7 @@ type Trip struct {
 	// Activity-specific fields
 	ActivityType       string         `db:"activity_type" json:"activity_type"`
 	DifficultyLevel    string         `db:"difficulty_level" json:"difficulty_level"`
+	DifficultyEstimated bool          `db:"difficulty_estimated" json:"difficulty_estimated"`
 	DurationHours      *float64       `db:"duration_hours" json:"duration_hours"`
 	DistanceKm         *float64       `db:"distance_km" json:"distance_km"`
 	ElevationGainM     *int           `db:"elevation_gain_m" json:"elevation_gain_m"`
@@ -42,6 +49,8 @@ type Trip struct {
 	BestSeasons        pq.StringArray `db:"best_seasons" json:"best_seasons"`
 	TrailConditions    string         `db:"trail_conditions" json:"trail_conditions"`
 	AccessibilityNotes string         `db:"accessibility_notes" json:"accessibility_notes"`
+	Accessibility      pq.StringArray `db:"accessibility" json:"accessibility"`
+	AccessFees         AccessFees     `db:"access_fees" json:"access_fees"`
 	ParkingInfo        *JSONB         `db:"parking_info" json:"parking_info"`
 	PermitsRequired    pq.StringArray `db:"permits_required" json:"permits_required"`
 	Hazards            pq.StringArray `db:"hazards" json:"hazards"`
@@ -54,9 +63,16 @@ type Trip struct {
 	Featured           bool           `db:"featured" json:"featured"`
 	Verified           bool           `db:"verified" json:"verified"`
 
+	// Scheduled publication
+	PublishAt    *time.Time `db:"publish_at" json:"publish_at,omitempty"`
+	PublishJobID *string    `db:"publish_job_id" json:"-"`
+
 	// Joined fields
 	Collaborators []Collaborator `json:"collaborators,omitempty"`
 	Waypoints     []Waypoint     `json:"waypoints,omitempty"`
+
+	// Set on detail responses for the requesting user
+	EstimatedDurationForYou *DurationEstimate `db:"-" json:"estimated_duration_for_you,omitempty"`
 }
 
 type Collaborator struct {
@@ -72,9 +88,46 @@ type Collaborator struct {
 	JoinedAt               *time.Time `db:"joined_at" json:"joined_at"`
 
 	// Joined fields
-	Username    string `json:"username,omitempty"`
-	DisplayName string `json:"display_name,omitempty"`
-	AvatarURL   string `json:"avatar_url,omitempty"`
+	Username    string `db:"username" json:"username,omitempty"`
+	DisplayName string `db:"display_name" json:"display_name,omitempty"`
+	AvatarURL   string `db:"avatar_url" json:"avatar_url,omitempty"`
+}
+
+// OwnershipTransfer is a request to hand trip ownership to a collaborator
+type OwnershipTransfer struct {
+	ID          string     `db:"id" json:"id"`
+	EntityType  string     `db:"entity_type" json:"entity_type"`
+	EntityID    string     `db:"entity_id" json:"entity_id"`
+	FromUserID  string     `db:"from_user_id" json:"from_user_id"`
+	ToUserID    string     `db:"to_user_id" json:"to_user_id"`
+	Status      string     `db:"status" json:"status"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	RespondedAt *time.Time `db:"responded_at" json:"responded_at,omitempty"`
+}
+
+// TripDraft is a user's autosaved, unapplied edit of a trip
+type TripDraft struct {
+	TripID    string    `db:"trip_id" json:"trip_id"`
+	UserID    string    `db:"user_id" json:"user_id"`
+	Data      JSONB     `db:"data" json:"data"`
+	Base      JSONB     `db:"base" json:"-"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// DraftConflict is a draft field that was also changed on the trip since the
+// draft was started
+type DraftConflict struct {
+	Field   string      `json:"field"`
+	Draft   interface{} `json:"draft"`
+	Current interface{} `json:"current"`
+}
+
+// DraftApplyResult reports how a draft was merged into its trip
+type DraftApplyResult struct {
+	Trip      *Trip           `json:"trip"`
+	Applied   []string        `json:"applied"`
+	Conflicts []DraftConflict `json:"conflicts,omitempty"`
 }
 
 type Waypoint struct {
@@ -85,22 +138,38 @@ type Waypoint struct {
 	ArrivalTime   *time.Time `db:"arrival_time" json:"arrival_time"`
 	DepartureTime *time.Time `db:"departure_time" json:"departure_time"`
 	Notes         string     `db:"notes" json:"notes"`
+	NotesFormat   string     `db:"notes_format" json:"notes_format"`
+	Kind          string     `db:"kind" json:"kind"`
+	Window        *TimeWindow `json:"time_window,omitempty"`
 	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
 	UpdatedAt     time.Time  `db:"updated_at" json:"updated_at"`
 
+	// NotesRendering is what clients need to render markdown notes, nil for
+	// plain ones
+	NotesRendering *richtext.Document `db:"notes_doc" json:"notes_rendering,omitempty"`
+
 	// Joined place info
 	Place *Place `json:"place,omitempty"`
 }
 
+// Waypoint kinds
+const (
+	WaypointStop    = "stop"    // A stop on the route
+	WaypointBailout = "bailout" // Where the route can be left early, not a stop
+)
+
 type Place struct {
-	ID          string   `db:"id" json:"id"`
-	Name        string   `db:"name" json:"name"`
-	Description string   `db:"description" json:"description"`
-	Type        string   `db:"type" json:"type"`
-	Location    *GeoJSON `db:"location" json:"location"`
-	Address     string   `db:"street_address" json:"address"`
-	City        string   `db:"city" json:"city"`
-	Country     string   `db:"country" json:"country"`
+	ID          string         `db:"id" json:"id"`
+	Name        string         `db:"name" json:"name"`
+	Description string         `db:"description" json:"description"`
+	Type        string         `db:"type" json:"type"`
+	Location    *GeoJSON       `db:"location" json:"location"`
+	Address     string         `db:"street_address" json:"address"`
+	City        string         `db:"city" json:"city"`
+	Country     string         `db:"country" json:"country"`
+	AccessFees  AccessFees     `db:"access_fees" json:"access_fees,omitempty"`
+	Amenities   pq.StringArray `db:"amenities" json:"amenities,omitempty"`
+	Privacy     string         `db:"privacy" json:"privacy,omitempty"`
 }
 
 // GeoJSON represents a PostGIS geography point
@@ -203,6 +272,7 @@ func (j *JSONB) Scan(value interface{}) error {
 type CreateTripInput struct {
 	Title       string     `json:"title" binding:"required,min=3,max=255"`
 	Description string     `json:"description" binding:"max=1000"`
+	Content     TripContent `json:"content,omitempty"` // Replaces the description with its plain text
 	Privacy     string     `json:"privacy" binding:"omitempty,oneof=public friends private invite_only"`
 	StartDate   *time.Time `json:"start_date"`
 	EndDate     *time.Time `json:"end_date"`
@@ -225,6 +295,8 @@ type CreateTripInput struct {
 	BestSeasons        []string       `json:"best_seasons"`
 	TrailConditions    string         `json:"trail_conditions" binding:"max=500"`
 	AccessibilityNotes string         `json:"accessibility_notes" binding:"max=500"`
+	Accessibility      []string       `json:"accessibility" binding:"omitempty,dive,oneof=dog_friendly stroller_ok wheelchair_accessible toddler_ok"`
+	AccessFees         []AccessFee    `json:"access_fees" binding:"omitempty,max=20,dive"`
 	ParkingInfo        *JSONB         `json:"parking_info"`
 	PermitsRequired    []string       `json:"permits_required"`
 	Hazards            []string       `json:"hazards"`
@@ -236,6 +308,7 @@ type CreateTripInput struct {
 type UpdateTripInput struct {
 	Title       *string    `json:"title,omitempty" binding:"omitempty,min=3,max=255"`
 	Description *string    `json:"description,omitempty" binding:"omitempty,max=1000"`
+	Content     *TripContent `json:"content,omitempty"` // An empty list goes back to the plain description
 	Privacy     *string    `json:"privacy,omitempty" binding:"omitempty,oneof=public friends private invite_only"`
 	StartDate   *time.Time `json:"start_date,omitempty"`
 	EndDate     *time.Time `json:"end_date,omitempty"`
@@ -259,6 +332,8 @@ type UpdateTripInput struct {
 	BestSeasons        []string       `json:"best_seasons,omitempty"`
 	TrailConditions    *string        `json:"trail_conditions,omitempty" binding:"omitempty,max=500"`
 	AccessibilityNotes *string        `json:"accessibility_notes,omitempty" binding:"omitempty,max=500"`
+	Accessibility      []string       `json:"accessibility,omitempty" binding:"omitempty,dive,oneof=dog_friendly stroller_ok wheelchair_accessible toddler_ok"`
+	AccessFees         []AccessFee    `json:"access_fees,omitempty" binding:"omitempty,max=20,dive"`
 	ParkingInfo        *JSONB         `json:"parking_info,omitempty"`
 	PermitsRequired    []string       `json:"permits_required,omitempty"`
 	Hazards            []string       `json:"hazards,omitempty"`
@@ -284,29 +359,64 @@ type UpdateCollaboratorInput struct {
 	CanModerateSuggestions *bool   `json:"can_moderate_suggestions,omitempty"`
 }
 
+type TransferOwnershipInput struct {
+	UserID string `json:"user_id" binding:"required,uuid"`
+}
+
+type SaveDraftInput struct {
+	Data JSONB `json:"data" binding:"required"`
+}
+
+type SchedulePublicationInput struct {
+	PublishAt time.Time `json:"publish_at" binding:"required"`
+}
+
 type AddWaypointInput struct {
 	PlaceID       string     `json:"place_id" binding:"required,uuid"`
-	OrderPosition int        `json:"order_position" binding:"min=0"`
+	OrderPosition *int       `json:"order_position" binding:"omitempty,min=0"` // Appended when omitted
 	ArrivalTime   *time.Time `json:"arrival_time"`
 	DepartureTime *time.Time `json:"departure_time"`
-	Notes         string     `json:"notes" binding:"max=500"`
+	Notes         string     `json:"notes" binding:"max=10000"`
+	NotesFormat   string     `json:"notes_format" binding:"omitempty,oneof=plain markdown"` // Plain when omitted
+}
+
+// AddWaypointsInput appends places to a trip as stops, in the order given,
+// such as the places of a collection
+type AddWaypointsInput struct {
+	PlaceIDs []string `json:"place_ids" binding:"required,min=1,max=100,dive,uuid"`
+	
+	// Notes are the notes of the stops, matched to the places by position
+	Notes       []string `json:"notes" binding:"omitempty,max=100,dive,max=10000"`
+	NotesFormat string   `json:"notes_format" binding:"omitempty,oneof=plain markdown"`
+	
+	// AutoRoute draws the trip's route through its stops once they are added
+	AutoRoute bool `json:"auto_route"`
+}
+
+type ReorderWaypointsInput struct {
+	WaypointIDs []string `json:"waypoint_ids" binding:"required,min=1,dive,uuid"`
 }
 
 type UpdateWaypointInput struct {
 	OrderPosition *int       `json:"order_position,omitempty" binding:"omitempty,min=0"`
 	ArrivalTime   *time.Time `json:"arrival_time,omitempty"`
 	DepartureTime *time.Time `json:"departure_time,omitempty"`
-	Notes         *string    `json:"notes,omitempty" binding:"omitempty,max=500"`
+	Notes         *string    `json:"notes,omitempty" binding:"omitempty,max=10000"`
+	NotesFormat   *string    `json:"notes_format,omitempty" binding:"omitempty,oneof=plain markdown"`
 }
 
 type TripFilters struct {
 	OwnerID       string    `form:"owner_id"`
 	CollaboratorID string    `form:"collaborator_id"`
+	EditorID      string    `form:"-"`
+	IDs           []string  `form:"-"`
 	Privacy       string    `form:"privacy"`
+	VisibleTo     string    `form:"-"` // Public, owned by or shared with the user
 	Status        string    `form:"status"`
 	Tags          []string  `form:"tags"`
 	StartDateFrom *time.Time `form:"start_date_from"`
 	StartDateTo   *time.Time `form:"start_date_to"`
+	UpdatedAfter  *time.Time `form:"-"`
 	Search        string    `form:"search"`
 	Limit         int       `form:"limit"`
 	Offset        int       `form:"offset"`
@@ -322,6 +432,7 @@ type TripFilters struct {
 	MaxDistance     *float64 `form:"max_distance"`
 	WaterFeatures   []string `form:"water_features"`
 	TerrainTypes    []string `form:"terrain_types"`
+	Accessibility   []string `form:"accessibility"`
 	Visibility      string   `form:"visibility"`
 	Featured        *bool    `form:"featured"`
 	Verified        *bool    `form:"verified"`
@@ -332,6 +443,24 @@ type TripFilters struct {
 	RadiusKm        *float64 `form:"radius_km"`
 	BoundsNorthEast []float64 `form:"bounds_ne"`
 	BoundsSouthWest []float64 `form:"bounds_sw"`
+
+	// Keyset pagination, replaces Offset and SortBy when set
+	After *pagination.Cursor `form:"-"`
+
+	// Related records to load, all of them when nil
+	Relations *Relations `form:"-"`
+}
+
+// Relations selects the related records loaded along with trips
+type Relations struct {
+	Collaborators bool
+	Waypoints     bool
+}
+
+// AllRelations loads every related record
+var AllRelations = Relations{
+	Collaborators: true,
+	Waypoints:     true,
 }
 
 // Helper methods
@@ -348,6 +477,48 @@ func (t *Trip) HasCollaborator(userID string) bool {
 	return false
 }
 
+// VisibleTo reports whether the user may view the trip. Collaborators must be
+// loaded for private trips to be visible to anyone but the owner.
+func (t *Trip) VisibleTo(userID string) bool {
+	return t.Privacy == "public" || t.IsOwner(userID) || t.HasCollaborator(userID)
+}
+
+// Days is the number of calendar days the trip spans, one when it has no
+// end date
+func (t *Trip) Days() int {
+	if t.StartDate == nil || t.EndDate == nil || t.EndDate.Before(*t.StartDate) {
+		return 1
+	}
+	start := time.Date(t.StartDate.Year(), t.StartDate.Month(), t.StartDate.Day(), 0, 0, 0, 0, time.UTC)
+	end := time.Date(t.EndDate.Year(), t.EndDate.Month(), t.EndDate.Day(), 0, 0, 0, 0, time.UTC)
+	return int(end.Sub(start).Hours()/24) + 1
+}
+
+// Pace estimates the daily distance and moving time of the trip, and its
+// speed. It is nil when the trip has neither a distance nor a duration.
+func (t *Trip) Pace() *PaceEstimate {
+	if t.DistanceKm == nil && t.DurationHours == nil {
+		return nil
+	}
+
+	days := t.Days()
+	pace := &PaceEstimate{Days: days}
+	if t.DistanceKm != nil {
+		perDay := *t.DistanceKm / float64(days)
+		pace.DistanceKmPerDay = &perDay
+	}
+	if t.DurationHours != nil {
+		perDay := *t.DurationHours / float64(days)
+		pace.HoursPerDay = &perDay
+	}
+	if t.DistanceKm != nil && t.DurationHours != nil && *t.DurationHours > 0 {
+		speed := *t.DistanceKm / *t.DurationHours
+		pace.SpeedKmh = &speed
+	}
+
+	return pace
+}
+
 func (t *Trip) GetCollaborator(userID string) *Collaborator {
 	for _, c := range t.Collaborators {
 		if c.UserID == userID {
@@ -409,6 +580,17 @@ func (t *Trip) CanUserModerateSuggestions(userID string) bool {
 	return collaborator.CanModerateSuggestions || collaborator.Role == "admin"
 }
 
+// CanUserVerifyConditions reports whether the user may vouch for condition
+// reports on the trip: the owner and admins
+func (t *Trip) CanUserVerifyConditions(userID string) bool {
+	if t.IsOwner(userID) {
+		return true
+	}
+	
+	collaborator := t.GetCollaborator(userID)
+	return collaborator != nil && collaborator.Role == "admin"
+}
+
 func (t *Trip) CanUserPerform(userID string, permission string) bool {
 	// Convert permission string to check specific capabilities
 	switch permission {
@@ -423,6 +605,8 @@ func (t *Trip) CanUserPerform(userID string, permission string) bool {
 		return t.CanUserInvite(userID)
 	case "suggestion.moderate":
 		return t.CanUserModerateSuggestions(userID)
+	case "condition.verify":
+		return t.CanUserVerifyConditions(userID)
 	default:
 		// For any other permission, check if user is owner
 		return t.IsOwner(userID)
@@ -473,6 +657,11 @@ type ActivityCondition struct {
 	ValidUntil     *time.Time     `db:"valid_until" json:"valid_until"`
 	Verified       bool           `db:"verified" json:"verified"`
 	VerifiedBy     *string        `db:"verified_by" json:"verified_by"`
+	ReviewStatus   *string        `db:"review_status" json:"review_status,omitempty"` // Of a trusted verifier: confirmed or disputed
+	ReviewNote     *string        `db:"review_note" json:"review_note,omitempty"`
+	ReviewedBy     *string        `db:"reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedAt     *time.Time     `db:"reviewed_at" json:"reviewed_at,omitempty"`
+	Weight         int            `db:"weight" json:"weight"` // How far the report is vouched for, see ConditionWeight
 	CreatedAt      time.Time      `db:"created_at" json:"created_at"`
 }
 
@@ -491,7 +680,6 @@ type ActivityShareLink struct {
 
 // Input types for activity features
 type CreateActivityCompletionInput struct {
-	TripID             string   `json:"trip_id" binding:"required,uuid"`
 	CompletedAt        time.Time `json:"completed_at" binding:"required"`
 	DurationMinutes    *int     `json:"duration_minutes" binding:"omitempty,min=1,max=10000"`
 	DifficultyRating   *int     `json:"difficulty_rating" binding:"omitempty,min=1,max=5"`
@@ -499,12 +687,15 @@ type CreateActivityCompletionInput struct {
 	WeatherConditions  string   `json:"weather_conditions" binding:"max=500"`
 	TrailConditions    string   `json:"trail_conditions" binding:"max=500"`
 	Notes              string   `json:"notes" binding:"max=1000"`
-	Photos             []string `json:"photos"`
+	Photos             []string `json:"photos" binding:"max=20,dive,uuid"`
 	GPXTrack           *JSONB   `json:"gpx_track"`
+
+	// GPX is the recorded track as the text of a GPX file. It is stored as
+	// a GeoJSON track in place of gpx_track.
+	GPX string `json:"gpx" binding:"max=20971520"`
 }
 
 type CreateActivityRatingInput struct {
-	TripID               string `json:"trip_id" binding:"required,uuid"`
 	OverallRating        int    `json:"overall_rating" binding:"required,min=1,max=5"`
 	DifficultyAccuracy   *int   `json:"difficulty_accuracy" binding:"omitempty,min=1,max=5"`
 	DescriptionAccuracy  *int   `json:"description_accuracy" binding:"omitempty,min=1,max=5"`
@@ -513,17 +704,15 @@ type CreateActivityRatingInput struct {
 }
 
 type CreateActivityConditionInput struct {
-	TripID         string     `json:"trip_id" binding:"required,uuid"`
 	ConditionType  string     `json:"condition_type" binding:"required,oneof=trail weather closure hazard"`
 	Severity       string     `json:"severity" binding:"omitempty,oneof=info warning danger"`
 	Description    string     `json:"description" binding:"required,min=10,max=1000"`
 	Location       *GeoJSON   `json:"location"`
-	Photos         []string   `json:"photos"`
+	Photos         []string   `json:"photos" binding:"max=20,dive,uuid"`
 	ValidUntil     *time.Time `json:"valid_until"`
 }
 
 type CreateShareLinkInput struct {
-	TripID      string     `json:"trip_id" binding:"required,uuid"`
 	Permissions string     `json:"permissions" binding:"omitempty,oneof=view edit"`
 	MaxUses     *int       `json:"max_uses" binding:"omitempty,min=1,max=1000"`
 	ExpiresAt   *time.Time `json:"expires_at"`
@@ -0,0 +1,88 @@
+package trips
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrip_Days(t *testing.T) {
+	date := func(day, hour int) *time.Time {
+		d := time.Date(2026, 6, day, hour, 0, 0, 0, time.UTC)
+		return &d
+	}
+
+	tests := []struct {
+		name  string
+		start *time.Time
+		end   *time.Time
+		want  int
+	}{
+		{"no dates", nil, nil, 1},
+		{"no end date", date(1, 8), nil, 1},
+		{"no start date", nil, date(3, 8), 1},
+		{"single day", date(1, 8), date(1, 18), 1},
+		{"overnight", date(1, 18), date(2, 8), 2},
+		{"several days", date(1, 8), date(5, 8), 5},
+		{"end before start", date(5, 8), date(1, 8), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trip := &Trip{StartDate: tt.start, EndDate: tt.end}
+			assert.Equal(t, tt.want, trip.Days())
+		})
+	}
+}
+
+func TestTrip_Pace(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 3)
+
+	t.Run("nothing to go on", func(t *testing.T) {
+		assert.Nil(t, (&Trip{StartDate: &start, EndDate: &end}).Pace())
+	})
+
+	t.Run("single day", func(t *testing.T) {
+		pace := (&Trip{DistanceKm: float64Ptr(12), DurationHours: float64Ptr(4)}).Pace()
+		require.NotNil(t, pace)
+		assert.Equal(t, 1, pace.Days)
+		assert.Equal(t, 12.0, *pace.DistanceKmPerDay)
+		assert.Equal(t, 4.0, *pace.HoursPerDay)
+		assert.Equal(t, 3.0, *pace.SpeedKmh)
+	})
+
+	t.Run("split over the days", func(t *testing.T) {
+		pace := (&Trip{StartDate: &start, EndDate: &end, DistanceKm: float64Ptr(60), DurationHours: float64Ptr(20)}).Pace()
+		require.NotNil(t, pace)
+		assert.Equal(t, 4, pace.Days)
+		assert.Equal(t, 15.0, *pace.DistanceKmPerDay)
+		assert.Equal(t, 5.0, *pace.HoursPerDay)
+		assert.Equal(t, 3.0, *pace.SpeedKmh)
+	})
+
+	t.Run("distance only", func(t *testing.T) {
+		pace := (&Trip{DistanceKm: float64Ptr(8)}).Pace()
+		require.NotNil(t, pace)
+		assert.Equal(t, 8.0, *pace.DistanceKmPerDay)
+		assert.Nil(t, pace.HoursPerDay)
+		assert.Nil(t, pace.SpeedKmh)
+	})
+
+	t.Run("duration only", func(t *testing.T) {
+		pace := (&Trip{DurationHours: float64Ptr(3)}).Pace()
+		require.NotNil(t, pace)
+		assert.Nil(t, pace.DistanceKmPerDay)
+		assert.Equal(t, 3.0, *pace.HoursPerDay)
+		assert.Nil(t, pace.SpeedKmh)
+	})
+
+	t.Run("zero duration", func(t *testing.T) {
+		pace := (&Trip{DistanceKm: float64Ptr(8), DurationHours: float64Ptr(0)}).Pace()
+		require.NotNil(t, pace)
+		assert.Equal(t, 0.0, *pace.HoursPerDay)
+		assert.Nil(t, pace.SpeedKmh, "no speed without a duration")
+	})
+}
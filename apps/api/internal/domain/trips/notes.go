@@ -0,0 +1,30 @@
+package trips
+
+import "github.com/Oferzz/newMap/apps/api/pkg/richtext"
+
+// setNotes sets the notes of a waypoint in a format, plain when none is
+// given. Markdown notes are sanitized, and parsed for the checklists and
+// mentions clients render.
+func (w *Waypoint) setNotes(notes, format string) {
+	if format == "" {
+		format = richtext.FormatPlain
+	}
+
+	w.NotesFormat = format
+	w.NotesRendering = nil
+	if format == richtext.FormatMarkdown {
+		notes = richtext.Sanitize(notes)
+		if notes != "" {
+			w.NotesRendering = richtext.Parse(notes)
+		}
+	}
+	w.Notes = notes
+}
+
+// PlainNotes is the notes of the waypoint as plain text, for exports
+func (w *Waypoint) PlainNotes() string {
+	if w.NotesRendering != nil {
+		return w.NotesRendering.Text
+	}
+	return w.Notes
+}
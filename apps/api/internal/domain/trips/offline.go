@@ -0,0 +1,453 @@
+package trips
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+	"github.com/Oferzz/newMap/apps/api/internal/media"
+)
+
+// JobBuildOfflinePack builds the offline map pack of a trip
+const JobBuildOfflinePack = "trip.offline_pack"
+
+// OfflinePackFormatVersion is written to the metadata of every pack, so that
+// clients can tell packs they do not understand
+const OfflinePackFormatVersion = 1
+
+// Offline pack statuses
+const (
+	OfflinePackPending   = "pending"
+	OfflinePackCompleted = "completed"
+	OfflinePackFailed    = "failed"
+)
+
+// offlinePackStaleAfter is how long a pack may be pending before asking for
+// it again builds it anew, in case its job was lost
+const offlinePackStaleAfter = 15 * time.Minute
+
+// OfflinePack is a zip of everything a mobile client needs to show a trip
+// without a connection. A trip has one pack for everyone who may see it, so
+// it holds the trip as the public sees it. While a new pack is built the
+// previous one, if any, can still be downloaded.
+type OfflinePack struct {
+	TripID        string     `db:"trip_id" json:"trip_id"`
+	Status        string     `db:"status" json:"status"`
+	StoragePath   *string    `db:"storage_path" json:"-"`
+	SizeBytes     *int64     `db:"size_bytes" json:"size_bytes,omitempty"`
+	Error         *string    `db:"error" json:"-"`
+	TripUpdatedAt *time.Time `db:"trip_updated_at" json:"trip_updated_at,omitempty"` // When the trip the pack holds was last edited
+	RequestedBy   *string    `db:"requested_by" json:"requested_by,omitempty"`
+	RequestedAt   time.Time  `db:"requested_at" json:"requested_at"`
+	CompletedAt   *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+	URL           string     `db:"-" json:"url,omitempty"`
+}
+
+// OfflinePackMetadata is the metadata.json of a pack. The route, variants,
+// waypoints and annotations are also in trip.geojson, ready to draw, and
+// each layer's file is in the layers folder.
+type OfflinePackMetadata struct {
+	FormatVersion int                 `json:"format_version"`
+	GeneratedAt   time.Time           `json:"generated_at"`
+	Trip          *Trip               `json:"trip"` // As the public sees it, with its waypoints and their places
+	Variants      []*TripRouteVariant `json:"variants"`
+	Annotations   []*TripAnnotation   `json:"annotations"`
+	Layers        []OfflinePackLayer  `json:"layers"`
+	Fees          *FeeSummary         `json:"fees"`
+	Media         []*OfflinePackMedia `json:"media"`
+}
+
+// OfflinePackLayer is a custom map layer of the trip in a pack
+type OfflinePackLayer struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Format       string `json:"format"`
+	FeatureCount int    `json:"feature_count"`
+	File         string `json:"file,omitempty"` // Empty when the file could not be read
+}
+
+// OfflinePackMedia is an image the trip shows. Only its URL is packed, which
+// is signed for a limited time when the storage signs URLs, so clients
+// download the images as soon as they have the pack.
+type OfflinePackMedia struct {
+	ID          string  `db:"id" json:"id,omitempty"` // Empty for the cover image
+	StoragePath string  `db:"storage_path" json:"-"`
+	CDNURL      *string `db:"cdn_url" json:"-"`
+	MimeType    string  `db:"mime_type" json:"mime_type,omitempty"`
+	URL         string  `db:"-" json:"url"`
+	Caption     string  `db:"-" json:"caption,omitempty"`
+	Cover       bool    `db:"-" json:"cover,omitempty"`
+}
+
+type offlinePackPayload struct {
+	TripID string `json:"trip_id"`
+}
+
+// OfflinePackService builds offline map packs of trips in the background and
+// keeps them in media storage
+type OfflinePackService struct {
+	repo      Repository
+	storage   media.Storage
+	queue     jobs.Queue
+	urlExpiry time.Duration
+}
+
+// NewOfflinePackService creates an offline pack service and registers its
+// job handler. URLs are signed for urlExpiry when the storage signs URLs.
+func NewOfflinePackService(repo Repository, storage media.Storage, queue jobs.Queue, urlExpiry time.Duration) *OfflinePackService {
+	s := &OfflinePackService{
+		repo:      repo,
+		storage:   storage,
+		queue:     queue,
+		urlExpiry: urlExpiry,
+	}
+
+	queue.Register(JobBuildOfflinePack, s.build)
+
+	return s
+}
+
+// RequestPack queues a new pack of a trip the user can view. A pack that is
+// already being built is returned as it is.
+func (s *OfflinePackService) RequestPack(ctx context.Context, userID, tripID string) (*OfflinePack, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if !trip.VisibleTo(userID) {
+		return nil, ErrUnauthorized
+	}
+
+	pack, queued, err := s.repo.RequestOfflinePack(ctx, tripID, userID, time.Now().Add(-offlinePackStaleAfter))
+	if err != nil {
+		return nil, err
+	}
+
+	if queued {
+		if _, err := s.queue.Enqueue(ctx, JobBuildOfflinePack, offlinePackPayload{TripID: tripID}); err != nil {
+			s.fail(ctx, pack, err)
+			return nil, fmt.Errorf("failed to queue offline pack: %w", err)
+		}
+	}
+
+	if err := s.setURL(pack); err != nil {
+		return nil, err
+	}
+	return pack, nil
+}
+
+// GetPack returns the pack of a trip the user can view, with the URL it can
+// be downloaded from once built
+func (s *OfflinePackService) GetPack(ctx context.Context, userID, tripID string) (*OfflinePack, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if !trip.VisibleTo(userID) {
+		return nil, ErrUnauthorized
+	}
+
+	pack, err := s.repo.GetOfflinePack(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.setURL(pack); err != nil {
+		return nil, err
+	}
+	return pack, nil
+}
+
+// build is the job handler for JobBuildOfflinePack. A pack that keeps
+// failing is marked as failed when its last attempt does.
+func (s *OfflinePackService) build(ctx context.Context, job *jobs.Job) error {
+	var payload offlinePackPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	pack, err := s.repo.GetOfflinePack(ctx, payload.TripID)
+	if errors.Is(err, ErrOfflinePackNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if pack.Status != OfflinePackPending {
+		return nil
+	}
+
+	data, trip, err := s.assemble(ctx, payload.TripID)
+	if errors.Is(err, ErrTripNotFound) {
+		// Deleted since the pack was asked for
+		s.fail(ctx, pack, err)
+		return nil
+	}
+	if err != nil {
+		if job.Attempts >= job.MaxAttempts {
+			s.fail(ctx, pack, err)
+		}
+		return err
+	}
+
+	path := filepath.Join("offline", payload.TripID, fmt.Sprintf("%d.zip", time.Now().UnixNano()))
+	if _, err := s.storage.Save(path, data); err != nil {
+		return fmt.Errorf("failed to store offline pack: %w", err)
+	}
+
+	previous := pack.StoragePath
+	size := int64(len(data))
+	pack.StoragePath = &path
+	pack.SizeBytes = &size
+	pack.TripUpdatedAt = &trip.UpdatedAt
+
+	completed, err := s.repo.CompleteOfflinePack(ctx, pack)
+	if err != nil || !completed {
+		// Not saved, or asked for again meanwhile so that a newer pack
+		// replaces this one
+		_ = s.storage.Delete(path)
+		return err
+	}
+
+	if previous != nil {
+		_ = s.storage.Delete(*previous)
+	}
+	return nil
+}
+
+// fail marks a pack as failed. It has already failed, so a failure to record
+// that is only logged.
+func (s *OfflinePackService) fail(ctx context.Context, pack *OfflinePack, cause error) {
+	if err := s.repo.FailOfflinePack(ctx, pack.TripID, pack.RequestedAt, cause.Error()); err != nil {
+		log.Printf("Failed to mark offline pack of trip %s as failed: %v", pack.TripID, err)
+	}
+}
+
+// assemble gathers the trip and everything drawn on its map, and writes them
+// out as a pack
+func (s *OfflinePackService) assemble(ctx context.Context, tripID string) ([]byte, *Trip, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Waypoints: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	trip.localizeTimes()
+	trip.fillContent()
+	view := trip.ForAudience(AudiencePublic)
+
+	variants, err := s.repo.ListRouteVariants(ctx, tripID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	annotations, err := s.repo.ListAnnotations(ctx, tripID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, annotation := range annotations {
+		annotation.measure()
+	}
+
+	layers, err := s.repo.ListLayers(ctx, tripID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	images, err := s.media(ctx, view)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metadata := &OfflinePackMetadata{
+		FormatVersion: OfflinePackFormatVersion,
+		GeneratedAt:   time.Now().UTC(),
+		Trip:          view,
+		Variants:      variants,
+		Annotations:   annotations,
+		Layers:        []OfflinePackLayer{},
+		Fees:          summarizeFees(view),
+		Media:         images,
+	}
+
+	files := map[string][]byte{}
+	for _, layer := range layers {
+		packed := OfflinePackLayer{
+			ID:           layer.ID,
+			Name:         layer.Name,
+			Format:       layer.Format,
+			FeatureCount: layer.FeatureCount,
+		}
+		data, err := readStoredFile(s.storage, layer.StoragePath)
+		if err != nil {
+			log.Printf("Failed to read layer %s for offline pack of trip %s: %v", layer.ID, tripID, err)
+		} else {
+			packed.File = "layers/" + layer.ID + "." + layer.Format
+			files[packed.File] = data
+		}
+		metadata.Layers = append(metadata.Layers, packed)
+	}
+
+	data, err := writeOfflinePack(metadata, files)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, trip, nil
+}
+
+// media lists the trip's cover image and the images of its content, with
+// the URLs they can be fetched from
+func (s *OfflinePackService) media(ctx context.Context, trip *Trip) ([]*OfflinePackMedia, error) {
+	images := []*OfflinePackMedia{}
+	if trip.CoverImage != "" {
+		images = append(images, &OfflinePackMedia{URL: trip.CoverImage, Cover: true})
+	}
+
+	ids := trip.Content.MediaIDs()
+	if len(ids) == 0 {
+		return images, nil
+	}
+
+	files, err := s.repo.ListMediaFiles(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]*OfflinePackMedia, len(files))
+	for _, file := range files {
+		stored[file.ID] = file
+	}
+
+	// In the order the content shows them, leaving out media since deleted
+	for _, id := range ids {
+		file, ok := stored[id]
+		if !ok {
+			continue
+		}
+		if file.CDNURL != nil && *file.CDNURL != "" {
+			file.URL = *file.CDNURL
+		} else {
+			url, err := storageURL(s.storage, file.StoragePath, s.urlExpiry)
+			if err != nil {
+				return nil, err
+			}
+			file.URL = url
+		}
+		file.Caption = imageCaption(trip.Content, id)
+		images = append(images, file)
+	}
+
+	return images, nil
+}
+
+// imageCaption is the first caption the content gives the image
+func imageCaption(content TripContent, mediaID string) string {
+	for _, block := range content {
+		if block.Type == BlockImage && block.MediaID == mediaID && block.Caption != "" {
+			return block.Caption
+		}
+	}
+	return ""
+}
+
+// setURL sets the URL the pack's latest file can be downloaded from
+func (s *OfflinePackService) setURL(pack *OfflinePack) error {
+	if pack.StoragePath == nil {
+		return nil
+	}
+
+	url, err := storageURL(s.storage, *pack.StoragePath, s.urlExpiry)
+	if err != nil {
+		return err
+	}
+	pack.URL = url
+
+	return nil
+}
+
+// storageURL is the URL a stored file can be fetched from, signed for expiry
+// when the storage signs URLs
+func storageURL(storage media.Storage, path string, expiry time.Duration) (string, error) {
+	url := storage.GetURL(path)
+
+	signer, ok := storage.(media.URLSigner)
+	if !ok {
+		return url, nil
+	}
+
+	signed, err := signer.SignURL(url, expiry)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign URL: %w", err)
+	}
+	return signed, nil
+}
+
+// readStoredFile reads a stored file, wherever the storage keeps it
+func readStoredFile(storage media.Storage, path string) ([]byte, error) {
+	file, err := storage.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+// writeOfflinePack zips up trip.geojson, metadata.json and the other files
+// of a pack
+func writeOfflinePack(metadata *OfflinePackMetadata, files map[string][]byte) ([]byte, error) {
+	alternatives := make([]*TripRouteVariant, 0, len(metadata.Variants))
+	for _, variant := range metadata.Variants {
+		if !variant.IsPrimary {
+			alternatives = append(alternatives, variant)
+		}
+	}
+
+	geoJSON, err := exportGeoJSON(metadata.Trip, alternatives, metadata.Annotations, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataJSON, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{"trip.geojson", "metadata.json"}
+	contents := map[string][]byte{"trip.geojson": geoJSON, "metadata.json": metadataJSON}
+	for _, layer := range metadata.Layers {
+		if layer.File != "" {
+			names = append(names, layer.File)
+			contents[layer.File] = files[layer.File]
+		}
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: metadata.GeneratedAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write offline pack: %w", err)
+		}
+		if _, err := w.Write(contents[name]); err != nil {
+			return nil, fmt.Errorf("failed to write offline pack: %w", err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write offline pack: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,67 @@
+package trips
+
+// Bases of a duration estimate
+const (
+	EstimateFromHistory  = "history"
+	EstimateFromNaismith = "naismith"
+)
+
+// Naismith's rule: 5 km/h on the flat plus an hour for every 600 m climbed,
+// which makes each metre of ascent worth 1/120 km of walking
+const (
+	naismithSpeedKmh  = 5.0
+	naismithClimbKmPM = naismithSpeedKmh / 600
+)
+
+// DurationEstimate is how long a trip is expected to take a particular user
+type DurationEstimate struct {
+	Hours       float64 `json:"hours"`
+	Basis       string  `json:"basis"`
+	Completions int     `json:"completions,omitempty"`
+}
+
+// UserPace sums a user's completions of one activity type that have both a
+// recorded duration and a trip distance
+type UserPace struct {
+	Completions int     `db:"completions"`
+	EffortKm    float64 `db:"effort_km"`
+	Hours       float64 `db:"hours"`
+}
+
+// effortKm is the distance of flat ground that takes as long as the trip,
+// counting climbing by Naismith's rule
+func effortKm(distanceKm float64, elevationGainM *int) float64 {
+	effort := distanceKm
+	if elevationGainM != nil && *elevationGainM > 0 {
+		effort += float64(*elevationGainM) * naismithClimbKmPM
+	}
+	return effort
+}
+
+// estimateDuration estimates the trip's moving time from the user's pace on
+// past trips of the same activity, or by Naismith's rule for hikes when
+// there is no history. It returns nil when neither applies.
+func estimateDuration(trip *Trip, pace *UserPace) *DurationEstimate {
+	if trip.DistanceKm == nil || *trip.DistanceKm <= 0 {
+		return nil
+	}
+	effort := effortKm(*trip.DistanceKm, trip.ElevationGainM)
+
+	if pace != nil && pace.Completions > 0 && pace.EffortKm > 0 && pace.Hours > 0 {
+		speed := pace.EffortKm / pace.Hours
+		return &DurationEstimate{
+			Hours:       effort / speed,
+			Basis:       EstimateFromHistory,
+			Completions: pace.Completions,
+		}
+	}
+
+	if trip.ActivityType == "hiking" {
+		return &DurationEstimate{
+			Hours: effort / naismithSpeedKmh,
+			Basis: EstimateFromNaismith,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package trips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEffortKm(t *testing.T) {
+	climb := 600
+	descent := -200
+
+	assert.Equal(t, 10.0, effortKm(10, nil))
+	assert.Equal(t, 15.0, effortKm(10, &climb), "600 m of climbing is an hour, or 5 km")
+	assert.Equal(t, 10.0, effortKm(10, &descent))
+}
+
+func TestEstimateDuration(t *testing.T) {
+	climb := 600
+
+	t.Run("from the user's history", func(t *testing.T) {
+		trip := &Trip{ActivityType: "hiking", DistanceKm: float64Ptr(10), ElevationGainM: &climb}
+		estimate := estimateDuration(trip, &UserPace{Completions: 4, EffortKm: 30, Hours: 10})
+		require.NotNil(t, estimate)
+		assert.Equal(t, EstimateFromHistory, estimate.Basis)
+		assert.Equal(t, 4, estimate.Completions)
+		assert.InDelta(t, 5.0, estimate.Hours, 1e-9)
+	})
+
+	t.Run("by Naismith's rule without history", func(t *testing.T) {
+		trip := &Trip{ActivityType: "hiking", DistanceKm: float64Ptr(10), ElevationGainM: &climb}
+		for _, pace := range []*UserPace{nil, {}, {Completions: 2, EffortKm: 20}} {
+			estimate := estimateDuration(trip, pace)
+			require.NotNil(t, estimate)
+			assert.Equal(t, EstimateFromNaismith, estimate.Basis)
+			assert.Zero(t, estimate.Completions)
+			assert.InDelta(t, 3.0, estimate.Hours, 1e-9)
+		}
+	})
+
+	t.Run("no estimate", func(t *testing.T) {
+		tests := []struct {
+			name string
+			trip *Trip
+		}{
+			{"no distance", &Trip{ActivityType: "hiking"}},
+			{"zero distance", &Trip{ActivityType: "hiking", DistanceKm: float64Ptr(0)}},
+			{"not a hike", &Trip{ActivityType: "cycling", DistanceKm: float64Ptr(40)}},
+		}
+		for _, tt := range tests {
+			assert.Nil(t, estimateDuration(tt.trip, nil), tt.name)
+		}
+	})
+
+	t.Run("history applies to any activity", func(t *testing.T) {
+		trip := &Trip{ActivityType: "cycling", DistanceKm: float64Ptr(40)}
+		estimate := estimateDuration(trip, &UserPace{Completions: 1, EffortKm: 20, Hours: 1})
+		require.NotNil(t, estimate)
+		assert.InDelta(t, 2.0, estimate.Hours, 1e-9)
+	})
+}
@@ -0,0 +1,396 @@
+package trips
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+)
+
+// Participant statuses. Invitees answer going, maybe or declined; those
+// going when there is no room are waitlisted instead.
+const (
+	ParticipantInvited    = "invited"
+	ParticipantGoing      = "going"
+	ParticipantMaybe      = "maybe"
+	ParticipantDeclined   = "declined"
+	ParticipantWaitlisted = "waitlisted"
+)
+
+// MaxGuests bounds the guests a participant brings along
+const MaxGuests = 10
+
+// TripParticipant is someone invited on a trip and their answer. Unlike
+// collaborators, participants come along but do not plan the trip.
+type TripParticipant struct {
+	TripID       string     `db:"trip_id" json:"trip_id"`
+	UserID       string     `db:"user_id" json:"user_id"`
+	Status       string     `db:"status" json:"status"`
+	Guests       int        `db:"guests" json:"guests"`
+	InvitedBy    string     `db:"invited_by" json:"invited_by,omitempty"`
+	RespondedAt  *time.Time `db:"responded_at" json:"responded_at"`
+	WaitlistedAt *time.Time `db:"waitlisted_at" json:"waitlisted_at,omitempty"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
+
+	// Joined fields
+	Username    string `db:"username" json:"username,omitempty"`
+	DisplayName string `db:"display_name" json:"display_name,omitempty"`
+	AvatarURL   string `db:"avatar_url" json:"avatar_url,omitempty"`
+
+	// Place in line, from 1, while waitlisted
+	WaitlistPosition int `db:"-" json:"waitlist_position,omitempty"`
+}
+
+// party is how many people the participant brings, themselves included
+func (p *TripParticipant) party() int {
+	return 1 + p.Guests
+}
+
+// ParticipantRoster is everyone invited on a trip, with the head count
+// against its capacity
+type ParticipantRoster struct {
+	TripID string `json:"trip_id"`
+	// The most people going, guests included; no limit when nil
+	Capacity     *int               `json:"capacity"`
+	Going        int                `json:"going"`
+	Maybe        int                `json:"maybe"`
+	Waitlisted   int                `json:"waitlisted"`
+	SpotsLeft    *int               `json:"spots_left"`
+	Participants []*TripParticipant `json:"participants"`
+
+	// Changes for the repository to save
+	capacityChanged bool
+	changed         map[string]bool
+	removed         []string
+	promoted        []*TripParticipant
+}
+
+type InviteParticipantsInput struct {
+	UserIDs []string `json:"user_ids" binding:"required,min=1,max=50,dive,uuid"`
+}
+
+type RSVPInput struct {
+	Status string `json:"status" binding:"required,oneof=going maybe declined"`
+	Guests int    `json:"guests" binding:"min=0,max=10"`
+}
+
+type SetCapacityInput struct {
+	// No limit when left out or null
+	Capacity *int `json:"capacity" binding:"omitempty,min=1"`
+}
+
+// newParticipantRoster builds the roster of a trip and tallies it
+func newParticipantRoster(tripID string, capacity *int, participants []*TripParticipant) *ParticipantRoster {
+	if participants == nil {
+		participants = []*TripParticipant{}
+	}
+	roster := &ParticipantRoster{
+		TripID:       tripID,
+		Capacity:     capacity,
+		Participants: participants,
+		changed:      map[string]bool{},
+	}
+	roster.tally()
+	return roster
+}
+
+// Participant returns the user's place on the roster, or nil
+func (r *ParticipantRoster) Participant(userID string) *TripParticipant {
+	for _, p := range r.Participants {
+		if p.UserID == userID {
+			return p
+		}
+	}
+	return nil
+}
+
+// Promoted returns the participants moved off the waitlist by the changes
+func (r *ParticipantRoster) Promoted() []*TripParticipant {
+	return r.promoted
+}
+
+// Invite adds the user to the roster, reporting false when they are on it
+// already
+func (r *ParticipantRoster) Invite(userID, invitedBy string) bool {
+	if r.Participant(userID) != nil {
+		return false
+	}
+	r.Participants = append(r.Participants, &TripParticipant{
+		TripID:    r.TripID,
+		UserID:    userID,
+		Status:    ParticipantInvited,
+		InvitedBy: invitedBy,
+	})
+	r.changed[userID] = true
+	return true
+}
+
+// Respond records the user's answer. Going joins the back of the waitlist
+// unless there is room and no one is waiting; those already going can only
+// bring more guests into spots that are free.
+func (r *ParticipantRoster) Respond(userID, status string, guests int, now time.Time) error {
+	p := r.Participant(userID)
+	if p == nil {
+		return ErrParticipantNotFound
+	}
+
+	switch {
+	case status == ParticipantGoing && p.Status == ParticipantGoing:
+		if !r.fits(r.goingHeads() - p.party() + 1 + guests) {
+			return ErrNotEnoughSpots
+		}
+	case status == ParticipantGoing:
+		p.Status = ParticipantWaitlisted
+		if p.WaitlistedAt == nil {
+			p.WaitlistedAt = &now
+		}
+	default:
+		p.Status = status
+		p.WaitlistedAt = nil
+	}
+
+	p.Guests = guests
+	p.RespondedAt = &now
+	r.changed[userID] = true
+
+	r.promote()
+
+	// Seated straight away rather than moved up the line
+	for i, promoted := range r.promoted {
+		if promoted == p {
+			r.promoted = append(r.promoted[:i], r.promoted[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Remove takes the user off the roster, freeing their spots
+func (r *ParticipantRoster) Remove(userID string) error {
+	for i, p := range r.Participants {
+		if p.UserID == userID {
+			r.Participants = append(r.Participants[:i], r.Participants[i+1:]...)
+			r.removed = append(r.removed, userID)
+			delete(r.changed, userID)
+			r.promote()
+			return nil
+		}
+	}
+	return ErrParticipantNotFound
+}
+
+// SetCapacity limits how many people can go. Lowering it below the head
+// count turns no one away; the spots just do not open again.
+func (r *ParticipantRoster) SetCapacity(capacity *int) {
+	r.Capacity = capacity
+	r.capacityChanged = true
+	r.promote()
+}
+
+// promote seats the waitlist in the order people joined it, for as long as
+// the party at the front fits
+func (r *ParticipantRoster) promote() {
+	var waiting []*TripParticipant
+	for _, p := range r.Participants {
+		if p.Status == ParticipantWaitlisted {
+			waiting = append(waiting, p)
+		}
+	}
+	sort.SliceStable(waiting, func(i, j int) bool {
+		return waiting[i].WaitlistedAt.Before(*waiting[j].WaitlistedAt)
+	})
+
+	for _, p := range waiting {
+		if !r.fits(r.goingHeads() + p.party()) {
+			break
+		}
+		p.Status = ParticipantGoing
+		p.WaitlistedAt = nil
+		r.changed[p.UserID] = true
+		r.promoted = append(r.promoted, p)
+	}
+
+	r.tally()
+}
+
+// fits reports whether the head count is within capacity
+func (r *ParticipantRoster) fits(heads int) bool {
+	return r.Capacity == nil || heads <= *r.Capacity
+}
+
+// goingHeads counts the people going, guests included
+func (r *ParticipantRoster) goingHeads() int {
+	heads := 0
+	for _, p := range r.Participants {
+		if p.Status == ParticipantGoing {
+			heads += p.party()
+		}
+	}
+	return heads
+}
+
+// participantOrder lists those going first and those who declined last
+var participantOrder = map[string]int{
+	ParticipantGoing:      0,
+	ParticipantMaybe:      1,
+	ParticipantInvited:    2,
+	ParticipantWaitlisted: 3,
+	ParticipantDeclined:   4,
+}
+
+// tally counts heads and spots, orders the participants and numbers the
+// waitlist
+func (r *ParticipantRoster) tally() {
+	r.Going, r.Maybe, r.Waitlisted = r.goingHeads(), 0, 0
+	for _, p := range r.Participants {
+		switch p.Status {
+		case ParticipantMaybe:
+			r.Maybe += p.party()
+		case ParticipantWaitlisted:
+			r.Waitlisted += p.party()
+		}
+	}
+
+	r.SpotsLeft = nil
+	if r.Capacity != nil {
+		left := *r.Capacity - r.Going
+		if left < 0 {
+			left = 0
+		}
+		r.SpotsLeft = &left
+	}
+
+	sort.SliceStable(r.Participants, func(i, j int) bool {
+		a, b := r.Participants[i], r.Participants[j]
+		if a.Status != b.Status {
+			return participantOrder[a.Status] < participantOrder[b.Status]
+		}
+		if a.Status == ParticipantWaitlisted {
+			return a.WaitlistedAt.Before(*b.WaitlistedAt)
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+
+	position := 0
+	for _, p := range r.Participants {
+		p.WaitlistPosition = 0
+		if p.Status == ParticipantWaitlisted {
+			position++
+			p.WaitlistPosition = position
+		}
+	}
+}
+
+// GetParticipants returns the trip's roster. The owner, collaborators and
+// anyone invited can see it.
+func (s *servicePg) GetParticipants(ctx context.Context, userID, tripID string) (*ParticipantRoster, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	roster, err := s.repo.GetParticipants(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID == "" || !(trip.IsOwner(userID) || trip.HasCollaborator(userID) || roster.Participant(userID) != nil) {
+		return nil, ErrUnauthorized
+	}
+
+	return roster, nil
+}
+
+// InviteParticipants invites users on the trip. Those invited already keep
+// their answers.
+func (s *servicePg) InviteParticipants(ctx context.Context, userID, tripID string, input *InviteParticipantsInput) (*ParticipantRoster, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.canUserInviteToTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+
+	for _, inviteeID := range input.UserIDs {
+		if trip.IsOwner(inviteeID) {
+			return nil, ErrOwnerParticipant
+		}
+	}
+
+	return s.updateParticipants(ctx, userID, tripID, func(roster *ParticipantRoster) error {
+		for _, inviteeID := range input.UserIDs {
+			roster.Invite(inviteeID, userID)
+		}
+		return nil
+	})
+}
+
+// RespondToInvite records whether the user is coming and how many guests
+// they bring
+func (s *servicePg) RespondToInvite(ctx context.Context, userID, tripID string, input *RSVPInput) (*ParticipantRoster, error) {
+	now := time.Now()
+	return s.updateParticipants(ctx, userID, tripID, func(roster *ParticipantRoster) error {
+		return roster.Respond(userID, input.Status, input.Guests, now)
+	})
+}
+
+// RemoveParticipant takes someone off the roster. Participants can remove
+// themselves; otherwise it takes the right to invite.
+func (s *servicePg) RemoveParticipant(ctx context.Context, userID, tripID, participantID string) error {
+	if userID != participantID {
+		trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+		if err != nil {
+			return err
+		}
+		if !s.canUserInviteToTrip(trip, userID) {
+			return ErrUnauthorized
+		}
+	}
+
+	_, err := s.updateParticipants(ctx, userID, tripID, func(roster *ParticipantRoster) error {
+		return roster.Remove(participantID)
+	})
+	return err
+}
+
+// SetParticipantCapacity limits how many people can go on the trip
+func (s *servicePg) SetParticipantCapacity(ctx context.Context, userID, tripID string, input *SetCapacityInput) (*ParticipantRoster, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+
+	return s.updateParticipants(ctx, userID, tripID, func(roster *ParticipantRoster) error {
+		roster.SetCapacity(input.Capacity)
+		return nil
+	})
+}
+
+// updateParticipants applies a change to the roster, announces it along
+// with whoever moved off the waitlist, and returns the roster as saved
+func (s *servicePg) updateParticipants(ctx context.Context, userID, tripID string, update func(*ParticipantRoster) error) (*ParticipantRoster, error) {
+	roster, err := s.repo.UpdateParticipants(ctx, tripID, update)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{"fields": []string{"participants"}}
+	if promoted := roster.Promoted(); len(promoted) > 0 {
+		ids := make([]string, len(promoted))
+		for i, p := range promoted {
+			ids[i] = p.UserID
+		}
+		data["promoted"] = ids
+	}
+	s.announce(ctx, events.TripUpdated, tripID, userID, data)
+
+	return s.repo.GetParticipants(ctx, tripID)
+}
@@ -0,0 +1,55 @@
+package trips
+
+import (
+	"context"
+	"math"
+
+	"github.com/Oferzz/newMap/apps/api/internal/directions"
+)
+
+// PlanRouteInput asks for a route through waypoints, in order
+type PlanRouteInput struct {
+	Waypoints []RoutePoint `json:"waypoints" binding:"required,min=2,max=25,dive"`
+	Profile   string       `json:"profile" binding:"required,oneof=walking cycling driving"`
+}
+
+// RoutePoint is a waypoint a planned route passes through
+type RoutePoint struct {
+	Latitude  float64 `json:"latitude" binding:"min=-90,max=90"`
+	Longitude float64 `json:"longitude" binding:"min=-180,max=180"`
+}
+
+// RoutePlanner plans trip routes along roads and trails, so they need not be
+// drawn by hand
+type RoutePlanner struct {
+	router directions.Router
+}
+
+// NewRoutePlanner creates a route planner
+func NewRoutePlanner(router directions.Router) *RoutePlanner {
+	return &RoutePlanner{
+		router: router,
+	}
+}
+
+// Plan plans a route through the waypoints and returns the update that sets
+// it as the trip's route, with its distance and duration
+func (p *RoutePlanner) Plan(ctx context.Context, input *PlanRouteInput) (*UpdateTripInput, error) {
+	points := make([]directions.Point, len(input.Waypoints))
+	for i, waypoint := range input.Waypoints {
+		points[i] = directions.Point{Longitude: waypoint.Longitude, Latitude: waypoint.Latitude}
+	}
+
+	route, err := p.router.Route(ctx, input.Profile, points)
+	if err != nil {
+		return nil, err
+	}
+
+	distanceKm := math.Round(route.DistanceM/10) / 100
+	durationHours := math.Round(route.DurationS/36) / 100
+	return &UpdateTripInput{
+		RouteGeoJSON:  &GeoJSONRoute{Type: "LineString", Coordinates: route.Coordinates},
+		DistanceKm:    &distanceKm,
+		DurationHours: &durationHours,
+	}, nil
+}
@@ -0,0 +1,43 @@
+package trips
+
+// Privacy decides who sees a trip. Visibility is the older public/private
+// setting from activities; it is derived from privacy, by the database for
+// stored trips, and still accepted from and shown to clients that use it.
+
+// Trip privacy levels
+const (
+	PrivacyPublic     = "public"
+	PrivacyFriends    = "friends"
+	PrivacyPrivate    = "private"
+	PrivacyInviteOnly = "invite_only"
+)
+
+// Trip visibilities
+const (
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
+// VisibilityFor is the visibility a privacy level shows as: public for public
+// trips, private for every other level
+func VisibilityFor(privacy string) string {
+	if privacy == PrivacyPublic {
+		return VisibilityPublic
+	}
+	return VisibilityPrivate
+}
+
+// resolvePrivacy is the privacy asked for by a client that may send privacy,
+// visibility or both. Visibility alone stands for the privacy of the same
+// name; sent together, they must agree. It is empty when neither is sent.
+func resolvePrivacy(privacy, visibility string) (string, error) {
+	switch {
+	case visibility == "":
+		return privacy, nil
+	case privacy == "":
+		return visibility, nil
+	case VisibilityFor(privacy) != visibility:
+		return "", ErrPrivacyMismatch
+	}
+	return privacy, nil
+}
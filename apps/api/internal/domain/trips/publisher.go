@@ -0,0 +1,183 @@
+package trips
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+)
+
+// JobPublishTrip flips a trip to public at its scheduled time
+const JobPublishTrip = "trip.publish"
+
+type publishPayload struct {
+	TripID string `json:"trip_id"`
+}
+
+// ScheduledPublication is a trip waiting to go public
+type ScheduledPublication struct {
+	TripID    string    `db:"id"`
+	PublishAt time.Time `db:"publish_at"`
+	JobID     *string   `db:"publish_job_id"`
+}
+
+// Publisher schedules trips for publication and announces them once they go
+// public
+type Publisher struct {
+	repo  Repository
+	queue jobs.Queue
+	bus   events.Bus
+}
+
+// NewPublisher creates a new publisher and registers its job handler
+func NewPublisher(repo Repository, queue jobs.Queue, bus events.Bus) *Publisher {
+	p := &Publisher{
+		repo:  repo,
+		queue: queue,
+		bus:   bus,
+	}
+
+	queue.Register(JobPublishTrip, p.publish)
+
+	return p
+}
+
+// Schedule replaces any pending publication of the trip with one at publishAt
+func (p *Publisher) Schedule(ctx context.Context, trip *Trip, publishAt time.Time) error {
+	p.cancelJob(ctx, trip)
+
+	job, err := p.queue.Schedule(ctx, JobPublishTrip, publishPayload{TripID: trip.ID}, publishAt)
+	if err != nil {
+		return fmt.Errorf("failed to schedule publication: %w", err)
+	}
+
+	updates := map[string]interface{}{
+		"publish_at":     publishAt,
+		"publish_job_id": job.ID,
+	}
+	if err := p.repo.Update(ctx, trip.ID, updates); err != nil {
+		p.queue.Cancel(ctx, job.ID)
+		return err
+	}
+
+	return nil
+}
+
+// Reschedule queues the publication of every trip still waiting for one.
+// Jobs on the in-process queue do not survive a restart, so this runs when
+// the server starts; trips whose time has passed are published right away.
+func (p *Publisher) Reschedule(ctx context.Context) error {
+	publications, err := p.repo.ListScheduledPublications(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, publication := range publications {
+		trip := &Trip{ID: publication.TripID, PublishJobID: publication.JobID}
+		if err := p.Schedule(ctx, trip, publication.PublishAt); err != nil {
+			fmt.Printf("Failed to reschedule publication of trip %s: %v\n", publication.TripID, err)
+		}
+	}
+
+	return nil
+}
+
+// Cancel withdraws the trip's pending publication
+func (p *Publisher) Cancel(ctx context.Context, trip *Trip) error {
+	p.cancelJob(ctx, trip)
+
+	updates := map[string]interface{}{
+		"publish_at":     nil,
+		"publish_job_id": nil,
+	}
+	return p.repo.Update(ctx, trip.ID, updates)
+}
+
+// Announce tells subscribers about a change to a trip that has already been
+// saved, so a failure is only logged
+func (p *Publisher) Announce(ctx context.Context, eventType, tripID, actorID string, data map[string]interface{}) {
+	event := events.New(eventType, "trip", tripID, actorID, data)
+	if err := p.bus.Publish(ctx, event); err != nil {
+		fmt.Printf("Failed to publish %s for trip %s: %v\n", eventType, tripID, err)
+	}
+}
+
+// cancelJob removes the queued job. The job checks the trip's publish_job_id
+// before doing anything, so a job that cannot be removed is harmless.
+func (p *Publisher) cancelJob(ctx context.Context, trip *Trip) {
+	if trip.PublishJobID == nil {
+		return
+	}
+	if err := p.queue.Cancel(ctx, *trip.PublishJobID); err != nil && !errors.Is(err, jobs.ErrJobNotFound) {
+		fmt.Printf("Failed to cancel publication job %s: %v\n", *trip.PublishJobID, err)
+	}
+}
+
+// publish is the job handler for JobPublishTrip
+func (p *Publisher) publish(ctx context.Context, job *jobs.Job) error {
+	var payload publishPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	published, err := p.repo.PublishScheduled(ctx, payload.TripID, job.ID)
+	if err != nil {
+		return err
+	}
+	if !published {
+		// Cancelled, rescheduled or deleted since the job was queued
+		return nil
+	}
+
+	trip, err := p.repo.GetByID(ctx, payload.TripID)
+	if err != nil {
+		return err
+	}
+
+	// The cache, discovery, the search index and share cards subscribe to
+	// this event
+	event := events.New(events.TripPublished, "trip", trip.ID, trip.OwnerID, SearchDocument(trip))
+	return p.bus.Publish(ctx, event)
+}
+
+// SearchDocument is the public view of a trip carried on publish events and
+// indexed for search
+func SearchDocument(trip *Trip) map[string]interface{} {
+	return map[string]interface{}{
+		"id":               trip.ID,
+		"type":             "trip",
+		"title":            trip.Title,
+		"description":      trip.Description,
+		"summary":          trip.Summary,
+		"owner_id":         trip.OwnerID,
+		"cover_image":      trip.CoverImage,
+		"tags":             []string(trip.Tags),
+		"activity_type":    trip.ActivityType,
+		"difficulty_level": trip.DifficultyLevel,
+		"distance_km":      trip.DistanceKm,
+		"duration_hours":   trip.DurationHours,
+		"elevation_gain_m": trip.ElevationGainM,
+		"route_type":       trip.RouteType,
+		"accessibility":    []string(trip.Accessibility),
+		// Searches for amenities match trips by the place they start from
+		"trailhead_amenities": trailheadAmenities(trip),
+		"privacy":             trip.Privacy,
+		"visibility":          VisibilityFor(trip.Privacy),
+		"created_at":          trip.CreatedAt,
+		"updated_at":          trip.UpdatedAt,
+	}
+}
+
+// trailheadAmenities are the amenities of the place the trip starts from,
+// its first stop
+func trailheadAmenities(trip *Trip) []string {
+	for _, waypoint := range trip.Waypoints {
+		if waypoint.Kind == WaypointStop && waypoint.Place != nil {
+			return waypoint.Place.Amenities
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package trips
+
+import (
+	"context"
+)
+
+// RateTrip records the user's rating of a trip they can see. Owners cannot
+// rate their own trips, and everyone else rates each trip once.
+func (s *servicePg) RateTrip(ctx context.Context, userID, tripID string, input *CreateActivityRatingInput) (*ActivityRating, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+
+	if trip.IsOwner(userID) {
+		return nil, ErrOwnTripRating
+	}
+
+	rating := &ActivityRating{
+		TripID:              tripID,
+		UserID:              userID,
+		OverallRating:       input.OverallRating,
+		DifficultyAccuracy:  input.DifficultyAccuracy,
+		DescriptionAccuracy: input.DescriptionAccuracy,
+		SceneryRating:       input.SceneryRating,
+		ReviewText:          input.ReviewText,
+	}
+	if err := s.repo.CreateRating(ctx, rating); err != nil {
+		return nil, err
+	}
+
+	return rating, nil
+}
+
+// ListRatings returns the ratings of a trip, newest first
+func (s *servicePg) ListRatings(ctx context.Context, userID, tripID string, limit, offset int) ([]*ActivityRating, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+
+	return s.repo.ListRatings(ctx, tripID, limit, offset)
+}
+
+// DeleteRating removes the user's own rating of a trip
+func (s *servicePg) DeleteRating(ctx context.Context, userID, tripID string) error {
+	return s.repo.DeleteRating(ctx, tripID, userID)
+}
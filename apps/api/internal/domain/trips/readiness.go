@@ -0,0 +1,156 @@
+package trips
+
+import (
+	"time"
+)
+
+// Readiness checks, in the order they are reported
+const (
+	ReadinessRoute             = "route"
+	ReadinessDates             = "dates"
+	ReadinessPermits           = "permits"
+	ReadinessGear              = "gear"
+	ReadinessEmergencyContacts = "emergency_contacts"
+	ReadinessWeather           = "weather"
+	ReadinessWater             = "water"
+	ReadinessFees              = "fees"
+)
+
+// weatherFreshness is how long a forecast check or weather report counts
+const weatherFreshness = 72 * time.Hour
+
+// IsManualReadinessCheck reports whether a check can be confirmed by hand,
+// for what the trip's own details cannot show
+func IsManualReadinessCheck(check string) bool {
+	switch check {
+	case ReadinessPermits, ReadinessWeather, ReadinessWater, ReadinessFees:
+		return true
+	}
+	return false
+}
+
+// ReadinessState is what the readiness checks need beyond the trip itself
+type ReadinessState struct {
+	Confirmed       map[string]time.Time // Manual checks, by name
+	WeatherReportAt *time.Time           // Latest weather condition report
+	Water           *WaterPlan           // Nil when water need not be planned
+}
+
+// ReadinessCheck is one rule a trip is evaluated against
+type ReadinessCheck struct {
+	Check       string     `json:"check"`
+	Passed      bool       `json:"passed"`
+	Manual      bool       `json:"manual"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+	Action      string     `json:"action,omitempty"` // What to do when not passed
+}
+
+// TripReadiness is a trip's readiness checklist
+type TripReadiness struct {
+	TripID string           `json:"trip_id"`
+	Ready  bool             `json:"ready"`
+	Checks []ReadinessCheck `json:"checks"`
+	Gaps   []ReadinessCheck `json:"gaps"`
+}
+
+// evaluateReadiness checks the trip against every readiness rule
+func evaluateReadiness(trip *Trip, state *ReadinessState, now time.Time) *TripReadiness {
+	confirmed := func(check string) *time.Time {
+		if at, ok := state.Confirmed[check]; ok {
+			return &at
+		}
+		return nil
+	}
+
+	checks := []ReadinessCheck{
+		{
+			Check:  ReadinessRoute,
+			Passed: trip.RouteGeoJSON != nil || len(trip.Waypoints) >= 2,
+			Action: "Draw a route or add at least two waypoints",
+		},
+		{
+			Check:  ReadinessDates,
+			Passed: trip.StartDate != nil && trip.EndDate != nil,
+			Action: "Set start and end dates",
+		},
+		permitsCheck(trip, confirmed(ReadinessPermits)),
+		{
+			Check:  ReadinessGear,
+			Passed: len(trip.EssentialGear) > 0,
+			Action: "List the essential gear",
+		},
+		{
+			Check:  ReadinessEmergencyContacts,
+			Passed: hasEmergencyContacts(trip.EmergencyContacts),
+			Action: "Add at least one emergency contact",
+		},
+		weatherCheck(state.WeatherReportAt, confirmed(ReadinessWeather), now),
+		waterCheck(state.Water, confirmed(ReadinessWater)),
+		feesCheck(summarizeFees(trip), confirmed(ReadinessFees)),
+	}
+
+	readiness := &TripReadiness{
+		TripID: trip.ID,
+		Ready:  true,
+		Checks: checks,
+		Gaps:   []ReadinessCheck{},
+	}
+	for i := range checks {
+		if checks[i].Passed {
+			checks[i].Action = ""
+			continue
+		}
+		readiness.Ready = false
+		readiness.Gaps = append(readiness.Gaps, checks[i])
+	}
+
+	return readiness
+}
+
+// permitsCheck passes when no permits are required or someone has confirmed
+// they are in hand
+func permitsCheck(trip *Trip, confirmedAt *time.Time) ReadinessCheck {
+	return ReadinessCheck{
+		Check:       ReadinessPermits,
+		Manual:      true,
+		ConfirmedAt: confirmedAt,
+		Passed:      len(trip.PermitsRequired) == 0 || confirmedAt != nil,
+		Action:      "Confirm the required permits have been obtained",
+	}
+}
+
+// weatherCheck passes when the forecast was checked, or weather reported for
+// the trip, recently enough to still hold
+func weatherCheck(reportAt, confirmedAt *time.Time, now time.Time) ReadinessCheck {
+	fresh := func(at *time.Time) bool {
+		return at != nil && now.Sub(*at) <= weatherFreshness
+	}
+	return ReadinessCheck{
+		Check:       ReadinessWeather,
+		Manual:      true,
+		ConfirmedAt: confirmedAt,
+		Passed:      fresh(reportAt) || fresh(confirmedAt),
+		Action:      "Check the forecast within 3 days of setting out",
+	}
+}
+
+// hasEmergencyContacts reports whether the contacts hold at least one
+// non-empty entry
+func hasEmergencyContacts(contacts *JSONB) bool {
+	if contacts == nil {
+		return false
+	}
+	for _, value := range *contacts {
+		if value == nil {
+			continue
+		}
+		if list, ok := value.([]interface{}); ok && len(list) == 0 {
+			continue
+		}
+		if text, ok := value.(string); ok && text == "" {
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package trips
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateReadiness(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	yesterday := now.Add(-24 * time.Hour)
+
+	ready := func() *Trip {
+		start, end := now.AddDate(0, 0, 3), now.AddDate(0, 0, 4)
+		return &Trip{
+			ID:                tripID,
+			RouteGeoJSON:      &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{7.1, 46.1}, {7.2, 46.2}}},
+			StartDate:         &start,
+			EndDate:           &end,
+			EssentialGear:     []string{"headlamp"},
+			EmergencyContacts: &JSONB{"phone": "+41 79 000 00 00"},
+		}
+	}
+	gaps := func(readiness *TripReadiness) []string {
+		checks := []string{}
+		for _, gap := range readiness.Gaps {
+			checks = append(checks, gap.Check)
+		}
+		return checks
+	}
+
+	t.Run("ready", func(t *testing.T) {
+		readiness := evaluateReadiness(ready(), &ReadinessState{WeatherReportAt: &yesterday}, now)
+		assert.True(t, readiness.Ready)
+		assert.Empty(t, readiness.Gaps)
+		require.Len(t, readiness.Checks, 8)
+		for _, check := range readiness.Checks {
+			assert.True(t, check.Passed, check.Check)
+			assert.Empty(t, check.Action, "passed checks need no action")
+			assert.Equal(t, IsManualReadinessCheck(check.Check), check.Manual, check.Check)
+		}
+	})
+
+	t.Run("nothing planned", func(t *testing.T) {
+		readiness := evaluateReadiness(&Trip{ID: tripID}, &ReadinessState{}, now)
+		assert.False(t, readiness.Ready)
+		assert.Equal(t, []string{ReadinessRoute, ReadinessDates, ReadinessGear, ReadinessEmergencyContacts, ReadinessWeather}, gaps(readiness))
+		for _, gap := range readiness.Gaps {
+			assert.NotEmpty(t, gap.Action, gap.Check)
+		}
+	})
+
+	t.Run("rules", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			trip  func(*Trip)
+			state ReadinessState
+			gap   string
+		}{
+			{
+				name: "waypoints stand in for a route",
+				trip: func(trip *Trip) { trip.RouteGeoJSON, trip.Waypoints = nil, []Waypoint{{}, {}} },
+			},
+			{
+				name: "a single waypoint is no route",
+				trip: func(trip *Trip) { trip.RouteGeoJSON, trip.Waypoints = nil, []Waypoint{{}} },
+				gap:  ReadinessRoute,
+			},
+			{
+				name: "no end date",
+				trip: func(trip *Trip) { trip.EndDate = nil },
+				gap:  ReadinessDates,
+			},
+			{
+				name: "permits not confirmed",
+				trip: func(trip *Trip) { trip.PermitsRequired = []string{"Wilderness permit"} },
+				gap:  ReadinessPermits,
+			},
+			{
+				name:  "permits confirmed",
+				trip:  func(trip *Trip) { trip.PermitsRequired = []string{"Wilderness permit"} },
+				state: ReadinessState{Confirmed: map[string]time.Time{ReadinessPermits: yesterday}},
+			},
+			{
+				name: "no gear",
+				trip: func(trip *Trip) { trip.EssentialGear = nil },
+				gap:  ReadinessGear,
+			},
+			{
+				name: "blank emergency contacts",
+				trip: func(trip *Trip) { trip.EmergencyContacts = &JSONB{"phone": "", "names": []interface{}{}, "email": nil} },
+				gap:  ReadinessEmergencyContacts,
+			},
+			{
+				name:  "dry stretch to plan for",
+				state: ReadinessState{Water: &WaterPlan{LongestDryKm: 14, DryStretches: []DryStretch{{FromKm: 2, ToKm: 16, LengthKm: 14}}}},
+				gap:   ReadinessWater,
+			},
+			{
+				name:  "water always at hand",
+				state: ReadinessState{Water: &WaterPlan{LongestDryKm: 3}},
+			},
+			{
+				name: "fees to pay",
+				trip: func(trip *Trip) { trip.AccessFees = AccessFees{{Kind: "parking_pass", Name: "Parking"}} },
+				gap:  ReadinessFees,
+			},
+			{
+				name:  "fees paid",
+				trip:  func(trip *Trip) { trip.AccessFees = AccessFees{{Kind: "parking_pass", Name: "Parking"}} },
+				state: ReadinessState{Confirmed: map[string]time.Time{ReadinessFees: yesterday}},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				trip := ready()
+				if tt.trip != nil {
+					tt.trip(trip)
+				}
+				state := tt.state
+				state.WeatherReportAt = &yesterday
+
+				readiness := evaluateReadiness(trip, &state, now)
+				if tt.gap == "" {
+					assert.True(t, readiness.Ready, gaps(readiness))
+					return
+				}
+				assert.False(t, readiness.Ready)
+				assert.Equal(t, []string{tt.gap}, gaps(readiness))
+			})
+		}
+	})
+}
+
+func TestWeatherCheck(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(ago time.Duration) *time.Time {
+		at := now.Add(-ago)
+		return &at
+	}
+
+	tests := []struct {
+		name      string
+		reportAt  *time.Time
+		confirmed *time.Time
+		passed    bool
+	}{
+		{"never checked", nil, nil, false},
+		{"recent report", at(time.Hour), nil, true},
+		{"report on the limit", at(weatherFreshness), nil, true},
+		{"stale report", at(weatherFreshness + time.Second), nil, false},
+		{"recently confirmed", nil, at(time.Hour), true},
+		{"stale confirmation", nil, at(weatherFreshness + time.Minute), false},
+		{"stale report but recently confirmed", at(weatherFreshness * 2), at(time.Hour), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := weatherCheck(tt.reportAt, tt.confirmed, now)
+			assert.Equal(t, tt.passed, check.Passed)
+			assert.Equal(t, tt.confirmed, check.ConfirmedAt)
+		})
+	}
+}
@@ -2,16 +2,26 @@ package trips
 
 import (
 	"context"
+	"time"
 )
 
 // Repository defines the interface for trip data operations
 type Repository interface {
+	WaypointRepository
+	
 	// Create creates a new trip
 	Create(ctx context.Context, trip *Trip) error
 	
 	// GetByID retrieves a trip by ID with collaborators and waypoints
 	GetByID(ctx context.Context, id string) (*Trip, error)
 	
+	// GetByIDWith retrieves a trip by ID with the requested related records
+	GetByIDWith(ctx context.Context, id string, relations Relations) (*Trip, error)
+	
+	// GetByIDs retrieves the trips with the given IDs and their collaborators,
+	// skipping any that are missing or deleted
+	GetByIDs(ctx context.Context, ids []string) ([]*Trip, error)
+	
 	// Update updates a trip
 	Update(ctx context.Context, id string, updates map[string]interface{}) error
 	
@@ -21,9 +31,15 @@ type Repository interface {
 	// List retrieves trips with filters
 	List(ctx context.Context, filters TripFilters) ([]*Trip, error)
 	
+	// Count returns how many trips match the filters, across all pages
+	Count(ctx context.Context, filters TripFilters) (int64, error)
+	
 	// AddCollaborator adds a collaborator to a trip
 	AddCollaborator(ctx context.Context, tripID string, collaborator Collaborator) error
 	
+	// AddCollaborators adds several collaborators to a trip in one transaction
+	AddCollaborators(ctx context.Context, tripID string, collaborators []Collaborator) error
+	
 	// UpdateCollaborator updates a collaborator's role and permissions
 	UpdateCollaborator(ctx context.Context, tripID, userID string, updates map[string]interface{}) error
 	
@@ -38,22 +54,281 @@ type Repository interface {
 	
 	// IncrementShareCount increments the share count for a trip
 	IncrementShareCount(ctx context.Context, tripID string) error
+	
+	// CreateOwnershipTransfer records a pending ownership transfer
+	CreateOwnershipTransfer(ctx context.Context, transfer *OwnershipTransfer) error
+	
+	// GetPendingOwnershipTransfer retrieves the pending ownership transfer for a trip
+	GetPendingOwnershipTransfer(ctx context.Context, tripID string) (*OwnershipTransfer, error)
+	
+	// ResolveOwnershipTransfer accepts or declines a pending ownership transfer
+	ResolveOwnershipTransfer(ctx context.Context, transfer *OwnershipTransfer, actorID string, accept bool) error
+	
+	// GetCompletion retrieves a recorded activity completion
+	GetCompletion(ctx context.Context, id string) (*ActivityCompletion, error)
+	
+	// CreateCompletion records an activity completion and counts it on the trip
+	CreateCompletion(ctx context.Context, completion *ActivityCompletion) error
+	
+	// ListCompletions retrieves a page of the trip's completions, most recent
+	// first, without their tracks
+	ListCompletions(ctx context.Context, tripID string, limit, offset int) ([]*ActivityCompletion, error)
+	
+	// CreateRating records a user's rating of a trip and recomputes the
+	// trip's average. Each user rates a trip once.
+	CreateRating(ctx context.Context, rating *ActivityRating) error
+	
+	// ListRatings retrieves a page of the trip's ratings, newest first
+	ListRatings(ctx context.Context, tripID string, limit, offset int) ([]*ActivityRating, error)
+	
+	// DeleteRating removes a user's rating of a trip and recomputes the
+	// trip's average
+	DeleteRating(ctx context.Context, tripID, userID string) error
+	
+	// CreateCondition records a condition report on a trip
+	CreateCondition(ctx context.Context, condition *ActivityCondition) error
+	
+	// ListConditions retrieves the condition reports on a trip, newest
+	// first; only those in effect now unless expired ones are included
+	ListConditions(ctx context.Context, tripID string, includeExpired bool) ([]*ActivityCondition, error)
+	
+	// GetCondition retrieves a condition report
+	GetCondition(ctx context.Context, id string) (*ActivityCondition, error)
+	
+	// VerifyCondition marks a condition report as verified by the user
+	VerifyCondition(ctx context.Context, id, userID string) error
+	
+	// ReviewCondition records a trusted verifier's confirmation or dispute
+	// of a condition report
+	ReviewCondition(ctx context.Context, id, userID, status, note string) error
+
+	// CreateConditionVerifier grants a user verifier status
+	CreateConditionVerifier(ctx context.Context, verifier *ConditionVerifier) error
+
+	// ListConditionVerifiers retrieves the verifier grants, of one user or
+	// of everyone when userID is empty
+	ListConditionVerifiers(ctx context.Context, userID string) ([]*ConditionVerifier, error)
+
+	// DeleteConditionVerifier revokes a verifier grant
+	DeleteConditionVerifier(ctx context.Context, id string) error
+
+	// IsConditionVerifier reports whether the user holds a verifier grant
+	// covering a condition report
+	IsConditionVerifier(ctx context.Context, userID, conditionID string) (bool, error)
+
+	// CreateConditionSubscription saves a subscription to condition reports,
+	// failing with ErrTooManySubscriptions when the user already has max
+	CreateConditionSubscription(ctx context.Context, subscription *ConditionSubscription, max int) error
+	
+	// ListConditionSubscriptions retrieves the user's condition
+	// subscriptions, newest first
+	ListConditionSubscriptions(ctx context.Context, userID string) ([]*ConditionSubscription, error)
+	
+	// DeleteConditionSubscription deletes one of the user's condition
+	// subscriptions
+	DeleteConditionSubscription(ctx context.Context, userID, id string) error
+	
+	// QueueConditionAlerts records an alert of a verified condition report
+	// for each subscription it matches, and returns those that had no send
+	// due with the time one is now due
+	QueueConditionAlerts(ctx context.Context, conditionID string) ([]*ConditionSubscription, error)
+	
+	// TakeConditionAlerts marks the subscription's waiting alerts sent and
+	// returns them, clearing its due send
+	TakeConditionAlerts(ctx context.Context, subscriptionID string) (*ConditionSubscription, []*ConditionAlert, error)
+	
+	// CreateDatePoll records a date poll with its options
+	CreateDatePoll(ctx context.Context, poll *DatePoll) error
+	
+	// ListDatePolls retrieves the date polls of a trip with their options
+	// and votes, newest first
+	ListDatePolls(ctx context.Context, tripID string) ([]*DatePoll, error)
+	
+	// GetDatePoll retrieves a date poll with its options and votes
+	GetDatePoll(ctx context.Context, id string) (*DatePoll, error)
+	
+	// SetAvailability records a member's answers to a poll's options,
+	// replacing any earlier ones
+	SetAvailability(ctx context.Context, userID string, votes []DatePollVote) error
+	
+	// FinalizeDatePoll closes an open poll on one of its options and sets
+	// the trip's dates to it
+	FinalizeDatePoll(ctx context.Context, pollID, optionID string) error
+	
+	// DeleteDatePoll removes a date poll
+	DeleteDatePoll(ctx context.Context, id string) error
+	
+	// GetParticipants retrieves the trip's capacity and everyone invited on it
+	GetParticipants(ctx context.Context, tripID string) (*ParticipantRoster, error)
+	
+	// UpdateParticipants applies a change to the trip's roster and saves it.
+	// The trip is locked meanwhile, so that changes cannot seat more people
+	// than the capacity between them.
+	UpdateParticipants(ctx context.Context, tripID string, update func(*ParticipantRoster) error) (*ParticipantRoster, error)
+	
+	// GetUserPace sums the user's timed completions of an activity type
+	GetUserPace(ctx context.Context, userID, activityType string) (*UserPace, error)
+	
+	// PublishScheduled makes a trip public if jobID is still its scheduled publication
+	PublishScheduled(ctx context.Context, tripID, jobID string) (bool, error)
+	
+	// ListScheduledPublications lists the trips waiting to be published
+	ListScheduledPublications(ctx context.Context) ([]*ScheduledPublication, error)
+	
+	// SetSummary saves the trip's generated summary. It is not an edit, so
+	// the trip's updated time is kept.
+	SetSummary(ctx context.Context, tripID, summary string) error
+	
+	// SetSuggestedTags saves the tags suggested for the trip and those its
+	// owner dismissed, keeping the trip's updated time
+	SetSuggestedTags(ctx context.Context, tripID string, suggested, dismissed []string) error
+	
+	// SaveDraft creates or replaces a user's draft, keeping the base values
+	// already recorded for fields the draft touched before
+	SaveDraft(ctx context.Context, draft *TripDraft) error
+	
+	// GetDraft retrieves a user's draft of a trip
+	GetDraft(ctx context.Context, tripID, userID string) (*TripDraft, error)
+	
+	// DeleteDraft removes a user's draft of a trip
+	DeleteDraft(ctx context.Context, tripID, userID string) error
+	
+	// GetReadinessState retrieves the confirmed readiness checks of a trip and
+	// its latest weather report
+	GetReadinessState(ctx context.Context, tripID string) (*ReadinessState, error)
+	
+	// ConfirmReadinessCheck records that a member confirmed a manual check
+	ConfirmReadinessCheck(ctx context.Context, tripID, check, userID string) error
+	
+	// ClearReadinessCheck withdraws the confirmation of a manual check
+	ClearReadinessCheck(ctx context.Context, tripID, check string) error
+	
+	// CreateLayer records a custom map layer of a trip
+	CreateLayer(ctx context.Context, layer *TripLayer) error
+	
+	// ListLayers retrieves the custom map layers of a trip, oldest first
+	ListLayers(ctx context.Context, tripID string) ([]*TripLayer, error)
+	
+	// GetLayer retrieves a custom map layer
+	GetLayer(ctx context.Context, id string) (*TripLayer, error)
+	
+	// DeleteLayer removes a custom map layer
+	DeleteLayer(ctx context.Context, id string) error
+	
+	// RequestOfflinePack marks the trip's offline pack as pending, unless it
+	// has been pending since after staleBefore. It reports whether the pack
+	// needs building.
+	RequestOfflinePack(ctx context.Context, tripID, userID string, staleBefore time.Time) (*OfflinePack, bool, error)
+	
+	// GetOfflinePack retrieves the offline pack of a trip
+	GetOfflinePack(ctx context.Context, tripID string) (*OfflinePack, error)
+	
+	// CompleteOfflinePack saves the file of a built pack. It reports false
+	// when the pack has been requested again since the build started.
+	CompleteOfflinePack(ctx context.Context, pack *OfflinePack) (bool, error)
+	
+	// FailOfflinePack records why the pack requested at requestedAt failed
+	FailOfflinePack(ctx context.Context, tripID string, requestedAt time.Time, reason string) error
+	
+	// ListMediaFiles retrieves where uploaded media files are stored
+	ListMediaFiles(ctx context.Context, mediaIDs []string) ([]*OfflinePackMedia, error)
+	
+	// CreateAnnotation records a map annotation of a trip
+	CreateAnnotation(ctx context.Context, annotation *TripAnnotation) error
+	
+	// ListAnnotations retrieves the map annotations of a trip, oldest first
+	ListAnnotations(ctx context.Context, tripID string) ([]*TripAnnotation, error)
+	
+	// GetAnnotation retrieves a map annotation
+	GetAnnotation(ctx context.Context, id string) (*TripAnnotation, error)
+	
+	// UpdateAnnotation saves the geometry, label and style of an annotation
+	UpdateAnnotation(ctx context.Context, annotation *TripAnnotation) error
+	
+	// DeleteAnnotation removes a map annotation
+	DeleteAnnotation(ctx context.Context, id string) error
+	
+	// SetWaypointWindow sets or, when window is nil, clears the time window
+	// of a waypoint of the trip
+	SetWaypointWindow(ctx context.Context, tripID, waypointID string, window *TimeWindow) error
+	
+	// ListVisitTimes retrieves when the trip was completed and when conditions
+	// were reported on it since the given time
+	ListVisitTimes(ctx context.Context, tripID string, since time.Time) ([]time.Time, error)
+	
+	// FindWaterSources finds public water sources within corridorM metres of
+	// the route
+	FindWaterSources(ctx context.Context, route *GeoJSONRoute, corridorM float64) ([]*WaterSourcePoint, error)
+	
+	// FindBailoutPlaces finds public places of the given categories within
+	// corridorM metres of the route
+	FindBailoutPlaces(ctx context.Context, route *GeoJSONRoute, corridorM float64, categories []string) ([]*BailoutPoint, error)
+	
+	// CountPlacesNear counts public places of the given categories within
+	// radiusM metres of a [longitude, latitude] position
+	CountPlacesNear(ctx context.Context, position []float64, radiusM float64, categories []string) (int, error)
+	
+	// RouteShareNear is the share, from 0 to 1, of the route's length that
+	// runs within corridorM metres of public places of the given categories
+	RouteShareNear(ctx context.Context, route *GeoJSONRoute, corridorM float64, categories []string) (float64, error)
+	
+	// AddBailoutWaypoint adds a bail-out waypoint after the trip's other
+	// waypoints
+	AddBailoutWaypoint(ctx context.Context, waypoint *Waypoint) error
+	
+	// RemoveBailoutWaypoint removes a bail-out waypoint of a trip
+	RemoveBailoutWaypoint(ctx context.Context, tripID, waypointID string) error
+	
+	// AddWaypoints adds stops after the trip's other waypoints, in order and
+	// in one transaction
+	AddWaypoints(ctx context.Context, tripID string, waypoints []*Waypoint) error
+	
+	// CreateRouteVariant records a route variant of a trip
+	CreateRouteVariant(ctx context.Context, variant *TripRouteVariant) error
+	
+	// ListRouteVariants retrieves the route variants of a trip, primary first
+	ListRouteVariants(ctx context.Context, tripID string) ([]*TripRouteVariant, error)
+	
+	// GetRouteVariant retrieves a route variant
+	GetRouteVariant(ctx context.Context, id string) (*TripRouteVariant, error)
+	
+	// UpdateRouteVariant saves the name, description, route and stats of a
+	// route variant
+	UpdateRouteVariant(ctx context.Context, variant *TripRouteVariant) error
+	
+	// DeleteRouteVariant removes a route variant
+	DeleteRouteVariant(ctx context.Context, id string) error
+	
+	// SetPrimaryRouteVariant makes a variant the trip's primary one and copies
+	// its route and stats onto the trip
+	SetPrimaryRouteVariant(ctx context.Context, tripID, variantID string) error
+	
+	// CreateShareLink stores a share link
+	CreateShareLink(ctx context.Context, link *ActivityShareLink) error
+	
+	// RedeemShareLink counts a use of the share link with the given token,
+	// provided it has not expired or run out of uses
+	RedeemShareLink(ctx context.Context, token string) (*ActivityShareLink, error)
 }
 
-// WaypointRepository defines the interface for waypoint operations
+// WaypointRepository defines the interface for waypoint operations. Order
+// positions are kept numbered 0, 1, 2, ... within a trip.
 type WaypointRepository interface {
-	// AddWaypoint adds a waypoint to a trip
-	AddWaypoint(ctx context.Context, tripID string, waypoint *Waypoint) error
+	// AddWaypoint inserts a stop at its order position, moving the waypoints
+	// from there on back one. A position past the end appends it.
+	AddWaypoint(ctx context.Context, waypoint *Waypoint) error
 	
-	// UpdateWaypoint updates a waypoint
-	UpdateWaypoint(ctx context.Context, waypointID string, updates map[string]interface{}) error
+	// UpdateWaypoint saves the times and notes of a waypoint and moves it to
+	// its order position
+	UpdateWaypoint(ctx context.Context, waypoint *Waypoint) error
 	
-	// RemoveWaypoint removes a waypoint from a trip
-	RemoveWaypoint(ctx context.Context, waypointID string) error
+	// RemoveWaypoint removes a waypoint of a trip, closing the gap it leaves
+	RemoveWaypoint(ctx context.Context, tripID, waypointID string) error
 	
-	// ReorderWaypoints updates the order of waypoints
+	// ReorderWaypoints puts a trip's waypoints in the order given, which must
+	// list each of them once
 	ReorderWaypoints(ctx context.Context, tripID string, waypointIDs []string) error
 	
-	// GetWaypoints retrieves all waypoints for a trip
+	// GetWaypoints retrieves the waypoints of a trip in order, with their places
 	GetWaypoints(ctx context.Context, tripID string) ([]Waypoint, error)
 }
\ No newline at end of file
@@ -3,16 +3,26 @@ package trips
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
-
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/audit"
+	"github.com/Oferzz/newMap/apps/api/internal/diagnostics"
+	"github.com/Oferzz/newMap/apps/api/pkg/pagination"
+	"github.com/Oferzz/newMap/apps/api/pkg/repoerr"
+	"github.com/Oferzz/newMap/apps/api/pkg/sqlbuilder"
+	"github.com/Oferzz/newMap/apps/api/pkg/textsearch"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 )
 
 // PostgresRepository implements the repository interface for PostgreSQL
 type PostgresRepository struct {
-	db *sqlx.DB
+	db          *sqlx.DB
+	slowQueries *diagnostics.SlowQueryLog
 }
 
 // NewPostgresRepository creates a new PostgreSQL repository
@@ -22,6 +32,11 @@ func NewPostgresRepository(db *sqlx.DB) *PostgresRepository {
 	}
 }
 
+// SetSlowQueryLog enables slow query tracking for the trip listing queries
+func (r *PostgresRepository) SetSlowQueryLog(slowQueries *diagnostics.SlowQueryLog) {
+	r.slowQueries = slowQueries
+}
+
 // Create creates a new trip
 func (r *PostgresRepository) Create(ctx context.Context, trip *Trip) error {
 	tx, err := r.db.BeginTxx(ctx, nil)
@@ -38,13 +53,14 @@ func (r *PostgresRepository) Create(ctx context.Context, trip *Trip) error {
 			activity_type, difficulty_level, duration_hours, distance_km,
 			elevation_gain_m, max_elevation_m, route_type, route_geojson,
 			water_features, terrain_types, essential_gear, best_seasons,
-			trail_conditions, accessibility_notes, parking_info,
+			trail_conditions, accessibility_notes, accessibility, access_fees, parking_info,
 			permits_required, hazards, emergency_contacts,
-			visibility, shared_with
+			shared_with, difficulty_estimated, content
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
 			$11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
-			$21, $22, $23, $24, $25, $26, $27, $28, $29, $30
+			$21, $22, $23, COALESCE($24::text[], '{}'), $25, $26, $27, $28, $29, $30,
+			$31, $32, $33
 		) RETURNING id, created_at, updated_at`
 
 	err = tx.QueryRowContext(ctx, query,
@@ -72,18 +88,27 @@ func (r *PostgresRepository) Create(ctx context.Context, trip *Trip) error {
 		pq.Array(trip.BestSeasons),
 		trip.TrailConditions,
 		trip.AccessibilityNotes,
+		pq.Array(trip.Accessibility),
+		trip.AccessFees,
 		trip.ParkingInfo,
 		pq.Array(trip.PermitsRequired),
 		pq.Array(trip.Hazards),
 		trip.EmergencyContacts,
-		trip.Visibility,
 		pq.Array(trip.SharedWith),
+		trip.DifficultyEstimated,
+		trip.Content,
 	).Scan(&trip.ID, &trip.CreatedAt, &trip.UpdatedAt)
 
 	if err != nil {
 		return fmt.Errorf("failed to create trip: %w", err)
 	}
 
+	if len(trip.Content) > 0 {
+		if err := syncContentMedia(ctx, tx, trip.ID, trip.Content); err != nil {
+			return err
+		}
+	}
+
 	// Add owner as admin collaborator
 	collaboratorQuery := `
 		INSERT INTO trip_collaborators (
@@ -103,51 +128,124 @@ func (r *PostgresRepository) Create(ctx context.Context, trip *Trip) error {
 
 // GetByID retrieves a trip by ID with collaborators and waypoints
 func (r *PostgresRepository) GetByID(ctx context.Context, id string) (*Trip, error) {
+	return r.GetByIDWith(ctx, id, AllRelations)
+}
+
+// GetByIDWith retrieves a trip by ID with the requested related records
+func (r *PostgresRepository) GetByIDWith(ctx context.Context, id string, relations Relations) (*Trip, error) {
 	var trip Trip
 	
 	// Get trip with all activity fields
 	tripQuery := `
 		SELECT 
-			id, title, description, owner_id, cover_image, privacy, status,
-			start_date, end_date, timezone, tags, view_count, share_count,
-			suggestion_count, created_at, updated_at, deleted_at,
-			activity_type, difficulty_level, duration_hours, distance_km,
+			id, title, description, content, summary, owner_id, cover_image, privacy, status,
+			start_date, end_date, timezone, tags, suggested_tags, dismissed_tags,
+			view_count, share_count, suggestion_count, created_at, updated_at, deleted_at,
+			activity_type, difficulty_level, difficulty_estimated, duration_hours, distance_km,
 			elevation_gain_m, max_elevation_m, route_type, route_geojson,
 			water_features, terrain_types, essential_gear, best_seasons,
-			trail_conditions, accessibility_notes, parking_info,
+			trail_conditions, accessibility_notes, accessibility, access_fees, parking_info,
 			permits_required, hazards, emergency_contacts,
 			visibility, shared_with, completion_count, average_rating,
-			rating_count, featured, verified
+			rating_count, featured, verified, publish_at, publish_job_id
 		FROM trips
 		WHERE id = $1 AND deleted_at IS NULL`
 
 	err := r.db.GetContext(ctx, &trip, tripQuery, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("trip not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("trip %s: %w", id, ErrTripNotFound)
 		}
 		return nil, fmt.Errorf("failed to get trip: %w", err)
 	}
 
 	// Get collaborators
-	collaborators, err := r.getCollaborators(ctx, id)
-	if err != nil {
-		return nil, err
+	if relations.Collaborators {
+		collaborators, err := r.getCollaborators(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		trip.Collaborators = collaborators
 	}
-	trip.Collaborators = collaborators
 
 	// Get waypoints
-	waypoints, err := r.getWaypoints(ctx, id)
-	if err != nil {
-		return nil, err
+	if relations.Waypoints {
+		waypoints, err := r.GetWaypoints(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		trip.Waypoints = waypoints
 	}
-	trip.Waypoints = waypoints
 
 	return &trip, nil
 }
 
-// Update updates a trip
+// GetByIDs retrieves several trips with their collaborators in two queries
+func (r *PostgresRepository) GetByIDs(ctx context.Context, ids []string) ([]*Trip, error) {
+	if len(ids) == 0 {
+		return []*Trip{}, nil
+	}
+
+	return r.List(ctx, TripFilters{
+		IDs:       ids,
+		Limit:     len(ids),
+		Relations: &Relations{Collaborators: true},
+	})
+}
+
+// Update updates a trip. When its content changes, the images the content
+// shows are recorded as in use in the same transaction.
 func (r *PostgresRepository) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	content, ok := updates["content"].(TripContent)
+	if !ok {
+		return updateTrip(ctx, r.db, id, updates)
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := updateTrip(ctx, tx, id, updates); err != nil {
+		return err
+	}
+	if err := syncContentMedia(ctx, tx, id, content); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// syncContentMedia records the images of a trip's content as in use by it,
+// and no longer counts the ones it has stopped showing, so that media
+// cleanup removes those unless they are used elsewhere. IDs of media that
+// does not exist are ignored.
+func syncContentMedia(ctx context.Context, tx *sqlx.Tx, tripID string, content TripContent) error {
+	ids := pq.Array(content.MediaIDs())
+
+	_, err := tx.ExecContext(ctx, `
+		DELETE FROM media_usage
+		WHERE entity_type = $1 AND entity_id = $2 AND NOT (media_id = ANY($3::uuid[]))`,
+		MediaUsageTripContent, tripID, ids)
+	if err != nil {
+		return fmt.Errorf("failed to update content media: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO media_usage (media_id, entity_type, entity_id)
+		SELECT id, $1, $2 FROM media WHERE id = ANY($3::uuid[])
+		ON CONFLICT (media_id, entity_type, entity_id) DO NOTHING`,
+		MediaUsageTripContent, tripID, ids)
+	if err != nil {
+		return fmt.Errorf("failed to update content media: %w", err)
+	}
+
+	return nil
+}
+
+// updateTrip sets the fields of a trip
+func updateTrip(ctx context.Context, db sqlx.ExecerContext, id string, updates map[string]interface{}) error {
 	// Build dynamic update query
 	setClause := ""
 	args := []interface{}{id}
@@ -157,7 +255,8 @@ func (r *PostgresRepository) Update(ctx context.Context, id string, updates map[
 	arrayFields := map[string]bool{
 		"tags": true, "water_features": true, "terrain_types": true,
 		"essential_gear": true, "best_seasons": true, "permits_required": true,
-		"hazards": true, "shared_with": true,
+		"hazards": true, "shared_with": true, "accessibility": true,
+		"suggested_tags": true,
 	}
 
 	for field, value := range updates {
@@ -185,7 +284,7 @@ func (r *PostgresRepository) Update(ctx context.Context, id string, updates map[
 		WHERE id = $1 AND deleted_at IS NULL
 	`, setClause)
 
-	result, err := r.db.ExecContext(ctx, query, args...)
+	result, err := db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to update trip: %w", err)
 	}
@@ -196,7 +295,7 @@ func (r *PostgresRepository) Update(ctx context.Context, id string, updates map[
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("trip not found")
+		return ErrTripNotFound
 	}
 
 	return nil
@@ -220,7 +319,7 @@ func (r *PostgresRepository) Delete(ctx context.Context, id string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("trip not found")
+		return ErrTripNotFound
 	}
 
 	return nil
@@ -229,195 +328,263 @@ func (r *PostgresRepository) Delete(ctx context.Context, id string) error {
 // List retrieves trips with filters
 func (r *PostgresRepository) List(ctx context.Context, filters TripFilters) ([]*Trip, error) {
 	var trips []*Trip
-	query := `
+	query, args := listQuery(filters)
+
+	done := r.slowQueries.Track(ctx, "trips.list", query, args...)
+	err := r.db.SelectContext(ctx, &trips, query, args...)
+	done()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trips: %w", err)
+	}
+
+	relations := AllRelations
+	if filters.Relations != nil {
+		relations = *filters.Relations
+	}
+	if err := r.loadRelations(ctx, trips, relations); err != nil {
+		return nil, err
+	}
+
+	return trips, nil
+}
+
+// loadRelations loads the requested related records of the trips with one
+// query per relation, however many trips there are
+func (r *PostgresRepository) loadRelations(ctx context.Context, trips []*Trip, relations Relations) error {
+	if len(trips) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(trips))
+	byID := make(map[string]*Trip, len(trips))
+	for i, trip := range trips {
+		ids[i] = trip.ID
+		byID[trip.ID] = trip
+	}
+
+	if relations.Collaborators {
+		collaborators, err := r.selectCollaborators(ctx, "tc.trip_id = ANY($1)", pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		for _, collaborator := range collaborators {
+			if trip, ok := byID[collaborator.TripID]; ok {
+				trip.Collaborators = append(trip.Collaborators, collaborator)
+			}
+		}
+	}
+
+	if relations.Waypoints {
+		waypoints, err := r.selectWaypoints(ctx, "tw.trip_id = ANY($1)", pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		for _, waypoint := range waypoints {
+			if trip, ok := byID[waypoint.TripID]; ok {
+				trip.Waypoints = append(trip.Waypoints, waypoint)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Count returns how many trips match the filters, ignoring the cursor and
+// paging
+func (r *PostgresRepository) Count(ctx context.Context, filters TripFilters) (int64, error) {
+	query, args := countQuery(filters)
+
+	var total int64
+	done := r.slowQueries.Track(ctx, "trips.count", query, args...)
+	err := r.db.GetContext(ctx, &total, query, args...)
+	done()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count trips: %w", err)
+	}
+
+	return total, nil
+}
+
+// listQuery builds the query List runs for the given filters
+func listQuery(filters TripFilters) (string, []interface{}) {
+	b := sqlbuilder.New(`
 		SELECT 
-			t.id, t.title, t.description, t.owner_id, t.cover_image, 
+			t.id, t.title, t.description, t.summary, t.owner_id, t.cover_image, 
 			t.privacy, t.status, t.start_date, t.end_date, t.timezone, 
 			t.tags, t.view_count, t.share_count, t.suggestion_count,
 			t.created_at, t.updated_at,
-			t.activity_type, t.difficulty_level, t.duration_hours, t.distance_km,
+			t.activity_type, t.difficulty_level, t.difficulty_estimated, t.duration_hours, t.distance_km,
 			t.elevation_gain_m, t.max_elevation_m, t.route_type, t.route_geojson,
 			t.water_features, t.terrain_types, t.essential_gear, t.best_seasons,
-			t.trail_conditions, t.accessibility_notes, t.parking_info,
+			t.trail_conditions, t.accessibility_notes, t.accessibility, t.access_fees, t.parking_info,
 			t.permits_required, t.hazards, t.emergency_contacts,
 			t.visibility, t.shared_with, t.completion_count, t.average_rating,
 			t.rating_count, t.featured, t.verified
 		FROM trips t
-		WHERE t.deleted_at IS NULL`
+		WHERE t.deleted_at IS NULL`)
+
+	search := applyListFilters(b, filters)
+
+	desc := strings.ToUpper(filters.SortOrder) != "ASC"
+
+	// Keyset pagination always walks created_at order
+	if filters.After != nil {
+		condition, cursorArgs := filters.After.Condition("t.created_at", "t.id", desc, b.Next())
+		b.WhereNumbered(condition, cursorArgs...)
+		filters.SortBy = ""
+		filters.Offset = 0
+	}
+
+	// Add sorting. The id keeps the order stable between rows with equal keys.
+	sortColumn := "t.created_at"
+	switch filters.SortBy {
+	case "title":
+		sortColumn = "t.title"
+	case "start_date":
+		sortColumn = "t.start_date"
+	case "updated_at":
+		sortColumn = "t.updated_at"
+	}
+
+	// Searches put the best matches first unless another order is asked for
+	if search != "" && (filters.SortBy == "" || filters.SortBy == "relevance") && filters.After == nil {
+		b.Append(" ORDER BY "+textsearch.Rank("t.search_vector")+" DESC, t.id", search)
+	} else {
+		b.Append(" ORDER BY " + pagination.OrderBy(sortColumn, "t.id", desc))
+	}
+
+	// Add pagination
+	b.Append(" LIMIT ? OFFSET ?", filters.Limit, filters.Offset)
+
+	return b.SQL(), b.Args()
+}
+
+// countQuery builds the query Count runs for the given filters. A cursor,
+// sorting and paging do not change how many trips match.
+func countQuery(filters TripFilters) (string, []interface{}) {
+	b := sqlbuilder.New(`
+		SELECT COUNT(*)
+		FROM trips t
+		WHERE t.deleted_at IS NULL`)
+
+	applyListFilters(b, filters)
 
-	args := []interface{}{}
-	argCount := 1
+	return b.SQL(), b.Args()
+}
 
-	// Apply filters
+// applyListFilters adds the conditions of the filters to a query over trips
+// aliased t. It returns the text search query, empty when not searching.
+func applyListFilters(b *sqlbuilder.Builder, filters TripFilters) string {
 	if filters.OwnerID != "" {
-		query += fmt.Sprintf(" AND t.owner_id = $%d", argCount)
-		args = append(args, filters.OwnerID)
-		argCount++
+		b.Where("t.owner_id = ?", filters.OwnerID)
 	}
 
 	if filters.CollaboratorID != "" {
-		query += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM trip_collaborators tc WHERE tc.trip_id = t.id AND tc.user_id = $%d)", argCount)
-		args = append(args, filters.CollaboratorID)
-		argCount++
+		b.Where("EXISTS (SELECT 1 FROM trip_collaborators tc WHERE tc.trip_id = t.id AND tc.user_id = ?)", filters.CollaboratorID)
+	}
+
+	// Trips the user owns or may edit as a collaborator
+	if filters.EditorID != "" {
+		b.Where("(? = t.owner_id OR EXISTS (SELECT 1 FROM trip_collaborators te WHERE te.trip_id = t.id AND te.user_id = ? AND te.can_edit))",
+			filters.EditorID, filters.EditorID)
+	}
+
+	if len(filters.IDs) > 0 {
+		b.Where("t.id = ANY(?)", pq.Array(filters.IDs))
 	}
 
 	if filters.Privacy != "" {
-		query += fmt.Sprintf(" AND t.privacy = $%d", argCount)
-		args = append(args, filters.Privacy)
-		argCount++
+		b.Where("t.privacy = ?", filters.Privacy)
+	}
+
+	// Trips the user may see, as Trip.VisibleTo decides
+	if filters.VisibleTo != "" {
+		b.Where("(t.privacy = 'public' OR ? = t.owner_id OR EXISTS (SELECT 1 FROM trip_collaborators tv WHERE tv.trip_id = t.id AND tv.user_id = ?))",
+			filters.VisibleTo, filters.VisibleTo)
 	}
 
 	if filters.Status != "" {
-		query += fmt.Sprintf(" AND t.status = $%d", argCount)
-		args = append(args, filters.Status)
-		argCount++
+		b.Where("t.status = ?", filters.Status)
 	}
 
 	if len(filters.Tags) > 0 {
-		query += fmt.Sprintf(" AND t.tags && $%d", argCount)
-		args = append(args, pq.Array(filters.Tags))
-		argCount++
+		b.Where("t.tags && ?", pq.Array(filters.Tags))
 	}
 
 	if filters.StartDateFrom != nil {
-		query += fmt.Sprintf(" AND t.start_date >= $%d", argCount)
-		args = append(args, filters.StartDateFrom)
-		argCount++
+		b.Where("t.start_date >= ?", filters.StartDateFrom)
 	}
 
 	if filters.StartDateTo != nil {
-		query += fmt.Sprintf(" AND t.start_date <= $%d", argCount)
-		args = append(args, filters.StartDateTo)
-		argCount++
+		b.Where("t.start_date <= ?", filters.StartDateTo)
+	}
+
+	if filters.UpdatedAfter != nil {
+		b.Where("t.updated_at >= ?", filters.UpdatedAfter)
 	}
 
 	// Activity-specific filters
 	if len(filters.ActivityTypes) > 0 {
-		query += fmt.Sprintf(" AND t.activity_type = ANY($%d)", argCount)
-		args = append(args, pq.Array(filters.ActivityTypes))
-		argCount++
+		b.Where("t.activity_type = ANY(?)", pq.Array(filters.ActivityTypes))
 	}
 
 	if len(filters.DifficultyLevels) > 0 {
-		query += fmt.Sprintf(" AND t.difficulty_level = ANY($%d)", argCount)
-		args = append(args, pq.Array(filters.DifficultyLevels))
-		argCount++
+		b.Where("t.difficulty_level = ANY(?)", pq.Array(filters.DifficultyLevels))
 	}
 
 	if filters.MinDuration != nil {
-		query += fmt.Sprintf(" AND t.duration_hours >= $%d", argCount)
-		args = append(args, filters.MinDuration)
-		argCount++
+		b.Where("t.duration_hours >= ?", filters.MinDuration)
 	}
 
 	if filters.MaxDuration != nil {
-		query += fmt.Sprintf(" AND t.duration_hours <= $%d", argCount)
-		args = append(args, filters.MaxDuration)
-		argCount++
+		b.Where("t.duration_hours <= ?", filters.MaxDuration)
 	}
 
 	if filters.MinDistance != nil {
-		query += fmt.Sprintf(" AND t.distance_km >= $%d", argCount)
-		args = append(args, filters.MinDistance)
-		argCount++
+		b.Where("t.distance_km >= ?", filters.MinDistance)
 	}
 
 	if filters.MaxDistance != nil {
-		query += fmt.Sprintf(" AND t.distance_km <= $%d", argCount)
-		args = append(args, filters.MaxDistance)
-		argCount++
+		b.Where("t.distance_km <= ?", filters.MaxDistance)
 	}
 
 	if len(filters.WaterFeatures) > 0 {
-		query += fmt.Sprintf(" AND t.water_features && $%d", argCount)
-		args = append(args, pq.Array(filters.WaterFeatures))
-		argCount++
+		b.Where("t.water_features && ?", pq.Array(filters.WaterFeatures))
 	}
 
 	if len(filters.TerrainTypes) > 0 {
-		query += fmt.Sprintf(" AND t.terrain_types && $%d", argCount)
-		args = append(args, pq.Array(filters.TerrainTypes))
-		argCount++
+		b.Where("t.terrain_types && ?", pq.Array(filters.TerrainTypes))
+	}
+
+	// Every attribute asked for must hold, unlike the features above
+	if len(filters.Accessibility) > 0 {
+		b.Where("t.accessibility @> ?", pq.Array(filters.Accessibility))
 	}
 
 	if filters.Visibility != "" {
-		query += fmt.Sprintf(" AND t.visibility = $%d", argCount)
-		args = append(args, filters.Visibility)
-		argCount++
+		b.Where("t.visibility = ?", filters.Visibility)
 	}
 
 	if filters.Featured != nil {
-		query += fmt.Sprintf(" AND t.featured = $%d", argCount)
-		args = append(args, *filters.Featured)
-		argCount++
+		b.Where("t.featured = ?", *filters.Featured)
 	}
 
 	if filters.Verified != nil {
-		query += fmt.Sprintf(" AND t.verified = $%d", argCount)
-		args = append(args, *filters.Verified)
-		argCount++
+		b.Where("t.verified = ?", *filters.Verified)
 	}
 
-	// Geospatial filters
+	// Geospatial filters. The route expression matches idx_trips_route_geography.
 	if filters.NearLat != nil && filters.NearLng != nil && filters.RadiusKm != nil {
-		query += fmt.Sprintf(" AND ST_DWithin(t.route_geojson::geography, ST_MakePoint($%d, $%d)::geography, $%d)", 
-			argCount, argCount+1, argCount+2)
-		args = append(args, *filters.NearLng, *filters.NearLat, *filters.RadiusKm*1000) // Convert km to meters
-		argCount += 3
-	}
-
-	if filters.Search != "" {
-		query += fmt.Sprintf(" AND (t.title ILIKE $%d OR t.description ILIKE $%d)", argCount, argCount)
-		searchPattern := "%" + filters.Search + "%"
-		args = append(args, searchPattern)
-		argCount++
-	}
-
-	// Add sorting
-	orderBy := " ORDER BY "
-	switch filters.SortBy {
-	case "title":
-		orderBy += "t.title"
-	case "start_date":
-		orderBy += "t.start_date"
-	case "updated_at":
-		orderBy += "t.updated_at"
-	default:
-		orderBy += "t.created_at"
-	}
-
-	if strings.ToUpper(filters.SortOrder) == "ASC" {
-		orderBy += " ASC"
-	} else {
-		orderBy += " DESC"
-	}
-	query += orderBy
-
-	// Add pagination
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argCount, argCount+1)
-	args = append(args, filters.Limit, filters.Offset)
-
-	err := r.db.SelectContext(ctx, &trips, query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list trips: %w", err)
+		b.Where("ST_DWithin(ST_GeomFromGeoJSON(t.route_geojson::text)::geography, ST_MakePoint(?, ?)::geography, ?)",
+			*filters.NearLng, *filters.NearLat, *filters.RadiusKm*1000) // Convert km to meters
 	}
 
-	// Load collaborators and waypoints for each trip
-	for _, trip := range trips {
-		collaborators, err := r.getCollaborators(ctx, trip.ID)
-		if err != nil {
-			return nil, err
-		}
-		trip.Collaborators = collaborators
-
-		waypoints, err := r.getWaypoints(ctx, trip.ID)
-		if err != nil {
-			return nil, err
-		}
-		trip.Waypoints = waypoints
+	search := textsearch.PrefixQuery(filters.Search)
+	if search != "" {
+		b.Where(textsearch.Match("t.search_vector"), search)
 	}
 
-	return trips, nil
+	return search
 }
 
 // AddCollaborator adds a collaborator to a trip
@@ -441,15 +608,48 @@ func (r *PostgresRepository) AddCollaborator(ctx context.Context, tripID string,
 	)
 
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-			return fmt.Errorf("user is already a collaborator")
-		}
-		return fmt.Errorf("failed to add collaborator: %w", err)
+		return fmt.Errorf("failed to add collaborator %s: %w", collaborator.UserID,
+			repoerr.Classify(err, nil, ErrAlreadyCollaborator, ErrUserNotFound))
 	}
 
 	return nil
 }
 
+// AddCollaborators adds several collaborators to a trip in one transaction
+func (r *PostgresRepository) AddCollaborators(ctx context.Context, tripID string, collaborators []Collaborator) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO trip_collaborators (
+			trip_id, user_id, role, can_edit, can_delete, can_invite,
+			can_moderate_suggestions
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7
+		)`
+
+	for _, collaborator := range collaborators {
+		_, err := tx.ExecContext(ctx, query,
+			tripID,
+			collaborator.UserID,
+			collaborator.Role,
+			collaborator.CanEdit,
+			collaborator.CanDelete,
+			collaborator.CanInvite,
+			collaborator.CanModerateSuggestions,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to add collaborator %s: %w", collaborator.UserID,
+				repoerr.Classify(err, nil, ErrAlreadyCollaborator, ErrUserNotFound))
+		}
+	}
+
+	return tx.Commit()
+}
+
 // UpdateCollaborator updates a collaborator's role and permissions
 func (r *PostgresRepository) UpdateCollaborator(ctx context.Context, tripID, userID string, updates map[string]interface{}) error {
 	// Build dynamic update query
@@ -487,7 +687,7 @@ func (r *PostgresRepository) UpdateCollaborator(ctx context.Context, tripID, use
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("collaborator not found")
+		return fmt.Errorf("collaborator %s on trip %s: %w", userID, tripID, ErrCollaboratorNotFound)
 	}
 
 	return nil
@@ -510,7 +710,7 @@ func (r *PostgresRepository) RemoveCollaborator(ctx context.Context, tripID, use
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("collaborator not found")
+		return fmt.Errorf("collaborator %s on trip %s: %w", userID, tripID, ErrCollaboratorNotFound)
 	}
 
 	return nil
@@ -524,15 +724,16 @@ func (r *PostgresRepository) GetCollaborator(ctx context.Context, tripID, userID
 			tc.id, tc.trip_id, tc.user_id, tc.role, tc.can_edit, 
 			tc.can_delete, tc.can_invite, tc.can_moderate_suggestions,
 			tc.invited_at, tc.joined_at,
-			u.username, u.display_name, u.avatar_url
+			u.username, COALESCE(u.display_name, '') AS display_name,
+			COALESCE(u.avatar_url, '') AS avatar_url
 		FROM trip_collaborators tc
 		JOIN users u ON tc.user_id = u.id
 		WHERE tc.trip_id = $1 AND tc.user_id = $2`
 
 	err := r.db.GetContext(ctx, &collaborator, query, tripID, userID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("collaborator not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("collaborator %s on trip %s: %w", userID, tripID, ErrCollaboratorNotFound)
 		}
 		return nil, fmt.Errorf("failed to get collaborator: %w", err)
 	}
@@ -543,19 +744,26 @@ func (r *PostgresRepository) GetCollaborator(ctx context.Context, tripID, userID
 // Helper functions
 
 func (r *PostgresRepository) getCollaborators(ctx context.Context, tripID string) ([]Collaborator, error) {
+	return r.selectCollaborators(ctx, "tc.trip_id = $1", tripID)
+}
+
+// selectCollaborators lists the collaborators matching the condition, in the
+// order they joined
+func (r *PostgresRepository) selectCollaborators(ctx context.Context, condition string, args ...interface{}) ([]Collaborator, error) {
 	var collaborators []Collaborator
 	query := `
 		SELECT 
 			tc.id, tc.trip_id, tc.user_id, tc.role, tc.can_edit, 
 			tc.can_delete, tc.can_invite, tc.can_moderate_suggestions,
 			tc.invited_at, tc.joined_at,
-			u.username, u.display_name, u.avatar_url
+			u.username, COALESCE(u.display_name, '') AS display_name,
+			COALESCE(u.avatar_url, '') AS avatar_url
 		FROM trip_collaborators tc
 		JOIN users u ON tc.user_id = u.id
-		WHERE tc.trip_id = $1
+		WHERE ` + condition + `
 		ORDER BY tc.joined_at`
 
-	err := r.db.SelectContext(ctx, &collaborators, query, tripID)
+	err := r.db.SelectContext(ctx, &collaborators, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get collaborators: %w", err)
 	}
@@ -563,44 +771,61 @@ func (r *PostgresRepository) getCollaborators(ctx context.Context, tripID string
 	return collaborators, nil
 }
 
-func (r *PostgresRepository) getWaypoints(ctx context.Context, tripID string) ([]Waypoint, error) {
+// GetWaypoints retrieves the waypoints of a trip in order, with their places
+func (r *PostgresRepository) GetWaypoints(ctx context.Context, tripID string) ([]Waypoint, error) {
+	return r.selectWaypoints(ctx, "tw.trip_id = $1", tripID)
+}
+
+// selectWaypoints lists the waypoints matching the condition with their
+// places, trip by trip in order
+func (r *PostgresRepository) selectWaypoints(ctx context.Context, condition string, args ...interface{}) ([]Waypoint, error) {
 	var waypoints []Waypoint
 	query := `
 		SELECT 
 			tw.id, tw.trip_id, tw.place_id, tw.order_position,
-			tw.arrival_time, tw.departure_time, tw.notes,
+			tw.arrival_time, tw.departure_time, COALESCE(tw.notes, ''), tw.notes_format, tw.notes_doc, tw.kind,
+			tw.window_opens_at, tw.window_closes_at, COALESCE(tw.window_label, ''),
 			tw.created_at, tw.updated_at,
 			p.id as "place.id", p.name as "place.name", 
-			p.description as "place.description", p.type as "place.type",
+			COALESCE(p.description, '') as "place.description", p.type as "place.type",
 			ST_AsGeoJSON(p.location) as "place.location",
-			p.street_address as "place.street_address", 
-			p.city as "place.city", p.country as "place.country"
+			COALESCE(p.street_address, '') as "place.street_address", 
+			COALESCE(p.city, '') as "place.city", COALESCE(p.country, '') as "place.country",
+			p.access_fees as "place.access_fees", p.amenities as "place.amenities",
+			COALESCE(p.privacy, 'public') as "place.privacy"
 		FROM trip_waypoints tw
 		JOIN places p ON tw.place_id = p.id
-		WHERE tw.trip_id = $1
-		ORDER BY tw.order_position`
+		WHERE ` + condition + `
+		ORDER BY tw.trip_id, tw.order_position`
 
-	rows, err := r.db.QueryContext(ctx, query, tripID)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get waypoints: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var w Waypoint
+		w := Waypoint{Place: &Place{}}
 		var placeLocation sql.NullString
+		var window TimeWindow
 
 		err := rows.Scan(
 			&w.ID, &w.TripID, &w.PlaceID, &w.OrderPosition,
-			&w.ArrivalTime, &w.DepartureTime, &w.Notes,
+			&w.ArrivalTime, &w.DepartureTime, &w.Notes, &w.NotesFormat, &w.NotesRendering, &w.Kind,
+			&window.OpensAt, &window.ClosesAt, &window.Label,
 			&w.CreatedAt, &w.UpdatedAt,
 			&w.Place.ID, &w.Place.Name, &w.Place.Description, &w.Place.Type,
 			&placeLocation, &w.Place.Address, &w.Place.City, &w.Place.Country,
+			&w.Place.AccessFees, &w.Place.Amenities, &w.Place.Privacy,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan waypoint: %w", err)
 		}
 
+		if !window.IsZero() {
+			w.Window = &window
+		}
+
 		// Parse GeoJSON location
 		if placeLocation.Valid {
 			var geoJSON GeoJSON
@@ -615,32 +840,2281 @@ func (r *PostgresRepository) getWaypoints(ctx context.Context, tripID string) ([
 	return waypoints, nil
 }
 
-// IncrementViewCount increments the view count for a trip
-func (r *PostgresRepository) IncrementViewCount(ctx context.Context, tripID string) error {
+// SetWaypointWindow sets or, when window is nil, clears the time window of
+// a waypoint of the trip
+func (r *PostgresRepository) SetWaypointWindow(ctx context.Context, tripID, waypointID string, window *TimeWindow) error {
+	if window == nil {
+		window = &TimeWindow{}
+	}
+
 	query := `
-		UPDATE trips
-		SET view_count = view_count + 1
-		WHERE id = $1 AND deleted_at IS NULL`
+		UPDATE trip_waypoints
+		SET window_opens_at = $3, window_closes_at = $4, window_label = NULLIF($5, ''),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND trip_id = $2`
 
-	_, err := r.db.ExecContext(ctx, query, tripID)
+	result, err := r.db.ExecContext(ctx, query, waypointID, tripID, window.OpensAt, window.ClosesAt, window.Label)
 	if err != nil {
-		return fmt.Errorf("failed to increment view count: %w", err)
+		return fmt.Errorf("failed to set waypoint window: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrWaypointNotFound
 	}
 
 	return nil
 }
 
-// IncrementShareCount increments the share count for a trip
-func (r *PostgresRepository) IncrementShareCount(ctx context.Context, tripID string) error {
+// FindBailoutPlaces finds public places of the given categories within
+// corridorM metres of the route. Places with only bounds, such as roads, are
+// located at their point nearest the route.
+func (r *PostgresRepository) FindBailoutPlaces(ctx context.Context, route *GeoJSONRoute, corridorM float64, categories []string) ([]*BailoutPoint, error) {
+	routeJSON, err := json.Marshal(route)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal route: %w", err)
+	}
+
+	points := []*BailoutPoint{}
 	query := `
-		UPDATE trips
-		SET share_count = share_count + 1
-		WHERE id = $1 AND deleted_at IS NULL`
+		SELECT 
+			p.id AS place_id, p.name, p.category,
+			ST_AsGeoJSON(ST_ClosestPoint(COALESCE(p.location, p.bounds)::geometry, r.geom::geometry)) AS location,
+			ST_Distance(COALESCE(p.location, p.bounds), r.geom) AS distance_m
+		FROM places p, (SELECT ST_SetSRID(ST_GeomFromGeoJSON($1), 4326)::geography AS geom) r
+		WHERE p.category && $2
+			AND p.privacy = 'public' AND p.status = 'active'
+			AND ST_DWithin(COALESCE(p.location, p.bounds), r.geom, $3)
+		ORDER BY distance_m
+		LIMIT 100`
+
+	if err := r.db.SelectContext(ctx, &points, query, string(routeJSON), pq.Array(categories), corridorM); err != nil {
+		return nil, fmt.Errorf("failed to find bail-out places: %w", err)
+	}
 
-	_, err := r.db.ExecContext(ctx, query, tripID)
+	return points, nil
+}
+
+// CountPlacesNear counts public places of the given categories within
+// radiusM metres of a [longitude, latitude] position
+func (r *PostgresRepository) CountPlacesNear(ctx context.Context, position []float64, radiusM float64, categories []string) (int, error) {
+	var count int
+	query := `
+		SELECT COUNT(*)
+		FROM places p
+		WHERE p.category && $3
+			AND p.privacy = 'public' AND p.status = 'active'
+			AND ST_DWithin(COALESCE(p.location, p.bounds), ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography, $4)`
+
+	if err := r.db.GetContext(ctx, &count, query, position[0], position[1], pq.Array(categories), radiusM); err != nil {
+		return 0, fmt.Errorf("failed to count places near position: %w", err)
+	}
+
+	return count, nil
+}
+
+// RouteShareNear is the share of the route's length that runs within
+// corridorM metres of public places of the given categories. The places are
+// buffered by the corridor and the route clipped to them, so that a river
+// mapped as a line counts along its whole length.
+func (r *PostgresRepository) RouteShareNear(ctx context.Context, route *GeoJSONRoute, corridorM float64, categories []string) (float64, error) {
+	routeJSON, err := json.Marshal(route)
 	if err != nil {
-		return fmt.Errorf("failed to increment share count: %w", err)
+		return 0, fmt.Errorf("failed to marshal route: %w", err)
+	}
+
+	var share float64
+	query := `
+		WITH r AS (
+			SELECT ST_SetSRID(ST_GeomFromGeoJSON($1), 4326)::geography AS geom
+		), near AS (
+			SELECT ST_Union(ST_Buffer(COALESCE(p.location, p.bounds), $2)::geometry) AS geom
+			FROM places p, r
+			WHERE p.category && $3
+				AND p.privacy = 'public' AND p.status = 'active'
+				AND ST_DWithin(COALESCE(p.location, p.bounds), r.geom, $2)
+		)
+		SELECT LEAST(COALESCE(
+			ST_Length(ST_Intersection(r.geom::geometry, near.geom)::geography) / NULLIF(ST_Length(r.geom), 0),
+			0), 1)
+		FROM r, near`
+
+	if err := r.db.GetContext(ctx, &share, query, string(routeJSON), corridorM, pq.Array(categories)); err != nil {
+		return 0, fmt.Errorf("failed to measure route near places: %w", err)
+	}
+
+	return share, nil
+}
+
+// FindWaterSources finds public water sources within corridorM metres of the
+// route, with how far they can be counted on
+func (r *PostgresRepository) FindWaterSources(ctx context.Context, route *GeoJSONRoute, corridorM float64) ([]*WaterSourcePoint, error) {
+	routeJSON, err := json.Marshal(route)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal route: %w", err)
+	}
+
+	sources := []*WaterSourcePoint{}
+	query := `
+		SELECT 
+			p.id AS place_id, p.name,
+			ST_AsGeoJSON(ST_ClosestPoint(COALESCE(p.location, p.bounds)::geometry, r.geom::geometry)) AS location,
+			ST_Distance(COALESCE(p.location, p.bounds), r.geom) AS distance_m,
+			w.kind, w.reliability, w.flowing_months, w.treatment_required, w.last_verified_at
+		FROM places p
+		JOIN place_water_sources w ON w.place_id = p.id,
+			(SELECT ST_SetSRID(ST_GeomFromGeoJSON($1), 4326)::geography AS geom) r
+		WHERE p.privacy = 'public' AND p.status = 'active'
+			AND ST_DWithin(COALESCE(p.location, p.bounds), r.geom, $2)
+		ORDER BY distance_m
+		LIMIT 200`
+
+	if err := r.db.SelectContext(ctx, &sources, query, string(routeJSON), corridorM); err != nil {
+		return nil, fmt.Errorf("failed to find water sources: %w", err)
+	}
+
+	return sources, nil
+}
+
+// AddBailoutWaypoint adds a bail-out waypoint after the trip's other
+// waypoints
+func (r *PostgresRepository) AddBailoutWaypoint(ctx context.Context, waypoint *Waypoint) error {
+	query := `
+		INSERT INTO trip_waypoints (id, trip_id, place_id, order_position, notes, kind)
+		SELECT $1, $2, $3, COALESCE(MAX(order_position), -1) + 1, NULLIF($4, ''), 'bailout'
+		FROM trip_waypoints WHERE trip_id = $2
+		RETURNING order_position, created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		waypoint.ID,
+		waypoint.TripID,
+		waypoint.PlaceID,
+		waypoint.Notes,
+	).Scan(&waypoint.OrderPosition, &waypoint.CreatedAt, &waypoint.UpdatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return ErrPlaceNotFound
+		}
+		return fmt.Errorf("failed to add bail-out waypoint: %w", err)
 	}
 
+	waypoint.Kind = WaypointBailout
 	return nil
-}
\ No newline at end of file
+}
+
+// RemoveBailoutWaypoint removes a bail-out waypoint of a trip, leaving its
+// stops alone
+func (r *PostgresRepository) RemoveBailoutWaypoint(ctx context.Context, tripID, waypointID string) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM trip_waypoints
+		WHERE id = $1 AND trip_id = $2 AND kind = 'bailout'`, waypointID, tripID)
+	if err != nil {
+		return fmt.Errorf("failed to remove bail-out waypoint: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrWaypointNotFound
+	}
+
+	return nil
+}
+
+// AddWaypoints adds stops after the trip's other waypoints, in order and in
+// one transaction
+func (r *PostgresRepository) AddWaypoints(ctx context.Context, tripID string, waypoints []*Waypoint) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockWaypoints(ctx, tx, tripID); err != nil {
+		return err
+	}
+
+	var next int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(order_position), -1) + 1
+		FROM trip_waypoints WHERE trip_id = $1`, tripID).Scan(&next)
+	if err != nil {
+		return fmt.Errorf("failed to get next waypoint position: %w", err)
+	}
+
+	query := `
+		INSERT INTO trip_waypoints (id, trip_id, place_id, order_position, notes, notes_format, notes_doc, kind)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), COALESCE(NULLIF($6, ''), 'plain'), $7, 'stop')
+		RETURNING created_at, updated_at`
+
+	for _, waypoint := range waypoints {
+		waypoint.TripID = tripID
+		waypoint.OrderPosition = next
+		waypoint.Kind = WaypointStop
+		err := tx.QueryRowContext(ctx, query,
+			waypoint.ID,
+			tripID,
+			waypoint.PlaceID,
+			waypoint.OrderPosition,
+			waypoint.Notes,
+			waypoint.NotesFormat,
+			waypoint.NotesRendering,
+		).Scan(&waypoint.CreatedAt, &waypoint.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to add waypoint for place %s: %w", waypoint.PlaceID,
+				repoerr.Classify(err, nil, nil, ErrPlaceNotFound))
+		}
+		next++
+	}
+
+	return tx.Commit()
+}
+
+// lockWaypoints locks a trip so that changes to the order of its waypoints
+// wait for each other and never take the same positions
+func lockWaypoints(ctx context.Context, tx *sqlx.Tx, tripID string) error {
+	var locked string
+	err := tx.QueryRowContext(ctx, `
+		SELECT id FROM trips
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE`, tripID).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("trip %s: %w", tripID, ErrTripNotFound)
+		}
+		return fmt.Errorf("failed to lock trip: %w", err)
+	}
+	return nil
+}
+
+// waypointOrder lists the IDs of a trip's waypoints in order
+func waypointOrder(ctx context.Context, tx *sqlx.Tx, tripID string) ([]string, error) {
+	var ids []string
+	err := tx.SelectContext(ctx, &ids, `
+		SELECT id FROM trip_waypoints
+		WHERE trip_id = $1
+		ORDER BY order_position`, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waypoint order: %w", err)
+	}
+	return ids, nil
+}
+
+// renumberWaypoints gives a trip's waypoints the positions 0, 1, 2, ... in
+// the order of ids. Positions are unique within a trip and checked row by
+// row, so they are first moved out of the way to negatives.
+func renumberWaypoints(ctx context.Context, tx *sqlx.Tx, tripID string, ids []string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE trip_waypoints SET order_position = -1 - order_position
+		WHERE trip_id = $1 AND order_position >= 0`, tripID)
+	if err != nil {
+		return fmt.Errorf("failed to renumber waypoints: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE trip_waypoints tw
+		SET order_position = o.position - 1, updated_at = CURRENT_TIMESTAMP
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS o(id, position)
+		WHERE tw.trip_id = $1 AND tw.id = o.id`, tripID, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to renumber waypoints: %w", err)
+	}
+	return nil
+}
+
+// moveWaypoint moves an ID to a position in the order, or to the end when
+// the position is past it
+func moveWaypoint(ids []string, id string, position int) []string {
+	moved := make([]string, 0, len(ids)+1)
+	for _, other := range ids {
+		if other != id {
+			moved = append(moved, other)
+		}
+	}
+	if position > len(moved) {
+		position = len(moved)
+	}
+
+	moved = append(moved, "")
+	copy(moved[position+1:], moved[position:])
+	moved[position] = id
+	return moved
+}
+
+// AddWaypoint inserts a stop at its order position, moving the waypoints
+// from there on back one. A position past the end appends it.
+func (r *PostgresRepository) AddWaypoint(ctx context.Context, waypoint *Waypoint) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockWaypoints(ctx, tx, waypoint.TripID); err != nil {
+		return err
+	}
+	ids, err := waypointOrder(ctx, tx, waypoint.TripID)
+	if err != nil {
+		return err
+	}
+
+	// Added at the end, then moved into place
+	query := `
+		INSERT INTO trip_waypoints (id, trip_id, place_id, order_position, arrival_time, departure_time, notes, notes_format, notes_doc, kind)
+		SELECT $1, $2, $3, COALESCE(MAX(order_position), -1) + 1, $4, $5, NULLIF($6, ''), COALESCE(NULLIF($7, ''), 'plain'), $8, 'stop'
+		FROM trip_waypoints WHERE trip_id = $2
+		RETURNING created_at, updated_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		waypoint.ID,
+		waypoint.TripID,
+		waypoint.PlaceID,
+		waypoint.ArrivalTime,
+		waypoint.DepartureTime,
+		waypoint.Notes,
+		waypoint.NotesFormat,
+		waypoint.NotesRendering,
+	).Scan(&waypoint.CreatedAt, &waypoint.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add waypoint: %w", repoerr.Classify(err, nil, nil, ErrPlaceNotFound))
+	}
+
+	if waypoint.OrderPosition > len(ids) {
+		waypoint.OrderPosition = len(ids)
+	}
+	if err := renumberWaypoints(ctx, tx, waypoint.TripID, moveWaypoint(ids, waypoint.ID, waypoint.OrderPosition)); err != nil {
+		return err
+	}
+
+	waypoint.Kind = WaypointStop
+	return tx.Commit()
+}
+
+// UpdateWaypoint saves the times and notes of a waypoint and moves it to
+// its order position
+func (r *PostgresRepository) UpdateWaypoint(ctx context.Context, waypoint *Waypoint) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockWaypoints(ctx, tx, waypoint.TripID); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE trip_waypoints
+		SET arrival_time = $3, departure_time = $4, notes = NULLIF($5, ''),
+			notes_format = COALESCE(NULLIF($6, ''), 'plain'), notes_doc = $7,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND trip_id = $2
+		RETURNING updated_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		waypoint.ID,
+		waypoint.TripID,
+		waypoint.ArrivalTime,
+		waypoint.DepartureTime,
+		waypoint.Notes,
+		waypoint.NotesFormat,
+		waypoint.NotesRendering,
+	).Scan(&waypoint.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("waypoint %s: %w", waypoint.ID, ErrWaypointNotFound)
+		}
+		return fmt.Errorf("failed to update waypoint: %w", err)
+	}
+
+	ids, err := waypointOrder(ctx, tx, waypoint.TripID)
+	if err != nil {
+		return err
+	}
+	if waypoint.OrderPosition >= len(ids) {
+		waypoint.OrderPosition = len(ids) - 1
+	}
+	if err := renumberWaypoints(ctx, tx, waypoint.TripID, moveWaypoint(ids, waypoint.ID, waypoint.OrderPosition)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveWaypoint removes a waypoint of a trip, moving the waypoints after
+// it forward to close the gap
+func (r *PostgresRepository) RemoveWaypoint(ctx context.Context, tripID, waypointID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockWaypoints(ctx, tx, tripID); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM trip_waypoints
+		WHERE id = $1 AND trip_id = $2`, waypointID, tripID)
+	if err != nil {
+		return fmt.Errorf("failed to remove waypoint: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("waypoint %s: %w", waypointID, ErrWaypointNotFound)
+	}
+
+	ids, err := waypointOrder(ctx, tx, tripID)
+	if err != nil {
+		return err
+	}
+	if err := renumberWaypoints(ctx, tx, tripID, ids); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReorderWaypoints puts a trip's waypoints in the order given, which must
+// list each of them once
+func (r *PostgresRepository) ReorderWaypoints(ctx context.Context, tripID string, waypointIDs []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockWaypoints(ctx, tx, tripID); err != nil {
+		return err
+	}
+
+	// Checked again under the lock, in case waypoints were added or removed
+	// since the order was read
+	ids, err := waypointOrder(ctx, tx, tripID)
+	if err != nil {
+		return err
+	}
+	if !sameWaypoints(ids, waypointIDs) {
+		return ErrInvalidWaypointOrder
+	}
+
+	if err := renumberWaypoints(ctx, tx, tripID, waypointIDs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// IncrementViewCount increments the view count for a trip
+func (r *PostgresRepository) IncrementViewCount(ctx context.Context, tripID string) error {
+	query := `
+		UPDATE trips
+		SET view_count = view_count + 1
+		WHERE id = $1 AND deleted_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, tripID)
+	if err != nil {
+		return fmt.Errorf("failed to increment view count: %w", err)
+	}
+
+	return nil
+}
+
+// IncrementShareCount increments the share count for a trip
+func (r *PostgresRepository) IncrementShareCount(ctx context.Context, tripID string) error {
+	query := `
+		UPDATE trips
+		SET share_count = share_count + 1
+		WHERE id = $1 AND deleted_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, tripID)
+	if err != nil {
+		return fmt.Errorf("failed to increment share count: %w", err)
+	}
+
+	return nil
+}
+// CreateOwnershipTransfer records a pending ownership transfer for a trip
+func (r *PostgresRepository) CreateOwnershipTransfer(ctx context.Context, transfer *OwnershipTransfer) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO ownership_transfers (entity_type, entity_id, from_user_id, to_user_id)
+		VALUES ('trip', $1, $2, $3)
+		RETURNING id, entity_type, status, created_at`
+
+	err = tx.QueryRowContext(ctx, query, transfer.EntityID, transfer.FromUserID, transfer.ToUserID).
+		Scan(&transfer.ID, &transfer.EntityType, &transfer.Status, &transfer.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrTransferPending
+		}
+		return fmt.Errorf("failed to create ownership transfer: %w", err)
+	}
+
+	err = audit.Record(ctx, tx, &audit.Entry{
+		ActorID:    transfer.FromUserID,
+		Action:     audit.ActionOwnershipTransferRequested,
+		EntityType: "trip",
+		EntityID:   transfer.EntityID,
+		Details:    map[string]interface{}{"transfer_id": transfer.ID, "to_user_id": transfer.ToUserID},
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetPendingOwnershipTransfer retrieves the pending ownership transfer for a trip
+func (r *PostgresRepository) GetPendingOwnershipTransfer(ctx context.Context, tripID string) (*OwnershipTransfer, error) {
+	var transfer OwnershipTransfer
+	query := `
+		SELECT id, entity_type, entity_id, from_user_id, to_user_id, status, created_at, responded_at
+		FROM ownership_transfers
+		WHERE entity_type = 'trip' AND entity_id = $1 AND status = 'pending'`
+
+	err := r.db.GetContext(ctx, &transfer, query, tripID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTransferNotFound
+		}
+		return nil, fmt.Errorf("failed to get ownership transfer: %w", err)
+	}
+
+	return &transfer, nil
+}
+
+// ResolveOwnershipTransfer accepts or declines a pending transfer. On
+// acceptance the recipient becomes the owner and the previous owner is kept
+// on as an admin collaborator.
+func (r *PostgresRepository) ResolveOwnershipTransfer(ctx context.Context, transfer *OwnershipTransfer, actorID string, accept bool) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	status := "declined"
+	action := audit.ActionOwnershipTransferDeclined
+	if accept {
+		status = "accepted"
+		action = audit.ActionOwnershipTransferAccepted
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE ownership_transfers
+		SET status = $2, responded_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'pending'`, transfer.ID, status)
+	if err != nil {
+		return fmt.Errorf("failed to update ownership transfer: %w", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	} else if rowsAffected == 0 {
+		return ErrTransferNotFound
+	}
+
+	if accept {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE trips
+			SET owner_id = $2, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND owner_id = $3 AND deleted_at IS NULL`,
+			transfer.EntityID, transfer.ToUserID, transfer.FromUserID)
+		if err != nil {
+			return fmt.Errorf("failed to transfer trip ownership: %w", err)
+		}
+		if rowsAffected, err := result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		} else if rowsAffected == 0 {
+			return ErrTripNotFound
+		}
+
+		// Both the new and the previous owner end up as full admins
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO trip_collaborators (
+				trip_id, user_id, role, can_edit, can_delete, can_invite,
+				can_moderate_suggestions, joined_at
+			)
+			SELECT $1, u, 'admin', true, true, true, true, CURRENT_TIMESTAMP
+			FROM unnest($2::uuid[]) AS u
+			ON CONFLICT (trip_id, user_id) DO UPDATE SET
+				role = 'admin', can_edit = true, can_delete = true,
+				can_invite = true, can_moderate_suggestions = true`,
+			transfer.EntityID, pq.Array([]string{transfer.FromUserID, transfer.ToUserID}))
+		if err != nil {
+			return fmt.Errorf("failed to update collaborators: %w", err)
+		}
+	}
+
+	err = audit.Record(ctx, tx, &audit.Entry{
+		ActorID:    actorID,
+		Action:     action,
+		EntityType: "trip",
+		EntityID:   transfer.EntityID,
+		Details: map[string]interface{}{
+			"transfer_id":  transfer.ID,
+			"from_user_id": transfer.FromUserID,
+			"to_user_id":   transfer.ToUserID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetCompletion retrieves a recorded activity completion
+func (r *PostgresRepository) GetCompletion(ctx context.Context, id string) (*ActivityCompletion, error) {
+	var completion ActivityCompletion
+	query := `
+		SELECT
+			id, trip_id, user_id, completed_at, duration_minutes,
+			difficulty_rating, overall_rating,
+			COALESCE(weather_conditions, '') AS weather_conditions,
+			COALESCE(trail_conditions, '') AS trail_conditions,
+			COALESCE(notes, '') AS notes,
+			photos, gpx_track, created_at
+		FROM activity_completions
+		WHERE id = $1`
+
+	err := r.db.GetContext(ctx, &completion, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCompletionNotFound
+		}
+		return nil, fmt.Errorf("failed to get completion: %w", err)
+	}
+
+	return &completion, nil
+}
+
+// CreateCompletion records an activity completion and counts it on the trip
+func (r *PostgresRepository) CreateCompletion(ctx context.Context, completion *ActivityCompletion) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO activity_completions (
+			trip_id, user_id, completed_at, duration_minutes,
+			difficulty_rating, overall_rating, weather_conditions,
+			trail_conditions, notes, photos, gpx_track
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		completion.TripID,
+		completion.UserID,
+		completion.CompletedAt,
+		completion.DurationMinutes,
+		completion.DifficultyRating,
+		completion.OverallRating,
+		completion.WeatherConditions,
+		completion.TrailConditions,
+		completion.Notes,
+		completion.Photos,
+		completion.GPXTrack,
+	).Scan(&completion.ID, &completion.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create completion: %w",
+			repoerr.Classify(err, nil, ErrCompletionExists, ErrTripNotFound))
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE trips SET completion_count = completion_count + 1
+		WHERE id = $1`, completion.TripID)
+	if err != nil {
+		return fmt.Errorf("failed to count completion: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ListCompletions retrieves a page of the trip's completions, most recent
+// first, without their tracks
+func (r *PostgresRepository) ListCompletions(ctx context.Context, tripID string, limit, offset int) ([]*ActivityCompletion, error) {
+	completions := []*ActivityCompletion{}
+	query := `
+		SELECT
+			id, trip_id, user_id, completed_at, duration_minutes,
+			difficulty_rating, overall_rating,
+			COALESCE(weather_conditions, '') AS weather_conditions,
+			COALESCE(trail_conditions, '') AS trail_conditions,
+			COALESCE(notes, '') AS notes,
+			photos, created_at
+		FROM activity_completions
+		WHERE trip_id = $1
+		ORDER BY completed_at DESC, id DESC
+		LIMIT $2 OFFSET $3`
+
+	err := r.db.SelectContext(ctx, &completions, query, tripID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list completions: %w", err)
+	}
+
+	return completions, nil
+}
+
+// recomputeRatingQuery sets a trip's average rating and rating count from
+// its ratings. The average is NULL once the last rating is removed.
+const recomputeRatingQuery = `
+	UPDATE trips t
+	SET average_rating = r.average, rating_count = r.count
+	FROM (
+		SELECT ROUND(AVG(overall_rating), 2) AS average, COUNT(*) AS count
+		FROM activity_ratings
+		WHERE trip_id = $1
+	) r
+	WHERE t.id = $1`
+
+// CreateRating records a user's rating of a trip and recomputes the trip's
+// average. Each user rates a trip once.
+func (r *PostgresRepository) CreateRating(ctx context.Context, rating *ActivityRating) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO activity_ratings (
+			trip_id, user_id, overall_rating, difficulty_accuracy,
+			description_accuracy, scenery_rating, review_text
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, helpful_count, created_at, updated_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		rating.TripID,
+		rating.UserID,
+		rating.OverallRating,
+		rating.DifficultyAccuracy,
+		rating.DescriptionAccuracy,
+		rating.SceneryRating,
+		rating.ReviewText,
+	).Scan(&rating.ID, &rating.HelpfulCount, &rating.CreatedAt, &rating.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create rating: %w",
+			repoerr.Classify(err, nil, ErrRatingExists, ErrTripNotFound))
+	}
+
+	if _, err := tx.ExecContext(ctx, recomputeRatingQuery, rating.TripID); err != nil {
+		return fmt.Errorf("failed to recompute trip rating: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ListRatings retrieves a page of the trip's ratings, newest first
+func (r *PostgresRepository) ListRatings(ctx context.Context, tripID string, limit, offset int) ([]*ActivityRating, error) {
+	ratings := []*ActivityRating{}
+	query := `
+		SELECT
+			id, trip_id, user_id, overall_rating, difficulty_accuracy,
+			description_accuracy, scenery_rating,
+			COALESCE(review_text, '') AS review_text,
+			helpful_count, created_at, updated_at
+		FROM activity_ratings
+		WHERE trip_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3`
+
+	err := r.db.SelectContext(ctx, &ratings, query, tripID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ratings: %w", err)
+	}
+
+	return ratings, nil
+}
+
+// DeleteRating removes a user's rating of a trip and recomputes the trip's
+// average
+func (r *PostgresRepository) DeleteRating(ctx context.Context, tripID, userID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM activity_ratings
+		WHERE trip_id = $1 AND user_id = $2`, tripID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete rating: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrRatingNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, recomputeRatingQuery, tripID); err != nil {
+		return fmt.Errorf("failed to recompute trip rating: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// conditionWeight ranks condition reports as ConditionWeight does
+const conditionWeight = `
+	CASE
+		WHEN review_status = 'disputed' THEN 0
+		WHEN review_status = 'confirmed' THEN 3
+		WHEN verified THEN 2
+		ELSE 1
+	END`
+
+const conditionColumns = `
+	id, trip_id, reported_by, condition_type, COALESCE(severity, '') AS severity,
+	description, ST_AsGeoJSON(location) AS location, photos,
+	valid_from, valid_until, COALESCE(verified, false) AS verified,
+	verified_by::text AS verified_by, review_status, review_note,
+	reviewed_by::text AS reviewed_by, reviewed_at, ` + conditionWeight + ` AS weight,
+	created_at`
+
+// CreateCondition records a condition report on a trip
+func (r *PostgresRepository) CreateCondition(ctx context.Context, condition *ActivityCondition) error {
+	query := `
+		INSERT INTO activity_conditions (
+			trip_id, reported_by, condition_type, severity, description,
+			location, photos, valid_until
+		) VALUES ($1, $2, $3, $4, $5, ST_SetSRID(ST_GeomFromGeoJSON($6), 4326)::geography, $7, $8)
+		RETURNING id, valid_from, created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		condition.TripID,
+		condition.ReportedBy,
+		condition.ConditionType,
+		condition.Severity,
+		condition.Description,
+		condition.Location,
+		condition.Photos,
+		condition.ValidUntil,
+	).Scan(&condition.ID, &condition.ValidFrom, &condition.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create condition: %w",
+			repoerr.Classify(err, nil, nil, ErrTripNotFound))
+	}
+
+	return nil
+}
+
+// ListConditions retrieves the condition reports on a trip, the most
+// vouched for first and then newest first; only those in effect now unless
+// expired ones are included
+func (r *PostgresRepository) ListConditions(ctx context.Context, tripID string, includeExpired bool) ([]*ActivityCondition, error) {
+	conditions := []*ActivityCondition{}
+	query := `SELECT ` + conditionColumns + `
+		FROM activity_conditions
+		WHERE trip_id = $1
+			AND ($2 OR (valid_from <= NOW() AND (valid_until IS NULL OR valid_until > NOW())))
+		ORDER BY weight DESC, created_at DESC, id DESC`
+
+	if err := r.db.SelectContext(ctx, &conditions, query, tripID, includeExpired); err != nil {
+		return nil, fmt.Errorf("failed to list conditions: %w", err)
+	}
+
+	return conditions, nil
+}
+
+// GetCondition retrieves a condition report
+func (r *PostgresRepository) GetCondition(ctx context.Context, id string) (*ActivityCondition, error) {
+	var condition ActivityCondition
+	query := `SELECT ` + conditionColumns + `
+		FROM activity_conditions
+		WHERE id = $1`
+
+	err := r.db.GetContext(ctx, &condition, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrConditionNotFound
+		}
+		return nil, fmt.Errorf("failed to get condition: %w", err)
+	}
+
+	return &condition, nil
+}
+
+// VerifyCondition marks a condition report as verified by the user
+func (r *PostgresRepository) VerifyCondition(ctx context.Context, id, userID string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE activity_conditions
+		SET verified = true, verified_by = $2
+		WHERE id = $1`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to verify condition: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrConditionNotFound
+	}
+
+	return nil
+}
+
+// ReviewCondition records a trusted verifier's confirmation or dispute of a
+// condition report. A confirmed report is verified by the verifier, a
+// disputed one no longer verified.
+func (r *PostgresRepository) ReviewCondition(ctx context.Context, id, userID, status, note string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE activity_conditions
+		SET review_status = $3, review_note = NULLIF($4, ''), reviewed_by = $2, reviewed_at = NOW(),
+			verified = ($3 = 'confirmed'),
+			verified_by = CASE WHEN $3 = 'confirmed' THEN $2::uuid END
+		WHERE id = $1`, id, userID, status, note)
+	if err != nil {
+		return fmt.Errorf("failed to review condition: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrConditionNotFound
+	}
+
+	return nil
+}
+
+const conditionVerifierColumns = `
+	id, user_id, ST_AsGeoJSON(region) AS region, activity_type,
+	granted_by::text AS granted_by, created_at`
+
+// CreateConditionVerifier grants a user verifier status
+func (r *PostgresRepository) CreateConditionVerifier(ctx context.Context, verifier *ConditionVerifier) error {
+	query := `
+		INSERT INTO condition_verifiers (user_id, region, activity_type, granted_by)
+		VALUES ($1, ST_SetSRID(ST_GeomFromGeoJSON($2), 4326)::geography, $3, $4)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		verifier.UserID,
+		verifier.Region,
+		verifier.ActivityType,
+		verifier.GrantedBy,
+	).Scan(&verifier.ID, &verifier.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create condition verifier: %w",
+			repoerr.Classify(err, nil, nil, ErrUserNotFound))
+	}
+
+	return nil
+}
+
+// ListConditionVerifiers retrieves the verifier grants, of one user or of
+// everyone when userID is empty, newest first
+func (r *PostgresRepository) ListConditionVerifiers(ctx context.Context, userID string) ([]*ConditionVerifier, error) {
+	verifiers := []*ConditionVerifier{}
+	query := `SELECT ` + conditionVerifierColumns + `
+		FROM condition_verifiers
+		WHERE $1 = '' OR user_id::text = $1
+		ORDER BY created_at DESC, id DESC`
+
+	if err := r.db.SelectContext(ctx, &verifiers, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list condition verifiers: %w", err)
+	}
+
+	return verifiers, nil
+}
+
+// DeleteConditionVerifier revokes a verifier grant
+func (r *PostgresRepository) DeleteConditionVerifier(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM condition_verifiers WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete condition verifier: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrVerifierNotFound
+	}
+
+	return nil
+}
+
+// IsConditionVerifier reports whether the user holds a verifier grant
+// covering a condition report: one for its trip's activity, if any, and for
+// a region its location, or else its trip's route, lies in, if any
+func (r *PostgresRepository) IsConditionVerifier(ctx context.Context, userID, conditionID string) (bool, error) {
+	var covered bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM activity_conditions c
+			JOIN trips t ON t.id = c.trip_id
+			JOIN condition_verifiers v ON v.user_id = $1
+			WHERE c.id = $2
+				AND (v.activity_type IS NULL OR v.activity_type = t.activity_type)
+				AND (v.region IS NULL OR ST_Intersects(v.region,
+					COALESCE(c.location, ST_SetSRID(ST_GeomFromGeoJSON(t.route_geojson::text), 4326)::geography)))
+		)`
+
+	if err := r.db.GetContext(ctx, &covered, query, userID, conditionID); err != nil {
+		return false, fmt.Errorf("failed to check condition verifier: %w", err)
+	}
+
+	return covered, nil
+}
+
+const conditionSubscriptionColumns = `
+	id, user_id, trip_id, ST_AsGeoJSON(region) AS region, severities, frequency,
+	next_send_at, last_sent_at, created_at`
+
+// CreateConditionSubscription saves a subscription to condition reports,
+// unless the user already has max of them
+func (r *PostgresRepository) CreateConditionSubscription(ctx context.Context, subscription *ConditionSubscription, max int) error {
+	query := `
+		INSERT INTO condition_subscriptions (user_id, trip_id, region, severities, frequency)
+		SELECT $1, $2, ST_SetSRID(ST_GeomFromGeoJSON($3), 4326)::geography, $4, $5
+		WHERE (SELECT COUNT(*) FROM condition_subscriptions WHERE user_id = $1) < $6
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		subscription.UserID,
+		subscription.TripID,
+		subscription.Region,
+		subscription.Severities,
+		subscription.Frequency,
+		max,
+	).Scan(&subscription.ID, &subscription.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTooManySubscriptions
+		}
+		return fmt.Errorf("failed to create condition subscription: %w",
+			repoerr.Classify(err, nil, nil, ErrTripNotFound))
+	}
+
+	return nil
+}
+
+// ListConditionSubscriptions retrieves the user's condition subscriptions,
+// newest first
+func (r *PostgresRepository) ListConditionSubscriptions(ctx context.Context, userID string) ([]*ConditionSubscription, error) {
+	subscriptions := []*ConditionSubscription{}
+	query := `SELECT ` + conditionSubscriptionColumns + `
+		FROM condition_subscriptions
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC`
+
+	if err := r.db.SelectContext(ctx, &subscriptions, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list condition subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// DeleteConditionSubscription deletes one of the user's condition
+// subscriptions
+func (r *PostgresRepository) DeleteConditionSubscription(ctx context.Context, userID, id string) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM condition_subscriptions
+		WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete condition subscription: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrSubscriptionNotFound
+	}
+
+	return nil
+}
+
+// QueueConditionAlerts records an alert of a verified condition report still
+// in effect, and not disputed, for each subscription it matches: those to its trip by users who
+// can view it, and those to a region its location, or else its trip's route,
+// lies in when the trip is public. Nobody is alerted of their own reports.
+// Subscriptions with no send due, or whose send is an hour overdue and so
+// was lost, are given one after their frequency's delay.
+func (r *PostgresRepository) QueueConditionAlerts(ctx context.Context, conditionID string) ([]*ConditionSubscription, error) {
+	due := []*ConditionSubscription{}
+	query := `
+		WITH matched AS (
+			INSERT INTO condition_alerts (subscription_id, condition_id)
+			SELECT s.id, c.id
+			FROM activity_conditions c
+			JOIN trips t ON t.id = c.trip_id AND t.deleted_at IS NULL
+			JOIN condition_subscriptions s ON c.severity = ANY(s.severities)
+			WHERE c.id = $1 AND c.verified AND c.review_status IS DISTINCT FROM 'disputed'
+				AND (c.valid_until IS NULL OR c.valid_until > NOW())
+				AND s.user_id <> c.reported_by
+				AND (
+					(s.trip_id = t.id AND (t.privacy = 'public' OR t.owner_id = s.user_id
+						OR EXISTS (SELECT 1 FROM trip_collaborators tc WHERE tc.trip_id = t.id AND tc.user_id = s.user_id)))
+					OR (s.region IS NOT NULL AND t.privacy = 'public' AND ST_Intersects(s.region,
+						COALESCE(c.location, ST_SetSRID(ST_GeomFromGeoJSON(t.route_geojson::text), 4326)::geography)))
+				)
+			ON CONFLICT DO NOTHING
+			RETURNING subscription_id
+		)
+		UPDATE condition_subscriptions s
+		SET next_send_at = NOW() + CASE s.frequency
+			WHEN 'hourly' THEN INTERVAL '1 hour'
+			WHEN 'daily' THEN INTERVAL '1 day'
+			ELSE INTERVAL '0'
+		END
+		FROM matched
+		WHERE s.id = matched.subscription_id
+			AND (s.next_send_at IS NULL OR s.next_send_at < NOW() - INTERVAL '1 hour')
+		RETURNING s.id, s.user_id, s.trip_id, ST_AsGeoJSON(s.region) AS region, s.severities,
+			s.frequency, s.next_send_at, s.last_sent_at, s.created_at`
+
+	if err := r.db.SelectContext(ctx, &due, query, conditionID); err != nil {
+		return nil, fmt.Errorf("failed to queue condition alerts: %w", err)
+	}
+
+	return due, nil
+}
+
+// TakeConditionAlerts marks the subscription's waiting alerts sent and
+// returns them, oldest report first. Its due send is cleared in the same
+// transaction, so alerts queued meanwhile schedule a new one.
+func (r *PostgresRepository) TakeConditionAlerts(ctx context.Context, subscriptionID string) (*ConditionSubscription, []*ConditionAlert, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var subscription ConditionSubscription
+	err = tx.GetContext(ctx, &subscription, `
+		UPDATE condition_subscriptions
+		SET next_send_at = NULL, last_sent_at = NOW()
+		WHERE id = $1
+		RETURNING `+conditionSubscriptionColumns, subscriptionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrSubscriptionNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to get condition subscription: %w", err)
+	}
+
+	alerts := []*ConditionAlert{}
+	err = tx.SelectContext(ctx, &alerts, `
+		WITH taken AS (
+			UPDATE condition_alerts
+			SET sent_at = NOW()
+			WHERE subscription_id = $1 AND sent_at IS NULL
+			RETURNING condition_id
+		)
+		SELECT c.id AS condition_id, c.trip_id, t.title AS trip_title, c.condition_type,
+			COALESCE(c.severity, '') AS severity, c.description, c.created_at AS reported_at
+		FROM taken
+		JOIN activity_conditions c ON c.id = taken.condition_id
+		JOIN trips t ON t.id = c.trip_id
+		ORDER BY c.created_at, c.id`, subscriptionID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to take condition alerts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &subscription, alerts, nil
+}
+
+const datePollColumns = `
+	id, trip_id, title, status, COALESCE(created_by::text, '') AS created_by,
+	chosen_option_id, finalized_at, created_at, updated_at`
+
+// CreateDatePoll records a date poll with its options
+func (r *PostgresRepository) CreateDatePoll(ctx context.Context, poll *DatePoll) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO trip_date_polls (trip_id, title, status, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		poll.TripID,
+		poll.Title,
+		poll.Status,
+		poll.CreatedBy,
+	).Scan(&poll.ID, &poll.CreatedAt, &poll.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create date poll: %w",
+			repoerr.Classify(err, nil, nil, ErrTripNotFound))
+	}
+
+	for i, option := range poll.Options {
+		option.PollID = poll.ID
+		option.Position = i
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO trip_date_poll_options (poll_id, start_date, end_date, position)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id`,
+			option.PollID, option.StartDate, option.EndDate, option.Position,
+		).Scan(&option.ID)
+		if err != nil {
+			return fmt.Errorf("failed to create date poll option: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListDatePolls retrieves the date polls of a trip with their options and
+// votes, newest first
+func (r *PostgresRepository) ListDatePolls(ctx context.Context, tripID string) ([]*DatePoll, error) {
+	polls := []*DatePoll{}
+	query := `SELECT ` + datePollColumns + `
+		FROM trip_date_polls
+		WHERE trip_id = $1
+		ORDER BY created_at DESC, id DESC`
+
+	if err := r.db.SelectContext(ctx, &polls, query, tripID); err != nil {
+		return nil, fmt.Errorf("failed to list date polls: %w", err)
+	}
+
+	if err := r.loadDatePollOptions(ctx, polls); err != nil {
+		return nil, err
+	}
+
+	return polls, nil
+}
+
+// GetDatePoll retrieves a date poll with its options and votes
+func (r *PostgresRepository) GetDatePoll(ctx context.Context, id string) (*DatePoll, error) {
+	var poll DatePoll
+	query := `SELECT ` + datePollColumns + `
+		FROM trip_date_polls
+		WHERE id = $1`
+
+	err := r.db.GetContext(ctx, &poll, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDatePollNotFound
+		}
+		return nil, fmt.Errorf("failed to get date poll: %w", err)
+	}
+
+	if err := r.loadDatePollOptions(ctx, []*DatePoll{&poll}); err != nil {
+		return nil, err
+	}
+
+	return &poll, nil
+}
+
+// loadDatePollOptions attaches their options and votes to the polls
+func (r *PostgresRepository) loadDatePollOptions(ctx context.Context, polls []*DatePoll) error {
+	if len(polls) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(polls))
+	byID := make(map[string]*DatePoll, len(polls))
+	for i, poll := range polls {
+		ids[i] = poll.ID
+		byID[poll.ID] = poll
+		poll.Options = []*DatePollOption{}
+	}
+
+	var options []*DatePollOption
+	err := r.db.SelectContext(ctx, &options, `
+		SELECT id, poll_id, start_date, end_date, position
+		FROM trip_date_poll_options
+		WHERE poll_id = ANY($1)
+		ORDER BY position, id`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to get date poll options: %w", err)
+	}
+
+	var votes []DatePollVote
+	err = r.db.SelectContext(ctx, &votes, `
+		SELECT v.option_id, v.user_id, v.availability, v.updated_at
+		FROM trip_date_poll_votes v
+		JOIN trip_date_poll_options o ON v.option_id = o.id
+		WHERE o.poll_id = ANY($1)
+		ORDER BY v.updated_at`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to get date poll votes: %w", err)
+	}
+
+	optionsByID := make(map[string]*DatePollOption, len(options))
+	for _, option := range options {
+		option.Votes = []DatePollVote{}
+		optionsByID[option.ID] = option
+		if poll, ok := byID[option.PollID]; ok {
+			poll.Options = append(poll.Options, option)
+		}
+	}
+	for _, vote := range votes {
+		if option, ok := optionsByID[vote.OptionID]; ok {
+			option.Votes = append(option.Votes, vote)
+		}
+	}
+
+	return nil
+}
+
+// SetAvailability records a member's answers to a poll's options, replacing
+// any earlier ones
+func (r *PostgresRepository) SetAvailability(ctx context.Context, userID string, votes []DatePollVote) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, vote := range votes {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO trip_date_poll_votes (option_id, user_id, availability)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (option_id, user_id)
+			DO UPDATE SET availability = EXCLUDED.availability, updated_at = CURRENT_TIMESTAMP`,
+			vote.OptionID, userID, vote.Availability)
+		if err != nil {
+			return fmt.Errorf("failed to set availability: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FinalizeDatePoll closes an open poll on one of its options and sets the
+// trip's dates to it. The status is checked in the update itself so that a
+// poll is only finalized once.
+func (r *PostgresRepository) FinalizeDatePoll(ctx context.Context, pollID, optionID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE trip_date_polls
+		SET status = $3, chosen_option_id = $2, finalized_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $4`,
+		pollID, optionID, DatePollFinalized, DatePollOpen)
+	if err != nil {
+		return fmt.Errorf("failed to finalize date poll: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrDatePollClosed
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE trips t
+		SET start_date = o.start_date, end_date = o.end_date, updated_at = CURRENT_TIMESTAMP
+		FROM trip_date_polls p
+		JOIN trip_date_poll_options o ON o.poll_id = p.id
+		WHERE p.id = $1 AND o.id = $2 AND t.id = p.trip_id`,
+		pollID, optionID)
+	if err != nil {
+		return fmt.Errorf("failed to set trip dates: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// DeleteDatePoll removes a date poll
+func (r *PostgresRepository) DeleteDatePoll(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM trip_date_polls WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete date poll: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrDatePollNotFound
+	}
+
+	return nil
+}
+
+const participantColumns = `
+	tp.trip_id, tp.user_id, tp.status, tp.guests,
+	COALESCE(tp.invited_by::text, '') AS invited_by, tp.responded_at,
+	tp.waitlisted_at, tp.created_at, tp.updated_at,
+	u.username, COALESCE(u.display_name, '') AS display_name,
+	COALESCE(u.avatar_url, '') AS avatar_url`
+
+// GetParticipants retrieves the trip's capacity and everyone invited on it
+func (r *PostgresRepository) GetParticipants(ctx context.Context, tripID string) (*ParticipantRoster, error) {
+	return r.getParticipants(ctx, r.db, tripID, "")
+}
+
+// UpdateParticipants applies a change to the trip's roster and saves it. The
+// trip row stays locked until the change is saved, so that concurrent
+// answers cannot seat more people than the capacity between them.
+func (r *PostgresRepository) UpdateParticipants(ctx context.Context, tripID string, update func(*ParticipantRoster) error) (*ParticipantRoster, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	roster, err := r.getParticipants(ctx, tx, tripID, "FOR UPDATE")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := update(roster); err != nil {
+		return nil, err
+	}
+
+	if roster.capacityChanged {
+		if _, err := tx.ExecContext(ctx, `UPDATE trips SET capacity = $2 WHERE id = $1`, tripID, roster.Capacity); err != nil {
+			return nil, fmt.Errorf("failed to set capacity: %w", err)
+		}
+	}
+
+	for _, userID := range roster.removed {
+		_, err := tx.ExecContext(ctx, `DELETE FROM trip_participants WHERE trip_id = $1 AND user_id = $2`, tripID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove participant: %w", err)
+		}
+	}
+
+	for _, p := range roster.Participants {
+		if !roster.changed[p.UserID] {
+			continue
+		}
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO trip_participants
+				(trip_id, user_id, status, guests, invited_by, responded_at, waitlisted_at)
+			VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6, $7)
+			ON CONFLICT (trip_id, user_id) DO UPDATE SET
+				status = EXCLUDED.status,
+				guests = EXCLUDED.guests,
+				responded_at = EXCLUDED.responded_at,
+				waitlisted_at = EXCLUDED.waitlisted_at,
+				updated_at = CURRENT_TIMESTAMP
+			RETURNING created_at, updated_at`,
+			tripID, p.UserID, p.Status, p.Guests, p.InvitedBy, p.RespondedAt, p.WaitlistedAt,
+		).Scan(&p.CreatedAt, &p.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save participant: %w",
+				repoerr.Classify(err, nil, nil, ErrUserNotFound))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return roster, nil
+}
+
+// getParticipants reads the roster, locking the trip row when lock is set
+func (r *PostgresRepository) getParticipants(ctx context.Context, q sqlx.QueryerContext, tripID, lock string) (*ParticipantRoster, error) {
+	var capacity *int
+	err := sqlx.GetContext(ctx, q, &capacity, `
+		SELECT t.capacity FROM trips t
+		WHERE t.id = $1 AND t.deleted_at IS NULL `+lock, tripID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTripNotFound
+		}
+		return nil, fmt.Errorf("failed to get trip capacity: %w", err)
+	}
+
+	var participants []*TripParticipant
+	err = sqlx.SelectContext(ctx, q, &participants, `
+		SELECT `+participantColumns+`
+		FROM trip_participants tp
+		JOIN users u ON tp.user_id = u.id
+		WHERE tp.trip_id = $1`, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get participants: %w", err)
+	}
+
+	return newParticipantRoster(tripID, capacity, participants), nil
+}
+
+// GetReadinessState retrieves the confirmed readiness checks of a trip and
+// its latest weather report
+func (r *PostgresRepository) GetReadinessState(ctx context.Context, tripID string) (*ReadinessState, error) {
+	var confirmations []struct {
+		CheckName string    `db:"check_name"`
+		CheckedAt time.Time `db:"checked_at"`
+	}
+	query := `
+		SELECT check_name, checked_at
+		FROM trip_readiness_checks
+		WHERE trip_id = $1`
+
+	if err := r.db.SelectContext(ctx, &confirmations, query, tripID); err != nil {
+		return nil, fmt.Errorf("failed to get readiness checks: %w", err)
+	}
+
+	state := &ReadinessState{Confirmed: make(map[string]time.Time, len(confirmations))}
+	for _, confirmation := range confirmations {
+		state.Confirmed[confirmation.CheckName] = confirmation.CheckedAt
+	}
+
+	query = `
+		SELECT MAX(created_at)
+		FROM activity_conditions
+		WHERE trip_id = $1 AND condition_type = 'weather'`
+
+	if err := r.db.GetContext(ctx, &state.WeatherReportAt, query, tripID); err != nil {
+		return nil, fmt.Errorf("failed to get weather reports: %w", err)
+	}
+
+	return state, nil
+}
+
+// ConfirmReadinessCheck records that a member confirmed a manual check,
+// refreshing the time of an earlier confirmation
+func (r *PostgresRepository) ConfirmReadinessCheck(ctx context.Context, tripID, check, userID string) error {
+	query := `
+		INSERT INTO trip_readiness_checks (trip_id, check_name, checked_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (trip_id, check_name) DO UPDATE SET
+			checked_by = EXCLUDED.checked_by,
+			checked_at = CURRENT_TIMESTAMP`
+
+	if _, err := r.db.ExecContext(ctx, query, tripID, check, userID); err != nil {
+		return fmt.Errorf("failed to confirm readiness check: %w", err)
+	}
+
+	return nil
+}
+
+// ClearReadinessCheck withdraws the confirmation of a manual check
+func (r *PostgresRepository) ClearReadinessCheck(ctx context.Context, tripID, check string) error {
+	query := `DELETE FROM trip_readiness_checks WHERE trip_id = $1 AND check_name = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, tripID, check); err != nil {
+		return fmt.Errorf("failed to clear readiness check: %w", err)
+	}
+
+	return nil
+}
+
+// CreateLayer records a custom map layer of a trip
+func (r *PostgresRepository) CreateLayer(ctx context.Context, layer *TripLayer) error {
+	query := `
+		INSERT INTO trip_layers (id, trip_id, name, format, storage_path, size_bytes, feature_count, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		layer.ID,
+		layer.TripID,
+		layer.Name,
+		layer.Format,
+		layer.StoragePath,
+		layer.SizeBytes,
+		layer.FeatureCount,
+		layer.CreatedBy,
+	).Scan(&layer.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create layer: %w", err)
+	}
+
+	return nil
+}
+
+// ListLayers retrieves the custom map layers of a trip, oldest first
+func (r *PostgresRepository) ListLayers(ctx context.Context, tripID string) ([]*TripLayer, error) {
+	layers := []*TripLayer{}
+	query := `
+		SELECT id, trip_id, name, format, storage_path, size_bytes, feature_count,
+			COALESCE(created_by::text, '') AS created_by, created_at
+		FROM trip_layers
+		WHERE trip_id = $1
+		ORDER BY created_at, id`
+
+	if err := r.db.SelectContext(ctx, &layers, query, tripID); err != nil {
+		return nil, fmt.Errorf("failed to list layers: %w", err)
+	}
+
+	return layers, nil
+}
+
+// GetLayer retrieves a custom map layer
+func (r *PostgresRepository) GetLayer(ctx context.Context, id string) (*TripLayer, error) {
+	var layer TripLayer
+	query := `
+		SELECT id, trip_id, name, format, storage_path, size_bytes, feature_count,
+			COALESCE(created_by::text, '') AS created_by, created_at
+		FROM trip_layers
+		WHERE id = $1`
+
+	err := r.db.GetContext(ctx, &layer, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrLayerNotFound
+		}
+		return nil, fmt.Errorf("failed to get layer: %w", err)
+	}
+
+	return &layer, nil
+}
+
+// DeleteLayer removes a custom map layer
+func (r *PostgresRepository) DeleteLayer(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM trip_layers WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete layer: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrLayerNotFound
+	}
+
+	return nil
+}
+
+const offlinePackColumns = `
+	trip_id, status, storage_path, size_bytes, error, trip_updated_at,
+	requested_by::text AS requested_by, requested_at, completed_at`
+
+// RequestOfflinePack marks the trip's offline pack as pending, unless it
+// has been pending since after staleBefore. It reports whether the pack
+// needs building.
+func (r *PostgresRepository) RequestOfflinePack(ctx context.Context, tripID, userID string, staleBefore time.Time) (*OfflinePack, bool, error) {
+	var pack OfflinePack
+	query := `
+		INSERT INTO trip_offline_packs (trip_id, requested_by)
+		VALUES ($1, NULLIF($2, '')::uuid)
+		ON CONFLICT (trip_id) DO UPDATE
+		SET status = 'pending', error = NULL, completed_at = NULL,
+			requested_by = EXCLUDED.requested_by, requested_at = CURRENT_TIMESTAMP
+		WHERE trip_offline_packs.status <> 'pending' OR trip_offline_packs.requested_at < $3
+		RETURNING` + offlinePackColumns
+
+	err := r.db.GetContext(ctx, &pack, query, tripID, userID, staleBefore)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Already being built
+		existing, err := r.GetOfflinePack(ctx, tripID)
+		return existing, false, err
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to request offline pack: %w", err)
+	}
+
+	return &pack, true, nil
+}
+
+// GetOfflinePack retrieves the offline pack of a trip
+func (r *PostgresRepository) GetOfflinePack(ctx context.Context, tripID string) (*OfflinePack, error) {
+	var pack OfflinePack
+	query := `SELECT` + offlinePackColumns + ` FROM trip_offline_packs WHERE trip_id = $1`
+
+	err := r.db.GetContext(ctx, &pack, query, tripID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOfflinePackNotFound
+		}
+		return nil, fmt.Errorf("failed to get offline pack: %w", err)
+	}
+
+	return &pack, nil
+}
+
+// CompleteOfflinePack saves the file of a built pack. It reports false when
+// the pack has been requested again since the build started.
+func (r *PostgresRepository) CompleteOfflinePack(ctx context.Context, pack *OfflinePack) (bool, error) {
+	query := `
+		UPDATE trip_offline_packs
+		SET status = 'completed', storage_path = $3, size_bytes = $4, trip_updated_at = $5,
+			error = NULL, completed_at = CURRENT_TIMESTAMP
+		WHERE trip_id = $1 AND requested_at = $2`
+
+	result, err := r.db.ExecContext(ctx, query, pack.TripID, pack.RequestedAt, pack.StoragePath, pack.SizeBytes, pack.TripUpdatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to complete offline pack: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// FailOfflinePack records why the pack requested at requestedAt failed
+func (r *PostgresRepository) FailOfflinePack(ctx context.Context, tripID string, requestedAt time.Time, reason string) error {
+	query := `
+		UPDATE trip_offline_packs
+		SET status = 'failed', error = $3, completed_at = CURRENT_TIMESTAMP
+		WHERE trip_id = $1 AND requested_at = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, tripID, requestedAt, reason); err != nil {
+		return fmt.Errorf("failed to record offline pack failure: %w", err)
+	}
+
+	return nil
+}
+
+// ListMediaFiles retrieves where uploaded media files are stored
+func (r *PostgresRepository) ListMediaFiles(ctx context.Context, mediaIDs []string) ([]*OfflinePackMedia, error) {
+	files := []*OfflinePackMedia{}
+	query := `
+		SELECT id, storage_path, cdn_url, mime_type
+		FROM media
+		WHERE id = ANY($1::uuid[])`
+
+	if err := r.db.SelectContext(ctx, &files, query, pq.Array(mediaIDs)); err != nil {
+		return nil, fmt.Errorf("failed to list media files: %w", err)
+	}
+
+	return files, nil
+}
+
+const annotationColumns = `
+	id, trip_id, kind, geometry, label, style,
+	COALESCE(created_by::text, '') AS created_by,
+	COALESCE(updated_by::text, '') AS updated_by,
+	created_at, updated_at`
+
+// CreateAnnotation records a map annotation of a trip
+func (r *PostgresRepository) CreateAnnotation(ctx context.Context, annotation *TripAnnotation) error {
+	query := `
+		INSERT INTO trip_annotations (id, trip_id, kind, geometry, label, style, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		RETURNING created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		annotation.ID,
+		annotation.TripID,
+		annotation.Kind,
+		annotation.Geometry,
+		annotation.Label,
+		annotation.Style,
+		annotation.CreatedBy,
+	).Scan(&annotation.CreatedAt, &annotation.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create annotation: %w", err)
+	}
+
+	return nil
+}
+
+// ListAnnotations retrieves the map annotations of a trip, oldest first
+func (r *PostgresRepository) ListAnnotations(ctx context.Context, tripID string) ([]*TripAnnotation, error) {
+	annotations := []*TripAnnotation{}
+	query := `SELECT ` + annotationColumns + `
+		FROM trip_annotations
+		WHERE trip_id = $1
+		ORDER BY created_at, id`
+
+	if err := r.db.SelectContext(ctx, &annotations, query, tripID); err != nil {
+		return nil, fmt.Errorf("failed to list annotations: %w", err)
+	}
+
+	return annotations, nil
+}
+
+// GetAnnotation retrieves a map annotation
+func (r *PostgresRepository) GetAnnotation(ctx context.Context, id string) (*TripAnnotation, error) {
+	var annotation TripAnnotation
+	query := `SELECT ` + annotationColumns + `
+		FROM trip_annotations
+		WHERE id = $1`
+
+	err := r.db.GetContext(ctx, &annotation, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAnnotationNotFound
+		}
+		return nil, fmt.Errorf("failed to get annotation: %w", err)
+	}
+
+	return &annotation, nil
+}
+
+// UpdateAnnotation saves the geometry, label and style of an annotation
+func (r *PostgresRepository) UpdateAnnotation(ctx context.Context, annotation *TripAnnotation) error {
+	query := `
+		UPDATE trip_annotations
+		SET geometry = $2, label = $3, style = $4, updated_by = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		annotation.ID,
+		annotation.Geometry,
+		annotation.Label,
+		annotation.Style,
+		annotation.UpdatedBy,
+	).Scan(&annotation.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAnnotationNotFound
+		}
+		return fmt.Errorf("failed to update annotation: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteAnnotation removes a map annotation
+func (r *PostgresRepository) DeleteAnnotation(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM trip_annotations WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete annotation: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrAnnotationNotFound
+	}
+
+	return nil
+}
+
+const routeVariantColumns = `
+	id, trip_id, name, description, route_geojson,
+	distance_km, elevation_gain_m, duration_hours, is_primary,
+	COALESCE(created_by::text, '') AS created_by, created_at, updated_at`
+
+// CreateRouteVariant records a route variant of a trip
+func (r *PostgresRepository) CreateRouteVariant(ctx context.Context, variant *TripRouteVariant) error {
+	query := `
+		INSERT INTO trip_route_variants (
+			id, trip_id, name, description, route_geojson,
+			distance_km, elevation_gain_m, duration_hours, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		variant.ID,
+		variant.TripID,
+		variant.Name,
+		variant.Description,
+		variant.RouteGeoJSON,
+		variant.DistanceKm,
+		variant.ElevationGainM,
+		variant.DurationHours,
+		variant.CreatedBy,
+	).Scan(&variant.CreatedAt, &variant.UpdatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrVariantNameTaken
+		}
+		return fmt.Errorf("failed to create route variant: %w", err)
+	}
+
+	return nil
+}
+
+// ListRouteVariants retrieves the route variants of a trip, primary first
+func (r *PostgresRepository) ListRouteVariants(ctx context.Context, tripID string) ([]*TripRouteVariant, error) {
+	variants := []*TripRouteVariant{}
+	query := `SELECT ` + routeVariantColumns + `
+		FROM trip_route_variants
+		WHERE trip_id = $1
+		ORDER BY is_primary DESC, created_at, id`
+
+	if err := r.db.SelectContext(ctx, &variants, query, tripID); err != nil {
+		return nil, fmt.Errorf("failed to list route variants: %w", err)
+	}
+
+	return variants, nil
+}
+
+// GetRouteVariant retrieves a route variant
+func (r *PostgresRepository) GetRouteVariant(ctx context.Context, id string) (*TripRouteVariant, error) {
+	var variant TripRouteVariant
+	query := `SELECT ` + routeVariantColumns + `
+		FROM trip_route_variants
+		WHERE id = $1`
+
+	err := r.db.GetContext(ctx, &variant, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVariantNotFound
+		}
+		return nil, fmt.Errorf("failed to get route variant: %w", err)
+	}
+
+	return &variant, nil
+}
+
+// UpdateRouteVariant saves the name, description, route and stats of a
+// route variant
+func (r *PostgresRepository) UpdateRouteVariant(ctx context.Context, variant *TripRouteVariant) error {
+	query := `
+		UPDATE trip_route_variants
+		SET name = $2, description = $3, route_geojson = $4,
+			distance_km = $5, elevation_gain_m = $6, duration_hours = $7,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		variant.ID,
+		variant.Name,
+		variant.Description,
+		variant.RouteGeoJSON,
+		variant.DistanceKm,
+		variant.ElevationGainM,
+		variant.DurationHours,
+	).Scan(&variant.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrVariantNotFound
+		}
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrVariantNameTaken
+		}
+		return fmt.Errorf("failed to update route variant: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteRouteVariant removes a route variant
+func (r *PostgresRepository) DeleteRouteVariant(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM trip_route_variants WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete route variant: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrVariantNotFound
+	}
+
+	return nil
+}
+
+// SetPrimaryRouteVariant makes a variant the trip's primary one and copies
+// its route and stats onto the trip
+func (r *PostgresRepository) SetPrimaryRouteVariant(ctx context.Context, tripID, variantID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Cleared first, as the one primary per trip is checked row by row
+	_, err = tx.ExecContext(ctx, `
+		UPDATE trip_route_variants SET is_primary = false
+		WHERE trip_id = $1 AND is_primary AND id <> $2`, tripID, variantID)
+	if err != nil {
+		return fmt.Errorf("failed to clear primary route variant: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE trip_route_variants SET is_primary = true
+		WHERE id = $1 AND trip_id = $2`, variantID, tripID)
+	if err != nil {
+		return fmt.Errorf("failed to set primary route variant: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrVariantNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE trips t
+		SET route_geojson = v.route_geojson,
+			distance_km = v.distance_km,
+			elevation_gain_m = v.elevation_gain_m,
+			duration_hours = v.duration_hours,
+			updated_at = CURRENT_TIMESTAMP
+		FROM trip_route_variants v
+		WHERE v.id = $1 AND t.id = v.trip_id`, variantID)
+	if err != nil {
+		return fmt.Errorf("failed to copy primary route onto trip: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetUserPace sums the user's completions of an activity type that have a
+// duration and a trip distance, with climbing counted by Naismith's rule
+func (r *PostgresRepository) GetUserPace(ctx context.Context, userID, activityType string) (*UserPace, error) {
+	var pace UserPace
+	query := `
+		SELECT
+			COUNT(*) AS completions,
+			COALESCE(SUM(t.distance_km + COALESCE(GREATEST(t.elevation_gain_m, 0), 0) * $3), 0) AS effort_km,
+			COALESCE(SUM(c.duration_minutes), 0) / 60.0 AS hours
+		FROM activity_completions c
+		JOIN trips t ON t.id = c.trip_id
+		WHERE c.user_id = $1
+			AND t.activity_type = $2
+			AND c.duration_minutes > 0
+			AND t.distance_km > 0`
+
+	err := r.db.GetContext(ctx, &pace, query, userID, activityType, naismithClimbKmPM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user pace: %w", err)
+	}
+
+	return &pace, nil
+}
+
+// ListVisitTimes retrieves when the trip was completed and when conditions
+// were reported on it since the given time. Each is taken as a visit.
+func (r *PostgresRepository) ListVisitTimes(ctx context.Context, tripID string, since time.Time) ([]time.Time, error) {
+	query := `
+		SELECT completed_at FROM activity_completions
+		WHERE trip_id = $1 AND completed_at >= $2
+		UNION ALL
+		SELECT created_at FROM activity_conditions
+		WHERE trip_id = $1 AND created_at >= $2`
+
+	visits := []time.Time{}
+	if err := r.db.SelectContext(ctx, &visits, query, tripID, since); err != nil {
+		return nil, fmt.Errorf("failed to list visit times: %w", err)
+	}
+
+	return visits, nil
+}
+
+// PublishScheduled makes a trip public if the given job is still the one
+// scheduled to publish it. It reports whether the trip was published.
+func (r *PostgresRepository) PublishScheduled(ctx context.Context, tripID, jobID string) (bool, error) {
+	query := `
+		UPDATE trips
+		SET privacy = 'public',
+			publish_at = NULL, publish_job_id = NULL,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND publish_job_id = $2 AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, tripID, jobID)
+	if err != nil {
+		return false, fmt.Errorf("failed to publish trip: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ListScheduledPublications lists the trips that are not public yet but
+// have a publication scheduled, soonest first
+func (r *PostgresRepository) ListScheduledPublications(ctx context.Context) ([]*ScheduledPublication, error) {
+	query := `
+		SELECT id, publish_at, publish_job_id
+		FROM trips
+		WHERE publish_at IS NOT NULL AND privacy <> 'public' AND deleted_at IS NULL
+		ORDER BY publish_at`
+
+	var publications []*ScheduledPublication
+	if err := r.db.SelectContext(ctx, &publications, query); err != nil {
+		return nil, fmt.Errorf("failed to list scheduled publications: %w", err)
+	}
+
+	return publications, nil
+}
+
+// SetSummary saves the trip's generated summary without touching its
+// updated time
+func (r *PostgresRepository) SetSummary(ctx context.Context, tripID, summary string) error {
+	query := `UPDATE trips SET summary = $2 WHERE id = $1 AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, tripID, summary)
+	if err != nil {
+		return fmt.Errorf("failed to save trip summary: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("trip %s: %w", tripID, ErrTripNotFound)
+	}
+
+	return nil
+}
+
+// SetSuggestedTags saves the tags suggested for the trip and those its owner
+// dismissed. Like its summary, they are not an edit, so the trip's updated
+// time is kept.
+func (r *PostgresRepository) SetSuggestedTags(ctx context.Context, tripID string, suggested, dismissed []string) error {
+	query := `UPDATE trips SET suggested_tags = $2, dismissed_tags = $3 WHERE id = $1 AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, tripID, pq.Array(suggested), pq.Array(dismissed))
+	if err != nil {
+		return fmt.Errorf("failed to save suggested tags: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("trip %s: %w", tripID, ErrTripNotFound)
+	}
+
+	return nil
+}
+
+// SaveDraft creates or replaces a user's draft, keeping the base values
+// already recorded for fields the draft touched before
+func (r *PostgresRepository) SaveDraft(ctx context.Context, draft *TripDraft) error {
+	query := `
+		INSERT INTO trip_drafts (trip_id, user_id, data, base)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (trip_id, user_id) DO UPDATE SET
+			data = EXCLUDED.data,
+			base = EXCLUDED.base || trip_drafts.base,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		draft.TripID,
+		draft.UserID,
+		draft.Data,
+		draft.Base,
+	).Scan(&draft.CreatedAt, &draft.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save draft: %w", err)
+	}
+
+	return nil
+}
+
+// GetDraft retrieves a user's draft of a trip
+func (r *PostgresRepository) GetDraft(ctx context.Context, tripID, userID string) (*TripDraft, error) {
+	var draft TripDraft
+	query := `
+		SELECT trip_id, user_id, data, base, created_at, updated_at
+		FROM trip_drafts
+		WHERE trip_id = $1 AND user_id = $2`
+
+	err := r.db.GetContext(ctx, &draft, query, tripID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDraftNotFound
+		}
+		return nil, fmt.Errorf("failed to get draft: %w", err)
+	}
+
+	return &draft, nil
+}
+
+// DeleteDraft removes a user's draft of a trip
+func (r *PostgresRepository) DeleteDraft(ctx context.Context, tripID, userID string) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM trip_drafts
+		WHERE trip_id = $1 AND user_id = $2`, tripID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete draft: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrDraftNotFound
+	}
+
+	return nil
+}
+
+// CreateShareLink stores a share link, filling in its ID, use count and
+// creation time
+func (r *PostgresRepository) CreateShareLink(ctx context.Context, link *ActivityShareLink) error {
+	query := `
+		INSERT INTO activity_share_links (trip_id, created_by, share_token, permissions, max_uses, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, use_count, created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		link.TripID,
+		link.CreatedBy,
+		link.ShareToken,
+		link.Permissions,
+		link.MaxUses,
+		link.ExpiresAt,
+	).Scan(&link.ID, &link.UseCount, &link.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create share link: %w", repoerr.Classify(err, nil, nil, ErrTripNotFound))
+	}
+
+	return nil
+}
+
+// RedeemShareLink counts a use of the share link with the given token,
+// provided it has not expired or run out of uses. Checking and counting in
+// one statement keeps concurrent redemptions within max_uses.
+func (r *PostgresRepository) RedeemShareLink(ctx context.Context, token string) (*ActivityShareLink, error) {
+	var link ActivityShareLink
+	query := `
+		UPDATE activity_share_links
+		SET use_count = COALESCE(use_count, 0) + 1, last_used_at = CURRENT_TIMESTAMP
+		WHERE share_token = $1
+			AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+			AND (max_uses IS NULL OR COALESCE(use_count, 0) < max_uses)
+		RETURNING id, trip_id, created_by, share_token, COALESCE(permissions, 'view') AS permissions,
+			max_uses, use_count, expires_at, created_at, last_used_at`
+
+	err := r.db.GetContext(ctx, &link, query, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrShareLinkNotFound
+		}
+		return nil, fmt.Errorf("failed to redeem share link: %w", err)
+	}
+
+	return &link, nil
+}
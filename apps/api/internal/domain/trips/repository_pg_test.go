@@ -3,446 +3,1081 @@ package trips
 import (
 	"context"
 	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
-	"github.com/google/uuid"
+	"github.com/Oferzz/newMap/apps/api/internal/audit"
+	"github.com/Oferzz/newMap/apps/api/pkg/pagination"
+	"github.com/Oferzz/newMap/apps/api/pkg/repoerr"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func TestPostgreSQLRepository_Create(t *testing.T) {
+var _ Repository = (*PostgresRepository)(nil)
+
+func newMockRepository(t *testing.T) (*PostgresRepository, sqlmock.Sqlmock) {
+	t.Helper()
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
-	defer db.Close()
+	t.Cleanup(func() { db.Close() })
+	return NewPostgresRepository(sqlx.NewDb(db, "postgres")), mock
+}
 
-	repo := &postgresRepository{db: db}
+func TestPostgresRepository_Create(t *testing.T) {
 	ctx := context.Background()
 
-	t.Run("successful creation", func(t *testing.T) {
-		trip := &Trip{
-			ID:          uuid.New().String(),
-			Title:       "Test Trip",
-			Description: "Test Description",
-			CreatorID:   uuid.New().String(),
-			Collaborators: []Collaborator{
-				{UserID: "user1", Role: "editor", AddedAt: time.Now()},
-			},
-			StartDate: time.Now(),
-			EndDate:   time.Now().Add(7 * 24 * time.Hour),
-			Places:    []string{"place1", "place2"},
-			Waypoints: []Waypoint{
-				{PlaceID: "place1", Order: 1, VisitDate: time.Now()},
-			},
-			IsPublic:  true,
-			ViewCount: 0,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		}
-
-		rows := sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
-			AddRow(trip.ID, trip.CreatedAt, trip.UpdatedAt)
+	t.Run("adds the owner as admin", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		trip := &Trip{Title: "Test Trip", OwnerID: ownerID, Privacy: "private", Status: "planning"}
+		now := time.Now()
 
+		mock.ExpectBegin()
 		mock.ExpectQuery(`INSERT INTO trips`).
-			WithArgs(
-				trip.ID,
-				trip.Title,
-				trip.Description,
-				trip.CreatorID,
-				sqlmock.AnyArg(), // collaborators jsonb
-				trip.StartDate,
-				trip.EndDate,
-				sqlmock.AnyArg(), // places array
-				sqlmock.AnyArg(), // waypoints jsonb
-				trip.IsPublic,
-				trip.ViewCount,
-				trip.CreatedAt,
-				trip.UpdatedAt,
-			).
-			WillReturnRows(rows)
-
-		err := repo.Create(ctx, trip)
-		assert.NoError(t, err)
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(tripID, now, now))
+		mock.ExpectExec(`INSERT INTO trip_collaborators`).
+			WithArgs(tripID, ownerID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, repo.Create(ctx, trip))
+		assert.Equal(t, tripID, trip.ID)
+		assert.Equal(t, now, trip.CreatedAt)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	t.Run("database error", func(t *testing.T) {
-		trip := &Trip{
-			ID:        uuid.New().String(),
-			Title:     "Test Trip",
-			CreatorID: uuid.New().String(),
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		}
+	t.Run("rolls back on error", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 
-		mock.ExpectQuery(`INSERT INTO trips`).
-			WithArgs(sqlmock.AnyArg()).
-			WillReturnError(sql.ErrConnDone)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO trips`).WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
 
-		err := repo.Create(ctx, trip)
-		assert.Error(t, err)
+		err := repo.Create(ctx, &Trip{Title: "Test Trip", OwnerID: ownerID})
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
-func TestPostgreSQLRepository_GetByID(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
-
-	repo := &postgresRepository{db: db}
+func TestPostgresRepository_GetByIDWith(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("trip found", func(t *testing.T) {
-		tripID := uuid.New().String()
-		creatorID := uuid.New().String()
-		now := time.Now()
+		repo, mock := newMockRepository(t)
 
-		rows := sqlmock.NewRows([]string{
-			"id", "title", "description", "creator_id", "collaborators",
-			"start_date", "end_date", "places", "waypoints", "is_public",
-			"view_count", "created_at", "updated_at",
-		}).AddRow(
-			tripID, "Test Trip", "Test Description", creatorID,
-			`[{"user_id": "user1", "role": "editor", "added_at": "2024-01-01T00:00:00Z"}]`,
-			now, now.Add(7*24*time.Hour), "{place1,place2}",
-			`[{"place_id": "place1", "order": 1, "visit_date": "2024-01-01T00:00:00Z"}]`,
-			true, 10, now, now,
-		)
-
-		mock.ExpectQuery(`SELECT (.+) FROM trips WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT (.+) FROM trips\s+WHERE id = \$1 AND deleted_at IS NULL`).
+			WithArgs(tripID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "owner_id", "privacy", "tags", "access_fees"}).
+				AddRow(tripID, "Test Trip", ownerID, "public", "{hike,desert}", `[{"kind":"entry_fee","name":"Entry"}]`))
+		mock.ExpectQuery(`FROM trip_collaborators tc`).
 			WithArgs(tripID).
-			WillReturnRows(rows)
+			WillReturnRows(sqlmock.NewRows([]string{"id", "trip_id", "user_id", "role", "can_edit"}).
+				AddRow("c1", tripID, ownerID, "admin", true))
 
-		trip, err := repo.GetByID(ctx, tripID)
-		assert.NoError(t, err)
-		assert.NotNil(t, trip)
-		assert.Equal(t, tripID, trip.ID)
+		trip, err := repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+		require.NoError(t, err)
 		assert.Equal(t, "Test Trip", trip.Title)
+		assert.Equal(t, pq.StringArray{"hike", "desert"}, trip.Tags)
+		assert.Equal(t, AccessFees{{Kind: FeeEntryFee, Name: "Entry"}}, trip.AccessFees)
+		require.Len(t, trip.Collaborators, 1)
+		assert.Equal(t, "admin", trip.Collaborators[0].Role)
+		assert.Nil(t, trip.Waypoints)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
 	t.Run("trip not found", func(t *testing.T) {
-		tripID := uuid.New().String()
+		repo, mock := newMockRepository(t)
 
-		mock.ExpectQuery(`SELECT (.+) FROM trips WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT (.+) FROM trips`).
 			WithArgs(tripID).
 			WillReturnError(sql.ErrNoRows)
 
 		trip, err := repo.GetByID(ctx, tripID)
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrTripNotFound)
 		assert.Nil(t, trip)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
-func TestPostgreSQLRepository_GetByCreator(t *testing.T) {
-	db, mock, err := sqlmock.New()
+func TestPostgresRepository_ListRelations(t *testing.T) {
+	ctx := context.Background()
+	repo, mock := newMockRepository(t)
+	otherTripID := "7b0d0c8e-0000-4000-8000-0000000000f2"
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT (.+) FROM trips t`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "owner_id"}).
+			AddRow(tripID, "First", ownerID).
+			AddRow(otherTripID, "Second", ownerID))
+	// One query per relation for the whole page
+	mock.ExpectQuery(`FROM trip_collaborators tc\s+JOIN users u ON tc.user_id = u.id\s+WHERE tc.trip_id = ANY\(\$1\)`).
+		WithArgs(pq.Array([]string{tripID, otherTripID})).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "trip_id", "user_id", "role"}).
+			AddRow("c1", otherTripID, editorID, "editor").
+			AddRow("c2", tripID, viewerID, "viewer").
+			AddRow("c3", otherTripID, viewerID, "viewer"))
+	mock.ExpectQuery(`FROM trip_waypoints tw\s+JOIN places p ON tw.place_id = p.id\s+WHERE tw.trip_id = ANY\(\$1\)\s+ORDER BY tw.trip_id, tw.order_position`).
+		WithArgs(pq.Array([]string{tripID, otherTripID})).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "trip_id", "place_id", "order_position", "arrival_time", "departure_time", "notes", "notes_format", "notes_doc", "kind",
+			"window_opens_at", "window_closes_at", "window_label", "created_at", "updated_at",
+			"place.id", "place.name", "place.description", "place.type", "place.location",
+			"place.street_address", "place.city", "place.country", "place.access_fees", "place.amenities",
+			"place.privacy",
+		}).
+			AddRow("w1", tripID, "p1", 0, nil, nil, "", "plain", nil, "stop", nil, nil, "", now, now, "p1", "Trailhead", "", "poi", nil, "", "", "", nil, "{toilets,parking}", "public").
+			AddRow("w2", tripID, "p2", 1, nil, nil, "- [x] Permit", "markdown", `{"checklist":[{"text":"Permit","checked":true,"line":0}],"text":"[x] Permit"}`, "stop", nil, nil, "", now, now, "p2", "Summit", "", "poi", nil, "", "", "", nil, nil, "private"))
+
+	trips, err := repo.List(ctx, TripFilters{Limit: 20})
 	require.NoError(t, err)
-	defer db.Close()
+	require.Len(t, trips, 2)
+
+	assert.Len(t, trips[0].Collaborators, 1)
+	assert.Equal(t, "c2", trips[0].Collaborators[0].ID)
+	assert.Len(t, trips[1].Collaborators, 2)
+	require.Len(t, trips[0].Waypoints, 2)
+	assert.Equal(t, "Summit", trips[0].Waypoints[1].Place.Name)
+	assert.Equal(t, "private", trips[0].Waypoints[1].Place.Privacy)
+	assert.Nil(t, trips[0].Waypoints[0].NotesRendering)
+	require.NotNil(t, trips[0].Waypoints[1].NotesRendering)
+	assert.True(t, trips[0].Waypoints[1].NotesRendering.Checklist[0].Checked)
+	assert.Equal(t, []string{"toilets", "parking"}, trailheadAmenities(trips[0]))
+	assert.Empty(t, trips[1].Waypoints)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
 
-	repo := &postgresRepository{db: db}
+func TestPostgresRepository_Update(t *testing.T) {
 	ctx := context.Background()
 
-	t.Run("trips found", func(t *testing.T) {
-		creatorID := uuid.New().String()
-		now := time.Now()
+	t.Run("array fields are bound as arrays", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 
-		rows := sqlmock.NewRows([]string{
-			"id", "title", "description", "creator_id", "collaborators",
-			"start_date", "end_date", "places", "waypoints", "is_public",
-			"view_count", "created_at", "updated_at",
-		}).
-			AddRow(
-				"trip1", "Trip 1", "Description 1", creatorID,
-				"[]", now, now.Add(7*24*time.Hour), "{}", "[]",
-				true, 5, now, now,
-			).
-			AddRow(
-				"trip2", "Trip 2", "Description 2", creatorID,
-				"[]", now, now.Add(14*24*time.Hour), "{}", "[]",
-				false, 0, now, now,
-			)
-
-		mock.ExpectQuery(`SELECT (.+) FROM trips WHERE creator_id = \$1`).
-			WithArgs(creatorID).
-			WillReturnRows(rows)
-
-		trips, err := repo.GetByCreator(ctx, creatorID)
+		mock.ExpectExec(`UPDATE trips\s+SET tags = \$2, updated_at = CURRENT_TIMESTAMP\s+WHERE id = \$1`).
+			WithArgs(tripID, pq.Array([]string{"hike"})).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Update(ctx, tripID, map[string]interface{}{"tags": []string{"hike"}})
 		assert.NoError(t, err)
-		assert.Len(t, trips, 2)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("content media is recorded as in use", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		mediaID := "6f1c3a1e-2b7d-4c55-9a0e-8d7a3c2b1f00"
+		content := TripContent{{Type: BlockImage, MediaID: mediaID}}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE trips\s+SET content = \$2`).
+			WithArgs(tripID, content).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM media_usage`).
+			WithArgs(MediaUsageTripContent, tripID, pq.Array([]string{mediaID})).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO media_usage`).
+			WithArgs(MediaUsageTripContent, tripID, pq.Array([]string{mediaID})).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.Update(ctx, tripID, map[string]interface{}{"content": content}))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no updates", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		assert.NoError(t, repo.Update(ctx, tripID, map[string]interface{}{}))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("trip not found", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectExec(`UPDATE trips`).
+			WithArgs(tripID, "Renamed").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Update(ctx, tripID, map[string]interface{}{"title": "Renamed"})
+		assert.ErrorIs(t, err, ErrTripNotFound)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
-func TestPostgreSQLRepository_GetByCollaborator(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
+func TestPostgresRepository_Delete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("soft deletes", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectExec(`UPDATE trips\s+SET deleted_at = CURRENT_TIMESTAMP`).
+			WithArgs(tripID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
 
-	repo := &postgresRepository{db: db}
+		assert.NoError(t, repo.Delete(ctx, tripID))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("trip not found", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectExec(`UPDATE trips\s+SET deleted_at = CURRENT_TIMESTAMP`).
+			WithArgs(tripID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.ErrorIs(t, repo.Delete(ctx, tripID), ErrTripNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresRepository_Collaborators(t *testing.T) {
 	ctx := context.Background()
 
-	t.Run("trips found", func(t *testing.T) {
-		userID := uuid.New().String()
-		now := time.Now()
+	t.Run("add", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		collaborator := collaboratorForRole(tripID, editorID, "editor")
 
-		rows := sqlmock.NewRows([]string{
-			"id", "title", "description", "creator_id", "collaborators",
-			"start_date", "end_date", "places", "waypoints", "is_public",
-			"view_count", "created_at", "updated_at",
-		}).
-			AddRow(
-				"trip1", "Trip 1", "Description 1", "creator1",
-				`[{"user_id": "`+userID+`", "role": "editor", "added_at": "2024-01-01T00:00:00Z"}]`,
-				now, now.Add(7*24*time.Hour), "{}", "[]",
-				true, 5, now, now,
-			)
-
-		mock.ExpectQuery(`SELECT (.+) FROM trips WHERE collaborators @> \$1`).
-			WithArgs(sqlmock.AnyArg()). // JSONB contains query
-			WillReturnRows(rows)
-
-		trips, err := repo.GetByCollaborator(ctx, userID)
-		assert.NoError(t, err)
-		assert.Len(t, trips, 1)
+		mock.ExpectExec(`INSERT INTO trip_collaborators`).
+			WithArgs(tripID, editorID, "editor", true, false, false, true).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.AddCollaborator(ctx, tripID, collaborator))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("add twice", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectExec(`INSERT INTO trip_collaborators`).
+			WillReturnError(&pq.Error{Code: "23505"})
+
+		err := repo.AddCollaborator(ctx, tripID, collaboratorForRole(tripID, editorID, "editor"))
+		assert.ErrorIs(t, err, ErrAlreadyCollaborator)
+		assert.ErrorIs(t, err, repoerr.ErrConflict)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectExec(`DELETE FROM trip_collaborators\s+WHERE trip_id = \$1 AND user_id = \$2`).
+			WithArgs(tripID, editorID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.RemoveCollaborator(ctx, tripID, editorID))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("remove one not there", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectExec(`DELETE FROM trip_collaborators`).
+			WithArgs(tripID, viewerID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.RemoveCollaborator(ctx, tripID, viewerID)
+		assert.ErrorIs(t, err, ErrCollaboratorNotFound)
+		assert.ErrorIs(t, err, repoerr.ErrNotFound)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
-func TestPostgreSQLRepository_Update(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
+func TestPostgresRepository_AddCollaborators(t *testing.T) {
+	ctx := context.Background()
+	collaborators := []Collaborator{
+		{UserID: editorID, Role: "editor", CanEdit: true, CanModerateSuggestions: true},
+		{UserID: viewerID, Role: "viewer"},
+	}
+
+	t.Run("all in one transaction", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO trip_collaborators`).
+			WithArgs(tripID, editorID, "editor", true, false, false, true).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO trip_collaborators`).
+			WithArgs(tripID, viewerID, "viewer", false, false, false, false).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, repo.AddCollaborators(ctx, tripID, collaborators))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back when one fails", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 
-	repo := &postgresRepository{db: db}
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO trip_collaborators`).
+			WithArgs(tripID, editorID, "editor", true, false, false, true).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO trip_collaborators`).
+			WithArgs(tripID, viewerID, "viewer", false, false, false, false).
+			WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
+
+		err := repo.AddCollaborators(ctx, tripID, collaborators)
+		assert.ErrorIs(t, err, ErrAlreadyCollaborator)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresRepository_AddWaypoints(t *testing.T) {
 	ctx := context.Background()
+	placeA := "30000000-0000-0000-0000-00000000000a"
+	placeB := "30000000-0000-0000-0000-00000000000b"
+
+	t.Run("appends after the last waypoint", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		now := time.Now()
 
-	t.Run("successful update", func(t *testing.T) {
-		trip := &Trip{
-			ID:          uuid.New().String(),
-			Title:       "Updated Trip",
-			Description: "Updated Description",
-			CreatorID:   uuid.New().String(),
-			Collaborators: []Collaborator{
-				{UserID: "user1", Role: "editor", AddedAt: time.Now()},
-			},
-			StartDate: time.Now(),
-			EndDate:   time.Now().Add(7 * 24 * time.Hour),
-			Places:    []string{"place1", "place2", "place3"},
-			IsPublic:  true,
-			UpdatedAt: time.Now(),
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM trips\s+WHERE id = \$1 AND deleted_at IS NULL\s+FOR UPDATE`).
+			WithArgs(tripID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(tripID))
+		mock.ExpectQuery(`SELECT COALESCE\(MAX\(order_position\), -1\) \+ 1`).
+			WithArgs(tripID).
+			WillReturnRows(sqlmock.NewRows([]string{"next"}).AddRow(3))
+		for i, placeID := range []string{placeA, placeB} {
+			mock.ExpectQuery(`INSERT INTO trip_waypoints`).
+				WithArgs("w"+strconv.Itoa(i), tripID, placeID, 3+i, "", "", nil).
+				WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at"}).AddRow(now, now))
 		}
+		mock.ExpectCommit()
+
+		waypoints := []*Waypoint{{ID: "w0", PlaceID: placeA}, {ID: "w1", PlaceID: placeB}}
+		require.NoError(t, repo.AddWaypoints(ctx, tripID, waypoints))
+		assert.Equal(t, 3, waypoints[0].OrderPosition)
+		assert.Equal(t, 4, waypoints[1].OrderPosition)
+		assert.Equal(t, WaypointStop, waypoints[1].Kind)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown place rolls back", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM trips`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(tripID))
+		mock.ExpectQuery(`SELECT COALESCE`).
+			WillReturnRows(sqlmock.NewRows([]string{"next"}).AddRow(0))
+		mock.ExpectQuery(`INSERT INTO trip_waypoints`).
+			WillReturnError(&pq.Error{Code: "23503"})
+		mock.ExpectRollback()
+
+		err := repo.AddWaypoints(ctx, tripID, []*Waypoint{{ID: "w0", PlaceID: placeA}})
+		assert.ErrorIs(t, err, ErrPlaceNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("deleted trip", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 
-		mock.ExpectExec(`UPDATE trips SET`).
-			WithArgs(
-				trip.Title,
-				trip.Description,
-				sqlmock.AnyArg(), // collaborators jsonb
-				trip.StartDate,
-				trip.EndDate,
-				sqlmock.AnyArg(), // places array
-				sqlmock.AnyArg(), // waypoints jsonb
-				trip.IsPublic,
-				sqlmock.AnyArg(), // updated_at
-				trip.ID,
-			).
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM trips`).WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		err := repo.AddWaypoints(ctx, tripID, []*Waypoint{{ID: "w0", PlaceID: placeA}})
+		assert.ErrorIs(t, err, ErrTripNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresRepository_AddWaypoint(t *testing.T) {
+	ctx := context.Background()
+	placeID := "30000000-0000-0000-0000-00000000000a"
+
+	repo, mock := newMockRepository(t)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM trips`).
+		WithArgs(tripID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(tripID))
+	mock.ExpectQuery(`SELECT id FROM trip_waypoints`).
+		WithArgs(tripID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("w0").AddRow("w1"))
+	mock.ExpectQuery(`INSERT INTO trip_waypoints`).
+		WithArgs("new", tripID, placeID, nil, nil, "", "", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at"}).AddRow(now, now))
+	mock.ExpectExec(`UPDATE trip_waypoints SET order_position = -1 - order_position`).
+		WithArgs(tripID).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`unnest\(\$2::uuid\[\]\) WITH ORDINALITY`).
+		WithArgs(tripID, pq.Array([]string{"w0", "new", "w1"})).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	waypoint := &Waypoint{ID: "new", TripID: tripID, PlaceID: placeID, OrderPosition: 1}
+	require.NoError(t, repo.AddWaypoint(ctx, waypoint))
+	assert.Equal(t, WaypointStop, waypoint.Kind)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepository_RemoveWaypoint(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("closes the gap", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM trips`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(tripID))
+		mock.ExpectExec(`DELETE FROM trip_waypoints`).
+			WithArgs("w1", tripID).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`SELECT id FROM trip_waypoints`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("w0").AddRow("w2"))
+		mock.ExpectExec(`UPDATE trip_waypoints SET order_position = -1 - order_position`).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(`WITH ORDINALITY`).
+			WithArgs(tripID, pq.Array([]string{"w0", "w2"})).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		require.NoError(t, repo.RemoveWaypoint(ctx, tripID, "w1"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 
-		err := repo.Update(ctx, trip)
-		assert.NoError(t, err)
+	t.Run("waypoint of another trip", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM trips`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(tripID))
+		mock.ExpectExec(`DELETE FROM trip_waypoints`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := repo.RemoveWaypoint(ctx, tripID, "elsewhere")
+		assert.ErrorIs(t, err, ErrWaypointNotFound)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
-func TestPostgreSQLRepository_Delete(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
+func TestPostgresRepository_ReorderWaypoints(t *testing.T) {
+	ctx := context.Background()
 
-	repo := &postgresRepository{db: db}
+	t.Run("order changed since it was read", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM trips`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(tripID))
+		mock.ExpectQuery(`SELECT id FROM trip_waypoints`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("w0").AddRow("w1").AddRow("w2"))
+		mock.ExpectRollback()
+
+		err := repo.ReorderWaypoints(ctx, tripID, []string{"w1", "w0"})
+		assert.ErrorIs(t, err, ErrInvalidWaypointOrder)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresRepository_IncrementViewCount(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectExec(`UPDATE trips\s+SET view_count = view_count \+ 1`).
+		WithArgs(tripID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.IncrementViewCount(context.Background(), tripID))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepository_SetSummary(t *testing.T) {
 	ctx := context.Background()
 
-	t.Run("successful deletion", func(t *testing.T) {
-		tripID := uuid.New().String()
+	t.Run("leaves the updated time alone", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 
-		mock.ExpectExec(`DELETE FROM trips WHERE id = \$1`).
-			WithArgs(tripID).
+		mock.ExpectExec(`UPDATE trips SET summary = \$2 WHERE id = \$1 AND deleted_at IS NULL$`).
+			WithArgs(tripID, "Easy trip.").
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		err := repo.Delete(ctx, tripID)
-		assert.NoError(t, err)
+		assert.NoError(t, repo.SetSummary(ctx, tripID, "Easy trip."))
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("deleted trip", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectExec(`UPDATE trips SET summary`).
+			WithArgs(tripID, "Easy trip.").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.ErrorIs(t, repo.SetSummary(ctx, tripID, "Easy trip."), ErrTripNotFound)
+	})
 }
 
-func TestPostgreSQLRepository_AddCollaborator(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
+func TestPostgresRepository_SetSuggestedTags(t *testing.T) {
+	ctx := context.Background()
+	repo, mock := newMockRepository(t)
 
-	repo := &postgresRepository{db: db}
+	mock.ExpectExec(`UPDATE trips SET suggested_tags = \$2, dismissed_tags = \$3 WHERE id = \$1 AND deleted_at IS NULL$`).
+		WithArgs(tripID, pq.Array([]string{"summit"}), pq.Array([]string{"loop"})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.SetSuggestedTags(ctx, tripID, []string{"summit"}, []string{"loop"}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepository_ReviewCondition(t *testing.T) {
 	ctx := context.Background()
 
-	t.Run("successful add collaborator", func(t *testing.T) {
-		tripID := uuid.New().String()
-		collaborator := Collaborator{
-			UserID:  "user123",
-			Role:    "editor",
-			AddedAt: time.Now(),
-		}
+	t.Run("reviewed", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 
-		mock.ExpectExec(`UPDATE trips SET collaborators = collaborators \|\| \$1`).
-			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), tripID).
+		mock.ExpectExec(`UPDATE activity_conditions\s+SET review_status = \$3`).
+			WithArgs("c1", editorID, ReviewDisputed, "Cleared yesterday").
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		err := repo.AddCollaborator(ctx, tripID, collaborator)
-		assert.NoError(t, err)
+		require.NoError(t, repo.ReviewCondition(ctx, "c1", editorID, ReviewDisputed, "Cleared yesterday"))
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectExec(`UPDATE activity_conditions`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.ReviewCondition(ctx, "c1", editorID, ReviewConfirmed, "")
+		assert.ErrorIs(t, err, ErrConditionNotFound)
+	})
 }
 
-func TestPostgreSQLRepository_RemoveCollaborator(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
+func TestPostgresRepository_CreateConditionSubscription(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("saved", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		subscription := &ConditionSubscription{UserID: ownerID, TripID: &[]string{tripID}[0], Severities: pq.StringArray{"danger"}, Frequency: "hourly"}
+
+		mock.ExpectQuery(`INSERT INTO condition_subscriptions .* WHERE \(SELECT COUNT\(\*\) FROM condition_subscriptions WHERE user_id = \$1\) < \$6`).
+			WithArgs(ownerID, tripID, nil, subscription.Severities, "hourly", 50).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("sub-1", time.Now()))
+
+		require.NoError(t, repo.CreateConditionSubscription(ctx, subscription, 50))
+		assert.Equal(t, "sub-1", subscription.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("over the limit", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 
-	repo := &postgresRepository{db: db}
+		mock.ExpectQuery(`INSERT INTO condition_subscriptions`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
+
+		err := repo.CreateConditionSubscription(ctx, &ConditionSubscription{UserID: ownerID}, 50)
+		assert.ErrorIs(t, err, ErrTooManySubscriptions)
+	})
+}
+
+func TestPostgresRepository_OwnershipTransfer(t *testing.T) {
 	ctx := context.Background()
 
-	t.Run("successful remove collaborator", func(t *testing.T) {
-		tripID := uuid.New().String()
-		userID := "user123"
+	t.Run("request is audited", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		transfer := &OwnershipTransfer{EntityID: tripID, FromUserID: ownerID, ToUserID: editorID}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO ownership_transfers`).
+			WithArgs(tripID, ownerID, editorID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "entity_type", "status", "created_at"}).
+				AddRow("transfer-1", "trip", "pending", time.Now()))
+		mock.ExpectExec(`INSERT INTO audit_log`).
+			WithArgs(ownerID, audit.ActionOwnershipTransferRequested, "trip", tripID, []byte(`{"to_user_id":"`+editorID+`","transfer_id":"transfer-1"}`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, repo.CreateOwnershipTransfer(ctx, transfer))
+		assert.Equal(t, "transfer-1", transfer.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("one pending transfer at a time", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 
-		// First query to get current collaborators
-		rows := sqlmock.NewRows([]string{"collaborators"}).
-			AddRow(`[{"user_id": "user123", "role": "editor"}, {"user_id": "user456", "role": "viewer"}]`)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO ownership_transfers`).WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
 
-		mock.ExpectQuery(`SELECT collaborators FROM trips WHERE id = \$1`).
-			WithArgs(tripID).
-			WillReturnRows(rows)
+		err := repo.CreateOwnershipTransfer(ctx, &OwnershipTransfer{EntityID: tripID, FromUserID: ownerID, ToUserID: editorID})
+		assert.ErrorIs(t, err, ErrTransferPending)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 
-		// Update query to remove collaborator
-		mock.ExpectExec(`UPDATE trips SET collaborators = \$1`).
-			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), tripID).
+	t.Run("acceptance moves ownership and is audited", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		transfer := &OwnershipTransfer{ID: "transfer-1", EntityID: tripID, FromUserID: ownerID, ToUserID: editorID}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE ownership_transfers`).
+			WithArgs("transfer-1", "accepted").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE trips\s+SET owner_id = \$2`).
+			WithArgs(tripID, editorID, ownerID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO trip_collaborators`).
+			WithArgs(tripID, pq.Array([]string{ownerID, editorID})).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(`INSERT INTO audit_log`).
+			WithArgs(editorID, audit.ActionOwnershipTransferAccepted, "trip", tripID, sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
 
-		err := repo.RemoveCollaborator(ctx, tripID, userID)
-		assert.NoError(t, err)
+		require.NoError(t, repo.ResolveOwnershipTransfer(ctx, transfer, editorID, true))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("declining leaves the trip and is audited", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		transfer := &OwnershipTransfer{ID: "transfer-1", EntityID: tripID, FromUserID: ownerID, ToUserID: editorID}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE ownership_transfers`).
+			WithArgs("transfer-1", "declined").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO audit_log`).
+			WithArgs(ownerID, audit.ActionOwnershipTransferDeclined, "trip", tripID, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, repo.ResolveOwnershipTransfer(ctx, transfer, ownerID, false))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failed audit write rolls back", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		transfer := &OwnershipTransfer{ID: "transfer-1", EntityID: tripID, FromUserID: ownerID, ToUserID: editorID}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE ownership_transfers`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO audit_log`).WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		err := repo.ResolveOwnershipTransfer(ctx, transfer, editorID, false)
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already resolved", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE ownership_transfers`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := repo.ResolveOwnershipTransfer(ctx, &OwnershipTransfer{ID: "transfer-1"}, editorID, true)
+		assert.ErrorIs(t, err, ErrTransferNotFound)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
-func TestPostgreSQLRepository_IncrementViewCount(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
+func TestPostgresRepository_RequestOfflinePack(t *testing.T) {
+	ctx := context.Background()
+	columns := []string{"trip_id", "status", "storage_path", "size_bytes", "error", "trip_updated_at", "requested_by", "requested_at", "completed_at"}
+	staleBefore := time.Now().Add(-time.Minute)
+
+	t.Run("queued", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectQuery(`INSERT INTO trip_offline_packs .+ ON CONFLICT \(trip_id\) DO UPDATE .+ WHERE trip_offline_packs.status <> 'pending' OR trip_offline_packs.requested_at < \$3`).
+			WithArgs(tripID, ownerID, staleBefore).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(tripID, OfflinePackPending, nil, nil, nil, nil, ownerID, time.Now(), nil))
+
+		pack, queued, err := repo.RequestOfflinePack(ctx, tripID, ownerID, staleBefore)
+		require.NoError(t, err)
+		assert.True(t, queued)
+		assert.Equal(t, OfflinePackPending, pack.Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already being built", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+		path := "offline/old.zip"
+
+		mock.ExpectQuery(`INSERT INTO trip_offline_packs`).
+			WithArgs(tripID, ownerID, staleBefore).
+			WillReturnRows(sqlmock.NewRows(columns))
+		mock.ExpectQuery(`SELECT\s+trip_id, .+ FROM trip_offline_packs WHERE trip_id = \$1`).
+			WithArgs(tripID).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(tripID, OfflinePackPending, path, 10, nil, nil, editorID, time.Now(), nil))
+
+		pack, queued, err := repo.RequestOfflinePack(ctx, tripID, ownerID, staleBefore)
+		require.NoError(t, err)
+		assert.False(t, queued)
+		assert.Equal(t, path, *pack.StoragePath)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// assertPlaceholders checks that a query numbers its placeholders $1 to $n
+// without gaps, one for each argument
+func assertPlaceholders(t *testing.T, query string, args []interface{}) {
+	t.Helper()
+	used := map[int]bool{}
+	for _, match := range regexp.MustCompile(`\$(\d+)`).FindAllStringSubmatch(query, -1) {
+		n, _ := strconv.Atoi(match[1])
+		used[n] = true
+	}
+	assert.Len(t, used, len(args), query)
+	for n := 1; n <= len(args); n++ {
+		assert.True(t, used[n], "$%d unused in %s", n, query)
+	}
+}
 
-	repo := &postgresRepository{db: db}
+func TestPostgresRepository_CreateCompletion(t *testing.T) {
 	ctx := context.Background()
+	completedAt := time.Now().Add(-time.Hour)
 
-	t.Run("successful increment", func(t *testing.T) {
-		tripID := uuid.New().String()
+	t.Run("counts the completion", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 
-		mock.ExpectExec(`UPDATE trips SET view_count = view_count \+ 1`).
-			WithArgs(sqlmock.AnyArg(), tripID).
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO activity_completions`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("c1", time.Now()))
+		mock.ExpectExec(`UPDATE trips SET completion_count = completion_count \+ 1`).
+			WithArgs(tripID).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
 
-		err := repo.IncrementViewCount(ctx, tripID)
-		assert.NoError(t, err)
+		completion := &ActivityCompletion{TripID: tripID, UserID: ownerID, CompletedAt: completedAt}
+		require.NoError(t, repo.CreateCompletion(ctx, completion))
+		assert.Equal(t, "c1", completion.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already logged", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO activity_completions`).
+			WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
+
+		err := repo.CreateCompletion(ctx, &ActivityCompletion{TripID: tripID, UserID: ownerID, CompletedAt: completedAt})
+		assert.ErrorIs(t, err, ErrCompletionExists)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
-func TestPostgreSQLRepository_Search(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	require.NoError(t, err)
-	defer db.Close()
+func TestPostgresRepository_CreateRating(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("recomputes the average", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO activity_ratings`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "helpful_count", "created_at", "updated_at"}).
+				AddRow("r1", 0, time.Now(), time.Now()))
+		mock.ExpectExec(`UPDATE trips t\s+SET average_rating = r.average, rating_count = r.count`).
+			WithArgs(tripID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		rating := &ActivityRating{TripID: tripID, UserID: viewerID, OverallRating: 5}
+		require.NoError(t, repo.CreateRating(ctx, rating))
+		assert.Equal(t, "r1", rating.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already rated", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO activity_ratings`).
+			WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
 
-	repo := &postgresRepository{db: db}
+		err := repo.CreateRating(ctx, &ActivityRating{TripID: tripID, UserID: viewerID, OverallRating: 5})
+		assert.ErrorIs(t, err, ErrRatingExists)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresRepository_CreateShareLink(t *testing.T) {
 	ctx := context.Background()
+	repo, mock := newMockRepository(t)
+
+	maxUses := 5
+	mock.ExpectQuery(`INSERT INTO activity_share_links`).
+		WithArgs(tripID, ownerID, "token", SharePermissionView, &maxUses, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "use_count", "created_at"}).
+			AddRow("link-1", 0, time.Now()))
+
+	link := &ActivityShareLink{TripID: tripID, CreatedBy: ownerID, ShareToken: "token", Permissions: SharePermissionView, MaxUses: &maxUses}
+	require.NoError(t, repo.CreateShareLink(ctx, link))
+	assert.Equal(t, "link-1", link.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
 
-	t.Run("search with results", func(t *testing.T) {
-		query := "test"
-		filters := SearchFilters{
-			IsPublic: true,
-			Limit:    10,
-			Offset:   0,
-		}
-		now := time.Now()
+func TestPostgresRepository_DeleteRating(t *testing.T) {
+	ctx := context.Background()
 
-		// Count query
-		countRows := sqlmock.NewRows([]string{"count"}).AddRow(2)
-		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM trips WHERE`).
-			WithArgs("%"+query+"%", "%"+query+"%", filters.IsPublic).
-			WillReturnRows(countRows)
-
-		// Search query
-		rows := sqlmock.NewRows([]string{
-			"id", "title", "description", "creator_id", "collaborators",
-			"start_date", "end_date", "places", "waypoints", "is_public",
-			"view_count", "created_at", "updated_at",
-		}).
-			AddRow(
-				"trip1", "Test Trip 1", "Description 1", "creator1",
-				"[]", now, now.Add(7*24*time.Hour), "{}", "[]",
-				true, 5, now, now,
-			).
-			AddRow(
-				"trip2", "Test Trip 2", "Description 2", "creator2",
-				"[]", now, now.Add(14*24*time.Hour), "{}", "[]",
-				true, 10, now, now,
-			)
-
-		mock.ExpectQuery(`SELECT (.+) FROM trips WHERE`).
-			WithArgs("%"+query+"%", "%"+query+"%", filters.IsPublic, filters.Limit, filters.Offset).
-			WillReturnRows(rows)
-
-		result, err := repo.Search(ctx, query, filters)
-		assert.NoError(t, err)
-		assert.NotNil(t, result)
-		assert.Len(t, result.Trips, 2)
-		assert.Equal(t, int64(2), result.Total)
+	t.Run("not rated", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`DELETE FROM activity_ratings`).
+			WithArgs(tripID, viewerID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, repo.DeleteRating(ctx, tripID, viewerID), ErrRatingNotFound)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
-func TestPostgreSQLRepository_GetPopular(t *testing.T) {
-	db, mock, err := sqlmock.New()
+func TestPostgresRepository_FinalizeDatePoll(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sets the trip dates", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE trip_date_polls`).
+			WithArgs("poll-1", "option-1", DatePollFinalized, DatePollOpen).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE trips t\s+SET start_date = o.start_date, end_date = o.end_date`).
+			WithArgs("poll-1", "option-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, repo.FinalizeDatePoll(ctx, "poll-1", "option-1"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already finalized", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE trip_date_polls`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, repo.FinalizeDatePoll(ctx, "poll-1", "option-1"), ErrDatePollClosed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresRepository_ListScheduledPublications(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	publishAt := time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT id, publish_at, publish_job_id\s+FROM trips\s+WHERE publish_at IS NOT NULL AND privacy <> 'public' AND deleted_at IS NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "publish_at", "publish_job_id"}).
+			AddRow(tripID, publishAt, "job-1").
+			AddRow("trip-2", publishAt, nil))
+
+	publications, err := repo.ListScheduledPublications(context.Background())
 	require.NoError(t, err)
-	defer db.Close()
+	require.Len(t, publications, 2)
+	assert.Equal(t, tripID, publications[0].TripID)
+	assert.Equal(t, publishAt, publications[0].PublishAt)
+	assert.Equal(t, "job-1", *publications[0].JobID)
+	assert.Nil(t, publications[1].JobID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepository_SaveDraft(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	savedAt := time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	// Base values already recorded win over the new ones, so a field keeps
+	// the value it had when the draft first touched it
+	mock.ExpectQuery(regexp.QuoteMeta("base = EXCLUDED.base || trip_drafts.base")).
+		WithArgs(tripID, editorID, []byte(`{"title":"Dipsea Trail"}`), []byte(`{"title":"Dipsea"}`)).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at"}).AddRow(savedAt, savedAt))
+
+	draft := &TripDraft{
+		TripID: tripID,
+		UserID: editorID,
+		Data:   JSONB{"title": "Dipsea Trail"},
+		Base:   JSONB{"title": "Dipsea"},
+	}
+	require.NoError(t, repo.SaveDraft(context.Background(), draft))
+	assert.Equal(t, savedAt, draft.UpdatedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListQuery(t *testing.T) {
+	now := time.Now()
+	number := 5.0
+	yes := true
+
+	filters := []struct {
+		name      string
+		set       func(*TripFilters)
+		condition string
+	}{
+		{"owner", func(f *TripFilters) { f.OwnerID = ownerID }, "t.owner_id = $"},
+		{"collaborator", func(f *TripFilters) { f.CollaboratorID = editorID }, "tc.user_id = $"},
+		{"editor", func(f *TripFilters) { f.EditorID = editorID }, "te.can_edit))"},
+		{"ids", func(f *TripFilters) { f.IDs = []string{tripID} }, "t.id = ANY($"},
+		{"privacy", func(f *TripFilters) { f.Privacy = "public" }, "t.privacy = $"},
+		{"visible to", func(f *TripFilters) { f.VisibleTo = editorID }, "tv.user_id = $"},
+		{"status", func(f *TripFilters) { f.Status = "planning" }, "t.status = $"},
+		{"tags", func(f *TripFilters) { f.Tags = []string{"hike"} }, "t.tags && $"},
+		{"start from", func(f *TripFilters) { f.StartDateFrom = &now }, "t.start_date >= $"},
+		{"start to", func(f *TripFilters) { f.StartDateTo = &now }, "t.start_date <= $"},
+		{"updated after", func(f *TripFilters) { f.UpdatedAfter = &now }, "t.updated_at >= $"},
+		{"activity types", func(f *TripFilters) { f.ActivityTypes = []string{"hiking"} }, "t.activity_type = ANY($"},
+		{"difficulty", func(f *TripFilters) { f.DifficultyLevels = []string{"easy"} }, "t.difficulty_level = ANY($"},
+		{"min duration", func(f *TripFilters) { f.MinDuration = &number }, "t.duration_hours >= $"},
+		{"max duration", func(f *TripFilters) { f.MaxDuration = &number }, "t.duration_hours <= $"},
+		{"min distance", func(f *TripFilters) { f.MinDistance = &number }, "t.distance_km >= $"},
+		{"max distance", func(f *TripFilters) { f.MaxDistance = &number }, "t.distance_km <= $"},
+		{"water", func(f *TripFilters) { f.WaterFeatures = []string{"river"} }, "t.water_features && $"},
+		{"terrain", func(f *TripFilters) { f.TerrainTypes = []string{"desert"} }, "t.terrain_types && $"},
+		{"accessibility", func(f *TripFilters) { f.Accessibility = []string{"dog_friendly"} }, "t.accessibility @> $"},
+		{"visibility", func(f *TripFilters) { f.Visibility = "public" }, "t.visibility = $"},
+		{"featured", func(f *TripFilters) { f.Featured = &yes }, "t.featured = $"},
+		{"verified", func(f *TripFilters) { f.Verified = &yes }, "t.verified = $"},
+		{"near", func(f *TripFilters) { f.NearLat, f.NearLng, f.RadiusKm = &number, &number, &number }, "ST_DWithin("},
+		{"search", func(f *TripFilters) { f.Search = "ridge" }, "t.search_vector @@ to_tsquery('english', $"},
+		{"cursor", func(f *TripFilters) { f.After = pagination.New(now, tripID) }, "(t.created_at, t.id) < ($"},
+	}
+
+	check := func(t *testing.T, set ...int) {
+		t.Helper()
+		f := TripFilters{Limit: 20, Offset: 40}
+		for _, i := range set {
+			filters[i].set(&f)
+		}
+
+		query, args := listQuery(f)
+		assertPlaceholders(t, query, args)
+		for i, filter := range filters {
+			included := false
+			for _, j := range set {
+				included = included || i == j
+			}
+			assert.Equal(t, included, strings.Contains(query, filter.condition), "%s in %s", filter.name, query)
+		}
+		assert.Equal(t, f.Limit, args[len(args)-2])
+	}
+
+	t.Run("no filters", func(t *testing.T) {
+		query, args := listQuery(TripFilters{Limit: 20})
+		assert.True(t, strings.HasSuffix(query, "WHERE t.deleted_at IS NULL ORDER BY t.created_at DESC, t.id DESC LIMIT $1 OFFSET $2"), query)
+		assert.Equal(t, []interface{}{20, 0}, args)
+	})
+
+	t.Run("every pair of filters", func(t *testing.T) {
+		for i := range filters {
+			check(t, i)
+			for j := i + 1; j < len(filters); j++ {
+				check(t, i, j)
+			}
+		}
+	})
+
+	t.Run("every filter", func(t *testing.T) {
+		all := make([]int, len(filters))
+		for i := range all {
+			all[i] = i
+		}
+		check(t, all...)
+	})
+
+	t.Run("search ranks the best matches first", func(t *testing.T) {
+		query, args := listQuery(TripFilters{OwnerID: ownerID, Search: "Ridge loop", Limit: 20})
+		assert.Contains(t, query, "AND t.search_vector @@ to_tsquery('english', $2)")
+		assert.Contains(t, query, "ORDER BY ts_rank(t.search_vector, to_tsquery('english', $3)) DESC, t.id LIMIT $4 OFFSET $5")
+		assert.Equal(t, []interface{}{ownerID, "ridge & loop:*", "ridge & loop:*", 20, 0}, args)
+
+		// Another order can still be asked for
+		query, _ = listQuery(TripFilters{Search: "ridge", SortBy: "title", Limit: 20})
+		assert.Contains(t, query, "ORDER BY t.title DESC, t.id DESC")
+	})
 
-	repo := &postgresRepository{db: db}
+	t.Run("a search without words is no search", func(t *testing.T) {
+		query, args := listQuery(TripFilters{Search: "&|!", Limit: 20})
+		assert.NotContains(t, query, "search_vector")
+		assert.Equal(t, []interface{}{20, 0}, args)
+	})
+
+	t.Run("a cursor replaces the offset and sort", func(t *testing.T) {
+		query, args := listQuery(TripFilters{After: pagination.New(now, tripID), SortBy: "title", Limit: 20, Offset: 40})
+		assert.Contains(t, query, "ORDER BY t.created_at DESC, t.id DESC LIMIT $3 OFFSET $4")
+		assert.Equal(t, []interface{}{now, tripID, 20, 0}, args)
+	})
+}
+
+func TestCountQuery(t *testing.T) {
+	now := time.Now()
+	filters := TripFilters{
+		OwnerID:   ownerID,
+		Search:    "ridge",
+		After:     pagination.New(now, tripID),
+		SortBy:    "title",
+		Limit:     20,
+		Offset:    40,
+		Relations: &Relations{},
+	}
+
+	// The same conditions as the list, without the cursor, order or page
+	query, args := countQuery(filters)
+	assertPlaceholders(t, query, args)
+	assert.True(t, strings.HasSuffix(query, "WHERE t.deleted_at IS NULL AND t.owner_id = $1 AND t.search_vector @@ to_tsquery('english', $2)"), query)
+	assert.Equal(t, []interface{}{ownerID, "ridge:*"}, args)
+}
+
+func TestPostgresRepository_UpdateParticipants(t *testing.T) {
 	ctx := context.Background()
+	participantRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"trip_id", "user_id", "status", "guests", "invited_by", "username"}).
+			AddRow(tripID, editorID, ParticipantGoing, 1, ownerID, "editor")
+	}
 
-	t.Run("get popular trips", func(t *testing.T) {
-		limit := 10
+	t.Run("saves only what changed, with the trip locked", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
 		now := time.Now()
 
-		rows := sqlmock.NewRows([]string{
-			"id", "title", "description", "creator_id", "collaborators",
-			"start_date", "end_date", "places", "waypoints", "is_public",
-			"view_count", "created_at", "updated_at",
-		}).
-			AddRow(
-				"trip1", "Popular Trip 1", "Description 1", "creator1",
-				"[]", now, now.Add(7*24*time.Hour), "{}", "[]",
-				true, 100, now, now,
-			).
-			AddRow(
-				"trip2", "Popular Trip 2", "Description 2", "creator2",
-				"[]", now, now.Add(14*24*time.Hour), "{}", "[]",
-				true, 50, now, now,
-			)
-
-		mock.ExpectQuery(`SELECT (.+) FROM trips WHERE is_public = true ORDER BY view_count DESC`).
-			WithArgs(limit).
-			WillReturnRows(rows)
-
-		trips, err := repo.GetPopular(ctx, limit)
-		assert.NoError(t, err)
-		assert.Len(t, trips, 2)
-		assert.Equal(t, 100, trips[0].ViewCount)
-		assert.Equal(t, 50, trips[1].ViewCount)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT t.capacity FROM trips t\s+WHERE t.id = \$1 AND t.deleted_at IS NULL FOR UPDATE`).
+			WithArgs(tripID).
+			WillReturnRows(sqlmock.NewRows([]string{"capacity"}).AddRow(2))
+		mock.ExpectQuery(`FROM trip_participants tp`).
+			WithArgs(tripID).
+			WillReturnRows(participantRows())
+		mock.ExpectQuery(`INSERT INTO trip_participants`).
+			WithArgs(tripID, viewerID, ParticipantInvited, 0, ownerID, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at"}).AddRow(now, now))
+		mock.ExpectCommit()
+
+		roster, err := repo.UpdateParticipants(ctx, tripID, func(roster *ParticipantRoster) error {
+			roster.Invite(viewerID, ownerID)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Len(t, roster.Participants, 2)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
-}
\ No newline at end of file
+
+	t.Run("a failed change saves nothing", func(t *testing.T) {
+		repo, mock := newMockRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT t.capacity FROM trips t`).
+			WithArgs(tripID).
+			WillReturnRows(sqlmock.NewRows([]string{"capacity"}).AddRow(2))
+		mock.ExpectQuery(`FROM trip_participants tp`).
+			WithArgs(tripID).
+			WillReturnRows(participantRows())
+		mock.ExpectRollback()
+
+		_, err := repo.UpdateParticipants(ctx, tripID, func(roster *ParticipantRoster) error {
+			return roster.Respond(editorID, ParticipantGoing, 3, time.Now())
+		})
+		assert.ErrorIs(t, err, ErrNotEnoughSpots)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
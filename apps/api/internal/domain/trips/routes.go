@@ -0,0 +1,141 @@
+package trips
+
+import (
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/internal/media"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers the trip routes, and the share link routes that
+// open a trip
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *users.RouteMiddleware) {
+	// Share links are exchanged for a token scoped to their trip
+	router.POST("/share/:token/session", h.RedeemShareLink)
+
+	// Anyone holding a share link can open its trip, each visit counting
+	// as a use
+	router.GET("/shared/:token", mw.RequireShareLink(users.PermissionTripRead), h.GetSharedTrip)
+
+	// Public routes (authentication optional)
+	public := router.Group("/trips", mw.OptionalAuth)
+	{
+		public.GET("", h.List)
+		public.GET("/:id", h.GetByID)
+		public.GET("/:id/stats", h.GetStats)
+		public.GET("/:id/layers", h.ListLayers)
+		public.GET("/:id/annotations", h.ListAnnotations)
+		public.GET("/:id/itinerary", h.GetItinerary)
+		public.GET("/:id/variants", h.ListRouteVariants)
+		public.GET("/:id/bailouts", h.GetBailouts)
+		public.GET("/:id/water-sources", h.GetWaterSources)
+		public.GET("/:id/elevation-profile", h.GetElevationProfile)
+		public.GET("/:id/crowd-estimate", h.GetCrowdEstimate)
+		public.GET("/:id/export", h.ExportTrip)
+		public.GET("/:id/completions", h.ListCompletions)
+		public.GET("/:id/completions/:completionId", h.GetCompletion)
+		public.GET("/:id/ratings", h.ListRatings)
+		public.GET("/:id/conditions", h.ListConditions)
+	}
+
+	// Condition alerts of the signed-in user
+	subscriptions := router.Group("/subscriptions/conditions", mw.RequireAuth)
+	{
+		subscriptions.POST("", mw.LimitGeoJSONBody, h.SubscribeToConditions)
+		subscriptions.GET("", h.ListConditionSubscriptions)
+		subscriptions.DELETE("/:id", h.DeleteConditionSubscription)
+	}
+
+	// Trusted verifiers of condition reports, granted and revoked by admins
+	verifiers := router.Group("/admin/condition-verifiers", mw.RequireAdmin...)
+	{
+		verifiers.GET("", h.ListConditionVerifiers)
+		verifiers.POST("", mw.LimitGeoJSONBody, h.GrantConditionVerifier)
+		verifiers.DELETE("/:id", h.RevokeConditionVerifier)
+	}
+
+	// Protected routes (authentication required, share-link guests are
+	// limited to what their grant allows)
+	trips := router.Group("/trips", mw.RequireAuthOrShare)
+	update := mw.RequireTripPermission(users.PermissionTripUpdate)
+	updateShared := mw.RequireTripPermissionOrShare(users.PermissionTripUpdate)
+	invite := mw.RequireTripPermission(users.PermissionTripInvite)
+	{
+		// Create trip (any authenticated user)
+		trips.POST("", mw.RequireSystemPermission(users.PermissionTripCreate), mw.LimitGeoJSONBody, h.Create)
+
+		// Trip-specific routes (permission based on trip role)
+		trips.PUT("/:id", updateShared, mw.LimitGeoJSONBody, h.Update)
+		trips.POST("/:id/route", updateShared, h.PlanRoute)
+		trips.DELETE("/:id", mw.RequireTripOwnership, h.Delete)
+		trips.POST("/:id/difficulty/estimate", update, h.RecomputeDifficulty)
+		trips.POST("/:id/suggested-tags/accept", mw.RequireTripOwnership, h.AcceptSuggestedTags)
+		trips.POST("/:id/suggested-tags/dismiss", mw.RequireTripOwnership, h.DismissSuggestedTags)
+		trips.POST("/:id/import", update, mw.LimitUploadBody, media.ValidateFileUpload(media.DefaultUploadLimits(MaxGPXSize+64*1024)), h.ImportGPX)
+		trips.POST("/:id/share-links", h.CreateShareLink)
+		trips.POST("/:id/completions", mw.LimitGeoJSONBody, h.LogCompletion)
+		trips.POST("/:id/ratings", h.RateTrip)
+		trips.DELETE("/:id/ratings", h.DeleteRating)
+		trips.POST("/:id/conditions", h.ReportCondition)
+		trips.POST("/:id/conditions/:conditionId/verify", h.VerifyCondition)
+		trips.POST("/:id/conditions/:conditionId/confirm", h.ConfirmCondition)
+		trips.POST("/:id/conditions/:conditionId/dispute", h.DisputeCondition)
+		trips.GET("/:id/date-polls", h.ListDatePolls)
+		trips.POST("/:id/date-polls", h.CreateDatePoll)
+		trips.GET("/:id/date-polls/:pollId", h.GetDatePoll)
+		trips.PUT("/:id/date-polls/:pollId/availability", h.SetAvailability)
+		trips.POST("/:id/date-polls/:pollId/finalize", h.FinalizeDatePoll)
+		trips.DELETE("/:id/date-polls/:pollId", h.DeleteDatePoll)
+		trips.GET("/:id/participants", h.ListParticipants)
+		trips.POST("/:id/participants", invite, h.InviteParticipants)
+		trips.PUT("/:id/participants/rsvp", h.RespondToInvite)
+		trips.PUT("/:id/participants/capacity", update, h.SetParticipantCapacity)
+		trips.DELETE("/:id/participants/:userId", h.RemoveParticipant)
+		trips.GET("/:id/readiness", h.GetReadiness)
+		trips.PUT("/:id/readiness/:check", update, h.ConfirmReadinessCheck)
+		trips.DELETE("/:id/readiness/:check", update, h.ClearReadinessCheck)
+		// Layer uploads are checked while streaming, with room for the form fields
+		layerLimits := media.DefaultUploadLimits(MaxLayerSize + 64*1024)
+		layerLimits.Kinds[media.KindJSON] = media.TypeLimits{MaxSize: MaxLayerSize, MaxDepth: 32}
+		trips.POST("/:id/layers", update, mw.LimitUploadBody, media.ValidateFileUpload(layerLimits), h.CreateLayer)
+		trips.DELETE("/:id/layers/:layerId", update, h.DeleteLayer)
+		trips.POST("/:id/offline-pack", h.RequestOfflinePack)
+		trips.GET("/:id/offline-pack", h.GetOfflinePack)
+		trips.POST("/:id/annotations", update, h.CreateAnnotation)
+		trips.PUT("/:id/annotations/:annotationId", update, h.UpdateAnnotation)
+		trips.DELETE("/:id/annotations/:annotationId", update, h.DeleteAnnotation)
+		trips.POST("/:id/waypoints", updateShared, h.AddWaypoint)
+		trips.POST("/:id/waypoints/bulk", updateShared, mw.LimitGeoJSONBody, h.AddWaypoints)
+		trips.PUT("/:id/waypoints/order", updateShared, h.ReorderWaypoints)
+		trips.PUT("/:id/waypoints/:waypointId", updateShared, h.UpdateWaypoint)
+		trips.DELETE("/:id/waypoints/:waypointId", updateShared, h.RemoveWaypoint)
+		trips.PUT("/:id/waypoints/:waypointId/window", update, h.SetWaypointWindow)
+		trips.POST("/:id/variants", update, mw.LimitGeoJSONBody, h.CreateRouteVariant)
+		trips.PUT("/:id/variants/:variantId", update, mw.LimitGeoJSONBody, h.UpdateRouteVariant)
+		trips.DELETE("/:id/variants/:variantId", update, h.DeleteRouteVariant)
+		trips.POST("/:id/variants/:variantId/primary", update, h.SetPrimaryRouteVariant)
+		trips.POST("/:id/bailouts", update, h.AttachBailout)
+		trips.DELETE("/:id/bailouts/:waypointId", update, h.DetachBailout)
+
+		// Collaborator management
+		trips.POST("/:id/collaborators", update, h.InviteCollaborator)
+		trips.POST("/:id/collaborators/bulk", invite, h.BulkInviteCollaborators)
+		trips.DELETE("/:id/collaborators/:userId", mw.RequireTripOwnership, h.RemoveCollaborator)
+		trips.PUT("/:id/collaborators/role", mw.RequireTripOwnership, h.UpdateCollaboratorRole)
+		trips.POST("/:id/leave", h.LeaveTrip)
+
+		// Ownership transfer
+		trips.POST("/:id/transfer-ownership", mw.RequireTripOwnership, h.TransferOwnership)
+		trips.POST("/:id/transfer-ownership/accept", h.AcceptOwnershipTransfer)
+		trips.POST("/:id/transfer-ownership/decline", h.DeclineOwnershipTransfer)
+
+		// Draft autosave
+		trips.GET("/:id/draft", h.GetDraft)
+		trips.PUT("/:id/draft", update, mw.LimitGeoJSONBody, h.SaveDraft)
+		trips.DELETE("/:id/draft", h.DiscardDraft)
+		trips.POST("/:id/draft/apply", update, h.ApplyDraft)
+
+		// Scheduled publication
+		trips.PUT("/:id/publish-schedule", update, h.SchedulePublication)
+		trips.DELETE("/:id/publish-schedule", update, h.CancelScheduledPublication)
+	}
+}
@@ -3,7 +3,11 @@ package trips
 import (
 	"context"
 	"errors"
+	"io"
 	"time"
+
+	"github.com/Oferzz/newMap/apps/api/pkg/pagination"
+	"github.com/Oferzz/newMap/apps/api/pkg/repoerr"
 )
 
 // Service defines the interface for trip operations
@@ -11,6 +15,9 @@ type Service interface {
 	// Basic CRUD operations
 	Create(ctx context.Context, userID string, input *CreateTripInput) (*Trip, error)
 	GetByID(ctx context.Context, userID, tripID string) (*Trip, error)
+	GetByIDWith(ctx context.Context, userID, tripID string, relations Relations) (*Trip, error)
+	AudienceOf(ctx context.Context, userID string, trip *Trip) string
+	ViewAs(ctx context.Context, userID, tripID, audience string, relations Relations) (*TripPreview, error)
 	Update(ctx context.Context, userID, tripID string, input *UpdateTripInput) (*Trip, error)
 	Delete(ctx context.Context, userID, tripID string) error
 	
@@ -25,23 +32,201 @@ type Service interface {
 	RemoveCollaborator(ctx context.Context, userID, tripID, collaboratorID string) error
 	UpdateCollaboratorRole(ctx context.Context, userID, tripID, collaboratorID, role string) error
 	InviteCollaborator(ctx context.Context, userID, tripID string, input *InviteCollaboratorInput) error
+	BulkInviteCollaborators(ctx context.Context, userID, tripID string, input *BulkInviteInput) ([]BulkInviteResult, error)
 	
 	// Waypoint management
 	AddWaypoint(ctx context.Context, userID, tripID string, input *AddWaypointInput) (*Waypoint, error)
 	UpdateWaypoint(ctx context.Context, userID, tripID, waypointID string, input *UpdateWaypointInput) (*Waypoint, error)
 	RemoveWaypoint(ctx context.Context, userID, tripID, waypointID string) error
 	ReorderWaypoints(ctx context.Context, userID, tripID string, waypointIDs []string) error
+	AddWaypoints(ctx context.Context, userID, tripID string, input *AddWaypointsInput) (*Trip, error)
+	SetWaypointWindow(ctx context.Context, userID, tripID, waypointID string, input *SetTimeWindowInput) (*Itinerary, error)
+	
+	// Itinerary
+	GetItinerary(ctx context.Context, userID, tripID string) (*Itinerary, error)
 	
 	// Additional operations
 	GetTripStats(ctx context.Context, userID, tripID string) (*TripStats, error)
-	ExportTrip(ctx context.Context, userID, tripID, format string) ([]byte, error)
+	RecomputeDifficulty(ctx context.Context, userID, tripID string) (*Trip, error)
+	AcceptSuggestedTags(ctx context.Context, userID, tripID string, input *SuggestedTagsInput) (*Trip, error)
+	DismissSuggestedTags(ctx context.Context, userID, tripID string, input *SuggestedTagsInput) (*Trip, error)
+	ImportGPX(ctx context.Context, userID, tripID string, file io.Reader) (*Trip, error)
+	EstimateDuration(ctx context.Context, userID string, trip *Trip) (*DurationEstimate, error)
+	
+	// Readiness checklist
+	GetReadiness(ctx context.Context, userID, tripID string) (*TripReadiness, error)
+	ConfirmReadinessCheck(ctx context.Context, userID, tripID, check string) (*TripReadiness, error)
+	ClearReadinessCheck(ctx context.Context, userID, tripID, check string) (*TripReadiness, error)
+	ExportTrip(ctx context.Context, userID, tripID, format, acceptLanguage string) (*TripExport, error)
 	CloneTrip(ctx context.Context, userID, tripID string) (*Trip, error)
+	
+	// Ownership transfer
+	TransferOwnership(ctx context.Context, userID, tripID, newOwnerID string) (*OwnershipTransfer, error)
+	AcceptOwnershipTransfer(ctx context.Context, userID, tripID string) (*Trip, error)
+	DeclineOwnershipTransfer(ctx context.Context, userID, tripID string) error
+	
+	// Scheduled publication
+	SchedulePublication(ctx context.Context, userID, tripID string, input *SchedulePublicationInput) (*Trip, error)
+	CancelScheduledPublication(ctx context.Context, userID, tripID string) (*Trip, error)
+	
+	// Draft autosave
+	SaveDraft(ctx context.Context, userID, tripID string, input *SaveDraftInput) (*TripDraft, error)
+	GetDraft(ctx context.Context, userID, tripID string) (*TripDraft, error)
+	DiscardDraft(ctx context.Context, userID, tripID string) error
+	ApplyDraft(ctx context.Context, userID, tripID string) (*DraftApplyResult, error)
+	
+	// Map annotations
+	ListAnnotations(ctx context.Context, userID, tripID string) ([]*TripAnnotation, error)
+	CreateAnnotation(ctx context.Context, userID, tripID string, input *CreateAnnotationInput) (*TripAnnotation, error)
+	UpdateAnnotation(ctx context.Context, userID, tripID, annotationID string, input *UpdateAnnotationInput) (*TripAnnotation, error)
+	DeleteAnnotation(ctx context.Context, userID, tripID, annotationID string) error
+	
+	// Completions
+	LogCompletion(ctx context.Context, userID, tripID string, input *CreateActivityCompletionInput) (*ActivityCompletion, error)
+	ListCompletions(ctx context.Context, userID, tripID string, limit, offset int) ([]*ActivityCompletion, error)
+	GetCompletion(ctx context.Context, userID, tripID, completionID string) (*ActivityCompletion, error)
+	
+	// Ratings
+	RateTrip(ctx context.Context, userID, tripID string, input *CreateActivityRatingInput) (*ActivityRating, error)
+	ListRatings(ctx context.Context, userID, tripID string, limit, offset int) ([]*ActivityRating, error)
+	DeleteRating(ctx context.Context, userID, tripID string) error
+	
+	// Conditions
+	ReportCondition(ctx context.Context, userID, tripID string, input *CreateActivityConditionInput) (*ActivityCondition, error)
+	ListConditions(ctx context.Context, userID, tripID string, includeExpired bool) ([]*ActivityCondition, error)
+	VerifyCondition(ctx context.Context, userID, tripID, conditionID string) (*ActivityCondition, error)
+	ConfirmCondition(ctx context.Context, userID, tripID, conditionID string, input *ReviewConditionInput) (*ActivityCondition, error)
+	DisputeCondition(ctx context.Context, userID, tripID, conditionID string, input *ReviewConditionInput) (*ActivityCondition, error)
+
+	// Trusted condition verifiers, granted by admins
+	GrantConditionVerifier(ctx context.Context, adminID string, input *GrantConditionVerifierInput) (*ConditionVerifier, error)
+	ListConditionVerifiers(ctx context.Context, userID string) ([]*ConditionVerifier, error)
+	RevokeConditionVerifier(ctx context.Context, id string) error
+	
+	// Date polls
+	ListDatePolls(ctx context.Context, userID, tripID string) ([]*DatePoll, error)
+	GetDatePoll(ctx context.Context, userID, tripID, pollID string) (*DatePoll, error)
+	CreateDatePoll(ctx context.Context, userID, tripID string, input *CreateDatePollInput) (*DatePoll, error)
+	SetAvailability(ctx context.Context, userID, tripID, pollID string, input *SetAvailabilityInput) (*DatePoll, error)
+	FinalizeDatePoll(ctx context.Context, userID, tripID, pollID string, input *FinalizeDatePollInput) (*DatePoll, error)
+	DeleteDatePoll(ctx context.Context, userID, tripID, pollID string) error
+	
+	// Participants
+	GetParticipants(ctx context.Context, userID, tripID string) (*ParticipantRoster, error)
+	InviteParticipants(ctx context.Context, userID, tripID string, input *InviteParticipantsInput) (*ParticipantRoster, error)
+	RespondToInvite(ctx context.Context, userID, tripID string, input *RSVPInput) (*ParticipantRoster, error)
+	RemoveParticipant(ctx context.Context, userID, tripID, participantID string) error
+	SetParticipantCapacity(ctx context.Context, userID, tripID string, input *SetCapacityInput) (*ParticipantRoster, error)
+	
+	// Crowdedness
+	GetCrowdEstimate(ctx context.Context, userID, tripID string) (*CrowdEstimate, error)
+	
+	// Water sources
+	GetWaterSources(ctx context.Context, userID, tripID string, query *WaterQuery) (*WaterPlan, error)
+	
+	// Bail-out points
+	GetBailouts(ctx context.Context, userID, tripID string, query *BailoutQuery) ([]*BailoutPoint, error)
+	AttachBailout(ctx context.Context, userID, tripID string, input *AttachBailoutInput) (*Waypoint, error)
+	DetachBailout(ctx context.Context, userID, tripID, waypointID string) error
+	
+	// Route variants
+	ListRouteVariants(ctx context.Context, userID, tripID string) ([]*TripRouteVariant, error)
+	CreateRouteVariant(ctx context.Context, userID, tripID string, input *CreateRouteVariantInput) (*TripRouteVariant, error)
+	UpdateRouteVariant(ctx context.Context, userID, tripID, variantID string, input *UpdateRouteVariantInput) (*TripRouteVariant, error)
+	DeleteRouteVariant(ctx context.Context, userID, tripID, variantID string) error
+	SetPrimaryRouteVariant(ctx context.Context, userID, tripID, variantID string) (*TripRouteVariant, error)
+	
+	// Share links
+	CreateShareLink(ctx context.Context, userID, tripID string, input *CreateShareLinkInput) (*ActivityShareLink, error)
+	RedeemShareLink(ctx context.Context, token string) (*ActivityShareLink, error)
+	GetSharedWith(ctx context.Context, grant *ShareGrant, tripID string, relations Relations) (*Trip, error)
+	UpdateShared(ctx context.Context, grant *ShareGrant, tripID string, input *UpdateTripInput) (*Trip, error)
+	AddWaypointShared(ctx context.Context, grant *ShareGrant, tripID string, input *AddWaypointInput) (*Waypoint, error)
+	UpdateWaypointShared(ctx context.Context, grant *ShareGrant, tripID, waypointID string, input *UpdateWaypointInput) (*Waypoint, error)
+	RemoveWaypointShared(ctx context.Context, grant *ShareGrant, tripID, waypointID string) error
+	ReorderWaypointsShared(ctx context.Context, grant *ShareGrant, tripID string, waypointIDs []string) error
+	AddWaypointsShared(ctx context.Context, grant *ShareGrant, tripID string, input *AddWaypointsInput) (*Trip, error)
 }
 
 // Common errors
 var (
-	ErrTripNotFound = errors.New("trip not found")
+	ErrTripNotFound = repoerr.NotFound("trip not found")
 	ErrUnauthorized = errors.New("unauthorized")
+	
+	ErrCollaboratorNotFound = repoerr.NotFound("collaborator not found")
+	ErrAlreadyCollaborator  = repoerr.Conflict("user is already a collaborator")
+	ErrUserNotFound         = repoerr.ForeignKey("user not found")
+	
+	ErrTransferNotFound = repoerr.NotFound("no pending ownership transfer")
+	ErrTransferPending  = repoerr.Conflict("an ownership transfer is already pending")
+	
+	ErrCompletionNotFound = repoerr.NotFound("completion not found")
+	ErrCompletionExists   = repoerr.Conflict("completion already logged")
+	ErrCompletionInFuture = errors.New("completed_at must not be in the future")
+	
+	ErrRatingNotFound = repoerr.NotFound("rating not found")
+	ErrRatingExists   = repoerr.Conflict("you have already rated this trip")
+	ErrOwnTripRating  = errors.New("you cannot rate your own trip")
+	
+	ErrConditionNotFound        = repoerr.NotFound("condition report not found")
+	ErrConditionExpired         = errors.New("valid_until must be in the future")
+	ErrInvalidConditionLocation = errors.New("condition location must be a GeoJSON point")
+	
+	ErrDatePollNotFound  = repoerr.NotFound("date poll not found")
+	ErrDatePollClosed    = repoerr.Conflict("date poll has already been finalized")
+	ErrInvalidDateRange  = errors.New("each date range must end on or after the day it starts")
+	ErrUnknownDateOption = errors.New("option is not one of the poll's date ranges")
+	ErrNoBestDates       = errors.New("no one has said which dates they can make yet")
+	
+	ErrParticipantNotFound = repoerr.NotFound("user is not invited on this trip")
+	ErrNotEnoughSpots      = repoerr.Conflict("not enough spots left for your guests")
+	ErrOwnerParticipant    = errors.New("the trip's owner cannot be invited on it")
+	
+	ErrAlreadyPublic           = repoerr.Conflict("trip is already public")
+	ErrPublishAtInPast         = errors.New("publish_at must be in the future")
+	ErrNoScheduledPublication  = repoerr.NotFound("trip has no scheduled publication")
+	ErrSchedulingNotConfigured = errors.New("scheduled publication is not available")
+	
+	ErrDraftNotFound = repoerr.NotFound("draft not found")
+	ErrDraftTooLarge = errors.New("draft is too large")
+	ErrInvalidDraft  = errors.New("draft is not a valid trip update")
+	
+	ErrShareLinkNotFound = repoerr.NotFound("share link is invalid or has expired")
+	ErrShareExpiryInPast = errors.New("share link expiry must be in the future")
+	
+	ErrPrivacyMismatch = errors.New("visibility must be public for public trips and private otherwise")
+	
+	ErrInvalidAudience = errors.New("view_as must be public, friend or collaborator")
+	
+	ErrNoDifficultyInputs = errors.New("trip has no distance, elevation or terrain to estimate difficulty from")
+	
+	ErrNotManualCheck = errors.New("only the permits and weather checks can be confirmed")
+	
+	ErrLayerNotFound = repoerr.NotFound("layer not found")
+	ErrInvalidLayer  = errors.New("layer must be a GeoJSON or KML file")
+	ErrLayerTooLarge = errors.New("layer files are limited to 10 MB")
+	
+	ErrOfflinePackNotFound = repoerr.NotFound("no offline pack has been requested for this trip")
+	
+	ErrAnnotationNotFound = repoerr.NotFound("annotation not found")
+	ErrInvalidAnnotation  = errors.New("geometry does not suit the annotation: measurements need a line, bearings a line of two points, labels a point and text, areas a closed polygon")
+	
+	ErrWaypointNotFound     = repoerr.NotFound("waypoint not found")
+	ErrInvalidTimeWindow    = errors.New("time window must close after it opens")
+	ErrInvalidWaypointTimes = errors.New("departure time must not be before arrival time")
+	ErrInvalidTimezone      = errors.New("timezone must be an IANA time zone, such as Europe/Zurich")
+	ErrInvalidWaypointOrder = errors.New("order must list each of the trip's waypoints once")
+	
+	ErrNoRouteGeometry = errors.New("trip has no route or located waypoints to follow")
+	ErrPlaceNotFound   = repoerr.NotFound("place not found")
+	
+	ErrVariantNotFound  = repoerr.NotFound("route variant not found")
+	ErrVariantNameTaken = repoerr.Conflict("the trip already has a route variant with this name")
+	ErrInvalidRoute     = errors.New("route must be a LineString or MultiLineString of at least two points")
+	
+	ErrInvalidGPX = errors.New("file must be GPX with a track or route of at least two points")
+	
+	ErrUnsupportedExportFormat = errors.New("export format must be gpx, geojson or ics")
 )
 
 // TripFilter contains filter criteria for trips
@@ -51,6 +236,8 @@ type TripFilter struct {
 	EndDate   *time.Time
 	Privacy   string
 	Tags      []string
+	After     *pagination.Cursor // keyset pagination, replaces the offset
+	Relations *Relations         // related records to load, all of them when nil
 }
 
 // TripStats contains trip statistics
@@ -61,6 +248,16 @@ type TripStats struct {
 	TotalSuggestions int `json:"total_suggestions"`
 	TotalViews       int `json:"total_views"`
 	TotalShares      int `json:"total_shares"`
+	Pace             *PaceEstimate `json:"pace,omitempty"`
+	AccessFees       *FeeSummary   `json:"access_fees"`
+}
+
+// PaceEstimate spreads a trip's distance and moving time over its days
+type PaceEstimate struct {
+	Days             int      `json:"days"`
+	DistanceKmPerDay *float64 `json:"distance_km_per_day,omitempty"`
+	HoursPerDay      *float64 `json:"hours_per_day,omitempty"`
+	SpeedKmh         *float64 `json:"speed_kmh,omitempty"`
 }
 
 // InviteCollaboratorInput for service compatibility
@@ -71,4 +268,26 @@ type InviteCollaboratorInput struct {
 	CanDelete   bool   `json:"can_delete"`
 	CanInvite   bool   `json:"can_invite"`
 	CanModerate bool   `json:"can_moderate_suggestions"`
-}
\ No newline at end of file
+}
+
+// BulkInviteEntry identifies one invitee by user ID or email
+type BulkInviteEntry struct {
+	UserID string `json:"user_id" binding:"omitempty,uuid"`
+	Email  string `json:"email" binding:"omitempty,email"`
+	Role   string `json:"role" binding:"required,oneof=viewer editor admin"`
+}
+
+// BulkInviteInput invites several collaborators at once
+type BulkInviteInput struct {
+	Invites []BulkInviteEntry `json:"invites" binding:"required,min=1,max=100,dive"`
+}
+
+// BulkInviteResult reports the outcome for a single bulk invite entry
+type BulkInviteResult struct {
+	Index   int    `json:"index"`
+	UserID  string `json:"user_id,omitempty"`
+	Email   string `json:"email,omitempty"`
+	Role    string `json:"role"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
@@ -2,27 +2,43 @@ package trips
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"time"
 
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/pkg/pagination"
 )
 
 type servicePg struct {
-	repo     Repository
-	userRepo users.Repository
+	repo      Repository
+	userRepo  users.Repository
+	publisher *Publisher
+	travel    TravelEstimator
+	layers    *LayerService
 }
 
 // NewService creates a new trip service
-func NewService(repo Repository, userRepo users.Repository) Service {
+func NewService(repo Repository, userRepo users.Repository, publisher *Publisher) *servicePg {
 	return &servicePg{
-		repo:     repo,
-		userRepo: userRepo,
+		repo:      repo,
+		userRepo:  userRepo,
+		publisher: publisher,
+		travel:    straightLineEstimator{},
 	}
 }
 
+// SetLayerService lets GeoJSON exports carry the trip's custom layers
+func (s *servicePg) SetLayerService(layers *LayerService) {
+	s.layers = layers
+}
+
 func (s *servicePg) Create(ctx context.Context, userID string, input *CreateTripInput) (*Trip, error) {
 	trip := &Trip{
 		ID:          uuid.New().String(),
@@ -30,7 +46,7 @@ func (s *servicePg) Create(ctx context.Context, userID string, input *CreateTrip
 		Description: input.Description,
 		OwnerID:     userID,
 		CoverImage:  input.CoverImage,
-		Privacy:     "private",
+		Privacy:     PrivacyPrivate,
 		Status:      "planning",
 		StartDate:   input.StartDate,
 		EndDate:     input.EndDate,
@@ -54,11 +70,12 @@ func (s *servicePg) Create(ctx context.Context, userID string, input *CreateTrip
 		BestSeasons:        input.BestSeasons,
 		TrailConditions:    input.TrailConditions,
 		AccessibilityNotes: input.AccessibilityNotes,
+		Accessibility:      input.Accessibility,
+		AccessFees:         input.AccessFees,
 		ParkingInfo:        input.ParkingInfo,
 		PermitsRequired:    input.PermitsRequired,
 		Hazards:            input.Hazards,
 		EmergencyContacts:  input.EmergencyContacts,
-		Visibility:         "private",
 		SharedWith:         input.SharedWith,
 		CompletionCount:    0,
 		RatingCount:        0,
@@ -66,35 +83,65 @@ func (s *servicePg) Create(ctx context.Context, userID string, input *CreateTrip
 		Verified:           false,
 	}
 	
-	// Set default privacy if provided
-	if input.Privacy != "" {
-		trip.Privacy = input.Privacy
+	// Structured content is described in plain text for older clients
+	if len(input.Content) > 0 {
+		if err := input.Content.normalize(); err != nil {
+			return nil, err
+		}
+		trip.Content = input.Content
+		trip.Description = trip.Content.PlainText()
 	}
 	
-	// Set visibility (for activity features)
-	if input.Visibility != "" {
-		trip.Visibility = input.Visibility
+	// Set privacy if provided, from visibility for older clients
+	privacy, err := resolvePrivacy(input.Privacy, input.Visibility)
+	if err != nil {
+		return nil, err
 	}
+	if privacy != "" {
+		trip.Privacy = privacy
+	}
+	trip.Visibility = VisibilityFor(trip.Privacy)
 	
 	// Set default timezone if not provided
 	if trip.Timezone == "" {
 		trip.Timezone = "UTC"
 	}
+	if !validTimezone(trip.Timezone) {
+		return nil, ErrInvalidTimezone
+	}
 	
 	// Set default activity type if not provided
 	if trip.ActivityType == "" {
 		trip.ActivityType = "general"
 	}
 	
+	// Estimate a difficulty the creator left out
+	if trip.DifficultyLevel == "" {
+		if level, ok := EstimateDifficulty(trip); ok {
+			trip.DifficultyLevel = level
+			trip.DifficultyEstimated = true
+		}
+	}
+	
 	if err := s.repo.Create(ctx, trip); err != nil {
 		return nil, fmt.Errorf("failed to create trip: %w", err)
 	}
+	trip.fillContent()
+	s.announce(ctx, events.TripCreated, trip.ID, userID, nil)
 	
 	return trip, nil
 }
 
 func (s *servicePg) GetByID(ctx context.Context, userID, tripID string) (*Trip, error) {
-	trip, err := s.repo.GetByID(ctx, tripID)
+	return s.GetByIDWith(ctx, userID, tripID, AllRelations)
+}
+
+func (s *servicePg) GetByIDWith(ctx context.Context, userID, tripID string, relations Relations) (*Trip, error) {
+	// Collaborators are always needed for the permission check
+	load := relations
+	load.Collaborators = true
+
+	trip, err := s.repo.GetByIDWith(ctx, tripID, load)
 	if err != nil {
 		return nil, err
 	}
@@ -104,9 +151,62 @@ func (s *servicePg) GetByID(ctx context.Context, userID, tripID string) (*Trip,
 		return nil, ErrUnauthorized
 	}
 	
+	if !relations.Collaborators {
+		trip.Collaborators = nil
+	}
+	
+	trip.localizeTimes()
+	trip.fillContent()
+	
 	return trip, nil
 }
 
+// RedeemShareLink counts a use of a share link, failing with
+// ErrShareLinkNotFound when it is unknown, expired or used up
+func (s *servicePg) RedeemShareLink(ctx context.Context, token string) (*ActivityShareLink, error) {
+	if token == "" {
+		return nil, ErrShareLinkNotFound
+	}
+	return s.repo.RedeemShareLink(ctx, token)
+}
+
+// GetSharedWith loads a trip for a share-link guest, whatever its privacy, as
+// the audience the grant stands for
+func (s *servicePg) GetSharedWith(ctx context.Context, grant *ShareGrant, tripID string, relations Relations) (*Trip, error) {
+	if !grant.Allows(tripID, "trip.read") {
+		return nil, ErrUnauthorized
+	}
+	
+	trip, err := s.repo.GetByIDWith(ctx, tripID, relations)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Guests see who else is on the trip only if they asked to
+	if !relations.Collaborators {
+		trip.Collaborators = nil
+	}
+	
+	trip.localizeTimes()
+	trip.fillContent()
+	
+	return trip.ForAudience(grant.Audience()), nil
+}
+
+// UpdateShared updates a trip for a share-link guest with edit access
+func (s *servicePg) UpdateShared(ctx context.Context, grant *ShareGrant, tripID string, input *UpdateTripInput) (*Trip, error) {
+	if !grant.Allows(tripID, "trip.update") {
+		return nil, ErrUnauthorized
+	}
+	
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	return s.update(ctx, "", trip, input)
+}
+
 func (s *servicePg) Update(ctx context.Context, userID, tripID string, input *UpdateTripInput) (*Trip, error) {
 	trip, err := s.repo.GetByID(ctx, tripID)
 	if err != nil {
@@ -118,6 +218,14 @@ func (s *servicePg) Update(ctx context.Context, userID, tripID string, input *Up
 		return nil, ErrUnauthorized
 	}
 	
+	return s.update(ctx, userID, trip, input)
+}
+
+// update applies the input to a trip the actor may edit. The actor is empty
+// for share-link guests.
+func (s *servicePg) update(ctx context.Context, actorID string, trip *Trip, input *UpdateTripInput) (*Trip, error) {
+	tripID := trip.ID
+	
 	// Build updates map for dynamic update
 	updates := make(map[string]interface{})
 	
@@ -128,14 +236,43 @@ func (s *servicePg) Update(ctx context.Context, userID, tripID string, input *Up
 	if input.Description != nil {
 		updates["description"] = *input.Description
 	}
+	if input.Content != nil {
+		content := *input.Content
+		if len(content) == 0 {
+			updates["content"] = TripContent(nil)
+		} else {
+			if err := content.normalize(); err != nil {
+				return nil, err
+			}
+			updates["content"] = content
+			updates["description"] = content.PlainText()
+		}
+	}
 	if input.StartDate != nil {
 		updates["start_date"] = input.StartDate
 	}
 	if input.EndDate != nil {
 		updates["end_date"] = input.EndDate
 	}
+	var privacy, visibility string
 	if input.Privacy != nil {
-		updates["privacy"] = *input.Privacy
+		privacy = *input.Privacy
+	}
+	if input.Visibility != nil {
+		visibility = *input.Visibility
+	}
+	privacy, err := resolvePrivacy(privacy, visibility)
+	if err != nil {
+		return nil, err
+	}
+	if privacy != "" {
+		updates["privacy"] = privacy
+		
+		// Choosing a privacy by hand overrides a scheduled publication
+		if trip.PublishAt != nil {
+			updates["publish_at"] = nil
+			updates["publish_job_id"] = nil
+		}
 	}
 	if input.Status != nil {
 		updates["status"] = *input.Status
@@ -147,6 +284,9 @@ func (s *servicePg) Update(ctx context.Context, userID, tripID string, input *Up
 		updates["cover_image"] = *input.CoverImage
 	}
 	if input.Timezone != nil {
+		if !validTimezone(*input.Timezone) {
+			return nil, ErrInvalidTimezone
+		}
 		updates["timezone"] = *input.Timezone
 	}
 	
@@ -193,6 +333,14 @@ func (s *servicePg) Update(ctx context.Context, userID, tripID string, input *Up
 	if input.AccessibilityNotes != nil {
 		updates["accessibility_notes"] = *input.AccessibilityNotes
 	}
+	// An empty list clears the attributes
+	if input.Accessibility != nil {
+		updates["accessibility"] = input.Accessibility
+	}
+	// An empty list clears the fees
+	if input.AccessFees != nil {
+		updates["access_fees"] = AccessFees(input.AccessFees)
+	}
 	if input.ParkingInfo != nil {
 		updates["parking_info"] = input.ParkingInfo
 	}
@@ -205,13 +353,17 @@ func (s *servicePg) Update(ctx context.Context, userID, tripID string, input *Up
 	if input.EmergencyContacts != nil {
 		updates["emergency_contacts"] = input.EmergencyContacts
 	}
-	if input.Visibility != nil {
-		updates["visibility"] = *input.Visibility
-	}
 	if len(input.SharedWith) > 0 {
 		updates["shared_with"] = input.SharedWith
 	}
 	
+	// A difficulty chosen by hand is kept; an estimated one follows the route
+	if input.DifficultyLevel != nil {
+		updates["difficulty_estimated"] = false
+	} else if trip.DifficultyEstimated || trip.DifficultyLevel == "" {
+		reestimateDifficulty(trip, input, updates)
+	}
+	
 	if err := s.repo.Update(ctx, tripID, updates); err != nil {
 		return nil, fmt.Errorf("failed to update trip: %w", err)
 	}
@@ -222,9 +374,51 @@ func (s *servicePg) Update(ctx context.Context, userID, tripID string, input *Up
 		return nil, fmt.Errorf("failed to get updated trip: %w", err)
 	}
 	
+	fields := make([]string, 0, len(updates))
+	for field := range updates {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	s.announce(ctx, events.TripUpdated, tripID, actorID, map[string]interface{}{"fields": fields})
+	
+	updatedTrip.localizeTimes()
+	updatedTrip.fillContent()
+	
 	return updatedTrip, nil
 }
 
+// RecomputeDifficulty replaces the trip's difficulty with a fresh estimate,
+// including one the creator chose
+func (s *servicePg) RecomputeDifficulty(ctx context.Context, userID, tripID string) (*Trip, error) {
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	level, ok := EstimateDifficulty(trip)
+	if !ok {
+		return nil, ErrNoDifficultyInputs
+	}
+	
+	updates := map[string]interface{}{
+		"difficulty_level":     level,
+		"difficulty_estimated": true,
+	}
+	if err := s.repo.Update(ctx, tripID, updates); err != nil {
+		return nil, fmt.Errorf("failed to update trip: %w", err)
+	}
+	
+	trip.DifficultyLevel = level
+	trip.DifficultyEstimated = true
+	s.announce(ctx, events.TripUpdated, tripID, userID, map[string]interface{}{"fields": []string{"difficulty_estimated", "difficulty_level"}})
+	
+	return trip, nil
+}
+
 func (s *servicePg) Delete(ctx context.Context, userID, tripID string) error {
 	trip, err := s.repo.GetByID(ctx, tripID)
 	if err != nil {
@@ -236,7 +430,12 @@ func (s *servicePg) Delete(ctx context.Context, userID, tripID string) error {
 		return ErrUnauthorized
 	}
 	
-	return s.repo.Delete(ctx, tripID)
+	if err := s.repo.Delete(ctx, tripID); err != nil {
+		return err
+	}
+	s.announce(ctx, events.TripDeleted, tripID, userID, nil)
+	
+	return nil
 }
 
 func (s *servicePg) List(ctx context.Context, userID string, filter *TripFilter, limit, offset int) ([]*Trip, int64, error) {
@@ -248,15 +447,32 @@ func (s *servicePg) List(ctx context.Context, userID string, filter *TripFilter,
 		Tags:           filter.Tags,
 		Limit:          limit,
 		Offset:         offset,
+		After:          filter.After,
+		Relations:      filter.Relations,
 	}
 	
+	return s.page(ctx, filters)
+}
+
+// page lists a page of trips with the number of trips matching the filters
+// across all pages. Pages after a cursor are not counted, since cursor pages
+// have no total.
+func (s *servicePg) page(ctx context.Context, filters TripFilters) ([]*Trip, int64, error) {
 	trips, err := s.repo.List(ctx, filters)
 	if err != nil {
 		return nil, 0, err
 	}
 	
-	// TODO: Get total count properly
-	total := int64(len(trips))
+	if filters.After != nil {
+		return trips, 0, nil
+	}
+	
+	total, err := pagination.Total(len(trips), filters.Limit, filters.Offset, func() (int64, error) {
+		return s.repo.Count(ctx, filters)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
 	
 	return trips, total, nil
 }
@@ -269,13 +485,7 @@ func (s *servicePg) GetUserTrips(ctx context.Context, userID string, limit, offs
 		Offset:  offset,
 	}
 	
-	trips, err := s.repo.List(ctx, filters)
-	if err != nil {
-		return nil, 0, err
-	}
-	
-	total := int64(len(trips))
-	return trips, total, nil
+	return s.page(ctx, filters)
 }
 
 func (s *servicePg) GetSharedTrips(ctx context.Context, userID string, limit, offset int) ([]*Trip, int64, error) {
@@ -286,13 +496,7 @@ func (s *servicePg) GetSharedTrips(ctx context.Context, userID string, limit, of
 		Offset:         offset,
 	}
 	
-	trips, err := s.repo.List(ctx, filters)
-	if err != nil {
-		return nil, 0, err
-	}
-	
-	total := int64(len(trips))
-	return trips, total, nil
+	return s.page(ctx, filters)
 }
 
 func (s *servicePg) Search(ctx context.Context, userID string, query string, limit, offset int) ([]*Trip, int64, error) {
@@ -303,13 +507,7 @@ func (s *servicePg) Search(ctx context.Context, userID string, query string, lim
 		Offset:         offset,
 	}
 	
-	trips, err := s.repo.List(ctx, filters)
-	if err != nil {
-		return nil, 0, err
-	}
-	
-	total := int64(len(trips))
-	return trips, total, nil
+	return s.page(ctx, filters)
 }
 
 func (s *servicePg) AddCollaborator(ctx context.Context, userID, tripID, collaboratorID, role string) error {
@@ -325,13 +523,13 @@ func (s *servicePg) AddCollaborator(ctx context.Context, userID, tripID, collabo
 	
 	// Check if collaborator exists
 	if _, err := s.userRepo.GetByID(ctx, collaboratorID); err != nil {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 	
 	// Check if already a collaborator
 	for _, collab := range trip.Collaborators {
 		if collab.UserID == collaboratorID {
-			return errors.New("user is already a collaborator")
+			return ErrAlreadyCollaborator
 		}
 	}
 	
@@ -352,7 +550,12 @@ func (s *servicePg) AddCollaborator(ctx context.Context, userID, tripID, collabo
 		InvitedAt:              time.Now(),
 	}
 	
-	return s.repo.AddCollaborator(ctx, tripID, collaborator)
+	if err := s.repo.AddCollaborator(ctx, tripID, collaborator); err != nil {
+		return err
+	}
+	s.announce(ctx, events.TripCollaboratorsChanged, tripID, userID, map[string]interface{}{"user_id": collaborator.UserID, "role": collaborator.Role})
+	
+	return nil
 }
 
 func (s *servicePg) RemoveCollaborator(ctx context.Context, userID, tripID, collaboratorID string) error {
@@ -366,7 +569,12 @@ func (s *servicePg) RemoveCollaborator(ctx context.Context, userID, tripID, coll
 		return ErrUnauthorized
 	}
 	
-	return s.repo.RemoveCollaborator(ctx, tripID, collaboratorID)
+	if err := s.repo.RemoveCollaborator(ctx, tripID, collaboratorID); err != nil {
+		return err
+	}
+	s.announce(ctx, events.TripCollaboratorsChanged, tripID, userID, map[string]interface{}{"user_id": collaboratorID})
+	
+	return nil
 }
 
 func (s *servicePg) UpdateCollaboratorRole(ctx context.Context, userID, tripID, collaboratorID, role string) error {
@@ -394,7 +602,12 @@ func (s *servicePg) UpdateCollaboratorRole(ctx context.Context, userID, tripID,
 		"can_moderate_suggestions": canModerate,
 	}
 	
-	return s.repo.UpdateCollaborator(ctx, tripID, collaboratorID, updates)
+	if err := s.repo.UpdateCollaborator(ctx, tripID, collaboratorID, updates); err != nil {
+		return err
+	}
+	s.announce(ctx, events.TripCollaboratorsChanged, tripID, userID, map[string]interface{}{"user_id": collaboratorID, "role": role})
+	
+	return nil
 }
 
 func (s *servicePg) InviteCollaborator(ctx context.Context, userID, tripID string, input *InviteCollaboratorInput) error {
@@ -410,13 +623,13 @@ func (s *servicePg) InviteCollaborator(ctx context.Context, userID, tripID strin
 	
 	// Check if collaborator exists
 	if _, err := s.userRepo.GetByID(ctx, input.UserID); err != nil {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 	
 	// Check if already a collaborator
 	for _, collab := range trip.Collaborators {
 		if collab.UserID == input.UserID {
-			return errors.New("user is already a collaborator")
+			return ErrAlreadyCollaborator
 		}
 	}
 	
@@ -431,123 +644,1383 @@ func (s *servicePg) InviteCollaborator(ctx context.Context, userID, tripID strin
 		InvitedAt:              time.Now(),
 	}
 	
-	return s.repo.AddCollaborator(ctx, tripID, collaborator)
-}
-
-func (s *servicePg) AddWaypoint(ctx context.Context, userID, tripID string, input *AddWaypointInput) (*Waypoint, error) {
-	// TODO: Implement waypoint functionality with WaypointRepository
-	return nil, errors.New("waypoint functionality not yet implemented")
-}
-
-func (s *servicePg) UpdateWaypoint(ctx context.Context, userID, tripID, waypointID string, input *UpdateWaypointInput) (*Waypoint, error) {
-	// TODO: Implement waypoint functionality with WaypointRepository
-	return nil, errors.New("waypoint functionality not yet implemented")
-}
-
-func (s *servicePg) RemoveWaypoint(ctx context.Context, userID, tripID, waypointID string) error {
-	// TODO: Implement waypoint functionality with WaypointRepository
-	return errors.New("waypoint functionality not yet implemented")
-}
-
-func (s *servicePg) ReorderWaypoints(ctx context.Context, userID, tripID string, waypointIDs []string) error {
-	// TODO: Implement waypoint functionality with WaypointRepository
-	return errors.New("waypoint functionality not yet implemented")
+	if err := s.repo.AddCollaborator(ctx, tripID, collaborator); err != nil {
+		return err
+	}
+	s.announce(ctx, events.TripCollaboratorsChanged, tripID, userID, map[string]interface{}{"user_id": collaborator.UserID, "role": collaborator.Role})
+	
+	return nil
 }
 
-func (s *servicePg) GetTripStats(ctx context.Context, userID, tripID string) (*TripStats, error) {
+func (s *servicePg) BulkInviteCollaborators(ctx context.Context, userID, tripID string, input *BulkInviteInput) ([]BulkInviteResult, error) {
 	trip, err := s.repo.GetByID(ctx, tripID)
 	if err != nil {
 		return nil, err
 	}
 	
-	// Check if user can view
-	if !s.canUserAccessTrip(trip, userID) {
+	// Check if user can invite
+	if !s.canUserInviteToTrip(trip, userID) {
 		return nil, ErrUnauthorized
 	}
 	
-	// TODO: Implement actual stats gathering
-	return &TripStats{
-		TotalPlaces:        0,
-		TotalWaypoints:     len(trip.Waypoints),
-		TotalCollaborators: len(trip.Collaborators),
-		TotalSuggestions:   trip.SuggestionCount,
-		TotalViews:         trip.ViewCount,
-		TotalShares:        trip.ShareCount,
-	}, nil
+	results := make([]BulkInviteResult, len(input.Invites))
+	seen := make(map[string]bool)
+	var collaborators []Collaborator
+	var pending []int
+	
+	// Validate every entry before touching the database
+	for i, entry := range input.Invites {
+		result := BulkInviteResult{
+			Index:  i,
+			UserID: entry.UserID,
+			Email:  entry.Email,
+			Role:   entry.Role,
+		}
+		
+		inviteeID, err := s.resolveInvitee(ctx, entry)
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+		case inviteeID == trip.OwnerID || trip.HasCollaborator(inviteeID):
+			result.Error = ErrAlreadyCollaborator.Error()
+		case seen[inviteeID]:
+			result.Error = "duplicate entry"
+		default:
+			seen[inviteeID] = true
+			result.UserID = inviteeID
+			collaborators = append(collaborators, collaboratorForRole(tripID, inviteeID, entry.Role))
+			pending = append(pending, i)
+		}
+		
+		results[i] = result
+	}
+	
+	if len(collaborators) == 0 {
+		return results, nil
+	}
+	
+	// Invite all valid entries in one transaction
+	if err := s.repo.AddCollaborators(ctx, tripID, collaborators); err != nil {
+		for _, i := range pending {
+			results[i].Error = err.Error()
+		}
+		return results, nil
+	}
+	
+	for _, i := range pending {
+		results[i].Success = true
+	}
+	
+	return results, nil
 }
 
-func (s *servicePg) ExportTrip(ctx context.Context, userID, tripID, format string) ([]byte, error) {
-	trip, err := s.repo.GetByID(ctx, tripID)
-	if err != nil {
-		return nil, err
+// resolveInvitee looks up the user referenced by a bulk invite entry
+func (s *servicePg) resolveInvitee(ctx context.Context, entry BulkInviteEntry) (string, error) {
+	if entry.UserID == "" && entry.Email == "" {
+		return "", errors.New("user_id or email is required")
 	}
 	
-	// Check if user can view
-	if !s.canUserAccessTrip(trip, userID) {
-		return nil, ErrUnauthorized
+	if entry.UserID != "" {
+		user, err := s.userRepo.GetByID(ctx, entry.UserID)
+		if err != nil {
+			return "", ErrUserNotFound
+		}
+		return user.ID, nil
 	}
 	
-	// TODO: Implement export functionality
-	return []byte{}, errors.New("export not implemented")
+	user, err := s.userRepo.GetByEmail(ctx, entry.Email)
+	if err != nil {
+		return "", ErrUserNotFound
+	}
+	return user.ID, nil
 }
 
-func (s *servicePg) CloneTrip(ctx context.Context, userID, tripID string) (*Trip, error) {
-	sourceTrip, err := s.repo.GetByID(ctx, tripID)
+// AddWaypoint adds a place to the trip as a stop, at the position given or
+// else at the end
+func (s *servicePg) AddWaypoint(ctx context.Context, userID, tripID string, input *AddWaypointInput) (*Waypoint, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
 	if err != nil {
 		return nil, err
 	}
-	
-	// Check if user can view source trip
-	if !s.canUserAccessTrip(sourceTrip, userID) {
+
+	if !s.canUserEditTrip(trip, userID) {
 		return nil, ErrUnauthorized
 	}
-	
-	// Create new trip
-	newTrip := &Trip{
-		ID:          uuid.New().String(),
-		Title:       sourceTrip.Title + " (Copy)",
-		Description: sourceTrip.Description,
-		OwnerID:     userID,
-		CoverImage:  sourceTrip.CoverImage,
-		Privacy:     "private",
-		Status:      "planning",
-		StartDate:   sourceTrip.StartDate,
-		EndDate:     sourceTrip.EndDate,
-		Timezone:    sourceTrip.Timezone,
-		Tags:        sourceTrip.Tags,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+
+	return s.addWaypoint(ctx, userID, trip, input)
+}
+
+// AddWaypointShared is AddWaypoint for a share-link guest with edit access
+func (s *servicePg) AddWaypointShared(ctx context.Context, grant *ShareGrant, tripID string, input *AddWaypointInput) (*Waypoint, error) {
+	if !grant.Allows(tripID, "trip.update") {
+		return nil, ErrUnauthorized
 	}
-	
-	if err := s.repo.Create(ctx, newTrip); err != nil {
-		return nil, fmt.Errorf("failed to clone trip: %w", err)
+
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
 	}
-	
-	// TODO: Clone waypoints and other related data
-	
-	return newTrip, nil
+
+	return s.addWaypoint(ctx, "", trip, input)
 }
 
-// Helper methods
-func (s *servicePg) canUserAccessTrip(trip *Trip, userID string) bool {
-	// Owner can always access
-	if trip.OwnerID == userID {
-		return true
+// addWaypoint adds a waypoint to a trip the actor may edit. The actor is
+// empty for share-link guests.
+func (s *servicePg) addWaypoint(ctx context.Context, actorID string, trip *Trip, input *AddWaypointInput) (*Waypoint, error) {
+	tripID := trip.ID
+
+	if !validWaypointTimes(input.ArrivalTime, input.DepartureTime) {
+		return nil, ErrInvalidWaypointTimes
 	}
-	
-	// Public trips can be accessed by anyone
-	if trip.Privacy == "public" {
-		return true
+
+	waypoint := &Waypoint{
+		ID:            uuid.New().String(),
+		TripID:        tripID,
+		PlaceID:       input.PlaceID,
+		OrderPosition: math.MaxInt32,
+		ArrivalTime:   timeIn(input.ArrivalTime, time.UTC),
+		DepartureTime: timeIn(input.DepartureTime, time.UTC),
+	}
+	waypoint.setNotes(input.Notes, input.NotesFormat)
+	if input.OrderPosition != nil {
+		waypoint.OrderPosition = *input.OrderPosition
+	}
+	if err := s.repo.AddWaypoint(ctx, waypoint); err != nil {
+		return nil, err
 	}
-	
-	// Check if user is collaborator
-	for _, collab := range trip.Collaborators {
-		if collab.UserID == userID {
-			return true
+	s.announceWaypoints(ctx, tripID, actorID, "added", waypoint.ID)
+
+	return s.waypoint(ctx, trip, waypoint.ID)
+}
+
+// UpdateWaypoint changes the times and notes of a waypoint, or moves it
+func (s *servicePg) UpdateWaypoint(ctx context.Context, userID, tripID, waypointID string, input *UpdateWaypointInput) (*Waypoint, error) {
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+
+	return s.updateWaypoint(ctx, userID, trip, waypointID, input)
+}
+
+// UpdateWaypointShared is UpdateWaypoint for a share-link guest with edit
+// access
+func (s *servicePg) UpdateWaypointShared(ctx context.Context, grant *ShareGrant, tripID, waypointID string, input *UpdateWaypointInput) (*Waypoint, error) {
+	if !grant.Allows(tripID, "trip.update") {
+		return nil, ErrUnauthorized
+	}
+
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.updateWaypoint(ctx, "", trip, waypointID, input)
+}
+
+// updateWaypoint updates a waypoint of a trip the actor may edit. The actor
+// is empty for share-link guests.
+func (s *servicePg) updateWaypoint(ctx context.Context, actorID string, trip *Trip, waypointID string, input *UpdateWaypointInput) (*Waypoint, error) {
+	tripID := trip.ID
+
+	index := -1
+	for i := range trip.Waypoints {
+		if trip.Waypoints[i].ID == waypointID {
+			index = i
+			break
 		}
 	}
-	
-	return false
+	if index < 0 {
+		return nil, ErrWaypointNotFound
+	}
+
+	// Stored positions may have gaps left by removed bail-outs, so a waypoint
+	// that isn't moved keeps its place in the order
+	waypoint := &trip.Waypoints[index]
+	waypoint.OrderPosition = index
+	if input.OrderPosition != nil {
+		waypoint.OrderPosition = *input.OrderPosition
+	}
+	if input.ArrivalTime != nil {
+		waypoint.ArrivalTime = timeIn(input.ArrivalTime, time.UTC)
+	}
+	if input.DepartureTime != nil {
+		waypoint.DepartureTime = timeIn(input.DepartureTime, time.UTC)
+	}
+	if input.Notes != nil || input.NotesFormat != nil {
+		notes, format := waypoint.Notes, waypoint.NotesFormat
+		if input.Notes != nil {
+			notes = *input.Notes
+		}
+		if input.NotesFormat != nil {
+			format = *input.NotesFormat
+		}
+		waypoint.setNotes(notes, format)
+	}
+
+	if !validWaypointTimes(waypoint.ArrivalTime, waypoint.DepartureTime) {
+		return nil, ErrInvalidWaypointTimes
+	}
+
+	if err := s.repo.UpdateWaypoint(ctx, waypoint); err != nil {
+		return nil, err
+	}
+	s.announceWaypoints(ctx, tripID, actorID, "updated", waypointID)
+
+	return s.waypoint(ctx, trip, waypointID)
+}
+
+// RemoveWaypoint removes a waypoint of the trip, stop or bail-out
+func (s *servicePg) RemoveWaypoint(ctx context.Context, userID, tripID, waypointID string) error {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return err
+	}
+
+	if !s.canUserEditTrip(trip, userID) {
+		return ErrUnauthorized
+	}
+
+	return s.removeWaypoint(ctx, userID, trip, waypointID)
+}
+
+// RemoveWaypointShared is RemoveWaypoint for a share-link guest with edit
+// access
+func (s *servicePg) RemoveWaypointShared(ctx context.Context, grant *ShareGrant, tripID, waypointID string) error {
+	if !grant.Allows(tripID, "trip.update") {
+		return ErrUnauthorized
+	}
+
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return err
+	}
+
+	return s.removeWaypoint(ctx, "", trip, waypointID)
+}
+
+// removeWaypoint removes a waypoint of a trip the actor may edit. The actor
+// is empty for share-link guests.
+func (s *servicePg) removeWaypoint(ctx context.Context, actorID string, trip *Trip, waypointID string) error {
+	tripID := trip.ID
+
+	if err := s.repo.RemoveWaypoint(ctx, tripID, waypointID); err != nil {
+		return err
+	}
+	s.announceWaypoints(ctx, tripID, actorID, "removed", waypointID)
+
+	return nil
+}
+
+// ReorderWaypoints puts the trip's waypoints in the order given, which must
+// list each of them once
+func (s *servicePg) ReorderWaypoints(ctx context.Context, userID, tripID string, waypointIDs []string) error {
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return err
+	}
+
+	if !s.canUserEditTrip(trip, userID) {
+		return ErrUnauthorized
+	}
+
+	return s.reorderWaypoints(ctx, userID, trip, waypointIDs)
+}
+
+// ReorderWaypointsShared is ReorderWaypoints for a share-link guest with edit
+// access
+func (s *servicePg) ReorderWaypointsShared(ctx context.Context, grant *ShareGrant, tripID string, waypointIDs []string) error {
+	if !grant.Allows(tripID, "trip.update") {
+		return ErrUnauthorized
+	}
+
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return err
+	}
+
+	return s.reorderWaypoints(ctx, "", trip, waypointIDs)
+}
+
+// reorderWaypoints reorders the waypoints of a trip the actor may edit. The
+// actor is empty for share-link guests.
+func (s *servicePg) reorderWaypoints(ctx context.Context, actorID string, trip *Trip, waypointIDs []string) error {
+	tripID := trip.ID
+
+	ids := make([]string, len(trip.Waypoints))
+	for i, waypoint := range trip.Waypoints {
+		ids[i] = waypoint.ID
+	}
+	if !sameWaypoints(ids, waypointIDs) {
+		return ErrInvalidWaypointOrder
+	}
+
+	if err := s.repo.ReorderWaypoints(ctx, tripID, waypointIDs); err != nil {
+		return err
+	}
+	s.announceWaypoints(ctx, tripID, actorID, "reordered", waypointIDs...)
+
+	return nil
+}
+
+// waypoint reads a waypoint of the trip back with its place
+func (s *servicePg) waypoint(ctx context.Context, trip *Trip, waypointID string) (*Waypoint, error) {
+	waypoints, err := s.repo.GetWaypoints(ctx, trip.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range waypoints {
+		if waypoints[i].ID == waypointID {
+			waypoints[i].localize(tripLocation(trip))
+			return &waypoints[i], nil
+		}
+	}
+	return nil, ErrWaypointNotFound
+}
+
+// validWaypointTimes reports whether a waypoint is not left before it is
+// reached
+func validWaypointTimes(arrival, departure *time.Time) bool {
+	return arrival == nil || departure == nil || !departure.Before(*arrival)
+}
+
+// sameWaypoints reports whether an order lists each of the IDs once
+func sameWaypoints(ids, order []string) bool {
+	if len(ids) != len(order) {
+		return false
+	}
+
+	remaining := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		remaining[id] = true
+	}
+	for _, id := range order {
+		if !remaining[id] {
+			return false
+		}
+		delete(remaining, id)
+	}
+	return true
+}
+
+// AddWaypoints appends places to the trip as stops in one go. With
+// AutoRoute the trip's route is redrawn through all its stops, as straight
+// lines between them; fewer than two located stops leave the route as it was.
+func (s *servicePg) AddWaypoints(ctx context.Context, userID, tripID string, input *AddWaypointsInput) (*Trip, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+
+	return s.addWaypoints(ctx, userID, trip, input)
+}
+
+// AddWaypointsShared is AddWaypoints for a share-link guest with edit access
+func (s *servicePg) AddWaypointsShared(ctx context.Context, grant *ShareGrant, tripID string, input *AddWaypointsInput) (*Trip, error) {
+	if !grant.Allows(tripID, "trip.update") {
+		return nil, ErrUnauthorized
+	}
+
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.addWaypoints(ctx, "", trip, input)
+}
+
+// addWaypoints adds waypoints to a trip the actor may edit. The actor is
+// empty for share-link guests.
+func (s *servicePg) addWaypoints(ctx context.Context, actorID string, trip *Trip, input *AddWaypointsInput) (*Trip, error) {
+	tripID := trip.ID
+
+	waypoints := make([]*Waypoint, len(input.PlaceIDs))
+	for i, placeID := range input.PlaceIDs {
+		waypoints[i] = &Waypoint{ID: uuid.New().String(), PlaceID: placeID}
+		notes := ""
+		if i < len(input.Notes) {
+			notes = input.Notes[i]
+		}
+		waypoints[i].setNotes(notes, input.NotesFormat)
+	}
+	if err := s.repo.AddWaypoints(ctx, tripID, waypoints); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(waypoints))
+	for i, waypoint := range waypoints {
+		ids[i] = waypoint.ID
+	}
+	s.announceWaypoints(ctx, tripID, actorID, "added", ids...)
+
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get updated trip: %w", err)
+	}
+
+	if !input.AutoRoute {
+		trip.localizeTimes()
+		trip.fillContent()
+		return trip, nil
+	}
+	route, ok := stopsRoute(trip)
+	if !ok {
+		trip.localizeTimes()
+		trip.fillContent()
+		return trip, nil
+	}
+	distance, _ := routeLengthKm(route)
+	return s.update(ctx, actorID, trip, &UpdateTripInput{RouteGeoJSON: route, DistanceKm: &distance})
+}
+
+// SetWaypointWindow sets the time window of a waypoint, or clears it when
+// the input sets no times, and returns the itinerary checked against it
+func (s *servicePg) SetWaypointWindow(ctx context.Context, userID, tripID, waypointID string, input *SetTimeWindowInput) (*Itinerary, error) {
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	window := &TimeWindow{OpensAt: input.OpensAt, ClosesAt: input.ClosesAt, Label: input.Label}
+	if window.OpensAt != nil && window.ClosesAt != nil && !window.ClosesAt.After(*window.OpensAt) {
+		return nil, ErrInvalidTimeWindow
+	}
+	if window.IsZero() {
+		window = nil
+	}
+	window = window.in(time.UTC)
+	
+	if err := s.repo.SetWaypointWindow(ctx, tripID, waypointID, window); err != nil {
+		return nil, err
+	}
+	
+	for i := range trip.Waypoints {
+		if trip.Waypoints[i].ID == waypointID {
+			trip.Waypoints[i].Window = window
+		}
+	}
+	s.announceWaypoints(ctx, tripID, userID, "updated", waypointID)
+	
+	return planItinerary(ctx, trip, s.travel)
+}
+
+// GetItinerary estimates when each waypoint is reached and warns of time
+// windows the plan cannot make
+func (s *servicePg) GetItinerary(ctx context.Context, userID, tripID string) (*Itinerary, error) {
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	return planItinerary(ctx, trip, s.travel)
+}
+
+func (s *servicePg) GetTripStats(ctx context.Context, userID, tripID string) (*TripStats, error) {
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Check if user can view
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	// TODO: Implement actual stats gathering
+	return &TripStats{
+		TotalPlaces:        0,
+		TotalWaypoints:     len(trip.Waypoints),
+		TotalCollaborators: len(trip.Collaborators),
+		TotalSuggestions:   trip.SuggestionCount,
+		TotalViews:         trip.ViewCount,
+		TotalShares:        trip.ShareCount,
+		Pace:               trip.Pace(),
+		AccessFees:         summarizeFees(trip),
+	}, nil
+}
+
+// EstimateDuration estimates how long the trip would take the user, from
+// their own pace when they have recorded completions of the same activity
+func (s *servicePg) EstimateDuration(ctx context.Context, userID string, trip *Trip) (*DurationEstimate, error) {
+	if trip.DistanceKm == nil || *trip.DistanceKm <= 0 {
+		return nil, nil
+	}
+	
+	var pace *UserPace
+	if userID != "" {
+		var err error
+		pace, err = s.repo.GetUserPace(ctx, userID, trip.ActivityType)
+		if err != nil {
+			return nil, err
+		}
+	}
+	
+	return estimateDuration(trip, pace), nil
+}
+
+// GetReadiness evaluates the trip against the readiness checklist
+func (s *servicePg) GetReadiness(ctx context.Context, userID, tripID string) (*TripReadiness, error) {
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	return s.readiness(ctx, trip)
+}
+
+// ConfirmReadinessCheck marks a manual check as done by the user
+func (s *servicePg) ConfirmReadinessCheck(ctx context.Context, userID, tripID, check string) (*TripReadiness, error) {
+	trip, err := s.editableForReadiness(ctx, userID, tripID, check)
+	if err != nil {
+		return nil, err
+	}
+	
+	if err := s.repo.ConfirmReadinessCheck(ctx, tripID, check, userID); err != nil {
+		return nil, err
+	}
+	
+	return s.readiness(ctx, trip)
+}
+
+// ClearReadinessCheck withdraws the confirmation of a manual check
+func (s *servicePg) ClearReadinessCheck(ctx context.Context, userID, tripID, check string) (*TripReadiness, error) {
+	trip, err := s.editableForReadiness(ctx, userID, tripID, check)
+	if err != nil {
+		return nil, err
+	}
+	
+	if err := s.repo.ClearReadinessCheck(ctx, tripID, check); err != nil {
+		return nil, err
+	}
+	
+	return s.readiness(ctx, trip)
+}
+
+func (s *servicePg) editableForReadiness(ctx context.Context, userID, tripID, check string) (*Trip, error) {
+	if !IsManualReadinessCheck(check) {
+		return nil, ErrNotManualCheck
+	}
+	
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	return trip, nil
+}
+
+func (s *servicePg) readiness(ctx context.Context, trip *Trip) (*TripReadiness, error) {
+	state, err := s.repo.GetReadinessState(ctx, trip.ID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if route, ok := followedRoute(trip); ok && waterActivities[trip.ActivityType] {
+		state.Water, err = s.planWater(ctx, trip, route, DefaultWaterCorridorM)
+		if err != nil {
+			return nil, err
+		}
+	}
+	
+	return evaluateReadiness(trip, state, time.Now()), nil
+}
+
+// ListAnnotations returns the map annotations of a trip, measured
+func (s *servicePg) ListAnnotations(ctx context.Context, userID, tripID string) ([]*TripAnnotation, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	annotations, err := s.repo.ListAnnotations(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	for _, annotation := range annotations {
+		annotation.measure()
+	}
+	
+	return annotations, nil
+}
+
+// CreateAnnotation adds a map annotation to a trip and tells the other
+// members about it
+func (s *servicePg) CreateAnnotation(ctx context.Context, userID, tripID string, input *CreateAnnotationInput) (*TripAnnotation, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	annotation := &TripAnnotation{
+		ID:        uuid.New().String(),
+		TripID:    tripID,
+		Kind:      input.Kind,
+		Geometry:  input.Geometry,
+		Label:     input.Label,
+		Style:     input.Style,
+		CreatedBy: userID,
+		UpdatedBy: userID,
+	}
+	if err := annotation.validate(); err != nil {
+		return nil, err
+	}
+	
+	if err := s.repo.CreateAnnotation(ctx, annotation); err != nil {
+		return nil, err
+	}
+	
+	annotation.measure()
+	s.announceAnnotation(ctx, tripID, userID, annotation.ID, "created")
+	
+	return annotation, nil
+}
+
+// UpdateAnnotation changes the geometry, label or style of an annotation
+func (s *servicePg) UpdateAnnotation(ctx context.Context, userID, tripID, annotationID string, input *UpdateAnnotationInput) (*TripAnnotation, error) {
+	annotation, err := s.editableAnnotation(ctx, userID, tripID, annotationID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if input.Geometry != nil {
+		annotation.Geometry = input.Geometry
+	}
+	if input.Label != nil {
+		annotation.Label = *input.Label
+	}
+	if input.Style != nil {
+		annotation.Style = input.Style
+	}
+	annotation.UpdatedBy = userID
+	if err := annotation.validate(); err != nil {
+		return nil, err
+	}
+	
+	if err := s.repo.UpdateAnnotation(ctx, annotation); err != nil {
+		return nil, err
+	}
+	
+	annotation.measure()
+	s.announceAnnotation(ctx, tripID, userID, annotationID, "updated")
+	
+	return annotation, nil
+}
+
+// DeleteAnnotation removes a map annotation
+func (s *servicePg) DeleteAnnotation(ctx context.Context, userID, tripID, annotationID string) error {
+	if _, err := s.editableAnnotation(ctx, userID, tripID, annotationID); err != nil {
+		return err
+	}
+	
+	if err := s.repo.DeleteAnnotation(ctx, annotationID); err != nil {
+		return err
+	}
+	
+	s.announceAnnotation(ctx, tripID, userID, annotationID, "deleted")
+	
+	return nil
+}
+
+// editableAnnotation loads an annotation of the trip the user may edit
+func (s *servicePg) editableAnnotation(ctx context.Context, userID, tripID, annotationID string) (*TripAnnotation, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	annotation, err := s.repo.GetAnnotation(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+	if annotation.TripID != tripID {
+		return nil, ErrAnnotationNotFound
+	}
+	
+	return annotation, nil
+}
+
+// announceAnnotation tells connected members an annotation changed, so
+// their maps stay in step
+func (s *servicePg) announceAnnotation(ctx context.Context, tripID, actorID, annotationID, action string) {
+	s.announce(ctx, events.TripUpdated, tripID, actorID, map[string]interface{}{
+		"fields":        []string{"annotations"},
+		"annotation_id": annotationID,
+		"action":        action,
+	})
+}
+
+// announceWaypoints tells collaborators which waypoints changed and how:
+// "added", "updated", "removed" or "reordered"
+func (s *servicePg) announceWaypoints(ctx context.Context, tripID, actorID, action string, waypointIDs ...string) {
+	data := map[string]interface{}{"action": action}
+	if len(waypointIDs) == 1 {
+		data["waypoint_id"] = waypointIDs[0]
+	} else {
+		data["waypoint_ids"] = waypointIDs
+	}
+	s.announce(ctx, events.TripWaypointsChanged, tripID, actorID, data)
+}
+
+// GetCrowdEstimate estimates how busy the trip tends to be on each day of the
+// week and hour of the day from the past year of visits
+func (s *servicePg) GetCrowdEstimate(ctx context.Context, userID, tripID string) (*CrowdEstimate, error) {
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	since := time.Now().Add(-crowdWindow).UTC()
+	visits, err := s.repo.ListVisitTimes(ctx, tripID, since)
+	if err != nil {
+		return nil, err
+	}
+	
+	return estimateCrowd(trip, visits, since), nil
+}
+
+// GetWaterSources returns the water sources along the trip's route, judged
+// for the month it sets out in, and the dry stretches between them
+func (s *servicePg) GetWaterSources(ctx context.Context, userID, tripID string, query *WaterQuery) (*WaterPlan, error) {
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	route, ok := followedRoute(trip)
+	if !ok {
+		return nil, ErrNoRouteGeometry
+	}
+	
+	corridor := float64(DefaultWaterCorridorM)
+	if query != nil && query.CorridorM > 0 {
+		corridor = math.Min(query.CorridorM, MaxWaterCorridorM)
+	}
+	
+	return s.planWater(ctx, trip, route, corridor)
+}
+
+func (s *servicePg) planWater(ctx context.Context, trip *Trip, route *GeoJSONRoute, corridorM float64) (*WaterPlan, error) {
+	sources, err := s.repo.FindWaterSources(ctx, route, corridorM)
+	if err != nil {
+		return nil, err
+	}
+	
+	return planWater(trip, route, sources, tripMonth(trip, time.Now())), nil
+}
+
+// GetBailouts suggests places along the trip's route where it can be left
+// early, in the order they are reached
+func (s *servicePg) GetBailouts(ctx context.Context, userID, tripID string, query *BailoutQuery) ([]*BailoutPoint, error) {
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	route, ok := followedRoute(trip)
+	if !ok {
+		return nil, ErrNoRouteGeometry
+	}
+	
+	corridor := float64(DefaultBailoutCorridorM)
+	if query != nil && query.CorridorM > 0 {
+		corridor = math.Min(query.CorridorM, MaxBailoutCorridorM)
+	}
+	
+	points, err := s.repo.FindBailoutPlaces(ctx, route, corridor, BailoutCategories)
+	if err != nil {
+		return nil, err
+	}
+	
+	attached := make(map[string]string)
+	for _, waypoint := range trip.Waypoints {
+		if waypoint.Kind == WaypointBailout {
+			attached[waypoint.PlaceID] = waypoint.ID
+		}
+	}
+	for _, point := range points {
+		point.WaypointID = attached[point.PlaceID]
+	}
+	
+	placeBailouts(route, points)
+	return points, nil
+}
+
+// AttachBailout adds a place to the trip as a bail-out waypoint
+func (s *servicePg) AttachBailout(ctx context.Context, userID, tripID string, input *AttachBailoutInput) (*Waypoint, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	waypoint := &Waypoint{
+		ID:      uuid.New().String(),
+		TripID:  tripID,
+		PlaceID: input.PlaceID,
+		Notes:   input.Notes,
+	}
+	if err := s.repo.AddBailoutWaypoint(ctx, waypoint); err != nil {
+		return nil, err
+	}
+	
+	s.announceWaypoints(ctx, tripID, userID, "added", waypoint.ID)
+	
+	return waypoint, nil
+}
+
+// DetachBailout removes a bail-out waypoint from the trip
+func (s *servicePg) DetachBailout(ctx context.Context, userID, tripID, waypointID string) error {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return err
+	}
+	
+	if !s.canUserEditTrip(trip, userID) {
+		return ErrUnauthorized
+	}
+	
+	if err := s.repo.RemoveBailoutWaypoint(ctx, tripID, waypointID); err != nil {
+		return err
+	}
+	
+	s.announceWaypoints(ctx, tripID, userID, "removed", waypointID)
+	
+	return nil
+}
+
+// ListRouteVariants returns the route variants of a trip, primary first
+func (s *servicePg) ListRouteVariants(ctx context.Context, userID, tripID string) ([]*TripRouteVariant, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	return s.repo.ListRouteVariants(ctx, tripID)
+}
+
+// CreateRouteVariant adds a route variant to a trip, measuring its distance
+// when none is given
+func (s *servicePg) CreateRouteVariant(ctx context.Context, userID, tripID string, input *CreateRouteVariantInput) (*TripRouteVariant, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	variant := &TripRouteVariant{
+		ID:             uuid.New().String(),
+		TripID:         tripID,
+		Name:           input.Name,
+		Description:    input.Description,
+		RouteGeoJSON:   input.RouteGeoJSON,
+		DistanceKm:     input.DistanceKm,
+		ElevationGainM: input.ElevationGainM,
+		DurationHours:  input.DurationHours,
+		CreatedBy:      userID,
+	}
+	if err := measureVariant(variant, input.DistanceKm == nil); err != nil {
+		return nil, err
+	}
+	
+	if err := s.repo.CreateRouteVariant(ctx, variant); err != nil {
+		return nil, err
+	}
+	
+	if input.IsPrimary {
+		if err := s.repo.SetPrimaryRouteVariant(ctx, tripID, variant.ID); err != nil {
+			return nil, err
+		}
+		variant.IsPrimary = true
+		s.announce(ctx, events.TripUpdated, tripID, userID, map[string]interface{}{"fields": variantTripFields})
+	}
+	
+	return variant, nil
+}
+
+// UpdateRouteVariant changes a route variant, keeping the trip in step when
+// it is the primary one
+func (s *servicePg) UpdateRouteVariant(ctx context.Context, userID, tripID, variantID string, input *UpdateRouteVariantInput) (*TripRouteVariant, error) {
+	variant, err := s.editableVariant(ctx, userID, tripID, variantID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if input.Name != nil {
+		variant.Name = *input.Name
+	}
+	if input.Description != nil {
+		variant.Description = *input.Description
+	}
+	if input.RouteGeoJSON != nil {
+		variant.RouteGeoJSON = input.RouteGeoJSON
+	}
+	if input.DistanceKm != nil {
+		variant.DistanceKm = input.DistanceKm
+	}
+	if input.ElevationGainM != nil {
+		variant.ElevationGainM = input.ElevationGainM
+	}
+	if input.DurationHours != nil {
+		variant.DurationHours = input.DurationHours
+	}
+	
+	// A new route is measured again unless given a distance with it
+	remeasure := input.RouteGeoJSON != nil && input.DistanceKm == nil
+	if err := measureVariant(variant, remeasure); err != nil {
+		return nil, err
+	}
+	
+	if err := s.repo.UpdateRouteVariant(ctx, variant); err != nil {
+		return nil, err
+	}
+	
+	if variant.IsPrimary {
+		if err := s.repo.SetPrimaryRouteVariant(ctx, tripID, variantID); err != nil {
+			return nil, err
+		}
+		s.announce(ctx, events.TripUpdated, tripID, userID, map[string]interface{}{"fields": variantTripFields})
+	}
+	
+	return variant, nil
+}
+
+// DeleteRouteVariant removes a route variant. The trip keeps the route of a
+// deleted primary variant.
+func (s *servicePg) DeleteRouteVariant(ctx context.Context, userID, tripID, variantID string) error {
+	if _, err := s.editableVariant(ctx, userID, tripID, variantID); err != nil {
+		return err
+	}
+	
+	return s.repo.DeleteRouteVariant(ctx, variantID)
+}
+
+// SetPrimaryRouteVariant makes a variant the trip's route
+func (s *servicePg) SetPrimaryRouteVariant(ctx context.Context, userID, tripID, variantID string) (*TripRouteVariant, error) {
+	variant, err := s.editableVariant(ctx, userID, tripID, variantID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if err := s.repo.SetPrimaryRouteVariant(ctx, tripID, variantID); err != nil {
+		return nil, err
+	}
+	
+	variant.IsPrimary = true
+	s.announce(ctx, events.TripUpdated, tripID, userID, map[string]interface{}{"fields": variantTripFields})
+	
+	return variant, nil
+}
+
+// variantTripFields are the trip fields copied from its primary route variant
+var variantTripFields = []string{"distance_km", "duration_hours", "elevation_gain_m", "route_geojson"}
+
+// measureVariant checks the variant's route and, when asked, measures its
+// distance from it
+func measureVariant(variant *TripRouteVariant, measureDistance bool) error {
+	length, ok := routeLengthKm(variant.RouteGeoJSON)
+	if !ok {
+		return ErrInvalidRoute
+	}
+	if measureDistance {
+		variant.DistanceKm = &length
+	}
+	return nil
+}
+
+// editableVariant loads a route variant of the trip the user may edit
+func (s *servicePg) editableVariant(ctx context.Context, userID, tripID, variantID string) (*TripRouteVariant, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	variant, err := s.repo.GetRouteVariant(ctx, variantID)
+	if err != nil {
+		return nil, err
+	}
+	if variant.TripID != tripID {
+		return nil, ErrVariantNotFound
+	}
+	
+	return variant, nil
+}
+
+func (s *servicePg) CloneTrip(ctx context.Context, userID, tripID string) (*Trip, error) {
+	sourceTrip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Check if user can view source trip
+	if !s.canUserAccessTrip(sourceTrip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	// Create new trip
+	newTrip := &Trip{
+		ID:          uuid.New().String(),
+		Title:       sourceTrip.Title + " (Copy)",
+		Description: sourceTrip.Description,
+		OwnerID:     userID,
+		CoverImage:  sourceTrip.CoverImage,
+		Privacy:     "private",
+		Status:      "planning",
+		StartDate:   sourceTrip.StartDate,
+		EndDate:     sourceTrip.EndDate,
+		Timezone:    sourceTrip.Timezone,
+		Tags:        sourceTrip.Tags,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	
+	if err := s.repo.Create(ctx, newTrip); err != nil {
+		return nil, fmt.Errorf("failed to clone trip: %w", err)
+	}
+	
+	// TODO: Clone waypoints and other related data
+	
+	return newTrip, nil
+}
+
+func (s *servicePg) TransferOwnership(ctx context.Context, userID, tripID, newOwnerID string) (*OwnershipTransfer, error) {
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Only the owner can hand the trip off
+	if !trip.IsOwner(userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	if newOwnerID == userID {
+		return nil, errors.New("you already own this trip")
+	}
+	
+	// The recipient must already be collaborating on the trip
+	if !trip.HasCollaborator(newOwnerID) {
+		return nil, errors.New("new owner must be a collaborator on the trip")
+	}
+	
+	transfer := &OwnershipTransfer{
+		EntityID:   tripID,
+		FromUserID: userID,
+		ToUserID:   newOwnerID,
+	}
+	
+	if err := s.repo.CreateOwnershipTransfer(ctx, transfer); err != nil {
+		return nil, err
+	}
+	
+	return transfer, nil
+}
+
+func (s *servicePg) AcceptOwnershipTransfer(ctx context.Context, userID, tripID string) (*Trip, error) {
+	transfer, err := s.repo.GetPendingOwnershipTransfer(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Only the recipient can accept
+	if transfer.ToUserID != userID {
+		return nil, ErrUnauthorized
+	}
+	
+	if err := s.repo.ResolveOwnershipTransfer(ctx, transfer, userID, true); err != nil {
+		return nil, err
+	}
+	
+	return s.repo.GetByID(ctx, tripID)
+}
+
+func (s *servicePg) DeclineOwnershipTransfer(ctx context.Context, userID, tripID string) error {
+	transfer, err := s.repo.GetPendingOwnershipTransfer(ctx, tripID)
+	if err != nil {
+		return err
+	}
+	
+	// The recipient can decline and the owner can withdraw the request
+	if transfer.ToUserID != userID && transfer.FromUserID != userID {
+		return ErrUnauthorized
+	}
+	
+	return s.repo.ResolveOwnershipTransfer(ctx, transfer, userID, false)
+}
+
+func (s *servicePg) SchedulePublication(ctx context.Context, userID, tripID string, input *SchedulePublicationInput) (*Trip, error) {
+	if s.publisher == nil {
+		return nil, ErrSchedulingNotConfigured
+	}
+	
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	if trip.Privacy == "public" {
+		return nil, ErrAlreadyPublic
+	}
+	
+	if !input.PublishAt.After(time.Now()) {
+		return nil, ErrPublishAtInPast
+	}
+	
+	if err := s.publisher.Schedule(ctx, trip, input.PublishAt); err != nil {
+		return nil, err
+	}
+	
+	return s.repo.GetByID(ctx, tripID)
+}
+
+func (s *servicePg) CancelScheduledPublication(ctx context.Context, userID, tripID string) (*Trip, error) {
+	if s.publisher == nil {
+		return nil, ErrSchedulingNotConfigured
+	}
+	
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	if trip.PublishAt == nil {
+		return nil, ErrNoScheduledPublication
+	}
+	
+	if err := s.publisher.Cancel(ctx, trip); err != nil {
+		return nil, err
+	}
+	
+	return s.repo.GetByID(ctx, tripID)
+}
+
+func (s *servicePg) SaveDraft(ctx context.Context, userID, tripID string, input *SaveDraftInput) (*TripDraft, error) {
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	encoded, err := json.Marshal(input.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode draft: %w", err)
+	}
+	if len(encoded) > MaxDraftSize {
+		return nil, ErrDraftTooLarge
+	}
+	
+	current, err := tripValues(trip)
+	if err != nil {
+		return nil, err
+	}
+	
+	draft := &TripDraft{
+		TripID: tripID,
+		UserID: userID,
+		Data:   input.Data,
+		Base:   draftBase(input.Data, current),
+	}
+	
+	if err := s.repo.SaveDraft(ctx, draft); err != nil {
+		return nil, err
+	}
+	
+	return draft, nil
+}
+
+func (s *servicePg) GetDraft(ctx context.Context, userID, tripID string) (*TripDraft, error) {
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	return s.repo.GetDraft(ctx, tripID, userID)
+}
+
+func (s *servicePg) DiscardDraft(ctx context.Context, userID, tripID string) error {
+	// Drafts belong to their author, so no trip permission is needed to drop one
+	return s.repo.DeleteDraft(ctx, tripID, userID)
+}
+
+func (s *servicePg) ApplyDraft(ctx context.Context, userID, tripID string) (*DraftApplyResult, error) {
+	trip, err := s.repo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	
+	draft, err := s.repo.GetDraft(ctx, tripID, userID)
+	if err != nil {
+		return nil, err
+	}
+	
+	current, err := tripValues(trip)
+	if err != nil {
+		return nil, err
+	}
+	
+	changes, applied, conflicts := mergeDraft(draft, current)
+	
+	result := &DraftApplyResult{
+		Trip:      trip,
+		Applied:   applied,
+		Conflicts: conflicts,
+	}
+	
+	if len(changes) > 0 {
+		var input UpdateTripInput
+		encoded, err := json.Marshal(changes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode draft changes: %w", err)
+		}
+		if err := json.Unmarshal(encoded, &input); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDraft, err)
+		}
+		if err := binding.Validator.ValidateStruct(&input); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDraft, err)
+		}
+		
+		updated, err := s.Update(ctx, userID, tripID, &input)
+		if err != nil {
+			return nil, err
+		}
+		result.Trip = updated
+	}
+	
+	if len(conflicts) == 0 {
+		if err := s.repo.DeleteDraft(ctx, tripID, userID); err != nil && !errors.Is(err, ErrDraftNotFound) {
+			return nil, err
+		}
+		return result, nil
+	}
+	
+	// Keep only the conflicting fields, rebased on the values the user has now
+	// seen, so applying again keeps the draft's version
+	remaining := &TripDraft{
+		TripID: tripID,
+		UserID: userID,
+		Data:   JSONB{},
+		Base:   JSONB{},
+	}
+	for _, conflict := range conflicts {
+		remaining.Data[conflict.Field] = conflict.Draft
+		remaining.Base[conflict.Field] = conflict.Current
+	}
+	if err := s.repo.DeleteDraft(ctx, tripID, userID); err != nil && !errors.Is(err, ErrDraftNotFound) {
+		return nil, err
+	}
+	if err := s.repo.SaveDraft(ctx, remaining); err != nil {
+		return nil, err
+	}
+	
+	return result, nil
+}
+
+// Helper methods
+
+// announce publishes a change to a trip when events are configured
+func (s *servicePg) announce(ctx context.Context, eventType, tripID, actorID string, data map[string]interface{}) {
+	if s.publisher == nil {
+		return
+	}
+	s.publisher.Announce(ctx, eventType, tripID, actorID, data)
+}
+
+// collaboratorForRole builds a collaborator with the default permissions for a role
+func collaboratorForRole(tripID, userID, role string) Collaborator {
+	return Collaborator{
+		TripID:                 tripID,
+		UserID:                 userID,
+		Role:                   role,
+		CanEdit:                role == "editor" || role == "admin",
+		CanDelete:              role == "admin",
+		CanInvite:              role == "admin",
+		CanModerateSuggestions: role == "admin" || role == "editor",
+		InvitedAt:              time.Now(),
+	}
+}
+
+func (s *servicePg) canUserAccessTrip(trip *Trip, userID string) bool {
+	return trip.VisibleTo(userID)
 }
 
 func (s *servicePg) canUserEditTrip(trip *Trip, userID string) bool {
@@ -1,364 +1,2677 @@
 package trips
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/Oferzz/newMap/apps/api/internal/config"
 	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/internal/elevation"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+	"github.com/Oferzz/newMap/apps/api/internal/locale"
+	"github.com/Oferzz/newMap/apps/api/internal/media"
+	"github.com/Oferzz/newMap/apps/api/pkg/pagination"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
-type mockTripRepository struct {
-	trips map[string]*Trip
+// mockRepository stubs the repository calls made by the service under test
+type mockRepository struct {
+	mock.Mock
+	Repository
 }
 
-func newMockTripRepository() *mockTripRepository {
-	return &mockTripRepository{
-		trips: make(map[string]*Trip),
+func (m *mockRepository) Create(ctx context.Context, trip *Trip) error {
+	args := m.Called(ctx, trip)
+	return args.Error(0)
+}
+
+func (m *mockRepository) GetByID(ctx context.Context, id string) (*Trip, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Trip), args.Error(1)
+}
+
+func (m *mockRepository) GetByIDWith(ctx context.Context, id string, relations Relations) (*Trip, error) {
+	args := m.Called(ctx, id, relations)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
 	}
+	return args.Get(0).(*Trip), args.Error(1)
+}
+
+func (m *mockRepository) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	args := m.Called(ctx, id, updates)
+	return args.Error(0)
+}
+
+func (m *mockRepository) SetSummary(ctx context.Context, tripID, summary string) error {
+	args := m.Called(ctx, tripID, summary)
+	return args.Error(0)
+}
+
+func (m *mockRepository) SetSuggestedTags(ctx context.Context, tripID string, suggested, dismissed []string) error {
+	args := m.Called(ctx, tripID, suggested, dismissed)
+	return args.Error(0)
+}
+
+func (m *mockRepository) CountPlacesNear(ctx context.Context, position []float64, radiusM float64, categories []string) (int, error) {
+	args := m.Called(ctx, position, radiusM, categories)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockRepository) RouteShareNear(ctx context.Context, route *GeoJSONRoute, corridorM float64, categories []string) (float64, error) {
+	args := m.Called(ctx, route, corridorM, categories)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *mockRepository) ReviewCondition(ctx context.Context, id, userID, status, note string) error {
+	args := m.Called(ctx, id, userID, status, note)
+	return args.Error(0)
+}
+
+func (m *mockRepository) CreateConditionVerifier(ctx context.Context, verifier *ConditionVerifier) error {
+	args := m.Called(ctx, verifier)
+	return args.Error(0)
+}
+
+func (m *mockRepository) IsConditionVerifier(ctx context.Context, userID, conditionID string) (bool, error) {
+	args := m.Called(ctx, userID, conditionID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockRepository) CreateConditionSubscription(ctx context.Context, subscription *ConditionSubscription, max int) error {
+	args := m.Called(ctx, subscription, max)
+	return args.Error(0)
 }
 
-func (m *mockTripRepository) Create(ctx context.Context, trip *Trip) error {
-	trip.ID = uuid.New().String()
-	trip.CreatedAt = time.Now()
-	trip.UpdatedAt = time.Now()
-	m.trips[trip.ID] = trip
-	return nil
+func (m *mockRepository) QueueConditionAlerts(ctx context.Context, conditionID string) ([]*ConditionSubscription, error) {
+	args := m.Called(ctx, conditionID)
+	return args.Get(0).([]*ConditionSubscription), args.Error(1)
 }
 
-func (m *mockTripRepository) GetByID(ctx context.Context, id string) (*Trip, error) {
-	if trip, exists := m.trips[id]; exists {
-		return trip, nil
+func (m *mockRepository) TakeConditionAlerts(ctx context.Context, subscriptionID string) (*ConditionSubscription, []*ConditionAlert, error) {
+	args := m.Called(ctx, subscriptionID)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
 	}
-	return nil, ErrTripNotFound
+	return args.Get(0).(*ConditionSubscription), args.Get(1).([]*ConditionAlert), args.Error(2)
 }
 
-func (m *mockTripRepository) Update(ctx context.Context, trip *Trip) error {
-	if trip, exists := m.trips[id.Hex()]; exists {
-		// Simple update simulation
-		if name, ok := update["name"].(string); ok {
-			trip.Name = name
-		}
-		if desc, ok := update["description"].(string); ok {
-			trip.Description = desc
-		}
-		trip.UpdatedAt = time.Now()
-		return nil
+func (m *mockRepository) GetOfflinePack(ctx context.Context, tripID string) (*OfflinePack, error) {
+	args := m.Called(ctx, tripID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
 	}
-	return ErrTripNotFound
+	return args.Get(0).(*OfflinePack), args.Error(1)
+}
+
+func (m *mockRepository) CompleteOfflinePack(ctx context.Context, pack *OfflinePack) (bool, error) {
+	args := m.Called(ctx, pack)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockRepository) ListLayers(ctx context.Context, tripID string) ([]*TripLayer, error) {
+	args := m.Called(ctx, tripID)
+	return args.Get(0).([]*TripLayer), args.Error(1)
+}
+
+func (m *mockRepository) ListMediaFiles(ctx context.Context, mediaIDs []string) ([]*OfflinePackMedia, error) {
+	args := m.Called(ctx, mediaIDs)
+	return args.Get(0).([]*OfflinePackMedia), args.Error(1)
 }
 
-func (m *mockTripRepository) Delete(ctx context.Context, id string) error {
-	if _, exists := m.trips[id.Hex()]; exists {
-		delete(m.trips, id.Hex())
-		return nil
+func (m *mockRepository) AddWaypoints(ctx context.Context, tripID string, waypoints []*Waypoint) error {
+	args := m.Called(ctx, tripID, waypoints)
+	return args.Error(0)
+}
+
+func (m *mockRepository) ListAnnotations(ctx context.Context, tripID string) ([]*TripAnnotation, error) {
+	args := m.Called(ctx, tripID)
+	return args.Get(0).([]*TripAnnotation), args.Error(1)
+}
+
+func (m *mockRepository) ListRouteVariants(ctx context.Context, tripID string) ([]*TripRouteVariant, error) {
+	args := m.Called(ctx, tripID)
+	return args.Get(0).([]*TripRouteVariant), args.Error(1)
+}
+
+func (m *mockRepository) UpdateWaypoint(ctx context.Context, waypoint *Waypoint) error {
+	args := m.Called(ctx, waypoint)
+	return args.Error(0)
+}
+
+func (m *mockRepository) ReorderWaypoints(ctx context.Context, tripID string, waypointIDs []string) error {
+	args := m.Called(ctx, tripID, waypointIDs)
+	return args.Error(0)
+}
+
+func (m *mockRepository) GetWaypoints(ctx context.Context, tripID string) ([]Waypoint, error) {
+	args := m.Called(ctx, tripID)
+	return args.Get(0).([]Waypoint), args.Error(1)
+}
+
+func (m *mockRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *mockRepository) CreateCompletion(ctx context.Context, completion *ActivityCompletion) error {
+	args := m.Called(ctx, completion)
+	return args.Error(0)
+}
+
+func (m *mockRepository) CreateRating(ctx context.Context, rating *ActivityRating) error {
+	args := m.Called(ctx, rating)
+	return args.Error(0)
+}
+
+func (m *mockRepository) CreateShareLink(ctx context.Context, link *ActivityShareLink) error {
+	args := m.Called(ctx, link)
+	return args.Error(0)
+}
+
+func (m *mockRepository) CreateCondition(ctx context.Context, condition *ActivityCondition) error {
+	args := m.Called(ctx, condition)
+	return args.Error(0)
+}
+
+func (m *mockRepository) GetCondition(ctx context.Context, id string) (*ActivityCondition, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
 	}
-	return ErrTripNotFound
+	return args.Get(0).(*ActivityCondition), args.Error(1)
 }
 
-func (m *mockTripRepository) List(ctx context.Context, filter TripFilter, opts *options.FindOptions) ([]*Trip, error) {
-	var trips []*Trip
-	for _, trip := range m.trips {
-		// Simple filter implementation
-		if filter.OwnerID != nil && trip.OwnerID != *filter.OwnerID {
-			continue
-		}
-		if filter.Status != nil && trip.Status != *filter.Status {
-			continue
-		}
-		trips = append(trips, trip)
+func (m *mockRepository) VerifyCondition(ctx context.Context, id, userID string) error {
+	args := m.Called(ctx, id, userID)
+	return args.Error(0)
+}
+
+func (m *mockRepository) GetDatePoll(ctx context.Context, id string) (*DatePoll, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
 	}
-	return trips, nil
+	return args.Get(0).(*DatePoll), args.Error(1)
 }
 
-func (m *mockTripRepository) Count(ctx context.Context, filter TripFilter) (int64, error) {
-	trips, _ := m.List(ctx, filter, nil)
-	return int64(len(trips)), nil
+func (m *mockRepository) FinalizeDatePoll(ctx context.Context, pollID, optionID string) error {
+	args := m.Called(ctx, pollID, optionID)
+	return args.Error(0)
 }
 
-func (m *mockTripRepository) AddCollaborator(ctx context.Context, tripID, userID, role string, canEdit, canDelete, canInvite, canModerate bool) error {
-	if trip, exists := m.trips[tripID.Hex()]; exists {
-		trip.Collaborators = append(trip.Collaborators, *collaborator)
-		trip.UpdatedAt = time.Now()
-		return nil
+func (m *mockRepository) GetParticipants(ctx context.Context, tripID string) (*ParticipantRoster, error) {
+	args := m.Called(ctx, tripID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
 	}
-	return ErrTripNotFound
+	return args.Get(0).(*ParticipantRoster), args.Error(1)
 }
 
-func (m *mockTripRepository) RemoveCollaborator(ctx context.Context, tripID, userID string) error {
-	if trip, exists := m.trips[tripID.Hex()]; exists {
-		for i, collab := range trip.Collaborators {
-			if collab.UserID == userID {
-				trip.Collaborators = append(trip.Collaborators[:i], trip.Collaborators[i+1:]...)
-				trip.UpdatedAt = time.Now()
-				return nil
-			}
-		}
-		return nil
+// UpdateParticipants applies the update to the roster given to Return
+func (m *mockRepository) UpdateParticipants(ctx context.Context, tripID string, update func(*ParticipantRoster) error) (*ParticipantRoster, error) {
+	args := m.Called(ctx, tripID)
+	roster := args.Get(0).(*ParticipantRoster)
+	if err := update(roster); err != nil {
+		return nil, err
+	}
+	return roster, args.Error(1)
+}
+
+func (m *mockRepository) List(ctx context.Context, filters TripFilters) ([]*Trip, error) {
+	args := m.Called(ctx, filters)
+	return args.Get(0).([]*Trip), args.Error(1)
+}
+
+func (m *mockRepository) Count(ctx context.Context, filters TripFilters) (int64, error) {
+	args := m.Called(ctx, filters)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockRepository) CreateOwnershipTransfer(ctx context.Context, transfer *OwnershipTransfer) error {
+	args := m.Called(ctx, transfer)
+	return args.Error(0)
+}
+
+func (m *mockRepository) GetPendingOwnershipTransfer(ctx context.Context, tripID string) (*OwnershipTransfer, error) {
+	args := m.Called(ctx, tripID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*OwnershipTransfer), args.Error(1)
+}
+
+func (m *mockRepository) ResolveOwnershipTransfer(ctx context.Context, transfer *OwnershipTransfer, actorID string, accept bool) error {
+	args := m.Called(ctx, transfer, actorID, accept)
+	return args.Error(0)
+}
+
+func (m *mockRepository) AddCollaborators(ctx context.Context, tripID string, collaborators []Collaborator) error {
+	args := m.Called(ctx, tripID, collaborators)
+	return args.Error(0)
+}
+
+func (m *mockRepository) ListScheduledPublications(ctx context.Context) ([]*ScheduledPublication, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*ScheduledPublication), args.Error(1)
+}
+
+func (m *mockRepository) FindBailoutPlaces(ctx context.Context, route *GeoJSONRoute, corridorM float64, categories []string) ([]*BailoutPoint, error) {
+	args := m.Called(ctx, route, corridorM, categories)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*BailoutPoint), args.Error(1)
+}
+
+const (
+	ownerID  = "00000000-0000-0000-0000-000000000001"
+	editorID = "00000000-0000-0000-0000-000000000002"
+	viewerID = "00000000-0000-0000-0000-000000000003"
+	tripID   = "20000000-0000-0000-0000-000000000001"
+)
+
+func privateTrip() *Trip {
+	return &Trip{
+		ID:      tripID,
+		Title:   "Test Trip",
+		OwnerID: ownerID,
+		Privacy: "private",
+		Collaborators: []Collaborator{
+			{TripID: tripID, UserID: editorID, Role: "editor", CanEdit: true},
+			{TripID: tripID, UserID: viewerID, Role: "viewer"},
+		},
 	}
-	return ErrTripNotFound
 }
 
-func (m *mockTripRepository) UpdateCollaboratorRole(ctx context.Context, tripID, userID, role string, canEdit, canDelete, canInvite, canModerate bool) error {
-	if trip, exists := m.trips[tripID.Hex()]; exists {
-		for i, collab := range trip.Collaborators {
-			if collab.UserID == userID {
-				trip.Collaborators[i].Role = users.Role(role)
-				trip.UpdatedAt = time.Now()
-				return nil
+func TestService_Create(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("applies defaults", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("Create", ctx, mock.AnythingOfType("*trips.Trip")).Return(nil).Once()
+
+		trip, err := service.Create(ctx, ownerID, &CreateTripInput{
+			Title: "Test Trip",
+			Tags:  []string{"test"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, ownerID, trip.OwnerID)
+		assert.Equal(t, "private", trip.Privacy)
+		assert.Equal(t, "planning", trip.Status)
+		assert.Equal(t, "UTC", trip.Timezone)
+		assert.Equal(t, "general", trip.ActivityType)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("estimates a difficulty left out", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("Create", ctx, mock.AnythingOfType("*trips.Trip")).Return(nil).Once()
+
+		distance := 30.0
+		gain := 1500
+		trip, err := service.Create(ctx, ownerID, &CreateTripInput{
+			Title:          "Ridge Walk",
+			ActivityType:   "hiking",
+			DistanceKm:     &distance,
+			ElevationGainM: &gain,
+		})
+		require.NoError(t, err)
+		assert.NotEmpty(t, trip.DifficultyLevel)
+		assert.True(t, trip.DifficultyEstimated)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		dbErr := errors.New("database error")
+		repo.On("Create", ctx, mock.Anything).Return(dbErr).Once()
+
+		trip, err := service.Create(ctx, ownerID, &CreateTripInput{Title: "Test Trip"})
+		assert.ErrorIs(t, err, dbErr)
+		assert.Nil(t, trip)
+	})
+
+	t.Run("unknown timezone", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+
+		_, err := service.Create(ctx, ownerID, &CreateTripInput{Title: "Test Trip", Timezone: "Europe/Atlantis"})
+		assert.ErrorIs(t, err, ErrInvalidTimezone)
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("content is described in plain text", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("Create", ctx, mock.AnythingOfType("*trips.Trip")).Return(nil).Once()
+
+		trip, err := service.Create(ctx, ownerID, &CreateTripInput{
+			Title:       "Test Trip",
+			Description: "Replaced",
+			Content: TripContent{
+				{Type: BlockText, Text: "A **long** day <script>alert(1)</script>"},
+				{Type: BlockWarning, Title: "Tides", Text: "Cross before noon", Caption: "dropped"},
+				{Type: BlockElevationChart},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "A long day alert(1)\n\nTides: Cross before noon", trip.Description)
+		assert.Equal(t, ContentBlock{Type: BlockWarning, Title: "Tides", Text: "Cross before noon", Severity: WarningCaution}, trip.Content[1])
+	})
+
+	t.Run("invalid content", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+
+		_, err := service.Create(ctx, ownerID, &CreateTripInput{
+			Title:   "Test Trip",
+			Content: TripContent{{Type: BlockImage, MediaID: "cover.jpg"}},
+		})
+		assert.ErrorIs(t, err, ErrInvalidContent)
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestService_Privacy(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		privacy    string
+		visibility string
+		want       string
+		err        error
+	}{
+		{"default", "", "", PrivacyPrivate, nil},
+		{"privacy", PrivacyFriends, "", PrivacyFriends, nil},
+		{"visibility from older clients", "", VisibilityPublic, PrivacyPublic, nil},
+		{"both agreeing", PrivacyInviteOnly, VisibilityPrivate, PrivacyInviteOnly, nil},
+		{"both disagreeing", PrivacyPrivate, VisibilityPublic, "", ErrPrivacyMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run("create "+tt.name, func(t *testing.T) {
+			repo := new(mockRepository)
+			service := NewService(repo, nil, nil)
+			repo.On("Create", ctx, mock.AnythingOfType("*trips.Trip")).Return(nil).Maybe()
+
+			trip, err := service.Create(ctx, ownerID, &CreateTripInput{
+				Title:      "Test Trip",
+				Privacy:    tt.privacy,
+				Visibility: tt.visibility,
+			})
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
 			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, trip.Privacy)
+			assert.Equal(t, VisibilityFor(tt.want), trip.Visibility)
+		})
+	}
+
+	t.Run("update only writes privacy", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		visibility := VisibilityPublic
+		repo.On("GetByID", ctx, tripID).Return(privateTrip(), nil).Twice()
+		repo.On("Update", ctx, tripID, mock.MatchedBy(func(updates map[string]interface{}) bool {
+			_, hasVisibility := updates["visibility"]
+			return updates["privacy"] == PrivacyPublic && !hasVisibility
+		})).Return(nil).Once()
+
+		_, err := service.Update(ctx, ownerID, tripID, &UpdateTripInput{Visibility: &visibility})
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("update rejects disagreeing settings", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		privacy, visibility := PrivacyFriends, VisibilityPublic
+		repo.On("GetByID", ctx, tripID).Return(privateTrip(), nil).Once()
+
+		_, err := service.Update(ctx, ownerID, tripID, &UpdateTripInput{Privacy: &privacy, Visibility: &visibility})
+		assert.ErrorIs(t, err, ErrPrivacyMismatch)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestService_ListTotal(t *testing.T) {
+	ctx := context.Background()
+	page := func(n int) []*Trip {
+		trips := make([]*Trip, n)
+		for i := range trips {
+			trips[i] = &Trip{ID: tripID}
 		}
-		return nil
+		return trips
 	}
-	return ErrTripNotFound
+
+	t.Run("full pages are counted", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("List", ctx, mock.Anything).Return(page(20), nil).Once()
+		repo.On("Count", ctx, mock.MatchedBy(func(f TripFilters) bool { return f.OwnerID == ownerID })).Return(int64(57), nil).Once()
+
+		trips, total, err := service.GetUserTrips(ctx, ownerID, 20, 20)
+		require.NoError(t, err)
+		assert.Len(t, trips, 20)
+		assert.Equal(t, int64(57), total)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("a short page is the last", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("List", ctx, mock.Anything).Return(page(17), nil).Once()
+
+		_, total, err := service.Search(ctx, ownerID, "ridge", 20, 40)
+		require.NoError(t, err)
+		assert.Equal(t, int64(57), total)
+		repo.AssertNotCalled(t, "Count", mock.Anything, mock.Anything)
+	})
+
+	t.Run("cursor pages are not counted", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("List", ctx, mock.Anything).Return(page(20), nil).Once()
+
+		_, total, err := service.List(ctx, ownerID, &TripFilter{After: pagination.New(time.Now(), tripID)}, 20, 0)
+		require.NoError(t, err)
+		assert.Zero(t, total)
+		repo.AssertNotCalled(t, "Count", mock.Anything, mock.Anything)
+	})
+}
+
+func TestService_GetByIDWith(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("collaborators load for the permission check", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+
+		trip, err := service.GetByIDWith(ctx, viewerID, tripID, Relations{})
+		require.NoError(t, err)
+		assert.Nil(t, trip.Collaborators)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("private trip hidden from strangers", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, AllRelations).Return(privateTrip(), nil).Once()
+
+		trip, err := service.GetByID(ctx, "stranger", tripID)
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		assert.Nil(t, trip)
+	})
+
+	t.Run("trip not found", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, AllRelations).Return(nil, ErrTripNotFound).Once()
+
+		_, err := service.GetByID(ctx, ownerID, tripID)
+		assert.ErrorIs(t, err, ErrTripNotFound)
+	})
+}
+
+// friendsRepository is a user repository that only knows who is friends
+// with whom
+type friendsRepository struct {
+	users.Repository
+	friends map[string][]string
 }
 
-func (m *mockTripRepository) IncrementPlaceCount(ctx context.Context, tripID string, delta int) error {
-	if trip, exists := m.trips[tripID.Hex()]; exists {
-		trip.PlaceCount += delta
-		return nil
+func (r *friendsRepository) GetFriends(ctx context.Context, userID string) ([]*users.User, error) {
+	var friends []*users.User
+	for _, id := range r.friends[userID] {
+		friends = append(friends, &users.User{ID: id})
 	}
-	return ErrTripNotFound
+	return friends, nil
 }
 
-func (m *mockTripRepository) IncrementViewCount(ctx context.Context, tripID string) error {
-	if trip, exists := m.trips[tripID.Hex()]; exists {
-		trip.ViewCount++
-		return nil
+func TestTrip_ForAudience(t *testing.T) {
+	arrival := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	publishAt := arrival.Add(-24 * time.Hour)
+	trip := privateTrip()
+	trip.EmergencyContacts = &JSONB{"ranger": "555-0100"}
+	trip.SharedWith = []string{viewerID}
+	trip.PublishAt = &publishAt
+	trip.Waypoints = []Waypoint{
+		{ID: "home", Notes: "spare key under the mat", ArrivalTime: &arrival, Place: &Place{
+			Name: "Cabin", Address: "12 Pine Rd", City: "Bend", Privacy: PrivacyPrivate,
+			Location: &GeoJSON{Type: "Point", Coordinates: []float64{-121.31234, 44.05876}},
+		}},
+		{ID: "summit", Notes: "lunch here", ArrivalTime: &arrival, Place: &Place{Name: "Summit", Privacy: PrivacyPublic}},
 	}
-	return ErrTripNotFound
+
+	t.Run("collaborators see everything", func(t *testing.T) {
+		view := trip.ForAudience(AudienceCollaborator)
+		assert.Equal(t, trip, view)
+		assert.NotSame(t, trip, view)
+	})
+
+	t.Run("friends see private places roughly", func(t *testing.T) {
+		view := trip.ForAudience(AudienceFriend)
+		assert.Nil(t, view.EmergencyContacts)
+		assert.Nil(t, view.SharedWith)
+		assert.Nil(t, view.PublishAt)
+		assert.Len(t, view.Collaborators, 2)
+
+		require.Len(t, view.Waypoints, 2)
+		home := view.Waypoints[0]
+		assert.Equal(t, "spare key under the mat", home.Notes)
+		assert.Equal(t, "Cabin", home.Place.Name)
+		assert.Equal(t, "Bend", home.Place.City)
+		assert.Empty(t, home.Place.Address)
+		assert.Equal(t, []float64{-121.31, 44.06}, home.Place.Location.Coordinates)
+		assert.Equal(t, trip.Waypoints[1], view.Waypoints[1])
+	})
+
+	t.Run("the public sees no private places, notes or times", func(t *testing.T) {
+		view := trip.ForAudience(AudiencePublic)
+		assert.Nil(t, view.EmergencyContacts)
+		assert.Nil(t, view.Collaborators)
+
+		require.Len(t, view.Waypoints, 1)
+		assert.Equal(t, "summit", view.Waypoints[0].ID)
+		assert.Empty(t, view.Waypoints[0].Notes)
+		assert.Nil(t, view.Waypoints[0].ArrivalTime)
+	})
+
+	// The trip itself is left as it was
+	assert.NotNil(t, trip.EmergencyContacts)
+	assert.Len(t, trip.Waypoints, 2)
+	assert.Equal(t, "12 Pine Rd", trip.Waypoints[0].Place.Address)
+	assert.Equal(t, []float64{-121.31234, 44.05876}, trip.Waypoints[0].Place.Location.Coordinates)
+	assert.Equal(t, "lunch here", trip.Waypoints[1].Notes)
+}
+
+func TestService_Audience(t *testing.T) {
+	ctx := context.Background()
+	userRepo := &friendsRepository{friends: map[string][]string{ownerID: {"friend"}}}
+
+	t.Run("audience of each viewer", func(t *testing.T) {
+		service := NewService(new(mockRepository), userRepo, nil)
+		trip := privateTrip()
+
+		assert.Equal(t, "", service.AudienceOf(ctx, ownerID, trip))
+		assert.Equal(t, AudienceCollaborator, service.AudienceOf(ctx, viewerID, trip))
+		assert.Equal(t, AudienceFriend, service.AudienceOf(ctx, "friend", trip))
+		assert.Equal(t, AudiencePublic, service.AudienceOf(ctx, "stranger", trip))
+		assert.Equal(t, AudiencePublic, service.AudienceOf(ctx, "", trip))
+	})
+
+	t.Run("owners preview trips before the audience can see them", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, userRepo, nil)
+		repo.On("GetByIDWith", ctx, tripID, AllRelations).Return(privateTrip(), nil).Once()
+
+		preview, err := service.ViewAs(ctx, ownerID, tripID, AudiencePublic, AllRelations)
+		require.NoError(t, err)
+		assert.Equal(t, AudiencePublic, preview.Audience)
+		assert.False(t, preview.Visible)
+		assert.Nil(t, preview.Trip.Collaborators)
+	})
+
+	t.Run("only owners preview", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, userRepo, nil)
+		repo.On("GetByIDWith", ctx, tripID, AllRelations).Return(privateTrip(), nil).Once()
+
+		_, err := service.ViewAs(ctx, editorID, tripID, AudiencePublic, AllRelations)
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("unknown audience", func(t *testing.T) {
+		service := NewService(new(mockRepository), userRepo, nil)
+
+		_, err := service.ViewAs(ctx, ownerID, tripID, "everyone", AllRelations)
+		assert.ErrorIs(t, err, ErrInvalidAudience)
+	})
 }
 
-type mockUserRepository struct {
-	users map[string]*users.User
+func TestService_Permissions(t *testing.T) {
+	ctx := context.Background()
+	title := "Updated Trip"
+
+	t.Run("owner and editors can update", func(t *testing.T) {
+		for _, userID := range []string{ownerID, editorID} {
+			repo := new(mockRepository)
+			service := NewService(repo, nil, nil)
+			repo.On("GetByID", ctx, tripID).Return(privateTrip(), nil).Twice()
+			repo.On("Update", ctx, tripID, mock.MatchedBy(func(updates map[string]interface{}) bool {
+				return updates["title"] == title
+			})).Return(nil).Once()
+
+			_, err := service.Update(ctx, userID, tripID, &UpdateTripInput{Title: &title})
+			assert.NoError(t, err, userID)
+			repo.AssertExpectations(t)
+		}
+	})
+
+	t.Run("viewers cannot update", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(privateTrip(), nil).Once()
+
+		_, err := service.Update(ctx, viewerID, tripID, &UpdateTripInput{Title: &title})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("only the owner can delete", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(privateTrip(), nil).Twice()
+		repo.On("Delete", ctx, tripID).Return(nil).Once()
+
+		assert.ErrorIs(t, service.Delete(ctx, editorID, tripID), ErrUnauthorized)
+		assert.NoError(t, service.Delete(ctx, ownerID, tripID))
+		repo.AssertExpectations(t)
+	})
 }
 
-func newMockUserRepository() *mockUserRepository {
-	return &mockUserRepository{
-		users: make(map[string]*users.User),
+func TestService_OwnershipTransfer(t *testing.T) {
+	ctx := context.Background()
+	pending := func() *OwnershipTransfer {
+		return &OwnershipTransfer{ID: "transfer-1", EntityType: "trip", EntityID: tripID, FromUserID: ownerID, ToUserID: editorID, Status: "pending"}
 	}
+
+	t.Run("owner offers the trip to a collaborator", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(privateTrip(), nil).Once()
+		repo.On("CreateOwnershipTransfer", ctx, &OwnershipTransfer{EntityID: tripID, FromUserID: ownerID, ToUserID: editorID}).Return(nil).Once()
+
+		transfer, err := service.TransferOwnership(ctx, ownerID, tripID, editorID)
+		require.NoError(t, err)
+		assert.Equal(t, editorID, transfer.ToUserID)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("rejected offers", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			userID     string
+			newOwnerID string
+		}{
+			{"not the owner", editorID, viewerID},
+			{"to the owner", ownerID, ownerID},
+			{"to a stranger", ownerID, "00000000-0000-0000-0000-000000000009"},
+		}
+		for _, tt := range tests {
+			repo := new(mockRepository)
+			service := NewService(repo, nil, nil)
+			repo.On("GetByID", ctx, tripID).Return(privateTrip(), nil).Once()
+
+			_, err := service.TransferOwnership(ctx, tt.userID, tripID, tt.newOwnerID)
+			assert.Error(t, err, tt.name)
+			repo.AssertNotCalled(t, "CreateOwnershipTransfer", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("recipient accepts", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		transferred := privateTrip()
+		transferred.OwnerID = editorID
+		repo.On("GetPendingOwnershipTransfer", ctx, tripID).Return(pending(), nil).Once()
+		repo.On("ResolveOwnershipTransfer", ctx, pending(), editorID, true).Return(nil).Once()
+		repo.On("GetByID", ctx, tripID).Return(transferred, nil).Once()
+
+		trip, err := service.AcceptOwnershipTransfer(ctx, editorID, tripID)
+		require.NoError(t, err)
+		assert.Equal(t, editorID, trip.OwnerID)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("only the recipient accepts", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetPendingOwnershipTransfer", ctx, tripID).Return(pending(), nil).Once()
+
+		_, err := service.AcceptOwnershipTransfer(ctx, ownerID, tripID)
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNotCalled(t, "ResolveOwnershipTransfer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("recipient declines and owner withdraws", func(t *testing.T) {
+		for _, userID := range []string{editorID, ownerID} {
+			repo := new(mockRepository)
+			service := NewService(repo, nil, nil)
+			repo.On("GetPendingOwnershipTransfer", ctx, tripID).Return(pending(), nil).Once()
+			repo.On("ResolveOwnershipTransfer", ctx, pending(), userID, false).Return(nil).Once()
+
+			assert.NoError(t, service.DeclineOwnershipTransfer(ctx, userID, tripID), userID)
+			repo.AssertExpectations(t)
+		}
+	})
+
+	t.Run("others cannot decline", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetPendingOwnershipTransfer", ctx, tripID).Return(pending(), nil).Once()
+
+		assert.ErrorIs(t, service.DeclineOwnershipTransfer(ctx, viewerID, tripID), ErrUnauthorized)
+	})
+
+	t.Run("nothing pending", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetPendingOwnershipTransfer", ctx, tripID).Return(nil, ErrTransferNotFound).Twice()
+
+		_, err := service.AcceptOwnershipTransfer(ctx, editorID, tripID)
+		assert.ErrorIs(t, err, ErrTransferNotFound)
+		assert.ErrorIs(t, service.DeclineOwnershipTransfer(ctx, editorID, tripID), ErrTransferNotFound)
+	})
 }
 
-func (m *mockUserRepository) Create(ctx context.Context, user *users.User) error {
-	user.ID = primitive.NewObjectID()
-	m.users[user.Email] = user
-	m.users[user.ID.Hex()] = user
-	return nil
+// directoryRepository looks up users by ID and email
+type directoryRepository struct {
+	users.Repository
+	users []*users.User
 }
 
-func (m *mockUserRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*users.User, error) {
-	if user, exists := m.users[id.Hex()]; exists {
-		return user, nil
+func (r *directoryRepository) GetByID(ctx context.Context, id string) (*users.User, error) {
+	for _, user := range r.users {
+		if user.ID == id {
+			return user, nil
+		}
 	}
 	return nil, users.ErrUserNotFound
 }
 
-func (m *mockUserRepository) GetByEmail(ctx context.Context, email string) (*users.User, error) {
-	if user, exists := m.users[email]; exists {
-		return user, nil
+func (r *directoryRepository) GetByEmail(ctx context.Context, email string) (*users.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
 	}
 	return nil, users.ErrUserNotFound
 }
 
-func (m *mockUserRepository) GetByUsername(ctx context.Context, username string) (*users.User, error) {
-	return nil, users.ErrUserNotFound
+func TestService_BulkInviteCollaborators(t *testing.T) {
+	ctx := context.Background()
+	const (
+		aliceID = "00000000-0000-0000-0000-00000000000a"
+		bobID   = "00000000-0000-0000-0000-00000000000b"
+	)
+	userRepo := &directoryRepository{users: []*users.User{
+		{ID: aliceID, Email: "alice@example.com"},
+		{ID: bobID, Email: "bob@example.com"},
+		{ID: editorID, Email: "editor@example.com"},
+	}}
+	input := &BulkInviteInput{Invites: []BulkInviteEntry{
+		{UserID: aliceID, Role: "editor"},
+		{Email: "bob@example.com", Role: "admin"},
+		{Email: "nobody@example.com", Role: "viewer"},
+		{UserID: editorID, Role: "viewer"},
+		{Email: "alice@example.com", Role: "viewer"},
+		{Role: "viewer"},
+	}}
+	valid := mock.MatchedBy(func(collaborators []Collaborator) bool {
+		return len(collaborators) == 2 &&
+			collaborators[0].UserID == aliceID && collaborators[0].CanEdit && !collaborators[0].CanInvite &&
+			collaborators[1].UserID == bobID && collaborators[1].CanInvite
+	})
+
+	t.Run("invites the valid entries and reports the rest", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, userRepo, nil)
+		repo.On("GetByID", ctx, tripID).Return(privateTrip(), nil).Once()
+		repo.On("AddCollaborators", ctx, tripID, valid).Return(nil).Once()
+
+		results, err := service.BulkInviteCollaborators(ctx, ownerID, tripID, input)
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+
+		require.Len(t, results, 6)
+		assert.True(t, results[0].Success)
+		assert.True(t, results[1].Success)
+		assert.Equal(t, bobID, results[1].UserID)
+		assert.Equal(t, ErrUserNotFound.Error(), results[2].Error)
+		assert.Equal(t, ErrAlreadyCollaborator.Error(), results[3].Error)
+		assert.Equal(t, "duplicate entry", results[4].Error)
+		assert.NotEmpty(t, results[5].Error)
+		for _, result := range results[2:] {
+			assert.False(t, result.Success)
+		}
+	})
+
+	t.Run("a failed transaction fails every valid entry", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, userRepo, nil)
+		repo.On("GetByID", ctx, tripID).Return(privateTrip(), nil).Once()
+		repo.On("AddCollaborators", ctx, tripID, valid).Return(sql.ErrConnDone).Once()
+
+		results, err := service.BulkInviteCollaborators(ctx, ownerID, tripID, input)
+		require.NoError(t, err)
+		for _, result := range results {
+			assert.False(t, result.Success)
+		}
+		assert.Equal(t, sql.ErrConnDone.Error(), results[0].Error)
+		assert.Equal(t, sql.ErrConnDone.Error(), results[1].Error)
+	})
+
+	t.Run("nothing valid to invite", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, userRepo, nil)
+		repo.On("GetByID", ctx, tripID).Return(privateTrip(), nil).Once()
+
+		results, err := service.BulkInviteCollaborators(ctx, ownerID, tripID, &BulkInviteInput{Invites: input.Invites[2:4]})
+		require.NoError(t, err)
+		assert.Len(t, results, 2)
+		repo.AssertNotCalled(t, "AddCollaborators", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("only those who may invite", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, userRepo, nil)
+		repo.On("GetByID", ctx, tripID).Return(privateTrip(), nil).Once()
+
+		_, err := service.BulkInviteCollaborators(ctx, viewerID, tripID, input)
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
 }
 
-func (m *mockUserRepository) Update(ctx context.Context, id primitive.ObjectID, update *users.UpdateUserInput) error {
-	return nil
+func TestService_GetTripStats(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mockRepository)
+	service := NewService(repo, nil, nil)
+
+	entry := 40.0
+	trip := privateTrip()
+	trip.AccessFees = AccessFees{{Kind: FeeParkingPass, Name: "Day pass", Per: FeePerVehicle}}
+	trip.Waypoints = []Waypoint{
+		{ID: "w1", Kind: WaypointStop, Place: &Place{ID: "p1", Name: "Park", AccessFees: AccessFees{
+			{Kind: FeeEntryFee, Name: "Entry", Amount: &entry, Currency: "ILS"},
+		}}},
+		{ID: "w2", Kind: WaypointStop, Place: &Place{ID: "p2", Name: "Lookout"}},
+	}
+	repo.On("GetByID", ctx, tripID).Return(trip, nil).Once()
+
+	stats, err := service.GetTripStats(ctx, viewerID, tripID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.TotalWaypoints)
+	require.Len(t, stats.AccessFees.Fees, 2)
+	assert.Equal(t, "Park", stats.AccessFees.Fees[1].PlaceName)
+	assert.Equal(t, []FeeTotal{{Currency: "ILS", Per: FeePerPerson, Amount: 40}}, stats.AccessFees.Totals)
+	assert.Equal(t, 1, stats.AccessFees.Unpriced)
 }
 
-func (m *mockUserRepository) UpdatePassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error {
-	return nil
+func TestService_ImportGPX(t *testing.T) {
+	ctx := context.Background()
+	file := `<gpx><trk><trkseg>
+		<trkpt lat="46.0" lon="7.0"><ele>1000</ele></trkpt>
+		<trkpt lat="46.01" lon="7.0"><ele>1120.4</ele></trkpt>
+	</trkseg></trk></gpx>`
+
+	t.Run("sets the route and its measurements", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(privateTrip(), nil).Twice()
+
+		var updates map[string]interface{}
+		repo.On("Update", ctx, tripID, mock.Anything).
+			Run(func(args mock.Arguments) { updates = args.Get(2).(map[string]interface{}) }).
+			Return(nil).Once()
+
+		_, err := service.ImportGPX(ctx, editorID, tripID, strings.NewReader(file))
+		require.NoError(t, err)
+
+		assert.Equal(t, &GeoJSONRoute{
+			Type:        "LineString",
+			Coordinates: [][]float64{{7, 46, 1000}, {7, 46.01, 1120.4}},
+		}, updates["route_geojson"])
+		assert.Equal(t, 1.11, *updates["distance_km"].(*float64))
+		assert.Equal(t, 120, *updates["elevation_gain_m"].(*int))
+		assert.Equal(t, 1120, *updates["max_elevation_m"].(*int))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("viewers cannot import", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(privateTrip(), nil).Once()
+
+		_, err := service.ImportGPX(ctx, viewerID, tripID, strings.NewReader(file))
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("file without a track", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(privateTrip(), nil).Once()
+
+		_, err := service.ImportGPX(ctx, ownerID, tripID, strings.NewReader(`<gpx><wpt lat="1" lon="2"/></gpx>`))
+		assert.ErrorIs(t, err, ErrInvalidGPX)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
 }
 
-func (m *mockUserRepository) UpdateLastLogin(ctx context.Context, id primitive.ObjectID) error {
-	return nil
+func TestService_AddWaypoints(t *testing.T) {
+	ctx := context.Background()
+	placeIDs := []string{"30000000-0000-0000-0000-00000000000a", "30000000-0000-0000-0000-00000000000b"}
+
+	withStops := func() *Trip {
+		trip := privateTrip()
+		for i, placeID := range placeIDs {
+			trip.Waypoints = append(trip.Waypoints, Waypoint{
+				PlaceID: placeID,
+				Kind:    WaypointStop,
+				Place:   &Place{ID: placeID, Location: &GeoJSON{Type: "Point", Coordinates: []float64{7, 46 + float64(i)/100}}},
+			})
+		}
+		return trip
+	}
+
+	t.Run("appends the places in order", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+		repo.On("AddWaypoints", ctx, tripID, mock.MatchedBy(func(waypoints []*Waypoint) bool {
+			return len(waypoints) == 2 && waypoints[0].PlaceID == placeIDs[0] && waypoints[1].PlaceID == placeIDs[1]
+		})).Return(nil).Once()
+		repo.On("GetByID", ctx, tripID).Return(withStops(), nil).Once()
+
+		trip, err := service.AddWaypoints(ctx, editorID, tripID, &AddWaypointsInput{PlaceIDs: placeIDs})
+		require.NoError(t, err)
+		assert.Len(t, trip.Waypoints, 2)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("auto-routes through the stops", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+		repo.On("AddWaypoints", ctx, tripID, mock.Anything).Return(nil).Once()
+		repo.On("GetByID", ctx, tripID).Return(withStops(), nil).Twice()
+
+		var updates map[string]interface{}
+		repo.On("Update", ctx, tripID, mock.Anything).
+			Run(func(args mock.Arguments) { updates = args.Get(2).(map[string]interface{}) }).
+			Return(nil).Once()
+
+		_, err := service.AddWaypoints(ctx, editorID, tripID, &AddWaypointsInput{PlaceIDs: placeIDs, AutoRoute: true})
+		require.NoError(t, err)
+		assert.Equal(t, &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{7, 46}, {7, 46.01}}}, updates["route_geojson"])
+		assert.Equal(t, 1.11, *updates["distance_km"].(*float64))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("viewers cannot add", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+
+		_, err := service.AddWaypoints(ctx, viewerID, tripID, &AddWaypointsInput{PlaceIDs: placeIDs})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNotCalled(t, "AddWaypoints", mock.Anything, mock.Anything, mock.Anything)
+	})
 }
 
-func (m *mockUserRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
-	return nil
+func TestService_ExportTrip(t *testing.T) {
+	ctx := context.Background()
+
+	exportable := func() *Trip {
+		trip := privateTrip()
+		trip.Title = "Über the Ridge!"
+		distance, gain := 12.4, 850
+		trip.DistanceKm, trip.ElevationGainM = &distance, &gain
+		trip.RouteGeoJSON = &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{7, 46, 1000}, {7, 46.01}}}
+		trip.Waypoints = []Waypoint{
+			{PlaceID: "p1", Kind: WaypointStop, Place: &Place{Name: "Hut", Location: &GeoJSON{Type: "Point", Coordinates: []float64{7, 46}}}},
+			{PlaceID: "p2", Kind: WaypointBailout, Place: &Place{Name: "Road"}},
+		}
+		return trip
+	}
+	variants := []*TripRouteVariant{
+		{Name: "Main", IsPrimary: true, RouteGeoJSON: &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{7, 46}, {7, 46.01}}}},
+		{Name: "Low road", RouteGeoJSON: &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{7, 46}, {7.01, 46.01}}}},
+	}
+
+	t.Run("gpx", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(exportable(), nil).Once()
+		repo.On("ListRouteVariants", ctx, tripID).Return(variants, nil).Once()
+
+		export, err := service.ExportTrip(ctx, viewerID, tripID, ExportFormatGPX, "")
+		require.NoError(t, err)
+
+		assert.Equal(t, "application/gpx+xml", export.ContentType)
+		assert.Equal(t, "ber-the-ridge.gpx", export.Filename)
+		gpx := string(export.Data)
+		assert.Equal(t, 1, strings.Count(gpx, "<wpt "), "waypoints without a location are left out")
+		assert.Contains(t, gpx, "<type>stop</type>")
+		assert.Equal(t, 2, strings.Count(gpx, "<trk>"), "the route and the non-primary variant")
+		assert.Contains(t, gpx, "<name>Low road</name>")
+		assert.NotContains(t, gpx, "<name>Main</name>")
+		assert.Contains(t, gpx, "<ele>1000</ele>")
+		assert.Contains(t, gpx, "<desc>7.7 mi · 2,789 ft climb</desc>", "US formats without a locale")
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("geojson", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(exportable(), nil).Once()
+		repo.On("ListRouteVariants", ctx, tripID).Return(variants, nil).Once()
+		repo.On("ListAnnotations", ctx, tripID).Return([]*TripAnnotation{
+			{Kind: AnnotationLabel, Label: "Viewpoint", Geometry: &GeoJSONRoute{Type: "Point", Coordinates: []float64{7, 46.005}}},
+		}, nil).Once()
+
+		export, err := service.ExportTrip(ctx, ownerID, tripID, ExportFormatGeoJSON, "")
+		require.NoError(t, err)
+
+		assert.Equal(t, "application/geo+json", export.ContentType)
+		var collection struct {
+			Type     string
+			Name     string
+			Features []struct {
+				Geometry   struct{ Type string }
+				Properties map[string]interface{}
+			}
+		}
+		require.NoError(t, json.Unmarshal(export.Data, &collection))
+		assert.Equal(t, "FeatureCollection", collection.Type)
+		assert.Equal(t, "Über the Ridge!", collection.Name)
+
+		var kinds []string
+		for _, feature := range collection.Features {
+			kinds = append(kinds, feature.Properties["feature"].(string)+":"+feature.Geometry.Type)
+		}
+		assert.Equal(t, []string{"route:LineString", "variant:LineString", "waypoint:Point", "annotation:Point"}, kinds)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("strangers export what the public sees", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		trip := exportable()
+		trip.Privacy = PrivacyPublic
+		arrival := time.Date(2026, 7, 4, 9, 0, 0, 0, time.UTC)
+		trip.Waypoints[0].Notes, trip.Waypoints[0].ArrivalTime = "Key under the mat", &arrival
+		trip.Waypoints = append(trip.Waypoints, Waypoint{PlaceID: "p3", Kind: WaypointStop, Place: &Place{Name: "Home", Privacy: PrivacyPrivate, Location: &GeoJSON{Type: "Point", Coordinates: []float64{7.001, 46.001}}}})
+		repo.On("GetByID", ctx, tripID).Return(trip, nil).Once()
+		repo.On("ListRouteVariants", ctx, tripID).Return(variants, nil).Once()
+		repo.On("ListAnnotations", ctx, tripID).Return([]*TripAnnotation{}, nil).Once()
+
+		export, err := service.ExportTrip(ctx, "stranger", tripID, ExportFormatGeoJSON, "")
+		require.NoError(t, err)
+
+		data := string(export.Data)
+		assert.Contains(t, data, `"name":"Hut"`)
+		assert.NotContains(t, data, "Home")
+		assert.NotContains(t, data, "Key under the mat")
+		assert.NotContains(t, data, "2026-07-04")
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("geojson carries the custom layers", func(t *testing.T) {
+		storage, err := media.NewDiskStorage(&config.MediaConfig{StoragePath: t.TempDir(), CDNURL: "http://localhost:8080/media"})
+		require.NoError(t, err)
+		_, err = storage.Save("layers/zones.geojson", []byte(`{"type":"FeatureCollection","features":[
+			{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[7,46],[7.1,46],[7.1,46.1],[7,46]]]},"properties":{"danger":"high"}}
+		]}`))
+		require.NoError(t, err)
+
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		service.SetLayerService(NewLayerService(repo, storage, time.Minute))
+		repo.On("GetByID", ctx, tripID).Return(exportable(), nil).Once()
+		repo.On("ListRouteVariants", ctx, tripID).Return(variants, nil).Once()
+		repo.On("ListAnnotations", ctx, tripID).Return([]*TripAnnotation{}, nil).Once()
+		repo.On("ListLayers", ctx, tripID).Return([]*TripLayer{
+			{ID: "zones", Name: "Avalanche zones", Format: LayerFormatGeoJSON, FeatureCount: 1, StoragePath: "layers/zones.geojson"},
+			{ID: "huts", Name: "Huts", Format: LayerFormatKML, FeatureCount: 3, StoragePath: "layers/huts.kml"},
+		}, nil).Once()
+
+		export, err := service.ExportTrip(ctx, ownerID, tripID, ExportFormatGeoJSON, "")
+		require.NoError(t, err)
+
+		var collection struct {
+			Features []struct {
+				Geometry   struct{ Type string }
+				Properties map[string]interface{}
+			}
+			Layers []exportLayer
+		}
+		require.NoError(t, json.Unmarshal(export.Data, &collection))
+
+		layer := collection.Features[len(collection.Features)-1]
+		assert.Equal(t, "Polygon", layer.Geometry.Type)
+		assert.Equal(t, map[string]interface{}{
+			"feature": "layer", "layer_id": "zones", "layer_name": "Avalanche zones", "danger": "high",
+		}, layer.Properties)
+
+		require.Len(t, collection.Layers, 2)
+		assert.Equal(t, "http://localhost:8080/media/layers/zones.geojson", collection.Layers[0].URL)
+		assert.Equal(t, exportLayer{ID: "huts", Name: "Huts", Format: LayerFormatKML, FeatureCount: 3, URL: "http://localhost:8080/media/layers/huts.kml"}, collection.Layers[1])
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("ics across a daylight saving change", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		trip := exportable()
+		trip.Timezone = "Europe/Zurich"
+		start, end := time.Date(2026, 3, 28, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 30, 0, 0, 0, 0, time.UTC)
+		trip.StartDate, trip.EndDate = &start, &end
+		// Clocks go forward at 01:00 UTC on the 29th
+		arrival, departure := time.Date(2026, 3, 29, 7, 0, 0, 0, time.UTC), time.Date(2026, 3, 29, 9, 30, 0, 0, time.UTC)
+		trip.Waypoints[0].ID = "w1"
+		trip.Waypoints[0].ArrivalTime, trip.Waypoints[0].DepartureTime = &arrival, &departure
+		opens, closes := time.Date(2026, 3, 29, 6, 0, 0, 0, time.UTC), time.Date(2026, 3, 29, 16, 0, 0, 0, time.UTC)
+		trip.Waypoints[0].Window = &TimeWindow{OpensAt: &opens, ClosesAt: &closes, Label: "Hut open"}
+		repo.On("GetByID", ctx, tripID).Return(trip, nil).Once()
+		repo.On("ListRouteVariants", ctx, tripID).Return(variants, nil).Once()
+
+		export, err := service.ExportTrip(ctx, viewerID, tripID, ExportFormatICS, "fr-FR,fr;q=0.9,en;q=0.8")
+		require.NoError(t, err)
+
+		assert.Equal(t, "ber-the-ridge.ics", export.Filename)
+		ics := string(export.Data)
+		assert.True(t, strings.HasSuffix(ics, "END:VCALENDAR\r\n"))
+		assert.Contains(t, ics, "DTSTART;VALUE=DATE:20260328\r\n")
+		assert.Contains(t, ics, "DTEND;VALUE=DATE:20260331\r\n", "all-day events end the day after")
+		assert.Contains(t, ics, "DTSTART;TZID=Europe/Zurich:20260329T090000\r\n")
+		assert.Contains(t, ics, "DTEND;TZID=Europe/Zurich:20260329T113000\r\n")
+		assert.Contains(t, ics, "BEGIN:DAYLIGHT\r\nDTSTART:20260329T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\n")
+		assert.Equal(t, 1, strings.Count(ics, "UID:w1@newmap"), "bail-outs are not stops")
+		assert.Contains(t, ics, "DESCRIPTION:12\\,4 km · 850 m climb\r\n")
+		assert.Contains(t, ics, "DESCRIPTION:Hut open: 08:00 – 18:00\r\n", "on the trip's 24-hour clock")
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("the user's preferences win over their client's", func(t *testing.T) {
+		repo := new(mockRepository)
+		userRepo := &preferencesRepository{user: &users.User{ID: viewerID, Locale: "de-DE", Units: locale.UnitsImperial}}
+		service := NewService(repo, userRepo, nil)
+		trip := exportable()
+		arrival := time.Date(2026, 6, 14, 13, 5, 0, 0, time.UTC)
+		trip.Waypoints[0].ArrivalTime = &arrival
+		repo.On("GetByID", ctx, tripID).Return(trip, nil).Once()
+		repo.On("ListRouteVariants", ctx, tripID).Return(variants, nil).Once()
+
+		export, err := service.ExportTrip(ctx, viewerID, tripID, ExportFormatGPX, "en-US")
+		require.NoError(t, err)
+
+		gpx := string(export.Data)
+		assert.Contains(t, gpx, "<desc>7,7 mi · 2.789 ft climb</desc>")
+		assert.Contains(t, gpx, "<desc>Arrive 14.06.2026 13:05</desc>")
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+
+		_, err := service.ExportTrip(ctx, ownerID, tripID, "kml", "")
+		assert.ErrorIs(t, err, ErrUnsupportedExportFormat)
+		repo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("outsiders cannot export private trips", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(exportable(), nil).Once()
+
+		_, err := service.ExportTrip(ctx, "", tripID, ExportFormatGPX, "")
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
 }
 
-func (m *mockUserRepository) List(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*users.User, error) {
-	return nil, nil
+// preferencesRepository is a user repository that only knows one user
+type preferencesRepository struct {
+	users.Repository
+	user *users.User
 }
 
-func (m *mockUserRepository) Count(ctx context.Context, filter bson.M) (int64, error) {
-	return 0, nil
+func (r *preferencesRepository) GetByID(ctx context.Context, id string) (*users.User, error) {
+	if id != r.user.ID {
+		return nil, users.ErrUserNotFound
+	}
+	return r.user, nil
 }
 
-func TestTripService_Create(t *testing.T) {
-	tripRepo := newMockTripRepository()
-	userRepo := newMockUserRepository()
-	service := NewService(tripRepo, userRepo)
+func TestService_Waypoints(t *testing.T) {
+	ctx := context.Background()
 
-	// Create test user
-	testUser := &users.User{
-		ID:       primitive.NewObjectID(),
-		Email:    "test@example.com",
-		Username: "testuser",
-		FullName: "Test User",
+	withWaypoints := func() *Trip {
+		trip := privateTrip()
+		// Position 1 was a bail-out since removed
+		trip.Waypoints = []Waypoint{
+			{ID: "w0", TripID: tripID, OrderPosition: 0},
+			{ID: "w2", TripID: tripID, OrderPosition: 2, Notes: "lunch"},
+			{ID: "w3", TripID: tripID, OrderPosition: 3},
+		}
+		return trip
 	}
-	userRepo.users[testUser.ID.Hex()] = testUser
 
+	t.Run("updating notes keeps the waypoint in its place", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(withWaypoints(), nil).Once()
+		repo.On("UpdateWaypoint", ctx, mock.MatchedBy(func(waypoint *Waypoint) bool {
+			return waypoint.ID == "w2" && waypoint.OrderPosition == 1 && waypoint.Notes == "dinner"
+		})).Return(nil).Once()
+		repo.On("GetWaypoints", ctx, tripID).Return(withWaypoints().Waypoints, nil).Once()
+
+		notes := "dinner"
+		waypoint, err := service.UpdateWaypoint(ctx, editorID, tripID, "w2", &UpdateWaypointInput{Notes: &notes})
+		require.NoError(t, err)
+		assert.Equal(t, "w2", waypoint.ID)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("markdown notes are sanitized and parsed", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(withWaypoints(), nil).Once()
+		var saved *Waypoint
+		repo.On("UpdateWaypoint", ctx, mock.AnythingOfType("*trips.Waypoint")).Run(func(args mock.Arguments) {
+			saved = args.Get(1).(*Waypoint)
+		}).Return(nil).Once()
+		repo.On("GetWaypoints", ctx, tripID).Return(withWaypoints().Waypoints, nil).Once()
+
+		notes := "<b>Lunch</b>\n- [x] Sandwiches\n- [ ] [Menu](javascript:alert(1))"
+		format := "markdown"
+		_, err := service.UpdateWaypoint(ctx, editorID, tripID, "w2", &UpdateWaypointInput{Notes: &notes, NotesFormat: &format})
+		require.NoError(t, err)
+
+		require.NotNil(t, saved)
+		assert.Equal(t, "Lunch\n- [x] Sandwiches\n- [ ] Menu", saved.Notes)
+		assert.Equal(t, "markdown", saved.NotesFormat)
+		require.NotNil(t, saved.NotesRendering)
+		assert.Len(t, saved.NotesRendering.Checklist, 2)
+		assert.Equal(t, "Lunch\n[x] Sandwiches\n[ ] Menu", saved.PlainNotes())
+	})
+
+	t.Run("departure before arrival", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(withWaypoints(), nil).Once()
+
+		arrival := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+		departure := arrival.Add(-time.Hour)
+		_, err := service.UpdateWaypoint(ctx, ownerID, tripID, "w0", &UpdateWaypointInput{ArrivalTime: &arrival, DepartureTime: &departure})
+		assert.ErrorIs(t, err, ErrInvalidWaypointTimes)
+		repo.AssertNotCalled(t, "UpdateWaypoint", mock.Anything, mock.Anything)
+	})
+
+	t.Run("reorder must list every waypoint once", func(t *testing.T) {
+		for name, order := range map[string][]string{
+			"missing":  {"w3", "w0"},
+			"repeated": {"w3", "w0", "w0"},
+			"unknown":  {"w3", "w0", "other"},
+		} {
+			repo := new(mockRepository)
+			service := NewService(repo, nil, nil)
+			repo.On("GetByID", ctx, tripID).Return(withWaypoints(), nil).Once()
+
+			err := service.ReorderWaypoints(ctx, ownerID, tripID, order)
+			assert.ErrorIs(t, err, ErrInvalidWaypointOrder, name)
+			repo.AssertNotCalled(t, "ReorderWaypoints", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("viewers cannot reorder", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(withWaypoints(), nil).Once()
+
+		err := service.ReorderWaypoints(ctx, viewerID, tripID, []string{"w3", "w2", "w0"})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("edit links change waypoints", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(withWaypoints(), nil).Once()
+		repo.On("UpdateWaypoint", ctx, mock.MatchedBy(func(waypoint *Waypoint) bool {
+			return waypoint.ID == "w2" && waypoint.Notes == "dinner"
+		})).Return(nil).Once()
+		repo.On("GetWaypoints", ctx, tripID).Return(withWaypoints().Waypoints, nil).Once()
+
+		notes := "dinner"
+		grant := &ShareGrant{TripID: tripID, Scope: ShareScopeEdit}
+		_, err := service.UpdateWaypointShared(ctx, grant, tripID, "w2", &UpdateWaypointInput{Notes: &notes})
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("view links cannot change waypoints", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+
+		grant := &ShareGrant{TripID: tripID, Scope: ShareScopeRead}
+		err := service.RemoveWaypointShared(ctx, grant, tripID, "w2")
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		err = service.ReorderWaypointsShared(ctx, grant, tripID, []string{"w3", "w2", "w0"})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "GetByIDWith", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestService_LogCompletion(t *testing.T) {
 	ctx := context.Background()
+	completedAt := time.Now().Add(-2 * time.Hour)
 
-	// Test successful trip creation
-	input := &CreateTripInput{
-		Name:        "Test Trip",
-		Description: "A test trip",
-		StartDate:   time.Now().Add(24 * time.Hour),
-		EndDate:     time.Now().Add(48 * time.Hour),
-		IsPublic:    true,
-		Tags:        []string{"test", "vacation"},
-	}
+	t.Run("stores the GPX track", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+
+		var stored *ActivityCompletion
+		repo.On("CreateCompletion", ctx, mock.Anything).
+			Run(func(args mock.Arguments) { stored = args.Get(1).(*ActivityCompletion) }).
+			Return(nil).Once()
+
+		_, err := service.LogCompletion(ctx, viewerID, tripID, &CreateActivityCompletionInput{
+			CompletedAt: completedAt,
+			GPX: `<gpx><trk><trkseg>
+				<trkpt lat="46.0" lon="7.0"/><trkpt lat="46.01" lon="7.0"/>
+			</trkseg></trk></gpx>`,
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, viewerID, stored.UserID)
+		assert.Equal(t, tripID, stored.TripID)
+		require.NotNil(t, stored.GPXTrack)
+		assert.Equal(t, "LineString", (*stored.GPXTrack)["type"])
+		assert.Equal(t, [][]float64{{7, 46}, {7, 46.01}}, (*stored.GPXTrack)["coordinates"])
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("private trip hidden from others", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+
+		_, err := service.LogCompletion(ctx, "00000000-0000-0000-0000-000000000009", tripID, &CreateActivityCompletionInput{CompletedAt: completedAt})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNotCalled(t, "CreateCompletion", mock.Anything, mock.Anything)
+	})
+
+	t.Run("invalid GPX", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+
+		_, err := service.LogCompletion(ctx, ownerID, tripID, &CreateActivityCompletionInput{CompletedAt: completedAt, GPX: "not gpx"})
+		assert.ErrorIs(t, err, ErrInvalidGPX)
+		repo.AssertNotCalled(t, "CreateCompletion", mock.Anything, mock.Anything)
+	})
+
+	t.Run("completed in the future", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+
+		_, err := service.LogCompletion(ctx, ownerID, tripID, &CreateActivityCompletionInput{CompletedAt: time.Now().Add(time.Hour)})
+		assert.ErrorIs(t, err, ErrCompletionInFuture)
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestService_RateTrip(t *testing.T) {
+	ctx := context.Background()
+	input := &CreateActivityRatingInput{OverallRating: 4, ReviewText: "Steep but worth it"}
+
+	t.Run("collaborator rates", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+		repo.On("CreateRating", ctx, mock.MatchedBy(func(r *ActivityRating) bool {
+			return r.TripID == tripID && r.UserID == viewerID && r.OverallRating == 4
+		})).Return(nil).Once()
+
+		_, err := service.RateTrip(ctx, viewerID, tripID, input)
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("owner cannot rate their own trip", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
 
-	trip, err := service.Create(ctx, testUser.ID, input)
-	if err != nil {
-		t.Fatalf("Expected no error, got %v", err)
+		_, err := service.RateTrip(ctx, ownerID, tripID, input)
+		assert.ErrorIs(t, err, ErrOwnTripRating)
+		repo.AssertNotCalled(t, "CreateRating", mock.Anything, mock.Anything)
+	})
+
+	t.Run("private trip hidden from others", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+
+		_, err := service.RateTrip(ctx, "00000000-0000-0000-0000-000000000009", tripID, input)
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNotCalled(t, "CreateRating", mock.Anything, mock.Anything)
+	})
+}
+
+func TestService_CreateShareLink(t *testing.T) {
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		userID  string
+		input   *CreateShareLinkInput
+		wantErr error
+	}{
+		{"editor shares to view", editorID, &CreateShareLinkInput{}, nil},
+		{"owner shares to edit", ownerID, &CreateShareLinkInput{Permissions: SharePermissionEdit}, nil},
+		{"editor cannot share to edit", editorID, &CreateShareLinkInput{Permissions: SharePermissionEdit}, ErrUnauthorized},
+		{"viewer cannot share", viewerID, &CreateShareLinkInput{}, ErrUnauthorized},
+		{"expiry in the past", ownerID, &CreateShareLinkInput{ExpiresAt: &past}, ErrShareExpiryInPast},
 	}
 
-	if trip.Name != input.Name {
-		t.Errorf("Expected name %s, got %s", input.Name, trip.Name)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockRepository)
+			service := NewService(repo, nil, nil)
+			repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Maybe()
+			repo.On("CreateShareLink", ctx, mock.MatchedBy(func(l *ActivityShareLink) bool {
+				return l.TripID == tripID && l.CreatedBy == tt.userID && len(l.ShareToken) == 43
+			})).Return(nil).Maybe()
+
+			link, err := service.CreateShareLink(ctx, tt.userID, tripID, tt.input)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				repo.AssertNotCalled(t, "CreateShareLink", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			if tt.input.Permissions == "" {
+				assert.Equal(t, SharePermissionView, link.Permissions)
+			}
+			repo.AssertExpectations(t)
+		})
 	}
+}
 
-	if trip.OwnerID != testUser.ID {
-		t.Errorf("Expected owner ID %s, got %s", testUser.ID, trip.OwnerID)
+func TestService_GetSharedWith(t *testing.T) {
+	ctx := context.Background()
+	sharedTrip := func() *Trip {
+		trip := privateTrip()
+		trip.EmergencyContacts = &JSONB{"ranger": "555-0100"}
+		trip.Waypoints = []Waypoint{
+			{PlaceID: "home", Kind: WaypointStop, Notes: "Key under the mat", Place: &Place{
+				ID: "home", Name: "Home", Address: "12 Pine Rd", Privacy: PrivacyPrivate,
+				Location: &GeoJSON{Type: "Point", Coordinates: []float64{7.123456, 46.123456}},
+			}},
+		}
+		return trip
 	}
 
-	if trip.Status != StatusUpcoming {
-		t.Errorf("Expected status %s, got %s", StatusUpcoming, trip.Status)
+	t.Run("view links see what friends see", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(sharedTrip(), nil).Once()
+
+		trip, err := service.GetSharedWith(ctx, &ShareGrant{TripID: tripID, Scope: ShareScopeRead}, tripID, Relations{Collaborators: true})
+		require.NoError(t, err)
+		assert.Nil(t, trip.EmergencyContacts)
+		assert.Len(t, trip.Collaborators, 2)
+		require.Len(t, trip.Waypoints, 1)
+		assert.Empty(t, trip.Waypoints[0].Place.Address)
+		assert.NotEqual(t, 7.123456, trip.Waypoints[0].Place.Location.Coordinates[0])
+		assert.Equal(t, "Key under the mat", trip.Waypoints[0].Notes)
+	})
+
+	t.Run("edit links see what collaborators see", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{}).Return(sharedTrip(), nil).Once()
+
+		trip, err := service.GetSharedWith(ctx, &ShareGrant{TripID: tripID, Scope: ShareScopeEdit}, tripID, Relations{})
+		require.NoError(t, err)
+		assert.NotNil(t, trip.EmergencyContacts)
+		assert.Nil(t, trip.Collaborators)
+		assert.Equal(t, "12 Pine Rd", trip.Waypoints[0].Place.Address)
+	})
+
+	t.Run("other trips are refused", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+
+		_, err := service.GetSharedWith(ctx, &ShareGrant{TripID: "other", Scope: ShareScopeEdit}, tripID, Relations{})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNotCalled(t, "GetByIDWith", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func datePoll() *DatePoll {
+	vote := func(userID, availability string) DatePollVote {
+		return DatePollVote{UserID: userID, Availability: availability}
+	}
+	return &DatePoll{
+		ID:     "poll-1",
+		TripID: tripID,
+		Status: DatePollOpen,
+		Options: []*DatePollOption{
+			{ID: "june", StartDate: time.Date(2026, 6, 5, 0, 0, 0, 0, time.UTC), Votes: []DatePollVote{
+				vote(ownerID, AvailabilityYes), vote(editorID, AvailabilityMaybe), vote(viewerID, AvailabilityNo),
+			}},
+			{ID: "july", StartDate: time.Date(2026, 7, 3, 0, 0, 0, 0, time.UTC), Votes: []DatePollVote{
+				vote(ownerID, AvailabilityYes), vote(editorID, AvailabilityMaybe), vote(viewerID, AvailabilityMaybe),
+			}},
+			{ID: "august", StartDate: time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC)},
+		},
 	}
 }
 
-func TestTripService_InviteCollaborator(t *testing.T) {
-	tripRepo := newMockTripRepository()
-	userRepo := newMockUserRepository()
-	service := NewService(tripRepo, userRepo)
+func TestDatePoll_Tally(t *testing.T) {
+	t.Run("a maybe counts for half", func(t *testing.T) {
+		poll := datePoll()
+		poll.tally()
 
-	// Create test users
-	owner := &users.User{
-		ID:       primitive.NewObjectID(),
-		Email:    "owner@example.com",
-		Username: "owner",
-	}
-	collaborator := &users.User{
-		ID:       primitive.NewObjectID(),
-		Email:    "collaborator@example.com",
-		Username: "collaborator",
+		assert.Equal(t, "july", poll.BestOptionID)
+		assert.Equal(t, 1, poll.Options[1].Available)
+		assert.Equal(t, 2, poll.Options[1].Maybe)
+		assert.Equal(t, 1, poll.Options[0].Unavailable)
+	})
+
+	t.Run("ties go to the earlier dates", func(t *testing.T) {
+		poll := datePoll()
+		poll.Options[1].Votes[2].Availability = AvailabilityNo
+		poll.tally()
+
+		assert.Equal(t, "june", poll.BestOptionID)
+	})
+
+	t.Run("no answers", func(t *testing.T) {
+		poll := datePoll()
+		poll.Options = poll.Options[2:]
+		poll.tally()
+
+		assert.Empty(t, poll.BestOptionID)
+	})
+}
+
+func TestService_FinalizeDatePoll(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("goes with the best dates", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+		repo.On("GetDatePoll", ctx, "poll-1").Return(datePoll(), nil).Once()
+		repo.On("FinalizeDatePoll", ctx, "poll-1", "july").Return(nil).Once()
+		finalized := datePoll()
+		finalized.Status = DatePollFinalized
+		repo.On("GetDatePoll", ctx, "poll-1").Return(finalized, nil).Once()
+
+		poll, err := service.FinalizeDatePoll(ctx, ownerID, tripID, "poll-1", &FinalizeDatePollInput{})
+		require.NoError(t, err)
+		assert.Equal(t, DatePollFinalized, poll.Status)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("only the owner finalizes", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+
+		_, err := service.FinalizeDatePoll(ctx, editorID, tripID, "poll-1", &FinalizeDatePollInput{})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNotCalled(t, "FinalizeDatePoll", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("option from another poll", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+		repo.On("GetDatePoll", ctx, "poll-1").Return(datePoll(), nil).Once()
+
+		_, err := service.FinalizeDatePoll(ctx, ownerID, tripID, "poll-1", &FinalizeDatePollInput{OptionID: "september"})
+		assert.ErrorIs(t, err, ErrUnknownDateOption)
+		repo.AssertNotCalled(t, "FinalizeDatePoll", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("already finalized", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		poll := datePoll()
+		poll.Status = DatePollFinalized
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+		repo.On("GetDatePoll", ctx, "poll-1").Return(poll, nil).Once()
+
+		_, err := service.FinalizeDatePoll(ctx, ownerID, tripID, "poll-1", &FinalizeDatePollInput{})
+		assert.ErrorIs(t, err, ErrDatePollClosed)
+	})
+}
+
+func TestService_ReportCondition(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("defaults to info", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+		repo.On("CreateCondition", ctx, mock.MatchedBy(func(c *ActivityCondition) bool {
+			return c.ReportedBy == viewerID && c.Severity == SeverityInfo && !c.Verified
+		})).Return(nil).Once()
+
+		_, err := service.ReportCondition(ctx, viewerID, tripID, &CreateActivityConditionInput{
+			ConditionType: "closure", Description: "Bridge washed out below the hut",
+		})
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("already expired", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		past := time.Now().Add(-time.Hour)
+
+		_, err := service.ReportCondition(ctx, viewerID, tripID, &CreateActivityConditionInput{
+			ConditionType: "weather", Description: "Fog on the ridge all morning", ValidUntil: &past,
+		})
+		assert.ErrorIs(t, err, ErrConditionExpired)
+		repo.AssertNotCalled(t, "CreateCondition", mock.Anything, mock.Anything)
+	})
+
+	t.Run("location must be a point", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+
+		_, err := service.ReportCondition(ctx, viewerID, tripID, &CreateActivityConditionInput{
+			ConditionType: "hazard", Description: "Rockfall across the trail",
+			Location: &GeoJSON{Type: "Point", Coordinates: []float64{200, 46}},
+		})
+		assert.ErrorIs(t, err, ErrInvalidConditionLocation)
+	})
+}
+
+func TestService_VerifyCondition(t *testing.T) {
+	ctx := context.Background()
+	adminID := "00000000-0000-0000-0000-000000000005"
+	withAdmin := func() *Trip {
+		trip := privateTrip()
+		trip.Collaborators = append(trip.Collaborators, Collaborator{TripID: tripID, UserID: adminID, Role: "admin"})
+		return trip
 	}
-	userRepo.users[owner.ID.Hex()] = owner
-	userRepo.users[owner.Email] = owner
-	userRepo.users[collaborator.ID.Hex()] = collaborator
-	userRepo.users[collaborator.Email] = collaborator
 
-	// Create test trip
-	trip := &Trip{
-		ID:            primitive.NewObjectID(),
-		Name:          "Test Trip",
-		OwnerID:       owner.ID,
-		Collaborators: []Collaborator{},
+	t.Run("admin verifies", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(withAdmin(), nil).Once()
+		repo.On("GetCondition", ctx, "c1").Return(&ActivityCondition{ID: "c1", TripID: tripID}, nil).Once()
+		repo.On("VerifyCondition", ctx, "c1", adminID).Return(nil).Once()
+
+		condition, err := service.VerifyCondition(ctx, adminID, tripID, "c1")
+		require.NoError(t, err)
+		assert.True(t, condition.Verified)
+		assert.Equal(t, adminID, *condition.VerifiedBy)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("editors cannot verify", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(withAdmin(), nil).Once()
+
+		_, err := service.VerifyCondition(ctx, editorID, tripID, "c1")
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNotCalled(t, "VerifyCondition", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("report on another trip", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(withAdmin(), nil).Once()
+		repo.On("GetCondition", ctx, "c1").Return(&ActivityCondition{ID: "c1", TripID: "other-trip"}, nil).Once()
+
+		_, err := service.VerifyCondition(ctx, ownerID, tripID, "c1")
+		assert.ErrorIs(t, err, ErrConditionNotFound)
+		repo.AssertNotCalled(t, "VerifyCondition", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestService_ReviewCondition(t *testing.T) {
+	ctx := context.Background()
+	verifierID := "00000000-0000-0000-0000-000000000006"
+	reported := func() *ActivityCondition {
+		return &ActivityCondition{ID: "c1", TripID: tripID, ReportedBy: editorID, Severity: SeverityWarning}
 	}
-	tripRepo.trips[trip.ID.Hex()] = trip
 
+	t.Run("verifier confirms", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(&Trip{ID: tripID, OwnerID: ownerID, Privacy: "public"}, nil).Once()
+		repo.On("GetCondition", ctx, "c1").Return(reported(), nil).Once()
+		repo.On("IsConditionVerifier", ctx, verifierID, "c1").Return(true, nil).Once()
+		repo.On("ReviewCondition", ctx, "c1", verifierID, ReviewConfirmed, "Saw it this morning").Return(nil).Once()
+
+		condition, err := service.ConfirmCondition(ctx, verifierID, tripID, "c1", &ReviewConditionInput{Note: "Saw it this morning"})
+		require.NoError(t, err)
+		assert.True(t, condition.Verified)
+		assert.Equal(t, verifierID, *condition.VerifiedBy)
+		assert.Equal(t, 3, condition.Weight)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("verifier disputes", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		verified := reported()
+		verified.Verified = true
+		verified.VerifiedBy = &[]string{ownerID}[0]
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(&Trip{ID: tripID, OwnerID: ownerID, Privacy: "public"}, nil).Once()
+		repo.On("GetCondition", ctx, "c1").Return(verified, nil).Once()
+		repo.On("IsConditionVerifier", ctx, verifierID, "c1").Return(true, nil).Once()
+		repo.On("ReviewCondition", ctx, "c1", verifierID, ReviewDisputed, "").Return(nil).Once()
+
+		condition, err := service.DisputeCondition(ctx, verifierID, tripID, "c1", &ReviewConditionInput{})
+		require.NoError(t, err)
+		assert.False(t, condition.Verified)
+		assert.Nil(t, condition.VerifiedBy)
+		assert.Nil(t, condition.ReviewNote)
+		assert.Equal(t, 0, condition.Weight)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("users without a grant covering the report", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(&Trip{ID: tripID, OwnerID: ownerID, Privacy: "public"}, nil).Once()
+		repo.On("GetCondition", ctx, "c1").Return(reported(), nil).Once()
+		repo.On("IsConditionVerifier", ctx, verifierID, "c1").Return(false, nil).Once()
+
+		_, err := service.ConfirmCondition(ctx, verifierID, tripID, "c1", &ReviewConditionInput{})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNotCalled(t, "ReviewCondition", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("verifiers cannot review their own reports", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+		repo.On("GetCondition", ctx, "c1").Return(reported(), nil).Once()
+
+		_, err := service.ConfirmCondition(ctx, editorID, tripID, "c1", &ReviewConditionInput{})
+		assert.ErrorIs(t, err, ErrOwnCondition)
+		repo.AssertNotCalled(t, "IsConditionVerifier", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("private trips stay private", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+
+		_, err := service.DisputeCondition(ctx, verifierID, tripID, "c1", &ReviewConditionInput{})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNotCalled(t, "GetCondition", mock.Anything, mock.Anything)
+	})
+}
+
+func TestService_GrantConditionVerifier(t *testing.T) {
 	ctx := context.Background()
+	adminID := "00000000-0000-0000-0000-000000000005"
+
+	repo := new(mockRepository)
+	service := NewService(repo, nil, nil)
+	repo.On("CreateConditionVerifier", ctx, mock.MatchedBy(func(v *ConditionVerifier) bool {
+		return v.UserID == editorID && v.Region == nil && *v.ActivityType == "skiing" && *v.GrantedBy == adminID
+	})).Return(nil).Once()
+
+	_, err := service.GrantConditionVerifier(ctx, adminID, &GrantConditionVerifierInput{UserID: editorID, ActivityType: "skiing"})
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+
+	line := &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{35, 31}, {35, 31.1}}}
+	_, err = service.GrantConditionVerifier(ctx, adminID, &GrantConditionVerifierInput{UserID: editorID, Region: line})
+	assert.ErrorIs(t, err, ErrInvalidVerifier)
+}
 
-	// Test successful invitation
-	input := &InviteCollaboratorInput{
-		Email: collaborator.Email,
-		Role:  users.RoleEditor,
+// participantRoster is a trip for four: two going, one of them with a
+// guest, and two waiting to join
+func participantRoster() *ParticipantRoster {
+	at := func(minute int) *time.Time {
+		t := time.Date(2026, 5, 1, 12, minute, 0, 0, time.UTC)
+		return &t
 	}
+	capacity := 4
+	return newParticipantRoster(tripID, &capacity, []*TripParticipant{
+		{UserID: editorID, Status: ParticipantGoing, Guests: 1},
+		{UserID: viewerID, Status: ParticipantGoing},
+		{UserID: "waiting-pair", Status: ParticipantWaitlisted, Guests: 1, WaitlistedAt: at(1)},
+		{UserID: "waiting-solo", Status: ParticipantWaitlisted, WaitlistedAt: at(2)},
+		{UserID: "invitee", Status: ParticipantInvited},
+	})
+}
+
+func TestParticipantRoster(t *testing.T) {
+	now := time.Date(2026, 5, 2, 9, 0, 0, 0, time.UTC)
+
+	t.Run("tallies heads and the waitlist", func(t *testing.T) {
+		roster := participantRoster()
+
+		assert.Equal(t, 3, roster.Going)
+		assert.Equal(t, 3, roster.Waitlisted)
+		require.NotNil(t, roster.SpotsLeft)
+		assert.Equal(t, 1, *roster.SpotsLeft)
+		assert.Equal(t, 1, roster.Participant("waiting-pair").WaitlistPosition)
+		assert.Equal(t, 2, roster.Participant("waiting-solo").WaitlistPosition)
+	})
+
+	t.Run("going joins the back of the line while others wait", func(t *testing.T) {
+		roster := participantRoster()
+
+		require.NoError(t, roster.Respond("invitee", ParticipantGoing, 0, now))
+		assert.Equal(t, ParticipantWaitlisted, roster.Participant("invitee").Status)
+		assert.Equal(t, 3, roster.Participant("invitee").WaitlistPosition)
+		assert.Empty(t, roster.Promoted())
+	})
+
+	t.Run("going with room and no line", func(t *testing.T) {
+		roster := participantRoster()
+		require.NoError(t, roster.Remove("waiting-pair"))
+		require.NoError(t, roster.Remove(viewerID))
+
+		require.NoError(t, roster.Respond("invitee", ParticipantGoing, 0, now))
+		assert.Equal(t, ParticipantGoing, roster.Participant("invitee").Status)
+		assert.Nil(t, roster.Participant("invitee").WaitlistedAt)
+		assert.Equal(t, 4, roster.Going)
+	})
+
+	t.Run("declining moves the line up in order", func(t *testing.T) {
+		roster := participantRoster()
+
+		require.NoError(t, roster.Respond(editorID, ParticipantDeclined, 0, now))
+		assert.Equal(t, ParticipantGoing, roster.Participant("waiting-pair").Status)
+		assert.Equal(t, ParticipantGoing, roster.Participant("waiting-solo").Status)
+		assert.Len(t, roster.Promoted(), 2)
+		assert.Equal(t, 4, roster.Going)
+	})
+
+	t.Run("a party that does not fit holds the line", func(t *testing.T) {
+		roster := participantRoster()
+
+		require.NoError(t, roster.Remove(viewerID))
+		// Two spots free up the pair at the front, not the solo behind it
+		assert.Equal(t, ParticipantGoing, roster.Participant("waiting-pair").Status)
+		assert.Equal(t, ParticipantWaitlisted, roster.Participant("waiting-solo").Status)
+		assert.Equal(t, 1, roster.Participant("waiting-solo").WaitlistPosition)
+	})
+
+	t.Run("raising capacity seats the line", func(t *testing.T) {
+		roster := participantRoster()
+		capacity := 10
+
+		roster.SetCapacity(&capacity)
+		assert.Len(t, roster.Promoted(), 2)
+		assert.Equal(t, 6, roster.Going)
+		assert.Equal(t, 4, *roster.SpotsLeft)
+	})
+
+	t.Run("lowering capacity turns no one away", func(t *testing.T) {
+		roster := participantRoster()
+		capacity := 2
+
+		roster.SetCapacity(&capacity)
+		assert.Equal(t, 3, roster.Going)
+		assert.Equal(t, 0, *roster.SpotsLeft)
+	})
+
+	t.Run("guests only take free spots", func(t *testing.T) {
+		roster := participantRoster()
+
+		assert.ErrorIs(t, roster.Respond(viewerID, ParticipantGoing, 2, now), ErrNotEnoughSpots)
+		assert.Equal(t, 0, roster.Participant(viewerID).Guests)
+		require.NoError(t, roster.Respond(viewerID, ParticipantGoing, 1, now))
+		assert.Equal(t, 4, roster.Going)
+	})
+
+	t.Run("only invitees answer", func(t *testing.T) {
+		roster := participantRoster()
+
+		assert.ErrorIs(t, roster.Respond(ownerID, ParticipantGoing, 0, now), ErrParticipantNotFound)
+	})
+}
+
+func TestService_RespondToInvite(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mockRepository)
+	service := NewService(repo, nil, nil)
+
+	roster := participantRoster()
+	repo.On("UpdateParticipants", ctx, tripID).Return(roster, nil).Once()
+	repo.On("GetParticipants", ctx, tripID).Return(roster, nil).Once()
+
+	got, err := service.RespondToInvite(ctx, editorID, tripID, &RSVPInput{Status: ParticipantMaybe})
+	require.NoError(t, err)
+	assert.Equal(t, ParticipantMaybe, got.Participant(editorID).Status)
+	assert.Equal(t, ParticipantGoing, got.Participant("waiting-pair").Status)
+	repo.AssertExpectations(t)
+}
+
+func TestService_InviteParticipants(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("viewers cannot invite", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+
+		_, err := service.InviteParticipants(ctx, viewerID, tripID, &InviteParticipantsInput{UserIDs: []string{"invitee"}})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNotCalled(t, "UpdateParticipants", mock.Anything, mock.Anything)
+	})
 
-	err := service.InviteCollaborator(ctx, trip.ID, owner.ID, input)
-	if err != nil {
-		t.Fatalf("Expected no error, got %v", err)
+	t.Run("invitees keep their answers", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		roster := participantRoster()
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil).Once()
+		repo.On("UpdateParticipants", ctx, tripID).Return(roster, nil).Once()
+		repo.On("GetParticipants", ctx, tripID).Return(roster, nil).Once()
+
+		got, err := service.InviteParticipants(ctx, ownerID, tripID, &InviteParticipantsInput{UserIDs: []string{editorID, "newcomer"}})
+		require.NoError(t, err)
+		assert.Equal(t, ParticipantGoing, got.Participant(editorID).Status)
+		assert.Equal(t, ParticipantInvited, got.Participant("newcomer").Status)
+		assert.True(t, roster.changed["newcomer"])
+		assert.False(t, roster.changed[editorID])
+	})
+}
+
+// slopeProvider models a slope that climbs 1000 m for every 0.01 degrees
+// east of the prime meridian
+type slopeProvider struct{}
+
+func (slopeProvider) Elevations(ctx context.Context, points []elevation.Point) ([]float64, error) {
+	heights := make([]float64, len(points))
+	for i, p := range points {
+		heights[i] = 1000 + p.Longitude*100000
 	}
+	return heights, nil
+}
+
+func TestElevationProfiler_Profile(t *testing.T) {
+	profiler := NewElevationProfiler(slopeProvider{})
+
+	// Up the slope and halfway back down
+	route := &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{0, 0}, {0.01, 0}, {0.005, 0}}}
+	profile, err := profiler.Profile(context.Background(), route)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.67, profile.DistanceKm)
+	assert.Len(t, profile.Points, 167, "a sample every 10 m")
+	assert.Equal(t, 0.0, profile.Points[0].DistanceKm)
+	assert.InDelta(t, 1.668, profile.Points[len(profile.Points)-1].DistanceKm, 0.001)
+	assert.InDelta(t, 0.005, profile.Points[len(profile.Points)-1].Longitude, 1e-9)
+	assert.InDelta(t, 1000, profile.GainM, 10)
+	assert.InDelta(t, 500, profile.LossM, 10)
+	assert.Equal(t, 1000, profile.MinElevationM)
+	assert.InDelta(t, 2000, profile.MaxElevationM, 10)
 
-	// Verify collaborator was added
-	updatedTrip := tripRepo.trips[trip.ID.Hex()]
-	if len(updatedTrip.Collaborators) != 1 {
-		t.Errorf("Expected 1 collaborator, got %d", len(updatedTrip.Collaborators))
+	_, err = profiler.Profile(context.Background(), &GeoJSONRoute{Type: "Point", Coordinates: []float64{0, 0}})
+	assert.ErrorIs(t, err, ErrNoRouteGeometry)
+}
+
+func TestSampleRoute_Long(t *testing.T) {
+	lines := [][][]float64{{{0, 0}, {1, 0}}, {{2, 0}, {3, 0}}}
+	samples := sampleRoute(lines, MaxElevationSamples)
+
+	require.Len(t, samples, MaxElevationSamples)
+	assert.Equal(t, 0.0, samples[0].Longitude)
+	assert.InDelta(t, 3, samples[len(samples)-1].Longitude, 1e-9)
+	assert.InDelta(t, 222.39, samples[len(samples)-1].DistanceKm, 0.01, "the gap between the lines is not counted")
+}
+
+func TestTripContent(t *testing.T) {
+	mediaID := "6f1c3a1e-2b7d-4c55-9a0e-8d7a3c2b1f00"
+
+	t.Run("invalid blocks", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			block ContentBlock
+		}{
+			{"unknown type", ContentBlock{Type: "video"}},
+			{"empty text", ContentBlock{Type: BlockText, Text: "<b></b>"}},
+			{"unknown severity", ContentBlock{Type: BlockWarning, Text: "Ice", Severity: "extreme"}},
+			{"place without an ID", ContentBlock{Type: BlockPlace, Caption: "Hut"}},
+			{"long caption", ContentBlock{Type: BlockImage, MediaID: mediaID, Caption: strings.Repeat("a", MaxBlockLabelLength+1)}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				assert.ErrorIs(t, TripContent{tt.block}.normalize(), ErrInvalidContent)
+			})
+		}
+
+		assert.ErrorIs(t, make(TripContent, MaxContentBlocks+1).normalize(), ErrInvalidContent)
+	})
+
+	t.Run("media is listed once", func(t *testing.T) {
+		content := TripContent{
+			{Type: BlockImage, MediaID: strings.ToUpper(mediaID)},
+			{Type: BlockText, Text: "Between"},
+			{Type: BlockImage, MediaID: mediaID, Caption: "Again"},
+		}
+		require.NoError(t, content.normalize())
+		assert.Equal(t, []string{mediaID}, content.MediaIDs())
+		assert.Equal(t, "Between\n\nAgain", content.PlainText())
+	})
+
+	t.Run("plain text is cut to a description", func(t *testing.T) {
+		content := TripContent{{Type: BlockText, Text: strings.Repeat("word ", 300)}}
+		text := content.PlainText()
+		assert.Len(t, []rune(text), maxDescriptionLength)
+		assert.True(t, strings.HasSuffix(text, "…"))
+	})
+
+	t.Run("plain descriptions are shown as a text block", func(t *testing.T) {
+		trip := &Trip{Description: "Meet at <i>the</i> trailhead"}
+		trip.fillContent()
+		assert.Equal(t, TripContent{{Type: BlockText, Text: "Meet at the trailhead"}}, trip.Content)
+
+		empty := &Trip{}
+		empty.fillContent()
+		assert.Nil(t, empty.Content)
+	})
+}
+
+func TestSummarizeTrip(t *testing.T) {
+	distance, hours, gain := 12.44, 4.8, 650
+	stop := func(name string) Waypoint {
+		return Waypoint{Kind: WaypointStop, Place: &Place{Name: name}}
 	}
 
-	if updatedTrip.Collaborators[0].UserID != collaborator.ID {
-		t.Errorf("Expected collaborator ID %s, got %s", collaborator.ID, updatedTrip.Collaborators[0].UserID)
+	tests := []struct {
+		name string
+		trip *Trip
+		want string
+	}{
+		{
+			name: "everything",
+			trip: &Trip{
+				DifficultyLevel: "moderate", DistanceKm: &distance, ActivityType: "hiking", RouteType: "loop",
+				ElevationGainM: &gain, DurationHours: &hours, BestSeasons: []string{"Summer", "autumn"},
+				Waypoints: []Waypoint{
+					stop("Emerald Bay"), {Kind: WaypointBailout, Place: &Place{Name: "Road"}}, stop("Eagle Falls"),
+					stop("Emerald Bay"), stop("Vikingsholm"), stop("Fannette Island"),
+				},
+			},
+			want: "Moderate 12.4 km hiking loop with 650 m of climbing, taking about 5 hours. " +
+				"Passes Emerald Bay, Eagle Falls and Vikingsholm. Best in summer and autumn.",
+		},
+		{
+			name: "general activity and no route type",
+			trip: &Trip{DifficultyLevel: "easy", ActivityType: "general", Waypoints: []Waypoint{stop("Hut")}},
+			want: "Easy trip. Passes Hut.",
+		},
+		{
+			name: "seasons only",
+			trip: &Trip{ActivityType: "general", BestSeasons: []string{"winter"}},
+			want: "Best in winter.",
+		},
+		{
+			name: "nothing known",
+			trip: &Trip{ActivityType: "general"},
+			want: "",
+		},
 	}
 
-	// Test inviting same user again
-	err = service.InviteCollaborator(ctx, trip.ID, owner.ID, input)
-	if err == nil {
-		t.Error("Expected error when inviting existing collaborator")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SummarizeTrip(tt.trip))
+		})
 	}
+
+	t.Run("sentences that do not fit are left out", func(t *testing.T) {
+		long := strings.Repeat("Long name ", 12)
+		trip := &Trip{DifficultyLevel: "hard", Waypoints: []Waypoint{stop(long + "A"), stop(long + "B"), stop(long + "C")}}
+		assert.Equal(t, "Hard trip.", SummarizeTrip(trip))
+	})
+}
+
+func TestSummaries(t *testing.T) {
+	ctx := context.Background()
+	distance := 8.0
+
+	t.Run("only material changes are summarized", func(t *testing.T) {
+		assert.True(t, changesSummary([]string{"title", "distance_km"}))
+		assert.True(t, changesSummary([]interface{}{"best_seasons"}))
+		assert.False(t, changesSummary([]string{"title", "tags"}))
+		assert.False(t, changesSummary(nil))
+	})
+
+	t.Run("a changed summary is saved and the trip invalidated", func(t *testing.T) {
+		repo := new(mockRepository)
+		bus := events.NewLocalBus()
+		var invalidated []events.Event
+		bus.Subscribe(events.TripInvalidated, func(ctx context.Context, event events.Event) error {
+			invalidated = append(invalidated, event)
+			return nil
+		})
+		summaries := NewSummaries(repo, jobs.NewLocalQueue(), TemplateSummarizer{}, bus)
+
+		trip := privateTrip()
+		trip.DistanceKm = &distance
+		repo.On("GetByIDWith", ctx, tripID, AllRelations).Return(trip, nil).Once()
+		repo.On("SetSummary", ctx, tripID, "8.0 km trip.").Return(nil).Once()
+
+		job := &jobs.Job{Type: JobSummarizeTrip, Payload: json.RawMessage(`{"trip_id": "` + tripID + `"}`)}
+		require.NoError(t, summaries.summarize(ctx, job))
+		require.Len(t, invalidated, 1)
+		assert.Equal(t, tripID, invalidated[0].EntityID)
+
+		// Summarizing it again changes nothing
+		trip.Summary = "8.0 km trip."
+		repo.On("GetByIDWith", ctx, tripID, AllRelations).Return(trip, nil).Once()
+		require.NoError(t, summaries.summarize(ctx, job))
+		assert.Len(t, invalidated, 1)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("deleted trips are skipped", func(t *testing.T) {
+		repo := new(mockRepository)
+		summaries := NewSummaries(repo, jobs.NewLocalQueue(), TemplateSummarizer{}, events.NewLocalBus())
+		repo.On("GetByIDWith", ctx, tripID, AllRelations).Return(nil, ErrTripNotFound).Once()
+
+		job := &jobs.Job{Type: JobSummarizeTrip, Payload: json.RawMessage(`{"trip_id": "` + tripID + `"}`)}
+		assert.NoError(t, summaries.summarize(ctx, job))
+	})
 }
 
-func TestTripService_Permissions(t *testing.T) {
-	tripRepo := newMockTripRepository()
-	userRepo := newMockUserRepository()
-	service := NewService(tripRepo, userRepo)
+func TestRouteShape(t *testing.T) {
+	// About 1.1 km north and back, east along a parallel 500 m away
+	out := [][]float64{{7.0, 46.0}, {7.0, 46.005}, {7.0, 46.01}}
+	back := [][]float64{{7.0, 46.01}, {7.0, 46.005}, {7.0, 46.0}}
+	loop := [][]float64{{7.0, 46.0}, {7.0, 46.01}, {7.0065, 46.01}, {7.0065, 46.0}, {7.0, 46.0}}
 
-	// Create test users
-	owner := &users.User{
-		ID: primitive.NewObjectID(),
+	tests := []struct {
+		name  string
+		route *GeoJSONRoute
+		shape string
+	}{
+		{"there and back", &GeoJSONRoute{Type: "LineString", Coordinates: append(out, back[1:]...)}, TagOutAndBack},
+		{"round a block", &GeoJSONRoute{Type: "LineString", Coordinates: loop}, TagLoop},
+		{"one way", &GeoJSONRoute{Type: "LineString", Coordinates: out}, TagPointToPoint},
+		{"split into lines", &GeoJSONRoute{Type: "MultiLineString", Coordinates: [][][]float64{out, back}}, TagOutAndBack},
+		{"a point", &GeoJSONRoute{Type: "Point", Coordinates: []float64{7.0, 46.0}}, ""},
+		{"no route", nil, ""},
 	}
-	editor := &users.User{
-		ID: primitive.NewObjectID(),
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.shape, RouteShape(tt.route))
+		})
 	}
-	viewer := &users.User{
-		ID: primitive.NewObjectID(),
+}
+
+func TestTagger(t *testing.T) {
+	ctx := context.Background()
+	summitRoute := &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{7.0, 46.0, 1200}, {7.0, 46.01, 2400}, {7.0, 46.02, 1900}}}
+
+	t.Run("only route and tag changes are tagged", func(t *testing.T) {
+		assert.True(t, changesAny([]string{"title", "route_geojson"}, autotagFields))
+		assert.True(t, changesAny([]interface{}{"tags"}, autotagFields))
+		assert.False(t, changesAny([]string{"title", "distance_km"}, autotagFields))
+	})
+
+	t.Run("suggests the shape, summit and waterways of the route", func(t *testing.T) {
+		repo := new(mockRepository)
+		bus := events.NewLocalBus()
+		var invalidated []events.Event
+		bus.Subscribe(events.TripInvalidated, func(ctx context.Context, event events.Event) error {
+			invalidated = append(invalidated, event)
+			return nil
+		})
+		tagger := NewTagger(repo, jobs.NewLocalQueue(), bus)
+
+		trip := privateTrip()
+		trip.RouteGeoJSON = summitRoute
+		trip.Tags = pq.StringArray{TagPointToPoint}
+		trip.DismissedTags = pq.StringArray{TagRiverside}
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(trip, nil).Once()
+		repo.On("CountPlacesNear", ctx, []float64{7.0, 46.01, 2400}, summitRadiusM, PeakCategories).Return(1, nil).Once()
+		repo.On("RouteShareNear", ctx, summitRoute, riversideCorridorM, WaterwayCategories).Return(0.8, nil).Once()
+		repo.On("SetSuggestedTags", ctx, tripID, []string{TagSummit}, []string{TagRiverside}).Return(nil).Once()
+
+		job := &jobs.Job{Type: JobTagTrip, Payload: json.RawMessage(`{"trip_id": "` + tripID + `"}`)}
+		require.NoError(t, tagger.tag(ctx, job))
+		require.Len(t, invalidated, 1)
+		assert.Equal(t, tripID, invalidated[0].EntityID)
+
+		// Tagging it again changes nothing
+		trip.SuggestedTags = pq.StringArray{TagSummit}
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(trip, nil).Once()
+		repo.On("CountPlacesNear", ctx, []float64{7.0, 46.01, 2400}, summitRadiusM, PeakCategories).Return(1, nil).Once()
+		repo.On("RouteShareNear", ctx, summitRoute, riversideCorridorM, WaterwayCategories).Return(0.8, nil).Once()
+		require.NoError(t, tagger.tag(ctx, job))
+		assert.Len(t, invalidated, 1)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("routes without elevations are not summits without a profiler", func(t *testing.T) {
+		repo := new(mockRepository)
+		tagger := NewTagger(repo, jobs.NewLocalQueue(), events.NewLocalBus())
+		route := &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{7.0, 46.0}, {7.0, 46.02}}}
+		repo.On("RouteShareNear", ctx, route, riversideCorridorM, WaterwayCategories).Return(0.1, nil).Once()
+
+		tags, err := tagger.Classify(ctx, &Trip{RouteGeoJSON: route})
+		require.NoError(t, err)
+		assert.Equal(t, []string{TagPointToPoint}, tags)
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestService_SuggestedTags(t *testing.T) {
+	ctx := context.Background()
+	suggested := func() *Trip {
+		trip := privateTrip()
+		trip.Tags = pq.StringArray{"alps"}
+		trip.SuggestedTags = pq.StringArray{TagLoop, TagSummit}
+		return trip
 	}
-	userRepo.users[owner.ID.Hex()] = owner
-	userRepo.users[editor.ID.Hex()] = editor
-	userRepo.users[viewer.ID.Hex()] = viewer
 
-	// Create test trip with collaborators
-	trip := &Trip{
-		ID:      primitive.NewObjectID(),
-		Name:    "Test Trip",
-		OwnerID: owner.ID,
-		Collaborators: []Collaborator{
-			{UserID: editor.ID, Role: users.RoleEditor},
-			{UserID: viewer.ID, Role: users.RoleViewer},
-		},
+	t.Run("accepting moves tags into the trip's tags", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(suggested(), nil).Once()
+		repo.On("Update", ctx, tripID, map[string]interface{}{
+			"tags":           []string{"alps", TagSummit},
+			"suggested_tags": []string{TagLoop},
+		}).Return(nil).Once()
+
+		trip, err := service.AcceptSuggestedTags(ctx, ownerID, tripID, &SuggestedTagsInput{Tags: []string{TagSummit}})
+		require.NoError(t, err)
+		assert.Equal(t, pq.StringArray{"alps", TagSummit}, trip.Tags)
+		assert.Equal(t, pq.StringArray{TagLoop}, trip.SuggestedTags)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("dismissed tags are remembered", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(suggested(), nil).Once()
+		repo.On("SetSuggestedTags", ctx, tripID, []string{TagSummit}, []string{TagLoop}).Return(nil).Once()
+
+		trip, err := service.DismissSuggestedTags(ctx, ownerID, tripID, &SuggestedTagsInput{Tags: []string{TagLoop}})
+		require.NoError(t, err)
+		assert.Equal(t, pq.StringArray{TagSummit}, trip.SuggestedTags)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("only suggested tags, by the owner", func(t *testing.T) {
+		repo := new(mockRepository)
+		service := NewService(repo, nil, nil)
+		repo.On("GetByID", ctx, tripID).Return(suggested(), nil)
+
+		_, err := service.AcceptSuggestedTags(ctx, ownerID, tripID, &SuggestedTagsInput{Tags: []string{"riverside"}})
+		assert.ErrorIs(t, err, ErrTagNotSuggested)
+
+		_, err = service.DismissSuggestedTags(ctx, editorID, tripID, &SuggestedTagsInput{Tags: []string{TagLoop}})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestConditionAlerts_Subscribe(t *testing.T) {
+	ctx := context.Background()
+	square := func(size float64) *GeoJSONRoute {
+		return &GeoJSONRoute{Type: "Polygon", Coordinates: [][][]float64{{{7, 46}, {7 + size, 46}, {7 + size, 46 + size}, {7, 46 + size}, {7, 46}}}}
 	}
-	tripRepo.trips[trip.ID.Hex()] = trip
 
+	t.Run("to a region, with defaults", func(t *testing.T) {
+		repo := new(mockRepository)
+		alerts := NewConditionAlerts(repo, jobs.NewLocalQueue(), events.NewLocalBus())
+		repo.On("CreateConditionSubscription", ctx, mock.MatchedBy(func(s *ConditionSubscription) bool {
+			return s.UserID == viewerID && s.TripID == nil && s.Frequency == AlertsInstant &&
+				assert.ObjectsAreEqual(pq.StringArray(DefaultAlertSeverities), s.Severities)
+		}), MaxConditionSubscriptions).Return(nil).Once()
+
+		_, err := alerts.Subscribe(ctx, viewerID, &CreateConditionSubscriptionInput{Region: square(0.5)})
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("to a trip the user can view", func(t *testing.T) {
+		repo := new(mockRepository)
+		alerts := NewConditionAlerts(repo, jobs.NewLocalQueue(), events.NewLocalBus())
+		repo.On("GetByIDWith", ctx, tripID, Relations{Collaborators: true}).Return(privateTrip(), nil)
+		repo.On("CreateConditionSubscription", ctx, mock.Anything, MaxConditionSubscriptions).Return(nil).Once()
+
+		subscription, err := alerts.Subscribe(ctx, editorID, &CreateConditionSubscriptionInput{TripID: tripID, Frequency: AlertsDaily})
+		require.NoError(t, err)
+		assert.Equal(t, tripID, *subscription.TripID)
+		assert.Equal(t, AlertsDaily, subscription.Frequency)
+
+		_, err = alerts.Subscribe(ctx, "stranger", &CreateConditionSubscriptionInput{TripID: tripID})
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		repo.AssertNumberOfCalls(t, "CreateConditionSubscription", 1)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		alerts := NewConditionAlerts(new(mockRepository), jobs.NewLocalQueue(), events.NewLocalBus())
+		tests := []struct {
+			name  string
+			input CreateConditionSubscriptionInput
+			err   error
+		}{
+			{"neither", CreateConditionSubscriptionInput{}, ErrInvalidSubscription},
+			{"both", CreateConditionSubscriptionInput{TripID: tripID, Region: square(0.5)}, ErrInvalidSubscription},
+			{"not a polygon", CreateConditionSubscriptionInput{Region: &GeoJSONRoute{Type: "Point", Coordinates: []float64{7, 46}}}, ErrInvalidSubscription},
+			{"too large", CreateConditionSubscriptionInput{Region: square(3)}, ErrAlertRegionTooLarge},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := alerts.Subscribe(ctx, viewerID, &tt.input)
+				assert.ErrorIs(t, err, tt.err)
+			})
+		}
+	})
+}
+
+func TestConditionAlerts_Deliver(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sends are scheduled when due", func(t *testing.T) {
+		repo := new(mockRepository)
+		queue := jobs.NewLocalQueue()
+		alerts := NewConditionAlerts(repo, queue, events.NewLocalBus())
+		dueAt := time.Now().Add(time.Hour)
+		repo.On("QueueConditionAlerts", ctx, "c1").Return([]*ConditionSubscription{{ID: "s1", NextSendAt: &dueAt}}, nil).Once()
+
+		event := events.New(events.ConditionVerified, "trip", tripID, ownerID, map[string]interface{}{"condition_id": "c1"})
+		require.NoError(t, alerts.HandleConditionVerified(ctx, event))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("waiting alerts are sent as one event", func(t *testing.T) {
+		repo := new(mockRepository)
+		bus := events.NewLocalBus()
+		var sent []events.Event
+		bus.Subscribe(events.ConditionAlerts, func(ctx context.Context, event events.Event) error {
+			sent = append(sent, event)
+			return nil
+		})
+		alerts := NewConditionAlerts(repo, jobs.NewLocalQueue(), bus)
+
+		subscription := &ConditionSubscription{ID: "s1", UserID: viewerID, Frequency: AlertsDaily}
+		batch := []*ConditionAlert{{ConditionID: "c1", TripID: tripID}, {ConditionID: "c2", TripID: tripID}}
+		repo.On("TakeConditionAlerts", ctx, "s1").Return(subscription, batch, nil).Once()
+		repo.On("TakeConditionAlerts", ctx, "s1").Return(subscription, []*ConditionAlert{}, nil).Once()
+
+		job := &jobs.Job{Type: JobSendConditionAlerts, Payload: json.RawMessage(`{"subscription_id": "s1"}`)}
+		require.NoError(t, alerts.send(ctx, job))
+		require.Len(t, sent, 1)
+		assert.Equal(t, viewerID, sent[0].EntityID)
+		assert.Equal(t, batch, sent[0].Data["alerts"])
+
+		// Nothing is sent when nothing is waiting
+		require.NoError(t, alerts.send(ctx, job))
+		assert.Len(t, sent, 1)
+	})
+
+	t.Run("unsubscribed", func(t *testing.T) {
+		repo := new(mockRepository)
+		alerts := NewConditionAlerts(repo, jobs.NewLocalQueue(), events.NewLocalBus())
+		repo.On("TakeConditionAlerts", ctx, "s1").Return(nil, nil, ErrSubscriptionNotFound).Once()
+
+		job := &jobs.Job{Type: JobSendConditionAlerts, Payload: json.RawMessage(`{"subscription_id": "s1"}`)}
+		assert.NoError(t, alerts.send(ctx, job))
+	})
+}
+
+func TestPublisher_Reschedule(t *testing.T) {
 	ctx := context.Background()
 
-	// Test owner can update
-	updateInput := &UpdateTripInput{
-		Name: strPtr("Updated Trip"),
+	t.Run("every waiting trip is queued again", func(t *testing.T) {
+		repo := new(mockRepository)
+		publisher := NewPublisher(repo, jobs.NewLocalQueue(), events.NewLocalBus())
+
+		lostJob := "lost-job"
+		overdue := time.Now().Add(-time.Hour)
+		upcoming := time.Now().Add(time.Hour)
+		repo.On("ListScheduledPublications", ctx).Return([]*ScheduledPublication{
+			{TripID: "overdue", PublishAt: overdue, JobID: &lostJob},
+			{TripID: "upcoming", PublishAt: upcoming},
+		}, nil).Once()
+
+		rescheduled := func(publishAt time.Time) interface{} {
+			return mock.MatchedBy(func(updates map[string]interface{}) bool {
+				jobID, _ := updates["publish_job_id"].(string)
+				return updates["publish_at"] == publishAt && jobID != "" && jobID != lostJob
+			})
+		}
+		// A trip that fails to reschedule does not hold up the others
+		repo.On("Update", ctx, "overdue", rescheduled(overdue)).Return(errors.New("database error")).Once()
+		repo.On("Update", ctx, "upcoming", rescheduled(upcoming)).Return(nil).Once()
+
+		require.NoError(t, publisher.Reschedule(ctx))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("listing fails", func(t *testing.T) {
+		repo := new(mockRepository)
+		publisher := NewPublisher(repo, jobs.NewLocalQueue(), events.NewLocalBus())
+		repo.On("ListScheduledPublications", ctx).Return(nil, errors.New("database error")).Once()
+
+		assert.Error(t, publisher.Reschedule(ctx))
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+// remoteStorage is storage whose files are not on the local disk, so they
+// can only be read through Open
+type remoteStorage struct {
+	media.Storage
+}
+
+func (remoteStorage) GetFullPath(filePath string) string {
+	return "/nonexistent/" + filePath
+}
+
+func TestOfflinePackService_Build(t *testing.T) {
+	ctx := context.Background()
+	mediaID := "6f1c3a1e-2b7d-4c55-9a0e-8d7a3c2b1f00"
+	storage, err := media.NewDiskStorage(&config.MediaConfig{StoragePath: t.TempDir(), CDNURL: "http://localhost:8080/media"})
+	require.NoError(t, err)
+	_, err = storage.Save("layers/zones.geojson", []byte(`{"type":"FeatureCollection","features":[]}`))
+	require.NoError(t, err)
+
+	repo := new(mockRepository)
+	packs := NewOfflinePackService(repo, remoteStorage{storage}, jobs.NewLocalQueue(), time.Minute)
+
+	previous := "offline/old.zip"
+	_, err = storage.Save(previous, []byte("old"))
+	require.NoError(t, err)
+	requestedAt := time.Now()
+	repo.On("GetOfflinePack", ctx, tripID).Return(&OfflinePack{TripID: tripID, Status: OfflinePackPending, StoragePath: &previous, RequestedAt: requestedAt}, nil).Once()
+
+	trip := privateTrip()
+	trip.CoverImage = "http://localhost:8080/media/cover.jpg"
+	trip.Content = TripContent{{Type: BlockImage, MediaID: mediaID, Caption: "The col"}}
+	trip.AccessFees = AccessFees{{Name: "Parking"}}
+	trip.EmergencyContacts = &JSONB{"ranger": "555-0100"}
+	trip.SharedWith = pq.StringArray{"friend@example.com"}
+	arrival := time.Date(2026, 7, 4, 9, 0, 0, 0, time.UTC)
+	trip.Waypoints = []Waypoint{
+		{Kind: WaypointStop, PlaceID: "place-1", Notes: "Key under the mat", ArrivalTime: &arrival, Place: &Place{ID: "place-1", Name: "Hut", Location: &GeoJSON{Type: "Point", Coordinates: []float64{7.1, 46.1}}}},
+		{Kind: WaypointStop, PlaceID: "home", Place: &Place{ID: "home", Name: "Home", Privacy: PrivacyPrivate, Location: &GeoJSON{Type: "Point", Coordinates: []float64{7.2, 46.2}}, AccessFees: AccessFees{{Name: "Driveway"}}}},
 	}
-	_, err := service.Update(ctx, trip.ID, owner.ID, updateInput)
-	if err != nil {
-		t.Errorf("Owner should be able to update trip: %v", err)
+	repo.On("GetByIDWith", ctx, tripID, Relations{Waypoints: true}).Return(trip, nil).Once()
+	repo.On("ListRouteVariants", ctx, tripID).Return([]*TripRouteVariant{
+		{ID: "primary", Name: "Main", IsPrimary: true},
+		{ID: "alt", Name: "Low route", RouteGeoJSON: &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{7.1, 46.1}, {7.2, 46.2}}}},
+	}, nil).Once()
+	repo.On("ListAnnotations", ctx, tripID).Return([]*TripAnnotation{{ID: "note", Kind: "label", Label: "Water", Geometry: &GeoJSONRoute{Type: "Point", Coordinates: []float64{7.1, 46.1}}}}, nil).Once()
+	repo.On("ListLayers", ctx, tripID).Return([]*TripLayer{
+		{ID: "zones", Name: "Zones", Format: LayerFormatGeoJSON, StoragePath: "layers/zones.geojson"},
+		{ID: "gone", Name: "Gone", Format: LayerFormatKML, StoragePath: "layers/gone.kml"},
+	}, nil).Once()
+	repo.On("ListMediaFiles", ctx, []string{mediaID}).Return([]*OfflinePackMedia{{ID: mediaID, StoragePath: "images/col.jpg", MimeType: "image/jpeg"}}, nil).Once()
+
+	var saved *OfflinePack
+	repo.On("CompleteOfflinePack", ctx, mock.AnythingOfType("*trips.OfflinePack")).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*OfflinePack)
+	}).Return(true, nil).Once()
+
+	job := &jobs.Job{Type: JobBuildOfflinePack, Payload: json.RawMessage(`{"trip_id": "` + tripID + `"}`)}
+	require.NoError(t, packs.build(ctx, job))
+	repo.AssertExpectations(t)
+
+	require.NotNil(t, saved)
+	assert.NoFileExists(t, storage.GetFullPath(previous))
+	data, err := os.ReadFile(storage.GetFullPath(*saved.StoragePath))
+	require.NoError(t, err)
+	assert.EqualValues(t, len(data), *saved.SizeBytes)
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, file := range archive.File {
+		r, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		files[file.Name] = content
 	}
+	assert.Len(t, files, 3)
+	assert.Contains(t, files, "layers/zones.geojson")
 
-	// Test editor can update
-	_, err = service.Update(ctx, trip.ID, editor.ID, updateInput)
-	if err != nil {
-		t.Errorf("Editor should be able to update trip: %v", err)
+	var geoJSON exportFeatureCollection
+	require.NoError(t, json.Unmarshal(files["trip.geojson"], &geoJSON))
+	kinds := []string{}
+	for _, feature := range geoJSON.Features {
+		kinds = append(kinds, feature.Properties["feature"].(string))
 	}
+	assert.Equal(t, []string{"variant", "waypoint", "annotation"}, kinds)
 
-	// Test viewer cannot update
-	_, err = service.Update(ctx, trip.ID, viewer.ID, updateInput)
-	if err != ErrUnauthorized {
-		t.Errorf("Viewer should not be able to update trip")
+	var metadata OfflinePackMetadata
+	require.NoError(t, json.Unmarshal(files["metadata.json"], &metadata))
+	assert.Equal(t, OfflinePackFormatVersion, metadata.FormatVersion)
+	require.Len(t, metadata.Trip.Waypoints, 1)
+	assert.Equal(t, "Hut", metadata.Trip.Waypoints[0].Place.Name)
+
+	// Anyone who may see the trip can download the pack, so it holds the
+	// trip as the public sees it
+	assert.Nil(t, metadata.Trip.EmergencyContacts)
+	assert.Empty(t, metadata.Trip.SharedWith)
+	assert.Empty(t, metadata.Trip.Collaborators)
+	assert.Empty(t, metadata.Trip.Waypoints[0].Notes)
+	assert.Nil(t, metadata.Trip.Waypoints[0].ArrivalTime)
+	for _, name := range []string{"metadata.json", "trip.geojson"} {
+		assert.NotContains(t, string(files[name]), "555-0100", name)
+		assert.NotContains(t, string(files[name]), "Home", name)
+		assert.NotContains(t, string(files[name]), "Key under the mat", name)
 	}
+	assert.Len(t, metadata.Variants, 2)
+	assert.Len(t, metadata.Fees.Fees, 1)
+	require.Len(t, metadata.Layers, 2)
+	assert.Equal(t, "layers/zones.geojson", metadata.Layers[0].File)
+	assert.Empty(t, metadata.Layers[1].File)
+	require.Len(t, metadata.Media, 2)
+	assert.True(t, metadata.Media[0].Cover)
+	assert.Equal(t, "The col", metadata.Media[1].Caption)
+	assert.Equal(t, "http://localhost:8080/media/images/col.jpg", metadata.Media[1].URL)
+}
 
-	// Test only owner can delete
-	err = service.Delete(ctx, trip.ID, editor.ID)
-	if err != ErrUnauthorized {
-		t.Errorf("Non-owner should not be able to delete trip")
+func TestDraftBase(t *testing.T) {
+	current := map[string]interface{}{"title": "Dipsea", "description": "Stairs", "owner_id": ownerID}
+	data := JSONB{"title": "Dipsea Trail", "owner_id": viewerID, "cursor": 12}
+
+	// Only fields a draft can change are recorded, editor state is not
+	assert.Equal(t, JSONB{"title": "Dipsea"}, draftBase(data, current))
+	assert.Empty(t, draftBase(JSONB{}, current))
+}
+
+func TestMergeDraft(t *testing.T) {
+	current := map[string]interface{}{
+		"title":       "Dipsea",
+		"description": "Stairs, then more stairs",
+		"tags":        []interface{}{"coast", "stairs"},
 	}
 
-	err = service.Delete(ctx, trip.ID, owner.ID)
-	if err != nil {
-		t.Errorf("Owner should be able to delete trip: %v", err)
+	tests := []struct {
+		name      string
+		data      JSONB
+		base      JSONB
+		changes   JSONB
+		applied   []string
+		conflicts []DraftConflict
+	}{
+		{
+			name:    "unchanged since the draft started",
+			data:    JSONB{"title": "Dipsea Trail"},
+			base:    JSONB{"title": "Dipsea"},
+			changes: JSONB{"title": "Dipsea Trail"},
+			applied: []string{"title"},
+		},
+		{
+			name:    "changed on both sides",
+			data:    JSONB{"title": "Dipsea Trail"},
+			base:    JSONB{"title": "Dipsea Race"},
+			changes: JSONB{},
+			conflicts: []DraftConflict{
+				{Field: "title", Draft: "Dipsea Trail", Current: "Dipsea"},
+			},
+		},
+		{
+			name:    "already applied",
+			data:    JSONB{"title": "Dipsea", "tags": []interface{}{"coast", "stairs"}},
+			base:    JSONB{"title": "Something else", "tags": []interface{}{}},
+			changes: JSONB{},
+		},
+		{
+			name:    "some fields apply and others conflict",
+			data:    JSONB{"description": "Mind the stairs", "tags": []interface{}{"coast"}},
+			base:    JSONB{"description": "Stairs, then more stairs", "tags": []interface{}{"coast", "hill"}},
+			changes: JSONB{"description": "Mind the stairs"},
+			applied: []string{"description"},
+			conflicts: []DraftConflict{
+				{Field: "tags", Draft: []interface{}{"coast"}, Current: []interface{}{"coast", "stairs"}},
+			},
+		},
+		{
+			name:    "editor state is never applied",
+			data:    JSONB{"cursor": 12, "selection": "title"},
+			base:    JSONB{},
+			changes: JSONB{},
+		},
 	}
-}
 
-func strPtr(s string) *string {
-	return &s
-}
\ No newline at end of file
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, applied, conflicts := mergeDraft(&TripDraft{Data: tt.data, Base: tt.base}, current)
+			assert.Equal(t, tt.changes, changes)
+			assert.Equal(t, tt.applied, applied)
+			assert.Equal(t, tt.conflicts, conflicts)
+		})
+	}
+}
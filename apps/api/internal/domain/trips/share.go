@@ -0,0 +1,120 @@
+package trips
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+// Share link permissions
+const (
+	SharePermissionView = "view"
+	SharePermissionEdit = "edit"
+)
+
+// shareTokenBytes is how much randomness a share token carries
+const shareTokenBytes = 32
+
+// Share scopes, carried by tokens minted from a share link
+const (
+	ShareScopeRead = "trip:read"
+	ShareScopeEdit = "trip:edit"
+)
+
+// ShareGrant is what a guest holding a scoped token may do. It belongs to no
+// user and covers a single trip.
+type ShareGrant struct {
+	TripID string
+	Scope  string
+}
+
+// Allows reports whether the grant covers the permission on the trip. Read
+// access covers viewing the trip; edit access also covers updating it.
+func (g *ShareGrant) Allows(tripID, permission string) bool {
+	if g == nil || g.TripID != tripID {
+		return false
+	}
+
+	switch permission {
+	case "trip.read":
+		return g.Scope == ShareScopeRead || g.Scope == ShareScopeEdit
+	case "trip.update":
+		return g.Scope == ShareScopeEdit
+	default:
+		return false
+	}
+}
+
+// Audience returns the audience the guest sees the trip as. Only the owner
+// can hand out edit access, so edit grants see what collaborators see; any
+// editor can share the trip to be viewed, so read grants see what friends see.
+func (g *ShareGrant) Audience() string {
+	if g.Scope == ShareScopeEdit {
+		return AudienceCollaborator
+	}
+	return AudienceFriend
+}
+
+// Scope returns the scope a token minted from the link carries
+func (l *ActivityShareLink) Scope() string {
+	if l.Permissions == SharePermissionEdit {
+		return ShareScopeEdit
+	}
+	return ShareScopeRead
+}
+
+// CreateShareLink mints a link that lets anyone holding it open the trip
+// without an account. Anyone who can edit the trip can share it to be
+// viewed; only the owner can hand out edit access.
+func (s *servicePg) CreateShareLink(ctx context.Context, userID, tripID string, input *CreateShareLinkInput) (*ActivityShareLink, error) {
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, ErrShareExpiryInPast
+	}
+
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := input.Permissions
+	if permissions == "" {
+		permissions = SharePermissionView
+	}
+
+	if !s.canUserEditTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+	if permissions == SharePermissionEdit && !trip.IsOwner(userID) {
+		return nil, ErrUnauthorized
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return nil, err
+	}
+
+	link := &ActivityShareLink{
+		TripID:      tripID,
+		CreatedBy:   userID,
+		ShareToken:  token,
+		Permissions: permissions,
+		MaxUses:     input.MaxUses,
+		ExpiresAt:   input.ExpiresAt,
+	}
+	if err := s.repo.CreateShareLink(ctx, link); err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// newShareToken returns a random token that is safe to put in a URL
+func newShareToken() (string, error) {
+	b := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate share token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
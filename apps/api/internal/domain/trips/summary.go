@@ -0,0 +1,291 @@
+package trips
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Oferzz/newMap/apps/api/internal/cache"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+)
+
+// JobSummarizeTrip regenerates a trip's summary after it changes
+const JobSummarizeTrip = "trip.summarize"
+
+// MaxSummaryLength is how long a generated summary may be, in characters
+const MaxSummaryLength = 280
+
+// maxSummaryHighlights is how many waypoints a summary names
+const maxSummaryHighlights = 3
+
+// summaryFields are the fields of a trip its summary is written from. Edits
+// to any other field leave the summary as it is.
+var summaryFields = map[string]bool{
+	"activity_type":    true,
+	"best_seasons":     true,
+	"difficulty_level": true,
+	"distance_km":      true,
+	"duration_hours":   true,
+	"elevation_gain_m": true,
+	"route_type":       true,
+}
+
+// Summarizer writes a short summary of a trip, which has its waypoints
+// loaded
+type Summarizer interface {
+	Summarize(ctx context.Context, trip *Trip) (string, error)
+}
+
+// TemplateSummarizer summarizes a trip by filling in sentences from its
+// stats, waypoints and seasons
+type TemplateSummarizer struct{}
+
+// Summarize implements Summarizer
+func (TemplateSummarizer) Summarize(ctx context.Context, trip *Trip) (string, error) {
+	return SummarizeTrip(trip), nil
+}
+
+// SummarizeTrip describes a trip in a few sentences, such as "Moderate
+// 12.4 km hiking loop with 650 m of climbing. Passes Emerald Bay and Eagle
+// Falls. Best in summer and autumn." Sentences that would make it longer
+// than MaxSummaryLength are left out.
+func SummarizeTrip(trip *Trip) string {
+	sentences := []string{}
+	if sentence := outlineSentence(trip); sentence != "" {
+		sentences = append(sentences, sentence)
+	}
+	if names := highlights(trip); len(names) > 0 {
+		sentences = append(sentences, "Passes "+joinNames(names)+".")
+	}
+	if len(trip.BestSeasons) > 0 {
+		seasons := make([]string, len(trip.BestSeasons))
+		for i, season := range trip.BestSeasons {
+			seasons[i] = strings.ToLower(season)
+		}
+		sentences = append(sentences, "Best in "+joinNames(seasons)+".")
+	}
+
+	summary := ""
+	for _, sentence := range sentences {
+		next := strings.TrimSpace(summary + " " + sentence)
+		if utf8.RuneCountInString(next) > MaxSummaryLength {
+			break
+		}
+		summary = next
+	}
+	if summary == "" && len(sentences) > 0 {
+		summary = cutSummary(sentences[0])
+	}
+	return summary
+}
+
+// cutSummary shortens a summary to MaxSummaryLength
+func cutSummary(summary string) string {
+	if runes := []rune(summary); len(runes) > MaxSummaryLength {
+		return strings.TrimSpace(string(runes[:MaxSummaryLength-1])) + "…"
+	}
+	return summary
+}
+
+// routeNouns name a trip by the shape of its route
+var routeNouns = map[string]string{
+	"loop":           "loop",
+	"out_and_back":   "out-and-back",
+	"point_to_point": "point-to-point route",
+}
+
+// outlineSentence describes the trip's difficulty, length and shape, or is
+// empty when none of them are known
+func outlineSentence(trip *Trip) string {
+	words := []string{}
+	if trip.DifficultyLevel != "" {
+		words = append(words, trip.DifficultyLevel)
+	}
+	if trip.DistanceKm != nil && *trip.DistanceKm > 0 {
+		words = append(words, fmt.Sprintf("%.1f km", *trip.DistanceKm))
+	}
+	if trip.ActivityType != "" && trip.ActivityType != "general" {
+		words = append(words, trip.ActivityType)
+	}
+
+	clauses := []string{}
+	if trip.ElevationGainM != nil && *trip.ElevationGainM > 0 {
+		clauses = append(clauses, fmt.Sprintf("with %d m of climbing", *trip.ElevationGainM))
+	}
+	if trip.DurationHours != nil && *trip.DurationHours > 0 {
+		clauses = append(clauses, "taking about "+formatHours(*trip.DurationHours))
+	}
+
+	noun, ok := routeNouns[trip.RouteType]
+	if len(words) == 0 && len(clauses) == 0 && !ok {
+		return ""
+	}
+	if !ok {
+		noun = "trip"
+	}
+
+	sentence := strings.Join(append(words, noun), " ")
+	if len(clauses) > 0 {
+		sentence += " " + strings.Join(clauses, ", ")
+	}
+	return strings.ToUpper(sentence[:1]) + sentence[1:] + "."
+}
+
+// formatHours rounds a duration to the half hour
+func formatHours(hours float64) string {
+	rounded := math.Max(math.Round(hours*2)/2, 0.5)
+	if rounded == 1 {
+		return "1 hour"
+	}
+	return fmt.Sprintf("%g hours", rounded)
+}
+
+// highlights are the names of the first places the trip stops at, each once
+func highlights(trip *Trip) []string {
+	names := []string{}
+	seen := make(map[string]bool)
+	for _, waypoint := range trip.Waypoints {
+		if waypoint.Kind != WaypointStop || waypoint.Place == nil {
+			continue
+		}
+		name := strings.TrimSpace(waypoint.Place.Name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+		if len(names) == maxSummaryHighlights {
+			break
+		}
+	}
+	return names
+}
+
+// joinNames lists names as "a, b and c"
+func joinNames(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+type summarizePayload struct {
+	TripID string `json:"trip_id"`
+}
+
+// Summaries keeps trips' summaries in step with the trips, regenerating one
+// in the background whenever a trip changes in a way its summary shows
+type Summaries struct {
+	repo       Repository
+	queue      jobs.Queue
+	summarizer Summarizer
+	bus        events.Bus
+}
+
+// NewSummaries creates summaries written by summarizer and registers their
+// job handler. A changed summary is announced on bus as an invalidation of
+// the trip, so that cached copies of it are dropped.
+func NewSummaries(repo Repository, queue jobs.Queue, summarizer Summarizer, bus events.Bus) *Summaries {
+	s := &Summaries{
+		repo:       repo,
+		queue:      queue,
+		summarizer: summarizer,
+		bus:        bus,
+	}
+
+	queue.Register(JobSummarizeTrip, s.summarize)
+
+	return s
+}
+
+// HandleTripCreated queues a summary of a new trip
+func (s *Summaries) HandleTripCreated(ctx context.Context, event events.Event) error {
+	return s.Queue(ctx, event.EntityID)
+}
+
+// HandleTripUpdated queues a new summary of a trip when a field the summary
+// is written from changed
+func (s *Summaries) HandleTripUpdated(ctx context.Context, event events.Event) error {
+	if !changesSummary(event.Data["fields"]) {
+		return nil
+	}
+	return s.Queue(ctx, event.EntityID)
+}
+
+// HandleWaypointsChanged queues a new summary of a trip whose stops changed,
+// since it names them
+func (s *Summaries) HandleWaypointsChanged(ctx context.Context, event events.Event) error {
+	return s.Queue(ctx, event.EntityID)
+}
+
+// Queue queues a summary of the trip
+func (s *Summaries) Queue(ctx context.Context, tripID string) error {
+	if _, err := s.queue.Enqueue(ctx, JobSummarizeTrip, summarizePayload{TripID: tripID}); err != nil {
+		return fmt.Errorf("failed to queue trip summary: %w", err)
+	}
+	return nil
+}
+
+// changesSummary reports whether the fields of an update event include one
+// the summary is written from
+func changesSummary(fields interface{}) bool {
+	return changesAny(fields, summaryFields)
+}
+
+// changesAny reports whether the fields of an update event include one of
+// the watched fields, whether they are strings or decoded from JSON
+func changesAny(fields interface{}, watched map[string]bool) bool {
+	switch fields := fields.(type) {
+	case []string:
+		for _, field := range fields {
+			if watched[field] {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, field := range fields {
+			if name, ok := field.(string); ok && watched[name] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// summarize is the job handler for JobSummarizeTrip. The summary is only
+// saved when it changed.
+func (s *Summaries) summarize(ctx context.Context, job *jobs.Job) error {
+	var payload summarizePayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	trip, err := s.repo.GetByIDWith(ctx, payload.TripID, AllRelations)
+	if err != nil {
+		if errors.Is(err, ErrTripNotFound) {
+			// Deleted since the job was queued
+			return nil
+		}
+		return err
+	}
+
+	summary, err := s.summarizer.Summarize(ctx, trip)
+	if err != nil {
+		return fmt.Errorf("failed to summarize trip %s: %w", trip.ID, err)
+	}
+	summary = cutSummary(strings.TrimSpace(summary))
+	if summary == trip.Summary {
+		return nil
+	}
+
+	if err := s.repo.SetSummary(ctx, trip.ID, summary); err != nil {
+		return err
+	}
+
+	invalidation := cache.TripInvalidation{TripID: trip.ID, UserIDs: tripMembers(trip)}
+	return s.bus.Publish(ctx, invalidation.Event(""))
+}
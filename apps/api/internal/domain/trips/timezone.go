@@ -0,0 +1,83 @@
+package trips
+
+import (
+	"time"
+)
+
+// Waypoint times and time windows are instants: they are stored in UTC and
+// shown in the trip's time zone. Trip dates are calendar dates in that zone.
+
+// validTimezone reports whether the name is an IANA time zone, such as
+// "Europe/Zurich" or "UTC"
+func validTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// tripLocation is the trip's time zone, UTC when it has none or an unknown one
+func tripLocation(trip *Trip) *time.Location {
+	if trip.Timezone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(trip.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// startOfDay is midnight at the start of the calendar date in the location.
+// Dates are read off as they are, without converting them first, so a date
+// stored as UTC midnight stays the same day.
+func startOfDay(date time.Time, location *time.Location) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, location)
+}
+
+// timeIn returns the time in the location, or nil
+func timeIn(t *time.Time, location *time.Location) *time.Time {
+	if t == nil {
+		return nil
+	}
+	in := t.In(location)
+	return &in
+}
+
+// in returns a copy of the window with its times in the location
+func (w *TimeWindow) in(location *time.Location) *TimeWindow {
+	if w == nil {
+		return nil
+	}
+	return &TimeWindow{
+		OpensAt:  timeIn(w.OpensAt, location),
+		ClosesAt: timeIn(w.ClosesAt, location),
+		Label:    w.Label,
+	}
+}
+
+// localize shows the waypoint's times in the location
+func (w *Waypoint) localize(location *time.Location) {
+	w.ArrivalTime = timeIn(w.ArrivalTime, location)
+	w.DepartureTime = timeIn(w.DepartureTime, location)
+	w.Window = w.Window.in(location)
+}
+
+// localizeTimes shows the times of the trip's waypoints in its time zone
+func (t *Trip) localizeTimes() {
+	location := tripLocation(t)
+	for i := range t.Waypoints {
+		t.Waypoints[i].localize(location)
+	}
+}
+
+// localize shows the itinerary's estimated times and windows in the location
+func (it *Itinerary) localize(location *time.Location) {
+	for i := range it.Stops {
+		stop := &it.Stops[i]
+		stop.EstimatedArrival = timeIn(stop.EstimatedArrival, location)
+		stop.EstimatedDeparture = timeIn(stop.EstimatedDeparture, location)
+		stop.Window = stop.Window.in(location)
+	}
+}
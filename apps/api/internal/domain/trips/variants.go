@@ -0,0 +1,82 @@
+package trips
+
+import (
+	"math"
+	"time"
+)
+
+// TripRouteVariant is a named alternative to a trip's route, such as a winter
+// route or a bad-weather bailout. The primary variant is the trip's route.
+type TripRouteVariant struct {
+	ID             string        `db:"id" json:"id"`
+	TripID         string        `db:"trip_id" json:"trip_id"`
+	Name           string        `db:"name" json:"name"`
+	Description    string        `db:"description" json:"description"`
+	RouteGeoJSON   *GeoJSONRoute `db:"route_geojson" json:"route_geojson"`
+	DistanceKm     *float64      `db:"distance_km" json:"distance_km"`
+	ElevationGainM *int          `db:"elevation_gain_m" json:"elevation_gain_m"`
+	DurationHours  *float64      `db:"duration_hours" json:"duration_hours"`
+	IsPrimary      bool          `db:"is_primary" json:"is_primary"`
+	CreatedBy      string        `db:"created_by" json:"created_by"`
+	CreatedAt      time.Time     `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time     `db:"updated_at" json:"updated_at"`
+}
+
+type CreateRouteVariantInput struct {
+	Name           string        `json:"name" binding:"required,min=1,max=100"`
+	Description    string        `json:"description" binding:"max=1000"`
+	RouteGeoJSON   *GeoJSONRoute `json:"route_geojson" binding:"required"`
+	DistanceKm     *float64      `json:"distance_km" binding:"omitempty,min=0"`
+	ElevationGainM *int          `json:"elevation_gain_m" binding:"omitempty,min=0"`
+	DurationHours  *float64      `json:"duration_hours" binding:"omitempty,min=0"`
+	IsPrimary      bool          `json:"is_primary"`
+}
+
+type UpdateRouteVariantInput struct {
+	Name           *string       `json:"name,omitempty" binding:"omitempty,min=1,max=100"`
+	Description    *string       `json:"description,omitempty" binding:"omitempty,max=1000"`
+	RouteGeoJSON   *GeoJSONRoute `json:"route_geojson,omitempty"`
+	DistanceKm     *float64      `json:"distance_km,omitempty" binding:"omitempty,min=0"`
+	ElevationGainM *int          `json:"elevation_gain_m,omitempty" binding:"omitempty,min=0"`
+	DurationHours  *float64      `json:"duration_hours,omitempty" binding:"omitempty,min=0"`
+}
+
+// routeLengthKm measures a LineString or MultiLineString route, reporting
+// false for any other geometry
+func routeLengthKm(route *GeoJSONRoute) (float64, bool) {
+	if route == nil {
+		return 0, false
+	}
+
+	var lines [][][]float64
+	switch route.Type {
+	case "LineString":
+		var line [][]float64
+		if !decodeCoordinates(route.Coordinates, &line) {
+			return 0, false
+		}
+		lines = [][][]float64{line}
+	case "MultiLineString":
+		if !decodeCoordinates(route.Coordinates, &lines) {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	meters := 0.0
+	for _, line := range lines {
+		if len(line) < 2 {
+			return 0, false
+		}
+		for _, position := range line {
+			if !validPosition(position) {
+				return 0, false
+			}
+		}
+		meters += lineLength(line)
+	}
+
+	// Kept to the precision the column stores
+	return math.Round(meters/10) / 100, true
+}
@@ -0,0 +1,33 @@
+package trips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteLengthKm(t *testing.T) {
+	tests := []struct {
+		name   string
+		route  *GeoJSONRoute
+		length float64
+		ok     bool
+	}{
+		{"line", &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{0, 0}, {0, 0.5}, {0, 1}}}, 111.2, true},
+		{"decoded JSON", &GeoJSONRoute{Type: "LineString", Coordinates: []interface{}{[]interface{}{0.0, 0.0, 1200.0}, []interface{}{0.0, 1.0, 1400.0}}}, 111.2, true},
+		{"lines are added up", &GeoJSONRoute{Type: "MultiLineString", Coordinates: [][][]float64{{{0, 0}, {0, 1}}, {{0, 1}, {0, 2}}}}, 222.39, true},
+		{"no route", nil, 0, false},
+		{"a point", &GeoJSONRoute{Type: "Point", Coordinates: []float64{0, 0}}, 0, false},
+		{"a single position", &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{0, 0}}}, 0, false},
+		{"off the earth", &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{0, 0}, {0, 91}}}, 0, false},
+		{"not positions", &GeoJSONRoute{Type: "LineString", Coordinates: "0,0 0,1"}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			length, ok := routeLengthKm(tt.route)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.length, length)
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package trips
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/pkg/repoerr"
+)
+
+// How a trusted verifier reviewed a condition report
+const (
+	ReviewConfirmed = "confirmed"
+	ReviewDisputed  = "disputed"
+)
+
+// ConditionWeight ranks a condition report by how far it is vouched for, for
+// trip details to show the most trustworthy reports first: confirmed by a
+// trusted verifier, then verified by the trip's owner, then unverified, with
+// disputed reports last
+func ConditionWeight(condition *ActivityCondition) int {
+	switch {
+	case condition.ReviewStatus != nil && *condition.ReviewStatus == ReviewDisputed:
+		return 0
+	case condition.ReviewStatus != nil && *condition.ReviewStatus == ReviewConfirmed:
+		return 3
+	case condition.Verified:
+		return 2
+	default:
+		return 1
+	}
+}
+
+var (
+	ErrVerifierNotFound = repoerr.NotFound("condition verifier not found")
+	ErrInvalidVerifier  = errors.New("verifier region must be a GeoJSON polygon")
+	ErrOwnCondition     = errors.New("you cannot review your own condition report")
+)
+
+// ConditionVerifier grants a user trusted verifier status for condition
+// reports in a region, on trips of an activity, or both. A grant with
+// neither covers every trip.
+type ConditionVerifier struct {
+	ID           string        `db:"id" json:"id"`
+	UserID       string        `db:"user_id" json:"user_id"`
+	Region       *GeoJSONRoute `db:"region" json:"region,omitempty"` // Polygon
+	ActivityType *string       `db:"activity_type" json:"activity_type,omitempty"`
+	GrantedBy    *string       `db:"granted_by" json:"granted_by,omitempty"`
+	CreatedAt    time.Time     `db:"created_at" json:"created_at"`
+}
+
+type GrantConditionVerifierInput struct {
+	UserID       string        `json:"user_id" binding:"required,uuid"`
+	Region       *GeoJSONRoute `json:"region"`
+	ActivityType string        `json:"activity_type" binding:"omitempty,oneof=hiking biking climbing skiing snowboarding kayaking canoeing rafting swimming surfing running walking backpacking camping fishing birdwatching photography sightseeing general"`
+}
+
+// ReviewConditionInput is a verifier's note on their confirmation or dispute
+// of a condition report
+type ReviewConditionInput struct {
+	Note string `json:"note" binding:"max=1000"`
+}
+
+// GrantConditionVerifier makes a user a trusted verifier of the condition
+// reports in a region or on trips of an activity. Grants are made by admins.
+func (s *servicePg) GrantConditionVerifier(ctx context.Context, adminID string, input *GrantConditionVerifierInput) (*ConditionVerifier, error) {
+	verifier := &ConditionVerifier{
+		UserID:    input.UserID,
+		GrantedBy: &adminID,
+	}
+	if input.Region != nil {
+		ring, ok := polygonRing(input.Region)
+		if !ok || len(ring) < 4 {
+			return nil, ErrInvalidVerifier
+		}
+		verifier.Region = input.Region
+	}
+	if input.ActivityType != "" {
+		verifier.ActivityType = &input.ActivityType
+	}
+
+	if err := s.repo.CreateConditionVerifier(ctx, verifier); err != nil {
+		return nil, err
+	}
+	return verifier, nil
+}
+
+// ListConditionVerifiers returns the verifier grants, of one user or of
+// everyone when userID is empty
+func (s *servicePg) ListConditionVerifiers(ctx context.Context, userID string) ([]*ConditionVerifier, error) {
+	return s.repo.ListConditionVerifiers(ctx, userID)
+}
+
+// RevokeConditionVerifier revokes a verifier grant. Reports the verifier
+// already reviewed keep their review.
+func (s *servicePg) RevokeConditionVerifier(ctx context.Context, id string) error {
+	return s.repo.DeleteConditionVerifier(ctx, id)
+}
+
+// ConfirmCondition confirms a condition report as a trusted verifier
+// covering it, verifying it and alerting its subscribers
+func (s *servicePg) ConfirmCondition(ctx context.Context, userID, tripID, conditionID string, input *ReviewConditionInput) (*ActivityCondition, error) {
+	condition, err := s.reviewCondition(ctx, userID, tripID, conditionID, ReviewConfirmed, input.Note)
+	if err != nil {
+		return nil, err
+	}
+
+	s.announce(ctx, events.ConditionVerified, tripID, userID, map[string]interface{}{
+		"condition_id": condition.ID,
+		"severity":     condition.Severity,
+	})
+
+	return condition, nil
+}
+
+// DisputeCondition disputes a condition report as a trusted verifier
+// covering it. Disputed reports are no longer verified, and are listed
+// after every other report.
+func (s *servicePg) DisputeCondition(ctx context.Context, userID, tripID, conditionID string, input *ReviewConditionInput) (*ActivityCondition, error) {
+	condition, err := s.reviewCondition(ctx, userID, tripID, conditionID, ReviewDisputed, input.Note)
+	if err != nil {
+		return nil, err
+	}
+
+	s.announce(ctx, events.TripUpdated, tripID, userID, map[string]interface{}{
+		"fields":       []string{"conditions"},
+		"condition_id": condition.ID,
+	})
+
+	return condition, nil
+}
+
+// reviewCondition records a verifier's review of a report on a trip they
+// can see, which they did not report themselves and which a grant of theirs
+// covers
+func (s *servicePg) reviewCondition(ctx context.Context, userID, tripID, conditionID, status, note string) (*ActivityCondition, error) {
+	trip, err := s.repo.GetByIDWith(ctx, tripID, Relations{Collaborators: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.canUserAccessTrip(trip, userID) {
+		return nil, ErrUnauthorized
+	}
+
+	condition, err := s.repo.GetCondition(ctx, conditionID)
+	if err != nil {
+		return nil, err
+	}
+	if condition.TripID != tripID {
+		return nil, ErrConditionNotFound
+	}
+	if condition.ReportedBy == userID {
+		return nil, ErrOwnCondition
+	}
+
+	covered, err := s.repo.IsConditionVerifier(ctx, userID, conditionID)
+	if err != nil {
+		return nil, err
+	}
+	if !covered {
+		return nil, ErrUnauthorized
+	}
+
+	if err := s.repo.ReviewCondition(ctx, conditionID, userID, status, note); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	condition.ReviewStatus = &status
+	condition.ReviewedBy = &userID
+	condition.ReviewedAt = &now
+	condition.ReviewNote = nil
+	if note != "" {
+		condition.ReviewNote = &note
+	}
+	condition.Verified = status == ReviewConfirmed
+	condition.VerifiedBy = nil
+	if condition.Verified {
+		condition.VerifiedBy = &userID
+	}
+	condition.Weight = ConditionWeight(condition)
+
+	return condition, nil
+}
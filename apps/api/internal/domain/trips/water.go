@@ -0,0 +1,160 @@
+package trips
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Water source corridor widths, in metres either side of the route
+const (
+	DefaultWaterCorridorM = 500
+	MaxWaterCorridorM     = 2000
+)
+
+// MaxDryStretchKm is the longest part of a route that can be planned without
+// a water source to count on before the readiness check warns of it
+const MaxDryStretchKm = 15.0
+
+// waterActivities are the activities a route is walked, run or ridden
+// self-supported, so that water along it has to be planned for
+var waterActivities = map[string]bool{
+	"hiking":      true,
+	"backpacking": true,
+	"running":     true,
+	"biking":      true,
+	"walking":     true,
+	"camping":     true,
+}
+
+// WaterSourcePoint is a water source near a trip's route
+type WaterSourcePoint struct {
+	PlaceID            string        `db:"place_id" json:"place_id"`
+	Name               string        `db:"name" json:"name"`
+	Location           *GeoJSON      `db:"location" json:"location"`
+	DistanceFromRouteM float64       `db:"distance_m" json:"distance_from_route_m"`
+	Kind               string        `db:"kind" json:"kind"`
+	Reliability        string        `db:"reliability" json:"reliability"`
+	FlowingMonths      pq.Int64Array `db:"flowing_months" json:"flowing_months"`
+	TreatmentRequired  bool          `db:"treatment_required" json:"treatment_required"`
+	LastVerifiedAt     *time.Time    `db:"last_verified_at" json:"last_verified_at,omitempty"`
+	RouteKm            float64       `db:"-" json:"route_km"`         // How far along the route it is reached
+	FromPreviousKm     float64       `db:"-" json:"from_previous_km"` // From the previous source counted on, or the start
+	Dependable         bool          `db:"-" json:"dependable"`       // Can be counted on in the month of the trip
+}
+
+// flowsIn reports whether the source can be counted on in the month.
+// Sources of unknown reliability are not.
+func (p *WaterSourcePoint) flowsIn(month time.Month) bool {
+	switch p.Reliability {
+	case "reliable":
+		return true
+	case "seasonal":
+		for _, m := range p.FlowingMonths {
+			if time.Month(m) == month {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DryStretch is a part of the route with no water source to count on
+type DryStretch struct {
+	FromKm   float64 `json:"from_km"`
+	ToKm     float64 `json:"to_km"`
+	LengthKm float64 `json:"length_km"`
+}
+
+// WaterPlan is the water sources along a trip's route and the stretches
+// between them
+type WaterPlan struct {
+	TripID       string              `json:"trip_id"`
+	Month        time.Month          `json:"month"` // 1-12, that the sources are judged for
+	RouteKm      float64             `json:"route_km"`
+	Sources      []*WaterSourcePoint `json:"sources"`
+	LongestDryKm float64             `json:"longest_dry_km"`
+	DryStretches []DryStretch        `json:"dry_stretches"` // Longer than MaxDryStretchKm
+	MaxDryKm     float64             `json:"max_dry_km"`
+}
+
+type WaterQuery struct {
+	CorridorM float64 `form:"corridor_m" binding:"omitempty,min=50,max=2000"`
+}
+
+// tripMonth is the month the trip sets out in, or the current month in its
+// time zone when it has no start date. The start date is a calendar date, so
+// it is not converted.
+func tripMonth(trip *Trip, now time.Time) time.Month {
+	if trip.StartDate != nil {
+		return trip.StartDate.Month()
+	}
+	return now.In(tripLocation(trip)).Month()
+}
+
+// planWater places the sources along the route in order and measures the
+// stretches between those that can be counted on in the month
+func planWater(trip *Trip, route *GeoJSONRoute, sources []*WaterSourcePoint, month time.Month) *WaterPlan {
+	routeKm, _ := routeLengthKm(route)
+	lines := routeLines(route)
+
+	for _, source := range sources {
+		if source.Location != nil {
+			source.RouteKm = math.Round(alongRouteM(lines, source.Location.Coordinates)/10) / 100
+		}
+		source.Dependable = source.flowsIn(month)
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].RouteKm < sources[j].RouteKm
+	})
+
+	plan := &WaterPlan{
+		TripID:       trip.ID,
+		Month:        month,
+		RouteKm:      routeKm,
+		Sources:      sources,
+		DryStretches: []DryStretch{},
+		MaxDryKm:     MaxDryStretchKm,
+	}
+
+	addStretch := func(from, to float64) {
+		length := math.Round((to-from)*100) / 100
+		if length > plan.LongestDryKm {
+			plan.LongestDryKm = length
+		}
+		if length > MaxDryStretchKm {
+			plan.DryStretches = append(plan.DryStretches, DryStretch{FromKm: from, ToKm: to, LengthKm: length})
+		}
+	}
+
+	previous := 0.0
+	for _, source := range sources {
+		source.FromPreviousKm = math.Round((source.RouteKm-previous)*100) / 100
+		if source.Dependable {
+			addStretch(previous, source.RouteKm)
+			previous = source.RouteKm
+		}
+	}
+	addStretch(previous, routeKm)
+
+	return plan
+}
+
+// waterCheck passes when the route has no long dry stretch, or someone has
+// confirmed enough water is carried for it. Trips whose activity needs no
+// water planning, or with no route yet, pass.
+func waterCheck(plan *WaterPlan, confirmedAt *time.Time) ReadinessCheck {
+	check := ReadinessCheck{
+		Check:       ReadinessWater,
+		Manual:      true,
+		ConfirmedAt: confirmedAt,
+		Passed:      plan == nil || len(plan.DryStretches) == 0 || confirmedAt != nil,
+	}
+	if plan != nil {
+		check.Action = fmt.Sprintf("Plan to carry water for %.1f km with no source to count on, and confirm it", plan.LongestDryKm)
+	}
+	return check
+}
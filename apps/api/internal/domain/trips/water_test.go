@@ -0,0 +1,103 @@
+package trips
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaterSourcePoint_FlowsIn(t *testing.T) {
+	seasonal := &WaterSourcePoint{Reliability: "seasonal", FlowingMonths: pq.Int64Array{3, 4, 5}}
+	assert.True(t, seasonal.flowsIn(time.April))
+	assert.False(t, seasonal.flowsIn(time.August))
+
+	assert.True(t, (&WaterSourcePoint{Reliability: "reliable"}).flowsIn(time.August))
+	assert.False(t, (&WaterSourcePoint{Reliability: "unknown", FlowingMonths: pq.Int64Array{8}}).flowsIn(time.August))
+}
+
+func TestTripMonth(t *testing.T) {
+	// Late on 31 July in UTC is already August in Jerusalem
+	now := time.Date(2026, 7, 31, 22, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.July, tripMonth(&Trip{}, now))
+	assert.Equal(t, time.August, tripMonth(&Trip{Timezone: "Asia/Jerusalem"}, now))
+
+	// The start date is read as it is
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.October, tripMonth(&Trip{StartDate: &start, Timezone: "America/Los_Angeles"}, now))
+}
+
+func TestPlanWater(t *testing.T) {
+	route := &GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{0, 0}, {0, 0.3}}}
+	source := func(id, reliability string, lat float64, months ...int64) *WaterSourcePoint {
+		return &WaterSourcePoint{PlaceID: id, Reliability: reliability, FlowingMonths: months, Location: &GeoJSON{Type: "Point", Coordinates: []float64{0.001, lat}}}
+	}
+
+	t.Run("stretches between dependable sources", func(t *testing.T) {
+		sources := []*WaterSourcePoint{
+			source("creek", "seasonal", 0.15, 3, 4, 5),
+			source("spring", "reliable", 0.1),
+			source("tank", "unknown", 0.05),
+		}
+
+		plan := planWater(&Trip{ID: tripID}, route, sources, time.August)
+
+		assert.Equal(t, tripID, plan.TripID)
+		assert.Equal(t, time.August, plan.Month)
+		assert.Equal(t, 33.36, plan.RouteKm)
+		assert.Equal(t, MaxDryStretchKm, plan.MaxDryKm)
+
+		var ids []string
+		for _, s := range plan.Sources {
+			ids = append(ids, s.PlaceID)
+		}
+		assert.Equal(t, []string{"tank", "spring", "creek"}, ids)
+		assert.Equal(t, []float64{5.56, 11.12, 16.68}, []float64{plan.Sources[0].RouteKm, plan.Sources[1].RouteKm, plan.Sources[2].RouteKm})
+		assert.Equal(t, []bool{false, true, false}, []bool{plan.Sources[0].Dependable, plan.Sources[1].Dependable, plan.Sources[2].Dependable})
+
+		// Distances are from the last source counted on
+		assert.Equal(t, 5.56, plan.Sources[0].FromPreviousKm)
+		assert.Equal(t, 11.12, plan.Sources[1].FromPreviousKm)
+		assert.Equal(t, 5.56, plan.Sources[2].FromPreviousKm)
+
+		// The dry creek leaves the rest of the route without water
+		assert.Equal(t, 22.24, plan.LongestDryKm)
+		assert.Equal(t, []DryStretch{{FromKm: 11.12, ToKm: 33.36, LengthKm: 22.24}}, plan.DryStretches)
+	})
+
+	t.Run("seasonal source in season", func(t *testing.T) {
+		sources := []*WaterSourcePoint{source("spring", "reliable", 0.1), source("creek", "seasonal", 0.2, 3, 4, 5)}
+
+		plan := planWater(&Trip{ID: tripID}, route, sources, time.April)
+
+		assert.InDelta(t, 11.12, plan.LongestDryKm, 0.01)
+		assert.Empty(t, plan.DryStretches)
+	})
+
+	t.Run("no sources", func(t *testing.T) {
+		plan := planWater(&Trip{ID: tripID}, route, []*WaterSourcePoint{}, time.April)
+
+		assert.Equal(t, 33.36, plan.LongestDryKm)
+		assert.Equal(t, []DryStretch{{FromKm: 0, ToKm: 33.36, LengthKm: 33.36}}, plan.DryStretches)
+	})
+}
+
+func TestWaterCheck(t *testing.T) {
+	confirmed := time.Now()
+	dry := &WaterPlan{LongestDryKm: 22.24, DryStretches: []DryStretch{{FromKm: 11.12, ToKm: 33.36, LengthKm: 22.24}}}
+
+	check := waterCheck(dry, nil)
+	assert.Equal(t, ReadinessWater, check.Check)
+	assert.True(t, check.Manual)
+	assert.False(t, check.Passed)
+	assert.Equal(t, "Plan to carry water for 22.2 km with no source to count on, and confirm it", check.Action)
+
+	assert.True(t, waterCheck(dry, &confirmed).Passed)
+	assert.True(t, waterCheck(&WaterPlan{LongestDryKm: 9}, nil).Passed)
+
+	// Nothing to plan for
+	check = waterCheck(nil, nil)
+	assert.True(t, check.Passed)
+	assert.Empty(t, check.Action)
+}
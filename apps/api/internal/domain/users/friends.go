@@ -0,0 +1,99 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/google/uuid"
+)
+
+// ErrSelfFriendRequest is returned for friend requests users send themselves
+var ErrSelfFriendRequest = errors.New("cannot send a friend request to yourself")
+
+// SendFriendRequest asks another user to be friends, letting them know. When
+// they had already asked, the two become friends straight away.
+func (s *postgresService) SendFriendRequest(ctx context.Context, fromUserID, toUserID string) (*FriendRequest, error) {
+	if _, err := uuid.Parse(toUserID); err != nil {
+		return nil, ErrUserNotFound
+	}
+	if fromUserID == toUserID {
+		return nil, ErrSelfFriendRequest
+	}
+
+	recipient, err := s.repo.GetByID(ctx, toUserID)
+	if err != nil {
+		return nil, err
+	}
+	if recipient.Status != "active" {
+		return nil, ErrUserNotFound
+	}
+
+	request, err := s.repo.CreateFriendRequest(ctx, fromUserID, toUserID)
+	if err != nil {
+		// A blocked sender is not told, and the recipient is not bothered
+		if errors.Is(err, errFriendshipBlocked) {
+			return &FriendRequest{FromUserID: fromUserID, ToUserID: toUserID, Status: "pending"}, nil
+		}
+		return nil, err
+	}
+
+	if request.Status == "accepted" {
+		// The recipient had asked first, so it is their request that was accepted
+		s.notify(ctx, events.FriendRequestAccepted, toUserID, fromUserID, request)
+	} else {
+		s.notify(ctx, events.FriendRequestReceived, toUserID, fromUserID, request)
+	}
+	return request, nil
+}
+
+// AcceptFriendRequest accepts a request sent to the user, making the two
+// friends, and lets the sender know
+func (s *postgresService) AcceptFriendRequest(ctx context.Context, userID, requestID string) error {
+	if _, err := uuid.Parse(requestID); err != nil {
+		return ErrFriendRequestNotFound
+	}
+
+	request, err := s.repo.RespondToFriendRequest(ctx, userID, requestID, true)
+	if err != nil {
+		return err
+	}
+
+	s.notify(ctx, events.FriendRequestAccepted, request.FromUserID, userID, request)
+	return nil
+}
+
+// RejectFriendRequest turns down a request sent to the user. The sender is
+// not told.
+func (s *postgresService) RejectFriendRequest(ctx context.Context, userID, requestID string) error {
+	if _, err := uuid.Parse(requestID); err != nil {
+		return ErrFriendRequestNotFound
+	}
+
+	_, err := s.repo.RespondToFriendRequest(ctx, userID, requestID, false)
+	return err
+}
+
+// GetFriendRequests returns the pending requests sent to the user, or the
+// ones they sent when incoming is false
+func (s *postgresService) GetFriendRequests(ctx context.Context, userID string, incoming bool, limit, offset int) ([]*FriendRequest, int64, error) {
+	return s.repo.ListFriendRequests(ctx, userID, incoming, limit, offset)
+}
+
+// notify tells a user about a friend request. The request has already been
+// saved, so a failure is only logged.
+func (s *postgresService) notify(ctx context.Context, eventType, userID, actorID string, request *FriendRequest) {
+	if s.bus == nil {
+		return
+	}
+
+	event := events.New(eventType, "user", userID, actorID, map[string]interface{}{
+		"request_id":   request.ID,
+		"from_user_id": request.FromUserID,
+		"to_user_id":   request.ToUserID,
+	})
+	if err := s.bus.Publish(ctx, event); err != nil {
+		log.Printf("users: failed to publish %s for user %s: %v", eventType, userID, err)
+	}
+}
@@ -1,6 +1,7 @@
 package users
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 
@@ -33,11 +34,7 @@ func (h *Handler) Register(c *gin.Context) {
 	user, err := h.service.Create(c.Request.Context(), &input)
 	if err != nil {
 		fmt.Printf("DEBUG: Service.Create failed with error: %v\n", err)
-		if err.Error() == "email already exists" || err.Error() == "username already exists" {
-			response.Conflict(c, err.Error())
-			return
-		}
-		response.InternalServerError(c, "Failed to create user")
+		response.FromError(c, err, "Failed to create user")
 		return
 	}
 
@@ -55,6 +52,8 @@ func (h *Handler) Login(c *gin.Context) {
 	}
 
 	fmt.Printf("DEBUG: Login attempt with input: Email=%s, Password=%s\n", input.Email, input.Password)
+	input.UserAgent = c.Request.UserAgent()
+	input.IPAddress = c.ClientIP()
 
 	loginResp, err := h.service.Login(c.Request.Context(), &input)
 	if err != nil {
@@ -81,13 +80,86 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 
 	loginResp, err := h.service.RefreshToken(c.Request.Context(), input.RefreshToken)
 	if err != nil {
-		response.Unauthorized(c, "Invalid refresh token")
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrUserNotFound) {
+			response.Unauthorized(c, "Invalid refresh token")
+			return
+		}
+		response.InternalServerError(c, "Failed to refresh token")
 		return
 	}
 
 	response.Success(c, loginResp)
 }
 
+// Logout ends the session of the refresh token
+func (h *Handler) Logout(c *gin.Context) {
+	var input RefreshTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.service.Logout(c.Request.Context(), input.RefreshToken); err != nil {
+		response.InternalServerError(c, "Failed to log out")
+		return
+	}
+
+	response.NoContent(c)
+}
+
+// LogoutAll ends all of the current user's sessions
+func (h *Handler) LogoutAll(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.service.LogoutAll(c.Request.Context(), userID.(string)); err != nil {
+		response.InternalServerError(c, "Failed to log out")
+		return
+	}
+
+	response.NoContent(c)
+}
+
+// ListSessions lists the devices the current user is signed in on
+func (h *Handler) ListSessions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	sessions, err := h.service.ListSessions(c.Request.Context(), userID.(string), c.GetString("sessionID"))
+	if err != nil {
+		response.InternalServerError(c, "Failed to list sessions")
+		return
+	}
+
+	response.Success(c, sessions)
+}
+
+// RevokeSession signs the current user out on one device
+func (h *Handler) RevokeSession(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.service.RevokeSession(c.Request.Context(), userID.(string), c.Param("id")); err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			response.NotFound(c, "Session not found")
+			return
+		}
+		response.InternalServerError(c, "Failed to end session")
+		return
+	}
+
+	response.NoContent(c)
+}
+
 // GetProfile retrieves the current user's profile
 func (h *Handler) GetProfile(c *gin.Context) {
 	userID, exists := c.Get("userID")
@@ -98,7 +170,11 @@ func (h *Handler) GetProfile(c *gin.Context) {
 
 	user, err := h.service.GetByID(c.Request.Context(), userID.(string))
 	if err != nil {
-		response.NotFound(c, "User not found")
+		if errors.Is(err, ErrUserNotFound) {
+			response.NotFound(c, "User not found")
+			return
+		}
+		response.InternalServerError(c, "Failed to get user")
 		return
 	}
 
@@ -111,7 +187,11 @@ func (h *Handler) GetUser(c *gin.Context) {
 	
 	user, err := h.service.GetByID(c.Request.Context(), userID)
 	if err != nil {
-		response.NotFound(c, "User not found")
+		if errors.Is(err, ErrUserNotFound) {
+			response.NotFound(c, "User not found")
+			return
+		}
+		response.InternalServerError(c, "Failed to get user")
 		return
 	}
 
@@ -134,6 +214,10 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 
 	user, err := h.service.Update(c.Request.Context(), userID.(string), &input)
 	if err != nil {
+		if errors.Is(err, ErrInvalidLocale) {
+			response.BadRequest(c, err.Error())
+			return
+		}
 		response.InternalServerError(c, "Failed to update profile")
 		return
 	}
@@ -157,7 +241,7 @@ func (h *Handler) ChangePassword(c *gin.Context) {
 
 	err := h.service.ChangePassword(c.Request.Context(), userID.(string), &input)
 	if err != nil {
-		if err.Error() == "invalid current password" {
+		if errors.Is(err, ErrInvalidPassword) {
 			response.BadRequest(c, err.Error())
 			return
 		}
@@ -181,6 +265,7 @@ func (h *Handler) SendPasswordReset(c *gin.Context) {
 	err := h.service.SendPasswordResetEmail(c.Request.Context(), input.Email)
 	if err != nil {
 		// Don't reveal if email exists or not
+		fmt.Printf("Failed to send password reset email: %v\n", err)
 		response.Success(c, gin.H{"message": "If the email exists, a password reset link has been sent"})
 		return
 	}
@@ -198,13 +283,58 @@ func (h *Handler) ResetPassword(c *gin.Context) {
 
 	err := h.service.ResetPassword(c.Request.Context(), &input)
 	if err != nil {
-		response.BadRequest(c, "Invalid or expired reset token")
+		if errors.Is(err, ErrInvalidToken) {
+			response.BadRequest(c, "Invalid or expired reset token")
+			return
+		}
+		response.InternalServerError(c, "Failed to reset password")
 		return
 	}
 
 	response.Success(c, gin.H{"message": "Password reset successfully"})
 }
 
+// VerifyEmail verifies an email address with the token sent to it
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	err := h.service.VerifyEmail(c.Request.Context(), input.Token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			response.BadRequest(c, "Invalid or expired verification token")
+			return
+		}
+		response.InternalServerError(c, "Failed to verify email")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerification sends a new verification email
+func (h *Handler) ResendVerification(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	// Don't reveal if email exists or not
+	if err := h.service.ResendVerificationEmail(c.Request.Context(), input.Email); err != nil {
+		fmt.Printf("Failed to resend verification email: %v\n", err)
+	}
+
+	response.Success(c, gin.H{"message": "If the email needs verifying, a verification link has been sent"})
+}
+
 // SearchUsers searches for users
 func (h *Handler) SearchUsers(c *gin.Context) {
 	query := c.Query("q")
@@ -215,6 +345,12 @@ func (h *Handler) SearchUsers(c *gin.Context) {
 
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
 
 	users, total, err := h.service.Search(c.Request.Context(), query, limit, offset)
 	if err != nil {
@@ -261,23 +397,19 @@ func (h *Handler) SendFriendRequest(c *gin.Context) {
 		return
 	}
 
-	toUserID := c.Param("id")
-	if toUserID == userID.(string) {
-		response.BadRequest(c, "Cannot send friend request to yourself")
+	var input SendFriendRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		response.BadRequest(c, err.Error())
 		return
 	}
 
-	err := h.service.SendFriendRequest(c.Request.Context(), userID.(string), toUserID)
+	request, err := h.service.SendFriendRequest(c.Request.Context(), userID.(string), input.UserID)
 	if err != nil {
-		if err.Error() == "friend request already exists" {
-			response.Conflict(c, err.Error())
-			return
-		}
-		response.InternalServerError(c, "Failed to send friend request")
+		friendRequestError(c, err, "Failed to send friend request")
 		return
 	}
 
-	response.Success(c, gin.H{"message": "Friend request sent successfully"})
+	response.Created(c, request)
 }
 
 // AcceptFriendRequest accepts a friend request
@@ -292,7 +424,7 @@ func (h *Handler) AcceptFriendRequest(c *gin.Context) {
 
 	err := h.service.AcceptFriendRequest(c.Request.Context(), userID.(string), requestID)
 	if err != nil {
-		response.InternalServerError(c, "Failed to accept friend request")
+		friendRequestError(c, err, "Failed to accept friend request")
 		return
 	}
 
@@ -311,13 +443,29 @@ func (h *Handler) RejectFriendRequest(c *gin.Context) {
 
 	err := h.service.RejectFriendRequest(c.Request.Context(), userID.(string), requestID)
 	if err != nil {
-		response.InternalServerError(c, "Failed to reject friend request")
+		friendRequestError(c, err, "Failed to reject friend request")
 		return
 	}
 
 	response.Success(c, gin.H{"message": "Friend request rejected"})
 }
 
+// friendRequestError responds with the status matching a friend request error
+func friendRequestError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrSelfFriendRequest):
+		response.BadRequest(c, err.Error())
+	case errors.Is(err, ErrUserNotFound):
+		response.NotFound(c, "User not found")
+	case errors.Is(err, ErrFriendRequestNotFound):
+		response.NotFound(c, "Friend request not found")
+	case errors.Is(err, ErrFriendRequestExists), errors.Is(err, ErrAlreadyFriends):
+		response.Conflict(c, err.Error())
+	default:
+		response.InternalServerError(c, fallback)
+	}
+}
+
 // RemoveFriend removes a friend
 func (h *Handler) RemoveFriend(c *gin.Context) {
 	userID, exists := c.Get("userID")
@@ -348,6 +496,12 @@ func (h *Handler) GetFriendRequests(c *gin.Context) {
 	incoming := c.Query("type") != "sent"
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
 
 	requests, total, err := h.service.GetFriendRequests(c.Request.Context(), userID.(string), incoming, limit, offset)
 	if err != nil {
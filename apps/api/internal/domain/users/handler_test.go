@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -73,6 +74,29 @@ func (m *MockService) RefreshToken(ctx context.Context, refreshToken string) (*L
 	return args.Get(0).(*LoginResponse), args.Error(1)
 }
 
+func (m *MockService) Logout(ctx context.Context, refreshToken string) error {
+	args := m.Called(ctx, refreshToken)
+	return args.Error(0)
+}
+
+func (m *MockService) LogoutAll(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockService) ListSessions(ctx context.Context, userID, currentSessionID string) ([]*Session, error) {
+	args := m.Called(ctx, userID, currentSessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*Session), args.Error(1)
+}
+
+func (m *MockService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
 func (m *MockService) ChangePassword(ctx context.Context, userID string, input *ChangePasswordInput) error {
 	args := m.Called(ctx, userID, input)
 	return args.Error(0)
@@ -98,12 +122,12 @@ func (m *MockService) ResendVerificationEmail(ctx context.Context, email string)
 	return args.Error(0)
 }
 
-func (m *MockService) Search(ctx context.Context, query string, limit, offset int) ([]*User, int64, error) {
+func (m *MockService) Search(ctx context.Context, query string, limit, offset int) ([]*PublicProfile, int64, error) {
 	args := m.Called(ctx, query, limit, offset)
 	if args.Get(0) == nil {
 		return nil, args.Get(1).(int64), args.Error(2)
 	}
-	return args.Get(0).([]*User), args.Get(1).(int64), args.Error(2)
+	return args.Get(0).([]*PublicProfile), args.Get(1).(int64), args.Error(2)
 }
 
 func (m *MockService) GetFriends(ctx context.Context, userID string, limit, offset int) ([]*User, int64, error) {
@@ -114,9 +138,12 @@ func (m *MockService) GetFriends(ctx context.Context, userID string, limit, offs
 	return args.Get(0).([]*User), args.Get(1).(int64), args.Error(2)
 }
 
-func (m *MockService) SendFriendRequest(ctx context.Context, fromUserID, toUserID string) error {
+func (m *MockService) SendFriendRequest(ctx context.Context, fromUserID, toUserID string) (*FriendRequest, error) {
 	args := m.Called(ctx, fromUserID, toUserID)
-	return args.Error(0)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*FriendRequest), args.Error(1)
 }
 
 func (m *MockService) AcceptFriendRequest(ctx context.Context, userID, requestID string) error {
@@ -198,7 +225,7 @@ func TestHandler_Register(t *testing.T) {
 				DisplayName: "Test User",
 			},
 			mockSetup: func(ms *MockService) {
-				ms.On("Create", mock.Anything, mock.Anything).Return(nil, errors.New("email already exists"))
+				ms.On("Create", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("failed to create user: %w", ErrEmailTaken))
 			},
 			expectedCode: http.StatusConflict,
 			expectedBody: map[string]interface{}{
@@ -351,7 +378,7 @@ func TestHandler_GetProfile(t *testing.T) {
 			name:   "user not found",
 			userID: "nonexistent",
 			mockSetup: func(ms *MockService) {
-				ms.On("GetByID", mock.Anything, "nonexistent").Return(nil, errors.New("user not found"))
+				ms.On("GetByID", mock.Anything, "nonexistent").Return(nil, fmt.Errorf("failed to get user: %w", ErrUserNotFound))
 			},
 			expectedCode: http.StatusNotFound,
 			expectedBody: map[string]interface{}{
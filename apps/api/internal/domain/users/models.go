@@ -54,6 +54,10 @@ type User struct {
 	PushNotifications       bool           `db:"push_notifications" json:"push_notifications"`
 	SuggestionNotifications bool           `db:"suggestion_notifications" json:"suggestion_notifications"`
 	TripInviteNotifications bool           `db:"trip_invite_notifications" json:"trip_invite_notifications"`
+	Discoverable            bool           `db:"discoverable" json:"discoverable"`
+	AnalyticsOptOut         bool           `db:"analytics_opt_out" json:"analytics_opt_out"`
+	Locale                  string         `db:"locale" json:"locale"` // Language tag exports are formatted for; the browser's when empty
+	Units                   string         `db:"units" json:"units"`   // "metric" or "imperial"; the locale's when empty
 	IsVerified              bool           `db:"is_verified" json:"is_verified"`  // Added for compatibility
 	Profile                 Profile        `json:"profile"`  // Added for profile compatibility
 	CreatedAt               time.Time      `db:"created_at" json:"created_at"`
@@ -81,11 +85,42 @@ type UpdateUserInput struct {
 	PushNotifications       *bool   `json:"push_notifications,omitempty"`
 	SuggestionNotifications *bool   `json:"suggestion_notifications,omitempty"`
 	TripInviteNotifications *bool   `json:"trip_invite_notifications,omitempty"`
+	Discoverable            *bool   `json:"discoverable,omitempty"`
+	AnalyticsOptOut         *bool   `json:"analytics_opt_out,omitempty"`
+	Locale                  *string `json:"locale,omitempty" binding:"omitempty,max=35"`
+	Units                   *string `json:"units,omitempty" binding:"omitempty,oneof=metric imperial"`
+}
+
+// PublicProfile is what other users may see of an account
+type PublicProfile struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	Bio         string `json:"bio"`
+}
+
+// PublicProfile returns the public part of the user's profile
+func (u *User) PublicProfile() *PublicProfile {
+	return &PublicProfile{
+		ID:          u.ID,
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		AvatarURL:   u.AvatarURL,
+		Bio:         u.Bio,
+	}
 }
 
 type LoginInput struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+
+	// DeviceName labels the session, as in "Work laptop"
+	DeviceName string `json:"device_name" binding:"max=200"`
+
+	// Taken from the request rather than the body
+	UserAgent string `json:"-"`
+	IPAddress string `json:"-"`
 }
 
 type LoginResponse struct {
@@ -112,6 +147,10 @@ type ResetPasswordInput struct {
 	NewPassword string `json:"new_password" binding:"required,min=8"`
 }
 
+type SendFriendRequestInput struct {
+	UserID string `json:"user_id" binding:"required"`
+}
+
 type FriendRequest struct {
 	ID          string    `json:"id"`
 	FromUserID  string    `json:"from_user_id"`
@@ -160,6 +199,9 @@ const (
 	PermissionUserRead   Permission = "user.read"
 	PermissionUserUpdate Permission = "user.update"
 	PermissionUserDelete Permission = "user.delete"
+	
+	// System permissions
+	PermissionSystemAdmin Permission = "system.admin"
 )
 
 var RolePermissions = map[Role][]Permission{
@@ -168,6 +210,7 @@ var RolePermissions = map[Role][]Permission{
 		PermissionPlaceCreate, PermissionPlaceRead, PermissionPlaceUpdate, PermissionPlaceDelete, PermissionPlaceMedia,
 		PermissionSuggestionCreate, PermissionSuggestionRead, PermissionSuggestionModerate,
 		PermissionUserRead, PermissionUserUpdate, PermissionUserDelete,
+		PermissionSystemAdmin,
 	},
 	RoleEditor: {
 		PermissionTripCreate, PermissionTripRead, PermissionTripUpdate, PermissionTripShare,
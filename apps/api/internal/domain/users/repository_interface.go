@@ -2,11 +2,28 @@ package users
 
 import (
 	"context"
-	"errors"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/pkg/repoerr"
 )
 
 var (
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserNotFound  = repoerr.NotFound("user not found")
+	ErrEmailTaken    = repoerr.Conflict("email already exists")
+	ErrUsernameTaken = repoerr.Conflict("username already exists")
+	ErrInvalidToken  = repoerr.NotFound("invalid or expired token")
+
+	ErrSessionNotFound     = repoerr.NotFound("session not found")
+	ErrInvalidRefreshToken = repoerr.NotFound("invalid or expired refresh token")
+	ErrRefreshTokenReused  = repoerr.Conflict("refresh token was already used")
+
+	ErrFriendRequestNotFound = repoerr.NotFound("friend request not found")
+	ErrFriendRequestExists   = repoerr.Conflict("friend request already exists")
+	ErrAlreadyFriends        = repoerr.Conflict("already friends")
+
+	// errFriendshipBlocked is returned for requests between users where one
+	// has blocked the other. It is never shown to the sender.
+	errFriendshipBlocked = repoerr.Conflict("friendship blocked")
 )
 
 // Repository defines the interface for user data access
@@ -17,8 +34,29 @@ type Repository interface {
 	GetByUsername(ctx context.Context, username string) (*User, error)
 	Update(ctx context.Context, user *User) error
 	Delete(ctx context.Context, id string) error
-	Search(ctx context.Context, query string) ([]*User, error)
+	Search(ctx context.Context, query string, limit, offset int) ([]*User, int64, error)
 	AddFriend(ctx context.Context, userID, friendID string) error
 	RemoveFriend(ctx context.Context, userID, friendID string) error
 	GetFriends(ctx context.Context, userID string) ([]*User, error)
-}
\ No newline at end of file
+
+	// Friend requests, which become friendships when accepted
+	CreateFriendRequest(ctx context.Context, fromUserID, toUserID string) (*FriendRequest, error)
+	RespondToFriendRequest(ctx context.Context, userID, requestID string, accept bool) (*FriendRequest, error)
+	ListFriendRequests(ctx context.Context, userID string, incoming bool, limit, offset int) ([]*FriendRequest, int64, error)
+
+	// Emailed tokens and what they unlock
+	CreateToken(ctx context.Context, userID, purpose, tokenHash string, expiresAt time.Time) error
+	ConsumeToken(ctx context.Context, purpose, tokenHash string) (string, error)
+	DeleteTokens(ctx context.Context, userID, purpose string) error
+	UpdatePassword(ctx context.Context, userID, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, userID string) error
+
+	// Signed-in devices and their refresh tokens
+	CreateSession(ctx context.Context, session *Session, tokenHash string) error
+	RotateRefreshToken(ctx context.Context, tokenHash, newTokenHash string, expiresAt time.Time) (*Session, error)
+	RevokeSessionByToken(ctx context.Context, tokenHash string) error
+	ListSessions(ctx context.Context, userID string) ([]*Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	RevokeSessions(ctx context.Context, userID string) error
+	SessionActive(ctx context.Context, sessionID string) (bool, error)
+}
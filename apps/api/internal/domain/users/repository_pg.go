@@ -3,9 +3,12 @@ package users
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/Oferzz/newMap/apps/api/pkg/repoerr"
 	"github.com/lib/pq"
 )
 
@@ -31,9 +34,10 @@ func (r *postgresRepository) Create(ctx context.Context, user *User) error {
 			bio, location, roles, profile_visibility, location_sharing,
 			trip_default_privacy, email_notifications, push_notifications,
 			suggestion_notifications, trip_invite_notifications, status,
-			created_at, updated_at, last_active
+			created_at, updated_at, last_active, discoverable, analytics_opt_out,
+			locale, units
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24
 		) RETURNING id, created_at, updated_at`
 
 	fmt.Printf("DEBUG: Executing SQL query with parameters:\n")
@@ -79,6 +83,10 @@ func (r *postgresRepository) Create(ctx context.Context, user *User) error {
 		user.CreatedAt,
 		user.UpdatedAt,
 		user.LastActive,
+		user.Discoverable,
+		user.AnalyticsOptOut,
+		user.Locale,
+		user.Units,
 	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
@@ -95,10 +103,10 @@ func (r *postgresRepository) Create(ctx context.Context, user *User) error {
 			
 			if pqErr.Code == "23505" { // unique_violation
 				if pqErr.Constraint == "users_email_key" {
-					return fmt.Errorf("email already exists")
+					return ErrEmailTaken
 				}
 				if pqErr.Constraint == "users_username_key" {
-					return fmt.Errorf("username already exists")
+					return ErrUsernameTaken
 				}
 			}
 		}
@@ -119,7 +127,8 @@ func (r *postgresRepository) GetByID(ctx context.Context, id string) (*User, err
 			bio, location, roles, profile_visibility, location_sharing,
 			trip_default_privacy, email_notifications, push_notifications,
 			suggestion_notifications, trip_invite_notifications, status,
-			created_at, updated_at, last_active
+			discoverable, analytics_opt_out, locale, units, created_at, updated_at, last_active,
+			email_verified_at IS NOT NULL
 		FROM users
 		WHERE id = $1`
 
@@ -144,17 +153,22 @@ func (r *postgresRepository) GetByID(ctx context.Context, id string) (*User, err
 		&user.SuggestionNotifications,
 		&user.TripInviteNotifications,
 		&user.Status,
+		&user.Discoverable,
+		&user.AnalyticsOptOut,
+		&user.Locale,
+		&user.Units,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.LastActive,
+		&user.IsVerified,
 	)
 	
 	// Assign the scanned roles to the user
 	user.Roles = rolesArray
 	
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("user %s: %w", id, ErrUserNotFound)
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -173,7 +187,8 @@ func (r *postgresRepository) GetByEmail(ctx context.Context, email string) (*Use
 			bio, location, roles, profile_visibility, location_sharing,
 			trip_default_privacy, email_notifications, push_notifications,
 			suggestion_notifications, trip_invite_notifications, status,
-			created_at, updated_at, last_active
+			discoverable, analytics_opt_out, locale, units, created_at, updated_at, last_active,
+			email_verified_at IS NOT NULL
 		FROM users
 		WHERE email = $1`
 
@@ -198,18 +213,23 @@ func (r *postgresRepository) GetByEmail(ctx context.Context, email string) (*Use
 		&user.SuggestionNotifications,
 		&user.TripInviteNotifications,
 		&user.Status,
+		&user.Discoverable,
+		&user.AnalyticsOptOut,
+		&user.Locale,
+		&user.Units,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.LastActive,
+		&user.IsVerified,
 	)
 	
 	// Assign the scanned roles to the user
 	user.Roles = rolesArray
 	
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			fmt.Printf("DEBUG: GetByEmail - No user found with email: %s\n", email)
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		fmt.Printf("DEBUG: GetByEmail - Database error: %v\n", err)
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
@@ -229,7 +249,8 @@ func (r *postgresRepository) GetByUsername(ctx context.Context, username string)
 			bio, location, roles, profile_visibility, location_sharing,
 			trip_default_privacy, email_notifications, push_notifications,
 			suggestion_notifications, trip_invite_notifications, status,
-			created_at, updated_at, last_active
+			discoverable, analytics_opt_out, locale, units, created_at, updated_at, last_active,
+			email_verified_at IS NOT NULL
 		FROM users
 		WHERE username = $1`
 
@@ -254,17 +275,22 @@ func (r *postgresRepository) GetByUsername(ctx context.Context, username string)
 		&user.SuggestionNotifications,
 		&user.TripInviteNotifications,
 		&user.Status,
+		&user.Discoverable,
+		&user.AnalyticsOptOut,
+		&user.Locale,
+		&user.Units,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.LastActive,
+		&user.IsVerified,
 	)
 	
 	// Assign the scanned roles to the user
 	user.Roles = rolesArray
 	
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by username: %w", err)
 	}
@@ -280,7 +306,8 @@ func (r *postgresRepository) Update(ctx context.Context, user *User) error {
 			avatar_url = $6, bio = $7, location = $8, roles = $9,
 			profile_visibility = $10, location_sharing = $11, trip_default_privacy = $12,
 			email_notifications = $13, push_notifications = $14, suggestion_notifications = $15,
-			trip_invite_notifications = $16, status = $17, updated_at = $18, last_active = $19
+			trip_invite_notifications = $16, status = $17, updated_at = $18, last_active = $19,
+			discoverable = $20, analytics_opt_out = $21, locale = $22, units = $23
 		WHERE id = $1`
 
 	user.UpdatedAt = time.Now()
@@ -305,6 +332,10 @@ func (r *postgresRepository) Update(ctx context.Context, user *User) error {
 		user.Status,
 		user.UpdatedAt,
 		user.LastActive,
+		user.Discoverable,
+		user.AnalyticsOptOut,
+		user.Locale,
+		user.Units,
 	)
 
 	if err != nil {
@@ -326,62 +357,53 @@ func (r *postgresRepository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
-// Search searches for users by query
-func (r *postgresRepository) Search(ctx context.Context, query string) ([]*User, error) {
-	var users []*User
-	
+// Search finds active, discoverable users by username or display name.
+// Only the public profile columns are loaded.
+func (r *postgresRepository) Search(ctx context.Context, query string, limit, offset int) ([]*User, int64, error) {
+	searchPattern := "%" + escapeLike(query) + "%"
+	where := `
+		WHERE discoverable AND status = 'active'
+			AND (username ILIKE $1 OR display_name ILIKE $1)`
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`+where, searchPattern).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
 	searchQuery := `
-		SELECT 
-			id, username, email, password_hash, display_name, avatar_url,
-			bio, location, roles, profile_visibility, location_sharing,
-			trip_default_privacy, email_notifications, push_notifications,
-			suggestion_notifications, trip_invite_notifications, status,
-			created_at, updated_at, last_active
-		FROM users
-		WHERE username ILIKE $1 OR email ILIKE $2 OR display_name ILIKE $3`
+		SELECT id, username, COALESCE(display_name, ''), COALESCE(avatar_url, ''), COALESCE(bio, '')
+		FROM users` + where + `
+		ORDER BY username
+		LIMIT $2 OFFSET $3`
 
-	searchPattern := "%" + query + "%"
-	rows, err := r.db.QueryContext(ctx, searchQuery, searchPattern, searchPattern, searchPattern)
+	rows, err := r.db.QueryContext(ctx, searchQuery, searchPattern, limit, offset)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search users: %w", err)
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
 	}
 	defer rows.Close()
 
+	users := []*User{}
 	for rows.Next() {
 		var user User
-		var rolesArray pq.StringArray
-		
 		err := rows.Scan(
 			&user.ID,
 			&user.Username,
-			&user.Email,
-			&user.PasswordHash,
 			&user.DisplayName,
 			&user.AvatarURL,
 			&user.Bio,
-			&user.Location,
-			&rolesArray,
-			&user.ProfileVisibility,
-			&user.LocationSharing,
-			&user.TripDefaultPrivacy,
-			&user.EmailNotifications,
-			&user.PushNotifications,
-			&user.SuggestionNotifications,
-			&user.TripInviteNotifications,
-			&user.Status,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-			&user.LastActive,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan user: %w", err)
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
 		}
-		
-		user.Roles = rolesArray
 		users = append(users, &user)
 	}
 
-	return users, nil
+	return users, total, rows.Err()
+}
+
+// escapeLike makes a search term match literally inside an ILIKE pattern
+func escapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(term)
 }
 
 // AddFriend adds a friend relationship using the user_friends table
@@ -411,6 +433,87 @@ func (r *postgresRepository) RemoveFriend(ctx context.Context, userID, friendID
 	return nil
 }
 
+// CreateToken stores the hash of a token sent to the user, replacing any
+// earlier token of the same purpose
+func (r *postgresRepository) CreateToken(ctx context.Context, userID, purpose, tokenHash string, expiresAt time.Time) error {
+	query := `
+		WITH replaced AS (
+			DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2
+		)
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, purpose, tokenHash, expiresAt); err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+	return nil
+}
+
+// ConsumeToken marks an unused, unexpired token as used and returns the user
+// it was sent to
+func (r *postgresRepository) ConsumeToken(ctx context.Context, purpose, tokenHash string) (string, error) {
+	query := `
+		UPDATE user_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING user_id`
+
+	var userID string
+	err := r.db.QueryRowContext(ctx, query, tokenHash, purpose).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrInvalidToken
+		}
+		return "", fmt.Errorf("failed to consume token: %w", err)
+	}
+	return userID, nil
+}
+
+// DeleteTokens removes the user's tokens of the purpose, used or not
+func (r *postgresRepository) DeleteTokens(ctx context.Context, userID, purpose string) error {
+	query := `DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, purpose); err != nil {
+		return fmt.Errorf("failed to delete tokens: %w", err)
+	}
+	return nil
+}
+
+// UpdatePassword replaces the user's password hash
+func (r *postgresRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	query := `
+		UPDATE users
+		SET password_hash = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, userID, passwordHash)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("user %s: %w", userID, ErrUserNotFound)
+	}
+	return nil
+}
+
+// MarkEmailVerified records that the user's email address was verified. An
+// address verified before keeps its first verification time.
+func (r *postgresRepository) MarkEmailVerified(ctx context.Context, userID string) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+		WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("user %s: %w", userID, ErrUserNotFound)
+	}
+	return nil
+}
+
 // GetFriends retrieves a user's friends using the user_friends table
 func (r *postgresRepository) GetFriends(ctx context.Context, userID string) ([]*User, error) {
 	var users []*User
@@ -467,4 +570,415 @@ func (r *postgresRepository) GetFriends(ctx context.Context, userID string) ([]*
 	}
 
 	return users, nil
-}
\ No newline at end of file
+}
+
+// CreateFriendRequest records a request from one user to another. When the
+// other user had already asked, the two requests make a friendship and the
+// accepted request is returned.
+func (r *postgresRepository) CreateFriendRequest(ctx context.Context, fromUserID, toUserID string) (*FriendRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, user_id, status
+		FROM user_friends
+		WHERE (user_id = $1 AND friend_id = $2) OR (user_id = $2 AND friend_id = $1)
+		FOR UPDATE`, fromUserID, toUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get friendship: %w", err)
+	}
+	var reverseID string
+	for rows.Next() {
+		var id, userID, status string
+		if err := rows.Scan(&id, &userID, &status); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan friendship: %w", err)
+		}
+		switch {
+		case status == "blocked":
+			rows.Close()
+			return nil, errFriendshipBlocked
+		case status == "accepted":
+			rows.Close()
+			return nil, ErrAlreadyFriends
+		case userID == fromUserID:
+			rows.Close()
+			return nil, ErrFriendRequestExists
+		default:
+			reverseID = id
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get friendship: %w", err)
+	}
+
+	var request *FriendRequest
+	if reverseID != "" {
+		request, err = acceptFriendRequest(ctx, tx, reverseID)
+	} else {
+		request = &FriendRequest{FromUserID: fromUserID, ToUserID: toUserID, Status: "pending"}
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO user_friends (user_id, friend_id, status)
+			VALUES ($1, $2, 'pending')
+			RETURNING id, requested_at`, fromUserID, toUserID).Scan(&request.ID, &request.CreatedAt)
+		if err != nil {
+			err = fmt.Errorf("failed to create friend request: %w", repoerr.Classify(err, nil, ErrFriendRequestExists, ErrUserNotFound))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// RespondToFriendRequest accepts or rejects a pending request sent to the
+// user. Rejected requests are removed, so they can be sent again.
+func (r *postgresRepository) RespondToFriendRequest(ctx context.Context, userID, requestID string, accept bool) (*FriendRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	request := &FriendRequest{ID: requestID}
+	err = tx.QueryRowContext(ctx, `
+		SELECT user_id, friend_id, status, requested_at
+		FROM user_friends
+		WHERE id = $1 AND friend_id = $2 AND status = 'pending'
+		FOR UPDATE`, requestID, userID).Scan(&request.FromUserID, &request.ToUserID, &request.Status, &request.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrFriendRequestNotFound
+		}
+		return nil, fmt.Errorf("failed to get friend request: %w", err)
+	}
+
+	if accept {
+		request, err = acceptFriendRequest(ctx, tx, requestID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_friends WHERE id = $1`, requestID); err != nil {
+			return nil, fmt.Errorf("failed to reject friend request: %w", err)
+		}
+		request.Status = "rejected"
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// acceptFriendRequest turns a locked pending request into a friendship,
+// which has a row in each direction
+func acceptFriendRequest(ctx context.Context, tx *sql.Tx, requestID string) (*FriendRequest, error) {
+	request := &FriendRequest{ID: requestID}
+	err := tx.QueryRowContext(ctx, `
+		UPDATE user_friends
+		SET status = 'accepted', responded_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING user_id, friend_id, status, requested_at`, requestID).Scan(&request.FromUserID, &request.ToUserID, &request.Status, &request.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept friend request: %w", err)
+	}
+
+	// Requests sent both ways at the same time leave a pending row behind
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_friends (user_id, friend_id, status, responded_at)
+		VALUES ($1, $2, 'accepted', CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id, friend_id) DO UPDATE
+		SET status = 'accepted', responded_at = CURRENT_TIMESTAMP`, request.ToUserID, request.FromUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add friend: %w", err)
+	}
+
+	return request, nil
+}
+
+// ListFriendRequests returns the pending requests sent to the user, or sent
+// by them, newest first, with the other user's public profile
+func (r *postgresRepository) ListFriendRequests(ctx context.Context, userID string, incoming bool, limit, offset int) ([]*FriendRequest, int64, error) {
+	// The user on the other side of each request
+	mine, other := "uf.friend_id", "uf.user_id"
+	if !incoming {
+		mine, other = other, mine
+	}
+
+	query := `
+		SELECT uf.id, uf.user_id, uf.friend_id, uf.status, uf.requested_at,
+			u.id, u.username, COALESCE(u.display_name, ''), COALESCE(u.avatar_url, ''),
+			COUNT(*) OVER()
+		FROM user_friends uf
+		JOIN users u ON u.id = ` + other + `
+		WHERE ` + mine + ` = $1 AND uf.status = 'pending' AND u.status = 'active'
+		ORDER BY uf.requested_at DESC, uf.id
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get friend requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []*FriendRequest{}
+	var total int64
+	for rows.Next() {
+		var request FriendRequest
+		var user User
+		err := rows.Scan(
+			&request.ID,
+			&request.FromUserID,
+			&request.ToUserID,
+			&request.Status,
+			&request.CreatedAt,
+			&user.ID,
+			&user.Username,
+			&user.DisplayName,
+			&user.AvatarURL,
+			&total,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan friend request: %w", err)
+		}
+		if incoming {
+			request.FromUser = &user
+		} else {
+			request.ToUser = &user
+		}
+		requests = append(requests, &request)
+	}
+
+	return requests, total, rows.Err()
+}
+
+const sessionColumns = `id, user_id, device_name, user_agent, ip_address, created_at, last_used_at, expires_at`
+
+func scanSession(row interface{ Scan(...interface{}) error }) (*Session, error) {
+	var session Session
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.DeviceName,
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+		&session.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// CreateSession records a newly signed-in device with its first refresh
+// token
+func (r *postgresRepository) CreateSession(ctx context.Context, session *Session, tokenHash string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO user_sessions (user_id, device_name, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, last_used_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		session.UserID, session.DeviceName, session.UserAgent, session.IPAddress, session.ExpiresAt,
+	).Scan(&session.ID, &session.CreatedAt, &session.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (token_hash, session_id, expires_at)
+		VALUES ($1, $2, $3)`,
+		tokenHash, session.ID, session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// RotateRefreshToken trades a refresh token for a new one of the same
+// session, extending the session to expiresAt. A token that was already
+// traded ends its session, as only a stolen copy would be used twice; only
+// the last traded token is kept to tell so.
+func (r *postgresRepository) RotateRefreshToken(ctx context.Context, tokenHash, newTokenHash string, expiresAt time.Time) (*Session, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var sessionID string
+	var used, live bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT rt.session_id, rt.used_at IS NOT NULL,
+			rt.expires_at > CURRENT_TIMESTAMP AND s.revoked_at IS NULL
+		FROM refresh_tokens rt
+		JOIN user_sessions s ON s.id = rt.session_id
+		WHERE rt.token_hash = $1
+		FOR UPDATE OF rt, s`, tokenHash).Scan(&sessionID, &used, &live)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if !live {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if used {
+		_, err := tx.ExecContext(ctx, `UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1`, sessionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM refresh_tokens WHERE session_id = $1 AND used_at IS NOT NULL`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete used refresh tokens: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use refresh token: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (token_hash, session_id, expires_at)
+		VALUES ($1, $2, $3)`,
+		newTokenHash, sessionID, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	session, err := scanSession(tx.QueryRowContext(ctx, `
+		UPDATE user_sessions
+		SET last_used_at = CURRENT_TIMESTAMP, expires_at = $2
+		WHERE id = $1
+		RETURNING `+sessionColumns, sessionID, expiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to extend session: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// RevokeSessionByToken ends the session a refresh token belongs to. Unknown
+// tokens are ignored, so signing out twice is not an error.
+func (r *postgresRepository) RevokeSessionByToken(ctx context.Context, tokenHash string) error {
+	query := `
+		UPDATE user_sessions s
+		SET revoked_at = CURRENT_TIMESTAMP
+		FROM refresh_tokens rt
+		WHERE rt.token_hash = $1 AND s.id = rt.session_id AND s.revoked_at IS NULL`
+
+	if _, err := r.db.ExecContext(ctx, query, tokenHash); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// ListSessions retrieves the user's active sessions, most recently used
+// first
+func (r *postgresRepository) ListSessions(ctx context.Context, userID string) ([]*Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY last_used_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// RevokeSession ends one of the user's active sessions
+func (r *postgresRepository) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	query := `
+		UPDATE user_sessions
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeSessions ends all of the user's sessions
+func (r *postgresRepository) RevokeSessions(ctx context.Context, userID string) error {
+	query := `
+		UPDATE user_sessions
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND revoked_at IS NULL`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// SessionActive reports whether the session has neither ended nor expired
+func (r *postgresRepository) SessionActive(ctx context.Context, sessionID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM user_sessions
+			WHERE id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		)`
+
+	var active bool
+	if err := r.db.QueryRowContext(ctx, query, sessionID).Scan(&active); err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+	return active, nil
+}
@@ -3,6 +3,7 @@ package users
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"testing"
 	"time"
 
@@ -13,6 +14,24 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// userColumns are the columns GetByID, GetByEmail and GetByUsername select
+var userColumns = []string{
+	"id", "username", "email", "password_hash", "display_name", "avatar_url",
+	"bio", "location", "roles", "profile_visibility", "location_sharing",
+	"trip_default_privacy", "email_notifications", "push_notifications",
+	"suggestion_notifications", "trip_invite_notifications", "status",
+	"discoverable", "analytics_opt_out", "locale", "units", "created_at", "updated_at", "last_active", "is_verified",
+}
+
+func userRow(id, username, email string, now time.Time) []driver.Value {
+	return []driver.Value{
+		id, username, email, "hashedpassword", "Test User", "avatar.jpg",
+		"Test bio", "New York", "{user}", "public", false,
+		"private", true, true, true, true, "active",
+		true, false, "", "", now, now, now, true,
+	}
+}
+
 func TestPostgreSQLRepository_Create(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -23,15 +42,16 @@ func TestPostgreSQLRepository_Create(t *testing.T) {
 
 	t.Run("successful creation", func(t *testing.T) {
 		user := &User{
-			ID:        uuid.New().String(),
-			Username:  "testuser",
-			Email:     "test@example.com",
-			PasswordHash:  "hashedpassword",
-			Profile:   Profile{Name: "Test User", Bio: "Test bio"},
-			Role:      "user",
-			Roles:     pq.StringArray{"user"},
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+			ID:           uuid.New().String(),
+			Username:     "testuser",
+			Email:        "test@example.com",
+			PasswordHash: "hashedpassword",
+			DisplayName:  "Test User",
+			Roles:        pq.StringArray{"user"},
+			Status:       "active",
+			Discoverable: true,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
 		}
 
 		rows := sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
@@ -59,6 +79,10 @@ func TestPostgreSQLRepository_Create(t *testing.T) {
 				user.CreatedAt,
 				user.UpdatedAt,
 				user.LastActive,
+				user.Discoverable,
+				user.AnalyticsOptOut,
+				user.Locale,
+				user.Units,
 			).
 			WillReturnRows(rows)
 
@@ -67,21 +91,34 @@ func TestPostgreSQLRepository_Create(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("duplicate email", func(t *testing.T) {
+		user := &User{
+			ID:       uuid.New().String(),
+			Username: "testuser",
+			Email:    "test@example.com",
+		}
+
+		mock.ExpectQuery(`INSERT INTO users`).
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "users_email_key"})
+
+		err := repo.Create(ctx, user)
+		assert.ErrorIs(t, err, ErrEmailTaken)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("database error", func(t *testing.T) {
 		user := &User{
-			ID:        uuid.New().String(),
-			Username:  "testuser",
-			Email:     "test@example.com",
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+			ID:       uuid.New().String(),
+			Username: "testuser",
+			Email:    "test@example.com",
 		}
 
 		mock.ExpectQuery(`INSERT INTO users`).
-			WithArgs(sqlmock.AnyArg()).
 			WillReturnError(sql.ErrConnDone)
 
 		err := repo.Create(ctx, user)
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
@@ -95,43 +132,31 @@ func TestPostgreSQLRepository_GetByID(t *testing.T) {
 
 	t.Run("user found", func(t *testing.T) {
 		userID := uuid.New().String()
-		now := time.Now()
 
-		rows := sqlmock.NewRows([]string{
-			"id", "username", "email", "password_hash", "display_name", "avatar_url",
-			"bio", "location", "roles", "profile_visibility", "location_sharing",
-			"trip_default_privacy", "email_notifications", "push_notifications",
-			"suggestion_notifications", "trip_invite_notifications", "status",
-			"created_at", "updated_at", "last_active",
-		}).AddRow(
-			userID, "testuser", "test@example.com", "hashedpassword", "Test User", "avatar.jpg",
-			"Test bio", "New York", "{user}", "public", false,
-			"private", true, true, true, true, "active",
-			now, now, now,
-		)
-
-		mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT (.+) FROM users\s+WHERE id = \$1`).
 			WithArgs(userID).
-			WillReturnRows(rows)
+			WillReturnRows(sqlmock.NewRows(userColumns).AddRow(userRow(userID, "testuser", "test@example.com", time.Now())...))
 
 		user, err := repo.GetByID(ctx, userID)
-		assert.NoError(t, err)
-		assert.NotNil(t, user)
+		require.NoError(t, err)
 		assert.Equal(t, userID, user.ID)
 		assert.Equal(t, "testuser", user.Username)
+		assert.Equal(t, pq.StringArray{"user"}, user.Roles)
+		assert.True(t, user.Discoverable)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
 	t.Run("user not found", func(t *testing.T) {
 		userID := uuid.New().String()
 
-		mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT (.+) FROM users\s+WHERE id = \$1`).
 			WithArgs(userID).
 			WillReturnError(sql.ErrNoRows)
 
 		user, err := repo.GetByID(ctx, userID)
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrUserNotFound)
 		assert.Nil(t, user)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
@@ -145,27 +170,26 @@ func TestPostgreSQLRepository_GetByEmail(t *testing.T) {
 
 	t.Run("user found", func(t *testing.T) {
 		email := "test@example.com"
-		userID := uuid.New().String()
-		now := time.Now()
 
-		rows := sqlmock.NewRows([]string{
-			"id", "username", "email", "password", "profile_name",
-			"profile_bio", "profile_avatar", "profile_location",
-			"profile_website", "role", "friends", "created_at", "updated_at",
-		}).AddRow(
-			userID, "testuser", email, "hashedpassword",
-			"Test User", "Test bio", "avatar.jpg", "New York",
-			"https://example.com", "user", "{}", now, now,
-		)
-
-		mock.ExpectQuery(`SELECT (.+) FROM users WHERE email = \$1`).
+		mock.ExpectQuery(`SELECT (.+) FROM users\s+WHERE email = \$1`).
 			WithArgs(email).
-			WillReturnRows(rows)
+			WillReturnRows(sqlmock.NewRows(userColumns).AddRow(userRow(uuid.New().String(), "testuser", email, time.Now())...))
 
 		user, err := repo.GetByEmail(ctx, email)
-		assert.NoError(t, err)
-		assert.NotNil(t, user)
+		require.NoError(t, err)
 		assert.Equal(t, email, user.Email)
+		assert.Equal(t, "hashedpassword", user.PasswordHash)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM users\s+WHERE email = \$1`).
+			WithArgs("missing@example.com").
+			WillReturnError(sql.ErrNoRows)
+
+		user, err := repo.GetByEmail(ctx, "missing@example.com")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		assert.Nil(t, user)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
@@ -180,26 +204,13 @@ func TestPostgreSQLRepository_GetByUsername(t *testing.T) {
 
 	t.Run("user found", func(t *testing.T) {
 		username := "testuser"
-		userID := uuid.New().String()
-		now := time.Now()
 
-		rows := sqlmock.NewRows([]string{
-			"id", "username", "email", "password", "profile_name",
-			"profile_bio", "profile_avatar", "profile_location",
-			"profile_website", "role", "friends", "created_at", "updated_at",
-		}).AddRow(
-			userID, username, "test@example.com", "hashedpassword",
-			"Test User", "Test bio", "avatar.jpg", "New York",
-			"https://example.com", "user", "{}", now, now,
-		)
-
-		mock.ExpectQuery(`SELECT (.+) FROM users WHERE username = \$1`).
+		mock.ExpectQuery(`SELECT (.+) FROM users\s+WHERE username = \$1`).
 			WithArgs(username).
-			WillReturnRows(rows)
+			WillReturnRows(sqlmock.NewRows(userColumns).AddRow(userRow(uuid.New().String(), username, "test@example.com", time.Now())...))
 
 		user, err := repo.GetByUsername(ctx, username)
-		assert.NoError(t, err)
-		assert.NotNil(t, user)
+		require.NoError(t, err)
 		assert.Equal(t, username, user.Username)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -215,35 +226,47 @@ func TestPostgreSQLRepository_Update(t *testing.T) {
 
 	t.Run("successful update", func(t *testing.T) {
 		user := &User{
-			ID:        uuid.New().String(),
-			Username:  "updateduser",
-			Email:     "updated@example.com",
-			PasswordHash:  "newhashedpassword",
-			Profile:   Profile{Name: "Updated User", Bio: "Updated bio"},
-			Role:      "user",
-			Roles:     pq.StringArray{"user"},
-			UpdatedAt: time.Now(),
+			ID:           uuid.New().String(),
+			Username:     "updateduser",
+			Email:        "updated@example.com",
+			PasswordHash: "newhashedpassword",
+			DisplayName:  "Updated User",
+			Bio:          "Updated bio",
+			Roles:        pq.StringArray{"user"},
+			Status:       "active",
 		}
 
-		mock.ExpectExec(`UPDATE users SET`).
+		mock.ExpectExec(`UPDATE users\s+SET`).
 			WithArgs(
+				user.ID,
 				user.Username,
 				user.Email,
-				user.Password,
-				user.Profile.Name,
-				user.Profile.Bio,
-				user.Profile.Avatar,
-				user.Profile.Location,
-				user.Profile.Website,
-				user.Role,
-				sqlmock.AnyArg(), // friends array
+				user.PasswordHash,
+				user.DisplayName,
+				user.AvatarURL,
+				user.Bio,
+				user.Location,
+				sqlmock.AnyArg(), // roles array
+				user.ProfileVisibility,
+				user.LocationSharing,
+				user.TripDefaultPrivacy,
+				user.EmailNotifications,
+				user.PushNotifications,
+				user.SuggestionNotifications,
+				user.TripInviteNotifications,
+				user.Status,
 				sqlmock.AnyArg(), // updated_at
-				user.ID,
+				user.LastActive,
+				user.Discoverable,
+				user.AnalyticsOptOut,
+				user.Locale,
+				user.Units,
 			).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
 		err := repo.Update(ctx, user)
 		assert.NoError(t, err)
+		assert.False(t, user.UpdatedAt.IsZero())
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
@@ -278,30 +301,25 @@ func TestPostgreSQLRepository_Search(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("search with results", func(t *testing.T) {
-		query := "test"
-		now := time.Now()
+		// Wildcards in the query match literally
+		pattern := `%test\_1%`
 
-		rows := sqlmock.NewRows([]string{
-			"id", "username", "email", "password", "profile_name",
-			"profile_bio", "profile_avatar", "profile_location",
-			"profile_website", "role", "friends", "created_at", "updated_at",
-		}).
-			AddRow(
-				"1", "testuser1", "test1@example.com", "hash1",
-				"Test User 1", "Bio 1", "", "", "", "user", "{}", now, now,
-			).
-			AddRow(
-				"2", "testuser2", "test2@example.com", "hash2",
-				"Test User 2", "Bio 2", "", "", "", "user", "{}", now, now,
-			)
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users\s+WHERE discoverable`).
+			WithArgs(pattern).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+		rows := sqlmock.NewRows([]string{"id", "username", "display_name", "avatar_url", "bio"}).
+			AddRow("1", "test_1", "Test User 1", "", "Bio 1")
 
-		mock.ExpectQuery(`SELECT (.+) FROM users WHERE`).
-			WithArgs("%"+query+"%", "%"+query+"%", "%"+query+"%").
+		mock.ExpectQuery(`SELECT (.+) FROM users\s+WHERE discoverable`).
+			WithArgs(pattern, 20, 0).
 			WillReturnRows(rows)
 
-		users, err := repo.Search(ctx, query)
+		users, total, err := repo.Search(ctx, "test_1", 20, 0)
 		assert.NoError(t, err)
-		assert.Len(t, users, 2)
+		assert.Equal(t, int64(1), total)
+		assert.Len(t, users, 1)
+		assert.Empty(t, users[0].Email)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
@@ -318,8 +336,8 @@ func TestPostgreSQLRepository_AddFriend(t *testing.T) {
 		userID := uuid.New().String()
 		friendID := uuid.New().String()
 
-		mock.ExpectExec(`UPDATE users SET friends = array_append`).
-			WithArgs(friendID, sqlmock.AnyArg(), userID).
+		mock.ExpectExec(`INSERT INTO user_friends`).
+			WithArgs(userID, friendID).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
 		err := repo.AddFriend(ctx, userID, friendID)
@@ -340,8 +358,8 @@ func TestPostgreSQLRepository_RemoveFriend(t *testing.T) {
 		userID := uuid.New().String()
 		friendID := uuid.New().String()
 
-		mock.ExpectExec(`UPDATE users SET friends = array_remove`).
-			WithArgs(friendID, sqlmock.AnyArg(), userID).
+		mock.ExpectExec(`DELETE FROM user_friends WHERE user_id = \$1 AND friend_id = \$2`).
+			WithArgs(userID, friendID).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
 		err := repo.RemoveFriend(ctx, userID, friendID)
@@ -362,36 +380,255 @@ func TestPostgreSQLRepository_GetFriends(t *testing.T) {
 		userID := uuid.New().String()
 		now := time.Now()
 
-		// First query to get friend IDs
-		friendRows := sqlmock.NewRows([]string{"friends"}).
-			AddRow("{friend1,friend2}")
+		// GetFriends selects the same columns as GetByID but discoverable,
+		// analytics_opt_out, locale, units and is_verified
+		columns := append(append([]string{}, userColumns[:17]...), userColumns[21:24]...)
+		row := func(id, username string) []driver.Value {
+			values := userRow(id, username, username+"@example.com", now)
+			return append(append([]driver.Value{}, values[:17]...), values[21:24]...)
+		}
 
-		mock.ExpectQuery(`SELECT friends FROM users WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT (.+) FROM users u\s+INNER JOIN user_friends uf`).
 			WithArgs(userID).
-			WillReturnRows(friendRows)
-
-		// Second query to get friend details
-		rows := sqlmock.NewRows([]string{
-			"id", "username", "email", "password", "profile_name",
-			"profile_bio", "profile_avatar", "profile_location",
-			"profile_website", "role", "friends", "created_at", "updated_at",
-		}).
-			AddRow(
-				"friend1", "friend1user", "friend1@example.com", "hash1",
-				"Friend 1", "Bio 1", "", "", "", "user", "{}", now, now,
-			).
-			AddRow(
-				"friend2", "friend2user", "friend2@example.com", "hash2",
-				"Friend 2", "Bio 2", "", "", "", "user", "{}", now, now,
-			)
-
-		mock.ExpectQuery(`SELECT (.+) FROM users WHERE id = ANY`).
-			WithArgs(sqlmock.AnyArg()).
-			WillReturnRows(rows)
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(row("friend1", "friend1user")...).
+				AddRow(row("friend2", "friend2user")...))
 
 		friends, err := repo.GetFriends(ctx, userID)
-		assert.NoError(t, err)
-		assert.Len(t, friends, 2)
+		require.NoError(t, err)
+		require.Len(t, friends, 2)
+		assert.Equal(t, "friend2user", friends[1].Username)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
-}
\ No newline at end of file
+}
+
+func TestPostgreSQLRepository_ConsumeToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &postgresRepository{db: db}
+	ctx := context.Background()
+
+	t.Run("valid token", func(t *testing.T) {
+		userID := uuid.New().String()
+
+		mock.ExpectQuery(`UPDATE user_tokens\s+SET used_at = CURRENT_TIMESTAMP\s+WHERE token_hash = \$1 AND purpose = \$2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP`).
+			WithArgs("hash", TokenPasswordReset).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(userID))
+
+		got, err := repo.ConsumeToken(ctx, TokenPasswordReset, "hash")
+		require.NoError(t, err)
+		assert.Equal(t, userID, got)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("used, expired or unknown token", func(t *testing.T) {
+		mock.ExpectQuery(`UPDATE user_tokens`).
+			WithArgs("hash", TokenEmailVerification).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.ConsumeToken(ctx, TokenEmailVerification, "hash")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgreSQLRepository_CreateToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &postgresRepository{db: db}
+	userID := uuid.New().String()
+	expiresAt := time.Now().Add(time.Hour)
+
+	// Earlier tokens of the purpose stop working when a new one is sent
+	mock.ExpectExec(`DELETE FROM user_tokens WHERE user_id = \$1 AND purpose = \$2\s+\)\s+INSERT INTO user_tokens`).
+		WithArgs(userID, TokenPasswordReset, "hash", expiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = repo.CreateToken(context.Background(), userID, TokenPasswordReset, "hash", expiresAt)
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgreSQLRepository_RotateRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &postgresRepository{db: db}
+	ctx := context.Background()
+	sessionID := uuid.New().String()
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	t.Run("rotates a live token", func(t *testing.T) {
+		now := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM refresh_tokens rt\s+JOIN user_sessions s ON s.id = rt.session_id\s+WHERE rt.token_hash = \$1\s+FOR UPDATE`).
+			WithArgs("old").
+			WillReturnRows(sqlmock.NewRows([]string{"session_id", "used", "live"}).AddRow(sessionID, false, true))
+		mock.ExpectExec(`DELETE FROM refresh_tokens WHERE session_id = \$1 AND used_at IS NOT NULL`).
+			WithArgs(sessionID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE token_hash = \$1`).
+			WithArgs("old").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO refresh_tokens`).
+			WithArgs("new", sessionID, expiresAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`UPDATE user_sessions\s+SET last_used_at = CURRENT_TIMESTAMP, expires_at = \$2`).
+			WithArgs(sessionID, expiresAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "device_name", "user_agent", "ip_address", "created_at", "last_used_at", "expires_at"}).
+				AddRow(sessionID, "user-1", "Work laptop", "", "", now, now, expiresAt))
+		mock.ExpectCommit()
+
+		session, err := repo.RotateRefreshToken(ctx, "old", "new", expiresAt)
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", session.UserID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a used token ends its session", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM refresh_tokens rt`).
+			WithArgs("old").
+			WillReturnRows(sqlmock.NewRows([]string{"session_id", "used", "live"}).AddRow(sessionID, true, true))
+		mock.ExpectExec(`UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = \$1`).
+			WithArgs(sessionID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		_, err := repo.RotateRefreshToken(ctx, "old", "new", expiresAt)
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("expired token or ended session", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM refresh_tokens rt`).
+			WithArgs("old").
+			WillReturnRows(sqlmock.NewRows([]string{"session_id", "used", "live"}).AddRow(sessionID, false, false))
+		mock.ExpectRollback()
+
+		_, err := repo.RotateRefreshToken(ctx, "old", "new", expiresAt)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgreSQLRepository_CreateFriendRequest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &postgresRepository{db: db}
+	ctx := context.Background()
+	senderID := uuid.New().String()
+	recipientID := uuid.New().String()
+	requestID := uuid.New().String()
+	friendshipColumns := []string{"id", "user_id", "status"}
+
+	t.Run("new request", func(t *testing.T) {
+		now := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id, user_id, status\s+FROM user_friends\s+WHERE (.+) FOR UPDATE`).
+			WithArgs(senderID, recipientID).
+			WillReturnRows(sqlmock.NewRows(friendshipColumns))
+		mock.ExpectQuery(`INSERT INTO user_friends \(user_id, friend_id, status\)\s+VALUES \(\$1, \$2, 'pending'\)`).
+			WithArgs(senderID, recipientID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "requested_at"}).AddRow(requestID, now))
+		mock.ExpectCommit()
+
+		request, err := repo.CreateFriendRequest(ctx, senderID, recipientID)
+		require.NoError(t, err)
+		assert.Equal(t, requestID, request.ID)
+		assert.Equal(t, "pending", request.Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("asking back accepts the other request", func(t *testing.T) {
+		now := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM user_friends`).
+			WithArgs(senderID, recipientID).
+			WillReturnRows(sqlmock.NewRows(friendshipColumns).AddRow(requestID, recipientID, "pending"))
+		mock.ExpectQuery(`UPDATE user_friends\s+SET status = 'accepted'`).
+			WithArgs(requestID).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "friend_id", "status", "requested_at"}).
+				AddRow(recipientID, senderID, "accepted", now))
+		mock.ExpectExec(`INSERT INTO user_friends (.+) ON CONFLICT \(user_id, friend_id\) DO UPDATE`).
+			WithArgs(senderID, recipientID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		request, err := repo.CreateFriendRequest(ctx, senderID, recipientID)
+		require.NoError(t, err)
+		assert.Equal(t, "accepted", request.Status)
+		assert.Equal(t, recipientID, request.FromUserID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	for status, want := range map[string]error{
+		"pending":  ErrFriendRequestExists,
+		"accepted": ErrAlreadyFriends,
+		"blocked":  errFriendshipBlocked,
+	} {
+		t.Run("existing "+status, func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(`FROM user_friends`).
+				WithArgs(senderID, recipientID).
+				WillReturnRows(sqlmock.NewRows(friendshipColumns).AddRow(requestID, senderID, status))
+			mock.ExpectRollback()
+
+			_, err := repo.CreateFriendRequest(ctx, senderID, recipientID)
+			assert.ErrorIs(t, err, want)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgreSQLRepository_RespondToFriendRequest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := &postgresRepository{db: db}
+	ctx := context.Background()
+	senderID := uuid.New().String()
+	recipientID := uuid.New().String()
+	requestID := uuid.New().String()
+	requestColumns := []string{"user_id", "friend_id", "status", "requested_at"}
+
+	t.Run("reject removes the request", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM user_friends\s+WHERE id = \$1 AND friend_id = \$2 AND status = 'pending'\s+FOR UPDATE`).
+			WithArgs(requestID, recipientID).
+			WillReturnRows(sqlmock.NewRows(requestColumns).AddRow(senderID, recipientID, "pending", time.Now()))
+		mock.ExpectExec(`DELETE FROM user_friends WHERE id = \$1`).
+			WithArgs(requestID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		request, err := repo.RespondToFriendRequest(ctx, recipientID, requestID, false)
+		require.NoError(t, err)
+		assert.Equal(t, "rejected", request.Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("only the recipient can respond", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM user_friends`).
+			WithArgs(requestID, senderID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := repo.RespondToFriendRequest(ctx, senderID, requestID, true)
+		assert.ErrorIs(t, err, ErrFriendRequestNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,71 @@
+package users
+
+import "github.com/gin-gonic/gin"
+
+// RouteMiddleware is the auth and permission middleware handlers put in
+// front of the routes they register. It is built by the middleware package,
+// which the domain packages cannot import since it imports them.
+type RouteMiddleware struct {
+	// Version is the API version the routes are mounted under. v1 is
+	// frozen, so new routes may be registered for later versions only.
+	Version string
+
+	OptionalAuth       gin.HandlerFunc
+	OptionalStreamAuth gin.HandlerFunc
+	RequireAuth        gin.HandlerFunc
+	RequireAuthOrShare gin.HandlerFunc
+
+	// RequireAdmin signs in the user and requires PermissionSystemAdmin
+	RequireAdmin []gin.HandlerFunc
+
+	RequireSystemPermission func(permission Permission) gin.HandlerFunc
+	RequireTripPermission   func(permission Permission) gin.HandlerFunc
+	RequireTripOwnership    gin.HandlerFunc
+	RequirePlacePermission  func(permission Permission) gin.HandlerFunc
+	RequireShareLink        func(permission Permission) gin.HandlerFunc
+
+	// RequireTripPermissionOrShare also lets share-link guests whose grant
+	// covers the permission through, for handlers that act on the grant
+	RequireTripPermissionOrShare func(permission Permission) gin.HandlerFunc
+
+	// Bodies are limited to small JSON documents unless a route raises the
+	// limit for the GeoJSON or uploads it takes
+	LimitGeoJSONBody gin.HandlerFunc
+	LimitUploadBody  gin.HandlerFunc
+}
+
+// RouteRegistrar is a handler that registers its own routes, with the
+// middleware they need, on the API group of each version
+type RouteRegistrar interface {
+	RegisterRoutes(router *gin.RouterGroup, mw *RouteMiddleware)
+}
+
+// RegisterRoutes registers the auth and profile routes
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *RouteMiddleware) {
+	auth := router.Group("/auth")
+	{
+		auth.POST("/register", h.Register)
+		auth.POST("/login", h.Login)
+		auth.POST("/refresh", h.RefreshToken)
+		auth.POST("/logout", h.Logout)
+		auth.POST("/logout-all", mw.RequireAuth, h.LogoutAll)
+		auth.GET("/sessions", mw.RequireAuth, h.ListSessions)
+		auth.DELETE("/sessions/:id", mw.RequireAuth, h.RevokeSession)
+		auth.POST("/password/forgot", h.SendPasswordReset)
+		auth.POST("/password/reset", h.ResetPassword)
+		auth.POST("/verify-email", h.VerifyEmail)
+		auth.POST("/verify-email/resend", h.ResendVerification)
+	}
+
+	me := router.Group("/users/me", mw.RequireAuth)
+	{
+		me.GET("", h.GetProfile)
+		me.PUT("", h.UpdateProfile)
+		me.PUT("/password", h.ChangePassword)
+		me.GET("/friend-requests", h.GetFriendRequests)
+		me.POST("/friend-requests", h.SendFriendRequest)
+		me.POST("/friend-requests/:id/accept", h.AcceptFriendRequest)
+		me.POST("/friend-requests/:id/reject", h.RejectFriendRequest)
+		// me.DELETE("", h.DeleteAccount) // TODO: Implement DeleteAccount
+	}
+}
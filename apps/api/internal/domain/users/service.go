@@ -16,6 +16,10 @@ type Service interface {
 	// Authentication operations
 	Login(ctx context.Context, input *LoginInput) (*LoginResponse, error)
 	RefreshToken(ctx context.Context, refreshToken string) (*LoginResponse, error)
+	Logout(ctx context.Context, refreshToken string) error
+	LogoutAll(ctx context.Context, userID string) error
+	ListSessions(ctx context.Context, userID, currentSessionID string) ([]*Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
 	ChangePassword(ctx context.Context, userID string, input *ChangePasswordInput) error
 	ResetPassword(ctx context.Context, input *ResetPasswordInput) error
 	SendPasswordResetEmail(ctx context.Context, email string) error
@@ -23,9 +27,9 @@ type Service interface {
 	ResendVerificationEmail(ctx context.Context, email string) error
 
 	// Search and social operations
-	Search(ctx context.Context, query string, limit, offset int) ([]*User, int64, error)
+	Search(ctx context.Context, query string, limit, offset int) ([]*PublicProfile, int64, error)
 	GetFriends(ctx context.Context, userID string, limit, offset int) ([]*User, int64, error)
-	SendFriendRequest(ctx context.Context, fromUserID, toUserID string) error
+	SendFriendRequest(ctx context.Context, fromUserID, toUserID string) (*FriendRequest, error)
 	AcceptFriendRequest(ctx context.Context, userID, requestID string) error
 	RejectFriendRequest(ctx context.Context, userID, requestID string) error
 	RemoveFriend(ctx context.Context, userID, friendID string) error
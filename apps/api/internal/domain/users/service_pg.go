@@ -4,41 +4,71 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/internal/email"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/internal/locale"
 	"github.com/Oferzz/newMap/apps/api/internal/utils"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
 
+// ErrInvalidPassword is returned when the current password given to change
+// it is wrong
+var ErrInvalidPassword = errors.New("invalid current password")
+
+// ErrInvalidLocale is returned for a preferred locale that is not a
+// language tag
+var ErrInvalidLocale = errors.New("locale must be a language tag such as en-US")
+
 // postgresService implements the service layer for PostgreSQL
 type postgresService struct {
 	repo       Repository
 	jwtManager *utils.JWTManager
+	mailer     email.Sender
+	bus        events.Bus
+	appName    string
+	webURL     string
 }
 
-// NewPostgreSQLService creates a new PostgreSQL service
+// NewPostgreSQLService creates a new PostgreSQL service. Until an email
+// sender is set, emails are logged rather than sent.
 func NewPostgreSQLService(repo Repository, cfg *config.Config) *postgresService {
 	return &postgresService{
 		repo:       repo,
 		jwtManager: utils.NewJWTManager(&cfg.JWT),
+		mailer:     email.NoOpSender{},
+		appName:    cfg.App.Name,
+		webURL:     cfg.App.WebURL,
 	}
 }
 
+// SetEmailSender sets how password reset and verification emails are sent
+func (s *postgresService) SetEmailSender(sender email.Sender) {
+	s.mailer = sender
+}
+
+// SetEventBus sets where notifications about friend requests are published
+func (s *postgresService) SetEventBus(bus events.Bus) {
+	s.bus = bus
+}
+
 
 // Register creates a new user account
 func (s *postgresService) Register(ctx context.Context, username, email, password string) (*User, error) {
 	// Check if email already exists
 	existingUser, err := s.repo.GetByEmail(ctx, email)
 	if err == nil && existingUser != nil {
-		return nil, errors.New("email already exists")
+		return nil, ErrEmailTaken
 	}
 
 	// Check if username already exists
 	existingUser, err = s.repo.GetByUsername(ctx, username)
 	if err == nil && existingUser != nil {
-		return nil, errors.New("username already exists")
+		return nil, ErrUsernameTaken
 	}
 
 	// Hash password
@@ -176,20 +206,6 @@ func (s *postgresService) RemoveFriend(ctx context.Context, userID, friendID str
 	return nil
 }
 
-// SearchUsers searches for users by query
-func (s *postgresService) SearchUsers(ctx context.Context, query string) ([]*User, error) {
-	if query == "" {
-		return nil, errors.New("search query cannot be empty")
-	}
-
-	users, err := s.repo.Search(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search users: %w", err)
-	}
-
-	return users, nil
-}
-
 // GetFriends returns a user's friends
 func (s *postgresService) GetFriendsOriginal(ctx context.Context, userID string) ([]*User, error) {
 	friends, err := s.repo.GetFriends(ctx, userID)
@@ -222,7 +238,7 @@ func (s *postgresService) Create(ctx context.Context, input *CreateUserInput) (*
 	}
 	if existingUser != nil {
 		fmt.Printf("DEBUG: Email already exists for user: %+v\n", existingUser)
-		return nil, errors.New("email already exists")
+		return nil, ErrEmailTaken
 	}
 
 	// Check if username already exists
@@ -232,7 +248,7 @@ func (s *postgresService) Create(ctx context.Context, input *CreateUserInput) (*
 	}
 	if existingUser != nil {
 		fmt.Printf("DEBUG: Username already exists for user: %+v\n", existingUser)
-		return nil, errors.New("username already exists")
+		return nil, ErrUsernameTaken
 	}
 
 	// Hash password
@@ -259,6 +275,7 @@ func (s *postgresService) Create(ctx context.Context, input *CreateUserInput) (*
 		PushNotifications:       true,
 		SuggestionNotifications: true,
 		TripInviteNotifications: true,
+		Discoverable:            true,
 		IsVerified:              false,
 		Status:                  "active",
 		CreatedAt:               time.Now(),
@@ -275,6 +292,13 @@ func (s *postgresService) Create(ctx context.Context, input *CreateUserInput) (*
 	}
 
 	fmt.Printf("DEBUG: Repository Create succeeded\n")
+
+	// The account works without a verified address, so a failed email is
+	// only logged; the user can ask for another
+	if err := s.sendVerification(ctx, user); err != nil {
+		fmt.Printf("Failed to send verification email to user %s: %v\n", user.ID, err)
+	}
+
 	return user, nil
 }
 
@@ -284,8 +308,65 @@ func (s *postgresService) GetByEmail(ctx context.Context, email string) (*User,
 }
 
 func (s *postgresService) Update(ctx context.Context, id string, input *UpdateUserInput) (*User, error) {
-	// TODO: Implement
-	return nil, errors.New("not implemented")
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.DisplayName != nil {
+		user.DisplayName = *input.DisplayName
+	}
+	if input.Bio != nil {
+		user.Bio = *input.Bio
+	}
+	if input.AvatarURL != nil {
+		user.AvatarURL = *input.AvatarURL
+	}
+	if input.Location != nil {
+		user.Location = *input.Location
+	}
+	if input.ProfileVisibility != nil {
+		user.ProfileVisibility = *input.ProfileVisibility
+	}
+	if input.LocationSharing != nil {
+		user.LocationSharing = *input.LocationSharing
+	}
+	if input.TripDefaultPrivacy != nil {
+		user.TripDefaultPrivacy = *input.TripDefaultPrivacy
+	}
+	if input.EmailNotifications != nil {
+		user.EmailNotifications = *input.EmailNotifications
+	}
+	if input.PushNotifications != nil {
+		user.PushNotifications = *input.PushNotifications
+	}
+	if input.SuggestionNotifications != nil {
+		user.SuggestionNotifications = *input.SuggestionNotifications
+	}
+	if input.TripInviteNotifications != nil {
+		user.TripInviteNotifications = *input.TripInviteNotifications
+	}
+	if input.Discoverable != nil {
+		user.Discoverable = *input.Discoverable
+	}
+	if input.AnalyticsOptOut != nil {
+		user.AnalyticsOptOut = *input.AnalyticsOptOut
+	}
+	if input.Locale != nil {
+		if *input.Locale != "" && !locale.ValidTag(*input.Locale) {
+			return nil, ErrInvalidLocale
+		}
+		user.Locale = *input.Locale
+	}
+	if input.Units != nil {
+		user.Units = *input.Units
+	}
+
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
 }
 
 func (s *postgresService) Delete(ctx context.Context, id string) error {
@@ -317,56 +398,116 @@ func (s *postgresService) Login(ctx context.Context, input *LoginInput) (*LoginR
 
 	fmt.Printf("DEBUG: Login - Password check succeeded, generating tokens\n")
 
-	// Generate tokens
-	accessToken, refreshToken, err := s.jwtManager.GenerateTokenPair(user.ID, user.Email)
+	// Each login is a session of its own, so devices sign out separately
+	return s.startSession(ctx, user, input)
+}
+
+// ChangePassword sets a new password for a user who knows the current one.
+// Reset links sent before stop working.
+func (s *postgresService) ChangePassword(ctx context.Context, userID string, input *ChangePasswordInput) error {
+	user, err := s.repo.GetByID(ctx, userID)
 	if err != nil {
-		fmt.Printf("DEBUG: Login - Failed to generate tokens: %v\n", err)
-		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+		return err
+	}
+	if !utils.CheckPassword(input.CurrentPassword, user.PasswordHash) {
+		return ErrInvalidPassword
 	}
 
-	fmt.Printf("DEBUG: Login - Tokens generated successfully\n")
-
-	return &LoginResponse{
-		User:         user,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    900, // 15 minutes
-	}, nil
+	return s.setPassword(ctx, userID, input.NewPassword)
 }
 
-func (s *postgresService) RefreshToken(ctx context.Context, refreshToken string) (*LoginResponse, error) {
-	// TODO: Implement
-	return nil, errors.New("not implemented")
-}
+// ResetPassword sets a new password with a token from a reset email. Getting
+// the email shows the address is the user's, so it counts as verified too.
+func (s *postgresService) ResetPassword(ctx context.Context, input *ResetPasswordInput) error {
+	userID, err := s.repo.ConsumeToken(ctx, TokenPasswordReset, hashToken(input.Token))
+	if err != nil {
+		return err
+	}
 
-func (s *postgresService) ChangePassword(ctx context.Context, userID string, input *ChangePasswordInput) error {
-	// TODO: Implement
-	return errors.New("not implemented")
+	if err := s.setPassword(ctx, userID, input.NewPassword); err != nil {
+		return err
+	}
+	if err := s.repo.MarkEmailVerified(ctx, userID); err != nil {
+		return err
+	}
+
+	// Whoever knew the old password is signed out everywhere
+	return s.repo.RevokeSessions(ctx, userID)
 }
 
-func (s *postgresService) ResetPassword(ctx context.Context, input *ResetPasswordInput) error {
-	// TODO: Implement
-	return errors.New("not implemented")
+func (s *postgresService) setPassword(ctx context.Context, userID, password string) error {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := s.repo.UpdatePassword(ctx, userID, hashedPassword); err != nil {
+		return err
+	}
+	return s.repo.DeleteTokens(ctx, userID, TokenPasswordReset)
 }
 
-func (s *postgresService) SendPasswordResetEmail(ctx context.Context, email string) error {
-	// TODO: Implement
-	return errors.New("not implemented")
+// SendPasswordResetEmail emails a reset link to the account with the
+// address. Addresses without an account are ignored, so the response does not
+// tell who has one.
+func (s *postgresService) SendPasswordResetEmail(ctx context.Context, address string) error {
+	user, err := s.repo.GetByEmail(ctx, address)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return s.sendPasswordReset(ctx, user)
 }
 
+// VerifyEmail verifies the address a token was sent to
 func (s *postgresService) VerifyEmail(ctx context.Context, token string) error {
-	// TODO: Implement
-	return errors.New("not implemented")
+	userID, err := s.repo.ConsumeToken(ctx, TokenEmailVerification, hashToken(token))
+	if err != nil {
+		return err
+	}
+
+	return s.repo.MarkEmailVerified(ctx, userID)
 }
 
-func (s *postgresService) ResendVerificationEmail(ctx context.Context, email string) error {
-	// TODO: Implement
-	return errors.New("not implemented")
+// ResendVerificationEmail sends a new verification link to an account whose
+// address is not verified yet. Like password resets, it says nothing about
+// addresses without an account.
+func (s *postgresService) ResendVerificationEmail(ctx context.Context, address string) error {
+	user, err := s.repo.GetByEmail(ctx, address)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.IsVerified {
+		return nil
+	}
+
+	return s.sendVerification(ctx, user)
 }
 
-func (s *postgresService) Search(ctx context.Context, query string, limit, offset int) ([]*User, int64, error) {
-	// TODO: Implement
-	return nil, 0, errors.New("not implemented")
+// Search finds users who chose to be discoverable, matching username or
+// display name but never email, and returns only their public profiles
+func (s *postgresService) Search(ctx context.Context, query string, limit, offset int) ([]*PublicProfile, int64, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, 0, errors.New("search query cannot be empty")
+	}
+
+	users, total, err := s.repo.Search(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	profiles := make([]*PublicProfile, 0, len(users))
+	for _, user := range users {
+		profiles = append(profiles, user.PublicProfile())
+	}
+
+	return profiles, total, nil
 }
 
 // Rename existing GetFriends to avoid conflict
@@ -394,23 +535,3 @@ func (s *postgresService) GetFriends(ctx context.Context, userID string, limit,
 	
 	return users[start:end], int64(len(users)), nil
 }
-
-func (s *postgresService) SendFriendRequest(ctx context.Context, fromUserID, toUserID string) error {
-	// TODO: Implement
-	return errors.New("not implemented")
-}
-
-func (s *postgresService) AcceptFriendRequest(ctx context.Context, userID, requestID string) error {
-	// TODO: Implement
-	return errors.New("not implemented")
-}
-
-func (s *postgresService) RejectFriendRequest(ctx context.Context, userID, requestID string) error {
-	// TODO: Implement
-	return errors.New("not implemented")
-}
-
-func (s *postgresService) GetFriendRequests(ctx context.Context, userID string, incoming bool, limit, offset int) ([]*FriendRequest, int64, error) {
-	// TODO: Implement
-	return nil, 0, errors.New("not implemented")
-}
\ No newline at end of file
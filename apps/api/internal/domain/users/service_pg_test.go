@@ -2,11 +2,15 @@ package users
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/internal/email"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
 	"github.com/Oferzz/newMap/apps/api/internal/utils"
 	"github.com/lib/pq"
 	"github.com/google/uuid"
@@ -58,12 +62,12 @@ func (m *MockRepository) Delete(ctx context.Context, id string) error {
 	return args.Error(0)
 }
 
-func (m *MockRepository) Search(ctx context.Context, query string) ([]*User, error) {
-	args := m.Called(ctx, query)
+func (m *MockRepository) Search(ctx context.Context, query string, limit, offset int) ([]*User, int64, error) {
+	args := m.Called(ctx, query, limit, offset)
 	if args.Get(0) == nil {
-		return nil, args.Error(1)
+		return nil, args.Get(1).(int64), args.Error(2)
 	}
-	return args.Get(0).([]*User), args.Error(1)
+	return args.Get(0).([]*User), args.Get(1).(int64), args.Error(2)
 }
 
 func (m *MockRepository) AddFriend(ctx context.Context, userID, friendID string) error {
@@ -84,6 +88,106 @@ func (m *MockRepository) GetFriends(ctx context.Context, userID string) ([]*User
 	return args.Get(0).([]*User), args.Error(1)
 }
 
+func (m *MockRepository) CreateToken(ctx context.Context, userID, purpose, tokenHash string, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, purpose, tokenHash, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ConsumeToken(ctx context.Context, purpose, tokenHash string) (string, error) {
+	args := m.Called(ctx, purpose, tokenHash)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRepository) DeleteTokens(ctx context.Context, userID, purpose string) error {
+	args := m.Called(ctx, userID, purpose)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	args := m.Called(ctx, userID, passwordHash)
+	return args.Error(0)
+}
+
+func (m *MockRepository) MarkEmailVerified(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateSession(ctx context.Context, session *Session, tokenHash string) error {
+	args := m.Called(ctx, session, tokenHash)
+	if session.ID == "" {
+		session.ID = uuid.New().String()
+	}
+	return args.Error(0)
+}
+
+func (m *MockRepository) RotateRefreshToken(ctx context.Context, tokenHash, newTokenHash string, expiresAt time.Time) (*Session, error) {
+	args := m.Called(ctx, tokenHash, newTokenHash, expiresAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Session), args.Error(1)
+}
+
+func (m *MockRepository) RevokeSessionByToken(ctx context.Context, tokenHash string) error {
+	args := m.Called(ctx, tokenHash)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListSessions(ctx context.Context, userID string) ([]*Session, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*Session), args.Error(1)
+}
+
+func (m *MockRepository) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RevokeSessions(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SessionActive(ctx context.Context, sessionID string) (bool, error) {
+	args := m.Called(ctx, sessionID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) CreateFriendRequest(ctx context.Context, fromUserID, toUserID string) (*FriendRequest, error) {
+	args := m.Called(ctx, fromUserID, toUserID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*FriendRequest), args.Error(1)
+}
+
+func (m *MockRepository) RespondToFriendRequest(ctx context.Context, userID, requestID string, accept bool) (*FriendRequest, error) {
+	args := m.Called(ctx, userID, requestID, accept)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*FriendRequest), args.Error(1)
+}
+
+func (m *MockRepository) ListFriendRequests(ctx context.Context, userID string, incoming bool, limit, offset int) ([]*FriendRequest, int64, error) {
+	args := m.Called(ctx, userID, incoming, limit, offset)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*FriendRequest), args.Get(1).(int64), args.Error(2)
+}
+
+// recordingSender keeps the emails it is asked to send
+type recordingSender struct {
+	sent []email.Message
+}
+
+func (s *recordingSender) Send(ctx context.Context, msg email.Message) error {
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
 func TestServicePG_Register(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockConfig := &config.Config{
@@ -184,6 +288,7 @@ func TestServicePG_Login(t *testing.T) {
 		}
 
 		mockRepo.On("GetByEmail", ctx, email).Return(user, nil).Once()
+		mockRepo.On("CreateSession", ctx, mock.AnythingOfType("*users.Session"), mock.AnythingOfType("string")).Return(nil).Once()
 
 		result, err := service.Login(ctx, &LoginInput{Email: email, Password: password})
 		assert.NoError(t, err)
@@ -218,7 +323,6 @@ func TestServicePG_Login(t *testing.T) {
 		password := "password123"
 
 		mockRepo.On("GetByEmail", ctx, email).Return(nil, errors.New("not found")).Once()
-		mockRepo.On("GetByUsername", ctx, email).Return(nil, errors.New("not found")).Once()
 
 		result, err := service.Login(ctx, &LoginInput{Email: email, Password: password})
 		assert.Error(t, err)
@@ -284,6 +388,28 @@ func TestServicePG_UpdateProfile(t *testing.T) {
 	})
 }
 
+func TestServicePG_UpdateLocale(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewPostgreSQLService(mockRepo, &config.Config{})
+	ctx := context.Background()
+	userID := uuid.New().String()
+
+	tag, units := "de-CH", "imperial"
+	mockRepo.On("GetByID", ctx, userID).Return(&User{ID: userID}, nil).Once()
+	mockRepo.On("Update", ctx, mock.MatchedBy(func(user *User) bool {
+		return user.Locale == tag && user.Units == units
+	})).Return(nil).Once()
+
+	_, err := service.Update(ctx, userID, &UpdateUserInput{Locale: &tag, Units: &units})
+	assert.NoError(t, err)
+
+	invalid := "not a language"
+	mockRepo.On("GetByID", ctx, userID).Return(&User{ID: userID}, nil).Once()
+	_, err = service.Update(ctx, userID, &UpdateUserInput{Locale: &invalid})
+	assert.ErrorIs(t, err, ErrInvalidLocale)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestServicePG_AddFriend(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockConfig := &config.Config{
@@ -343,21 +469,23 @@ func TestServicePG_AddFriend(t *testing.T) {
 		friendID := uuid.New().String()
 
 		user := &User{
-			ID:      userID,
-			Roles: pq.StringArray{"user"}, // Already friends
+			ID:    userID,
+			Roles: pq.StringArray{"user"},
 		}
 
 		friend := &User{
-			ID:      friendID,
+			ID:    friendID,
 			Roles: pq.StringArray{"user"},
 		}
 
+		// The repository ignores a friendship that already exists
 		mockRepo.On("GetByID", ctx, userID).Return(user, nil).Once()
 		mockRepo.On("GetByID", ctx, friendID).Return(friend, nil).Once()
+		mockRepo.On("AddFriend", ctx, userID, friendID).Return(nil).Once()
+		mockRepo.On("AddFriend", ctx, friendID, userID).Return(nil).Once()
 
 		err := service.AddFriend(ctx, userID, friendID)
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "already friends")
+		assert.NoError(t, err)
 		mockRepo.AssertExpectations(t)
 	})
 }
@@ -398,31 +526,20 @@ func TestServicePG_RemoveFriend(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
-	t.Run("not friends", func(t *testing.T) {
+	t.Run("user not found", func(t *testing.T) {
 		userID := uuid.New().String()
 		friendID := uuid.New().String()
 
-		user := &User{
-			ID:      userID,
-			Roles: pq.StringArray{"user"}, // Not friends
-		}
-
-		friend := &User{
-			ID:      friendID,
-			Roles: pq.StringArray{"user"},
-		}
-
-		mockRepo.On("GetByID", ctx, userID).Return(user, nil).Once()
-		mockRepo.On("GetByID", ctx, friendID).Return(friend, nil).Once()
+		mockRepo.On("GetByID", ctx, userID).Return(nil, errors.New("not found")).Once()
 
 		err := service.RemoveFriend(ctx, userID, friendID)
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "not friends")
+		assert.Contains(t, err.Error(), "user not found")
 		mockRepo.AssertExpectations(t)
 	})
 }
 
-func TestServicePG_SearchUsers(t *testing.T) {
+func TestServicePG_Search(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockConfig := &config.Config{
 		JWT: config.JWTConfig{
@@ -438,9 +555,10 @@ func TestServicePG_SearchUsers(t *testing.T) {
 		query := "test"
 		users := []*User{
 			{
-				ID:       uuid.New().String(),
-				Username: "testuser1",
-				Email:    "test1@example.com",
+				ID:          uuid.New().String(),
+				Username:    "testuser1",
+				Email:       "test1@example.com",
+				DisplayName: "Test User 1",
 			},
 			{
 				ID:       uuid.New().String(),
@@ -449,18 +567,23 @@ func TestServicePG_SearchUsers(t *testing.T) {
 			},
 		}
 
-		mockRepo.On("Search", ctx, query).Return(users, nil).Once()
+		mockRepo.On("Search", ctx, query, 20, 0).Return(users, int64(2), nil).Once()
 
-		result, err := service.SearchUsers(ctx, query)
+		result, total, err := service.Search(ctx, " test ", 20, 0)
 		assert.NoError(t, err)
+		assert.Equal(t, int64(2), total)
 		assert.Len(t, result, 2)
+		assert.Equal(t, "Test User 1", result[0].DisplayName)
 		mockRepo.AssertExpectations(t)
+
+		// Emails are never part of the results
+		body, err := json.Marshal(result)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(body), "example.com")
 	})
 
 	t.Run("empty query", func(t *testing.T) {
-		query := ""
-
-		result, err := service.SearchUsers(ctx, query)
+		result, _, err := service.Search(ctx, "  ", 20, 0)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "search query cannot be empty")
 		assert.Nil(t, result)
@@ -515,4 +638,340 @@ func TestServicePG_GetFriends(t *testing.T) {
 		assert.Equal(t, int64(0), total)
 		mockRepo.AssertExpectations(t)
 	})
-}
\ No newline at end of file
+}
+func TestServicePG_ChangePassword(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewPostgreSQLService(mockRepo, &config.Config{})
+	ctx := context.Background()
+
+	userID := uuid.New().String()
+	hash, err := utils.HashPassword("old-password")
+	assert.NoError(t, err)
+
+	t.Run("wrong current password", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, userID).Return(&User{ID: userID, PasswordHash: hash}, nil).Once()
+
+		err := service.ChangePassword(ctx, userID, &ChangePasswordInput{
+			CurrentPassword: "not-it",
+			NewPassword:     "new-password",
+		})
+		assert.ErrorIs(t, err, ErrInvalidPassword)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("successful change", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, userID).Return(&User{ID: userID, PasswordHash: hash}, nil).Once()
+		mockRepo.On("UpdatePassword", ctx, userID, mock.MatchedBy(func(newHash string) bool {
+			return utils.CheckPassword("new-password", newHash)
+		})).Return(nil).Once()
+		mockRepo.On("DeleteTokens", ctx, userID, TokenPasswordReset).Return(nil).Once()
+
+		err := service.ChangePassword(ctx, userID, &ChangePasswordInput{
+			CurrentPassword: "old-password",
+			NewPassword:     "new-password",
+		})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestServicePG_ResetPassword(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewPostgreSQLService(mockRepo, &config.Config{})
+	ctx := context.Background()
+
+	t.Run("invalid token", func(t *testing.T) {
+		mockRepo.On("ConsumeToken", ctx, TokenPasswordReset, hashToken("expired")).Return("", ErrInvalidToken).Once()
+
+		err := service.ResetPassword(ctx, &ResetPasswordInput{Token: "expired", NewPassword: "new-password"})
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("successful reset", func(t *testing.T) {
+		userID := uuid.New().String()
+
+		mockRepo.On("ConsumeToken", ctx, TokenPasswordReset, hashToken("valid")).Return(userID, nil).Once()
+		mockRepo.On("UpdatePassword", ctx, userID, mock.AnythingOfType("string")).Return(nil).Once()
+		mockRepo.On("DeleteTokens", ctx, userID, TokenPasswordReset).Return(nil).Once()
+		mockRepo.On("MarkEmailVerified", ctx, userID).Return(nil).Once()
+		mockRepo.On("RevokeSessions", ctx, userID).Return(nil).Once()
+
+		err := service.ResetPassword(ctx, &ResetPasswordInput{Token: "valid", NewPassword: "new-password"})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestServicePG_SendPasswordResetEmail(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewPostgreSQLService(mockRepo, &config.Config{
+		App: config.AppConfig{Name: "newMap", WebURL: "https://example.com/"},
+	})
+	sender := &recordingSender{}
+	service.SetEmailSender(sender)
+	ctx := context.Background()
+
+	t.Run("unknown email", func(t *testing.T) {
+		mockRepo.On("GetByEmail", ctx, "nobody@example.com").Return(nil, ErrUserNotFound).Once()
+
+		err := service.SendPasswordResetEmail(ctx, "nobody@example.com")
+		assert.NoError(t, err)
+		assert.Empty(t, sender.sent)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("sends a link whose token is stored hashed", func(t *testing.T) {
+		user := &User{ID: uuid.New().String(), Username: "test", Email: "test@example.com"}
+		var storedHash string
+
+		mockRepo.On("GetByEmail", ctx, user.Email).Return(user, nil).Once()
+		mockRepo.On("CreateToken", ctx, user.ID, TokenPasswordReset, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).
+			Run(func(args mock.Arguments) { storedHash = args.String(3) }).
+			Return(nil).Once()
+
+		err := service.SendPasswordResetEmail(ctx, user.Email)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+
+		if assert.Len(t, sender.sent, 1) {
+			msg := sender.sent[0]
+			assert.Equal(t, user.Email, msg.To)
+			assert.Contains(t, msg.Body, "https://example.com/reset-password?token=")
+
+			start := strings.Index(msg.Body, "?token=") + len("?token=")
+			token := msg.Body[start : start+strings.IndexByte(msg.Body[start:], '\n')]
+			assert.Equal(t, hashToken(token), storedHash)
+		}
+	})
+}
+
+func TestServicePG_VerifyEmail(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewPostgreSQLService(mockRepo, &config.Config{})
+	ctx := context.Background()
+	userID := uuid.New().String()
+
+	mockRepo.On("ConsumeToken", ctx, TokenEmailVerification, hashToken("valid")).Return(userID, nil).Once()
+	mockRepo.On("MarkEmailVerified", ctx, userID).Return(nil).Once()
+
+	assert.NoError(t, service.VerifyEmail(ctx, "valid"))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServicePG_ResendVerificationEmail(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewPostgreSQLService(mockRepo, &config.Config{})
+	sender := &recordingSender{}
+	service.SetEmailSender(sender)
+	ctx := context.Background()
+
+	t.Run("already verified", func(t *testing.T) {
+		user := &User{ID: uuid.New().String(), Email: "verified@example.com", IsVerified: true}
+		mockRepo.On("GetByEmail", ctx, user.Email).Return(user, nil).Once()
+
+		assert.NoError(t, service.ResendVerificationEmail(ctx, user.Email))
+		assert.Empty(t, sender.sent)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("not verified", func(t *testing.T) {
+		user := &User{ID: uuid.New().String(), Email: "new@example.com"}
+		mockRepo.On("GetByEmail", ctx, user.Email).Return(user, nil).Once()
+		mockRepo.On("CreateToken", ctx, user.ID, TokenEmailVerification, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil).Once()
+
+		assert.NoError(t, service.ResendVerificationEmail(ctx, user.Email))
+		assert.Len(t, sender.sent, 1)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestServicePG_Sessions(t *testing.T) {
+	mockConfig := &config.Config{
+		JWT: config.JWTConfig{
+			Secret:        "test-secret",
+			AccessExpiry:  15 * time.Minute,
+			RefreshExpiry: 7 * 24 * time.Hour,
+		},
+	}
+	ctx := context.Background()
+	hash, err := utils.HashPassword("password123")
+	assert.NoError(t, err)
+	user := &User{ID: uuid.New().String(), Email: "test@example.com", PasswordHash: hash}
+
+	t.Run("login starts a session", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewPostgreSQLService(mockRepo, mockConfig)
+		var refreshHash string
+
+		mockRepo.On("GetByEmail", ctx, user.Email).Return(user, nil).Once()
+		mockRepo.On("CreateSession", ctx, mock.MatchedBy(func(session *Session) bool {
+			return session.UserID == user.ID && session.DeviceName == "Work laptop" && session.UserAgent == "test-agent"
+		}), mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) { refreshHash = args.String(2) }).
+			Return(nil).Once()
+
+		resp, err := service.Login(ctx, &LoginInput{
+			Email:      user.Email,
+			Password:   "password123",
+			DeviceName: "Work laptop",
+			UserAgent:  "test-agent",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, hashToken(resp.RefreshToken), refreshHash)
+		assert.Equal(t, int64(900), resp.ExpiresIn)
+
+		claims, err := utils.NewJWTManager(&mockConfig.JWT).ValidateToken(resp.AccessToken)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, claims.SessionID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("refresh rotates the token", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewPostgreSQLService(mockRepo, mockConfig)
+		session := &Session{ID: uuid.New().String(), UserID: user.ID}
+		var newHash string
+
+		mockRepo.On("RotateRefreshToken", ctx, hashToken("old-token"), mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).
+			Run(func(args mock.Arguments) { newHash = args.String(2) }).
+			Return(session, nil).Once()
+		mockRepo.On("GetByID", ctx, user.ID).Return(user, nil).Once()
+
+		resp, err := service.RefreshToken(ctx, "old-token")
+		assert.NoError(t, err)
+		assert.NotEqual(t, "old-token", resp.RefreshToken)
+		assert.Equal(t, hashToken(resp.RefreshToken), newHash)
+
+		claims, err := utils.NewJWTManager(&mockConfig.JWT).ValidateToken(resp.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, session.ID, claims.SessionID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("a reused token is invalid", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewPostgreSQLService(mockRepo, mockConfig)
+
+		mockRepo.On("RotateRefreshToken", ctx, hashToken("stolen"), mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).
+			Return(nil, ErrRefreshTokenReused).Once()
+
+		_, err := service.RefreshToken(ctx, "stolen")
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("listing marks the current session", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewPostgreSQLService(mockRepo, mockConfig)
+		sessions := []*Session{{ID: "phone"}, {ID: "laptop"}}
+
+		mockRepo.On("ListSessions", ctx, user.ID).Return(sessions, nil).Once()
+
+		got, err := service.ListSessions(ctx, user.ID, "laptop")
+		assert.NoError(t, err)
+		assert.False(t, got[0].Current)
+		assert.True(t, got[1].Current)
+	})
+
+	t.Run("revoking a malformed session ID", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewPostgreSQLService(mockRepo, mockConfig)
+
+		err := service.RevokeSession(ctx, user.ID, "not-a-uuid")
+		assert.ErrorIs(t, err, ErrSessionNotFound)
+		mockRepo.AssertNotCalled(t, "RevokeSession", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestServicePG_FriendRequests(t *testing.T) {
+	mockConfig := &config.Config{JWT: config.JWTConfig{Secret: "test-secret"}}
+	ctx := context.Background()
+	senderID := uuid.New().String()
+	recipientID := uuid.New().String()
+	requestID := uuid.New().String()
+
+	newService := func() (*postgresService, *MockRepository, *[]events.Event) {
+		mockRepo := new(MockRepository)
+		service := NewPostgreSQLService(mockRepo, mockConfig)
+		bus := events.NewLocalBus()
+		var published []events.Event
+		record := func(ctx context.Context, event events.Event) error {
+			published = append(published, event)
+			return nil
+		}
+		bus.Subscribe(events.FriendRequestReceived, record)
+		bus.Subscribe(events.FriendRequestAccepted, record)
+		service.SetEventBus(bus)
+		return service, mockRepo, &published
+	}
+
+	t.Run("send notifies the recipient", func(t *testing.T) {
+		service, mockRepo, published := newService()
+		request := &FriendRequest{ID: requestID, FromUserID: senderID, ToUserID: recipientID, Status: "pending"}
+
+		mockRepo.On("GetByID", ctx, recipientID).Return(&User{ID: recipientID, Status: "active"}, nil).Once()
+		mockRepo.On("CreateFriendRequest", ctx, senderID, recipientID).Return(request, nil).Once()
+
+		got, err := service.SendFriendRequest(ctx, senderID, recipientID)
+		assert.NoError(t, err)
+		assert.Equal(t, request, got)
+		if assert.Len(t, *published, 1) {
+			event := (*published)[0]
+			assert.Equal(t, events.FriendRequestReceived, event.Type)
+			assert.Equal(t, "user", event.EntityType)
+			assert.Equal(t, recipientID, event.EntityID)
+			assert.Equal(t, senderID, event.ActorID)
+			assert.Equal(t, requestID, event.Data["request_id"])
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("send to yourself", func(t *testing.T) {
+		service, mockRepo, published := newService()
+
+		_, err := service.SendFriendRequest(ctx, senderID, senderID)
+		assert.ErrorIs(t, err, ErrSelfFriendRequest)
+		assert.Empty(t, *published)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("a blocked request looks sent but notifies nobody", func(t *testing.T) {
+		service, mockRepo, published := newService()
+
+		mockRepo.On("GetByID", ctx, recipientID).Return(&User{ID: recipientID, Status: "active"}, nil).Once()
+		mockRepo.On("CreateFriendRequest", ctx, senderID, recipientID).Return(nil, errFriendshipBlocked).Once()
+
+		got, err := service.SendFriendRequest(ctx, senderID, recipientID)
+		assert.NoError(t, err)
+		assert.Equal(t, "pending", got.Status)
+		assert.Empty(t, *published)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("accept notifies the sender", func(t *testing.T) {
+		service, mockRepo, published := newService()
+		request := &FriendRequest{ID: requestID, FromUserID: senderID, ToUserID: recipientID, Status: "accepted"}
+
+		mockRepo.On("RespondToFriendRequest", ctx, recipientID, requestID, true).Return(request, nil).Once()
+
+		assert.NoError(t, service.AcceptFriendRequest(ctx, recipientID, requestID))
+		if assert.Len(t, *published, 1) {
+			assert.Equal(t, events.FriendRequestAccepted, (*published)[0].Type)
+			assert.Equal(t, senderID, (*published)[0].EntityID)
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("reject tells nobody", func(t *testing.T) {
+		service, mockRepo, published := newService()
+
+		mockRepo.On("RespondToFriendRequest", ctx, recipientID, requestID, false).
+			Return(&FriendRequest{ID: requestID, FromUserID: senderID, ToUserID: recipientID, Status: "rejected"}, nil).Once()
+
+		assert.NoError(t, service.RejectFriendRequest(ctx, recipientID, requestID))
+		assert.Empty(t, *published)
+		assert.ErrorIs(t, service.RejectFriendRequest(ctx, recipientID, "not-a-uuid"), ErrFriendRequestNotFound)
+		mockRepo.AssertExpectations(t)
+	})
+}
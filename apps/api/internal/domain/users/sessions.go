@@ -0,0 +1,134 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxUserAgent bounds the user agent kept for a session
+const maxUserAgent = 500
+
+// Session is a device signed in to an account. Its refresh token changes
+// every time it is used.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"-"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+
+	// Set when listing, for the session the request was made from
+	Current bool `json:"current"`
+}
+
+// startSession signs the user in on a new device
+func (s *postgresService) startSession(ctx context.Context, user *User, input *LoginInput) (*LoginResponse, error) {
+	refreshToken, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	userAgent := input.UserAgent
+	if len(userAgent) > maxUserAgent {
+		userAgent = userAgent[:maxUserAgent]
+	}
+
+	session := &Session{
+		UserID:     user.ID,
+		DeviceName: input.DeviceName,
+		UserAgent:  userAgent,
+		IPAddress:  input.IPAddress,
+		ExpiresAt:  time.Now().Add(s.jwtManager.GetRefreshTokenExpiry()),
+	}
+	if err := s.repo.CreateSession(ctx, session, hashToken(refreshToken)); err != nil {
+		return nil, err
+	}
+
+	return s.loginResponse(user, session, refreshToken)
+}
+
+// loginResponse issues an access token for the session
+func (s *postgresService) loginResponse(user *User, session *Session, refreshToken string) (*LoginResponse, error) {
+	accessToken, err := s.jwtManager.GenerateAccessToken(user.ID, user.Email, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	return &LoginResponse{
+		User:         user,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.jwtManager.GetAccessTokenExpiry().Seconds()),
+	}, nil
+}
+
+// RefreshToken trades a refresh token for a new access token and a new
+// refresh token; the one given stops working
+func (s *postgresService) RefreshToken(ctx context.Context, refreshToken string) (*LoginResponse, error) {
+	newRefreshToken, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.jwtManager.GetRefreshTokenExpiry())
+	session, err := s.repo.RotateRefreshToken(ctx, hashToken(refreshToken), hashToken(newRefreshToken), expiresAt)
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			log.Printf("users: a used refresh token was presented again; its session has been ended")
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	user, err := s.repo.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.loginResponse(user, session, newRefreshToken)
+}
+
+// Logout ends the session the refresh token belongs to
+func (s *postgresService) Logout(ctx context.Context, refreshToken string) error {
+	return s.repo.RevokeSessionByToken(ctx, hashToken(refreshToken))
+}
+
+// LogoutAll ends every session of the user, on all devices
+func (s *postgresService) LogoutAll(ctx context.Context, userID string) error {
+	return s.repo.RevokeSessions(ctx, userID)
+}
+
+// ListSessions returns the devices the user is signed in on, marking the
+// current one
+func (s *postgresService) ListSessions(ctx context.Context, userID, currentSessionID string) ([]*Session, error) {
+	sessions, err := s.repo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs the user out on one device
+func (s *postgresService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return ErrSessionNotFound
+	}
+	return s.repo.RevokeSession(ctx, userID, sessionID)
+}
+
+// SessionActive reports whether access tokens of the session still work
+func (s *postgresService) SessionActive(ctx context.Context, sessionID string) (bool, error) {
+	return s.repo.SessionActive(ctx, sessionID)
+}
@@ -0,0 +1,99 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/email"
+)
+
+// Token purposes
+const (
+	TokenPasswordReset     = "password_reset"
+	TokenEmailVerification = "email_verification"
+)
+
+const (
+	// tokenBytes is the length of emailed tokens before encoding
+	tokenBytes = 32
+
+	// passwordResetExpiry is how long a password reset link works
+	passwordResetExpiry = time.Hour
+
+	// emailVerificationExpiry is how long a verification link works
+	emailVerificationExpiry = 48 * time.Hour
+)
+
+// newToken returns a random token that is safe to put in a URL
+func newToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is what is stored of a token, so the tokens themselves are only
+// ever in the emails
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueToken creates a token for the user, replacing the one sent before
+func (s *postgresService) issueToken(ctx context.Context, userID, purpose string, expiry time.Duration) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.CreateToken(ctx, userID, purpose, hashToken(token), time.Now().Add(expiry)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// link is the web app page for a token
+func (s *postgresService) link(path, token string) string {
+	return strings.TrimRight(s.webURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendPasswordReset emails the user a link to choose a new password
+func (s *postgresService) sendPasswordReset(ctx context.Context, user *User) error {
+	token, err := s.issueToken(ctx, user.ID, TokenPasswordReset, passwordResetExpiry)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, email.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nSomeone asked to reset the password of your %s account. To choose a new password, follow this link within the hour:\n\n%s\n\nIf it wasn't you, ignore this email and your password stays as it is.\n",
+			user.Username, s.appName, s.link("/reset-password", token),
+		),
+	})
+}
+
+// sendVerification emails the user a link that verifies their address
+func (s *postgresService) sendVerification(ctx context.Context, user *User) error {
+	token, err := s.issueToken(ctx, user.ID, TokenEmailVerification, emailVerificationExpiry)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, email.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nTo confirm this is the email address of your %s account, follow this link within 48 hours:\n\n%s\n\nIf you didn't sign up, ignore this email.\n",
+			user.Username, s.appName, s.link("/verify-email", token),
+		),
+	})
+}
@@ -137,7 +137,35 @@ func (c *Client) IndexPlace(ctx context.Context, placeID string, place map[strin
 	return nil
 }
 
-// SearchUnified performs a unified search across activities and places
+// IndexCollection indexes a collection document
+func (c *Client) IndexCollection(ctx context.Context, collectionID string, collection map[string]interface{}) error {
+	body, err := json.Marshal(collection)
+	if err != nil {
+		return fmt.Errorf("failed to marshal collection: %w", err)
+	}
+
+	req := esapi.IndexRequest{
+		Index:      "collections",
+		DocumentID: collectionID,
+		Body:       bytes.NewReader(body),
+		Refresh:    "true",
+	}
+
+	res, err := req.Do(ctx, c.es)
+	if err != nil {
+		return fmt.Errorf("failed to index collection: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("indexing failed: %s", res.Status())
+	}
+
+	return nil
+}
+
+// SearchUnified performs a unified search across activities, places and
+// collections
 func (c *Client) SearchUnified(ctx context.Context, query map[string]interface{}) (*SearchResponse, error) {
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(query); err != nil {
@@ -146,7 +174,9 @@ func (c *Client) SearchUnified(ctx context.Context, query map[string]interface{}
 
 	res, err := c.es.Search(
 		c.es.Search.WithContext(ctx),
-		c.es.Search.WithIndex("activities", "places"),
+		c.es.Search.WithIndex("activities", "places", "collections"),
+		// The collections index only exists once a collection is made public
+		c.es.Search.WithIgnoreUnavailable(true),
 		c.es.Search.WithBody(&buf),
 		c.es.Search.WithTrackTotalHits(true),
 	)
@@ -182,8 +212,11 @@ func (c *Client) SearchUnified(ctx context.Context, query map[string]interface{}
 	results := make([]SearchResult, len(response.Hits.Hits))
 	for i, hit := range response.Hits.Hits {
 		docType := "activity"
-		if hit.Index == "places" {
+		switch hit.Index {
+		case "places":
 			docType = "place"
+		case "collections":
+			docType = "collection"
 		}
 		
 		results[i] = SearchResult{
@@ -311,6 +344,149 @@ func (c *Client) DeleteDocument(ctx context.Context, index, documentID string) e
 	return nil
 }
 
+// IndexVersioned indexes a document unless a newer version of it is already
+// indexed. The caller picks versions, such as when the change was made, so
+// changes applied out of order never bring back an older document.
+func (c *Client) IndexVersioned(ctx context.Context, index, documentID string, document map[string]interface{}, version int64) error {
+	body, err := json.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("failed to marshal document: %w", err)
+	}
+
+	v := int(version)
+	req := esapi.IndexRequest{
+		Index:       index,
+		DocumentID:  documentID,
+		Body:        bytes.NewReader(body),
+		Version:     &v,
+		VersionType: "external_gte",
+		Refresh:     "true",
+	}
+
+	res, err := req.Do(ctx, c.es)
+	if err != nil {
+		return fmt.Errorf("failed to index document: %w", err)
+	}
+	defer res.Body.Close()
+
+	// A conflict means a newer version is indexed already
+	if res.IsError() && res.StatusCode != http.StatusConflict {
+		return fmt.Errorf("indexing failed: %s", res.Status())
+	}
+
+	return nil
+}
+
+// DeleteVersioned deletes a document unless a newer version of it has been
+// indexed since
+func (c *Client) DeleteVersioned(ctx context.Context, index, documentID string, version int64) error {
+	v := int(version)
+	req := esapi.DeleteRequest{
+		Index:       index,
+		DocumentID:  documentID,
+		Version:     &v,
+		VersionType: "external_gte",
+		Refresh:     "true",
+	}
+
+	res, err := req.Do(ctx, c.es)
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() && res.StatusCode != http.StatusNotFound && res.StatusCode != http.StatusConflict {
+		return fmt.Errorf("delete failed: %s", res.Status())
+	}
+
+	return nil
+}
+
+// BulkDocument is a document indexed in bulk
+type BulkDocument struct {
+	ID       string
+	Document map[string]interface{}
+}
+
+// BulkIndexVersioned indexes documents in one request, like IndexVersioned
+// with the same version for each. The index is not refreshed, so bulk loads
+// stay cheap. Documents a newer version of is indexed already are skipped;
+// any other failure fails the whole call.
+func (c *Client) BulkIndexVersioned(ctx context.Context, index string, documents []BulkDocument, version int64) error {
+	if len(documents) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, doc := range documents {
+		action := map[string]interface{}{
+			"index": map[string]interface{}{
+				"_index":       index,
+				"_id":          doc.ID,
+				"version":      version,
+				"version_type": "external_gte",
+			},
+		}
+		if err := encoder.Encode(action); err != nil {
+			return fmt.Errorf("failed to marshal bulk action: %w", err)
+		}
+		if err := encoder.Encode(doc.Document); err != nil {
+			return fmt.Errorf("failed to marshal document %s: %w", doc.ID, err)
+		}
+	}
+
+	req := esapi.BulkRequest{
+		Index: index,
+		Body:  &body,
+	}
+
+	res, err := req.Do(ctx, c.es)
+	if err != nil {
+		return fmt.Errorf("failed to bulk index: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("bulk indexing failed: %s", res.Status())
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string `json:"_id"`
+			Status int    `json:"status"`
+			Error  struct {
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to parse bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+
+	failed, first := 0, ""
+	for _, item := range result.Items {
+		for _, outcome := range item {
+			if outcome.Status < 300 || outcome.Status == http.StatusConflict {
+				continue
+			}
+			if failed == 0 {
+				first = fmt.Sprintf("%s: %s", outcome.ID, outcome.Error.Reason)
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("bulk indexing failed for %d of %d documents, first %s", failed, len(documents), first)
+	}
+
+	return nil
+}
+
 // BuildQuery builds an Elasticsearch query from search parameters
 func BuildQuery(searchText string, filters map[string]interface{}, limit, offset int) map[string]interface{} {
 	query := map[string]interface{}{
@@ -388,11 +564,47 @@ func buildFilters(filters map[string]interface{}) []map[string]interface{} {
 					},
 				})
 			}
-		case "visibility":
-			if vis, ok := value.(string); ok && vis != "" {
+		case "accessibility":
+			// Every attribute asked for must hold, so one term each
+			if attributes, ok := value.([]string); ok {
+				for _, attribute := range attributes {
+					filterClauses = append(filterClauses, map[string]interface{}{
+						"term": map[string]interface{}{
+							"accessibility": attribute,
+						},
+					})
+				}
+			}
+		case "amenities":
+			// Every amenity must be offered, by the place or by the place a
+			// trip starts from
+			if amenities, ok := value.([]string); ok {
+				for _, amenity := range amenities {
+					filterClauses = append(filterClauses, map[string]interface{}{
+						"bool": map[string]interface{}{
+							"should": []map[string]interface{}{
+								{"term": map[string]interface{}{"amenities": amenity}},
+								{"term": map[string]interface{}{"trailhead_amenities": amenity}},
+							},
+							"minimum_should_match": 1,
+						},
+					})
+				}
+			}
+		case "privacy":
+			if privacy, ok := value.(string); ok && privacy != "" {
+				filterClauses = append(filterClauses, map[string]interface{}{
+					"term": map[string]interface{}{
+						"privacy": privacy,
+					},
+				})
+			}
+		case "open_at":
+			// Places index their weekly hours as ranges of minutes of the week
+			if minute, ok := value.(int); ok {
 				filterClauses = append(filterClauses, map[string]interface{}{
 					"term": map[string]interface{}{
-						"visibility": vis,
+						"open_hours": minute,
 					},
 				})
 			}
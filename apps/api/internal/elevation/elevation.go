@@ -0,0 +1,24 @@
+// Package elevation looks up the height of the ground at points, from a
+// digital elevation model
+package elevation
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoData is returned when the model has no height for a point, such as
+// one outside its coverage
+var ErrNoData = errors.New("no elevation data for the point")
+
+// Point is a place on the ground
+type Point struct {
+	Longitude float64
+	Latitude  float64
+}
+
+// Provider looks up the elevation of points, in metres above sea level and
+// in the order of the points
+type Provider interface {
+	Elevations(ctx context.Context, points []Point) ([]float64, error)
+}
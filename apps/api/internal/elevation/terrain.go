@@ -0,0 +1,153 @@
+package elevation
+
+import (
+	"context"
+	"fmt"
+	"image"
+	_ "image/png" // Terrain tiles are PNGs
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/mapbox"
+)
+
+const mapboxTerrainTiles = "https://api.mapbox.com/v4/mapbox.terrain-rgb"
+
+// Terrain tiles are fetched at the most detailed zoom that needs no more
+// than MaxTiles of them for the points. At zoom 14 a pixel is about 10 m
+// across at the equator.
+const (
+	MaxTiles = 16
+	maxZoom  = 14
+	minZoom  = 8
+)
+
+// maxLatitude is the furthest north or south web mercator tiles reach
+const maxLatitude = 85.05112878
+
+// MapboxTerrain looks up elevations in Mapbox's terrain-RGB tiles, which
+// encode the height of each pixel in its colour
+type MapboxTerrain struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewMapboxTerrain creates a Mapbox terrain provider
+func NewMapboxTerrain(apiKey string) *MapboxTerrain {
+	return &MapboxTerrain{
+		apiKey:  apiKey,
+		baseURL: mapboxTerrainTiles,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// tile is a web mercator map tile
+type tile struct {
+	z, x, y int
+}
+
+// Elevations returns the height of the terrain at each point. The tiles the
+// points fall in are each fetched once.
+func (t *MapboxTerrain) Elevations(ctx context.Context, points []Point) ([]float64, error) {
+	if t.apiKey == "" {
+		return nil, fmt.Errorf("mapbox API key not configured")
+	}
+
+	zoom := zoomFor(points)
+	tiles := make(map[tile]image.Image)
+	elevations := make([]float64, len(points))
+	for i, p := range points {
+		key, fx, fy := locate(p, zoom)
+		img, ok := tiles[key]
+		if !ok {
+			var err error
+			if img, err = t.fetch(ctx, key); err != nil {
+				return nil, err
+			}
+			tiles[key] = img
+		}
+
+		bounds := img.Bounds()
+		px := bounds.Min.X + int(fx*float64(bounds.Dx()))
+		py := bounds.Min.Y + int(fy*float64(bounds.Dy()))
+		elevations[i] = decodeHeight(img, px, py)
+	}
+
+	return elevations, nil
+}
+
+func (t *MapboxTerrain) fetch(ctx context.Context, key tile) (image.Image, error) {
+	params := url.Values{}
+	params.Set("access_token", t.apiKey)
+	endpoint := fmt.Sprintf("%s/%d/%d/%d.pngraw?%s", t.baseURL, key.z, key.x, key.y, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch terrain tile: %w", mapbox.StripURL(err))
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrNoData
+	default:
+		return nil, fmt.Errorf("mapbox API returned status %d", resp.StatusCode)
+	}
+
+	img, _, err := image.Decode(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode terrain tile: %w", err)
+	}
+	return img, nil
+}
+
+// zoomFor is the most detailed zoom at which the points fall in no more
+// than MaxTiles tiles
+func zoomFor(points []Point) int {
+	for zoom := maxZoom; zoom > minZoom; zoom-- {
+		tiles := make(map[tile]bool)
+		for _, p := range points {
+			key, _, _ := locate(p, zoom)
+			tiles[key] = true
+		}
+		if len(tiles) <= MaxTiles {
+			return zoom
+		}
+	}
+	return minZoom
+}
+
+// locate finds the web mercator tile a point falls in at a zoom, and where
+// in the tile it lies as fractions of its width and height
+func locate(p Point, zoom int) (tile, float64, float64) {
+	n := math.Exp2(float64(zoom))
+	lat := math.Max(-maxLatitude, math.Min(maxLatitude, p.Latitude)) * math.Pi / 180
+
+	x := (p.Longitude + 180) / 360 * n
+	y := (1 - math.Log(math.Tan(lat)+1/math.Cos(lat))/math.Pi) / 2 * n
+
+	// The antimeridian and the poles are on the last tile, not past it
+	x = math.Min(math.Max(x, 0), n-1e-9)
+	y = math.Min(math.Max(y, 0), n-1e-9)
+
+	return tile{z: zoom, x: int(x), y: int(y)}, x - math.Floor(x), y - math.Floor(y)
+}
+
+// decodeHeight reads the height a terrain-RGB pixel encodes, in tenths of
+// a metre above -10000 m
+func decodeHeight(img image.Image, x, y int) float64 {
+	r, g, b, _ := img.At(x, y).RGBA()
+	value := (r>>8)*256*256 + (g>>8)*256 + (b >> 8)
+	return -10000 + float64(value)*0.1
+}
@@ -0,0 +1,90 @@
+package elevation
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// terrainTile is a tile of two pixels, its west half at 500 m and its east
+// half at 1523.7 m
+func terrainTile() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	for x, height := range []float64{500, 1523.7} {
+		value := int(math.Round((height + 10000) * 10))
+		img.Set(x, 0, color.NRGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 255})
+	}
+	return img
+}
+
+// tilePoint is the point at a position in tile coordinates
+func tilePoint(zoom int, x, y float64) Point {
+	n := math.Exp2(float64(zoom))
+	return Point{
+		Longitude: x/n*360 - 180,
+		Latitude:  math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * 180 / math.Pi,
+	}
+}
+
+func TestMapboxTerrain_Elevations(t *testing.T) {
+	fetched := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched++
+		assert.Equal(t, "/14/2702/5874.pngraw", r.URL.Path)
+		assert.Equal(t, "token", r.URL.Query().Get("access_token"))
+		png.Encode(w, terrainTile())
+	}))
+	t.Cleanup(server.Close)
+
+	terrain := NewMapboxTerrain("token")
+	terrain.baseURL = server.URL
+
+	points := []Point{tilePoint(14, 2702.25, 5874.5), tilePoint(14, 2702.75, 5874.5)}
+	elevations, err := terrain.Elevations(context.Background(), points)
+	require.NoError(t, err)
+	require.Len(t, elevations, 2)
+	assert.InDelta(t, 500, elevations[0], 0.05)
+	assert.InDelta(t, 1523.7, elevations[1], 0.05)
+	assert.Equal(t, 1, fetched, "points on one tile fetch it once")
+}
+
+func TestMapboxTerrain_Errors(t *testing.T) {
+	points := []Point{{Longitude: -121.14, Latitude: 44.37}}
+
+	t.Run("no data", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		t.Cleanup(server.Close)
+		terrain := NewMapboxTerrain("token")
+		terrain.baseURL = server.URL
+
+		_, err := terrain.Elevations(context.Background(), points)
+		assert.ErrorIs(t, err, ErrNoData)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		_, err := NewMapboxTerrain("").Elevations(context.Background(), points)
+		assert.Error(t, err)
+	})
+}
+
+func TestZoomFor(t *testing.T) {
+	near := []Point{{Longitude: -121.14, Latitude: 44.37}, {Longitude: -121.13, Latitude: 44.37}}
+	assert.Equal(t, maxZoom, zoomFor(near))
+
+	// A route across the country would need hundreds of tiles at full detail
+	var far []Point
+	for lng := -124.0; lng <= -70; lng += 0.5 {
+		far = append(far, Point{Longitude: lng, Latitude: 40})
+	}
+	zoom := zoomFor(far)
+	assert.Less(t, zoom, maxZoom)
+	assert.GreaterOrEqual(t, zoom, minZoom)
+}
@@ -0,0 +1,121 @@
+// Package email sends the transactional emails of the API, such as password
+// resets and address verification.
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// ErrInvalidMessage is returned for messages that cannot be sent as they are
+var ErrInvalidMessage = errors.New("invalid email message")
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers emails
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NoOpSender is used when no mail server is configured. It logs messages
+// instead of sending them, with their bodies only when LogBody is set, so
+// links in them can be followed in development.
+type NoOpSender struct {
+	LogBody bool
+}
+
+func (s NoOpSender) Send(ctx context.Context, msg Message) error {
+	if s.LogBody {
+		log.Printf("email: not sending %q to %s:\n%s", msg.Subject, msg.To, msg.Body)
+		return nil
+	}
+	log.Printf("email: not sending %q to %s, no mail server is configured", msg.Subject, msg.To)
+	return nil
+}
+
+// SMTPSender sends emails through a mail server
+type SMTPSender struct {
+	addr     string
+	auth     smtp.Auth
+	from     string
+	envelope string // the address of from, for the SMTP conversation
+}
+
+// NewSMTPSender creates a sender for the mail server at host:port. From may
+// include a name, as in "newMap <no-reply@example.com>". Without a username,
+// mail is sent without authenticating.
+func NewSMTPSender(host string, port int, username, password, from string) *SMTPSender {
+	s := &SMTPSender{
+		addr:     net.JoinHostPort(host, fmt.Sprint(port)),
+		from:     from,
+		envelope: from,
+	}
+	if address, err := mail.ParseAddress(from); err == nil {
+		s.envelope = address.Address
+	}
+	if username != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	data, err := s.format(msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// net/smtp takes no context, so the send runs until it finishes even
+	// when ctx is done
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.addr, s.auth, s.envelope, []string{msg.To}, data)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// format writes the message with its headers. Header values cannot span
+// lines, so that they cannot add headers of their own.
+func (s *SMTPSender) format(msg Message, at time.Time) ([]byte, error) {
+	for _, value := range []string{s.from, msg.To, msg.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("%w: header has a line break", ErrInvalidMessage)
+		}
+	}
+	if msg.To == "" {
+		return nil, fmt.Errorf("%w: no recipient", ErrInvalidMessage)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", at.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String()), nil
+}
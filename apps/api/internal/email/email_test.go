@@ -0,0 +1,54 @@
+package email
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMTPSender_Format(t *testing.T) {
+	sender := NewSMTPSender("smtp.example.com", 587, "", "", "newMap <no-reply@example.com>")
+	at := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	assert.Equal(t, "no-reply@example.com", sender.envelope)
+
+	t.Run("headers and body", func(t *testing.T) {
+		data, err := sender.format(Message{
+			To:      "hiker@example.com",
+			Subject: "Reset your password",
+			Body:    "Hi,\nfollow the link.\n",
+		}, at)
+		require.NoError(t, err)
+
+		message := string(data)
+		assert.True(t, strings.HasPrefix(message, "From: newMap <no-reply@example.com>\r\nTo: hiker@example.com\r\nSubject: Reset your password\r\n"))
+		assert.Contains(t, message, "Date: Fri, 16 Oct 2026 09:30:00 +0000\r\n")
+		assert.True(t, strings.HasSuffix(message, "\r\n\r\nHi,\r\nfollow the link.\r\n"))
+	})
+
+	t.Run("non-ASCII subjects are encoded", func(t *testing.T) {
+		data, err := sender.format(Message{To: "hiker@example.com", Subject: "Café"}, at)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "Subject: =?utf-8?q?Caf=C3=A9?=\r\n")
+	})
+
+	t.Run("headers cannot be injected", func(t *testing.T) {
+		_, err := sender.format(Message{To: "hiker@example.com\r\nBcc: everyone@example.com", Subject: "Hi"}, at)
+		assert.ErrorIs(t, err, ErrInvalidMessage)
+
+		_, err = sender.format(Message{To: "hiker@example.com", Subject: "Hi\nBcc: everyone@example.com"}, at)
+		assert.ErrorIs(t, err, ErrInvalidMessage)
+	})
+
+	t.Run("recipient is required", func(t *testing.T) {
+		_, err := sender.format(Message{Subject: "Hi"}, at)
+		assert.ErrorIs(t, err, ErrInvalidMessage)
+	})
+}
+
+func TestNoOpSender(t *testing.T) {
+	assert.NoError(t, NoOpSender{}.Send(context.Background(), Message{To: "hiker@example.com", Subject: "Hi"}))
+}
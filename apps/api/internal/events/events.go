@@ -0,0 +1,135 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types
+const (
+	TripCreated              = "trip.created"
+	TripPublished            = "trip.published"
+	TripUpdated              = "trip.updated"
+	TripDeleted              = "trip.deleted"
+	TripCollaboratorsChanged = "trip.collaborators_changed"
+
+	// TripWaypointsChanged is sent when stops or bail-outs are added,
+	// changed, removed or reordered. Data holds the action and waypoint_id.
+	TripWaypointsChanged = "trip.waypoints_changed"
+
+	// ConditionVerified is sent when a condition report on the trip is
+	// verified. Data holds the condition_id and severity.
+	ConditionVerified = "trip.condition_verified"
+
+	// Sent about the trip or place a suggestion was made on
+	SuggestionCreated   = "suggestion.created"
+	SuggestionReviewed  = "suggestion.reviewed"
+	SuggestionCommented = "suggestion.commented"
+
+	// TripInvalidated asks every cache holding a trip to drop it
+	TripInvalidated = "trip.invalidated"
+
+	// PlacePermissionsChanged is sent when a change to a place, such as an
+	// ownership transfer or a delete, can change who may edit the places
+	// nested beneath it
+	PlacePermissionsChanged = "place.permissions_changed"
+
+	// What users search, view and complete, for the analytics export. The
+	// actor is the user; searches have no entity.
+	SearchPerformed = "search.performed"
+	TripViewed      = "trip.viewed"
+	PlaceViewed     = "place.viewed"
+	TripCompleted   = "trip.completed"
+
+	// Sent to the user they are about, whose ID is the entity ID
+	FriendRequestReceived = "user.friend_request_received"
+	FriendRequestAccepted = "user.friend_request_accepted"
+	ConditionAlerts       = "user.condition_alerts" // Verified condition reports a subscription matched
+)
+
+// Event describes something that happened to a domain entity
+type Event struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   string                 `json:"entity_id"`
+	ActorID    string                 `json:"actor_id,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// New creates an event with a fresh ID and timestamp
+func New(eventType, entityType, entityID, actorID string, data map[string]interface{}) Event {
+	return Event{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		EntityType: entityType,
+		EntityID:   entityID,
+		ActorID:    actorID,
+		Data:       data,
+		OccurredAt: time.Now(),
+	}
+}
+
+// Handler reacts to an event
+type Handler func(ctx context.Context, event Event) error
+
+// Bus delivers events to the subscribers of their type
+type Bus interface {
+	// Publish delivers an event to its subscribers
+	Publish(ctx context.Context, event Event) error
+
+	// Subscribe registers a handler for an event type. It runs once per
+	// event, on the instance that published it.
+	Subscribe(eventType string, handler Handler)
+
+	// SubscribeBroadcast registers a handler for an event type that runs on
+	// every instance, for state held in memory such as connected clients
+	SubscribeBroadcast(eventType string, handler Handler)
+}
+
+// LocalBus delivers events to subscribers in the same process
+type LocalBus struct {
+	mu          sync.RWMutex
+	subscribers map[string][]Handler
+}
+
+// NewLocalBus creates a new in-process event bus
+func NewLocalBus() *LocalBus {
+	return &LocalBus{
+		subscribers: make(map[string][]Handler),
+	}
+}
+
+// Subscribe registers a handler for an event type
+func (b *LocalBus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[eventType] = append(b.subscribers[eventType], handler)
+}
+
+// SubscribeBroadcast registers a handler for an event type. With a single
+// process every handler is a broadcast handler.
+func (b *LocalBus) SubscribeBroadcast(eventType string, handler Handler) {
+	b.Subscribe(eventType, handler)
+}
+
+// Publish calls every subscriber in turn. A failing subscriber is logged and
+// does not stop the others.
+func (b *LocalBus) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	handlers := b.subscribers[event.Type]
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			log.Printf("events: %s subscriber failed for %s %s: %v", event.Type, event.EntityType, event.EntityID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/Oferzz/newMap/apps/api/internal/database"
+	"github.com/google/uuid"
+)
+
+// broadcastChannel carries events between instances
+const broadcastChannel = "events:broadcast"
+
+// envelope is an event on the wire, tagged with the instance that sent it
+type envelope struct {
+	Origin string `json:"origin"`
+	Event  Event  `json:"event"`
+}
+
+// RedisBus delivers events across instances over Redis pub/sub. Handlers
+// registered with Subscribe run once on the publishing instance, as they do
+// with LocalBus. Broadcast handlers run on every instance.
+type RedisBus struct {
+	local      *LocalBus
+	broadcast  *LocalBus
+	redis      *database.RedisClient
+	instanceID string
+}
+
+// NewRedisBus creates a new event bus on top of Redis. Start must be called
+// to receive events from other instances.
+func NewRedisBus(redisClient *database.RedisClient) *RedisBus {
+	return &RedisBus{
+		local:      NewLocalBus(),
+		broadcast:  NewLocalBus(),
+		redis:      redisClient,
+		instanceID: uuid.New().String(),
+	}
+}
+
+// Subscribe registers a handler that runs on the publishing instance
+func (b *RedisBus) Subscribe(eventType string, handler Handler) {
+	b.local.Subscribe(eventType, handler)
+}
+
+// SubscribeBroadcast registers a handler that runs on every instance
+func (b *RedisBus) SubscribeBroadcast(eventType string, handler Handler) {
+	b.broadcast.Subscribe(eventType, handler)
+}
+
+// Publish delivers an event to this instance's subscribers, then sends it to
+// the other instances for their broadcast subscribers
+func (b *RedisBus) Publish(ctx context.Context, event Event) error {
+	b.local.Publish(ctx, event)
+	b.broadcast.Publish(ctx, event)
+
+	data, err := json.Marshal(envelope{Origin: b.instanceID, Event: event})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	if err := b.redis.Publish(ctx, broadcastChannel, data); err != nil {
+		return fmt.Errorf("failed to broadcast %s: %w", event.Type, err)
+	}
+	return nil
+}
+
+// Start receives events published by other instances until ctx is cancelled
+func (b *RedisBus) Start(ctx context.Context) {
+	pubsub := b.redis.Subscribe(ctx, broadcastChannel)
+
+	go func() {
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				b.receive(ctx, message.Payload)
+			}
+		}
+	}()
+}
+
+func (b *RedisBus) receive(ctx context.Context, payload string) {
+	var env envelope
+	if err := json.Unmarshal([]byte(payload), &env); err != nil {
+		log.Printf("events: dropping malformed broadcast: %v", err)
+		return
+	}
+
+	// Our own events were delivered when they were published
+	if env.Origin == b.instanceID {
+		return
+	}
+
+	b.broadcast.Publish(ctx, env.Event)
+}
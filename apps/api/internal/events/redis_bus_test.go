@@ -0,0 +1,39 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisBus_Receive(t *testing.T) {
+	bus := NewRedisBus(nil)
+
+	var once, broadcast []string
+	bus.Subscribe(TripUpdated, func(ctx context.Context, event Event) error {
+		once = append(once, event.EntityID)
+		return nil
+	})
+	bus.SubscribeBroadcast(TripUpdated, func(ctx context.Context, event Event) error {
+		broadcast = append(broadcast, event.EntityID)
+		return nil
+	})
+
+	send := func(origin, tripID string) {
+		data, err := json.Marshal(envelope{Origin: origin, Event: New(TripUpdated, "trip", tripID, "", nil)})
+		require.NoError(t, err)
+		bus.receive(context.Background(), string(data))
+	}
+
+	send("other-instance", "1")
+	send(bus.instanceID, "2")
+	bus.receive(context.Background(), "not json")
+
+	// Only broadcast handlers see other instances' events, and our own
+	// events are not delivered twice
+	assert.Empty(t, once)
+	assert.Equal(t, []string{"1"}, broadcast)
+}
@@ -0,0 +1,102 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/database"
+	"github.com/redis/go-redis/v9"
+)
+
+// notFoundTTL is how long a name that matched nothing is remembered. It is
+// shorter than the TTL of hits so that new places are picked up.
+const notFoundTTL = time.Hour
+
+// cachedResult is what the cache stores for a query. Found is false for
+// queries that matched nothing.
+type cachedResult struct {
+	Found  bool    `json:"found"`
+	Result *Result `json:"result,omitempty"`
+}
+
+// CachedGeocoder remembers results in Redis, since the same few names are
+// searched over and over and geocoding APIs are billed per request
+type CachedGeocoder struct {
+	next  Geocoder
+	redis *database.RedisClient
+	ttl   time.Duration
+}
+
+// NewCachedGeocoder caches the results of next for ttl
+func NewCachedGeocoder(next Geocoder, redisClient *database.RedisClient, ttl time.Duration) *CachedGeocoder {
+	return &CachedGeocoder{
+		next:  next,
+		redis: redisClient,
+		ttl:   ttl,
+	}
+}
+
+// Geocode returns the cached result, geocoding the query on a miss. Cache
+// failures fall through to the geocoder.
+func (g *CachedGeocoder) Geocode(ctx context.Context, query string) (*Result, error) {
+	key := database.BuildGeocodeCacheKey(Normalize(query))
+
+	var cached cachedResult
+	err := g.redis.GetJSON(ctx, key, &cached)
+	switch {
+	case err == nil:
+		if !cached.Found {
+			return nil, ErrNotFound
+		}
+		return cached.Result, nil
+	case !errors.Is(err, redis.Nil):
+		log.Printf("geocode: failed to read cached %q: %v", query, err)
+	}
+
+	result, err := g.next.Geocode(ctx, query)
+	switch {
+	case err == nil:
+		cached = cachedResult{Found: true, Result: result}
+		if err := g.redis.SetJSON(ctx, key, cached, g.ttl); err != nil {
+			log.Printf("geocode: failed to cache %q: %v", query, err)
+		}
+	case errors.Is(err, ErrNotFound):
+		if err := g.redis.SetJSON(ctx, key, cachedResult{}, notFoundTTL); err != nil {
+			log.Printf("geocode: failed to cache %q: %v", query, err)
+		}
+	}
+	return result, err
+}
+
+// Search returns cached matches, searching on a miss. Cache failures fall
+// through to the geocoder.
+func (g *CachedGeocoder) Search(ctx context.Context, query string, limit int) ([]*Result, error) {
+	key := database.BuildGeocodeCacheKey(fmt.Sprintf("search:%d:%s", limit, Normalize(query)))
+
+	var cached []*Result
+	err := g.redis.GetJSON(ctx, key, &cached)
+	switch {
+	case err == nil:
+		return cached, nil
+	case !errors.Is(err, redis.Nil):
+		log.Printf("geocode: failed to read cached search %q: %v", query, err)
+	}
+
+	results, err := g.next.Search(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Searches that found nothing are kept no longer than missed geocodes
+	ttl := g.ttl
+	if len(results) == 0 {
+		ttl = notFoundTTL
+	}
+	if err := g.redis.SetJSON(ctx, key, results, ttl); err != nil {
+		log.Printf("geocode: failed to cache search %q: %v", query, err)
+	}
+	return results, nil
+}
@@ -0,0 +1,51 @@
+package geocode
+
+import (
+	"log"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+)
+
+// NewFromConfig creates a geocoder asking the configured providers in
+// order, each within its rate limit. Providers that lack the settings they
+// need are skipped, and nil is returned when none is left.
+func NewFromConfig(cfg *config.GeocodeConfig) Geocoder {
+	var providers []Provider
+	for _, name := range cfg.Providers {
+		var geocoder Geocoder
+		var perMinute int
+		switch name {
+		case "mapbox":
+			if cfg.MapboxAPIKey == "" {
+				log.Println("geocode: skipping mapbox, no API key is configured")
+				continue
+			}
+			geocoder, perMinute = NewMapboxGeocoder(cfg.MapboxAPIKey), cfg.MapboxRatePerMin
+		case "nominatim":
+			geocoder, perMinute = NewNominatimGeocoder(cfg.NominatimURL, cfg.NominatimUserAgent, cfg.NominatimEmail), cfg.NominatimRatePerMin
+		case "pelias":
+			if cfg.PeliasURL == "" {
+				log.Println("geocode: skipping pelias, no URL is configured")
+				continue
+			}
+			geocoder, perMinute = NewPeliasGeocoder(cfg.PeliasURL, cfg.PeliasAPIKey), cfg.PeliasRatePerMin
+		default:
+			log.Printf("geocode: skipping unknown provider %q", name)
+			continue
+		}
+
+		if perMinute > 0 {
+			geocoder = NewRateLimitedGeocoder(geocoder, perMinute)
+		}
+		providers = append(providers, Provider{Name: name, Geocoder: geocoder})
+	}
+
+	switch len(providers) {
+	case 0:
+		return nil
+	case 1:
+		return providers[0].Geocoder
+	default:
+		return NewFailoverGeocoder(providers...)
+	}
+}
@@ -0,0 +1,63 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Provider is a geocoder with the name its failures are logged under
+type Provider struct {
+	Name     string
+	Geocoder Geocoder
+}
+
+// FailoverGeocoder asks its providers in order, moving on to the next when
+// one fails or is rate limited. A provider that finds nothing has answered:
+// asking the others as well would only cost more requests.
+type FailoverGeocoder struct {
+	providers []Provider
+}
+
+// NewFailoverGeocoder creates a geocoder trying the providers in order
+func NewFailoverGeocoder(providers ...Provider) *FailoverGeocoder {
+	return &FailoverGeocoder{providers: providers}
+}
+
+// Geocode returns the best match of the first provider that answers
+func (g *FailoverGeocoder) Geocode(ctx context.Context, query string) (*Result, error) {
+	return failover(ctx, g.providers, func(geocoder Geocoder) (*Result, error) {
+		return geocoder.Geocode(ctx, query)
+	})
+}
+
+// Search returns the matches of the first provider that answers
+func (g *FailoverGeocoder) Search(ctx context.Context, query string, limit int) ([]*Result, error) {
+	return failover(ctx, g.providers, func(geocoder Geocoder) ([]*Result, error) {
+		return geocoder.Search(ctx, query, limit)
+	})
+}
+
+func failover[T any](ctx context.Context, providers []Provider, call func(Geocoder) (T, error)) (T, error) {
+	var zero T
+	var errs []error
+	for i, provider := range providers {
+		result, err := call(provider.Geocoder)
+		if err == nil || errors.Is(err, ErrNotFound) {
+			return result, err
+		}
+		if ctx.Err() != nil {
+			return zero, ctx.Err()
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name, err))
+		if i < len(providers)-1 && !errors.Is(err, ErrRateLimited) {
+			log.Printf("geocode: %s failed, trying %s: %v", provider.Name, providers[i+1].Name, err)
+		}
+	}
+	if len(errs) == 0 {
+		return zero, errors.New("no geocoders configured")
+	}
+	return zero, fmt.Errorf("all geocoders failed: %w", errors.Join(errs...))
+}
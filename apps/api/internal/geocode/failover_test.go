@@ -0,0 +1,124 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubGeocoder answers every query the same way and counts the calls
+type stubGeocoder struct {
+	result *Result
+	err    error
+	calls  int
+}
+
+func (g *stubGeocoder) Geocode(ctx context.Context, query string) (*Result, error) {
+	g.calls++
+	return g.result, g.err
+}
+
+func (g *stubGeocoder) Search(ctx context.Context, query string, limit int) ([]*Result, error) {
+	g.calls++
+	if g.err != nil {
+		return nil, g.err
+	}
+	return []*Result{g.result}, nil
+}
+
+func TestFailoverGeocoder(t *testing.T) {
+	ctx := context.Background()
+	bend := &Result{Name: "Bend", Latitude: 44.0582, Longitude: -121.3153}
+
+	t.Run("fails over on errors", func(t *testing.T) {
+		down := &stubGeocoder{err: errors.New("mapbox API returned status 503")}
+		limited := &stubGeocoder{err: ErrRateLimited}
+		up := &stubGeocoder{result: bend}
+		geocoder := NewFailoverGeocoder(Provider{"mapbox", down}, Provider{"pelias", limited}, Provider{"nominatim", up})
+
+		result, err := geocoder.Geocode(ctx, "bend")
+		require.NoError(t, err)
+		assert.Equal(t, bend, result)
+
+		results, err := geocoder.Search(ctx, "bend", 5)
+		require.NoError(t, err)
+		assert.Equal(t, []*Result{bend}, results)
+		assert.Equal(t, 2, down.calls)
+		assert.Equal(t, 2, up.calls)
+	})
+
+	t.Run("a miss is an answer", func(t *testing.T) {
+		miss := &stubGeocoder{err: ErrNotFound}
+		next := &stubGeocoder{result: bend}
+		geocoder := NewFailoverGeocoder(Provider{"mapbox", miss}, Provider{"nominatim", next})
+
+		_, err := geocoder.Geocode(ctx, "atlantis")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Zero(t, next.calls)
+	})
+
+	t.Run("every provider failing", func(t *testing.T) {
+		geocoder := NewFailoverGeocoder(
+			Provider{"mapbox", &stubGeocoder{err: errors.New("timeout")}},
+			Provider{"nominatim", &stubGeocoder{err: ErrRateLimited}},
+		)
+
+		_, err := geocoder.Geocode(ctx, "bend")
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrRateLimited)
+		assert.NotErrorIs(t, err, ErrNotFound)
+		assert.Contains(t, err.Error(), "mapbox: timeout")
+	})
+}
+
+func TestRateLimitedGeocoder(t *testing.T) {
+	ctx := context.Background()
+	next := &stubGeocoder{result: &Result{Name: "Bend"}}
+	now := time.Unix(1714521600, 0)
+
+	// The public Nominatim instance allows a request a second
+	geocoder := NewRateLimitedGeocoder(next, 60)
+	geocoder.now = func() time.Time { return now }
+
+	_, err := geocoder.Geocode(ctx, "bend")
+	require.NoError(t, err)
+	_, err = geocoder.Search(ctx, "bend", 5)
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, 1, next.calls)
+
+	now = now.Add(time.Second)
+	_, err = geocoder.Geocode(ctx, "bend")
+	assert.NoError(t, err)
+
+	// Quiet time does not add up past the burst
+	now = now.Add(time.Minute)
+	_, err = geocoder.Geocode(ctx, "bend")
+	assert.NoError(t, err)
+	_, err = geocoder.Geocode(ctx, "bend")
+	assert.ErrorIs(t, err, ErrRateLimited)
+}
+
+func TestNewFromConfig(t *testing.T) {
+	assert.Nil(t, NewFromConfig(&config.GeocodeConfig{Providers: []string{"mapbox", "pelias"}}))
+
+	single := NewFromConfig(&config.GeocodeConfig{Providers: []string{"mapbox"}, MapboxAPIKey: "token"})
+	assert.IsType(t, &MapboxGeocoder{}, single)
+
+	chain := NewFromConfig(&config.GeocodeConfig{
+		Providers:           []string{"mapbox", "nominatim", "pelias"},
+		MapboxAPIKey:        "token",
+		NominatimRatePerMin: 60,
+		PeliasURL:           "http://pelias.internal:4000",
+	})
+	require.IsType(t, &FailoverGeocoder{}, chain)
+	providers := chain.(*FailoverGeocoder).providers
+	require.Len(t, providers, 3)
+	assert.Equal(t, "nominatim", providers[1].Name)
+	assert.IsType(t, &RateLimitedGeocoder{}, providers[1].Geocoder)
+	assert.IsType(t, &PeliasGeocoder{}, providers[2].Geocoder)
+}
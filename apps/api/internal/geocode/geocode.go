@@ -0,0 +1,68 @@
+// Package geocode resolves place names to coordinates
+package geocode
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrNotFound = errors.New("location not found")
+
+	// ErrRateLimited is returned without calling a provider that has used up
+	// its request budget, so the next provider can be tried
+	ErrRateLimited = errors.New("geocoder rate limit reached")
+)
+
+// Kinds of result, the same whichever provider found them
+const (
+	KindPOI     = "poi"
+	KindAddress = "address"
+	KindPlace   = "place" // cities, towns and neighborhoods
+	KindRegion  = "region"
+	KindCountry = "country"
+)
+
+// Result is where a place name resolved to
+type Result struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// BBox is [minLng, minLat, maxLng, maxLat] for places with an extent,
+	// such as cities and regions, and nil for points
+	BBox []float64 `json:"bbox,omitempty"`
+
+	// ID is the provider's ID for the place, prefixed with the provider
+	ID string `json:"id,omitempty"`
+	// Text is the place's own name, where Name is its full label
+	Text    string  `json:"text,omitempty"`
+	Kind    string  `json:"kind,omitempty"`
+	Address Address `json:"address"`
+}
+
+// Address is the postal address of a result, as far as the provider knows it
+type Address struct {
+	Street     string `json:"street,omitempty"`
+	City       string `json:"city,omitempty"`
+	State      string `json:"state,omitempty"`
+	Country    string `json:"country,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+}
+
+// Geocoder resolves free-form place names. Implementations differ in
+// provider but not in results, which are normalized to Result.
+type Geocoder interface {
+	// Geocode returns the best matching area, such as a city or region, for
+	// a search location. It returns ErrNotFound when nothing matches.
+	Geocode(ctx context.Context, query string) (*Result, error)
+
+	// Search returns up to limit places of any kind matching the query,
+	// best first. No matches is an empty result, not an error.
+	Search(ctx context.Context, query string, limit int) ([]*Result, error)
+}
+
+// Normalize folds the spellings of a query that geocode to the same place
+func Normalize(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
@@ -0,0 +1,167 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/mapbox"
+)
+
+const mapboxGeocodingAPI = "https://api.mapbox.com/geocoding/v5/mapbox.places"
+
+// mapboxTypes are the feature types a search location may name. Addresses
+// and POIs are left to the places search.
+const mapboxTypes = "country,region,district,place,locality,neighborhood"
+
+// mapboxSearchTypes are the feature types places are searched among
+const mapboxSearchTypes = "poi,address,place,locality,neighborhood"
+
+// MapboxGeocoder geocodes with the Mapbox Geocoding API
+type MapboxGeocoder struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewMapboxGeocoder creates a Mapbox geocoder
+func NewMapboxGeocoder(apiKey string) *MapboxGeocoder {
+	return &MapboxGeocoder{
+		apiKey:  apiKey,
+		baseURL: mapboxGeocodingAPI,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+type mapboxFeature struct {
+	ID        string    `json:"id"` // the feature type, a dot and a number
+	Text      string    `json:"text"`
+	PlaceName string    `json:"place_name"`
+	Center    []float64 `json:"center"` // [longitude, latitude]
+	BBox      []float64 `json:"bbox"`
+	Context   []struct {
+		ID   string `json:"id"`
+		Text string `json:"text"`
+	} `json:"context"`
+}
+
+type mapboxResponse struct {
+	Features []mapboxFeature `json:"features"`
+}
+
+// Geocode returns Mapbox's best match for the query
+func (g *MapboxGeocoder) Geocode(ctx context.Context, query string) (*Result, error) {
+	results, err := g.request(ctx, query, 1, mapboxTypes)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+	return results[0], nil
+}
+
+// Search returns Mapbox's matches for the query
+func (g *MapboxGeocoder) Search(ctx context.Context, query string, limit int) ([]*Result, error) {
+	return g.request(ctx, query, limit, mapboxSearchTypes)
+}
+
+func (g *MapboxGeocoder) request(ctx context.Context, query string, limit int, types string) ([]*Result, error) {
+	if g.apiKey == "" {
+		return nil, fmt.Errorf("mapbox API key not configured")
+	}
+
+	params := url.Values{}
+	params.Set("access_token", g.apiKey)
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("types", types)
+	endpoint := fmt.Sprintf("%s/%s.json?%s", g.baseURL, url.PathEscape(query), params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to geocode %q: %w", query, mapbox.StripURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("mapbox: %w", ErrRateLimited)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("mapbox API returned status %d", resp.StatusCode)
+	}
+
+	var body mapboxResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	results := make([]*Result, 0, len(body.Features))
+	for _, feature := range body.Features {
+		if len(feature.Center) < 2 {
+			continue
+		}
+		results = append(results, feature.result())
+	}
+	return results, nil
+}
+
+// result normalizes a Mapbox feature
+func (f mapboxFeature) result() *Result {
+	featureType, _, _ := strings.Cut(f.ID, ".")
+	result := &Result{
+		ID:        "mapbox:" + f.ID,
+		Name:      f.PlaceName,
+		Text:      f.Text,
+		Kind:      mapboxKind(featureType),
+		Longitude: f.Center[0],
+		Latitude:  f.Center[1],
+	}
+	if len(f.BBox) == 4 {
+		result.BBox = f.BBox
+	}
+
+	if featureType == "address" {
+		result.Address.Street = f.Text
+	}
+	for _, c := range f.Context {
+		contextType, _, _ := strings.Cut(c.ID, ".")
+		switch contextType {
+		case "place":
+			result.Address.City = c.Text
+		case "region":
+			result.Address.State = c.Text
+		case "country":
+			result.Address.Country = c.Text
+		case "postcode":
+			result.Address.PostalCode = c.Text
+		}
+	}
+	return result
+}
+
+func mapboxKind(featureType string) string {
+	switch featureType {
+	case "poi":
+		return KindPOI
+	case "address":
+		return KindAddress
+	case "region", "district":
+		return KindRegion
+	case "country":
+		return KindCountry
+	default:
+		return KindPlace
+	}
+}
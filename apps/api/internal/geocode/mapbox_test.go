@@ -0,0 +1,94 @@
+package geocode
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMapbox(t *testing.T, handler http.HandlerFunc) *MapboxGeocoder {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	geocoder := NewMapboxGeocoder("token")
+	geocoder.baseURL = server.URL
+	return geocoder
+}
+
+func TestMapboxGeocoder_Geocode(t *testing.T) {
+	geocoder := testMapbox(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/bend oregon.json", r.URL.Path)
+		assert.Equal(t, "token", r.URL.Query().Get("access_token"))
+		assert.Equal(t, "1", r.URL.Query().Get("limit"))
+		w.Write([]byte(`{"features": [{
+			"id": "place.123",
+			"text": "Bend",
+			"place_name": "Bend, Oregon, United States",
+			"center": [-121.3153, 44.0582],
+			"bbox": [-121.38, 43.99, -121.25, 44.13],
+			"context": [{"id": "region.1", "text": "Oregon"}, {"id": "country.2", "text": "United States"}]
+		}]}`))
+	})
+
+	result, err := geocoder.Geocode(context.Background(), "bend oregon")
+	require.NoError(t, err)
+	assert.Equal(t, &Result{
+		Name:      "Bend, Oregon, United States",
+		Latitude:  44.0582,
+		Longitude: -121.3153,
+		BBox:      []float64{-121.38, 43.99, -121.25, 44.13},
+		ID:        "mapbox:place.123",
+		Text:      "Bend",
+		Kind:      KindPlace,
+		Address:   Address{State: "Oregon", Country: "United States"},
+	}, result)
+}
+
+func TestMapboxGeocoder_Search(t *testing.T) {
+	geocoder := testMapbox(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "5", r.URL.Query().Get("limit"))
+		assert.Contains(t, r.URL.Query().Get("types"), "poi")
+		w.Write([]byte(`{"features": [
+			{"id": "poi.1", "text": "Smith Rock", "place_name": "Smith Rock, Terrebonne, Oregon", "center": [-121.14, 44.36],
+			 "context": [{"id": "place.3", "text": "Terrebonne"}, {"id": "postcode.4", "text": "97760"}]},
+			{"id": "address.2", "text": "Main Street", "place_name": "Main Street, Bend, Oregon", "center": [-121.31, 44.05]},
+			{"id": "poi.5", "text": "Nowhere"}
+		]}`))
+	})
+
+	results, err := geocoder.Search(context.Background(), "smith rock", 5)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, KindPOI, results[0].Kind)
+	assert.Equal(t, Address{City: "Terrebonne", PostalCode: "97760"}, results[0].Address)
+	assert.Equal(t, KindAddress, results[1].Kind)
+	assert.Equal(t, "Main Street", results[1].Address.Street)
+}
+
+func TestMapboxGeocoder_NotFound(t *testing.T) {
+	geocoder := testMapbox(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"features": []}`))
+	})
+
+	_, err := geocoder.Geocode(context.Background(), "nowhere")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMapboxGeocoder_Error(t *testing.T) {
+	geocoder := testMapbox(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	_, err := geocoder.Geocode(context.Background(), "bend")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotFound)
+	assert.NotContains(t, err.Error(), "token")
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "bend oregon", Normalize("  Bend   Oregon "))
+}
@@ -0,0 +1,192 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const nominatimAPI = "https://nominatim.openstreetmap.org"
+
+// NominatimGeocoder geocodes with Nominatim, OpenStreetMap's geocoder. The
+// public instance allows one request a second and asks for an identifying
+// user agent and contact email.
+type NominatimGeocoder struct {
+	baseURL    string
+	userAgent  string
+	email      string
+	httpClient *http.Client
+}
+
+// NewNominatimGeocoder creates a Nominatim geocoder for the instance at
+// baseURL, the public one when empty
+func NewNominatimGeocoder(baseURL, userAgent, email string) *NominatimGeocoder {
+	if baseURL == "" {
+		baseURL = nominatimAPI
+	}
+	return &NominatimGeocoder{
+		baseURL:   baseURL,
+		userAgent: userAgent,
+		email:     email,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+type nominatimPlace struct {
+	PlaceID     int64    `json:"place_id"`
+	Lat         string   `json:"lat"`
+	Lon         string   `json:"lon"`
+	Name        string   `json:"name"`
+	DisplayName string   `json:"display_name"`
+	Category    string   `json:"category"`
+	AddressType string   `json:"addresstype"`
+	BoundingBox []string `json:"boundingbox"` // [minLat, maxLat, minLng, maxLng]
+	Address     struct {
+		HouseNumber string `json:"house_number"`
+		Road        string `json:"road"`
+		City        string `json:"city"`
+		Town        string `json:"town"`
+		Village     string `json:"village"`
+		State       string `json:"state"`
+		Country     string `json:"country"`
+		Postcode    string `json:"postcode"`
+	} `json:"address"`
+}
+
+// Geocode returns Nominatim's best match for the query
+func (g *NominatimGeocoder) Geocode(ctx context.Context, query string) (*Result, error) {
+	results, err := g.Search(ctx, query, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+	return results[0], nil
+}
+
+// Search returns Nominatim's matches for the query
+func (g *NominatimGeocoder) Search(ctx context.Context, query string, limit int) ([]*Result, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "jsonv2")
+	params.Set("addressdetails", "1")
+	params.Set("limit", strconv.Itoa(limit))
+	if g.email != "" {
+		params.Set("email", g.email)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.baseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if g.userAgent != "" {
+		req.Header.Set("User-Agent", g.userAgent)
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to geocode %q: %w", query, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("nominatim: %w", ErrRateLimited)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("nominatim API returned status %d", resp.StatusCode)
+	}
+
+	var places []nominatimPlace
+	if err := json.NewDecoder(resp.Body).Decode(&places); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	results := make([]*Result, 0, len(places))
+	for _, place := range places {
+		if result, ok := place.result(); ok {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// result normalizes a Nominatim place. Nominatim sends coordinates as
+// strings, and places whose coordinates do not parse are skipped.
+func (p nominatimPlace) result() (*Result, bool) {
+	lat, err := strconv.ParseFloat(p.Lat, 64)
+	if err != nil {
+		return nil, false
+	}
+	lng, err := strconv.ParseFloat(p.Lon, 64)
+	if err != nil {
+		return nil, false
+	}
+
+	result := &Result{
+		ID:        "nominatim:" + strconv.FormatInt(p.PlaceID, 10),
+		Name:      p.DisplayName,
+		Text:      p.Name,
+		Kind:      nominatimKind(p.Category, p.AddressType),
+		Latitude:  lat,
+		Longitude: lng,
+	}
+	if result.Text == "" {
+		result.Text = p.DisplayName
+	}
+
+	// Points come with a box around them too
+	if result.Kind != KindPOI && result.Kind != KindAddress && len(p.BoundingBox) == 4 {
+		bbox := make([]float64, 4)
+		for i, j := range []int{2, 0, 3, 1} {
+			if bbox[i], err = strconv.ParseFloat(p.BoundingBox[j], 64); err != nil {
+				bbox = nil
+				break
+			}
+		}
+		result.BBox = bbox
+	}
+
+	address := p.Address
+	result.Address = Address{
+		State:      address.State,
+		Country:    address.Country,
+		PostalCode: address.Postcode,
+	}
+	if address.Road != "" {
+		result.Address.Street = address.Road
+		if address.HouseNumber != "" {
+			result.Address.Street = address.HouseNumber + " " + address.Road
+		}
+	}
+	for _, city := range []string{address.City, address.Town, address.Village} {
+		if city != "" {
+			result.Address.City = city
+			break
+		}
+	}
+	return result, true
+}
+
+func nominatimKind(category, addressType string) string {
+	switch addressType {
+	case "country":
+		return KindCountry
+	case "state", "region", "province", "county", "state_district":
+		return KindRegion
+	case "city", "town", "village", "hamlet", "municipality", "suburb", "neighbourhood", "quarter":
+		return KindPlace
+	case "road", "house", "building":
+		return KindAddress
+	}
+	if category == "boundary" || category == "place" {
+		return KindPlace
+	}
+	return KindPOI
+}
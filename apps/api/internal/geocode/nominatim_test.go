@@ -0,0 +1,74 @@
+package geocode
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNominatimGeocoder_Search(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/search", r.URL.Path)
+		assert.Equal(t, "bend", r.URL.Query().Get("q"))
+		assert.Equal(t, "jsonv2", r.URL.Query().Get("format"))
+		assert.Equal(t, "ops@example.com", r.URL.Query().Get("email"))
+		assert.Equal(t, "newMap-test", r.Header.Get("User-Agent"))
+		w.Write([]byte(`[
+			{"place_id": 42, "lat": "44.0582", "lon": "-121.3153", "name": "Bend",
+			 "display_name": "Bend, Deschutes County, Oregon, United States",
+			 "category": "boundary", "addresstype": "city",
+			 "boundingbox": ["43.99", "44.13", "-121.38", "-121.25"],
+			 "address": {"city": "Bend", "state": "Oregon", "country": "United States"}},
+			{"place_id": 43, "lat": "44.06", "lon": "-121.31", "name": "",
+			 "display_name": "12, Wall Street, Bend", "category": "building", "addresstype": "building",
+			 "boundingbox": ["44.05", "44.07", "-121.32", "-121.30"],
+			 "address": {"house_number": "12", "road": "Wall Street", "town": "Bend", "postcode": "97701"}},
+			{"place_id": 44, "lat": "north", "lon": "-121"}
+		]`))
+	}))
+	defer server.Close()
+
+	geocoder := NewNominatimGeocoder(server.URL, "newMap-test", "ops@example.com")
+	results, err := geocoder.Search(context.Background(), "bend", 5)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, &Result{
+		Name:      "Bend, Deschutes County, Oregon, United States",
+		Latitude:  44.0582,
+		Longitude: -121.3153,
+		BBox:      []float64{-121.38, 43.99, -121.25, 44.13},
+		ID:        "nominatim:42",
+		Text:      "Bend",
+		Kind:      KindPlace,
+		Address:   Address{City: "Bend", State: "Oregon", Country: "United States"},
+	}, results[0])
+
+	assert.Equal(t, KindAddress, results[1].Kind)
+	assert.Nil(t, results[1].BBox)
+	assert.Equal(t, "12, Wall Street, Bend", results[1].Text)
+	assert.Equal(t, Address{Street: "12 Wall Street", City: "Bend", PostalCode: "97701"}, results[1].Address)
+}
+
+func TestNominatimGeocoder_Geocode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("q") {
+		case "busy":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	geocoder := NewNominatimGeocoder(server.URL, "", "")
+	_, err := geocoder.Geocode(context.Background(), "nowhere")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = geocoder.Geocode(context.Background(), "busy")
+	assert.ErrorIs(t, err, ErrRateLimited)
+}
@@ -0,0 +1,154 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// peliasCoarseLayers are the layers a search location may name
+const peliasCoarseLayers = "coarse"
+
+// PeliasGeocoder geocodes with a Pelias instance, self-hosted or hosted
+type PeliasGeocoder struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewPeliasGeocoder creates a geocoder for the Pelias instance at baseURL.
+// The API key is only sent when set, as self-hosted instances take none.
+func NewPeliasGeocoder(baseURL, apiKey string) *PeliasGeocoder {
+	return &PeliasGeocoder{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+type peliasResponse struct {
+	Features []struct {
+		Geometry struct {
+			Coordinates []float64 `json:"coordinates"` // [longitude, latitude]
+		} `json:"geometry"`
+		Properties struct {
+			GID         string `json:"gid"`
+			Layer       string `json:"layer"`
+			Name        string `json:"name"`
+			Label       string `json:"label"`
+			HouseNumber string `json:"housenumber"`
+			Street      string `json:"street"`
+			Locality    string `json:"locality"`
+			Region      string `json:"region"`
+			Country     string `json:"country"`
+			PostalCode  string `json:"postalcode"`
+		} `json:"properties"`
+		BBox []float64 `json:"bbox"`
+	} `json:"features"`
+}
+
+// Geocode returns Pelias's best match for the query
+func (g *PeliasGeocoder) Geocode(ctx context.Context, query string) (*Result, error) {
+	results, err := g.request(ctx, query, 1, peliasCoarseLayers)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+	return results[0], nil
+}
+
+// Search returns Pelias's matches for the query
+func (g *PeliasGeocoder) Search(ctx context.Context, query string, limit int) ([]*Result, error) {
+	return g.request(ctx, query, limit, "")
+}
+
+func (g *PeliasGeocoder) request(ctx context.Context, query string, limit int, layers string) ([]*Result, error) {
+	params := url.Values{}
+	params.Set("text", query)
+	params.Set("size", strconv.Itoa(limit))
+	if layers != "" {
+		params.Set("layers", layers)
+	}
+	if g.apiKey != "" {
+		params.Set("api_key", g.apiKey)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.baseURL+"/v1/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		// The error includes the URL, and with it the API key
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to geocode %q: %w", query, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("pelias: %w", ErrRateLimited)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("pelias API returned status %d", resp.StatusCode)
+	}
+
+	var body peliasResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	results := make([]*Result, 0, len(body.Features))
+	for _, feature := range body.Features {
+		if len(feature.Geometry.Coordinates) < 2 {
+			continue
+		}
+		props := feature.Properties
+		result := &Result{
+			ID:        "pelias:" + props.GID,
+			Name:      props.Label,
+			Text:      props.Name,
+			Kind:      peliasKind(props.Layer),
+			Longitude: feature.Geometry.Coordinates[0],
+			Latitude:  feature.Geometry.Coordinates[1],
+			Address: Address{
+				Street:     strings.TrimSpace(props.HouseNumber + " " + props.Street),
+				City:       props.Locality,
+				State:      props.Region,
+				Country:    props.Country,
+				PostalCode: props.PostalCode,
+			},
+		}
+		if len(feature.BBox) == 4 {
+			result.BBox = feature.BBox
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func peliasKind(layer string) string {
+	switch layer {
+	case "venue":
+		return KindPOI
+	case "address", "street":
+		return KindAddress
+	case "country", "dependency":
+		return KindCountry
+	case "region", "macroregion", "county", "macrocounty":
+		return KindRegion
+	default:
+		return KindPlace
+	}
+}
@@ -0,0 +1,55 @@
+package geocode
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeliasGeocoder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/search", r.URL.Path)
+		assert.Equal(t, "key", r.URL.Query().Get("api_key"))
+		if r.URL.Query().Get("layers") != "coarse" {
+			w.Write([]byte(`{"features": [{
+				"geometry": {"coordinates": [-121.14, 44.36]},
+				"properties": {"gid": "openstreetmap:venue:node/1", "layer": "venue", "name": "Smith Rock",
+					"label": "Smith Rock, Terrebonne, OR, USA", "locality": "Terrebonne", "region": "Oregon",
+					"country": "United States", "postalcode": "97760"}
+			}]}`))
+			return
+		}
+		w.Write([]byte(`{"features": [{
+			"geometry": {"coordinates": [-121.3153, 44.0582]},
+			"properties": {"gid": "whosonfirst:locality:101", "layer": "locality", "name": "Bend",
+				"label": "Bend, OR, USA", "region": "Oregon", "country": "United States"},
+			"bbox": [-121.38, 43.99, -121.25, 44.13]
+		}]}`))
+	}))
+	defer server.Close()
+
+	geocoder := NewPeliasGeocoder(server.URL+"/", "key")
+
+	result, err := geocoder.Geocode(context.Background(), "bend")
+	require.NoError(t, err)
+	assert.Equal(t, &Result{
+		Name:      "Bend, OR, USA",
+		Latitude:  44.0582,
+		Longitude: -121.3153,
+		BBox:      []float64{-121.38, 43.99, -121.25, 44.13},
+		ID:        "pelias:whosonfirst:locality:101",
+		Text:      "Bend",
+		Kind:      KindPlace,
+		Address:   Address{State: "Oregon", Country: "United States"},
+	}, result)
+
+	results, err := geocoder.Search(context.Background(), "smith rock", 5)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, KindPOI, results[0].Kind)
+	assert.Equal(t, Address{City: "Terrebonne", State: "Oregon", Country: "United States", PostalCode: "97760"}, results[0].Address)
+}
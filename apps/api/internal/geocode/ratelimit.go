@@ -0,0 +1,74 @@
+package geocode
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimitedGeocoder keeps a provider within its request quota. Requests
+// over it fail with ErrRateLimited rather than wait, so that a failover can
+// ask the next provider straight away.
+type RateLimitedGeocoder struct {
+	next Geocoder
+
+	mu       sync.Mutex
+	interval time.Duration // between tokens
+	burst    float64
+	tokens   float64
+	last     time.Time
+	now      func() time.Time
+}
+
+// NewRateLimitedGeocoder allows next perMinute requests a minute, in bursts
+// of up to a second's worth
+func NewRateLimitedGeocoder(next Geocoder, perMinute int) *RateLimitedGeocoder {
+	burst := float64(perMinute) / 60
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimitedGeocoder{
+		next:     next,
+		interval: time.Minute / time.Duration(perMinute),
+		burst:    burst,
+		tokens:   burst,
+		now:      time.Now,
+	}
+}
+
+// Geocode geocodes the query if the quota allows
+func (g *RateLimitedGeocoder) Geocode(ctx context.Context, query string) (*Result, error) {
+	if !g.allow() {
+		return nil, ErrRateLimited
+	}
+	return g.next.Geocode(ctx, query)
+}
+
+// Search searches for the query if the quota allows
+func (g *RateLimitedGeocoder) Search(ctx context.Context, query string, limit int) ([]*Result, error) {
+	if !g.allow() {
+		return nil, ErrRateLimited
+	}
+	return g.next.Search(ctx, query, limit)
+}
+
+// allow takes a token from the bucket, which refills at the quota's rate
+func (g *RateLimitedGeocoder) allow() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	if !g.last.IsZero() {
+		g.tokens += float64(now.Sub(g.last)) / float64(g.interval)
+		if g.tokens > g.burst {
+			g.tokens = g.burst
+		}
+	}
+	g.last = now
+
+	if g.tokens < 1 {
+		return false
+	}
+	g.tokens--
+	return true
+}
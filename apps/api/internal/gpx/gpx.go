@@ -0,0 +1,180 @@
+// Package gpx reads the tracks recorded in GPX files, measures them, and
+// writes tracks and waypoints out as GPX
+package gpx
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+)
+
+// EarthRadiusM is the mean radius of the earth, in metres
+const EarthRadiusM = 6371008.8
+
+// climbThreshold is how far the elevation has to move before it counts
+// towards the climb, in metres, so that the jitter of GPS and barometric
+// readings is not summed into it
+const climbThreshold = 3.0
+
+var (
+	// ErrInvalid is a file that is not GPX or holds an impossible position
+	ErrInvalid = errors.New("file is not valid GPX")
+
+	// ErrNoTrack is a GPX file without a track or route of two points or more
+	ErrNoTrack = errors.New("GPX file has no track or route of at least two points")
+)
+
+// Point is a position on a track. Elevation is in metres and nil when the
+// file does not record it.
+type Point struct {
+	Lat float64
+	Lon float64
+	Ele *float64
+}
+
+// Track is the path a GPX file describes, as the segments it was recorded
+// in. A file with several tracks has the segments of all of them, and the
+// name of the first.
+type Track struct {
+	Name        string
+	Description string // Only written, not read
+	Segments    [][]Point
+}
+
+type document struct {
+	Tracks []struct {
+		Name     string `xml:"name"`
+		Segments []struct {
+			Points []point `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+	Routes []struct {
+		Name   string  `xml:"name"`
+		Points []point `xml:"rtept"`
+	} `xml:"rte"`
+}
+
+type point struct {
+	Lat float64  `xml:"lat,attr"`
+	Lon float64  `xml:"lon,attr"`
+	Ele *float64 `xml:"ele"`
+}
+
+// Parse reads the tracks of a GPX file. Routes are read instead when the
+// file has no tracks, as planning tools export routes. Segments of fewer
+// than two points are left out.
+func Parse(r io.Reader) (*Track, error) {
+	var doc document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, ErrInvalid
+	}
+
+	track := &Track{}
+	var lines [][]point
+	for _, trk := range doc.Tracks {
+		if track.Name == "" {
+			track.Name = trk.Name
+		}
+		for _, seg := range trk.Segments {
+			lines = append(lines, seg.Points)
+		}
+	}
+	if len(lines) == 0 {
+		for _, rte := range doc.Routes {
+			if track.Name == "" {
+				track.Name = rte.Name
+			}
+			lines = append(lines, rte.Points)
+		}
+	}
+
+	for _, line := range lines {
+		if len(line) < 2 {
+			continue
+		}
+
+		segment := make([]Point, len(line))
+		for i, p := range line {
+			if p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 {
+				return nil, ErrInvalid
+			}
+			segment[i] = Point{Lat: p.Lat, Lon: p.Lon, Ele: p.Ele}
+		}
+		track.Segments = append(track.Segments, segment)
+	}
+
+	if len(track.Segments) == 0 {
+		return nil, ErrNoTrack
+	}
+
+	return track, nil
+}
+
+// DistanceKm is the great-circle length of the track. The gaps between
+// segments, where the recording was paused, are not counted.
+func (t *Track) DistanceKm() float64 {
+	meters := 0.0
+	for _, segment := range t.Segments {
+		for i := 1; i < len(segment); i++ {
+			meters += Haversine(segment[i-1], segment[i])
+		}
+	}
+	return meters / 1000
+}
+
+// ElevationGainM is the total climb along the track, ignoring movements
+// smaller than a few metres. It is false when the track has no elevations.
+func (t *Track) ElevationGainM() (float64, bool) {
+	gain := 0.0
+	found := false
+	for _, segment := range t.Segments {
+		var ref *float64
+		for _, p := range segment {
+			if p.Ele == nil {
+				continue
+			}
+			found = true
+			if ref == nil {
+				ref = p.Ele
+				continue
+			}
+			switch {
+			case *p.Ele-*ref >= climbThreshold:
+				gain += *p.Ele - *ref
+				ref = p.Ele
+			case *ref-*p.Ele >= climbThreshold:
+				ref = p.Ele
+			}
+		}
+	}
+	return gain, found
+}
+
+// MaxElevationM is the highest elevation on the track. It is false when the
+// track has no elevations.
+func (t *Track) MaxElevationM() (float64, bool) {
+	highest := math.Inf(-1)
+	for _, segment := range t.Segments {
+		for _, p := range segment {
+			if p.Ele != nil && *p.Ele > highest {
+				highest = *p.Ele
+			}
+		}
+	}
+	return highest, !math.IsInf(highest, -1)
+}
+
+// Haversine is the great-circle distance between two points, in metres.
+// Elevations are ignored.
+func Haversine(from, to Point) float64 {
+	lat1, lat2 := radians(from.Lat), radians(to.Lat)
+	dLat := lat2 - lat1
+	dLon := radians(to.Lon - from.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusM * math.Asin(math.Sqrt(h))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
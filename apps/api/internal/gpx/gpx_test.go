@@ -0,0 +1,135 @@
+package gpx
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const trackFile = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk>
+    <name>Ridge</name>
+    <trkseg>
+      <trkpt lat="46.0" lon="7.0"><ele>1000</ele></trkpt>
+      <trkpt lat="46.01" lon="7.0"><ele>1001</ele></trkpt>
+      <trkpt lat="46.02" lon="7.0"><ele>1100</ele></trkpt>
+    </trkseg>
+    <trkseg>
+      <trkpt lat="46.03" lon="7.0"><ele>1050</ele></trkpt>
+      <trkpt lat="46.04" lon="7.0"><ele>1250</ele></trkpt>
+    </trkseg>
+    <trkseg>
+      <trkpt lat="47.0" lon="8.0"/>
+    </trkseg>
+  </trk>
+</gpx>`
+
+func TestParse_Track(t *testing.T) {
+	track, err := Parse(strings.NewReader(trackFile))
+	require.NoError(t, err)
+
+	// The single-point segment is left out
+	require.Len(t, track.Segments, 2)
+	assert.Equal(t, "Ridge", track.Name)
+	assert.Len(t, track.Segments[0], 3)
+	assert.Equal(t, 46.04, track.Segments[1][1].Lat)
+	require.NotNil(t, track.Segments[1][1].Ele)
+	assert.Equal(t, 1250.0, *track.Segments[1][1].Ele)
+
+	// A hundredth of a degree of latitude is about 1.11 km, and the gap
+	// between segments is not counted
+	assert.InDelta(t, 3*1.112, track.DistanceKm(), 0.01)
+
+	gain, ok := track.ElevationGainM()
+	assert.True(t, ok)
+	assert.Equal(t, 300.0, gain)
+
+	highest, ok := track.MaxElevationM()
+	assert.True(t, ok)
+	assert.Equal(t, 1250.0, highest)
+}
+
+func TestParse_Route(t *testing.T) {
+	route := `<gpx><rte><rtept lat="0" lon="0"/><rtept lat="0" lon="1"/></rte></gpx>`
+
+	track, err := Parse(strings.NewReader(route))
+	require.NoError(t, err)
+	require.Len(t, track.Segments, 1)
+	assert.InDelta(t, 111.2, track.DistanceKm(), 0.1)
+
+	_, ok := track.ElevationGainM()
+	assert.False(t, ok)
+	_, ok = track.MaxElevationM()
+	assert.False(t, ok)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		err  error
+	}{
+		{"not xml", "lat,lon\n1,2", ErrInvalid},
+		{"latitude out of range", `<gpx><trk><trkseg><trkpt lat="91" lon="0"/><trkpt lat="0" lon="0"/></trkseg></trk></gpx>`, ErrInvalid},
+		{"bad coordinate", `<gpx><trk><trkseg><trkpt lat="north" lon="0"/><trkpt lat="0" lon="0"/></trkseg></trk></gpx>`, ErrInvalid},
+		{"waypoints only", `<gpx><wpt lat="1" lon="2"/><wpt lat="1" lon="3"/></gpx>`, ErrNoTrack},
+		{"single point", `<gpx><trk><trkseg><trkpt lat="1" lon="2"/></trkseg></trk></gpx>`, ErrNoTrack},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.file))
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestElevationGain_IgnoresJitter(t *testing.T) {
+	elevations := []float64{100, 101, 100, 102, 101, 100, 110, 109, 111}
+	segment := make([]Point, len(elevations))
+	for i := range elevations {
+		segment[i] = Point{Ele: &elevations[i]}
+	}
+
+	gain, ok := (&Track{Segments: [][]Point{segment}}).ElevationGainM()
+	assert.True(t, ok)
+	// Only the climb from 100 to 110 counts
+	assert.Equal(t, 10.0, gain)
+}
+
+func TestHaversine(t *testing.T) {
+	// A degree of latitude, anywhere
+	assert.InDelta(t, EarthRadiusM*math.Pi/180, Haversine(Point{Lat: 46, Lon: 7}, Point{Lat: 47, Lon: 7}), 1e-6)
+	// A degree of longitude shrinks away from the equator
+	assert.InDelta(t, 55597.5, Haversine(Point{Lat: 60, Lon: 7}, Point{Lat: 60, Lon: 8}), 1)
+	assert.Zero(t, Haversine(Point{Lat: 46, Lon: 7}, Point{Lat: 46, Lon: 7}))
+}
+
+func TestWrite_RoundTrip(t *testing.T) {
+	ele := 1200.0
+	file := &File{
+		Name: "Ridge & valley",
+		Waypoints: []Waypoint{
+			{Point: Point{Lat: 46.5, Lon: 7.9}, Name: "Hut", Type: "stop"},
+		},
+		Tracks: []*Track{
+			{Name: "Main", Segments: [][]Point{{{Lat: 46.5, Lon: 7.9, Ele: &ele}, {Lat: 46.6, Lon: 8.0}}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, file))
+	assert.Contains(t, buf.String(), `<gpx version="1.1" creator="newMap" xmlns="http://www.topografix.com/GPX/1/1">`)
+	assert.Contains(t, buf.String(), `<wpt lat="46.5" lon="7.9">`)
+	assert.Contains(t, buf.String(), "<name>Ridge &amp; valley</name>")
+
+	track, err := Parse(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "Main", track.Name)
+	assert.Equal(t, file.Tracks[0].Segments, track.Segments)
+}
@@ -0,0 +1,96 @@
+package gpx
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// namespace is the GPX 1.1 schema that written files declare
+const namespace = "http://www.topografix.com/GPX/1/1"
+
+// Waypoint is a named position of a GPX file, apart from its tracks
+type Waypoint struct {
+	Point
+	Name        string
+	Description string
+	Type        string
+}
+
+// File is what a GPX file is written from: its waypoints and tracks, named
+// and described in its metadata
+type File struct {
+	Name        string
+	Description string
+	Waypoints   []Waypoint
+	Tracks      []*Track
+}
+
+type output struct {
+	XMLName   xml.Name         `xml:"gpx"`
+	Version   string           `xml:"version,attr"`
+	Creator   string           `xml:"creator,attr"`
+	Namespace string           `xml:"xmlns,attr"`
+	Metadata  *outputMetadata  `xml:"metadata,omitempty"`
+	Waypoints []outputWaypoint `xml:"wpt"`
+	Tracks    []outputTrack    `xml:"trk"`
+}
+
+type outputMetadata struct {
+	Name        string `xml:"name,omitempty"`
+	Description string `xml:"desc,omitempty"`
+}
+
+type outputWaypoint struct {
+	point
+	Name        string `xml:"name,omitempty"`
+	Description string `xml:"desc,omitempty"`
+	Type        string `xml:"type,omitempty"`
+}
+
+type outputTrack struct {
+	Name        string          `xml:"name,omitempty"`
+	Description string          `xml:"desc,omitempty"`
+	Segments    []outputSegment `xml:"trkseg"`
+}
+
+type outputSegment struct {
+	Points []point `xml:"trkpt"`
+}
+
+// Write writes a file as GPX 1.1
+func Write(w io.Writer, file *File) error {
+	doc := output{Version: "1.1", Creator: "newMap", Namespace: namespace}
+	if file.Name != "" || file.Description != "" {
+		doc.Metadata = &outputMetadata{Name: file.Name, Description: file.Description}
+	}
+
+	for _, wpt := range file.Waypoints {
+		doc.Waypoints = append(doc.Waypoints, outputWaypoint{
+			point:       point{Lat: wpt.Lat, Lon: wpt.Lon, Ele: wpt.Ele},
+			Name:        wpt.Name,
+			Description: wpt.Description,
+			Type:        wpt.Type,
+		})
+	}
+
+	for _, track := range file.Tracks {
+		trk := outputTrack{Name: track.Name, Description: track.Description, Segments: make([]outputSegment, len(track.Segments))}
+		for i, segment := range track.Segments {
+			for _, p := range segment {
+				trk.Segments[i].Points = append(trk.Segments[i].Points, point{Lat: p.Lat, Lon: p.Lon, Ele: p.Ele})
+			}
+		}
+		doc.Tracks = append(doc.Tracks, trk)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
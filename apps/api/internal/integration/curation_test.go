@@ -0,0 +1,68 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/curation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pinnedIDs(pins []curation.Pin) []string {
+	ids := make([]string, 0, len(pins))
+	for _, pin := range pins {
+		ids = append(ids, pin.EntityID)
+	}
+	return ids
+}
+
+func TestCuration_Match(t *testing.T) {
+	testDB.Reset(t, "users", "places", "trips")
+	service := curation.NewService(testDB.DB)
+	ctx := context.Background()
+	adminID := "00000000-0000-0000-0000-000000000001"
+
+	lastYear := time.Now().AddDate(-1, 0, 0)
+	lastMonth := time.Now().AddDate(0, -1, 0)
+	inputs := []curation.CreatePinInput{
+		// Any search located in Jerusalem
+		{EntityType: "place", EntityID: "10000000-0000-0000-0000-000000000001", Region: []float64{35.1, 31.7, 35.3, 31.9}, Position: 1},
+		// Only this query, anywhere
+		{EntityType: "trip", EntityID: "20000000-0000-0000-0000-000000000003", Query: "Beach  Days", Label: curation.LabelSponsored},
+		// This query in Tel Aviv
+		{EntityType: "trip", EntityID: "20000000-0000-0000-0000-000000000002", Query: "beach days", Region: []float64{34.7, 32.0, 34.9, 32.2}, Position: 2},
+		// Expired
+		{EntityType: "place", EntityID: "10000000-0000-0000-0000-000000000007", Query: "beach days", StartsAt: &lastYear, EndsAt: &lastMonth},
+	}
+	for i := range inputs {
+		_, err := service.Create(ctx, adminID, &inputs[i])
+		require.NoError(t, err)
+	}
+
+	all, err := service.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 4)
+	assert.InDeltaSlice(t, []float64{35.1, 31.7, 35.3, 31.9}, all[2].Region, 1e-9)
+
+	jerusalemLat, jerusalemLng := 31.7767, 35.2342
+	telAvivLat, telAvivLng := 32.0800, 34.7630
+
+	pins, err := service.Match(ctx, "beach days", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20000000-0000-0000-0000-000000000003"}, pinnedIDs(pins))
+
+	pins, err = service.Match(ctx, "BEACH days", &telAvivLat, &telAvivLng)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20000000-0000-0000-0000-000000000003", "20000000-0000-0000-0000-000000000002"}, pinnedIDs(pins))
+
+	pins, err = service.Match(ctx, "markets", &jerusalemLat, &jerusalemLng)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10000000-0000-0000-0000-000000000001"}, pinnedIDs(pins))
+
+	require.NoError(t, service.Delete(ctx, pins[0].ID))
+	assert.Equal(t, curation.ErrPinNotFound, service.Delete(ctx, pins[0].ID))
+}
@@ -0,0 +1,30 @@
+//go:build integration
+
+// Package integration runs the repositories against a real PostGIS database,
+// catching the SQL drift that mocked tests cannot. Run with:
+//
+//	go test -tags integration ./internal/integration/...
+package integration
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/testutil/pgtest"
+)
+
+var testDB *pgtest.DB
+
+func TestMain(m *testing.M) {
+	db, err := pgtest.Start()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	testDB = db
+
+	code := m.Run()
+	db.Close()
+	os.Exit(code)
+}
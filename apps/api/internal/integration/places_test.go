@@ -0,0 +1,258 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Around the Western Wall in Jerusalem's Old City
+const (
+	oldCityLat = 31.7767
+	oldCityLng = 35.2342
+)
+
+func placeNames(list []*places.Place) []string {
+	names := make([]string, 0, len(list))
+	for _, place := range list {
+		names = append(names, place.Name)
+	}
+	return names
+}
+
+func TestPlaces_GetNearby(t *testing.T) {
+	testDB.Reset(t, "users", "places")
+	repo := places.NewPostgresRepository(testDB.DB)
+
+	// The archived cafe is closer than the church but must not be returned
+	nearby, err := repo.GetNearby(context.Background(), oldCityLat, oldCityLng, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Western Wall", "Church of the Holy Sepulchre"}, placeNames(nearby))
+
+	nearby, err = repo.GetNearby(context.Background(), oldCityLat, oldCityLng, 5, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Western Wall", "Church of the Holy Sepulchre", "Mahane Yehuda Market"}, placeNames(nearby))
+
+	nearby, err = repo.GetNearby(context.Background(), oldCityLat, oldCityLng, 5, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Western Wall"}, placeNames(nearby))
+
+	require.NotNil(t, nearby[0].Location)
+	assert.InDelta(t, oldCityLng, nearby[0].Location.Coordinates[0], 1e-6)
+	assert.InDelta(t, oldCityLat, nearby[0].Location.Coordinates[1], 1e-6)
+}
+
+func TestPlaces_GetInBounds(t *testing.T) {
+	testDB.Reset(t, "users", "places")
+	repo := places.NewPostgresRepository(testDB.DB)
+
+	inBounds, err := repo.GetInBounds(context.Background(), places.Bounds{
+		MinLat: 32.0, MaxLat: 32.1,
+		MinLng: 34.7, MaxLng: 34.8,
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Jaffa Port", "Carmel Market"}, placeNames(inBounds))
+
+	inBounds, err = repo.GetInBounds(context.Background(), places.Bounds{
+		MinLat: 30.0, MaxLat: 31.0,
+		MinLng: 34.0, MaxLng: 35.0,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, inBounds)
+}
+
+func TestPlaces_SearchPlaces(t *testing.T) {
+	testDB.Reset(t, "users", "places")
+	repo := places.NewPostgresRepository(testDB.DB)
+
+	// Text matches both markets, the radius keeps only the one in Jerusalem
+	lat, lng, radius := oldCityLat, oldCityLng, 50000
+	found, err := repo.SearchPlaces(context.Background(), places.SearchPlacesInput{
+		Query:     "market",
+		Latitude:  &lat,
+		Longitude: &lng,
+		Radius:    &radius,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Mahane Yehuda Market"}, placeNames(found))
+
+	found, err = repo.SearchPlaces(context.Background(), places.SearchPlacesInput{
+		Tags:  []string{"beach"},
+		Limit: 10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Coral Beach"}, placeNames(found))
+}
+
+func TestPlaces_GetByIDs(t *testing.T) {
+	testDB.Reset(t, "users", "places")
+	repo := places.NewPostgresRepository(testDB.DB)
+
+	// The archived cafe and the unknown ID are skipped
+	found, err := repo.GetByIDs(context.Background(), []string{
+		"10000000-0000-0000-0000-000000000001",
+		"10000000-0000-0000-0000-000000000004",
+		"10000000-0000-0000-0000-000000000007",
+		"10000000-0000-0000-0000-0000000000ff",
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Western Wall", "Coral Beach"}, placeNames(found))
+	for _, place := range found {
+		assert.NotNil(t, place.Location)
+	}
+}
+
+func TestPlaces_SearchByAccessibility(t *testing.T) {
+	testDB.Reset(t, "users", "places")
+	repo := places.NewPostgresRepository(testDB.DB)
+	ctx := context.Background()
+
+	_, err := testDB.ExecContext(ctx, `
+		UPDATE places SET accessibility = CASE id
+			WHEN '10000000-0000-0000-0000-000000000001' THEN ARRAY['wheelchair_accessible', 'stroller_ok']
+			WHEN '10000000-0000-0000-0000-000000000003' THEN ARRAY['stroller_ok']
+		END
+		WHERE id IN ('10000000-0000-0000-0000-000000000001', '10000000-0000-0000-0000-000000000003')`)
+	require.NoError(t, err)
+
+	// Every attribute asked for must hold
+	found, err := repo.SearchPlaces(ctx, places.SearchPlacesInput{Accessibility: []string{"stroller_ok"}, Limit: 10})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Western Wall", "Mahane Yehuda Market"}, placeNames(found))
+
+	found, err = repo.SearchPlaces(ctx, places.SearchPlacesInput{Accessibility: []string{"stroller_ok", "wheelchair_accessible"}, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Western Wall"}, placeNames(found))
+	assert.ElementsMatch(t, []string{"wheelchair_accessible", "stroller_ok"}, []string(found[0].Accessibility))
+}
+
+func TestPlaces_WaterSource(t *testing.T) {
+	testDB.Reset(t, "users", "places")
+	repo := places.NewPostgresRepository(testDB.DB)
+	ctx := context.Background()
+	placeID := "10000000-0000-0000-0000-000000000001"
+
+	_, err := testDB.ExecContext(ctx, `UPDATE places SET category = ARRAY['water_source'] WHERE id = $1`, placeID)
+	require.NoError(t, err)
+
+	place, err := repo.GetByID(ctx, placeID)
+	require.NoError(t, err)
+	assert.Nil(t, place.WaterSource)
+
+	require.NoError(t, repo.SetWaterSource(ctx, placeID, &places.WaterSource{
+		Kind:              "spring",
+		Reliability:       places.WaterSeasonal,
+		FlowingMonths:     []int64{1, 2, 3},
+		TreatmentRequired: true,
+	}))
+
+	place, err = repo.GetByID(ctx, placeID)
+	require.NoError(t, err)
+	require.NotNil(t, place.WaterSource)
+	assert.Equal(t, "spring", place.WaterSource.Kind)
+	assert.Equal(t, places.WaterSeasonal, place.WaterSource.Reliability)
+	assert.Equal(t, []int64{1, 2, 3}, []int64(place.WaterSource.FlowingMonths))
+
+	err = repo.SetWaterSource(ctx, "10000000-0000-0000-0000-0000000000ff", &places.WaterSource{Kind: "tap", Reliability: places.WaterReliable})
+	assert.ErrorIs(t, err, places.ErrPlaceNotFound)
+
+	require.NoError(t, repo.ClearWaterSource(ctx, placeID))
+	place, err = repo.GetByID(ctx, placeID)
+	require.NoError(t, err)
+	assert.Nil(t, place.WaterSource)
+}
+
+func TestPlaces_CampgroundLink(t *testing.T) {
+	testDB.Reset(t, "users", "places")
+	repo := places.NewPostgresRepository(testDB.DB)
+	ctx := context.Background()
+	placeID := "10000000-0000-0000-0000-000000000001"
+
+	link := &places.CampgroundLink{Provider: "recreation_gov", FacilityID: "232447"}
+	require.NoError(t, repo.SetCampgroundLink(ctx, placeID, link))
+	assert.False(t, link.UpdatedAt.IsZero())
+
+	// Links are only loaded for campgrounds
+	place, err := repo.GetByID(ctx, placeID)
+	require.NoError(t, err)
+	assert.Nil(t, place.Campground)
+
+	_, err = testDB.ExecContext(ctx, `UPDATE places SET category = ARRAY['campground'] WHERE id = $1`, placeID)
+	require.NoError(t, err)
+	place, err = repo.GetByID(ctx, placeID)
+	require.NoError(t, err)
+	require.NotNil(t, place.Campground)
+	assert.Equal(t, "232447", place.Campground.FacilityID)
+
+	require.NoError(t, repo.ClearCampgroundLink(ctx, placeID))
+	place, err = repo.GetByID(ctx, placeID)
+	require.NoError(t, err)
+	assert.Nil(t, place.Campground)
+}
+
+func TestPlaces_Reviews(t *testing.T) {
+	testDB.Reset(t, "users", "places")
+	repo := places.NewPostgresRepository(testDB.DB)
+	ctx := context.Background()
+	placeID := "10000000-0000-0000-0000-000000000001"
+	danaID := "00000000-0000-0000-0000-000000000001"
+	omerID := "00000000-0000-0000-0000-000000000002"
+	photoID := "30000000-0000-0000-0000-000000000001"
+
+	_, err := testDB.ExecContext(ctx, `
+		INSERT INTO media (id, filename, original_name, mime_type, size_bytes, storage_path, uploaded_by)
+		VALUES ($1, 'wall.jpg', 'wall.jpg', 'image/jpeg', 1024, 'media/wall.jpg', $2)`, photoID, omerID)
+	require.NoError(t, err)
+
+	rating := func() (*float32, int) {
+		place, err := repo.GetByID(ctx, placeID)
+		require.NoError(t, err)
+		return place.AverageRating, place.RatingCount
+	}
+
+	// Photos have to be the reviewer's own uploads
+	err = repo.CreateReview(ctx, &places.PlaceReview{PlaceID: placeID, UserID: danaID, Rating: 5, MediaIDs: []string{photoID}})
+	assert.ErrorIs(t, err, places.ErrInvalidReviewMedia)
+
+	require.NoError(t, repo.CreateReview(ctx, &places.PlaceReview{PlaceID: placeID, UserID: omerID, Rating: 4, ReviewText: "Busy at noon", MediaIDs: []string{photoID}}))
+	require.NoError(t, repo.CreateReview(ctx, &places.PlaceReview{PlaceID: placeID, UserID: danaID, Rating: 5}))
+	err = repo.CreateReview(ctx, &places.PlaceReview{PlaceID: placeID, UserID: omerID, Rating: 1})
+	assert.ErrorIs(t, err, places.ErrReviewExists)
+
+	average, count := rating()
+	require.NotNil(t, average)
+	assert.InDelta(t, 4.5, *average, 0.001)
+	assert.Equal(t, 2, count)
+
+	reviews, err := repo.ListReviews(ctx, placeID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, reviews, 2)
+	assert.Equal(t, "Dana", reviews[0].DisplayName)
+	assert.Equal(t, []string{photoID}, []string(reviews[1].MediaIDs))
+
+	var uses int
+	require.NoError(t, testDB.GetContext(ctx, &uses, `SELECT COUNT(*) FROM media_usage WHERE media_id = $1`, photoID))
+	assert.Equal(t, 1, uses)
+
+	require.NoError(t, repo.DeleteReview(ctx, placeID, omerID))
+	assert.ErrorIs(t, repo.DeleteReview(ctx, placeID, omerID), places.ErrReviewNotFound)
+	require.NoError(t, testDB.GetContext(ctx, &uses, `SELECT COUNT(*) FROM media_usage WHERE media_id = $1`, photoID))
+	assert.Zero(t, uses)
+
+	average, count = rating()
+	require.NotNil(t, average)
+	assert.InDelta(t, 5, *average, 0.001)
+	assert.Equal(t, 1, count)
+
+	// The average is cleared with the last review
+	require.NoError(t, repo.DeleteReview(ctx, placeID, danaID))
+	average, count = rating()
+	assert.Nil(t, average)
+	assert.Zero(t, count)
+}
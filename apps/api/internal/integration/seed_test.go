@@ -0,0 +1,57 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/cache"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/seed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeed_Idempotent(t *testing.T) {
+	testDB.Reset(t)
+	ctx := context.Background()
+	seeder := seed.NewSeeder(testDB.DB, cache.NewNoOpCache())
+
+	counts := func() map[string]int {
+		result := make(map[string]int)
+		for _, table := range []string{"users", "places", "media", "media_usage", "trips", "trip_waypoints", "trip_collaborators", "collections", "collection_locations"} {
+			var count int
+			require.NoError(t, testDB.GetContext(ctx, &count, "SELECT COUNT(*) FROM "+table))
+			result[table] = count
+		}
+		return result
+	}
+
+	_, err := seeder.Run(ctx, seed.Demo())
+	require.NoError(t, err)
+	first := counts()
+	assert.Equal(t, len(seed.Demo().Trips), first["trips"])
+
+	// Edits made while using the demo are undone by seeding again
+	_, err = testDB.ExecContext(ctx, `UPDATE trips SET title = 'Renamed', deleted_at = now()`)
+	require.NoError(t, err)
+	_, err = testDB.ExecContext(ctx, `DELETE FROM trip_waypoints`)
+	require.NoError(t, err)
+
+	_, err = seeder.Run(ctx, seed.Demo())
+	require.NoError(t, err)
+	assert.Equal(t, first, counts())
+
+	// The seeded rows are readable through the repositories
+	listed, err := trips.NewPostgresRepository(testDB.DB).List(ctx, trips.TripFilters{
+		OwnerID:   seed.MayaID,
+		Limit:     10,
+		SortBy:    "title",
+		SortOrder: "ASC",
+		Relations: &trips.Relations{Collaborators: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Crater Lake Weekend", "Smith Rock Misery Ridge Loop"}, tripTitles(listed))
+	assert.Len(t, listed[0].Collaborators, 1)
+}
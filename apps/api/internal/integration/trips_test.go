@@ -0,0 +1,989 @@
+//go:build integration
+
+package integration
+
+import (
+	"archive/zip"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+	"github.com/Oferzz/newMap/apps/api/internal/media"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tripTitles(list []*trips.Trip) []string {
+	titles := make([]string, 0, len(list))
+	for _, trip := range list {
+		titles = append(titles, trip.Title)
+	}
+	return titles
+}
+
+func listTrips(t *testing.T, filters trips.TripFilters) []string {
+	t.Helper()
+	repo := trips.NewPostgresRepository(testDB.DB)
+
+	filters.Limit = 20
+	filters.SortBy = "title"
+	filters.SortOrder = "ASC"
+	filters.Relations = &trips.Relations{}
+
+	list, err := repo.List(context.Background(), filters)
+	require.NoError(t, err)
+	return tripTitles(list)
+}
+
+func TestTrips_ListNear(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+
+	// The deleted ramparts walk and the museum day without a route are skipped
+	lat, lng, radius := oldCityLat, oldCityLng, 10.0
+	assert.Equal(t, []string{"Old City Walk"}, listTrips(t, trips.TripFilters{
+		NearLat:  &lat,
+		NearLng:  &lng,
+		RadiusKm: &radius,
+	}))
+
+	// A route counts as near when any part of it is, not just its start
+	lat, lng, radius = 32.0800, 34.7630, 1.0
+	assert.Equal(t, []string{"Tel Aviv Beach Ride"}, listTrips(t, trips.TripFilters{
+		NearLat:  &lat,
+		NearLng:  &lng,
+		RadiusKm: &radius,
+	}))
+
+	radius = 500
+	assert.Equal(t, []string{"Old City Walk", "Red Sea Snorkel", "Tel Aviv Beach Ride"}, listTrips(t, trips.TripFilters{
+		NearLat:  &lat,
+		NearLng:  &lng,
+		RadiusKm: &radius,
+	}))
+}
+
+func TestTrips_ListTags(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+
+	assert.Equal(t, []string{"Red Sea Snorkel", "Tel Aviv Beach Ride"}, listTrips(t, trips.TripFilters{
+		Tags: []string{"beach"},
+	}))
+
+	// Tags match any of those given
+	assert.Equal(t, []string{"Museum Day", "Old City Walk", "Red Sea Snorkel"}, listTrips(t, trips.TripFilters{
+		Tags: []string{"history", "diving"},
+	}))
+
+	assert.Equal(t, []string{"Old City Walk", "Tel Aviv Beach Ride"}, listTrips(t, trips.TripFilters{
+		OwnerID: "00000000-0000-0000-0000-000000000001",
+		Tags:    []string{"beach", "walking"},
+	}))
+}
+
+func TestTrips_GetByIDs(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+	repo := trips.NewPostgresRepository(testDB.DB)
+
+	// The deleted ramparts walk and the unknown ID are skipped
+	found, err := repo.GetByIDs(context.Background(), []string{
+		"20000000-0000-0000-0000-000000000001",
+		"20000000-0000-0000-0000-000000000004",
+		"20000000-0000-0000-0000-000000000005",
+		"20000000-0000-0000-0000-0000000000ff",
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Old City Walk", "Museum Day"}, tripTitles(found))
+}
+
+func TestTrips_RedeemShareLink(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+	repo := trips.NewPostgresRepository(testDB.DB)
+	ctx := context.Background()
+
+	_, err := testDB.ExecContext(ctx, `
+		INSERT INTO activity_share_links (trip_id, created_by, share_token, permissions, max_uses, expires_at)
+		VALUES
+			('20000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', 'once', 'edit', 1, NULL),
+			('20000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', 'stale', 'view', NULL, NOW() - INTERVAL '1 day')`)
+	require.NoError(t, err)
+
+	link, err := repo.RedeemShareLink(ctx, "once")
+	require.NoError(t, err)
+	assert.Equal(t, "20000000-0000-0000-0000-000000000001", link.TripID)
+	assert.Equal(t, trips.ShareScopeEdit, link.Scope())
+	assert.Equal(t, 1, link.UseCount)
+	assert.NotNil(t, link.LastUsedAt)
+
+	// Used up, expired and unknown links all fail the same way
+	for _, token := range []string{"once", "stale", "unknown"} {
+		_, err := repo.RedeemShareLink(ctx, token)
+		assert.ErrorIs(t, err, trips.ErrShareLinkNotFound, token)
+	}
+}
+
+func TestTrips_EstimatedDifficulty(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+	service := trips.NewService(trips.NewPostgresRepository(testDB.DB), nil, nil)
+	ctx := context.Background()
+	ownerID := "00000000-0000-0000-0000-000000000001"
+
+	distance, gain := 6.0, 200
+	trip, err := service.Create(ctx, ownerID, &trips.CreateTripInput{
+		Title:          "Forest Loop",
+		DistanceKm:     &distance,
+		ElevationGainM: &gain,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, trips.DifficultyEasy, trip.DifficultyLevel)
+	assert.True(t, trip.DifficultyEstimated)
+
+	// The estimate follows the route while nobody has chosen a difficulty
+	distance, gain = 24.0, 1600
+	trip, err = service.Update(ctx, ownerID, trip.ID, &trips.UpdateTripInput{
+		DistanceKm:     &distance,
+		ElevationGainM: &gain,
+		TerrainTypes:   []string{"scramble"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, trips.DifficultyExpert, trip.DifficultyLevel)
+	assert.True(t, trip.DifficultyEstimated)
+
+	// A chosen difficulty is kept until an estimate is asked for
+	level := trips.DifficultyModerate
+	trip, err = service.Update(ctx, ownerID, trip.ID, &trips.UpdateTripInput{DifficultyLevel: &level})
+	require.NoError(t, err)
+	assert.False(t, trip.DifficultyEstimated)
+
+	trip, err = service.RecomputeDifficulty(ctx, ownerID, trip.ID)
+	require.NoError(t, err)
+	assert.Equal(t, trips.DifficultyExpert, trip.DifficultyLevel)
+	assert.True(t, trip.DifficultyEstimated)
+}
+
+func TestTrips_EstimateDuration(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+	service := trips.NewService(trips.NewPostgresRepository(testDB.DB), nil, nil)
+	ctx := context.Background()
+	ownerID := "00000000-0000-0000-0000-000000000001"
+
+	distance, gain := 10.0, 600
+	trip, err := service.Create(ctx, ownerID, &trips.CreateTripInput{
+		Title:          "Ridge Walk",
+		ActivityType:   "hiking",
+		DistanceKm:     &distance,
+		ElevationGainM: &gain,
+	})
+	require.NoError(t, err)
+
+	// Without history a hike is timed by Naismith's rule: 2 h walking, 1 h climbing
+	estimate, err := service.EstimateDuration(ctx, ownerID, trip)
+	require.NoError(t, err)
+	require.NotNil(t, estimate)
+	assert.Equal(t, trips.EstimateFromNaismith, estimate.Basis)
+	assert.InDelta(t, 3.0, estimate.Hours, 0.001)
+
+	// Other activities have no rule to fall back on
+	paddle := &trips.Trip{ActivityType: "kayaking", DistanceKm: &distance}
+	estimate, err = service.EstimateDuration(ctx, ownerID, paddle)
+	require.NoError(t, err)
+	assert.Nil(t, estimate)
+
+	// A past hike of 15 effort km in 5 hours sets a pace of 3 km/h
+	pastDistance, pastGain := 9.0, 720
+	past, err := service.Create(ctx, ownerID, &trips.CreateTripInput{
+		Title:          "Valley Hike",
+		ActivityType:   "hiking",
+		DistanceKm:     &pastDistance,
+		ElevationGainM: &pastGain,
+	})
+	require.NoError(t, err)
+	_, err = testDB.ExecContext(ctx, `
+		INSERT INTO activity_completions (trip_id, user_id, completed_at, duration_minutes)
+		VALUES ($1, $2, NOW(), 300)`, past.ID, ownerID)
+	require.NoError(t, err)
+
+	estimate, err = service.EstimateDuration(ctx, ownerID, trip)
+	require.NoError(t, err)
+	require.NotNil(t, estimate)
+	assert.Equal(t, trips.EstimateFromHistory, estimate.Basis)
+	assert.Equal(t, 1, estimate.Completions)
+	assert.InDelta(t, 5.0, estimate.Hours, 0.001)
+
+	// Anonymous visitors get the rule of thumb
+	estimate, err = service.EstimateDuration(ctx, "", trip)
+	require.NoError(t, err)
+	assert.Equal(t, trips.EstimateFromNaismith, estimate.Basis)
+}
+
+func TestTrips_Readiness(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+	service := trips.NewService(trips.NewPostgresRepository(testDB.DB), nil, nil)
+	ctx := context.Background()
+	ownerID := "00000000-0000-0000-0000-000000000001"
+
+	trip, err := service.Create(ctx, ownerID, &trips.CreateTripInput{
+		Title:           "Glacier Crossing",
+		PermitsRequired: []string{"glacier permit"},
+		EssentialGear:   []string{"crampons"},
+	})
+	require.NoError(t, err)
+
+	gaps := func(readiness *trips.TripReadiness) []string {
+		checks := []string{}
+		for _, gap := range readiness.Gaps {
+			checks = append(checks, gap.Check)
+		}
+		return checks
+	}
+
+	readiness, err := service.GetReadiness(ctx, ownerID, trip.ID)
+	require.NoError(t, err)
+	assert.False(t, readiness.Ready)
+	assert.Equal(t, []string{
+		trips.ReadinessRoute,
+		trips.ReadinessDates,
+		trips.ReadinessPermits,
+		trips.ReadinessEmergencyContacts,
+		trips.ReadinessWeather,
+	}, gaps(readiness))
+	assert.NotEmpty(t, readiness.Gaps[0].Action)
+
+	// Permits and weather are confirmed by hand
+	readiness, err = service.ConfirmReadinessCheck(ctx, ownerID, trip.ID, trips.ReadinessPermits)
+	require.NoError(t, err)
+	assert.NotContains(t, gaps(readiness), trips.ReadinessPermits)
+
+	// A fresh weather report counts as checking the weather
+	_, err = testDB.ExecContext(ctx, `
+		INSERT INTO activity_conditions (trip_id, reported_by, condition_type, description)
+		VALUES ($1, $2, 'weather', 'Clear skies expected all week')`, trip.ID, ownerID)
+	require.NoError(t, err)
+	readiness, err = service.GetReadiness(ctx, ownerID, trip.ID)
+	require.NoError(t, err)
+	assert.NotContains(t, gaps(readiness), trips.ReadinessWeather)
+
+	readiness, err = service.ClearReadinessCheck(ctx, ownerID, trip.ID, trips.ReadinessPermits)
+	require.NoError(t, err)
+	assert.Contains(t, gaps(readiness), trips.ReadinessPermits)
+
+	_, err = service.ConfirmReadinessCheck(ctx, ownerID, trip.ID, trips.ReadinessDates)
+	assert.ErrorIs(t, err, trips.ErrNotManualCheck)
+}
+
+func TestTrips_Layers(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+	repo := trips.NewPostgresRepository(testDB.DB)
+	service := trips.NewService(repo, nil, nil)
+	ctx := context.Background()
+	ownerID := "00000000-0000-0000-0000-000000000001"
+	otherID := "00000000-0000-0000-0000-000000000002"
+
+	storage, err := media.NewDiskStorage(&config.MediaConfig{
+		StoragePath: t.TempDir(),
+		CDNURL:      "http://localhost:8080/media",
+	})
+	require.NoError(t, err)
+	layers := trips.NewLayerService(repo, storage, time.Minute)
+
+	trip, err := service.Create(ctx, ownerID, &trips.CreateTripInput{Title: "Backcountry Tour"})
+	require.NoError(t, err)
+
+	zones := []byte(`{"type":"FeatureCollection","features":[
+		{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[7.1,46.1],[7.2,46.1],[7.2,46.2],[7.1,46.1]]]},"properties":{}},
+		{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[7.3,46.1],[7.4,46.1],[7.4,46.2],[7.3,46.1]]]},"properties":{}}
+	]}`)
+	layer, err := layers.CreateLayer(ctx, ownerID, trip.ID, &trips.CreateLayerInput{
+		Filename: "avalanche-zones.geojson",
+		Data:     zones,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "avalanche-zones", layer.Name)
+	assert.Equal(t, trips.LayerFormatGeoJSON, layer.Format)
+	assert.Equal(t, 2, layer.FeatureCount)
+	assert.FileExists(t, storage.GetFullPath(layer.StoragePath))
+
+	boundaries := []byte(`<?xml version="1.0"?><kml xmlns="http://www.opengis.net/kml/2.2"><Document><Placemark><name>Farm</name></Placemark></Document></kml>`)
+	_, err = layers.CreateLayer(ctx, ownerID, trip.ID, &trips.CreateLayerInput{
+		Name:     "Property boundaries",
+		Filename: "boundaries.kml",
+		Data:     boundaries,
+	})
+	require.NoError(t, err)
+
+	_, err = layers.CreateLayer(ctx, ownerID, trip.ID, &trips.CreateLayerInput{Filename: "notes.txt", Data: []byte("hello")})
+	assert.ErrorIs(t, err, trips.ErrInvalidLayer)
+	_, err = layers.CreateLayer(ctx, ownerID, trip.ID, &trips.CreateLayerInput{Filename: "fake.kml", Data: []byte("<html></html>")})
+	assert.ErrorIs(t, err, trips.ErrInvalidLayer)
+	_, err = layers.CreateLayer(ctx, otherID, trip.ID, &trips.CreateLayerInput{Filename: "zones.geojson", Data: zones})
+	assert.ErrorIs(t, err, trips.ErrUnauthorized)
+
+	list, err := layers.ListLayers(ctx, ownerID, trip.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "Property boundaries", list[1].Name)
+	assert.Equal(t, 1, list[1].FeatureCount)
+	assert.NotEmpty(t, list[0].URL)
+
+	// The trip is private
+	_, err = layers.ListLayers(ctx, otherID, trip.ID)
+	assert.ErrorIs(t, err, trips.ErrUnauthorized)
+
+	require.NoError(t, layers.DeleteLayer(ctx, ownerID, trip.ID, layer.ID))
+	assert.NoFileExists(t, storage.GetFullPath(layer.StoragePath))
+	assert.ErrorIs(t, layers.DeleteLayer(ctx, ownerID, trip.ID, layer.ID), trips.ErrLayerNotFound)
+}
+
+func TestTrips_OfflinePack(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+	repo := trips.NewPostgresRepository(testDB.DB)
+	service := trips.NewService(repo, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ownerID := "00000000-0000-0000-0000-000000000001"
+	otherID := "00000000-0000-0000-0000-000000000002"
+
+	storage, err := media.NewDiskStorage(&config.MediaConfig{
+		StoragePath: t.TempDir(),
+		CDNURL:      "http://localhost:8080/media",
+	})
+	require.NoError(t, err)
+	queue := jobs.NewLocalQueue()
+	packs := trips.NewOfflinePackService(repo, storage, queue, time.Minute)
+	layers := trips.NewLayerService(repo, storage, time.Minute)
+
+	trip, err := service.Create(ctx, ownerID, &trips.CreateTripInput{Title: "Backcountry Tour"})
+	require.NoError(t, err)
+	layer, err := layers.CreateLayer(ctx, ownerID, trip.ID, &trips.CreateLayerInput{
+		Filename: "zones.geojson",
+		Data:     []byte(`{"type":"FeatureCollection","features":[]}`),
+	})
+	require.NoError(t, err)
+
+	_, err = packs.GetPack(ctx, ownerID, trip.ID)
+	assert.ErrorIs(t, err, trips.ErrOfflinePackNotFound)
+	_, err = packs.RequestPack(ctx, otherID, trip.ID)
+	assert.ErrorIs(t, err, trips.ErrUnauthorized)
+
+	pack, err := packs.RequestPack(ctx, ownerID, trip.ID)
+	require.NoError(t, err)
+	assert.Equal(t, trips.OfflinePackPending, pack.Status)
+
+	// Asking again while it is built changes nothing
+	again, err := packs.RequestPack(ctx, ownerID, trip.ID)
+	require.NoError(t, err)
+	assert.Equal(t, pack.RequestedAt, again.RequestedAt)
+
+	queue.Start(ctx, 1)
+	require.Eventually(t, func() bool {
+		pack, err = packs.GetPack(ctx, ownerID, trip.ID)
+		return err == nil && pack.Status != trips.OfflinePackPending
+	}, 5*time.Second, 20*time.Millisecond)
+	require.Equal(t, trips.OfflinePackCompleted, pack.Status)
+	assert.NotEmpty(t, pack.URL)
+	require.NotNil(t, pack.TripUpdatedAt)
+
+	row := testDB.DB.QueryRowContext(ctx, `SELECT storage_path FROM trip_offline_packs WHERE trip_id = $1`, trip.ID)
+	var path string
+	require.NoError(t, row.Scan(&path))
+	archive, err := zip.OpenReader(storage.GetFullPath(path))
+	require.NoError(t, err)
+	defer archive.Close()
+	names := []string{}
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	assert.Equal(t, []string{"trip.geojson", "metadata.json", "layers/" + layer.ID + ".geojson"}, names)
+}
+
+func TestTrips_Annotations(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+	service := trips.NewService(trips.NewPostgresRepository(testDB.DB), nil, nil)
+	ctx := context.Background()
+	ownerID := "00000000-0000-0000-0000-000000000001"
+	otherID := "00000000-0000-0000-0000-000000000002"
+
+	trip, err := service.Create(ctx, ownerID, &trips.CreateTripInput{Title: "Coastal Traverse"})
+	require.NoError(t, err)
+
+	// A line due north along a meridian, a tenth of a degree long
+	north := &trips.GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{35, 31}, {35, 31.1}}}
+	bearing, err := service.CreateAnnotation(ctx, ownerID, trip.ID, &trips.CreateAnnotationInput{
+		Kind:     trips.AnnotationBearing,
+		Geometry: north,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, bearing.LengthM)
+	require.NotNil(t, bearing.BearingDeg)
+	assert.InDelta(t, 11119.5, *bearing.LengthM, 1)
+	assert.InDelta(t, 0, *bearing.BearingDeg, 0.001)
+
+	square := &trips.GeoJSONRoute{Type: "Polygon", Coordinates: [][][]float64{{{0, 0}, {0.01, 0}, {0.01, 0.01}, {0, 0.01}, {0, 0}}}}
+	_, err = service.CreateAnnotation(ctx, ownerID, trip.ID, &trips.CreateAnnotationInput{
+		Kind:     trips.AnnotationArea,
+		Geometry: square,
+		Label:    "Camp zone",
+	})
+	require.NoError(t, err)
+
+	// Labels need text, bearings exactly two points
+	point := &trips.GeoJSONRoute{Type: "Point", Coordinates: []float64{35, 31}}
+	_, err = service.CreateAnnotation(ctx, ownerID, trip.ID, &trips.CreateAnnotationInput{Kind: trips.AnnotationLabel, Geometry: point})
+	assert.ErrorIs(t, err, trips.ErrInvalidAnnotation)
+	_, err = service.CreateAnnotation(ctx, ownerID, trip.ID, &trips.CreateAnnotationInput{Kind: trips.AnnotationBearing, Geometry: point})
+	assert.ErrorIs(t, err, trips.ErrInvalidAnnotation)
+	_, err = service.CreateAnnotation(ctx, otherID, trip.ID, &trips.CreateAnnotationInput{Kind: trips.AnnotationBearing, Geometry: north})
+	assert.ErrorIs(t, err, trips.ErrUnauthorized)
+
+	label := "Take the ridge"
+	updated, err := service.UpdateAnnotation(ctx, ownerID, trip.ID, bearing.ID, &trips.UpdateAnnotationInput{Label: &label})
+	require.NoError(t, err)
+	assert.Equal(t, label, updated.Label)
+
+	annotations, err := service.ListAnnotations(ctx, ownerID, trip.ID)
+	require.NoError(t, err)
+	require.Len(t, annotations, 2)
+	assert.Equal(t, label, annotations[0].Label)
+	require.NotNil(t, annotations[1].AreaM2)
+	assert.InDelta(t, 1.2364e6, *annotations[1].AreaM2, 1e3)
+
+	require.NoError(t, service.DeleteAnnotation(ctx, ownerID, trip.ID, bearing.ID))
+	assert.ErrorIs(t, service.DeleteAnnotation(ctx, ownerID, trip.ID, bearing.ID), trips.ErrAnnotationNotFound)
+}
+
+func TestTrips_ItineraryTimeWindows(t *testing.T) {
+	testDB.Reset(t, "users", "places", "trips")
+	service := trips.NewService(trips.NewPostgresRepository(testDB.DB), nil, nil)
+	ctx := context.Background()
+	ownerID := "00000000-0000-0000-0000-000000000001"
+
+	start := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	trip, err := service.Create(ctx, ownerID, &trips.CreateTripInput{
+		Title:        "Jerusalem to Jaffa",
+		ActivityType: "hiking",
+		StartDate:    &start,
+	})
+	require.NoError(t, err)
+
+	// Western Wall, then Jaffa Port some 55 km away, then Carmel Market
+	_, err = testDB.ExecContext(ctx, `
+		INSERT INTO trip_waypoints (id, trip_id, place_id, order_position) VALUES
+			('40000000-0000-0000-0000-000000000001', $1, '10000000-0000-0000-0000-000000000001', 0),
+			('40000000-0000-0000-0000-000000000002', $1, '10000000-0000-0000-0000-000000000005', 1),
+			('40000000-0000-0000-0000-000000000003', $1, '10000000-0000-0000-0000-000000000006', 2)`, trip.ID)
+	require.NoError(t, err)
+
+	itinerary, err := service.GetItinerary(ctx, ownerID, trip.ID)
+	require.NoError(t, err)
+	require.Len(t, itinerary.Stops, 3)
+	assert.Empty(t, itinerary.Warnings)
+	assert.WithinDuration(t, start, *itinerary.Stops[0].EstimatedArrival, 0)
+	assert.True(t, itinerary.Stops[1].EstimatedArrival.After(start.Add(12*time.Hour)))
+
+	// The ferry leaves long before a walker gets to the port
+	closes := start.Add(12 * time.Hour)
+	itinerary, err = service.SetWaypointWindow(ctx, ownerID, trip.ID, "40000000-0000-0000-0000-000000000002", &trips.SetTimeWindowInput{
+		ClosesAt: &closes,
+		Label:    "the last ferry",
+	})
+	require.NoError(t, err)
+	require.Len(t, itinerary.Warnings, 1)
+	assert.Equal(t, trips.WarningMissedWindow, itinerary.Warnings[0].Kind)
+	assert.Equal(t, "40000000-0000-0000-0000-000000000002", itinerary.Warnings[0].WaypointID)
+	assert.Greater(t, itinerary.Warnings[0].Minutes, 0)
+
+	// The market opens later still, so the plan waits for it
+	opens := start.Add(48 * time.Hour)
+	_, err = service.SetWaypointWindow(ctx, ownerID, trip.ID, "40000000-0000-0000-0000-000000000003", &trips.SetTimeWindowInput{OpensAt: &opens})
+	require.NoError(t, err)
+	itinerary, err = service.GetItinerary(ctx, ownerID, trip.ID)
+	require.NoError(t, err)
+	require.Len(t, itinerary.Warnings, 2)
+	assert.Equal(t, trips.WarningWaitsWindow, itinerary.Warnings[1].Kind)
+	assert.WithinDuration(t, opens, *itinerary.Stops[2].EstimatedDeparture, 0)
+	require.NotNil(t, itinerary.Stops[2].Window)
+
+	_, err = service.SetWaypointWindow(ctx, ownerID, trip.ID, "40000000-0000-0000-0000-000000000003", &trips.SetTimeWindowInput{OpensAt: &opens, ClosesAt: &start})
+	assert.ErrorIs(t, err, trips.ErrInvalidTimeWindow)
+	_, err = service.SetWaypointWindow(ctx, ownerID, trip.ID, "40000000-0000-0000-0000-000000000009", &trips.SetTimeWindowInput{OpensAt: &opens})
+	assert.ErrorIs(t, err, trips.ErrWaypointNotFound)
+}
+
+func TestTrips_RouteVariants(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+	service := trips.NewService(trips.NewPostgresRepository(testDB.DB), nil, nil)
+	ctx := context.Background()
+	ownerID := "00000000-0000-0000-0000-000000000001"
+	otherID := "00000000-0000-0000-0000-000000000002"
+
+	trip, err := service.Create(ctx, ownerID, &trips.CreateTripInput{Title: "Mount Meron Loop"})
+	require.NoError(t, err)
+
+	// A tenth of a degree north, about 11.12 km
+	standard, err := service.CreateRouteVariant(ctx, ownerID, trip.ID, &trips.CreateRouteVariantInput{
+		Name:         "Standard",
+		RouteGeoJSON: &trips.GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{35, 31}, {35, 31.1}}},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, standard.DistanceKm)
+	assert.InDelta(t, 11.12, *standard.DistanceKm, 0.001)
+	assert.False(t, standard.IsPrimary)
+
+	winterDistance := 8.5
+	winter, err := service.CreateRouteVariant(ctx, ownerID, trip.ID, &trips.CreateRouteVariantInput{
+		Name:         "Winter",
+		RouteGeoJSON: &trips.GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{35, 31}, {35.05, 31.05}}},
+		DistanceKm:   &winterDistance,
+		IsPrimary:    true,
+	})
+	require.NoError(t, err)
+	assert.True(t, winter.IsPrimary)
+
+	// The primary variant is the trip's route
+	loaded, err := service.GetByID(ctx, ownerID, trip.ID)
+	require.NoError(t, err)
+	require.NotNil(t, loaded.DistanceKm)
+	assert.InDelta(t, winterDistance, *loaded.DistanceKm, 0.001)
+
+	_, err = service.CreateRouteVariant(ctx, ownerID, trip.ID, &trips.CreateRouteVariantInput{
+		Name:         "Winter",
+		RouteGeoJSON: &trips.GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{35, 31}, {35, 31.2}}},
+	})
+	assert.ErrorIs(t, err, trips.ErrVariantNameTaken)
+	_, err = service.CreateRouteVariant(ctx, ownerID, trip.ID, &trips.CreateRouteVariantInput{
+		Name:         "Point",
+		RouteGeoJSON: &trips.GeoJSONRoute{Type: "Point", Coordinates: []float64{35, 31}},
+	})
+	assert.ErrorIs(t, err, trips.ErrInvalidRoute)
+	_, err = service.CreateRouteVariant(ctx, otherID, trip.ID, &trips.CreateRouteVariantInput{
+		Name:         "Mine",
+		RouteGeoJSON: &trips.GeoJSONRoute{Type: "LineString", Coordinates: [][]float64{{35, 31}, {35, 31.1}}},
+	})
+	assert.ErrorIs(t, err, trips.ErrUnauthorized)
+
+	// Switching the primary variant moves the trip onto its route
+	_, err = service.SetPrimaryRouteVariant(ctx, ownerID, trip.ID, standard.ID)
+	require.NoError(t, err)
+	variants, err := service.ListRouteVariants(ctx, ownerID, trip.ID)
+	require.NoError(t, err)
+	require.Len(t, variants, 2)
+	assert.Equal(t, standard.ID, variants[0].ID)
+	assert.True(t, variants[0].IsPrimary)
+	assert.False(t, variants[1].IsPrimary)
+
+	loaded, err = service.GetByID(ctx, ownerID, trip.ID)
+	require.NoError(t, err)
+	require.NotNil(t, loaded.DistanceKm)
+	assert.InDelta(t, 11.12, *loaded.DistanceKm, 0.001)
+
+	require.NoError(t, service.DeleteRouteVariant(ctx, ownerID, trip.ID, winter.ID))
+	assert.ErrorIs(t, service.DeleteRouteVariant(ctx, ownerID, trip.ID, winter.ID), trips.ErrVariantNotFound)
+}
+
+func TestTrips_Bailouts(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+	service := trips.NewService(trips.NewPostgresRepository(testDB.DB), nil, nil)
+	ctx := context.Background()
+	ownerID := "00000000-0000-0000-0000-000000000001"
+
+	// A route a tenth of a degree due north, about 11.1 km
+	trip, err := service.Create(ctx, ownerID, &trips.CreateTripInput{Title: "Ridge Walk"})
+	require.NoError(t, err)
+	_, err = testDB.ExecContext(ctx, `UPDATE trips SET route_geojson = $2 WHERE id = $1`,
+		trip.ID, `{"type":"LineString","coordinates":[[35,31],[35,31.1]]}`)
+	require.NoError(t, err)
+
+	_, err = testDB.ExecContext(ctx, `
+		INSERT INTO places (id, name, type, location, bounds, created_by, category, privacy, status) VALUES
+			('50000000-0000-0000-0000-000000000001', 'South Trailhead', 'poi',
+				ST_GeogFromText('SRID=4326;POINT(35.005 31.02)'), NULL, $1, ARRAY['trailhead'], 'public', 'active'),
+			('50000000-0000-0000-0000-000000000002', 'Valley Road', 'area',
+				NULL, ST_GeogFromText('SRID=4326;POLYGON((35.003 31.06, 35.004 31.06, 35.004 31.061, 35.003 31.061, 35.003 31.06))'),
+				$1, ARRAY['road'], 'public', 'active'),
+			('50000000-0000-0000-0000-000000000003', 'Far Trailhead', 'poi',
+				ST_GeogFromText('SRID=4326;POINT(35.05 31.05)'), NULL, $1, ARRAY['trailhead'], 'public', 'active'),
+			('50000000-0000-0000-0000-000000000004', 'Private Track', 'poi',
+				ST_GeogFromText('SRID=4326;POINT(35.001 31.05)'), NULL, $1, ARRAY['trailhead'], 'private', 'active'),
+			('50000000-0000-0000-0000-000000000005', 'Summit Cafe', 'poi',
+				ST_GeogFromText('SRID=4326;POINT(35.001 31.09)'), NULL, $1, ARRAY['food'], 'public', 'active')`, ownerID)
+	require.NoError(t, err)
+
+	// Within the default kilometre, in the order they are reached
+	points, err := service.GetBailouts(ctx, ownerID, trip.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	assert.Equal(t, "South Trailhead", points[0].Name)
+	assert.InDelta(t, 2.22, points[0].RouteKm, 0.05)
+	assert.Equal(t, "Valley Road", points[1].Name)
+	assert.InDelta(t, 285, points[1].DistanceFromRouteM, 5)
+	require.NotNil(t, points[1].Location)
+	assert.InDelta(t, 35.003, points[1].Location.Coordinates[0], 1e-6)
+
+	points, err = service.GetBailouts(ctx, ownerID, trip.ID, &trips.BailoutQuery{CorridorM: 5000})
+	require.NoError(t, err)
+	assert.Len(t, points, 3)
+
+	waypoint, err := service.AttachBailout(ctx, ownerID, trip.ID, &trips.AttachBailoutInput{
+		PlaceID: "50000000-0000-0000-0000-000000000001",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, trips.WaypointBailout, waypoint.Kind)
+	_, err = service.AttachBailout(ctx, ownerID, trip.ID, &trips.AttachBailoutInput{
+		PlaceID: "50000000-0000-0000-0000-0000000000ff",
+	})
+	assert.ErrorIs(t, err, trips.ErrPlaceNotFound)
+
+	points, err = service.GetBailouts(ctx, ownerID, trip.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, waypoint.ID, points[0].WaypointID)
+
+	// Bail-outs are not stops on the itinerary
+	itinerary, err := service.GetItinerary(ctx, ownerID, trip.ID)
+	require.NoError(t, err)
+	assert.Empty(t, itinerary.Stops)
+
+	require.NoError(t, service.DetachBailout(ctx, ownerID, trip.ID, waypoint.ID))
+	assert.ErrorIs(t, service.DetachBailout(ctx, ownerID, trip.ID, waypoint.ID), trips.ErrWaypointNotFound)
+}
+
+func TestTrips_ListByAccessibility(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+	repo := trips.NewPostgresRepository(testDB.DB)
+	service := trips.NewService(repo, nil, nil)
+	ctx := context.Background()
+	ownerID := "00000000-0000-0000-0000-000000000001"
+
+	dogs, err := service.Create(ctx, ownerID, &trips.CreateTripInput{
+		Title:         "Forest Loop",
+		Accessibility: []string{"dog_friendly", "toddler_ok"},
+	})
+	require.NoError(t, err)
+	_, err = service.Create(ctx, ownerID, &trips.CreateTripInput{
+		Title:         "Promenade Walk",
+		Accessibility: []string{"stroller_ok", "toddler_ok"},
+	})
+	require.NoError(t, err)
+
+	found, err := repo.List(ctx, trips.TripFilters{Accessibility: []string{"toddler_ok"}})
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+
+	found, err = repo.List(ctx, trips.TripFilters{Accessibility: []string{"dog_friendly", "toddler_ok"}})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, dogs.ID, found[0].ID)
+
+	// An empty list clears the attributes
+	_, err = service.Update(ctx, ownerID, dogs.ID, &trips.UpdateTripInput{Accessibility: []string{}})
+	require.NoError(t, err)
+	found, err = repo.List(ctx, trips.TripFilters{Accessibility: []string{"dog_friendly"}})
+	require.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func TestTrips_CrowdEstimate(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+	service := trips.NewService(trips.NewPostgresRepository(testDB.DB), nil, nil)
+	ctx := context.Background()
+	tripID := "20000000-0000-0000-0000-000000000001"
+
+	// Too few visits to tell
+	estimate, err := service.GetCrowdEstimate(ctx, "", tripID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, estimate.Visits)
+	assert.Equal(t, trips.CrowdUnknown, estimate.Level)
+	assert.Nil(t, estimate.BestTime)
+	require.Len(t, estimate.Weekdays, 7)
+	require.Len(t, estimate.Hours, 24)
+
+	// Both users on each of the last four Saturdays at 10:00, and a condition
+	// report on a Tuesday at 08:00, Jerusalem time
+	_, err = testDB.ExecContext(ctx, `
+		INSERT INTO activity_completions (trip_id, user_id, completed_at)
+		SELECT $1, u.id,
+			(date_trunc('week', NOW() AT TIME ZONE 'Asia/Jerusalem') - n * INTERVAL '1 week' + INTERVAL '5 days 10 hours')
+				AT TIME ZONE 'Asia/Jerusalem'
+		FROM generate_series(1, 4) n
+		CROSS JOIN (VALUES ('00000000-0000-0000-0000-000000000001'::uuid), ('00000000-0000-0000-0000-000000000002'::uuid)) u(id)`,
+		tripID)
+	require.NoError(t, err)
+	_, err = testDB.ExecContext(ctx, `
+		INSERT INTO activity_conditions (trip_id, reported_by, condition_type, description, created_at)
+		VALUES ($1, '00000000-0000-0000-0000-000000000002', 'trail', 'Muddy after rain',
+			(date_trunc('week', NOW() AT TIME ZONE 'Asia/Jerusalem') - INTERVAL '1 week' + INTERVAL '1 day 8 hours')
+				AT TIME ZONE 'Asia/Jerusalem')`,
+		tripID)
+	require.NoError(t, err)
+
+	estimate, err = service.GetCrowdEstimate(ctx, "", tripID)
+	require.NoError(t, err)
+	assert.Equal(t, 9, estimate.Visits)
+	assert.Equal(t, trips.CrowdQuiet, estimate.Level)
+	assert.InDelta(t, 0.89, estimate.WeekendShare, 0.001)
+
+	saturday := estimate.Weekdays[time.Saturday]
+	assert.Equal(t, 8, saturday.Visits)
+	assert.Equal(t, trips.CrowdBusy, saturday.Level)
+	assert.Equal(t, 1, estimate.Weekdays[time.Tuesday].Visits)
+	assert.Equal(t, trips.CrowdQuiet, estimate.Weekdays[time.Wednesday].Level)
+	assert.Equal(t, 8, estimate.Hours[10].Visits)
+	assert.Equal(t, 1, estimate.Hours[8].Visits)
+
+	require.NotNil(t, estimate.BestTime)
+	assert.Equal(t, "Monday", estimate.BestTime.Day)
+	assert.Equal(t, 6, estimate.BestTime.Hour)
+}
+
+func TestTrips_WaterSources(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+	service := trips.NewService(trips.NewPostgresRepository(testDB.DB), nil, nil)
+	ctx := context.Background()
+	ownerID := "00000000-0000-0000-0000-000000000001"
+
+	// A hike 0.36 degrees due north, about 40 km, setting out in July
+	july := time.Date(2025, time.July, 12, 6, 0, 0, 0, time.UTC)
+	trip, err := service.Create(ctx, ownerID, &trips.CreateTripInput{
+		Title:        "Desert Traverse",
+		ActivityType: "hiking",
+		StartDate:    &july,
+	})
+	require.NoError(t, err)
+	_, err = testDB.ExecContext(ctx, `UPDATE trips SET route_geojson = $2 WHERE id = $1`,
+		trip.ID, `{"type":"LineString","coordinates":[[35,31],[35,31.36]]}`)
+	require.NoError(t, err)
+
+	// A reliable spring at 10 km, a stream flowing in winter at 20 km and a
+	// tank nobody vouches for at 30 km
+	_, err = testDB.ExecContext(ctx, `
+		INSERT INTO places (id, name, type, location, created_by, category, privacy, status) VALUES
+			('60000000-0000-0000-0000-000000000001', 'Ein Spring', 'poi',
+				ST_GeogFromText('SRID=4326;POINT(35.002 31.09)'), $1, ARRAY['water_source'], 'public', 'active'),
+			('60000000-0000-0000-0000-000000000002', 'Winter Stream', 'poi',
+				ST_GeogFromText('SRID=4326;POINT(34.998 31.18)'), $1, ARRAY['water_source'], 'public', 'active'),
+			('60000000-0000-0000-0000-000000000003', 'Old Tank', 'poi',
+				ST_GeogFromText('SRID=4326;POINT(35.002 31.27)'), $1, ARRAY['water_source'], 'public', 'active')`, ownerID)
+	require.NoError(t, err)
+	_, err = testDB.ExecContext(ctx, `
+		INSERT INTO place_water_sources (place_id, kind, reliability, flowing_months) VALUES
+			('60000000-0000-0000-0000-000000000001', 'spring', 'reliable', '{}'),
+			('60000000-0000-0000-0000-000000000002', 'stream', 'seasonal', '{12, 1, 2, 3}'),
+			('60000000-0000-0000-0000-000000000003', 'tank', 'unknown', '{}')`)
+	require.NoError(t, err)
+
+	plan, err := service.GetWaterSources(ctx, ownerID, trip.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, time.July, plan.Month)
+	require.Len(t, plan.Sources, 3)
+	assert.Equal(t, []bool{true, false, false}, []bool{plan.Sources[0].Dependable, plan.Sources[1].Dependable, plan.Sources[2].Dependable})
+	assert.InDelta(t, 10.0, plan.Sources[0].RouteKm, 0.05)
+	assert.InDelta(t, 10.0, plan.Sources[1].FromPreviousKm, 0.05)
+	require.Len(t, plan.DryStretches, 1)
+	assert.InDelta(t, 10.0, plan.DryStretches[0].FromKm, 0.05)
+	assert.InDelta(t, 30.0, plan.DryStretches[0].LengthKm, 0.05)
+
+	// The dry stretch holds the trip back until water is confirmed
+	readiness, err := service.GetReadiness(ctx, ownerID, trip.ID)
+	require.NoError(t, err)
+	var water *trips.ReadinessCheck
+	for i := range readiness.Gaps {
+		if readiness.Gaps[i].Check == trips.ReadinessWater {
+			water = &readiness.Gaps[i]
+		}
+	}
+	require.NotNil(t, water)
+	assert.Contains(t, water.Action, "30.0 km")
+
+	readiness, err = service.ConfirmReadinessCheck(ctx, ownerID, trip.ID, trips.ReadinessWater)
+	require.NoError(t, err)
+	for _, gap := range readiness.Gaps {
+		assert.NotEqual(t, trips.ReadinessWater, gap.Check)
+	}
+
+	// In February the stream flows too
+	february := time.Date(2026, time.February, 1, 6, 0, 0, 0, time.UTC)
+	_, err = service.Update(ctx, ownerID, trip.ID, &trips.UpdateTripInput{StartDate: &february})
+	require.NoError(t, err)
+	plan, err = service.GetWaterSources(ctx, ownerID, trip.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, plan.DryStretches, 1)
+	assert.InDelta(t, 20.0, plan.DryStretches[0].FromKm, 0.05)
+	assert.InDelta(t, 20.0, plan.LongestDryKm, 0.05)
+}
+
+func TestTrips_AccessFees(t *testing.T) {
+	testDB.Reset(t, "users", "places", "trips")
+	service := trips.NewService(trips.NewPostgresRepository(testDB.DB), nil, nil)
+	ctx := context.Background()
+	ownerID := "00000000-0000-0000-0000-000000000001"
+
+	shuttle := 12.5
+	trip, err := service.Create(ctx, ownerID, &trips.CreateTripInput{
+		Title: "Old City Loop",
+		AccessFees: []trips.AccessFee{
+			{Kind: trips.FeeShuttle, Name: "Light rail", Amount: &shuttle, Currency: "ILS"},
+			{Kind: trips.FeeParkingPass, Name: "Mamilla parking", Per: trips.FeePerVehicle},
+		},
+	})
+	require.NoError(t, err)
+
+	// The market is visited twice, its fee counts once
+	_, err = testDB.ExecContext(ctx, `
+		UPDATE places SET access_fees = '[{"kind":"entry_fee","name":"Tour","amount":40,"currency":"ILS"}]'
+		WHERE id = '10000000-0000-0000-0000-000000000003'`)
+	require.NoError(t, err)
+	_, err = testDB.ExecContext(ctx, `
+		INSERT INTO trip_waypoints (id, trip_id, place_id, order_position) VALUES
+			('40000000-0000-0000-0000-000000000001', $1, '10000000-0000-0000-0000-000000000003', 0),
+			('40000000-0000-0000-0000-000000000002', $1, '10000000-0000-0000-0000-000000000001', 1),
+			('40000000-0000-0000-0000-000000000003', $1, '10000000-0000-0000-0000-000000000003', 2)`, trip.ID)
+	require.NoError(t, err)
+
+	stats, err := service.GetTripStats(ctx, ownerID, trip.ID)
+	require.NoError(t, err)
+	require.Len(t, stats.AccessFees.Fees, 3)
+	assert.Equal(t, "10000000-0000-0000-0000-000000000003", stats.AccessFees.Fees[2].PlaceID)
+	assert.Equal(t, []trips.FeeTotal{{Currency: "ILS", Per: trips.FeePerPerson, Amount: 52.5}}, stats.AccessFees.Totals)
+	assert.Equal(t, 1, stats.AccessFees.Unpriced)
+
+	// The fees hold the trip back until they are confirmed
+	hasGap := func(readiness *trips.TripReadiness) bool {
+		for _, gap := range readiness.Gaps {
+			if gap.Check == trips.ReadinessFees {
+				return true
+			}
+		}
+		return false
+	}
+	readiness, err := service.GetReadiness(ctx, ownerID, trip.ID)
+	require.NoError(t, err)
+	assert.True(t, hasGap(readiness))
+	readiness, err = service.ConfirmReadinessCheck(ctx, ownerID, trip.ID, trips.ReadinessFees)
+	require.NoError(t, err)
+	assert.False(t, hasGap(readiness))
+
+	// An empty list clears the trip's own fees
+	updated, err := service.Update(ctx, ownerID, trip.ID, &trips.UpdateTripInput{AccessFees: []trips.AccessFee{}})
+	require.NoError(t, err)
+	assert.Empty(t, updated.AccessFees)
+}
+
+func TestTrips_ConditionAlerts(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+	repo := trips.NewPostgresRepository(testDB.DB)
+	service := trips.NewService(repo, nil, nil)
+	alerts := trips.NewConditionAlerts(repo, jobs.NewLocalQueue(), events.NewLocalBus())
+	ctx := context.Background()
+	ownerID := "00000000-0000-0000-0000-000000000001"
+	subscriberID := "00000000-0000-0000-0000-000000000002"
+	tripID := "20000000-0000-0000-0000-000000000001"
+
+	// Around the Old City, which the Old City Walk crosses
+	oldCity := &trips.GeoJSONRoute{Type: "Polygon", Coordinates: [][][]float64{{{35.22, 31.77}, {35.24, 31.77}, {35.24, 31.79}, {35.22, 31.79}, {35.22, 31.77}}}}
+	subscription, err := alerts.Subscribe(ctx, subscriberID, &trips.CreateConditionSubscriptionInput{Region: oldCity, Frequency: trips.AlertsHourly})
+	require.NoError(t, err)
+
+	verify := func(severity string) string {
+		condition, err := service.ReportCondition(ctx, ownerID, tripID, &trips.CreateActivityConditionInput{
+			ConditionType: "trail",
+			Severity:      severity,
+			Description:   "Steps closed for repairs near the gate",
+		})
+		require.NoError(t, err)
+		_, err = service.VerifyCondition(ctx, ownerID, tripID, condition.ID)
+		require.NoError(t, err)
+
+		event := events.New(events.ConditionVerified, "trip", tripID, ownerID, map[string]interface{}{"condition_id": condition.ID})
+		require.NoError(t, alerts.HandleConditionVerified(ctx, event))
+		return condition.ID
+	}
+
+	warningID := verify(trips.SeverityWarning)
+	verify(trips.SeverityInfo) // Not among the default severities
+
+	subscriptions, err := alerts.ListSubscriptions(ctx, subscriberID)
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 1)
+	require.NotNil(t, subscriptions[0].NextSendAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *subscriptions[0].NextSendAt, time.Minute)
+
+	_, sent, err := repo.TakeConditionAlerts(ctx, subscription.ID)
+	require.NoError(t, err)
+	require.Len(t, sent, 1)
+	assert.Equal(t, warningID, sent[0].ConditionID)
+	assert.Equal(t, "Old City Walk", sent[0].TripTitle)
+
+	// Alerts are only sent once
+	_, sent, err = repo.TakeConditionAlerts(ctx, subscription.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sent)
+
+	require.NoError(t, alerts.Unsubscribe(ctx, subscriberID, subscription.ID))
+	_, _, err = repo.TakeConditionAlerts(ctx, subscription.ID)
+	assert.ErrorIs(t, err, trips.ErrSubscriptionNotFound)
+}
+
+func TestTrips_ConditionVerifiers(t *testing.T) {
+	testDB.Reset(t, "users", "trips")
+	service := trips.NewService(trips.NewPostgresRepository(testDB.DB), nil, nil)
+	ctx := context.Background()
+	ownerID := "00000000-0000-0000-0000-000000000001"
+	verifierID := "00000000-0000-0000-0000-000000000002"
+	tripID := "20000000-0000-0000-0000-000000000001"
+
+	report := func(description string) *trips.ActivityCondition {
+		condition, err := service.ReportCondition(ctx, ownerID, tripID, &trips.CreateActivityConditionInput{
+			ConditionType: "trail",
+			Severity:      trips.SeverityWarning,
+			Description:   description,
+		})
+		require.NoError(t, err)
+		return condition
+	}
+	confirmed := report("Steps closed for repairs near the gate")
+	disputed := report("Fallen tree blocking the lower path")
+	verified := report("Muddy stretch after the rain")
+	unverified := report("Crowded around midday on weekends")
+	_, err := service.VerifyCondition(ctx, ownerID, tripID, verified.ID)
+	require.NoError(t, err)
+
+	// Until granted verifier status, users cannot review reports
+	_, err = service.ConfirmCondition(ctx, verifierID, tripID, confirmed.ID, &trips.ReviewConditionInput{})
+	assert.ErrorIs(t, err, trips.ErrUnauthorized)
+
+	// A grant elsewhere does not cover the trip
+	tiberias := &trips.GeoJSONRoute{Type: "Polygon", Coordinates: [][][]float64{{{35.5, 32.7}, {35.6, 32.7}, {35.6, 32.8}, {35.5, 32.8}, {35.5, 32.7}}}}
+	_, err = service.GrantConditionVerifier(ctx, ownerID, &trips.GrantConditionVerifierInput{UserID: verifierID, Region: tiberias})
+	require.NoError(t, err)
+	_, err = service.ConfirmCondition(ctx, verifierID, tripID, confirmed.ID, &trips.ReviewConditionInput{})
+	assert.ErrorIs(t, err, trips.ErrUnauthorized)
+
+	oldCity := &trips.GeoJSONRoute{Type: "Polygon", Coordinates: [][][]float64{{{35.22, 31.77}, {35.24, 31.77}, {35.24, 31.79}, {35.22, 31.79}, {35.22, 31.77}}}}
+	grant, err := service.GrantConditionVerifier(ctx, ownerID, &trips.GrantConditionVerifierInput{UserID: verifierID, Region: oldCity})
+	require.NoError(t, err)
+
+	_, err = service.ConfirmCondition(ctx, verifierID, tripID, confirmed.ID, &trips.ReviewConditionInput{Note: "Checked this morning"})
+	require.NoError(t, err)
+	_, err = service.DisputeCondition(ctx, verifierID, tripID, disputed.ID, &trips.ReviewConditionInput{})
+	require.NoError(t, err)
+
+	// Reports are listed the most vouched for first
+	conditions, err := service.ListConditions(ctx, verifierID, tripID, false)
+	require.NoError(t, err)
+	require.Len(t, conditions, 4)
+	ids := []string{conditions[0].ID, conditions[1].ID, conditions[2].ID, conditions[3].ID}
+	assert.Equal(t, []string{confirmed.ID, verified.ID, unverified.ID, disputed.ID}, ids)
+	assert.Equal(t, "Checked this morning", *conditions[0].ReviewNote)
+	assert.False(t, conditions[3].Verified)
+
+	grants, err := service.ListConditionVerifiers(ctx, verifierID)
+	require.NoError(t, err)
+	assert.Len(t, grants, 2)
+
+	require.NoError(t, service.RevokeConditionVerifier(ctx, grant.ID))
+	assert.ErrorIs(t, service.RevokeConditionVerifier(ctx, grant.ID), trips.ErrVerifierNotFound)
+	_, err = service.ConfirmCondition(ctx, verifierID, tripID, disputed.ID, &trips.ReviewConditionInput{})
+	assert.ErrorIs(t, err, trips.ErrUnauthorized)
+}
@@ -0,0 +1,138 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultMaxAttempts is how often a failing job is tried before it is dropped
+const DefaultMaxAttempts = 5
+
+var (
+	ErrJobNotFound    = errors.New("job not found")
+	ErrUnknownJobType = errors.New("unknown job type")
+)
+
+// Job is a unit of background work
+type Job struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+// Decode unmarshals the job payload into dest
+func (j *Job) Decode(dest interface{}) error {
+	if err := json.Unmarshal(j.Payload, dest); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %w", j.Type, err)
+	}
+	return nil
+}
+
+// Handler processes a job. Returning an error schedules a retry.
+type Handler func(ctx context.Context, job *Job) error
+
+// Queue defines the interface for background job queues
+type Queue interface {
+	// Register sets the handler for a job type
+	Register(jobType string, handler Handler)
+
+	// Enqueue adds a job that should run as soon as a worker is free
+	Enqueue(ctx context.Context, jobType string, payload interface{}) (*Job, error)
+
+	// Schedule adds a job that should not run before runAt
+	Schedule(ctx context.Context, jobType string, payload interface{}, runAt time.Time) (*Job, error)
+
+	// Cancel removes a job that has not started yet
+	Cancel(ctx context.Context, jobID string) error
+
+	// Start runs the workers until the context is cancelled
+	Start(ctx context.Context, workers int)
+}
+
+// registry holds the handlers shared by the queue implementations
+type registry struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+func newRegistry() registry {
+	return registry{handlers: make(map[string]Handler)}
+}
+
+func (r *registry) Register(jobType string, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[jobType] = handler
+}
+
+func (r *registry) handler(jobType string) (Handler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	h, ok := r.handlers[jobType]
+	return h, ok
+}
+
+// run executes a job and records the attempt on it
+func (r *registry) run(ctx context.Context, job *Job) (err error) {
+	job.Attempts++
+
+	handler, ok := r.handler(job.Type)
+	if !ok {
+		return ErrUnknownJobType
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("job panicked: %v", rec)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// Helper functions
+
+func newJob(jobType string, payload interface{}, runAt time.Time) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job payload: %w", err)
+	}
+
+	now := time.Now()
+	if runAt.IsZero() {
+		runAt = now
+	}
+
+	return &Job{
+		ID:          uuid.New().String(),
+		Type:        jobType,
+		Payload:     data,
+		MaxAttempts: DefaultMaxAttempts,
+		RunAt:       runAt,
+		CreatedAt:   now,
+	}, nil
+}
+
+// backoff returns the delay before the next attempt, doubling from 5s and
+// capped at 10 minutes
+func backoff(attempts int) time.Duration {
+	delay := 5 * time.Second
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= 10*time.Minute {
+			return 10 * time.Minute
+		}
+	}
+	return delay
+}
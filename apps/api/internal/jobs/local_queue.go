@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// LocalQueue runs jobs in-process. It is used when Redis is not available,
+// so pending jobs are lost on restart.
+type LocalQueue struct {
+	registry
+
+	mu      sync.Mutex
+	timers  map[string]*time.Timer
+	ctx     context.Context
+	sem     chan struct{}
+	started chan struct{}
+	once    sync.Once
+}
+
+// NewLocalQueue creates a new in-process queue
+func NewLocalQueue() *LocalQueue {
+	return &LocalQueue{
+		registry: newRegistry(),
+		timers:   make(map[string]*time.Timer),
+		ctx:      context.Background(),
+		started:  make(chan struct{}),
+	}
+}
+
+// Enqueue adds a job that should run as soon as a worker is free
+func (q *LocalQueue) Enqueue(ctx context.Context, jobType string, payload interface{}) (*Job, error) {
+	return q.Schedule(ctx, jobType, payload, time.Time{})
+}
+
+// Schedule adds a job that should not run before runAt
+func (q *LocalQueue) Schedule(ctx context.Context, jobType string, payload interface{}, runAt time.Time) (*Job, error) {
+	job, err := newJob(jobType, payload, runAt)
+	if err != nil {
+		return nil, err
+	}
+
+	q.schedule(job)
+	return job, nil
+}
+
+// Cancel removes a job that has not started yet
+func (q *LocalQueue) Cancel(ctx context.Context, jobID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	timer, ok := q.timers[jobID]
+	if !ok || !timer.Stop() {
+		return ErrJobNotFound
+	}
+	delete(q.timers, jobID)
+
+	return nil
+}
+
+// Start lets queued jobs run on up to workers goroutines until the context
+// is cancelled
+func (q *LocalQueue) Start(ctx context.Context, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	q.once.Do(func() {
+		q.mu.Lock()
+		q.ctx = ctx
+		q.sem = make(chan struct{}, workers)
+		q.mu.Unlock()
+		close(q.started)
+	})
+}
+
+func (q *LocalQueue) schedule(job *Job) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.timers[job.ID] = time.AfterFunc(time.Until(job.RunAt), func() {
+		q.mu.Lock()
+		delete(q.timers, job.ID)
+		q.mu.Unlock()
+
+		q.dispatch(job)
+	})
+}
+
+// dispatch waits for the queue to start and for a free worker slot
+func (q *LocalQueue) dispatch(job *Job) {
+	<-q.started
+
+	q.mu.Lock()
+	ctx, sem := q.ctx, q.sem
+	q.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return
+	case sem <- struct{}{}:
+	}
+	defer func() { <-sem }()
+
+	err := q.run(ctx, job)
+	if err == nil {
+		return
+	}
+
+	if errors.Is(err, ErrUnknownJobType) || job.Attempts >= job.MaxAttempts {
+		log.Printf("jobs: giving up on %s job %s after %d attempts: %v", job.Type, job.ID, job.Attempts, err)
+		return
+	}
+
+	job.LastError = err.Error()
+	job.RunAt = time.Now().Add(backoff(job.Attempts))
+	log.Printf("jobs: %s job %s failed, retrying at %s: %v", job.Type, job.ID, job.RunAt.Format(time.RFC3339), err)
+	q.schedule(job)
+}
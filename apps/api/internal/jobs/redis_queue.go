@@ -0,0 +1,217 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/database"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	readyKey     = "jobs:ready"
+	scheduledKey = "jobs:scheduled"
+	dataKey      = "jobs:data"
+
+	pollInterval = time.Second
+)
+
+// RedisQueue implements Queue on top of Redis so jobs survive restarts and
+// can be shared between API and worker processes
+type RedisQueue struct {
+	registry
+	redis *database.RedisClient
+}
+
+// NewRedisQueue creates a new Redis backed queue
+func NewRedisQueue(redisClient *database.RedisClient) *RedisQueue {
+	return &RedisQueue{
+		registry: newRegistry(),
+		redis:    redisClient,
+	}
+}
+
+// Enqueue adds a job that should run as soon as a worker is free
+func (q *RedisQueue) Enqueue(ctx context.Context, jobType string, payload interface{}) (*Job, error) {
+	return q.Schedule(ctx, jobType, payload, time.Time{})
+}
+
+// Schedule adds a job that should not run before runAt
+func (q *RedisQueue) Schedule(ctx context.Context, jobType string, payload interface{}, runAt time.Time) (*Job, error) {
+	job, err := newJob(jobType, payload, runAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := q.save(ctx, job); err != nil {
+		return nil, err
+	}
+
+	if job.RunAt.After(time.Now()) {
+		err = q.schedule(ctx, job)
+	} else {
+		err = q.redis.LPush(ctx, readyKey, job.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	return job, nil
+}
+
+// Cancel removes a job that has not started yet
+func (q *RedisQueue) Cancel(ctx context.Context, jobID string) error {
+	if _, err := q.load(ctx, jobID); err != nil {
+		return err
+	}
+
+	if _, err := q.redis.ZRem(ctx, scheduledKey, jobID); err != nil {
+		return fmt.Errorf("failed to unschedule job: %w", err)
+	}
+
+	// Workers skip ids whose data is gone, which covers jobs already promoted
+	// to the ready list
+	if err := q.redis.HDel(ctx, dataKey, jobID); err != nil {
+		return fmt.Errorf("failed to cancel job: %w", err)
+	}
+
+	return nil
+}
+
+// Start runs the promoter and the workers until the context is cancelled
+func (q *RedisQueue) Start(ctx context.Context, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	go q.promote(ctx)
+	for i := 0; i < workers; i++ {
+		go q.work(ctx)
+	}
+}
+
+// promote moves due jobs from the schedule to the ready list
+func (q *RedisQueue) promote(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		ids, err := q.redis.ZRangeByScore(ctx, scheduledKey, "-inf", now, 100)
+		if err != nil {
+			log.Printf("jobs: failed to read schedule: %v", err)
+			continue
+		}
+
+		for _, id := range ids {
+			// Only the process that removes the entry gets to promote it
+			removed, err := q.redis.ZRem(ctx, scheduledKey, id)
+			if err != nil || removed == 0 {
+				continue
+			}
+			if err := q.redis.LPush(ctx, readyKey, id); err != nil {
+				log.Printf("jobs: failed to promote job %s: %v", id, err)
+			}
+		}
+	}
+}
+
+// work pops ready jobs and runs them
+func (q *RedisQueue) work(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		result, err := q.redis.BRPop(ctx, pollInterval, readyKey)
+		if err != nil {
+			if !errors.Is(err, redis.Nil) && ctx.Err() == nil {
+				log.Printf("jobs: failed to pop job: %v", err)
+				time.Sleep(pollInterval)
+			}
+			continue
+		}
+
+		job, err := q.load(ctx, result[1])
+		if err != nil {
+			// Cancelled after it was promoted
+			continue
+		}
+
+		q.process(ctx, job)
+	}
+}
+
+func (q *RedisQueue) process(ctx context.Context, job *Job) {
+	err := q.run(ctx, job)
+	if err == nil {
+		if err := q.redis.HDel(ctx, dataKey, job.ID); err != nil {
+			log.Printf("jobs: failed to clear job %s: %v", job.ID, err)
+		}
+		return
+	}
+
+	if errors.Is(err, ErrUnknownJobType) || job.Attempts >= job.MaxAttempts {
+		log.Printf("jobs: giving up on %s job %s after %d attempts: %v", job.Type, job.ID, job.Attempts, err)
+		q.redis.HDel(ctx, dataKey, job.ID)
+		return
+	}
+
+	job.LastError = err.Error()
+	job.RunAt = time.Now().Add(backoff(job.Attempts))
+	log.Printf("jobs: %s job %s failed, retrying at %s: %v", job.Type, job.ID, job.RunAt.Format(time.RFC3339), err)
+
+	if err := q.save(ctx, job); err != nil {
+		log.Printf("jobs: failed to save job %s for retry: %v", job.ID, err)
+		return
+	}
+	if err := q.schedule(ctx, job); err != nil {
+		log.Printf("jobs: failed to reschedule job %s: %v", job.ID, err)
+	}
+}
+
+// schedule places a job on the schedule, keyed by the time it becomes due
+func (q *RedisQueue) schedule(ctx context.Context, job *Job) error {
+	return q.redis.ZAdd(ctx, scheduledKey, float64(job.RunAt.UnixMilli()), job.ID)
+}
+
+func (q *RedisQueue) save(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	if err := q.redis.HSet(ctx, dataKey, job.ID, data); err != nil {
+		return fmt.Errorf("failed to store job: %w", err)
+	}
+
+	return nil
+}
+
+func (q *RedisQueue) load(ctx context.Context, jobID string) (*Job, error) {
+	data, err := q.redis.HGet(ctx, dataKey, jobID)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to load job: %w", err)
+	}
+
+	var job Job
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+	}
+
+	return &job, nil
+}
@@ -0,0 +1,87 @@
+package locale
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	kmPerMile  = 1.609344
+	feetPerM   = 3.28084
+	timeLayout = "15:04"
+)
+
+// Number writes a number rounded to the decimals given, with thousands
+// grouped
+func (l Locale) Number(v float64, decimals int) string {
+	formatted := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	whole, fraction, _ := strings.Cut(formatted, ".")
+
+	var b strings.Builder
+	if v < 0 && strings.Trim(formatted, "0.") != "" {
+		b.WriteString("-")
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(l.group)
+		}
+		b.WriteRune(digit)
+	}
+	if fraction != "" {
+		b.WriteString(l.decimal)
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
+
+// Distance writes a distance given in kilometres, in miles for imperial
+// readers
+func (l Locale) Distance(km float64) string {
+	if l.Imperial {
+		return l.Number(km/kmPerMile, 1) + " mi"
+	}
+	return l.Number(km, 1) + " km"
+}
+
+// Elevation writes a height or climb given in metres, in feet for imperial
+// readers
+func (l Locale) Elevation(m float64) string {
+	if l.Imperial {
+		return l.Number(m*feetPerM, 0) + " ft"
+	}
+	return l.Number(m, 0) + " m"
+}
+
+// Duration writes a duration in hours and minutes, such as "5 h 30 min"
+func (l Locale) Duration(d time.Duration) string {
+	minutes := int(d.Round(time.Minute).Minutes())
+	switch {
+	case minutes < 60:
+		return fmt.Sprintf("%d min", minutes)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%d h", minutes/60)
+	default:
+		return fmt.Sprintf("%d h %d min", minutes/60, minutes%60)
+	}
+}
+
+// Date writes a calendar date in its short numeric form
+func (l Locale) Date(t time.Time) string {
+	return t.Format(l.dateLayout)
+}
+
+// Time writes a time of day on the reader's clock
+func (l Locale) Time(t time.Time) string {
+	if l.Hour12 {
+		return t.Format("3:04 PM")
+	}
+	return t.Format(timeLayout)
+}
+
+// DateTime writes a date and a time of day
+func (l Locale) DateTime(t time.Time) string {
+	return l.Date(t) + " " + l.Time(t)
+}
@@ -0,0 +1,204 @@
+// Package locale formats numbers, dates, times and measurements the way
+// readers of a language and region expect them. It covers the conventions
+// exports need, not the whole of CLDR.
+package locale
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Units preferences
+const (
+	UnitsMetric   = "metric"
+	UnitsImperial = "imperial"
+)
+
+// DefaultTag is the locale used when nothing better is known
+const DefaultTag = "en-US"
+
+var tagPattern = regexp.MustCompile(`^([a-zA-Z]{2,3})(?:[-_]([a-zA-Z]{4}))?(?:[-_]([a-zA-Z]{2}|[0-9]{3}))?(?:[-_][a-zA-Z0-9]+)*$`)
+
+// Locale is how numbers, dates, times and measurements are written for a
+// reader
+type Locale struct {
+	Tag      string // Language tag, such as "en-US"
+	Imperial bool   // Miles and feet instead of kilometres and metres
+	Hour12   bool   // "3:04 PM" instead of "15:04"
+
+	decimal    string
+	group      string
+	dateLayout string
+}
+
+// Default returns the locale used when nothing better is known
+func Default() Locale {
+	l, _ := Parse(DefaultTag)
+	return l
+}
+
+// Parse returns the locale of a language tag such as "de-CH" or "fr", and
+// false when the tag is not one. Languages without a region take the region
+// they are most used in for English, and no region otherwise.
+func Parse(tag string) (Locale, bool) {
+	match := tagPattern.FindStringSubmatch(strings.TrimSpace(tag))
+	if match == nil {
+		return Locale{}, false
+	}
+
+	language := strings.ToLower(match[1])
+	region := strings.ToUpper(match[3])
+	if region == "" && language == "en" {
+		region = "US"
+	}
+
+	l := Locale{
+		Tag:        language,
+		Imperial:   imperialRegions[region],
+		Hour12:     hour12Regions[region] && language == "en",
+		decimal:    ".",
+		group:      ",",
+		dateLayout: "2006-01-02",
+	}
+	if region != "" {
+		l.Tag += "-" + region
+	}
+
+	if group, ok := commaDecimalLanguages[language]; ok {
+		l.decimal, l.group = ",", group
+	}
+	if separators, ok := regionSeparators[language+"-"+region]; ok {
+		l.decimal, l.group = separators[0], separators[1]
+	}
+
+	if layout, ok := regionDateLayouts[region]; ok && language == "en" {
+		l.dateLayout = layout
+	} else if layout, ok := languageDateLayouts[language]; ok {
+		l.dateLayout = layout
+	}
+
+	return l, true
+}
+
+// FromAcceptLanguage returns the locale the client prefers most of those in
+// an Accept-Language header, or the default
+func FromAcceptLanguage(header string) Locale {
+	type candidate struct {
+		tag     string
+		quality float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if tag == "" || tag == "*" || quality <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{tag: tag, quality: quality})
+	}
+
+	// Equal qualities keep the order the client listed them in
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+	for _, c := range candidates {
+		if l, ok := Parse(c.tag); ok {
+			return l
+		}
+	}
+	return Default()
+}
+
+// Resolve returns the locale for a reader: their preferred language tag if
+// they set one, or else what their client asks for, with their choice of
+// units when they made one
+func Resolve(preferred, units, acceptLanguage string) Locale {
+	l, ok := Parse(preferred)
+	if !ok {
+		l = FromAcceptLanguage(acceptLanguage)
+	}
+
+	switch units {
+	case UnitsMetric:
+		l.Imperial = false
+	case UnitsImperial:
+		l.Imperial = true
+	}
+	return l
+}
+
+// ValidTag reports whether a tag is one Parse accepts
+func ValidTag(tag string) bool {
+	_, ok := Parse(tag)
+	return ok
+}
+
+// imperialRegions measure distances in miles and heights in feet
+var imperialRegions = map[string]bool{"US": true, "LR": true, "MM": true}
+
+// hour12Regions write English times on a 12-hour clock
+var hour12Regions = map[string]bool{
+	"US": true, "CA": true, "AU": true, "NZ": true, "IN": true,
+	"PH": true, "PK": true, "BD": true, "MY": true,
+}
+
+// commaDecimalLanguages write decimals with a comma, and group thousands
+// with the separator given. Spaces are non-breaking so numbers stay whole.
+var commaDecimalLanguages = map[string]string{
+	"de": ".", "es": ".", "it": ".", "pt": ".", "nl": ".", "tr": ".",
+	"id": ".", "da": ".", "ro": ".", "el": ".", "hr": ".", "sl": ".",
+	"ca": ".",
+	"fr": "\u202f", "ru": "\u00a0", "pl": "\u00a0", "sv": "\u00a0",
+	"nb": "\u00a0", "no": "\u00a0", "nn": "\u00a0", "fi": "\u00a0",
+	"cs": "\u00a0", "sk": "\u00a0", "uk": "\u00a0", "hu": "\u00a0",
+	"bg": "\u00a0", "lt": "\u00a0", "lv": "\u00a0", "et": "\u00a0",
+}
+
+// regionSeparators are the decimal and group separators of regions that
+// differ from the rest of their language
+var regionSeparators = map[string][2]string{
+	"es-MX": {".", ","},
+	"es-US": {".", ","},
+	"de-CH": {".", "’"},
+	"it-CH": {".", "’"},
+}
+
+// regionDateLayouts are the short dates of English-speaking regions
+var regionDateLayouts = map[string]string{
+	"US": "1/2/2006",
+	"PH": "1/2/2006",
+	"CA": "2006-01-02",
+	"ZA": "2006/01/02",
+	"GB": "02/01/2006",
+	"IE": "02/01/2006",
+	"AU": "02/01/2006",
+	"NZ": "02/01/2006",
+	"IN": "02/01/2006",
+}
+
+// languageDateLayouts are the short dates of languages
+var languageDateLayouts = map[string]string{
+	"en": "02/01/2006",
+	"fr": "02/01/2006", "es": "02/01/2006", "it": "02/01/2006",
+	"pt": "02/01/2006", "el": "02/01/2006", "ca": "02/01/2006",
+	"de": "02.01.2006", "ru": "02.01.2006", "pl": "02.01.2006",
+	"nb": "02.01.2006", "no": "02.01.2006", "nn": "02.01.2006",
+	"fi": "2.1.2006", "cs": "2. 1. 2006", "sk": "2. 1. 2006",
+	"tr": "02.01.2006", "uk": "02.01.2006", "ro": "02.01.2006",
+	"da": "02.01.2006", "hr": "02. 01. 2006.", "sl": "2. 1. 2006",
+	"bg": "2.01.2006 г.", "lv": "02.01.2006.", "et": "02.01.2006",
+	"nl": "2-1-2006",
+	"sv": "2006-01-02", "lt": "2006-01-02",
+	"hu": "2006. 01. 02.",
+	"ja": "2006/01/02",
+	"zh": "2006/1/2",
+	"ko": "2006. 1. 2.",
+}
@@ -0,0 +1,94 @@
+package locale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	l, ok := Parse("en_gb")
+	assert.True(t, ok)
+	assert.Equal(t, "en-GB", l.Tag)
+	assert.False(t, l.Imperial)
+	assert.False(t, l.Hour12)
+
+	// English without a region reads as American English
+	l, ok = Parse("en")
+	assert.True(t, ok)
+	assert.Equal(t, "en-US", l.Tag)
+	assert.True(t, l.Imperial)
+	assert.True(t, l.Hour12)
+
+	// Scripts and extensions are accepted and dropped
+	l, ok = Parse("zh-Hant-TW")
+	assert.True(t, ok)
+	assert.Equal(t, "zh-TW", l.Tag)
+
+	for _, tag := range []string{"", "*", "e", "english", "en-", "12-US"} {
+		_, ok := Parse(tag)
+		assert.False(t, ok, tag)
+	}
+}
+
+func TestFromAcceptLanguage(t *testing.T) {
+	assert.Equal(t, "fr-CA", FromAcceptLanguage("de;q=0.5, fr-CA, en;q=0.8").Tag)
+	assert.Equal(t, "de", FromAcceptLanguage("*, x;q=1, de;q=0.1").Tag)
+	assert.Equal(t, "nl", FromAcceptLanguage("nl, de").Tag, "ties keep the client's order")
+	assert.Equal(t, DefaultTag, FromAcceptLanguage("").Tag)
+	assert.Equal(t, DefaultTag, FromAcceptLanguage("fr;q=0, de;q=bad").Tag)
+}
+
+func TestResolve(t *testing.T) {
+	l := Resolve("en-GB", "", "de-DE")
+	assert.Equal(t, "en-GB", l.Tag)
+	assert.False(t, l.Imperial)
+
+	l = Resolve("", UnitsImperial, "de-DE")
+	assert.Equal(t, "de-DE", l.Tag)
+	assert.True(t, l.Imperial)
+
+	l = Resolve("not a tag", UnitsMetric, "en-US")
+	assert.Equal(t, "en-US", l.Tag)
+	assert.False(t, l.Imperial)
+}
+
+func TestLocale_Format(t *testing.T) {
+	at := time.Date(2026, 6, 4, 15, 7, 0, 0, time.UTC)
+
+	tests := []struct {
+		tag       string
+		number    string
+		distance  string
+		elevation string
+		date      string
+		time      string
+	}{
+		{"en-US", "1,234,567.89", "6.2 mi", "4,921 ft", "6/4/2026", "3:07 PM"},
+		{"en-GB", "1,234,567.89", "10.0 km", "1,500 m", "04/06/2026", "15:07"},
+		{"en-CA", "1,234,567.89", "10.0 km", "1,500 m", "2026-06-04", "3:07 PM"},
+		{"de-DE", "1.234.567,89", "10,0 km", "1.500 m", "04.06.2026", "15:07"},
+		{"de-CH", "1’234’567.89", "10.0 km", "1’500 m", "04.06.2026", "15:07"},
+		{"fr-FR", "1\u202f234\u202f567,89", "10,0 km", "1\u202f500 m", "04/06/2026", "15:07"},
+		{"fr-CA", "1\u202f234\u202f567,89", "10,0 km", "1\u202f500 m", "04/06/2026", "15:07"},
+		{"ja-JP", "1,234,567.89", "10.0 km", "1,500 m", "2026/06/04", "15:07"},
+	}
+	for _, tt := range tests {
+		l, ok := Parse(tt.tag)
+		assert.True(t, ok, tt.tag)
+		assert.Equal(t, tt.number, l.Number(1234567.891, 2), tt.tag)
+		assert.Equal(t, tt.distance, l.Distance(10), tt.tag)
+		assert.Equal(t, tt.elevation, l.Elevation(1500), tt.tag)
+		assert.Equal(t, tt.date, l.Date(at), tt.tag)
+		assert.Equal(t, tt.time, l.Time(at), tt.tag)
+	}
+
+	l := Default()
+	assert.Equal(t, "-1,000", l.Number(-999.6, 0))
+	assert.Equal(t, "0", l.Number(-0.4, 0))
+	assert.Equal(t, "45 min", l.Duration(44*time.Minute+40*time.Second))
+	assert.Equal(t, "2 h", l.Duration(2*time.Hour))
+	assert.Equal(t, "5 h 30 min", l.Duration(5*time.Hour+30*time.Minute))
+	assert.Equal(t, "6/4/2026 3:07 PM", l.DateTime(at))
+}
@@ -0,0 +1,17 @@
+// Package mapbox holds what the clients of the Mapbox APIs share
+package mapbox
+
+import (
+	"errors"
+	"net/url"
+)
+
+// StripURL unwraps the *url.Error an HTTP client returns, so the request URL,
+// and with it the access token, stays out of errors and logs
+func StripURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,26 @@
+package mapbox
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripURL(t *testing.T) {
+	cause := errors.New("connection refused")
+
+	t.Run("drops the URL", func(t *testing.T) {
+		err := &url.Error{Op: "Get", URL: "https://api.mapbox.com/x?access_token=secret", Err: cause}
+
+		stripped := StripURL(err)
+
+		assert.Equal(t, cause, stripped)
+		assert.NotContains(t, stripped.Error(), "secret")
+	})
+
+	t.Run("leaves other errors alone", func(t *testing.T) {
+		assert.Equal(t, cause, StripURL(cause))
+	})
+}
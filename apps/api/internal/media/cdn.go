@@ -0,0 +1,61 @@
+package media
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// versionParam is the query parameter that busts CDN caches when a file
+// changes
+const versionParam = "v"
+
+// CDN rewrites the URLs of stored files, which point at the API's /media
+// route, to a CDN host. A file keeps the same version for as long as it is
+// unchanged, so every response hands out the same URL and the CDN serves it
+// from cache instead of fetching it from the API again.
+//
+// A nil CDN leaves URLs as they are, which is how development serves files.
+type CDN struct {
+	originURL string
+	baseURL   string
+}
+
+// NewCDN creates a CDN for files stored under originURL, the storage's
+// public URL, to be served from baseURL
+func NewCDN(originURL, baseURL string) *CDN {
+	return &CDN{
+		originURL: strings.TrimRight(originURL, "/"),
+		baseURL:   strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// URL returns the CDN URL of a stored file's URL, versioned by when the file
+// was last written. URLs that are not under the origin are returned
+// unchanged, as are signed URLs: their signatures change with every
+// response, so the CDN would only ever miss and pay for the file twice.
+func (c *CDN) URL(rawURL string, modifiedAt time.Time) string {
+	if c == nil {
+		return rawURL
+	}
+
+	prefix := c.originURL + "/"
+	if !strings.HasPrefix(rawURL, prefix) {
+		return rawURL
+	}
+
+	filePath, rawQuery, _ := strings.Cut(strings.TrimPrefix(rawURL, prefix), "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil || query.Has(signatureParam) {
+		return rawURL
+	}
+
+	if !modifiedAt.IsZero() {
+		query.Set(versionParam, strconv.FormatInt(modifiedAt.Unix(), 36))
+	}
+	if len(query) == 0 {
+		return c.baseURL + "/" + filePath
+	}
+	return c.baseURL + "/" + filePath + "?" + query.Encode()
+}
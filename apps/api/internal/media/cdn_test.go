@@ -0,0 +1,35 @@
+package media
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCDN_URL(t *testing.T) {
+	cdn := NewCDN("http://localhost:8080/media", "https://cdn.example.com/")
+	uploadedAt := time.Unix(1714521600, 0)
+
+	assert.Equal(t, "https://cdn.example.com/images/original/2024/05/01/abc.jpg?v=scs5c0",
+		cdn.URL("http://localhost:8080/media/images/original/2024/05/01/abc.jpg", uploadedAt))
+
+	// The version follows the file, so a rewritten file gets a new URL
+	assert.NotEqual(t,
+		cdn.URL("http://localhost:8080/media/images/original/2024/05/01/abc.jpg", uploadedAt),
+		cdn.URL("http://localhost:8080/media/images/original/2024/05/01/abc.jpg", uploadedAt.Add(time.Second)))
+
+	assert.Equal(t, "https://cdn.example.com/images/original/2024/05/01/abc.jpg",
+		cdn.URL("http://localhost:8080/media/images/original/2024/05/01/abc.jpg", time.Time{}))
+
+	// Files stored elsewhere and signed URLs stay where they are
+	assert.Equal(t, "https://res.cloudinary.com/demo/abc.jpg",
+		cdn.URL("https://res.cloudinary.com/demo/abc.jpg", uploadedAt))
+	signed := "http://localhost:8080/media/images/original/2024/05/01/abc.jpg?expires=1714522500&signature=00ff"
+	assert.Equal(t, signed, cdn.URL(signed, uploadedAt))
+
+	// Without a CDN, files are served by the API
+	var local *CDN
+	assert.Equal(t, "http://localhost:8080/media/images/original/2024/05/01/abc.jpg",
+		local.URL("http://localhost:8080/media/images/original/2024/05/01/abc.jpg", uploadedAt))
+}
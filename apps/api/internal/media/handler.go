@@ -1,39 +1,56 @@
 package media
 
 import (
-	"fmt"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"os"
 	"strconv"
 
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
 	"github.com/gin-gonic/gin"
 )
 
 // Handler handles media-related HTTP requests
 type Handler struct {
 	service *Service
+	limits  UploadLimits
 }
 
+// defaultMaxUploadSize is the size uploads are limited to unless the
+// handler is given limits of its own
+const defaultMaxUploadSize = 50 * 1024 * 1024
+
 // NewHandler creates a new media handler
 func NewHandler(service *Service) *Handler {
 	return &Handler{
 		service: service,
+		limits:  DefaultUploadLimits(defaultMaxUploadSize),
 	}
 }
 
+// SetUploadLimits sets the limits uploads are checked against while they
+// stream in
+func (h *Handler) SetUploadLimits(limits UploadLimits) {
+	h.limits = limits
+}
+
 // RegisterRoutes registers media routes
-func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
-	media := router.Group("/media")
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *users.RouteMiddleware) {
+	// Cloudinary endpoints (public - no auth required for hero images)
+	router.POST("/media/cloudinary/sign", SignCloudinaryURL)
+	router.GET("/media/cloudinary/config", GetCloudinaryConfig)
+	router.POST("/media/cloudinary/list", ListCloudinaryImages)
+
+	media := router.Group("/media", mw.RequireAuth, mw.LimitUploadBody, ValidateFileUpload(h.limits))
 	{
 		media.POST("/upload", h.UploadMedia)
 		media.GET("/:id", h.GetMedia)
 		media.DELETE("/:id", h.DeleteMedia)
 		media.GET("/user/:userID", h.GetUserMedia)
 		media.POST("/:id/attach", h.AttachMedia)
-		
-		// Cloudinary endpoints (public - no auth required for hero images)
-		media.POST("/cloudinary/sign", SignCloudinaryURL)
-		media.GET("/cloudinary/config", GetCloudinaryConfig)
-		media.POST("/cloudinary/list", ListCloudinaryImages)
 	}
 }
 
@@ -250,6 +267,14 @@ func (h *Handler) ServeMedia(storage Storage) gin.HandlerFunc {
 			return
 		}
 
+		// Signed URLs let files be fetched without a session
+		if verifier, ok := storage.(URLVerifier); ok {
+			if err := verifier.VerifyURL(path, c.Request.URL.Query()); err != nil {
+				c.Status(http.StatusForbidden)
+				return
+			}
+		}
+
 		fullPath := storage.GetFullPath(path)
 		
 		// Check if file exists
@@ -262,23 +287,104 @@ func (h *Handler) ServeMedia(storage Storage) gin.HandlerFunc {
 	}
 }
 
-// Middleware to validate file upload
-func ValidateFileUpload(maxSize int64) gin.HandlerFunc {
+// ValidateFileUpload checks multipart uploads against the limits while
+// reading them, so an oversized file or one that would expand dangerously
+// when processed is turned away before it has been buffered. Validated parts
+// are spooled to a temporary file from which the form is parsed for the
+// handler. Other requests pass through.
+func ValidateFileUpload(limits UploadLimits) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
-		
-		if err := c.Request.ParseMultipartForm(maxSize); err != nil {
-			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
-				"success": false,
-				"error": map[string]interface{}{
-					"code":    "FILE_TOO_LARGE",
-					"message": fmt.Sprintf("File size exceeds maximum allowed size of %d bytes", maxSize),
-				},
-			})
-			c.Abort()
+		mediaType, params, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if err != nil || mediaType != "multipart/form-data" {
+			c.Next()
 			return
 		}
-		
+
+		spool, err := os.CreateTemp("", "upload-*")
+		if err != nil {
+			abortUpload(c, err)
+			return
+		}
+		defer func() {
+			spool.Close()
+			os.Remove(spool.Name())
+		}()
+
+		body := http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxSize)
+		writer, err := spoolMultipart(spool, multipart.NewReader(body, params["boundary"]), limits)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				err = tooLarge(limits.MaxSize)
+			}
+			abortUpload(c, err)
+			return
+		}
+
+		size, err := spool.Seek(0, io.SeekCurrent)
+		if err == nil {
+			_, err = spool.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			abortUpload(c, err)
+			return
+		}
+
+		c.Request.Body = io.NopCloser(spool)
+		c.Request.ContentLength = size
+		c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+		if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+			abortUpload(c, err)
+			return
+		}
+		defer c.Request.MultipartForm.RemoveAll()
+
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// spoolMultipart copies a multipart body to dst part by part, checking each
+// file against the limits on the way
+func spoolMultipart(dst io.Writer, reader *multipart.Reader, limits UploadLimits) (*multipart.Writer, error) {
+	writer := multipart.NewWriter(dst)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return nil, err
+		}
+		if part.FileName() == "" {
+			_, err = io.Copy(out, part)
+		} else {
+			err = limits.copyFilePart(out, part)
+		}
+		part.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return writer, writer.Close()
+}
+
+// abortUpload rejects an upload, with the limit it broke when it broke one
+func abortUpload(c *gin.Context, err error) {
+	limitErr := &LimitError{Status: http.StatusBadRequest, Code: "INVALID_UPLOAD", Message: "Upload could not be read"}
+	errors.As(err, &limitErr)
+
+	c.JSON(limitErr.Status, gin.H{
+		"success": false,
+		"error": map[string]interface{}{
+			"code":    limitErr.Code,
+			"message": limitErr.Message,
+		},
+	})
+	c.Abort()
+}
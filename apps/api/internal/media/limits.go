@@ -0,0 +1,314 @@
+package media
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// Upload kinds with limits of their own
+const (
+	KindImage = "image"
+	KindVideo = "video"
+	KindGPX   = "gpx"
+	KindJSON  = "json"
+)
+
+// TypeLimits bounds what an upload of one kind may contain. Zero means no
+// limit beyond the overall size.
+type TypeLimits struct {
+	MaxSize      int64 // Bytes
+	MaxDimension int   // Width or height of an image, in pixels
+	MaxPixels    int64 // Width times height of an image
+	MaxPoints    int   // Track, route and waypoints in a GPX file
+	MaxDepth     int   // Nesting of a JSON document
+}
+
+// UploadLimits are the limits ValidateFileUpload enforces
+type UploadLimits struct {
+	MaxSize int64 // Whole request, in bytes
+	Kinds   map[string]TypeLimits
+}
+
+// DefaultUploadLimits keeps every upload within maxSize and caps the content
+// of the kinds that can expand far beyond their size when processed
+func DefaultUploadLimits(maxSize int64) UploadLimits {
+	return UploadLimits{
+		MaxSize: maxSize,
+		Kinds: map[string]TypeLimits{
+			// A 12000x12000 image decodes to over half a gigabyte
+			KindImage: {MaxDimension: 12000, MaxPixels: 50_000_000},
+			KindVideo: {},
+			KindGPX:   {MaxSize: 20 * 1024 * 1024, MaxPoints: 100_000},
+			KindJSON:  {MaxSize: 5 * 1024 * 1024, MaxDepth: 32},
+		},
+	}
+}
+
+// LimitError is an upload that breaks a limit
+type LimitError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *LimitError) Error() string {
+	return e.Message
+}
+
+func tooLarge(limit int64) *LimitError {
+	return &LimitError{
+		Status:  http.StatusRequestEntityTooLarge,
+		Code:    "FILE_TOO_LARGE",
+		Message: fmt.Sprintf("File size exceeds maximum allowed size of %d bytes", limit),
+	}
+}
+
+func unprocessable(code, format string, args ...interface{}) *LimitError {
+	return &LimitError{
+		Status:  http.StatusUnprocessableEntity,
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// sniffSize is how much of a file is read to tell its kind
+const sniffSize = 512
+
+// copyFilePart copies an uploaded file to dst while checking it against the
+// limits of its kind. Checks run on the stream, so a file that breaks a limit
+// is rejected as soon as that is known rather than after it has been read.
+func (l UploadLimits) copyFilePart(dst io.Writer, part *multipart.Part) error {
+	src := bufio.NewReaderSize(part, sniffSize)
+	head, err := src.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return err
+	}
+
+	kind := uploadKind(part, head)
+	limits := l.Kinds[kind]
+
+	var r io.Reader = src
+	if limits.MaxSize > 0 {
+		r = &sizeLimitReader{r: r, remaining: limits.MaxSize, limit: limits.MaxSize}
+	}
+	r = io.TeeReader(r, dst)
+
+	switch kind {
+	case KindImage:
+		err = checkImage(r, limits)
+	case KindGPX:
+		err = checkGPX(r, limits)
+	case KindJSON:
+		err = checkJSON(r, limits)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Whatever the check did not need still has to be copied
+	_, err = io.Copy(io.Discard, r)
+	return err
+}
+
+// uploadKind tells the kind of an uploaded file from its content where that
+// is reliable, and from its declared type or extension for text formats
+func uploadKind(part *multipart.Part, head []byte) string {
+	sniffed := http.DetectContentType(head)
+	if isWebP(head) {
+		sniffed = "image/webp"
+	}
+	switch {
+	case strings.HasPrefix(sniffed, "image/"):
+		return KindImage
+	case strings.HasPrefix(sniffed, "video/"):
+		return KindVideo
+	}
+
+	declared, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+	switch {
+	case declared == "application/gpx+xml" || strings.EqualFold(filepath.Ext(part.FileName()), ".gpx"):
+		return KindGPX
+	case declared == "application/json" || declared == "application/geo+json":
+		return KindJSON
+	}
+	switch strings.ToLower(filepath.Ext(part.FileName())) {
+	case ".json", ".geojson":
+		return KindJSON
+	}
+
+	return ""
+}
+
+// checkImage reads the image header and checks its dimensions, so that an
+// image that would take gigabytes to decode is never decoded
+func checkImage(r io.Reader, limits TypeLimits) error {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(30)
+
+	var width, height int
+	if isWebP(head) {
+		w, h, err := webpDimensions(head)
+		if err != nil {
+			return unprocessable("INVALID_IMAGE", "Image could not be read: %v", err)
+		}
+		width, height = w, h
+	} else {
+		config, _, err := image.DecodeConfig(br)
+		if err != nil {
+			if limitBroken(err) {
+				return err
+			}
+			return unprocessable("INVALID_IMAGE", "Image could not be read: %v", err)
+		}
+		width, height = config.Width, config.Height
+	}
+
+	if limits.MaxDimension > 0 && (width > limits.MaxDimension || height > limits.MaxDimension) {
+		return unprocessable("IMAGE_TOO_LARGE", "Image is %dx%d pixels, sides are limited to %d", width, height, limits.MaxDimension)
+	}
+	if limits.MaxPixels > 0 && int64(width)*int64(height) > limits.MaxPixels {
+		return unprocessable("IMAGE_TOO_LARGE", "Image is %dx%d pixels, images are limited to %d pixels", width, height, limits.MaxPixels)
+	}
+
+	// The rest of the image is copied by the caller
+	_, err := io.Copy(io.Discard, br)
+	return err
+}
+
+func isWebP(head []byte) bool {
+	return len(head) >= 12 && string(head[0:4]) == "RIFF" && string(head[8:12]) == "WEBP"
+}
+
+// webpDimensions reads the canvas size from the first chunk of a WebP file
+func webpDimensions(head []byte) (int, int, error) {
+	if len(head) < 30 {
+		return 0, 0, errors.New("truncated header")
+	}
+
+	switch string(head[12:16]) {
+	case "VP8X":
+		// 24-bit canvas width and height, each stored minus one
+		width := int(head[24]) | int(head[25])<<8 | int(head[26])<<16
+		height := int(head[27]) | int(head[28])<<8 | int(head[29])<<16
+		return width + 1, height + 1, nil
+	case "VP8L":
+		// 14-bit width and height minus one, after a signature byte
+		bits := binary.LittleEndian.Uint32(head[21:25])
+		return int(bits&0x3FFF) + 1, int(bits>>14&0x3FFF) + 1, nil
+	case "VP8 ":
+		// 14-bit width and height after the frame tag and start code
+		width := binary.LittleEndian.Uint16(head[26:28]) & 0x3FFF
+		height := binary.LittleEndian.Uint16(head[28:30]) & 0x3FFF
+		return int(width), int(height), nil
+	}
+
+	return 0, 0, errors.New("unknown WebP format")
+}
+
+// checkGPX counts the points of a GPX file as it is read
+func checkGPX(r io.Reader, limits TypeLimits) error {
+	decoder := xml.NewDecoder(r)
+	points := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if limitBroken(err) {
+				return err
+			}
+			return unprocessable("INVALID_GPX", "GPX file could not be read: %v", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "trkpt", "rtept", "wpt":
+			points++
+			if limits.MaxPoints > 0 && points > limits.MaxPoints {
+				return unprocessable("TOO_MANY_POINTS", "GPX files are limited to %d points", limits.MaxPoints)
+			}
+		}
+	}
+}
+
+// checkJSON tracks the nesting of a JSON document as it is read
+func checkJSON(r io.Reader, limits TypeLimits) error {
+	decoder := json.NewDecoder(r)
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if limitBroken(err) {
+				return err
+			}
+			return unprocessable("INVALID_JSON", "JSON file could not be read: %v", err)
+		}
+
+		delim, ok := token.(json.Delim)
+		if !ok {
+			continue
+		}
+		switch delim {
+		case '{', '[':
+			depth++
+			if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+				return unprocessable("JSON_TOO_DEEP", "JSON files are limited to %d levels of nesting", limits.MaxDepth)
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+}
+
+// limitBroken reports whether a read failed because a size limit was reached,
+// which is reported as such rather than as a malformed file
+func limitBroken(err error) bool {
+	var limitErr *LimitError
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &limitErr) || errors.As(err, &maxBytesErr)
+}
+
+// sizeLimitReader fails once more than its limit has been read
+type sizeLimitReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, tooLarge(l.limit)
+	}
+	// Read one byte past the limit to tell a file that fits exactly from one
+	// that does not
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, tooLarge(l.limit)
+	}
+	return n, err
+}
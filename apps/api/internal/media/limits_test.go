@@ -0,0 +1,145 @@
+package media
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLimits() UploadLimits {
+	return UploadLimits{
+		MaxSize: 1 << 20,
+		Kinds: map[string]TypeLimits{
+			KindImage: {MaxDimension: 1000, MaxPixels: 250_000},
+			KindGPX:   {MaxPoints: 3},
+			KindJSON:  {MaxSize: 1024, MaxDepth: 4},
+		},
+	}
+}
+
+// uploadRouter echoes the uploaded file back, as a handler sees it
+func uploadRouter(limits UploadLimits) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/upload", ValidateFileUpload(limits), func(c *gin.Context) {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		file, _ := header.Open()
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+		c.Data(http.StatusOK, "application/octet-stream", data)
+	})
+	router.GET("/upload", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func upload(t *testing.T, router *gin.Engine, filename string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	require.NoError(t, writer.WriteField("caption", "Summit"))
+	part, err := writer.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	_, err = part.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return body.Error.Code
+}
+
+func pngImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func TestValidateFileUpload_Passes(t *testing.T) {
+	router := uploadRouter(testLimits())
+
+	// The handler sees exactly what was sent
+	photo := pngImage(t, 400, 300)
+	rec := upload(t, router, "summit.png", photo)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, photo, rec.Body.Bytes())
+
+	gpx := []byte(`<gpx><trk><trkseg><trkpt lat="1" lon="2"/><trkpt lat="1" lon="3"/></trkseg></trk><wpt lat="1" lon="2"/></gpx>`)
+	rec = upload(t, router, "route.gpx", gpx)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, gpx, rec.Body.Bytes())
+
+	// Requests that are not uploads are left alone
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/upload", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestValidateFileUpload_Limits(t *testing.T) {
+	router := uploadRouter(testLimits())
+
+	tests := []struct {
+		name     string
+		filename string
+		data     []byte
+		status   int
+		code     string
+	}{
+		{"wide image", "wide.png", pngImage(t, 1200, 10), http.StatusUnprocessableEntity, "IMAGE_TOO_LARGE"},
+		{"too many pixels", "big.png", pngImage(t, 600, 600), http.StatusUnprocessableEntity, "IMAGE_TOO_LARGE"},
+		{"broken image", "broken.png", pngImage(t, 10, 10)[:20], http.StatusUnprocessableEntity, "INVALID_IMAGE"},
+		{"too many points", "route.gpx", []byte(`<gpx><wpt/><wpt/><trk><trkseg><trkpt/><trkpt/></trkseg></trk></gpx>`), http.StatusUnprocessableEntity, "TOO_MANY_POINTS"},
+		{"deep JSON", "deep.json", []byte(`{"a":[[{"b":[1]}]]}`), http.StatusUnprocessableEntity, "JSON_TOO_DEEP"},
+		{"large JSON", "large.geojson", []byte(`["` + strings.Repeat("x", 2048) + `"]`), http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE"},
+		{"large request", "clip.mp4", bytes.Repeat([]byte{0}, 2<<20), http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := upload(t, router, tt.filename, tt.data)
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.code, errorCode(t, rec))
+		})
+	}
+}
+
+func TestWebPDimensions(t *testing.T) {
+	// VP8X header for a 20000x3 canvas
+	head := make([]byte, 30)
+	copy(head, "RIFF\x00\x00\x00\x00WEBPVP8X")
+	head[24], head[25], head[26] = 0x1F, 0x4E, 0x00 // 19999
+	head[27] = 2
+
+	width, height, err := webpDimensions(head)
+	require.NoError(t, err)
+	assert.Equal(t, 20000, width)
+	assert.Equal(t, 3, height)
+}
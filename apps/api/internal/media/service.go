@@ -3,24 +3,84 @@ package media
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"mime/multipart"
+	"strings"
+	"time"
 
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
 	"github.com/jmoiron/sqlx"
 )
 
+// JobThumbnails makes the thumbnails of an uploaded image
+const JobThumbnails = "media.thumbnails"
+
+type thumbnailsPayload struct {
+	MediaID string `json:"media_id"`
+}
+
 // Service handles media operations
 type Service struct {
-	db      *sqlx.DB
-	storage Storage
+	db        *sqlx.DB
+	storage   Storage
+	urlExpiry time.Duration
+	cdn       *CDN
+	queue     jobs.Queue
 }
 
 // NewService creates a new media service
 func NewService(db *sqlx.DB, storage Storage) *Service {
 	return &Service{
-		db:      db,
-		storage: storage,
+		db:        db,
+		storage:   storage,
+		urlExpiry: defaultURLExpiry,
+	}
+}
+
+// SetURLExpiry sets how long the signed URLs handed out by the service last
+func (s *Service) SetURLExpiry(expiry time.Duration) {
+	if expiry > 0 {
+		s.urlExpiry = expiry
+	}
+}
+
+// SetCDN serves the files handed out by the service from a CDN
+func (s *Service) SetCDN(cdn *CDN) {
+	s.cdn = cdn
+}
+
+// SetJobQueue makes thumbnails in the background. Until it is set, they are
+// made while the upload request waits.
+func (s *Service) SetJobQueue(queue jobs.Queue) {
+	s.queue = queue
+	queue.Register(JobThumbnails, s.makeThumbnails)
+}
+
+// signURLs replaces the URLs of the files with signed ones when the storage
+// supports it, and points the unsigned ones at the CDN. Stored records keep
+// their plain URLs; signatures and CDN hosts are only ever handed out.
+func (s *Service) signURLs(files ...*MediaFile) error {
+	signer, _ := s.storage.(URLSigner)
+
+	for _, file := range files {
+		for _, u := range []*string{&file.URL, &file.ThumbnailSmall, &file.ThumbnailMedium, &file.ThumbnailLarge} {
+			if *u == "" {
+				continue
+			}
+			if signer != nil {
+				signed, err := signer.SignURL(*u, s.urlExpiry)
+				if err != nil {
+					return fmt.Errorf("failed to sign media URL: %w", err)
+				}
+				*u = signed
+			}
+			*u = s.cdn.URL(*u, file.UploadedAt)
+		}
 	}
+
+	return nil
 }
 
 // UploadMedia handles file upload and database record creation
@@ -63,6 +123,14 @@ func (s *Service) UploadMedia(ctx context.Context, file *multipart.FileHeader, u
 		return nil, fmt.Errorf("failed to save media record: %w", err)
 	}
 
+	if strings.HasPrefix(mediaFile.MimeType, "image/") {
+		s.queueThumbnails(ctx, mediaFile)
+	}
+
+	if err := s.signURLs(mediaFile); err != nil {
+		return nil, err
+	}
+
 	return mediaFile, nil
 }
 
@@ -86,6 +154,10 @@ func (s *Service) GetMedia(ctx context.Context, mediaID string) (*MediaFile, err
 		return nil, fmt.Errorf("failed to get media: %w", err)
 	}
 
+	if err := s.signURLs(&media); err != nil {
+		return nil, err
+	}
+
 	return &media, nil
 }
 
@@ -177,6 +249,10 @@ func (s *Service) GetEntityMedia(ctx context.Context, entityType, entityID strin
 		return nil, fmt.Errorf("failed to get entity media: %w", err)
 	}
 
+	if err := s.signURLs(media...); err != nil {
+		return nil, err
+	}
+
 	return media, nil
 }
 
@@ -198,6 +274,10 @@ func (s *Service) GetUserMedia(ctx context.Context, userID string, limit, offset
 		return nil, fmt.Errorf("failed to get user media: %w", err)
 	}
 
+	if err := s.signURLs(media...); err != nil {
+		return nil, err
+	}
+
 	return media, nil
 }
 
@@ -219,4 +299,81 @@ func (s *Service) CleanupUnusedMedia(ctx context.Context, olderThanDays int) err
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+// queueThumbnails has the thumbnails of an uploaded image made. The upload
+// itself has succeeded, so a failure is only logged; the image is shown in
+// full size until its thumbnails exist.
+func (s *Service) queueThumbnails(ctx context.Context, mediaFile *MediaFile) {
+	if _, ok := s.storage.(ThumbnailGenerator); !ok {
+		return
+	}
+
+	if s.queue != nil {
+		if _, err := s.queue.Enqueue(ctx, JobThumbnails, thumbnailsPayload{MediaID: mediaFile.ID}); err != nil {
+			log.Printf("Failed to queue thumbnails for media %s: %v", mediaFile.ID, err)
+		}
+		return
+	}
+
+	thumbnails, err := s.generateThumbnails(ctx, mediaFile.ID, mediaFile.StoragePath)
+	if err != nil {
+		log.Printf("Failed to make thumbnails for media %s: %v", mediaFile.ID, err)
+		return
+	}
+	mediaFile.ThumbnailSmall = thumbnails.Small
+	mediaFile.ThumbnailMedium = thumbnails.Medium
+	mediaFile.ThumbnailLarge = thumbnails.Large
+	mediaFile.Width = thumbnails.Width
+	mediaFile.Height = thumbnails.Height
+}
+
+// makeThumbnails runs the thumbnail job of an uploaded image
+func (s *Service) makeThumbnails(ctx context.Context, job *jobs.Job) error {
+	var payload thumbnailsPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	var storagePath string
+	err := s.db.GetContext(ctx, &storagePath, `SELECT storage_path FROM media WHERE id = $1`, payload.MediaID)
+	if err == sql.ErrNoRows {
+		// Deleted before its thumbnails were made
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get media: %w", err)
+	}
+
+	_, err = s.generateThumbnails(ctx, payload.MediaID, storagePath)
+	if errors.Is(err, ErrUnsupportedImage) {
+		// Trying again would not help
+		log.Printf("Skipping thumbnails for media %s: %v", payload.MediaID, err)
+		return nil
+	}
+	return err
+}
+
+// generateThumbnails makes the thumbnails of an image and records them
+func (s *Service) generateThumbnails(ctx context.Context, mediaID, storagePath string) (*Thumbnails, error) {
+	generator, ok := s.storage.(ThumbnailGenerator)
+	if !ok {
+		return nil, fmt.Errorf("storage cannot make thumbnails")
+	}
+
+	thumbnails, err := generator.GenerateThumbnails(storagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE media
+		SET thumbnail_small = $2, thumbnail_medium = $3, thumbnail_large = $4,
+			width = $5, height = $6
+		WHERE id = $1`,
+		mediaID, thumbnails.Small, thumbnails.Medium, thumbnails.Large, thumbnails.Width, thumbnails.Height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save thumbnails: %w", err)
+	}
+
+	return thumbnails, nil
+}
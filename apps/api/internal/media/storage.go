@@ -2,9 +2,11 @@ package media
 
 import (
 	"crypto/md5"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,15 +15,27 @@ import (
 	"github.com/Oferzz/newMap/apps/api/internal/config"
 )
 
+// ErrFileNotFound is returned for a path with no stored file
+var ErrFileNotFound = errors.New("file not found")
+
 // Storage defines the interface for media storage
 type Storage interface {
 	Upload(file *multipart.FileHeader, userID string) (*MediaFile, error)
+	Save(filePath string, data []byte) (string, error)
+	Open(filePath string) (io.ReadCloser, error)
+	Stat(filePath string) (*FileInfo, error)
 	Delete(filePath string) error
 	GetURL(filePath string) string
 	GetFullPath(filePath string) string
 	EnsureDirectories() error
 }
 
+// FileInfo describes a stored file
+type FileInfo struct {
+	Size    int64
+	ModTime time.Time
+}
+
 // MediaFile represents a stored media file
 type MediaFile struct {
 	ID              string    `json:"id"`
@@ -45,6 +59,7 @@ type DiskStorage struct {
 	basePath string
 	cdnURL   string
 	config   *config.MediaConfig
+	signer   *Signer // nil when URLs are not signed
 }
 
 // NewDiskStorage creates a new disk storage instance
@@ -55,6 +70,12 @@ func NewDiskStorage(cfg *config.MediaConfig) (*DiskStorage, error) {
 		config:   cfg,
 	}
 
+	if cfg.URLSecret != "" {
+		storage.signer = NewSigner(cfg.URLSecret)
+	} else if cfg.RequireSignedURLs {
+		return nil, fmt.Errorf("signed media URLs are required but MEDIA_URL_SECRET is not set")
+	}
+
 	// Ensure base directories exist
 	if err := storage.EnsureDirectories(); err != nil {
 		return nil, fmt.Errorf("failed to create storage directories: %w", err)
@@ -166,13 +187,54 @@ func (s *DiskStorage) Upload(fileHeader *multipart.FileHeader, userID string) (*
 		UploadedAt:   time.Now(),
 	}
 
-	// Process thumbnails for images asynchronously
-	if strings.HasPrefix(mimeType, "image/") {
-		// In a real implementation, this would be done asynchronously
-		go s.generateThumbnails(fullPath, relativePath, mediaFile)
+	return mediaFile, nil
+}
+
+// Save writes generated content to the given relative path, replacing any
+// existing file, and returns its public URL
+func (s *DiskStorage) Save(filePath string, data []byte) (string, error) {
+	fullPath := filepath.Join(s.basePath, filePath)
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	return mediaFile, nil
+	// Write to a temp file first so readers never see a partial file
+	tmpPath := fullPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmpPath, fullPath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+
+	return s.GetURL(filePath), nil
+}
+
+// Open reads a stored file. It returns ErrFileNotFound when there is none.
+func (s *DiskStorage) Open(filePath string) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Join(s.basePath, filePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return file, nil
+}
+
+// Stat describes a stored file. It returns ErrFileNotFound when there is
+// none.
+func (s *DiskStorage) Stat(filePath string) (*FileInfo, error) {
+	info, err := os.Stat(filepath.Join(s.basePath, filePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	return &FileInfo{Size: info.Size(), ModTime: info.ModTime()}, nil
 }
 
 // Delete removes a file from disk
@@ -197,6 +259,45 @@ func (s *DiskStorage) GetURL(filePath string) string {
 	return fmt.Sprintf("%s/%s", s.cdnURL, filePath)
 }
 
+// SignURL returns a URL that serves the file until the expiry has passed.
+// URLs that are not under the storage's base URL are returned unchanged, as
+// are all URLs when signing is not configured.
+func (s *DiskStorage) SignURL(rawURL string, expiry time.Duration) (string, error) {
+	prefix := s.cdnURL + "/"
+	if s.signer == nil || !strings.HasPrefix(rawURL, prefix) {
+		return rawURL, nil
+	}
+
+	filePath, rawQuery, _ := strings.Cut(strings.TrimPrefix(rawURL, prefix), "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse media URL: %w", err)
+	}
+
+	// Requests are checked against the decoded path they are served from
+	decoded, err := url.PathUnescape(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse media URL: %w", err)
+	}
+	for key, values := range s.signer.Query(decoded, expiry) {
+		query[key] = values
+	}
+
+	return prefix + filePath + "?" + query.Encode(), nil
+}
+
+// VerifyURL checks the signature of a request for a file. Unsigned requests
+// pass unless signed URLs are required.
+func (s *DiskStorage) VerifyURL(filePath string, query url.Values) error {
+	if s.signer == nil {
+		return nil
+	}
+	if query.Get(signatureParam) == "" && !s.config.RequireSignedURLs {
+		return nil
+	}
+	return s.signer.Verify(filePath, query)
+}
+
 // GetFullPath returns the full filesystem path
 func (s *DiskStorage) GetFullPath(filePath string) string {
 	return filepath.Join(s.basePath, filePath)
@@ -212,22 +313,6 @@ func (s *DiskStorage) isAllowedMimeType(mimeType string) bool {
 	return false
 }
 
-// generateThumbnails creates thumbnails for an image
-func (s *DiskStorage) generateThumbnails(originalPath, relativePath string, mediaFile *MediaFile) {
-	// This is a placeholder - in production, use an image processing library
-	// like github.com/disintegration/imaging
-	
-	// For now, just set the thumbnail URLs to the original
-	mediaFile.ThumbnailSmall = mediaFile.URL
-	mediaFile.ThumbnailMedium = mediaFile.URL
-	mediaFile.ThumbnailLarge = mediaFile.URL
-}
-
-// deleteThumbnails removes all thumbnails for an image
-func (s *DiskStorage) deleteThumbnails(originalPath string) {
-	// This would delete the actual thumbnail files
-}
-
 // Helper functions
 
 func generateFileID(filename, userID string) string {
@@ -0,0 +1,35 @@
+package media
+
+import (
+	"io"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskStorage_OpenAndStat(t *testing.T) {
+	storage, err := NewDiskStorage(&config.MediaConfig{StoragePath: t.TempDir()})
+	require.NoError(t, err)
+
+	_, err = storage.Save("share/trips/t1.png", []byte("card"))
+	require.NoError(t, err)
+
+	info, err := storage.Stat("share/trips/t1.png")
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, info.Size)
+	assert.False(t, info.ModTime.IsZero())
+
+	file, err := storage.Open("share/trips/t1.png")
+	require.NoError(t, err)
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.Equal(t, "card", string(data))
+
+	_, err = storage.Stat("share/trips/missing.png")
+	assert.ErrorIs(t, err, ErrFileNotFound)
+	_, err = storage.Open("share/trips/missing.png")
+	assert.ErrorIs(t, err, ErrFileNotFound)
+}
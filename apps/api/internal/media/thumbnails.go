@@ -0,0 +1,169 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsupportedImage is returned for images thumbnails cannot be made of
+var ErrUnsupportedImage = errors.New("unsupported image")
+
+// thumbnailSizes are the longest sides of the thumbnails made of an image,
+// largest first so each is scaled from the one before
+var thumbnailSizes = []struct {
+	name string
+	max  int
+}{
+	{"large", 1200},
+	{"medium", 600},
+	{"small", 200},
+}
+
+// Thumbnails are the scaled copies made of an uploaded image
+type Thumbnails struct {
+	Small  string
+	Medium string
+	Large  string
+	Width  int // of the original
+	Height int
+}
+
+// ThumbnailGenerator is implemented by storages that can make thumbnails
+type ThumbnailGenerator interface {
+	GenerateThumbnails(storagePath string) (*Thumbnails, error)
+}
+
+// GenerateThumbnails scales the stored image down to each thumbnail size and
+// stores the results as JPEGs. Images smaller than a size are stored as they
+// are, only re-encoded.
+func (s *DiskStorage) GenerateThumbnails(storagePath string) (*Thumbnails, error) {
+	file, err := os.Open(filepath.Join(s.basePath, storagePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer file.Close()
+
+	src, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+
+	bounds := src.Bounds()
+	thumbnails := &Thumbnails{Width: bounds.Dx(), Height: bounds.Dy()}
+
+	quality := s.config.ThumbnailQuality
+	if quality < 1 || quality > 100 {
+		quality = jpeg.DefaultQuality
+	}
+
+	current := toRGBA(src)
+	for _, size := range thumbnailSizes {
+		current = scaleDown(current, size.max)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, current, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, fmt.Errorf("failed to encode %s thumbnail: %w", size.name, err)
+		}
+
+		url, err := s.Save(thumbnailPath(storagePath, size.name), buf.Bytes())
+		if err != nil {
+			return nil, err
+		}
+
+		switch size.name {
+		case "small":
+			thumbnails.Small = url
+		case "medium":
+			thumbnails.Medium = url
+		case "large":
+			thumbnails.Large = url
+		}
+	}
+
+	return thumbnails, nil
+}
+
+// deleteThumbnails removes all thumbnails for an image
+func (s *DiskStorage) deleteThumbnails(originalPath string) {
+	for _, size := range thumbnailSizes {
+		path := filepath.Join(s.basePath, thumbnailPath(originalPath, size.name))
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("Warning: failed to delete thumbnail %s: %v\n", path, err)
+		}
+	}
+}
+
+// thumbnailPath returns where the thumbnail of a size is stored for an
+// original image, mirroring its path under images/thumbnails/<size>
+func thumbnailPath(originalPath, size string) string {
+	rel := strings.TrimPrefix(filepath.ToSlash(originalPath), "images/original/")
+	rel = strings.TrimSuffix(rel, filepath.Ext(rel)) + ".jpg"
+	return filepath.Join("images", "thumbnails", size, filepath.FromSlash(rel))
+}
+
+// toRGBA copies an image onto a white background, as JPEGs have no
+// transparency
+func toRGBA(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Over)
+	return dst
+}
+
+// scaleDown shrinks an image so its longest side is at most max, averaging
+// the source pixels each target pixel covers
+func scaleDown(src *image.RGBA, max int) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if w <= max && h <= max {
+		return src
+	}
+
+	dw, dh := max, h*max/w
+	if h > w {
+		dw, dh = w*max/h, max
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, (y+1)*h/dh
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, (x+1)*w/dw
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					b += uint32(p[2])
+					a += uint32(p[3])
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
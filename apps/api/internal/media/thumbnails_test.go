@@ -0,0 +1,92 @@
+package media
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskStorage_GenerateThumbnails(t *testing.T) {
+	storage, err := NewDiskStorage(&config.MediaConfig{
+		StoragePath:      t.TempDir(),
+		CDNURL:           "http://localhost:8080/media",
+		ThumbnailQuality: 80,
+	})
+	require.NoError(t, err)
+
+	src := image.NewRGBA(image.Rect(0, 0, 1600, 800))
+	for y := 0; y < 800; y++ {
+		for x := 0; x < 1600; x++ {
+			src.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	original := filepath.Join("images", "original", "2024", "05", "01", "abc.png")
+	require.NoError(t, os.MkdirAll(filepath.Dir(storage.GetFullPath(original)), 0755))
+	file, err := os.Create(storage.GetFullPath(original))
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(file, src))
+	file.Close()
+
+	thumbnails, err := storage.GenerateThumbnails(original)
+	require.NoError(t, err)
+	assert.Equal(t, 1600, thumbnails.Width)
+	assert.Equal(t, 800, thumbnails.Height)
+	assert.Equal(t, "http://localhost:8080/media/images/thumbnails/small/2024/05/01/abc.jpg", thumbnails.Small)
+
+	for size, width := range map[string]int{"small": 200, "medium": 600, "large": 1200} {
+		file, err := os.Open(storage.GetFullPath(thumbnailPath(original, size)))
+		require.NoError(t, err, size)
+		config, err := jpeg.DecodeConfig(file)
+		file.Close()
+		require.NoError(t, err, size)
+		assert.Equal(t, width, config.Width, size)
+		assert.Equal(t, width/2, config.Height, size)
+	}
+
+	// Thumbnails go with the original
+	require.NoError(t, storage.Delete(original))
+	_, err = os.Stat(storage.GetFullPath(thumbnailPath(original, "small")))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = storage.GenerateThumbnails(original)
+	assert.Error(t, err)
+}
+
+func TestDiskStorage_GenerateThumbnailsRejectsNonImages(t *testing.T) {
+	storage, err := NewDiskStorage(&config.MediaConfig{StoragePath: t.TempDir()})
+	require.NoError(t, err)
+
+	_, err = storage.Save("images/original/bad.jpg", []byte("not an image"))
+	require.NoError(t, err)
+	_, err = storage.GenerateThumbnails("images/original/bad.jpg")
+	assert.ErrorIs(t, err, ErrUnsupportedImage)
+}
+
+func TestScaleDown(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	// Left half black, right half white
+	for y := 0; y < 2; y++ {
+		for x := 2; x < 4; x++ {
+			src.Set(x, y, color.White)
+		}
+		for x := 0; x < 2; x++ {
+			src.Set(x, y, color.Black)
+		}
+	}
+
+	dst := scaleDown(src, 2)
+	assert.Equal(t, image.Rect(0, 0, 2, 1), dst.Bounds())
+	assert.Equal(t, color.RGBA{0, 0, 0, 255}, dst.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, dst.RGBAAt(1, 0))
+
+	// Images already small enough are left alone
+	assert.Same(t, src, scaleDown(src, 10))
+}
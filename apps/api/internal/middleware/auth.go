@@ -1,8 +1,12 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"log"
 	"strings"
 
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
 	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
 	"github.com/Oferzz/newMap/apps/api/internal/utils"
 	"github.com/Oferzz/newMap/apps/api/pkg/response"
@@ -14,10 +18,20 @@ const (
 	BearerPrefix        = "Bearer "
 	UserIDKey           = "userID"
 	UserEmailKey        = "userEmail"
+	SessionIDKey        = "sessionID"
+	ShareGrantKey       = "shareGrant"
 )
 
+var errSessionEnded = errors.New("session has ended")
+
+// SessionChecker reports whether a signed-in session is still active
+type SessionChecker interface {
+	SessionActive(ctx context.Context, sessionID string) (bool, error)
+}
+
 type AuthMiddleware struct {
 	jwtManager *utils.JWTManager
+	sessions   SessionChecker
 }
 
 func NewAuthMiddleware(jwtManager *utils.JWTManager) *AuthMiddleware {
@@ -26,6 +40,47 @@ func NewAuthMiddleware(jwtManager *utils.JWTManager) *AuthMiddleware {
 	}
 }
 
+// SetSessionChecker makes access tokens stop working when the session they
+// were issued to ends, rather than when they expire
+func (m *AuthMiddleware) SetSessionChecker(sessions SessionChecker) {
+	m.sessions = sessions
+}
+
+// validate checks the token and, for tokens issued to a session, that the
+// session is still active
+func (m *AuthMiddleware) validate(c *gin.Context, token string) (*utils.TokenClaims, error) {
+	claims, err := m.jwtManager.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+	
+	if claims.SessionID != "" && m.sessions != nil {
+		active, err := m.sessions.SessionActive(c.Request.Context(), claims.SessionID)
+		if err != nil {
+			log.Printf("auth: failed to check session %s: %v", claims.SessionID, err)
+			return nil, err
+		}
+		if !active {
+			return nil, errSessionEnded
+		}
+	}
+	
+	return claims, nil
+}
+
+// rejectToken responds to a token that did not validate
+func rejectToken(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrExpiredToken):
+		response.Unauthorized(c, "Token has expired")
+	case errors.Is(err, errSessionEnded):
+		response.Unauthorized(c, "Session has ended")
+	default:
+		response.Unauthorized(c, "Invalid authentication token")
+	}
+	c.Abort()
+}
+
 func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := m.extractToken(c)
@@ -35,19 +90,45 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 		
-		claims, err := m.jwtManager.ValidateToken(token)
+		claims, err := m.validate(c, token)
 		if err != nil {
-			if err == utils.ErrExpiredToken {
-				response.Unauthorized(c, "Token has expired")
-			} else {
-				response.Unauthorized(c, "Invalid authentication token")
-			}
+			rejectToken(c, err)
+			return
+		}
+		
+		// A share-link token is not a user session
+		if claims.IsScoped() {
+			response.Unauthorized(c, "Share tokens cannot be used here")
 			c.Abort()
 			return
 		}
 		
 		c.Set(UserIDKey, claims.UserID)
 		c.Set(UserEmailKey, claims.Email)
+		c.Set(SessionIDKey, claims.SessionID)
+		c.Next()
+	}
+}
+
+// RequireAuthOrShare is RequireAuth that also lets share-link guests through.
+// A guest has a share grant and no user ID, so handlers and RBAC checks that
+// need a user still refuse them.
+func (m *AuthMiddleware) RequireAuthOrShare() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := m.extractToken(c)
+		if token == "" {
+			response.Unauthorized(c, "Missing authentication token")
+			c.Abort()
+			return
+		}
+		
+		claims, err := m.validate(c, token)
+		if err != nil {
+			rejectToken(c, err)
+			return
+		}
+		
+		setClaims(c, claims)
 		c.Next()
 	}
 }
@@ -60,18 +141,29 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 			return
 		}
 		
-		claims, err := m.jwtManager.ValidateToken(token)
+		claims, err := m.validate(c, token)
 		if err != nil {
 			c.Next()
 			return
 		}
 		
-		c.Set(UserIDKey, claims.UserID)
-		c.Set(UserEmailKey, claims.Email)
+		setClaims(c, claims)
 		c.Next()
 	}
 }
 
+// OptionalStreamAuth is OptionalAuth for event streams. Browsers cannot set
+// headers on an EventSource, so the token may be sent as ?access_token=.
+func (m *AuthMiddleware) OptionalStreamAuth() gin.HandlerFunc {
+	optional := m.OptionalAuth()
+	return func(c *gin.Context) {
+		if token := c.Query("access_token"); token != "" && c.GetHeader(AuthorizationHeader) == "" {
+			c.Request.Header.Set(AuthorizationHeader, BearerPrefix+token)
+		}
+		optional(c)
+	}
+}
+
 func (m *AuthMiddleware) RequirePermission(permission users.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// This middleware should be used after RequireAuth
@@ -81,6 +173,18 @@ func (m *AuthMiddleware) RequirePermission(permission users.Permission) gin.Hand
 	}
 }
 
+// setClaims records who the token belongs to: a user, or a share-link guest
+func setClaims(c *gin.Context, claims *utils.TokenClaims) {
+	if claims.IsScoped() {
+		c.Set(ShareGrantKey, &trips.ShareGrant{TripID: claims.TripID, Scope: claims.Scope})
+		return
+	}
+	
+	c.Set(UserIDKey, claims.UserID)
+	c.Set(UserEmailKey, claims.Email)
+	c.Set(SessionIDKey, claims.SessionID)
+}
+
 func (m *AuthMiddleware) extractToken(c *gin.Context) string {
 	header := c.GetHeader(AuthorizationHeader)
 	if header == "" {
@@ -112,4 +216,15 @@ func GetUserEmail(c *gin.Context) (string, bool) {
 	
 	str, ok := email.(string)
 	return str, ok
+}
+
+// GetShareGrant returns the grant of a share-link guest
+func GetShareGrant(c *gin.Context) (*trips.ShareGrant, bool) {
+	value, exists := c.Get(ShareGrantKey)
+	if !exists {
+		return nil, false
+	}
+	
+	grant, ok := value.(*trips.ShareGrant)
+	return grant, ok && grant != nil
 }
\ No newline at end of file
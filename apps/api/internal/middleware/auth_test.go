@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sessionChecker knows which sessions are still active
+type sessionChecker map[string]bool
+
+func (s sessionChecker) SessionActive(ctx context.Context, sessionID string) (bool, error) {
+	return s[sessionID], nil
+}
+
+func TestSessionTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtManager := utils.NewJWTManager(&config.JWTConfig{
+		Secret:       "test-secret-key",
+		AccessExpiry: 15 * time.Minute,
+	})
+
+	auth := NewAuthMiddleware(jwtManager)
+	auth.SetSessionChecker(sessionChecker{"active": true, "ended": false})
+
+	router := gin.New()
+	router.GET("/me", auth.RequireAuth(), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(SessionIDKey))
+	})
+	router.GET("/maybe", auth.OptionalAuth(), func(c *gin.Context) {
+		if _, ok := GetUserID(c); ok {
+			c.Status(http.StatusOK)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	token := func(sessionID string) string {
+		token, err := jwtManager.GenerateAccessToken("user-1", "test@example.com", sessionID)
+		require.NoError(t, err)
+		return token
+	}
+
+	assert.Equal(t, http.StatusOK, authorized(router, http.MethodGet, "/me", token("active")))
+	assert.Equal(t, http.StatusUnauthorized, authorized(router, http.MethodGet, "/me", token("ended")))
+
+	// Tokens issued before sessions existed work until they expire
+	assert.Equal(t, http.StatusOK, authorized(router, http.MethodGet, "/me", token("")))
+
+	// An ended session is anonymous where signing in is optional
+	assert.Equal(t, http.StatusNoContent, authorized(router, http.MethodGet, "/maybe", token("ended")))
+}
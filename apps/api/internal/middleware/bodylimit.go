@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimit limits request bodies to limit bytes and answers 413 to those
+// over it. A route's own BodyLimit replaces that of the group around it, so
+// the API can default to a small limit that GeoJSON and upload routes raise.
+// Bodies are checked as they are read, against their declared length first,
+// so nothing past the limit is ever read.
+func BodyLimit(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if body, ok := c.Request.Body.(*limitedBody); ok {
+			body.limit = limit
+			c.Next()
+			return
+		}
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		c.Request.Body = &limitedBody{ReadCloser: c.Request.Body, c: c, limit: limit}
+		c.Next()
+	}
+}
+
+// limitedBody is a request body limited to the limit of its route. The
+// limit is only settled once reading starts, after every middleware of the
+// route had its say.
+type limitedBody struct {
+	io.ReadCloser
+	c      *gin.Context
+	limit  int64
+	reader io.Reader
+	err    error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.reader == nil {
+		if b.c.Request.ContentLength > b.limit {
+			return 0, b.tooLarge()
+		}
+		b.reader = http.MaxBytesReader(b.c.Writer, b.ReadCloser, b.limit)
+	}
+
+	n, err := b.reader.Read(p)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return n, b.tooLarge()
+	}
+	return n, err
+}
+
+// tooLarge answers 413 as soon as the body turns out to be over its limit.
+// The handler still sees a failed read, and what it answers to that is
+// dropped.
+func (b *limitedBody) tooLarge() error {
+	b.err = &http.MaxBytesError{Limit: b.limit}
+	if !b.c.Writer.Written() {
+		response.PayloadTooLarge(b.c, b.limit)
+		b.c.Writer = answeredWriter{b.c.Writer}
+	}
+	b.c.Abort()
+	return b.err
+}
+
+// answeredWriter drops what is written to a request that was answered
+// already
+type answeredWriter struct {
+	gin.ResponseWriter
+}
+
+func (answeredWriter) WriteHeader(int) {}
+
+func (answeredWriter) WriteHeaderNow() {}
+
+func (answeredWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (answeredWriter) WriteString(s string) (int, error) {
+	return len(s), nil
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bodyLimitRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	// Echoes the body, or fails as handlers do when it cannot be read
+	echo := func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		c.String(http.StatusOK, string(body))
+	}
+
+	router := gin.New()
+	api := router.Group("/api", BodyLimit(8))
+	api.POST("/json", echo)
+	api.POST("/geojson", BodyLimit(32), echo)
+	return router
+}
+
+type limitedRequest struct {
+	path    string
+	body    string
+	chunked bool // Sent without a declared length
+}
+
+func serveLimited(router *gin.Engine, r limitedRequest) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, r.path, strings.NewReader(r.body))
+	if r.chunked {
+		req.ContentLength = -1
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestBodyLimit(t *testing.T) {
+	router := bodyLimitRouter()
+
+	tests := []struct {
+		name    string
+		request limitedRequest
+		status  int
+	}{
+		{"within the group's limit", limitedRequest{path: "/api/json", body: `{"a":1}`}, http.StatusOK},
+		{"declared over the group's limit", limitedRequest{path: "/api/json", body: `{"a":12345}`}, http.StatusRequestEntityTooLarge},
+		{"streamed over the group's limit", limitedRequest{path: "/api/json", body: `{"a":12345}`, chunked: true}, http.StatusRequestEntityTooLarge},
+		{"within the route's own limit", limitedRequest{path: "/api/geojson", body: `{"type":"LineString"}`}, http.StatusOK},
+		{"over the route's own limit", limitedRequest{path: "/api/geojson", body: `{"type":"LineString","coordinates":[]}`, chunked: true}, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveLimited(router, tt.request)
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.request.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestBodyLimit_Response(t *testing.T) {
+	rec := serveLimited(bodyLimitRouter(), limitedRequest{path: "/api/json", body: `{"a":12345}`, chunked: true})
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	// Only the 413 is sent, not what the handler answered after failing to
+	// read the body
+	var resp response.Response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "PAYLOAD_TOO_LARGE", resp.Error.Code)
+	assert.Equal(t, float64(8), resp.Error.Details["limit"])
+}
@@ -0,0 +1,275 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// encoder is a content encoding the compression middleware can produce
+type encoder struct {
+	name string
+	new  func(w io.Writer, level int) (io.WriteCloser, error)
+}
+
+// encoders in order of preference when a client accepts several equally.
+// Brotli can be added here once a brotli package is a dependency.
+var encoders = []encoder{
+	{name: "gzip", new: newGzipWriter},
+}
+
+var gzipPools sync.Map // level -> *sync.Pool
+
+type pooledGzipWriter struct {
+	*gzip.Writer
+	pool *sync.Pool
+}
+
+func (w *pooledGzipWriter) Close() error {
+	err := w.Writer.Close()
+	w.pool.Put(w.Writer)
+	return err
+}
+
+func newGzipWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	value, _ := gzipPools.LoadOrStore(level, &sync.Pool{})
+	pool := value.(*sync.Pool)
+
+	if gz, ok := pool.Get().(*gzip.Writer); ok {
+		gz.Reset(w)
+		return &pooledGzipWriter{Writer: gz, pool: pool}, nil
+	}
+
+	gz, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledGzipWriter{Writer: gz, pool: pool}, nil
+}
+
+// Compress encodes responses that are large enough and of a compressible
+// content type with the best encoding the client accepts. Bodies are
+// buffered up to the minimum size before deciding, so small responses and
+// already compressed media pass through untouched.
+func Compress(cfg *config.CompressionConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.Enabled || !compressibleRequest(c.Request) {
+			c.Next()
+			return
+		}
+
+		enc, ok := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if !ok {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{
+			ResponseWriter: c.Writer,
+			cfg:            cfg,
+			encoder:        enc,
+		}
+		c.Writer = w
+		defer w.close()
+
+		c.Next()
+	}
+}
+
+// compressibleRequest rules out requests whose responses must not be encoded
+func compressibleRequest(r *http.Request) bool {
+	if r.Method == http.MethodHead {
+		return false
+	}
+	// Byte ranges refer to the unencoded body
+	if r.Header.Get("Range") != "" {
+		return false
+	}
+	// Upgraded connections are hijacked from the response writer
+	if r.Header.Get("Upgrade") != "" {
+		return false
+	}
+	return true
+}
+
+// negotiateEncoding picks the supported encoding with the highest q-value in
+// an Accept-Encoding header
+func negotiateEncoding(header string) (encoder, bool) {
+	if header == "" {
+		return encoder{}, false
+	}
+
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+
+	best, bestQ := encoder{}, 0.0
+	for _, enc := range encoders {
+		q, ok := accepted[enc.name]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+
+	return best, bestQ > 0
+}
+
+// compressWriter buffers the start of a response until it knows whether to
+// encode it
+type compressWriter struct {
+	gin.ResponseWriter
+	cfg     *config.CompressionConfig
+	encoder encoder
+
+	buf      []byte
+	decided  bool
+	writer   io.WriteCloser // set once compression has started
+	writeErr error
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.cfg.MinSize {
+			return len(data), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+
+	if w.writer != nil {
+		return w.writer.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap lets http.ResponseController reach the connection
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow commits to the response as buffered so far
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.decide()
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Flush sends what has been written so far, committing to an encoding first
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+	if gz, ok := w.writer.(*pooledGzipWriter); ok {
+		gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide starts encoding if the response qualifies, then writes out the
+// buffered body
+func (w *compressWriter) decide() error {
+	w.decided = true
+
+	header := w.Header()
+	if w.shouldCompress() {
+		writer, err := w.encoder.new(w.ResponseWriter, w.cfg.Level)
+		if err == nil {
+			header.Set("Content-Encoding", w.encoder.name)
+			header.Del("Content-Length")
+			w.writer = writer
+		}
+	}
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if w.writer != nil {
+		_, w.writeErr = w.writer.Write(buf)
+	} else {
+		_, w.writeErr = w.ResponseWriter.Write(buf)
+	}
+	return w.writeErr
+}
+
+func (w *compressWriter) shouldCompress() bool {
+	header := w.Header()
+
+	// Whether or not this response is encoded, others from the same URL may be
+	if w.compressibleType(header.Get("Content-Type")) {
+		header.Add("Vary", "Accept-Encoding")
+	}
+
+	if len(w.buf) < w.cfg.MinSize {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	switch w.Status() {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		return false
+	}
+	return w.compressibleType(header.Get("Content-Type"))
+}
+
+func (w *compressWriter) compressibleType(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	// Event streams are flushed per message and must reach the client unbuffered
+	if mediaType == "text/event-stream" {
+		return false
+	}
+
+	for _, allowed := range w.cfg.ContentTypes {
+		if strings.HasSuffix(allowed, "/") {
+			if strings.HasPrefix(mediaType, allowed) {
+				return true
+			}
+		} else if mediaType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// close finishes the response once the handler chain has returned
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide()
+	}
+	if w.writer != nil {
+		w.writer.Close()
+	}
+}
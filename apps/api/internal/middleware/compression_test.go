@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compressionRouter(body string, contentType string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Compress(&config.CompressionConfig{
+		Enabled:      true,
+		Level:        gzip.DefaultCompression,
+		MinSize:      64,
+		ContentTypes: []string{"application/json", "application/geo+json", "text/"},
+	}))
+	router.GET("/", func(c *gin.Context) {
+		c.Data(http.StatusOK, contentType, []byte(body))
+	})
+	return router
+}
+
+func serve(router *gin.Engine, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCompress_LargeJSON(t *testing.T) {
+	body := `{"type":"LineString","coordinates":[` + strings.Repeat("[35.2,31.7],", 100) + `[35.2,31.7]]}`
+	rec := serve(compressionRouter(body, "application/geo+json"), "br;q=1.0, gzip;q=0.8")
+
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+	assert.Less(t, rec.Body.Len(), len(body))
+
+	reader, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(decoded))
+}
+
+func TestCompress_BelowMinSize(t *testing.T) {
+	rec := serve(compressionRouter(`{"ok":true}`, "application/json"), "gzip")
+
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, `{"ok":true}`, rec.Body.String())
+}
+
+func TestCompress_SkipsMedia(t *testing.T) {
+	body := strings.Repeat("\xff\xd8", 100)
+	rec := serve(compressionRouter(body, "image/jpeg"), "gzip")
+
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, body, rec.Body.String())
+}
+
+func TestCompress_ClientWithoutGzip(t *testing.T) {
+	body := strings.Repeat("a", 200)
+	for _, acceptEncoding := range []string{"", "identity", "gzip;q=0"} {
+		rec := serve(compressionRouter(body, "text/plain"), acceptEncoding)
+
+		assert.Empty(t, rec.Header().Get("Content-Encoding"), acceptEncoding)
+		assert.Equal(t, body, rec.Body.String(), acceptEncoding)
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	enc, ok := negotiateEncoding("deflate, gzip;q=0.5")
+	assert.True(t, ok)
+	assert.Equal(t, "gzip", enc.name)
+
+	enc, ok = negotiateEncoding("*")
+	assert.True(t, ok)
+	assert.Equal(t, "gzip", enc.name)
+
+	_, ok = negotiateEncoding("deflate")
+	assert.False(t, ok)
+}
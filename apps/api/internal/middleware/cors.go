@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// CORS applies one of two policies by path. Public paths, such as embeds and
+// tiles, may be read from any origin but only with safe methods and without
+// credentials. Everything else is limited to the configured origins.
+// Preflight requests never reach a route's own middleware, which is why the
+// policy is chosen here rather than per route group.
+func CORS(security *config.SecurityConfig, allowedOrigins []string) gin.HandlerFunc {
+	public := cors.New(cors.Config{
+		AllowAllOrigins: true,
+		AllowMethods:    []string{"GET", "HEAD", "OPTIONS"},
+		AllowHeaders:    []string{"Origin", "Accept", "Range", "X-Request-ID"},
+		ExposeHeaders:   []string{"Content-Length", "Content-Range", "ETag", "X-Request-ID"},
+		MaxAge:          12 * time.Hour,
+	})
+
+	apiConfig := cors.Config{
+		AllowOrigins:     allowedOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-Request-ID", "X-Session-ID"},
+		ExposeHeaders:    []string{"Content-Length", "ETag", "X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}
+	if len(allowedOrigins) == 0 {
+		// No origin configured means no cross-origin access at all
+		apiConfig.AllowOriginFunc = func(string) bool { return false }
+	}
+	api := cors.New(apiConfig)
+
+	return func(c *gin.Context) {
+		if isPublicPath(c.Request.URL.Path, security.PublicCORSPaths) {
+			public(c)
+			return
+		}
+		api(c)
+	}
+}
+
+// isPublicPath reports whether the path is one of the prefixes or below one
+func isPublicPath(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func securityRouter(security *config.SecurityConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(SecurityHeaders(security))
+	router.Use(CORS(security, []string{"https://app.example.com"}))
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/v1/trips", ok)
+	router.POST("/api/v1/trips", ok)
+	router.GET("/api/v1/og/trips/:id", ok)
+	router.GET("/api/v1/ogre", ok)
+	router.GET("/media/*filepath", MediaSecurityHeaders(), ok)
+	return router
+}
+
+func request(router *gin.Engine, method, path, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORS_API(t *testing.T) {
+	router := securityRouter(&config.SecurityConfig{PublicCORSPaths: []string{"/api/v1/og", "/media/"}})
+
+	rec := request(router, http.MethodGet, "/api/v1/trips", "https://app.example.com")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+
+	// Other origins are turned away, preflight included
+	rec = request(router, http.MethodGet, "/api/v1/trips", "https://evil.example.com")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = request(router, http.MethodOptions, "/api/v1/trips", "https://evil.example.com")
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+	// A prefix only matches whole path segments
+	rec = request(router, http.MethodGet, "/api/v1/ogre", "https://evil.example.com")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestCORS_Public(t *testing.T) {
+	router := securityRouter(&config.SecurityConfig{PublicCORSPaths: []string{"/api/v1/og", "/media/"}})
+
+	for _, path := range []string{"/api/v1/og/trips/1", "/media/trips/cover.jpg"} {
+		rec := request(router, http.MethodGet, path, "https://blog.example.org")
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+		assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"), path)
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"), path)
+	}
+
+	// Public paths are read-only
+	rec := request(router, http.MethodOptions, "/api/v1/og/trips/1", "https://blog.example.org")
+	assert.NotContains(t, rec.Header().Get("Access-Control-Allow-Methods"), http.MethodPost)
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	router := securityRouter(&config.SecurityConfig{HSTSMaxAge: 365 * 24 * time.Hour})
+
+	rec := request(router, http.MethodGet, "/api/v1/trips", "")
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "max-age=31536000; includeSubDomains", rec.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, apiContentSecurityPolicy, rec.Header().Get("Content-Security-Policy"))
+
+	// Uploaded files get a policy that displays them without running them
+	rec = request(router, http.MethodGet, "/media/uploads/page.svg", "")
+	assert.Equal(t, mediaContentSecurityPolicy, rec.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+
+	// Without a max age there is no HSTS
+	router = securityRouter(&config.SecurityConfig{})
+	rec = request(router, http.MethodGet, "/api/v1/trips", "")
+	assert.Empty(t, rec.Header().Get("Strict-Transport-Security"))
+}
@@ -2,22 +2,32 @@ package middleware
 
 import (
 	"context"
+	"errors"
 
 	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
 	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/pkg/repoerr"
 	"github.com/Oferzz/newMap/apps/api/pkg/response"
 	"github.com/gin-gonic/gin"
 )
 
+// PlacePermissionChecker resolves place permissions, including those
+// inherited from parent areas
+type PlacePermissionChecker interface {
+	CanUserPerform(ctx context.Context, userID, placeID, permission string) (bool, error)
+}
+
 type RBACMiddleware struct {
-	userRepo users.Repository
-	tripRepo trips.Repository
+	userRepo         users.Repository
+	tripRepo         trips.Repository
+	placePermissions PlacePermissionChecker
 }
 
-func NewRBACMiddleware(userRepo users.Repository, tripRepo trips.Repository) *RBACMiddleware {
+func NewRBACMiddleware(userRepo users.Repository, tripRepo trips.Repository, placePermissions PlacePermissionChecker) *RBACMiddleware {
 	return &RBACMiddleware{
-		userRepo: userRepo,
-		tripRepo: tripRepo,
+		userRepo:         userRepo,
+		tripRepo:         tripRepo,
+		placePermissions: placePermissions,
 	}
 }
 
@@ -64,16 +74,22 @@ func (m *RBACMiddleware) RequireSystemPermission(permission users.Permission) gi
 	}
 }
 
-// RequireTripPermission checks if the user has the required permission for a specific trip
+// RequireTripPermission checks if the user has the required permission for a
+// specific trip. Share-link guests are refused, as the handlers behind it
+// need a user.
 func (m *RBACMiddleware) RequireTripPermission(permission users.Permission) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		userID, exists := GetUserID(c)
-		if !exists {
-			response.Unauthorized(c, "User not authenticated")
-			c.Abort()
-			return
-		}
+	return m.tripPermission(permission, false)
+}
 
+// RequireTripPermissionOrShare is RequireTripPermission that also lets
+// share-link guests through when their grant covers the trip and permission,
+// for handlers that act on the grant
+func (m *RBACMiddleware) RequireTripPermissionOrShare(permission users.Permission) gin.HandlerFunc {
+	return m.tripPermission(permission, true)
+}
+
+func (m *RBACMiddleware) tripPermission(permission users.Permission, allowShared bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
 		// Get trip ID from URL parameter
 		tripIDStr := c.Param("tripId")
 		if tripIDStr == "" {
@@ -82,10 +98,30 @@ func (m *RBACMiddleware) RequireTripPermission(permission users.Permission) gin.
 
 		tripID := tripIDStr
 
+		userID, exists := GetUserID(c)
+		if !exists {
+			grant, shared := GetShareGrant(c)
+			if !shared {
+				response.Unauthorized(c, "User not authenticated")
+				c.Abort()
+				return
+			}
+			if !allowShared {
+				response.Forbidden(c, "Share links cannot be used for this action")
+				c.Abort()
+				return
+			}
+			if !grant.Allows(tripID, string(permission)) {
+				response.Forbidden(c, "Your share link does not allow this action on this trip")
+				c.Abort()
+				return
+			}
+		}
+
 		// Get trip from database
 		trip, err := m.tripRepo.GetByID(context.Background(), tripID)
 		if err != nil {
-			if err == trips.ErrTripNotFound {
+			if errors.Is(err, trips.ErrTripNotFound) {
 				response.NotFound(c, "Trip not found")
 			} else {
 				response.InternalServerError(c, "Failed to check permissions")
@@ -95,7 +131,7 @@ func (m *RBACMiddleware) RequireTripPermission(permission users.Permission) gin.
 		}
 
 		// Check if user has permission for this specific trip
-		if !trip.CanUserPerform(userID, string(permission)) {
+		if exists && !trip.CanUserPerform(userID, string(permission)) {
 			response.Forbidden(c, "You don't have permission to perform this action on this trip")
 			c.Abort()
 			return
@@ -128,7 +164,7 @@ func (m *RBACMiddleware) RequireTripOwnership() gin.HandlerFunc {
 		// Get trip from database
 		trip, err := m.tripRepo.GetByID(context.Background(), tripID)
 		if err != nil {
-			if err == trips.ErrTripNotFound {
+			if errors.Is(err, trips.ErrTripNotFound) {
 				response.NotFound(c, "Trip not found")
 			} else {
 				response.InternalServerError(c, "Failed to check ownership")
@@ -154,7 +190,8 @@ func (m *RBACMiddleware) RequireTripOwnership() gin.HandlerFunc {
 func (m *RBACMiddleware) OptionalTripPermission(permission users.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, exists := GetUserID(c)
-		if !exists {
+		grant, shared := GetShareGrant(c)
+		if !exists && !shared {
 			c.Next()
 			return
 		}
@@ -181,8 +218,48 @@ func (m *RBACMiddleware) OptionalTripPermission(permission users.Permission) gin
 
 		// Store trip permission status
 		canPerform := trip.CanUserPerform(userID, string(permission))
+		if !exists {
+			canPerform = grant.Allows(tripID, string(permission))
+		}
 		c.Set("canPerformTripAction", canPerform)
 		c.Set("trip", trip)
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// RequirePlacePermission checks if the user has the required permission for a specific place
+func (m *RBACMiddleware) RequirePlacePermission(permission users.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := GetUserID(c)
+		if !exists {
+			response.Unauthorized(c, "User not authenticated")
+			c.Abort()
+			return
+		}
+
+		// Get place ID from URL parameter
+		placeID := c.Param("placeId")
+		if placeID == "" {
+			placeID = c.Param("id")
+		}
+
+		allowed, err := m.placePermissions.CanUserPerform(c.Request.Context(), userID, placeID, string(permission))
+		if err != nil {
+			if errors.Is(err, repoerr.ErrNotFound) {
+				response.NotFound(c, "Place not found")
+			} else {
+				response.InternalServerError(c, "Failed to check permissions")
+			}
+			c.Abort()
+			return
+		}
+
+		if !allowed {
+			response.Forbidden(c, "You don't have permission to perform this action on this place")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/Oferzz/newMap/apps/api/pkg/requestid"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const RequestIDKey = "requestID"
+
+// RequestID tags every request with an ID, reusing one sent by a proxy, and
+// makes it available to handlers and to anything holding the request context
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if id == "" || len(id) > 128 {
+			id = uuid.New().String()
+		}
+
+		c.Set(RequestIDKey, id)
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+		c.Header(requestid.Header, id)
+		c.Next()
+	}
+}
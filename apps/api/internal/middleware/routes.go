@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/gin-gonic/gin"
+)
+
+// RouteMiddleware gathers the middleware handlers register their routes
+// with, for the routes of one API version
+func RouteMiddleware(version string, limits *config.BodyLimitsConfig, auth *AuthMiddleware, rbac *RBACMiddleware, share *ShareLinkMiddleware) *users.RouteMiddleware {
+	return &users.RouteMiddleware{
+		Version:            version,
+		OptionalAuth:       auth.OptionalAuth(),
+		OptionalStreamAuth: auth.OptionalStreamAuth(),
+		RequireAuth:        auth.RequireAuth(),
+		RequireAuthOrShare: auth.RequireAuthOrShare(),
+		RequireAdmin: []gin.HandlerFunc{
+			auth.RequireAuth(),
+			rbac.RequireSystemPermission(users.PermissionSystemAdmin),
+		},
+		RequireSystemPermission:      rbac.RequireSystemPermission,
+		RequireTripPermission:        rbac.RequireTripPermission,
+		RequireTripPermissionOrShare: rbac.RequireTripPermissionOrShare,
+		RequireTripOwnership:         rbac.RequireTripOwnership(),
+		RequirePlacePermission:       rbac.RequirePlacePermission,
+		RequireShareLink:             share.RequireShareLink,
+		LimitGeoJSONBody:             BodyLimit(limits.GeoJSON),
+		LimitUploadBody:              BodyLimit(limits.Upload),
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// ShareLinkKey holds the share link a request was made through
+const ShareLinkKey = "shareLink"
+
+// ShareLinkRedeemer counts uses of share links
+type ShareLinkRedeemer interface {
+	RedeemShareLink(ctx context.Context, token string) (*trips.ActivityShareLink, error)
+}
+
+// ShareLinkMiddleware admits visitors holding a share link
+type ShareLinkMiddleware struct {
+	links ShareLinkRedeemer
+}
+
+func NewShareLinkMiddleware(links ShareLinkRedeemer) *ShareLinkMiddleware {
+	return &ShareLinkMiddleware{links: links}
+}
+
+// RequireShareLink lets anyone holding the share link in the token path
+// parameter through, signed in or not. Each request counts as a use of the
+// link; links that have expired or run out of uses are refused, as are links
+// that do not cover the permission. The visitor gets the link's grant on its
+// trip, like a guest holding a token minted from it.
+func (m *ShareLinkMiddleware) RequireShareLink(permission users.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		link, err := m.links.RedeemShareLink(c.Request.Context(), c.Param("token"))
+		if err != nil {
+			if errors.Is(err, trips.ErrShareLinkNotFound) {
+				response.NotFound(c, "Share link is invalid or has expired")
+			} else {
+				response.InternalServerError(c, "Failed to open share link")
+			}
+			c.Abort()
+			return
+		}
+
+		grant := &trips.ShareGrant{TripID: link.TripID, Scope: link.Scope()}
+		if !grant.Allows(link.TripID, string(permission)) {
+			response.Forbidden(c, "Your share link does not allow this action on this trip")
+			c.Abort()
+			return
+		}
+
+		c.Set(ShareLinkKey, link)
+		c.Set(ShareGrantKey, grant)
+		c.Next()
+	}
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	sharedTripID = "3a1e6f0c-0000-4000-8000-000000000001"
+	otherTripID  = "3a1e6f0c-0000-4000-8000-000000000002"
+	tripOwnerID  = "3a1e6f0c-0000-4000-8000-0000000000f1"
+)
+
+// tripRepository serves private trips owned by someone else
+type tripRepository struct {
+	trips.Repository
+}
+
+func (r *tripRepository) GetByID(ctx context.Context, id string) (*trips.Trip, error) {
+	return &trips.Trip{ID: id, OwnerID: tripOwnerID, Privacy: "private"}, nil
+}
+
+func shareRouter(jwtManager *utils.JWTManager) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	auth := NewAuthMiddleware(jwtManager)
+	rbac := NewRBACMiddleware(nil, &tripRepository{}, nil)
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router := gin.New()
+	router.GET("/me", auth.RequireAuth(), ok)
+	router.GET("/trips/:id", auth.RequireAuthOrShare(), rbac.RequireTripPermissionOrShare(users.PermissionTripRead), ok)
+	router.PUT("/trips/:id", auth.RequireAuthOrShare(), rbac.RequireTripPermissionOrShare(users.PermissionTripUpdate), ok)
+	router.POST("/trips/:id/waypoints", auth.RequireAuthOrShare(), rbac.RequireTripPermissionOrShare(users.PermissionTripUpdate), ok)
+	router.POST("/trips/:id/variants", auth.RequireAuthOrShare(), rbac.RequireTripPermission(users.PermissionTripUpdate), ok)
+	router.DELETE("/trips/:id", auth.RequireAuthOrShare(), rbac.RequireTripOwnership(), ok)
+	return router
+}
+
+func authorized(router *gin.Engine, method, path, token string) int {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(AuthorizationHeader, BearerPrefix+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestShareTokens(t *testing.T) {
+	jwtManager := utils.NewJWTManager(&config.JWTConfig{
+		Secret:        "test-secret-key",
+		AccessExpiry:  15 * time.Minute,
+		RefreshExpiry: time.Hour,
+		ShareExpiry:   time.Hour,
+		Issuer:        "test-issuer",
+	})
+	router := shareRouter(jwtManager)
+
+	readToken, err := jwtManager.GenerateScopedToken(sharedTripID, trips.ShareScopeRead)
+	require.NoError(t, err)
+	editToken, err := jwtManager.GenerateScopedToken(sharedTripID, trips.ShareScopeEdit)
+	require.NoError(t, err)
+	userToken, _, err := jwtManager.GenerateTokenPair(tripOwnerID, "owner@example.com")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"read guest views the trip", http.MethodGet, "/trips/" + sharedTripID, readToken, http.StatusOK},
+		{"read guest cannot update", http.MethodPut, "/trips/" + sharedTripID, readToken, http.StatusForbidden},
+		{"edit guest updates the trip", http.MethodPut, "/trips/" + sharedTripID, editToken, http.StatusOK},
+		{"edit guest adds waypoints", http.MethodPost, "/trips/" + sharedTripID + "/waypoints", editToken, http.StatusOK},
+		{"read guest cannot add waypoints", http.MethodPost, "/trips/" + sharedTripID + "/waypoints", readToken, http.StatusForbidden},
+		{"guest is refused where a user is needed", http.MethodPost, "/trips/" + sharedTripID + "/variants", editToken, http.StatusForbidden},
+		{"guest cannot reach another trip", http.MethodGet, "/trips/" + otherTripID, editToken, http.StatusForbidden},
+		{"guest is not an owner", http.MethodDelete, "/trips/" + sharedTripID, editToken, http.StatusUnauthorized},
+		{"guest is not a user", http.MethodGet, "/me", readToken, http.StatusUnauthorized},
+		{"owner is unaffected", http.MethodDelete, "/trips/" + sharedTripID, userToken, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, authorized(router, tt.method, tt.path, tt.token))
+		})
+	}
+}
+
+// shareLinks serves the links by token, counting uses like the database does
+type shareLinks map[string]*trips.ActivityShareLink
+
+func (l shareLinks) RedeemShareLink(ctx context.Context, token string) (*trips.ActivityShareLink, error) {
+	link, ok := l[token]
+	if !ok || (link.MaxUses != nil && link.UseCount >= *link.MaxUses) {
+		return nil, trips.ErrShareLinkNotFound
+	}
+	link.UseCount++
+	return link, nil
+}
+
+func TestRequireShareLink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	once := 1
+	links := shareLinks{
+		"view": {TripID: sharedTripID, Permissions: trips.SharePermissionView},
+		"edit": {TripID: sharedTripID, Permissions: trips.SharePermissionEdit},
+		"once": {TripID: sharedTripID, Permissions: trips.SharePermissionView, MaxUses: &once},
+	}
+	shared := NewShareLinkMiddleware(links)
+
+	router := gin.New()
+	router.GET("/shared/:token", shared.RequireShareLink(users.PermissionTripRead), func(c *gin.Context) {
+		grant, ok := GetShareGrant(c)
+		require.True(t, ok)
+		c.String(http.StatusOK, grant.Scope)
+	})
+	router.PUT("/shared/:token", shared.RequireShareLink(users.PermissionTripUpdate), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	serve := func(method, token string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, "/shared/"+token, nil))
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "view")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, trips.ShareScopeRead, rec.Body.String())
+
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPut, "view").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "edit").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "unknown").Code)
+
+	// Used up after its one use
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "once").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "once").Code)
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/pkg/apiversion"
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// unversionedPaths are served under /api without a version
+var unversionedPaths = []string{"/api/health", "/api/changelog"}
+
+// NegotiateVersion routes requests to /api paths without a version to the
+// version the client asks for, in the API-Version header or an Accept media
+// type such as application/vnd.newmap.v2+json, and to the default version
+// when it asks for none. It wraps the router since the path has to be
+// rewritten before a route is matched.
+func NegotiateVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := unversionedPath(r.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		version, ok := requestedVersion(r)
+		if !ok {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response.Response{
+				Success: false,
+				Error: &response.Error{
+					Code:    "UNSUPPORTED_VERSION",
+					Message: "Unsupported API version",
+					Details: map[string]interface{}{"supported": apiversion.Supported},
+				},
+			})
+			return
+		}
+
+		r.URL.Path = "/api/" + version + rest
+		r.URL.RawPath = ""
+		w.Header().Add("Vary", apiversion.Header)
+		w.Header().Add("Vary", "Accept")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// unversionedPath returns what follows /api in a path that does not start
+// with a version
+func unversionedPath(path string) (string, bool) {
+	if !strings.HasPrefix(path, "/api/") {
+		return "", false
+	}
+	for _, unversioned := range unversionedPaths {
+		if path == unversioned || strings.HasPrefix(path, unversioned+"/") {
+			return "", false
+		}
+	}
+
+	rest := strings.TrimPrefix(path, "/api")
+	segment := strings.SplitN(strings.TrimPrefix(rest, "/"), "/", 2)[0]
+	for _, version := range apiversion.Supported {
+		if segment == version {
+			return "", false
+		}
+	}
+	return rest, true
+}
+
+// requestedVersion is the version the request asks for, the default when it
+// asks for none. It reports false for a version the API does not answer.
+func requestedVersion(r *http.Request) (string, bool) {
+	if header := r.Header.Get(apiversion.Header); header != "" {
+		return apiversion.Parse(header)
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(accept, ";", 2)[0])
+		if !strings.HasPrefix(mediaType, apiversion.MediaTypePrefix) {
+			continue
+		}
+		version := strings.TrimPrefix(mediaType, apiversion.MediaTypePrefix)
+		version = strings.TrimSuffix(version, "+json")
+		return apiversion.Parse(version)
+	}
+
+	return apiversion.Default, true
+}
+
+// APIVersion serves the routes it is used on as the version, which decides
+// the shape of their responses
+func APIVersion(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiversion.Set(c, version)
+		c.Header(apiversion.Header, version)
+		c.Next()
+	}
+}
+
+// Deprecated marks responses as coming from a deprecated version, with when
+// it was deprecated, when it stops being served, if that is known, and the
+// same resource in the version that replaces it
+func Deprecated(version, successor string, deprecatedAt, sunset time.Time) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", deprecatedAt.Unix())
+	prefix := "/api/" + version
+
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		path := "/api/" + successor + strings.TrimPrefix(c.Request.URL.Path, prefix)
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, path))
+		c.Next()
+	}
+}
+
+// TypedIDs turns away requests whose ID path parameters, id and those ending
+// in Id or ID, are not UUIDs, before they reach a handler or the database
+func TypedIDs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, param := range c.Params {
+			if !isIDParam(param.Key) {
+				continue
+			}
+			if _, err := uuid.Parse(param.Value); err != nil {
+				response.InvalidID(c, param.Key)
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+func isIDParam(name string) bool {
+	return name == "id" || strings.HasSuffix(name, "Id") || strings.HasSuffix(name, "ID")
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/pkg/apiversion"
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tripUUID = "6f1c2d3e-4b5a-4c7d-8e9f-0a1b2c3d4e5f"
+
+func versionedRouter(sunset time.Time) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+
+	routes := func(api *gin.RouterGroup) {
+		api.GET("/trips", func(c *gin.Context) {
+			response.SuccessWithMeta(c, []string{"a"}, response.NewCursorMeta(1, "next"))
+		})
+		api.GET("/trips/:id", func(c *gin.Context) {
+			response.NotFound(c, "Trip not found")
+		})
+		api.GET("/discover/:list", func(c *gin.Context) {
+			response.Success(c, c.Param("list"))
+		})
+	}
+	routes(router.Group("/api/v1", APIVersion(apiversion.V1), Deprecated(apiversion.V1, apiversion.V2, apiversion.V2Released, sunset)))
+	routes(router.Group("/api/v2", APIVersion(apiversion.V2), TypedIDs()))
+	router.GET("/api/changelog", func(c *gin.Context) { response.Success(c, apiversion.Changelog) })
+	return router
+}
+
+func serveVersioned(handler http.Handler, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestVersion_V1IsDeprecated(t *testing.T) {
+	sunset := time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+	rec := serveVersioned(versionedRouter(sunset), "/api/v1/trips/"+tripUUID, nil)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, apiversion.V1, rec.Header().Get(apiversion.Header))
+	assert.Equal(t, "@1792108800", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Thu, 01 Apr 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v2/trips/`+tripUUID+`>; rel="successor-version"`, rec.Header().Get("Link"))
+
+	// The v1 envelope is unchanged
+	var body response.Response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.False(t, body.Success)
+	assert.Equal(t, "NOT_FOUND", body.Error.Code)
+
+	// Without a sunset date there is no Sunset header
+	rec = serveVersioned(versionedRouter(time.Time{}), "/api/v1/trips", nil)
+	assert.Empty(t, rec.Header().Get("Sunset"))
+	assert.NotEmpty(t, rec.Header().Get("Deprecation"))
+}
+
+func TestVersion_V2Envelope(t *testing.T) {
+	router := versionedRouter(time.Time{})
+
+	rec := serveVersioned(router, "/api/v2/trips", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+	assert.JSONEq(t, `{"data":["a"],"meta":{"limit":1,"has_more":true,"next_cursor":"next"}}`, rec.Body.String())
+
+	rec = serveVersioned(router, "/api/v2/trips/"+tripUUID, map[string]string{"X-Request-ID": "req-1"})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"error":{"code":"NOT_FOUND","message":"Trip not found","request_id":"req-1"}}`, rec.Body.String())
+}
+
+func TestVersion_TypedIDs(t *testing.T) {
+	router := versionedRouter(time.Time{})
+
+	rec := serveVersioned(router, "/api/v2/trips/not-a-uuid", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var body response.Envelope
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "INVALID_ID", body.Error.Code)
+	assert.Equal(t, "id", body.Error.Details["param"])
+
+	// Other parameters are left alone, and v1 keeps accepting any ID
+	assert.Equal(t, http.StatusOK, serveVersioned(router, "/api/v2/discover/trending", nil).Code)
+	assert.Equal(t, http.StatusNotFound, serveVersioned(router, "/api/v1/trips/not-a-uuid", nil).Code)
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	handler := NegotiateVersion(versionedRouter(time.Time{}))
+
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		status  int
+		version string
+	}{
+		{"default", "/api/trips", nil, http.StatusOK, apiversion.V1},
+		{"header", "/api/trips", map[string]string{"API-Version": "2"}, http.StatusOK, apiversion.V2},
+		{"media type", "/api/trips", map[string]string{"Accept": "application/vnd.newmap.v2+json; q=1, */*"}, http.StatusOK, apiversion.V2},
+		{"header over media type", "/api/trips", map[string]string{"API-Version": "v1", "Accept": "application/vnd.newmap.v2+json"}, http.StatusOK, apiversion.V1},
+		{"explicit path wins", "/api/v2/trips", map[string]string{"API-Version": "1"}, http.StatusOK, apiversion.V2},
+		{"unsupported", "/api/trips", map[string]string{"API-Version": "9"}, http.StatusBadRequest, ""},
+		{"unversioned route", "/api/changelog", map[string]string{"API-Version": "2"}, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveVersioned(handler, tt.path, tt.headers)
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.version, rec.Header().Get(apiversion.Header))
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -147,6 +148,17 @@ func (p *Parser) parseWithRules(query string) *ParsedQuery {
 		p.parseActivityFilters(query, parsed)
 	}
 
+	// Accessibility applies to activities and places alike
+	p.parseAccessibility(query, parsed)
+
+	// Amenities are offered by places, and by activities at their trailhead
+	p.parseAmenities(query, parsed)
+
+	// Only places keep opening hours
+	if p.parseOpenNow(query, parsed) && parsed.Intent == IntentUnknown {
+		parsed.Intent = IntentPlace
+	}
+
 	// Parse location information
 	location := p.parseLocation(query)
 	if location != nil {
@@ -164,6 +176,10 @@ func (p *Parser) parseWithRules(query string) *ParsedQuery {
 	// Parse duration and distance
 	p.parseDurationAndDistance(query, parsed)
 
+	if parsed.Confidence > 1 {
+		parsed.Confidence = 1
+	}
+
 	return parsed
 }
 
@@ -262,6 +278,15 @@ func (p *Parser) parseActivityFilters(query string, parsed *ParsedQuery) {
 		}
 	}
 
+	// The keyword maps are iterated in random order; sort so that the same
+	// query always parses to the same output
+	if types, ok := parsed.Filters["activity_types"].([]string); ok {
+		sort.Strings(types)
+	}
+	if levels, ok := parsed.Filters["difficulty_levels"].([]string); ok {
+		sort.Strings(levels)
+	}
+
 	// Water features
 	waterFeatures := []string{
 		"waterfall", "waterfalls", "river", "rivers", "lake", "lakes",
@@ -291,6 +316,103 @@ func (p *Parser) parseActivityFilters(query string, parsed *ParsedQuery) {
 	}
 }
 
+// accessibilityPhrases maps how people ask for each accessibility attribute
+// to the attribute
+var accessibilityPhrases = map[string][]string{
+	"dog_friendly":          {"dog friendly", "dog-friendly", "dogs allowed", "with my dog", "with dogs", "with the dog", "pet friendly", "pet-friendly"},
+	"stroller_ok":           {"stroller", "pram", "pushchair", "buggy"},
+	"wheelchair_accessible": {"wheelchair", "step-free", "step free"},
+	"toddler_ok":            {"toddler", "kid friendly", "kid-friendly", "with kids", "family friendly", "family-friendly", "little ones"},
+}
+
+// accessibilityLabels are the attributes as explanations show them
+var accessibilityLabels = map[string]string{
+	"dog_friendly":          "dog friendly",
+	"stroller_ok":           "stroller friendly",
+	"wheelchair_accessible": "wheelchair accessible",
+	"toddler_ok":            "toddler friendly",
+}
+
+// parseAccessibility extracts the accessibility attributes asked for. All of
+// them must hold for a result to match.
+func (p *Parser) parseAccessibility(query string, parsed *ParsedQuery) {
+	var attributes []string
+	for attribute, phrases := range accessibilityPhrases {
+		for _, phrase := range phrases {
+			if strings.Contains(query, phrase) {
+				attributes = append(attributes, attribute)
+				break
+			}
+		}
+	}
+
+	if len(attributes) > 0 {
+		sort.Strings(attributes)
+		parsed.Filters["accessibility"] = attributes
+	}
+}
+
+// amenityPhrases maps how people ask for each amenity to the amenity, as
+// places list it
+var amenityPhrases = map[string][]string{
+	"toilets":       {"toilet", "restroom", "bathroom"},
+	"potable_water": {"drinking water", "potable water", "water fountain", "water refill"},
+	"ev_charging":   {"ev charging", "ev charger", "charging station", "electric car charging"},
+	"showers":       {"shower"},
+	"parking":       {"parking", "car park"},
+	"picnic_tables": {"picnic"},
+	"trash_bins":    {"trash bin", "trash can", "garbage bin", "rubbish bin"},
+	"wifi":          {"wifi", "wi-fi"},
+	"bike_racks":    {"bike rack", "bicycle rack"},
+}
+
+// amenityLabels are the amenities as explanations show them
+var amenityLabels = map[string]string{
+	"toilets":       "toilets",
+	"potable_water": "drinking water",
+	"ev_charging":   "EV charging",
+	"showers":       "showers",
+	"parking":       "parking",
+	"picnic_tables": "picnic tables",
+	"trash_bins":    "trash bins",
+	"wifi":          "Wi-Fi",
+	"bike_racks":    "bike racks",
+}
+
+// parseAmenities extracts the amenities asked for. All of them must be
+// offered for a result to match.
+func (p *Parser) parseAmenities(query string, parsed *ParsedQuery) {
+	var amenities []string
+	for amenity, phrases := range amenityPhrases {
+		for _, phrase := range phrases {
+			if strings.Contains(query, phrase) {
+				amenities = append(amenities, amenity)
+				break
+			}
+		}
+	}
+
+	if len(amenities) > 0 {
+		sort.Strings(amenities)
+		parsed.Filters["amenities"] = amenities
+	}
+}
+
+// openNowPhrases ask for places open at the time of the search
+var openNowPhrases = []string{"open now", "open right now", "currently open", "open at the moment"}
+
+// parseOpenNow reports whether the query asks for places that are open now,
+// filtering on it when it does
+func (p *Parser) parseOpenNow(query string, parsed *ParsedQuery) bool {
+	for _, phrase := range openNowPhrases {
+		if strings.Contains(query, phrase) {
+			parsed.Filters["open_now"] = true
+			return true
+		}
+	}
+	return false
+}
+
 // parseLocation extracts location information from the query
 func (p *Parser) parseLocation(query string) *LocationFilter {
 	// Look for location patterns
@@ -555,7 +677,7 @@ func (p *Parser) parseDurationAndDistance(query string, parsed *ParsedQuery) {
 func (p *Parser) extractKeywords(query string) []string {
 	// Simple keyword extraction - split and filter
 	words := strings.Fields(query)
-	var keywords []string
+	keywords := []string{}
 
 	// Filter out common stop words
 	stopWords := map[string]bool{
@@ -603,6 +725,24 @@ func (p *Parser) GenerateExplanation(parsed *ParsedQuery) string {
 		parts = append(parts, fmt.Sprintf("Difficulty: %s", strings.Join(difficultyLevels, ", ")))
 	}
 
+	// Accessibility
+	if attributes, ok := parsed.Filters["accessibility"].([]string); ok && len(attributes) > 0 {
+		labels := make([]string, len(attributes))
+		for i, attribute := range attributes {
+			labels[i] = accessibilityLabels[attribute]
+		}
+		parts = append(parts, fmt.Sprintf("Accessibility: %s", strings.Join(labels, ", ")))
+	}
+
+	// Amenities
+	if amenities, ok := parsed.Filters["amenities"].([]string); ok && len(amenities) > 0 {
+		labels := make([]string, len(amenities))
+		for i, amenity := range amenities {
+			labels[i] = amenityLabels[amenity]
+		}
+		parts = append(parts, fmt.Sprintf("Amenities: %s", strings.Join(labels, ", ")))
+	}
+
 	// Location
 	if parsed.Location != nil && parsed.Location.Name != "" {
 		parts = append(parts, fmt.Sprintf("Near %s", parsed.Location.Name))
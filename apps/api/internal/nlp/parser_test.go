@@ -0,0 +1,162 @@
+package nlp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// updateCorpus rewrites the expected outputs in the corpus from the current
+// parser. Review the diff before committing it.
+var updateCorpus = flag.Bool("update", false, "rewrite testdata/corpus.jsonl with the current parser output")
+
+const corpusPath = "testdata/corpus.jsonl"
+
+// corpusEntry is one line of the corpus: a query as users typed it and what
+// the parser is expected to make of it
+type corpusEntry struct {
+	Query    string          `json:"query"`
+	Expected json.RawMessage `json:"expected,omitempty"`
+}
+
+func readCorpus(t testing.TB) []corpusEntry {
+	t.Helper()
+
+	file, err := os.Open(corpusPath)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var entries []corpusEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry corpusEntry
+		require.NoError(t, json.Unmarshal(line, &entry), "corpus line %q", line)
+		entries = append(entries, entry)
+	}
+	require.NoError(t, scanner.Err())
+	return entries
+}
+
+func writeCorpus(t testing.TB, entries []corpusEntry) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		require.NoError(t, err)
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	require.NoError(t, os.WriteFile(corpusPath, buf.Bytes(), 0o644))
+}
+
+// TestParser_Corpus replays the query corpus and fails on any change in how a
+// query is parsed. To add queries, append {"query": "..."} lines to the
+// corpus and run:
+//
+//	go test ./internal/nlp -run Corpus -update
+func TestParser_Corpus(t *testing.T) {
+	parser := NewParser()
+	entries := readCorpus(t)
+
+	for i, entry := range entries {
+		parsed, err := parser.ParseQuery(context.Background(), entry.Query)
+		require.NoError(t, err, entry.Query)
+		actual, err := json.Marshal(parsed)
+		require.NoError(t, err)
+
+		if *updateCorpus {
+			entries[i].Expected = actual
+			continue
+		}
+
+		if assert.NotEmpty(t, entry.Expected, "no expected output for %q, run with -update", entry.Query) {
+			assert.JSONEq(t, string(entry.Expected), string(actual), "query %q", entry.Query)
+		}
+	}
+
+	if *updateCorpus {
+		writeCorpus(t, entries)
+	}
+}
+
+func TestParser_Deterministic(t *testing.T) {
+	parser := NewParser()
+	query := "easy or moderate hiking, biking and climbing trails"
+
+	first, err := parser.ParseQuery(context.Background(), query)
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		again, err := parser.ParseQuery(context.Background(), query)
+		require.NoError(t, err)
+		assert.Equal(t, first, again)
+	}
+}
+
+func TestParser_Accessibility(t *testing.T) {
+	parser := NewParser()
+	tests := []struct {
+		query      string
+		attributes interface{}
+	}{
+		{"dog-friendly waterfalls", []string{"dog_friendly"}},
+		{"hikes with my dog", []string{"dog_friendly"}},
+		{"step-free lakeside walk with a pram", []string{"stroller_ok", "wheelchair_accessible"}},
+		{"kid friendly beaches for little ones", []string{"toddler_ok"}},
+		{"Wheelchair Accessible viewpoints", []string{"wheelchair_accessible"}},
+		{"hard scrambles", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			parsed, err := parser.ParseQuery(context.Background(), tt.query)
+			require.NoError(t, err)
+			if tt.attributes == nil {
+				assert.NotContains(t, parsed.Filters, "accessibility")
+				return
+			}
+			assert.Equal(t, tt.attributes, parsed.Filters["accessibility"])
+		})
+	}
+}
+
+func FuzzParseQuery(f *testing.F) {
+	for _, entry := range readCorpus(f) {
+		f.Add(entry.Query)
+	}
+
+	parser := NewParser()
+	f.Fuzz(func(t *testing.T, query string) {
+		parsed, err := parser.ParseQuery(context.Background(), query)
+		require.NoError(t, err)
+		require.NotNil(t, parsed)
+
+		assert.Contains(t, []QueryIntent{IntentActivity, IntentPlace, IntentMixed, IntentUnknown}, parsed.Intent)
+		assert.NotNil(t, parsed.Filters)
+		assert.NotNil(t, parsed.Keywords)
+		assert.GreaterOrEqual(t, parsed.Confidence, 0.0)
+		assert.LessOrEqual(t, parsed.Confidence, 1.0)
+
+		// The result is served as JSON, so it must always encode
+		_, err = json.Marshal(parsed)
+		assert.NoError(t, err)
+
+		if utf8.ValidString(query) {
+			for _, keyword := range parsed.Keywords {
+				assert.True(t, utf8.ValidString(keyword), "keyword %q", keyword)
+			}
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/google/uuid"
+)
+
+// TripAuthorizer lets users follow the trips they can view. Missing trips are
+// reported as forbidden so their existence is not leaked.
+func TripAuthorizer(service trips.Service) Authorizer {
+	return func(ctx context.Context, userID, tripID string) (bool, error) {
+		if _, err := uuid.Parse(tripID); err != nil {
+			return false, nil
+		}
+
+		_, err := service.GetByIDWith(ctx, userID, tripID, trips.Relations{})
+		switch {
+		case err == nil:
+			return true, nil
+		case errors.Is(err, trips.ErrTripNotFound), errors.Is(err, trips.ErrUnauthorized):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+}
+
+// UserAuthorizer lets users follow their own notifications, and nobody
+// else's
+func UserAuthorizer() Authorizer {
+	return func(ctx context.Context, userID, subjectID string) (bool, error) {
+		return userID != "" && userID == subjectID, nil
+	}
+}
@@ -0,0 +1,159 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/internal/middleware"
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// heartbeatInterval keeps idle streams open through proxies that close
+	// silent connections
+	heartbeatInterval = 15 * time.Second
+
+	// retryAfter is how long EventSource clients wait before reconnecting
+	retryAfter = 3 * time.Second
+)
+
+// Handler serves the server-sent events stream
+type Handler struct {
+	hub *Hub
+}
+
+// NewHandler creates a new realtime handler
+func NewHandler(hub *Hub) *Handler {
+	return &Handler{
+		hub: hub,
+	}
+}
+
+// Stream sends events on the requested topics as server-sent events. Clients
+// reconnecting with a Last-Event-ID header are sent what they missed, or a
+// "reset" event when that is no longer known and they should reload.
+func (h *Handler) Stream(c *gin.Context) {
+	topics, err := ParseTopics(c.Query("topics"))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	for _, topic := range topics {
+		if err := h.hub.CanFollow(c.Request.Context(), userID, topic); err != nil {
+			switch {
+			case errors.Is(err, ErrInvalidTopic):
+				response.BadRequest(c, err.Error())
+			case errors.Is(err, ErrTopicForbidden):
+				response.Forbidden(c, err.Error())
+			default:
+				response.InternalServerError(c, "Failed to authorize topics")
+			}
+			return
+		}
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		// EventSource polyfills that cannot set headers send it as a parameter
+		lastEventID = c.Query("lastEventId")
+	}
+
+	sub, replay, resumed := h.hub.Subscribe(topics, lastEventID)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	controller := http.NewResponseController(c.Writer)
+	w := c.Writer
+
+	send := func(write func(io.Writer) error) bool {
+		// The server's write timeout would otherwise end every stream
+		controller.SetWriteDeadline(time.Now().Add(2 * heartbeatInterval))
+		if err := write(w); err != nil {
+			return false
+		}
+		w.Flush()
+		return true
+	}
+
+	if !send(func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "retry: %d\n\n", retryAfter.Milliseconds())
+		return err
+	}) {
+		return
+	}
+
+	if !resumed {
+		if !send(func(w io.Writer) error {
+			_, err := fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+			return err
+		}) {
+			return
+		}
+	}
+
+	for _, message := range replay {
+		if !send(writeMessage(message)) {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case message, ok := <-sub.Messages():
+			if !ok {
+				// Fell behind or the server is shutting down; the client
+				// reconnects and resumes from its last event
+				return
+			}
+			if !send(writeMessage(message)) {
+				return
+			}
+		case <-heartbeat.C:
+			if !send(func(w io.Writer) error {
+				_, err := fmt.Fprint(w, ": heartbeat\n\n")
+				return err
+			}) {
+				return
+			}
+		}
+	}
+}
+
+func writeMessage(message Message) func(io.Writer) error {
+	return func(w io.Writer) error {
+		data, err := json.Marshal(message.Event)
+		if err != nil {
+			log.Printf("realtime: failed to encode %s event %s: %v", message.Event.Type, message.Event.ID, err)
+			return nil
+		}
+		_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", message.Event.ID, message.Event.Type, data)
+		return err
+	}
+}
+
+// RegisterRoutes registers the event stream and the WebSocket channel. They
+// accept tokens in the query string, since neither EventSource nor WebSocket
+// clients in browsers can set an Authorization header.
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *users.RouteMiddleware) {
+	router.GET("/events", mw.OptionalStreamAuth, h.Stream)
+	router.GET("/ws", mw.OptionalStreamAuth, h.Socket)
+}
@@ -0,0 +1,334 @@
+// Package realtime fans domain events out to connected clients. Clients follow
+// topics such as "trip:123" and receive every event about that entity.
+package realtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+)
+
+var (
+	ErrInvalidTopic   = errors.New("invalid topic")
+	ErrTooManyTopics  = errors.New("too many topics")
+	ErrTopicForbidden = errors.New("not allowed to follow topic")
+)
+
+const (
+	// MaxTopics is how many topics one connection may follow
+	MaxTopics = 20
+
+	// historySize is how many recent messages each topic keeps for clients
+	// resuming after a dropped connection
+	historySize = 100
+
+	// historyRetention is how long the history of a topic nobody follows is
+	// kept, long enough for clients to reconnect
+	historyRetention = 5 * time.Minute
+
+	// bufferSize is how many messages a subscriber may fall behind by before
+	// it is disconnected
+	bufferSize = 64
+)
+
+// Topic names the stream of events about one entity
+func Topic(entityType, entityID string) string {
+	return entityType + ":" + entityID
+}
+
+// ParseTopics parses a comma separated list of topics
+func ParseTopics(raw string) ([]string, error) {
+	return CheckTopics(strings.Split(raw, ","))
+}
+
+// CheckTopics validates a list of topics, dropping blanks and duplicates
+func CheckTopics(list []string) ([]string, error) {
+	var topics []string
+	seen := make(map[string]bool)
+	for _, topic := range list {
+		topic = strings.TrimSpace(topic)
+		if topic == "" || seen[topic] {
+			continue
+		}
+		entityType, entityID, ok := strings.Cut(topic, ":")
+		if !ok || entityType == "" || entityID == "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidTopic, topic)
+		}
+		seen[topic] = true
+		topics = append(topics, topic)
+	}
+
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("%w: no topics given", ErrInvalidTopic)
+	}
+	if len(topics) > MaxTopics {
+		return nil, ErrTooManyTopics
+	}
+	return topics, nil
+}
+
+// Authorizer decides whether a user may follow the entity with the given ID.
+// userID is empty for anonymous clients.
+type Authorizer func(ctx context.Context, userID, entityID string) (bool, error)
+
+// Message is an event delivered on a topic
+type Message struct {
+	Topic string
+	Event events.Event
+}
+
+// Hub tracks subscribers by topic and delivers events to them
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[*Subscription]struct{}
+	history     map[string][]Message
+	authorizers map[string]Authorizer
+	closed      bool
+	lastSweep   time.Time
+}
+
+// NewHub creates an empty hub
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[string]map[*Subscription]struct{}),
+		history:     make(map[string][]Message),
+		authorizers: make(map[string]Authorizer),
+	}
+}
+
+// Authorize registers the authorizer for topics of an entity type. Topics of
+// entity types without an authorizer cannot be followed.
+func (h *Hub) Authorize(entityType string, authorizer Authorizer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.authorizers[entityType] = authorizer
+}
+
+// CanFollow checks a user may follow a topic
+func (h *Hub) CanFollow(ctx context.Context, userID, topic string) error {
+	entityType, entityID, _ := strings.Cut(topic, ":")
+
+	h.mu.RLock()
+	authorizer, ok := h.authorizers[entityType]
+	h.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrInvalidTopic, topic)
+	}
+
+	allowed, err := authorizer(ctx, userID, entityID)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s", ErrTopicForbidden, topic)
+	}
+	return nil
+}
+
+// HandleEvent delivers an event to the subscribers of its entity's topic. It
+// is subscribed to the event bus for every event type clients may see.
+func (h *Hub) HandleEvent(ctx context.Context, event events.Event) error {
+	h.Deliver(Message{Topic: Topic(event.EntityType, event.EntityID), Event: event})
+	return nil
+}
+
+// Deliver records a message in its topic's history and sends it to the
+// topic's subscribers. Subscribers that have fallen too far behind are
+// disconnected and can resume from the history.
+func (h *Hub) Deliver(message Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sweep()
+
+	// Nobody can resume a topic that was not being followed
+	if h.subscribers[message.Topic] == nil && h.history[message.Topic] == nil {
+		return
+	}
+
+	history := append(h.history[message.Topic], message)
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+	h.history[message.Topic] = history
+
+	for sub := range h.subscribers[message.Topic] {
+		select {
+		case sub.messages <- message:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// Subscribe follows topics. When lastEventID is given, messages on those
+// topics since that event are returned for replay; resumed is false when the
+// event is no longer in the history and the client must reload instead.
+func (h *Hub) Subscribe(topics []string, lastEventID string) (sub *Subscription, replay []Message, resumed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub = &Subscription{
+		hub:      h,
+		topics:   topics,
+		messages: make(chan Message, bufferSize),
+	}
+
+	if h.closed {
+		sub.done = true
+		close(sub.messages)
+		return sub, nil, true
+	}
+
+	h.sweep()
+
+	for _, topic := range topics {
+		if h.subscribers[topic] == nil {
+			h.subscribers[topic] = make(map[*Subscription]struct{})
+		}
+		h.subscribers[topic][sub] = struct{}{}
+	}
+
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+	replay, resumed = h.since(topics, lastEventID)
+	return sub, replay, resumed
+}
+
+// SubscribeEach follows each topic with a subscription of its own, so that
+// topics can later be unfollowed one at a time. Replay is as for Subscribe,
+// across all the topics.
+func (h *Hub) SubscribeEach(topics []string, lastEventID string) (subs map[string]*Subscription, replay []Message, resumed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs = make(map[string]*Subscription, len(topics))
+	for _, topic := range topics {
+		sub := &Subscription{
+			hub:      h,
+			topics:   []string{topic},
+			messages: make(chan Message, bufferSize),
+		}
+		subs[topic] = sub
+
+		if h.closed {
+			sub.done = true
+			close(sub.messages)
+			continue
+		}
+		if h.subscribers[topic] == nil {
+			h.subscribers[topic] = make(map[*Subscription]struct{})
+		}
+		h.subscribers[topic][sub] = struct{}{}
+	}
+
+	if h.closed || lastEventID == "" {
+		return subs, nil, true
+	}
+	h.sweep()
+	replay, resumed = h.since(topics, lastEventID)
+	return subs, replay, resumed
+}
+
+// since collects the messages on topics that followed lastEventID
+func (h *Hub) since(topics []string, lastEventID string) ([]Message, bool) {
+	var last *events.Event
+	for _, topic := range topics {
+		for i := range h.history[topic] {
+			if h.history[topic][i].Event.ID == lastEventID {
+				last = &h.history[topic][i].Event
+			}
+		}
+	}
+	if last == nil {
+		return nil, false
+	}
+
+	var replay []Message
+	for _, topic := range topics {
+		for _, message := range h.history[topic] {
+			if message.Event.OccurredAt.After(last.OccurredAt) {
+				replay = append(replay, message)
+			}
+		}
+	}
+
+	sort.SliceStable(replay, func(i, j int) bool {
+		return replay[i].Event.OccurredAt.Before(replay[j].Event.OccurredAt)
+	})
+	return replay, true
+}
+
+// sweep drops the history of topics nobody has followed for a while. It must
+// be called with h.mu held and does the work at most once a minute.
+func (h *Hub) sweep() {
+	now := time.Now()
+	if now.Sub(h.lastSweep) < time.Minute {
+		return
+	}
+	h.lastSweep = now
+
+	for topic, history := range h.history {
+		if h.subscribers[topic] != nil {
+			continue
+		}
+		if now.Sub(history[len(history)-1].Event.OccurredAt) > historyRetention {
+			delete(h.history, topic)
+		}
+	}
+}
+
+// Close disconnects every subscriber so open streams end during shutdown
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, subs := range h.subscribers {
+		for sub := range subs {
+			h.remove(sub)
+		}
+	}
+}
+
+// remove must be called with h.mu held
+func (h *Hub) remove(sub *Subscription) {
+	if sub.done {
+		return
+	}
+	sub.done = true
+	for _, topic := range sub.topics {
+		delete(h.subscribers[topic], sub)
+		if len(h.subscribers[topic]) == 0 {
+			delete(h.subscribers, topic)
+		}
+	}
+	close(sub.messages)
+}
+
+// Subscription is one client's view of the topics it follows
+type Subscription struct {
+	hub      *Hub
+	topics   []string
+	messages chan Message
+	done     bool
+}
+
+// Messages delivers events until the subscription is closed
+func (s *Subscription) Messages() <-chan Message {
+	return s.messages
+}
+
+// Close stops delivery
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
@@ -0,0 +1,119 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tripEvent(tripID string, at time.Time) events.Event {
+	event := events.New(events.TripUpdated, "trip", tripID, "user-1", nil)
+	event.OccurredAt = at
+	return event
+}
+
+func TestParseTopics(t *testing.T) {
+	topics, err := ParseTopics("trip:1, trip:2,trip:1,")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"trip:1", "trip:2"}, topics)
+
+	_, err = ParseTopics("")
+	assert.ErrorIs(t, err, ErrInvalidTopic)
+
+	_, err = ParseTopics("trip")
+	assert.ErrorIs(t, err, ErrInvalidTopic)
+}
+
+func TestHub_Deliver(t *testing.T) {
+	hub := NewHub()
+	sub, replay, resumed := hub.Subscribe([]string{"trip:1"}, "")
+	defer sub.Close()
+	assert.Empty(t, replay)
+	assert.True(t, resumed)
+
+	hub.HandleEvent(context.Background(), tripEvent("2", time.Now()))
+	hub.HandleEvent(context.Background(), tripEvent("1", time.Now()))
+
+	select {
+	case message := <-sub.Messages():
+		assert.Equal(t, "trip:1", message.Topic)
+		assert.Equal(t, "1", message.Event.EntityID)
+	case <-time.After(time.Second):
+		t.Fatal("event was not delivered")
+	}
+	assert.Empty(t, sub.Messages())
+}
+
+func TestHub_Resume(t *testing.T) {
+	hub := NewHub()
+	first, _, _ := hub.Subscribe([]string{"trip:1", "trip:2"}, "")
+
+	start := time.Now()
+	seen := tripEvent("1", start)
+	missedA := tripEvent("2", start.Add(time.Second))
+	missedB := tripEvent("1", start.Add(2*time.Second))
+	for _, event := range []events.Event{seen, missedA, missedB} {
+		hub.HandleEvent(context.Background(), event)
+	}
+	first.Close()
+
+	sub, replay, resumed := hub.Subscribe([]string{"trip:1", "trip:2"}, seen.ID)
+	defer sub.Close()
+	assert.True(t, resumed)
+	require.Len(t, replay, 2)
+	assert.Equal(t, missedA.ID, replay[0].Event.ID)
+	assert.Equal(t, missedB.ID, replay[1].Event.ID)
+
+	// An event that has left the history cannot be resumed from
+	other, replay, resumed := hub.Subscribe([]string{"trip:1"}, "unknown")
+	defer other.Close()
+	assert.False(t, resumed)
+	assert.Empty(t, replay)
+}
+
+func TestHub_DropsSlowSubscribers(t *testing.T) {
+	hub := NewHub()
+	sub, _, _ := hub.Subscribe([]string{"trip:1"}, "")
+
+	for i := 0; i <= bufferSize; i++ {
+		hub.HandleEvent(context.Background(), tripEvent("1", time.Now()))
+	}
+
+	received := 0
+	for range sub.Messages() {
+		received++
+	}
+	assert.Equal(t, bufferSize, received)
+
+	// Closing after being dropped is harmless
+	sub.Close()
+}
+
+func TestHub_CanFollow(t *testing.T) {
+	hub := NewHub()
+	hub.Authorize("trip", func(ctx context.Context, userID, tripID string) (bool, error) {
+		return userID == "owner", nil
+	})
+
+	assert.NoError(t, hub.CanFollow(context.Background(), "owner", "trip:1"))
+	assert.ErrorIs(t, hub.CanFollow(context.Background(), "", "trip:1"), ErrTopicForbidden)
+	assert.ErrorIs(t, hub.CanFollow(context.Background(), "owner", "place:1"), ErrInvalidTopic)
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := NewHub()
+	sub, _, _ := hub.Subscribe([]string{"trip:1"}, "")
+	hub.Close()
+
+	_, open := <-sub.Messages()
+	assert.False(t, open)
+
+	late, _, _ := hub.Subscribe([]string{"trip:1"}, "")
+	_, open = <-late.Messages()
+	assert.False(t, open)
+	late.Close()
+}
@@ -0,0 +1,306 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// maxClientMessage bounds the size of what clients send, which is only ever
+// a subscription change
+const maxClientMessage = 4 << 10
+
+// Message types exchanged over the socket
+const (
+	// Sent by clients
+	wsSubscribe   = "subscribe"
+	wsUnsubscribe = "unsubscribe"
+
+	// Sent by the server
+	wsSubscribed   = "subscribed"
+	wsUnsubscribed = "unsubscribed"
+	wsEvent        = "event"
+	wsReset        = "reset"
+	wsError        = "error"
+	wsHeartbeat    = "heartbeat"
+)
+
+// clientMessage changes what a socket follows. LastEventID resumes the
+// topics from an event received on an earlier connection.
+type clientMessage struct {
+	Type        string   `json:"type"`
+	Topics      []string `json:"topics"`
+	LastEventID string   `json:"last_event_id,omitempty"`
+}
+
+// serverMessage is sent to the client. Events carry the topic they were
+// delivered on.
+type serverMessage struct {
+	Type   string        `json:"type"`
+	Topic  string        `json:"topic,omitempty"`
+	Topics []string      `json:"topics,omitempty"`
+	Event  *events.Event `json:"event,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// Socket serves the WebSocket channel. Unlike the event stream, a socket can
+// follow and unfollow topics while it is open:
+//
+//	-> {"type": "subscribe", "topics": ["trip:123"], "last_event_id": "..."}
+//	<- {"type": "subscribed", "topics": ["trip:123"]}
+//	<- {"type": "event", "topic": "trip:123", "event": {...}}
+//	-> {"type": "unsubscribe", "topics": ["trip:123"]}
+//
+// A "reset" message lists topics whose events since last_event_id are no
+// longer known, which the client should reload. The socket is closed when the
+// client falls too far behind, and it resumes by reconnecting.
+func (h *Handler) Socket(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+
+	server := websocket.Server{
+		// Cross-origin requests have already been checked against the
+		// allowed origins, and clients outside browsers send no Origin
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			ws.MaxPayloadBytes = maxClientMessage
+			newSocket(h.hub, ws, userID).run(c.Request.Context())
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// socket is one WebSocket connection and the topics it follows
+type socket struct {
+	hub    *Hub
+	ws     *websocket.Conn
+	userID string
+
+	// Messages from every subscription are forwarded to deliveries, and a
+	// subscription the hub drops is reported on dropped
+	deliveries chan Message
+	dropped    chan string
+	done       chan struct{}
+
+	mu   sync.Mutex
+	subs map[string]*Subscription
+}
+
+func newSocket(hub *Hub, ws *websocket.Conn, userID string) *socket {
+	return &socket{
+		hub:        hub,
+		ws:         ws,
+		userID:     userID,
+		deliveries: make(chan Message, bufferSize),
+		dropped:    make(chan string, 1),
+		done:       make(chan struct{}),
+		subs:       make(map[string]*Subscription),
+	}
+}
+
+// run serves the socket until either side closes it. Only run writes to the
+// connection; a separate goroutine reads from it.
+func (s *socket) run(ctx context.Context) {
+	defer s.close()
+
+	// The server's read and write timeouts were meant for the handshake
+	s.ws.SetReadDeadline(time.Time{})
+
+	requests := make(chan clientMessage)
+	go s.read(requests)
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case request, ok := <-requests:
+			if !ok {
+				return
+			}
+			if !s.handle(ctx, request) {
+				return
+			}
+		case message := <-s.deliveries:
+			event := message.Event
+			if !s.send(serverMessage{Type: wsEvent, Topic: message.Topic, Event: &event}) {
+				return
+			}
+		case <-s.dropped:
+			// Fell behind or the server is shutting down; the client
+			// reconnects and resumes from its last event
+			return
+		case <-heartbeat.C:
+			if !s.send(serverMessage{Type: wsHeartbeat}) {
+				return
+			}
+		}
+	}
+}
+
+// read passes client messages to run until the connection is closed.
+// Messages that do not decode are passed on without a type, to be answered
+// with an error.
+func (s *socket) read(requests chan<- clientMessage) {
+	defer close(requests)
+	for {
+		var request clientMessage
+		if err := websocket.JSON.Receive(s.ws, &request); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr) {
+				return
+			}
+			request = clientMessage{}
+		}
+
+		select {
+		case requests <- request:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// handle applies a client message, returning false when the connection
+// should be closed
+func (s *socket) handle(ctx context.Context, request clientMessage) bool {
+	switch request.Type {
+	case wsSubscribe:
+		return s.subscribe(ctx, request)
+	case wsUnsubscribe:
+		topics := s.unsubscribe(request.Topics)
+		return s.send(serverMessage{Type: wsUnsubscribed, Topics: topics})
+	default:
+		return s.send(serverMessage{Type: wsError, Error: "unknown message type"})
+	}
+}
+
+func (s *socket) subscribe(ctx context.Context, request clientMessage) bool {
+	topics, err := CheckTopics(request.Topics)
+	if err != nil {
+		return s.send(serverMessage{Type: wsError, Error: err.Error()})
+	}
+
+	s.mu.Lock()
+	var added []string
+	for _, topic := range topics {
+		if s.subs[topic] == nil {
+			added = append(added, topic)
+		}
+	}
+	following := len(s.subs)
+	s.mu.Unlock()
+
+	if following+len(added) > MaxTopics {
+		return s.send(serverMessage{Type: wsError, Error: ErrTooManyTopics.Error()})
+	}
+	for _, topic := range added {
+		if err := s.hub.CanFollow(ctx, s.userID, topic); err != nil {
+			if !errors.Is(err, ErrInvalidTopic) && !errors.Is(err, ErrTopicForbidden) {
+				log.Printf("realtime: failed to authorize %s: %v", topic, err)
+				err = errors.New("failed to authorize topic")
+			}
+			return s.send(serverMessage{Type: wsError, Error: err.Error()})
+		}
+	}
+
+	subs, replay, resumed := s.hub.SubscribeEach(added, request.LastEventID)
+	s.mu.Lock()
+	for topic, sub := range subs {
+		s.subs[topic] = sub
+	}
+	s.mu.Unlock()
+	for topic, sub := range subs {
+		go s.forward(topic, sub)
+	}
+
+	if !s.send(serverMessage{Type: wsSubscribed, Topics: topics}) {
+		return false
+	}
+	if !resumed && !s.send(serverMessage{Type: wsReset, Topics: added}) {
+		return false
+	}
+	for _, message := range replay {
+		event := message.Event
+		if !s.send(serverMessage{Type: wsEvent, Topic: message.Topic, Event: &event}) {
+			return false
+		}
+	}
+	return true
+}
+
+// unsubscribe stops following topics and returns those that were followed
+func (s *socket) unsubscribe(topics []string) []string {
+	s.mu.Lock()
+	var removed []*Subscription
+	var names []string
+	for _, topic := range topics {
+		if sub := s.subs[topic]; sub != nil {
+			delete(s.subs, topic)
+			removed = append(removed, sub)
+			names = append(names, topic)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, sub := range removed {
+		sub.Close()
+	}
+	return names
+}
+
+// forward passes a subscription's messages on to run. A subscription that
+// ends while still followed was dropped by the hub.
+func (s *socket) forward(topic string, sub *Subscription) {
+	for message := range sub.Messages() {
+		select {
+		case s.deliveries <- message:
+		case <-s.done:
+			return
+		}
+	}
+
+	s.mu.Lock()
+	followed := s.subs[topic] == sub
+	s.mu.Unlock()
+	if followed {
+		select {
+		case s.dropped <- topic:
+		default:
+		}
+	}
+}
+
+// send writes a message, returning false when the connection is gone
+func (s *socket) send(message serverMessage) bool {
+	s.ws.SetWriteDeadline(time.Now().Add(2 * heartbeatInterval))
+	if err := websocket.JSON.Send(s.ws, message); err != nil {
+		return false
+	}
+	return true
+}
+
+func (s *socket) close() {
+	close(s.done)
+
+	s.mu.Lock()
+	subs := s.subs
+	s.subs = make(map[string]*Subscription)
+	s.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.Close()
+	}
+	s.ws.Close()
+}
@@ -0,0 +1,109 @@
+package realtime
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func setupSocket(t *testing.T) (*Hub, func() *websocket.Conn) {
+	gin.SetMode(gin.TestMode)
+
+	hub := NewHub()
+	hub.Authorize("trip", func(ctx context.Context, userID, tripID string) (bool, error) {
+		return tripID != "private", nil
+	})
+
+	router := gin.New()
+	NewHandler(hub).RegisterRoutes(router.Group("/api/v1"), &users.RouteMiddleware{OptionalStreamAuth: func(c *gin.Context) {}})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	dial := func() *websocket.Conn {
+		url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws"
+		ws, err := websocket.Dial(url, "", server.URL)
+		require.NoError(t, err)
+		t.Cleanup(func() { ws.Close() })
+		ws.SetDeadline(time.Now().Add(5 * time.Second))
+		return ws
+	}
+	return hub, dial
+}
+
+func exchange(t *testing.T, ws *websocket.Conn, request clientMessage) serverMessage {
+	require.NoError(t, websocket.JSON.Send(ws, request))
+	return receive(t, ws)
+}
+
+func receive(t *testing.T, ws *websocket.Conn) serverMessage {
+	var message serverMessage
+	require.NoError(t, websocket.JSON.Receive(ws, &message))
+	return message
+}
+
+func TestSocket(t *testing.T) {
+	hub, dial := setupSocket(t)
+	ws := dial()
+
+	reply := exchange(t, ws, clientMessage{Type: wsSubscribe, Topics: []string{"trip:1", "trip:2"}})
+	assert.Equal(t, serverMessage{Type: wsSubscribed, Topics: []string{"trip:1", "trip:2"}}, reply)
+
+	event := tripEvent("2", time.Now())
+	hub.HandleEvent(context.Background(), event)
+	reply = receive(t, ws)
+	assert.Equal(t, wsEvent, reply.Type)
+	assert.Equal(t, "trip:2", reply.Topic)
+	require.NotNil(t, reply.Event)
+	assert.Equal(t, event.ID, reply.Event.ID)
+
+	reply = exchange(t, ws, clientMessage{Type: wsSubscribe, Topics: []string{"trip:private"}})
+	assert.Equal(t, wsError, reply.Type)
+	assert.Contains(t, reply.Error, ErrTopicForbidden.Error())
+
+	reply = exchange(t, ws, clientMessage{Type: wsUnsubscribe, Topics: []string{"trip:2", "trip:3"}})
+	assert.Equal(t, serverMessage{Type: wsUnsubscribed, Topics: []string{"trip:2"}}, reply)
+
+	// Only trip 1 is still followed
+	hub.HandleEvent(context.Background(), tripEvent("2", time.Now()))
+	last := tripEvent("1", time.Now())
+	hub.HandleEvent(context.Background(), last)
+	reply = receive(t, ws)
+	assert.Equal(t, last.ID, reply.Event.ID)
+
+	reply = exchange(t, ws, clientMessage{Type: "publish"})
+	assert.Equal(t, serverMessage{Type: wsError, Error: "unknown message type"}, reply)
+}
+
+func TestSocket_Resume(t *testing.T) {
+	hub, dial := setupSocket(t)
+
+	first := dial()
+	exchange(t, first, clientMessage{Type: wsSubscribe, Topics: []string{"trip:1"}})
+	seen := tripEvent("1", time.Now())
+	hub.HandleEvent(context.Background(), seen)
+	receive(t, first)
+	first.Close()
+
+	missed := tripEvent("1", time.Now().Add(time.Second))
+	hub.HandleEvent(context.Background(), missed)
+
+	ws := dial()
+	reply := exchange(t, ws, clientMessage{Type: wsSubscribe, Topics: []string{"trip:1"}, LastEventID: seen.ID})
+	assert.Equal(t, wsSubscribed, reply.Type)
+	reply = receive(t, ws)
+	require.NotNil(t, reply.Event)
+	assert.Equal(t, missed.ID, reply.Event.ID)
+
+	// Topics with no history to resume from are reset
+	reply = exchange(t, ws, clientMessage{Type: wsSubscribe, Topics: []string{"trip:9"}, LastEventID: "unknown"})
+	assert.Equal(t, wsSubscribed, reply.Type)
+	assert.Equal(t, serverMessage{Type: wsReset, Topics: []string{"trip:9"}}, receive(t, ws))
+}
@@ -0,0 +1,76 @@
+package recent
+
+import (
+	"strconv"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/internal/middleware"
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// maxPlanningLimit is the most trips to continue planning returned at once
+const maxPlanningLimit = 20
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the routes listing what the user viewed lately
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *users.RouteMiddleware) {
+	me := router.Group("/users/me", mw.RequireAuth)
+	{
+		me.GET("/recent", h.Recent)
+		me.GET("/continue-planning", h.ContinuePlanning)
+	}
+}
+
+// Recent returns the trips and places the user viewed lately
+func (h *Handler) Recent(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > maxViews {
+		limit = 20
+	}
+
+	items, err := h.service.Recent(c.Request.Context(), userID, limit)
+	if err != nil {
+		response.InternalServerError(c, "Failed to get recently viewed")
+		return
+	}
+
+	response.Success(c, items)
+}
+
+// ContinuePlanning returns the trips the user was planning lately
+func (h *Handler) ContinuePlanning(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if limit < 1 || limit > maxPlanningLimit {
+		limit = 5
+	}
+
+	trips, err := h.service.ContinuePlanning(c.Request.Context(), userID, limit)
+	if err != nil {
+		response.InternalServerError(c, "Failed to get trips to continue planning")
+		return
+	}
+
+	response.Success(c, trips)
+}
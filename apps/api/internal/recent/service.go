@@ -0,0 +1,118 @@
+package recent
+
+import (
+	"context"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+)
+
+// planningWindow is how recently a trip must have been edited to be
+// offered for planning further
+const planningWindow = 60 * 24 * time.Hour
+
+// Item is a trip or place the user viewed, with when they last did
+type Item struct {
+	Kind     string        `json:"kind"`
+	ViewedAt time.Time     `json:"viewed_at"`
+	Trip     *trips.Trip   `json:"trip,omitempty"`
+	Place    *places.Place `json:"place,omitempty"`
+}
+
+// Service serves the home screen the user's recent views and the trips
+// they were planning
+type Service struct {
+	views     *Tracker
+	tripRepo  trips.Repository
+	placeRepo places.Repository
+}
+
+// NewService creates a new recent service. Without a tracker, as when
+// Redis is unavailable, no views are remembered.
+func NewService(views *Tracker, tripRepo trips.Repository, placeRepo places.Repository) *Service {
+	return &Service{
+		views:     views,
+		tripRepo:  tripRepo,
+		placeRepo: placeRepo,
+	}
+}
+
+// Recent returns what the user viewed lately, newest first. Trips and
+// places deleted or hidden from the user since are left out.
+func (s *Service) Recent(ctx context.Context, userID string, limit int) ([]*Item, error) {
+	items := []*Item{}
+	if s.views == nil {
+		return items, nil
+	}
+
+	views, err := s.views.Views(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var tripIDs, placeIDs []string
+	for _, view := range views {
+		switch view.Kind {
+		case KindTrip:
+			tripIDs = append(tripIDs, view.ID)
+		case KindPlace:
+			placeIDs = append(placeIDs, view.ID)
+		}
+	}
+
+	tripsByID := make(map[string]*trips.Trip, len(tripIDs))
+	if len(tripIDs) > 0 {
+		found, err := s.tripRepo.List(ctx, trips.TripFilters{
+			IDs:       tripIDs,
+			Limit:     len(tripIDs),
+			Relations: &trips.Relations{Collaborators: true},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, trip := range found {
+			tripsByID[trip.ID] = trip
+		}
+	}
+
+	placesByID := make(map[string]*places.Place, len(placeIDs))
+	if len(placeIDs) > 0 {
+		found, err := s.placeRepo.GetByIDs(ctx, placeIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, place := range found {
+			placesByID[place.ID] = place
+		}
+	}
+
+	for _, view := range views {
+		switch view.Kind {
+		case KindTrip:
+			if trip, ok := tripsByID[view.ID]; ok && trip.VisibleTo(userID) {
+				items = append(items, &Item{Kind: KindTrip, ViewedAt: view.ViewedAt, Trip: trip})
+			}
+		case KindPlace:
+			if place, ok := placesByID[view.ID]; ok && place.VisibleTo(userID) {
+				items = append(items, &Item{Kind: KindPlace, ViewedAt: view.ViewedAt, Place: place})
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// ContinuePlanning returns the trips still being planned that the user can
+// edit, most recently edited first
+func (s *Service) ContinuePlanning(ctx context.Context, userID string, limit int) ([]*trips.Trip, error) {
+	since := time.Now().Add(-planningWindow)
+	return s.tripRepo.List(ctx, trips.TripFilters{
+		EditorID:     userID,
+		Status:       "planning",
+		UpdatedAfter: &since,
+		SortBy:       "updated_at",
+		Limit:        limit,
+		Relations:    &trips.Relations{},
+	})
+}
@@ -0,0 +1,48 @@
+package recent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type listingTripRepository struct {
+	trips.Repository
+	filters trips.TripFilters
+}
+
+func (r *listingTripRepository) List(ctx context.Context, filters trips.TripFilters) ([]*trips.Trip, error) {
+	r.filters = filters
+	return []*trips.Trip{{ID: "trip-1", Status: "planning"}}, nil
+}
+
+func TestService_ContinuePlanning(t *testing.T) {
+	repo := &listingTripRepository{}
+	service := NewService(nil, repo, nil)
+
+	found, err := service.ContinuePlanning(context.Background(), "user-1", 5)
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+
+	assert.Equal(t, "user-1", repo.filters.EditorID)
+	assert.Equal(t, "planning", repo.filters.Status)
+	assert.Equal(t, "updated_at", repo.filters.SortBy)
+	assert.Equal(t, 5, repo.filters.Limit)
+	require.NotNil(t, repo.filters.UpdatedAfter)
+	assert.WithinDuration(t, time.Now().Add(-planningWindow), *repo.filters.UpdatedAfter, time.Minute)
+	require.NotNil(t, repo.filters.Relations)
+	assert.Equal(t, trips.Relations{}, *repo.filters.Relations)
+}
+
+func TestService_RecentWithoutTracker(t *testing.T) {
+	service := NewService(nil, &listingTripRepository{}, nil)
+
+	items, err := service.Recent(context.Background(), "user-1", 20)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	assert.NotNil(t, items)
+}
@@ -0,0 +1,82 @@
+// Package recent remembers the trips and places each user looks at, and
+// serves them back along with the trips they were planning, for the home
+// screen
+package recent
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/database"
+)
+
+// Kinds of things a user can view
+const (
+	KindTrip  = "trip"
+	KindPlace = "place"
+)
+
+const (
+	// maxViews is how many of their latest views are kept per user
+	maxViews = 50
+
+	// viewsTTL is how long a user's views are kept after their last one
+	viewsTTL = 30 * 24 * time.Hour
+)
+
+// View is a trip or place a user looked at
+type View struct {
+	Kind     string
+	ID       string
+	ViewedAt time.Time
+}
+
+// Tracker keeps each user's latest views in a Redis sorted set scored by
+// when they happened, so viewing something again moves it to the top
+type Tracker struct {
+	redis *database.RedisClient
+}
+
+// NewTracker creates a tracker of recent views
+func NewTracker(redisClient *database.RedisClient) *Tracker {
+	return &Tracker{redis: redisClient}
+}
+
+// RecordView remembers that the user viewed a trip or place. A view that
+// cannot be recorded is logged and otherwise ignored, as it must not fail
+// the request it came from.
+func (t *Tracker) RecordView(ctx context.Context, userID, kind, id string) {
+	key := database.BuildRecentViewsKey(userID)
+
+	if err := t.redis.ZAdd(ctx, key, float64(time.Now().UnixMilli()), kind+":"+id); err != nil {
+		log.Printf("recent: failed to record view of %s %s: %v", kind, id, err)
+		return
+	}
+	if err := t.redis.ZRemRangeByRank(ctx, key, 0, -maxViews-1); err != nil {
+		log.Printf("recent: failed to trim views of user %s: %v", userID, err)
+	}
+	if err := t.redis.Expire(ctx, key, viewsTTL); err != nil {
+		log.Printf("recent: failed to extend views of user %s: %v", userID, err)
+	}
+}
+
+// Views returns the user's latest views, newest first
+func (t *Tracker) Views(ctx context.Context, userID string, limit int) ([]View, error) {
+	members, err := t.redis.ZRevRangeWithScores(ctx, database.BuildRecentViewsKey(userID), 0, int64(limit)-1)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]View, 0, len(members))
+	for _, member := range members {
+		value, _ := member.Member.(string)
+		kind, id, ok := strings.Cut(value, ":")
+		if !ok {
+			continue
+		}
+		views = append(views, View{Kind: kind, ID: id, ViewedAt: time.UnixMilli(int64(member.Score))})
+	}
+	return views, nil
+}
@@ -0,0 +1,305 @@
+package search
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/elasticsearch"
+	"github.com/Oferzz/newMap/apps/api/internal/nlp"
+	"github.com/Oferzz/newMap/apps/api/pkg/pagination"
+)
+
+// fallbackSearch searches the database when Elasticsearch is unavailable,
+// translating the parsed query into the filters of the trips and places
+// repositories. Hits come back in the shape Elasticsearch returns them, scored
+// by their rank, so they are resolved and paged the same way. Collections are
+// only searchable through the index.
+func (s *Service) fallbackSearch(ctx context.Context, parsedQuery *nlp.ParsedQuery, req *SearchRequest) *elasticsearch.SearchResponse {
+	log.Printf("Using PostgreSQL fallback search for query: %s", req.Query)
+	start := time.Now()
+
+	searchTrips := parsedQuery.Intent != nlp.IntentPlace && s.tripRepo != nil
+	searchPlaces := parsedQuery.Intent != nlp.IntentActivity && s.placeRepo != nil
+
+	// A page of a mixed search can hold anything up to its end from either
+	// type, so both are read from the start and merged
+	limit, offset := req.Limit, req.Offset
+	if searchTrips && searchPlaces {
+		limit, offset = req.Limit+req.Offset, 0
+	}
+
+	response := &elasticsearch.SearchResponse{Results: []elasticsearch.SearchResult{}}
+	if searchTrips {
+		hits, total, err := s.fallbackTrips(ctx, parsedQuery, limit, offset)
+		if err != nil {
+			log.Printf("Fallback trip search failed: %v", err)
+		}
+		response.Results = append(response.Results, hits...)
+		response.Total += total
+	}
+	if searchPlaces {
+		hits, total, err := s.fallbackPlaces(ctx, parsedQuery, limit, offset)
+		if err != nil {
+			log.Printf("Fallback place search failed: %v", err)
+		}
+		response.Results = append(response.Results, hits...)
+		response.Total += total
+	}
+
+	if searchTrips && searchPlaces {
+		// Equal ranks keep trips ahead of places
+		sort.SliceStable(response.Results, func(i, j int) bool {
+			return response.Results[i].Score > response.Results[j].Score
+		})
+		response.Results = page(response.Results, req.Limit, req.Offset)
+	}
+
+	response.Took = int(time.Since(start).Milliseconds())
+	return response
+}
+
+// fallbackTrips searches trips for a parsed query, returning a page of hits
+// and how many trips match in all
+func (s *Service) fallbackTrips(ctx context.Context, parsedQuery *nlp.ParsedQuery, limit, offset int) ([]elasticsearch.SearchResult, int64, error) {
+	filters := tripFilters(parsedQuery)
+	filters.Limit = limit
+	filters.Offset = offset
+
+	found, err := s.tripRepo.List(ctx, filters)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := pagination.Total(len(found), limit, offset, func() (int64, error) {
+		return s.tripRepo.Count(ctx, filters)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]elasticsearch.SearchResult, len(found))
+	for i, trip := range found {
+		hits[i] = elasticsearch.SearchResult{ID: trip.ID, Type: "activity", Score: rankScore(offset + i)}
+	}
+	return hits, total, nil
+}
+
+// fallbackPlaces searches places for a parsed query, returning a page of hits
+// and how many places match in all
+func (s *Service) fallbackPlaces(ctx context.Context, parsedQuery *nlp.ParsedQuery, limit, offset int) ([]elasticsearch.SearchResult, int64, error) {
+	filters := places.SearchFilters{
+		Accessibility: stringsFilter(parsedQuery.Filters, "accessibility"),
+		Amenities:     stringsFilter(parsedQuery.Filters, "amenities"),
+		Limit:         limit,
+		Offset:        offset,
+	}
+
+	result, err := s.placeRepo.SearchWithSpatialContext(ctx, fallbackText(parsedQuery), placeSpatial(parsedQuery), filters)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]elasticsearch.SearchResult, len(result.Places))
+	for i, place := range result.Places {
+		hits[i] = elasticsearch.SearchResult{ID: place.ID, Type: "place", Score: rankScore(offset + i)}
+	}
+	return hits, result.Total, nil
+}
+
+// fillerWords say what is searched for without being part of what is found
+var fillerWords = map[string]bool{
+	"near": true, "around": true, "close": true, "under": true, "over": true,
+	"less": true, "more": true, "than": true, "within": true, "best": true,
+	"good": true, "find": true, "show": true, "some": true, "any": true,
+	"trail": true, "trails": true, "place": true, "places": true,
+	"spot": true, "spots": true, "things": true, "activities": true,
+	"mile": true, "miles": true, "kilometers": true, "kilometres": true,
+	"hour": true, "hours": true, "day": true, "days": true,
+}
+
+// fallbackText returns the words of the query the database matches text
+// against. Unlike Elasticsearch, the database wants every word to match, so
+// words the filters already stand for, such as "easy" or "hike", numbers and
+// filler are left out, and so is a location name once it was resolved to
+// coordinates.
+func fallbackText(parsedQuery *nlp.ParsedQuery) string {
+	var covered []string
+	for _, key := range []string{"activity_types", "difficulty_levels", "water_features", "accessibility", "amenities"} {
+		// Attributes such as dog_friendly stand for each of their words
+		for _, value := range stringsFilter(parsedQuery.Filters, key) {
+			covered = append(covered, strings.Split(value, "_")...)
+		}
+	}
+	if parsedQuery.Location != nil && parsedQuery.Location.Name != "" {
+		if _, resolved := locationFilter(parsedQuery); resolved {
+			covered = append(covered, strings.Fields(strings.ToLower(parsedQuery.Location.Name))...)
+		}
+	}
+
+	var words []string
+	for _, keyword := range parsedQuery.Keywords {
+		if fillerWords[keyword] || isNumber(keyword) || coveredBy(keyword, covered) {
+			continue
+		}
+		words = append(words, keyword)
+	}
+	return strings.Join(words, " ")
+}
+
+// coveredBy reports whether a word is one of the values, or another form of
+// one, such as "hike" of "hiking" or "waterfall" of "waterfalls": they share
+// all but the last letter of the shorter, and at least three
+func coveredBy(word string, values []string) bool {
+	for _, value := range values {
+		value = strings.ToLower(value)
+		shared := 0
+		for shared < len(word) && shared < len(value) && word[shared] == value[shared] {
+			shared++
+		}
+		if shared >= 3 && shared >= min(len(word), len(value))-1 {
+			return true
+		}
+	}
+	return false
+}
+
+func isNumber(word string) bool {
+	_, err := strconv.ParseFloat(strings.TrimRight(word, "kmi"), 64)
+	return err == nil
+}
+
+// tripFilters translates the filters of a parsed query into trip filters.
+// Related records are left out, as hits are loaded again when resolved.
+func tripFilters(parsedQuery *nlp.ParsedQuery) trips.TripFilters {
+	filters := trips.TripFilters{
+		Search:           fallbackText(parsedQuery),
+		ActivityTypes:    stringsFilter(parsedQuery.Filters, "activity_types"),
+		DifficultyLevels: stringsFilter(parsedQuery.Filters, "difficulty_levels"),
+		WaterFeatures:    stringsFilter(parsedQuery.Filters, "water_features"),
+		Accessibility:    stringsFilter(parsedQuery.Filters, "accessibility"),
+		Relations:        &trips.Relations{},
+	}
+
+	if maxDistance, ok := parsedQuery.Filters["max_distance"].(float64); ok {
+		filters.MaxDistance = &maxDistance
+	}
+	if maxDuration, ok := parsedQuery.Filters["max_duration"].(float64); ok {
+		filters.MaxDuration = &maxDuration
+	}
+
+	if visibility, ok := parsedQuery.Filters["visibility_filter"].(map[string]interface{}); ok {
+		filters.VisibleTo, _ = visibility["user_id"].(string)
+	}
+	if filters.VisibleTo == "" {
+		filters.Privacy = trips.PrivacyPublic
+	}
+
+	if lat, lng, radius, ok := searchCircle(parsedQuery); ok {
+		filters.NearLat, filters.NearLng, filters.RadiusKm = &lat, &lng, &radius
+	}
+
+	return filters
+}
+
+// placeSpatial returns the spatial context places are searched in, adding
+// the location of the query when it was resolved to coordinates
+func placeSpatial(parsedQuery *nlp.ParsedQuery) *nlp.SpatialSearchContext {
+	location, ok := locationFilter(parsedQuery)
+	if !ok {
+		return parsedQuery.Spatial
+	}
+
+	// The parsed query is returned to the client as it was
+	spatial := &nlp.SpatialSearchContext{}
+	if parsedQuery.Spatial != nil {
+		*spatial = *parsedQuery.Spatial
+	}
+	if spatial.Near == nil {
+		radius := location.radius
+		spatial.Near = &nlp.AreaFilter{
+			Type:        "circle",
+			Coordinates: []interface{}{location.lng, location.lat},
+			Radius:      &radius,
+		}
+	}
+	return spatial
+}
+
+// searchCircle returns the circle trips are searched in: the location of the
+// query, or else the first circle of its spatial context. Trips cannot be
+// searched within other shapes.
+func searchCircle(parsedQuery *nlp.ParsedQuery) (lat, lng, radius float64, ok bool) {
+	if location, ok := locationFilter(parsedQuery); ok {
+		return location.lat, location.lng, location.radius, true
+	}
+
+	spatial := parsedQuery.Spatial
+	if spatial == nil {
+		return 0, 0, 0, false
+	}
+	for _, area := range []*nlp.AreaFilter{spatial.Near, spatial.Within, spatial.Intersects} {
+		if area == nil || area.Type != "circle" || area.Radius == nil {
+			continue
+		}
+		coords, isList := area.Coordinates.([]interface{})
+		if !isList || len(coords) < 2 {
+			continue
+		}
+		lng, lngOk := coords[0].(float64)
+		lat, latOk := coords[1].(float64)
+		if latOk && lngOk {
+			return lat, lng, *area.Radius, true
+		}
+	}
+	return 0, 0, 0, false
+}
+
+type circle struct {
+	lat, lng, radius float64
+}
+
+// locationFilter returns the location filter the Elasticsearch query was
+// built with. Locations without a radius are searched as regions.
+func locationFilter(parsedQuery *nlp.ParsedQuery) (circle, bool) {
+	location, ok := parsedQuery.Filters["location"].(map[string]interface{})
+	if !ok {
+		return circle{}, false
+	}
+	lat, latOk := location["lat"].(float64)
+	lng, lngOk := location["lng"].(float64)
+	if !latOk || !lngOk {
+		return circle{}, false
+	}
+	radius, _ := location["radius"].(float64)
+	if radius <= 0 {
+		radius = regionRadiusKm
+	}
+	return circle{lat: lat, lng: lng, radius: radius}, true
+}
+
+func stringsFilter(filters map[string]interface{}, key string) []string {
+	values, _ := filters[key].([]string)
+	return values
+}
+
+// rankScore scores a hit by its rank, so hits of different types merge in
+// turn
+func rankScore(rank int) float64 {
+	return 1 / float64(rank+1)
+}
+
+func page(hits []elasticsearch.SearchResult, limit, offset int) []elasticsearch.SearchResult {
+	if offset >= len(hits) {
+		return []elasticsearch.SearchResult{}
+	}
+	hits = hits[offset:]
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
@@ -0,0 +1,124 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/geocode"
+	"github.com/Oferzz/newMap/apps/api/internal/nlp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFallbackService() (*Service, *fakeTripRepository, *fakePlaceRepository) {
+	service, tripRepo, placeRepo := newHydrationService()
+	service.nlpParser = nlp.NewParser()
+	return service, tripRepo, placeRepo
+}
+
+func TestService_FallbackSearchTrips(t *testing.T) {
+	service, tripRepo, placeRepo := newFallbackService()
+
+	response, err := service.Search(context.Background(), &SearchRequest{Query: "easy hiking trails under 10 km"})
+	require.NoError(t, err)
+
+	// Activities only, with the parsed filters and what is left of the text
+	require.Len(t, tripRepo.filters, 1)
+	filters := tripRepo.filters[0]
+	assert.Equal(t, []string{"hiking"}, filters.ActivityTypes)
+	assert.Equal(t, []string{"easy"}, filters.DifficultyLevels)
+	require.NotNil(t, filters.MaxDistance)
+	assert.Equal(t, 10.0, *filters.MaxDistance)
+	assert.Empty(t, filters.Search)
+	assert.Equal(t, 20, filters.Limit)
+	assert.Equal(t, &trips.Relations{}, filters.Relations)
+	assert.Empty(t, placeRepo.searches)
+
+	// Guests only search public trips, and the private one is dropped anyway
+	assert.Equal(t, trips.PrivacyPublic, filters.Privacy)
+	assert.Empty(t, filters.VisibleTo)
+	assert.Equal(t, []string{publicTripID}, resultIDs(response.Results))
+	assert.Equal(t, int64(1), response.Total)
+
+	// Members also search what they own or were invited to
+	_, err = service.Search(context.Background(), &SearchRequest{Query: "hiking", UserID: memberID})
+	require.NoError(t, err)
+	assert.Equal(t, memberID, tripRepo.filters[1].VisibleTo)
+	assert.Empty(t, tripRepo.filters[1].Privacy)
+}
+
+func TestService_FallbackSearchPlaces(t *testing.T) {
+	service, tripRepo, placeRepo := newFallbackService()
+	service.geocoder = &fakeGeocoder{results: map[string]*geocode.Result{
+		"bend": {Latitude: 44.05, Longitude: -121.31},
+	}}
+
+	response, err := service.Search(context.Background(), &SearchRequest{Query: "cafes near Bend"})
+	require.NoError(t, err)
+	assert.Empty(t, tripRepo.filters)
+
+	// The resolved location becomes a circle to search around, and its name
+	// is no longer matched as text
+	require.Len(t, placeRepo.searches, 1)
+	search := placeRepo.searches[0]
+	assert.Equal(t, "cafes", search.query)
+	require.NotNil(t, search.spatial)
+	require.NotNil(t, search.spatial.Near)
+	assert.Equal(t, []interface{}{-121.31, 44.05}, search.spatial.Near.Coordinates)
+	assert.Equal(t, 50.0, *search.spatial.Near.Radius)
+	assert.Nil(t, response.Query.Spatial)
+
+	// The secret place is found but not shown
+	assert.Equal(t, []string{publicPlaceID}, resultIDs(response.Results))
+	assert.Equal(t, int64(1), response.Total)
+}
+
+func TestService_FallbackSearchMixed(t *testing.T) {
+	service, tripRepo, placeRepo := newFallbackService()
+	placeRepo.places = append(placeRepo.places, &places.Place{ID: "7b0d0c8e-0000-4000-8000-0000000000b3", Name: "Fall River", Privacy: "public"})
+
+	// Either type can fill the page, so both are read up to its end and
+	// merged in turn
+	response, err := service.Search(context.Background(), &SearchRequest{Query: "waterfalls", Limit: 2, Offset: 1, UserID: memberID})
+	require.NoError(t, err)
+	assert.Equal(t, 3, tripRepo.filters[0].Limit)
+	assert.Equal(t, 0, tripRepo.filters[0].Offset)
+	assert.Equal(t, 3, placeRepo.searches[0].filters.Limit)
+	assert.Equal(t, "waterfalls", placeRepo.searches[0].query)
+
+	// Trips and places in turn: public trip, public place, private trip,
+	// secret place; the page starts at the second
+	assert.Equal(t, []string{publicPlaceID, privateTripID}, resultIDs(response.Results))
+	assert.Equal(t, int64(5), response.Total)
+}
+
+func TestService_FallbackSearchAccessibility(t *testing.T) {
+	service, tripRepo, _ := newFallbackService()
+
+	_, err := service.Search(context.Background(), &SearchRequest{Query: "dog friendly easy hiking trails"})
+	require.NoError(t, err)
+
+	// Every attribute asked for is required, and none is matched as text
+	require.Len(t, tripRepo.filters, 1)
+	assert.Equal(t, []string{"dog_friendly"}, tripRepo.filters[0].Accessibility)
+	assert.Empty(t, tripRepo.filters[0].Search)
+}
+
+func TestFallbackText(t *testing.T) {
+	parser := nlp.NewParser()
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"waterfalls", "waterfalls"},
+		{"easy hike to a lake", "lake"},
+		{"biking trails under 20 miles", ""},
+	}
+	for _, tt := range tests {
+		parsed, err := parser.ParseQuery(context.Background(), tt.query)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, fallbackText(parsed), tt.query)
+	}
+}
@@ -1,10 +1,12 @@
 package search
 
 import (
+	"net/http"
 	"strconv"
 
-	"github.com/gin-gonic/gin"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
 	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
 )
 
 // Handler handles search-related HTTP requests
@@ -118,43 +120,54 @@ func (h *Handler) GetSuggestions(c *gin.Context) {
 	response.Success(c, suggestions)
 }
 
-// ParseQuery handles query parsing requests (for debugging/testing)
+// ParseRequest is the body of a parse request
+type ParseRequest struct {
+	Query string `json:"query" binding:"required,max=500"`
+}
+
+// ParseQuery shows how a query is interpreted, without running the search.
+// It is meant for debugging the parser and checking queries from the logs.
 // @Summary Parse Natural Language Query
 // @Description Parse a natural language query to show how it will be interpreted
 // @Tags search
 // @Accept json
 // @Produce json
-// @Param q query string true "Query to parse"
+// @Param request body ParseRequest true "Query to parse"
 // @Success 200 {object} response.Response{data=nlp.ParsedQuery}
 // @Failure 400 {object} response.Response
 // @Failure 500 {object} response.Response
-// @Router /api/v1/search/parse [get]
+// @Router /api/v1/search/parse [post]
 func (h *Handler) ParseQuery(c *gin.Context) {
-	query := c.Query("q")
-	if query == "" {
-		response.BadRequest(c, "Query parameter 'q' is required")
+	var req ParseRequest
+	if c.Request.Method == http.MethodGet {
+		// Kept for clients of the earlier GET endpoint
+		req.Query = c.Query("q")
+		if req.Query == "" {
+			response.BadRequest(c, "Query parameter 'q' is required")
+			return
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	parsed, err := h.service.ParseQuery(c.Request.Context(), req.Query)
+	if err != nil {
+		response.InternalServerError(c, "Failed to parse query")
 		return
 	}
 
-	// This would require access to the NLP parser
-	// For now, we'll return a simple response
-	response.Success(c, map[string]interface{}{
-		"query": query,
-		"note":  "Query parsing endpoint - implementation depends on NLP service integration",
-	})
+	response.Success(c, parsed)
 }
 
-// RegisterRoutes registers search routes with the gin router
-func (h *Handler) RegisterRoutes(router *gin.RouterGroup, authMiddleware ...gin.HandlerFunc) {
-	search := router.Group("/search")
+// RegisterRoutes registers search routes with the gin router. Search is
+// public; signed-in users also find what was shared with them.
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *users.RouteMiddleware) {
+	search := router.Group("/search", mw.OptionalAuth)
 	{
-		// Apply optional auth middleware if provided
-		if len(authMiddleware) > 0 {
-			search.Use(authMiddleware[0])
-		}
-		
 		search.GET("", h.Search)
 		search.GET("/suggestions", h.GetSuggestions)
+		search.POST("/parse", h.ParseQuery)
 		search.GET("/parse", h.ParseQuery)
 	}
 }
\ No newline at end of file
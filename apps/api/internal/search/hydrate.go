@@ -0,0 +1,307 @@
+package search
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/collections"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/elasticsearch"
+	"github.com/google/uuid"
+)
+
+// Result types
+const (
+	ResultTypeTrip       = "trip"
+	ResultTypePlace      = "place"
+	ResultTypeCollection = "collection"
+)
+
+// Result is a search hit resolved against the database. Exactly one of Trip,
+// Place and Collection is set, matching Type.
+type Result struct {
+	Type       string             `json:"type"`
+	Score      float64            `json:"score"`
+	Trip       *TripSummary       `json:"trip,omitempty"`
+	Place      *PlaceSummary      `json:"place,omitempty"`
+	Collection *CollectionSummary `json:"collection,omitempty"`
+
+	// Curation is "curated" or "sponsored" for results pinned by an admin
+	Curation string `json:"curation,omitempty"`
+}
+
+// ID returns the ID of the trip, place or collection
+func (r Result) ID() string {
+	if r.Trip != nil {
+		return r.Trip.ID
+	}
+	if r.Place != nil {
+		return r.Place.ID
+	}
+	if r.Collection != nil {
+		return r.Collection.ID
+	}
+	return ""
+}
+
+// TripSummary is what a search result shows of a trip
+type TripSummary struct {
+	ID              string    `json:"id"`
+	Title           string    `json:"title"`
+	Description     string    `json:"description"`
+	Summary         string    `json:"summary,omitempty"` // Generated, for the result's snippet
+	OwnerID         string    `json:"owner_id"`
+	CoverImage      string    `json:"cover_image,omitempty"`
+	ActivityType    string    `json:"activity_type,omitempty"`
+	DifficultyLevel string    `json:"difficulty_level,omitempty"`
+	Tags            []string  `json:"tags"`
+	Accessibility   []string  `json:"accessibility,omitempty"`
+	Stats           TripStats `json:"stats"`
+}
+
+// TripStats are the figures shown on a trip result
+type TripStats struct {
+	DistanceKm      *float64 `json:"distance_km,omitempty"`
+	DurationHours   *float64 `json:"duration_hours,omitempty"`
+	ElevationGainM  *int     `json:"elevation_gain_m,omitempty"`
+	ViewCount       int      `json:"view_count"`
+	CompletionCount int      `json:"completion_count"`
+	AverageRating   *float64 `json:"average_rating,omitempty"`
+	RatingCount     int      `json:"rating_count"`
+}
+
+// PlaceSummary is what a search result shows of a place
+type PlaceSummary struct {
+	ID            string           `json:"id"`
+	Name          string           `json:"name"`
+	Type          string           `json:"type"`
+	City          string           `json:"city,omitempty"`
+	Country       string           `json:"country,omitempty"`
+	Location      *places.GeoPoint `json:"location,omitempty"`
+	Category      []string         `json:"category"`
+	Accessibility []string         `json:"accessibility,omitempty"`
+	CoverImage    string           `json:"cover_image,omitempty"`
+	OpenNow       *bool            `json:"open_now,omitempty"` // for places with opening hours
+	Stats         PlaceStats       `json:"stats"`
+}
+
+// PlaceStats are the figures shown on a place result
+type PlaceStats struct {
+	AverageRating *float32 `json:"average_rating,omitempty"`
+	RatingCount   int      `json:"rating_count"`
+}
+
+// CollectionSummary is what a search result shows of a collection
+type CollectionSummary struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	OwnerID     string `json:"owner_id"`
+}
+
+// hydrate resolves search hits against the trips, places and collections
+// repositories, one batch per type. The index can lag behind the database, so hits for
+// records that are gone are dropped and removed from the index, and hits the
+// user may not see are dropped. Without a repository nothing of its type can
+// be checked, so nothing of its type is returned.
+func (s *Service) hydrate(ctx context.Context, hits []elasticsearch.SearchResult, userID string) ([]Result, error) {
+	var tripIDs, placeIDs []string
+	var collectionIDs []uuid.UUID
+	for _, hit := range hits {
+		id, err := uuid.Parse(hit.ID)
+		if err != nil {
+			continue
+		}
+		switch hit.Type {
+		case "activity":
+			tripIDs = append(tripIDs, hit.ID)
+		case "place":
+			placeIDs = append(placeIDs, hit.ID)
+		case "collection":
+			collectionIDs = append(collectionIDs, id)
+		}
+	}
+
+	tripsByID := make(map[string]*trips.Trip, len(tripIDs))
+	if s.tripRepo != nil && len(tripIDs) > 0 {
+		found, err := s.tripRepo.GetByIDs(ctx, tripIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, trip := range found {
+			tripsByID[trip.ID] = trip
+		}
+		s.purgeMissing("activity", tripIDs, func(id string) bool { return tripsByID[id] != nil })
+	}
+
+	placesByID := make(map[string]*places.Place, len(placeIDs))
+	if s.placeRepo != nil && len(placeIDs) > 0 {
+		found, err := s.placeRepo.GetByIDs(ctx, placeIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, place := range found {
+			placesByID[place.ID] = place
+		}
+		s.purgeMissing("place", placeIDs, func(id string) bool { return placesByID[id] != nil })
+	}
+
+	collectionsByID := make(map[string]*collections.Collection, len(collectionIDs))
+	if s.collectionRepo != nil && len(collectionIDs) > 0 {
+		found, err := s.collectionRepo.GetByIDs(ctx, collectionIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range found {
+			collectionsByID[found[i].ID.String()] = &found[i]
+		}
+		ids := make([]string, len(collectionIDs))
+		for i, id := range collectionIDs {
+			ids[i] = id.String()
+		}
+		s.purgeMissing("collection", ids, func(id string) bool { return collectionsByID[id] != nil })
+	}
+
+	results := make([]Result, 0, len(hits))
+	for _, hit := range hits {
+		switch hit.Type {
+		case "activity":
+			if trip, ok := tripsByID[hit.ID]; ok && trip.VisibleTo(userID) {
+				// Summaries are built from what the public sees of the trip, so
+				// nothing private can reach one as summaries grow
+				if !trip.IsOwner(userID) && !trip.HasCollaborator(userID) {
+					trip = trip.ForAudience(trips.AudiencePublic)
+				}
+				results = append(results, Result{Type: ResultTypeTrip, Score: hit.Score, Trip: summarizeTrip(trip)})
+			}
+		case "place":
+			if place, ok := placesByID[hit.ID]; ok && place.VisibleTo(userID) {
+				results = append(results, Result{Type: ResultTypePlace, Score: hit.Score, Place: summarizePlace(place)})
+			}
+		case "collection":
+			// Unlisted collections are reachable by link but never listed
+			if collection, ok := collectionsByID[hit.ID]; ok && collection.Discoverable() {
+				results = append(results, Result{Type: ResultTypeCollection, Score: hit.Score, Collection: summarizeCollection(collection)})
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// openOnly keeps the places that are open, dropping everything without
+// opening hours
+func openOnly(results []Result) []Result {
+	open := results[:0]
+	for _, result := range results {
+		if result.Place != nil && result.Place.OpenNow != nil && *result.Place.OpenNow {
+			open = append(open, result)
+		}
+	}
+	return open
+}
+
+// purgeMissing removes the documents of records that no longer exist from the
+// index, in the background so the search does not wait on it
+func (s *Service) purgeMissing(docType string, ids []string, exists func(string) bool) {
+	var missing []string
+	for _, id := range ids {
+		if !exists(id) {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 || s.esClient == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		for _, id := range missing {
+			if err := s.DeleteFromIndex(ctx, docType, id); err != nil {
+				log.Printf("Failed to remove stale %s %s from search index: %v", docType, id, err)
+			}
+		}
+	}()
+}
+
+func summarizeTrip(trip *trips.Trip) *TripSummary {
+	tags := []string(trip.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return &TripSummary{
+		ID:              trip.ID,
+		Title:           trip.Title,
+		Description:     trip.Description,
+		Summary:         trip.Summary,
+		OwnerID:         trip.OwnerID,
+		CoverImage:      trip.CoverImage,
+		ActivityType:    trip.ActivityType,
+		DifficultyLevel: trip.DifficultyLevel,
+		Tags:            tags,
+		Accessibility:   trip.Accessibility,
+		Stats: TripStats{
+			DistanceKm:      trip.DistanceKm,
+			DurationHours:   trip.DurationHours,
+			ElevationGainM:  trip.ElevationGainM,
+			ViewCount:       trip.ViewCount,
+			CompletionCount: trip.CompletionCount,
+			AverageRating:   trip.AverageRating,
+			RatingCount:     trip.RatingCount,
+		},
+	}
+}
+
+func summarizeCollection(collection *collections.Collection) *CollectionSummary {
+	summary := &CollectionSummary{
+		ID:      collection.ID.String(),
+		Name:    collection.Name,
+		OwnerID: collection.UserID.String(),
+	}
+	if collection.Description != nil {
+		summary.Description = *collection.Description
+	}
+	return summary
+}
+
+func summarizePlace(place *places.Place) *PlaceSummary {
+	category := []string(place.Category)
+	if category == nil {
+		category = []string{}
+	}
+
+	summary := &PlaceSummary{
+		ID:            place.ID,
+		Name:          place.Name,
+		Type:          place.Type,
+		City:          place.City,
+		Country:       place.Country,
+		Location:      place.Location,
+		Category:      category,
+		Accessibility: place.Accessibility,
+		Stats: PlaceStats{
+			AverageRating: place.AverageRating,
+			RatingCount:   place.RatingCount,
+		},
+	}
+
+	if place.OpeningHours != nil && place.OpeningHours.Known() {
+		open := place.OpeningHours.OpenAt(time.Now())
+		summary.OpenNow = &open
+	}
+
+	// The first media item is the cover, its thumbnail when there is one
+	if len(place.Media) > 0 {
+		summary.CoverImage = place.Media[0].ThumbnailURL
+		if summary.CoverImage == "" {
+			summary.CoverImage = place.Media[0].URL
+		}
+	}
+
+	return summary
+}
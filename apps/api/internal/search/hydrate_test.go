@@ -0,0 +1,244 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/collections"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/elasticsearch"
+	"github.com/Oferzz/newMap/apps/api/internal/nlp"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	ownerID    = "7b0d0c8e-0000-4000-8000-000000000001"
+	memberID   = "7b0d0c8e-0000-4000-8000-000000000002"
+	strangerID = "7b0d0c8e-0000-4000-8000-000000000003"
+
+	publicTripID  = "7b0d0c8e-0000-4000-8000-0000000000a1"
+	privateTripID = "7b0d0c8e-0000-4000-8000-0000000000a2"
+	deletedTripID = "7b0d0c8e-0000-4000-8000-0000000000a3"
+	publicPlaceID = "7b0d0c8e-0000-4000-8000-0000000000b1"
+	secretPlaceID = "7b0d0c8e-0000-4000-8000-0000000000b2"
+)
+
+type fakeTripRepository struct {
+	trips   []*trips.Trip
+	calls   [][]string
+	filters []trips.TripFilters
+}
+
+func (r *fakeTripRepository) GetByIDs(ctx context.Context, ids []string) ([]*trips.Trip, error) {
+	r.calls = append(r.calls, ids)
+	return find(r.trips, ids, func(t *trips.Trip) string { return t.ID }), nil
+}
+
+// List returns a page of every trip, whatever the filters, and records them
+func (r *fakeTripRepository) List(ctx context.Context, filters trips.TripFilters) ([]*trips.Trip, error) {
+	r.filters = append(r.filters, filters)
+	return pageOf(r.trips, filters.Limit, filters.Offset), nil
+}
+
+func (r *fakeTripRepository) Count(ctx context.Context, filters trips.TripFilters) (int64, error) {
+	return int64(len(r.trips)), nil
+}
+
+type fakePlaceRepository struct {
+	places   []*places.Place
+	calls    [][]string
+	searches []placeSearch
+}
+
+type placeSearch struct {
+	query   string
+	spatial *nlp.SpatialSearchContext
+	filters places.SearchFilters
+}
+
+func (r *fakePlaceRepository) GetByIDs(ctx context.Context, ids []string) ([]*places.Place, error) {
+	r.calls = append(r.calls, ids)
+	return find(r.places, ids, func(p *places.Place) string { return p.ID }), nil
+}
+
+// SearchWithSpatialContext returns a page of every place, whatever the
+// search, and records it
+func (r *fakePlaceRepository) SearchWithSpatialContext(ctx context.Context, query string, spatial *nlp.SpatialSearchContext, filters places.SearchFilters) (*places.SearchResult, error) {
+	r.searches = append(r.searches, placeSearch{query: query, spatial: spatial, filters: filters})
+	return &places.SearchResult{Places: pageOf(r.places, filters.Limit, filters.Offset), Total: int64(len(r.places))}, nil
+}
+
+func pageOf[T any](records []T, limit, offset int) []T {
+	if offset >= len(records) {
+		return []T{}
+	}
+	records = records[offset:]
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records
+}
+
+func find[T any](records []T, ids []string, id func(T) string) []T {
+	found := []T{}
+	for _, record := range records {
+		for _, wanted := range ids {
+			if id(record) == wanted {
+				found = append(found, record)
+			}
+		}
+	}
+	return found
+}
+
+func newHydrationService() (*Service, *fakeTripRepository, *fakePlaceRepository) {
+	distance := 12.5
+	tripRepo := &fakeTripRepository{trips: []*trips.Trip{
+		{ID: publicTripID, Title: "Smith Rock Loop", OwnerID: ownerID, Privacy: "public", CoverImage: "https://cdn.example/smith.jpg", DistanceKm: &distance, ViewCount: 40},
+		{ID: privateTripID, Title: "Secret Canyon", OwnerID: ownerID, Privacy: "private",
+			Collaborators: []trips.Collaborator{{TripID: privateTripID, UserID: memberID}}},
+	}}
+	placeRepo := &fakePlaceRepository{places: []*places.Place{
+		{ID: publicPlaceID, Name: "Tumalo Falls", CreatedBy: ownerID, Privacy: "public", RatingCount: 3,
+			Media: []places.Media{{URL: "https://cdn.example/falls.jpg", ThumbnailURL: "https://cdn.example/falls-thumb.jpg"}}},
+		{ID: secretPlaceID, Name: "Hidden Spring", CreatedBy: ownerID, Privacy: "private"},
+	}}
+
+	service := NewService(nil, nil, nil)
+	service.SetRepositories(placeRepo, tripRepo)
+	return service, tripRepo, placeRepo
+}
+
+func hits() []elasticsearch.SearchResult {
+	return []elasticsearch.SearchResult{
+		{ID: publicTripID, Type: "activity", Score: 9},
+		{ID: secretPlaceID, Type: "place", Score: 8},
+		{ID: privateTripID, Type: "activity", Score: 7},
+		{ID: deletedTripID, Type: "activity", Score: 6},
+		{ID: publicPlaceID, Type: "place", Score: 5},
+		{ID: "not-a-uuid", Type: "place", Score: 4},
+	}
+}
+
+func resultIDs(results []Result) []string {
+	ids := []string{}
+	for _, result := range results {
+		ids = append(ids, result.ID())
+	}
+	return ids
+}
+
+func TestService_Hydrate(t *testing.T) {
+	service, tripRepo, placeRepo := newHydrationService()
+
+	results, err := service.hydrate(context.Background(), hits(), strangerID)
+	require.NoError(t, err)
+
+	// Deleted and private records are dropped, the rest keep their order
+	assert.Equal(t, []string{publicTripID, publicPlaceID}, resultIDs(results))
+
+	// One lookup per type, without the malformed ID
+	assert.Equal(t, [][]string{{publicTripID, privateTripID, deletedTripID}}, tripRepo.calls)
+	assert.Equal(t, [][]string{{secretPlaceID, publicPlaceID}}, placeRepo.calls)
+
+	trip := results[0]
+	assert.Equal(t, ResultTypeTrip, trip.Type)
+	assert.Equal(t, 9.0, trip.Score)
+	assert.Equal(t, "https://cdn.example/smith.jpg", trip.Trip.CoverImage)
+	assert.Equal(t, 12.5, *trip.Trip.Stats.DistanceKm)
+	assert.Equal(t, 40, trip.Trip.Stats.ViewCount)
+	assert.Equal(t, []string{}, trip.Trip.Tags)
+
+	place := results[1]
+	assert.Equal(t, ResultTypePlace, place.Type)
+	assert.Equal(t, "https://cdn.example/falls-thumb.jpg", place.Place.CoverImage)
+	assert.Equal(t, 3, place.Place.Stats.RatingCount)
+}
+
+func TestService_HydrateVisibility(t *testing.T) {
+	service, _, _ := newHydrationService()
+
+	tests := []struct {
+		name   string
+		userID string
+		want   []string
+	}{
+		{"anonymous", "", []string{publicTripID, publicPlaceID}},
+		{"collaborator", memberID, []string{publicTripID, privateTripID, publicPlaceID}},
+		{"owner", ownerID, []string{publicTripID, secretPlaceID, privateTripID, publicPlaceID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := service.hydrate(context.Background(), hits(), tt.userID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, resultIDs(results))
+		})
+	}
+}
+
+func TestOpenOnly(t *testing.T) {
+	open, closed := true, false
+	results := []Result{
+		{Type: ResultTypePlace, Place: &PlaceSummary{ID: "open", OpenNow: &open}},
+		{Type: ResultTypeTrip, Trip: &TripSummary{ID: "trip"}},
+		{Type: ResultTypePlace, Place: &PlaceSummary{ID: "closed", OpenNow: &closed}},
+		{Type: ResultTypePlace, Place: &PlaceSummary{ID: "no hours"}},
+	}
+
+	// Results without opening hours cannot be open
+	assert.Equal(t, []string{"open"}, resultIDs(openOnly(results)))
+}
+
+func TestService_HydrateWithoutRepositories(t *testing.T) {
+	service := NewService(nil, nil, nil)
+
+	// Nothing can be checked, so nothing is returned
+	results, err := service.hydrate(context.Background(), hits(), ownerID)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+type fakeCollectionRepository struct {
+	collections []collections.Collection
+}
+
+func (r *fakeCollectionRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]collections.Collection, error) {
+	found := []collections.Collection{}
+	for _, collection := range r.collections {
+		for _, id := range ids {
+			if collection.ID == id {
+				found = append(found, collection)
+			}
+		}
+	}
+	return found, nil
+}
+
+func TestService_HydrateCollections(t *testing.T) {
+	publicID, unlistedID, privateID := uuid.New(), uuid.New(), uuid.New()
+	description := "Waterfalls within an hour of Bend"
+
+	service, _, _ := newHydrationService()
+	service.SetCollectionRepository(&fakeCollectionRepository{collections: []collections.Collection{
+		{ID: publicID, Name: "Falls", Description: &description, UserID: uuid.MustParse(ownerID), Privacy: collections.PrivacyPublic},
+		{ID: unlistedID, Name: "Shared with friends", UserID: uuid.MustParse(ownerID), Privacy: collections.PrivacyUnlisted},
+		{ID: privateID, Name: "Mine", UserID: uuid.MustParse(ownerID), Privacy: collections.PrivacyPrivate},
+	}})
+
+	results, err := service.hydrate(context.Background(), []elasticsearch.SearchResult{
+		{ID: privateID.String(), Type: "collection", Score: 3},
+		{ID: publicID.String(), Type: "collection", Score: 2},
+		{ID: unlistedID.String(), Type: "collection", Score: 1},
+	}, ownerID)
+	require.NoError(t, err)
+
+	// Only public collections are listed, even to their owner
+	require.Equal(t, []string{publicID.String()}, resultIDs(results))
+	assert.Equal(t, ResultTypeCollection, results[0].Type)
+	assert.Equal(t, description, results[0].Collection.Description)
+	assert.Equal(t, ownerID, results[0].Collection.OwnerID)
+}
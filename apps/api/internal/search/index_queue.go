@@ -0,0 +1,118 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/elasticsearch"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+)
+
+const (
+	// JobIndex indexes a document for search
+	JobIndex = "search.index"
+
+	// JobUnindex removes a document from the search index
+	JobUnindex = "search.unindex"
+)
+
+// ErrIndexUnavailable is returned while Elasticsearch cannot be reached, so
+// the job is tried again later
+var ErrIndexUnavailable = errors.New("search index unavailable")
+
+// documentIndex is the part of the Elasticsearch client queued changes are
+// applied to
+type documentIndex interface {
+	IsAvailable() bool
+	IndexVersioned(ctx context.Context, index, documentID string, document map[string]interface{}, version int64) error
+	DeleteVersioned(ctx context.Context, index, documentID string, version int64) error
+}
+
+type indexPayload struct {
+	DocType  string                 `json:"doc_type"`
+	ID       string                 `json:"id"`
+	Document map[string]interface{} `json:"document,omitempty"`
+	Version  int64                  `json:"version"`
+}
+
+// IndexQueue keeps the search index in step with trips, places and
+// collections in the background. Changes are queued as jobs, so saving them
+// never waits on Elasticsearch, and they are retried while it is down.
+// Each change is versioned by when it was queued, so a retried change never
+// overwrites a newer one.
+type IndexQueue struct {
+	index documentIndex // nil when Elasticsearch is not configured
+	queue jobs.Queue
+	now   func() time.Time
+}
+
+// NewIndexQueue creates an index queue and registers its job handlers
+func NewIndexQueue(esClient *elasticsearch.Client, queue jobs.Queue) *IndexQueue {
+	q := &IndexQueue{queue: queue, now: time.Now}
+	if esClient != nil {
+		q.index = esClient
+	}
+
+	queue.Register(JobIndex, q.run)
+	queue.Register(JobUnindex, q.run)
+	return q
+}
+
+// IndexActivity queues a trip to be indexed
+func (q *IndexQueue) IndexActivity(ctx context.Context, activityID string, activity map[string]interface{}) error {
+	return q.enqueue(ctx, JobIndex, "activity", activityID, activity)
+}
+
+// IndexPlace queues a place to be indexed
+func (q *IndexQueue) IndexPlace(ctx context.Context, placeID string, place map[string]interface{}) error {
+	return q.enqueue(ctx, JobIndex, "place", placeID, place)
+}
+
+// IndexCollection queues a public collection to be indexed
+func (q *IndexQueue) IndexCollection(ctx context.Context, collectionID string, collection map[string]interface{}) error {
+	return q.enqueue(ctx, JobIndex, "collection", collectionID, collection)
+}
+
+// DeleteFromIndex queues a document to be removed from the index
+func (q *IndexQueue) DeleteFromIndex(ctx context.Context, docType, documentID string) error {
+	return q.enqueue(ctx, JobUnindex, docType, documentID, nil)
+}
+
+// HandleTripPublished queues a trip to be indexed once it becomes public
+func (q *IndexQueue) HandleTripPublished(ctx context.Context, event events.Event) error {
+	return q.IndexActivity(ctx, event.EntityID, event.Data)
+}
+
+func (q *IndexQueue) enqueue(ctx context.Context, jobType, docType, id string, document map[string]interface{}) error {
+	_, err := q.queue.Enqueue(ctx, jobType, indexPayload{
+		DocType:  docType,
+		ID:       id,
+		Document: document,
+		Version:  q.now().UnixNano(),
+	})
+	return err
+}
+
+// run applies a queued change to the index
+func (q *IndexQueue) run(ctx context.Context, job *jobs.Job) error {
+	var payload indexPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	if q.index == nil {
+		log.Printf("Elasticsearch not configured, skipping %s of %s %s", job.Type, payload.DocType, payload.ID)
+		return nil
+	}
+	if !q.index.IsAvailable() {
+		return ErrIndexUnavailable
+	}
+
+	if job.Type == JobUnindex {
+		return q.index.DeleteVersioned(ctx, indexName(payload.DocType), payload.ID, payload.Version)
+	}
+	return q.index.IndexVersioned(ctx, indexName(payload.DocType), payload.ID, payload.Document, payload.Version)
+}
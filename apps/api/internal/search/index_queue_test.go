@@ -0,0 +1,86 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeIndex struct {
+	available bool
+	versions  map[string]int64
+	documents map[string]map[string]interface{}
+}
+
+func newFakeIndex() *fakeIndex {
+	return &fakeIndex{available: true, versions: map[string]int64{}, documents: map[string]map[string]interface{}{}}
+}
+
+func (f *fakeIndex) IsAvailable() bool { return f.available }
+
+func (f *fakeIndex) IndexVersioned(ctx context.Context, index, documentID string, document map[string]interface{}, version int64) error {
+	key := index + "/" + documentID
+	if version >= f.versions[key] {
+		f.versions[key] = version
+		f.documents[key] = document
+	}
+	return nil
+}
+
+func (f *fakeIndex) DeleteVersioned(ctx context.Context, index, documentID string, version int64) error {
+	key := index + "/" + documentID
+	if version >= f.versions[key] {
+		f.versions[key] = version
+		delete(f.documents, key)
+	}
+	return nil
+}
+
+func indexJob(t *testing.T, jobType string, payload indexPayload) *jobs.Job {
+	data, err := json.Marshal(payload)
+	require.NoError(t, err)
+	return &jobs.Job{Type: jobType, Payload: data}
+}
+
+func TestIndexQueue_Run(t *testing.T) {
+	index := newFakeIndex()
+	q := NewIndexQueue(nil, jobs.NewLocalQueue())
+	q.index = index
+	ctx := context.Background()
+
+	newer := indexJob(t, JobIndex, indexPayload{DocType: "place", ID: "place-1", Document: map[string]interface{}{"name": "Newer"}, Version: 2})
+	older := indexJob(t, JobIndex, indexPayload{DocType: "place", ID: "place-1", Document: map[string]interface{}{"name": "Older"}, Version: 1})
+	require.NoError(t, q.run(ctx, newer))
+	require.NoError(t, q.run(ctx, older))
+	assert.Equal(t, "Newer", index.documents["places/place-1"]["name"])
+
+	// A delete queued before the newest change does not remove it
+	require.NoError(t, q.run(ctx, indexJob(t, JobUnindex, indexPayload{DocType: "place", ID: "place-1", Version: 1})))
+	assert.Contains(t, index.documents, "places/place-1")
+	require.NoError(t, q.run(ctx, indexJob(t, JobUnindex, indexPayload{DocType: "place", ID: "place-1", Version: 3})))
+	assert.NotContains(t, index.documents, "places/place-1")
+
+	require.NoError(t, q.run(ctx, indexJob(t, JobIndex, indexPayload{DocType: "activity", ID: "trip-1", Document: map[string]interface{}{}, Version: 1})))
+	assert.Contains(t, index.documents, "activities/trip-1")
+}
+
+func TestIndexQueue_RetriesWhileUnavailable(t *testing.T) {
+	index := newFakeIndex()
+	index.available = false
+	q := NewIndexQueue(nil, jobs.NewLocalQueue())
+	q.index = index
+
+	job := indexJob(t, JobIndex, indexPayload{DocType: "collection", ID: "c-1", Document: map[string]interface{}{}, Version: 1})
+	assert.ErrorIs(t, q.run(context.Background(), job), ErrIndexUnavailable)
+
+	index.available = true
+	require.NoError(t, q.run(context.Background(), job))
+	assert.Contains(t, index.documents, "collections/c-1")
+
+	// Without Elasticsearch there is nothing to retry for
+	assert.NoError(t, NewIndexQueue(nil, jobs.NewLocalQueue()).run(context.Background(), job))
+}
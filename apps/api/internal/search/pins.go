@@ -0,0 +1,82 @@
+package search
+
+import (
+	"context"
+	"log"
+
+	"github.com/Oferzz/newMap/apps/api/internal/curation"
+	"github.com/Oferzz/newMap/apps/api/internal/elasticsearch"
+	"github.com/Oferzz/newMap/apps/api/internal/nlp"
+)
+
+// Curator supplies the pins that apply to a search
+type Curator interface {
+	Match(ctx context.Context, query string, lat, lng *float64) ([]curation.Pin, error)
+}
+
+// SetCurator sets where pinned results come from. Without one, results are
+// never pinned.
+func (s *Service) SetCurator(curator Curator) {
+	s.curator = curator
+}
+
+// applyPins puts the results pinned for the search above the rest, labelled
+// as curated or sponsored. Pinned records go through the same visibility
+// checks as any other result, and are not repeated further down. It returns
+// the results and how many were added.
+func (s *Service) applyPins(ctx context.Context, req *SearchRequest, parsedQuery *nlp.ParsedQuery, results []Result) ([]Result, int) {
+	if s.curator == nil {
+		return results, 0
+	}
+
+	var lat, lng *float64
+	if location := parsedQuery.Location; location != nil && (location.Latitude != 0 || location.Longitude != 0) {
+		lat, lng = &location.Latitude, &location.Longitude
+	}
+
+	pins, err := s.curator.Match(ctx, req.Query, lat, lng)
+	if err != nil {
+		// Pins are an extra, the search itself still succeeded
+		log.Printf("Failed to match search pins: %v", err)
+		return results, 0
+	}
+	if len(pins) == 0 {
+		return results, 0
+	}
+
+	hits := make([]elasticsearch.SearchResult, 0, len(pins))
+	labels := make(map[string]string, len(pins))
+	for _, pin := range pins {
+		if _, ok := labels[pin.EntityID]; ok {
+			continue
+		}
+		docType := "place"
+		if pin.EntityType == ResultTypeTrip {
+			docType = "activity"
+		}
+		hits = append(hits, elasticsearch.SearchResult{ID: pin.EntityID, Type: docType})
+		labels[pin.EntityID] = pin.Label
+	}
+
+	pinned, err := s.hydrate(ctx, hits, req.UserID)
+	if err != nil {
+		log.Printf("Failed to load pinned search results: %v", err)
+		return results, 0
+	}
+	for i := range pinned {
+		pinned[i].Curation = labels[pinned[i].ID()]
+	}
+
+	merged := make([]Result, 0, len(pinned)+len(results))
+	merged = append(merged, pinned...)
+	added := len(pinned)
+	for _, result := range results {
+		if _, ok := labels[result.ID()]; ok {
+			added--
+			continue
+		}
+		merged = append(merged, result)
+	}
+
+	return merged, added
+}
@@ -0,0 +1,61 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/curation"
+	"github.com/Oferzz/newMap/apps/api/internal/nlp"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCurator struct {
+	pins     []curation.Pin
+	err      error
+	lat, lng *float64
+}
+
+func (c *fakeCurator) Match(ctx context.Context, query string, lat, lng *float64) ([]curation.Pin, error) {
+	c.lat, c.lng = lat, lng
+	return c.pins, c.err
+}
+
+func TestService_ApplyPins(t *testing.T) {
+	service, _, _ := newHydrationService()
+	curator := &fakeCurator{pins: []curation.Pin{
+		{EntityType: "place", EntityID: publicPlaceID, Label: curation.LabelSponsored},
+		{EntityType: "trip", EntityID: privateTripID, Label: curation.LabelCurated},
+		{EntityType: "trip", EntityID: deletedTripID, Label: curation.LabelCurated},
+	}}
+	service.SetCurator(curator)
+
+	organic, err := service.hydrate(context.Background(), hits(), strangerID)
+	assert.NoError(t, err)
+
+	parsed := &nlp.ParsedQuery{Location: &nlp.LocationFilter{Name: "bend", Latitude: 44.0582, Longitude: -121.3153}}
+	results, added := service.applyPins(context.Background(), &SearchRequest{Query: "waterfalls", UserID: strangerID}, parsed, organic)
+
+	// The private and deleted trips are not shown, the place moves to the top
+	assert.Equal(t, []string{publicPlaceID, publicTripID}, resultIDs(results))
+	assert.Equal(t, curation.LabelSponsored, results[0].Curation)
+	assert.Empty(t, results[1].Curation)
+	assert.Equal(t, 0, added)
+
+	// Pins are matched against the geocoded location
+	assert.Equal(t, 44.0582, *curator.lat)
+	assert.Equal(t, -121.3153, *curator.lng)
+}
+
+func TestService_ApplyPinsFailure(t *testing.T) {
+	service, _, _ := newHydrationService()
+	service.SetCurator(&fakeCurator{err: errors.New("connection refused")})
+
+	organic, err := service.hydrate(context.Background(), hits(), strangerID)
+	assert.NoError(t, err)
+
+	// A failed lookup leaves the results as they were
+	results, added := service.applyPins(context.Background(), &SearchRequest{Query: "waterfalls"}, &nlp.ParsedQuery{}, organic)
+	assert.Equal(t, organic, results)
+	assert.Equal(t, 0, added)
+}
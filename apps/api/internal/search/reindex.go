@@ -0,0 +1,291 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/elasticsearch"
+	"github.com/Oferzz/newMap/apps/api/pkg/pagination"
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultReindexBatchSize is how many rows a reindex reads and indexes at a
+// time unless told otherwise
+const DefaultReindexBatchSize = 500
+
+// ReindexTypes are the document types a reindex covers, in the order they
+// are indexed
+var ReindexTypes = []string{"activity", "place"}
+
+// bulkIndex is the part of the Elasticsearch client a reindex writes to
+type bulkIndex interface {
+	IsAvailable() bool
+	BulkIndexVersioned(ctx context.Context, index string, documents []elasticsearch.BulkDocument, version int64) error
+}
+
+// PlaceLister is the part of the places repository a reindex reads
+type PlaceLister interface {
+	Search(ctx context.Context, query string, filters places.SearchFilters) (*places.SearchResult, error)
+}
+
+// Checkpoint is how far a reindex of one document type got
+type Checkpoint struct {
+	DocType   string    `db:"doc_type"`
+	After     string    `db:"after_cursor"` // Encoded cursor of the last row read
+	Scanned   int64     `db:"scanned"`
+	Indexed   int64     `db:"indexed"`
+	StartedAt time.Time `db:"started_at"`
+}
+
+// CheckpointStore keeps reindex checkpoints between runs
+type CheckpointStore interface {
+	Load(ctx context.Context, docType string) (*Checkpoint, error) // nil when there is none
+	Save(ctx context.Context, checkpoint *Checkpoint) error
+	Clear(ctx context.Context, docType string) error
+}
+
+// Reindexer rebuilds the search index from the database: public trips and
+// searchable places are read in batches, newest first, and indexed in bulk.
+// A checkpoint is saved after every batch, so a run that stops part way is
+// carried on from there by the next. Documents are versioned by when their
+// run started, so changes indexed in the meantime are never overwritten.
+type Reindexer struct {
+	index       bulkIndex
+	tripRepo    TripRepository
+	placeRepo   PlaceLister
+	checkpoints CheckpointStore
+	batchSize   int
+	now         func() time.Time
+	logf        func(format string, args ...interface{})
+}
+
+// NewReindexer creates a reindexer
+func NewReindexer(esClient *elasticsearch.Client, tripRepo TripRepository, placeRepo PlaceLister, checkpoints CheckpointStore) *Reindexer {
+	return &Reindexer{
+		index:       esClient,
+		tripRepo:    tripRepo,
+		placeRepo:   placeRepo,
+		checkpoints: checkpoints,
+		batchSize:   DefaultReindexBatchSize,
+		now:         time.Now,
+		logf:        log.Printf,
+	}
+}
+
+// SetBatchSize sets how many rows are read and indexed at a time
+func (r *Reindexer) SetBatchSize(size int) {
+	if size > 0 {
+		r.batchSize = size
+	}
+}
+
+// Run reindexes the document types in turn. With restart, checkpoints of
+// earlier runs are dropped and every type is indexed from the start.
+func (r *Reindexer) Run(ctx context.Context, docTypes []string, restart bool) error {
+	for _, docType := range docTypes {
+		if docType != "activity" && docType != "place" {
+			return fmt.Errorf("cannot reindex %q, only %v", docType, ReindexTypes)
+		}
+	}
+	if !r.index.IsAvailable() {
+		return ErrIndexUnavailable
+	}
+
+	for _, docType := range docTypes {
+		if restart {
+			if err := r.checkpoints.Clear(ctx, docType); err != nil {
+				return fmt.Errorf("failed to clear %s checkpoint: %w", docType, err)
+			}
+		}
+		if err := r.reindex(ctx, docType); err != nil {
+			return fmt.Errorf("failed to reindex %s: %w", docType, err)
+		}
+	}
+	return nil
+}
+
+// reindexPage is one batch of rows read for a reindex
+type reindexPage struct {
+	documents []elasticsearch.BulkDocument
+	scanned   int                // Rows read, including those left out of the index
+	last      *pagination.Cursor // nil when no rows were read
+}
+
+// reindex indexes every document of a type after its checkpoint, reporting
+// progress after each batch
+func (r *Reindexer) reindex(ctx context.Context, docType string) error {
+	checkpoint, err := r.checkpoints.Load(ctx, docType)
+	if err != nil {
+		return fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+
+	var after *pagination.Cursor
+	if checkpoint == nil {
+		checkpoint = &Checkpoint{DocType: docType, StartedAt: r.now()}
+	} else {
+		after, err = pagination.Decode(checkpoint.After)
+		if err != nil {
+			return fmt.Errorf("invalid checkpoint: %w", err)
+		}
+	}
+
+	total, err := r.count(ctx, docType)
+	if err != nil {
+		return err
+	}
+	if after != nil {
+		r.logf("Resuming %s reindex started %s: %d of %d read, %d indexed", docType, checkpoint.StartedAt.Format(time.RFC3339), checkpoint.Scanned, total, checkpoint.Indexed)
+	} else {
+		r.logf("Reindexing %s: %d to read", docType, total)
+	}
+
+	version := checkpoint.StartedAt.UnixNano()
+	for {
+		page, err := r.read(ctx, docType, after)
+		if err != nil {
+			return err
+		}
+		if page.scanned == 0 {
+			break
+		}
+
+		if err := r.index.BulkIndexVersioned(ctx, indexName(docType), page.documents, version); err != nil {
+			return err
+		}
+
+		after = page.last
+		checkpoint.After = after.Encode()
+		checkpoint.Scanned += int64(page.scanned)
+		checkpoint.Indexed += int64(len(page.documents))
+		if err := r.checkpoints.Save(ctx, checkpoint); err != nil {
+			return fmt.Errorf("failed to save checkpoint: %w", err)
+		}
+		r.logf("Reindexing %s: %d of %d read, %d indexed", docType, checkpoint.Scanned, total, checkpoint.Indexed)
+
+		if page.scanned < r.batchSize {
+			break
+		}
+	}
+
+	r.logf("Reindexed %s: %d indexed", docType, checkpoint.Indexed)
+	return r.checkpoints.Clear(ctx, docType)
+}
+
+// count returns how many rows of a type a reindex reads in all. Rows added
+// while it runs are indexed as they are added rather than by the reindex.
+func (r *Reindexer) count(ctx context.Context, docType string) (int64, error) {
+	if docType == "place" {
+		result, err := r.placeRepo.Search(ctx, "", places.SearchFilters{Limit: 1})
+		if err != nil {
+			return 0, fmt.Errorf("failed to count places: %w", err)
+		}
+		return result.Total, nil
+	}
+
+	total, err := r.tripRepo.Count(ctx, trips.TripFilters{Privacy: trips.PrivacyPublic})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count trips: %w", err)
+	}
+	return total, nil
+}
+
+// read reads the batch of rows of a type after the cursor
+func (r *Reindexer) read(ctx context.Context, docType string, after *pagination.Cursor) (*reindexPage, error) {
+	if docType == "place" {
+		return r.readPlaces(ctx, after)
+	}
+	return r.readTrips(ctx, after)
+}
+
+// readTrips reads a batch of public trips with the waypoints their
+// documents are made from
+func (r *Reindexer) readTrips(ctx context.Context, after *pagination.Cursor) (*reindexPage, error) {
+	found, err := r.tripRepo.List(ctx, trips.TripFilters{
+		Privacy:   trips.PrivacyPublic,
+		Limit:     r.batchSize,
+		After:     after,
+		Relations: &trips.Relations{Waypoints: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trips: %w", err)
+	}
+
+	page := &reindexPage{scanned: len(found)}
+	for _, trip := range found {
+		page.documents = append(page.documents, elasticsearch.BulkDocument{ID: trip.ID, Document: trips.SearchDocument(trip)})
+		page.last = pagination.New(trip.CreatedAt, trip.ID)
+	}
+	return page, nil
+}
+
+// readPlaces reads a batch of active places, of which only those that are
+// searchable are indexed
+func (r *Reindexer) readPlaces(ctx context.Context, after *pagination.Cursor) (*reindexPage, error) {
+	result, err := r.placeRepo.Search(ctx, "", places.SearchFilters{Limit: r.batchSize, After: after})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list places: %w", err)
+	}
+
+	at := r.now()
+	page := &reindexPage{scanned: len(result.Places)}
+	for _, place := range result.Places {
+		if place.Searchable() {
+			page.documents = append(page.documents, elasticsearch.BulkDocument{ID: place.ID, Document: places.SearchDocument(place, at)})
+		}
+		page.last = pagination.New(place.CreatedAt, place.ID)
+	}
+	return page, nil
+}
+
+// PostgresCheckpoints keeps reindex checkpoints in the database
+type PostgresCheckpoints struct {
+	db *sqlx.DB
+}
+
+// NewPostgresCheckpoints creates a checkpoint store
+func NewPostgresCheckpoints(db *sqlx.DB) *PostgresCheckpoints {
+	return &PostgresCheckpoints{db: db}
+}
+
+// Load returns the checkpoint of a document type, or nil when it has none
+func (s *PostgresCheckpoints) Load(ctx context.Context, docType string) (*Checkpoint, error) {
+	var checkpoint Checkpoint
+	err := s.db.GetContext(ctx, &checkpoint, `
+		SELECT doc_type, after_cursor, scanned, indexed, started_at
+		FROM search_reindex_checkpoints
+		WHERE doc_type = $1`, docType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// Save stores the checkpoint of a document type
+func (s *PostgresCheckpoints) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO search_reindex_checkpoints (doc_type, after_cursor, scanned, indexed, started_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+		ON CONFLICT (doc_type) DO UPDATE SET
+			after_cursor = EXCLUDED.after_cursor,
+			scanned = EXCLUDED.scanned,
+			indexed = EXCLUDED.indexed,
+			started_at = EXCLUDED.started_at,
+			updated_at = CURRENT_TIMESTAMP`,
+		checkpoint.DocType, checkpoint.After, checkpoint.Scanned, checkpoint.Indexed, checkpoint.StartedAt)
+	return err
+}
+
+// Clear drops the checkpoint of a document type
+func (s *PostgresCheckpoints) Clear(ctx context.Context, docType string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM search_reindex_checkpoints WHERE doc_type = $1`, docType)
+	return err
+}
@@ -0,0 +1,183 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/elasticsearch"
+	"github.com/Oferzz/newMap/apps/api/pkg/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bulkCall struct {
+	index   string
+	ids     []string
+	version int64
+}
+
+type fakeBulkIndex struct {
+	calls  []bulkCall
+	failAt int // Fails the call with this number, counting from 1
+}
+
+func (i *fakeBulkIndex) IsAvailable() bool { return true }
+
+func (i *fakeBulkIndex) BulkIndexVersioned(ctx context.Context, index string, documents []elasticsearch.BulkDocument, version int64) error {
+	if len(i.calls)+1 == i.failAt {
+		i.failAt = 0
+		return errors.New("bulk indexing failed")
+	}
+	call := bulkCall{index: index, version: version}
+	for _, doc := range documents {
+		call.ids = append(call.ids, doc.ID)
+	}
+	i.calls = append(i.calls, call)
+	return nil
+}
+
+// keysetTrips pages trips newest first after a cursor
+type keysetTrips struct {
+	TripRepository
+	trips []*trips.Trip
+}
+
+func (r *keysetTrips) List(ctx context.Context, filters trips.TripFilters) ([]*trips.Trip, error) {
+	found := []*trips.Trip{}
+	for _, trip := range r.trips {
+		if filters.After == nil || filters.After.Follows(trip.CreatedAt, trip.ID, true) {
+			found = append(found, trip)
+		}
+	}
+	return pageOf(found, filters.Limit, 0), nil
+}
+
+func (r *keysetTrips) Count(ctx context.Context, filters trips.TripFilters) (int64, error) {
+	return int64(len(r.trips)), nil
+}
+
+// keysetPlaces pages places newest first after a cursor
+type keysetPlaces struct {
+	places []*places.Place
+}
+
+func (r *keysetPlaces) Search(ctx context.Context, query string, filters places.SearchFilters) (*places.SearchResult, error) {
+	found := []*places.Place{}
+	for _, place := range r.places {
+		if filters.After == nil || filters.After.Follows(place.CreatedAt, place.ID, true) {
+			found = append(found, place)
+		}
+	}
+	return &places.SearchResult{Places: pageOf(found, filters.Limit, 0), Total: int64(len(r.places))}, nil
+}
+
+type memoryCheckpoints map[string]Checkpoint
+
+func (m memoryCheckpoints) Load(ctx context.Context, docType string) (*Checkpoint, error) {
+	checkpoint, ok := m[docType]
+	if !ok {
+		return nil, nil
+	}
+	return &checkpoint, nil
+}
+
+func (m memoryCheckpoints) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	m[checkpoint.DocType] = *checkpoint
+	return nil
+}
+
+func (m memoryCheckpoints) Clear(ctx context.Context, docType string) error {
+	delete(m, docType)
+	return nil
+}
+
+var reindexStart = time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC)
+
+func newTestReindexer() (*Reindexer, *fakeBulkIndex, memoryCheckpoints, *[]string) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tripRepo := &keysetTrips{}
+	for i := 5; i >= 1; i-- {
+		tripRepo.trips = append(tripRepo.trips, &trips.Trip{
+			ID:        fmt.Sprintf("trip-%d", i),
+			Privacy:   trips.PrivacyPublic,
+			CreatedAt: created.AddDate(0, 0, i),
+		})
+	}
+	placeRepo := &keysetPlaces{places: []*places.Place{
+		{ID: "place-3", Status: "active", Privacy: "public", CreatedAt: created.AddDate(0, 0, 3)},
+		{ID: "place-2", Status: "active", Privacy: "private", CreatedAt: created.AddDate(0, 0, 2)},
+		{ID: "place-1", Status: "active", Privacy: "public", CreatedAt: created.AddDate(0, 0, 1)},
+	}}
+
+	index := &fakeBulkIndex{}
+	checkpoints := memoryCheckpoints{}
+	var logs []string
+
+	r := NewReindexer(nil, tripRepo, placeRepo, checkpoints)
+	r.index = index
+	r.SetBatchSize(2)
+	r.now = func() time.Time { return reindexStart }
+	r.logf = func(format string, args ...interface{}) { logs = append(logs, fmt.Sprintf(format, args...)) }
+	return r, index, checkpoints, &logs
+}
+
+func TestReindexer_Run(t *testing.T) {
+	r, index, checkpoints, logs := newTestReindexer()
+
+	require.NoError(t, r.Run(context.Background(), ReindexTypes, false))
+
+	// Newest first in batches, private places read but left out, all at the
+	// version of the run's start
+	version := reindexStart.UnixNano()
+	assert.Equal(t, []bulkCall{
+		{index: "activities", ids: []string{"trip-5", "trip-4"}, version: version},
+		{index: "activities", ids: []string{"trip-3", "trip-2"}, version: version},
+		{index: "activities", ids: []string{"trip-1"}, version: version},
+		{index: "places", ids: []string{"place-3"}, version: version},
+		{index: "places", ids: []string{"place-1"}, version: version},
+	}, index.calls)
+
+	assert.Contains(t, *logs, "Reindexing activity: 4 of 5 read, 4 indexed")
+	assert.Contains(t, *logs, "Reindexed place: 2 indexed")
+
+	// Finished runs leave nothing to resume
+	assert.Empty(t, checkpoints)
+}
+
+func TestReindexer_Resume(t *testing.T) {
+	r, index, checkpoints, logs := newTestReindexer()
+	index.failAt = 2
+
+	err := r.Run(context.Background(), []string{"activity"}, false)
+	require.Error(t, err)
+
+	// The first batch is kept
+	checkpoint := checkpoints["activity"]
+	assert.Equal(t, int64(2), checkpoint.Scanned)
+	assert.Equal(t, reindexStart, checkpoint.StartedAt)
+	assert.Equal(t, pagination.New(time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), "trip-4").Encode(), checkpoint.After)
+
+	// The next run carries on after it, at the first run's version
+	r.now = func() time.Time { return reindexStart.Add(time.Hour) }
+	require.NoError(t, r.Run(context.Background(), []string{"activity"}, false))
+	require.Len(t, index.calls, 3)
+	assert.Equal(t, []string{"trip-3", "trip-2"}, index.calls[1].ids)
+	assert.Equal(t, reindexStart.UnixNano(), index.calls[1].version)
+	assert.Contains(t, *logs, "Resuming activity reindex started 2026-05-04T12:00:00Z: 2 of 5 read, 2 indexed")
+	assert.Empty(t, checkpoints)
+}
+
+func TestReindexer_Restart(t *testing.T) {
+	r, index, checkpoints, _ := newTestReindexer()
+	checkpoints["place"] = Checkpoint{DocType: "place", After: pagination.New(time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC), "place-3").Encode(), StartedAt: reindexStart}
+
+	require.NoError(t, r.Run(context.Background(), []string{"place"}, true))
+	assert.Equal(t, []string{"place-3"}, index.calls[0].ids)
+
+	assert.Error(t, r.Run(context.Background(), []string{"collection"}, false))
+}
@@ -2,21 +2,55 @@ package search
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
+	"github.com/Oferzz/newMap/apps/api/internal/domain/collections"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
 	"github.com/Oferzz/newMap/apps/api/internal/elasticsearch"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/internal/geocode"
 	"github.com/Oferzz/newMap/apps/api/internal/nlp"
+	"github.com/google/uuid"
 )
 
+// regionRadiusKm is the radius searched around a named region the geocoder
+// returned no extent for
+const regionRadiusKm = 25.0
+
 // Service handles unified search across activities and places
 type Service struct {
 	esClient  *elasticsearch.Client
 	nlpParser *nlp.Parser
-	// Add database repositories for fallback search
-	placeRepo interface{}
-	tripRepo  interface{}
+	geocoder  geocode.Geocoder
+	// Database repositories results are resolved against
+	placeRepo      PlaceRepository
+	tripRepo       TripRepository
+	collectionRepo CollectionRepository
+	curator        Curator
+	bus            events.Bus
+}
+
+// TripRepository is the part of the trips repository search reads
+type TripRepository interface {
+	GetByIDs(ctx context.Context, ids []string) ([]*trips.Trip, error)
+	List(ctx context.Context, filters trips.TripFilters) ([]*trips.Trip, error)
+	Count(ctx context.Context, filters trips.TripFilters) (int64, error)
+}
+
+// PlaceRepository is the part of the places repository search reads
+type PlaceRepository interface {
+	GetByIDs(ctx context.Context, ids []string) ([]*places.Place, error)
+	SearchWithSpatialContext(ctx context.Context, query string, spatial *nlp.SpatialSearchContext, filters places.SearchFilters) (*places.SearchResult, error)
+}
+
+// CollectionRepository is the part of the collections repository search
+// reads
+type CollectionRepository interface {
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]collections.Collection, error)
 }
 
 // SearchRequest represents a search request
@@ -30,27 +64,41 @@ type SearchRequest struct {
 
 // SearchResponse represents the complete search response
 type SearchResponse struct {
-	Query       *nlp.ParsedQuery              `json:"query"`
-	Results     []elasticsearch.SearchResult  `json:"results"`
-	Total       int64                         `json:"total"`
-	Took        int                           `json:"took"`
-	Suggestions []string                      `json:"suggestions,omitempty"`
+	Query       *nlp.ParsedQuery `json:"query"`
+	Results     []Result         `json:"results"`
+	Total       int64            `json:"total"`
+	Took        int              `json:"took"`
+	Suggestions []string         `json:"suggestions,omitempty"`
 }
 
-// NewService creates a new search service
-func NewService(esClient *elasticsearch.Client, nlpParser *nlp.Parser) *Service {
+// NewService creates a new search service. Without a geocoder, location
+// names in queries are matched as text only.
+func NewService(esClient *elasticsearch.Client, nlpParser *nlp.Parser, geocoder geocode.Geocoder) *Service {
 	return &Service{
 		esClient:  esClient,
 		nlpParser: nlpParser,
+		geocoder:  geocoder,
 	}
 }
 
-// SetRepositories sets the database repositories for fallback search
-func (s *Service) SetRepositories(placeRepo, tripRepo interface{}) {
+// SetRepositories sets the database repositories search results are
+// resolved against. Until they are set, searches return no results.
+func (s *Service) SetRepositories(placeRepo PlaceRepository, tripRepo TripRepository) {
 	s.placeRepo = placeRepo
 	s.tripRepo = tripRepo
 }
 
+// SetCollectionRepository sets the repository collection results are
+// resolved against. Until it is set, searches return no collections.
+func (s *Service) SetCollectionRepository(collectionRepo CollectionRepository) {
+	s.collectionRepo = collectionRepo
+}
+
+// SetEventBus enables announcing the searches users make
+func (s *Service) SetEventBus(bus events.Bus) {
+	s.bus = bus
+}
+
 // Search performs a unified natural language search
 func (s *Service) Search(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
 	// Set defaults
@@ -67,9 +115,19 @@ func (s *Service) Search(ctx context.Context, req *SearchRequest) (*SearchRespon
 		return nil, fmt.Errorf("failed to parse query: %w", err)
 	}
 
+	// Resolve location names so they can be searched spatially
+	s.resolveLocations(ctx, parsedQuery)
+
 	// Add user-specific filters for visibility
 	s.addVisibilityFilters(parsedQuery, req.UserID)
 
+	// The index narrows open places down by their weekly hours, the hours
+	// themselves decide once results are loaded
+	openNow, _ := parsedQuery.Filters["open_now"].(bool)
+	if openNow {
+		parsedQuery.Filters["open_at"] = places.MinuteOfWeek(time.Now())
+	}
+
 	// Build Elasticsearch query
 	esQuery := s.buildElasticsearchQuery(parsedQuery, req.Limit, req.Offset)
 
@@ -96,31 +154,129 @@ func (s *Service) Search(ctx context.Context, req *SearchRequest) (*SearchRespon
 		esResponse = s.fallbackSearch(ctx, parsedQuery, req)
 	}
 
+	// Resolve hits to current, visible records
+	results, err := s.hydrate(ctx, esResponse.Results, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load search results: %w", err)
+	}
+	if openNow {
+		results = openOnly(results)
+	}
+	esResponse.Total -= int64(len(esResponse.Results) - len(results))
+	if esResponse.Total < 0 {
+		esResponse.Total = 0
+	}
+
+	// Pinned results lead the first page only
+	if req.Offset == 0 {
+		var added int
+		results, added = s.applyPins(ctx, req, parsedQuery, results)
+		esResponse.Total += int64(added)
+	}
+
 	// Generate search suggestions
 	suggestions := s.generateSuggestions(parsedQuery, esResponse)
 
 	// Log the search for analytics (async)
 	go s.logSearch(context.Background(), req, parsedQuery, esResponse)
+	s.announceSearch(ctx, req, parsedQuery, esResponse)
 
 	return &SearchResponse{
 		Query:       parsedQuery,
-		Results:     esResponse.Results,
+		Results:     results,
 		Total:       esResponse.Total,
 		Took:        esResponse.Took,
 		Suggestions: suggestions,
 	}, nil
 }
 
-// addVisibilityFilters adds user-specific visibility filters
+// ParseQuery interprets a query the way Search would, without running it
+func (s *Service) ParseQuery(ctx context.Context, query string) (*nlp.ParsedQuery, error) {
+	parsedQuery, err := s.nlpParser.ParseQuery(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %w", err)
+	}
+	return parsedQuery, nil
+}
+
+// resolveLocations geocodes the location and areas the parser only found
+// names for. Named regions with a known extent become bounds, everything else
+// a circle around the match. Names that fail to resolve are left as they are
+// and searched as text.
+func (s *Service) resolveLocations(ctx context.Context, parsedQuery *nlp.ParsedQuery) {
+	if s.geocoder == nil {
+		return
+	}
+
+	// A query often names the same place more than once
+	resolved := make(map[string]*geocode.Result)
+	lookup := func(name string) *geocode.Result {
+		key := geocode.Normalize(name)
+		if result, ok := resolved[key]; ok {
+			return result
+		}
+		result, err := s.geocoder.Geocode(ctx, name)
+		if err != nil && !errors.Is(err, geocode.ErrNotFound) {
+			log.Printf("Failed to geocode %q: %v", name, err)
+		}
+		resolved[key] = result
+		return result
+	}
+
+	if location := parsedQuery.Location; location != nil && location.Name != "" &&
+		location.Latitude == 0 && location.Longitude == 0 {
+		if result := lookup(location.Name); result != nil {
+			location.Latitude = result.Latitude
+			location.Longitude = result.Longitude
+		}
+	}
+
+	spatial := parsedQuery.Spatial
+	if spatial == nil {
+		return
+	}
+	for _, area := range []*nlp.AreaFilter{spatial.Within, spatial.Near, spatial.Intersects} {
+		resolveArea(area, lookup)
+	}
+	for i := range spatial.Areas {
+		resolveArea(&spatial.Areas[i], lookup)
+	}
+}
+
+func resolveArea(area *nlp.AreaFilter, lookup func(string) *geocode.Result) {
+	if area == nil || area.Name == "" || area.Coordinates != nil {
+		return
+	}
+	result := lookup(area.Name)
+	if result == nil {
+		return
+	}
+
+	if area.Type == "region" && result.BBox != nil {
+		area.Type = "bounds"
+		area.Coordinates = []interface{}{result.BBox[0], result.BBox[1], result.BBox[2], result.BBox[3]}
+		return
+	}
+
+	if area.Radius == nil {
+		radius := regionRadiusKm
+		area.Radius = &radius
+	}
+	area.Type = "circle"
+	area.Coordinates = []interface{}{result.Longitude, result.Latitude}
+}
+
+// addVisibilityFilters adds user-specific visibility filters. Privacy decides
+// who sees a trip; the visibility stored with older documents is ignored.
 func (s *Service) addVisibilityFilters(parsedQuery *nlp.ParsedQuery, userID string) {
 	if userID != "" {
-		// Authenticated user - can see public + their private content
+		// Authenticated user - can see public + their own content
 		parsedQuery.Filters["visibility_filter"] = map[string]interface{}{
 			"user_id": userID,
 		}
 	} else {
 		// Guest user - only public content
-		parsedQuery.Filters["visibility"] = "public"
+		parsedQuery.Filters["privacy"] = trips.PrivacyPublic
 	}
 }
 
@@ -159,72 +315,34 @@ func (s *Service) buildElasticsearchQuery(parsedQuery *nlp.ParsedQuery, limit, o
 	if visibilityFilter, ok := parsedQuery.Filters["visibility_filter"].(map[string]interface{}); ok {
 		userID := visibilityFilter["user_id"].(string)
 		
-		// Build complex visibility query: public OR (private AND owned by user)
+		// Build visibility query: public OR owned by user
 		if boolQuery, ok := query["query"].(map[string]interface{})["bool"].(map[string]interface{}); ok {
-			if filters, ok := boolQuery["filter"].([]map[string]interface{}); ok {
-				visibilityClause := map[string]interface{}{
-					"bool": map[string]interface{}{
-						"should": []map[string]interface{}{
-							{
-								"term": map[string]interface{}{
-									"visibility": "public",
-								},
+			filters, _ := boolQuery["filter"].([]map[string]interface{})
+			visibilityClause := map[string]interface{}{
+				"bool": map[string]interface{}{
+					"should": []map[string]interface{}{
+						{
+							"term": map[string]interface{}{
+								"privacy": trips.PrivacyPublic,
 							},
-							{
-								"bool": map[string]interface{}{
-									"must": []map[string]interface{}{
-										{
-											"term": map[string]interface{}{
-												"visibility": "private",
-											},
-										},
-										{
-											"term": map[string]interface{}{
-												"owner_id": userID,
-											},
-										},
-									},
-								},
+						},
+						{
+							"term": map[string]interface{}{
+								"owner_id": userID,
 							},
 						},
-						"minimum_should_match": 1,
 					},
-				}
-				
-				boolQuery["filter"] = append(filters, visibilityClause)
+					"minimum_should_match": 1,
+				},
 			}
+			
+			boolQuery["filter"] = append(filters, visibilityClause)
 		}
 	}
 
 	return query
 }
 
-// fallbackSearch provides database-based search when Elasticsearch is unavailable
-func (s *Service) fallbackSearch(ctx context.Context, parsedQuery *nlp.ParsedQuery, req *SearchRequest) *elasticsearch.SearchResponse {
-	log.Printf("Using PostgreSQL fallback search for query: %s", req.Query)
-	
-	// For now, return a message indicating Elasticsearch is not configured
-	// In production, this would query PostgreSQL using the parsed query
-	results := []elasticsearch.SearchResult{
-		{
-			ID:   "fallback-message",
-			Type: "message",
-			Source: map[string]interface{}{
-				"message": "Search is currently using basic functionality. Full-text search will be available soon.",
-				"query":   req.Query,
-				"intent":  string(parsedQuery.Intent),
-			},
-			Score: 1.0,
-		},
-	}
-	
-	return &elasticsearch.SearchResponse{
-		Total:   int64(len(results)),
-		Results: results,
-		Took:    10,
-	}
-}
-
 // generateSuggestions creates search suggestions based on the query and results
 func (s *Service) generateSuggestions(parsedQuery *nlp.ParsedQuery, results *elasticsearch.SearchResponse) []string {
 	suggestions := []string{}
@@ -295,6 +413,33 @@ func (s *Service) logSearch(ctx context.Context, req *SearchRequest, parsedQuery
 	}
 }
 
+// announceSearch publishes a search that ran. The filters that only narrow
+// results to what the user may see are left out.
+func (s *Service) announceSearch(ctx context.Context, req *SearchRequest, parsedQuery *nlp.ParsedQuery, results *elasticsearch.SearchResponse) {
+	if s.bus == nil {
+		return
+	}
+
+	filters := make(map[string]interface{}, len(parsedQuery.Filters))
+	for name, value := range parsedQuery.Filters {
+		if name != "visibility_filter" && name != "privacy" && name != "open_at" {
+			filters[name] = value
+		}
+	}
+
+	event := events.New(events.SearchPerformed, "search", "", req.UserID, map[string]interface{}{
+		"query":         req.Query,
+		"intent":        string(parsedQuery.Intent),
+		"filters":       filters,
+		"results_count": results.Total,
+		"offset":        req.Offset,
+		"took_ms":       results.Took,
+	})
+	if err := s.bus.Publish(ctx, event); err != nil {
+		log.Printf("search: failed to announce search: %v", err)
+	}
+}
+
 // GetSearchSuggestions provides autocomplete suggestions
 func (s *Service) GetSearchSuggestions(ctx context.Context, prefix string, limit int) ([]string, error) {
 	// This would implement autocomplete functionality
@@ -356,19 +501,37 @@ func (s *Service) IndexPlace(ctx context.Context, placeID string, place map[stri
 	return s.esClient.IndexPlace(ctx, placeID, place)
 }
 
+// IndexCollection indexes a public collection for search
+func (s *Service) IndexCollection(ctx context.Context, collectionID string, collection map[string]interface{}) error {
+	if !s.esClient.IsAvailable() {
+		log.Printf("Elasticsearch not available, skipping collection indexing: %s", collectionID)
+		return nil
+	}
+
+	return s.esClient.IndexCollection(ctx, collectionID, collection)
+}
+
 // DeleteFromIndex removes a document from the search index
 func (s *Service) DeleteFromIndex(ctx context.Context, docType, documentID string) error {
 	if !s.esClient.IsAvailable() {
 		return nil
 	}
 
-	index := "activities"
-	if docType == "place" {
-		index = "places"
-	}
+	return s.esClient.DeleteDocument(ctx, indexName(docType), documentID)
+}
 
-	return s.esClient.DeleteDocument(ctx, index, documentID)
+// indexName returns the index documents of a type are kept in
+func indexName(docType string) string {
+	switch docType {
+	case "place":
+		return "places"
+	case "collection":
+		return "collections"
+	default:
+		return "activities"
+	}
 }
+
 // addSpatialFilters adds enhanced spatial search filters to Elasticsearch query
 func (s *Service) addSpatialFilters(query map[string]interface{}, spatial *nlp.SpatialSearchContext) {
 	if spatial == nil {
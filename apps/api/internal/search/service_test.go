@@ -0,0 +1,143 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/elasticsearch"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/internal/geocode"
+	"github.com/Oferzz/newMap/apps/api/internal/nlp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeGeocoder struct {
+	results map[string]*geocode.Result
+	calls   []string
+}
+
+func (g *fakeGeocoder) Geocode(ctx context.Context, query string) (*geocode.Result, error) {
+	g.calls = append(g.calls, query)
+	if result, ok := g.results[query]; ok {
+		return result, nil
+	}
+	return nil, geocode.ErrNotFound
+}
+
+func (g *fakeGeocoder) Search(ctx context.Context, query string, limit int) ([]*geocode.Result, error) {
+	if result, err := g.Geocode(ctx, query); err == nil {
+		return []*geocode.Result{result}, nil
+	}
+	return []*geocode.Result{}, nil
+}
+
+func TestService_ResolveLocations(t *testing.T) {
+	geocoder := &fakeGeocoder{results: map[string]*geocode.Result{
+		"bend":    {Name: "Bend", Latitude: 44.0582, Longitude: -121.3153},
+		"cascade": {Name: "Cascade Range", Latitude: 44, Longitude: -121.8, BBox: []float64{-122.5, 40.5, -120.5, 49}},
+	}}
+	service := NewService(nil, nlp.NewParser(), geocoder)
+
+	radius := 16.0
+	parsed := &nlp.ParsedQuery{
+		Location: &nlp.LocationFilter{Name: "bend", Radius: 50},
+		Spatial: &nlp.SpatialSearchContext{
+			Near:   &nlp.AreaFilter{Type: "circle", Name: "bend", Radius: &radius},
+			Within: &nlp.AreaFilter{Type: "region", Name: "cascade"},
+			Areas:  []nlp.AreaFilter{{Type: "region", Name: "atlantis"}},
+		},
+	}
+	service.resolveLocations(context.Background(), parsed)
+
+	assert.Equal(t, 44.0582, parsed.Location.Latitude)
+	assert.Equal(t, -121.3153, parsed.Location.Longitude)
+
+	assert.Equal(t, "circle", parsed.Spatial.Near.Type)
+	assert.Equal(t, []interface{}{-121.3153, 44.0582}, parsed.Spatial.Near.Coordinates)
+	assert.Equal(t, 16.0, *parsed.Spatial.Near.Radius)
+
+	assert.Equal(t, "bounds", parsed.Spatial.Within.Type)
+	assert.Equal(t, []interface{}{-122.5, 40.5, -120.5, 49.0}, parsed.Spatial.Within.Coordinates)
+
+	// Unknown names stay text searches
+	assert.Equal(t, "region", parsed.Spatial.Areas[0].Type)
+	assert.Nil(t, parsed.Spatial.Areas[0].Coordinates)
+
+	// Each name is geocoded once per query
+	assert.Equal(t, []string{"bend", "cascade", "atlantis"}, geocoder.calls)
+}
+
+func TestService_SearchQueryUsesGeocodedLocation(t *testing.T) {
+	geocoder := &fakeGeocoder{results: map[string]*geocode.Result{
+		"bend": {Name: "Bend", Latitude: 44.0582, Longitude: -121.3153},
+	}}
+	service := NewService(nil, nlp.NewParser(), geocoder)
+
+	parsed, err := service.ParseQuery(context.Background(), "easy hikes near bend")
+	require.NoError(t, err)
+	service.resolveLocations(context.Background(), parsed)
+
+	service.buildElasticsearchQuery(parsed, 20, 0)
+	assert.Equal(t, map[string]interface{}{
+		"lat":    44.0582,
+		"lng":    -121.3153,
+		"radius": 50.0,
+	}, parsed.Filters["location"])
+}
+
+func TestService_SearchQueryVisibility(t *testing.T) {
+	service := NewService(nil, nlp.NewParser(), nil)
+
+	filters := func(userID string) []map[string]interface{} {
+		parsed, err := service.ParseQuery(context.Background(), "waterfalls")
+		require.NoError(t, err)
+		service.addVisibilityFilters(parsed, userID)
+		query := service.buildElasticsearchQuery(parsed, 20, 0)
+		return query["query"].(map[string]interface{})["bool"].(map[string]interface{})["filter"].([]map[string]interface{})
+	}
+
+	// Guests only see public trips
+	assert.Contains(t, filters(""), map[string]interface{}{
+		"term": map[string]interface{}{"privacy": "public"},
+	})
+
+	// Users also see their own, whatever their privacy
+	assert.Contains(t, filters("user-1"), map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should": []map[string]interface{}{
+				{"term": map[string]interface{}{"privacy": "public"}},
+				{"term": map[string]interface{}{"owner_id": "user-1"}},
+			},
+			"minimum_should_match": 1,
+		},
+	})
+}
+
+func TestService_AnnounceSearch(t *testing.T) {
+	service := NewService(nil, nlp.NewParser(), nil)
+	bus := events.NewLocalBus()
+	service.SetEventBus(bus)
+
+	var announced []events.Event
+	bus.Subscribe(events.SearchPerformed, func(ctx context.Context, event events.Event) error {
+		announced = append(announced, event)
+		return nil
+	})
+
+	parsed, err := service.ParseQuery(context.Background(), "easy hikes")
+	require.NoError(t, err)
+	service.addVisibilityFilters(parsed, "user-1")
+	service.announceSearch(context.Background(), &SearchRequest{Query: "easy hikes", UserID: "user-1", SessionID: "s1"}, parsed,
+		&elasticsearch.SearchResponse{Total: 3, Took: 12})
+
+	require.Len(t, announced, 1)
+	event := announced[0]
+	assert.Equal(t, "user-1", event.ActorID)
+	assert.Equal(t, "easy hikes", event.Data["query"])
+	assert.Equal(t, int64(3), event.Data["results_count"])
+	assert.NotContains(t, event.Data, "session_id")
+
+	// Visibility filters say who searched, so they are left out
+	assert.NotContains(t, event.Data["filters"], "visibility_filter")
+}
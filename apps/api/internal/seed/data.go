@@ -0,0 +1,245 @@
+package seed
+
+import "time"
+
+// DemoPassword is the password of every demo user
+const DemoPassword = "demo-password"
+
+// demoPasswordHash is DemoPassword hashed once, so that seeding twice writes
+// identical rows
+const demoPasswordHash = "$2a$10$.bz.H/qLpOkVROVd41NbluDijOAmMm0ScH4f9b5j1Ng8r5UvVSCo2"
+
+// createdAt is when every demo record claims to have been created
+var createdAt = time.Date(2024, time.May, 1, 9, 0, 0, 0, time.UTC)
+
+// Dataset is a complete set of demo records. Every record has a fixed ID.
+type Dataset struct {
+	Users       []User
+	Places      []Place
+	Media       []Media
+	Trips       []Trip
+	Collections []Collection
+}
+
+type User struct {
+	ID          string
+	Email       string
+	Username    string
+	DisplayName string
+	Bio         string
+	Roles       []string
+}
+
+type Place struct {
+	ID          string
+	Name        string
+	Description string
+	Type        string
+	Lng, Lat    float64
+	City        string
+	State       string
+	Country     string
+	CreatedBy   string
+	Category    []string
+	Tags        []string
+}
+
+// Media is a reference to an uploaded file. The files themselves are not
+// seeded, so clients see broken images unless they are copied into storage.
+type Media struct {
+	ID         string
+	Filename   string
+	MimeType   string
+	SizeBytes  int64
+	Width      int
+	Height     int
+	Lng, Lat   float64
+	UploadedBy string
+}
+
+type Trip struct {
+	ID             string
+	Title          string
+	Description    string
+	OwnerID        string
+	Privacy        string
+	Status         string
+	Tags           []string
+	StartDate      string // YYYY-MM-DD, empty for none
+	EndDate        string
+	ActivityType   string
+	Difficulty     string
+	RouteType      string
+	DistanceKm     float64
+	DurationHours  float64
+	ElevationGainM int
+	Route          [][2]float64 // [longitude, latitude]
+	CoverMediaID   string
+	Waypoints      []Waypoint
+	Collaborators  []Collaborator
+}
+
+type Waypoint struct {
+	ID      string
+	PlaceID string
+	Notes   string
+}
+
+type Collaborator struct {
+	ID     string
+	UserID string
+	Role   string
+}
+
+type Collection struct {
+	ID          string
+	Name        string
+	Description string
+	UserID      string
+	Privacy     string
+	Locations   []CollectionLocation
+}
+
+type CollectionLocation struct {
+	ID       string
+	Name     string
+	Lat, Lng float64
+}
+
+// Demo user IDs, for tests and clients that log in as them
+const (
+	AdminID = "5eed0000-0000-4000-8000-000000000001"
+	MayaID  = "5eed0000-0000-4000-8000-000000000002"
+	NoamID  = "5eed0000-0000-4000-8000-000000000003"
+)
+
+const (
+	smithRockID    = "5eed0000-0000-4000-8001-000000000001"
+	pilotButteID   = "5eed0000-0000-4000-8001-000000000002"
+	tumaloFallsID  = "5eed0000-0000-4000-8001-000000000003"
+	breweryID      = "5eed0000-0000-4000-8001-000000000004"
+	craterLakeID   = "5eed0000-0000-4000-8001-000000000005"
+	paulinaPeakID  = "5eed0000-0000-4000-8001-000000000006"
+	sparksLakeID   = "5eed0000-0000-4000-8001-000000000007"
+	smithRockPhoto = "5eed0000-0000-4000-8002-000000000001"
+	tumaloPhoto    = "5eed0000-0000-4000-8002-000000000002"
+	craterPhoto    = "5eed0000-0000-4000-8002-000000000003"
+)
+
+// Demo returns the demo dataset: a handful of users around Bend, Oregon with
+// public and private trips, the places they visit, and their collections
+func Demo() Dataset {
+	return Dataset{
+		Users: []User{
+			{ID: AdminID, Email: "admin@demo.newmap.dev", Username: "demo-admin", DisplayName: "Demo Admin", Roles: []string{"admin"}},
+			{ID: MayaID, Email: "maya@demo.newmap.dev", Username: "maya", DisplayName: "Maya Cohen", Bio: "Climber and weekend hiker based in Bend.", Roles: []string{"user"}},
+			{ID: NoamID, Email: "noam@demo.newmap.dev", Username: "noam", DisplayName: "Noam Levi", Bio: "Waterfalls, lakes and good coffee.", Roles: []string{"user"}},
+		},
+		Places: []Place{
+			{ID: smithRockID, Name: "Smith Rock State Park", Description: "Volcanic tuff cliffs above the Crooked River, birthplace of American sport climbing.", Type: "area", Lng: -121.1390, Lat: 44.3672, City: "Terrebonne", State: "OR", Country: "USA", CreatedBy: MayaID, Category: []string{"park"}, Tags: []string{"climbing", "hiking"}},
+			{ID: pilotButteID, Name: "Pilot Butte", Description: "Cinder cone in the middle of Bend with views of the Cascades.", Type: "poi", Lng: -121.2828, Lat: 44.0598, City: "Bend", State: "OR", Country: "USA", CreatedBy: MayaID, Category: []string{"viewpoint"}, Tags: []string{"hiking", "views"}},
+			{ID: tumaloFallsID, Name: "Tumalo Falls", Description: "97 foot waterfall on Tumalo Creek.", Type: "poi", Lng: -121.5664, Lat: 44.0336, City: "Bend", State: "OR", Country: "USA", CreatedBy: NoamID, Category: []string{"waterfall"}, Tags: []string{"waterfall", "hiking"}},
+			{ID: breweryID, Name: "Deschutes Brewery Public House", Description: "The original brewpub downtown.", Type: "poi", Lng: -121.3146, Lat: 44.0590, City: "Bend", State: "OR", Country: "USA", CreatedBy: NoamID, Category: []string{"food"}, Tags: []string{"food", "beer"}},
+			{ID: craterLakeID, Name: "Crater Lake Rim Village", Description: "Lodge and visitor center on the caldera rim.", Type: "poi", Lng: -122.1390, Lat: 42.9096, City: "Crater Lake", State: "OR", Country: "USA", CreatedBy: MayaID, Category: []string{"park"}, Tags: []string{"lake", "views"}},
+			{ID: paulinaPeakID, Name: "Paulina Peak", Description: "Highest point of Newberry Volcano.", Type: "poi", Lng: -121.2520, Lat: 43.6880, City: "La Pine", State: "OR", Country: "USA", CreatedBy: MayaID, Category: []string{"viewpoint"}, Tags: []string{"hiking", "views"}},
+			{ID: sparksLakeID, Name: "Sparks Lake", Description: "Shallow lake below South Sister, popular for paddling.", Type: "poi", Lng: -121.7469, Lat: 44.0156, City: "Bend", State: "OR", Country: "USA", CreatedBy: NoamID, Category: []string{"lake"}, Tags: []string{"lake", "paddling"}},
+		},
+		Media: []Media{
+			{ID: smithRockPhoto, Filename: "seed/smith-rock.jpg", MimeType: "image/jpeg", SizeBytes: 482133, Width: 1920, Height: 1280, Lng: -121.1390, Lat: 44.3672, UploadedBy: MayaID},
+			{ID: tumaloPhoto, Filename: "seed/tumalo-falls.jpg", MimeType: "image/jpeg", SizeBytes: 391552, Width: 1280, Height: 1920, Lng: -121.5664, Lat: 44.0336, UploadedBy: NoamID},
+			{ID: craterPhoto, Filename: "seed/crater-lake.jpg", MimeType: "image/jpeg", SizeBytes: 530710, Width: 1920, Height: 1080, Lng: -122.1390, Lat: 42.9096, UploadedBy: MayaID},
+		},
+		Trips: []Trip{
+			{
+				ID:             "5eed0000-0000-4000-8003-000000000001",
+				Title:          "Smith Rock Misery Ridge Loop",
+				Description:    "Steep climb over Misery Ridge to Monkey Face, back along the river.",
+				OwnerID:        MayaID,
+				Privacy:        "public",
+				Status:         "completed",
+				Tags:           []string{"hiking", "climbing"},
+				ActivityType:   "hiking",
+				Difficulty:     "moderate",
+				RouteType:      "loop",
+				DistanceKm:     6.1,
+				DurationHours:  2.5,
+				ElevationGainM: 300,
+				Route:          [][2]float64{{-121.1390, 44.3672}, {-121.1432, 44.3680}, {-121.1478, 44.3711}, {-121.1446, 44.3741}, {-121.1390, 44.3672}},
+				CoverMediaID:   smithRockPhoto,
+				Waypoints: []Waypoint{
+					{ID: "5eed0000-0000-4000-8004-000000000001", PlaceID: smithRockID, Notes: "Park at the welcome center, day pass required."},
+				},
+			},
+			{
+				ID:             "5eed0000-0000-4000-8003-000000000002",
+				Title:          "Bend Waterfalls and Lakes",
+				Description:    "A day west of town: Tumalo Falls in the morning, paddling on Sparks Lake after lunch.",
+				OwnerID:        NoamID,
+				Privacy:        "public",
+				Status:         "planning",
+				Tags:           []string{"waterfall", "lake", "family"},
+				ActivityType:   "hiking",
+				Difficulty:     "easy",
+				RouteType:      "point_to_point",
+				DistanceKm:     41.5,
+				DurationHours:  7,
+				ElevationGainM: 250,
+				Route:          [][2]float64{{-121.3146, 44.0590}, {-121.5664, 44.0336}, {-121.7469, 44.0156}},
+				CoverMediaID:   tumaloPhoto,
+				Waypoints: []Waypoint{
+					{ID: "5eed0000-0000-4000-8004-000000000002", PlaceID: breweryID, Notes: "Breakfast burritos to go."},
+					{ID: "5eed0000-0000-4000-8004-000000000003", PlaceID: tumaloFallsID},
+					{ID: "5eed0000-0000-4000-8004-000000000004", PlaceID: sparksLakeID, Notes: "Kayak rental at the launch."},
+				},
+				Collaborators: []Collaborator{
+					{ID: "5eed0000-0000-4000-8005-000000000001", UserID: MayaID, Role: "editor"},
+				},
+			},
+			{
+				ID:           "5eed0000-0000-4000-8003-000000000003",
+				Title:        "Crater Lake Weekend",
+				Description:  "Two days around the rim with a stop at Newberry on the way.",
+				OwnerID:      MayaID,
+				Privacy:      "private",
+				Status:       "planning",
+				Tags:         []string{"lake", "road-trip"},
+				StartDate:    "2025-08-15",
+				EndDate:      "2025-08-17",
+				ActivityType: "general",
+				RouteType:    "point_to_point",
+				DistanceKm:   190,
+				Route:        [][2]float64{{-121.3146, 44.0590}, {-121.2520, 43.6880}, {-122.1390, 42.9096}},
+				CoverMediaID: craterPhoto,
+				Waypoints: []Waypoint{
+					{ID: "5eed0000-0000-4000-8004-000000000005", PlaceID: paulinaPeakID},
+					{ID: "5eed0000-0000-4000-8004-000000000006", PlaceID: craterLakeID, Notes: "Lodge booked for two nights."},
+				},
+				Collaborators: []Collaborator{
+					{ID: "5eed0000-0000-4000-8005-000000000002", UserID: NoamID, Role: "viewer"},
+				},
+			},
+		},
+		Collections: []Collection{
+			{
+				ID:          "5eed0000-0000-4000-8006-000000000001",
+				Name:        "Central Oregon climbing",
+				Description: "Crags worth the drive.",
+				UserID:      MayaID,
+				Privacy:     "public",
+				Locations: []CollectionLocation{
+					{ID: "5eed0000-0000-4000-8007-000000000001", Name: "Smith Rock", Lat: 44.3672, Lng: -121.1390},
+					{ID: "5eed0000-0000-4000-8007-000000000002", Name: "Meadow Camp", Lat: 44.0122, Lng: -121.3727},
+				},
+			},
+			{
+				ID:      "5eed0000-0000-4000-8006-000000000002",
+				Name:    "Coffee stops",
+				UserID:  NoamID,
+				Privacy: "private",
+				Locations: []CollectionLocation{
+					{ID: "5eed0000-0000-4000-8007-000000000003", Name: "Thump Coffee", Lat: 44.0582, Lng: -121.3153},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package seed
+
+import (
+	"testing"
+
+	"github.com/Oferzz/newMap/apps/api/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDemoPasswordHash(t *testing.T) {
+	assert.True(t, utils.CheckPassword(DemoPassword, demoPasswordHash))
+}
+
+func TestDemo_References(t *testing.T) {
+	data := Demo()
+
+	ids := make(map[string]bool)
+	add := func(id string) {
+		t.Helper()
+		require.False(t, ids[id], "duplicate id %s", id)
+		ids[id] = true
+	}
+
+	users := make(map[string]bool)
+	for _, user := range data.Users {
+		add(user.ID)
+		users[user.ID] = true
+	}
+
+	places := make(map[string]bool)
+	for _, place := range data.Places {
+		add(place.ID)
+		places[place.ID] = true
+		assert.True(t, users[place.CreatedBy], "place %s", place.Name)
+	}
+
+	media := make(map[string]bool)
+	for _, m := range data.Media {
+		add(m.ID)
+		media[m.ID] = true
+		assert.True(t, users[m.UploadedBy], "media %s", m.Filename)
+	}
+
+	for _, trip := range data.Trips {
+		add(trip.ID)
+		assert.True(t, users[trip.OwnerID], "trip %s", trip.Title)
+		if trip.CoverMediaID != "" {
+			assert.True(t, media[trip.CoverMediaID], "trip %s", trip.Title)
+		}
+		for _, waypoint := range trip.Waypoints {
+			add(waypoint.ID)
+			assert.True(t, places[waypoint.PlaceID], "waypoint of trip %s", trip.Title)
+		}
+		for _, collaborator := range trip.Collaborators {
+			add(collaborator.ID)
+			assert.True(t, users[collaborator.UserID], "collaborator of trip %s", trip.Title)
+			assert.NotEqual(t, trip.OwnerID, collaborator.UserID, "trip %s", trip.Title)
+		}
+	}
+
+	for _, collection := range data.Collections {
+		add(collection.ID)
+		assert.True(t, users[collection.UserID], "collection %s", collection.Name)
+		for _, location := range collection.Locations {
+			add(location.ID)
+		}
+	}
+}
+
+func TestRouteGeoJSON(t *testing.T) {
+	route, err := routeGeoJSON([][2]float64{{-121.1, 44.3}, {-121.2, 44.4}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"LineString","coordinates":[[-121.1,44.3],[-121.2,44.4]]}`, route.(string))
+
+	route, err = routeGeoJSON([][2]float64{{-121.1, 44.3}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"Point","coordinates":[-121.1,44.3]}`, route.(string))
+
+	route, err = routeGeoJSON(nil)
+	require.NoError(t, err)
+	assert.Nil(t, route)
+}
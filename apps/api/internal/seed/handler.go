@@ -0,0 +1,40 @@
+package seed
+
+import (
+	"log"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the seed endpoint
+type Handler struct {
+	seeder *Seeder
+}
+
+// NewHandler creates a new seed handler
+func NewHandler(seeder *Seeder) *Handler {
+	return &Handler{
+		seeder: seeder,
+	}
+}
+
+// Seed loads the demo dataset
+func (h *Handler) Seed(c *gin.Context) {
+	summary, err := h.seeder.Run(c.Request.Context(), Demo())
+	if err != nil {
+		log.Printf("seed: %v", err)
+		response.InternalServerError(c, "Failed to seed demo data")
+		return
+	}
+
+	response.Success(c, summary)
+}
+
+// RegisterRoutes registers the seed endpoint for admins. It overwrites demo
+// records, so it must never be registered in production.
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *users.RouteMiddleware) {
+	admin := router.Group("/admin", mw.RequireAdmin...)
+	admin.POST("/seed", h.Seed)
+}
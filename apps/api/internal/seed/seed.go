@@ -0,0 +1,357 @@
+// Package seed loads demo data for local development, demos and end-to-end
+// tests. Every record has a fixed ID and is upserted, so seeding again resets
+// the demo records to their original state without duplicating them and
+// without touching anything else in the database.
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Oferzz/newMap/apps/api/internal/cache"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Summary counts the records a seed wrote
+type Summary struct {
+	Users       int `json:"users"`
+	Places      int `json:"places"`
+	Media       int `json:"media"`
+	Trips       int `json:"trips"`
+	Collections int `json:"collections"`
+}
+
+// Seeder writes a dataset to the database
+type Seeder struct {
+	db    *sqlx.DB
+	cache cache.Cache
+}
+
+// NewSeeder creates a seeder. Cached copies of the records it writes are
+// dropped from c afterwards.
+func NewSeeder(db *sqlx.DB, c cache.Cache) *Seeder {
+	return &Seeder{
+		db:    db,
+		cache: c,
+	}
+}
+
+// Run writes the dataset in a single transaction
+func (s *Seeder) Run(ctx context.Context, data Dataset) (*Summary, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	steps := []func(context.Context, *sqlx.Tx, Dataset) error{
+		seedUsers,
+		seedPlaces,
+		seedMedia,
+		seedTrips,
+		seedCollections,
+	}
+	for _, step := range steps {
+		if err := step(ctx, tx, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit seed: %w", err)
+	}
+
+	s.invalidate(ctx, data)
+
+	return &Summary{
+		Users:       len(data.Users),
+		Places:      len(data.Places),
+		Media:       len(data.Media),
+		Trips:       len(data.Trips),
+		Collections: len(data.Collections),
+	}, nil
+}
+
+// invalidate drops cached copies of the seeded records. The data is already
+// written, so failures only leave stale entries until they expire.
+func (s *Seeder) invalidate(ctx context.Context, data Dataset) {
+	for _, user := range data.Users {
+		s.cache.DeleteUser(ctx, user.ID)
+	}
+	for _, place := range data.Places {
+		s.cache.DeletePlace(ctx, place.ID)
+	}
+	for _, trip := range data.Trips {
+		userIDs := []string{trip.OwnerID}
+		for _, collaborator := range trip.Collaborators {
+			userIDs = append(userIDs, collaborator.UserID)
+		}
+		cache.TripInvalidation{TripID: trip.ID, UserIDs: userIDs}.Apply(ctx, s.cache)
+	}
+}
+
+func seedUsers(ctx context.Context, tx *sqlx.Tx, data Dataset) error {
+	for _, user := range data.Users {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO users (
+				id, email, username, password_hash, display_name, bio, roles,
+				created_at, updated_at, status
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, 'active')
+			ON CONFLICT (id) DO UPDATE SET
+				email = EXCLUDED.email,
+				username = EXCLUDED.username,
+				password_hash = EXCLUDED.password_hash,
+				display_name = EXCLUDED.display_name,
+				bio = EXCLUDED.bio,
+				roles = EXCLUDED.roles,
+				updated_at = EXCLUDED.updated_at,
+				status = EXCLUDED.status`,
+			user.ID, user.Email, user.Username, demoPasswordHash, user.DisplayName,
+			user.Bio, pq.Array(user.Roles), createdAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to seed user %s: %w", user.Username, err)
+		}
+	}
+	return nil
+}
+
+func seedPlaces(ctx context.Context, tx *sqlx.Tx, data Dataset) error {
+	for _, place := range data.Places {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO places (
+				id, name, description, type, location,
+				street_address, city, state, country, postal_code,
+				created_by, category, tags, privacy, status, created_at, updated_at
+			) VALUES (
+				$1, $2, $3, $4, ST_SetSRID(ST_MakePoint($5, $6), 4326)::geography,
+				'', $7, $8, $9, '', $10, $11, $12, 'public', 'active', $13, $13
+			)
+			ON CONFLICT (id) DO UPDATE SET
+				name = EXCLUDED.name,
+				description = EXCLUDED.description,
+				type = EXCLUDED.type,
+				location = EXCLUDED.location,
+				street_address = EXCLUDED.street_address,
+				city = EXCLUDED.city,
+				state = EXCLUDED.state,
+				country = EXCLUDED.country,
+				postal_code = EXCLUDED.postal_code,
+				created_by = EXCLUDED.created_by,
+				category = EXCLUDED.category,
+				tags = EXCLUDED.tags,
+				privacy = EXCLUDED.privacy,
+				status = EXCLUDED.status,
+				updated_at = EXCLUDED.updated_at`,
+			place.ID, place.Name, place.Description, place.Type, place.Lng, place.Lat,
+			place.City, place.State, place.Country, place.CreatedBy,
+			pq.Array(place.Category), pq.Array(place.Tags), createdAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to seed place %s: %w", place.Name, err)
+		}
+	}
+	return nil
+}
+
+func seedMedia(ctx context.Context, tx *sqlx.Tx, data Dataset) error {
+	for _, media := range data.Media {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO media (
+				id, filename, original_name, mime_type, size_bytes, storage_path,
+				width, height, location, uploaded_by, created_at
+			) VALUES (
+				$1, $2, $2, $3, $4, $2, $5, $6,
+				ST_SetSRID(ST_MakePoint($7, $8), 4326)::geography, $9, $10
+			)
+			ON CONFLICT (id) DO UPDATE SET
+				filename = EXCLUDED.filename,
+				original_name = EXCLUDED.original_name,
+				mime_type = EXCLUDED.mime_type,
+				size_bytes = EXCLUDED.size_bytes,
+				storage_path = EXCLUDED.storage_path,
+				width = EXCLUDED.width,
+				height = EXCLUDED.height,
+				location = EXCLUDED.location,
+				uploaded_by = EXCLUDED.uploaded_by`,
+			media.ID, media.Filename, media.MimeType, media.SizeBytes,
+			media.Width, media.Height, media.Lng, media.Lat, media.UploadedBy, createdAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to seed media %s: %w", media.Filename, err)
+		}
+	}
+	return nil
+}
+
+func seedTrips(ctx context.Context, tx *sqlx.Tx, data Dataset) error {
+	mediaPaths := make(map[string]string, len(data.Media))
+	for _, media := range data.Media {
+		mediaPaths[media.ID] = "/media/" + media.Filename
+	}
+
+	for i, trip := range data.Trips {
+		route, err := routeGeoJSON(trip.Route)
+		if err != nil {
+			return fmt.Errorf("failed to encode route of trip %s: %w", trip.Title, err)
+		}
+
+		// Later trips are newer, so lists show them in a stable order
+		created := createdAt.AddDate(0, 0, i)
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO trips (
+				id, title, description, owner_id, cover_image, privacy, status,
+				start_date, end_date, timezone, tags,
+				activity_type, difficulty_level, duration_hours, distance_km,
+				elevation_gain_m, route_type, route_geojson, trail_conditions,
+				accessibility_notes, created_at, updated_at, deleted_at
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, $7, $8, $9, 'America/Los_Angeles', $10,
+				$11, $12, $13, $14, $15, $16, $17, '', '', $18, $18, NULL
+			)
+			ON CONFLICT (id) DO UPDATE SET
+				title = EXCLUDED.title,
+				description = EXCLUDED.description,
+				owner_id = EXCLUDED.owner_id,
+				cover_image = EXCLUDED.cover_image,
+				privacy = EXCLUDED.privacy,
+				status = EXCLUDED.status,
+				start_date = EXCLUDED.start_date,
+				end_date = EXCLUDED.end_date,
+				timezone = EXCLUDED.timezone,
+				tags = EXCLUDED.tags,
+				activity_type = EXCLUDED.activity_type,
+				difficulty_level = EXCLUDED.difficulty_level,
+				duration_hours = EXCLUDED.duration_hours,
+				distance_km = EXCLUDED.distance_km,
+				elevation_gain_m = EXCLUDED.elevation_gain_m,
+				route_type = EXCLUDED.route_type,
+				route_geojson = EXCLUDED.route_geojson,
+				trail_conditions = EXCLUDED.trail_conditions,
+				accessibility_notes = EXCLUDED.accessibility_notes,
+				updated_at = EXCLUDED.updated_at,
+				deleted_at = NULL`,
+			trip.ID, trip.Title, trip.Description, trip.OwnerID, mediaPaths[trip.CoverMediaID],
+			trip.Privacy, trip.Status, nullDate(trip.StartDate), nullDate(trip.EndDate),
+			pq.Array(trip.Tags), trip.ActivityType, trip.Difficulty, trip.DurationHours,
+			trip.DistanceKm, trip.ElevationGainM, trip.RouteType, route, created,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to seed trip %s: %w", trip.Title, err)
+		}
+
+		// Waypoints, collaborators and media links are replaced wholesale so
+		// that edits made while using the demo do not survive a reseed
+		for _, query := range []string{
+			`DELETE FROM trip_waypoints WHERE trip_id = $1`,
+			`DELETE FROM trip_collaborators WHERE trip_id = $1`,
+			`DELETE FROM media_usage WHERE entity_type = 'trip' AND entity_id = $1`,
+		} {
+			if _, err := tx.ExecContext(ctx, query, trip.ID); err != nil {
+				return fmt.Errorf("failed to reset trip %s: %w", trip.Title, err)
+			}
+		}
+
+		for position, waypoint := range trip.Waypoints {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO trip_waypoints (id, trip_id, place_id, order_position, notes, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $6)`,
+				waypoint.ID, trip.ID, waypoint.PlaceID, position+1, waypoint.Notes, created,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to seed waypoint of trip %s: %w", trip.Title, err)
+			}
+		}
+
+		for _, collaborator := range trip.Collaborators {
+			role := collaborator.Role
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO trip_collaborators (
+					id, trip_id, user_id, role, can_edit, can_delete, can_invite,
+					can_moderate_suggestions, invited_at, joined_at
+				) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)`,
+				collaborator.ID, trip.ID, collaborator.UserID, role,
+				role == "editor" || role == "admin", role == "admin", role == "admin",
+				role == "admin" || role == "editor", created,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to seed collaborator of trip %s: %w", trip.Title, err)
+			}
+		}
+
+		if trip.CoverMediaID != "" {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO media_usage (media_id, entity_type, entity_id, created_at)
+				VALUES ($1, 'trip', $2, $3)`,
+				trip.CoverMediaID, trip.ID, created,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to seed cover of trip %s: %w", trip.Title, err)
+			}
+		}
+	}
+	return nil
+}
+
+func seedCollections(ctx context.Context, tx *sqlx.Tx, data Dataset) error {
+	for _, collection := range data.Collections {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO collections (id, name, description, user_id, privacy, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $6)
+			ON CONFLICT (id) DO UPDATE SET
+				name = EXCLUDED.name,
+				description = EXCLUDED.description,
+				user_id = EXCLUDED.user_id,
+				privacy = EXCLUDED.privacy,
+				updated_at = EXCLUDED.updated_at`,
+			collection.ID, collection.Name, collection.Description, collection.UserID,
+			collection.Privacy, createdAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to seed collection %s: %w", collection.Name, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM collection_locations WHERE collection_id = $1`, collection.ID); err != nil {
+			return fmt.Errorf("failed to reset collection %s: %w", collection.Name, err)
+		}
+
+		for _, location := range collection.Locations {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO collection_locations (id, collection_id, name, latitude, longitude, added_at)
+				VALUES ($1, $2, $3, $4, $5, $6)`,
+				location.ID, collection.ID, location.Name, location.Lat, location.Lng, createdAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to seed location of collection %s: %w", collection.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// routeGeoJSON encodes a route as a GeoJSON LineString, or a Point when it has
+// a single position
+func routeGeoJSON(route [][2]float64) (interface{}, error) {
+	if len(route) == 0 {
+		return nil, nil
+	}
+
+	geometry := map[string]interface{}{"type": "LineString", "coordinates": route}
+	if len(route) == 1 {
+		geometry = map[string]interface{}{"type": "Point", "coordinates": route[0]}
+	}
+
+	data, err := json.Marshal(geometry)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func nullDate(date string) sql.NullString {
+	return sql.NullString{String: date, Valid: date != ""}
+}
@@ -0,0 +1,95 @@
+package sharecard
+
+import (
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/users"
+	"github.com/Oferzz/newMap/apps/api/internal/middleware"
+	"github.com/Oferzz/newMap/apps/api/pkg/httpcache"
+	"github.com/Oferzz/newMap/apps/api/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// CreateCompletionImage queues a share card for a completion and returns its URL
+func (h *Handler) CreateCompletionImage(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	image, err := h.service.RequestCompletionImage(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		switch err {
+		case trips.ErrCompletionNotFound:
+			response.NotFound(c, "Completion not found")
+		case ErrUnauthorized:
+			response.Forbidden(c, "You can only share your own completions")
+		default:
+			response.InternalServerError(c, "Failed to create share image")
+		}
+		return
+	}
+
+	response.Accepted(c, image)
+}
+
+// GetTripMetadata returns Open Graph metadata for a public trip
+func (h *Handler) GetTripMetadata(c *gin.Context) {
+	meta, err := h.service.TripMetadata(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		switch err {
+		case trips.ErrTripNotFound, ErrNotPublic:
+			// Private trips are reported as missing so their existence is not leaked
+			response.NotFound(c, "Trip not found")
+		default:
+			response.InternalServerError(c, "Failed to get trip metadata")
+		}
+		return
+	}
+
+	httpcache.Public(c, httpcache.Detail, httpcache.TripKey(c.Param("id")))
+	response.Success(c, meta)
+}
+
+// GetPlaceMetadata returns Open Graph metadata for a public place
+func (h *Handler) GetPlaceMetadata(c *gin.Context) {
+	meta, err := h.service.PlaceMetadata(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		switch err {
+		case places.ErrPlaceNotFound, ErrNotPublic:
+			response.NotFound(c, "Place not found")
+		default:
+			response.InternalServerError(c, "Failed to get place metadata")
+		}
+		return
+	}
+
+	httpcache.Public(c, httpcache.Detail, httpcache.PlaceKey(c.Param("id")))
+	response.Success(c, meta)
+}
+
+// RegisterRoutes registers the share card routes. Open Graph metadata is
+// public so link unfurlers can read it.
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup, mw *users.RouteMiddleware) {
+	og := router.Group("/og")
+	{
+		og.GET("/trips/:id", h.GetTripMetadata)
+		og.GET("/places/:id", h.GetPlaceMetadata)
+	}
+
+	completions := router.Group("/completions", mw.RequireAuth)
+	{
+		completions.POST("/:id/share-image", h.CreateCompletionImage)
+	}
+}
@@ -0,0 +1,150 @@
+package sharecard
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+	"github.com/Oferzz/newMap/apps/api/internal/media"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tripRepository struct {
+	trips.Repository
+	trips map[string]*trips.Trip
+}
+
+func (r *tripRepository) GetByID(ctx context.Context, id string) (*trips.Trip, error) {
+	trip, ok := r.trips[id]
+	if !ok {
+		return nil, trips.ErrTripNotFound
+	}
+	return trip, nil
+}
+
+type placeRepository struct {
+	places.Repository
+	places map[string]*places.Place
+}
+
+func (r *placeRepository) GetByID(ctx context.Context, id string) (*places.Place, error) {
+	place, ok := r.places[id]
+	if !ok {
+		return nil, places.ErrPlaceNotFound
+	}
+	return place, nil
+}
+
+func newTestHandler(t *testing.T) (*Handler, *Service, media.Storage) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	storage, err := media.NewDiskStorage(&config.MediaConfig{StoragePath: t.TempDir(), CDNURL: "https://cdn.example.com"})
+	require.NoError(t, err)
+
+	updated := time.Now().Add(-time.Hour)
+	tripRepo := &tripRepository{trips: map[string]*trips.Trip{
+		"public":  {ID: "public", Title: "Dipsea", Privacy: "public", CoverImage: "https://cdn.example.com/cover.jpg", UpdatedAt: updated},
+		"private": {ID: "private", Title: "Secret spot", Privacy: "private", UpdatedAt: updated},
+	}}
+	placeRepo := &placeRepository{places: map[string]*places.Place{
+		"summit": {ID: "summit", Name: "Mount Tam", Privacy: "public", UpdatedAt: updated},
+	}}
+
+	service := NewService(tripRepo, placeRepo, storage, jobs.NewLocalQueue(), &config.AppConfig{Name: "newMap", WebURL: "https://newmap.example.com/"})
+	return NewHandler(service), service, storage
+}
+
+func getMetadata(h *Handler, path string) (*httptest.ResponseRecorder, Metadata) {
+	router := gin.New()
+	router.GET("/og/trips/:id", h.GetTripMetadata)
+	router.GET("/og/places/:id", h.GetPlaceMetadata)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var body struct {
+		Data Metadata `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return w, body.Data
+}
+
+func TestHandler_GetTripMetadata(t *testing.T) {
+	t.Run("public trip with a rendered card", func(t *testing.T) {
+		h, service, storage := newTestHandler(t)
+		_, err := storage.Save(entityImagePath("trip", "public"), []byte("png"))
+		require.NoError(t, err)
+
+		w, meta := getMetadata(h, "/og/trips/public")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "Dipsea", meta.Title)
+		assert.Equal(t, "https://newmap.example.com/trips/public", meta.CanonicalURL)
+		assert.Equal(t, storage.GetURL("share/trips/public.png"), meta.ImageURL)
+		assert.Equal(t, "image/png", meta.ImageType)
+		assert.Equal(t, Width, meta.ImageWidth)
+		assert.Equal(t, Height, meta.ImageHeight)
+		assert.Contains(t, w.Header().Get("Cache-Control"), "public")
+		assert.Empty(t, service.queued)
+	})
+
+	t.Run("public trip without a card", func(t *testing.T) {
+		h, service, _ := newTestHandler(t)
+
+		w, meta := getMetadata(h, "/og/trips/public")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "https://cdn.example.com/cover.jpg", meta.ImageURL)
+		assert.Empty(t, meta.ImageType)
+		assert.Contains(t, service.queued, "trip:public")
+	})
+
+	t.Run("public trip with an outdated card", func(t *testing.T) {
+		h, service, storage := newTestHandler(t)
+		path := entityImagePath("trip", "public")
+		_, err := storage.Save(path, []byte("png"))
+		require.NoError(t, err)
+		stale := time.Now().Add(-2 * time.Hour)
+		require.NoError(t, os.Chtimes(storage.GetFullPath(path), stale, stale))
+
+		_, meta := getMetadata(h, "/og/trips/public")
+
+		assert.Equal(t, "https://cdn.example.com/cover.jpg", meta.ImageURL)
+		assert.Contains(t, service.queued, "trip:public")
+	})
+
+	t.Run("private and missing trips are not found", func(t *testing.T) {
+		h, service, _ := newTestHandler(t)
+
+		for _, id := range []string{"private", "missing"} {
+			w, _ := getMetadata(h, "/og/trips/"+id)
+			assert.Equal(t, http.StatusNotFound, w.Code, id)
+		}
+		assert.Empty(t, service.queued)
+	})
+}
+
+func TestHandler_GetPlaceMetadata(t *testing.T) {
+	h, service, storage := newTestHandler(t)
+	_, err := storage.Save(entityImagePath("place", "summit"), []byte("png"))
+	require.NoError(t, err)
+
+	w, meta := getMetadata(h, "/og/places/summit")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, storage.GetURL("share/places/summit.png"), meta.ImageURL)
+	assert.Empty(t, service.queued)
+
+	w, _ = getMetadata(h, "/og/places/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
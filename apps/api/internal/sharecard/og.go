@@ -0,0 +1,291 @@
+package sharecard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/events"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+)
+
+const (
+	// JobEntityImage renders the share card for a trip or place
+	JobEntityImage = "sharecard.entity"
+
+	// renderDebounce keeps repeated unfurls from queueing the same render
+	renderDebounce = time.Minute
+
+	maxDescriptionLength = 200
+)
+
+var ErrNotPublic = errors.New("content is not public")
+
+// Metadata is the Open Graph description of a public trip or place
+type Metadata struct {
+	Type         string `json:"type"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	ImageURL     string `json:"image_url,omitempty"`
+	ImageType    string `json:"image_type,omitempty"`
+	ImageWidth   int    `json:"image_width,omitempty"`
+	ImageHeight  int    `json:"image_height,omitempty"`
+	CanonicalURL string `json:"canonical_url"`
+	SiteName     string `json:"site_name"`
+}
+
+type entityPayload struct {
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+}
+
+// TripMetadata returns Open Graph metadata for a public trip and makes sure
+// its share card is up to date
+func (s *Service) TripMetadata(ctx context.Context, tripID string) (*Metadata, error) {
+	trip, err := s.tripRepo.GetByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if trip.Privacy != "public" {
+		return nil, ErrNotPublic
+	}
+
+	meta := &Metadata{
+		Type:         "article",
+		Title:        trip.Title,
+		Description:  summarize(trip.Summary),
+		CanonicalURL: fmt.Sprintf("%s/trips/%s", s.webURL, trip.ID),
+		SiteName:     s.siteName,
+	}
+	// Trips not yet summarized are described by their own words
+	if meta.Description == "" {
+		meta.Description = summarize(trip.Description)
+	}
+	if meta.Description == "" {
+		meta.Description = tripSummary(trip)
+	}
+
+	s.attachCard(ctx, meta, "trip", trip.ID, trip.UpdatedAt, trip.CoverImage)
+	return meta, nil
+}
+
+// PlaceMetadata returns Open Graph metadata for a public place and makes
+// sure its share card is up to date
+func (s *Service) PlaceMetadata(ctx context.Context, placeID string) (*Metadata, error) {
+	place, err := s.placeRepo.GetByID(ctx, placeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if place.Privacy != "public" {
+		return nil, ErrNotPublic
+	}
+
+	meta := &Metadata{
+		Type:         "place",
+		Title:        place.Name,
+		Description:  summarize(place.Description),
+		CanonicalURL: fmt.Sprintf("%s/places/%s", s.webURL, place.ID),
+		SiteName:     s.siteName,
+	}
+	if meta.Description == "" {
+		meta.Description = placeLocality(place)
+	}
+
+	var fallback string
+	if len(place.Media) > 0 {
+		fallback = place.Media[0].URL
+	}
+
+	s.attachCard(ctx, meta, "place", place.ID, place.UpdatedAt, fallback)
+	return meta, nil
+}
+
+// attachCard points the metadata at the pre-rendered share card. When the
+// card is missing or older than the content, a render is queued and the
+// fallback image is used until it is ready.
+func (s *Service) attachCard(ctx context.Context, meta *Metadata, entityType, entityID string, updatedAt time.Time, fallback string) {
+	path := entityImagePath(entityType, entityID)
+
+	info, err := s.storage.Stat(path)
+	if err == nil && !info.ModTime.Before(updatedAt) {
+		meta.ImageURL = s.storage.GetURL(path)
+		meta.ImageType = MimeType
+		meta.ImageWidth = Width
+		meta.ImageHeight = Height
+		return
+	}
+
+	meta.ImageURL = fallback
+	s.queueEntityImage(ctx, entityType, entityID)
+}
+
+// HandleTripPublished pre-renders the share card of a newly public trip so
+// the first unfurl already has an image
+func (s *Service) HandleTripPublished(ctx context.Context, event events.Event) error {
+	s.queueEntityImage(ctx, "trip", event.EntityID)
+	return nil
+}
+
+// queueEntityImage queues a share card render unless one was queued recently
+func (s *Service) queueEntityImage(ctx context.Context, entityType, entityID string) {
+	key := entityType + ":" + entityID
+
+	s.mu.Lock()
+	if queuedAt, ok := s.queued[key]; ok && time.Since(queuedAt) < renderDebounce {
+		s.mu.Unlock()
+		return
+	}
+	for k, queuedAt := range s.queued {
+		if time.Since(queuedAt) >= renderDebounce {
+			delete(s.queued, k)
+		}
+	}
+	s.queued[key] = time.Now()
+	s.mu.Unlock()
+
+	payload := entityPayload{EntityType: entityType, EntityID: entityID}
+	if _, err := s.queue.Enqueue(ctx, JobEntityImage, payload); err != nil {
+		log.Printf("Failed to queue share card for %s %s: %v", entityType, entityID, err)
+	}
+}
+
+// renderEntityImage is the job handler for JobEntityImage
+func (s *Service) renderEntityImage(ctx context.Context, job *jobs.Job) error {
+	var payload entityPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	var card *Card
+	switch payload.EntityType {
+	case "trip":
+		trip, err := s.tripRepo.GetByID(ctx, payload.EntityID)
+		if err != nil {
+			return err
+		}
+		card = tripCard(trip)
+	case "place":
+		place, err := s.placeRepo.GetByID(ctx, payload.EntityID)
+		if err != nil {
+			return err
+		}
+		card = placeCard(place)
+	default:
+		return fmt.Errorf("unsupported share card entity %q", payload.EntityType)
+	}
+
+	image, err := Render(card)
+	if err != nil {
+		return err
+	}
+	path := entityImagePath(payload.EntityType, payload.EntityID)
+	if _, err := s.storage.Save(path, image); err != nil {
+		return fmt.Errorf("failed to store share image: %w", err)
+	}
+
+	return nil
+}
+
+// tripCard builds the card content for a trip
+func tripCard(trip *trips.Trip) *Card {
+	card := &Card{
+		Title:    trip.Title,
+		Subtitle: tripSummary(trip),
+	}
+
+	if trip.RouteGeoJSON != nil {
+		card.Route = Flatten(trip.RouteGeoJSON.Coordinates)
+	}
+	if trip.Verified {
+		card.Badge = "verified route"
+	} else if trip.Featured {
+		card.Badge = "featured"
+	}
+
+	if trip.DistanceKm != nil {
+		card.Stats = append(card.Stats, Stat{Label: "Distance", Value: fmt.Sprintf("%.1f km", *trip.DistanceKm)})
+	}
+	if trip.DurationHours != nil {
+		card.Stats = append(card.Stats, Stat{Label: "Time", Value: formatDuration(int(*trip.DurationHours * 60))})
+	}
+	if trip.ElevationGainM != nil {
+		card.Stats = append(card.Stats, Stat{Label: "Elevation gain", Value: fmt.Sprintf("%d m", *trip.ElevationGainM)})
+	}
+	if trip.AverageRating != nil && trip.RatingCount > 0 {
+		card.Stats = append(card.Stats, Stat{Label: "Rating", Value: fmt.Sprintf("%.1f / 5", *trip.AverageRating)})
+	}
+	if trip.CompletionCount > 0 {
+		card.Stats = append(card.Stats, Stat{Label: "Completions", Value: fmt.Sprintf("%d", trip.CompletionCount)})
+	}
+
+	return card
+}
+
+// placeCard builds the card content for a place
+func placeCard(place *places.Place) *Card {
+	card := &Card{
+		Title:    place.Name,
+		Subtitle: placeLocality(place),
+	}
+
+	if place.Bounds != nil {
+		for _, ring := range place.Bounds.Coordinates {
+			for _, position := range ring {
+				if len(position) >= 2 {
+					card.Route = append(card.Route, [2]float64{position[0], position[1]})
+				}
+			}
+		}
+	}
+	if len(place.Category) > 0 {
+		card.Badge = place.Category[0]
+	}
+
+	card.Stats = append(card.Stats, Stat{Label: "Type", Value: place.Type})
+	if place.AverageRating != nil && place.RatingCount > 0 {
+		card.Stats = append(card.Stats, Stat{Label: "Rating", Value: fmt.Sprintf("%.1f / 5", *place.AverageRating)})
+	}
+
+	return card
+}
+
+func tripSummary(trip *trips.Trip) string {
+	parts := []string{}
+	if trip.DifficultyLevel != "" {
+		parts = append(parts, trip.DifficultyLevel)
+	}
+	if trip.ActivityType != "" {
+		parts = append(parts, trip.ActivityType)
+	}
+	if trip.DistanceKm != nil {
+		parts = append(parts, fmt.Sprintf("%.1f km", *trip.DistanceKm))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func placeLocality(place *places.Place) string {
+	parts := []string{}
+	for _, part := range []string{place.City, place.State, place.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// summarize shortens a description to fit link previews
+func summarize(description string) string {
+	description = strings.Join(strings.Fields(description), " ")
+	return truncate(description, maxDescriptionLength)
+}
+
+func entityImagePath(entityType, entityID string) string {
+	return fmt.Sprintf("share/%ss/%s.png", entityType, entityID)
+}
@@ -0,0 +1,343 @@
+package sharecard
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// Card dimensions match the Open Graph image size most networks expect
+const (
+	Width  = 1200
+	Height = 630
+
+	routeBoxX    = 640
+	routeBoxY    = 90
+	routeBoxSize = 450
+
+	routeWidth = 8
+)
+
+// MimeType is the format cards are rendered in. Link unfurlers do not
+// render SVG, so cards are rasterized.
+const MimeType = "image/png"
+
+var (
+	backgroundFrom = color.RGBA{0x0f, 0x3d, 0x3e, 0xff}
+	backgroundTo   = color.RGBA{0x1b, 0x6b, 0x5a, 0xff}
+	white          = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	subtitleColor  = color.RGBA{0xc8, 0xe6, 0xd8, 0xff}
+	labelColor     = color.RGBA{0x9f, 0xd3, 0xbd, 0xff}
+	accent         = color.RGBA{0xff, 0xd1, 0x66, 0xff}
+	routeBoxColor  = color.NRGBA{0xff, 0xff, 0xff, 0x14}
+)
+
+// Stat is a labelled figure shown on the card
+type Stat struct {
+	Label string
+	Value string
+}
+
+// Card describes the content of a share card
+type Card struct {
+	Title    string
+	Subtitle string
+	Stats    []Stat
+	Badge    string
+	Route    [][2]float64 // longitude, latitude pairs
+}
+
+var (
+	fontsOnce   sync.Once
+	regularFont *opentype.Font
+	boldFont    *opentype.Font
+	fontsErr    error
+)
+
+// loadFonts parses the bundled Go fonts the cards are set in
+func loadFonts() error {
+	fontsOnce.Do(func() {
+		if regularFont, fontsErr = opentype.Parse(goregular.TTF); fontsErr != nil {
+			return
+		}
+		boldFont, fontsErr = opentype.Parse(gobold.TTF)
+	})
+	return fontsErr
+}
+
+// Render draws the card as a PNG image
+func Render(card *Card) ([]byte, error) {
+	if err := loadFonts(); err != nil {
+		return nil, fmt.Errorf("failed to load card fonts: %w", err)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	fillGradient(img)
+
+	// Title block
+	if err := drawText(img, boldFont, 56, white, 60, 130, truncate(card.Title, 28)); err != nil {
+		return nil, err
+	}
+	if card.Subtitle != "" {
+		if err := drawText(img, regularFont, 28, subtitleColor, 60, 180, truncate(card.Subtitle, 40)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Stats grid, two per row
+	for i, stat := range card.Stats {
+		if i >= 6 {
+			break
+		}
+		x := 60 + (i%2)*270
+		y := 270 + (i/2)*110
+		if err := drawText(img, boldFont, 44, white, x, y, stat.Value); err != nil {
+			return nil, err
+		}
+		if err := drawText(img, regularFont, 22, labelColor, x, y+32, strings.ToUpper(stat.Label)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Route outline
+	fillPolygon(img, roundedRect(routeBoxX-20, routeBoxY-20, routeBoxSize+40, routeBoxSize+40, 24), routeBoxColor)
+	for _, shape := range strokeRoute(projectRoute(card.Route), routeWidth) {
+		fillPolygon(img, shape, accent)
+	}
+
+	// Badge
+	if card.Badge != "" {
+		badge := strings.ToUpper(truncate(card.Badge, 24))
+		face, err := newFace(boldFont, 26)
+		if err != nil {
+			return nil, err
+		}
+		width := 40 + font.MeasureString(face, badge).Ceil()
+		fillPolygon(img, roundedRect(60, 520, float64(width), 56, 28), accent)
+		drawString(img, face, backgroundFrom, 80, 557, badge)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode card: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// fillGradient paints the diagonal background gradient
+func fillGradient(img *image.RGBA) {
+	lerp := func(from, to uint8, t float64) uint8 {
+		return uint8(math.Round(float64(from) + (float64(to)-float64(from))*t))
+	}
+	for y := 0; y < Height; y++ {
+		for x := 0; x < Width; x++ {
+			t := (float64(x)/Width + float64(y)/Height) / 2
+			img.SetRGBA(x, y, color.RGBA{
+				R: lerp(backgroundFrom.R, backgroundTo.R, t),
+				G: lerp(backgroundFrom.G, backgroundTo.G, t),
+				B: lerp(backgroundFrom.B, backgroundTo.B, t),
+				A: 0xff,
+			})
+		}
+	}
+}
+
+func newFace(f *opentype.Font, size float64) (font.Face, error) {
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load card font: %w", err)
+	}
+	return face, nil
+}
+
+// drawText draws a line of text with its baseline at y
+func drawText(img draw.Image, f *opentype.Font, size float64, c color.Color, x, y int, text string) error {
+	face, err := newFace(f, size)
+	if err != nil {
+		return err
+	}
+	drawString(img, face, c, x, y, text)
+	return nil
+}
+
+func drawString(img draw.Image, face font.Face, c color.Color, x, y int, text string) {
+	drawer := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	drawer.DrawString(text)
+}
+
+// fillPolygon fills a closed polygon, anti-aliased, over what is drawn
+func fillPolygon(img draw.Image, points [][2]float64, c color.Color) {
+	if len(points) < 3 {
+		return
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range points {
+		minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+	bounds := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY))).
+		Intersect(img.Bounds())
+	if bounds.Empty() {
+		return
+	}
+
+	// The rasterizer only covers the polygon's bounds
+	z := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+	offsetX, offsetY := float64(bounds.Min.X), float64(bounds.Min.Y)
+	z.MoveTo(float32(points[0][0]-offsetX), float32(points[0][1]-offsetY))
+	for _, p := range points[1:] {
+		z.LineTo(float32(p[0]-offsetX), float32(p[1]-offsetY))
+	}
+	z.ClosePath()
+	z.Draw(img, bounds, image.NewUniform(c), image.Point{})
+}
+
+// roundedRect outlines a rectangle with rounded corners
+func roundedRect(x, y, width, height, radius float64) [][2]float64 {
+	radius = math.Min(radius, math.Min(width, height)/2)
+	corners := [][3]float64{
+		{x + width - radius, y + radius, -math.Pi / 2},
+		{x + width - radius, y + height - radius, 0},
+		{x + radius, y + height - radius, math.Pi / 2},
+		{x + radius, y + radius, math.Pi},
+	}
+
+	var points [][2]float64
+	for _, corner := range corners {
+		points = append(points, arc(corner[0], corner[1], radius, corner[2], corner[2]+math.Pi/2, 8)...)
+	}
+	return points
+}
+
+// arc approximates a circular arc from one angle to another with segments
+func arc(cx, cy, radius, from, to float64, segments int) [][2]float64 {
+	points := make([][2]float64, 0, segments+1)
+	for i := 0; i <= segments; i++ {
+		angle := from + (to-from)*float64(i)/float64(segments)
+		points = append(points, [2]float64{cx + radius*math.Cos(angle), cy + radius*math.Sin(angle)})
+	}
+	return points
+}
+
+// strokeRoute outlines a polyline drawn with round caps and joins: a
+// rectangle along each segment and a disc on each point
+func strokeRoute(points [][2]float64, width float64) [][][2]float64 {
+	if len(points) < 2 {
+		return nil
+	}
+
+	half := width / 2
+	shapes := [][][2]float64{}
+	for i, p := range points {
+		shapes = append(shapes, arc(p[0], p[1], half, 0, 2*math.Pi, 16))
+		if i == 0 {
+			continue
+		}
+
+		prev := points[i-1]
+		dx, dy := p[0]-prev[0], p[1]-prev[1]
+		length := math.Hypot(dx, dy)
+		if length == 0 {
+			continue
+		}
+		nx, ny := -dy/length*half, dx/length*half
+		shapes = append(shapes, [][2]float64{
+			{prev[0] + nx, prev[1] + ny},
+			{p[0] + nx, p[1] + ny},
+			{p[0] - nx, p[1] - ny},
+			{prev[0] - nx, prev[1] - ny},
+		})
+	}
+	return shapes
+}
+
+// projectRoute scales the route into the route box, keeping its aspect
+// ratio. Points less than a pixel from the last one kept are dropped.
+func projectRoute(route [][2]float64) [][2]float64 {
+	if len(route) < 2 {
+		return nil
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	projected := make([][2]float64, len(route))
+	for i, p := range route {
+		// Equirectangular projection corrected for latitude is plenty at
+		// trail scale
+		x := p[0] * math.Cos(p[1]*math.Pi/180)
+		y := -p[1]
+		projected[i] = [2]float64{x, y}
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+
+	span := math.Max(maxX-minX, maxY-minY)
+	if span == 0 {
+		return nil
+	}
+	scale := routeBoxSize / span
+	offsetX := routeBoxX + (routeBoxSize-(maxX-minX)*scale)/2
+	offsetY := routeBoxY + (routeBoxSize-(maxY-minY)*scale)/2
+
+	points := make([][2]float64, 0, len(projected))
+	for _, p := range projected {
+		point := [2]float64{offsetX + (p[0]-minX)*scale, offsetY + (p[1]-minY)*scale}
+		if n := len(points); n > 0 && math.Hypot(point[0]-points[n-1][0], point[1]-points[n-1][1]) < 1 {
+			continue
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// Flatten collects the positions of any GeoJSON coordinate array, so lines,
+// multi-lines and polygons can all be drawn as an outline
+func Flatten(coordinates interface{}) [][2]float64 {
+	var points [][2]float64
+
+	values, ok := coordinates.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	if len(values) >= 2 {
+		lng, lngOK := values[0].(float64)
+		lat, latOK := values[1].(float64)
+		if lngOK && latOK {
+			return [][2]float64{{lng, lat}}
+		}
+	}
+
+	for _, v := range values {
+		points = append(points, Flatten(v)...)
+	}
+	return points
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
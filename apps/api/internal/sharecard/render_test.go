@@ -0,0 +1,88 @@
+package sharecard
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		name     string
+		geojson  string
+		expected int
+	}{
+		{
+			name:     "line string",
+			geojson:  `[[-122.4, 37.7], [-122.5, 37.8], [-122.6, 37.9]]`,
+			expected: 3,
+		},
+		{
+			name:     "polygon",
+			geojson:  `[[[0, 0], [1, 0], [1, 1], [0, 0]]]`,
+			expected: 4,
+		},
+		{
+			name:     "positions with elevation",
+			geojson:  `[[-122.4, 37.7, 120], [-122.5, 37.8, 140]]`,
+			expected: 2,
+		},
+		{
+			name:     "not an array",
+			geojson:  `"nope"`,
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var coordinates interface{}
+			assert.NoError(t, json.Unmarshal([]byte(tt.geojson), &coordinates))
+			assert.Len(t, Flatten(coordinates), tt.expected)
+		})
+	}
+}
+
+// routePixels counts the accent coloured pixels inside the route box
+func routePixels(t *testing.T, data []byte) int {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, Width, Height), img.Bounds())
+
+	count := 0
+	for y := routeBoxY; y < routeBoxY+routeBoxSize; y++ {
+		for x := routeBoxX; x < routeBoxX+routeBoxSize; x++ {
+			if color.RGBAModel.Convert(img.At(x, y)) == accent {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestRender(t *testing.T) {
+	data, err := Render(&Card{
+		Title:    "Dipsea <Trail>",
+		Subtitle: "Completed June 1, 2025",
+		Stats:    []Stat{{Label: "Distance", Value: "11.3 km"}},
+		Badge:    "hard hiking finisher",
+		Route:    [][2]float64{{-122.6, 37.9}, {-122.5, 37.8}},
+	})
+	require.NoError(t, err)
+
+	assert.Greater(t, routePixels(t, data), routeBoxSize*routeWidth/2)
+}
+
+func TestRenderWithoutRoute(t *testing.T) {
+	data, err := Render(&Card{Title: "Untracked"})
+	require.NoError(t, err)
+
+	assert.Zero(t, routePixels(t, data))
+}
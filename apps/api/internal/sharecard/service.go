@@ -0,0 +1,175 @@
+package sharecard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/config"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/places"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+	"github.com/Oferzz/newMap/apps/api/internal/media"
+)
+
+// JobCompletionImage renders the share card for a trip completion
+const JobCompletionImage = "sharecard.completion"
+
+var ErrUnauthorized = errors.New("unauthorized")
+
+// ShareImage is returned when a share card is requested
+type ShareImage struct {
+	CompletionID string `json:"completion_id"`
+	URL          string `json:"url"`
+	JobID        string `json:"job_id"`
+	Status       string `json:"status"`
+}
+
+type completionPayload struct {
+	CompletionID string `json:"completion_id"`
+}
+
+// Service renders share cards in the background and stores them in media storage
+type Service struct {
+	tripRepo  trips.Repository
+	placeRepo places.Repository
+	storage   media.Storage
+	queue     jobs.Queue
+	webURL    string
+	siteName  string
+
+	mu     sync.Mutex
+	queued map[string]time.Time
+}
+
+// NewService creates a new share card service and registers its job handlers
+func NewService(tripRepo trips.Repository, placeRepo places.Repository, storage media.Storage, queue jobs.Queue, cfg *config.AppConfig) *Service {
+	s := &Service{
+		tripRepo:  tripRepo,
+		placeRepo: placeRepo,
+		storage:   storage,
+		queue:     queue,
+		webURL:    strings.TrimSuffix(cfg.WebURL, "/"),
+		siteName:  cfg.Name,
+		queued:    make(map[string]time.Time),
+	}
+
+	queue.Register(JobCompletionImage, s.renderCompletionImage)
+	queue.Register(JobEntityImage, s.renderEntityImage)
+
+	return s
+}
+
+// RequestCompletionImage queues a render of the completion's share card. The
+// returned URL is stable and serves the image once the job has finished.
+func (s *Service) RequestCompletionImage(ctx context.Context, userID, completionID string) (*ShareImage, error) {
+	completion, err := s.tripRepo.GetCompletion(ctx, completionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the person who completed the activity can share it
+	if completion.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	job, err := s.queue.Enqueue(ctx, JobCompletionImage, completionPayload{CompletionID: completion.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue share image: %w", err)
+	}
+
+	return &ShareImage{
+		CompletionID: completion.ID,
+		URL:          s.storage.GetURL(completionImagePath(completion.ID)),
+		JobID:        job.ID,
+		Status:       "pending",
+	}, nil
+}
+
+// renderCompletionImage is the job handler for JobCompletionImage
+func (s *Service) renderCompletionImage(ctx context.Context, job *jobs.Job) error {
+	var payload completionPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	completion, err := s.tripRepo.GetCompletion(ctx, payload.CompletionID)
+	if err != nil {
+		return err
+	}
+
+	trip, err := s.tripRepo.GetByID(ctx, completion.TripID)
+	if err != nil {
+		return err
+	}
+
+	image, err := Render(completionCard(trip, completion))
+	if err != nil {
+		return err
+	}
+	if _, err := s.storage.Save(completionImagePath(completion.ID), image); err != nil {
+		return fmt.Errorf("failed to store share image: %w", err)
+	}
+
+	return nil
+}
+
+// completionCard builds the card content from a trip and one completion of it
+func completionCard(trip *trips.Trip, completion *trips.ActivityCompletion) *Card {
+	card := &Card{
+		Title:    trip.Title,
+		Subtitle: "Completed " + completion.CompletedAt.Format("January 2, 2006"),
+		Badge:    badgeFor(trip),
+	}
+
+	if trip.RouteGeoJSON != nil {
+		card.Route = Flatten(trip.RouteGeoJSON.Coordinates)
+	}
+
+	if trip.DistanceKm != nil {
+		card.Stats = append(card.Stats, Stat{Label: "Distance", Value: fmt.Sprintf("%.1f km", *trip.DistanceKm)})
+	}
+	if completion.DurationMinutes != nil {
+		card.Stats = append(card.Stats, Stat{Label: "Time", Value: formatDuration(*completion.DurationMinutes)})
+	} else if trip.DurationHours != nil {
+		card.Stats = append(card.Stats, Stat{Label: "Time", Value: formatDuration(int(*trip.DurationHours * 60))})
+	}
+	if trip.ElevationGainM != nil {
+		card.Stats = append(card.Stats, Stat{Label: "Elevation gain", Value: fmt.Sprintf("%d m", *trip.ElevationGainM)})
+	}
+	if trip.MaxElevationM != nil {
+		card.Stats = append(card.Stats, Stat{Label: "High point", Value: fmt.Sprintf("%d m", *trip.MaxElevationM)})
+	}
+	if completion.OverallRating != nil {
+		card.Stats = append(card.Stats, Stat{Label: "Rating", Value: fmt.Sprintf("%d / 5", *completion.OverallRating)})
+	}
+
+	return card
+}
+
+// badgeFor names the achievement shown on the card
+func badgeFor(trip *trips.Trip) string {
+	parts := []string{}
+	if trip.DifficultyLevel != "" {
+		parts = append(parts, trip.DifficultyLevel)
+	}
+	if trip.ActivityType != "" {
+		parts = append(parts, trip.ActivityType)
+	}
+	parts = append(parts, "finisher")
+	return strings.Join(parts, " ")
+}
+
+func formatDuration(minutes int) string {
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %02dm", minutes/60, minutes%60)
+}
+
+func completionImagePath(completionID string) string {
+	return fmt.Sprintf("share/completions/%s.png", completionID)
+}
@@ -0,0 +1,25 @@
+-- Places in Jerusalem, Tel Aviv and Eilat. Text columns the repository scans
+-- into plain strings are never NULL, as the service never writes NULL to them.
+-- Depends on: users
+INSERT INTO places (id, name, description, type, location, street_address, city, state, country, postal_code, created_by, category, tags, privacy, status) VALUES
+    ('10000000-0000-0000-0000-000000000001', 'Western Wall', 'Remnant of the Second Temple', 'poi',
+        ST_GeogFromText('SRID=4326;POINT(35.2342 31.7767)'), '', 'Jerusalem', '', 'Israel', '',
+        '00000000-0000-0000-0000-000000000001', ARRAY['landmark'], ARRAY['history', 'religion'], 'public', 'active'),
+    ('10000000-0000-0000-0000-000000000002', 'Church of the Holy Sepulchre', 'Church in the Christian Quarter', 'poi',
+        ST_GeogFromText('SRID=4326;POINT(35.2296 31.7785)'), '', 'Jerusalem', '', 'Israel', '',
+        '00000000-0000-0000-0000-000000000001', ARRAY['landmark'], ARRAY['history', 'religion'], 'public', 'active'),
+    ('10000000-0000-0000-0000-000000000003', 'Mahane Yehuda Market', 'Covered market with food stalls', 'poi',
+        ST_GeogFromText('SRID=4326;POINT(35.2119 31.7854)'), '', 'Jerusalem', '', 'Israel', '',
+        '00000000-0000-0000-0000-000000000002', ARRAY['food'], ARRAY['market', 'food'], 'public', 'active'),
+    ('10000000-0000-0000-0000-000000000004', 'Old City Cafe', 'Closed for good', 'poi',
+        ST_GeogFromText('SRID=4326;POINT(35.2335 31.7760)'), '', 'Jerusalem', '', 'Israel', '',
+        '00000000-0000-0000-0000-000000000002', ARRAY['food'], ARRAY['coffee'], 'public', 'archived'),
+    ('10000000-0000-0000-0000-000000000005', 'Jaffa Port', 'Ancient port south of Tel Aviv', 'poi',
+        ST_GeogFromText('SRID=4326;POINT(34.7503 32.0543)'), '', 'Tel Aviv', '', 'Israel', '',
+        '00000000-0000-0000-0000-000000000001', ARRAY['landmark'], ARRAY['history', 'sea'], 'public', 'active'),
+    ('10000000-0000-0000-0000-000000000006', 'Carmel Market', 'Open-air market', 'poi',
+        ST_GeogFromText('SRID=4326;POINT(34.7687 32.0684)'), '', 'Tel Aviv', '', 'Israel', '',
+        '00000000-0000-0000-0000-000000000002', ARRAY['food'], ARRAY['market', 'food'], 'public', 'active'),
+    ('10000000-0000-0000-0000-000000000007', 'Coral Beach', 'Reef and snorkeling beach', 'area',
+        ST_GeogFromText('SRID=4326;POINT(34.9196 29.5104)'), '', 'Eilat', '', 'Israel', '',
+        '00000000-0000-0000-0000-000000000001', ARRAY['nature'], ARRAY['beach', 'diving'], 'public', 'active');
@@ -0,0 +1,27 @@
+-- Trips with routes in Jerusalem, Tel Aviv and Eilat. Text columns the
+-- repository scans into plain strings are never NULL, as the service never
+-- writes NULL to them.
+-- Depends on: users
+INSERT INTO trips (id, title, description, owner_id, cover_image, privacy, status, timezone, tags,
+        activity_type, difficulty_level, route_type, trail_conditions, accessibility_notes, visibility,
+        route_geojson, deleted_at) VALUES
+    ('20000000-0000-0000-0000-000000000001', 'Old City Walk', '', '00000000-0000-0000-0000-000000000001', '',
+        'public', 'planning', 'Asia/Jerusalem', ARRAY['walking', 'history'],
+        'hiking', 'easy', 'point_to_point', '', '', 'public',
+        '{"type": "LineString", "coordinates": [[35.2342, 31.7767], [35.2296, 31.7785]]}', NULL),
+    ('20000000-0000-0000-0000-000000000002', 'Tel Aviv Beach Ride', '', '00000000-0000-0000-0000-000000000001', '',
+        'public', 'planning', 'Asia/Jerusalem', ARRAY['cycling', 'beach'],
+        'biking', 'easy', 'point_to_point', '', '', 'public',
+        '{"type": "LineString", "coordinates": [[34.7503, 32.0543], [34.7630, 32.0800]]}', NULL),
+    ('20000000-0000-0000-0000-000000000003', 'Red Sea Snorkel', '', '00000000-0000-0000-0000-000000000002', '',
+        'public', 'planning', 'Asia/Jerusalem', ARRAY['beach', 'diving'],
+        'swimming', 'moderate', 'area', '', '', 'public',
+        '{"type": "Point", "coordinates": [34.9196, 29.5104]}', NULL),
+    ('20000000-0000-0000-0000-000000000004', 'Ramparts Walk', '', '00000000-0000-0000-0000-000000000002', '',
+        'public', 'planning', 'Asia/Jerusalem', ARRAY['walking', 'history'],
+        'hiking', 'easy', 'loop', '', '', 'public',
+        '{"type": "LineString", "coordinates": [[35.2300, 31.7810], [35.2370, 31.7790]]}', now()),
+    ('20000000-0000-0000-0000-000000000005', 'Museum Day', '', '00000000-0000-0000-0000-000000000002', '',
+        'public', 'planning', 'Asia/Jerusalem', ARRAY['history'],
+        'general', '', '', '', '', 'public',
+        NULL, NULL);
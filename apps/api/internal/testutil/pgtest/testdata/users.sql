@@ -0,0 +1,4 @@
+-- Owners for the places and trips datasets
+INSERT INTO users (id, email, username, password_hash, display_name) VALUES
+    ('00000000-0000-0000-0000-000000000001', 'dana@example.com', 'dana', 'x', 'Dana'),
+    ('00000000-0000-0000-0000-000000000002', 'omer@example.com', 'omer', 'x', 'Omer');
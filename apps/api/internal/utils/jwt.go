@@ -11,14 +11,30 @@ import (
 var (
 	ErrInvalidToken = errors.New("invalid token")
 	ErrExpiredToken = errors.New("token has expired")
+	ErrScopedToken  = errors.New("scoped tokens cannot be refreshed")
 )
 
 type TokenClaims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
+
+	// Scope and TripID are set on tokens minted from a share link, which
+	// belong to no user and grant access to a single trip
+	Scope  string `json:"scope,omitempty"`
+	TripID string `json:"trip_id,omitempty"`
+
+	// SessionID names the signed-in device the token was issued to, so the
+	// token stops working when that session ends
+	SessionID string `json:"sid,omitempty"`
+
 	jwt.RegisteredClaims
 }
 
+// IsScoped reports whether the token was minted from a share link
+func (c *TokenClaims) IsScoped() bool {
+	return c.Scope != ""
+}
+
 type JWTManager struct {
 	config *config.JWTConfig
 }
@@ -67,6 +83,40 @@ func (j *JWTManager) GenerateTokenPair(userID string, email string) (accessToken
 	return accessToken, refreshToken, nil
 }
 
+// GenerateAccessToken mints an access token for one of the user's sessions.
+// Refresh tokens for sessions are opaque and kept by the users service.
+func (j *JWTManager) GenerateAccessToken(userID, email, sessionID string) (string, error) {
+	claims := TokenClaims{
+		UserID:    userID,
+		Email:     email,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.config.AccessExpiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    j.config.Issuer,
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(j.config.Secret))
+}
+
+// GenerateScopedToken mints a short-lived token granting the scope on one trip
+// to whoever holds it. It has no refresh token; the share link is redeemed
+// again instead.
+func (j *JWTManager) GenerateScopedToken(tripID, scope string) (string, error) {
+	claims := TokenClaims{
+		Scope:  scope,
+		TripID: tripID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.config.ShareExpiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    j.config.Issuer,
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(j.config.Secret))
+}
+
 func (j *JWTManager) ValidateToken(tokenString string) (*TokenClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -100,6 +150,9 @@ func (j *JWTManager) RefreshAccessToken(refreshToken string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if claims.IsScoped() {
+		return "", ErrScopedToken
+	}
 	
 	// Generate new access token
 	accessClaims := TokenClaims{
@@ -121,6 +174,11 @@ func (j *JWTManager) GetAccessTokenExpiry() time.Duration {
 	return j.config.AccessExpiry
 }
 
+// GetShareTokenExpiry returns the scoped token expiry duration
+func (j *JWTManager) GetShareTokenExpiry() time.Duration {
+	return j.config.ShareExpiry
+}
+
 // GetRefreshTokenExpiry returns the refresh token expiry duration
 func (j *JWTManager) GetRefreshTokenExpiry() time.Duration {
 	return j.config.RefreshExpiry
@@ -131,4 +131,72 @@ func TestJWTManager_ExpiredToken(t *testing.T) {
 	if err != ErrExpiredToken {
 		t.Errorf("Expected ErrExpiredToken, got %v", err)
 	}
-}
\ No newline at end of file
+}
+func TestJWTManager_ScopedToken(t *testing.T) {
+	cfg := &config.JWTConfig{
+		Secret:        "test-secret-key",
+		AccessExpiry:  15 * time.Minute,
+		RefreshExpiry: 7 * 24 * time.Hour,
+		ShareExpiry:   time.Hour,
+		Issuer:        "test-issuer",
+	}
+
+	jwtManager := NewJWTManager(cfg)
+	tripID := "5f0c6a2e-8d1b-4c3a-9e7f-1a2b3c4d5e6f"
+
+	token, err := jwtManager.GenerateScopedToken(tripID, "trip:read")
+	if err != nil {
+		t.Fatalf("Failed to generate scoped token: %v", err)
+	}
+
+	claims, err := jwtManager.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate scoped token: %v", err)
+	}
+
+	if !claims.IsScoped() {
+		t.Error("Expected token to be scoped")
+	}
+
+	if claims.UserID != "" {
+		t.Errorf("Expected no user ID, got %s", claims.UserID)
+	}
+
+	if claims.TripID != tripID || claims.Scope != "trip:read" {
+		t.Errorf("Expected trip:read on %s, got %s on %s", tripID, claims.Scope, claims.TripID)
+	}
+
+	// A scoped token must not turn into a full access token
+	if _, err := jwtManager.RefreshAccessToken(token); err != ErrScopedToken {
+		t.Errorf("Expected ErrScopedToken, got %v", err)
+	}
+}
+
+func TestJWTManager_SessionAccessToken(t *testing.T) {
+	cfg := &config.JWTConfig{
+		Secret:       "test-secret-key",
+		AccessExpiry: 15 * time.Minute,
+		Issuer:       "test-issuer",
+	}
+
+	jwtManager := NewJWTManager(cfg)
+	sessionID := "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+
+	token, err := jwtManager.GenerateAccessToken("user-1", "test@example.com", sessionID)
+	if err != nil {
+		t.Fatalf("Failed to generate access token: %v", err)
+	}
+
+	claims, err := jwtManager.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate access token: %v", err)
+	}
+
+	if claims.SessionID != sessionID {
+		t.Errorf("Expected session %s, got %s", sessionID, claims.SessionID)
+	}
+
+	if claims.UserID != "user-1" || claims.IsScoped() {
+		t.Errorf("Expected an unscoped token for user-1, got %+v", claims)
+	}
+}
@@ -0,0 +1,131 @@
+// Package warmup fills the cache with what visitors ask for most, so the
+// first of them after a deployment or a cache flush are not the ones who wait
+// for the database.
+package warmup
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/cache"
+	"github.com/Oferzz/newMap/apps/api/internal/discovery"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+)
+
+// JobWarm fills the cache with the first pages of the discover lists and the
+// featured trips
+const JobWarm = "cache.warm"
+
+const (
+	// checkInterval is how often the cache is checked for having been
+	// flushed
+	checkInterval = time.Minute
+
+	// pageSize is the page of each list that is warmed, the one the discover
+	// endpoints serve by default
+	pageSize = 20
+)
+
+// DiscoverLister serves the discover lists, caching the pages it reads
+type DiscoverLister interface {
+	List(ctx context.Context, list string, filters discovery.ListFilters) ([]*discovery.TripCard, error)
+}
+
+// TripLoader loads trips, caching those it reads
+type TripLoader interface {
+	GetByID(ctx context.Context, userID, tripID string) (*trips.Trip, error)
+}
+
+// Warmer warms the cache once at startup, and again whenever the marker it
+// leaves is gone: after a flush, once the warmed pages expired, or when the
+// discover lists were refreshed.
+type Warmer struct {
+	cache    cache.Cache
+	queue    jobs.Queue
+	discover DiscoverLister
+	trips    TripLoader
+}
+
+// NewWarmer creates a warmer and registers its job handler
+func NewWarmer(c cache.Cache, queue jobs.Queue, discover DiscoverLister, tripLoader TripLoader) *Warmer {
+	w := &Warmer{
+		cache:    c,
+		queue:    queue,
+		discover: discover,
+		trips:    tripLoader,
+	}
+
+	queue.Register(JobWarm, w.run)
+
+	return w
+}
+
+// Start queues a warm-up now and checks every checkInterval whether another
+// is needed, until ctx is cancelled
+func (w *Warmer) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		w.enqueue(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			warm, err := w.cache.IsWarm(ctx)
+			if err != nil {
+				log.Printf("warmup: failed to check the cache: %v", err)
+				continue
+			}
+			if !warm {
+				w.enqueue(ctx)
+			}
+		}
+	}()
+}
+
+func (w *Warmer) enqueue(ctx context.Context) {
+	if _, err := w.queue.Enqueue(ctx, JobWarm, nil); err != nil {
+		log.Printf("warmup: failed to queue warm-up: %v", err)
+	}
+}
+
+// run is the job handler for JobWarm. The marker is set first, so other
+// instances that check meanwhile do not warm the cache as well, and lasts as
+// long as the pages it stands for.
+func (w *Warmer) run(ctx context.Context, job *jobs.Job) error {
+	if err := w.cache.MarkWarm(ctx, discovery.ListCacheTTL); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	lists, warmedTrips := 0, 0
+	for _, list := range discovery.Lists {
+		cards, err := w.discover.List(ctx, list, discovery.ListFilters{Limit: pageSize})
+		if err != nil {
+			log.Printf("warmup: failed to warm the %s list: %v", list, err)
+			continue
+		}
+		lists++
+
+		if list != discovery.ListFeatured {
+			continue
+		}
+		// Featured trips are the ones most visitors open
+		for _, card := range cards {
+			if _, err := w.trips.GetByID(ctx, "", card.TripID); err != nil {
+				log.Printf("warmup: failed to warm trip %s: %v", card.TripID, err)
+				continue
+			}
+			warmedTrips++
+		}
+	}
+
+	log.Printf("warmup: warmed %d lists and %d featured trips in %s", lists, warmedTrips, time.Since(start).Round(time.Millisecond))
+	return nil
+}
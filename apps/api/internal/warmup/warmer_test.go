@@ -0,0 +1,72 @@
+package warmup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Oferzz/newMap/apps/api/internal/cache"
+	"github.com/Oferzz/newMap/apps/api/internal/discovery"
+	"github.com/Oferzz/newMap/apps/api/internal/domain/trips"
+	"github.com/Oferzz/newMap/apps/api/internal/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type markingCache struct {
+	cache.Cache
+	marked time.Duration
+}
+
+func (c *markingCache) MarkWarm(ctx context.Context, ttl time.Duration) error {
+	c.marked = ttl
+	return nil
+}
+
+type fakeLister struct {
+	lists   []string
+	filters []discovery.ListFilters
+}
+
+func (l *fakeLister) List(ctx context.Context, list string, filters discovery.ListFilters) ([]*discovery.TripCard, error) {
+	l.lists = append(l.lists, list)
+	l.filters = append(l.filters, filters)
+	switch list {
+	case discovery.ListFeatured:
+		return []*discovery.TripCard{{TripID: "trip-1"}, {TripID: "trip-2"}}, nil
+	case discovery.ListQuiet:
+		return nil, errors.New("database unavailable")
+	}
+	return []*discovery.TripCard{{TripID: "trip-9"}}, nil
+}
+
+type fakeLoader struct {
+	loaded []string
+}
+
+func (l *fakeLoader) GetByID(ctx context.Context, userID, tripID string) (*trips.Trip, error) {
+	if userID != "" {
+		return nil, errors.New("warmed as a user")
+	}
+	l.loaded = append(l.loaded, tripID)
+	return &trips.Trip{ID: tripID}, nil
+}
+
+func TestWarmer_Run(t *testing.T) {
+	c := &markingCache{Cache: cache.NewNoOpCache()}
+	lister := &fakeLister{}
+	loader := &fakeLoader{}
+	w := NewWarmer(c, jobs.NewLocalQueue(), lister, loader)
+
+	// A list that fails to load does not stop the others
+	require.NoError(t, w.run(context.Background(), &jobs.Job{Type: JobWarm}))
+	assert.Equal(t, discovery.Lists, lister.lists)
+	assert.Equal(t, discovery.ListFilters{Limit: pageSize}, lister.filters[0])
+
+	// Only featured trips are opened, as a guest would
+	assert.Equal(t, []string{"trip-1", "trip-2"}, loader.loaded)
+
+	// The marker lasts as long as the warmed pages
+	assert.Equal(t, discovery.ListCacheTTL, c.marked)
+}
@@ -0,0 +1,30 @@
+-- Create ownership_transfers table for pending owner hand-offs
+CREATE TABLE IF NOT EXISTS ownership_transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entity_type VARCHAR(20) NOT NULL CHECK (entity_type IN ('trip', 'place')),
+    entity_id UUID NOT NULL,
+    from_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined')),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMPTZ
+);
+
+-- Only one pending transfer per entity
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ownership_transfers_pending
+    ON ownership_transfers(entity_type, entity_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_ownership_transfers_to_user ON ownership_transfers(to_user_id);
+
+-- Create audit_log table
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(100) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id UUID NOT NULL,
+    details JSONB,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id);
@@ -0,0 +1,6 @@
+ALTER TABLE trip_collaborators DROP COLUMN IF EXISTS invited_via_group;
+
+DROP TABLE IF EXISTS group_invitations;
+DROP TABLE IF EXISTS group_trips;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_trips_publish_at;
+
+ALTER TABLE trips DROP COLUMN IF EXISTS publish_job_id;
+ALTER TABLE trips DROP COLUMN IF EXISTS publish_at;
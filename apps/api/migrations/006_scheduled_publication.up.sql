@@ -0,0 +1,5 @@
+-- Scheduled publication of private trips
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ;
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS publish_job_id VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_trips_publish_at ON trips(publish_at) WHERE publish_at IS NOT NULL;
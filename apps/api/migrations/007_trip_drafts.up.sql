@@ -0,0 +1,12 @@
+-- Per-user autosaved drafts of trip edits
+CREATE TABLE IF NOT EXISTS trip_drafts (
+    trip_id UUID NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    data JSONB NOT NULL DEFAULT '{}',
+    base JSONB NOT NULL DEFAULT '{}', -- trip values the draft started from
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (trip_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_drafts_user ON trip_drafts(user_id);
@@ -0,0 +1,35 @@
+-- Pre-aggregated cards for public trips, served by the /discover endpoints.
+-- Rows are refreshed incrementally by the discovery.refresh job.
+CREATE TABLE IF NOT EXISTS trip_discovery (
+    trip_id UUID PRIMARY KEY REFERENCES trips(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    cover_image TEXT,
+    owner_id UUID NOT NULL,
+    owner_username VARCHAR(100),
+    owner_display_name VARCHAR(255),
+    owner_avatar_url TEXT,
+    activity_type VARCHAR(50),
+    difficulty_level VARCHAR(20),
+    duration_hours DECIMAL(5,2),
+    distance_km DECIMAL(8,2),
+    elevation_gain_m INTEGER,
+    tags TEXT[],
+    featured BOOLEAN DEFAULT false,
+    verified BOOLEAN DEFAULT false,
+    view_count INTEGER DEFAULT 0,
+    completion_count INTEGER DEFAULT 0,
+    recent_completion_count INTEGER DEFAULT 0, -- last 30 days
+    rating_count INTEGER DEFAULT 0,
+    average_rating DECIMAL(3,2),
+    trending_score DOUBLE PRECISION DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL,
+    refreshed_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_discovery_trending ON trip_discovery(trending_score DESC, trip_id);
+CREATE INDEX IF NOT EXISTS idx_trip_discovery_popular ON trip_discovery(completion_count DESC, trip_id);
+CREATE INDEX IF NOT EXISTS idx_trip_discovery_rating ON trip_discovery(average_rating DESC NULLS LAST, trip_id);
+CREATE INDEX IF NOT EXISTS idx_trip_discovery_recent ON trip_discovery(created_at DESC, trip_id);
+CREATE INDEX IF NOT EXISTS idx_trip_discovery_activity ON trip_discovery(activity_type);
+CREATE INDEX IF NOT EXISTS idx_trip_discovery_refreshed ON trip_discovery(refreshed_at);
@@ -0,0 +1,20 @@
+-- Trips and places pinned to the top of search results by admins. A pin
+-- applies to a query, a region the query is located in, or both.
+CREATE TABLE IF NOT EXISTS search_pins (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entity_type VARCHAR(20) NOT NULL CHECK (entity_type IN ('trip', 'place')),
+    entity_id UUID NOT NULL,
+    query TEXT, -- normalized: lower case, single spaces
+    region GEOGRAPHY(POLYGON, 4326),
+    label VARCHAR(20) NOT NULL DEFAULT 'curated' CHECK (label IN ('curated', 'sponsored')),
+    position INTEGER NOT NULL DEFAULT 0,
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CHECK (query IS NOT NULL OR region IS NOT NULL),
+    CHECK (ends_at IS NULL OR starts_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_pins_query ON search_pins(query) WHERE query IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_search_pins_region ON search_pins USING GIST(region) WHERE region IS NOT NULL;
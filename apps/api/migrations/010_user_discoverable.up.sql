@@ -0,0 +1,5 @@
+-- Whether the user can be found through user search. Existing accounts stay
+-- findable, as they were before the setting existed.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS discoverable BOOLEAN NOT NULL DEFAULT true;
+
+CREATE INDEX IF NOT EXISTS idx_users_discoverable ON users(username) WHERE discoverable AND status = 'active';
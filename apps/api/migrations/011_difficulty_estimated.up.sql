@@ -0,0 +1,3 @@
+-- Whether difficulty_level was estimated from the route rather than set by
+-- the creator
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS difficulty_estimated BOOLEAN NOT NULL DEFAULT false;
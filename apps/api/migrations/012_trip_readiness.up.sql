@@ -0,0 +1,9 @@
+-- Readiness checks confirmed by a trip member, for what the trip itself
+-- cannot show, such as permits having been obtained
+CREATE TABLE IF NOT EXISTS trip_readiness_checks (
+    trip_id UUID NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    check_name VARCHAR(50) NOT NULL,
+    checked_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (trip_id, check_name)
+);
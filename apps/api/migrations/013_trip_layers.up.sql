@@ -0,0 +1,15 @@
+-- Custom overlay layers drawn over a trip's map, with the files themselves in
+-- media storage
+CREATE TABLE IF NOT EXISTS trip_layers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    trip_id UUID NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    format VARCHAR(20) NOT NULL, -- 'geojson', 'kml'
+    storage_path TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    feature_count INTEGER NOT NULL DEFAULT 0,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_layers_trip ON trip_layers(trip_id);
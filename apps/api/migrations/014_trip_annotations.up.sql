@@ -0,0 +1,16 @@
+-- Planning marks drawn over a trip's map: measurements, bearing lines, text
+-- labels and areas
+CREATE TABLE IF NOT EXISTS trip_annotations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    trip_id UUID NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL, -- 'measurement', 'bearing', 'label', 'area'
+    geometry JSONB NOT NULL,
+    label TEXT NOT NULL DEFAULT '',
+    style JSONB,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_annotations_trip ON trip_annotations(trip_id);
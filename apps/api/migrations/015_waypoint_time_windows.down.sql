@@ -0,0 +1,3 @@
+ALTER TABLE trip_waypoints DROP COLUMN IF EXISTS window_label;
+ALTER TABLE trip_waypoints DROP COLUMN IF EXISTS window_closes_at;
+ALTER TABLE trip_waypoints DROP COLUMN IF EXISTS window_opens_at;
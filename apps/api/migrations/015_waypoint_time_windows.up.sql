@@ -0,0 +1,5 @@
+-- Times a waypoint must be reached within, such as a ferry departure or a
+-- hut check-in
+ALTER TABLE trip_waypoints ADD COLUMN IF NOT EXISTS window_opens_at TIMESTAMPTZ;
+ALTER TABLE trip_waypoints ADD COLUMN IF NOT EXISTS window_closes_at TIMESTAMPTZ;
+ALTER TABLE trip_waypoints ADD COLUMN IF NOT EXISTS window_label VARCHAR(100);
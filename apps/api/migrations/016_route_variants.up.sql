@@ -0,0 +1,19 @@
+-- Named alternatives to a trip's route, such as a winter route or a
+-- bad-weather bailout. The primary variant is copied onto the trip.
+CREATE TABLE IF NOT EXISTS trip_route_variants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    trip_id UUID NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    route_geojson JSONB NOT NULL,
+    distance_km DECIMAL(8,2),
+    elevation_gain_m INTEGER,
+    duration_hours DECIMAL(5,2),
+    is_primary BOOLEAN NOT NULL DEFAULT false,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(trip_id, name)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_route_variants_primary ON trip_route_variants(trip_id) WHERE is_primary;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_places_category;
+
+ALTER TABLE trip_waypoints DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,6 @@
+-- Bail-out waypoints are places the route can be left at in an emergency.
+-- They are kept with the trip's waypoints but are not stops on the route.
+ALTER TABLE trip_waypoints ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'stop'
+    CHECK (kind IN ('stop', 'bailout'));
+
+CREATE INDEX IF NOT EXISTS idx_places_category ON places USING GIN(category);
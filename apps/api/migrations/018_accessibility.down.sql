@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_places_accessibility;
+DROP INDEX IF EXISTS idx_trips_accessibility;
+
+ALTER TABLE places DROP COLUMN IF EXISTS accessibility;
+ALTER TABLE trips DROP COLUMN IF EXISTS accessibility;
@@ -0,0 +1,9 @@
+-- Structured accessibility attributes, alongside the free text
+-- accessibility notes of trips
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS accessibility TEXT[] NOT NULL DEFAULT '{}'
+    CHECK (accessibility <@ ARRAY['dog_friendly', 'stroller_ok', 'wheelchair_accessible', 'toddler_ok']);
+ALTER TABLE places ADD COLUMN IF NOT EXISTS accessibility TEXT[] NOT NULL DEFAULT '{}'
+    CHECK (accessibility <@ ARRAY['dog_friendly', 'stroller_ok', 'wheelchair_accessible', 'toddler_ok']);
+
+CREATE INDEX IF NOT EXISTS idx_trips_accessibility ON trips USING gin(accessibility);
+CREATE INDEX IF NOT EXISTS idx_places_accessibility ON places USING gin(accessibility);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_conditions_trip_created;
+DROP INDEX IF EXISTS idx_trip_discovery_quiet;
+
+ALTER TABLE trip_discovery DROP COLUMN IF EXISTS crowd_score;
@@ -0,0 +1,6 @@
+-- Visits per week over the last 90 days, counting completions and condition
+-- reports. Backs the "quiet" discover list.
+ALTER TABLE trip_discovery ADD COLUMN IF NOT EXISTS crowd_score DOUBLE PRECISION DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_trip_discovery_quiet ON trip_discovery(crowd_score, trip_id);
+CREATE INDEX IF NOT EXISTS idx_conditions_trip_created ON activity_conditions(trip_id, created_at);
@@ -0,0 +1,15 @@
+-- Water sources are places in the 'water_source' category. These details say
+-- how far each can be counted on.
+CREATE TABLE IF NOT EXISTS place_water_sources (
+    place_id UUID PRIMARY KEY REFERENCES places(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL
+        CHECK (kind IN ('spring', 'stream', 'lake', 'tap', 'tank', 'well', 'cistern')),
+    reliability VARCHAR(20) NOT NULL DEFAULT 'unknown'
+        CHECK (reliability IN ('reliable', 'seasonal', 'unreliable', 'unknown')),
+    flowing_months SMALLINT[] NOT NULL DEFAULT '{}' -- months 1-12 a seasonal source usually flows
+        CHECK (flowing_months <@ ARRAY[1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12]::SMALLINT[]),
+    treatment_required BOOLEAN NOT NULL DEFAULT true,
+    notes TEXT,
+    last_verified_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
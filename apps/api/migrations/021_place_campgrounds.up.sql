@@ -0,0 +1,8 @@
+-- Campground places linked to the reservation system they are booked
+-- through, so their availability can be checked
+CREATE TABLE IF NOT EXISTS place_campgrounds (
+    place_id UUID PRIMARY KEY REFERENCES places(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    facility_id VARCHAR(100) NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
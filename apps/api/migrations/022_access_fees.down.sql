@@ -0,0 +1,2 @@
+ALTER TABLE places DROP COLUMN IF EXISTS access_fees;
+ALTER TABLE trips DROP COLUMN IF EXISTS access_fees;
@@ -0,0 +1,6 @@
+-- Fees and passes needed to get to or use a trip or place, such as parking
+-- passes, park entry fees and shuttle tickets, as a JSON list
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS access_fees JSONB NOT NULL DEFAULT '[]'
+    CHECK (jsonb_typeof(access_fees) = 'array');
+ALTER TABLE places ADD COLUMN IF NOT EXISTS access_fees JSONB NOT NULL DEFAULT '[]'
+    CHECK (jsonb_typeof(access_fees) = 'array');
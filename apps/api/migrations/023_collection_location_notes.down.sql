@@ -0,0 +1 @@
+ALTER TABLE collection_locations DROP COLUMN IF EXISTS notes;
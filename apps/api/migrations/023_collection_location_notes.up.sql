@@ -0,0 +1,3 @@
+-- Notes on a saved location, carried over to the waypoint when the
+-- collection is turned into a trip
+ALTER TABLE collection_locations ADD COLUMN IF NOT EXISTS notes TEXT;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS trip_date_poll_votes;
+ALTER TABLE IF EXISTS trip_date_polls DROP CONSTRAINT IF EXISTS trip_date_polls_chosen_option_fkey;
+DROP TABLE IF EXISTS trip_date_poll_options;
+DROP TABLE IF EXISTS trip_date_polls;
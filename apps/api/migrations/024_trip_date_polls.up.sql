@@ -0,0 +1,38 @@
+-- Polls for agreeing when a group trip goes: the owner proposes date ranges,
+-- members say whether they can make each one, and finalizing a poll sets the
+-- trip's dates to the chosen range
+CREATE TABLE IF NOT EXISTS trip_date_polls (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    trip_id UUID NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    title VARCHAR(200) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- 'open', 'finalized'
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    chosen_option_id UUID,
+    finalized_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_date_polls_trip ON trip_date_polls(trip_id);
+
+CREATE TABLE IF NOT EXISTS trip_date_poll_options (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    poll_id UUID NOT NULL REFERENCES trip_date_polls(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    CHECK (end_date >= start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_date_poll_options_poll ON trip_date_poll_options(poll_id);
+
+ALTER TABLE trip_date_polls ADD CONSTRAINT trip_date_polls_chosen_option_fkey
+    FOREIGN KEY (chosen_option_id) REFERENCES trip_date_poll_options(id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS trip_date_poll_votes (
+    option_id UUID NOT NULL REFERENCES trip_date_poll_options(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    availability VARCHAR(20) NOT NULL, -- 'available', 'maybe', 'unavailable'
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (option_id, user_id)
+);
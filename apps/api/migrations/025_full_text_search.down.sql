@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_places_search_vector;
+DROP INDEX IF EXISTS idx_trips_search_vector;
+
+ALTER TABLE places DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE trips DROP COLUMN IF EXISTS search_vector;
+
+CREATE INDEX IF NOT EXISTS idx_trips_search ON trips USING gin(to_tsvector('english', title || ' ' || COALESCE(description, '')));
+CREATE INDEX IF NOT EXISTS idx_places_search ON places USING gin(to_tsvector('english', name || ' ' || COALESCE(description, '')));
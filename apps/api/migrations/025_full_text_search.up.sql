@@ -0,0 +1,21 @@
+-- Search vectors for full-text search, kept up to date by Postgres. Titles
+-- and names weigh most, then descriptions, then where a place is.
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+    ) STORED;
+
+ALTER TABLE places ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(name, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(description, '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(city, '') || ' ' || COALESCE(state, '') || ' ' || COALESCE(country, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_trips_search_vector ON trips USING GIN(search_vector);
+CREATE INDEX IF NOT EXISTS idx_places_search_vector ON places USING GIN(search_vector);
+
+-- Superseded by the indexes on the search vectors
+DROP INDEX IF EXISTS idx_trips_search;
+DROP INDEX IF EXISTS idx_places_search;
@@ -0,0 +1,3 @@
+-- Visibility goes back to a column of its own, starting out as it was derived
+ALTER TABLE trips ALTER COLUMN visibility DROP EXPRESSION;
+ALTER TABLE trips ALTER COLUMN visibility SET DEFAULT 'private';
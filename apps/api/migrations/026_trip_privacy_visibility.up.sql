@@ -0,0 +1,11 @@
+-- Privacy is the one setting that decides who sees a trip. Visibility was
+-- set on its own and could disagree with it; it now follows privacy. Where
+-- they disagreed privacy wins, since it is what access was checked against.
+ALTER TABLE trips DROP COLUMN IF EXISTS visibility;
+
+ALTER TABLE trips ADD COLUMN visibility VARCHAR(20)
+    GENERATED ALWAYS AS (
+        CASE WHEN privacy = 'public' THEN 'public' ELSE 'private' END
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_trips_visibility ON trips(visibility);
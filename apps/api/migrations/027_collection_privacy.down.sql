@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_collections_public;
+
+UPDATE collections SET privacy = 'private' WHERE privacy = 'unlisted';
+
+ALTER TABLE collections DROP CONSTRAINT IF EXISTS collections_privacy_check;
+ALTER TABLE collections ADD CONSTRAINT collections_privacy_check
+    CHECK (privacy IN ('public', 'private', 'friends'));
@@ -0,0 +1,11 @@
+-- Collections are private, unlisted or public. Collections never had friends
+-- to share with, so friends-only collections become private.
+UPDATE collections SET privacy = 'private' WHERE privacy NOT IN ('private', 'public');
+
+ALTER TABLE collections DROP CONSTRAINT IF EXISTS collections_privacy_check;
+ALTER TABLE collections ADD CONSTRAINT collections_privacy_check
+    CHECK (privacy IN ('private', 'unlisted', 'public'));
+
+-- Public collections, newest first, for discovery
+CREATE INDEX IF NOT EXISTS idx_collections_public ON collections(updated_at DESC, id DESC)
+    WHERE privacy = 'public';
@@ -0,0 +1 @@
+DROP FUNCTION IF EXISTS place_open_at(JSONB, TIMESTAMPTZ);
@@ -0,0 +1,55 @@
+-- place_open_at reports whether opening hours are open at an instant. It
+-- reads hours the way the API does (see internal/domain/places/hours.go):
+-- local times in the hours' time zone, UTC when there is none; a range
+-- closing at or before it opens runs past midnight; an exception replaces
+-- the weekly hours of its date. Hours that cannot be read count as closed.
+CREATE OR REPLACE FUNCTION place_open_at(hours JSONB, at TIMESTAMPTZ)
+RETURNS BOOLEAN AS $$
+DECLARE
+    days CONSTANT TEXT[] := ARRAY['monday', 'tuesday', 'wednesday', 'thursday', 'friday', 'saturday', 'sunday'];
+    local_time TIMESTAMP;
+    minute INT;
+    day DATE;
+    ranges JSONB;
+    time_range JSONB;
+    opens INT;
+    closes INT;
+BEGIN
+    IF hours IS NULL OR jsonb_typeof(hours) <> 'object' THEN
+        RETURN FALSE;
+    END IF;
+
+    local_time := at AT TIME ZONE COALESCE(NULLIF(hours->>'timezone', ''), 'UTC');
+    minute := EXTRACT(HOUR FROM local_time)::INT * 60 + EXTRACT(MINUTE FROM local_time)::INT;
+
+    -- Today's ranges, then yesterday's that run past midnight
+    FOR days_back IN 0..1 LOOP
+        day := local_time::DATE - days_back;
+
+        SELECT COALESCE(exception->'hours', '[]'::JSONB) INTO ranges
+        FROM jsonb_array_elements(COALESCE(hours->'exceptions', '[]'::JSONB)) AS exception
+        WHERE exception->>'date' = to_char(day, 'YYYY-MM-DD')
+        LIMIT 1;
+        IF NOT FOUND THEN
+            ranges := COALESCE(hours->(days[EXTRACT(ISODOW FROM day)::INT]), '[]'::JSONB);
+        END IF;
+
+        FOR time_range IN SELECT * FROM jsonb_array_elements(ranges) LOOP
+            opens := split_part(time_range->>'open', ':', 1)::INT * 60 + split_part(time_range->>'open', ':', 2)::INT;
+            closes := split_part(time_range->>'close', ':', 1)::INT * 60 + split_part(time_range->>'close', ':', 2)::INT;
+
+            IF days_back = 0 AND minute >= opens AND (closes <= opens OR minute < closes) THEN
+                RETURN TRUE;
+            END IF;
+            IF days_back = 1 AND closes <= opens AND minute < closes THEN
+                RETURN TRUE;
+            END IF;
+        END LOOP;
+    END LOOP;
+
+    RETURN FALSE;
+EXCEPTION
+    WHEN OTHERS THEN
+        RETURN FALSE;
+END;
+$$ LANGUAGE plpgsql STABLE;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_places_amenities;
+
+ALTER TABLE places
+    DROP CONSTRAINT IF EXISTS places_amenities_check,
+    ALTER COLUMN amenities DROP NOT NULL,
+    ALTER COLUMN amenities DROP DEFAULT;
@@ -0,0 +1,51 @@
+-- Amenities come from a managed list (see internal/domain/places/amenities.go).
+-- Free-form values written before are matched to it where they can be; the
+-- rest are kept as tags.
+WITH listed AS (
+    SELECT p.id, a.value AS original,
+        CASE regexp_replace(lower(trim(a.value)), '[\s-]+', '_', 'g')
+            WHEN 'toilet' THEN 'toilets'
+            WHEN 'restroom' THEN 'toilets'
+            WHEN 'restrooms' THEN 'toilets'
+            WHEN 'bathroom' THEN 'toilets'
+            WHEN 'bathrooms' THEN 'toilets'
+            WHEN 'drinking_water' THEN 'potable_water'
+            WHEN 'water' THEN 'potable_water'
+            WHEN 'ev_charger' THEN 'ev_charging'
+            WHEN 'shower' THEN 'showers'
+            WHEN 'picnic_table' THEN 'picnic_tables'
+            WHEN 'picnic_area' THEN 'picnic_tables'
+            WHEN 'trash_bin' THEN 'trash_bins'
+            WHEN 'wi_fi' THEN 'wifi'
+            WHEN 'bike_rack' THEN 'bike_racks'
+            ELSE regexp_replace(lower(trim(a.value)), '[\s-]+', '_', 'g')
+        END AS amenity
+    FROM places p, unnest(p.amenities) AS a(value)
+),
+matched AS (
+    SELECT id,
+        COALESCE(array_agg(DISTINCT amenity) FILTER (WHERE amenity = ANY(ARRAY[
+            'toilets', 'potable_water', 'ev_charging', 'showers', 'parking',
+            'picnic_tables', 'trash_bins', 'wifi', 'bike_racks'])), '{}') AS amenities,
+        COALESCE(array_agg(DISTINCT original) FILTER (WHERE amenity <> ALL(ARRAY[
+            'toilets', 'potable_water', 'ev_charging', 'showers', 'parking',
+            'picnic_tables', 'trash_bins', 'wifi', 'bike_racks'])), '{}') AS others
+    FROM listed
+    GROUP BY id
+)
+UPDATE places p
+SET amenities = m.amenities,
+    tags = ARRAY(SELECT DISTINCT unnest(COALESCE(p.tags, '{}') || m.others))
+FROM matched m
+WHERE p.id = m.id;
+
+UPDATE places SET amenities = '{}' WHERE amenities IS NULL;
+
+ALTER TABLE places
+    ALTER COLUMN amenities SET DEFAULT '{}',
+    ALTER COLUMN amenities SET NOT NULL,
+    ADD CONSTRAINT places_amenities_check CHECK (amenities <@ ARRAY[
+        'toilets', 'potable_water', 'ev_charging', 'showers', 'parking',
+        'picnic_tables', 'trash_bins', 'wifi', 'bike_racks']);
+
+CREATE INDEX IF NOT EXISTS idx_places_amenities ON places USING gin(amenities);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS trip_participants;
+ALTER TABLE trips DROP COLUMN IF EXISTS capacity;
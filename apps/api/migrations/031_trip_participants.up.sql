@@ -0,0 +1,21 @@
+-- Who is coming on a trip, kept apart from the collaborators who plan it.
+-- Invitees answer going, maybe or declined; those going when the trip is
+-- full wait in line and move up as spots open.
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS capacity INTEGER CHECK (capacity > 0);
+
+CREATE TABLE IF NOT EXISTS trip_participants (
+    trip_id UUID NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'invited'
+        CHECK (status IN ('invited', 'going', 'maybe', 'declined', 'waitlisted')),
+    guests INTEGER NOT NULL DEFAULT 0 CHECK (guests >= 0),
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    responded_at TIMESTAMPTZ,
+    waitlisted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (trip_id, user_id),
+    CHECK ((status = 'waitlisted') = (waitlisted_at IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_participants_user ON trip_participants(user_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_user_friends_incoming;
+
+ALTER TABLE user_friends
+    DROP CONSTRAINT IF EXISTS user_friends_not_self,
+    DROP CONSTRAINT IF EXISTS user_friends_status_check,
+    ALTER COLUMN status DROP NOT NULL;
@@ -0,0 +1,13 @@
+-- Friend requests are the pending rows of user_friends, from user_id to
+-- friend_id. Accepted friendships have a row in each direction.
+UPDATE user_friends SET status = 'pending' WHERE status IS NULL;
+
+ALTER TABLE user_friends
+    ALTER COLUMN status SET NOT NULL,
+    ADD CONSTRAINT user_friends_status_check CHECK (status IN ('pending', 'accepted', 'blocked')),
+    ADD CONSTRAINT user_friends_not_self CHECK (user_id <> friend_id);
+
+-- Incoming requests are listed by recipient
+CREATE INDEX IF NOT EXISTS idx_user_friends_incoming
+    ON user_friends (friend_id, requested_at DESC)
+    WHERE status = 'pending';
@@ -0,0 +1,11 @@
+-- Nightly data quality reports. Each check is stored as
+-- {"name", "description", "count", "sample_ids"}.
+CREATE TABLE IF NOT EXISTS data_quality_reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    generated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    duration_ms BIGINT NOT NULL,
+    checks JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_quality_reports_generated
+    ON data_quality_reports (generated_at DESC);
@@ -0,0 +1,16 @@
+-- Logical backups of a user's or a trip's content, written to object storage
+-- by a background job and restored with the restore command
+CREATE TABLE IF NOT EXISTS backups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('user', 'trip')),
+    subject_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'failed')),
+    object_key TEXT,
+    size_bytes BIGINT,
+    error TEXT,
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_backups_subject ON backups (scope, subject_id, created_at DESC);
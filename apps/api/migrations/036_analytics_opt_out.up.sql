@@ -0,0 +1,2 @@
+-- Whether the user's activity is left out of the anonymized analytics export
+ALTER TABLE users ADD COLUMN IF NOT EXISTS analytics_opt_out BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS units;
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
@@ -0,0 +1,5 @@
+-- How exports are formatted for the user. An empty locale follows the
+-- browser's language, and empty units follow the locale.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS units TEXT NOT NULL DEFAULT ''
+    CHECK (units IN ('', 'metric', 'imperial'));
@@ -0,0 +1,10 @@
+-- Where the last bulk reindex of each search document type got to, so an
+-- interrupted run of the reindex command carries on from there
+CREATE TABLE IF NOT EXISTS search_reindex_checkpoints (
+    doc_type VARCHAR(20) PRIMARY KEY,
+    after_cursor TEXT NOT NULL,
+    scanned BIGINT NOT NULL DEFAULT 0,
+    indexed BIGINT NOT NULL DEFAULT 0,
+    started_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+ALTER TABLE trip_waypoints DROP COLUMN IF EXISTS notes_doc;
+ALTER TABLE trip_waypoints DROP COLUMN IF EXISTS notes_format;
@@ -0,0 +1,6 @@
+-- Waypoint notes may be written in markdown, with checklists and mentions of
+-- places. notes_doc holds what clients need to render them: the checklist
+-- items, the places mentioned and the notes as plain text.
+ALTER TABLE trip_waypoints ADD COLUMN IF NOT EXISTS notes_format VARCHAR(20) NOT NULL DEFAULT 'plain'
+    CHECK (notes_format IN ('plain', 'markdown'));
+ALTER TABLE trip_waypoints ADD COLUMN IF NOT EXISTS notes_doc JSONB;
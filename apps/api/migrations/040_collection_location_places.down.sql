@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_collection_locations_place_id;
+ALTER TABLE collection_locations DROP COLUMN IF EXISTS place_id;
@@ -0,0 +1,4 @@
+-- The place a collection location was imported as, if any
+ALTER TABLE collection_locations ADD COLUMN IF NOT EXISTS place_id UUID REFERENCES places(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_collection_locations_place_id ON collection_locations(place_id);
@@ -0,0 +1,2 @@
+DELETE FROM media_usage WHERE entity_type = 'trip_content';
+ALTER TABLE trips DROP COLUMN IF EXISTS content;
@@ -0,0 +1,5 @@
+-- A trip may be described in structured blocks of text, images, place cards,
+-- elevation charts and warnings. The description stays as their plain text
+-- for search, exports and older clients. Images in the content are recorded
+-- in media_usage as entity type 'trip_content'.
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS content JSONB;
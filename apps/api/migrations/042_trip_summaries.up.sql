@@ -0,0 +1,4 @@
+-- A short summary of each trip, generated from its distance, difficulty,
+-- waypoints and seasons whenever those change. It is shown in search
+-- results, share cards and digests.
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS summary TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,15 @@
+-- The offline map pack of a trip: a zip of its route, waypoints, places,
+-- annotations, variants, layers and media URLs that mobile clients cache.
+-- Packs are built by a background job; a trip has at most one, replaced
+-- each time it is requested again.
+CREATE TABLE IF NOT EXISTS trip_offline_packs (
+    trip_id UUID PRIMARY KEY REFERENCES trips(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'failed')),
+    storage_path TEXT,
+    size_bytes BIGINT,
+    error TEXT,
+    trip_updated_at TIMESTAMPTZ,
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMPTZ
+);
@@ -0,0 +1,2 @@
+ALTER TABLE trips DROP COLUMN IF EXISTS dismissed_tags;
+ALTER TABLE trips DROP COLUMN IF EXISTS suggested_tags;
@@ -0,0 +1,5 @@
+-- Tags suggested for a trip from the shape of its route and the peaks and
+-- waterways along it, until its owner accepts them into its tags or dismisses
+-- them. Dismissed tags are not suggested again.
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS suggested_tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS dismissed_tags TEXT[] NOT NULL DEFAULT '{}';
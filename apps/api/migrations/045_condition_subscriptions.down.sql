@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS condition_alerts;
+DROP TABLE IF EXISTS condition_subscriptions;
@@ -0,0 +1,31 @@
+-- Subscriptions to verified condition reports, on one trip or on any public
+-- trip in a region. Each report a subscription matches waits in
+-- condition_alerts until it is sent, at once or batched into an hourly or
+-- daily digest; next_send_at is when the pending alerts are due.
+CREATE TABLE IF NOT EXISTS condition_subscriptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    trip_id UUID REFERENCES trips(id) ON DELETE CASCADE,
+    region GEOGRAPHY(POLYGON, 4326),
+    severities TEXT[] NOT NULL DEFAULT '{warning,danger}'
+        CHECK (severities <@ ARRAY['info', 'warning', 'danger']::TEXT[] AND cardinality(severities) > 0),
+    frequency VARCHAR(20) NOT NULL DEFAULT 'instant' CHECK (frequency IN ('instant', 'hourly', 'daily')),
+    next_send_at TIMESTAMPTZ,
+    last_sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((trip_id IS NULL) <> (region IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_condition_subscriptions_user ON condition_subscriptions(user_id);
+CREATE INDEX IF NOT EXISTS idx_condition_subscriptions_trip ON condition_subscriptions(trip_id) WHERE trip_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_condition_subscriptions_region ON condition_subscriptions USING GIST(region);
+
+CREATE TABLE IF NOT EXISTS condition_alerts (
+    subscription_id UUID NOT NULL REFERENCES condition_subscriptions(id) ON DELETE CASCADE,
+    condition_id UUID NOT NULL REFERENCES activity_conditions(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMPTZ,
+    PRIMARY KEY (subscription_id, condition_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_condition_alerts_pending ON condition_alerts(subscription_id) WHERE sent_at IS NULL;
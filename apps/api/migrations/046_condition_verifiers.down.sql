@@ -0,0 +1,7 @@
+ALTER TABLE activity_conditions
+    DROP COLUMN IF EXISTS reviewed_at,
+    DROP COLUMN IF EXISTS reviewed_by,
+    DROP COLUMN IF EXISTS review_note,
+    DROP COLUMN IF EXISTS review_status;
+
+DROP TABLE IF EXISTS condition_verifiers;
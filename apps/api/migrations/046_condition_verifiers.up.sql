@@ -0,0 +1,20 @@
+-- Trusted verifiers of condition reports, granted by admins for a region, an
+-- activity or both; a grant with neither covers every trip. Verifiers confirm
+-- or dispute the reports they cover, which ranks them above or below reports
+-- only their trip's owner has vouched for.
+CREATE TABLE IF NOT EXISTS condition_verifiers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    region GEOGRAPHY(POLYGON, 4326),
+    activity_type VARCHAR(50),
+    granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_condition_verifiers_user ON condition_verifiers(user_id);
+
+ALTER TABLE activity_conditions
+    ADD COLUMN IF NOT EXISTS review_status VARCHAR(20) CHECK (review_status IN ('confirmed', 'disputed')),
+    ADD COLUMN IF NOT EXISTS review_note TEXT,
+    ADD COLUMN IF NOT EXISTS reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMPTZ;
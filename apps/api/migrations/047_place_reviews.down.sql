@@ -0,0 +1,2 @@
+DELETE FROM media_usage WHERE entity_type = 'place_review';
+DROP TABLE IF EXISTS place_reviews;
@@ -0,0 +1,18 @@
+-- Reviews of places: a star rating with optional text and photos, one per
+-- user and place. A place's average_rating and rating_count are kept in step
+-- with its reviews. Photos are uploaded media, recorded in media_usage as
+-- entity type 'place_review' so that media cleanup keeps them.
+CREATE TABLE IF NOT EXISTS place_reviews (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    place_id UUID NOT NULL REFERENCES places(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    review_text TEXT NOT NULL DEFAULT '',
+    media_ids UUID[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (place_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_place_reviews_place_created ON place_reviews(place_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_place_reviews_user ON place_reviews(user_id);
@@ -0,0 +1,108 @@
+// Package apiversion describes the versions of the public API and the
+// changes between them.
+//
+// Each version is frozen once a newer one is out: breaking changes only land
+// in the newest version, and older versions keep answering as they always
+// have until their sunset date.
+package apiversion
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// API versions, the path segment after /api
+const (
+	V1 = "v1"
+	V2 = "v2"
+)
+
+const (
+	// Header names the version a request is for and that a response is in
+	Header = "API-Version"
+
+	// MediaTypePrefix starts the vendor media types clients can ask for a
+	// version with, such as application/vnd.newmap.v2+json
+	MediaTypePrefix = "application/vnd.newmap."
+
+	contextKey = "apiVersion"
+)
+
+// Supported lists the versions the API answers, oldest first
+var Supported = []string{V1, V2}
+
+// Latest is the newest version
+const Latest = V2
+
+// Default is the version of requests that do not ask for one, so clients
+// that never pinned a version are not moved onto breaking changes
+const Default = V1
+
+// Parse reads a version as clients send it: "v2", "V2" or "2". It reports
+// false for versions the API does not answer.
+func Parse(s string) (string, bool) {
+	version := strings.ToLower(strings.TrimSpace(s))
+	if version != "" && !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	for _, supported := range Supported {
+		if version == supported {
+			return version, true
+		}
+	}
+	return "", false
+}
+
+// Set records the version a request is served by
+func Set(c *gin.Context, version string) {
+	c.Set(contextKey, version)
+}
+
+// FromContext returns the version a request is served by, the default when
+// it was not served by a versioned route
+func FromContext(c *gin.Context) string {
+	if version := c.GetString(contextKey); version != "" {
+		return version
+	}
+	return Default
+}
+
+// Change is an entry in the public changelog
+type Change struct {
+	Version  string    `json:"version"`
+	Date     time.Time `json:"date"`
+	Breaking bool      `json:"breaking"`
+	Summary  string    `json:"summary"`
+}
+
+// V2Released is when v2 came out, and so when v1 was deprecated
+var V2Released = time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+
+// Changelog lists the changes to the public API, newest first
+var Changelog = []Change{
+	{
+		Version:  V2,
+		Date:     V2Released,
+		Breaking: true,
+		Summary:  "Responses drop the success flag: successful ones carry data and meta, failed ones only an error, which includes the request ID.",
+	},
+	{
+		Version:  V2,
+		Date:     V2Released,
+		Breaking: true,
+		Summary:  "List metadata is cursor based: pass next_cursor back as the after parameter while has_more is true. Page numbers are gone and the fields are snake_case.",
+	},
+	{
+		Version:  V2,
+		Date:     V2Released,
+		Breaking: true,
+		Summary:  "IDs in paths must be UUIDs. Anything else is a 400 with the INVALID_ID code instead of a 404 or 500.",
+	},
+	{
+		Version: V1,
+		Date:    V2Released,
+		Summary: "v1 is frozen and deprecated. Its responses carry Deprecation, Sunset and Link headers pointing to v2.",
+	},
+}
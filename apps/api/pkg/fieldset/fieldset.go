@@ -0,0 +1,143 @@
+// Package fieldset implements sparse fieldsets: a ?fields=id,title,location
+// parameter that trims responses down to the requested top-level fields, and
+// an ?include= parameter that picks the related records to load.
+package fieldset
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrUnknownInclude = errors.New("unknown include")
+
+// alwaysIncluded fields are kept in every response so clients can match
+// pruned records to the ones they already hold
+var alwaysIncluded = []string{"id"}
+
+// Set is a parsed fieldset. A nil Set selects every field.
+type Set map[string]bool
+
+// Parse parses a comma separated list of field names. An empty list selects
+// every field.
+func Parse(raw string) Set {
+	var set Set
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if set == nil {
+			set = Set{}
+			for _, always := range alwaysIncluded {
+				set[always] = true
+			}
+		}
+		set[field] = true
+	}
+	return set
+}
+
+// All reports whether every field is selected
+func (s Set) All() bool {
+	return s == nil
+}
+
+// Has reports whether a field is selected
+func (s Set) Has(field string) bool {
+	return s == nil || s[field]
+}
+
+// Named reports whether a field was explicitly requested
+func (s Set) Named(field string) bool {
+	return s != nil && s[field]
+}
+
+// Select prunes v, a struct or a slice of structs, to the selected fields of
+// its JSON encoding. v is returned unchanged when every field is selected.
+func (s Set) Select(v interface{}) (interface{}, error) {
+	if s.All() {
+		return v, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response: %w", err)
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	switch value := decoded.(type) {
+	case map[string]interface{}:
+		return s.prune(value), nil
+	case []interface{}:
+		for i, item := range value {
+			if object, ok := item.(map[string]interface{}); ok {
+				value[i] = s.prune(object)
+			}
+		}
+		return value, nil
+	default:
+		return decoded, nil
+	}
+}
+
+func (s Set) prune(object map[string]interface{}) map[string]interface{} {
+	for field := range object {
+		if !s[field] {
+			delete(object, field)
+		}
+	}
+	return object
+}
+
+// Include is a parsed ?include= list of related records. A nil Include means
+// the parameter was not given.
+type Include map[string]bool
+
+// ParseInclude parses a comma separated list of related records, rejecting
+// any not in allowed
+func ParseInclude(raw string, allowed ...string) (Include, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	include := Include{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !contains(allowed, name) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownInclude, name)
+		}
+		include[name] = true
+	}
+	return include, nil
+}
+
+// Load decides whether a related record is loaded. It is when named in the
+// include list or the fieldset. Otherwise it is not when either parameter
+// narrows the response, and def decides when neither was given.
+func (i Include) Load(name string, fields Set, def bool) bool {
+	if i[name] || fields.Named(name) {
+		return true
+	}
+	if i != nil || !fields.All() {
+		return false
+	}
+	return def
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package fieldset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pin struct {
+	ID       string    `json:"id"`
+	Title    string    `json:"title"`
+	Location []float64 `json:"location"`
+	Notes    string    `json:"notes,omitempty"`
+}
+
+func TestParse(t *testing.T) {
+	assert.True(t, Parse("").All())
+	assert.True(t, Parse(" , ").All())
+
+	set := Parse("title, location")
+	assert.False(t, set.All())
+	assert.True(t, set.Has("title"))
+	assert.True(t, set.Has("location"))
+	assert.True(t, set.Has("id"))
+	assert.False(t, set.Has("notes"))
+}
+
+func TestSelect_Object(t *testing.T) {
+	selected, err := Parse("location").Select(pin{ID: "1", Title: "Summit", Location: []float64{35.2, 31.7}, Notes: "windy"})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"id":       "1",
+		"location": []interface{}{35.2, 31.7},
+	}, selected)
+}
+
+func TestSelect_List(t *testing.T) {
+	selected, err := Parse("title").Select([]*pin{{ID: "1", Title: "A"}, {ID: "2", Title: "B"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"id": "1", "title": "A"},
+		map[string]interface{}{"id": "2", "title": "B"},
+	}, selected)
+}
+
+func TestSelect_AllFields(t *testing.T) {
+	value := pin{ID: "1"}
+	selected, err := Parse("").Select(value)
+	require.NoError(t, err)
+	assert.Equal(t, value, selected)
+}
+
+func TestParseInclude(t *testing.T) {
+	include, err := ParseInclude("")
+	require.NoError(t, err)
+	assert.Nil(t, include)
+
+	include, err = ParseInclude("waypoints, media", "collaborators", "waypoints", "media")
+	require.NoError(t, err)
+	assert.Equal(t, Include{"waypoints": true, "media": true}, include)
+
+	_, err = ParseInclude("waypoints,owner", "waypoints")
+	assert.ErrorIs(t, err, ErrUnknownInclude)
+}
+
+func TestInclude_Load(t *testing.T) {
+	var none Include
+
+	// Neither parameter given
+	assert.True(t, none.Load("waypoints", Parse(""), true))
+	assert.False(t, none.Load("waypoints", Parse(""), false))
+
+	// Named in either parameter
+	assert.True(t, Include{"waypoints": true}.Load("waypoints", Parse(""), false))
+	assert.True(t, none.Load("waypoints", Parse("title,waypoints"), false))
+
+	// Narrowed by either parameter
+	assert.False(t, Include{"media": true}.Load("waypoints", Parse(""), true))
+	assert.False(t, none.Load("waypoints", Parse("title"), true))
+}
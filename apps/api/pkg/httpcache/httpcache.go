@@ -0,0 +1,84 @@
+// Package httpcache sets the HTTP caching headers that let a CDN cache public
+// responses, and tags them with surrogate keys so they can be purged when the
+// records behind them change.
+package httpcache
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// SurrogateKeyHeader lists the keys a cached response can be purged by
+	SurrogateKeyHeader = "Surrogate-Key"
+
+	// TripListKey tags every page of the public trip list
+	TripListKey = "trips"
+	// PlaceSearchKey tags every page of public place search results
+	PlaceSearchKey = "places"
+	// DiscoverKey tags every discovery list
+	DiscoverKey = "discover"
+)
+
+// Policy is how long browsers and shared caches may keep a response
+type Policy struct {
+	MaxAge               time.Duration // Browsers
+	SharedMaxAge         time.Duration // CDNs and other shared caches
+	StaleWhileRevalidate time.Duration
+}
+
+var (
+	// Detail is the policy for single trip and place responses
+	Detail = Policy{MaxAge: time.Minute, SharedMaxAge: 10 * time.Minute, StaleWhileRevalidate: time.Minute}
+	// Listing is the policy for lists and search results, which go stale sooner
+	Listing = Policy{MaxAge: 30 * time.Second, SharedMaxAge: 2 * time.Minute, StaleWhileRevalidate: 30 * time.Second}
+)
+
+// TripKey is the surrogate key of a trip
+func TripKey(tripID string) string {
+	return "trip-" + tripID
+}
+
+// PlaceKey is the surrogate key of a place
+func PlaceKey(placeID string) string {
+	return "place-" + placeID
+}
+
+// DiscoverListKey is the surrogate key of one discovery list
+func DiscoverListKey(list string) string {
+	return "discover-" + list
+}
+
+// Public marks a response as cacheable by shared caches and tags it with
+// surrogate keys. Requests carrying credentials may see more than anonymous
+// ones, so they are marked private instead.
+func Public(c *gin.Context, policy Policy, keys ...string) {
+	c.Header("Vary", "Authorization")
+	if c.GetHeader("Authorization") != "" {
+		Private(c)
+		return
+	}
+
+	value := fmt.Sprintf("public, max-age=%d, s-maxage=%d", seconds(policy.MaxAge), seconds(policy.SharedMaxAge))
+	if policy.StaleWhileRevalidate > 0 {
+		value += fmt.Sprintf(", stale-while-revalidate=%d", seconds(policy.StaleWhileRevalidate))
+	}
+	c.Header("Cache-Control", value)
+
+	if len(keys) > 0 {
+		c.Header(SurrogateKeyHeader, strings.Join(keys, " "))
+	}
+}
+
+// Private marks a response as only cacheable by the requesting client, which
+// must revalidate it before reuse
+func Private(c *gin.Context) {
+	c.Header("Cache-Control", "private, no-cache")
+}
+
+func seconds(d time.Duration) int {
+	return int(d / time.Second)
+}
@@ -0,0 +1,64 @@
+package httpcache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func request(authorization string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	if authorization != "" {
+		c.Request.Header.Set("Authorization", authorization)
+	}
+	return c, rec
+}
+
+func TestPublic(t *testing.T) {
+	c, rec := request("")
+	Public(c, Policy{MaxAge: time.Minute, SharedMaxAge: 10 * time.Minute, StaleWhileRevalidate: 30 * time.Second}, TripKey("1"), TripListKey)
+
+	assert.Equal(t, "public, max-age=60, s-maxage=600, stale-while-revalidate=30", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "trip-1 trips", rec.Header().Get(SurrogateKeyHeader))
+	assert.Equal(t, "Authorization", rec.Header().Get("Vary"))
+}
+
+func TestPublic_Authenticated(t *testing.T) {
+	c, rec := request("Bearer token")
+	Public(c, Detail, TripKey("1"))
+
+	assert.Equal(t, "private, no-cache", rec.Header().Get("Cache-Control"))
+	assert.Empty(t, rec.Header().Get(SurrogateKeyHeader))
+}
+
+type recordingPurger chan []string
+
+func (p recordingPurger) Purge(ctx context.Context, keys ...string) error {
+	p <- keys
+	return nil
+}
+
+func TestPurgeAsync(t *testing.T) {
+	purger := make(recordingPurger, 1)
+	PurgeAsync(purger, PlaceKey("1"))
+
+	select {
+	case keys := <-purger:
+		assert.Equal(t, []string{"place-1"}, keys)
+	case <-time.After(time.Second):
+		t.Fatal("purge was not called")
+	}
+
+	// Nothing to purge or nowhere to send it
+	PurgeAsync(purger)
+	PurgeAsync(nil, PlaceKey("1"))
+	assert.Empty(t, purger)
+}
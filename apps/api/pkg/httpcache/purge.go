@@ -0,0 +1,87 @@
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Purger evicts every cached response tagged with any of the given keys
+type Purger interface {
+	Purge(ctx context.Context, keys ...string) error
+}
+
+// NoOpPurger is used when no CDN is configured
+type NoOpPurger struct{}
+
+func (NoOpPurger) Purge(ctx context.Context, keys ...string) error {
+	return nil
+}
+
+// FastlyPurger purges surrogate keys through the Fastly API
+type FastlyPurger struct {
+	serviceID string
+	apiKey    string
+	client    *http.Client
+}
+
+// NewFastlyPurger creates a purger for a Fastly service
+func NewFastlyPurger(serviceID, apiKey string) *FastlyPurger {
+	return &FastlyPurger{
+		serviceID: serviceID,
+		apiKey:    apiKey,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *FastlyPurger) Purge(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string][]string{"surrogate_keys": keys})
+	if err != nil {
+		return fmt.Errorf("failed to encode purge request: %w", err)
+	}
+
+	url := fmt.Sprintf("https://api.fastly.com/service/%s/purge", p.serviceID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create purge request: %w", err)
+	}
+	req.Header.Set("Fastly-Key", p.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to purge %v: %w", keys, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to purge %v: status %d", keys, resp.StatusCode)
+	}
+	return nil
+}
+
+// PurgeAsync purges keys in the background so writes are not held up by the
+// CDN. Failures are logged; cached responses still expire on their own.
+func PurgeAsync(purger Purger, keys ...string) {
+	if purger == nil || len(keys) == 0 {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := purger.Purge(ctx, keys...); err != nil {
+			log.Printf("Failed to purge CDN cache: %v", err)
+		}
+	}()
+}
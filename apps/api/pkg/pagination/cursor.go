@@ -0,0 +1,99 @@
+// Package pagination provides keyset (cursor) pagination over lists ordered
+// by created_at and id. Unlike offsets, a cursor stays on the same item when
+// rows are inserted or deleted ahead of it, so pages never skip or repeat.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor identifies the last item of a page
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// New returns the cursor for an item
+func New(createdAt time.Time, id string) *Cursor {
+	return &Cursor{CreatedAt: createdAt, ID: id}
+}
+
+// Encode returns the opaque string form of the cursor handed to clients
+func (c *Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode parses a cursor produced by Encode. An empty string is no cursor.
+func Decode(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{CreatedAt: t, ID: id}, nil
+}
+
+// Condition returns a SQL condition selecting the rows that come after the
+// cursor, using placeholders starting at $argN. Queries must order by
+// createdAtColumn then idColumn in the same direction, see OrderBy.
+func (c *Cursor) Condition(createdAtColumn, idColumn string, desc bool, argN int) (string, []interface{}) {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	condition := fmt.Sprintf("(%s, %s) %s ($%d, $%d)", createdAtColumn, idColumn, op, argN, argN+1)
+	return condition, []interface{}{c.CreatedAt, c.ID}
+}
+
+// Follows reports whether an item comes after the cursor, for lists that are
+// paged in memory
+func (c *Cursor) Follows(createdAt time.Time, id string, desc bool) bool {
+	if !createdAt.Equal(c.CreatedAt) {
+		return createdAt.After(c.CreatedAt) != desc
+	}
+	if desc {
+		return id < c.ID
+	}
+	return id > c.ID
+}
+
+// OrderBy returns an ORDER BY expression on column with the id as a tie
+// breaker, so rows with equal keys keep their order from page to page. Keyset
+// pagination needs column to be the created_at column.
+func OrderBy(column, idColumn string, desc bool) string {
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
+	}
+	return fmt.Sprintf("%s %s, %s %s", column, dir, idColumn, dir)
+}
+
+// Next returns the cursor for the page after one ending with the given item,
+// or "" when the page was not full and so is the last one
+func Next(count, limit int, createdAt time.Time, id string) string {
+	if count == 0 || count < limit {
+		return ""
+	}
+	return New(createdAt, id).Encode()
+}
@@ -0,0 +1,67 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	created := time.Date(2025, 3, 14, 15, 9, 26, 535897000, time.UTC)
+	cursor := New(created, "4f5c1f8e-2d7a-4b8e-9c31-0a1b2c3d4e5f")
+
+	decoded, err := Decode(cursor.Encode())
+	require.NoError(t, err)
+	assert.True(t, decoded.CreatedAt.Equal(created))
+	assert.Equal(t, cursor.ID, decoded.ID)
+}
+
+func TestDecode(t *testing.T) {
+	cursor, err := Decode("")
+	assert.NoError(t, err)
+	assert.Nil(t, cursor)
+
+	for _, input := range []string{"!!!", "bm8tc2VwYXJhdG9y", "bm90LWEtdGltZXxhYmM"} {
+		_, err := Decode(input)
+		assert.ErrorIs(t, err, ErrInvalidCursor, input)
+	}
+}
+
+func TestCursor_Condition(t *testing.T) {
+	cursor := New(time.Unix(0, 0), "abc")
+
+	condition, args := cursor.Condition("t.created_at", "t.id", true, 3)
+	assert.Equal(t, "(t.created_at, t.id) < ($3, $4)", condition)
+	assert.Equal(t, []interface{}{cursor.CreatedAt, "abc"}, args)
+
+	condition, _ = cursor.Condition("created_at", "id", false, 1)
+	assert.Equal(t, "(created_at, id) > ($1, $2)", condition)
+}
+
+func TestCursor_Follows(t *testing.T) {
+	now := time.Now()
+	cursor := New(now, "m")
+
+	assert.True(t, cursor.Follows(now.Add(-time.Second), "z", true))
+	assert.False(t, cursor.Follows(now.Add(time.Second), "a", true))
+	assert.True(t, cursor.Follows(now, "a", true))
+	assert.False(t, cursor.Follows(now, "m", true))
+
+	assert.True(t, cursor.Follows(now.Add(time.Second), "a", false))
+	assert.True(t, cursor.Follows(now, "z", false))
+}
+
+func TestNext(t *testing.T) {
+	now := time.Now()
+
+	assert.Empty(t, Next(0, 20, now, "a"))
+	assert.Empty(t, Next(19, 20, now, "a"))
+	assert.NotEmpty(t, Next(20, 20, now, "a"))
+}
+
+func TestOrderBy(t *testing.T) {
+	assert.Equal(t, "t.created_at DESC, t.id DESC", OrderBy("t.created_at", "t.id", true))
+	assert.Equal(t, "created_at ASC, id ASC", OrderBy("created_at", "id", false))
+}
@@ -0,0 +1,12 @@
+package pagination
+
+// Total returns how many items match a list across all pages, given how many
+// the page at offset held. A page shorter than the limit is the last one, so
+// its items are all that follow the offset; otherwise count is called to ask
+// the database. A limit of zero or less means the page was not limited.
+func Total(found, limit, offset int, count func() (int64, error)) (int64, error) {
+	if limit <= 0 || found < limit && (found > 0 || offset == 0) {
+		return int64(offset + found), nil
+	}
+	return count()
+}
@@ -0,0 +1,36 @@
+package pagination
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTotal(t *testing.T) {
+	counted := func() (int64, error) { return 57, nil }
+
+	tests := []struct {
+		name                 string
+		found, limit, offset int
+		want                 int64
+	}{
+		{"short first page", 3, 20, 0, 3},
+		{"empty first page", 0, 20, 0, 0},
+		{"short last page", 17, 20, 40, 57},
+		{"full page", 20, 20, 20, 57},
+		{"past the end", 0, 20, 60, 57},
+		{"unlimited", 57, 0, 0, 57},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, err := Total(tt.found, tt.limit, tt.offset, counted)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, total)
+		})
+	}
+
+	_, err := Total(20, 20, 0, func() (int64, error) { return 0, errors.New("boom") })
+	assert.Error(t, err)
+}
@@ -0,0 +1,133 @@
+// Package repoerr classifies the errors repositories and services return, so
+// a missing or conflicting record can be told apart from a failure of the
+// database itself however much context the error picks up on the way out.
+package repoerr
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is a record that does not exist, or that the caller may
+	// not know exists
+	ErrNotFound = errors.New("not found")
+
+	// ErrConflict is a write that clashes with a record already there
+	ErrConflict = errors.New("conflict")
+
+	// ErrForeignKey is a write that refers to a record that does not exist
+	ErrForeignKey = errors.New("referenced record does not exist")
+)
+
+// Postgres error codes
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+// kindError is a domain error of one of the kinds above
+type kindError struct {
+	message string
+	kind    error
+}
+
+func (e *kindError) Error() string {
+	return e.message
+}
+
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+// NotFound returns a new error of the ErrNotFound kind, for a domain's
+// sentinel errors such as a missing trip
+func NotFound(message string) error {
+	return &kindError{message: message, kind: ErrNotFound}
+}
+
+// Conflict returns a new error of the ErrConflict kind
+func Conflict(message string) error {
+	return &kindError{message: message, kind: ErrConflict}
+}
+
+// ForeignKey returns a new error of the ErrForeignKey kind
+func ForeignKey(message string) error {
+	return &kindError{message: message, kind: ErrForeignKey}
+}
+
+// Message returns the message of the domain error in err's chain, which
+// unlike the context wrapped around it is fit to show to clients. It is
+// false when err has none.
+func Message(err error) (string, bool) {
+	var domainErr *kindError
+	if errors.As(err, &domainErr) {
+		return domainErr.message, true
+	}
+	return "", false
+}
+
+// Known reports whether err is of one of the kinds above
+func Known(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) || errors.Is(err, ErrForeignKey)
+}
+
+// classified is a database error of a known kind
+type classified struct {
+	err  error
+	kind error
+}
+
+func (e *classified) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *classified) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// FromPostgres classifies a database error: no rows is ErrNotFound, a
+// unique violation ErrConflict and a foreign key violation ErrForeignKey.
+// The original error stays in the chain. Other errors are returned as they
+// are.
+func FromPostgres(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return &classified{err: err, kind: ErrNotFound}
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case uniqueViolation:
+			return &classified{err: err, kind: ErrConflict}
+		case foreignKeyViolation:
+			return &classified{err: err, kind: ErrForeignKey}
+		}
+	}
+	return err
+}
+
+// Classify returns the domain error for a database error of a known kind,
+// keeping the database error in the chain, and other errors as they are.
+// Kinds with no domain error given are left to FromPostgres.
+func Classify(err error, notFound, conflict, foreignKey error) error {
+	err = FromPostgres(err)
+	var domainErr error
+	switch {
+	case errors.Is(err, ErrNotFound):
+		domainErr = notFound
+	case errors.Is(err, ErrConflict):
+		domainErr = conflict
+	case errors.Is(err, ErrForeignKey):
+		domainErr = foreignKey
+	}
+	if domainErr == nil {
+		return err
+	}
+	return &classified{err: err, kind: domainErr}
+}
@@ -0,0 +1,89 @@
+package repoerr
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	errTripNotFound   = NotFound("trip not found")
+	errAlreadyInvited = Conflict("user is already a collaborator")
+	errUnknownUser    = ForeignKey("user not found")
+)
+
+func TestDomainErrors(t *testing.T) {
+	err := fmt.Errorf("trip 42: %w", errTripNotFound)
+
+	assert.ErrorIs(t, err, errTripNotFound)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NotErrorIs(t, err, ErrConflict)
+	assert.True(t, Known(err))
+
+	message, ok := Message(err)
+	assert.True(t, ok)
+	assert.Equal(t, "trip not found", message)
+}
+
+func TestMessage_Unclassified(t *testing.T) {
+	_, ok := Message(errors.New("connection refused"))
+	assert.False(t, ok)
+	assert.False(t, Known(errors.New("connection refused")))
+}
+
+func TestFromPostgres(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{name: "no rows", err: sql.ErrNoRows, kind: ErrNotFound},
+		{name: "unique violation", err: &pq.Error{Code: "23505"}, kind: ErrConflict},
+		{name: "foreign key violation", err: &pq.Error{Code: "23503"}, kind: ErrForeignKey},
+		{name: "wrapped", err: fmt.Errorf("failed to add: %w", &pq.Error{Code: "23505"}), kind: ErrConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := FromPostgres(tt.err)
+			assert.ErrorIs(t, err, tt.kind)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	t.Run("other errors", func(t *testing.T) {
+		err := &pq.Error{Code: "40001"}
+		assert.Same(t, err, FromPostgres(err))
+		assert.Nil(t, FromPostgres(nil))
+	})
+}
+
+func TestClassify(t *testing.T) {
+	pqErr := &pq.Error{Code: "23505"}
+	err := Classify(pqErr, errTripNotFound, errAlreadyInvited, errUnknownUser)
+
+	assert.ErrorIs(t, err, errAlreadyInvited)
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.ErrorIs(t, err, pqErr)
+	assert.NotErrorIs(t, err, errTripNotFound)
+
+	message, _ := Message(fmt.Errorf("failed to add collaborator: %w", err))
+	assert.Equal(t, "user is already a collaborator", message)
+
+	t.Run("kind without a domain error", func(t *testing.T) {
+		err := Classify(sql.ErrNoRows, nil, errAlreadyInvited, nil)
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, ok := Message(err)
+		assert.False(t, ok)
+	})
+
+	t.Run("unclassified", func(t *testing.T) {
+		err := errors.New("connection reset")
+		assert.Same(t, err, Classify(err, errTripNotFound, errAlreadyInvited, errUnknownUser))
+		assert.Nil(t, Classify(nil, errTripNotFound, nil, nil))
+	})
+}
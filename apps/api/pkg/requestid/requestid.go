@@ -0,0 +1,21 @@
+package requestid
+
+import (
+	"context"
+)
+
+// Header carries the request ID between clients, proxies and the API
+const Header = "X-Request-ID"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the request ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx, or an empty string
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
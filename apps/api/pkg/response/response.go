@@ -1,8 +1,11 @@
 package response
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
+	"github.com/Oferzz/newMap/apps/api/pkg/repoerr"
 	"github.com/gin-gonic/gin"
 )
 
@@ -14,10 +17,11 @@ type Response struct {
 }
 
 type Meta struct {
-	Page     int   `json:"page"`
-	Limit    int   `json:"limit"`
-	Total    int64 `json:"total"`
-	HasMore  bool  `json:"hasMore"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+	Total      int64  `json:"total"`
+	HasMore    bool   `json:"hasMore"`
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 type Error struct {
@@ -27,14 +31,14 @@ type Error struct {
 }
 
 func Success(c *gin.Context, data interface{}) {
-	c.JSON(http.StatusOK, Response{
+	render(c, http.StatusOK, Response{
 		Success: true,
 		Data:    data,
 	})
 }
 
 func SuccessWithMeta(c *gin.Context, data interface{}, meta *Meta) {
-	c.JSON(http.StatusOK, Response{
+	render(c, http.StatusOK, Response{
 		Success: true,
 		Data:    data,
 		Meta:    meta,
@@ -42,7 +46,14 @@ func SuccessWithMeta(c *gin.Context, data interface{}, meta *Meta) {
 }
 
 func Created(c *gin.Context, data interface{}) {
-	c.JSON(http.StatusCreated, Response{
+	render(c, http.StatusCreated, Response{
+		Success: true,
+		Data:    data,
+	})
+}
+
+func Accepted(c *gin.Context, data interface{}) {
+	render(c, http.StatusAccepted, Response{
 		Success: true,
 		Data:    data,
 	})
@@ -65,11 +76,11 @@ func BadRequest(c *gin.Context, message string, details ...map[string]interface{
 		resp.Error.Details = details[0]
 	}
 	
-	c.JSON(http.StatusBadRequest, resp)
+	render(c, http.StatusBadRequest, resp)
 }
 
 func Unauthorized(c *gin.Context, message string) {
-	c.JSON(http.StatusUnauthorized, Response{
+	render(c, http.StatusUnauthorized, Response{
 		Success: false,
 		Error: &Error{
 			Code:    "UNAUTHORIZED",
@@ -79,7 +90,7 @@ func Unauthorized(c *gin.Context, message string) {
 }
 
 func Forbidden(c *gin.Context, message string) {
-	c.JSON(http.StatusForbidden, Response{
+	render(c, http.StatusForbidden, Response{
 		Success: false,
 		Error: &Error{
 			Code:    "FORBIDDEN",
@@ -89,7 +100,7 @@ func Forbidden(c *gin.Context, message string) {
 }
 
 func NotFound(c *gin.Context, message string) {
-	c.JSON(http.StatusNotFound, Response{
+	render(c, http.StatusNotFound, Response{
 		Success: false,
 		Error: &Error{
 			Code:    "NOT_FOUND",
@@ -111,11 +122,23 @@ func Conflict(c *gin.Context, message string, details ...map[string]interface{})
 		resp.Error.Details = details[0]
 	}
 	
-	c.JSON(http.StatusConflict, resp)
+	render(c, http.StatusConflict, resp)
+}
+
+// PayloadTooLarge reports a request body over the limit of its route
+func PayloadTooLarge(c *gin.Context, limit int64) {
+	render(c, http.StatusRequestEntityTooLarge, Response{
+		Success: false,
+		Error: &Error{
+			Code:    "PAYLOAD_TOO_LARGE",
+			Message: fmt.Sprintf("Request body exceeds the maximum of %d bytes", limit),
+			Details: map[string]interface{}{"limit": limit},
+		},
+	})
 }
 
 func InternalServerError(c *gin.Context, message string) {
-	c.JSON(http.StatusInternalServerError, Response{
+	render(c, http.StatusInternalServerError, Response{
 		Success: false,
 		Error: &Error{
 			Code:    "INTERNAL_SERVER_ERROR",
@@ -124,8 +147,53 @@ func InternalServerError(c *gin.Context, message string) {
 	})
 }
 
+// BadGateway reports that a service the request depends on failed
+func BadGateway(c *gin.Context, message string) {
+	render(c, http.StatusBadGateway, Response{
+		Success: false,
+		Error: &Error{
+			Code:    "BAD_GATEWAY",
+			Message: message,
+		},
+	})
+}
+
+// UnprocessableEntity reports a request that is well formed but refers to
+// something that does not exist
+func UnprocessableEntity(c *gin.Context, message string) {
+	render(c, http.StatusUnprocessableEntity, Response{
+		Success: false,
+		Error: &Error{
+			Code:    "UNPROCESSABLE_ENTITY",
+			Message: message,
+		},
+	})
+}
+
+// FromError reports an error a service returned by its kind: missing records are
+// 404s, conflicting writes 409s and references to missing records 422s, each
+// with the domain error's message. Anything else is a 500 with the fallback
+// message, since its details are not for clients.
+func FromError(c *gin.Context, err error, fallback string) {
+	message, ok := repoerr.Message(err)
+	if !ok {
+		message = fallback
+	}
+
+	switch {
+	case errors.Is(err, repoerr.ErrNotFound):
+		NotFound(c, message)
+	case errors.Is(err, repoerr.ErrConflict):
+		Conflict(c, message)
+	case errors.Is(err, repoerr.ErrForeignKey):
+		UnprocessableEntity(c, message)
+	default:
+		InternalServerError(c, fallback)
+	}
+}
+
 func ValidationError(c *gin.Context, errors map[string]interface{}) {
-	c.JSON(http.StatusBadRequest, Response{
+	render(c, http.StatusBadRequest, Response{
 		Success: false,
 		Error: &Error{
 			Code:    "VALIDATION_ERROR",
@@ -143,4 +211,14 @@ func NewMeta(page, limit int, total int64) *Meta {
 		Total:   total,
 		HasMore: hasMore,
 	}
-}
\ No newline at end of file
+}
+
+// NewCursorMeta builds the meta for a keyset paginated list. An empty
+// nextCursor marks the last page.
+func NewCursorMeta(limit int, nextCursor string) *Meta {
+	return &Meta{
+		Limit:      limit,
+		HasMore:    nextCursor != "",
+		NextCursor: nextCursor,
+	}
+}
@@ -0,0 +1,80 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/Oferzz/newMap/apps/api/pkg/apiversion"
+	"github.com/Oferzz/newMap/apps/api/pkg/requestid"
+	"github.com/gin-gonic/gin"
+)
+
+// Envelope is the body of v2 responses. The status code tells success from
+// failure, so there is no flag: a success carries data and meta, a failure
+// only the error.
+type Envelope struct {
+	Data  interface{}    `json:"data,omitempty"`
+	Meta  *CursorMeta    `json:"meta,omitempty"`
+	Error *EnvelopeError `json:"error,omitempty"`
+}
+
+// CursorMeta is the meta of v2 lists, which are paged by cursor
+type CursorMeta struct {
+	Limit      int    `json:"limit"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+
+	// Only lists that count their matches have a total
+	Total *int64 `json:"total,omitempty"`
+}
+
+// EnvelopeError is a v2 error, with the ID to quote when reporting it
+type EnvelopeError struct {
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+}
+
+// render writes the response in the envelope of the request's API version
+func render(c *gin.Context, status int, resp Response) {
+	if apiversion.FromContext(c) != apiversion.V2 {
+		c.JSON(status, resp)
+		return
+	}
+
+	envelope := Envelope{Data: resp.Data}
+	if resp.Meta != nil {
+		envelope.Meta = &CursorMeta{
+			Limit:      resp.Meta.Limit,
+			HasMore:    resp.Meta.HasMore,
+			NextCursor: resp.Meta.NextCursor,
+		}
+		// Offset pages count their matches, cursor pages leave the page out
+		if resp.Meta.Page > 0 {
+			total := resp.Meta.Total
+			envelope.Meta.Total = &total
+		}
+	}
+	if resp.Error != nil {
+		envelope.Error = &EnvelopeError{
+			Code:      resp.Error.Code,
+			Message:   resp.Error.Message,
+			Details:   resp.Error.Details,
+			RequestID: requestid.FromContext(c.Request.Context()),
+		}
+	}
+
+	c.JSON(status, envelope)
+}
+
+// InvalidID reports a path parameter that is not a well formed ID
+func InvalidID(c *gin.Context, param string) {
+	render(c, http.StatusBadRequest, Response{
+		Success: false,
+		Error: &Error{
+			Code:    "INVALID_ID",
+			Message: "Invalid " + param,
+			Details: map[string]interface{}{"param": param},
+		},
+	})
+}
@@ -0,0 +1,206 @@
+// Package richtext sanitizes the markdown users write notes in, and picks
+// out the checklists and mentions in it that clients render specially
+package richtext
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Formats a note can be written in
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+)
+
+// MentionPlace is the kind of a mention of a place
+const MentionPlace = "place"
+
+var (
+	htmlComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlTag     = regexp.MustCompile(`</?[A-Za-z][A-Za-z0-9-]*(?:\s[^<>]*)?/?>`)
+
+	// Inline links and images, [text](url "title"), whose urls may hold
+	// balanced parentheses
+	inlineLink = regexp.MustCompile(`(!?)\[([^\]]*)\]\(\s*<?((?:[^()\s<>]|\([^()\s<>]*\))*)>?(?:\s+"[^"]*")?\s*\)`)
+	// Autolinks, <scheme:...>
+	autolink = regexp.MustCompile(`<([A-Za-z][A-Za-z0-9+.-]*:[^<>\s]*)>`)
+	// Link reference definitions, [label]: url
+	linkReference = regexp.MustCompile(`(?m)^ {0,3}\[[^\]]+\]:\s*<?(\S*?)>?(?:\s.*)?$`)
+
+	// Mentions are links to a place, [@Name](place:id)
+	mention = regexp.MustCompile(`\[@([^\]]+)\]\(place:([0-9A-Fa-f-]{36})\)`)
+	// Checklist items are list items starting with a box, - [ ] or - [x]
+	checklistItem = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+\[([ xX])\]\s+(.*)$`)
+
+	heading    = regexp.MustCompile(`^\s{0,3}#{1,6}\s+`)
+	blockquote = regexp.MustCompile(`^\s{0,3}(?:>\s?)+`)
+	emphasis   = regexp.MustCompile(`(\*\*|__|~~|\*|` + "`" + `)([^*_~` + "`" + `\n]+)(\*\*|__|~~|\*|` + "`" + `)`)
+)
+
+// safeSchemes are the URL schemes links may use. Links without a scheme are
+// relative and safe too.
+var safeSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+	"tel":    true,
+	"place":  true,
+}
+
+// ChecklistItem is an item of a checklist in a note
+type ChecklistItem struct {
+	Text    string `json:"text"`
+	Checked bool   `json:"checked"`
+	Line    int    `json:"line"` // Line of the note it is on, from 0, for clients to toggle it
+}
+
+// Mention is a link in a note to a record, such as a place
+type Mention struct {
+	Kind  string `json:"kind"`
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// Document is what clients need to render a markdown note beyond the
+// markdown itself
+type Document struct {
+	Checklist []ChecklistItem `json:"checklist,omitempty"`
+	Mentions  []Mention       `json:"mentions,omitempty"`
+
+	// Text is the note as plain text, for previews and exports
+	Text string `json:"text"`
+}
+
+// Sanitize makes markdown safe to render. Raw HTML is removed, as are links
+// to scripts and other unsafe schemes, keeping their text, and control
+// characters other than newlines and tabs.
+func Sanitize(markdown string) string {
+	markdown = strings.ReplaceAll(markdown, "\r\n", "\n")
+	markdown = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' || !unicode.IsControl(r) {
+			return r
+		}
+		return -1
+	}, markdown)
+
+	markdown = htmlComment.ReplaceAllString(markdown, "")
+	markdown = htmlTag.ReplaceAllString(markdown, "")
+
+	markdown = inlineLink.ReplaceAllStringFunc(markdown, func(link string) string {
+		parts := inlineLink.FindStringSubmatch(link)
+		if safeURL(parts[3]) {
+			return link
+		}
+		return parts[2]
+	})
+	markdown = autolink.ReplaceAllStringFunc(markdown, func(link string) string {
+		if safeURL(autolink.FindStringSubmatch(link)[1]) {
+			return link
+		}
+		return ""
+	})
+	markdown = linkReference.ReplaceAllStringFunc(markdown, func(definition string) string {
+		if safeURL(linkReference.FindStringSubmatch(definition)[1]) {
+			return definition
+		}
+		return ""
+	})
+
+	return strings.TrimSpace(markdown)
+}
+
+// safeURL reports whether a link URL is relative or has a safe scheme.
+// Renderers decode entities and ignore blanks in URLs, so they are decoded
+// and ignored before the scheme is read.
+func safeURL(url string) bool {
+	url = html.UnescapeString(url)
+	url = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, url)
+
+	colon := strings.IndexByte(url, ':')
+	if colon < 0 || strings.ContainsAny(url[:colon], "/?#") {
+		return true
+	}
+	return safeSchemes[strings.ToLower(url[:colon])]
+}
+
+// Parse picks out the checklist items and mentions of sanitized markdown,
+// and renders it as plain text
+func Parse(markdown string) *Document {
+	doc := &Document{}
+
+	seen := make(map[string]bool)
+	for _, match := range mention.FindAllStringSubmatch(markdown, -1) {
+		id := strings.ToLower(match[2])
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		doc.Mentions = append(doc.Mentions, Mention{Kind: MentionPlace, ID: id, Label: match[1]})
+	}
+
+	lines := strings.Split(markdown, "\n")
+	text := make([]string, len(lines))
+	for i, line := range lines {
+		if match := checklistItem.FindStringSubmatch(line); match != nil {
+			item := ChecklistItem{Text: plainText(match[2]), Checked: match[1] != " ", Line: i}
+			doc.Checklist = append(doc.Checklist, item)
+
+			box := "[ ]"
+			if item.Checked {
+				box = "[x]"
+			}
+			text[i] = box + " " + item.Text
+			continue
+		}
+
+		line = heading.ReplaceAllString(line, "")
+		line = blockquote.ReplaceAllString(line, "")
+		text[i] = plainText(line)
+	}
+	doc.Text = strings.TrimSpace(strings.Join(text, "\n"))
+
+	return doc
+}
+
+// plainText strips the inline markup of a line, keeping the text of links
+// and mentions
+func plainText(line string) string {
+	line = mention.ReplaceAllString(line, "$1")
+	line = inlineLink.ReplaceAllString(line, "$2")
+	line = autolink.ReplaceAllString(line, "$1")
+	line = emphasis.ReplaceAllString(line, "$2")
+	return strings.TrimRight(line, " \t")
+}
+
+// Value implements the driver.Valuer interface for Document
+func (d *Document) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+	return json.Marshal(d)
+}
+
+// Scan implements the sql.Scanner interface for Document
+func (d *Document) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return nil
+	}
+
+	return json.Unmarshal(data, d)
+}
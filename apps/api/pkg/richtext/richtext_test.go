@@ -0,0 +1,52 @@
+package richtext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{"markdown is kept", "## Parking\n- **Arrive early**, the lot fills by 8\n- [ ] Pay at the kiosk", "## Parking\n- **Arrive early**, the lot fills by 8\n- [ ] Pay at the kiosk"},
+		{"html is removed", "Bring <b>cash</b><script>alert(1)</script><!-- hidden -->", "Bring cashalert(1)"},
+		{"safe links are kept", "[Permits](https://example.com/permits) and <https://example.com>", "[Permits](https://example.com/permits) and <https://example.com>"},
+		{"relative links are kept", "[See above](#parking)", "[See above](#parking)"},
+		{"script links lose their url", "[Click](javascript:alert(1)) and ![x](data:image/png;base64,AAAA)", "Click and x"},
+		{"encoded schemes are caught", "[Click](java&#115;cript:alert)", "Click"},
+		{"unsafe autolinks are removed", "Go <javascript:alert(1)>", "Go"},
+		{"unsafe references are removed", "[a]\n\n[a]: vbscript:msgbox", "[a]"},
+		{"control characters are removed", "Line\r\none\x00\x1b", "Line\none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Sanitize(tt.markdown))
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	markdown := "# Gear check\n" +
+		"Meet at [@Smith Rock](place:3F2504E0-4F89-11D3-9A0C-0305E82C3301) *early*.\n" +
+		"- [x] Headlamp\n" +
+		"- [ ] Water, see [@Smith Rock](place:3f2504e0-4f89-11d3-9a0c-0305e82c3301)\n" +
+		"1. [X] **Permit**\n" +
+		"- Not an item"
+
+	doc := Parse(markdown)
+
+	assert.Equal(t, []ChecklistItem{
+		{Text: "Headlamp", Checked: true, Line: 2},
+		{Text: "Water, see Smith Rock", Checked: false, Line: 3},
+		{Text: "Permit", Checked: true, Line: 4},
+	}, doc.Checklist)
+	assert.Equal(t, []Mention{
+		{Kind: MentionPlace, ID: "3f2504e0-4f89-11d3-9a0c-0305e82c3301", Label: "Smith Rock"},
+	}, doc.Mentions)
+	assert.Equal(t, "Gear check\nMeet at Smith Rock early.\n[x] Headlamp\n[ ] Water, see Smith Rock\n[x] Permit\n- Not an item", doc.Text)
+}
@@ -0,0 +1,109 @@
+// Package sqlbuilder assembles Postgres queries whose conditions depend on
+// the filters a caller sets. Fragments are written with ? placeholders, which
+// are numbered $1, $2, ... as they are added, so placeholders always line up
+// with the bound arguments however many conditions are left out.
+package sqlbuilder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Builder is a query being assembled together with its arguments
+type Builder struct {
+	query strings.Builder
+	args  []interface{}
+}
+
+// New starts a query. Placeholders in base are numbered like any other
+// fragment's, so the base query may take arguments too.
+func New(base string, args ...interface{}) *Builder {
+	return new(Builder).Append(base, args...)
+}
+
+// Append adds a fragment such as an ORDER BY or LIMIT clause, binding one
+// argument to each ? in it. A literal question mark, such as the jsonb ?
+// operator, is written ??. A fragment that uses a value twice takes it twice.
+//
+// Append panics when the placeholders and arguments don't match, since that
+// is a mistake in the query rather than in its input.
+func (b *Builder) Append(fragment string, args ...interface{}) *Builder {
+	used := 0
+	for i := 0; i < len(fragment); i++ {
+		if fragment[i] != '?' {
+			b.query.WriteByte(fragment[i])
+			continue
+		}
+		if i+1 < len(fragment) && fragment[i+1] == '?' {
+			b.query.WriteByte('?')
+			i++
+			continue
+		}
+		if used == len(args) {
+			panic(fmt.Sprintf("sqlbuilder: more placeholders than the %d arguments in %q", len(args), fragment))
+		}
+		b.args = append(b.args, args[used])
+		used++
+		b.query.WriteString("$" + strconv.Itoa(len(b.args)))
+	}
+	if used != len(args) {
+		panic(fmt.Sprintf("sqlbuilder: %d placeholders for %d arguments in %q", used, len(args), fragment))
+	}
+	return b
+}
+
+// Where adds a condition with AND, so the base query must already have a
+// WHERE clause. Conditions joined with OR need their own parentheses.
+func (b *Builder) Where(condition string, args ...interface{}) *Builder {
+	b.query.WriteString(" AND ")
+	return b.Append(condition, args...)
+}
+
+// WhereAny adds conditions of which at least one must hold. Nothing is added
+// when there are none.
+func (b *Builder) WhereAny(conditions []Condition) *Builder {
+	if len(conditions) == 0 {
+		return b
+	}
+
+	parts := make([]string, len(conditions))
+	var args []interface{}
+	for i, condition := range conditions {
+		parts[i] = condition.SQL
+		args = append(args, condition.Args...)
+	}
+	return b.Where("("+strings.Join(parts, " OR ")+")", args...)
+}
+
+// Next returns the number of the next placeholder, for conditions numbered
+// by helpers such as pagination.Cursor.Condition
+func (b *Builder) Next() int {
+	return len(b.args) + 1
+}
+
+// WhereNumbered adds a condition whose placeholders were already numbered
+// starting at Next, binding its arguments in order
+func (b *Builder) WhereNumbered(condition string, args ...interface{}) *Builder {
+	b.query.WriteString(" AND ")
+	b.query.WriteString(condition)
+	b.args = append(b.args, args...)
+	return b
+}
+
+// SQL returns the query built so far
+func (b *Builder) SQL() string {
+	return b.query.String()
+}
+
+// Args returns the arguments bound so far, in placeholder order
+func (b *Builder) Args() []interface{} {
+	return b.args
+}
+
+// Condition is a condition with ? placeholders and the arguments they bind,
+// built before it is known how it will be combined
+type Condition struct {
+	SQL  string
+	Args []interface{}
+}
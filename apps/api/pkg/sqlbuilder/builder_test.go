@@ -0,0 +1,59 @@
+package sqlbuilder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder_NumbersPlaceholders(t *testing.T) {
+	b := New("SELECT id FROM trips WHERE deleted_at IS NULL").
+		Where("owner_id = ?", "u1").
+		Where("(title ILIKE ? OR description ILIKE ?)", "%x%", "%x%").
+		Append(" LIMIT ? OFFSET ?", 20, 0)
+
+	assert.Equal(t, "SELECT id FROM trips WHERE deleted_at IS NULL AND owner_id = $1 AND (title ILIKE $2 OR description ILIKE $3) LIMIT $4 OFFSET $5", b.SQL())
+	assert.Equal(t, []interface{}{"u1", "%x%", "%x%", 20, 0}, b.Args())
+}
+
+func TestBuilder_BaseArgs(t *testing.T) {
+	b := New("SELECT id FROM trips WHERE owner_id = ?", "u1").Where("status = ?", "active")
+
+	assert.Equal(t, "SELECT id FROM trips WHERE owner_id = $1 AND status = $2", b.SQL())
+	assert.Equal(t, []interface{}{"u1", "active"}, b.Args())
+}
+
+func TestBuilder_EscapedQuestionMark(t *testing.T) {
+	b := New("SELECT id FROM places WHERE true").Where("metadata ?? ?", "key")
+
+	assert.Equal(t, "SELECT id FROM places WHERE true AND metadata ? $1", b.SQL())
+	assert.Equal(t, []interface{}{"key"}, b.Args())
+}
+
+func TestBuilder_WhereAny(t *testing.T) {
+	b := New("SELECT id FROM places WHERE true").
+		WhereAny(nil).
+		Where("type = ?", "poi").
+		WhereAny([]Condition{
+			{SQL: "city ILIKE ?", Args: []interface{}{"%a%"}},
+			{SQL: "ST_Within(location, ST_MakeEnvelope(?, ?, ?, ?, 4326))", Args: []interface{}{1.0, 2.0, 3.0, 4.0}},
+		})
+
+	assert.Equal(t, "SELECT id FROM places WHERE true AND type = $1 AND (city ILIKE $2 OR ST_Within(location, ST_MakeEnvelope($3, $4, $5, $6, 4326)))", b.SQL())
+	assert.Len(t, b.Args(), 6)
+}
+
+func TestBuilder_WhereNumbered(t *testing.T) {
+	b := New("SELECT id FROM trips WHERE true").Where("owner_id = ?", "u1")
+	assert.Equal(t, 2, b.Next())
+
+	b.WhereNumbered("(created_at, id) < ($2, $3)", "t", "id").Append(" LIMIT ?", 10)
+
+	assert.Equal(t, "SELECT id FROM trips WHERE true AND owner_id = $1 AND (created_at, id) < ($2, $3) LIMIT $4", b.SQL())
+	assert.Equal(t, []interface{}{"u1", "t", "id", 10}, b.Args())
+}
+
+func TestBuilder_MismatchedArgs(t *testing.T) {
+	assert.Panics(t, func() { New("SELECT 1 WHERE true").Where("a = ? AND b = ?", 1) })
+	assert.Panics(t, func() { New("SELECT 1 WHERE true").Where("a = ?", 1, 2) })
+}
@@ -0,0 +1,41 @@
+// Package textsearch turns what people type into a search box into Postgres
+// full-text queries against the search_vector columns of trips and places.
+package textsearch
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Config is the text search configuration the search vectors are built with.
+// Queries must use the same one for their words to be stemmed alike.
+const Config = "english"
+
+// Match is the condition a search vector column meets for a query bound to
+// its placeholder
+func Match(column string) string {
+	return column + " @@ to_tsquery('" + Config + "', ?)"
+}
+
+// Rank is the relevance of a row to a query bound to its placeholder, higher
+// for better matches
+func Rank(column string) string {
+	return "ts_rank(" + column + ", to_tsquery('" + Config + "', ?))"
+}
+
+// PrefixQuery turns free text into a to_tsquery expression matching text
+// that has every word in it, the last one as a prefix so results follow
+// along while a word is being typed. Anything but letters and digits is
+// dropped, so operators in the text can't make the query invalid. It is
+// empty when the text has no words.
+func PrefixQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+
+	words[len(words)-1] += ":*"
+	return strings.Join(words, " & ")
+}
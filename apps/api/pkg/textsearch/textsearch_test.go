@@ -0,0 +1,29 @@
+package textsearch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefixQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"ridge", "ridge:*"},
+		{"Ein Gedi spring", "ein & gedi & spring:*"},
+		{"  wadi  ", "wadi:*"},
+		{"sea-to-sea", "sea & to & sea:*"},
+		{"a & !b | c:* <-> (d)", "a & b & c & d:*"},
+		{"Mitzpe Ramon 2024", "mitzpe & ramon & 2024:*"},
+		{"Zürich", "zürich:*"},
+		{"'; DROP TABLE trips; --", "drop & table & trips:*"},
+		{"&|!():*", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, PrefixQuery(tt.text), tt.text)
+	}
+}
@@ -137,6 +137,8 @@ class TripsService {
     if (params?.limit) queryParams.append('limit', params.limit.toString());
     if (params?.status) queryParams.append('status', params.status);
     if (params?.privacy) queryParams.append('privacy', params.privacy);
+    // Lists omit related records unless asked; the trips panel shows waypoint counts
+    queryParams.append('include', 'waypoints');
 
     const query = queryParams.toString();
     return api.get<PaginatedResponse<Trip>>(`/trips${query ? `?${query}` : ''}`);
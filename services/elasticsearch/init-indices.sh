@@ -46,8 +46,11 @@ curl -X PUT "http://localhost:9200/activities" -H 'Content-Type: application/jso
       "location": { "type": "geo_point" },
       "route": { "type": "geo_shape" },
       "water_features": { "type": "keyword" },
+      "accessibility": { "type": "keyword" },
+      "trailhead_amenities": { "type": "keyword" },
       "terrain_types": { "type": "keyword" },
       "best_seasons": { "type": "keyword" },
+      "privacy": { "type": "keyword" },
       "visibility": { "type": "keyword" },
       "owner_id": { "type": "keyword" },
       "tags": { "type": "keyword" },
@@ -93,9 +96,14 @@ curl -X PUT "http://localhost:9200/places" -H 'Content-Type: application/json' -
       "location": { "type": "geo_point" },
       "category": { "type": "keyword" },
       "tags": { "type": "keyword" },
+      "accessibility": { "type": "keyword" },
+      "amenities": { "type": "keyword" },
       "city": { "type": "keyword" },
       "state": { "type": "keyword" },
       "country": { "type": "keyword" },
+      "owner_id": { "type": "keyword" },
+      "privacy": { "type": "keyword" },
+      "open_hours": { "type": "integer_range" },
       "average_rating": { "type": "float" },
       "created_at": { "type": "date" },
       "updated_at": { "type": "date" }
@@ -103,6 +111,45 @@ curl -X PUT "http://localhost:9200/places" -H 'Content-Type: application/json' -
   }
 }'
 
+# Create collections index with mapping, for public collections
+curl -X PUT "http://localhost:9200/collections" -H 'Content-Type: application/json' -d '
+{
+  "settings": {
+    "number_of_shards": 1,
+    "number_of_replicas": 0,
+    "analysis": {
+      "analyzer": {
+        "collection_analyzer": {
+          "type": "custom",
+          "tokenizer": "standard",
+          "filter": ["lowercase", "asciifolding", "stop", "snowball"]
+        }
+      }
+    }
+  },
+  "mappings": {
+    "properties": {
+      "id": { "type": "keyword" },
+      "type": { "type": "keyword" },
+      "name": { 
+        "type": "text",
+        "analyzer": "collection_analyzer",
+        "fields": {
+          "keyword": { "type": "keyword" }
+        }
+      },
+      "description": { 
+        "type": "text",
+        "analyzer": "collection_analyzer"
+      },
+      "owner_id": { "type": "keyword" },
+      "privacy": { "type": "keyword" },
+      "created_at": { "type": "date" },
+      "updated_at": { "type": "date" }
+    }
+  }
+}'
+
 # Create search_queries index for analytics
 curl -X PUT "http://localhost:9200/search_queries" -H 'Content-Type: application/json' -d '
 {